@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/migrations"
 
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
@@ -23,8 +26,64 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	JWTSecret  string
+	Host       string
 	Port       string
 	Env        string
+
+	// Database connection pool: at most DBMaxOpenConns connections, of which DBMaxIdleConns are
+	// kept open when idle. Connections are replaced after DBConnMaxLifetime, or after
+	// DBConnMaxIdleTime unused.
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+
+	// Queries taking SlowQueryThreshold or longer are logged and counted; zero turns it off
+	SlowQueryThreshold time.Duration
+
+	// HTTP server timeouts
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+
+	// Cart items expire CartTTL after they were last changed; owners are reminded
+	// CartReminderBefore the expiry
+	CartTTL            time.Duration
+	CartReminderBefore time.Duration
+
+	// Sessions can be refreshed for SessionLifetime after the last refresh, or
+	// RememberMeLifetime when the user asked to be remembered on the device
+	SessionLifetime    time.Duration
+	RememberMeLifetime time.Duration
+
+	// OnlinePaymentWindow is how long an online order may stay unpaid before it is cancelled
+	OnlinePaymentWindow time.Duration
+
+	// AccountDeletionGrace is how long a deleted account can still be restored by logging in
+	// before its personal data is anonymized
+	AccountDeletionGrace time.Duration
+
+	// Currencies: prices are stored and charged in BaseCurrency; clients may request amounts in
+	// any of SupportedCurrencies, converted with rates refreshed from ExchangeRateURL
+	BaseCurrency        string
+	SupportedCurrencies []string
+	ExchangeRateURL     string
+	ExchangeRateRefresh time.Duration
+
+	// Catalog responses are cached for CatalogCacheTTL (zero turns the cache off) in
+	// CatalogCacheStore: memory or redis
+	CatalogCacheTTL   time.Duration
+	CatalogCacheStore string
+
+	// MigrateOnStart applies pending database migrations when the server starts; turn it off to
+	// run them separately with "readsphere migrate up"
+	MigrateOnStart bool
+}
+
+// Address returns the host:port the HTTP server listens on
+func (c *Config) Address() string {
+	return c.Host + ":" + c.Port
 }
 
 // LoadConfig loads configuration from environment variables
@@ -41,147 +100,140 @@ func LoadConfig() (*Config, error) {
 		DBPassword: os.Getenv("DB_PASSWORD"),
 		DBName:     os.Getenv("DB_NAME"),
 		JWTSecret:  os.Getenv("JWT_SECRET"),
-		Port:       os.Getenv("PORT"),
+		Host:       getEnvDefault("HOST", "0.0.0.0"),
+		Port:       getEnvDefault("PORT", "8080"),
 		Env:        os.Getenv("ENV"),
 	}
 
-	return config, nil
-}
-
-// MigrateCategoryNames standardizes category names and removes duplicates
-func MigrateCategoryNames(db *gorm.DB) error {
-	// Start transaction
-	tx := db.Begin()
-	if tx.Error != nil {
-		return tx.Error
+	if config.DBMaxOpenConns, err = getEnvInt("DB_MAX_OPEN_CONNS", 25); err != nil {
+		return nil, err
 	}
-
-	// Drop any existing indexes on the name column
-	if err := tx.Exec(`DROP INDEX IF EXISTS idx_categories_name_lower`).Error; err != nil {
-		tx.Rollback()
-		return err
+	if config.DBMaxIdleConns, err = getEnvInt("DB_MAX_IDLE_CONNS", 10); err != nil {
+		return nil, err
 	}
-	if err := tx.Exec(`DROP INDEX IF EXISTS categories_name_key`).Error; err != nil {
-		tx.Rollback()
-		return err
+	if config.DBMaxIdleConns > config.DBMaxOpenConns {
+		return nil, fmt.Errorf("DB_MAX_IDLE_CONNS (%d) must not exceed DB_MAX_OPEN_CONNS (%d)", config.DBMaxIdleConns, config.DBMaxOpenConns)
 	}
-	if err := tx.Exec(`DROP INDEX IF EXISTS idx_categories_name`).Error; err != nil {
-		tx.Rollback()
-		return err
+	if config.DBConnMaxLifetime, err = getEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute); err != nil {
+		return nil, err
 	}
-
-	// Get all categories
-	var categories []models.Category
-	if err := tx.Find(&categories).Error; err != nil {
-		tx.Rollback()
-		return err
+	if config.DBConnMaxIdleTime, err = getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute); err != nil {
+		return nil, err
 	}
-
-	// Process categories and handle duplicates
-	seen := make(map[string]*models.Category)
-	for i := range categories {
-		cat := &categories[i]
-		normalizedName := strings.TrimSpace(cat.Name)
-		lowerName := strings.ToLower(normalizedName)
-
-		if existing, exists := seen[lowerName]; exists {
-			// Update books to use the first category we saw
-			if err := tx.Model(&models.Book{}).Where("category_id = ?", cat.ID).Update("category_id", existing.ID).Error; err != nil {
-				tx.Rollback()
-				return err
-			}
-			// Mark for deletion by setting DeletedAt
-			if err := tx.Delete(cat).Error; err != nil {
-				tx.Rollback()
-				return err
-			}
-		} else {
-			// Update the name to be standardized
-			cat.Name = normalizedName
-			if err := tx.Save(cat).Error; err != nil {
-				tx.Rollback()
-				return err
-			}
-			seen[lowerName] = cat
-		}
-	}
-
-	// Create the case-insensitive unique index
-	if err := tx.Exec(`CREATE UNIQUE INDEX idx_categories_name_lower ON categories (LOWER(name)) WHERE deleted_at IS NULL`).Error; err != nil {
-		tx.Rollback()
-		return err
+	if config.SlowQueryThreshold, err = getEnvDurationOrZero("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if config.ReadTimeout, err = getEnvDuration("SERVER_READ_TIMEOUT", 15*time.Second); err != nil {
+		return nil, err
 	}
+	if config.WriteTimeout, err = getEnvDuration("SERVER_WRITE_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if config.IdleTimeout, err = getEnvDuration("SERVER_IDLE_TIMEOUT", 60*time.Second); err != nil {
+		return nil, err
+	}
+	if config.ShutdownTimeout, err = getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 20*time.Second); err != nil {
+		return nil, err
+	}
+	if config.CartTTL, err = getEnvDuration("CART_TTL", 7*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if config.CartReminderBefore, err = getEnvDuration("CART_REMINDER_BEFORE", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if config.CartReminderBefore >= config.CartTTL {
+		return nil, fmt.Errorf("CART_REMINDER_BEFORE (%s) must be shorter than CART_TTL (%s)", config.CartReminderBefore, config.CartTTL)
+	}
+	if config.SessionLifetime, err = getEnvDuration("SESSION_LIFETIME", 7*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if config.RememberMeLifetime, err = getEnvDuration("REMEMBER_ME_LIFETIME", 30*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if config.RememberMeLifetime < config.SessionLifetime {
+		return nil, fmt.Errorf("REMEMBER_ME_LIFETIME (%s) must not be shorter than SESSION_LIFETIME (%s)", config.RememberMeLifetime, config.SessionLifetime)
+	}
+	if config.OnlinePaymentWindow, err = getEnvDuration("ONLINE_PAYMENT_WINDOW", 30*time.Minute); err != nil {
+		return nil, err
+	}
+	if config.AccountDeletionGrace, err = getEnvDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour); err != nil {
+		return nil, err
+	}
+	config.BaseCurrency = strings.ToUpper(getEnvDefault("BASE_CURRENCY", "INR"))
+	config.SupportedCurrencies = strings.Split(getEnvDefault("SUPPORTED_CURRENCIES", config.BaseCurrency), ",")
+	config.ExchangeRateURL = os.Getenv("EXCHANGE_RATE_URL")
+	if config.ExchangeRateRefresh, err = getEnvDuration("EXCHANGE_RATE_REFRESH", 6*time.Hour); err != nil {
+		return nil, err
+	}
+	if config.CatalogCacheTTL, err = getEnvDurationOrZero("CATALOG_CACHE_TTL", time.Minute); err != nil {
+		return nil, err
+	}
+	config.CatalogCacheStore = getEnvDefault("CATALOG_CACHE_STORE", "memory")
+	config.MigrateOnStart = getEnvDefault("MIGRATE_ON_START", "true") != "false"
 
-	return tx.Commit().Error
+	return config, nil
 }
 
-// CleanupInvalidReferrals removes any referral records that reference non-existent coupons
-func CleanupInvalidReferrals() error {
-	// Check if referrals table exists
-	if !DB.Migrator().HasTable("referrals") {
-		return nil
+// getEnvDefault returns the environment variable or the fallback when it is unset
+func getEnvDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return fallback
+}
 
-	// Delete referrals with invalid coupon_id
-	result := DB.Exec(`
-		DELETE FROM referrals 
-		WHERE coupon_id NOT IN (SELECT id FROM coupons)
-	`)
-	if result.Error != nil {
-		log.Printf("Failed to cleanup invalid referrals: %v", result.Error)
-		return result.Error
+// getEnvDuration parses a duration such as "30s" or "1m" from the environment
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive duration like 30s", key, value)
 	}
+	return d, nil
+}
 
-	if result.RowsAffected > 0 {
-		log.Printf("Cleaned up %d invalid referral records", result.RowsAffected)
+// getEnvDurationOrZero is getEnvDuration for settings that zero turns off
+func getEnvDurationOrZero(key string, fallback time.Duration) (time.Duration, error) {
+	if os.Getenv(key) == "0" {
+		return 0, nil
 	}
-	return nil
+	return getEnvDuration(key, fallback)
+}
+
+// getEnvInt parses a positive integer from the environment
+func getEnvInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", key, value)
+	}
+	return n, nil
 }
 
-// MigrateDB migrates the database
+// MigrateDB applies pending versioned migrations, starting with the baseline schema
 func MigrateDB() error {
-	// Clean up invalid referrals before migration
-	if err := CleanupInvalidReferrals(); err != nil {
-		log.Printf("Warning: Failed to cleanup invalid referrals: %v", err)
-		// Continue with migration even if cleanup fails
-	}
-
-	// AutoMigrate will create tables, missing foreign keys, constraints, columns and indexes
-	if err := DB.AutoMigrate(
-		&models.User{},
-		&models.Admin{},
-		&models.Book{},
-		&models.Category{},
-		&models.Cart{},
-		&models.Address{},
-		&models.Order{},
-		&models.OrderItem{},
-		&models.Wishlist{},
-		&models.Coupon{},           // Migrate Coupon first
-		&models.UserReferralCode{}, // New referral system
-		&models.ReferralUsage{},    // New referral usage tracking
-		&models.Referral{},         // Legacy - can be removed later
-		&models.ReferralSignup{},
-		&models.ProductOffer{},
-		&models.CategoryOffer{},
-		&models.Wallet{},
-		&models.WalletTransaction{},
-		&models.WalletTopupOrder{},
-		&models.BlacklistedToken{},
-	); err != nil {
-		log.Printf("Failed to migrate database: %v", err)
+	applied, err := migrations.Up(DB)
+	for _, result := range applied {
+		log.Printf("Applied migration %s", filepath.Base(result.Source.Path))
+	}
+	if err != nil {
+		log.Printf("Failed to apply migrations: %v", err)
 		return err
 	}
-
 	return nil
 }
 
-// InitDB initializes the database connection
-func InitDB() {
+// ConnectDB opens the database connection without migrating the schema
+func ConnectDB() (*Config, error) {
 	config, err := LoadConfig()
 	if err != nil {
-		log.Printf("Failed to load config: %v", err)
-		panic(fmt.Sprintf("Failed to load config: %v", err))
+		return nil, fmt.Errorf("failed to load config: %v", err)
 	}
 
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Shanghai",
@@ -189,93 +241,47 @@ func InitDB() {
 
 	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
-	}
-
-	// Run category migration first
-	if err := MigrateCategoryNames(DB); err != nil {
-		log.Fatal("Failed to migrate category names:", err)
-	}
-
-	// Then run the regular migrations
-	if err := MigrateDB(); err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
 
-	// First, check if the username column exists
-	var columnExists bool
-	err = DB.Raw(`
-		SELECT EXISTS (
-			SELECT 1 
-			FROM information_schema.columns 
-			WHERE table_name = 'users' 
-			AND column_name = 'username'
-		)
-	`).Scan(&columnExists).Error
-	if err != nil {
-		log.Printf("Failed to check column existence: %v", err)
-		panic(fmt.Sprintf("Failed to check column existence: %v", err))
-	}
-
-	if !columnExists {
-		// Add username column as nullable first
-		err = DB.Exec(`ALTER TABLE users ADD COLUMN username text`).Error
-		if err != nil {
-			log.Printf("Failed to add username column: %v", err)
-			panic(fmt.Sprintf("Failed to add username column: %v", err))
-		}
-
-		// Update existing users with default usernames
-		err = DB.Exec(`
-			UPDATE users 
-			SET username = 'user_' || id::text 
-			WHERE username IS NULL
-		`).Error
-		if err != nil {
-			log.Printf("Failed to update existing users: %v", err)
-			panic(fmt.Sprintf("Failed to update existing users: %v", err))
-		}
-
-		// Make username column NOT NULL
-		err = DB.Exec(`ALTER TABLE users ALTER COLUMN username SET NOT NULL`).Error
-		if err != nil {
-			log.Printf("Failed to make username NOT NULL: %v", err)
-			panic(fmt.Sprintf("Failed to make username NOT NULL: %v", err))
-		}
-	}
-
-	// Update GoogleID column to be nullable
-	err = DB.Exec(`
-		ALTER TABLE users 
-		ALTER COLUMN google_id DROP NOT NULL,
-		ALTER COLUMN google_id SET DEFAULT NULL
-	`).Error
+	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Printf("Failed to update google_id column: %v", err)
-		panic(fmt.Sprintf("Failed to update google_id column: %v", err))
+		return nil, fmt.Errorf("failed to configure connection pool: %v", err)
 	}
+	sqlDB.SetMaxOpenConns(config.DBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(config.DBMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(config.DBConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(config.DBConnMaxIdleTime)
+	log.Printf("Database pool: max %d open, %d idle, lifetime %s, idle time %s",
+		config.DBMaxOpenConns, config.DBMaxIdleConns, config.DBConnMaxLifetime, config.DBConnMaxIdleTime)
+	return config, nil
+}
 
-	// Drop existing index if it exists
-	err = DB.Exec(`DROP INDEX IF EXISTS idx_coupons_code_lower`).Error
+// InitDB initializes the database connection and, unless MIGRATE_ON_START is false, brings the
+// schema up to date
+func InitDB() {
+	config, err := ConnectDB()
 	if err != nil {
-		log.Printf("Failed to drop existing index: %v", err)
-		panic(fmt.Sprintf("Failed to drop existing index: %v", err))
+		log.Fatal(err)
 	}
 
-	// Update existing coupon codes to uppercase
-	err = DB.Exec(`UPDATE coupons SET code = UPPER(code) WHERE code != UPPER(code)`).Error
-	if err != nil {
-		log.Printf("Failed to update existing coupon codes: %v", err)
-		panic(fmt.Sprintf("Failed to update existing coupon codes: %v", err))
+	if !config.MigrateOnStart {
+		log.Printf("MIGRATE_ON_START is false, skipping database migrations")
+		return
 	}
+	if err := MigrateDB(); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+}
 
-	// Create case-insensitive unique index on coupon code
-	err = DB.Exec(`
-		CREATE UNIQUE INDEX idx_coupons_code_lower 
-		ON coupons (LOWER(code))
-	`).Error
+// CloseDB closes the underlying database connection pool
+func CloseDB() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Printf("Failed to create case-insensitive index: %v", err)
-		panic(fmt.Sprintf("Failed to create case-insensitive index: %v", err))
+		return err
 	}
+	return sqlDB.Close()
 }
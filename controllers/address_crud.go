@@ -53,7 +53,7 @@ func AddAddress(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
 		return
 	}
 
@@ -63,15 +63,20 @@ func AddAddress(c *gin.Context) {
 	var req AddAddressRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request format for user ID: %d: %v", userModel.ID, err)
-		utils.BadRequest(c, "Invalid request format", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
 		return
 	}
 
 	// Business validation
 	errs := utils.ValidateAddressFields(req.Line1, req.Line2, req.City, req.State, req.Country, req.PostalCode, &req.IsDefault)
+	if len(errs) == 0 {
+		if pincodeErr := utils.ValidateDeliveryPincode(strings.TrimSpace(req.PostalCode)); pincodeErr != nil {
+			errs = append(errs, *pincodeErr)
+		}
+	}
 	if len(errs) > 0 {
 		utils.LogError("Address validation failed for user ID: %d: %v", userModel.ID, errs)
-		utils.BadRequest(c, "Validation failed", gin.H{"fields": errs})
+		utils.Fail(c, utils.CodeInvalidRequest, "Validation failed", gin.H{"fields": errs})
 		return
 	}
 
@@ -80,6 +85,17 @@ func AddAddress(c *gin.Context) {
 	req.State = utils.Title(strings.ToLower(strings.TrimSpace(req.State)))
 	req.Country = utils.Title(strings.ToLower(strings.TrimSpace(req.Country)))
 
+	// The first address always becomes the default so checkout has an address to price delivery for
+	var existingCount int64
+	if err := config.DB.Model(&models.Address{}).Where("user_id = ?", userModel.ID).Count(&existingCount).Error; err != nil {
+		utils.LogError("Failed to count addresses for user ID: %d: %v", userModel.ID, err)
+		utils.InternalServerError(c, "Failed to add address", err.Error())
+		return
+	}
+	if existingCount == 0 {
+		req.IsDefault = true
+	}
+
 	// Unset previous default if needed
 	if req.IsDefault {
 		if err := config.DB.Model(&models.Address{}).Where("user_id = ?", userModel.ID).Update("is_default", false).Error; err != nil {
@@ -96,7 +112,7 @@ func AddAddress(c *gin.Context) {
 		City:       req.City,
 		State:      req.State,
 		Country:    req.Country,
-		PostalCode: req.PostalCode,
+		PostalCode: strings.TrimSpace(req.PostalCode),
 		IsDefault:  req.IsDefault,
 	}
 
@@ -151,7 +167,7 @@ func EditAddress(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
 		return
 	}
 
@@ -162,14 +178,14 @@ func EditAddress(c *gin.Context) {
 	var req EditAddressRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request format for user ID: %d: %v", userModel.ID, err)
-		utils.BadRequest(c, "Invalid request format", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
 		return
 	}
 
 	var address models.Address
 	if err := config.DB.Where("id = ? AND user_id = ?", addressID, userModel.ID).First(&address).Error; err != nil {
 		utils.LogError("Address not found for user ID: %d, address ID: %s", userModel.ID, addressID)
-		utils.NotFound(c, "Address not found")
+		utils.Fail(c, utils.CodeAddressNotFound, "Address not found", nil)
 		return
 	}
 
@@ -199,9 +215,14 @@ func EditAddress(c *gin.Context) {
 		postalCode = address.PostalCode
 	}
 	errs := utils.ValidateAddressFields(line1, line2, city, state, country, postalCode, nil)
+	if len(errs) == 0 && req.PostalCode != "" {
+		if pincodeErr := utils.ValidateDeliveryPincode(strings.TrimSpace(req.PostalCode)); pincodeErr != nil {
+			errs = append(errs, *pincodeErr)
+		}
+	}
 	if len(errs) > 0 {
 		utils.LogError("Address validation failed for user ID: %d: %v", userModel.ID, errs)
-		utils.BadRequest(c, "Validation failed", gin.H{"fields": errs})
+		utils.Fail(c, utils.CodeInvalidRequest, "Validation failed", gin.H{"fields": errs})
 		return
 	}
 
@@ -233,7 +254,7 @@ func EditAddress(c *gin.Context) {
 		address.Country = req.Country
 	}
 	if req.PostalCode != "" {
-		address.PostalCode = req.PostalCode
+		address.PostalCode = strings.TrimSpace(req.PostalCode)
 	}
 
 	if err := config.DB.Save(&address).Error; err != nil {
@@ -278,7 +299,7 @@ func DeleteAddress(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
 		return
 	}
 	userModel := user.(models.User)
@@ -15,7 +15,7 @@ func SetDefaultAddress(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
 		return
 	}
 	userModel := user.(models.User)
@@ -26,7 +26,7 @@ func SetDefaultAddress(c *gin.Context) {
 	var address models.Address
 	if err := config.DB.Where("id = ? AND user_id = ?", addressID, userModel.ID).First(&address).Error; err != nil {
 		utils.LogError("Address not found for user ID: %d, address ID: %s", userModel.ID, addressID)
-		utils.NotFound(c, "Address not found")
+		utils.Fail(c, utils.CodeAddressNotFound, "Address not found", nil)
 		return
 	}
 
@@ -97,7 +97,7 @@ func GetAddresses(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
 		return
 	}
 	userModel := user.(models.User)
@@ -118,14 +118,91 @@ func GetAddresses(c *gin.Context) {
 	if err := config.DB.Table("addresses").
 		Select("id, user_id, line1, line2, city, state, country, postal_code, is_default").
 		Where("user_id = ?", userModel.ID).
+		Order("is_default DESC, updated_at DESC").
 		Find(&addresses).Error; err != nil {
 		utils.LogError("Failed to fetch addresses for user ID: %d: %v", userModel.ID, err)
 		utils.InternalServerError(c, "Failed to fetch addresses", err.Error())
 		return
 	}
 
+	// Flag addresses whose pincode is no longer serviceable
+	result := make([]gin.H, len(addresses))
+	formatter := utils.NewResponseFormatter(c)
+	for i, addr := range addresses {
+		result[i] = gin.H{
+			"id":          addr.ID,
+			"user_id":     addr.UserID,
+			"line1":       addr.Line1,
+			"line2":       addr.Line2,
+			"city":        addr.City,
+			"state":       addr.State,
+			"country":     addr.Country,
+			"postal_code": addr.PostalCode,
+			"is_default":  addr.IsDefault,
+		}
+		for k, v := range addressDeliveryInfo(formatter, addr.PostalCode) {
+			result[i][k] = v
+		}
+	}
+
 	utils.LogInfo("Successfully retrieved %d addresses for user ID: %d", len(addresses), userModel.ID)
 	utils.Success(c, "Addresses retrieved successfully", gin.H{
-		"addresses": addresses,
+		"addresses": result,
 	})
 }
+
+// GetAddress returns a single address of the authenticated user with its delivery details
+func GetAddress(c *gin.Context) {
+	utils.LogInfo("GetAddress called")
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
+		return
+	}
+	userModel := user.(models.User)
+	addressID := c.Param("id")
+
+	var address models.Address
+	if err := config.DB.Where("id = ? AND user_id = ?", addressID, userModel.ID).First(&address).Error; err != nil {
+		utils.LogError("Address not found for user ID: %d, address ID: %s", userModel.ID, addressID)
+		utils.Fail(c, utils.CodeAddressNotFound, "Address not found", nil)
+		return
+	}
+
+	response := gin.H{
+		"id":          address.ID,
+		"user_id":     address.UserID,
+		"line1":       address.Line1,
+		"line2":       address.Line2,
+		"city":        address.City,
+		"state":       address.State,
+		"country":     address.Country,
+		"postal_code": address.PostalCode,
+		"is_default":  address.IsDefault,
+	}
+	formatter := utils.NewResponseFormatter(c)
+	for k, v := range addressDeliveryInfo(formatter, address.PostalCode) {
+		response[k] = v
+	}
+
+	utils.Success(c, "Address retrieved successfully", gin.H{
+		"address": response,
+	})
+}
+
+// addressDeliveryInfo reports whether an address can be delivered to and its base charge
+func addressDeliveryInfo(f utils.ResponseFormatter, pincode string) gin.H {
+	rule, err := utils.GetDeliveryChargeByPincode(pincode)
+	if err != nil {
+		return gin.H{
+			"deliverable":     false,
+			"delivery_charge": nil,
+		}
+	}
+	return gin.H{
+		"deliverable":     true,
+		"delivery_charge": f.Money(rule.Charge),
+	}
+}
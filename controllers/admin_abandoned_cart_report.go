@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Admin: Abandoned cart report. Summarizes the carts detected as abandoned over a date range,
+// the reminders sent for them and the orders that recovered them, and lists the carts.
+func GetAbandonedCartReport(c *gin.Context) {
+	utils.LogInfo("GetAbandonedCartReport called")
+
+	// Default to the last 30 days including today
+	now := time.Now()
+	endDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Add(24 * time.Hour)
+	startDate := endDate.AddDate(0, 0, -30)
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			utils.LogError("Invalid start date format: %v", err)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid start date", "Start date must be in YYYY-MM-DD format")
+			return
+		}
+		startDate = parsed
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			utils.LogError("Invalid end date format: %v", err)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid end date", "End date must be in YYYY-MM-DD format")
+			return
+		}
+		// Include the entire end date
+		endDate = parsed.Add(24 * time.Hour)
+	}
+	if !endDate.After(startDate) {
+		utils.LogError("Invalid date range: %s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+		utils.Fail(c, utils.CodeInvalidDate, "Invalid date range", "End date must be after start date")
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.AbandonedCartStatusOpen, models.AbandonedCartStatusRecovered, models.AbandonedCartStatusClosed:
+	default:
+		utils.LogError("Invalid abandoned cart status: %s", status)
+		utils.BadRequest(c, "Invalid status", "status must be open, recovered or closed")
+		return
+	}
+
+	inRange := config.DB.Model(&models.AbandonedCart{}).Where("created_at >= ? AND created_at < ?", startDate, endDate)
+
+	var summary struct {
+		Detected           int64
+		AbandonedValue     float64
+		Reminded           int64
+		RemindersSent      int64
+		Recovered          int64
+		RecoveredValue     float64
+		RecoveredAfterMail int64
+		Open               int64
+		Closed             int64
+	}
+	if err := inRange.Select(
+		"COUNT(*) AS detected, "+
+			"COALESCE(SUM(cart_value), 0) AS abandoned_value, "+
+			"COUNT(*) FILTER (WHERE reminders_sent > 0) AS reminded, "+
+			"COALESCE(SUM(reminders_sent), 0) AS reminders_sent, "+
+			"COUNT(*) FILTER (WHERE status = ?) AS recovered, "+
+			"COALESCE(SUM(recovered_value) FILTER (WHERE status = ?), 0) AS recovered_value, "+
+			"COUNT(*) FILTER (WHERE status = ? AND reminders_sent > 0) AS recovered_after_mail, "+
+			"COUNT(*) FILTER (WHERE status = ?) AS open, "+
+			"COUNT(*) FILTER (WHERE status = ?) AS closed",
+		models.AbandonedCartStatusRecovered, models.AbandonedCartStatusRecovered, models.AbandonedCartStatusRecovered,
+		models.AbandonedCartStatusOpen, models.AbandonedCartStatusClosed,
+	).Scan(&summary).Error; err != nil {
+		utils.LogError("Failed to summarize abandoned carts: %v", err)
+		utils.InternalServerError(c, "Failed to generate abandoned cart report", err.Error())
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+	listQuery := config.DB.Model(&models.AbandonedCart{}).Where("created_at >= ? AND created_at < ?", startDate, endDate)
+	if status != "" {
+		listQuery = listQuery.Where("status = ?", status)
+	}
+	var total int64
+	if err := listQuery.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count abandoned carts: %v", err)
+		utils.InternalServerError(c, "Failed to generate abandoned cart report", err.Error())
+		return
+	}
+	var carts []models.AbandonedCart
+	if err := listQuery.Preload("User").Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).Limit(limit).Find(&carts).Error; err != nil {
+		utils.LogError("Failed to fetch abandoned carts: %v", err)
+		utils.InternalServerError(c, "Failed to generate abandoned cart report", err.Error())
+		return
+	}
+
+	list := make([]gin.H, len(carts))
+	formatter := utils.NewResponseFormatter(c)
+	for i, cart := range carts {
+		entry := gin.H{
+			"id":                 cart.ID,
+			"user_id":            cart.UserID,
+			"email":              cart.User.Email,
+			"status":             cart.Status,
+			"item_count":         cart.ItemCount,
+			"quantity":           cart.Quantity,
+			"cart_value":         formatter.Money(cart.CartValue),
+			"last_activity_at":   formatter.Timestamp(cart.LastActivityAt),
+			"detected_at":        formatter.Timestamp(cart.CreatedAt),
+			"reminders_sent":     cart.RemindersSent,
+			"recovered_order_id": cart.RecoveredOrderID,
+			"recovered_value":    formatter.Money(cart.RecoveredValue),
+		}
+		if cart.ResolvedAt != nil {
+			entry["resolved_at"] = formatter.Timestamp(*cart.ResolvedAt)
+		}
+		list[i] = entry
+	}
+
+	recoveryRate := 0.0
+	if summary.Detected > 0 {
+		recoveryRate = float64(summary.Recovered) / float64(summary.Detected) * 100
+	}
+
+	utils.LogInfo("Generated abandoned cart report: %d detected, %d recovered", summary.Detected, summary.Recovered)
+	utils.SuccessWithPagination(c, "Abandoned cart report generated successfully", gin.H{
+		"start_date": startDate.Format("2006-01-02"),
+		"end_date":   endDate.Add(-24 * time.Hour).Format("2006-01-02"),
+		"summary": gin.H{
+			"detected":             summary.Detected,
+			"abandoned_value":      formatter.Money(summary.AbandonedValue),
+			"reminded":             summary.Reminded,
+			"reminders_sent":       summary.RemindersSent,
+			"recovered":            summary.Recovered,
+			"recovered_after_mail": summary.RecoveredAfterMail,
+			"recovered_value":      formatter.Money(summary.RecoveredValue),
+			"recovery_rate":        formatter.Money(recoveryRate),
+			"open":                 summary.Open,
+			"closed":               summary.Closed,
+		},
+		"carts": list,
+	}, total, page, limit)
+}
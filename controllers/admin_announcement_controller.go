@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AnnouncementRequest represents an admin broadcast. A new arrival needs a category, taken
+// from the book when only the book is given; a price drop needs the book. Subject and message
+// default to a text built from the book or category.
+type AnnouncementRequest struct {
+	Type       string `json:"type" binding:"required,oneof=new_arrival price_drop"`
+	BookID     *uint  `json:"book_id"`
+	CategoryID *uint  `json:"category_id"`
+	Subject    string `json:"subject" binding:"max=200"`
+	Message    string `json:"message" binding:"max=5000"`
+	DryRun     bool   `json:"dry_run"`
+}
+
+// AdminSendAnnouncement emails a new arrival or price drop announcement to the users who
+// bought or wishlisted related books. The emails go out in the background; the announcement
+// records how many were sent and skipped.
+func AdminSendAnnouncement(c *gin.Context) {
+	utils.LogInfo("AdminSendAnnouncement called")
+
+	admin, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	adminModel, ok := admin.(models.Admin)
+	if !ok {
+		utils.LogError("Invalid admin type in context")
+		utils.InternalServerError(c, "Invalid admin type", nil)
+		return
+	}
+
+	var req AnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid input: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err.Error())
+		return
+	}
+
+	announcement := models.Announcement{
+		AdminID:    adminModel.ID,
+		Type:       req.Type,
+		BookID:     req.BookID,
+		CategoryID: req.CategoryID,
+		Status:     models.AnnouncementStatusSending,
+	}
+
+	var book *models.Book
+	if req.BookID != nil {
+		book = &models.Book{}
+		if err := config.DB.First(book, *req.BookID).Error; err != nil {
+			utils.LogError("Book not found: %d", *req.BookID)
+			utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+			return
+		}
+		if announcement.CategoryID == nil && req.Type == models.AnnouncementTypeNewArrival {
+			announcement.CategoryID = &book.CategoryID
+		}
+	}
+	if req.Type == models.AnnouncementTypePriceDrop && book == nil {
+		utils.BadRequest(c, "Invalid announcement", "A price drop needs book_id")
+		return
+	}
+	var category models.Category
+	if req.Type == models.AnnouncementTypeNewArrival {
+		if announcement.CategoryID == nil {
+			utils.BadRequest(c, "Invalid announcement", "A new arrival needs category_id or book_id")
+			return
+		}
+		if err := config.DB.First(&category, *announcement.CategoryID).Error; err != nil {
+			utils.LogError("Category not found: %d", *announcement.CategoryID)
+			utils.Fail(c, utils.CodeCategoryNotFound, "Category not found", nil)
+			return
+		}
+	}
+
+	announcement.Subject, announcement.Message = announcementText(req, book, category)
+
+	users, err := utils.AnnouncementAudience(announcement)
+	if err != nil {
+		utils.LogError("Failed to find announcement audience: %v", err)
+		utils.InternalServerError(c, "Failed to find announcement audience", err.Error())
+		return
+	}
+	announcement.Recipients = len(users)
+
+	if req.DryRun {
+		utils.LogInfo("Announcement dry run: %d recipients", len(users))
+		utils.Success(c, "Announcement preview", gin.H{
+			"announcement": announcement,
+			"dry_run":      true,
+		})
+		return
+	}
+
+	if err := config.DB.Create(&announcement).Error; err != nil {
+		utils.LogError("Failed to save announcement: %v", err)
+		utils.InternalServerError(c, "Failed to save announcement", err.Error())
+		return
+	}
+
+	sending := announcement
+	go func() {
+		if err := utils.SendAnnouncement(&sending, users); err != nil {
+			utils.LogError("Failed to record outcome of announcement %d: %v", sending.ID, err)
+			return
+		}
+		utils.LogInfo("Announcement %d sent to %d of %d users", sending.ID, sending.Sent, sending.Recipients)
+	}()
+
+	utils.LogInfo("Announcement %d queued for %d users by admin %s", announcement.ID, len(users), adminModel.Email)
+	utils.Success(c, "Announcement is being sent", gin.H{
+		"announcement": announcement,
+	})
+}
+
+// AdminGetAnnouncements lists sent announcements, newest first, with their delivery counts
+func AdminGetAnnouncements(c *gin.Context) {
+	utils.LogInfo("AdminGetAnnouncements called")
+
+	page, limit := utils.GetPaginationParams(c)
+	query := config.DB.Model(&models.Announcement{})
+	if announcementType := c.Query("type"); announcementType != "" {
+		query = query.Where("type = ?", announcementType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count announcements: %v", err)
+		utils.InternalServerError(c, "Failed to fetch announcements", err.Error())
+		return
+	}
+
+	var announcements []models.Announcement
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).
+		Find(&announcements).Error; err != nil {
+		utils.LogError("Failed to fetch announcements: %v", err)
+		utils.InternalServerError(c, "Failed to fetch announcements", err.Error())
+		return
+	}
+
+	utils.LogInfo("Retrieved %d announcements", len(announcements))
+	utils.SuccessWithPagination(c, "Announcements retrieved successfully", gin.H{
+		"announcements": announcements,
+	}, total, page, limit)
+}
+
+// announcementText returns the subject and HTML message of the announcement, using the
+// admin's text when given
+func announcementText(req AnnouncementRequest, book *models.Book, category models.Category) (string, string) {
+	subject := strings.TrimSpace(req.Subject)
+	message := strings.TrimSpace(req.Message)
+	link := ""
+	if book != nil {
+		link = fmt.Sprintf("<p><a href=\"%s/books/%d\">See the book</a></p>", os.Getenv("FRONTEND_URL"), book.ID)
+	}
+
+	if subject == "" {
+		switch {
+		case req.Type == models.AnnouncementTypePriceDrop:
+			subject = fmt.Sprintf("Price drop: %s", book.Name)
+		case book != nil:
+			subject = fmt.Sprintf("New in %s: %s", category.Name, book.Name)
+		default:
+			subject = fmt.Sprintf("New arrivals in %s", category.Name)
+		}
+	}
+	if message != "" {
+		return subject, fmt.Sprintf("<p>%s</p>%s", html.EscapeString(message), link)
+	}
+
+	switch {
+	case req.Type == models.AnnouncementTypePriceDrop:
+		message = fmt.Sprintf("<p><strong>%s</strong> by %s is now %s on ReadSphere.</p>",
+			html.EscapeString(book.Name), html.EscapeString(book.Author), utils.FormatMoney(book.Price, utils.BaseCurrency()))
+	case book != nil:
+		message = fmt.Sprintf("<p><strong>%s</strong> by %s has just arrived in %s on ReadSphere.</p>",
+			html.EscapeString(book.Name), html.EscapeString(book.Author), html.EscapeString(category.Name))
+	default:
+		message = fmt.Sprintf("<p>New books have just arrived in %s on ReadSphere.</p>", html.EscapeString(category.Name))
+	}
+	return subject, message + link
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// apiAnalyticsWindows are the selectable windows of the API traffic summary
+var apiAnalyticsWindows = map[string]time.Duration{
+	"15m": 15 * time.Minute,
+	"1h":  time.Hour,
+	"6h":  6 * time.Hour,
+	"24h": 24 * time.Hour,
+}
+
+// GetAPIAnalytics summarizes API traffic per route and per consumer over a recent window
+func GetAPIAnalytics(c *gin.Context) {
+	utils.LogInfo("GetAPIAnalytics called")
+
+	windowParam := c.DefaultQuery("window", "1h")
+	window, ok := apiAnalyticsWindows[windowParam]
+	if !ok {
+		utils.LogError("Invalid analytics window: %s", windowParam)
+		utils.BadRequest(c, "Invalid window. Must be one of: 15m, 1h, 6h, 24h", nil)
+		return
+	}
+
+	top, err := strconv.Atoi(c.DefaultQuery("top", "10"))
+	if err != nil || top < 1 || top > 100 {
+		utils.LogError("Invalid top consumers limit: %s", c.Query("top"))
+		utils.BadRequest(c, "top must be between 1 and 100", nil)
+		return
+	}
+
+	summary := utils.SummarizeTraffic(window, top)
+	utils.LogInfo("API analytics for window %s: %d requests, %d errors", windowParam, summary.Requests, summary.Errors)
+	utils.Success(c, "API analytics retrieved successfully", gin.H{
+		"window":    windowParam,
+		"analytics": summary,
+	})
+}
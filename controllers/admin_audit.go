@@ -0,0 +1,357 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// auditMaxPayload bounds the request and response bodies kept in an audit entry
+const auditMaxPayload = 16 << 10
+
+// auditSnapshotTables maps admin route groups to the table whose row :id names, so audit
+// entries can hold the row before and after the change
+var auditSnapshotTables = map[string]string{
+	"books":         "books",
+	"orders":        "orders",
+	"coupons":       "coupons",
+	"users":         "users",
+	"categories":    "categories",
+	"genres":        "genres",
+	"authors":       "authors",
+	"tags":          "tags",
+	"bundles":       "bundles",
+	"reviews":       "reviews",
+	"announcements": "announcements",
+	"gift-cards":    "gift_cards",
+	"home-sections": "home_sections",
+	"banners":       "banners",
+	"pages":         "pages",
+	"support":       "support_tickets",
+	"disputes":      "order_disputes",
+}
+
+// auditOfferSnapshotTables maps the offer route groups to their tables, since the admin route
+// group of both is offers
+var auditOfferSnapshotTables = map[string]string{
+	"/offers/products/":   "product_offers",
+	"/offers/categories/": "category_offers",
+}
+
+// auditSnapshotTable returns the table whose row :id names for a request to the endpoint in
+// the admin route group, or "" when there is none
+func auditSnapshotTable(entityType, endpoint string) string {
+	if entityType == "offers" {
+		for prefix, table := range auditOfferSnapshotTables {
+			if strings.Contains(endpoint, prefix) {
+				return table
+			}
+		}
+	}
+	return auditSnapshotTables[entityType]
+}
+
+// auditUnloggedBodies are the route groups whose request bodies hold credentials
+var auditUnloggedBodies = map[string]bool{"2fa": true}
+
+// auditSensitiveKeys are redacted from request bodies and snapshots wherever they appear in a key
+var auditSensitiveKeys = []string{"password", "secret", "otp", "token"}
+
+// AdminAuditMiddleware records every successful admin write in the audit log. Register it
+// after the admin authentication middleware so the acting admin is known. Failures to record
+// are logged and never fail the request.
+func AdminAuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		entityType, entityID := auditTarget(c)
+		var request string
+		if !auditUnloggedBodies[entityType] && strings.HasPrefix(c.ContentType(), "application/json") && c.Request.Body != nil {
+			body, err := io.ReadAll(c.Request.Body)
+			if err == nil {
+				c.Request.Body = io.NopCloser(bytes.NewReader(body))
+				request = redactAuditJSON(body)
+			}
+		}
+		table := auditSnapshotTable(entityType, c.FullPath())
+		var before string
+		if table != "" && entityID != "" {
+			before = auditSnapshot(table, entityID)
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := c.Writer.Status()
+		if status >= http.StatusBadRequest {
+			return
+		}
+		entry := models.AdminAuditLog{
+			Method:     method,
+			Endpoint:   c.FullPath(),
+			Path:       c.Request.URL.Path,
+			EntityType: entityType,
+			EntityID:   entityID,
+			Status:     status,
+			Request:    request,
+			Before:     before,
+			IPAddress:  c.ClientIP(),
+			RequestID:  c.GetString("RequestID"),
+		}
+		if adminVal, exists := c.Get("admin"); exists {
+			if admin, ok := adminVal.(models.Admin); ok {
+				entry.AdminID = admin.ID
+				entry.AdminEmail = admin.Email
+			}
+		}
+		if table != "" && entityID != "" {
+			entry.After = auditSnapshot(table, entityID)
+		} else {
+			// Without a row to look at, such as on create, keep what the admin was sent back
+			entry.After = writer.data()
+		}
+
+		if err := config.DB.Create(&entry).Error; err != nil {
+			utils.LogError("Failed to record admin audit entry for %s %s: %v", method, entry.Path, err)
+			return
+		}
+		utils.LogDebug("Recorded admin audit entry %d: %s %s by admin %d", entry.ID, method, entry.Path, entry.AdminID)
+	}
+}
+
+// auditTarget names the entity a request acts on: the admin route group and the :id
+// parameter, or the first parameter when there is no :id
+func auditTarget(c *gin.Context) (string, string) {
+	path := c.FullPath()
+	if i := strings.Index(path, "/admin/"); i >= 0 {
+		path = path[i+len("/admin/"):]
+	}
+	entityType := strings.SplitN(path, "/", 2)[0]
+
+	entityID := c.Param("id")
+	if entityID == "" && len(c.Params) > 0 {
+		entityID = c.Params[0].Value
+	}
+	return entityType, entityID
+}
+
+// auditSnapshot returns the row of table with the ID as JSON, soft-deleted or not, or "" when
+// there is none
+func auditSnapshot(table, id string) string {
+	row := map[string]interface{}{}
+	if err := config.DB.Table(table).Where("id = ?", id).Take(&row).Error; err != nil {
+		return ""
+	}
+	redactAuditValue(row)
+	snapshot, err := json.Marshal(row)
+	if err != nil {
+		utils.LogError("Failed to encode audit snapshot of %s %s: %v", table, id, err)
+		return ""
+	}
+	return string(snapshot)
+}
+
+// redactAuditJSON returns the JSON body with its secrets redacted, or "" when it is not JSON
+// or too large to keep
+func redactAuditJSON(body []byte) string {
+	if len(body) == 0 || len(body) > auditMaxPayload {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return ""
+	}
+	redactAuditValue(value)
+	redacted, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(redacted)
+}
+
+// redactAuditValue replaces the values of sensitive keys in decoded JSON, at any depth
+func redactAuditValue(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			lower := strings.ToLower(key)
+			redacted := false
+			for _, sensitive := range auditSensitiveKeys {
+				if strings.Contains(lower, sensitive) {
+					v[key] = "[redacted]"
+					redacted = true
+					break
+				}
+			}
+			if !redacted {
+				redactAuditValue(item)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			redactAuditValue(item)
+		}
+	}
+}
+
+// auditResponseWriter keeps a copy of the start of the response while it is sent
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.body.Len() <= auditMaxPayload {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	if w.body.Len() <= auditMaxPayload {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// data returns the data of the JSON response, redacted, or "" when there is none to keep
+func (w *auditResponseWriter) data() string {
+	if w.body.Len() > auditMaxPayload {
+		return ""
+	}
+	var response struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.body.Bytes(), &response); err != nil || len(response.Data) == 0 {
+		return ""
+	}
+	return redactAuditJSON(response.Data)
+}
+
+// formatAdminAuditLog converts an audit entry into its API representation, with the request
+// and snapshots decoded
+func formatAdminAuditLog(f utils.ResponseFormatter, entry models.AdminAuditLog) gin.H {
+	decode := func(value string) interface{} {
+		if value == "" {
+			return nil
+		}
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+			utils.LogError("Failed to decode admin audit entry %d: %v", entry.ID, err)
+			return nil
+		}
+		return decoded
+	}
+	return gin.H{
+		"id":          entry.ID,
+		"admin_id":    entry.AdminID,
+		"admin_email": entry.AdminEmail,
+		"method":      entry.Method,
+		"endpoint":    entry.Endpoint,
+		"path":        entry.Path,
+		"entity_type": entry.EntityType,
+		"entity_id":   entry.EntityID,
+		"status":      entry.Status,
+		"request":     decode(entry.Request),
+		"before":      decode(entry.Before),
+		"after":       decode(entry.After),
+		"ip_address":  entry.IPAddress,
+		"request_id":  entry.RequestID,
+		"created_at":  f.Timestamp(entry.CreatedAt),
+	}
+}
+
+// AdminGetAuditLogs lists admin write actions, newest first, filtered by admin, entity,
+// method, endpoint, date range or free text
+func AdminGetAuditLogs(c *gin.Context) {
+	utils.LogInfo("AdminGetAuditLogs called")
+
+	page, limit := utils.GetPaginationParams(c)
+	query := config.DB.Model(&models.AdminAuditLog{})
+
+	if adminID := c.Query("admin_id"); adminID != "" {
+		query = query.Where("admin_id = ?", adminID)
+	}
+	if entityType := c.Query("entity_type"); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if method := c.Query("method"); method != "" {
+		query = query.Where("method = ?", strings.ToUpper(method))
+	}
+	if endpoint := c.Query("endpoint"); endpoint != "" {
+		query = query.Where("endpoint ILIKE ?", "%"+endpoint+"%")
+	}
+	if search := c.Query("search"); search != "" {
+		like := "%" + search + "%"
+		query = query.Where("path ILIKE ? OR admin_email ILIKE ? OR request ILIKE ?", like, like, like)
+	}
+	if from := c.Query("from"); from != "" {
+		fromDate, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			utils.LogError("Invalid from date: %v", err)
+			utils.BadRequest(c, "Invalid from date", "Date must be in YYYY-MM-DD format")
+			return
+		}
+		query = query.Where("created_at >= ?", fromDate)
+	}
+	if to := c.Query("to"); to != "" {
+		toDate, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			utils.LogError("Invalid to date: %v", err)
+			utils.BadRequest(c, "Invalid to date", "Date must be in YYYY-MM-DD format")
+			return
+		}
+		query = query.Where("created_at < ?", toDate.AddDate(0, 0, 1))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count admin audit entries: %v", err)
+		utils.InternalServerError(c, "Failed to fetch audit log", err.Error())
+		return
+	}
+
+	var entries []models.AdminAuditLog
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&entries).Error; err != nil {
+		utils.LogError("Failed to fetch admin audit entries: %v", err)
+		utils.InternalServerError(c, "Failed to fetch audit log", err.Error())
+		return
+	}
+
+	logs := make([]gin.H, len(entries))
+	formatter := utils.NewResponseFormatter(c)
+	for i, entry := range entries {
+		logs[i] = formatAdminAuditLog(formatter, entry)
+	}
+
+	utils.LogInfo("Retrieved %d admin audit entries", len(logs))
+	utils.SuccessWithPagination(c, "Audit log retrieved successfully", gin.H{
+		"logs": logs,
+		"filters": gin.H{
+			"admin_id":    c.Query("admin_id"),
+			"entity_type": c.Query("entity_type"),
+			"entity_id":   c.Query("entity_id"),
+			"method":      c.Query("method"),
+			"endpoint":    c.Query("endpoint"),
+			"search":      c.Query("search"),
+			"from":        c.Query("from"),
+			"to":          c.Query("to"),
+		},
+	}, total, page, limit)
+}
@@ -25,33 +25,63 @@ func AdminLogin(c *gin.Context) {
 	var req AdminLoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid login request: %v", err)
-		utils.BadRequest(c, "Invalid input", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err)
 		return
 	}
 	utils.LogDebug("Processing login request for email: %s", req.Email)
 
+	// Password guesses are limited per admin as well as per IP
+	if utils.RateLimited(c, "admin_login:email:"+strings.ToLower(req.Email), adminLoginMaxPerAdmin, adminLoginWindow) {
+		return
+	}
+
 	var admin models.Admin
 	if err := config.DB.Where("email = ?", req.Email).First(&admin).Error; err != nil {
 		utils.LogError("Admin not found for email: %s: %v", req.Email, err)
-		utils.Unauthorized(c, "Invalid credentials")
+		utils.Fail(c, utils.CodeInvalidCredentials, "Invalid credentials", nil)
 		return
 	}
 	utils.LogDebug("Found admin record for email: %s", req.Email)
 
 	if !admin.IsActive {
 		utils.LogError("Inactive admin account attempted login: %s", admin.Email)
-		utils.Forbidden(c, "Admin account is inactive")
+		utils.Fail(c, utils.CodeAccountInactive, "Admin account is inactive", nil)
 		return
 	}
 	utils.LogDebug("Admin account is active: %s", admin.Email)
 
 	if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(req.Password)); err != nil {
 		utils.LogError("Invalid password for admin: %s", admin.Email)
-		utils.Unauthorized(c, "Invalid credentials")
+		utils.Fail(c, utils.CodeInvalidCredentials, "Invalid credentials", nil)
 		return
 	}
 	utils.LogDebug("Password verified for admin: %s", admin.Email)
 
+	// With two-factor authentication the login completes at /admin/login/2fa
+	if admin.TwoFactorEnabled {
+		if adminTwoFactorLocked(c, admin) {
+			return
+		}
+		pendingToken, err := issueAdminTwoFactorToken(admin)
+		if err != nil {
+			utils.LogError("Failed to issue two-factor token for admin: %s: %v", admin.Email, err)
+			utils.InternalServerError(c, "Failed to generate token", err.Error())
+			return
+		}
+		utils.LogInfo("Password verified, two-factor code required for admin: %s", admin.Email)
+		utils.Success(c, "Two-factor authentication required", gin.H{
+			"two_factor_required": true,
+			"two_factor_token":    pendingToken,
+			"expires_in":          int(adminTwoFactorTokenTTL.Seconds()),
+		})
+		return
+	}
+
+	completeAdminLogin(c, admin)
+}
+
+// completeAdminLogin records the login and responds with the admin's access token
+func completeAdminLogin(c *gin.Context, admin models.Admin) {
 	// Update last login
 	admin.LastLogin = time.Now()
 	if err := config.DB.Save(&admin).Error; err != nil {
@@ -0,0 +1,513 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Sources of the catalog change feed
+const (
+	catalogSourceAuditLog     = "audit_log"
+	catalogSourcePriceHistory = "price_history"
+)
+
+// catalogAuditGroups maps the catalog entity types of the feed to the admin route group their
+// audit entries are filed under and, for offers, the part of the endpoint that tells them apart
+var catalogAuditGroups = map[string]struct{ group, endpoint string }{
+	models.CatalogEntityBook:          {group: "books"},
+	models.CatalogEntityCategory:      {group: "categories"},
+	models.CatalogEntityGenre:         {group: "genres"},
+	models.CatalogEntityAuthor:        {group: "authors"},
+	models.CatalogEntityTag:           {group: "tags"},
+	models.CatalogEntityBundle:        {group: "bundles"},
+	models.CatalogEntityProductOffer:  {group: "offers", endpoint: "/offers/products"},
+	models.CatalogEntityCategoryOffer: {group: "offers", endpoint: "/offers/categories"},
+}
+
+// catalogActionConditions select the audit entries of each action other than update, by the
+// method and route template of the request
+var catalogActionConditions = map[string]string{
+	models.CatalogActionCreate:  "method = 'POST' AND entity_id = '' AND endpoint NOT LIKE '%/bulk-categorize'",
+	models.CatalogActionDelete:  "method = 'DELETE' AND endpoint LIKE '%/:id'",
+	models.CatalogActionRestore: "endpoint LIKE '%/restore'",
+	models.CatalogActionClone:   "endpoint LIKE '%/clone'",
+}
+
+// catalogUnchangedFields are snapshot columns left out of the changes of an entry
+var catalogUnchangedFields = map[string]bool{"updated_at": true}
+
+// catalogChangeFilter narrows the catalog change feed
+type catalogChangeFilter struct {
+	EntityType string
+	AdminID    string
+	Action     string
+	PriceOnly  bool
+	Since      time.Time
+}
+
+// catalogChange is an entry of the catalog change feed
+type catalogChange struct {
+	Source        string
+	ID            uint
+	EntityType    string
+	EntityID      uint
+	EntityName    string
+	Action        string
+	AdminID       uint
+	AdminEmail    string
+	Changes       map[string]FieldChange
+	IsPriceChange bool
+	CreatedAt     time.Time
+}
+
+// catalogChangeRef names a feed entry by its source and ID, in feed order
+type catalogChangeRef struct {
+	Source    string
+	ID        uint
+	CreatedAt time.Time
+}
+
+// catalogAuditQuery selects the audit entries of catalog edits that match the filter
+func catalogAuditQuery(filter catalogChangeFilter) *gorm.DB {
+	query := config.DB.Model(&models.AdminAuditLog{}).
+		Select("'"+catalogSourceAuditLog+"' AS source, id, created_at").
+		Where("endpoint NOT LIKE ? AND endpoint NOT LIKE ?", "%/reviews%", "%/offers/rules%")
+
+	if filter.EntityType != "" {
+		target := catalogAuditGroups[filter.EntityType]
+		query = query.Where("entity_type = ? AND endpoint LIKE ?", target.group, "%"+target.endpoint+"%")
+	} else {
+		groups := make([]string, 0, len(catalogAuditGroups))
+		for _, target := range catalogAuditGroups {
+			groups = append(groups, target.group)
+		}
+		query = query.Where("entity_type IN ?", groups)
+	}
+	if filter.PriceOnly {
+		query = query.Where("entity_type = ?", "offers")
+	}
+	if filter.AdminID != "" {
+		query = query.Where("admin_id = ?", filter.AdminID)
+	}
+	if condition, ok := catalogActionConditions[filter.Action]; ok {
+		query = query.Where(condition)
+	} else if filter.Action == models.CatalogActionUpdate {
+		for _, condition := range catalogActionConditions {
+			query = query.Where("NOT (" + condition + ")")
+		}
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	return query
+}
+
+// catalogPriceQuery selects the book price changes that match the filter, or returns nil when
+// the filter leaves them out. Price changes are updates of books not tied to an admin.
+func catalogPriceQuery(filter catalogChangeFilter) *gorm.DB {
+	if (filter.EntityType != "" && filter.EntityType != models.CatalogEntityBook) || filter.AdminID != "" ||
+		(filter.Action != "" && filter.Action != models.CatalogActionUpdate) {
+		return nil
+	}
+	query := config.DB.Model(&models.BookPriceChange{}).
+		Select("'" + catalogSourcePriceHistory + "' AS source, id, created_at")
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	return query
+}
+
+// findCatalogChanges returns the feed entries that match the filter, newest first, and how many
+// there are in all. A limit of 0 returns every entry.
+func findCatalogChanges(filter catalogChangeFilter, offset, limit int) ([]catalogChange, int64, error) {
+	parts := []interface{}{catalogAuditQuery(filter)}
+	if priceQuery := catalogPriceQuery(filter); priceQuery != nil {
+		parts = append(parts, priceQuery)
+	}
+	selects := make([]string, len(parts))
+	for i := range parts {
+		selects[i] = fmt.Sprintf("SELECT * FROM (?) AS part%d", i)
+	}
+	union := strings.Join(selects, " UNION ALL ")
+
+	var total int64
+	if err := config.DB.Raw("SELECT COUNT(*) FROM ("+union+") AS feed", parts...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := union + " ORDER BY created_at DESC, id DESC"
+	args := parts
+	if limit > 0 {
+		page += " LIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	}
+	var refs []catalogChangeRef
+	if err := config.DB.Raw(page, args...).Scan(&refs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	changes, err := loadCatalogChanges(refs)
+	return changes, total, err
+}
+
+// loadCatalogChanges builds the feed entries the refs name, in the same order
+func loadCatalogChanges(refs []catalogChangeRef) ([]catalogChange, error) {
+	var auditIDs, priceIDs []uint
+	for _, ref := range refs {
+		if ref.Source == catalogSourcePriceHistory {
+			priceIDs = append(priceIDs, ref.ID)
+		} else {
+			auditIDs = append(auditIDs, ref.ID)
+		}
+	}
+
+	audits := make(map[uint]models.AdminAuditLog, len(auditIDs))
+	if len(auditIDs) > 0 {
+		var entries []models.AdminAuditLog
+		if err := config.DB.Where("id IN ?", auditIDs).Find(&entries).Error; err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			audits[entry.ID] = entry
+		}
+	}
+
+	prices := make(map[uint]models.BookPriceChange, len(priceIDs))
+	bookNames := make(map[uint]string)
+	if len(priceIDs) > 0 {
+		var entries []models.BookPriceChange
+		if err := config.DB.Where("id IN ?", priceIDs).Find(&entries).Error; err != nil {
+			return nil, err
+		}
+		bookIDs := make([]uint, 0, len(entries))
+		for _, entry := range entries {
+			prices[entry.ID] = entry
+			bookIDs = append(bookIDs, entry.BookID)
+		}
+		var books []models.Book
+		if err := config.DB.Unscoped().Select("id", "name").Where("id IN ?", bookIDs).Find(&books).Error; err != nil {
+			return nil, err
+		}
+		for _, book := range books {
+			bookNames[book.ID] = book.Name
+		}
+	}
+
+	changes := make([]catalogChange, 0, len(refs))
+	for _, ref := range refs {
+		if ref.Source == catalogSourcePriceHistory {
+			if entry, ok := prices[ref.ID]; ok {
+				changes = append(changes, priceHistoryChange(entry, bookNames[entry.BookID]))
+			}
+		} else if entry, ok := audits[ref.ID]; ok {
+			changes = append(changes, auditLogChange(entry))
+		}
+	}
+	return changes, nil
+}
+
+// priceHistoryChange turns a book price change into a feed entry
+func priceHistoryChange(entry models.BookPriceChange, bookName string) catalogChange {
+	changes := diffFields(map[string]interface{}{
+		"price":               entry.OldPrice,
+		"original_price":      entry.OldOriginalPrice,
+		"discount_percentage": entry.OldDiscountPercentage,
+	}, map[string]interface{}{
+		"price":               entry.NewPrice,
+		"original_price":      entry.NewOriginalPrice,
+		"discount_percentage": entry.NewDiscountPercentage,
+	})
+	return catalogChange{
+		Source:        catalogSourcePriceHistory,
+		ID:            entry.ID,
+		EntityType:    models.CatalogEntityBook,
+		EntityID:      entry.BookID,
+		EntityName:    bookName,
+		Action:        models.CatalogActionUpdate,
+		Changes:       changes,
+		IsPriceChange: true,
+		CreatedAt:     entry.CreatedAt,
+	}
+}
+
+// auditLogChange turns the audit entry of a catalog edit into a feed entry, with the changes
+// read from the snapshots of the entity before and after it
+func auditLogChange(entry models.AdminAuditLog) catalogChange {
+	change := catalogChange{
+		Source:     catalogSourceAuditLog,
+		ID:         entry.ID,
+		EntityType: catalogAuditEntityType(entry),
+		Action:     catalogAuditAction(entry),
+		AdminID:    entry.AdminID,
+		AdminEmail: entry.AdminEmail,
+		CreatedAt:  entry.CreatedAt,
+	}
+
+	before, after := decodeAuditSnapshot(entry.Before), decodeAuditSnapshot(entry.After)
+	if entry.Before == "" {
+		// Without a row before the change, after is the response data, which may nest the entity
+		after = auditResponseEntity(after)
+	}
+	subject := after
+	if subject == nil {
+		subject = before
+	}
+	if id, err := strconv.ParseUint(entry.EntityID, 10, 64); err == nil {
+		change.EntityID = uint(id)
+	} else if id, ok := subject["id"].(float64); ok {
+		change.EntityID = uint(id)
+	}
+	change.EntityName = catalogEntityName(change.EntityType, subject)
+
+	if before != nil && after != nil {
+		change.Changes = diffFields(before, after)
+		for field := range catalogUnchangedFields {
+			delete(change.Changes, field)
+		}
+	}
+	change.IsPriceChange = change.EntityType == models.CatalogEntityProductOffer || change.EntityType == models.CatalogEntityCategoryOffer
+	for field := range change.Changes {
+		if priceFields[field] {
+			change.IsPriceChange = true
+		}
+	}
+	return change
+}
+
+// catalogAuditEntityType returns the feed entity type of a catalog audit entry
+func catalogAuditEntityType(entry models.AdminAuditLog) string {
+	for entityType, target := range catalogAuditGroups {
+		if target.group == entry.EntityType && strings.Contains(entry.Endpoint, target.endpoint) {
+			return entityType
+		}
+	}
+	return entry.EntityType
+}
+
+// catalogAuditAction returns the feed action of a catalog audit entry; it matches
+// catalogActionConditions
+func catalogAuditAction(entry models.AdminAuditLog) string {
+	switch {
+	case strings.HasSuffix(entry.Endpoint, "/restore"):
+		return models.CatalogActionRestore
+	case strings.HasSuffix(entry.Endpoint, "/clone"):
+		return models.CatalogActionClone
+	case entry.Method == "POST" && entry.EntityID == "" && !strings.HasSuffix(entry.Endpoint, "/bulk-categorize"):
+		return models.CatalogActionCreate
+	case entry.Method == "DELETE" && strings.HasSuffix(entry.Endpoint, "/:id"):
+		return models.CatalogActionDelete
+	}
+	return models.CatalogActionUpdate
+}
+
+// decodeAuditSnapshot decodes a JSON object kept in the audit log, or returns nil
+func decodeAuditSnapshot(value string) map[string]interface{} {
+	if value == "" {
+		return nil
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// auditResponseEntity returns the entity in response data: the data itself when it has an id,
+// or the first object in it that has one
+func auditResponseEntity(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	if _, ok := data["id"]; ok {
+		return data
+	}
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if nested, ok := data[key].(map[string]interface{}); ok {
+			if _, ok := nested["id"]; ok {
+				return nested
+			}
+		}
+	}
+	return data
+}
+
+// catalogEntityName names the entity of a snapshot; offers are named after what they discount
+func catalogEntityName(entityType string, snapshot map[string]interface{}) string {
+	switch entityType {
+	case models.CatalogEntityProductOffer:
+		if id, ok := snapshot["product_id"].(float64); ok {
+			return fmt.Sprintf("Book #%d", uint(id))
+		}
+	case models.CatalogEntityCategoryOffer:
+		if id, ok := snapshot["category_id"].(float64); ok {
+			return fmt.Sprintf("Category #%d", uint(id))
+		}
+	}
+	name, _ := snapshot["name"].(string)
+	return name
+}
+
+// formatCatalogChange converts a change feed entry into its API representation
+func formatCatalogChange(f utils.ResponseFormatter, change catalogChange) gin.H {
+	return gin.H{
+		"source":          change.Source,
+		"id":              change.ID,
+		"entity_type":     change.EntityType,
+		"entity_id":       change.EntityID,
+		"entity_name":     change.EntityName,
+		"action":          change.Action,
+		"admin_id":        change.AdminID,
+		"admin_email":     change.AdminEmail,
+		"changes":         change.Changes,
+		"is_price_change": change.IsPriceChange,
+		"created_at":      f.Timestamp(change.CreatedAt),
+	}
+}
+
+// GetCatalogChanges returns the consolidated catalog and pricing change feed, read from the
+// admin audit log and the book price history
+func GetCatalogChanges(c *gin.Context) {
+	utils.LogInfo("GetCatalogChanges called")
+
+	page, limit := utils.GetPaginationParams(c)
+	filter := catalogChangeFilter{
+		EntityType: c.Query("entity_type"),
+		AdminID:    c.Query("admin_id"),
+		Action:     c.Query("action"),
+		PriceOnly:  c.Query("price_only") == "true",
+	}
+	if _, ok := catalogAuditGroups[filter.EntityType]; filter.EntityType != "" && !ok {
+		utils.BadRequest(c, "Invalid entity type", "Entity type must be one of book, category, genre, author, tag, bundle, product_offer or category_offer")
+		return
+	}
+	if _, ok := catalogActionConditions[filter.Action]; filter.Action != "" && filter.Action != models.CatalogActionUpdate && !ok {
+		utils.BadRequest(c, "Invalid action", "Action must be one of create, update, delete, restore or clone")
+		return
+	}
+	if filter.AdminID != "" {
+		if _, err := strconv.ParseUint(filter.AdminID, 10, 64); err != nil {
+			utils.BadRequest(c, "Invalid admin ID", nil)
+			return
+		}
+		utils.LogDebug("Applied admin filter: %s", filter.AdminID)
+	}
+	if since := c.Query("since"); since != "" {
+		sinceDate, err := time.Parse("2006-01-02", since)
+		if err != nil {
+			utils.LogError("Invalid since date: %v", err)
+			utils.BadRequest(c, "Invalid since date", "Date must be in YYYY-MM-DD format")
+			return
+		}
+		filter.Since = sinceDate
+	}
+
+	changes, total, err := findCatalogChanges(filter, (page-1)*limit, limit)
+	if err != nil {
+		utils.LogError("Failed to fetch catalog changes: %v", err)
+		utils.InternalServerError(c, "Failed to fetch catalog changes", err.Error())
+		return
+	}
+
+	feed := make([]gin.H, len(changes))
+	formatter := utils.NewResponseFormatter(c)
+	for i, change := range changes {
+		feed[i] = formatCatalogChange(formatter, change)
+	}
+
+	utils.LogInfo("Retrieved %d catalog changes", len(feed))
+	utils.SuccessWithPagination(c, "Catalog changes retrieved successfully", gin.H{
+		"changes": feed,
+		"filters": gin.H{
+			"entity_type": filter.EntityType,
+			"admin_id":    filter.AdminID,
+			"action":      filter.Action,
+			"price_only":  filter.PriceOnly,
+			"since":       c.Query("since"),
+		},
+	}, total, page, limit)
+}
+
+// SendCatalogChangeDigest posts a summary of catalog changes made since the given time
+// to the webhook configured in CATALOG_DIGEST_WEBHOOK_URL (Slack-compatible payload)
+func SendCatalogChangeDigest(since time.Time) (int, error) {
+	webhookURL := os.Getenv("CATALOG_DIGEST_WEBHOOK_URL")
+	if webhookURL == "" {
+		return 0, fmt.Errorf("CATALOG_DIGEST_WEBHOOK_URL is not configured")
+	}
+
+	changes, _, err := findCatalogChanges(catalogChangeFilter{Since: since}, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(changes) == 0 {
+		utils.LogInfo("No catalog changes since %s, skipping digest", since.Format("2006-01-02 15:04"))
+		return 0, nil
+	}
+
+	var lines []string
+	priceChanges := 0
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		if change.IsPriceChange {
+			priceChanges++
+		}
+		line := fmt.Sprintf("• %s %s %q (#%d)", utils.Title(change.Action), strings.ReplaceAll(change.EntityType, "_", " "),
+			change.EntityName, change.EntityID)
+		if change.Source == catalogSourcePriceHistory {
+			line += " price"
+		} else if change.AdminEmail != "" {
+			line += " by " + change.AdminEmail
+		}
+		lines = append(lines, line)
+	}
+
+	text := fmt.Sprintf("*ReadSphere catalog digest* — %d changes (%d pricing) since %s\n%s",
+		len(changes), priceChanges, since.Format("2006-01-02 15:04"), strings.Join(lines, "\n"))
+	if err := utils.PostJSONWebhook(webhookURL, gin.H{"text": text}); err != nil {
+		return 0, err
+	}
+
+	utils.LogInfo("Catalog digest delivered with %d changes", len(changes))
+	return len(changes), nil
+}
+
+// TriggerCatalogChangeDigest sends the catalog digest for the last N hours on demand
+func TriggerCatalogChangeDigest(c *gin.Context) {
+	utils.LogInfo("TriggerCatalogChangeDigest called")
+
+	hours := 24
+	if h := c.Query("hours"); h != "" {
+		if _, err := fmt.Sscanf(h, "%d", &hours); err != nil || hours < 1 || hours > 24*31 {
+			utils.BadRequest(c, "Invalid hours", "Hours must be between 1 and 744")
+			return
+		}
+	}
+
+	count, err := SendCatalogChangeDigest(time.Now().Add(-time.Duration(hours) * time.Hour))
+	if err != nil {
+		utils.LogError("Failed to send catalog digest: %v", err)
+		utils.InternalServerError(c, "Failed to send catalog digest", err.Error())
+		return
+	}
+
+	utils.Success(c, "Catalog digest sent successfully", gin.H{
+		"changes_included": count,
+		"hours":            hours,
+	})
+}
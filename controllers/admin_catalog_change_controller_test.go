@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogChangesComeFromAuditLogAndPriceHistory(t *testing.T) {
+	db := testutil.NewDB(t, &models.AdminAuditLog{}, &models.BookPriceChange{}, &models.Book{})
+
+	book := models.Book{Name: "Dune", Price: 80, OriginalPrice: 100, ISBN: "9780000000101"}
+	require.NoError(t, db.Create(&book).Error)
+	start := time.Now().Add(-time.Hour)
+	entries := []models.AdminAuditLog{
+		{AdminID: 1, AdminEmail: "editor@example.com", Method: "POST", Endpoint: "/v1/admin/categories",
+			EntityType: "categories", After: `{"category":{"id":4,"name":"Sci-Fi"}}`, CreatedAt: start},
+		{AdminID: 2, AdminEmail: "pricing@example.com", Method: "PUT", Endpoint: "/v1/admin/books/:id",
+			EntityType: "books", EntityID: "1", Before: `{"id":1,"name":"Dune","price":100,"updated_at":"a"}`,
+			After: `{"id":1,"name":"Dune","price":80,"updated_at":"b"}`, CreatedAt: start.Add(time.Minute)},
+		{AdminID: 2, AdminEmail: "pricing@example.com", Method: "DELETE", Endpoint: "/v1/admin/offers/products/:id",
+			EntityType: "offers", EntityID: "7", Before: `{"id":7,"product_id":1,"discount_percent":10}`, CreatedAt: start.Add(2 * time.Minute)},
+		{AdminID: 1, Method: "PUT", Endpoint: "/v1/admin/books/:id/reviews/:reviewId/approve",
+			EntityType: "books", EntityID: "1", CreatedAt: start.Add(3 * time.Minute)},
+		{AdminID: 1, Method: "PUT", Endpoint: "/v1/admin/coupons/:id", EntityType: "coupons", EntityID: "3",
+			CreatedAt: start.Add(4 * time.Minute)},
+	}
+	require.NoError(t, db.Create(&entries).Error)
+	require.NoError(t, db.Create(&models.BookPriceChange{BookID: book.ID, OldPrice: 100, NewPrice: 80,
+		OldOriginalPrice: 100, NewOriginalPrice: 100, CreatedAt: start.Add(5 * time.Minute)}).Error)
+
+	changes, total, err := findCatalogChanges(catalogChangeFilter{}, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, total, "reviews and coupons are not catalog changes")
+	require.Len(t, changes, 4)
+
+	price := changes[0]
+	assert.Equal(t, catalogSourcePriceHistory, price.Source)
+	assert.Equal(t, "Dune", price.EntityName)
+	assert.True(t, price.IsPriceChange)
+	assert.Equal(t, FieldChange{Old: float64(100), New: float64(80)}, price.Changes["price"])
+	assert.NotContains(t, price.Changes, "original_price")
+
+	offer := changes[1]
+	assert.Equal(t, models.CatalogEntityProductOffer, offer.EntityType)
+	assert.Equal(t, models.CatalogActionDelete, offer.Action)
+	assert.Equal(t, "Book #1", offer.EntityName)
+	assert.True(t, offer.IsPriceChange)
+
+	update := changes[2]
+	assert.Equal(t, models.CatalogActionUpdate, update.Action)
+	assert.Equal(t, "pricing@example.com", update.AdminEmail)
+	assert.Equal(t, map[string]FieldChange{"price": {Old: float64(100), New: float64(80)}}, update.Changes)
+	assert.True(t, update.IsPriceChange)
+
+	create := changes[3]
+	assert.Equal(t, models.CatalogEntityCategory, create.EntityType)
+	assert.Equal(t, models.CatalogActionCreate, create.Action)
+	assert.Equal(t, uint(4), create.EntityID)
+	assert.Equal(t, "Sci-Fi", create.EntityName)
+
+	changes, total, err = findCatalogChanges(catalogChangeFilter{PriceOnly: true}, 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+
+	changes, _, err = findCatalogChanges(catalogChangeFilter{AdminID: "2", Action: models.CatalogActionUpdate}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, update.ID, changes[0].ID)
+
+	changes, total, err = findCatalogChanges(catalogChangeFilter{EntityType: models.CatalogEntityCategory}, 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	assert.Equal(t, create.ID, changes[0].ID)
+}
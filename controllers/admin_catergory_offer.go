@@ -20,17 +20,10 @@ func ListCategoryOffers(c *gin.Context) {
 	}
 	utils.LogDebug("Retrieved %d category offers", len(offers))
 
+	formatter := utils.NewResponseFormatter(c)
 	var formattedOffers []gin.H
 	for _, offer := range offers {
-		formattedOffers = append(formattedOffers, gin.H{
-			"id":               offer.ID,
-			"category_id":      offer.CategoryID,
-			"discount_percent": offer.DiscountPercent,
-			"start_date":       offer.StartDate.Format("2006-01-02"),
-			"end_date":         offer.EndDate.Format("2006-01-02"),
-			"active":           offer.Active,
-			"is_expired":       time.Now().After(offer.EndDate),
-		})
+		formattedOffers = append(formattedOffers, formatCategoryOffer(formatter, offer))
 	}
 
 	utils.LogInfo("Successfully retrieved %d category offers", len(formattedOffers))
@@ -47,6 +40,7 @@ func UpdateCategoryOffer(c *gin.Context) {
 		StartDate       string  `json:"start_date"`
 		EndDate         string  `json:"end_date"`
 		Active          *bool   `json:"active"`
+		Exclusive       *bool   `json:"exclusive"`
 	}
 	id := c.Param("id")
 	utils.LogDebug("Updating category offer with ID: %s", id)
@@ -54,14 +48,13 @@ func UpdateCategoryOffer(c *gin.Context) {
 	var offer models.CategoryOffer
 	if err := config.DB.First(&offer, id).Error; err != nil {
 		utils.LogError("Offer not found: %v", err)
-		utils.NotFound(c, "Offer not found")
+		utils.Fail(c, utils.CodeOfferNotFound, "Offer not found", nil)
 		return
 	}
 	utils.LogDebug("Found existing offer for category %d", offer.CategoryID)
-
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request data: %v", err)
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 
@@ -83,7 +76,7 @@ func UpdateCategoryOffer(c *gin.Context) {
 			utils.LogDebug("Updated start date to %s", t.Format(time.RFC3339))
 		} else {
 			utils.LogError("Invalid start date format: %v", err)
-			utils.BadRequest(c, "Invalid start date format. Use RFC3339.", nil)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid start date format. Use RFC3339.", nil)
 			return
 		}
 	}
@@ -93,7 +86,7 @@ func UpdateCategoryOffer(c *gin.Context) {
 			utils.LogDebug("Updated end date to %s", t.Format(time.RFC3339))
 		} else {
 			utils.LogError("Invalid end date format: %v", err)
-			utils.BadRequest(c, "Invalid end date format. Use RFC3339.", nil)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid end date format. Use RFC3339.", nil)
 			return
 		}
 	}
@@ -101,6 +94,27 @@ func UpdateCategoryOffer(c *gin.Context) {
 		offer.Active = *req.Active
 		utils.LogDebug("Updated active status to %v", *req.Active)
 	}
+	if req.Exclusive != nil {
+		offer.Exclusive = *req.Exclusive
+		utils.LogDebug("Updated exclusive flag to %v", *req.Exclusive)
+	}
+	if !offer.EndDate.After(offer.StartDate) {
+		utils.LogError("End date %s is not after start date %s", offer.EndDate.Format(time.RFC3339), offer.StartDate.Format(time.RFC3339))
+		utils.BadRequest(c, "End date must be after start date", nil)
+		return
+	}
+
+	if offer.Active {
+		var overlapping models.CategoryOffer
+		if err := config.DB.Where("id != ? AND category_id = ? AND active = ? AND start_date <= ? AND end_date >= ?",
+			offer.ID, offer.CategoryID, true, offer.EndDate, offer.StartDate).First(&overlapping).Error; err == nil {
+			utils.LogError("Offer %d would overlap active offer %d", offer.ID, overlapping.ID)
+			utils.BadRequest(c, "An active offer already exists for this category in these dates", gin.H{
+				"existing_offer": formatCategoryOffer(utils.NewResponseFormatter(c), overlapping),
+			})
+			return
+		}
+	}
 
 	if err := config.DB.Save(&offer).Error; err != nil {
 		utils.LogError("Failed to update offer: %v", err)
@@ -110,15 +124,7 @@ func UpdateCategoryOffer(c *gin.Context) {
 
 	utils.LogInfo("Successfully updated category offer %d", offer.ID)
 	utils.Success(c, "Category offer updated successfully", gin.H{
-		"offer": gin.H{
-			"id":               offer.ID,
-			"category_id":      offer.CategoryID,
-			"discount_percent": offer.DiscountPercent,
-			"start_date":       offer.StartDate.Format("2006-01-02"),
-			"end_date":         offer.EndDate.Format("2006-01-02"),
-			"active":           offer.Active,
-			"is_expired":       time.Now().After(offer.EndDate),
-		},
+		"offer": formatCategoryOffer(utils.NewResponseFormatter(c), offer),
 	})
 }
 
@@ -128,6 +134,9 @@ func DeleteCategoryOffer(c *gin.Context) {
 	id := c.Param("id")
 	utils.LogDebug("Deleting category offer with ID: %s", id)
 
+	var deletedOffer models.CategoryOffer
+	config.DB.First(&deletedOffer, id)
+
 	result := config.DB.Delete(&models.CategoryOffer{}, id)
 	if result.Error != nil {
 		utils.LogError("Failed to delete offer: %v", result.Error)
@@ -136,10 +145,27 @@ func DeleteCategoryOffer(c *gin.Context) {
 	}
 	if result.RowsAffected == 0 {
 		utils.LogError("Category offer not found: %s", id)
-		utils.NotFound(c, "Category offer not found")
+		utils.Fail(c, utils.CodeOfferNotFound, "Category offer not found", nil)
 		return
 	}
 
 	utils.LogInfo("Successfully deleted category offer %s", id)
 	utils.Success(c, "Category offer deleted successfully", nil)
 }
+
+// GetCategoryOffer returns a single category offer
+func GetCategoryOffer(c *gin.Context) {
+	utils.LogInfo("GetCategoryOffer called")
+
+	id := c.Param("id")
+	var offer models.CategoryOffer
+	if err := config.DB.First(&offer, id).Error; err != nil {
+		utils.LogError("Category offer not found: %s", id)
+		utils.Fail(c, utils.CodeOfferNotFound, "Category offer not found", nil)
+		return
+	}
+
+	utils.Success(c, "Category offer retrieved successfully", gin.H{
+		"offer": formatCategoryOffer(utils.NewResponseFormatter(c), offer),
+	})
+}
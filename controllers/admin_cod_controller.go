@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// codOrderInTransit loads the order and checks it is a Cash on Delivery order on its way to
+// the customer. It writes the error response itself and returns false on failure.
+func codOrderInTransit(c *gin.Context, db *gorm.DB, order *models.Order) bool {
+	if err := db.Preload("User").First(order, c.Param("id")).Error; err != nil {
+		utils.LogError("Order not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return false
+	}
+	if utils.NormalizePaymentMethod(order.PaymentMethod) != "cod" {
+		utils.LogError("Order ID: %d is not a Cash on Delivery order", order.ID)
+		utils.BadRequest(c, "Only Cash on Delivery orders use this step", nil)
+		return false
+	}
+	if !strings.EqualFold(order.Status, models.OrderStatusShipped) && !strings.EqualFold(order.Status, "Out for Delivery") {
+		utils.LogError("Order ID: %d is not out for delivery, status: %s", order.ID, order.Status)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Order must be shipped or out for delivery", gin.H{
+			"status": order.Status,
+		})
+		return false
+	}
+	return true
+}
+
+// GetCODBlockedPincodes lists the pincodes where Cash on Delivery has been turned off
+func GetCODBlockedPincodes(c *gin.Context) {
+	utils.LogInfo("GetCODBlockedPincodes called")
+
+	var pincodes []models.CODBlockedPincode
+	if err := config.DB.Order("pincode ASC").Find(&pincodes).Error; err != nil {
+		utils.LogError("Failed to fetch COD blocked pincodes: %v", err)
+		utils.InternalServerError(c, "Failed to fetch COD blocked pincodes", err.Error())
+		return
+	}
+
+	utils.Success(c, "COD blocked pincodes retrieved successfully", gin.H{
+		"pincodes": pincodes,
+	})
+}
+
+// BlockCODPincode turns Cash on Delivery off for a pincode
+func BlockCODPincode(c *gin.Context) {
+	utils.LogInfo("BlockCODPincode called")
+
+	admin, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	adminModel, ok := admin.(models.Admin)
+	if !ok {
+		utils.LogError("Invalid admin type in context")
+		utils.InternalServerError(c, "Invalid admin type", nil)
+		return
+	}
+
+	var req struct {
+		Pincode string `json:"pincode" binding:"required"`
+		Reason  string `json:"reason" binding:"max=500"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+	req.Pincode = strings.TrimSpace(req.Pincode)
+	if !utils.IsValidPincode(req.Pincode) {
+		utils.LogError("Invalid pincode: %s", req.Pincode)
+		utils.BadRequest(c, "Pincode must be a valid 6-digit Indian PIN code", nil)
+		return
+	}
+
+	var existing models.CODBlockedPincode
+	if err := config.DB.Where("pincode = ?", req.Pincode).First(&existing).Error; err == nil {
+		utils.LogError("COD already blocked for pincode %s", req.Pincode)
+		utils.Conflict(c, "Cash on Delivery is already turned off for this pincode", gin.H{
+			"pincode": existing,
+		})
+		return
+	}
+
+	blocked := models.CODBlockedPincode{
+		Pincode: req.Pincode,
+		Reason:  strings.TrimSpace(req.Reason),
+		AdminID: adminModel.ID,
+	}
+	if err := config.DB.Create(&blocked).Error; err != nil {
+		utils.LogError("Failed to block COD for pincode %s: %v", req.Pincode, err)
+		utils.InternalServerError(c, "Failed to turn off Cash on Delivery", err.Error())
+		return
+	}
+
+	utils.LogInfo("COD turned off for pincode %s by admin %s", req.Pincode, adminModel.Email)
+	utils.Success(c, "Cash on Delivery turned off for pincode", gin.H{
+		"pincode": blocked,
+	})
+}
+
+// UnblockCODPincode turns Cash on Delivery back on for a pincode
+func UnblockCODPincode(c *gin.Context) {
+	utils.LogInfo("UnblockCODPincode called")
+
+	pincode := c.Param("pincode")
+	result := config.DB.Where("pincode = ?", pincode).Delete(&models.CODBlockedPincode{})
+	if result.Error != nil {
+		utils.LogError("Failed to unblock COD for pincode %s: %v", pincode, result.Error)
+		utils.InternalServerError(c, "Failed to turn Cash on Delivery back on", result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.NotFound(c, "Cash on Delivery is not turned off for this pincode")
+		return
+	}
+
+	utils.LogInfo("COD turned back on for pincode %s", pincode)
+	utils.Success(c, "Cash on Delivery turned back on for pincode", nil)
+}
+
+// SendDeliveryOTP generates the handover OTP of a Cash on Delivery order and emails it to
+// the customer, who gives it to the delivery agent on receiving the parcel
+func SendDeliveryOTP(c *gin.Context) {
+	utils.LogInfo("SendDeliveryOTP called")
+
+	var order models.Order
+	if !codOrderInTransit(c, config.DB, &order) {
+		return
+	}
+	if order.DeliveryOTPVerifiedAt != nil {
+		utils.LogError("Delivery OTP already verified for order ID: %d", order.ID)
+		utils.BadRequest(c, "Delivery OTP is already verified for this order", nil)
+		return
+	}
+
+	otp, err := utils.IssueDeliveryOTP(config.DB, &order)
+	if err != nil {
+		utils.LogError("Failed to generate delivery OTP for order ID: %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to generate delivery OTP", err.Error())
+		return
+	}
+	if err := utils.SendDeliveryOTPEmail(order.User.Email, order.ID, otp); err != nil {
+		utils.LogError("Failed to email delivery OTP for order ID: %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to send delivery OTP", err.Error())
+		return
+	}
+
+	utils.LogInfo("Delivery OTP sent for order ID: %d", order.ID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Delivery OTP sent to the customer", gin.H{
+		"order_id":   order.ID,
+		"expires_at": formatter.Timestamp(*order.DeliveryOTPExpiresAt),
+	})
+}
+
+// VerifyDeliveryOTP checks the OTP the customer gave the delivery agent. Once it matches the
+// order can be marked Delivered.
+func VerifyDeliveryOTP(c *gin.Context) {
+	utils.LogInfo("VerifyDeliveryOTP called")
+
+	var req struct {
+		OTP string `json:"otp" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+
+	var order models.Order
+	if !codOrderInTransit(c, config.DB, &order) {
+		return
+	}
+	if order.DeliveryOTPVerifiedAt != nil {
+		utils.LogError("Delivery OTP already verified for order ID: %d", order.ID)
+		utils.BadRequest(c, "Delivery OTP is already verified for this order", nil)
+		return
+	}
+
+	if err := utils.VerifyDeliveryOTP(config.DB, &order, strings.TrimSpace(req.OTP)); err != nil {
+		utils.LogError("Delivery OTP check failed for order ID: %d: %v", order.ID, err)
+		switch {
+		case errors.Is(err, utils.ErrDeliveryOTPInvalid):
+			utils.Fail(c, utils.CodeDeliveryOTPInvalid, "Invalid delivery OTP", gin.H{
+				"attempts_left": utils.DeliveryOTPMaxAttempts - order.DeliveryOTPAttempts,
+			})
+		case errors.Is(err, utils.ErrDeliveryOTPNotIssued), errors.Is(err, utils.ErrDeliveryOTPExpired), errors.Is(err, utils.ErrDeliveryOTPAttempts):
+			utils.Fail(c, utils.CodeDeliveryOTPInvalid, err.Error(), "Send a new delivery OTP to the customer")
+		default:
+			utils.InternalServerError(c, "Failed to verify delivery OTP", err.Error())
+		}
+		return
+	}
+
+	utils.LogInfo("Delivery OTP verified for order ID: %d", order.ID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Delivery OTP verified. The order can now be marked as delivered.", gin.H{
+		"order_id":    order.ID,
+		"verified_at": formatter.Timestamp(*order.DeliveryOTPVerifiedAt),
+	})
+}
+
+// MarkDeliveryRefused records that the customer refused a Cash on Delivery parcel. The order
+// is cancelled and restocked, and the refusal counts against the customer's Cash on Delivery
+// eligibility.
+func MarkDeliveryRefused(c *gin.Context) {
+	utils.LogInfo("MarkDeliveryRefused called")
+
+	admin, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	adminModel, ok := admin.(models.Admin)
+	if !ok {
+		utils.LogError("Invalid admin type in context")
+		utils.InternalServerError(c, "Invalid admin type", nil)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"max=500"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.LogError("Invalid request format: %v", err)
+			utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+			return
+		}
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		reason = "Customer refused the delivery"
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to begin transaction: %v", tx.Error)
+		utils.InternalServerError(c, "Failed to begin transaction", nil)
+		return
+	}
+
+	var order models.Order
+	if !codOrderInTransit(c, tx, &order) {
+		tx.Rollback()
+		return
+	}
+
+	if err := tx.Model(&order).Updates(map[string]interface{}{
+		"status":              models.OrderStatusCancelled,
+		"delivery_refused":    true,
+		"cancellation_reason": reason,
+		"delivery_otp":        "",
+		"updated_at":          time.Now(),
+	}).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to mark order %d as refused: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to update order", nil)
+		return
+	}
+	if err := recordOrderStatusEvent(tx, order.ID, models.OrderStatusCancelled, "admin", adminModel.ID, reason); err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to record order status event: %v", err)
+		utils.InternalServerError(c, "Failed to update order", nil)
+		return
+	}
+
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to fetch order items: %v", err)
+		utils.InternalServerError(c, "Failed to fetch order items", nil)
+		return
+	}
+	restocked, err := utils.RestockOrderItems(tx, items, models.StockSourceCancellation)
+	if err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to update book stock for order %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to update book stock", nil)
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit transaction: %v", err)
+		utils.InternalServerError(c, "Failed to save changes", nil)
+		return
+	}
+
+	eligible, refused, err := utils.CODEligibility(order.UserID)
+	if err != nil {
+		utils.LogError("Failed to check COD eligibility for user ID: %d: %v", order.UserID, err)
+	}
+	utils.LogInfo("Order ID: %d marked as refused by admin %s, restocked %d items", order.ID, adminModel.Email, restocked)
+	utils.Success(c, "Order marked as refused and cancelled", gin.H{
+		"order_id":               order.ID,
+		"status":                 models.OrderStatusCancelled,
+		"restocked_items":        restocked,
+		"user_refused_count":     refused,
+		"user_cod_eligible":      eligible,
+		"max_refused_deliveries": utils.CODMaxRefusedDeliveries(),
+	})
+}
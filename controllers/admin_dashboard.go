@@ -1,8 +1,6 @@
 package controllers
 
 import (
-	"time"
-
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
@@ -22,12 +20,12 @@ type DashboardOverview struct {
 
 // OrderOverview represents simplified order data for the dashboard
 type OrderOverview struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Status    string    `json:"status"`
-	Total     float64   `json:"total"`
-	CreatedAt time.Time `json:"created_at"`
-	ItemCount int       `json:"item_count"`
+	ID        uint    `json:"id"`
+	Username  string  `json:"username"`
+	Status    string  `json:"status"`
+	Total     float64 `json:"total"`
+	CreatedAt string  `json:"created_at"`
+	ItemCount int     `json:"item_count"`
 }
 
 // BookOverview represents simplified book data for the dashboard
@@ -54,7 +52,7 @@ func GetDashboardOverview(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -129,6 +127,7 @@ func GetDashboardOverview(c *gin.Context) {
 	utils.LogDebug("Retrieved %d top books", len(topBooks))
 
 	// Prepare recent orders overview
+	formatter := utils.NewResponseFormatter(c)
 	recentOrdersOverview := make([]OrderOverview, 0, len(recentOrders))
 	for _, order := range recentOrders {
 		recentOrdersOverview = append(recentOrdersOverview, OrderOverview{
@@ -136,7 +135,7 @@ func GetDashboardOverview(c *gin.Context) {
 			Username:  order.User.Username,
 			Status:    order.Status,
 			Total:     order.FinalTotal,
-			CreatedAt: order.CreatedAt,
+			CreatedAt: formatter.Timestamp(order.CreatedAt),
 			ItemCount: len(order.OrderItems),
 		})
 	}
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/Govind-619/ReadSphere/config"
@@ -13,25 +12,25 @@ import (
 
 // DashboardStats represents the response structure for dashboard statistics
 type DashboardStats struct {
-	TotalSales     string `json:"total_sales"`
-	TotalOrders    int64  `json:"total_orders"`
-	TotalCustomers int64  `json:"total_customers"`
-	TotalProducts  int64  `json:"total_products"`
+	TotalSales     utils.Money `json:"total_sales"`
+	TotalOrders    int64       `json:"total_orders"`
+	TotalCustomers int64       `json:"total_customers"`
+	TotalProducts  int64       `json:"total_products"`
 }
 
 // SalesChartData represents the response structure for sales chart data
 type SalesChartData struct {
-	Labels []string `json:"labels"`
-	Data   []string `json:"data"` // Changed to string array for formatted values
+	Labels []string      `json:"labels"`
+	Data   []utils.Money `json:"data"`
 }
 
 // TopSellingItem represents a top selling item (product/category/brand)
 type TopSellingItem struct {
-	ID          uint   `json:"id"`
-	Name        string `json:"name"`
-	TotalSales  string `json:"total_sales"` // Changed to string for formatted value
-	TotalOrders int64  `json:"total_orders"`
-	Quantity    int64  `json:"quantity"`
+	ID          uint        `json:"id"`
+	Name        string      `json:"name"`
+	TotalSales  utils.Money `json:"total_sales"`
+	TotalOrders int64       `json:"total_orders"`
+	Quantity    int64       `json:"quantity"`
 }
 
 // GetDashboardStats returns overall dashboard statistics
@@ -47,8 +46,7 @@ func GetDashboardStats(c *gin.Context) {
 		Select("COALESCE(SUM(final_total), 0)").
 		Row().Scan(&totalSales)
 
-	// Format total sales with 2 decimal places
-	stats.TotalSales = fmt.Sprintf("%.2f", totalSales)
+	stats.TotalSales = utils.NewResponseFormatter(c).Money(totalSales)
 	utils.LogDebug("Total sales calculated: %s", stats.TotalSales)
 
 	// Get total orders
@@ -143,10 +141,11 @@ func GetSalesChart(c *gin.Context) {
 	}
 	utils.LogDebug("Retrieved %d data points for sales chart", len(results))
 
+	formatter := utils.NewResponseFormatter(c)
 	// Format data for response
 	for _, r := range results {
 		chartData.Labels = append(chartData.Labels, r.Period.Format(timeFormat))
-		chartData.Data = append(chartData.Data, fmt.Sprintf("%.2f", r.Total))
+		chartData.Data = append(chartData.Data, formatter.Money(r.Total))
 	}
 
 	utils.LogInfo("Successfully generated sales chart for period %s", period)
@@ -184,11 +183,12 @@ func GetTopSellingProducts(c *gin.Context) {
 
 	// Convert to formatted response
 	products := make([]TopSellingItem, len(rawProducts))
+	formatter := utils.NewResponseFormatter(c)
 	for i, p := range rawProducts {
 		products[i] = TopSellingItem{
 			ID:          p.ID,
 			Name:        p.Name,
-			TotalSales:  fmt.Sprintf("%.2f", p.TotalSales),
+			TotalSales:  formatter.Money(p.TotalSales),
 			TotalOrders: p.TotalOrders,
 			Quantity:    p.Quantity,
 		}
@@ -230,11 +230,12 @@ func GetTopSellingCategories(c *gin.Context) {
 
 	// Convert to formatted response
 	categories := make([]TopSellingItem, len(rawCategories))
+	formatter := utils.NewResponseFormatter(c)
 	for i, c := range rawCategories {
 		categories[i] = TopSellingItem{
 			ID:          c.ID,
 			Name:        c.Name,
-			TotalSales:  fmt.Sprintf("%.2f", c.TotalSales),
+			TotalSales:  formatter.Money(c.TotalSales),
 			TotalOrders: c.TotalOrders,
 			Quantity:    c.Quantity,
 		}
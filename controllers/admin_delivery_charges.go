@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
@@ -9,12 +10,58 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// validateDeliveryRange checks the pincode range and rejects it when it overlaps another rule.
+// It writes the error response itself and returns false on failure.
+func validateDeliveryRange(c *gin.Context, from, to string, excludeID uint) bool {
+	if !utils.IsValidPincode(from) || !utils.IsValidPincode(to) {
+		utils.LogError("Invalid pincode range: %s-%s", from, to)
+		utils.BadRequest(c, "Pincodes must be valid 6-digit Indian PIN codes", nil)
+		return false
+	}
+	if from > to {
+		utils.LogError("Pincode range start %s is after end %s", from, to)
+		utils.BadRequest(c, "pincode_from must not be greater than pincode_to", nil)
+		return false
+	}
+
+	var overlapping []models.DeliveryCharge
+	if err := config.DB.Where("id != ? AND pincode_from <= ? AND pincode_to >= ?", excludeID, to, from).
+		Find(&overlapping).Error; err != nil {
+		utils.LogError("Failed to check overlapping delivery charges: %v", err)
+		utils.InternalServerError(c, "Failed to validate delivery charge", err.Error())
+		return false
+	}
+	if len(overlapping) > 0 {
+		conflicts := make([]gin.H, len(overlapping))
+		for i, rule := range overlapping {
+			conflicts[i] = gin.H{
+				"id":           rule.ID,
+				"pincode_from": rule.PincodeFrom,
+				"pincode_to":   rule.PincodeTo,
+				"zone":         rule.Zone,
+			}
+		}
+		utils.LogError("Pincode range %s-%s overlaps %d existing rules", from, to, len(overlapping))
+		utils.Conflict(c, "Pincode range overlaps an existing delivery charge rule", gin.H{
+			"conflicts": conflicts,
+		})
+		return false
+	}
+	return true
+}
+
 // GetDeliveryCharges returns all delivery charges
 func GetDeliveryCharges(c *gin.Context) {
 	utils.LogInfo("GetDeliveryCharges called")
 
+	query := config.DB.Model(&models.DeliveryCharge{})
+	if zone := c.Query("zone"); zone != "" {
+		query = query.Where("zone ILIKE ?", zone)
+		utils.LogDebug("Applied zone filter: %s", zone)
+	}
+
 	var deliveryCharges []models.DeliveryCharge
-	if err := config.DB.Find(&deliveryCharges).Error; err != nil {
+	if err := query.Order("pincode_from ASC").Find(&deliveryCharges).Error; err != nil {
 		utils.LogError("Failed to fetch delivery charges: %v", err)
 		utils.InternalServerError(c, "Failed to fetch delivery charges", err.Error())
 		return
@@ -25,27 +72,59 @@ func GetDeliveryCharges(c *gin.Context) {
 	})
 }
 
-// AddDeliveryCharge adds a new delivery charge
+// AddDeliveryCharge adds a new delivery charge rule for a single pincode or a pincode range
 func AddDeliveryCharge(c *gin.Context) {
 	utils.LogInfo("AddDeliveryCharge called")
 
 	var req struct {
-		Pincode        string  `json:"pincode" binding:"required"`
-		Charge         float64 `json:"charge" binding:"required,min=0"`
-		MinOrderAmount float64 `json:"min_order_amount" binding:"min=0"`
+		Pincode           string  `json:"pincode"`
+		PincodeFrom       string  `json:"pincode_from"`
+		PincodeTo         string  `json:"pincode_to"`
+		Zone              string  `json:"zone"`
+		Charge            float64 `json:"charge" binding:"min=0"`
+		MinOrderAmount    float64 `json:"min_order_amount" binding:"min=0"`
+		FreeDeliveryAbove float64 `json:"free_delivery_above" binding:"min=0"`
+		CODAvailable      *bool   `json:"cod_available"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request format: %v", err)
-		utils.BadRequest(c, "Invalid request format", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
 		return
 	}
 
+	// A single pincode is a range of one
+	from := strings.TrimSpace(req.PincodeFrom)
+	to := strings.TrimSpace(req.PincodeTo)
+	if from == "" && to == "" {
+		from = strings.TrimSpace(req.Pincode)
+		to = from
+	} else if to == "" {
+		to = from
+	}
+	if from == "" {
+		utils.BadRequest(c, "Either pincode or pincode_from/pincode_to is required", nil)
+		return
+	}
+	if !validateDeliveryRange(c, from, to, 0) {
+		return
+	}
+
+	codAvailable := true
+	if req.CODAvailable != nil {
+		codAvailable = *req.CODAvailable
+	}
+
 	deliveryCharge := models.DeliveryCharge{
-		Pincode:        req.Pincode,
-		Charge:         req.Charge,
-		MinOrderAmount: req.MinOrderAmount,
-		IsActive:       true,
+		Pincode:           from,
+		PincodeFrom:       from,
+		PincodeTo:         to,
+		Zone:              strings.TrimSpace(req.Zone),
+		Charge:            req.Charge,
+		MinOrderAmount:    req.MinOrderAmount,
+		FreeDeliveryAbove: req.FreeDeliveryAbove,
+		CODAvailable:      codAvailable,
+		IsActive:          true,
 	}
 
 	if err := config.DB.Create(&deliveryCharge).Error; err != nil {
@@ -54,6 +133,7 @@ func AddDeliveryCharge(c *gin.Context) {
 		return
 	}
 
+	utils.LogInfo("Created delivery charge rule %d for pincodes %s-%s", deliveryCharge.ID, from, to)
 	utils.Success(c, "Delivery charge added successfully", gin.H{
 		"delivery_charge": deliveryCharge,
 	})
@@ -67,19 +147,24 @@ func UpdateDeliveryCharge(c *gin.Context) {
 	deliveryChargeID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
 		utils.LogError("Invalid delivery charge ID: %s", id)
-		utils.BadRequest(c, "Invalid delivery charge ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid delivery charge ID", nil)
 		return
 	}
 
 	var req struct {
-		Charge         *float64 `json:"charge"`
-		MinOrderAmount *float64 `json:"min_order_amount"`
-		IsActive       *bool    `json:"is_active"`
+		PincodeFrom       *string  `json:"pincode_from"`
+		PincodeTo         *string  `json:"pincode_to"`
+		Zone              *string  `json:"zone"`
+		Charge            *float64 `json:"charge"`
+		MinOrderAmount    *float64 `json:"min_order_amount"`
+		FreeDeliveryAbove *float64 `json:"free_delivery_above"`
+		CODAvailable      *bool    `json:"cod_available"`
+		IsActive          *bool    `json:"is_active"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request format: %v", err)
-		utils.BadRequest(c, "Invalid request format", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
 		return
 	}
 
@@ -91,12 +176,44 @@ func UpdateDeliveryCharge(c *gin.Context) {
 	}
 
 	updates := make(map[string]interface{})
+	if req.PincodeFrom != nil || req.PincodeTo != nil {
+		from, to := deliveryCharge.PincodeFrom, deliveryCharge.PincodeTo
+		if req.PincodeFrom != nil {
+			from = strings.TrimSpace(*req.PincodeFrom)
+		}
+		if req.PincodeTo != nil {
+			to = strings.TrimSpace(*req.PincodeTo)
+		}
+		if !validateDeliveryRange(c, from, to, deliveryCharge.ID) {
+			return
+		}
+		updates["pincode"] = from
+		updates["pincode_from"] = from
+		updates["pincode_to"] = to
+	}
+	if req.Zone != nil {
+		updates["zone"] = strings.TrimSpace(*req.Zone)
+	}
 	if req.Charge != nil {
+		if *req.Charge < 0 {
+			utils.BadRequest(c, "Charge must not be negative", nil)
+			return
+		}
 		updates["charge"] = *req.Charge
 	}
 	if req.MinOrderAmount != nil {
 		updates["min_order_amount"] = *req.MinOrderAmount
 	}
+	if req.FreeDeliveryAbove != nil {
+		if *req.FreeDeliveryAbove < 0 {
+			utils.BadRequest(c, "Free delivery threshold must not be negative", nil)
+			return
+		}
+		updates["free_delivery_above"] = *req.FreeDeliveryAbove
+	}
+	if req.CODAvailable != nil {
+		updates["cod_available"] = *req.CODAvailable
+	}
 	if req.IsActive != nil {
 		updates["is_active"] = *req.IsActive
 	}
@@ -120,7 +237,7 @@ func DeleteDeliveryCharge(c *gin.Context) {
 	deliveryChargeID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
 		utils.LogError("Invalid delivery charge ID: %s", id)
-		utils.BadRequest(c, "Invalid delivery charge ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid delivery charge ID", nil)
 		return
 	}
 
@@ -140,7 +257,7 @@ func DeleteDeliveryCharge(c *gin.Context) {
 	utils.Success(c, "Delivery charge deleted successfully", nil)
 }
 
-// GetDeliveryChargeByPincode returns delivery charge for a specific pincode
+// GetDeliveryChargeByPincode returns the delivery charge rule covering a specific pincode
 func GetDeliveryChargeByPincode(c *gin.Context) {
 	utils.LogInfo("GetDeliveryChargeByPincode called")
 
@@ -151,8 +268,8 @@ func GetDeliveryChargeByPincode(c *gin.Context) {
 		return
 	}
 
-	var deliveryCharge models.DeliveryCharge
-	if err := config.DB.Where("pincode = ? AND is_active = ?", pincode, true).First(&deliveryCharge).Error; err != nil {
+	deliveryCharge, err := utils.FindDeliveryRule(pincode)
+	if err != nil {
 		utils.LogError("Delivery charge not found for pincode %s: %v", pincode, err)
 		utils.NotFound(c, "Delivery charge not found for this pincode")
 		return
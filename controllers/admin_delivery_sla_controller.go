@@ -0,0 +1,262 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DeliverySLARequest sets the delivery time of a zone
+type DeliverySLARequest struct {
+	Zone         string `json:"zone" binding:"required,max=100"`
+	MinDays      int    `json:"min_days" binding:"gte=0,lte=60"`
+	MaxDays      int    `json:"max_days" binding:"gte=0,lte=60"`
+	SlotsEnabled bool   `json:"slots_enabled"`
+	IsActive     *bool  `json:"is_active"`
+}
+
+// DeliverySlotRequest is a delivery slot of a zone
+type DeliverySlotRequest struct {
+	Zone      string `json:"zone" binding:"required,max=100"`
+	StartTime string `json:"start_time" binding:"required"`
+	EndTime   string `json:"end_time" binding:"required"`
+	Capacity  int    `json:"capacity" binding:"required,gte=1,lte=10000"`
+	IsActive  *bool  `json:"is_active"`
+}
+
+// parseSlotWindow checks a slot's HH:MM start and end times and returns them zero-padded
+func parseSlotWindow(start, end string) (string, string, bool) {
+	startTime, err := time.Parse("15:04", strings.TrimSpace(start))
+	if err != nil {
+		return "", "", false
+	}
+	endTime, err := time.Parse("15:04", strings.TrimSpace(end))
+	if err != nil || !endTime.After(startTime) {
+		return "", "", false
+	}
+	return startTime.Format("15:04"), endTime.Format("15:04"), true
+}
+
+// GetDeliverySLAs lists the delivery time of each zone and its delivery slots, with the
+// default delivery time used for zones without their own
+func GetDeliverySLAs(c *gin.Context) {
+	utils.LogInfo("GetDeliverySLAs called")
+
+	var slas []models.DeliverySLA
+	if err := config.DB.Order("zone ASC").Find(&slas).Error; err != nil {
+		utils.LogError("Failed to fetch delivery SLAs: %v", err)
+		utils.InternalServerError(c, "Failed to fetch delivery times", err.Error())
+		return
+	}
+	var slots []models.DeliverySlot
+	if err := config.DB.Order("zone ASC, start_time ASC").Find(&slots).Error; err != nil {
+		utils.LogError("Failed to fetch delivery slots: %v", err)
+		utils.InternalServerError(c, "Failed to fetch delivery slots", err.Error())
+		return
+	}
+
+	minDays, maxDays := utils.DefaultDeliveryDays()
+	utils.Success(c, "Delivery times retrieved successfully", gin.H{
+		"default": gin.H{
+			"min_days": minDays,
+			"max_days": maxDays,
+		},
+		"delivery_slas":  slas,
+		"delivery_slots": slots,
+	})
+}
+
+// SetDeliverySLA creates or replaces the delivery time of a zone. Zones match the zone of
+// delivery charge rules, ignoring case.
+func SetDeliverySLA(c *gin.Context) {
+	utils.LogInfo("SetDeliverySLA called")
+
+	var req DeliverySLARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+	req.Zone = strings.TrimSpace(req.Zone)
+	if req.Zone == "" {
+		utils.BadRequest(c, "Zone is required", nil)
+		return
+	}
+	if req.MinDays > req.MaxDays {
+		utils.LogError("Delivery SLA for zone %s has min_days %d above max_days %d", req.Zone, req.MinDays, req.MaxDays)
+		utils.BadRequest(c, "min_days must not be greater than max_days", nil)
+		return
+	}
+
+	var sla models.DeliverySLA
+	err := config.DB.Where("LOWER(zone) = ?", strings.ToLower(req.Zone)).First(&sla).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.LogError("Failed to fetch delivery SLA for zone %s: %v", req.Zone, err)
+		utils.InternalServerError(c, "Failed to save delivery time", err.Error())
+		return
+	}
+	sla.Zone = req.Zone
+	sla.MinDays = req.MinDays
+	sla.MaxDays = req.MaxDays
+	sla.SlotsEnabled = req.SlotsEnabled
+	if req.IsActive != nil {
+		sla.IsActive = *req.IsActive
+	} else if sla.ID == 0 {
+		sla.IsActive = true
+	}
+	// Save writes every column, so an inactive SLA or disabled slots are stored as given
+	if err := config.DB.Save(&sla).Error; err != nil {
+		utils.LogError("Failed to save delivery SLA for zone %s: %v", req.Zone, err)
+		utils.InternalServerError(c, "Failed to save delivery time", err.Error())
+		return
+	}
+
+	utils.LogInfo("Set delivery SLA %d for zone %s: %d-%d days", sla.ID, sla.Zone, sla.MinDays, sla.MaxDays)
+	utils.Success(c, "Delivery time saved successfully", gin.H{
+		"delivery_sla": sla,
+	})
+}
+
+// DeleteDeliverySLA removes a zone's delivery time so the zone uses the defaults again
+func DeleteDeliverySLA(c *gin.Context) {
+	utils.LogInfo("DeleteDeliverySLA called")
+
+	slaID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid delivery SLA ID: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeInvalidID, "Invalid delivery time ID", nil)
+		return
+	}
+
+	result := config.DB.Delete(&models.DeliverySLA{}, slaID)
+	if result.Error != nil {
+		utils.LogError("Failed to delete delivery SLA %d: %v", slaID, result.Error)
+		utils.InternalServerError(c, "Failed to delete delivery time", result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.NotFound(c, "Delivery time not found")
+		return
+	}
+
+	utils.LogInfo("Deleted delivery SLA %d", slaID)
+	utils.Success(c, "Delivery time deleted successfully", nil)
+}
+
+// AddDeliverySlot adds a delivery slot to a zone
+func AddDeliverySlot(c *gin.Context) {
+	utils.LogInfo("AddDeliverySlot called")
+
+	var req DeliverySlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+	start, end, ok := parseSlotWindow(req.StartTime, req.EndTime)
+	if !ok {
+		utils.LogError("Invalid delivery slot window %s-%s", req.StartTime, req.EndTime)
+		utils.BadRequest(c, "start_time and end_time must be HH:MM times with start_time before end_time", nil)
+		return
+	}
+
+	slot := models.DeliverySlot{
+		Zone:      strings.TrimSpace(req.Zone),
+		StartTime: start,
+		EndTime:   end,
+		Capacity:  req.Capacity,
+		IsActive:  req.IsActive == nil || *req.IsActive,
+	}
+	if err := config.DB.Create(&slot).Error; err != nil {
+		utils.LogError("Failed to create delivery slot: %v", err)
+		utils.InternalServerError(c, "Failed to create delivery slot", err.Error())
+		return
+	}
+
+	utils.LogInfo("Created delivery slot %d for zone %s: %s-%s", slot.ID, slot.Zone, slot.StartTime, slot.EndTime)
+	utils.Success(c, "Delivery slot added successfully", gin.H{
+		"delivery_slot": slot,
+	})
+}
+
+// UpdateDeliverySlot changes a delivery slot. Orders already booked into it keep their slot.
+func UpdateDeliverySlot(c *gin.Context) {
+	utils.LogInfo("UpdateDeliverySlot called")
+
+	slotID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid delivery slot ID: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeInvalidID, "Invalid delivery slot ID", nil)
+		return
+	}
+	var req DeliverySlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+	start, end, ok := parseSlotWindow(req.StartTime, req.EndTime)
+	if !ok {
+		utils.LogError("Invalid delivery slot window %s-%s", req.StartTime, req.EndTime)
+		utils.BadRequest(c, "start_time and end_time must be HH:MM times with start_time before end_time", nil)
+		return
+	}
+
+	var slot models.DeliverySlot
+	if err := config.DB.First(&slot, slotID).Error; err != nil {
+		utils.LogError("Delivery slot not found: %v", err)
+		utils.NotFound(c, "Delivery slot not found")
+		return
+	}
+	slot.Zone = strings.TrimSpace(req.Zone)
+	slot.StartTime = start
+	slot.EndTime = end
+	slot.Capacity = req.Capacity
+	if req.IsActive != nil {
+		slot.IsActive = *req.IsActive
+	}
+	if err := config.DB.Save(&slot).Error; err != nil {
+		utils.LogError("Failed to update delivery slot %d: %v", slot.ID, err)
+		utils.InternalServerError(c, "Failed to update delivery slot", err.Error())
+		return
+	}
+
+	utils.LogInfo("Updated delivery slot %d", slot.ID)
+	utils.Success(c, "Delivery slot updated successfully", gin.H{
+		"delivery_slot": slot,
+	})
+}
+
+// DeleteDeliverySlot removes a delivery slot. Orders booked into it keep the date and window
+// they were given.
+func DeleteDeliverySlot(c *gin.Context) {
+	utils.LogInfo("DeleteDeliverySlot called")
+
+	slotID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid delivery slot ID: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeInvalidID, "Invalid delivery slot ID", nil)
+		return
+	}
+
+	result := config.DB.Delete(&models.DeliverySlot{}, slotID)
+	if result.Error != nil {
+		utils.LogError("Failed to delete delivery slot %d: %v", slotID, result.Error)
+		utils.InternalServerError(c, "Failed to delete delivery slot", result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.NotFound(c, "Delivery slot not found")
+		return
+	}
+
+	utils.LogInfo("Deleted delivery slot %d", slotID)
+	utils.Success(c, "Delivery slot deleted successfully", nil)
+}
@@ -0,0 +1,526 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxDisputeEvidenceSize caps one evidence file
+const maxDisputeEvidenceSize = 10 * 1024 * 1024
+
+// disputeEvidenceTypes are the evidence file types accepted, by detected content type, with
+// the extension they are stored under
+var disputeEvidenceTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}
+
+// disputeStatuses are the statuses a dispute can be in
+var disputeStatuses = map[string]bool{
+	models.DisputeStatusOpen:        true,
+	models.DisputeStatusUnderReview: true,
+	models.DisputeStatusWon:         true,
+	models.DisputeStatusLost:        true,
+	models.DisputeStatusClosed:      true,
+}
+
+// disputePhases are the Razorpay dispute phases
+var disputePhases = map[string]bool{
+	"chargeback":      true,
+	"pre_arbitration": true,
+	"arbitration":     true,
+	"retrieval":       true,
+	"fraud":           true,
+}
+
+// disputeResolved reports whether a dispute in status is over
+func disputeResolved(status string) bool {
+	return status == models.DisputeStatusWon || status == models.DisputeStatusLost || status == models.DisputeStatusClosed
+}
+
+// CreateDisputeRequest records a dispute against an order's Razorpay payment. Amount defaults
+// to the amount paid through Razorpay; opened_at defaults to now.
+type CreateDisputeRequest struct {
+	OrderID           uint       `json:"order_id" binding:"required"`
+	RazorpayDisputeID string     `json:"razorpay_dispute_id" binding:"max=64"`
+	Phase             string     `json:"phase"`
+	ReasonCode        string     `json:"reason_code" binding:"max=64"`
+	Reason            string     `json:"reason" binding:"max=2000"`
+	Amount            float64    `json:"amount" binding:"gte=0"`
+	AmountDeducted    float64    `json:"amount_deducted" binding:"gte=0"`
+	RespondBy         *time.Time `json:"respond_by"`
+	OpenedAt          *time.Time `json:"opened_at"`
+	Notes             string     `json:"notes" binding:"max=5000"`
+}
+
+// UpdateDisputeRequest changes a dispute; left out fields keep their value
+type UpdateDisputeRequest struct {
+	Status         string     `json:"status"`
+	Phase          string     `json:"phase"`
+	Amount         *float64   `json:"amount" binding:"omitempty,gte=0"`
+	AmountDeducted *float64   `json:"amount_deducted" binding:"omitempty,gte=0"`
+	RespondBy      *time.Time `json:"respond_by"`
+	Notes          *string    `json:"notes" binding:"omitempty,max=5000"`
+}
+
+// AdminCreateDispute records a chargeback or dispute against an order paid through Razorpay
+func AdminCreateDispute(c *gin.Context) {
+	utils.LogInfo("AdminCreateDispute called")
+
+	var req CreateDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid dispute request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	phase := req.Phase
+	if phase == "" {
+		phase = "chargeback"
+	}
+	if !disputePhases[phase] {
+		utils.BadRequest(c, "Invalid phase", "Phase must be chargeback, pre_arbitration, arbitration, retrieval or fraud")
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Select("id, user_id, razorpay_payment_id, total_with_delivery, wallet_amount").
+		First(&order, req.OrderID).Error; err != nil {
+		utils.LogError("Order not found for dispute: %d: %v", req.OrderID, err)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return
+	}
+	if order.RazorpayPaymentID == "" {
+		utils.Fail(c, utils.CodePaymentMethod, "Only orders paid through Razorpay can be disputed", gin.H{"order_id": order.ID})
+		return
+	}
+	paidOnline := math.Round((order.TotalWithDelivery-order.WalletAmount)*100) / 100
+	amount := req.Amount
+	if amount == 0 {
+		amount = paidOnline
+	}
+	if amount > paidOnline+financeAmountTolerance || req.AmountDeducted > paidOnline+financeAmountTolerance {
+		utils.BadRequest(c, "Dispute amount exceeds the amount paid through Razorpay", gin.H{"paid_online": paidOnline})
+		return
+	}
+
+	openedAt := time.Now()
+	if req.OpenedAt != nil {
+		openedAt = *req.OpenedAt
+	}
+	if req.RespondBy != nil && !req.RespondBy.After(openedAt) {
+		utils.BadRequest(c, "Invalid deadline", "respond_by must be after opened_at")
+		return
+	}
+
+	dispute := models.OrderDispute{
+		OrderID:           order.ID,
+		UserID:            order.UserID,
+		RazorpayPaymentID: order.RazorpayPaymentID,
+		RazorpayDisputeID: strings.TrimSpace(req.RazorpayDisputeID),
+		Phase:             phase,
+		ReasonCode:        strings.TrimSpace(req.ReasonCode),
+		Reason:            strings.TrimSpace(req.Reason),
+		Amount:            amount,
+		AmountDeducted:    req.AmountDeducted,
+		Status:            models.DisputeStatusOpen,
+		RespondBy:         req.RespondBy,
+		OpenedAt:          openedAt,
+		Notes:             req.Notes,
+	}
+	if adminVal, exists := c.Get("admin"); exists {
+		if admin, ok := adminVal.(models.Admin); ok {
+			dispute.AdminID = admin.ID
+		}
+	}
+	if err := config.DB.Create(&dispute).Error; err != nil {
+		utils.LogError("Failed to record dispute for order %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to record dispute", nil)
+		return
+	}
+
+	utils.LogInfo("Recorded %s dispute %d for order %d (%.2f)", dispute.Phase, dispute.ID, order.ID, dispute.Amount)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Created(c, "Dispute recorded successfully", gin.H{
+		"dispute": disputeResponse(formatter, dispute, time.Now()),
+	})
+}
+
+// AdminListDisputes lists disputes, the nearest deadline first. Filter by status, order,
+// due_within_days (unresolved disputes due within that many days) or overdue=true.
+func AdminListDisputes(c *gin.Context) {
+	utils.LogInfo("AdminListDisputes called")
+
+	page, limit := utils.GetPaginationParams(c)
+	now := time.Now()
+	query := config.DB.Model(&models.OrderDispute{})
+	unresolved := []string{models.DisputeStatusOpen, models.DisputeStatusUnderReview}
+
+	if status := c.Query("status"); status != "" {
+		if !disputeStatuses[status] {
+			utils.BadRequest(c, "Invalid status", "Status must be open, under_review, won, lost or closed")
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+	if orderID := c.Query("order_id"); orderID != "" {
+		query = query.Where("order_id = ?", orderID)
+	}
+	if within := c.Query("due_within_days"); within != "" {
+		days, err := strconv.Atoi(within)
+		if err != nil || days < 0 {
+			utils.BadRequest(c, "Invalid due_within_days", "due_within_days must be a whole number of days")
+			return
+		}
+		query = query.Where("status IN ? AND respond_by IS NOT NULL AND respond_by <= ?", unresolved, now.AddDate(0, 0, days))
+	}
+	if c.Query("overdue") == "true" {
+		query = query.Where("status IN ? AND respond_by < ?", unresolved, now)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count disputes: %v", err)
+		utils.InternalServerError(c, "Failed to fetch disputes", nil)
+		return
+	}
+
+	var disputes []models.OrderDispute
+	if err := query.Order("respond_by ASC NULLS LAST, id DESC").Offset((page - 1) * limit).Limit(limit).
+		Find(&disputes).Error; err != nil {
+		utils.LogError("Failed to fetch disputes: %v", err)
+		utils.InternalServerError(c, "Failed to fetch disputes", nil)
+		return
+	}
+
+	response := make([]gin.H, len(disputes))
+	formatter := utils.NewResponseFormatter(c)
+	for i, dispute := range disputes {
+		response[i] = disputeResponse(formatter, dispute, now)
+	}
+
+	utils.LogInfo("Retrieved %d disputes", len(response))
+	utils.SuccessWithPagination(c, "Disputes retrieved successfully", gin.H{
+		"disputes": response,
+		"filters": gin.H{
+			"status":          c.Query("status"),
+			"order_id":        c.Query("order_id"),
+			"due_within_days": c.Query("due_within_days"),
+			"overdue":         c.Query("overdue"),
+		},
+	}, total, page, limit)
+}
+
+// AdminGetDispute returns a dispute with its evidence
+func AdminGetDispute(c *gin.Context) {
+	utils.LogInfo("AdminGetDispute called")
+
+	dispute, ok := loadDispute(c)
+	if !ok {
+		return
+	}
+
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Dispute retrieved successfully", gin.H{
+		"dispute": disputeResponse(formatter, dispute, time.Now()),
+	})
+}
+
+// AdminUpdateDispute moves a dispute along: its status, phase, amounts, deadline or notes.
+// Won, lost and closed disputes are resolved; moving one back to open or under_review
+// reopens it.
+func AdminUpdateDispute(c *gin.Context) {
+	utils.LogInfo("AdminUpdateDispute called")
+
+	dispute, ok := loadDispute(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid dispute update for dispute %d: %v", dispute.ID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	if req.Status != "" && !disputeStatuses[req.Status] {
+		utils.BadRequest(c, "Invalid status", "Status must be open, under_review, won, lost or closed")
+		return
+	}
+	if req.Phase != "" && !disputePhases[req.Phase] {
+		utils.BadRequest(c, "Invalid phase", "Phase must be chargeback, pre_arbitration, arbitration, retrieval or fraud")
+		return
+	}
+	if req.RespondBy != nil && !req.RespondBy.After(dispute.OpenedAt) {
+		utils.BadRequest(c, "Invalid deadline", "respond_by must be after opened_at")
+		return
+	}
+
+	if req.Status != "" && req.Status != dispute.Status {
+		dispute.Status = req.Status
+		if disputeResolved(req.Status) {
+			now := time.Now()
+			dispute.ResolvedAt = &now
+		} else {
+			dispute.ResolvedAt = nil
+		}
+	}
+	if req.Phase != "" {
+		dispute.Phase = req.Phase
+	}
+	if req.Amount != nil {
+		dispute.Amount = *req.Amount
+	}
+	if req.AmountDeducted != nil {
+		dispute.AmountDeducted = *req.AmountDeducted
+	}
+	if req.RespondBy != nil {
+		dispute.RespondBy = req.RespondBy
+	}
+	if req.Notes != nil {
+		dispute.Notes = *req.Notes
+	}
+
+	if err := config.DB.Omit("Evidence").Save(&dispute).Error; err != nil {
+		utils.LogError("Failed to update dispute %d: %v", dispute.ID, err)
+		utils.InternalServerError(c, "Failed to update dispute", nil)
+		return
+	}
+
+	utils.LogInfo("Updated dispute %d, now %s", dispute.ID, dispute.Status)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Dispute updated successfully", gin.H{
+		"dispute": disputeResponse(formatter, dispute, time.Now()),
+	})
+}
+
+// AdminUploadDisputeEvidence attaches an evidence file (PDF, JPEG or PNG, multipart form field
+// "file", with an optional "description") to a dispute
+func AdminUploadDisputeEvidence(c *gin.Context) {
+	utils.LogInfo("AdminUploadDisputeEvidence called")
+
+	dispute, ok := loadDispute(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(maxDisputeEvidenceSize+1024*1024))
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.LogError("Invalid evidence upload for dispute %d: %v", dispute.ID, err)
+		utils.BadRequest(c, "Invalid upload", "Send the evidence as multipart/form-data under the field \"file\"")
+		return
+	}
+	if file.Size > maxDisputeEvidenceSize {
+		utils.BadRequest(c, "Invalid evidence file", "file size exceeds 10MB limit")
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		utils.LogError("Failed to open uploaded evidence %s: %v", file.Filename, err)
+		utils.BadRequest(c, "Invalid evidence file", err.Error())
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(src, maxDisputeEvidenceSize+1))
+	src.Close()
+	if err != nil {
+		utils.LogError("Failed to read uploaded evidence %s: %v", file.Filename, err)
+		utils.InternalServerError(c, "Failed to read uploaded file", err.Error())
+		return
+	}
+	if len(data) > maxDisputeEvidenceSize {
+		utils.BadRequest(c, "Invalid evidence file", "file size exceeds 10MB limit")
+		return
+	}
+	contentType := http.DetectContentType(data)
+	extension, allowed := disputeEvidenceTypes[contentType]
+	if !allowed {
+		utils.LogError("Rejected evidence %s for dispute %d: %s", file.Filename, dispute.ID, contentType)
+		utils.BadRequest(c, "Invalid evidence file", "Evidence must be a PDF, JPEG or PNG file")
+		return
+	}
+
+	storage := utils.GetStorage()
+	key := fmt.Sprintf("disputes/%d/%s%s", dispute.ID, uuid.New().String(), extension)
+	if err := storage.Put(key, data, contentType); err != nil {
+		utils.LogError("Failed to store evidence for dispute %d: %v", dispute.ID, err)
+		utils.InternalServerError(c, "Failed to store evidence", err.Error())
+		return
+	}
+
+	evidence := models.DisputeEvidence{
+		DisputeID:   dispute.ID,
+		FileName:    filepath.Base(file.Filename),
+		StorageKey:  key,
+		ContentType: contentType,
+		SizeBytes:   int64(len(data)),
+		Description: strings.TrimSpace(c.PostForm("description")),
+	}
+	if adminVal, exists := c.Get("admin"); exists {
+		if admin, ok := adminVal.(models.Admin); ok {
+			evidence.AdminID = admin.ID
+		}
+	}
+	if err := config.DB.Create(&evidence).Error; err != nil {
+		cleanupStoredImages(storage, []string{key})
+		utils.LogError("Failed to save evidence for dispute %d: %v", dispute.ID, err)
+		utils.InternalServerError(c, "Failed to save evidence", nil)
+		return
+	}
+
+	utils.LogInfo("Attached %s evidence %d to dispute %d (%d bytes)", contentType, evidence.ID, dispute.ID, len(data))
+	formatter := utils.NewResponseFormatter(c)
+	utils.Created(c, "Evidence uploaded successfully", gin.H{
+		"evidence": disputeEvidenceResponse(formatter, evidence),
+	})
+}
+
+// AdminDownloadDisputeEvidence sends an evidence file
+func AdminDownloadDisputeEvidence(c *gin.Context) {
+	utils.LogInfo("AdminDownloadDisputeEvidence called")
+
+	evidence, ok := loadDisputeEvidence(c)
+	if !ok {
+		return
+	}
+
+	data, err := utils.GetStorage().Get(evidence.StorageKey)
+	if err != nil {
+		utils.LogError("Failed to read evidence %d: %v", evidence.ID, err)
+		utils.InternalServerError(c, "Failed to load evidence", nil)
+		return
+	}
+
+	c.Header("Content-Type", evidence.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", evidence.FileName))
+	c.Header("Cache-Control", "private, no-store")
+	http.ServeContent(c.Writer, c.Request, evidence.FileName, evidence.CreatedAt, bytes.NewReader(data))
+}
+
+// AdminDeleteDisputeEvidence removes an evidence file from a dispute
+func AdminDeleteDisputeEvidence(c *gin.Context) {
+	utils.LogInfo("AdminDeleteDisputeEvidence called")
+
+	evidence, ok := loadDisputeEvidence(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Delete(&evidence).Error; err != nil {
+		utils.LogError("Failed to delete evidence %d: %v", evidence.ID, err)
+		utils.InternalServerError(c, "Failed to delete evidence", nil)
+		return
+	}
+	cleanupStoredImages(utils.GetStorage(), []string{evidence.StorageKey})
+
+	utils.LogInfo("Deleted evidence %d of dispute %d", evidence.ID, evidence.DisputeID)
+	utils.Success(c, "Evidence deleted successfully", nil)
+}
+
+// loadDispute loads the dispute named by :id with its evidence. It writes the error response
+// and returns false when there is no such dispute.
+func loadDispute(c *gin.Context) (models.OrderDispute, bool) {
+	var dispute models.OrderDispute
+	disputeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid dispute ID: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeInvalidID, "Invalid dispute ID", nil)
+		return dispute, false
+	}
+	if err := config.DB.Preload("Evidence", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at, id")
+	}).First(&dispute, disputeID).Error; err != nil {
+		utils.LogError("Dispute not found: %d: %v", disputeID, err)
+		utils.Fail(c, utils.CodeDisputeNotFound, "Dispute not found", nil)
+		return dispute, false
+	}
+	return dispute, true
+}
+
+// loadDisputeEvidence loads the evidence named by :evidenceId of the dispute named by :id. It
+// writes the error response and returns false when there is no such evidence.
+func loadDisputeEvidence(c *gin.Context) (models.DisputeEvidence, bool) {
+	var evidence models.DisputeEvidence
+	disputeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Fail(c, utils.CodeInvalidID, "Invalid dispute ID", nil)
+		return evidence, false
+	}
+	evidenceID, err := strconv.ParseUint(c.Param("evidenceId"), 10, 32)
+	if err != nil {
+		utils.Fail(c, utils.CodeInvalidID, "Invalid evidence ID", nil)
+		return evidence, false
+	}
+	if err := config.DB.Where("id = ? AND dispute_id = ?", evidenceID, disputeID).First(&evidence).Error; err != nil {
+		utils.LogError("Evidence %d of dispute %d not found: %v", evidenceID, disputeID, err)
+		utils.NotFound(c, "Evidence not found")
+		return evidence, false
+	}
+	return evidence, true
+}
+
+// disputeResponse is the admin view of a dispute, with how long is left to respond
+func disputeResponse(f utils.ResponseFormatter, dispute models.OrderDispute, now time.Time) gin.H {
+	evidence := make([]gin.H, len(dispute.Evidence))
+	for i, item := range dispute.Evidence {
+		evidence[i] = disputeEvidenceResponse(f, item)
+	}
+	response := gin.H{
+		"id":                  dispute.ID,
+		"order_id":            dispute.OrderID,
+		"user_id":             dispute.UserID,
+		"razorpay_payment_id": dispute.RazorpayPaymentID,
+		"razorpay_dispute_id": dispute.RazorpayDisputeID,
+		"phase":               dispute.Phase,
+		"reason_code":         dispute.ReasonCode,
+		"reason":              dispute.Reason,
+		"amount":              f.Money(dispute.Amount),
+		"amount_deducted":     f.Money(dispute.AmountDeducted),
+		"status":              dispute.Status,
+		"notes":               dispute.Notes,
+		"admin_id":            dispute.AdminID,
+		"evidence":            evidence,
+		"opened_at":           f.Timestamp(dispute.OpenedAt),
+		"created_at":          f.Timestamp(dispute.CreatedAt),
+		"updated_at":          f.Timestamp(dispute.UpdatedAt),
+	}
+	if dispute.RespondBy != nil {
+		response["respond_by"] = f.Timestamp(*dispute.RespondBy)
+		if !disputeResolved(dispute.Status) {
+			response["days_to_respond"] = int(math.Floor(dispute.RespondBy.Sub(now).Hours() / 24))
+			response["overdue"] = dispute.RespondBy.Before(now)
+		}
+	}
+	if dispute.ResolvedAt != nil {
+		response["resolved_at"] = f.Timestamp(*dispute.ResolvedAt)
+	}
+	return response
+}
+
+// disputeEvidenceResponse is the API view of an evidence file
+func disputeEvidenceResponse(f utils.ResponseFormatter, evidence models.DisputeEvidence) gin.H {
+	return gin.H{
+		"id":           evidence.ID,
+		"file_name":    evidence.FileName,
+		"content_type": evidence.ContentType,
+		"size_bytes":   evidence.SizeBytes,
+		"description":  evidence.Description,
+		"admin_id":     evidence.AdminID,
+		"download_url": fmt.Sprintf("/v1/admin/disputes/%d/evidence/%d", evidence.DisputeID, evidence.ID),
+		"created_at":   f.Timestamp(evidence.CreatedAt),
+	}
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetEmailTemplates lists the email templates that can be previewed
+func GetEmailTemplates(c *gin.Context) {
+	utils.LogInfo("GetEmailTemplates called")
+
+	utils.Success(c, "Email templates retrieved successfully", gin.H{
+		"templates": utils.EmailTemplateNames(),
+		"branding":  utils.GetEmailBranding(),
+	})
+}
+
+// PreviewEmailTemplate renders a template with sample data as the HTML page an email client
+// would show
+func PreviewEmailTemplate(c *gin.Context) {
+	utils.LogInfo("PreviewEmailTemplate called")
+
+	name := c.Param("name")
+	body, err := utils.EmailPreview(name)
+	if errors.Is(err, utils.ErrEmailTemplateNotFound) {
+		utils.NotFound(c, "Email template not found")
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to render email template %s: %v", name, err)
+		utils.InternalServerError(c, "Failed to render email template", err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(body))
+}
@@ -0,0 +1,521 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// financeReportMaxDays caps the date range of one finance report
+const financeReportMaxDays = 366
+
+// financeAmountTolerance is how far two amounts may differ before they count as a mismatch
+const financeAmountTolerance = 0.01
+
+// financeEntry is one money movement in the finance report
+type financeEntry struct {
+	Date      time.Time `json:"date"`
+	Source    string    `json:"source"`   // razorpay, cod, wallet
+	Category  string    `json:"category"` // payment, collection, order_payment, refund, cashback, topup, adjustment, gift_card, dispute, dispute_reversal, other
+	Direction string    `json:"direction"`
+	OrderID   *uint     `json:"order_id,omitempty"`
+	UserID    uint      `json:"user_id"`
+	Reference string    `json:"reference,omitempty"`
+	Amount    float64   `json:"amount"`
+	AdminID   *uint     `json:"admin_id,omitempty"` // admin who posted a manual adjustment
+}
+
+// financeMismatch is an order whose payment or refund records do not agree
+type financeMismatch struct {
+	OrderID  uint    `json:"order_id"`
+	UserID   uint    `json:"user_id"`
+	Type     string  `json:"type"`
+	Message  string  `json:"message"`
+	Expected float64 `json:"expected"`
+	Recorded float64 `json:"recorded"`
+}
+
+// walletEntryCategory classifies a wallet transaction by the reference it was posted with
+func walletEntryCategory(reference string) string {
+	switch {
+	case strings.HasPrefix(reference, "REFUND-"):
+		return "refund"
+	case strings.HasPrefix(reference, "ORDER-"):
+		return "order_payment"
+	case strings.HasPrefix(reference, "CASHBACK-"):
+		return "cashback"
+	case strings.HasPrefix(reference, "TOPUP-"):
+		return "topup"
+	case strings.HasPrefix(reference, "ADJUST-"):
+		return "adjustment"
+	case strings.HasPrefix(reference, "GIFTCARD-"):
+		return "gift_card"
+	}
+	return "other"
+}
+
+// Admin: Finance report. Lists the Razorpay payments and disputes, Cash on Delivery collections
+// and wallet movements of a date range, and reconciles the orders placed in it: recorded refunds
+// must match the refunds credited to wallets, and paid orders must have their payment on record.
+func GetFinanceReport(c *gin.Context) {
+	utils.LogInfo("GetFinanceReport called")
+
+	// Default to the last 30 days including today
+	now := time.Now()
+	endDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Add(24 * time.Hour)
+	startDate := endDate.AddDate(0, 0, -30)
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			utils.LogError("Invalid start date format: %v", err)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid start date", "Start date must be in YYYY-MM-DD format")
+			return
+		}
+		startDate = parsed
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			utils.LogError("Invalid end date format: %v", err)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid end date", "End date must be in YYYY-MM-DD format")
+			return
+		}
+		// Include the entire end date
+		endDate = parsed.Add(24 * time.Hour)
+	}
+	if !endDate.After(startDate) {
+		utils.LogError("Invalid date range: %s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+		utils.Fail(c, utils.CodeInvalidDate, "Invalid date range", "End date must be after start date")
+		return
+	}
+	if endDate.Sub(startDate) > financeReportMaxDays*24*time.Hour {
+		utils.LogError("Finance report range exceeds %d days", financeReportMaxDays)
+		utils.Fail(c, utils.CodeInvalidDate, "Invalid date range", fmt.Sprintf("Date range cannot exceed %d days", financeReportMaxDays))
+		return
+	}
+
+	entries, err := financeEntries(config.DB, startDate, endDate)
+	if err != nil {
+		utils.LogError("Failed to fetch finance entries: %v", err)
+		utils.InternalServerError(c, "Failed to generate finance report", err.Error())
+		return
+	}
+	mismatches, err := financeMismatches(config.DB, startDate, endDate)
+	if err != nil {
+		utils.LogError("Failed to reconcile orders: %v", err)
+		utils.InternalServerError(c, "Failed to generate finance report", err.Error())
+		return
+	}
+	utils.LogDebug("Finance report has %d entries and %d mismatches", len(entries), len(mismatches))
+
+	if utils.WantsCSV(c) {
+		headers := []string{"Date", "Source", "Category", "Direction", "Order ID", "User ID", "Reference", "Amount", "Admin ID", "Issue"}
+		issues := make(map[uint][]string)
+		for _, m := range mismatches {
+			issues[m.OrderID] = append(issues[m.OrderID], m.Type)
+		}
+		rows := make([][]string, 0, len(entries)+len(mismatches))
+		for _, entry := range entries {
+			orderID, adminID, issue := "", "", ""
+			if entry.OrderID != nil {
+				orderID = strconv.FormatUint(uint64(*entry.OrderID), 10)
+				issue = strings.Join(issues[*entry.OrderID], "; ")
+			}
+			if entry.AdminID != nil {
+				adminID = strconv.FormatUint(uint64(*entry.AdminID), 10)
+			}
+			rows = append(rows, []string{
+				entry.Date.Format("2006-01-02 15:04:05"),
+				entry.Source,
+				entry.Category,
+				entry.Direction,
+				orderID,
+				strconv.FormatUint(uint64(entry.UserID), 10),
+				entry.Reference,
+				fmt.Sprintf("%.2f", entry.Amount),
+				adminID,
+				issue,
+			})
+		}
+		// Mismatches follow the entries so they show up even when the order had no movement
+		for _, m := range mismatches {
+			rows = append(rows, []string{
+				"", "mismatch", m.Type, "",
+				strconv.FormatUint(uint64(m.OrderID), 10),
+				strconv.FormatUint(uint64(m.UserID), 10),
+				"",
+				fmt.Sprintf("%.2f", m.Recorded-m.Expected),
+				"",
+				m.Message,
+			})
+		}
+		if err := utils.WriteCSV(c, "finance_report", headers, rows); err != nil {
+			utils.LogError("Failed to write CSV file: %v", err)
+			utils.InternalServerError(c, "Failed to write CSV file", err.Error())
+		}
+		return
+	}
+
+	totals := make(map[string]float64)
+	for _, entry := range entries {
+		totals[entry.Source+"_"+entry.Direction] += entry.Amount
+	}
+	var refunded float64
+	adjustments := make(map[string]float64)
+	for _, entry := range entries {
+		switch entry.Category {
+		case "refund":
+			refunded += entry.Amount
+		case "adjustment":
+			adjustments[entry.Direction] += entry.Amount
+		}
+	}
+
+	disputes, err := financeDisputeSummary(config.DB, startDate, endDate)
+	if err != nil {
+		utils.LogError("Failed to summarize disputes: %v", err)
+		utils.InternalServerError(c, "Failed to generate finance report", err.Error())
+		return
+	}
+
+	utils.LogInfo("Generated finance report with %d entries and %d mismatches", len(entries), len(mismatches))
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Finance report generated successfully", gin.H{
+		"period": gin.H{
+			"start_date": formatter.Timestamp(startDate),
+			"end_date":   formatter.Timestamp(endDate),
+		},
+		"summary": gin.H{
+			"razorpay_collected":  formatter.Money(totals["razorpay_in"]),
+			"cod_collected":       formatter.Money(totals["cod_in"]),
+			"wallet_credits":      formatter.Money(totals["wallet_out"]),
+			"wallet_debits":       formatter.Money(totals["wallet_in"]),
+			"refunds_to_wallet":   formatter.Money(refunded),
+			"adjustment_credits":  formatter.Money(adjustments["out"]),
+			"adjustment_debits":   formatter.Money(adjustments["in"]),
+			"disputed_amount":     formatter.Money(disputes.Disputed),
+			"dispute_won_amount":  formatter.Money(disputes.Won),
+			"dispute_lost_amount": formatter.Money(disputes.Lost),
+			"open_disputes":       disputes.Open,
+			"entry_count":         len(entries),
+			"mismatch_count":      len(mismatches),
+		},
+		"mismatches": mismatches,
+		"entries":    financeEntriesResponse(formatter, entries),
+	})
+}
+
+// financeEntriesResponse formats the money movements for the response
+func financeEntriesResponse(f utils.ResponseFormatter, entries []financeEntry) []gin.H {
+	response := make([]gin.H, 0, len(entries))
+	for _, entry := range entries {
+		item := gin.H{
+			"date":      f.Timestamp(entry.Date),
+			"source":    entry.Source,
+			"category":  entry.Category,
+			"direction": entry.Direction,
+			"user_id":   entry.UserID,
+			"amount":    f.Money(entry.Amount),
+		}
+		if entry.OrderID != nil {
+			item["order_id"] = *entry.OrderID
+		}
+		if entry.Reference != "" {
+			item["reference"] = entry.Reference
+		}
+		if entry.AdminID != nil {
+			item["admin_id"] = *entry.AdminID
+		}
+		response = append(response, item)
+	}
+	return response
+}
+
+// financeEntries returns the money movements of the range, oldest first. Direction is seen
+// from the store: a wallet debit pays the store ("in"), a wallet credit is owed to the
+// customer ("out").
+func financeEntries(db *gorm.DB, startDate, endDate time.Time) ([]financeEntry, error) {
+	var entries []financeEntry
+
+	// Razorpay payments, dated by the order they paid for. The wallet part of a split payment
+	// is a wallet posting.
+	var paid []models.Order
+	if err := db.Select("id, user_id, created_at, total_with_delivery, wallet_amount, razorpay_payment_id").
+		Where("created_at >= ? AND created_at < ?", startDate, endDate).
+		Where("payment_status = ? AND razorpay_payment_id <> ''", models.PaymentStatusCompleted).
+		Find(&paid).Error; err != nil {
+		return nil, err
+	}
+	for _, order := range paid {
+		orderID := order.ID
+		entries = append(entries, financeEntry{
+			Date: order.CreatedAt, Source: "razorpay", Category: "payment", Direction: "in",
+			OrderID: &orderID, UserID: order.UserID, Reference: order.RazorpayPaymentID, Amount: order.TotalWithDelivery - order.WalletAmount,
+		})
+	}
+
+	// Gift cards paid online, dated by their purchase
+	var giftCards []models.GiftCard
+	if err := db.Select("id, purchaser_id, created_at, amount, razorpay_payment_id").
+		Where("created_at >= ? AND created_at < ?", startDate, endDate).
+		Where("razorpay_payment_id <> ''").
+		Find(&giftCards).Error; err != nil {
+		return nil, err
+	}
+	for _, card := range giftCards {
+		var userID uint
+		if card.PurchaserID != nil {
+			userID = *card.PurchaserID
+		}
+		entries = append(entries, financeEntry{
+			Date: card.CreatedAt, Source: "razorpay", Category: "gift_card", Direction: "in",
+			UserID: userID, Reference: card.RazorpayPaymentID, Amount: card.Amount,
+		})
+	}
+
+	// Cash on Delivery is collected on delivery
+	var delivered []models.Order
+	if err := db.Select("id, user_id, delivered_at, total_with_delivery, delivery_reference").
+		Where("LOWER(payment_method) = ? AND delivered_at >= ? AND delivered_at < ?", "cod", startDate, endDate).
+		Find(&delivered).Error; err != nil {
+		return nil, err
+	}
+	for _, order := range delivered {
+		orderID := order.ID
+		entries = append(entries, financeEntry{
+			Date: *order.DeliveredAt, Source: "cod", Category: "collection", Direction: "in",
+			OrderID: &orderID, UserID: order.UserID, Reference: order.DeliveryReference, Amount: order.TotalWithDelivery,
+		})
+	}
+
+	// Completed wallet postings
+	var walletRows []struct {
+		models.WalletTransaction
+		UserID uint
+	}
+	if err := db.Table("wallet_transactions").
+		Select("wallet_transactions.*, wallets.user_id").
+		Joins("JOIN wallets ON wallets.id = wallet_transactions.wallet_id").
+		Where("wallet_transactions.deleted_at IS NULL AND wallet_transactions.status = ?", models.TransactionStatusCompleted).
+		Where("wallet_transactions.created_at >= ? AND wallet_transactions.created_at < ?", startDate, endDate).
+		Scan(&walletRows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range walletRows {
+		direction := "out"
+		if row.Type == models.TransactionTypeDebit {
+			direction = "in"
+		}
+		entries = append(entries, financeEntry{
+			Date: row.CreatedAt, Source: "wallet", Category: walletEntryCategory(row.Reference), Direction: direction,
+			OrderID: row.OrderID, UserID: row.UserID, Reference: row.Reference, Amount: row.Amount,
+			AdminID: row.AdminID,
+		})
+	}
+
+	// Disputed Razorpay payments go out when the dispute opens and come back when it is won;
+	// a lost dispute stays out
+	var disputes []models.OrderDispute
+	if err := db.Where("(opened_at >= ? AND opened_at < ?) OR (status = ? AND resolved_at >= ? AND resolved_at < ?)",
+		startDate, endDate, models.DisputeStatusWon, startDate, endDate).
+		Find(&disputes).Error; err != nil {
+		return nil, err
+	}
+	for _, dispute := range disputes {
+		orderID := dispute.OrderID
+		if !dispute.OpenedAt.Before(startDate) && dispute.OpenedAt.Before(endDate) {
+			entries = append(entries, financeEntry{
+				Date: dispute.OpenedAt, Source: "razorpay", Category: "dispute", Direction: "out",
+				OrderID: &orderID, UserID: dispute.UserID, Reference: disputeReference(dispute), Amount: dispute.Amount,
+			})
+		}
+		if dispute.Status == models.DisputeStatusWon && dispute.ResolvedAt != nil &&
+			!dispute.ResolvedAt.Before(startDate) && dispute.ResolvedAt.Before(endDate) {
+			entries = append(entries, financeEntry{
+				Date: *dispute.ResolvedAt, Source: "razorpay", Category: "dispute_reversal", Direction: "in",
+				OrderID: &orderID, UserID: dispute.UserID, Reference: disputeReference(dispute), Amount: dispute.Amount,
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.Before(entries[j].Date)
+	})
+	return entries, nil
+}
+
+// disputeReference is the reference a dispute is listed under: its Razorpay dispute ID, or the
+// payment it disputes when the ID was not recorded
+func disputeReference(dispute models.OrderDispute) string {
+	if dispute.RazorpayDisputeID != "" {
+		return dispute.RazorpayDisputeID
+	}
+	return dispute.RazorpayPaymentID
+}
+
+// financeDisputes sums up the disputes of a finance report: the amount disputed in the range,
+// the amount won back and lost in it, and the disputes still open now
+type financeDisputes struct {
+	Disputed float64
+	Won      float64
+	Lost     float64
+	Open     int64
+}
+
+// financeDisputeSummary sums up the disputes opened or resolved in the range
+func financeDisputeSummary(db *gorm.DB, startDate, endDate time.Time) (financeDisputes, error) {
+	var summary financeDisputes
+	if err := db.Model(&models.OrderDispute{}).Select("COALESCE(SUM(amount), 0)").
+		Where("opened_at >= ? AND opened_at < ?", startDate, endDate).
+		Scan(&summary.Disputed).Error; err != nil {
+		return summary, err
+	}
+	var resolved []struct {
+		Status string
+		Amount float64
+	}
+	if err := db.Model(&models.OrderDispute{}).Select("status, SUM(amount) AS amount").
+		Where("status IN ? AND resolved_at >= ? AND resolved_at < ?",
+			[]string{models.DisputeStatusWon, models.DisputeStatusLost}, startDate, endDate).
+		Group("status").Scan(&resolved).Error; err != nil {
+		return summary, err
+	}
+	for _, row := range resolved {
+		if row.Status == models.DisputeStatusWon {
+			summary.Won = row.Amount
+		} else {
+			summary.Lost = row.Amount
+		}
+	}
+	if err := db.Model(&models.OrderDispute{}).
+		Where("status IN ?", []string{models.DisputeStatusOpen, models.DisputeStatusUnderReview}).
+		Count(&summary.Open).Error; err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+// financeMismatches reconciles the orders placed in the range against their payment and
+// wallet records, wherever those fall
+func financeMismatches(db *gorm.DB, startDate, endDate time.Time) ([]financeMismatch, error) {
+	var orders []models.Order
+	if err := db.Select("id, user_id, status, payment_method, payment_status, razorpay_payment_id, "+
+		"total_with_delivery, wallet_amount, refund_status, refund_amount").
+		Where("created_at >= ? AND created_at < ?", startDate, endDate).
+		Order("id").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return []financeMismatch{}, nil
+	}
+	inRange := db.Model(&models.Order{}).Select("id").Where("created_at >= ? AND created_at < ?", startDate, endDate)
+
+	// Refunds recorded on the items of the orders
+	var itemRefunds []struct {
+		OrderID uint
+		Amount  float64
+	}
+	if err := db.Model(&models.OrderItem{}).Select("order_id, SUM(refund_amount) AS amount").
+		Where("refund_status = ? AND order_id IN (?)", "completed", inRange).
+		Group("order_id").Scan(&itemRefunds).Error; err != nil {
+		return nil, err
+	}
+	recordedRefunds := make(map[uint]float64)
+	for _, row := range itemRefunds {
+		recordedRefunds[row.OrderID] += row.Amount
+	}
+
+	// Wallet refund credits and order payment debits of the orders
+	var postings []models.WalletTransaction
+	if err := db.Select("order_id, type, amount, reference").
+		Where("status = ? AND order_id IN (?)", models.TransactionStatusCompleted, inRange).
+		Find(&postings).Error; err != nil {
+		return nil, err
+	}
+	walletRefunds := make(map[uint]float64)
+	walletPayments := make(map[uint]float64)
+	for _, posting := range postings {
+		switch category := walletEntryCategory(posting.Reference); {
+		case category == "refund" && posting.Type == models.TransactionTypeCredit:
+			walletRefunds[*posting.OrderID] += posting.Amount
+		case category == "order_payment" && posting.Type == models.TransactionTypeDebit:
+			walletPayments[*posting.OrderID] += posting.Amount
+		}
+	}
+
+	// Disputes the store lost; the customer got their money back from the bank
+	var lostDisputes []struct {
+		OrderID uint
+		Amount  float64
+	}
+	if err := db.Model(&models.OrderDispute{}).Select("order_id, SUM(amount) AS amount").
+		Where("status = ? AND order_id IN (?)", models.DisputeStatusLost, inRange).
+		Group("order_id").Scan(&lostDisputes).Error; err != nil {
+		return nil, err
+	}
+	chargedBack := make(map[uint]float64)
+	for _, row := range lostDisputes {
+		chargedBack[row.OrderID] = row.Amount
+	}
+
+	mismatches := []financeMismatch{}
+	differs := func(a, b float64) bool { return math.Abs(a-b) > financeAmountTolerance }
+	for _, order := range orders {
+		add := func(kind, message string, expected, recorded float64) {
+			mismatches = append(mismatches, financeMismatch{
+				OrderID: order.ID, UserID: order.UserID, Type: kind, Message: message,
+				Expected: math.Round(expected*100) / 100, Recorded: math.Round(recorded*100) / 100,
+			})
+		}
+
+		recorded := recordedRefunds[order.ID]
+		if order.RefundStatus == "completed" {
+			recorded += order.RefundAmount
+		}
+		credited := walletRefunds[order.ID]
+		refundedStatus := order.Status == models.OrderStatusRefunded || order.Status == models.OrderStatusReturnCompleted
+		switch {
+		case recorded > 0 && credited == 0:
+			add("refund_without_record", "Order is marked refunded but no refund was credited to the wallet", recorded, 0)
+		case recorded == 0 && credited > 0:
+			add("refund_not_recorded", "A refund was credited to the wallet but the order records no refund", 0, credited)
+		case recorded > 0 && differs(recorded, credited):
+			add("refund_amount_mismatch", "Refund recorded on the order differs from the refund credited to the wallet", recorded, credited)
+		case recorded == 0 && refundedStatus:
+			add("refund_without_record", fmt.Sprintf("Order is %s but no refund is recorded", order.Status), 0, 0)
+		}
+
+		if lost := chargedBack[order.ID]; lost > 0 && recorded+credited > 0 {
+			add("refunded_and_charged_back", "Order was refunded and also lost a dispute for its payment", lost, math.Max(recorded, credited))
+		}
+
+		switch utils.NormalizePaymentMethod(order.PaymentMethod) {
+		case "online":
+			if order.PaymentStatus == models.PaymentStatusCompleted && order.RazorpayPaymentID == "" {
+				add("payment_without_record", "Order is marked paid but has no Razorpay payment ID", order.TotalWithDelivery, 0)
+			}
+			if order.Status == models.OrderStatusPaid && order.PaymentStatus != models.PaymentStatusCompleted {
+				add("payment_not_completed", "Order is marked Paid but its online payment is not completed", order.TotalWithDelivery, 0)
+			}
+			if order.WalletAmount > 0 && order.PaymentStatus == models.PaymentStatusCompleted {
+				if paidFromWallet := walletPayments[order.ID]; differs(paidFromWallet, order.WalletAmount) {
+					add("wallet_payment_mismatch", "Wallet debit for the order differs from the wallet part of its payment", order.WalletAmount, paidFromWallet)
+				}
+			}
+		case "wallet":
+			if paidFromWallet := walletPayments[order.ID]; differs(paidFromWallet, order.TotalWithDelivery) {
+				add("wallet_payment_mismatch", "Wallet debit for the order differs from its payable total", order.TotalWithDelivery, paidFromWallet)
+			}
+		}
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// IssueGiftCardRequest is a gift card issued by an admin without payment
+type IssueGiftCardRequest struct {
+	Amount         float64 `json:"amount" binding:"required,gt=0"`
+	RecipientEmail string  `json:"recipient_email" binding:"required,email"`
+	RecipientName  string  `json:"recipient_name" binding:"max=100"`
+	Message        string  `json:"message" binding:"max=250"`
+	ValidityDays   int     `json:"validity_days" binding:"omitempty,gte=1,lte=3650"` // defaults to the gift_card_validity_days setting
+}
+
+// VoidGiftCardRequest gives the reason a gift card is voided
+type VoidGiftCardRequest struct {
+	Reason string `json:"reason" binding:"required,min=3,max=500"`
+}
+
+// adminGiftCardResponse adds the purchase, issue and void details admins see
+func adminGiftCardResponse(f utils.ResponseFormatter, card models.GiftCard) gin.H {
+	response := giftCardResponse(f, card)
+	response["purchaser_id"] = card.PurchaserID
+	response["issued_by"] = card.IssuedBy
+	response["redeemed_by"] = card.RedeemedBy
+	response["razorpay_payment_id"] = card.RazorpayPaymentID
+	if card.VoidedAt != nil {
+		response["voided_by"] = card.VoidedBy
+		response["voided_at"] = f.Timestamp(*card.VoidedAt)
+		response["void_reason"] = card.VoidReason
+	}
+	return response
+}
+
+// AdminListGiftCards lists gift cards, filtered by status, code or recipient email
+func AdminListGiftCards(c *gin.Context) {
+	utils.LogInfo("AdminListGiftCards called")
+
+	page, limit := utils.GetPaginationParams(c)
+	query := config.DB.Model(&models.GiftCard{})
+
+	switch status := c.Query("status"); status {
+	case "":
+	case models.GiftCardStatusActive:
+		query = query.Where("status = ? AND expires_at >= ?", status, time.Now())
+	case models.GiftCardStatusExpired:
+		query = query.Where("status = ? OR (status = ? AND expires_at < ?)", status, models.GiftCardStatusActive, time.Now())
+	default:
+		query = query.Where("status = ?", status)
+	}
+	if code := c.Query("code"); code != "" {
+		query = query.Where("code = ?", utils.NormalizeGiftCardCode(code))
+	}
+	if email := c.Query("recipient_email"); email != "" {
+		query = query.Where("LOWER(recipient_email) = ?", strings.ToLower(strings.TrimSpace(email)))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count gift cards: %v", err)
+		utils.InternalServerError(c, "Failed to fetch gift cards", err.Error())
+		return
+	}
+	var cards []models.GiftCard
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&cards).Error; err != nil {
+		utils.LogError("Failed to fetch gift cards: %v", err)
+		utils.InternalServerError(c, "Failed to fetch gift cards", err.Error())
+		return
+	}
+
+	list := make([]gin.H, len(cards))
+	formatter := utils.NewResponseFormatter(c)
+	for i, card := range cards {
+		list[i] = adminGiftCardResponse(formatter, card)
+	}
+
+	utils.LogInfo("Retrieved %d gift cards", len(list))
+	utils.SuccessWithPagination(c, "Gift cards retrieved successfully", gin.H{
+		"gift_cards": list,
+		"filters": gin.H{
+			"status":          c.Query("status"),
+			"code":            c.Query("code"),
+			"recipient_email": c.Query("recipient_email"),
+		},
+	}, total, page, limit)
+}
+
+// AdminIssueGiftCard issues an active gift card, for example as a prize or apology, and emails
+// it to the recipient
+func AdminIssueGiftCard(c *gin.Context) {
+	utils.LogInfo("AdminIssueGiftCard called")
+
+	adminVal, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	admin := adminVal.(models.Admin)
+
+	var req IssueGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+	if maxAmount := utils.GiftCardMaxAmount(); req.Amount > maxAmount {
+		utils.LogError("Gift card amount %.2f above the maximum %.2f", req.Amount, maxAmount)
+		utils.BadRequest(c, fmt.Sprintf("A gift card can be worth at most %s", utils.FormatBaseMoney(maxAmount)), nil)
+		return
+	}
+
+	card, err := newGiftCard(req.Amount, req.RecipientEmail, req.RecipientName, req.Message)
+	if err != nil {
+		utils.LogError("Failed to generate gift card code: %v", err)
+		utils.InternalServerError(c, "Failed to create gift card", err.Error())
+		return
+	}
+	if req.ValidityDays > 0 {
+		card.ExpiresAt = time.Now().AddDate(0, 0, req.ValidityDays)
+	}
+	card.Status = models.GiftCardStatusActive
+	card.PaymentMethod = "admin"
+	card.IssuedBy = &admin.ID
+	if err := config.DB.Create(&card).Error; err != nil {
+		utils.LogError("Failed to create gift card: %v", err)
+		utils.InternalServerError(c, "Failed to create gift card", err.Error())
+		return
+	}
+
+	emailed := sendGiftCard(card, "ReadSphere")
+	utils.LogInfo("Admin %s issued gift card ID: %d worth %.2f to %s", admin.Email, card.ID, card.Amount, card.RecipientEmail)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Gift card issued successfully", gin.H{
+		"gift_card":  adminGiftCardResponse(formatter, card),
+		"email_sent": emailed,
+	})
+}
+
+// AdminVoidGiftCard voids a gift card that has not been redeemed so it can no longer be used.
+// Voiding does not refund the purchaser; refunds are made as wallet adjustments.
+func AdminVoidGiftCard(c *gin.Context) {
+	utils.LogInfo("AdminVoidGiftCard called")
+
+	adminVal, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	admin := adminVal.(models.Admin)
+
+	cardID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid gift card ID format: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid gift card ID", nil)
+		return
+	}
+	var req VoidGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeReasonRequired, "A reason is required to void a gift card", err)
+		return
+	}
+
+	var card models.GiftCard
+	if err := config.DB.First(&card, cardID).Error; err != nil {
+		utils.LogError("Gift card not found - ID: %d: %v", cardID, err)
+		utils.Fail(c, utils.CodeGiftCardInvalid, "Gift card not found", nil)
+		return
+	}
+
+	// Only a card nobody has redeemed can be voided; the status check guards a concurrent redemption
+	now := time.Now()
+	result := config.DB.Model(&models.GiftCard{}).
+		Where("id = ? AND status IN ?", card.ID, []string{models.GiftCardStatusPending, models.GiftCardStatusActive, models.GiftCardStatusExpired}).
+		Updates(map[string]interface{}{
+			"status":      models.GiftCardStatusVoided,
+			"voided_by":   admin.ID,
+			"voided_at":   now,
+			"void_reason": strings.TrimSpace(req.Reason),
+		})
+	if result.Error != nil {
+		utils.LogError("Failed to void gift card ID: %d: %v", card.ID, result.Error)
+		utils.InternalServerError(c, "Failed to void gift card", result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.LogError("Gift card ID: %d cannot be voided, status: %s", card.ID, card.Status)
+		utils.Fail(c, utils.CodeGiftCardUnavailable, fmt.Sprintf("A %s gift card cannot be voided", card.Status), nil)
+		return
+	}
+	card.Status = models.GiftCardStatusVoided
+	card.VoidedBy = &admin.ID
+	card.VoidedAt = &now
+	card.VoidReason = strings.TrimSpace(req.Reason)
+
+	utils.LogInfo("Admin %s voided gift card ID: %d", admin.Email, card.ID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Gift card voided successfully", gin.H{
+		"gift_card": adminGiftCardResponse(formatter, card),
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // AdminListProductsWithStock shows products with stock, filter, sort, pagination
@@ -63,13 +64,13 @@ func AdminListProductsWithStock(c *gin.Context) {
 func AdminBlockProduct(c *gin.Context) {
 	productID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		utils.BadRequest(c, "Invalid product ID", err.Error())
+		utils.Fail(c, utils.CodeInvalidID, "Invalid product ID", err.Error())
 		return
 	}
 
 	var book models.Book
 	if err := config.DB.First(&book, productID).Error; err != nil {
-		utils.NotFound(c, "Product not found")
+		utils.Fail(c, utils.CodeBookNotFound, "Product not found", nil)
 		return
 	}
 
@@ -88,13 +89,13 @@ func AdminBlockProduct(c *gin.Context) {
 func AdminUnblockProduct(c *gin.Context) {
 	productID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		utils.BadRequest(c, "Invalid product ID", err.Error())
+		utils.Fail(c, utils.CodeInvalidID, "Invalid product ID", err.Error())
 		return
 	}
 
 	var book models.Book
 	if err := config.DB.First(&book, productID).Error; err != nil {
-		utils.NotFound(c, "Product not found")
+		utils.Fail(c, utils.CodeBookNotFound, "Product not found", nil)
 		return
 	}
 
@@ -113,13 +114,13 @@ func AdminUnblockProduct(c *gin.Context) {
 func AdminBlockCategory(c *gin.Context) {
 	catID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		utils.BadRequest(c, "Invalid category ID", err.Error())
+		utils.Fail(c, utils.CodeInvalidID, "Invalid category ID", err.Error())
 		return
 	}
 
 	var cat models.Category
 	if err := config.DB.First(&cat, catID).Error; err != nil {
-		utils.NotFound(c, "Category not found")
+		utils.Fail(c, utils.CodeCategoryNotFound, "Category not found", nil)
 		return
 	}
 
@@ -138,13 +139,13 @@ func AdminBlockCategory(c *gin.Context) {
 func AdminUnblockCategory(c *gin.Context) {
 	catID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		utils.BadRequest(c, "Invalid category ID", err.Error())
+		utils.Fail(c, utils.CodeInvalidID, "Invalid category ID", err.Error())
 		return
 	}
 
 	var cat models.Category
 	if err := config.DB.First(&cat, catID).Error; err != nil {
-		utils.NotFound(c, "Category not found")
+		utils.Fail(c, utils.CodeCategoryNotFound, "Category not found", nil)
 		return
 	}
 
@@ -158,3 +159,79 @@ func AdminUnblockCategory(c *gin.Context) {
 		"category": cat,
 	})
 }
+
+// AdminRestockBook adds stock to a book and emails users waiting for it to be back in stock
+func AdminRestockBook(c *gin.Context) {
+	utils.LogInfo("AdminRestockBook called")
+
+	bookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Fail(c, utils.CodeInvalidID, "Invalid product ID", err.Error())
+		return
+	}
+
+	var req struct {
+		Quantity int `json:"quantity" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid restock request for book ID: %d: %v", bookID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+
+	var book models.Book
+	if err := config.DB.First(&book, bookID).Error; err != nil {
+		utils.Fail(c, utils.CodeBookNotFound, "Product not found", nil)
+		return
+	}
+	previousStock := book.Stock
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to begin transaction: %v", tx.Error)
+		utils.InternalServerError(c, "Failed to begin transaction", nil)
+		return
+	}
+	if err := tx.Model(&book).UpdateColumn("stock", gorm.Expr("stock + ?", req.Quantity)).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to restock book ID: %d: %v", bookID, err)
+		utils.InternalServerError(c, "Failed to restock product", err.Error())
+		return
+	}
+	if err := recordAdminStockMovement(c, tx, utils.StockChange{
+		BookID: book.ID,
+		Delta:  req.Quantity,
+		Source: models.StockSourceAdmin,
+		Note:   "Restock",
+	}); err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to record stock movement of book ID: %d: %v", bookID, err)
+		utils.InternalServerError(c, "Failed to restock product", err.Error())
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit restock of book ID: %d: %v", bookID, err)
+		utils.InternalServerError(c, "Failed to restock product", nil)
+		return
+	}
+	if err := config.DB.First(&book, bookID).Error; err != nil {
+		utils.LogError("Failed to reload book ID: %d after restock: %v", bookID, err)
+		utils.InternalServerError(c, "Product restocked but failed to fetch details", nil)
+		return
+	}
+
+	if previousStock <= 0 && book.Stock > 0 {
+		utils.LogInfo("Book ID: %d back in stock, notifying subscribers", bookID)
+		utils.TriggerBackInStockNotifications()
+	}
+
+	var waiting int64
+	config.DB.Model(&models.StockNotification{}).Where("book_id = ? AND notified_at IS NULL", book.ID).Count(&waiting)
+
+	utils.LogInfo("Restocked book ID: %d by %d, stock now %d", bookID, req.Quantity, book.Stock)
+	utils.Success(c, "Product restocked successfully", gin.H{
+		"product":             book,
+		"added":               req.Quantity,
+		"subscribers_waiting": waiting,
+	})
+}
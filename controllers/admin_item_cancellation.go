@@ -2,36 +2,39 @@ package controllers
 
 import (
 	"fmt"
-	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/Govind-619/ReadSphere/utils"
 )
 
-// AdminReviewItemCancellation handles admin approval or rejection of item cancellation requests
+// AdminReviewItemCancellation handles admin approval or rejection of item cancellation requests.
+// Requests can only be approved before the item ships; once every item of the order is
+// cancelled the order itself is cancelled.
 func AdminReviewItemCancellation(c *gin.Context) {
 	// Check if admin is in context
-	_, exists := c.Get("admin")
+	adminVal, exists := c.Get("admin")
 	if !exists {
-		utils.Unauthorized(c, "Admin not found")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found", nil)
 		return
 	}
+	admin := adminVal.(models.Admin)
 
 	// Parse order ID and item ID
 	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		utils.BadRequest(c, "Invalid order ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
 		return
 	}
 
 	itemID, err := strconv.ParseUint(c.Param("item_id"), 10, 32)
 	if err != nil {
-		utils.BadRequest(c, "Invalid item ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid item ID", nil)
 		return
 	}
 
@@ -42,7 +45,7 @@ func AdminReviewItemCancellation(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, "Invalid request data", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request data", err)
 		return
 	}
 
@@ -54,141 +57,177 @@ func AdminReviewItemCancellation(c *gin.Context) {
 
 	// If rejecting, reason is required
 	if req.Action == "reject" && req.Reason == "" {
-		utils.BadRequest(c, "Reason is required when rejecting a cancellation request", nil)
+		utils.Fail(c, utils.CodeReasonRequired, "Reason is required when rejecting a cancellation request", nil)
 		return
 	}
 
 	// Start a transaction
 	tx := config.DB.Begin()
 	if tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to begin transaction"})
+		utils.InternalServerError(c, "Failed to begin transaction", nil)
 		return
 	}
 
-	// Get the order item
-	var item models.OrderItem
-	if err := tx.Where("id = ? AND order_id = ?", itemID, orderID).First(&item).Error; err != nil {
+	// Get the order, locked so two reviews of the same order do not race on its totals
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusNotFound, gin.H{"error": "Order item not found"})
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 
-	// Get the order
-	var order models.Order
-	if err := tx.Preload("User").First(&order, orderID).Error; err != nil {
+	// Get the order item
+	var item models.OrderItem
+	if err := tx.Where("id = ? AND order_id = ?", itemID, orderID).First(&item).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		utils.Fail(c, utils.CodeOrderItemNotFound, "Order item not found", nil)
 		return
 	}
 
-	// Check if the item has a cancellation request
-	if !item.CancellationRequested {
+	// Check if the item has a pending cancellation request; items the user cancelled directly
+	// are already refunded
+	if !item.CancellationRequested || item.CancellationStatus != "Pending" {
 		tx.Rollback()
-		c.JSON(http.StatusBadRequest, gin.H{"error": "This item does not have a pending cancellation request"})
+		utils.BadRequest(c, "This item does not have a pending cancellation request", nil)
 		return
 	}
 
+	formatter := utils.NewResponseFormatter(c)
 	// Process based on action
 	if req.Action == "approve" {
+		// Items that have left the warehouse can only come back as a return
+		shipped := order.Status != models.OrderStatusPlaced && order.Status != models.OrderStatusPaid && order.Status != models.OrderStatusProcessing
+		if !shipped && item.ShipmentID != nil {
+			var shipment models.OrderShipment
+			if err := tx.First(&shipment, *item.ShipmentID).Error; err == nil && shipment.Status != models.ShipmentStatusPending {
+				shipped = true
+			}
+		}
+		if shipped {
+			tx.Rollback()
+			utils.Fail(c, utils.CodeOrderStatusInvalid, "This item has already shipped; reject the request so the user can return it instead", nil)
+			return
+		}
+
 		// Update item status
 		item.CancellationStatus = "Approved"
 
 		// Restore stock for this item
-		if err := tx.Model(&models.Book{}).Where("id = ?", item.BookID).UpdateColumn("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
+		if _, err := utils.RestockOrderItem(tx, &item, models.StockSourceCancellation); err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore book stock"})
+			utils.InternalServerError(c, "Failed to restore book stock", nil)
 			return
 		}
 
 		// If payment was made with wallet or online payment, process refund
 		shouldRefundWallet := order.PaymentMethod == "wallet" || order.PaymentMethod == "RAZORPAY" || order.PaymentMethod == "online"
 
+		var refund *models.WalletTransaction
 		if shouldRefundWallet {
-			// Get or create wallet
-			wallet, err := getOrCreateWallet(order.UserID)
-			if err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wallet"})
-				return
-			}
-
 			// Calculate refund amount for this item
 			// Use the final price the customer actually paid for this item
 			refundAmount := item.Total - item.CouponDiscount // This is the final price after all discounts
-
-			// Create a wallet transaction
-			reference := fmt.Sprintf("REFUND-ORDER-%d-ITEM-%d", orderID, itemID)
-			description := fmt.Sprintf("Refund for cancelled item #%d in order #%d", itemID, orderID)
-			orderIDUint := uint(orderID)
-
-			_, err = createWalletTransaction(wallet.ID, refundAmount, models.TransactionTypeCredit, description, &orderIDUint, reference)
+			refundAmount += utils.PaymentRefundAdjustment(&order, item.Total-item.CouponDiscount)
+
+			// Credit the refund in the same transaction as the approval
+			var err error
+			refund, err = utils.PostWalletEntry(tx, utils.WalletEntry{
+				UserID:      order.UserID,
+				Amount:      refundAmount,
+				Type:        models.TransactionTypeCredit,
+				Description: fmt.Sprintf("Refund for cancelled item #%d in order #%d", itemID, orderID),
+				Reference:   fmt.Sprintf("REFUND-ORDER-%d-ITEM-%d", orderID, itemID),
+				OrderID:     &order.ID,
+			})
 			if err != nil {
 				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create wallet transaction"})
-				return
-			}
-
-			// Update wallet balance
-			if err := updateWalletBalance(wallet.ID, refundAmount, models.TransactionTypeCredit); err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update wallet balance"})
+				utils.InternalServerError(c, "Failed to create wallet transaction", nil)
 				return
 			}
+			now := time.Now()
+			item.RefundStatus = "completed"
+			item.RefundAmount = refund.Amount
+			item.RefundedAt = &now
 		}
 
 		// Update order totals
 		order.TotalAmount -= item.Total
 		order.FinalTotal -= item.Total
-
-		if err := tx.Save(&order).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order totals"})
-			return
-		}
+		order.TotalWithDelivery = order.FinalTotal + order.DeliveryCharge + order.GiftWrapFee + utils.RemainingPaymentAdjustment(&order)
 
 		// Save the item with updated status
 		if err := tx.Save(&item).Error; err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item status"})
+			utils.InternalServerError(c, "Failed to update item status", nil)
 			return
 		}
 
 		// Check if this was the last pending item cancellation request
-		var pendingRequests int64
-		tx.Model(&models.OrderItem{}).Where("order_id = ? AND cancellation_requested = ? AND cancellation_status = ?", orderID, true, "Pending").Count(&pendingRequests)
-
+		pendingRequests, err := pendingCancellationRequests(tx, order.ID, nil)
+		if err != nil {
+			tx.Rollback()
+			utils.InternalServerError(c, "Failed to update order status", nil)
+			return
+		}
 		if pendingRequests == 0 {
 			order.HasItemCancellationRequests = false
-			if err := tx.Save(&order).Error; err != nil {
+		}
+
+		// Cancel the order once none of its items is left to ship
+		var activeItems int64
+		if err := tx.Model(&models.OrderItem{}).
+			Where("order_id = ? AND NOT (cancellation_requested = ? AND cancellation_status IN ?)",
+				order.ID, true, []string{"Approved", "Cancelled"}).
+			Count(&activeItems).Error; err != nil {
+			tx.Rollback()
+			utils.InternalServerError(c, "Failed to update order status", nil)
+			return
+		}
+		if activeItems == 0 {
+			order.Status = models.OrderStatusCancelled
+			order.CancellationReason = item.CancellationReason
+		}
+
+		if err := tx.Save(&order).Error; err != nil {
+			tx.Rollback()
+			utils.InternalServerError(c, "Failed to update order totals", nil)
+			return
+		}
+
+		if order.Status == models.OrderStatusCancelled {
+			if err := recordOrderStatusEvent(tx, order.ID, order.Status, "admin", admin.ID, "Your cancellation request was approved"); err != nil {
 				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+				utils.InternalServerError(c, "Failed to record order status", nil)
 				return
 			}
-		}
-
-		// Get the wallet transaction for reference
-		var transaction models.WalletTransaction
-		transactionRef := fmt.Sprintf("REFUND-RETURN-ORDER-%d-ITEM-%d", orderID, itemID)
-		if err := tx.Where("reference = ?", transactionRef).First(&transaction).Error; err != nil {
+		} else if _, err := utils.Notify(tx, order.UserID, models.NotificationTypeOrder,
+			fmt.Sprintf("Cancellation approved for order #%d", order.ID),
+			"An item of your order was cancelled as you requested", fmt.Sprintf("/v1/user/orders/%d", order.ID)); err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet transaction"})
+			utils.InternalServerError(c, "Failed to notify user", nil)
 			return
 		}
 
 		// Commit transaction
 		if err := tx.Commit().Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete approval"})
+			utils.InternalServerError(c, "Failed to complete approval", nil)
 			return
 		}
+		if refund != nil {
+			utils.RecordRefundIssued("cancellation", refund.Amount)
+		}
 
+		utils.LogInfo("Admin %d approved cancellation of item %d in order %d", admin.ID, itemID, orderID)
 		utils.Success(c, "Item cancellation approved and processed", gin.H{
 			"item": gin.H{
 				"id":                  item.ID,
 				"cancellation_status": "Approved",
+				"refund_amount":       formatter.Money(item.RefundAmount),
 			},
 			"order": gin.H{
 				"id":            order.ID,
-				"updated_total": fmt.Sprintf("%.2f", order.FinalTotal),
+				"status":        order.Status,
+				"updated_total": formatter.Money(order.FinalTotal),
 			},
 		})
 	} else {
@@ -198,29 +237,41 @@ func AdminReviewItemCancellation(c *gin.Context) {
 
 		if err := tx.Save(&item).Error; err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item status"})
+			utils.InternalServerError(c, "Failed to update item status", nil)
 			return
 		}
 
 		// Check if this was the last pending item cancellation request
-		var pendingRequests int64
-		tx.Model(&models.OrderItem{}).Where("order_id = ? AND cancellation_requested = ? AND cancellation_status = ?", orderID, true, "Pending").Count(&pendingRequests)
-
+		pendingRequests, err := pendingCancellationRequests(tx, order.ID, nil)
+		if err != nil {
+			tx.Rollback()
+			utils.InternalServerError(c, "Failed to update order status", nil)
+			return
+		}
 		if pendingRequests == 0 {
 			order.HasItemCancellationRequests = false
 			if err := tx.Save(&order).Error; err != nil {
 				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+				utils.InternalServerError(c, "Failed to update order status", nil)
 				return
 			}
 		}
 
+		if _, err := utils.Notify(tx, order.UserID, models.NotificationTypeOrder,
+			fmt.Sprintf("Cancellation request declined for order #%d", order.ID),
+			req.Reason, fmt.Sprintf("/v1/user/orders/%d", order.ID)); err != nil {
+			tx.Rollback()
+			utils.InternalServerError(c, "Failed to notify user", nil)
+			return
+		}
+
 		// Commit transaction
 		if err := tx.Commit().Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete rejection"})
+			utils.InternalServerError(c, "Failed to complete rejection", nil)
 			return
 		}
 
+		utils.LogInfo("Admin %d rejected cancellation of item %d in order %d", admin.ID, itemID, orderID)
 		utils.Success(c, "Item cancellation rejected", gin.H{
 			"item": gin.H{
 				"id":                  item.ID,
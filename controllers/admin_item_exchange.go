@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AdminListExchangeRequests lists order items with an exchange, newest first, optionally by status
+func AdminListExchangeRequests(c *gin.Context) {
+	utils.LogInfo("AdminListExchangeRequests called")
+
+	page, limit := utils.GetPaginationParams(c)
+	query := config.DB.Model(&models.OrderItem{}).Where("exchange_status <> ''")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("exchange_status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count exchange requests: %v", err)
+		utils.InternalServerError(c, "Failed to fetch exchange requests", nil)
+		return
+	}
+
+	var items []models.OrderItem
+	if err := query.Preload("Book").Order("id DESC").Limit(limit).Offset((page - 1) * limit).Find(&items).Error; err != nil {
+		utils.LogError("Failed to fetch exchange requests: %v", err)
+		utils.InternalServerError(c, "Failed to fetch exchange requests", nil)
+		return
+	}
+
+	itemIDs := make([]uint, len(items))
+	for i, item := range items {
+		itemIDs[i] = item.ID
+	}
+	var shipments []models.ReplacementShipment
+	if len(itemIDs) > 0 {
+		config.DB.Where("order_item_id IN ?", itemIDs).Find(&shipments)
+	}
+	shipmentByItem := make(map[uint]models.ReplacementShipment, len(shipments))
+	for _, shipment := range shipments {
+		shipmentByItem[shipment.OrderItemID] = shipment
+	}
+
+	response := make([]gin.H, 0, len(items))
+	for _, item := range items {
+		entry := exchangeItemResponse(item)
+		if shipment, ok := shipmentByItem[item.ID]; ok {
+			entry["replacement_shipment"] = shipment
+		}
+		response = append(response, entry)
+	}
+
+	utils.LogInfo("Retrieved %d exchange requests", len(response))
+	utils.SuccessWithPagination(c, "Exchange requests retrieved successfully", gin.H{
+		"exchanges": response,
+	}, total, page, limit)
+}
+
+// AdminReviewExchangeItem approves or rejects an exchange request. Approval reserves stock
+// for the replacement and creates its shipment record.
+func AdminReviewExchangeItem(c *gin.Context) {
+	utils.LogInfo("AdminReviewExchangeItem called")
+
+	var req struct {
+		Action string `json:"action" binding:"required,oneof=approve reject"`
+		Reason string `json:"reason"` // Required for rejection
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request data: %v", err)
+		utils.BadRequest(c, "Action must be either 'approve' or 'reject'", err)
+		return
+	}
+	if req.Action == "reject" && req.Reason == "" {
+		utils.LogError("Missing reason for exchange rejection")
+		utils.Fail(c, utils.CodeReasonRequired, "Reason is required when rejecting an exchange request", nil)
+		return
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to begin transaction: %v", tx.Error)
+		utils.InternalServerError(c, "Failed to begin transaction", nil)
+		return
+	}
+
+	var item models.OrderItem
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND order_id = ?", c.Param("item_id"), c.Param("id")).First(&item).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Order item not found: %v", err)
+		utils.Fail(c, utils.CodeOrderItemNotFound, "Order item not found", nil)
+		return
+	}
+	if item.ExchangeStatus != models.ExchangeStatusRequested {
+		tx.Rollback()
+		utils.LogError("Item %d does not have a pending exchange request (status: %s)", item.ID, item.ExchangeStatus)
+		utils.Fail(c, utils.CodeExchangeConflict, "This item does not have a pending exchange request", nil)
+		return
+	}
+
+	var shipment *models.ReplacementShipment
+	if req.Action == "approve" {
+		// Reserve the replacement copies
+		reserved := tx.Model(&models.Book{}).
+			Where("id = ? AND stock >= ?", item.BookID, item.Quantity).
+			UpdateColumn("stock", gorm.Expr("stock - ?", item.Quantity))
+		if reserved.Error != nil {
+			tx.Rollback()
+			utils.LogError("Failed to reserve stock for exchange of item %d: %v", item.ID, reserved.Error)
+			utils.InternalServerError(c, "Failed to reserve stock", nil)
+			return
+		}
+		if reserved.RowsAffected == 0 {
+			tx.Rollback()
+			utils.LogError("Not enough stock to exchange item %d (book %d, qty %d)", item.ID, item.BookID, item.Quantity)
+			utils.Fail(c, utils.CodeStockChanged, "Not enough stock for the replacement", nil)
+			return
+		}
+		if err := recordAdminStockMovement(c, tx, utils.OrderItemStockChange(&item, -item.Quantity, models.StockSourceExchange)); err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to record stock movement for exchange of item %d: %v", item.ID, err)
+			utils.InternalServerError(c, "Failed to reserve stock", nil)
+			return
+		}
+
+		shipment = &models.ReplacementShipment{
+			OrderID:     item.OrderID,
+			OrderItemID: item.ID,
+			BookID:      item.BookID,
+			Quantity:    item.Quantity,
+			Status:      models.ExchangeStatusApproved,
+		}
+		// A rejected request may be followed by a new one, so reuse its record if there is one
+		if err := tx.Where(models.ReplacementShipment{OrderItemID: item.ID}).
+			Assign(*shipment).FirstOrCreate(shipment).Error; err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to create replacement shipment for item %d: %v", item.ID, err)
+			utils.InternalServerError(c, "Failed to create replacement shipment", nil)
+			return
+		}
+		item.ExchangeStatus = models.ExchangeStatusApproved
+	} else {
+		item.ExchangeStatus = models.ExchangeStatusRejected
+		item.ExchangeRejectReason = req.Reason
+	}
+
+	if err := tx.Model(&item).Updates(map[string]interface{}{
+		"exchange_status":        item.ExchangeStatus,
+		"exchange_reject_reason": item.ExchangeRejectReason,
+	}).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to update exchange status of item %d: %v", item.ID, err)
+		utils.InternalServerError(c, "Failed to update exchange request", nil)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit exchange review of item %d: %v", item.ID, err)
+		utils.InternalServerError(c, "Failed to update exchange request", nil)
+		return
+	}
+
+	utils.LogInfo("Exchange request for item %d %sd", item.ID, req.Action)
+	response := gin.H{"item": exchangeItemResponse(item)}
+	if shipment != nil {
+		response["replacement_shipment"] = shipment
+	}
+	utils.Success(c, "Exchange request "+req.Action+"d successfully", response)
+}
+
+// AdminUpdateReplacementShipment marks the replacement of an approved exchange as shipped,
+// with its courier and tracking number, or as delivered
+func AdminUpdateReplacementShipment(c *gin.Context) {
+	utils.LogInfo("AdminUpdateReplacementShipment called")
+
+	var req struct {
+		Status         string `json:"status" binding:"required,oneof=shipped delivered"`
+		Courier        string `json:"courier"`
+		TrackingNumber string `json:"tracking_number"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request data: %v", err)
+		utils.BadRequest(c, "Status must be either 'shipped' or 'delivered'", err)
+		return
+	}
+
+	itemID, err := strconv.Atoi(c.Param("item_id"))
+	if err != nil {
+		utils.Fail(c, utils.CodeInvalidID, "Invalid item ID", nil)
+		return
+	}
+
+	var item models.OrderItem
+	if err := config.DB.Where("id = ? AND order_id = ?", itemID, c.Param("id")).First(&item).Error; err != nil {
+		utils.LogError("Order item not found: %v", err)
+		utils.Fail(c, utils.CodeOrderItemNotFound, "Order item not found", nil)
+		return
+	}
+	var shipment models.ReplacementShipment
+	if err := config.DB.Where("order_item_id = ?", item.ID).First(&shipment).Error; err != nil {
+		utils.LogError("Replacement shipment not found for item %d: %v", item.ID, err)
+		utils.NotFound(c, "No approved exchange for this item")
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{}
+	switch req.Status {
+	case "shipped":
+		if item.ExchangeStatus != models.ExchangeStatusApproved {
+			utils.Fail(c, utils.CodeExchangeConflict, "Only approved exchanges can be shipped", gin.H{"exchange_status": item.ExchangeStatus})
+			return
+		}
+		if req.TrackingNumber == "" {
+			utils.BadRequest(c, "Tracking number is required when shipping a replacement", nil)
+			return
+		}
+		item.ExchangeStatus = models.ExchangeStatusShipped
+		updates["courier"] = req.Courier
+		updates["tracking_number"] = req.TrackingNumber
+		updates["shipped_at"] = now
+	case "delivered":
+		if item.ExchangeStatus != models.ExchangeStatusShipped {
+			utils.Fail(c, utils.CodeExchangeConflict, "Only shipped replacements can be marked delivered", gin.H{"exchange_status": item.ExchangeStatus})
+			return
+		}
+		item.ExchangeStatus = models.ExchangeStatusDelivered
+		updates["delivered_at"] = now
+	}
+	updates["status"] = item.ExchangeStatus
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&shipment).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Model(&item).Update("exchange_status", item.ExchangeStatus).Error
+	}); err != nil {
+		utils.LogError("Failed to update replacement shipment for item %d: %v", item.ID, err)
+		utils.InternalServerError(c, "Failed to update replacement shipment", nil)
+		return
+	}
+	config.DB.First(&shipment, shipment.ID)
+
+	utils.LogInfo("Replacement for item %d marked %s", item.ID, req.Status)
+	utils.Success(c, "Replacement shipment updated successfully", gin.H{
+		"item":                 exchangeItemResponse(item),
+		"replacement_shipment": shipment,
+	})
+}
+
+// exchangeItemResponse is the response representation of an order item's exchange
+func exchangeItemResponse(item models.OrderItem) gin.H {
+	return gin.H{
+		"order_id":               item.OrderID,
+		"item_id":                item.ID,
+		"book_id":                item.BookID,
+		"name":                   item.Book.Name,
+		"quantity":               item.Quantity,
+		"exchange_status":        item.ExchangeStatus,
+		"exchange_reason":        item.ExchangeReason,
+		"exchange_reject_reason": item.ExchangeRejectReason,
+	}
+}
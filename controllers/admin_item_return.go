@@ -2,7 +2,6 @@ package controllers
 
 import (
 	"fmt"
-	"net/http"
 	"strconv"
 
 	"github.com/Govind-619/ReadSphere/config"
@@ -19,7 +18,7 @@ func AdminReviewReturnItem(c *gin.Context) {
 	_, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found", nil)
 		return
 	}
 
@@ -27,14 +26,14 @@ func AdminReviewReturnItem(c *gin.Context) {
 	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		utils.LogError("Invalid order ID: %v", err)
-		utils.BadRequest(c, "Invalid order ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
 		return
 	}
 
 	itemID, err := strconv.ParseUint(c.Param("item_id"), 10, 32)
 	if err != nil {
 		utils.LogError("Invalid item ID: %v", err)
-		utils.BadRequest(c, "Invalid item ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid item ID", nil)
 		return
 	}
 	utils.LogDebug("Processing return request for order %d, item %d", orderID, itemID)
@@ -48,7 +47,7 @@ func AdminReviewReturnItem(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request data: %v", err)
-		utils.BadRequest(c, "Invalid request data", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request data", err)
 		return
 	}
 	utils.LogDebug("Request action: %s, Reason: %s, Quality: %s", req.Action, req.Reason, req.Quality)
@@ -63,7 +62,7 @@ func AdminReviewReturnItem(c *gin.Context) {
 	// If rejecting, reason is required
 	if req.Action == "reject" && req.Reason == "" {
 		utils.LogError("Missing reason for rejection")
-		utils.BadRequest(c, "Reason is required when rejecting a return request", nil)
+		utils.Fail(c, utils.CodeReasonRequired, "Reason is required when rejecting a return request", nil)
 		return
 	}
 
@@ -71,7 +70,7 @@ func AdminReviewReturnItem(c *gin.Context) {
 	tx := config.DB.Begin()
 	if tx.Error != nil {
 		utils.LogError("Failed to begin transaction: %v", tx.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to begin transaction"})
+		utils.InternalServerError(c, "Failed to begin transaction", nil)
 		return
 	}
 	utils.LogDebug("Started database transaction")
@@ -81,7 +80,7 @@ func AdminReviewReturnItem(c *gin.Context) {
 	if err := tx.Where("id = ? AND order_id = ?", itemID, orderID).First(&item).Error; err != nil {
 		tx.Rollback()
 		utils.LogError("Order item not found: %v", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Order item not found"})
+		utils.Fail(c, utils.CodeOrderItemNotFound, "Order item not found", nil)
 		return
 	}
 	utils.LogDebug("Found order item with status: %s", item.ReturnStatus)
@@ -91,7 +90,7 @@ func AdminReviewReturnItem(c *gin.Context) {
 	if err := tx.Preload("User").First(&order, orderID).Error; err != nil {
 		tx.Rollback()
 		utils.LogError("Order not found: %v", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 	utils.LogDebug("Found order for user: %s", order.User.Username)
@@ -100,26 +99,17 @@ func AdminReviewReturnItem(c *gin.Context) {
 	if !item.ReturnRequested || item.ReturnStatus != "Pending" {
 		tx.Rollback()
 		utils.LogError("Item does not have a pending return request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "This item does not have a pending return request"})
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "This item does not have a pending return request", nil)
 		return
 	}
 
+	formatter := utils.NewResponseFormatter(c)
 	if req.Action == "approve" {
 		utils.LogDebug("Processing approval for item %d", itemID)
 		// Update item status
 		item.ReturnStatus = "Approved"
 		item.RefundStatus = "processing" // Set initial refund status
 
-		// Process refund
-		wallet, err := getOrCreateWallet(order.UserID)
-		if err != nil {
-			tx.Rollback()
-			utils.LogError("Failed to get wallet: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wallet"})
-			return
-		}
-		utils.LogDebug("Found wallet for user %d", order.UserID)
-
 		// Calculate refund amount for this item
 		itemTotal := item.Total
 
@@ -135,28 +125,23 @@ func AdminReviewReturnItem(c *gin.Context) {
 		utils.LogDebug("Calculated refund amount: %.2f (Item total: %.2f, Coupon discount per item: %.2f)",
 			refundAmount, itemTotal, couponDiscountPerItem)
 
-		// Create wallet transaction
+		// Credit the refund in the same transaction as the review
 		transactionRef := fmt.Sprintf("REFUND-RETURN-ORDER-%d-ITEM-%d", orderID, itemID)
-		description := fmt.Sprintf("Refund for returned item #%d in order #%d", itemID, orderID)
-		orderIDUint := uint(orderID)
-
-		_, err = createWalletTransaction(wallet.ID, refundAmount, models.TransactionTypeCredit, description, &orderIDUint, transactionRef)
+		transaction, err := utils.PostWalletEntry(tx, utils.WalletEntry{
+			UserID:      order.UserID,
+			Amount:      refundAmount,
+			Type:        models.TransactionTypeCredit,
+			Description: fmt.Sprintf("Refund for returned item #%d in order #%d", itemID, orderID),
+			Reference:   transactionRef,
+			OrderID:     &order.ID,
+		})
 		if err != nil {
 			tx.Rollback()
-			utils.LogError("Failed to create wallet transaction: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create wallet transaction"})
+			utils.LogError("Failed to credit refund: %v", err)
+			utils.InternalServerError(c, "Failed to create wallet transaction", nil)
 			return
 		}
-		utils.LogDebug("Created wallet transaction with reference: %s", transactionRef)
-
-		// Update wallet balance
-		if err := updateWalletBalance(wallet.ID, refundAmount, models.TransactionTypeCredit); err != nil {
-			tx.Rollback()
-			utils.LogError("Failed to update wallet balance: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update wallet balance"})
-			return
-		}
-		utils.LogDebug("Updated wallet balance for user %d", order.UserID)
+		utils.LogDebug("Credited refund with reference: %s", transactionRef)
 
 		// Update refund status after successful transaction
 		item.RefundStatus = "completed"
@@ -165,7 +150,7 @@ func AdminReviewReturnItem(c *gin.Context) {
 		if err := tx.Save(&item).Error; err != nil {
 			tx.Rollback()
 			utils.LogError("Failed to update item status: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item status"})
+			utils.InternalServerError(c, "Failed to update item status", nil)
 			return
 		}
 		utils.LogDebug("Updated item status to approved and refund completed")
@@ -179,29 +164,20 @@ func AdminReviewReturnItem(c *gin.Context) {
 			if err := tx.Save(&order).Error; err != nil {
 				tx.Rollback()
 				utils.LogError("Failed to update order status: %v", err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+				utils.InternalServerError(c, "Failed to update order status", nil)
 				return
 			}
 			utils.LogDebug("Updated order status - no more pending returns")
 		}
 
-		// Get the wallet transaction for reference
-		var transaction models.WalletTransaction
-		if err := tx.Where("reference = ?", transactionRef).First(&transaction).Error; err != nil {
-			tx.Rollback()
-			utils.LogError("Failed to fetch wallet transaction: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet transaction"})
-			return
-		}
-		utils.LogDebug("Retrieved wallet transaction details")
-
 		// Commit transaction
 		if err := tx.Commit().Error; err != nil {
 			utils.LogError("Failed to commit transaction: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete approval"})
+			utils.InternalServerError(c, "Failed to complete approval", nil)
 			return
 		}
 		utils.LogDebug("Successfully committed transaction")
+		utils.RecordRefundIssued("return", refundAmount)
 
 		utils.LogInfo("Successfully approved return request for order %d, item %d", orderID, itemID)
 		utils.Success(c, "Return request approved and refund processed", gin.H{
@@ -209,17 +185,17 @@ func AdminReviewReturnItem(c *gin.Context) {
 				"id":            item.ID,
 				"return_status": "Approved",
 				"refund_details": gin.H{
-					"amount":       fmt.Sprintf("%.2f", refundAmount),
-					"item_total":   fmt.Sprintf("%.2f", item.Total),
+					"amount":       formatter.Money(refundAmount),
+					"item_total":   formatter.Money(item.Total),
 					"refunded_to":  "wallet",
-					"refunded_at":  transaction.CreatedAt.Format("2006-01-02 15:04:05"),
+					"refunded_at":  formatter.Timestamp(transaction.CreatedAt),
 					"item_quality": req.Quality,
 					"transaction": gin.H{
 						"id":          transaction.ID,
-						"amount":      fmt.Sprintf("%.2f", transaction.Amount),
+						"amount":      formatter.Money(transaction.Amount),
 						"type":        transaction.Type,
 						"description": transaction.Description,
-						"created_at":  transaction.CreatedAt.Format("2006-01-02 15:04:05"),
+						"created_at":  formatter.Timestamp(transaction.CreatedAt),
 					},
 				},
 			},
@@ -237,7 +213,7 @@ func AdminReviewReturnItem(c *gin.Context) {
 		if err := tx.Save(&item).Error; err != nil {
 			tx.Rollback()
 			utils.LogError("Failed to update item status: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item status"})
+			utils.InternalServerError(c, "Failed to update item status", nil)
 			return
 		}
 		utils.LogDebug("Updated item status to rejected")
@@ -251,7 +227,7 @@ func AdminReviewReturnItem(c *gin.Context) {
 			if err := tx.Save(&order).Error; err != nil {
 				tx.Rollback()
 				utils.LogError("Failed to update order status: %v", err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+				utils.InternalServerError(c, "Failed to update order status", nil)
 				return
 			}
 			utils.LogDebug("Updated order status - no more pending returns")
@@ -259,7 +235,7 @@ func AdminReviewReturnItem(c *gin.Context) {
 
 		if err := tx.Commit().Error; err != nil {
 			utils.LogError("Failed to commit transaction: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete rejection"})
+			utils.InternalServerError(c, "Failed to complete rejection", nil)
 			return
 		}
 		utils.LogDebug("Successfully committed transaction")
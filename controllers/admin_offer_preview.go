@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// PreviewBookOfferPrice shows which offers apply to a book and its effective price, either
+// now or at the RFC3339 time given in the "at" query parameter
+func PreviewBookOfferPrice(c *gin.Context) {
+	utils.LogInfo("PreviewBookOfferPrice called")
+
+	bookID, err := strconv.ParseUint(c.Param("book_id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid book ID: %s", c.Param("book_id"))
+		utils.Fail(c, utils.CodeInvalidID, "Invalid book ID", nil)
+		return
+	}
+
+	at := time.Now()
+	if atParam := c.Query("at"); atParam != "" {
+		if at, err = time.Parse(time.RFC3339, atParam); err != nil {
+			utils.LogError("Invalid preview time: %v", err)
+			utils.BadRequest(c, "Invalid at time. Use RFC3339.", nil)
+			return
+		}
+	}
+
+	var book models.Book
+	if err := config.DB.First(&book, bookID).Error; err != nil {
+		utils.LogError("Book not found: %d", bookID)
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
+
+	breakdown, err := utils.GetOfferBreakdownForBookAt(book.ID, book.CategoryID, at)
+	if err != nil {
+		utils.LogError("Failed to compute offers for book %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to compute offers", err.Error())
+		return
+	}
+	finalPrice := utils.ApplyOfferToPrice(book.Price, breakdown.AppliedOfferPercent)
+
+	// Every offer running at that time, including ones suppressed by an exclusive offer
+	var productOffers []models.ProductOffer
+	config.DB.Where("product_id = ? AND active = ? AND start_date <= ? AND end_date >= ?", book.ID, true, at, at).
+		Order("discount_percent DESC").Find(&productOffers)
+	var categoryOffers []models.CategoryOffer
+	config.DB.Where("category_id = ? AND active = ? AND start_date <= ? AND end_date >= ?", book.CategoryID, true, at, at).
+		Order("discount_percent DESC").Find(&categoryOffers)
+
+	formatter := utils.NewResponseFormatter(c)
+	candidates := make([]gin.H, 0, len(productOffers)+len(categoryOffers))
+	for _, offer := range productOffers {
+		entry := formatProductOffer(formatter, offer)
+		entry["type"] = "product"
+		candidates = append(candidates, entry)
+	}
+	for _, offer := range categoryOffers {
+		entry := formatCategoryOffer(formatter, offer)
+		entry["type"] = "category"
+		candidates = append(candidates, entry)
+	}
+
+	utils.LogInfo("Offer preview for book %d at %s: %.2f%% (%s)", book.ID, at.Format(time.RFC3339), breakdown.AppliedOfferPercent, breakdown.AppliedOfferType)
+	utils.Success(c, "Offer preview generated successfully", gin.H{
+		"book_id":          book.ID,
+		"name":             book.Name,
+		"category_id":      book.CategoryID,
+		"at":               at.Format(time.RFC3339),
+		"price":            formatter.Money(book.Price),
+		"offer_breakdown":  breakdown,
+		"discount_amount":  formatter.Money(book.Price - finalPrice),
+		"effective_price":  formatter.Money(finalPrice),
+		"candidate_offers": candidates,
+	})
+}
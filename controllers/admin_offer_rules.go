@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateOfferRulesRequest changes how product and category offers combine; omitted fields are
+// unchanged
+type UpdateOfferRulesRequest struct {
+	StackingPolicy     *string  `json:"stacking_policy"`
+	MaxDiscountPercent *float64 `json:"max_discount_percent"`
+}
+
+// offerRulesResponse is the response representation of the offer rules
+func offerRulesResponse(rules models.OfferRules) gin.H {
+	return gin.H{
+		"stacking_policy":      rules.StackingPolicy,
+		"max_discount_percent": rules.MaxDiscountPercent,
+		"updated_by":           rules.UpdatedBy,
+		"policies": gin.H{
+			models.OfferStackingStack:     "Product and category offers add up, unless either offer is marked exclusive; then only the larger applies",
+			models.OfferStackingBestOf:    "Only the larger of the product and category offer applies",
+			models.OfferStackingExclusive: "A book's product offer replaces its category offer",
+		},
+	}
+}
+
+// GetOfferRules returns how product and category offers combine
+func GetOfferRules(c *gin.Context) {
+	utils.LogInfo("GetOfferRules called")
+
+	utils.Success(c, "Offer rules retrieved successfully", gin.H{
+		"rules": offerRulesResponse(utils.GetOfferRules()),
+	})
+}
+
+// UpdateOfferRules changes the stacking policy and the cap on the combined offer percent.
+// Carts are priced with the new rules straight away; placed orders keep their prices.
+func UpdateOfferRules(c *gin.Context) {
+	utils.LogInfo("UpdateOfferRules called")
+
+	adminVal, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	admin := adminVal.(models.Admin)
+
+	var req UpdateOfferRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+
+	rules := utils.GetOfferRules()
+	if req.StackingPolicy != nil {
+		rules.StackingPolicy = *req.StackingPolicy
+	}
+	if req.MaxDiscountPercent != nil {
+		rules.MaxDiscountPercent = *req.MaxDiscountPercent
+	}
+	if err := utils.ValidateOfferRules(rules); err != nil {
+		utils.LogError("Invalid offer rules: %v", err)
+		utils.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	rules.UpdatedBy = admin.ID
+	if err := utils.SaveOfferRules(&rules); err != nil {
+		utils.LogError("Failed to save offer rules: %v", err)
+		utils.InternalServerError(c, "Failed to save offer rules", err.Error())
+		return
+	}
+
+	utils.LogInfo("Offer rules updated by admin %s: %s, capped at %.2f%%", admin.Email, rules.StackingPolicy, rules.MaxDiscountPercent)
+	utils.Success(c, "Offer rules updated successfully", gin.H{
+		"rules": offerRulesResponse(rules),
+	})
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxBulkOrderStatusOrders caps how many orders one bulk status update may touch
+const maxBulkOrderStatusOrders = 100
+
+// bulkOrderStatusBatchSize is how many orders are updated in one transaction
+const bulkOrderStatusBatchSize = 20
+
+// BulkOrderStatusRequest moves several orders to the same status
+type BulkOrderStatusRequest struct {
+	OrderIDs []uint `json:"order_ids" binding:"required,min=1,max=100"`
+	Status   string `json:"status" binding:"required"`
+	Note     string `json:"note" binding:"max=500"`
+}
+
+// bulkOrderStatusResult is the outcome of moving one order
+type bulkOrderStatusResult struct {
+	OrderID uint            `json:"order_id"`
+	Success bool            `json:"success"`
+	Status  string          `json:"status,omitempty"`
+	Code    utils.ErrorCode `json:"code,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Details interface{}     `json:"details,omitempty"`
+}
+
+// AdminBulkUpdateOrderStatus moves many orders to one status with the same checks as a single
+// update. Orders are updated in batches, each in its own transaction; an order that cannot
+// move is rolled back on its own and reported without holding up the rest of its batch.
+func AdminBulkUpdateOrderStatus(c *gin.Context) {
+	utils.LogInfo("AdminBulkUpdateOrderStatus called")
+
+	admin, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	adminModel, ok := admin.(models.Admin)
+	if !ok {
+		utils.LogError("Invalid admin type in context")
+		utils.InternalServerError(c, "Invalid admin type", nil)
+		return
+	}
+
+	var req BulkOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", gin.H{
+			"error":      err.Error(),
+			"max_orders": maxBulkOrderStatusOrders,
+		})
+		return
+	}
+	if !validAdminOrderStatus(req.Status) {
+		utils.LogError("Invalid status requested: %s", req.Status)
+		utils.BadRequest(c, "Invalid status", gin.H{
+			"valid_statuses": adminOrderStatuses,
+		})
+		return
+	}
+
+	// Each order is handled once, in the order given
+	seen := make(map[uint]bool, len(req.OrderIDs))
+	var orderIDs []uint
+	for _, id := range req.OrderIDs {
+		if !seen[id] {
+			seen[id] = true
+			orderIDs = append(orderIDs, id)
+		}
+	}
+
+	update := orderStatusUpdate{Status: req.Status, Note: req.Note}
+	results := make([]bulkOrderStatusResult, 0, len(orderIDs))
+	for start := 0; start < len(orderIDs); start += bulkOrderStatusBatchSize {
+		end := start + bulkOrderStatusBatchSize
+		if end > len(orderIDs) {
+			end = len(orderIDs)
+		}
+		results = append(results, updateOrderStatusBatch(orderIDs[start:end], update, adminModel)...)
+	}
+
+	updated := 0
+	for _, result := range results {
+		if result.Success {
+			updated++
+		}
+	}
+
+	utils.LogInfo("Admin %s moved %d of %d orders to %s", adminModel.Email, updated, len(results), req.Status)
+	utils.Success(c, "Bulk order status update completed", gin.H{
+		"status":        req.Status,
+		"total_count":   len(results),
+		"updated_count": updated,
+		"failed_count":  len(results) - updated,
+		"results":       results,
+	})
+}
+
+// updateOrderStatusBatch moves one batch of orders in a single transaction. Each order is
+// guarded by a savepoint so that a refused or failed order only undoes its own changes; if
+// the batch cannot be committed every order in it is reported as failed.
+func updateOrderStatusBatch(orderIDs []uint, update orderStatusUpdate, admin models.Admin) []bulkOrderStatusResult {
+	results := make([]bulkOrderStatusResult, 0, len(orderIDs))
+	failAll := func(message string) []bulkOrderStatusResult {
+		failed := make([]bulkOrderStatusResult, 0, len(orderIDs))
+		for _, id := range orderIDs {
+			failed = append(failed, bulkOrderStatusResult{OrderID: id, Code: utils.CodeInternalError, Error: message})
+		}
+		return failed
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to begin transaction: %v", tx.Error)
+		return failAll("Failed to begin transaction")
+	}
+
+	for _, orderID := range orderIDs {
+		savepoint := fmt.Sprintf("order_%d", orderID)
+		if err := tx.SavePoint(savepoint).Error; err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to create savepoint for order %d: %v", orderID, err)
+			return failAll("Failed to update order status")
+		}
+
+		result := bulkOrderStatusResult{OrderID: orderID}
+		var order models.Order
+		err := tx.First(&order, orderID).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			result.Code, result.Error = utils.CodeOrderNotFound, "Order not found"
+		case err != nil:
+			utils.LogError("Failed to load order %d: %v", orderID, err)
+			result.Code, result.Error = utils.CodeInternalError, "Failed to load order"
+		default:
+			err = applyAdminOrderStatus(tx, &order, update, admin)
+			var statusErr *orderStatusError
+			switch {
+			case err == nil:
+				result.Success, result.Status = true, order.Status
+			case errors.As(err, &statusErr):
+				result.Code, result.Error, result.Details = statusErr.Code, statusErr.Message, statusErr.Details
+			default:
+				utils.LogError("Failed to update status of order %d: %v", orderID, err)
+				result.Code, result.Error = utils.CodeInternalError, "Failed to update order status"
+			}
+		}
+
+		if !result.Success {
+			if err := tx.RollbackTo(savepoint).Error; err != nil {
+				tx.Rollback()
+				utils.LogError("Failed to roll back order %d: %v", orderID, err)
+				return failAll("Failed to update order status")
+			}
+		}
+		results = append(results, result)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit bulk order status batch: %v", err)
+		return failAll("Failed to save changes")
+	}
+	for _, result := range results {
+		if result.Success {
+			emailOrderStatus(result.OrderID, result.Status, update.Note)
+		}
+	}
+	return results
+}
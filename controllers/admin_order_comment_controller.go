@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAddOrderComment adds an internal comment to an order. Customers never see comments.
+func AdminAddOrderComment(c *gin.Context) {
+	utils.LogInfo("AdminAddOrderComment called")
+
+	adminVal, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	admin, ok := adminVal.(models.Admin)
+	if !ok {
+		utils.LogError("Invalid admin type in context")
+		utils.InternalServerError(c, "Invalid admin type", nil)
+		return
+	}
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.LogError("Invalid order ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
+		return
+	}
+
+	var req struct {
+		Comment string `json:"comment" binding:"required,max=2000"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid order comment request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	text := strings.TrimSpace(req.Comment)
+	if text == "" {
+		utils.BadRequest(c, "Comment cannot be empty", nil)
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Select("id").First(&order, orderID).Error; err != nil {
+		utils.LogError("Order not found: %v", err)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return
+	}
+
+	comment := models.OrderComment{
+		OrderID:    order.ID,
+		AdminID:    admin.ID,
+		AdminEmail: admin.Email,
+		Comment:    text,
+	}
+	if err := config.DB.Create(&comment).Error; err != nil {
+		utils.LogError("Failed to add comment to order ID: %d: %v", orderID, err)
+		utils.InternalServerError(c, "Failed to add comment", nil)
+		return
+	}
+
+	utils.LogInfo("Admin %s commented on order ID: %d", admin.Email, orderID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Created(c, "Comment added successfully", gin.H{
+		"comment": orderCommentResponse(formatter, comment),
+	})
+}
+
+// AdminGetOrderComments lists an order's internal comments, oldest first
+func AdminGetOrderComments(c *gin.Context) {
+	utils.LogInfo("AdminGetOrderComments called")
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.LogError("Invalid order ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
+		return
+	}
+
+	var comments []models.OrderComment
+	if err := config.DB.Where("order_id = ?", orderID).Order("created_at ASC, id ASC").Find(&comments).Error; err != nil {
+		utils.LogError("Failed to fetch comments for order ID: %d: %v", orderID, err)
+		utils.InternalServerError(c, "Failed to fetch comments", nil)
+		return
+	}
+
+	utils.LogInfo("Retrieved %d comments for order ID: %d", len(comments), orderID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Comments retrieved successfully", gin.H{
+		"comments": orderCommentsResponse(formatter, comments),
+	})
+}
+
+// AdminDeleteOrderComment deletes an internal comment. Admins can only delete their own comments.
+func AdminDeleteOrderComment(c *gin.Context) {
+	utils.LogInfo("AdminDeleteOrderComment called")
+
+	adminVal, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	admin, ok := adminVal.(models.Admin)
+	if !ok {
+		utils.LogError("Invalid admin type in context")
+		utils.InternalServerError(c, "Invalid admin type", nil)
+		return
+	}
+
+	var comment models.OrderComment
+	if err := config.DB.Where("id = ? AND order_id = ?", c.Param("comment_id"), c.Param("id")).First(&comment).Error; err != nil {
+		utils.LogError("Order comment not found: %v", err)
+		utils.NotFound(c, "Comment not found")
+		return
+	}
+	if comment.AdminID != admin.ID {
+		utils.LogError("Admin %s attempted to delete comment %d by admin ID: %d", admin.Email, comment.ID, comment.AdminID)
+		utils.Forbidden(c, "You can only delete your own comments")
+		return
+	}
+
+	if err := config.DB.Delete(&comment).Error; err != nil {
+		utils.LogError("Failed to delete order comment %d: %v", comment.ID, err)
+		utils.InternalServerError(c, "Failed to delete comment", nil)
+		return
+	}
+
+	utils.LogInfo("Admin %s deleted comment %d on order ID: %d", admin.Email, comment.ID, comment.OrderID)
+	utils.Success(c, "Comment deleted successfully", nil)
+}
+
+// orderCommentResponse is the response representation of an internal order comment
+func orderCommentResponse(f utils.ResponseFormatter, comment models.OrderComment) gin.H {
+	return gin.H{
+		"id":          comment.ID,
+		"admin_id":    comment.AdminID,
+		"admin_email": comment.AdminEmail,
+		"comment":     comment.Comment,
+		"created_at":  f.Timestamp(comment.CreatedAt),
+	}
+}
+
+func orderCommentsResponse(f utils.ResponseFormatter, comments []models.OrderComment) []gin.H {
+	response := make([]gin.H, 0, len(comments))
+	for _, comment := range comments {
+		response = append(response, orderCommentResponse(f, comment))
+	}
+	return response
+}
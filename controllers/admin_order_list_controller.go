@@ -3,11 +3,13 @@ package controllers
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // AdminListOrders lists all orders with search, filter, sort, and pagination
@@ -18,7 +20,7 @@ func AdminListOrders(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -31,7 +33,7 @@ func AdminListOrders(c *gin.Context) {
 
 	utils.LogDebug("Admin authenticated: %s", adminModel.Email)
 
-	query := config.DB.Preload("User").Preload("OrderItems").Preload("Address")
+	query := utils.RequestDB(c.Request.Context()).Preload("User").Preload("OrderItems").Preload("Address")
 
 	// Filtering
 	if status := c.Query("status"); status != "" {
@@ -67,9 +69,28 @@ func AdminListOrders(c *gin.Context) {
 	if orderDir != "asc" && orderDir != "desc" {
 		orderDir = "desc"
 	}
-	query = query.Order(sortField + " " + orderDir)
 	utils.LogDebug("Applied sorting: %s %s", sortField, orderDir)
 
+	// Cursor pagination reads the orders past a cursor instead of skipping rows, for the sorts
+	// it can follow
+	if cursor, cursorMode, err := utils.GetCursorParams(c, sortField, orderDir); cursorMode && !utils.WantsCSV(c) {
+		if err == nil && !orderCursorColumns[sortField] {
+			err = fmt.Errorf("cannot page orders sorted by %s by cursor", sortField)
+		}
+		var key interface{}
+		if err == nil {
+			key, err = orderCursorKey(cursor, sortField)
+		}
+		if err != nil {
+			utils.LogError("Invalid cursor: %v", err)
+			utils.BadRequest(c, "Invalid cursor", "The cursor is malformed, belongs to another sort order, or the sort cannot be paged by cursor")
+			return
+		}
+		adminListCursorOrders(c, query, cursor, sortField, key)
+		return
+	}
+	query = query.Order(sortField + " " + orderDir)
+
 	// Pagination
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
@@ -90,6 +111,40 @@ func AdminListOrders(c *gin.Context) {
 	}
 	utils.LogDebug("Total orders found: %d", total)
 
+	// CSV exports include every order matching the filters
+	if utils.WantsCSV(c) {
+		var orders []models.Order
+		if err := query.Find(&orders).Error; err != nil {
+			utils.LogError("Failed to fetch orders for export: %v", err)
+			utils.InternalServerError(c, "Failed to fetch orders", err.Error())
+			return
+		}
+		headers := []string{"Order ID", "Username", "Email", "Status", "Total Amount", "Discount", "Coupon Discount", "Delivery Charge", "Total With Delivery", "Final Total", "Created At", "Items", "Payment Mode"}
+		rows := make([][]string, 0, len(orders))
+		for _, order := range orders {
+			rows = append(rows, []string{
+				fmt.Sprintf("%d", order.ID),
+				order.User.Username,
+				order.User.Email,
+				order.Status,
+				fmt.Sprintf("%.2f", order.TotalAmount),
+				fmt.Sprintf("%.2f", order.Discount),
+				fmt.Sprintf("%.2f", order.CouponDiscount),
+				fmt.Sprintf("%.2f", order.DeliveryCharge),
+				fmt.Sprintf("%.2f", order.TotalWithDelivery),
+				fmt.Sprintf("%.2f", order.FinalTotal),
+				order.CreatedAt.Format("2006-01-02 15:04:05"),
+				fmt.Sprintf("%d", len(order.OrderItems)),
+				order.PaymentMethod,
+			})
+		}
+		if err := utils.WriteCSV(c, "orders", headers, rows); err != nil {
+			utils.LogError("Failed to write CSV file: %v", err)
+			utils.InternalServerError(c, "Failed to write CSV file", err.Error())
+		}
+		return
+	}
+
 	// Apply pagination
 	var orders []models.Order
 	if err := query.Offset((page - 1) * limit).Limit(limit).Find(&orders).Error; err != nil {
@@ -99,36 +154,87 @@ func AdminListOrders(c *gin.Context) {
 	}
 	utils.LogDebug("Retrieved %d orders", len(orders))
 
+	sendAdminOrderList(c, orders, gin.H{
+		"current_page": page,
+		"per_page":     limit,
+		"total":        total,
+		"total_pages":  (total + int64(limit) - 1) / int64(limit),
+	}, sortField, orderDir)
+}
+
+// adminListCursorOrders sends the page of the filtered order query past the cursor, without
+// counting the matching orders
+func adminListCursorOrders(c *gin.Context, query *gorm.DB, cursor *utils.CursorParams, sortField string, key interface{}) {
+	direction := cursor.Direction()
+	if key != nil {
+		query = query.Where(fmt.Sprintf("(orders.%s, orders.id) %s (?, ?)", sortField, cursor.Comparison()), key, cursor.Cursor.ID)
+	}
+	utils.LogDebug("Applied cursor pagination - Limit: %d, Backward: %t", cursor.Limit, cursor.Backward)
+
+	var orders []models.Order
+	if err := query.Order(fmt.Sprintf("orders.%s %s, orders.id %s", sortField, direction, direction)).
+		Limit(cursor.Limit + 1).Find(&orders).Error; err != nil {
+		utils.LogError("Failed to fetch orders: %v", err)
+		utils.InternalServerError(c, "Failed to fetch orders", err.Error())
+		return
+	}
+	orders, pagination := orderCursorPage(orders, cursor, sortField)
+	utils.LogDebug("Retrieved %d orders", len(orders))
+	sendAdminOrderList(c, orders, pagination, sortField, cursor.Order)
+}
+
+// adminOrderSummary is an order in the admin order list
+type adminOrderSummary struct {
+	utils.OrderSummary
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+func newAdminOrderSummary(f utils.ResponseFormatter, order models.Order) adminOrderSummary {
+	return adminOrderSummary{
+		OrderSummary: utils.NewOrderSummary(f, order),
+		Username:     order.User.Username,
+		Email:        order.User.Email,
+	}
+}
+
+// adminOrderItem is an order line in the admin order details, with its return and
+// cancellation state
+type adminOrderItem struct {
+	utils.OrderItemSummary
+	Category   string `json:"category"`
+	Genre      string `json:"genre"`
+	Status     gin.H  `json:"status"`
+	ShipmentID *uint  `json:"shipment_id"`
+}
+
+// adminOrderDetail is the admin view of a single order
+type adminOrderDetail struct {
+	adminOrderSummary
+	CouponCode       string           `json:"coupon_code"`
+	Address          gin.H            `json:"address"`
+	Items            []adminOrderItem `json:"items"`
+	DeliveryNote     string           `json:"delivery_note"`
+	Gift             gin.H            `json:"gift"`
+	DeliverySchedule gin.H            `json:"delivery_schedule"`
+	Shipments        []gin.H          `json:"shipments"`
+	InternalComments []gin.H          `json:"internal_comments"`
+}
+
+// sendAdminOrderList sends a page of orders with the filters and sort that selected them
+func sendAdminOrderList(c *gin.Context, orders []models.Order, pagination gin.H, sortField, orderDir string) {
 	// Prepare minimal order response
-	var orderResponses []gin.H
+	formatter := utils.NewResponseFormatter(c)
+	var orderResponses []adminOrderSummary
 	for _, order := range orders {
-		orderResponses = append(orderResponses, gin.H{
-			"id":                  order.ID,
-			"username":            order.User.Username,
-			"email":               order.User.Email,
-			"status":              order.Status,
-			"total_amount":        fmt.Sprintf("%.2f", order.TotalAmount),
-			"discount":            fmt.Sprintf("%.2f", order.Discount),
-			"coupon_discount":     fmt.Sprintf("%.2f", order.CouponDiscount),
-			"delivery_charge":     fmt.Sprintf("%.2f", order.DeliveryCharge),
-			"total_with_delivery": fmt.Sprintf("%.2f", order.TotalWithDelivery),
-			"final_total":         fmt.Sprintf("%.2f", order.FinalTotal),
-			"created_at":          order.CreatedAt.Format("2006-01-02 15:04:05"),
-			"item_count":          len(order.OrderItems),
-			"payment_mode":        order.PaymentMethod,
-		})
+		orderResponses = append(orderResponses, newAdminOrderSummary(formatter, order))
 	}
 	utils.LogDebug("Prepared response for %d orders", len(orderResponses))
 
 	utils.LogInfo("Successfully retrieved %d orders", len(orderResponses))
 	utils.Success(c, "Orders retrieved successfully", gin.H{
-		"orders": orderResponses,
-		"pagination": gin.H{
-			"current_page": page,
-			"per_page":     limit,
-			"total":        total,
-			"total_pages":  (total + int64(limit) - 1) / int64(limit),
-		},
+		"orders":     orderResponses,
+		"pagination": pagination,
 		"filters": gin.H{
 			"status": c.Query("status"),
 			"date":   c.Query("date"),
@@ -151,7 +257,7 @@ func AdminGetOrderDetails(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -167,7 +273,7 @@ func AdminGetOrderDetails(c *gin.Context) {
 	orderID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.LogError("Invalid order ID: %v", err)
-		utils.BadRequest(c, "Invalid order ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
 		return
 	}
 	utils.LogDebug("Fetching details for order ID: %d", orderID)
@@ -181,59 +287,84 @@ func AdminGetOrderDetails(c *gin.Context) {
 		Preload("OrderItems.Book").
 		First(&order, orderID).Error; err != nil {
 		utils.LogError("Order not found: %v", err)
-		utils.NotFound(c, "Order not found")
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 	utils.LogDebug("Found order for user: %s", order.User.Username)
 
 	// Prepare items response
-	var items []gin.H
+	formatter := utils.NewResponseFormatter(c)
+	var items []adminOrderItem
 	for _, item := range order.OrderItems {
-		items = append(items, gin.H{
-			"id":       item.ID,
-			"name":     item.Book.Name,
-			"category": item.Book.Category.Name,
-			"genre":    item.Book.Genre.Name,
-			"quantity": item.Quantity,
-			"price":    fmt.Sprintf("%.2f", item.Price),
-			"total":    fmt.Sprintf("%.2f", item.Total),
-			"status": gin.H{
+		items = append(items, adminOrderItem{
+			OrderItemSummary: utils.NewOrderItemSummary(formatter, item),
+			Category:         item.Book.Category.Name,
+			Genre:            item.Book.Genre.Name,
+			Status: gin.H{
 				"return_requested":    item.ReturnRequested,
 				"return_status":       item.ReturnStatus,
 				"return_reason":       item.ReturnReason,
 				"refund_status":       item.RefundStatus,
-				"refund_amount":       fmt.Sprintf("%.2f", item.RefundAmount),
+				"refund_amount":       formatter.Money(item.RefundAmount),
 				"cancellation_status": item.CancellationStatus,
 				"cancellation_reason": item.CancellationReason,
 			},
+			ShipmentID: item.ShipmentID,
 		})
 	}
 	utils.LogDebug("Prepared response for %d order items", len(items))
 
 	utils.LogInfo("Successfully retrieved details for order ID: %d", orderID)
-	utils.Success(c, "Order details retrieved successfully", gin.H{
-		"order": gin.H{
-			"id":                  order.ID,
-			"username":            order.User.Username,
-			"email":               order.User.Email,
-			"status":              order.Status,
-			"total_amount":        fmt.Sprintf("%.2f", order.TotalAmount),
-			"discount":            fmt.Sprintf("%.2f", order.Discount),
-			"coupon_discount":     fmt.Sprintf("%.2f", order.CouponDiscount),
-			"coupon_code":         order.CouponCode,
-			"delivery_charge":     fmt.Sprintf("%.2f", order.DeliveryCharge),
-			"total_with_delivery": fmt.Sprintf("%.2f", order.TotalWithDelivery),
-			"final_total":         fmt.Sprintf("%.2f", order.FinalTotal),
-			"created_at":          order.CreatedAt.Format("2006-01-02 15:04:05"),
-			"payment_mode":        order.PaymentMethod,
-			"address": gin.H{
-				"line1":       order.Address.Line1,
-				"line2":       order.Address.Line2,
-				"city":        order.Address.City,
-				"state":       order.Address.State,
-				"postal_code": order.Address.PostalCode,
-			},
-			"items": items,
+	orderResponse := adminOrderDetail{
+		adminOrderSummary: newAdminOrderSummary(formatter, order),
+		CouponCode:        order.CouponCode,
+		Address: gin.H{
+			"line1":       order.Address.Line1,
+			"line2":       order.Address.Line2,
+			"city":        order.Address.City,
+			"state":       order.Address.State,
+			"postal_code": order.Address.PostalCode,
 		},
+		Items:            items,
+		DeliveryNote:     order.DeliveryNote,
+		Gift:             giftOptionsResponse(formatter, order),
+		DeliverySchedule: deliveryScheduleResponse(order),
+	}
+	shipments, err := orderShipmentsResponse(formatter, config.DB, order.ID, order.OrderItems)
+	if err != nil {
+		utils.LogError("Failed to fetch shipments for order ID: %d: %v", orderID, err)
+	}
+	orderResponse.Shipments = shipments
+
+	// Internal comments are for admins only and never reach the customer endpoints
+	var comments []models.OrderComment
+	if err := config.DB.Where("order_id = ?", order.ID).Order("created_at ASC, id ASC").Find(&comments).Error; err != nil {
+		utils.LogError("Failed to fetch comments for order ID: %d: %v", orderID, err)
+	}
+	orderResponse.InternalComments = orderCommentsResponse(formatter, comments)
+	utils.Success(c, "Order details retrieved successfully", gin.H{
+		"order": orderResponse,
 	})
 }
+
+// AdminLookupOrder finds the order a scanned code points to and returns its details, so
+// warehouse staff can scan a label barcode or invoice QR code and jump straight to the order
+func AdminLookupOrder(c *gin.Context) {
+	utils.LogInfo("AdminLookupOrder called")
+
+	code := c.Query("code")
+	if strings.TrimSpace(code) == "" {
+		utils.BadRequest(c, "code is required", nil)
+		return
+	}
+	orderID, err := utils.ParseOrderCode(code)
+	if err != nil {
+		utils.LogError("Invalid order code scanned: %s", code)
+		utils.Fail(c, utils.CodeOrderNotFound, "No order matches this code", nil)
+		return
+	}
+	utils.LogDebug("Order code %s resolved to order ID: %d", code, orderID)
+
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: strconv.FormatUint(uint64(orderID), 10)})
+	AdminGetOrderDetails(c)
+}
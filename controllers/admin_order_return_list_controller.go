@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"fmt"
 	"strconv"
 	"strings"
 
@@ -19,7 +18,7 @@ func AdminListReturnRequests(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -85,6 +84,7 @@ func AdminListReturnRequests(c *gin.Context) {
 
 	// Prepare response with return details
 	var returnRequests []gin.H
+	formatter := utils.NewResponseFormatter(c)
 	for _, order := range orders {
 		if showAllReturns {
 			// Simplified response for /returns route
@@ -138,13 +138,13 @@ func AdminListReturnRequests(c *gin.Context) {
 				"username":            order.User.Username,
 				"email":               order.User.Email,
 				"status":              order.Status,
-				"total_amount":        fmt.Sprintf("%.2f", order.TotalAmount),
-				"discount":            fmt.Sprintf("%.2f", order.Discount),
-				"coupon_discount":     fmt.Sprintf("%.2f", order.CouponDiscount),
-				"delivery_charge":     fmt.Sprintf("%.2f", order.DeliveryCharge),
-				"total_with_delivery": fmt.Sprintf("%.2f", order.TotalWithDelivery),
-				"final_total":         fmt.Sprintf("%.2f", order.FinalTotal),
-				"created_at":          order.CreatedAt.Format("2006-01-02 15:04:05"),
+				"total_amount":        formatter.Money(order.TotalAmount),
+				"discount":            formatter.Money(order.Discount),
+				"coupon_discount":     formatter.Money(order.CouponDiscount),
+				"delivery_charge":     formatter.Money(order.DeliveryCharge),
+				"total_with_delivery": formatter.Money(order.TotalWithDelivery),
+				"final_total":         formatter.Money(order.FinalTotal),
+				"created_at":          formatter.Timestamp(order.CreatedAt),
 				"return_reason":       order.ReturnReason,
 				"return_status": gin.H{
 					"total_items":    stats.total,
@@ -189,7 +189,7 @@ func AdminListAllReturns(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -236,6 +236,7 @@ func AdminListAllReturns(c *gin.Context) {
 
 	// Prepare response with return details
 	var returnRequests []gin.H
+	formatter := utils.NewResponseFormatter(c)
 	for _, order := range orders {
 		// Count return items by status
 		var stats = struct {
@@ -262,12 +263,12 @@ func AdminListAllReturns(c *gin.Context) {
 					"id":            item.ID,
 					"book_name":     item.Book.Name,
 					"quantity":      item.Quantity,
-					"price":         fmt.Sprintf("%.2f", item.Price),
-					"total":         fmt.Sprintf("%.2f", item.Total),
+					"price":         formatter.Money(item.Price),
+					"total":         formatter.Money(item.Total),
 					"return_status": item.ReturnStatus,
 					"return_reason": item.ReturnReason,
 					"refund_status": item.RefundStatus,
-					"refund_amount": fmt.Sprintf("%.2f", item.RefundAmount),
+					"refund_amount": formatter.Money(item.RefundAmount),
 				})
 			}
 		}
@@ -278,13 +279,13 @@ func AdminListAllReturns(c *gin.Context) {
 			"username":            order.User.Username,
 			"email":               order.User.Email,
 			"status":              order.Status,
-			"total_amount":        fmt.Sprintf("%.2f", order.TotalAmount),
-			"discount":            fmt.Sprintf("%.2f", order.Discount),
-			"coupon_discount":     fmt.Sprintf("%.2f", order.CouponDiscount),
-			"delivery_charge":     fmt.Sprintf("%.2f", order.DeliveryCharge),
-			"total_with_delivery": fmt.Sprintf("%.2f", order.TotalWithDelivery),
-			"final_total":         fmt.Sprintf("%.2f", order.FinalTotal),
-			"created_at":          order.CreatedAt.Format("2006-01-02 15:04:05"),
+			"total_amount":        formatter.Money(order.TotalAmount),
+			"discount":            formatter.Money(order.Discount),
+			"coupon_discount":     formatter.Money(order.CouponDiscount),
+			"delivery_charge":     formatter.Money(order.DeliveryCharge),
+			"total_with_delivery": formatter.Money(order.TotalWithDelivery),
+			"final_total":         formatter.Money(order.FinalTotal),
+			"created_at":          formatter.Timestamp(order.CreatedAt),
 			"return_reason":       order.ReturnReason,
 			"return_items":        items,
 			"return_summary": gin.H{
@@ -9,7 +9,6 @@ import (
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 // AdminReviewReturn handles the admin review of return requests
@@ -17,7 +16,7 @@ func AdminReviewReturn(c *gin.Context) {
 	// Verify admin
 	admin, exists := c.Get("admin")
 	if !exists {
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 	_, ok := admin.(models.Admin)
@@ -29,12 +28,12 @@ func AdminReviewReturn(c *gin.Context) {
 	// Parse parameters
 	orderID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		utils.BadRequest(c, "Invalid order ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
 		return
 	}
 	itemID, err := strconv.Atoi(c.Param("item_id"))
 	if err != nil {
-		utils.BadRequest(c, "Invalid item ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid item ID", nil)
 		return
 	}
 
@@ -44,7 +43,7 @@ func AdminReviewReturn(c *gin.Context) {
 		Quality string `json:"quality,omitempty" binding:"omitempty,oneof=good damaged unusable"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, "Invalid request body", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request body", err)
 		return
 	}
 
@@ -59,7 +58,7 @@ func AdminReviewReturn(c *gin.Context) {
 	var order models.Order
 	if err := tx.Preload("OrderItems").Preload("User").First(&order, orderID).Error; err != nil {
 		tx.Rollback()
-		utils.NotFound(c, "Order not found")
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 
@@ -76,7 +75,7 @@ func AdminReviewReturn(c *gin.Context) {
 
 	if !found {
 		tx.Rollback()
-		utils.NotFound(c, "Order item not found")
+		utils.Fail(c, utils.CodeOrderItemNotFound, "Order item not found", nil)
 		return
 	}
 
@@ -94,8 +93,7 @@ func AdminReviewReturn(c *gin.Context) {
 
 		// Restore stock if item quality is good
 		if req.Quality == "good" {
-			if err := tx.Model(&models.Book{}).Where("id = ?", item.BookID).
-				UpdateColumn("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
+			if _, err := utils.RestockOrderItem(tx, &item, models.StockSourceReturn); err != nil {
 				tx.Rollback()
 				utils.InternalServerError(c, "Failed to restore book stock", nil)
 				return
@@ -103,31 +101,22 @@ func AdminReviewReturn(c *gin.Context) {
 		}
 
 		// Process refund
-		refundAmount := item.Total
-		wallet, err := utils.GetOrCreateWallet(order.UserID)
-		if err != nil {
+		refundAmount := item.Total + utils.PaymentRefundAdjustment(&order, item.Total)
+
+		// Credit the refund in the same transaction as the review
+		if _, err := utils.PostWalletEntry(tx, utils.WalletEntry{
+			UserID:      order.UserID,
+			Amount:      refundAmount,
+			Type:        models.TransactionTypeCredit,
+			Description: fmt.Sprintf("Refund for returned item in order #%d", orderID),
+			Reference:   fmt.Sprintf("REFUND-RETURN-ORDER-%d-ITEM-%d", orderID, itemID),
+			OrderID:     &order.ID,
+		}); err != nil {
 			tx.Rollback()
 			utils.InternalServerError(c, "Failed to process refund", nil)
 			return
 		}
 
-		// Create refund transaction
-		reference := fmt.Sprintf("REFUND-RETURN-ORDER-%d-ITEM-%d", orderID, itemID)
-		description := fmt.Sprintf("Refund for returned item in order #%d", orderID)
-
-		if _, err := utils.CreateWalletTransaction(wallet.ID, refundAmount, models.TransactionTypeCredit, description, &order.ID, reference); err != nil {
-			tx.Rollback()
-			utils.InternalServerError(c, "Failed to create refund transaction", nil)
-			return
-		}
-
-		// Update wallet balance
-		if err := utils.UpdateWalletBalance(wallet.ID, refundAmount, models.TransactionTypeCredit); err != nil {
-			tx.Rollback()
-			utils.InternalServerError(c, "Failed to update wallet balance", nil)
-			return
-		}
-
 		// Update refund status
 		now := time.Now()
 		item.RefundStatus = "completed"
@@ -137,7 +126,7 @@ func AdminReviewReturn(c *gin.Context) {
 	case "reject":
 		if req.Reason == "" {
 			tx.Rollback()
-			utils.BadRequest(c, "Reason is required when rejecting a return", nil)
+			utils.Fail(c, utils.CodeReasonRequired, "Reason is required when rejecting a return", nil)
 			return
 		}
 		item.ReturnStatus = models.OrderStatusReturnRejected
@@ -173,13 +162,17 @@ func AdminReviewReturn(c *gin.Context) {
 		utils.InternalServerError(c, "Failed to process return review", nil)
 		return
 	}
+	if req.Action == "approve" {
+		utils.RecordRefundIssued("return", item.RefundAmount)
+	}
 
+	formatter := utils.NewResponseFormatter(c)
 	utils.Success(c, fmt.Sprintf("Return request %s successfully", req.Action), gin.H{
 		"item": gin.H{
 			"id":            item.ID,
 			"return_status": item.ReturnStatus,
 			"refund_status": item.RefundStatus,
-			"refund_amount": fmt.Sprintf("%.2f", item.RefundAmount),
+			"refund_amount": formatter.Money(item.RefundAmount),
 		},
 		"order": gin.H{
 			"id":                    order.ID,
@@ -0,0 +1,480 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateShipmentRequest puts order items into a new shipment
+type CreateShipmentRequest struct {
+	ItemIDs        []uint `json:"item_ids" binding:"required,min=1"`
+	Courier        string `json:"courier" binding:"max=100"`
+	TrackingNumber string `json:"tracking_number" binding:"max=100"`
+}
+
+// UpdateShipmentStatusRequest moves a shipment forward
+type UpdateShipmentStatusRequest struct {
+	Status            string `json:"status" binding:"required,oneof=Shipped 'Out for Delivery' Delivered"`
+	Note              string `json:"note" binding:"max=500"`
+	Courier           string `json:"courier" binding:"max=100"`
+	TrackingNumber    string `json:"tracking_number" binding:"max=100"`
+	DeliveryReference string `json:"delivery_reference" binding:"max=100"` // OTP or signature reference from the courier
+}
+
+// shipmentStatusRanks orders the shipping statuses; order statuses before shipping rank 0
+var shipmentStatusRanks = map[string]int{
+	strings.ToLower(models.ShipmentStatusPending):        0,
+	strings.ToLower(models.ShipmentStatusShipped):        1,
+	strings.ToLower(models.ShipmentStatusOutForDelivery): 2,
+	strings.ToLower(models.ShipmentStatusDelivered):      3,
+}
+
+// shipmentStatusRank is how far along the shipping statuses a status is
+func shipmentStatusRank(status string) int {
+	return shipmentStatusRanks[strings.ToLower(status)]
+}
+
+// shipsPhysically reports whether an order item needs shipping: digital books and cancelled
+// items do not
+func shipsPhysically(item models.OrderItem) bool {
+	return !item.Book.IsDigital && item.CancellationStatus != "Cancelled" && item.CancellationStatus != "Approved"
+}
+
+// orderHasShippedItems reports whether any shipment of the order has left the warehouse
+func orderHasShippedItems(tx *gorm.DB, orderID uint) (bool, error) {
+	var shipped int64
+	err := tx.Model(&models.OrderShipment{}).
+		Where("order_id = ? AND status <> ?", orderID, models.ShipmentStatusPending).
+		Count(&shipped).Error
+	return shipped > 0, err
+}
+
+// advanceOrderShipments moves the order's shipments that are behind status up to it, so
+// shipments agree with a status set on the whole order
+func advanceOrderShipments(tx *gorm.DB, orderID uint, status string) error {
+	rank := shipmentStatusRank(status)
+	if rank == 0 {
+		return nil
+	}
+	var shipments []models.OrderShipment
+	if err := tx.Where("order_id = ?", orderID).Find(&shipments).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, shipment := range shipments {
+		if shipmentStatusRank(shipment.Status) >= rank {
+			continue
+		}
+		updates := map[string]interface{}{"status": status}
+		if shipment.ShippedAt == nil {
+			updates["shipped_at"] = now
+		}
+		if rank == shipmentStatusRank(models.ShipmentStatusDelivered) {
+			updates["delivered_at"] = now
+		}
+		if err := tx.Model(&shipment).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// derivedOrderStatus is the shipping status all of the order's physical items have reached,
+// or "" while some are not shipped yet
+func derivedOrderStatus(items []models.OrderItem, shipments []models.OrderShipment) string {
+	statusByShipment := make(map[uint]string, len(shipments))
+	for _, shipment := range shipments {
+		statusByShipment[shipment.ID] = shipment.Status
+	}
+	derived, lowest := "", -1
+	for _, item := range items {
+		if !shipsPhysically(item) {
+			continue
+		}
+		if item.ShipmentID == nil {
+			return ""
+		}
+		status := statusByShipment[*item.ShipmentID]
+		if rank := shipmentStatusRank(status); lowest == -1 || rank < lowest {
+			derived, lowest = status, rank
+		}
+	}
+	if lowest < 1 {
+		return ""
+	}
+	return derived
+}
+
+// orderShipmentsResponse loads the order's shipments and formats them with their items
+func orderShipmentsResponse(f utils.ResponseFormatter, db *gorm.DB, orderID uint, items []models.OrderItem) ([]gin.H, error) {
+	var shipments []models.OrderShipment
+	if err := db.Where("order_id = ?", orderID).Order("id").Find(&shipments).Error; err != nil {
+		return nil, err
+	}
+	return formatOrderShipments(f, shipments, items), nil
+}
+
+// formatOrderShipments lists shipments with the items in each. items must be the order's
+// items with their books loaded.
+func formatOrderShipments(f utils.ResponseFormatter, shipments []models.OrderShipment, items []models.OrderItem) []gin.H {
+	itemsByShipment := make(map[uint][]gin.H)
+	for _, item := range items {
+		if item.ShipmentID == nil {
+			continue
+		}
+		itemsByShipment[*item.ShipmentID] = append(itemsByShipment[*item.ShipmentID], gin.H{
+			"id":       item.ID,
+			"book_id":  item.BookID,
+			"name":     item.Book.Name,
+			"quantity": item.Quantity,
+		})
+	}
+	response := make([]gin.H, 0, len(shipments))
+	for _, shipment := range shipments {
+		entry := gin.H{
+			"id":              shipment.ID,
+			"status":          shipment.Status,
+			"courier":         shipment.Courier,
+			"tracking_number": shipment.TrackingNumber,
+			"items":           itemsByShipment[shipment.ID],
+			"created_at":      f.Timestamp(shipment.CreatedAt),
+		}
+		if shipment.ShippedAt != nil {
+			entry["shipped_at"] = f.Timestamp(*shipment.ShippedAt)
+		}
+		if shipment.DeliveredAt != nil {
+			entry["delivered_at"] = f.Timestamp(*shipment.DeliveredAt)
+		}
+		response = append(response, entry)
+	}
+	return response
+}
+
+// unshippedItemIDs lists the physical items of the order not yet put in a shipment
+func unshippedItemIDs(items []models.OrderItem) []uint {
+	ids := make([]uint, 0)
+	for _, item := range items {
+		if shipsPhysically(item) && item.ShipmentID == nil {
+			ids = append(ids, item.ID)
+		}
+	}
+	return ids
+}
+
+// AdminListOrderShipments lists an order's shipments and the items not yet shipped
+func AdminListOrderShipments(c *gin.Context) {
+	utils.LogInfo("AdminListOrderShipments called")
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.LogError("Invalid order ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
+		return
+	}
+	var order models.Order
+	if err := config.DB.Preload("OrderItems.Book").First(&order, orderID).Error; err != nil {
+		utils.LogError("Order not found: %v", err)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return
+	}
+
+	formatter := utils.NewResponseFormatter(c)
+	shipments, err := orderShipmentsResponse(formatter, config.DB, order.ID, order.OrderItems)
+	if err != nil {
+		utils.LogError("Failed to fetch shipments for order %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to fetch shipments", err.Error())
+		return
+	}
+
+	utils.LogInfo("Retrieved %d shipments for order %d", len(shipments), order.ID)
+	utils.Success(c, "Shipments retrieved successfully", gin.H{
+		"order_id":        order.ID,
+		"order_status":    order.Status,
+		"shipments":       shipments,
+		"unshipped_items": unshippedItemIDs(order.OrderItems),
+	})
+}
+
+// AdminCreateOrderShipment puts some of an order's items into a new shipment, so they can ship
+// apart from the rest of the order
+func AdminCreateOrderShipment(c *gin.Context) {
+	utils.LogInfo("AdminCreateOrderShipment called")
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.LogError("Invalid order ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
+		return
+	}
+	var req CreateShipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to begin transaction: %v", tx.Error)
+		utils.InternalServerError(c, "Failed to begin transaction", nil)
+		return
+	}
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("OrderItems.Book").First(&order, orderID).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Order not found: %v", err)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return
+	}
+
+	// Only orders waiting to ship or partly shipped can be split
+	if shipmentStatusRank(order.Status) >= shipmentStatusRank(models.ShipmentStatusDelivered) ||
+		order.Status == models.OrderStatusCancelled || strings.HasPrefix(order.Status, "Return") {
+		tx.Rollback()
+		utils.LogError("Order %d cannot be split into shipments, status: %s", order.ID, order.Status)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, fmt.Sprintf("A %s order cannot be split into shipments", order.Status), nil)
+		return
+	}
+	if order.Status == models.OrderStatusPlaced && utils.NormalizePaymentMethod(order.PaymentMethod) == "online" &&
+		order.PaymentStatus != models.PaymentStatusCompleted {
+		tx.Rollback()
+		utils.LogError("Order %d is awaiting payment", order.ID)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "This order is still awaiting payment", nil)
+		return
+	}
+
+	itemsByID := make(map[uint]models.OrderItem, len(order.OrderItems))
+	for _, item := range order.OrderItems {
+		itemsByID[item.ID] = item
+	}
+	seen := make(map[uint]bool, len(req.ItemIDs))
+	for _, itemID := range req.ItemIDs {
+		item, ok := itemsByID[itemID]
+		message := ""
+		switch {
+		case !ok:
+			message = "is not part of this order"
+		case seen[itemID]:
+			message = "is listed more than once"
+		case item.ShipmentID != nil:
+			message = fmt.Sprintf("is already in shipment %d", *item.ShipmentID)
+		case !shipsPhysically(item):
+			message = "is cancelled or digital and does not ship"
+		case item.PreorderPending:
+			message = "is a pre-order still waiting for stock"
+		}
+		if message != "" {
+			tx.Rollback()
+			utils.LogError("Cannot ship item %d of order %d: %s", itemID, order.ID, message)
+			utils.BadRequest(c, fmt.Sprintf("Item %d %s", itemID, message), gin.H{"item_id": itemID})
+			return
+		}
+		seen[itemID] = true
+	}
+
+	shipment := models.OrderShipment{
+		OrderID:        order.ID,
+		Status:         models.ShipmentStatusPending,
+		Courier:        strings.TrimSpace(req.Courier),
+		TrackingNumber: strings.TrimSpace(req.TrackingNumber),
+	}
+	if err := tx.Create(&shipment).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to create shipment for order %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to create shipment", err.Error())
+		return
+	}
+	if err := tx.Model(&models.OrderItem{}).Where("order_id = ? AND id IN ?", order.ID, req.ItemIDs).
+		Update("shipment_id", shipment.ID).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to assign items to shipment %d: %v", shipment.ID, err)
+		utils.InternalServerError(c, "Failed to create shipment", err.Error())
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit transaction: %v", err)
+		utils.InternalServerError(c, "Failed to save changes", nil)
+		return
+	}
+
+	for i := range order.OrderItems {
+		if seen[order.OrderItems[i].ID] {
+			order.OrderItems[i].ShipmentID = &shipment.ID
+		}
+	}
+	formatter := utils.NewResponseFormatter(c)
+	shipments, err := orderShipmentsResponse(formatter, config.DB, order.ID, order.OrderItems)
+	if err != nil {
+		utils.LogError("Failed to fetch shipments for order %d: %v", order.ID, err)
+	}
+
+	utils.LogInfo("Created shipment %d with %d items for order %d", shipment.ID, len(req.ItemIDs), order.ID)
+	utils.Success(c, "Shipment created successfully", gin.H{
+		"shipment_id":     shipment.ID,
+		"shipments":       shipments,
+		"unshipped_items": unshippedItemIDs(order.OrderItems),
+	})
+}
+
+// AdminUpdateShipmentStatus moves a shipment forward. When every physical item of the order
+// has reached a status, the order moves to it as well, with the same checks as a status change
+// made on the whole order.
+func AdminUpdateShipmentStatus(c *gin.Context) {
+	utils.LogInfo("AdminUpdateShipmentStatus called")
+
+	adminVal, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	admin := adminVal.(models.Admin)
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.LogError("Invalid order ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
+		return
+	}
+	shipmentID, err := strconv.Atoi(c.Param("shipment_id"))
+	if err != nil {
+		utils.LogError("Invalid shipment ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid shipment ID", nil)
+		return
+	}
+	var req UpdateShipmentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.BadRequest(c, "Status must be one of Shipped, Out for Delivery or Delivered", err)
+		return
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to begin transaction: %v", tx.Error)
+		utils.InternalServerError(c, "Failed to begin transaction", nil)
+		return
+	}
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("OrderItems.Book").First(&order, orderID).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Order not found: %v", err)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return
+	}
+	if order.Status == models.OrderStatusCancelled {
+		tx.Rollback()
+		utils.LogError("Order %d is cancelled", order.ID)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "This order is cancelled", nil)
+		return
+	}
+	var shipment models.OrderShipment
+	if err := tx.Where("id = ? AND order_id = ?", shipmentID, order.ID).First(&shipment).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Shipment %d not found for order %d: %v", shipmentID, order.ID, err)
+		utils.Fail(c, utils.CodeNotFound, "Shipment not found", nil)
+		return
+	}
+	if shipmentStatusRank(req.Status) <= shipmentStatusRank(shipment.Status) {
+		tx.Rollback()
+		utils.LogError("Shipment %d cannot move from %s to %s", shipment.ID, shipment.Status, req.Status)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, fmt.Sprintf("Shipment is already %s", shipment.Status), nil)
+		return
+	}
+	pending, err := pendingCancellationRequests(tx, order.ID, &shipment.ID)
+	if err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to count cancellation requests for shipment %d: %v", shipment.ID, err)
+		utils.InternalServerError(c, "Failed to update shipment", err.Error())
+		return
+	}
+	if pending > 0 {
+		tx.Rollback()
+		utils.LogError("Shipment %d has %d pending cancellation requests", shipment.ID, pending)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Review the pending cancellation requests of this shipment before shipping it", gin.H{"pending_requests": pending})
+		return
+	}
+
+	now := time.Now()
+	shipment.Status = req.Status
+	if courier := strings.TrimSpace(req.Courier); courier != "" {
+		shipment.Courier = courier
+	}
+	if tracking := strings.TrimSpace(req.TrackingNumber); tracking != "" {
+		shipment.TrackingNumber = tracking
+	}
+	if shipment.ShippedAt == nil {
+		shipment.ShippedAt = &now
+	}
+	if req.Status == models.ShipmentStatusDelivered {
+		shipment.DeliveredAt = &now
+		shipment.DeliveryReference = strings.TrimSpace(req.DeliveryReference)
+	}
+	if err := tx.Save(&shipment).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to update shipment %d: %v", shipment.ID, err)
+		utils.InternalServerError(c, "Failed to update shipment", err.Error())
+		return
+	}
+
+	// The order follows once all of its items have reached the status
+	var shipments []models.OrderShipment
+	if err := tx.Where("order_id = ?", order.ID).Find(&shipments).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to fetch shipments for order %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to update shipment", err.Error())
+		return
+	}
+	derived := derivedOrderStatus(order.OrderItems, shipments)
+	if derived != "" && shipmentStatusRank(derived) > shipmentStatusRank(order.Status) {
+		note := strings.TrimSpace(req.Note)
+		if note == "" {
+			note = fmt.Sprintf("All shipments %s", strings.ToLower(derived))
+		}
+		update := orderStatusUpdate{Status: derived, Note: note, DeliveryReference: req.DeliveryReference}
+		if err := applyAdminOrderStatus(tx, &order, update, admin); err != nil {
+			tx.Rollback()
+			var statusErr *orderStatusError
+			if errors.As(err, &statusErr) {
+				utils.LogError("Cannot move order %d to %s: %s", order.ID, derived, statusErr.Message)
+				utils.Fail(c, statusErr.Code, statusErr.Message, statusErr.Details)
+				return
+			}
+			utils.LogError("Failed to update status of order %d: %v", order.ID, err)
+			utils.InternalServerError(c, "Failed to update order status", nil)
+			return
+		}
+		utils.LogInfo("Order %d moved to %s with its last shipment", order.ID, derived)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit transaction: %v", err)
+		utils.InternalServerError(c, "Failed to save changes", nil)
+		return
+	}
+
+	formatter := utils.NewResponseFormatter(c)
+	response, err := orderShipmentsResponse(formatter, config.DB, order.ID, order.OrderItems)
+	if err != nil {
+		utils.LogError("Failed to fetch shipments for order %d: %v", order.ID, err)
+	}
+	utils.LogInfo("Shipment %d of order %d moved to %s by %s", shipment.ID, order.ID, shipment.Status, admin.Email)
+	utils.Success(c, "Shipment status updated successfully", gin.H{
+		"shipment_id":  shipment.ID,
+		"status":       shipment.Status,
+		"order_status": order.Status,
+		"shipments":    response,
+	})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -21,7 +22,7 @@ func AdminUpdateOrderStatus(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -37,13 +38,15 @@ func AdminUpdateOrderStatus(c *gin.Context) {
 	orderID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.LogError("Invalid order ID: %v", err)
-		utils.BadRequest(c, "Invalid order ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
 		return
 	}
 	utils.LogDebug("Processing order ID: %d", orderID)
 
 	var req struct {
-		Status string `json:"status" binding:"required"`
+		Status            string `json:"status" binding:"required"`
+		Note              string `json:"note"`
+		DeliveryReference string `json:"delivery_reference"` // OTP or signature reference from the courier
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || req.Status == "" {
 		utils.LogError("Invalid status in request: %v", err)
@@ -52,18 +55,10 @@ func AdminUpdateOrderStatus(c *gin.Context) {
 	}
 	utils.LogDebug("Requested status update to: %s", req.Status)
 
-	validStatuses := []string{"Pending", "Shipped", "Out for Delivery", "Delivered", "Cancelled"}
-	found := false
-	for _, s := range validStatuses {
-		if strings.EqualFold(s, req.Status) {
-			found = true
-			break
-		}
-	}
-	if !found {
+	if !validAdminOrderStatus(req.Status) {
 		utils.LogError("Invalid status requested: %s", req.Status)
 		utils.BadRequest(c, "Invalid status", gin.H{
-			"valid_statuses": validStatuses,
+			"valid_statuses": adminOrderStatuses,
 		})
 		return
 	}
@@ -81,63 +76,23 @@ func AdminUpdateOrderStatus(c *gin.Context) {
 	if err := tx.First(&order, orderID).Error; err != nil {
 		tx.Rollback()
 		utils.LogError("Order not found: %v", err)
-		utils.NotFound(c, "Order not found")
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 	utils.LogDebug("Found order with current status: %s", order.Status)
 
-	// Prevent cancellation if order is already shipped, delivered, or out for delivery
-	if strings.EqualFold(req.Status, "Cancelled") &&
-		(strings.EqualFold(order.Status, "Shipped") ||
-			strings.EqualFold(order.Status, "Delivered") ||
-			strings.EqualFold(order.Status, "Out for Delivery")) {
-		tx.Rollback()
-		utils.LogError("Cannot cancel order %d: already %s", orderID, order.Status)
-		utils.BadRequest(c, "Cannot cancel an order that is already shipped, out for delivery, or delivered", nil)
-		return
-	}
-
-	// If status is being set to Cancelled and order is not shipped, restock books
-	shouldRestock := false
-	if strings.EqualFold(req.Status, "Cancelled") &&
-		(strings.EqualFold(order.Status, "Pending") ||
-			strings.EqualFold(order.Status, "Processing")) {
-		shouldRestock = true
-		utils.LogDebug("Order will be restocked due to cancellation")
-	}
-
-	order.Status = req.Status
-	order.UpdatedAt = time.Now()
-
-	if err := tx.Save(&order).Error; err != nil {
+	update := orderStatusUpdate{Status: req.Status, Note: req.Note, DeliveryReference: req.DeliveryReference}
+	if err := applyAdminOrderStatus(tx, &order, update, adminModel); err != nil {
 		tx.Rollback()
-		utils.LogError("Failed to update order status: %v", err)
-		utils.InternalServerError(c, "Failed to update order status", nil)
-		return
-	}
-	utils.LogDebug("Updated order status to: %s", order.Status)
-
-	if shouldRestock {
-		var items []models.OrderItem
-		if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
-			tx.Rollback()
-			utils.LogError("Failed to fetch order items: %v", err)
-			utils.InternalServerError(c, "Failed to fetch order items", nil)
+		var statusErr *orderStatusError
+		if errors.As(err, &statusErr) {
+			utils.LogError("Cannot move order %d to %s: %s", orderID, req.Status, statusErr.Message)
+			utils.Fail(c, statusErr.Code, statusErr.Message, statusErr.Details)
 			return
 		}
-		utils.LogDebug("Found %d items to restock", len(items))
-
-		for _, item := range items {
-			if err := tx.Model(&models.Book{}).
-				Where("id = ?", item.BookID).
-				UpdateColumn("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
-				tx.Rollback()
-				utils.LogError("Failed to update book stock for book %d: %v", item.BookID, err)
-				utils.InternalServerError(c, "Failed to update book stock", nil)
-				return
-			}
-			utils.LogDebug("Restocked book %d with %d units", item.BookID, item.Quantity)
-		}
+		utils.LogError("Failed to update status of order %d: %v", orderID, err)
+		utils.InternalServerError(c, "Failed to update order status", nil)
+		return
 	}
 
 	// Commit transaction
@@ -147,6 +102,7 @@ func AdminUpdateOrderStatus(c *gin.Context) {
 		return
 	}
 	utils.LogDebug("Successfully committed transaction")
+	emailOrderStatus(order.ID, order.Status, req.Note)
 
 	// Reload full order with all required relations for response
 	var fullOrder models.Order
@@ -165,6 +121,7 @@ func AdminUpdateOrderStatus(c *gin.Context) {
 
 	// Prepare items response
 	var items []gin.H
+	formatter := utils.NewResponseFormatter(c)
 	for _, item := range fullOrder.OrderItems {
 		items = append(items, gin.H{
 			"id":       item.ID,
@@ -172,8 +129,8 @@ func AdminUpdateOrderStatus(c *gin.Context) {
 			"category": item.Book.Category.Name,
 			"genre":    item.Book.Genre.Name,
 			"quantity": item.Quantity,
-			"price":    fmt.Sprintf("%.2f", item.Price),
-			"total":    fmt.Sprintf("%.2f", item.Total),
+			"price":    formatter.Money(item.Price),
+			"total":    formatter.Money(item.Total),
 		})
 	}
 	utils.LogDebug("Prepared response for %d order items", len(items))
@@ -185,17 +142,169 @@ func AdminUpdateOrderStatus(c *gin.Context) {
 			"username":            fullOrder.User.Username,
 			"email":               fullOrder.User.Email,
 			"status":              fullOrder.Status,
-			"total_amount":        fmt.Sprintf("%.2f", fullOrder.TotalAmount),
-			"discount":            fmt.Sprintf("%.2f", fullOrder.Discount),
-			"coupon_discount":     fmt.Sprintf("%.2f", fullOrder.CouponDiscount),
+			"total_amount":        formatter.Money(fullOrder.TotalAmount),
+			"discount":            formatter.Money(fullOrder.Discount),
+			"coupon_discount":     formatter.Money(fullOrder.CouponDiscount),
 			"coupon_code":         fullOrder.CouponCode,
-			"delivery_charge":     fmt.Sprintf("%.2f", fullOrder.DeliveryCharge),
-			"total_with_delivery": fmt.Sprintf("%.2f", fullOrder.TotalWithDelivery),
-			"final_total":         fmt.Sprintf("%.2f", fullOrder.FinalTotal),
-			"created_at":          fullOrder.CreatedAt.Format("2006-01-02 15:04:05"),
-			"updated_at":          fullOrder.UpdatedAt.Format("2006-01-02 15:04:05"),
+			"delivery_charge":     formatter.Money(fullOrder.DeliveryCharge),
+			"payment_adjustment":  formatter.Money(fullOrder.PaymentAdjustment),
+			"total_with_delivery": formatter.Money(fullOrder.TotalWithDelivery),
+			"final_total":         formatter.Money(fullOrder.FinalTotal),
+			"created_at":          formatter.Timestamp(fullOrder.CreatedAt),
+			"updated_at":          formatter.Timestamp(fullOrder.UpdatedAt),
 			"payment_mode":        fullOrder.PaymentMethod,
 			"items":               items,
 		},
 	})
 }
+
+// adminOrderStatuses are the statuses an admin can move an order to
+var adminOrderStatuses = []string{"Pending", "Shipped", "Out for Delivery", "Delivered", "Cancelled"}
+
+// validAdminOrderStatus reports whether an admin can move an order to the status
+func validAdminOrderStatus(status string) bool {
+	for _, s := range adminOrderStatuses {
+		if strings.EqualFold(s, status) {
+			return true
+		}
+	}
+	return false
+}
+
+// orderStatusUpdate is an admin's request to move an order to a status
+type orderStatusUpdate struct {
+	Status            string
+	Note              string
+	DeliveryReference string // OTP or signature reference from the courier
+}
+
+// orderStatusError is the reason an order cannot move to the requested status
+type orderStatusError struct {
+	Code    utils.ErrorCode
+	Message string
+	Details interface{}
+}
+
+func (e *orderStatusError) Error() string {
+	return e.Message
+}
+
+// applyAdminOrderStatus moves the order to the requested status inside tx: the transition is
+// validated, the change is added to the order timeline, cancelled orders are restocked and
+// lose their digital books, and delivered Cash on Delivery orders earn their cashback. An
+// *orderStatusError explains a refused transition; any other error is a failed update.
+func applyAdminOrderStatus(tx *gorm.DB, order *models.Order, update orderStatusUpdate, admin models.Admin) error {
+	// Prevent cancellation if order is already shipped, delivered, or out for delivery
+	if strings.EqualFold(update.Status, "Cancelled") &&
+		(strings.EqualFold(order.Status, "Shipped") ||
+			strings.EqualFold(order.Status, "Delivered") ||
+			strings.EqualFold(order.Status, "Out for Delivery")) {
+		return &orderStatusError{Code: utils.CodeOrderStatusInvalid, Message: "Cannot cancel an order that is already shipped, out for delivery, or delivered"}
+	}
+	if strings.EqualFold(update.Status, models.OrderStatusCancelled) {
+		shipped, err := orderHasShippedItems(tx, order.ID)
+		if err != nil {
+			return err
+		}
+		if shipped {
+			return &orderStatusError{Code: utils.CodeOrderStatusInvalid, Message: "Cannot cancel an order with a shipment already on its way"}
+		}
+	}
+
+	// Cash on delivery orders are only delivered once the customer's OTP confirms the handover
+	if strings.EqualFold(update.Status, models.OrderStatusDelivered) && utils.DeliveryOTPRequired(*order) {
+		return &orderStatusError{
+			Code:    utils.CodeDeliveryOTPRequired,
+			Message: "Verify the customer's delivery OTP before marking this Cash on Delivery order as delivered",
+			Details: gin.H{"verify_url": fmt.Sprintf("/v1/admin/orders/%d/delivery-otp/verify", order.ID)},
+		}
+	}
+
+	// Pre-orders ship once their books are released and stock is allocated to them
+	if order.IsPreorder && !strings.EqualFold(update.Status, models.OrderStatusCancelled) && !strings.EqualFold(update.Status, "Pending") {
+		var waiting int64
+		if err := tx.Model(&models.OrderItem{}).Where("order_id = ? AND preorder_pending = ?", order.ID, true).Count(&waiting).Error; err != nil {
+			return err
+		}
+		if waiting > 0 {
+			return &orderStatusError{
+				Code:    utils.CodeOrderStatusInvalid,
+				Message: "This pre-order ships once its books are released",
+				Details: gin.H{"waiting_items": waiting},
+			}
+		}
+	}
+
+	// Items the customer asked to cancel are reviewed before they leave the warehouse
+	if strings.EqualFold(update.Status, models.OrderStatusShipped) || strings.EqualFold(update.Status, "Out for Delivery") ||
+		strings.EqualFold(update.Status, models.OrderStatusDelivered) {
+		pending, err := pendingCancellationRequests(tx, order.ID, nil)
+		if err != nil {
+			return err
+		}
+		if pending > 0 {
+			return &orderStatusError{
+				Code:    utils.CodeOrderStatusInvalid,
+				Message: "Review the pending cancellation requests of this order before shipping it",
+				Details: gin.H{"pending_requests": pending},
+			}
+		}
+	}
+
+	// If status is being set to Cancelled and order is not shipped, restock books
+	shouldRestock := strings.EqualFold(update.Status, "Cancelled") &&
+		(strings.EqualFold(order.Status, "Pending") ||
+			strings.EqualFold(order.Status, "Processing"))
+
+	order.Status = update.Status
+	order.UpdatedAt = time.Now()
+	if strings.EqualFold(update.Status, models.OrderStatusDelivered) {
+		now := time.Now()
+		order.DeliveredAt = &now
+		if reference := strings.TrimSpace(update.DeliveryReference); reference != "" {
+			order.DeliveryReference = reference
+		}
+	}
+	if err := tx.Save(order).Error; err != nil {
+		return fmt.Errorf("failed to save order: %w", err)
+	}
+	if err := recordOrderStatusEvent(tx, order.ID, order.Status, "admin", admin.ID, update.Note); err != nil {
+		return fmt.Errorf("failed to record order status event: %w", err)
+	}
+	// Shipments of a split order keep up with a status set on the whole order
+	if err := advanceOrderShipments(tx, order.ID, order.Status); err != nil {
+		return fmt.Errorf("failed to update shipments: %w", err)
+	}
+
+	if shouldRestock {
+		var items []models.OrderItem
+		if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+			return fmt.Errorf("failed to fetch order items: %w", err)
+		}
+		restocked, err := utils.RestockOrderItems(tx, items, models.StockSourceCancellation)
+		if err != nil {
+			return fmt.Errorf("failed to update book stock: %w", err)
+		}
+		utils.LogDebug("Restocked %d items of order %d", restocked, order.ID)
+	}
+
+	// Cash on delivery orders are paid on delivery, so their cashback is earned then
+	if strings.EqualFold(update.Status, "Delivered") && utils.NormalizePaymentMethod(order.PaymentMethod) == "cod" {
+		if err := utils.CreditPaymentCashback(tx, order); err != nil {
+			return fmt.Errorf("failed to credit payment cashback: %w", err)
+		}
+		if err := utils.GrantDigitalEntitlements(tx, order); err != nil {
+			return fmt.Errorf("failed to grant digital books: %w", err)
+		}
+	}
+
+	if strings.EqualFold(update.Status, models.OrderStatusCancelled) {
+		if err := utils.RevokeDigitalEntitlements(tx, order.ID); err != nil {
+			return fmt.Errorf("failed to revoke digital books: %w", err)
+		}
+		if _, err := utils.ReleaseWalletHold(tx, order.UserID, order.ID); err != nil {
+			return fmt.Errorf("failed to release wallet hold: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// parsePaymentMethodParam validates the :method route parameter
+func parsePaymentMethodParam(c *gin.Context) (string, bool) {
+	method := strings.ToLower(strings.TrimSpace(c.Param("method")))
+	for _, m := range utils.PaymentMethods {
+		if m == method {
+			return method, true
+		}
+	}
+	utils.LogError("Invalid payment method: %s", method)
+	utils.Fail(c, utils.CodePaymentMethod, "Invalid payment method. Must be one of: cod, online, wallet", nil)
+	return "", false
+}
+
+// GetPaymentAdjustments returns the configured fee/discount/cashback rule of every payment method
+func GetPaymentAdjustments(c *gin.Context) {
+	utils.LogInfo("GetPaymentAdjustments called")
+
+	var adjustments []models.PaymentMethodAdjustment
+	if err := config.DB.Order("payment_method ASC").Find(&adjustments).Error; err != nil {
+		utils.LogError("Failed to fetch payment adjustments: %v", err)
+		utils.InternalServerError(c, "Failed to fetch payment adjustments", err.Error())
+		return
+	}
+
+	utils.Success(c, "Payment adjustments retrieved successfully", gin.H{
+		"payment_adjustments": adjustments,
+	})
+}
+
+// UpsertPaymentAdjustment creates or replaces the pricing rule for a payment method
+func UpsertPaymentAdjustment(c *gin.Context) {
+	utils.LogInfo("UpsertPaymentAdjustment called")
+
+	method, ok := parsePaymentMethodParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		AdjustmentType string  `json:"adjustment_type" binding:"required,oneof=fee discount cashback"`
+		Percent        float64 `json:"percent" binding:"min=0,max=100"`
+		FlatAmount     float64 `json:"flat_amount" binding:"min=0"`
+		MaxAmount      float64 `json:"max_amount" binding:"min=0"`
+		MinOrderAmount float64 `json:"min_order_amount" binding:"min=0"`
+		Description    string  `json:"description"`
+		IsActive       *bool   `json:"is_active"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+	if req.Percent == 0 && req.FlatAmount == 0 {
+		utils.BadRequest(c, "Either percent or flat_amount must be set", nil)
+		return
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var adjustment models.PaymentMethodAdjustment
+	config.DB.Where("payment_method = ?", method).First(&adjustment)
+	adjustment.PaymentMethod = method
+	adjustment.AdjustmentType = req.AdjustmentType
+	adjustment.Percent = req.Percent
+	adjustment.FlatAmount = req.FlatAmount
+	adjustment.MaxAmount = req.MaxAmount
+	adjustment.MinOrderAmount = req.MinOrderAmount
+	adjustment.Description = req.Description
+	adjustment.IsActive = isActive
+
+	if err := config.DB.Save(&adjustment).Error; err != nil {
+		utils.LogError("Failed to save payment adjustment for %s: %v", method, err)
+		utils.InternalServerError(c, "Failed to save payment adjustment", err.Error())
+		return
+	}
+
+	utils.LogInfo("Saved %s adjustment for payment method %s", adjustment.AdjustmentType, method)
+	utils.Success(c, "Payment adjustment saved successfully", gin.H{
+		"payment_adjustment": adjustment,
+	})
+}
+
+// DeletePaymentAdjustment removes the pricing rule for a payment method
+func DeletePaymentAdjustment(c *gin.Context) {
+	utils.LogInfo("DeletePaymentAdjustment called")
+
+	method, ok := parsePaymentMethodParam(c)
+	if !ok {
+		return
+	}
+
+	result := config.DB.Where("payment_method = ?", method).Delete(&models.PaymentMethodAdjustment{})
+	if result.Error != nil {
+		utils.LogError("Failed to delete payment adjustment for %s: %v", method, result.Error)
+		utils.InternalServerError(c, "Failed to delete payment adjustment", result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.NotFound(c, "No adjustment configured for this payment method")
+		return
+	}
+
+	utils.LogInfo("Deleted adjustment for payment method %s", method)
+	utils.Success(c, "Payment adjustment deleted successfully", nil)
+}
@@ -9,6 +9,38 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// formatProductOffer converts a product offer into its API representation
+func formatProductOffer(f utils.ResponseFormatter, offer models.ProductOffer) gin.H {
+	return gin.H{
+		"id":               offer.ID,
+		"product_id":       offer.ProductID,
+		"discount_percent": offer.DiscountPercent,
+		"start_date":       offer.StartDate.Format("2006-01-02"),
+		"end_date":         offer.EndDate.Format("2006-01-02"),
+		"active":           offer.Active,
+		"exclusive":        offer.Exclusive,
+		"publish_at":       f.OptionalTimestamp(offer.PublishAt),
+		"unpublish_at":     f.OptionalTimestamp(offer.UnpublishAt),
+		"is_expired":       time.Now().After(offer.EndDate),
+	}
+}
+
+// formatCategoryOffer converts a category offer into its API representation
+func formatCategoryOffer(f utils.ResponseFormatter, offer models.CategoryOffer) gin.H {
+	return gin.H{
+		"id":               offer.ID,
+		"category_id":      offer.CategoryID,
+		"discount_percent": offer.DiscountPercent,
+		"start_date":       offer.StartDate.Format("2006-01-02"),
+		"end_date":         offer.EndDate.Format("2006-01-02"),
+		"active":           offer.Active,
+		"exclusive":        offer.Exclusive,
+		"publish_at":       f.OptionalTimestamp(offer.PublishAt),
+		"unpublish_at":     f.OptionalTimestamp(offer.UnpublishAt),
+		"is_expired":       time.Now().After(offer.EndDate),
+	}
+}
+
 // ---- Product Offer CRUD ----
 func CreateProductOffer(c *gin.Context) {
 	utils.LogInfo("CreateProductOffer called")
@@ -18,10 +50,11 @@ func CreateProductOffer(c *gin.Context) {
 		DiscountPercent float64 `json:"discount_percent" binding:"required"`
 		StartDate       string  `json:"start_date" binding:"required"` // ISO8601
 		EndDate         string  `json:"end_date" binding:"required"`
+		Exclusive       bool    `json:"exclusive"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request data: %v", err)
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 	utils.LogDebug("Received request for product %d with %f%% discount", req.ProductID, req.DiscountPercent)
@@ -37,7 +70,7 @@ func CreateProductOffer(c *gin.Context) {
 	end, err2 := time.Parse(time.RFC3339, req.EndDate)
 	if err1 != nil || err2 != nil {
 		utils.LogError("Invalid date format: start=%v, end=%v", err1, err2)
-		utils.BadRequest(c, "Invalid date format. Use RFC3339.", nil)
+		utils.Fail(c, utils.CodeInvalidDate, "Invalid date format. Use RFC3339.", nil)
 		return
 	}
 	utils.LogDebug("Parsed dates - Start: %s, End: %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
@@ -48,13 +81,27 @@ func CreateProductOffer(c *gin.Context) {
 		utils.BadRequest(c, "End date cannot be in the past", nil)
 		return
 	}
+	if !end.After(start) {
+		utils.LogError("End date %s is not after start date %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+		utils.BadRequest(c, "End date must be after start date", nil)
+		return
+	}
+
+	var book models.Book
+	if err := config.DB.First(&book, req.ProductID).Error; err != nil {
+		utils.LogError("Book not found for offer: %d", req.ProductID)
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
 
-	// Check for existing active offers for this product
+	// Check for active offers of this product overlapping the new window
 	var existingOffer models.ProductOffer
-	if err := config.DB.Where("product_id = ? AND active = ? AND end_date > ?",
-		req.ProductID, true, time.Now()).First(&existingOffer).Error; err == nil {
-		utils.LogError("Active offer already exists for product %d", req.ProductID)
-		utils.BadRequest(c, "An active offer already exists for this product", nil)
+	if err := config.DB.Where("product_id = ? AND active = ? AND start_date <= ? AND end_date >= ?",
+		req.ProductID, true, end, start).First(&existingOffer).Error; err == nil {
+		utils.LogError("Active offer %d already overlaps these dates for product %d", existingOffer.ID, req.ProductID)
+		utils.BadRequest(c, "An active offer already exists for this product in these dates", gin.H{
+			"existing_offer": formatProductOffer(utils.NewResponseFormatter(c), existingOffer),
+		})
 		return
 	}
 	utils.LogDebug("No active offers found for product %d", req.ProductID)
@@ -65,6 +112,7 @@ func CreateProductOffer(c *gin.Context) {
 		StartDate:       start,
 		EndDate:         end,
 		Active:          true,
+		Exclusive:       req.Exclusive,
 	}
 
 	if err := config.DB.Create(&offer).Error; err != nil {
@@ -76,15 +124,7 @@ func CreateProductOffer(c *gin.Context) {
 
 	utils.LogInfo("Successfully created product offer for product %d", req.ProductID)
 	utils.Success(c, "Product offer created successfully", gin.H{
-		"offer": gin.H{
-			"id":               offer.ID,
-			"product_id":       offer.ProductID,
-			"discount_percent": offer.DiscountPercent,
-			"start_date":       offer.StartDate.Format("2006-01-02"),
-			"end_date":         offer.EndDate.Format("2006-01-02"),
-			"active":           offer.Active,
-			"is_expired":       time.Now().After(offer.EndDate),
-		},
+		"offer": formatProductOffer(utils.NewResponseFormatter(c), offer),
 	})
 }
 
@@ -99,17 +139,10 @@ func ListProductOffers(c *gin.Context) {
 	}
 	utils.LogDebug("Retrieved %d product offers", len(offers))
 
+	formatter := utils.NewResponseFormatter(c)
 	var formattedOffers []gin.H
 	for _, offer := range offers {
-		formattedOffers = append(formattedOffers, gin.H{
-			"id":               offer.ID,
-			"product_id":       offer.ProductID,
-			"discount_percent": offer.DiscountPercent,
-			"start_date":       offer.StartDate.Format("2006-01-02"),
-			"end_date":         offer.EndDate.Format("2006-01-02"),
-			"active":           offer.Active,
-			"is_expired":       time.Now().After(offer.EndDate),
-		})
+		formattedOffers = append(formattedOffers, formatProductOffer(formatter, offer))
 	}
 
 	utils.LogInfo("Successfully retrieved %d product offers", len(formattedOffers))
@@ -126,6 +159,7 @@ func UpdateProductOffer(c *gin.Context) {
 		StartDate       string  `json:"start_date"`
 		EndDate         string  `json:"end_date"`
 		Active          *bool   `json:"active"`
+		Exclusive       *bool   `json:"exclusive"`
 	}
 	id := c.Param("id")
 	utils.LogDebug("Updating offer with ID: %s", id)
@@ -133,14 +167,13 @@ func UpdateProductOffer(c *gin.Context) {
 	var offer models.ProductOffer
 	if err := config.DB.First(&offer, id).Error; err != nil {
 		utils.LogError("Offer not found: %v", err)
-		utils.NotFound(c, "Offer not found")
+		utils.Fail(c, utils.CodeOfferNotFound, "Offer not found", nil)
 		return
 	}
 	utils.LogDebug("Found existing offer for product %d", offer.ProductID)
-
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request data: %v", err)
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 
@@ -161,7 +194,7 @@ func UpdateProductOffer(c *gin.Context) {
 			utils.LogDebug("Updated start date to %s", t.Format(time.RFC3339))
 		} else {
 			utils.LogError("Invalid start date format: %v", err)
-			utils.BadRequest(c, "Invalid start date format. Use RFC3339.", nil)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid start date format. Use RFC3339.", nil)
 			return
 		}
 	}
@@ -177,7 +210,7 @@ func UpdateProductOffer(c *gin.Context) {
 			utils.LogDebug("Updated end date to %s", t.Format(time.RFC3339))
 		} else {
 			utils.LogError("Invalid end date format: %v", err)
-			utils.BadRequest(c, "Invalid end date format. Use RFC3339.", nil)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid end date format. Use RFC3339.", nil)
 			return
 		}
 	}
@@ -185,6 +218,27 @@ func UpdateProductOffer(c *gin.Context) {
 		offer.Active = *req.Active
 		utils.LogDebug("Updated active status to %v", *req.Active)
 	}
+	if req.Exclusive != nil {
+		offer.Exclusive = *req.Exclusive
+		utils.LogDebug("Updated exclusive flag to %v", *req.Exclusive)
+	}
+	if !offer.EndDate.After(offer.StartDate) {
+		utils.LogError("End date %s is not after start date %s", offer.EndDate.Format(time.RFC3339), offer.StartDate.Format(time.RFC3339))
+		utils.BadRequest(c, "End date must be after start date", nil)
+		return
+	}
+
+	if offer.Active {
+		var overlapping models.ProductOffer
+		if err := config.DB.Where("id != ? AND product_id = ? AND active = ? AND start_date <= ? AND end_date >= ?",
+			offer.ID, offer.ProductID, true, offer.EndDate, offer.StartDate).First(&overlapping).Error; err == nil {
+			utils.LogError("Offer %d would overlap active offer %d", offer.ID, overlapping.ID)
+			utils.BadRequest(c, "An active offer already exists for this product in these dates", gin.H{
+				"existing_offer": formatProductOffer(utils.NewResponseFormatter(c), overlapping),
+			})
+			return
+		}
+	}
 
 	if err := config.DB.Save(&offer).Error; err != nil {
 		utils.LogError("Failed to update offer: %v", err)
@@ -194,15 +248,7 @@ func UpdateProductOffer(c *gin.Context) {
 
 	utils.LogInfo("Successfully updated product offer %d", offer.ID)
 	utils.Success(c, "Product offer updated successfully", gin.H{
-		"offer": gin.H{
-			"id":               offer.ID,
-			"product_id":       offer.ProductID,
-			"discount_percent": offer.DiscountPercent,
-			"start_date":       offer.StartDate.Format("2006-01-02"),
-			"end_date":         offer.EndDate.Format("2006-01-02"),
-			"active":           offer.Active,
-			"is_expired":       time.Now().After(offer.EndDate),
-		},
+		"offer": formatProductOffer(utils.NewResponseFormatter(c), offer),
 	})
 }
 
@@ -212,6 +258,9 @@ func DeleteProductOffer(c *gin.Context) {
 	id := c.Param("id")
 	utils.LogDebug("Deleting offer with ID: %s", id)
 
+	var deletedOffer models.ProductOffer
+	config.DB.First(&deletedOffer, id)
+
 	result := config.DB.Delete(&models.ProductOffer{}, id)
 	if result.Error != nil {
 		utils.LogError("Failed to delete offer: %v", result.Error)
@@ -220,7 +269,7 @@ func DeleteProductOffer(c *gin.Context) {
 	}
 	if result.RowsAffected == 0 {
 		utils.LogError("Product offer not found: %s", id)
-		utils.NotFound(c, "Product offer not found")
+		utils.Fail(c, utils.CodeOfferNotFound, "Product offer not found", nil)
 		return
 	}
 
@@ -237,10 +286,11 @@ func CreateCategoryOffer(c *gin.Context) {
 		DiscountPercent float64 `json:"discount_percent" binding:"required"`
 		StartDate       string  `json:"start_date" binding:"required"`
 		EndDate         string  `json:"end_date" binding:"required"`
+		Exclusive       bool    `json:"exclusive"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request data: %v", err)
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 	utils.LogDebug("Received request for category %d with %f%% discount", req.CategoryID, req.DiscountPercent)
@@ -256,7 +306,7 @@ func CreateCategoryOffer(c *gin.Context) {
 	end, err2 := time.Parse(time.RFC3339, req.EndDate)
 	if err1 != nil || err2 != nil {
 		utils.LogError("Invalid date format: start=%v, end=%v", err1, err2)
-		utils.BadRequest(c, "Invalid date format. Use RFC3339.", nil)
+		utils.Fail(c, utils.CodeInvalidDate, "Invalid date format. Use RFC3339.", nil)
 		return
 	}
 	utils.LogDebug("Parsed dates - Start: %s, End: %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
@@ -267,13 +317,27 @@ func CreateCategoryOffer(c *gin.Context) {
 		utils.BadRequest(c, "End date cannot be in the past", nil)
 		return
 	}
+	if !end.After(start) {
+		utils.LogError("End date %s is not after start date %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+		utils.BadRequest(c, "End date must be after start date", nil)
+		return
+	}
+
+	var category models.Category
+	if err := config.DB.First(&category, req.CategoryID).Error; err != nil {
+		utils.LogError("Category not found for offer: %d", req.CategoryID)
+		utils.Fail(c, utils.CodeCategoryNotFound, "Category not found", nil)
+		return
+	}
 
-	// Check for existing active offers for this category
+	// Check for active offers of this category overlapping the new window
 	var existingOffer models.CategoryOffer
-	if err := config.DB.Where("category_id = ? AND active = ? AND end_date > ?",
-		req.CategoryID, true, time.Now()).First(&existingOffer).Error; err == nil {
-		utils.LogError("Active offer already exists for category %d", req.CategoryID)
-		utils.BadRequest(c, "An active offer already exists for this category", nil)
+	if err := config.DB.Where("category_id = ? AND active = ? AND start_date <= ? AND end_date >= ?",
+		req.CategoryID, true, end, start).First(&existingOffer).Error; err == nil {
+		utils.LogError("Active offer %d already overlaps these dates for category %d", existingOffer.ID, req.CategoryID)
+		utils.BadRequest(c, "An active offer already exists for this category in these dates", gin.H{
+			"existing_offer": formatCategoryOffer(utils.NewResponseFormatter(c), existingOffer),
+		})
 		return
 	}
 	utils.LogDebug("No active offers found for category %d", req.CategoryID)
@@ -284,6 +348,7 @@ func CreateCategoryOffer(c *gin.Context) {
 		StartDate:       start,
 		EndDate:         end,
 		Active:          true,
+		Exclusive:       req.Exclusive,
 	}
 
 	if err := config.DB.Create(&offer).Error; err != nil {
@@ -295,14 +360,23 @@ func CreateCategoryOffer(c *gin.Context) {
 
 	utils.LogInfo("Successfully created category offer for category %d", req.CategoryID)
 	utils.Success(c, "Category offer created successfully", gin.H{
-		"offer": gin.H{
-			"id":               offer.ID,
-			"category_id":      offer.CategoryID,
-			"discount_percent": offer.DiscountPercent,
-			"start_date":       offer.StartDate.Format("2006-01-02"),
-			"end_date":         offer.EndDate.Format("2006-01-02"),
-			"active":           offer.Active,
-			"is_expired":       time.Now().After(offer.EndDate),
-		},
+		"offer": formatCategoryOffer(utils.NewResponseFormatter(c), offer),
+	})
+}
+
+// GetProductOffer returns a single product offer
+func GetProductOffer(c *gin.Context) {
+	utils.LogInfo("GetProductOffer called")
+
+	id := c.Param("id")
+	var offer models.ProductOffer
+	if err := config.DB.First(&offer, id).Error; err != nil {
+		utils.LogError("Product offer not found: %s", id)
+		utils.Fail(c, utils.CodeOfferNotFound, "Product offer not found", nil)
+		return
+	}
+
+	utils.Success(c, "Product offer retrieved successfully", gin.H{
+		"offer": formatProductOffer(utils.NewResponseFormatter(c), offer),
 	})
 }
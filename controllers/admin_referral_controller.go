@@ -46,7 +46,7 @@ func GenerateReferralToken(c *gin.Context) {
 		UserID uint `json:"user_id" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 
@@ -148,6 +148,7 @@ func GetAllUserReferralCodes(c *gin.Context) {
 
 	// Format response with referral statistics
 	formattedCodes := make([]gin.H, len(userCodes))
+	formatter := utils.NewResponseFormatter(c)
 	for i, userCode := range userCodes {
 		// Get referral count for this user
 		var referralCount int64
@@ -168,7 +169,7 @@ func GetAllUserReferralCodes(c *gin.Context) {
 			"referral_url":    "/referral/" + userCode.ReferralCode,
 			"total_referrals": referralCount,
 			"is_active":       userCode.IsActive,
-			"created_at":      userCode.CreatedAt.Format("2006-01-02 15:04:05"),
+			"created_at":      formatter.Timestamp(userCode.CreatedAt),
 		}
 	}
 
@@ -185,7 +186,7 @@ func GetUserReferralStats(c *gin.Context) {
 	userIDStr := c.Param("user_id")
 	userID, err := strconv.ParseUint(userIDStr, 10, 32)
 	if err != nil {
-		utils.BadRequest(c, "Invalid user ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid user ID", nil)
 		return
 	}
 
@@ -210,6 +211,7 @@ func GetUserReferralStats(c *gin.Context) {
 
 	// Format referral details
 	formattedReferrals := make([]gin.H, len(referrals))
+	formatter := utils.NewResponseFormatter(c)
 	for i, referral := range referrals {
 		formattedReferrals[i] = gin.H{
 			"id": referral.ID,
@@ -220,18 +222,18 @@ func GetUserReferralStats(c *gin.Context) {
 				"first_name": referral.ReferredUser.FirstName,
 				"last_name":  referral.ReferredUser.LastName,
 			},
-			"used_at": referral.UsedAt.Format("2006-01-02 15:04:05"),
+			"used_at": formatter.Timestamp(referral.UsedAt),
 			"referrer_coupon": gin.H{
 				"id":       referral.ReferrerCouponID,
 				"code":     referral.ReferrerCoupon.Code,
-				"discount": referral.ReferrerCoupon.Value,
+				"discount": couponValueResponse(formatter, referral.ReferrerCoupon),
 				"type":     referral.ReferrerCoupon.Type,
 				"expiry":   referral.ReferrerCoupon.Expiry.Format("2006-01-02"),
 			},
 			"referred_coupon": gin.H{
 				"id":       referral.ReferredCouponID,
 				"code":     referral.ReferredCoupon.Code,
-				"discount": referral.ReferredCoupon.Value,
+				"discount": couponValueResponse(formatter, referral.ReferredCoupon),
 				"type":     referral.ReferredCoupon.Type,
 				"expiry":   referral.ReferredCoupon.Expiry.Format("2006-01-02"),
 			},
@@ -250,7 +252,7 @@ func GetUserReferralStats(c *gin.Context) {
 		"referral_url":    "/referral/" + userCode.ReferralCode,
 		"total_referrals": len(referrals),
 		"is_active":       userCode.IsActive,
-		"created_at":      userCode.CreatedAt.Format("2006-01-02 15:04:05"),
+		"created_at":      formatter.Timestamp(userCode.CreatedAt),
 		"referrals":       formattedReferrals,
 	})
 }
@@ -264,7 +266,7 @@ func ToggleReferralCodeStatus(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 
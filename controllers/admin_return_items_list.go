@@ -18,7 +18,7 @@ func AdminListReturnItems(c *gin.Context) {
 	_, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found", nil)
 		return
 	}
 
@@ -70,6 +70,7 @@ func AdminListReturnItems(c *gin.Context) {
 	itemCount := 0
 	startIdx := (page - 1) * limit
 
+	formatter := utils.NewResponseFormatter(c)
 	// Process orders and collect items with pagination
 	for _, order := range orders {
 		utils.LogDebug("Processing order ID: %d, Status: %s, HasItemReturnRequests: %v", order.ID, order.Status, order.HasItemReturnRequests)
@@ -106,36 +107,36 @@ func AdminListReturnItems(c *gin.Context) {
 					"item_id":      item.ID,
 					"book_name":    item.Book.Name,
 					"quantity":     item.Quantity,
-					"total":        item.Total,
+					"total":        formatter.Money(item.Total),
 					"reason":       item.ReturnReason,
 					"status":       item.ReturnStatus,
-					"requested_at": order.UpdatedAt.Format("2006-01-02 15:04:05"),
+					"requested_at": formatter.Timestamp(order.UpdatedAt),
 				}
 
 				// Calculate refund amount based on item total
 				refundAmount := item.Total
-				req["refund_amount"] = refundAmount
+				req["refund_amount"] = formatter.Money(refundAmount)
 
 				// Handle different return statuses (support both old and new formats)
 				switch item.ReturnStatus {
 				case models.OrderStatusReturnApproved, "Approved":
 					if item.RefundedAt != nil {
-						req["processed_at"] = item.RefundedAt.Format("2006-01-02 15:04:05")
+						req["processed_at"] = formatter.Timestamp(*item.RefundedAt)
 						req["refund_status"] = "completed"
-						req["refund_amount"] = item.RefundAmount // Use actual refunded amount if available
+						req["refund_amount"] = formatter.Money(item.RefundAmount) // Use actual refunded amount if available
 					} else {
-						req["processed_at"] = order.UpdatedAt.Format("2006-01-02 15:04:05")
+						req["processed_at"] = formatter.Timestamp(order.UpdatedAt)
 						req["refund_status"] = "processing"
-						req["refund_amount"] = refundAmount
+						req["refund_amount"] = formatter.Money(refundAmount)
 					}
 				case models.OrderStatusReturnRejected, "Rejected":
-					req["processed_at"] = order.UpdatedAt.Format("2006-01-02 15:04:05")
+					req["processed_at"] = formatter.Timestamp(order.UpdatedAt)
 					req["reject_reason"] = item.ReturnReason
 					req["refund_status"] = "rejected"
-					req["refund_amount"] = 0.0 // No refund for rejected items
+					req["refund_amount"] = formatter.Money(0) // No refund for rejected items
 				case models.OrderStatusReturnRequested, "Pending":
 					req["refund_status"] = "pending"
-					req["refund_amount"] = refundAmount
+					req["refund_amount"] = formatter.Money(refundAmount)
 				}
 
 				requests = append(requests, req)
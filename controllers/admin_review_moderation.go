@@ -0,0 +1,384 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxBulkReviews caps how many reviews one bulk moderation request may touch
+const maxBulkReviews = 100
+
+// BulkReviewModerationRequest approves or rejects several reviews at once. Flagged reviews are
+// only approved with include_flagged.
+type BulkReviewModerationRequest struct {
+	ReviewIDs      []uint `json:"review_ids" binding:"required,min=1,max=100"`
+	Reason         string `json:"reason" binding:"max=500"`
+	IncludeFlagged bool   `json:"include_flagged"`
+}
+
+// BannedWordsRequest adds words or phrases to the banned word list
+type BannedWordsRequest struct {
+	Words []string `json:"words" binding:"required,min=1,max=100,dive,max=100"`
+}
+
+// bulkReviewResult is the outcome of moderating one review
+type bulkReviewResult struct {
+	ReviewID uint   `json:"review_id"`
+	Success  bool   `json:"success"`
+	Status   string `json:"status,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// AdminListReviews is the review moderation queue: reviews by status, pending by default and
+// oldest first, filtered by flag, abuse reports, book, rating, verified purchase or a search in
+// the comment. Reported reviews come most reported first.
+func AdminListReviews(c *gin.Context) {
+	utils.LogInfo("AdminListReviews called")
+
+	status := c.DefaultQuery("status", models.ReviewStatusPending)
+	query := config.DB.Model(&models.Review{})
+	switch status {
+	case models.ReviewStatusPending, models.ReviewStatusApproved, models.ReviewStatusRejected:
+		query = query.Where("status = ?", status)
+	case "all":
+	default:
+		utils.LogError("Invalid review status: %s", status)
+		utils.BadRequest(c, "Invalid status", "status must be pending, approved, rejected or all")
+		return
+	}
+	if flagged := c.Query("flagged"); flagged != "" {
+		value, err := strconv.ParseBool(flagged)
+		if err != nil {
+			utils.BadRequest(c, "flagged must be true or false", nil)
+			return
+		}
+		query = query.Where("is_flagged = ?", value)
+	}
+	if verified := c.Query("verified_purchase"); verified != "" {
+		value, err := strconv.ParseBool(verified)
+		if err != nil {
+			utils.BadRequest(c, "verified_purchase must be true or false", nil)
+			return
+		}
+		query = query.Where("verified_purchase = ?", value)
+	}
+	if bookID := c.Query("book_id"); bookID != "" {
+		id, err := strconv.ParseUint(bookID, 10, 32)
+		if err != nil {
+			utils.Fail(c, utils.CodeInvalidID, "Invalid book ID", nil)
+			return
+		}
+		query = query.Where("book_id = ?", id)
+	}
+	if rating := c.Query("rating"); rating != "" {
+		value, err := strconv.Atoi(rating)
+		if err != nil || value < 1 || value > 5 {
+			utils.BadRequest(c, "rating must be between 1 and 5", nil)
+			return
+		}
+		query = query.Where("rating = ?", value)
+	}
+	order := "created_at ASC, id ASC"
+	if reported := c.Query("reported"); reported != "" {
+		value, err := strconv.ParseBool(reported)
+		if err != nil {
+			utils.BadRequest(c, "reported must be true or false", nil)
+			return
+		}
+		if value {
+			query = query.Where("report_count > 0")
+			order = "report_count DESC, created_at ASC, id ASC"
+		} else {
+			query = query.Where("report_count = 0")
+		}
+	}
+	if hidden := c.Query("hidden"); hidden != "" {
+		value, err := strconv.ParseBool(hidden)
+		if err != nil {
+			utils.BadRequest(c, "hidden must be true or false", nil)
+			return
+		}
+		query = query.Where("is_hidden = ?", value)
+	}
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		query = query.Where("comment ILIKE ?", "%"+search+"%")
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count reviews: %v", err)
+		utils.InternalServerError(c, "Failed to fetch reviews", err.Error())
+		return
+	}
+	var reviews []models.Review
+	if err := query.Preload("User").Preload("Images").Order(order).
+		Offset((page - 1) * limit).Limit(limit).Find(&reviews).Error; err != nil {
+		utils.LogError("Failed to fetch reviews: %v", err)
+		utils.InternalServerError(c, "Failed to fetch reviews", err.Error())
+		return
+	}
+
+	bookIDs := make([]uint, 0, len(reviews))
+	for _, review := range reviews {
+		bookIDs = append(bookIDs, review.BookID)
+	}
+	var books []models.Book
+	if err := config.DB.Unscoped().Select("id", "name").Where("id IN ?", bookIDs).Find(&books).Error; err != nil {
+		utils.LogError("Failed to fetch books of reviews: %v", err)
+		utils.InternalServerError(c, "Failed to fetch reviews", err.Error())
+		return
+	}
+	bookNames := make(map[uint]string, len(books))
+	for _, book := range books {
+		bookNames[book.ID] = book.Name
+	}
+
+	list := make([]gin.H, len(reviews))
+	formatter := utils.NewResponseFormatter(c)
+	for i, review := range reviews {
+		imageURLs := make([]string, len(review.Images))
+		for j, image := range review.Images {
+			imageURLs[j] = image.URL
+		}
+		entry := gin.H{
+			"id":                review.ID,
+			"book_id":           review.BookID,
+			"book_name":         bookNames[review.BookID],
+			"user_id":           review.UserID,
+			"username":          review.User.Username,
+			"rating":            review.Rating,
+			"comment":           review.Comment,
+			"image_urls":        imageURLs,
+			"verified_purchase": review.VerifiedPurchase,
+			"status":            review.Status,
+			"is_flagged":        review.IsFlagged,
+			"flagged_words":     review.FlaggedWords,
+			"report_count":      review.ReportCount,
+			"is_hidden":         review.IsHidden,
+			"rejection_reason":  review.RejectionReason,
+			"created_at":        formatter.Timestamp(review.CreatedAt),
+		}
+		if review.ModeratedAt != nil {
+			entry["moderated_at"] = formatter.Timestamp(*review.ModeratedAt)
+			entry["moderated_by"] = review.ModeratedBy
+		}
+		list[i] = entry
+	}
+
+	utils.LogInfo("Retrieved %d of %d %s reviews", len(list), total, status)
+	utils.SuccessWithPagination(c, "Reviews retrieved successfully", gin.H{
+		"reviews": list,
+		"status":  status,
+	}, total, page, limit)
+}
+
+// AdminBulkApproveReviews approves several reviews. Flagged reviews are skipped unless
+// include_flagged is set.
+func AdminBulkApproveReviews(c *gin.Context) {
+	utils.LogInfo("AdminBulkApproveReviews called")
+	moderateReviews(c, models.ReviewStatusApproved)
+}
+
+// AdminBulkRejectReviews rejects several reviews, with an optional reason
+func AdminBulkRejectReviews(c *gin.Context) {
+	utils.LogInfo("AdminBulkRejectReviews called")
+	moderateReviews(c, models.ReviewStatusRejected)
+}
+
+// moderateReviews moves the requested reviews to the status and reports each review's outcome
+func moderateReviews(c *gin.Context, status string) {
+	adminVal, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	admin := adminVal.(models.Admin)
+
+	var req BulkReviewModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", gin.H{
+			"error":       err.Error(),
+			"max_reviews": maxBulkReviews,
+		})
+		return
+	}
+
+	var reviews []models.Review
+	if err := config.DB.Where("id IN ?", req.ReviewIDs).Find(&reviews).Error; err != nil {
+		utils.LogError("Failed to fetch reviews: %v", err)
+		utils.InternalServerError(c, "Failed to fetch reviews", err.Error())
+		return
+	}
+	byID := make(map[uint]models.Review, len(reviews))
+	for _, review := range reviews {
+		byID[review.ID] = review
+	}
+
+	// Each review is reported once, in the order given
+	seen := make(map[uint]bool, len(req.ReviewIDs))
+	results := make([]bulkReviewResult, 0, len(req.ReviewIDs))
+	var eligible []uint
+	for _, id := range req.ReviewIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result := bulkReviewResult{ReviewID: id}
+		review, ok := byID[id]
+		switch {
+		case !ok:
+			result.Error = "Review not found"
+		case review.Status == status:
+			result.Error = "Review is already " + status
+		case status == models.ReviewStatusApproved && review.IsFlagged && !req.IncludeFlagged:
+			result.Error = "Review contains banned words: " + review.FlaggedWords
+		default:
+			result.Success, result.Status = true, status
+			eligible = append(eligible, id)
+		}
+		results = append(results, result)
+	}
+
+	if len(eligible) > 0 {
+		updates := map[string]interface{}{
+			"status":           status,
+			"is_approved":      status == models.ReviewStatusApproved,
+			"rejection_reason": "",
+			"moderated_by":     admin.ID,
+			"moderated_at":     time.Now(),
+		}
+		if status == models.ReviewStatusApproved {
+			// Approving a review hidden after abuse reports shows it again
+			updates["is_hidden"] = false
+			updates["hidden_at"] = nil
+		}
+		if status == models.ReviewStatusRejected {
+			updates["rejection_reason"] = strings.TrimSpace(req.Reason)
+		}
+		if err := config.DB.Model(&models.Review{}).Where("id IN ?", eligible).Updates(updates).Error; err != nil {
+			utils.LogError("Failed to moderate reviews: %v", err)
+			utils.InternalServerError(c, "Failed to update reviews", err.Error())
+			return
+		}
+	}
+
+	utils.LogInfo("Admin %s moved %d of %d reviews to %s", admin.Email, len(eligible), len(results), status)
+	utils.Success(c, "Bulk review moderation completed", gin.H{
+		"status":        status,
+		"total_count":   len(results),
+		"updated_count": len(eligible),
+		"failed_count":  len(results) - len(eligible),
+		"results":       results,
+	})
+}
+
+// AdminListBannedWords lists the words that get reviews flagged
+func AdminListBannedWords(c *gin.Context) {
+	utils.LogInfo("AdminListBannedWords called")
+
+	var words []models.BannedWord
+	if err := config.DB.Order("word").Find(&words).Error; err != nil {
+		utils.LogError("Failed to fetch banned words: %v", err)
+		utils.InternalServerError(c, "Failed to fetch banned words", err.Error())
+		return
+	}
+	utils.Success(c, "Banned words retrieved successfully", gin.H{
+		"banned_words": words,
+	})
+}
+
+// AdminAddBannedWords adds words or phrases to the banned word list and checks the pending
+// reviews against the new list
+func AdminAddBannedWords(c *gin.Context) {
+	utils.LogInfo("AdminAddBannedWords called")
+
+	adminVal, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	admin := adminVal.(models.Admin)
+
+	var req BannedWordsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+
+	var added []models.BannedWord
+	for _, word := range req.Words {
+		normalized := utils.NormalizeBannedWord(word)
+		if normalized == "" {
+			continue
+		}
+		banned := models.BannedWord{Word: normalized, AddedBy: admin.ID}
+		result := config.DB.Where("word = ?", normalized).FirstOrCreate(&banned)
+		if result.Error != nil {
+			utils.LogError("Failed to add banned word: %v", result.Error)
+			utils.InternalServerError(c, "Failed to add banned words", result.Error.Error())
+			return
+		}
+		if result.RowsAffected > 0 {
+			added = append(added, banned)
+		}
+	}
+
+	flagged, err := utils.RescanPendingReviews(config.DB)
+	if err != nil {
+		utils.LogError("Failed to rescan pending reviews: %v", err)
+		utils.InternalServerError(c, "Banned words added, but pending reviews could not be rechecked", err.Error())
+		return
+	}
+
+	utils.LogInfo("Admin %s added %d banned words; %d pending reviews flagged", admin.Email, len(added), flagged)
+	utils.Success(c, "Banned words added successfully", gin.H{
+		"added":                   added,
+		"flagged_pending_reviews": flagged,
+	})
+}
+
+// AdminDeleteBannedWord removes a word from the banned word list and clears the flag of pending
+// reviews that no longer contain banned words
+func AdminDeleteBannedWord(c *gin.Context) {
+	utils.LogInfo("AdminDeleteBannedWord called")
+
+	wordID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid banned word ID: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeInvalidID, "Invalid banned word ID", nil)
+		return
+	}
+	result := config.DB.Delete(&models.BannedWord{}, wordID)
+	if result.Error != nil {
+		utils.LogError("Failed to delete banned word %d: %v", wordID, result.Error)
+		utils.InternalServerError(c, "Failed to delete banned word", result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.NotFound(c, "Banned word not found")
+		return
+	}
+
+	flagged, err := utils.RescanPendingReviews(config.DB)
+	if err != nil {
+		utils.LogError("Failed to rescan pending reviews: %v", err)
+		utils.InternalServerError(c, "Banned word deleted, but pending reviews could not be rechecked", err.Error())
+		return
+	}
+
+	utils.LogInfo("Deleted banned word %d; %d pending reviews flagged", wordID, flagged)
+	utils.Success(c, "Banned word deleted successfully", gin.H{
+		"flagged_pending_reviews": flagged,
+	})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"fmt"
 	"math"
 	"time"
 
@@ -53,14 +54,14 @@ func GenerateSalesReport(c *gin.Context) {
 		startDate, err = time.Parse("2006-01-02", startDateStr)
 		if err != nil {
 			utils.LogError("Invalid start date format: %v", err)
-			utils.BadRequest(c, "Invalid start date", "Start date must be in YYYY-MM-DD format")
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid start date", "Start date must be in YYYY-MM-DD format")
 			return
 		}
 
 		endDate, err = time.Parse("2006-01-02", endDateStr)
 		if err != nil {
 			utils.LogError("Invalid end date format: %v", err)
-			utils.BadRequest(c, "Invalid end date", "End date must be in YYYY-MM-DD format")
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid end date", "End date must be in YYYY-MM-DD format")
 			return
 		}
 
@@ -71,13 +72,13 @@ func GenerateSalesReport(c *gin.Context) {
 		// Validate date range
 		if endDate.Before(startDate) {
 			utils.LogError("Invalid date range: end date before start date")
-			utils.BadRequest(c, "Invalid date range", "End date must be after start date")
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid date range", "End date must be after start date")
 			return
 		}
 
 		if endDate.Sub(startDate) > 90*24*time.Hour {
 			utils.LogError("Date range exceeds 90 days: %s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
-			utils.BadRequest(c, "Invalid date range", "Date range cannot exceed 90 days")
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid date range", "Date range cannot exceed 90 days")
 			return
 		}
 	default:
@@ -152,18 +153,44 @@ func GenerateSalesReport(c *gin.Context) {
 	utils.LogDebug("Summary calculated - Sales: %d, Revenue: %.2f, Items: %d, Customers: %d",
 		summary.TotalSales, summary.TotalRevenue, summary.TotalItems, summary.TotalCustomers)
 
+	// Export the same filtered orders as CSV if requested
+	if utils.WantsCSV(c) {
+		headers := []string{"Order ID", "User ID", "User Name", "Date", "Items", "Total", "Discount", "Net Amount", "Payment Mode", "Status"}
+		rows := make([][]string, 0, len(orders))
+		for _, order := range orders {
+			rows = append(rows, []string{
+				fmt.Sprintf("%d", order.ID),
+				fmt.Sprintf("%d", order.UserID),
+				order.User.Username,
+				order.CreatedAt.Format("2006-01-02 15:04:05"),
+				fmt.Sprintf("%d", len(order.OrderItems)),
+				fmt.Sprintf("%.2f", order.TotalAmount),
+				fmt.Sprintf("%.2f", order.Discount+order.CouponDiscount),
+				fmt.Sprintf("%.2f", order.TotalAmount-order.Discount-order.CouponDiscount),
+				order.PaymentMethod,
+				order.Status,
+			})
+		}
+		if err := utils.WriteCSV(c, "sales_report_"+period, headers, rows); err != nil {
+			utils.LogError("Failed to write CSV file: %v", err)
+			utils.InternalServerError(c, "Failed to write CSV file", err.Error())
+		}
+		return
+	}
+
 	// Format sales data for response
 	var salesData []gin.H
+	formatter := utils.NewResponseFormatter(c)
 	for _, order := range orders {
 		// Include all orders in the sales data
 		salesData = append(salesData, gin.H{
 			"order_id":      order.ID,
-			"date":          order.CreatedAt.Format("2006-01-02 15:04:05"),
+			"date":          formatter.Timestamp(order.CreatedAt),
 			"customer_name": order.User.Username,
 			"items":         len(order.OrderItems),
-			"total":         math.Round(order.TotalAmount*100) / 100,
-			"discount":      math.Round((order.Discount+order.CouponDiscount)*100) / 100,
-			"net_amount":    math.Round((order.TotalAmount-order.Discount-order.CouponDiscount)*100) / 100,
+			"total":         formatter.Money(order.TotalAmount),
+			"discount":      formatter.Money(order.Discount + order.CouponDiscount),
+			"net_amount":    formatter.Money(order.TotalAmount - order.Discount - order.CouponDiscount),
 			"payment_mode":  order.PaymentMethod,
 			"status":        order.Status,
 		})
@@ -173,10 +200,17 @@ func GenerateSalesReport(c *gin.Context) {
 	utils.Success(c, "Sales report generated successfully", gin.H{
 		"period": gin.H{
 			"type":       period,
-			"start_date": startDate.Format("2006-01-02 15:04:05"),
-			"end_date":   endDate.Format("2006-01-02 15:04:05"),
+			"start_date": formatter.Timestamp(startDate),
+			"end_date":   formatter.Timestamp(endDate),
 		},
 		"summary": summary,
 		"sales":   salesData,
 	})
 }
+
+// Admin: Download sales report as CSV
+func DownloadSalesReportCSV(c *gin.Context) {
+	utils.LogInfo("DownloadSalesReportCSV called")
+	utils.ForceCSVExport(c)
+	GenerateSalesReport(c)
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetScheduledJobs lists the background jobs with their schedule and last-run status
+func GetScheduledJobs(c *gin.Context) {
+	utils.LogInfo("GetScheduledJobs called")
+
+	var jobs []models.ScheduledJob
+	if err := config.DB.Order("name ASC").Find(&jobs).Error; err != nil {
+		utils.LogError("Failed to fetch scheduled jobs: %v", err)
+		utils.InternalServerError(c, "Failed to fetch scheduled jobs", err.Error())
+		return
+	}
+
+	utils.Success(c, "Scheduled jobs retrieved successfully", gin.H{
+		"jobs": jobs,
+	})
+}
+
+// RunScheduledJob runs a job immediately, outside its schedule
+func RunScheduledJob(c *gin.Context) {
+	utils.LogInfo("RunScheduledJob called")
+
+	name := c.Param("name")
+	job, err := utils.RunJobNow(name)
+	switch {
+	case errors.Is(err, utils.ErrJobNotFound):
+		utils.NotFound(c, "Scheduled job not found")
+		return
+	case errors.Is(err, utils.ErrJobLocked):
+		utils.Fail(c, utils.CodeJobRunning, "Scheduled job is already running", nil)
+		return
+	case err != nil:
+		utils.LogError("Scheduled job %s failed: %v", name, err)
+		utils.InternalServerError(c, "Scheduled job failed", gin.H{
+			"error": err.Error(),
+			"job":   job,
+		})
+		return
+	}
+
+	utils.LogInfo("Scheduled job %s run manually", name)
+	utils.Success(c, "Scheduled job completed successfully", gin.H{
+		"job": job,
+	})
+}
+
+// UpdateScheduledJobRequest enables or pauses a job
+type UpdateScheduledJobRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// UpdateScheduledJob enables or pauses a job's schedule. Paused jobs can still be run manually.
+func UpdateScheduledJob(c *gin.Context) {
+	utils.LogInfo("UpdateScheduledJob called")
+
+	var req UpdateScheduledJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+
+	var job models.ScheduledJob
+	if err := config.DB.Where("name = ?", c.Param("name")).First(&job).Error; err != nil {
+		utils.LogError("Scheduled job not found: %s", c.Param("name"))
+		utils.NotFound(c, "Scheduled job not found")
+		return
+	}
+
+	if err := config.DB.Model(&job).Update("enabled", *req.Enabled).Error; err != nil {
+		utils.LogError("Failed to update scheduled job %s: %v", job.Name, err)
+		utils.InternalServerError(c, "Failed to update scheduled job", err.Error())
+		return
+	}
+
+	utils.LogInfo("Scheduled job %s enabled=%t", job.Name, job.Enabled)
+	utils.Success(c, "Scheduled job updated successfully", gin.H{
+		"job": job,
+	})
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateSettingsRequest maps setting keys to their new values
+type UpdateSettingsRequest struct {
+	Settings map[string]float64 `json:"settings" binding:"required"`
+}
+
+// AdminGetSettings lists the store settings with their current values, defaults and allowed ranges
+func AdminGetSettings(c *gin.Context) {
+	utils.LogInfo("AdminGetSettings called")
+
+	settings, err := utils.GetSettings()
+	if err != nil {
+		utils.LogError("Failed to fetch settings: %v", err)
+		utils.InternalServerError(c, "Failed to fetch settings", err.Error())
+		return
+	}
+
+	utils.LogInfo("Retrieved %d settings", len(settings))
+	utils.Success(c, "Settings retrieved successfully", gin.H{
+		"settings": settings,
+	})
+}
+
+// AdminUpdateSettings changes one or more store settings. The new values apply to all
+// instances within a minute.
+func AdminUpdateSettings(c *gin.Context) {
+	utils.LogInfo("AdminUpdateSettings called")
+
+	adminVal, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	admin := adminVal.(models.Admin)
+
+	var req UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+	if len(req.Settings) == 0 {
+		utils.BadRequest(c, "No settings to update", nil)
+		return
+	}
+	for key, value := range req.Settings {
+		if err := utils.ValidateSetting(key, value); err != nil {
+			utils.LogError("Invalid setting %s=%v: %v", key, value, err)
+			utils.BadRequest(c, "Invalid setting value", err.Error())
+			return
+		}
+	}
+
+	if err := utils.UpdateSettings(req.Settings, admin.ID); err != nil {
+		utils.LogError("Failed to update settings: %v", err)
+		utils.InternalServerError(c, "Failed to update settings", err.Error())
+		return
+	}
+
+	settings, err := utils.GetSettings()
+	if err != nil {
+		utils.LogError("Failed to fetch settings: %v", err)
+		utils.InternalServerError(c, "Failed to fetch settings", err.Error())
+		return
+	}
+
+	utils.LogInfo("Admin %d updated %d settings", admin.ID, len(req.Settings))
+	utils.Success(c, "Settings updated successfully", gin.H{
+		"settings": settings,
+	})
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminGetSupportTickets is the support queue: tickets filtered by status, age, user or order,
+// the ones waiting longest first. min_age_hours keeps tickets opened at least that long ago;
+// waiting_hours keeps tickets whose customer has waited that long for a reply.
+func AdminGetSupportTickets(c *gin.Context) {
+	utils.LogInfo("AdminGetSupportTickets called")
+
+	page, limit := utils.GetPaginationParams(c)
+	now := time.Now()
+	query := config.DB.Model(&models.SupportTicket{})
+
+	if status := c.Query("status"); status != "" {
+		if !supportTicketStatuses[status] {
+			utils.BadRequest(c, "Invalid status", "Status must be open, pending or resolved")
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if orderID := c.Query("order_id"); orderID != "" {
+		query = query.Where("order_id = ?", orderID)
+	}
+	if search := c.Query("search"); search != "" {
+		query = query.Where("subject ILIKE ?", "%"+search+"%")
+	}
+	if minAge := c.Query("min_age_hours"); minAge != "" {
+		hours, err := strconv.Atoi(minAge)
+		if err != nil || hours < 0 {
+			utils.BadRequest(c, "Invalid min_age_hours", "min_age_hours must be a whole number of hours")
+			return
+		}
+		query = query.Where("created_at <= ?", now.Add(-time.Duration(hours)*time.Hour))
+	}
+	if waiting := c.Query("waiting_hours"); waiting != "" {
+		hours, err := strconv.Atoi(waiting)
+		if err != nil || hours < 0 {
+			utils.BadRequest(c, "Invalid waiting_hours", "waiting_hours must be a whole number of hours")
+			return
+		}
+		query = query.Where("status = ? AND last_sender = ? AND last_message_at <= ?",
+			models.TicketStatusOpen, models.TicketSenderUser, now.Add(-time.Duration(hours)*time.Hour))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count support tickets: %v", err)
+		utils.InternalServerError(c, "Failed to fetch support tickets", nil)
+		return
+	}
+
+	order := "last_message_at ASC, id ASC"
+	if c.Query("sort") == "newest" {
+		order = "created_at DESC, id DESC"
+	}
+	var tickets []models.SupportTicket
+	if err := query.Preload("User").Order(order).Offset((page - 1) * limit).Limit(limit).
+		Find(&tickets).Error; err != nil {
+		utils.LogError("Failed to fetch support tickets: %v", err)
+		utils.InternalServerError(c, "Failed to fetch support tickets", nil)
+		return
+	}
+
+	response := make([]gin.H, len(tickets))
+	formatter := utils.NewResponseFormatter(c)
+	for i, ticket := range tickets {
+		response[i] = adminSupportTicketResponse(formatter, ticket, now, false)
+	}
+
+	utils.LogInfo("Retrieved %d support tickets", len(response))
+	utils.SuccessWithPagination(c, "Support tickets retrieved successfully", gin.H{
+		"tickets": response,
+		"filters": gin.H{
+			"status":        c.Query("status"),
+			"user_id":       c.Query("user_id"),
+			"order_id":      c.Query("order_id"),
+			"search":        c.Query("search"),
+			"min_age_hours": c.Query("min_age_hours"),
+			"waiting_hours": c.Query("waiting_hours"),
+		},
+	}, total, page, limit)
+}
+
+// AdminGetSupportTicket returns a ticket with its conversation and customer
+func AdminGetSupportTicket(c *gin.Context) {
+	utils.LogInfo("AdminGetSupportTicket called")
+
+	ticket, ok := loadSupportTicket(c, config.DB.Preload("User"))
+	if !ok {
+		return
+	}
+
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Support ticket retrieved successfully", gin.H{
+		"ticket": adminSupportTicketResponse(formatter, ticket, time.Now(), true),
+	})
+}
+
+// AdminReplySupportTicket adds a support reply to a ticket and notifies the customer. The
+// ticket becomes pending on the customer unless status says otherwise.
+func AdminReplySupportTicket(c *gin.Context) {
+	utils.LogInfo("AdminReplySupportTicket called")
+
+	adminVal, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	admin, ok := adminVal.(models.Admin)
+	if !ok {
+		utils.LogError("Invalid admin type in context")
+		utils.InternalServerError(c, "Invalid admin type", nil)
+		return
+	}
+
+	var req SupportTicketMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid support ticket reply: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	text := strings.TrimSpace(req.Message)
+	if text == "" {
+		utils.BadRequest(c, "Message cannot be empty", nil)
+		return
+	}
+	status := req.Status
+	if status == "" {
+		status = models.TicketStatusPending
+	}
+	if !supportTicketStatuses[status] {
+		utils.BadRequest(c, "Invalid status", "Status must be open, pending or resolved")
+		return
+	}
+
+	ticket, ok := loadSupportTicket(c, config.DB.Preload("User"))
+	if !ok {
+		return
+	}
+
+	message := models.SupportTicketMessage{
+		TicketID:   ticket.ID,
+		SenderType: models.TicketSenderAdmin,
+		SenderID:   admin.ID,
+		SenderName: "ReadSphere Support",
+		Message:    text,
+	}
+	if err := addSupportTicketMessage(config.DB, &ticket, &message, status); err != nil {
+		utils.LogError("Failed to add reply to support ticket %d: %v", ticket.ID, err)
+		utils.InternalServerError(c, "Failed to add reply", nil)
+		return
+	}
+
+	if _, err := utils.Notify(config.DB, ticket.UserID, models.NotificationTypeSupport,
+		"Support replied to your ticket", fmt.Sprintf("New reply on \"%s\"", ticket.Subject),
+		fmt.Sprintf("/v1/user/support/tickets/%d", ticket.ID)); err != nil {
+		utils.LogError("Failed to notify user %d of reply on support ticket %d: %v", ticket.UserID, ticket.ID, err)
+	}
+
+	utils.LogInfo("Admin %s replied to support ticket %d, now %s", admin.Email, ticket.ID, ticket.Status)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Created(c, "Reply added successfully", gin.H{
+		"ticket": adminSupportTicketResponse(formatter, ticket, time.Now(), true),
+	})
+}
+
+// AdminUpdateSupportTicketStatus moves a ticket to another status without replying
+func AdminUpdateSupportTicketStatus(c *gin.Context) {
+	utils.LogInfo("AdminUpdateSupportTicketStatus called")
+
+	var req struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid support ticket status request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	if !supportTicketStatuses[req.Status] {
+		utils.BadRequest(c, "Invalid status", "Status must be open, pending or resolved")
+		return
+	}
+
+	ticket, ok := loadSupportTicket(c, config.DB.Preload("User"))
+	if !ok {
+		return
+	}
+	if ticket.Status == req.Status {
+		utils.BadRequest(c, "Ticket already has this status", gin.H{"status": ticket.Status})
+		return
+	}
+
+	if err := setSupportTicketStatus(config.DB, &ticket, req.Status); err != nil {
+		utils.LogError("Failed to update status of support ticket %d: %v", ticket.ID, err)
+		utils.InternalServerError(c, "Failed to update support ticket", nil)
+		return
+	}
+
+	utils.LogInfo("Support ticket %d is now %s", ticket.ID, ticket.Status)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Support ticket updated successfully", gin.H{
+		"ticket": adminSupportTicketResponse(formatter, ticket, time.Now(), true),
+	})
+}
+
+// adminSupportTicketResponse is the admin view of a ticket: the customer's view plus who the
+// customer is and how long the ticket has been open and waiting
+func adminSupportTicketResponse(f utils.ResponseFormatter, ticket models.SupportTicket, now time.Time, withMessages bool) gin.H {
+	response := supportTicketResponse(f, ticket, withMessages)
+	response["user"] = gin.H{
+		"id":       ticket.UserID,
+		"username": ticket.User.Username,
+		"email":    ticket.User.Email,
+	}
+	response["age_hours"] = int(now.Sub(ticket.CreatedAt).Hours())
+	if ticket.Status == models.TicketStatusOpen && ticket.LastSender == models.TicketSenderUser {
+		response["waiting_hours"] = int(now.Sub(ticket.LastMessageAt).Hours())
+	}
+	return response
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// topSellerDimension describes how order items are grouped for the top sellers report
+type topSellerDimension struct {
+	key   string // grouping expression, also used as the row ID
+	label string // display name expression
+	joins []string
+}
+
+var topSellerDimensions = map[string]topSellerDimension{
+	"book": {
+		key:   "books.id",
+		label: "books.name",
+	},
+	"author": {
+		key:   "books.author",
+		label: "books.author",
+	},
+	"category": {
+		key:   "categories.id",
+		label: "categories.name",
+		joins: []string{"JOIN categories ON categories.id = books.category_id"},
+	},
+	"genre": {
+		key:   "genres.id",
+		label: "genres.name",
+		joins: []string{"JOIN genres ON genres.id = books.genre_id"},
+	},
+}
+
+// Admin: Top sellers report. Ranks books, authors, categories or genres by quantity sold or
+// revenue over a date range, from the order items of orders that were not cancelled,
+// refunded or returned. Cancelled and returned items are left out as well.
+func GetTopSellersReport(c *gin.Context) {
+	utils.LogInfo("GetTopSellersReport called")
+
+	groupBy := c.DefaultQuery("group_by", "book")
+	dimension, ok := topSellerDimensions[groupBy]
+	if !ok {
+		utils.LogError("Invalid group_by: %s", groupBy)
+		utils.BadRequest(c, "Invalid group_by", "group_by must be book, author, category or genre")
+		return
+	}
+
+	sortBy := c.DefaultQuery("sort_by", "revenue")
+	if sortBy != "revenue" && sortBy != "quantity" {
+		utils.LogError("Invalid sort_by: %s", sortBy)
+		utils.BadRequest(c, "Invalid sort_by", "sort_by must be revenue or quantity")
+		return
+	}
+
+	// Default to the last 30 days including today
+	now := time.Now()
+	endDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Add(24 * time.Hour)
+	startDate := endDate.AddDate(0, 0, -30)
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			utils.LogError("Invalid start date format: %v", err)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid start date", "Start date must be in YYYY-MM-DD format")
+			return
+		}
+		startDate = parsed
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			utils.LogError("Invalid end date format: %v", err)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid end date", "End date must be in YYYY-MM-DD format")
+			return
+		}
+		// Include the entire end date
+		endDate = parsed.Add(24 * time.Hour)
+	}
+	if !endDate.After(startDate) {
+		utils.LogError("Invalid date range: %s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+		utils.Fail(c, utils.CodeInvalidDate, "Invalid date range", "End date must be after start date")
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+
+	query := config.DB.Model(&models.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Joins("JOIN books ON books.id = order_items.book_id")
+	for _, join := range dimension.joins {
+		query = query.Joins(join)
+	}
+	query = query.
+		Where("orders.created_at >= ? AND orders.created_at < ?", startDate, endDate).
+		Where("orders.status NOT IN ?", []string{models.OrderStatusCancelled, models.OrderStatusRefunded, models.OrderStatusReturnCompleted}).
+		Where("COALESCE(order_items.cancellation_status, '') NOT IN ?", []string{"Cancelled", "Approved"}).
+		Where("COALESCE(order_items.return_status, '') NOT IN ?", []string{"Approved", models.OrderStatusReturnApproved}).
+		Group(dimension.key + ", " + dimension.label)
+
+	var total int64
+	if err := config.DB.Table("(?) AS grouped", query.Session(&gorm.Session{}).Select(dimension.key)).
+		Count(&total).Error; err != nil {
+		utils.LogError("Failed to count top sellers: %v", err)
+		utils.InternalServerError(c, "Failed to generate top sellers report", err.Error())
+		return
+	}
+
+	type topSellerRow struct {
+		Key      string
+		Name     string
+		Quantity int64
+		Orders   int64
+		Revenue  float64
+	}
+	order := "revenue DESC, quantity DESC"
+	if sortBy == "quantity" {
+		order = "quantity DESC, revenue DESC"
+	}
+	rowsQuery := query.
+		Select(fmt.Sprintf("CAST(%s AS TEXT) AS key, %s AS name, SUM(order_items.quantity) AS quantity, "+
+			"COUNT(DISTINCT order_items.order_id) AS orders, "+
+			"SUM(order_items.total - order_items.coupon_discount) AS revenue", dimension.key, dimension.label)).
+		Order(order)
+	if !utils.WantsCSV(c) {
+		rowsQuery = rowsQuery.Limit(limit).Offset((page - 1) * limit)
+	}
+	var rows []topSellerRow
+	if err := rowsQuery.Scan(&rows).Error; err != nil {
+		utils.LogError("Failed to fetch top sellers: %v", err)
+		utils.InternalServerError(c, "Failed to generate top sellers report", err.Error())
+		return
+	}
+	utils.LogDebug("Retrieved %d top seller rows grouped by %s", len(rows), groupBy)
+
+	// CSV exports every row of the range, not just the current page
+	if utils.WantsCSV(c) {
+		headers := []string{"Rank", "ID", "Name", "Quantity", "Orders", "Revenue"}
+		csvRows := make([][]string, 0, len(rows))
+		for i, row := range rows {
+			csvRows = append(csvRows, []string{
+				strconv.Itoa(i + 1),
+				row.Key,
+				row.Name,
+				strconv.FormatInt(row.Quantity, 10),
+				strconv.FormatInt(row.Orders, 10),
+				fmt.Sprintf("%.2f", row.Revenue),
+			})
+		}
+		if err := utils.WriteCSV(c, "top_sellers_"+groupBy, headers, csvRows); err != nil {
+			utils.LogError("Failed to write CSV file: %v", err)
+			utils.InternalServerError(c, "Failed to write CSV file", err.Error())
+		}
+		return
+	}
+
+	items := make([]gin.H, 0, len(rows))
+	formatter := utils.NewResponseFormatter(c)
+	for i, row := range rows {
+		item := gin.H{
+			"rank":     (page-1)*limit + i + 1,
+			"name":     row.Name,
+			"quantity": row.Quantity,
+			"orders":   row.Orders,
+			"revenue":  formatter.Money(row.Revenue),
+		}
+		// Authors have no ID of their own
+		if groupBy != "author" {
+			if id, err := strconv.ParseUint(row.Key, 10, 64); err == nil {
+				item["id"] = id
+			}
+		}
+		items = append(items, item)
+	}
+
+	utils.LogInfo("Successfully generated top sellers report grouped by %s", groupBy)
+	utils.SuccessWithPagination(c, "Top sellers report generated successfully", gin.H{
+		"group_by": groupBy,
+		"sort_by":  sortBy,
+		"period": gin.H{
+			"start_date": formatter.Timestamp(startDate),
+			"end_date":   formatter.Timestamp(endDate),
+		},
+		"items": items,
+	}, total, page, limit)
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// TranslationRequest represents the translation upsert request
+type TranslationRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// parseTranslationTarget validates the entity type and ID path parameters and
+// makes sure the referenced catalog entity exists
+func parseTranslationTarget(c *gin.Context) (string, uint, bool) {
+	entityType := strings.ToLower(c.Param("entity_type"))
+
+	var model interface{}
+	switch entityType {
+	case models.TranslationEntityBook:
+		model = &models.Book{}
+	case models.TranslationEntityCategory:
+		model = &models.Category{}
+	case models.TranslationEntityGenre:
+		model = &models.Genre{}
+	default:
+		utils.LogError("Invalid translation entity type: %s", entityType)
+		utils.BadRequest(c, "Invalid entity type", "Entity type must be one of 'book', 'category' or 'genre'")
+		return "", 0, false
+	}
+
+	entityID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid entity ID: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeInvalidID, "Invalid entity ID", nil)
+		return "", 0, false
+	}
+
+	if err := config.DB.First(model, entityID).Error; err != nil {
+		utils.LogError("%s %d not found: %v", entityType, entityID, err)
+		utils.NotFound(c, utils.Title(entityType)+" not found")
+		return "", 0, false
+	}
+
+	return entityType, uint(entityID), true
+}
+
+// GetTranslations lists all translations stored for a catalog entity
+func GetTranslations(c *gin.Context) {
+	utils.LogInfo("GetTranslations called")
+
+	entityType, entityID, ok := parseTranslationTarget(c)
+	if !ok {
+		return
+	}
+
+	var translations []models.Translation
+	if err := config.DB.Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("locale ASC").Find(&translations).Error; err != nil {
+		utils.LogError("Failed to fetch translations for %s %d: %v", entityType, entityID, err)
+		utils.InternalServerError(c, "Failed to fetch translations", err.Error())
+		return
+	}
+
+	utils.LogInfo("Retrieved %d translations for %s %d", len(translations), entityType, entityID)
+	utils.Success(c, "Translations retrieved successfully", gin.H{
+		"entity_type":    entityType,
+		"entity_id":      entityID,
+		"default_locale": utils.DefaultLocale,
+		"translations":   translations,
+	})
+}
+
+// UpsertTranslation creates or replaces the translation of a catalog entity for a locale
+func UpsertTranslation(c *gin.Context) {
+	utils.LogInfo("UpsertTranslation called")
+
+	entityType, entityID, ok := parseTranslationTarget(c)
+	if !ok {
+		return
+	}
+
+	locale := strings.ToLower(strings.TrimSpace(c.Param("locale")))
+	if !utils.IsSupportedLocale(locale) {
+		utils.LogError("Unsupported locale: %s", locale)
+		utils.BadRequest(c, "Unsupported locale", nil)
+		return
+	}
+	if locale == utils.DefaultLocale {
+		utils.LogError("Attempt to translate %s %d into the default locale", entityType, entityID)
+		utils.BadRequest(c, "Default locale text is edited on the entity itself", nil)
+		return
+	}
+
+	var req TranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid translation request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", "Name is required")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.Description = strings.TrimSpace(req.Description)
+	if req.Name == "" {
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", "Name is required")
+		return
+	}
+
+	var translation models.Translation
+	err := config.DB.Where("entity_type = ? AND entity_id = ? AND locale = ?", entityType, entityID, locale).
+		First(&translation).Error
+	if err != nil {
+		translation = models.Translation{
+			EntityType: entityType,
+			EntityID:   entityID,
+			Locale:     locale,
+		}
+	}
+	translation.Name = req.Name
+	translation.Description = req.Description
+
+	if err := config.DB.Save(&translation).Error; err != nil {
+		utils.LogError("Failed to save translation for %s %d (%s): %v", entityType, entityID, locale, err)
+		utils.InternalServerError(c, "Failed to save translation", err.Error())
+		return
+	}
+
+	utils.LogInfo("Translation saved for %s %d (%s)", entityType, entityID, locale)
+	utils.Success(c, "Translation saved successfully", gin.H{
+		"translation": translation,
+	})
+}
+
+// DeleteTranslation removes the translation of a catalog entity for a locale
+func DeleteTranslation(c *gin.Context) {
+	utils.LogInfo("DeleteTranslation called")
+
+	entityType, entityID, ok := parseTranslationTarget(c)
+	if !ok {
+		return
+	}
+	locale := strings.ToLower(c.Param("locale"))
+
+	result := config.DB.Where("entity_type = ? AND entity_id = ? AND locale = ?", entityType, entityID, locale).
+		Delete(&models.Translation{})
+	if result.Error != nil {
+		utils.LogError("Failed to delete translation for %s %d (%s): %v", entityType, entityID, locale, result.Error)
+		utils.InternalServerError(c, "Failed to delete translation", result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.NotFound(c, "Translation not found")
+		return
+	}
+
+	utils.LogInfo("Translation deleted for %s %d (%s)", entityType, entityID, locale)
+	utils.Success(c, "Translation deleted successfully", nil)
+}
@@ -0,0 +1,425 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// adminTwoFactorTokenTTL is how long an admin has to enter the code after their password
+const adminTwoFactorTokenTTL = 5 * time.Minute
+
+// maxAdminTwoFactorFailures is how many wrong codes are accepted before the second login step
+// locks for adminTwoFactorLockout
+const maxAdminTwoFactorFailures = 5
+
+// adminTwoFactorLockout is how long no code is checked for an admin after too many wrong ones
+const adminTwoFactorLockout = 15 * time.Minute
+
+// Admin login attempts allowed per adminLoginWindow, for each client IP across both login steps
+// and for each admin at each step
+const (
+	adminLoginWindow      = 15 * time.Minute
+	adminLoginMaxPerIP    = 30
+	adminLoginMaxPerAdmin = 10
+)
+
+// AdminLoginRateLimit limits the admin login requests from each client IP
+func AdminLoginRateLimit() gin.HandlerFunc {
+	return utils.RateLimitMiddleware("admin_login", adminLoginMaxPerIP, adminLoginWindow)
+}
+
+// AdminTwoFactorLoginRequest is the second login step
+type AdminTwoFactorLoginRequest struct {
+	TwoFactorToken string `json:"two_factor_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // authenticator code or backup code
+}
+
+// AdminTwoFactorCodeRequest carries an authenticator code
+type AdminTwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// AdminDisableTwoFactorRequest confirms turning two-factor authentication off
+type AdminDisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // authenticator code or backup code
+}
+
+// issueAdminTwoFactorToken returns the short-lived token proving the admin's password was
+// verified. It has no admin_id claim, so it is not accepted as an access token. The random jti
+// keeps tokens issued in the same second apart, so revoking one leaves the others valid.
+func issueAdminTwoFactorToken(admin models.Admin) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"two_factor_admin_id": admin.ID,
+		"jti":                 hex.EncodeToString(jti),
+		"exp":                 time.Now().Add(adminTwoFactorTokenTTL).Unix(),
+	})
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+// parseAdminTwoFactorToken returns the admin ID and expiry of a pending two-factor token
+func parseAdminTwoFactorToken(tokenString string) (uint, time.Time, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err != nil || !token.Valid {
+		return 0, time.Time{}, fmt.Errorf("invalid token: %v", err)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, time.Time{}, fmt.Errorf("invalid token claims")
+	}
+	adminID, ok := claims["two_factor_admin_id"].(float64)
+	if !ok {
+		return 0, time.Time{}, fmt.Errorf("not a two-factor token")
+	}
+	exp, _ := claims["exp"].(float64)
+	return uint(adminID), time.Unix(int64(exp), 0), nil
+}
+
+// verifyAdminSecondFactor checks an authenticator or backup code and saves the used step or
+// the remaining backup codes. Returns whether the code was accepted.
+func verifyAdminSecondFactor(admin *models.Admin, code string) (bool, error) {
+	updates := map[string]interface{}{}
+	if step, ok := utils.ValidateTOTP(admin.TwoFactorSecret, code, admin.TwoFactorLastStep); ok {
+		admin.TwoFactorLastStep = step
+		updates["two_factor_last_step"] = step
+	} else if remaining, ok := utils.ConsumeBackupCode(admin.TwoFactorBackupCodes, code); ok {
+		utils.LogInfo("Admin %d used a backup code, %d left", admin.ID, utils.CountBackupCodes(remaining))
+		admin.TwoFactorBackupCodes = remaining
+		updates["two_factor_backup_codes"] = remaining
+	} else {
+		return false, nil
+	}
+	admin.TwoFactorFailures = 0
+	admin.TwoFactorLockedUntil = nil
+	updates["two_factor_failures"] = 0
+	updates["two_factor_locked_until"] = nil
+	return true, config.DB.Model(admin).Updates(updates).Error
+}
+
+// adminTwoFactorLocked sends the too many requests response and reports true while the admin's
+// second login step is locked
+func adminTwoFactorLocked(c *gin.Context, admin models.Admin) bool {
+	if admin.TwoFactorLockedUntil == nil || !admin.TwoFactorLockedUntil.After(time.Now()) {
+		return false
+	}
+	retryAfter := int(time.Until(*admin.TwoFactorLockedUntil).Seconds()) + 1
+	utils.LogError("Two-factor login locked for admin: %s, retry in %ds", admin.Email, retryAfter)
+	utils.Fail(c, utils.CodeTooManyRequests, "Too many invalid authentication codes, please try again later", gin.H{
+		"retry_after": retryAfter,
+	})
+	return true
+}
+
+// recordAdminTwoFactorFailure counts a wrong code for the admin and, at
+// maxAdminTwoFactorFailures, locks the second login step. Returns whether it locked.
+func recordAdminTwoFactorFailure(admin *models.Admin) (bool, error) {
+	if err := config.DB.Model(&models.Admin{}).Where("id = ?", admin.ID).
+		UpdateColumn("two_factor_failures", gorm.Expr("two_factor_failures + 1")).Error; err != nil {
+		return false, err
+	}
+	if err := config.DB.Model(&models.Admin{}).Where("id = ?", admin.ID).
+		Select("two_factor_failures").Row().Scan(&admin.TwoFactorFailures); err != nil {
+		return false, err
+	}
+	if admin.TwoFactorFailures < maxAdminTwoFactorFailures {
+		return false, nil
+	}
+
+	lockedUntil := time.Now().Add(adminTwoFactorLockout)
+	admin.TwoFactorFailures = 0
+	admin.TwoFactorLockedUntil = &lockedUntil
+	return true, config.DB.Model(&models.Admin{}).Where("id = ?", admin.ID).Updates(map[string]interface{}{
+		"two_factor_failures":     0,
+		"two_factor_locked_until": lockedUntil,
+	}).Error
+}
+
+// AdminVerifyTwoFactor completes an admin login with the authenticator or a backup code
+func AdminVerifyTwoFactor(c *gin.Context) {
+	utils.LogInfo("AdminVerifyTwoFactor called")
+
+	var req AdminTwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid two-factor request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err)
+		return
+	}
+
+	var blacklistedToken models.BlacklistedToken
+	if err := config.DB.Where("token = ? AND expires_at > ?", req.TwoFactorToken, time.Now()).First(&blacklistedToken).Error; err == nil {
+		utils.LogError("Two-factor token is blacklisted")
+		utils.Fail(c, utils.CodeTokenInvalid, "Please login again", nil)
+		return
+	}
+	adminID, expiresAt, err := parseAdminTwoFactorToken(req.TwoFactorToken)
+	if err != nil {
+		utils.LogError("Invalid two-factor token: %v", err)
+		utils.Fail(c, utils.CodeTokenInvalid, "Please login again", nil)
+		return
+	}
+	if utils.RateLimited(c, fmt.Sprintf("admin_login_2fa:admin:%d", adminID), adminLoginMaxPerAdmin, adminLoginWindow) {
+		return
+	}
+
+	var admin models.Admin
+	if err := config.DB.First(&admin, adminID).Error; err != nil {
+		utils.LogError("Admin not found for two-factor token: %v", err)
+		utils.Fail(c, utils.CodeTokenInvalid, "Please login again", nil)
+		return
+	}
+	if !admin.IsActive {
+		utils.LogError("Inactive admin account attempted login: %s", admin.Email)
+		utils.Fail(c, utils.CodeAccountInactive, "Admin account is inactive", nil)
+		return
+	}
+	if !admin.TwoFactorEnabled {
+		utils.LogError("Two-factor login step for admin without two-factor authentication: %s", admin.Email)
+		utils.Fail(c, utils.CodeTokenInvalid, "Please login again", nil)
+		return
+	}
+	if adminTwoFactorLocked(c, admin) {
+		return
+	}
+
+	ok, err := verifyAdminSecondFactor(&admin, req.Code)
+	if err != nil {
+		utils.LogError("Failed to save two-factor state for admin: %s: %v", admin.Email, err)
+		utils.InternalServerError(c, "Failed to verify code", nil)
+		return
+	}
+	if !ok {
+		locked, err := recordAdminTwoFactorFailure(&admin)
+		if err != nil {
+			utils.LogError("Failed to record invalid two-factor code for admin: %s: %v", admin.Email, err)
+			utils.InternalServerError(c, "Failed to verify code", nil)
+			return
+		}
+		if locked {
+			// Too many guesses, no code is checked until the lockout ends and the password
+			// has to be entered again
+			config.DB.Create(&models.BlacklistedToken{Token: req.TwoFactorToken, ExpiresAt: expiresAt})
+			utils.LogError("Invalid two-factor code for admin: %s, locked until %s", admin.Email, admin.TwoFactorLockedUntil.Format(time.RFC3339))
+			adminTwoFactorLocked(c, admin)
+			return
+		}
+		utils.LogError("Invalid two-factor code for admin: %s (%d failures)", admin.Email, admin.TwoFactorFailures)
+		utils.Fail(c, utils.CodeTwoFactorInvalid, "Invalid authentication code", nil)
+		return
+	}
+
+	// The pending token is single use
+	config.DB.Create(&models.BlacklistedToken{Token: req.TwoFactorToken, ExpiresAt: expiresAt})
+
+	utils.LogInfo("Two-factor code verified for admin: %s", admin.Email)
+	completeAdminLogin(c, admin)
+}
+
+// AdminGetTwoFactorStatus reports whether two-factor authentication is enabled and how many backup codes are left
+func AdminGetTwoFactorStatus(c *gin.Context) {
+	utils.LogInfo("AdminGetTwoFactorStatus called")
+	admin := c.MustGet("admin").(models.Admin)
+
+	utils.Success(c, "Two-factor status retrieved successfully", gin.H{
+		"enabled":           admin.TwoFactorEnabled,
+		"backup_codes_left": utils.CountBackupCodes(admin.TwoFactorBackupCodes),
+	})
+}
+
+// AdminSetupTwoFactor generates a new TOTP secret for the admin. It has to be confirmed with a
+// code at /admin/2fa/enable before login asks for codes.
+func AdminSetupTwoFactor(c *gin.Context) {
+	utils.LogInfo("AdminSetupTwoFactor called")
+	admin := c.MustGet("admin").(models.Admin)
+
+	if admin.TwoFactorEnabled {
+		utils.LogError("Two-factor setup for admin with two-factor already enabled: %s", admin.Email)
+		utils.Conflict(c, "Two-factor authentication is already enabled, disable it first to set it up again", nil)
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		utils.LogError("Failed to generate TOTP secret: %v", err)
+		utils.InternalServerError(c, "Failed to set up two-factor authentication", nil)
+		return
+	}
+	if err := config.DB.Model(&admin).Updates(map[string]interface{}{
+		"two_factor_secret":    secret,
+		"two_factor_last_step": 0,
+	}).Error; err != nil {
+		utils.LogError("Failed to save TOTP secret for admin: %s: %v", admin.Email, err)
+		utils.InternalServerError(c, "Failed to set up two-factor authentication", nil)
+		return
+	}
+
+	utils.LogInfo("Two-factor setup started for admin: %s", admin.Email)
+	utils.Success(c, "Scan the QR code with your authenticator app, then confirm with a code", gin.H{
+		"secret":      secret,
+		"otpauth_url": utils.TOTPProvisioningURL(utils.AppName, admin.Email, secret),
+	})
+}
+
+// AdminEnableTwoFactor confirms the setup with a code, turns two-factor authentication on and
+// returns the backup codes. The codes are only shown here.
+func AdminEnableTwoFactor(c *gin.Context) {
+	utils.LogInfo("AdminEnableTwoFactor called")
+	admin := c.MustGet("admin").(models.Admin)
+
+	var req AdminTwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err)
+		return
+	}
+	if admin.TwoFactorEnabled {
+		utils.Conflict(c, "Two-factor authentication is already enabled", nil)
+		return
+	}
+	if admin.TwoFactorSecret == "" {
+		utils.BadRequest(c, "Start two-factor setup first", nil)
+		return
+	}
+
+	step, ok := utils.ValidateTOTP(admin.TwoFactorSecret, req.Code, admin.TwoFactorLastStep)
+	if !ok {
+		utils.LogError("Invalid code confirming two-factor setup for admin: %s", admin.Email)
+		utils.Fail(c, utils.CodeTwoFactorInvalid, "Invalid authentication code", nil)
+		return
+	}
+
+	codes, hashed, err := utils.GenerateBackupCodes()
+	if err != nil {
+		utils.LogError("Failed to generate backup codes: %v", err)
+		utils.InternalServerError(c, "Failed to enable two-factor authentication", nil)
+		return
+	}
+	if err := config.DB.Model(&admin).Updates(map[string]interface{}{
+		"two_factor_enabled":      true,
+		"two_factor_last_step":    step,
+		"two_factor_backup_codes": hashed,
+		"two_factor_failures":     0,
+	}).Error; err != nil {
+		utils.LogError("Failed to enable two-factor authentication for admin: %s: %v", admin.Email, err)
+		utils.InternalServerError(c, "Failed to enable two-factor authentication", nil)
+		return
+	}
+
+	utils.LogInfo("Two-factor authentication enabled for admin: %s", admin.Email)
+	utils.Success(c, "Two-factor authentication enabled. Store the backup codes somewhere safe, they will not be shown again", gin.H{
+		"backup_codes": codes,
+	})
+}
+
+// AdminRegenerateBackupCodes replaces the admin's backup codes after checking an authenticator code
+func AdminRegenerateBackupCodes(c *gin.Context) {
+	utils.LogInfo("AdminRegenerateBackupCodes called")
+	admin := c.MustGet("admin").(models.Admin)
+
+	var req AdminTwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err)
+		return
+	}
+	if !admin.TwoFactorEnabled {
+		utils.BadRequest(c, "Two-factor authentication is not enabled", nil)
+		return
+	}
+
+	step, ok := utils.ValidateTOTP(admin.TwoFactorSecret, req.Code, admin.TwoFactorLastStep)
+	if !ok {
+		utils.LogError("Invalid code regenerating backup codes for admin: %s", admin.Email)
+		utils.Fail(c, utils.CodeTwoFactorInvalid, "Invalid authentication code", nil)
+		return
+	}
+
+	codes, hashed, err := utils.GenerateBackupCodes()
+	if err != nil {
+		utils.LogError("Failed to generate backup codes: %v", err)
+		utils.InternalServerError(c, "Failed to generate backup codes", nil)
+		return
+	}
+	if err := config.DB.Model(&admin).Updates(map[string]interface{}{
+		"two_factor_last_step":    step,
+		"two_factor_backup_codes": hashed,
+	}).Error; err != nil {
+		utils.LogError("Failed to save backup codes for admin: %s: %v", admin.Email, err)
+		utils.InternalServerError(c, "Failed to generate backup codes", nil)
+		return
+	}
+
+	utils.LogInfo("Backup codes regenerated for admin: %s", admin.Email)
+	utils.Success(c, "Backup codes regenerated, the previous codes no longer work", gin.H{
+		"backup_codes": codes,
+	})
+}
+
+// AdminDisableTwoFactor turns two-factor authentication off after checking the password and a code
+func AdminDisableTwoFactor(c *gin.Context) {
+	utils.LogInfo("AdminDisableTwoFactor called")
+	admin := c.MustGet("admin").(models.Admin)
+
+	var req AdminDisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err)
+		return
+	}
+	if !admin.TwoFactorEnabled {
+		utils.BadRequest(c, "Two-factor authentication is not enabled", nil)
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(req.Password)); err != nil {
+		utils.LogError("Invalid password disabling two-factor for admin: %s", admin.Email)
+		utils.Fail(c, utils.CodeInvalidCredentials, "Invalid credentials", nil)
+		return
+	}
+	ok, err := verifyAdminSecondFactor(&admin, strings.TrimSpace(req.Code))
+	if err != nil {
+		utils.LogError("Failed to save two-factor state for admin: %s: %v", admin.Email, err)
+		utils.InternalServerError(c, "Failed to verify code", nil)
+		return
+	}
+	if !ok {
+		utils.LogError("Invalid code disabling two-factor for admin: %s", admin.Email)
+		utils.Fail(c, utils.CodeTwoFactorInvalid, "Invalid authentication code", nil)
+		return
+	}
+
+	if err := config.DB.Model(&admin).Updates(map[string]interface{}{
+		"two_factor_enabled":      false,
+		"two_factor_secret":       "",
+		"two_factor_last_step":    0,
+		"two_factor_backup_codes": "",
+		"two_factor_failures":     0,
+		"two_factor_locked_until": nil,
+	}).Error; err != nil {
+		utils.LogError("Failed to disable two-factor authentication for admin: %s: %v", admin.Email, err)
+		utils.InternalServerError(c, "Failed to disable two-factor authentication", nil)
+		return
+	}
+
+	utils.LogInfo("Two-factor authentication disabled for admin: %s", admin.Email)
+	utils.Success(c, "Two-factor authentication disabled", nil)
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/testutil"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// postAdminLogin runs the admin login handler with the JSON request body
+func postAdminLogin(handler gin.HandlerFunc, body gin.H) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/admin/login", bytes.NewReader(payload))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler(c)
+	return recorder
+}
+
+func TestAdminTwoFactorLocksAfterRepeatedFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret")
+	db := testutil.NewDB(t, &models.Admin{}, &models.BlacklistedToken{}, &models.RateLimitCounter{})
+
+	secret, err := utils.GenerateTOTPSecret()
+	require.NoError(t, err)
+	backupCodes, stored, err := utils.GenerateBackupCodes()
+	require.NoError(t, err)
+	password, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+	admin := models.Admin{Email: "admin@example.com", Password: string(password), IsActive: true,
+		TwoFactorEnabled: true, TwoFactorSecret: secret, TwoFactorBackupCodes: stored}
+	require.NoError(t, db.Create(&admin).Error)
+
+	login := func() *httptest.ResponseRecorder {
+		return postAdminLogin(AdminLogin, gin.H{"email": admin.Email, "password": "correct horse"})
+	}
+	pendingToken := func() string {
+		recorder := login()
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		var response struct {
+			Data struct {
+				TwoFactorToken string `json:"two_factor_token"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.NotEmpty(t, response.Data.TwoFactorToken)
+		return response.Data.TwoFactorToken
+	}
+	verify := func(token, code string) *httptest.ResponseRecorder {
+		return postAdminLogin(AdminVerifyTwoFactor, gin.H{"two_factor_token": token, "code": code})
+	}
+
+	// Logging in with the password again does not forget the wrong codes
+	for i := 1; i < maxAdminTwoFactorFailures; i++ {
+		recorder := verify(pendingToken(), "000000")
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code, recorder.Body.String())
+	}
+	token := pendingToken()
+	recorder := verify(token, "000000")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code, recorder.Body.String())
+
+	var locked models.Admin
+	require.NoError(t, db.First(&locked, admin.ID).Error)
+	require.NotNil(t, locked.TwoFactorLockedUntil)
+	assert.WithinDuration(t, time.Now().Add(adminTwoFactorLockout), *locked.TwoFactorLockedUntil, time.Minute)
+
+	assert.Equal(t, http.StatusTooManyRequests, login().Code, "no new token while locked")
+	assert.Equal(t, http.StatusUnauthorized, verify(token, backupCodes[0]).Code, "the token is revoked")
+
+	// Once the lockout ends a valid code completes the login and clears it
+	require.NoError(t, db.Model(&models.Admin{}).Where("id = ?", admin.ID).
+		Update("two_factor_locked_until", time.Now().Add(-time.Second)).Error)
+	recorder = verify(pendingToken(), backupCodes[0])
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var unlocked models.Admin
+	require.NoError(t, db.First(&unlocked, admin.ID).Error)
+	assert.Nil(t, unlocked.TwoFactorLockedUntil)
+	assert.Zero(t, unlocked.TwoFactorFailures)
+}
+
+func TestAdminLoginIsRateLimitedPerAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testutil.NewDB(t, &models.Admin{}, &models.RateLimitCounter{})
+
+	for i := 0; i < adminLoginMaxPerAdmin; i++ {
+		recorder := postAdminLogin(AdminLogin, gin.H{"email": "nobody@example.com", "password": "guess"})
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code, recorder.Body.String())
+	}
+	recorder := postAdminLogin(AdminLogin, gin.H{"email": "Nobody@Example.com", "password": "guess"})
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code, recorder.Body.String())
+	assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+}
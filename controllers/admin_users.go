@@ -3,12 +3,14 @@ package controllers
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // UserListRequest represents the request parameters for user listing
@@ -77,6 +79,37 @@ func GetUsers(c *gin.Context) {
 	query.Count(&total)
 	utils.LogDebug("Total users count: %d", total)
 
+	// CSV exports include every user matching the search
+	if utils.WantsCSV(c) {
+		var users []models.User
+		if err := query.Find(&users).Error; err != nil {
+			utils.LogError("Failed to fetch users for export: %v", err)
+			utils.InternalServerError(c, "Failed to fetch users", err.Error())
+			return
+		}
+		headers := []string{"ID", "Username", "Email", "First Name", "Last Name", "Blocked", "Verified", "Created At", "Last Login", "Addresses"}
+		rows := make([][]string, 0, len(users))
+		for _, user := range users {
+			rows = append(rows, []string{
+				fmt.Sprintf("%d", user.ID),
+				user.Username,
+				user.Email,
+				user.FirstName,
+				user.LastName,
+				strconv.FormatBool(user.IsBlocked),
+				strconv.FormatBool(user.IsVerified),
+				user.CreatedAt.Format("2006-01-02 15:04:05"),
+				user.LastLoginAt.Format("2006-01-02 15:04:05"),
+				fmt.Sprintf("%d", len(user.Addresses)),
+			})
+		}
+		if err := utils.WriteCSV(c, "users", headers, rows); err != nil {
+			utils.LogError("Failed to write CSV file: %v", err)
+			utils.InternalServerError(c, "Failed to write CSV file", err.Error())
+		}
+		return
+	}
+
 	// Apply pagination
 	offset := (req.Page - 1) * req.Limit
 	query = query.Offset(offset).Limit(req.Limit)
@@ -96,6 +129,7 @@ func GetUsers(c *gin.Context) {
 	}
 
 	// Create clean response without sensitive data
+	formatter := utils.NewResponseFormatter(c)
 	cleanUsers := make([]gin.H, len(users))
 	for i, user := range users {
 		cleanUsers[i] = gin.H{
@@ -106,8 +140,8 @@ func GetUsers(c *gin.Context) {
 			"last_name":     user.LastName,
 			"is_blocked":    user.IsBlocked,
 			"is_verified":   user.IsVerified,
-			"created_at":    user.CreatedAt,
-			"last_login":    user.LastLoginAt,
+			"created_at":    formatter.Timestamp(user.CreatedAt),
+			"last_login":    formatter.Timestamp(user.LastLoginAt),
 			"address_count": len(user.Addresses),
 		}
 	}
@@ -128,6 +162,11 @@ func GetUsers(c *gin.Context) {
 	})
 }
 
+// BlockUserRequest is the optional body of a block toggle
+type BlockUserRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
 // BlockUser handles blocking/unblocking a user
 func BlockUser(c *gin.Context) {
 	utils.LogInfo("BlockUser called")
@@ -136,7 +175,7 @@ func BlockUser(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -159,11 +198,21 @@ func BlockUser(c *gin.Context) {
 
 	utils.LogDebug("Processing user with ID: %s", userID)
 
+	// The reason is optional, so an empty body is accepted
+	var req BlockUserRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.LogError("Invalid block request: %v", err)
+			utils.BadRequest(c, "Invalid request", err.Error())
+			return
+		}
+	}
+
 	// Find the user
 	var user models.User
 	if err := config.DB.First(&user, userID).Error; err != nil {
 		utils.LogError("User not found: %v", err)
-		utils.NotFound(c, "User not found")
+		utils.Fail(c, utils.CodeUserNotFound, "User not found", nil)
 		return
 	}
 
@@ -181,8 +230,19 @@ func BlockUser(c *gin.Context) {
 		"updated_at": time.Now(),
 	}
 
-	// Update the user
-	if err := config.DB.Model(&user).Updates(updates).Error; err != nil {
+	// Update the user and record the change in its block history
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserBlockEvent{
+			UserID:  user.ID,
+			AdminID: adminModel.ID,
+			Blocked: newBlockStatus,
+			Reason:  strings.TrimSpace(req.Reason),
+		}).Error
+	})
+	if err != nil {
 		utils.LogError("Failed to update user block status: %v", err)
 		utils.InternalServerError(c, "Failed to update user block status", err.Error())
 		return
@@ -198,3 +258,152 @@ func BlockUser(c *gin.Context) {
 		},
 	})
 }
+
+// recentOrdersLimit is the number of latest orders shown on the admin user detail view
+const recentOrdersLimit = 5
+
+// GetUserDetails returns a user's profile with addresses, lifetime order statistics, wallet
+// balance, latest orders and block history
+func GetUserDetails(c *gin.Context) {
+	utils.LogInfo("GetUserDetails called")
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.LogError("Invalid user ID format: %s", c.Param("id"))
+		utils.BadRequest(c, "Invalid user ID", nil)
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Preload("Addresses", func(db *gorm.DB) *gorm.DB {
+		return db.Order("is_default DESC, id")
+	}).First(&user, userID).Error; err != nil {
+		utils.LogError("User not found: %v", err)
+		utils.Fail(c, utils.CodeUserNotFound, "User not found", nil)
+		return
+	}
+	utils.LogDebug("Found user: %s", user.Email)
+
+	// Lifetime order counts per status; revenue excludes cancelled orders like the dashboard
+	var statusCounts []struct {
+		Status string
+		Count  int64
+	}
+	if err := config.DB.Model(&models.Order{}).
+		Select("status, COUNT(*) AS count").
+		Where("user_id = ?", user.ID).
+		Group("status").
+		Scan(&statusCounts).Error; err != nil {
+		utils.LogError("Failed to count orders for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch order statistics", err.Error())
+		return
+	}
+	var totals struct {
+		Revenue  float64
+		Refunded float64
+	}
+	if err := config.DB.Model(&models.Order{}).
+		Select("COALESCE(SUM(CASE WHEN status != ? THEN final_total ELSE 0 END), 0) AS revenue, "+
+			"COALESCE(SUM(refund_amount), 0) AS refunded", models.OrderStatusCancelled).
+		Where("user_id = ?", user.ID).
+		Scan(&totals).Error; err != nil {
+		utils.LogError("Failed to sum orders for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch order statistics", err.Error())
+		return
+	}
+	var totalOrders int64
+	ordersByStatus := make(map[string]int64, len(statusCounts))
+	for _, sc := range statusCounts {
+		ordersByStatus[sc.Status] = sc.Count
+		totalOrders += sc.Count
+	}
+
+	// Users without a wallet yet have a zero balance; none is created for a read
+	var wallet models.Wallet
+	if err := config.DB.Where("user_id = ?", user.ID).Limit(1).Find(&wallet).Error; err != nil {
+		utils.LogError("Failed to fetch wallet for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch wallet", err.Error())
+		return
+	}
+
+	var orders []models.Order
+	if err := config.DB.Where("user_id = ?", user.ID).
+		Order("created_at DESC").
+		Limit(recentOrdersLimit).
+		Find(&orders).Error; err != nil {
+		utils.LogError("Failed to fetch recent orders for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch recent orders", err.Error())
+		return
+	}
+	recentOrders := make([]gin.H, len(orders))
+	formatter := utils.NewResponseFormatter(c)
+	for i, order := range orders {
+		recentOrders[i] = gin.H{
+			"id":             order.ID,
+			"status":         order.Status,
+			"payment_method": order.PaymentMethod,
+			"final_total":    formatter.Money(order.FinalTotal),
+			"created_at":     formatter.Timestamp(order.CreatedAt),
+		}
+	}
+
+	var blockEvents []struct {
+		models.UserBlockEvent
+		AdminEmail string
+	}
+	if err := config.DB.Model(&models.UserBlockEvent{}).
+		Select("user_block_events.*, admins.email AS admin_email").
+		Joins("LEFT JOIN admins ON admins.id = user_block_events.admin_id").
+		Where("user_block_events.user_id = ?", user.ID).
+		Order("user_block_events.created_at DESC").
+		Scan(&blockEvents).Error; err != nil {
+		utils.LogError("Failed to fetch block history for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch block history", err.Error())
+		return
+	}
+	blockHistory := make([]gin.H, len(blockEvents))
+	for i, event := range blockEvents {
+		action := "blocked"
+		if !event.Blocked {
+			action = "unblocked"
+		}
+		blockHistory[i] = gin.H{
+			"action":      action,
+			"reason":      event.Reason,
+			"admin_id":    event.AdminID,
+			"admin_email": event.AdminEmail,
+			"created_at":  formatter.Timestamp(event.CreatedAt),
+		}
+	}
+
+	utils.LogInfo("Retrieved details for user %d", user.ID)
+	utils.Success(c, "User details retrieved successfully", gin.H{
+		"user": gin.H{
+			"id":                    user.ID,
+			"username":              user.Username,
+			"email":                 user.Email,
+			"first_name":            user.FirstName,
+			"last_name":             user.LastName,
+			"phone":                 user.Phone,
+			"profile_image":         user.ProfileImage,
+			"is_blocked":            user.IsBlocked,
+			"is_verified":           user.IsVerified,
+			"google_linked":         user.GoogleID != "",
+			"created_at":            formatter.Timestamp(user.CreatedAt),
+			"last_login":            formatter.Timestamp(user.LastLoginAt),
+			"deletion_scheduled_at": formatter.OptionalTimestamp(user.DeletionScheduledAt),
+		},
+		"addresses": user.Addresses,
+		"order_summary": gin.H{
+			"total_orders":   totalOrders,
+			"by_status":      ordersByStatus,
+			"total_revenue":  formatter.Money(totals.Revenue),
+			"total_refunded": formatter.Money(totals.Refunded),
+		},
+		"wallet": gin.H{
+			"balance": formatter.Money(wallet.Balance),
+		},
+		"recent_orders": recentOrders,
+		"block_history": blockHistory,
+	})
+}
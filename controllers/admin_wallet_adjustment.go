@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// WalletAdjustmentRequest is a manual credit or debit posted by support
+type WalletAdjustmentRequest struct {
+	Type   string  `json:"type" binding:"required,oneof=credit debit"`
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Reason string  `json:"reason" binding:"required,min=3,max=500"`
+}
+
+// AdjustUserWallet lets an admin credit a user's wallet, for example as goodwill, or debit it
+// to correct an error. The posting records the reason and the acting admin and shows up in the
+// finance report as an adjustment.
+func AdjustUserWallet(c *gin.Context) {
+	utils.LogInfo("AdjustUserWallet called")
+
+	adminVal, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	admin := adminVal.(models.Admin)
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid user ID format: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid user ID", nil)
+		return
+	}
+
+	var req WalletAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		utils.LogError("Empty wallet adjustment reason for user ID: %d", userID)
+		utils.BadRequest(c, "A reason is required", nil)
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		utils.LogError("User not found - User ID: %d: %v", userID, err)
+		utils.Fail(c, utils.CodeUserNotFound, "User not found", nil)
+		return
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to begin transaction: %v", tx.Error)
+		utils.InternalServerError(c, "Failed to start transaction", nil)
+		return
+	}
+
+	adminID := admin.ID
+	transaction, err := utils.PostWalletEntry(tx, utils.WalletEntry{
+		UserID:      user.ID,
+		Amount:      req.Amount,
+		Type:        req.Type,
+		Description: fmt.Sprintf("Wallet adjustment: %s", req.Reason),
+		Reference:   fmt.Sprintf("ADJUST-%d-%s", adminID, time.Now().Format("20060102150405")),
+		AdminID:     &adminID,
+	})
+	if errors.Is(err, utils.ErrInsufficientWalletBalance) {
+		tx.Rollback()
+		utils.LogError("Wallet debit of %.2f exceeds the available balance of user ID: %d", req.Amount, user.ID)
+		utils.Fail(c, utils.CodeWalletInsufficient, "The debit is larger than the user's available wallet balance", nil)
+		return
+	}
+	if err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to post wallet adjustment for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to adjust wallet", err.Error())
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit wallet adjustment for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to commit transaction", err.Error())
+		return
+	}
+
+	utils.LogInfo("Admin %s posted a wallet %s of %.2f for user ID: %d", admin.Email, req.Type, req.Amount, user.ID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Wallet adjusted successfully", gin.H{
+		"transaction": gin.H{
+			"id":          transaction.ID,
+			"type":        transaction.Type,
+			"amount":      formatter.Money(transaction.Amount),
+			"description": transaction.Description,
+			"reference":   transaction.Reference,
+			"status":      transaction.Status,
+			"admin_id":    adminID,
+			"created_at":  formatter.Timestamp(transaction.CreatedAt),
+		},
+		"wallet": gin.H{
+			"balance":   formatter.Money(transaction.Wallet.Balance),
+			"held":      formatter.Money(transaction.Wallet.Held),
+			"available": formatter.Money(transaction.Wallet.Balance - transaction.Wallet.Held),
+		},
+	})
+}
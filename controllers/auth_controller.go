@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,8 +14,8 @@ import (
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type GoogleUserInfo struct {
@@ -27,11 +28,19 @@ type GoogleUserInfo struct {
 	Picture       string `json:"picture"`
 }
 
+// googleLoginState is the OAuth state of a sign-in; linking uses a signed state instead
+const googleLoginState = "state"
+
 func GoogleLogin(c *gin.Context) {
-	url := config.GoogleOAuthConfig.AuthCodeURL("state")
+	url := config.GoogleOAuthConfig.AuthCodeURL(googleLoginState)
 	c.Redirect(http.StatusTemporaryRedirect, url)
 }
 
+// googleFrontendURL returns the frontend URL of path with the query params
+func googleFrontendURL(path string, params url.Values) string {
+	return fmt.Sprintf("%s%s?%s", os.Getenv("FRONTEND_URL"), path, params.Encode())
+}
+
 func GoogleCallback(c *gin.Context) {
 	// Check if this is a token-based callback (from frontend)
 	if token := c.Query("token"); token != "" {
@@ -78,17 +87,37 @@ func GoogleCallback(c *gin.Context) {
 		return
 	}
 
-	// Check if user exists
+	// Linking from the profile passes a signed state naming the account to link
+	if state := c.Query("state"); state != googleLoginState {
+		linkGoogleAccount(c, state, googleUser)
+		return
+	}
+
+	// Accounts are matched by Google ID; an account with the same email has to be linked first
 	var user models.User
-	if err := config.DB.Where("email = ?", googleUser.Email).First(&user).Error; err != nil {
-		// Create new user if doesn't exist
+	err = config.DB.Where("google_id = ?", googleUser.ID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var existingUser models.User
+		if err := config.DB.Where("email = ?", googleUser.Email).First(&existingUser).Error; err == nil {
+			utils.LogError("Google callback failed - Account with email %s is not linked to Google", googleUser.Email)
+			c.Redirect(http.StatusTemporaryRedirect, googleFrontendURL("/auth/google/callback", url.Values{
+				"error":   {"account_exists"},
+				"email":   {googleUser.Email},
+				"message": {"An account with this email already exists. Log in with your password and link Google from your profile."},
+			}))
+			return
+		}
+
+		// Create new user; the username and password are generated until the user replaces them
 		user = models.User{
-			Email:      googleUser.Email,
-			FirstName:  googleUser.GivenName,
-			LastName:   googleUser.FamilyName,
-			IsVerified: true,
-			GoogleID:   googleUser.ID,
-			Username:   googleUser.Email, // Using email as username for Google users
+			Email:             googleUser.Email,
+			FirstName:         googleUser.GivenName,
+			LastName:          googleUser.FamilyName,
+			IsVerified:        true,
+			GoogleID:          googleUser.ID,
+			Username:          googleUser.Email, // Using email as username for Google users
+			PasswordUnset:     true,
+			ProfileIncomplete: true,
 		}
 
 		// Generate a secure but shorter password for Google users
@@ -114,16 +143,32 @@ func GoogleCallback(c *gin.Context) {
 			utils.LogError("Failed to create referral code for new Google user: %s - %v", user.Email, err)
 			// Don't fail Google login if referral code creation fails
 		}
+	} else if err != nil {
+		utils.LogError("Google callback failed - User lookup error: %v", err)
+		utils.InternalServerError(c, "Failed to look up user", err.Error())
+		return
+	} else if user.DeletionRequestedAt != nil {
+		// Logging in during the deletion grace period restores the account
+		if err := config.DB.Model(&user).Updates(map[string]interface{}{
+			"deletion_requested_at": nil,
+			"deletion_scheduled_at": nil,
+		}).Error; err != nil {
+			utils.LogError("Google callback failed - Failed to restore account: %v", err)
+			utils.InternalServerError(c, "Failed to restore account", err.Error())
+			return
+		}
+		utils.LogInfo("Account deletion cancelled by Google login for user: %s", user.Email)
 	}
 
-	// Generate JWT token
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
+	session, refreshToken, err := utils.StartSession(config.DB, c, user.ID, false, "")
+	if err != nil {
+		utils.LogError("Google callback failed - Failed to start session: %v", err)
+		utils.InternalServerError(c, "Failed to generate token", nil)
+		return
+	}
 
-	tokenString, err := jwtToken.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	// Generate JWT token
+	tokenString, err := utils.SessionToken(&user, session)
 	if err != nil {
 		utils.LogError("Google callback failed - Token generation error: %v", err)
 		utils.InternalServerError(c, "Failed to generate token", err.Error())
@@ -132,17 +177,19 @@ func GoogleCallback(c *gin.Context) {
 
 	// Create user data for frontend
 	userData := gin.H{
-		"id":        user.ID,
-		"email":     user.Email,
-		"firstName": user.FirstName,
-		"lastName":  user.LastName,
+		"id":                user.ID,
+		"email":             user.Email,
+		"firstName":         user.FirstName,
+		"lastName":          user.LastName,
+		"profileIncomplete": user.ProfileIncomplete,
 	}
 	userDataJSON, _ := json.Marshal(userData)
 
 	// Redirect to frontend with token and user data
-	redirectURL := fmt.Sprintf("%s/auth/google/callback?token=%s&user=%s",
+	redirectURL := fmt.Sprintf("%s/auth/google/callback?token=%s&refresh_token=%s&user=%s",
 		os.Getenv("FRONTEND_URL"),
 		url.QueryEscape(tokenString),
+		url.QueryEscape(refreshToken),
 		url.QueryEscape(string(userDataJSON)))
 
 	utils.LogInfo("Google login successful for user: %s", user.Email)
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AuthorRequest represents the author creation/update request
+type AuthorRequest struct {
+	Name     string `json:"name" binding:"required,max=255"`
+	Bio      string `json:"bio" binding:"max=5000"`
+	PhotoURL string `json:"photo_url" binding:"omitempty,url"`
+}
+
+// authorResponse is the author as shown in admin and storefront responses
+func authorResponse(author models.Author) gin.H {
+	return gin.H{
+		"id":        author.ID,
+		"name":      author.Name,
+		"bio":       author.Bio,
+		"photo_url": author.PhotoURL,
+	}
+}
+
+// findOrCreateAuthor returns the author with the given name, ignoring case and surrounding
+// spaces, creating one when the name is new
+func findOrCreateAuthor(tx *gorm.DB, name string) (models.Author, error) {
+	name = strings.TrimSpace(name)
+	var author models.Author
+	err := tx.Where("LOWER(name) = LOWER(?)", name).First(&author).Error
+	if err == gorm.ErrRecordNotFound {
+		author = models.Author{Name: name}
+		err = tx.Create(&author).Error
+	}
+	return author, err
+}
+
+// GetAuthors lists authors with their book counts, optionally filtered by a name search
+func GetAuthors(c *gin.Context) {
+	utils.LogInfo("GetAuthors called")
+
+	pagination := utils.NewPagination(c)
+	query := config.DB.Model(&models.Author{})
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		query = query.Where("name ILIKE ?", "%"+search+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count authors: %v", err)
+		utils.InternalServerError(c, "Failed to fetch authors", err.Error())
+		return
+	}
+	pagination.SetTotal(total)
+
+	type authorRow struct {
+		models.Author
+		BookCount int64
+	}
+	var rows []authorRow
+	if err := query.Select("authors.*, (SELECT COUNT(*) FROM books WHERE books.author_id = authors.id AND books.deleted_at IS NULL) AS book_count").
+		Order("name ASC").Offset(pagination.Offset).Limit(pagination.Limit).Scan(&rows).Error; err != nil {
+		utils.LogError("Failed to fetch authors: %v", err)
+		utils.InternalServerError(c, "Failed to fetch authors", err.Error())
+		return
+	}
+
+	authors := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		author := authorResponse(row.Author)
+		author["book_count"] = row.BookCount
+		authors = append(authors, author)
+	}
+
+	utils.LogInfo("Retrieved %d authors", len(authors))
+	utils.Success(c, "Authors retrieved successfully", gin.H{
+		"authors": authors,
+		"pagination": gin.H{
+			"total":       total,
+			"page":        pagination.Page,
+			"limit":       pagination.Limit,
+			"total_pages": pagination.LastPage,
+		},
+	})
+}
+
+// CreateAuthor handles author creation
+func CreateAuthor(c *gin.Context) {
+	utils.LogInfo("CreateAuthor called")
+
+	var req AuthorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid input: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err.Error())
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		utils.BadRequest(c, "Author name is required", nil)
+		return
+	}
+
+	var existingAuthor models.Author
+	if err := config.DB.Where("LOWER(name) = LOWER(?)", req.Name).First(&existingAuthor).Error; err == nil {
+		utils.LogError("Author with name %s already exists", req.Name)
+		utils.Fail(c, utils.CodeAlreadyExists, "An author with this name already exists", gin.H{
+			"author_id": existingAuthor.ID,
+		})
+		return
+	}
+
+	author := models.Author{
+		Name:     req.Name,
+		Bio:      strings.TrimSpace(req.Bio),
+		PhotoURL: req.PhotoURL,
+	}
+	if err := config.DB.Create(&author).Error; err != nil {
+		utils.LogError("Failed to create author: %v", err)
+		utils.InternalServerError(c, "Failed to create author", err.Error())
+		return
+	}
+
+	utils.LogInfo("Author created successfully: %s", author.Name)
+	utils.Success(c, "Author created successfully", gin.H{
+		"author": authorResponse(author),
+	})
+}
+
+// UpdateAuthor handles author updates. A new name is copied to the author's books.
+func UpdateAuthor(c *gin.Context) {
+	utils.LogInfo("UpdateAuthor called")
+
+	var author models.Author
+	if err := config.DB.First(&author, c.Param("id")).Error; err != nil {
+		utils.LogError("Author not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeAuthorNotFound, "Author not found", nil)
+		return
+	}
+
+	var req AuthorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid input: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err.Error())
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		utils.BadRequest(c, "Author name is required", nil)
+		return
+	}
+
+	var existingAuthor models.Author
+	if err := config.DB.Where("LOWER(name) = LOWER(?) AND id != ?", req.Name, author.ID).First(&existingAuthor).Error; err == nil {
+		utils.LogError("Another author with name %s already exists", req.Name)
+		utils.Fail(c, utils.CodeAlreadyExists, "Another author with this name already exists", gin.H{
+			"author_id": existingAuthor.ID,
+		})
+		return
+	}
+
+	renamed := author.Name != req.Name
+	author.Name = req.Name
+	author.Bio = strings.TrimSpace(req.Bio)
+	author.PhotoURL = req.PhotoURL
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&author).Error; err != nil {
+			return err
+		}
+		if renamed {
+			return tx.Model(&models.Book{}).Unscoped().Where("author_id = ?", author.ID).Update("author", author.Name).Error
+		}
+		return nil
+	})
+	if err != nil {
+		utils.LogError("Failed to update author: %v", err)
+		utils.InternalServerError(c, "Failed to update author", err.Error())
+		return
+	}
+
+	utils.LogInfo("Author updated successfully: %s", author.Name)
+	utils.Success(c, "Author updated successfully", gin.H{
+		"author": authorResponse(author),
+	})
+}
+
+// DeleteAuthor handles author deletion. Authors with books, including books in the trash,
+// cannot be deleted.
+func DeleteAuthor(c *gin.Context) {
+	utils.LogInfo("DeleteAuthor called")
+
+	var author models.Author
+	if err := config.DB.First(&author, c.Param("id")).Error; err != nil {
+		utils.LogError("Author not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeAuthorNotFound, "Author not found", nil)
+		return
+	}
+
+	var bookCount int64
+	if err := config.DB.Model(&models.Book{}).Unscoped().Where("author_id = ?", author.ID).Count(&bookCount).Error; err != nil {
+		utils.LogError("Failed to count books: %v", err)
+		utils.InternalServerError(c, "Failed to check author usage", err.Error())
+		return
+	}
+	if bookCount > 0 {
+		utils.LogError("Cannot delete author with %d books", bookCount)
+		utils.BadRequest(c, "Cannot delete author that has books associated with it", gin.H{
+			"book_count": bookCount,
+		})
+		return
+	}
+
+	if err := config.DB.Delete(&author).Error; err != nil {
+		utils.LogError("Failed to delete author: %v", err)
+		utils.InternalServerError(c, "Failed to delete author", err.Error())
+		return
+	}
+
+	utils.LogInfo("Author deleted successfully: %s", author.Name)
+	utils.Success(c, "Author deleted successfully", nil)
+}
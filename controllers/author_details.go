@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuthorDetails returns an author's page: their profile and their available books
+func GetAuthorDetails(c *gin.Context) {
+	utils.LogInfo("GetAuthorDetails called")
+
+	authorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid author ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid author ID", nil)
+		return
+	}
+
+	var author models.Author
+	if err := config.DB.First(&author, authorID).Error; err != nil {
+		utils.LogError("Author not found: %v", err)
+		utils.Fail(c, utils.CodeAuthorNotFound, "Author not found", nil)
+		return
+	}
+
+	pagination := utils.NewPagination(c)
+	query := config.DB.Model(&models.Book{}).
+		Joins("JOIN categories ON categories.id = books.category_id AND categories.deleted_at IS NULL").
+		Where("books.author_id = ? AND books.is_active = ? AND books.blocked = ? AND categories.blocked = ?", author.ID, true, false, false)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count books for author ID: %d: %v", author.ID, err)
+		utils.InternalServerError(c, "Failed to fetch author books", err.Error())
+		return
+	}
+	pagination.SetTotal(total)
+
+	var books []BookListItem
+	if err := query.Select("books.id, books.name, books.author, books.price, books.image_url, books.is_active, books.stock").
+		Order("books.publication_year DESC, books.id DESC").Offset(pagination.Offset).Limit(pagination.Limit).
+		Scan(&books).Error; err != nil {
+		utils.LogError("Failed to fetch books for author ID: %d: %v", author.ID, err)
+		utils.InternalServerError(c, "Failed to fetch author books", err.Error())
+		return
+	}
+	if books == nil {
+		books = []BookListItem{}
+	}
+
+	utils.LogInfo("Found %d books for author %s", len(books), author.Name)
+	utils.Success(c, "Author retrieved successfully", gin.H{
+		"author": authorResponse(author),
+		"books":  books,
+		"pagination": gin.H{
+			"total":       total,
+			"page":        pagination.Page,
+			"limit":       pagination.Limit,
+			"total_pages": pagination.LastPage,
+		},
+	})
+}
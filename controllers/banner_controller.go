@@ -0,0 +1,349 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// defaultBannerPlacement is the storefront slot of banners created without one
+const defaultBannerPlacement = "home_hero"
+
+var bannerPlacementPattern = regexp.MustCompile(`^[a-z0-9_-]{1,50}$`)
+
+// BannerRequest represents the banner create/update request. ImageURL may be left out on
+// create when the image is uploaded afterwards, but the banner is not shown until it has one.
+type BannerRequest struct {
+	Title     string     `json:"title" binding:"required,min=2,max=100"`
+	Subtitle  string     `json:"subtitle" binding:"max=200"`
+	ImageURL  string     `json:"image_url"`
+	LinkURL   string     `json:"link_url"`
+	Placement string     `json:"placement"`
+	Position  int        `json:"position"`
+	IsActive  *bool      `json:"is_active"`
+	StartsAt  *time.Time `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+}
+
+// AdminListBanners lists every banner, optionally of one placement, in display order
+func AdminListBanners(c *gin.Context) {
+	utils.LogInfo("AdminListBanners called")
+
+	query := config.DB.Model(&models.Banner{})
+	if placement := c.Query("placement"); placement != "" {
+		query = query.Where("placement = ?", placement)
+	}
+
+	var banners []models.Banner
+	if err := query.Order("placement, position, id").Find(&banners).Error; err != nil {
+		utils.LogError("Failed to fetch banners: %v", err)
+		utils.InternalServerError(c, "Failed to fetch banners", nil)
+		return
+	}
+
+	now := time.Now()
+	response := make([]gin.H, 0, len(banners))
+	formatter := utils.NewResponseFormatter(c)
+	for _, banner := range banners {
+		response = append(response, bannerAdminResponse(formatter, banner, now))
+	}
+
+	utils.LogInfo("Retrieved %d banners", len(response))
+	utils.Success(c, "Banners retrieved successfully", gin.H{
+		"banners": response,
+	})
+}
+
+// AdminCreateBanner creates a banner
+func AdminCreateBanner(c *gin.Context) {
+	utils.LogInfo("AdminCreateBanner called")
+
+	var req BannerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid banner request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+
+	banner := models.Banner{IsActive: true}
+	if !applyBannerRequest(c, &banner, req) {
+		return
+	}
+	if err := config.DB.Create(&banner).Error; err != nil {
+		utils.LogError("Failed to create banner: %v", err)
+		utils.InternalServerError(c, "Failed to create banner", nil)
+		return
+	}
+
+	utils.LogInfo("Created banner %d in placement %s", banner.ID, banner.Placement)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Created(c, "Banner created successfully", gin.H{
+		"banner": bannerAdminResponse(formatter, banner, time.Now()),
+	})
+}
+
+// AdminUpdateBanner updates a banner. Leaving image_url out keeps the current image.
+func AdminUpdateBanner(c *gin.Context) {
+	utils.LogInfo("AdminUpdateBanner called")
+
+	var banner models.Banner
+	if err := config.DB.First(&banner, c.Param("id")).Error; err != nil {
+		utils.LogError("Banner not found: %s", c.Param("id"))
+		utils.NotFound(c, "Banner not found")
+		return
+	}
+
+	var req BannerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid banner request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+
+	oldImageKey := banner.ImageKey
+	if !applyBannerRequest(c, &banner, req) {
+		return
+	}
+	if err := config.DB.Save(&banner).Error; err != nil {
+		utils.LogError("Failed to update banner %d: %v", banner.ID, err)
+		utils.InternalServerError(c, "Failed to update banner", nil)
+		return
+	}
+	if oldImageKey != "" && banner.ImageKey != oldImageKey {
+		cleanupStoredImages(utils.GetStorage(), []string{oldImageKey})
+	}
+
+	utils.LogInfo("Updated banner %d", banner.ID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Banner updated successfully", gin.H{
+		"banner": bannerAdminResponse(formatter, banner, time.Now()),
+	})
+}
+
+// AdminUploadBannerImage stores the image sent as multipart form field "image" and makes it
+// the banner's image, replacing the previous upload
+func AdminUploadBannerImage(c *gin.Context) {
+	utils.LogInfo("AdminUploadBannerImage called")
+
+	var banner models.Banner
+	if err := config.DB.First(&banner, c.Param("id")).Error; err != nil {
+		utils.LogError("Banner not found: %s", c.Param("id"))
+		utils.NotFound(c, "Banner not found")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(utils.MaxBookImageSize+1024*1024))
+	file, err := c.FormFile("image")
+	if err != nil {
+		utils.LogError("No banner image uploaded: %v", err)
+		utils.BadRequest(c, "No image uploaded", "Send the image as multipart/form-data under the field \"image\"")
+		return
+	}
+	if file.Size > utils.MaxBookImageSize {
+		utils.BadRequest(c, "Invalid image", "file size exceeds 5MB limit")
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		utils.LogError("Failed to open uploaded file %s: %v", file.Filename, err)
+		utils.BadRequest(c, "Invalid image", err.Error())
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(src, utils.MaxBookImageSize+1))
+	src.Close()
+	if err != nil {
+		utils.LogError("Failed to read uploaded file %s: %v", file.Filename, err)
+		utils.InternalServerError(c, "Failed to read uploaded file", err.Error())
+		return
+	}
+	img, err := utils.ProcessImage(data)
+	if err != nil {
+		utils.LogError("Rejected image %s for banner %d: %v", file.Filename, banner.ID, err)
+		utils.BadRequest(c, "Invalid image", err.Error())
+		return
+	}
+
+	storage := utils.GetStorage()
+	key := fmt.Sprintf("banners/%d/%s%s", banner.ID, uuid.New().String(), img.Extension)
+	if err := storage.Put(key, img.Data, img.ContentType); err != nil {
+		utils.LogError("Failed to store image for banner %d: %v", banner.ID, err)
+		utils.InternalServerError(c, "Failed to store image", err.Error())
+		return
+	}
+
+	oldImageKey := banner.ImageKey
+	banner.ImageKey = key
+	banner.ImageURL = storage.URL(key)
+	if err := config.DB.Model(&banner).Updates(map[string]interface{}{
+		"image_key": banner.ImageKey,
+		"image_url": banner.ImageURL,
+	}).Error; err != nil {
+		cleanupStoredImages(storage, []string{key})
+		utils.LogError("Failed to save image of banner %d: %v", banner.ID, err)
+		utils.InternalServerError(c, "Failed to save image", nil)
+		return
+	}
+	if oldImageKey != "" {
+		cleanupStoredImages(storage, []string{oldImageKey})
+	}
+
+	utils.LogInfo("Uploaded image for banner %d", banner.ID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Banner image uploaded successfully", gin.H{
+		"banner": bannerAdminResponse(formatter, banner, time.Now()),
+	})
+}
+
+// AdminDeleteBanner deletes a banner and its uploaded image
+func AdminDeleteBanner(c *gin.Context) {
+	utils.LogInfo("AdminDeleteBanner called")
+
+	var banner models.Banner
+	if err := config.DB.First(&banner, c.Param("id")).Error; err != nil {
+		utils.LogError("Banner not found: %s", c.Param("id"))
+		utils.NotFound(c, "Banner not found")
+		return
+	}
+
+	if err := config.DB.Delete(&banner).Error; err != nil {
+		utils.LogError("Failed to delete banner %d: %v", banner.ID, err)
+		utils.InternalServerError(c, "Failed to delete banner", nil)
+		return
+	}
+	if banner.ImageKey != "" {
+		cleanupStoredImages(utils.GetStorage(), []string{banner.ImageKey})
+	}
+
+	utils.LogInfo("Deleted banner %d", banner.ID)
+	utils.Success(c, "Banner deleted successfully", nil)
+}
+
+// GetBanners returns the banners live right now for a storefront placement, home_hero by
+// default, in display order
+func GetBanners(c *gin.Context) {
+	utils.LogInfo("GetBanners called")
+
+	placement := c.DefaultQuery("placement", defaultBannerPlacement)
+	now := time.Now()
+
+	var banners []models.Banner
+	if err := config.DB.
+		Where("placement = ? AND is_active = ? AND image_url <> ''", placement, true).
+		Where("(starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)", now, now).
+		Order("position, id").
+		Find(&banners).Error; err != nil {
+		utils.LogError("Failed to fetch banners for %s: %v", placement, err)
+		utils.InternalServerError(c, "Failed to fetch banners", nil)
+		return
+	}
+
+	response := make([]gin.H, 0, len(banners))
+	for _, banner := range banners {
+		response = append(response, gin.H{
+			"id":        banner.ID,
+			"title":     banner.Title,
+			"subtitle":  banner.Subtitle,
+			"image_url": banner.ImageURL,
+			"link_url":  banner.LinkURL,
+			"position":  banner.Position,
+		})
+	}
+
+	utils.LogInfo("Retrieved %d banners for %s", len(response), placement)
+	utils.Success(c, "Banners retrieved successfully", gin.H{
+		"placement": placement,
+		"banners":   response,
+	})
+}
+
+// applyBannerRequest validates the request and copies it onto the banner. It writes the
+// error response and returns false when the request is invalid.
+func applyBannerRequest(c *gin.Context, banner *models.Banner, req BannerRequest) bool {
+	placement := strings.ToLower(strings.TrimSpace(req.Placement))
+	if placement == "" {
+		placement = banner.Placement
+	}
+	if placement == "" {
+		placement = defaultBannerPlacement
+	}
+	if !bannerPlacementPattern.MatchString(placement) {
+		utils.BadRequest(c, "Invalid placement", "Placement must be up to 50 lowercase letters, digits, '_' or '-'")
+		return false
+	}
+	imageURL := strings.TrimSpace(req.ImageURL)
+	if imageURL != "" && !validBannerURL(imageURL) {
+		utils.BadRequest(c, "Invalid image_url", "image_url must be an http(s) URL or a path starting with /")
+		return false
+	}
+	linkURL := strings.TrimSpace(req.LinkURL)
+	if linkURL != "" && !validBannerURL(linkURL) {
+		utils.BadRequest(c, "Invalid link_url", "link_url must be an http(s) URL or a path starting with /")
+		return false
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		utils.BadRequest(c, "Invalid schedule", "ends_at must be after starts_at")
+		return false
+	}
+
+	banner.Title = strings.TrimSpace(req.Title)
+	banner.Subtitle = req.Subtitle
+	if imageURL != "" && imageURL != banner.ImageURL {
+		banner.ImageURL = imageURL
+		banner.ImageKey = ""
+	}
+	banner.LinkURL = linkURL
+	banner.Placement = placement
+	banner.Position = req.Position
+	if req.IsActive != nil {
+		banner.IsActive = *req.IsActive
+	}
+	banner.StartsAt = req.StartsAt
+	banner.EndsAt = req.EndsAt
+	return true
+}
+
+// validBannerURL accepts absolute http(s) URLs and site-relative paths
+func validBannerURL(raw string) bool {
+	if strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, "//") {
+		return true
+	}
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// bannerAdminResponse is the admin view of a banner, including whether it is live at now
+func bannerAdminResponse(f utils.ResponseFormatter, banner models.Banner, now time.Time) gin.H {
+	live := banner.IsActive && banner.ImageURL != "" &&
+		(banner.StartsAt == nil || !banner.StartsAt.After(now)) &&
+		(banner.EndsAt == nil || banner.EndsAt.After(now))
+	response := gin.H{
+		"id":         banner.ID,
+		"title":      banner.Title,
+		"subtitle":   banner.Subtitle,
+		"image_url":  banner.ImageURL,
+		"link_url":   banner.LinkURL,
+		"placement":  banner.Placement,
+		"position":   banner.Position,
+		"is_active":  banner.IsActive,
+		"live":       live,
+		"created_at": f.Timestamp(banner.CreatedAt),
+		"updated_at": f.Timestamp(banner.UpdatedAt),
+	}
+	if banner.StartsAt != nil {
+		response["starts_at"] = f.Timestamp(*banner.StartsAt)
+	}
+	if banner.EndsAt != nil {
+		response["ends_at"] = f.Timestamp(*banner.EndsAt)
+	}
+	return response
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxBulkCategorizeBooks caps how many books one bulk re-categorization may touch
+const maxBulkCategorizeBooks = 1000
+
+// BulkCategorizeFilter selects books by their current taxonomy and metadata
+type BulkCategorizeFilter struct {
+	CategoryID uint   `json:"category_id"`
+	GenreID    uint   `json:"genre_id"`
+	Author     string `json:"author"`
+	Publisher  string `json:"publisher"`
+	Search     string `json:"search"`
+}
+
+// BulkCategorizeRequest moves the selected books to a new category and/or genre
+type BulkCategorizeRequest struct {
+	BookIDs          []uint                `json:"book_ids"`
+	Filter           *BulkCategorizeFilter `json:"filter"`
+	TargetCategoryID uint                  `json:"target_category_id"`
+	TargetGenreID    uint                  `json:"target_genre_id"`
+	DryRun           bool                  `json:"dry_run"`
+}
+
+// BulkCategorizeBooks re-categorizes many books at once, selected either by an explicit
+// ID list or by a filter. With dry_run set it only previews the changes.
+func BulkCategorizeBooks(c *gin.Context) {
+	utils.LogInfo("BulkCategorizeBooks called")
+
+	var req BulkCategorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+
+	if (len(req.BookIDs) == 0) == (req.Filter == nil) {
+		utils.BadRequest(c, "Provide either book_ids or filter", nil)
+		return
+	}
+	if req.TargetCategoryID == 0 && req.TargetGenreID == 0 {
+		utils.BadRequest(c, "Provide target_category_id and/or target_genre_id", nil)
+		return
+	}
+
+	// Validate targets
+	var targetCategory models.Category
+	if req.TargetCategoryID != 0 {
+		if err := config.DB.First(&targetCategory, req.TargetCategoryID).Error; err != nil {
+			utils.LogError("Target category not found: %d", req.TargetCategoryID)
+			utils.NotFound(c, "Target category not found")
+			return
+		}
+		if targetCategory.Blocked {
+			utils.BadRequest(c, "Cannot move books into a blocked category", nil)
+			return
+		}
+	}
+	var targetGenre models.Genre
+	if req.TargetGenreID != 0 {
+		if err := config.DB.First(&targetGenre, req.TargetGenreID).Error; err != nil {
+			utils.LogError("Target genre not found: %d", req.TargetGenreID)
+			utils.NotFound(c, "Target genre not found")
+			return
+		}
+	}
+
+	// Select books
+	query := config.DB.Model(&models.Book{})
+	if len(req.BookIDs) > 0 {
+		query = query.Where("id IN ?", req.BookIDs)
+	} else {
+		f := req.Filter
+		if f.CategoryID == 0 && f.GenreID == 0 && f.Author == "" && f.Publisher == "" && f.Search == "" {
+			utils.BadRequest(c, "Filter must set at least one criterion", nil)
+			return
+		}
+		if f.CategoryID != 0 {
+			query = query.Where("category_id = ?", f.CategoryID)
+		}
+		if f.GenreID != 0 {
+			query = query.Where("genre_id = ?", f.GenreID)
+		}
+		if f.Author != "" {
+			query = query.Where("author ILIKE ?", "%"+f.Author+"%")
+		}
+		if f.Publisher != "" {
+			query = query.Where("publisher ILIKE ?", "%"+f.Publisher+"%")
+		}
+		if f.Search != "" {
+			query = query.Where("name ILIKE ?", "%"+f.Search+"%")
+		}
+	}
+
+	var books []models.Book
+	if err := query.Order("id ASC").Limit(maxBulkCategorizeBooks + 1).Find(&books).Error; err != nil {
+		utils.LogError("Failed to select books for bulk categorize: %v", err)
+		utils.InternalServerError(c, "Failed to select books", err.Error())
+		return
+	}
+	if len(books) > maxBulkCategorizeBooks {
+		utils.BadRequest(c, "Selection matches too many books; narrow the filter", gin.H{
+			"max_books": maxBulkCategorizeBooks,
+		})
+		return
+	}
+
+	// Work out per-book changes; books already in the target taxonomy are skipped
+	preview := make([]gin.H, 0, len(books))
+	var changedIDs []uint
+	for _, book := range books {
+		updates := make(map[string]interface{})
+		if req.TargetCategoryID != 0 {
+			updates["category_id"] = req.TargetCategoryID
+		}
+		if req.TargetGenreID != 0 {
+			updates["genre_id"] = req.TargetGenreID
+		}
+		changes := diffFields(bookFieldValues(book), updates)
+		if len(changes) == 0 {
+			continue
+		}
+		changedIDs = append(changedIDs, book.ID)
+		preview = append(preview, gin.H{
+			"book_id": book.ID,
+			"name":    book.Name,
+			"changes": changes,
+		})
+	}
+
+	result := gin.H{
+		"dry_run":       req.DryRun,
+		"matched_count": len(books),
+		"changed_count": len(changedIDs),
+		"skipped_count": len(books) - len(changedIDs),
+		"books":         preview,
+	}
+
+	if req.DryRun || len(changedIDs) == 0 {
+		utils.LogInfo("Bulk categorize preview: %d matched, %d would change", len(books), len(changedIDs))
+		utils.Success(c, "Bulk categorization preview", result)
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.TargetCategoryID != 0 {
+		updates["category_id"] = req.TargetCategoryID
+	}
+	if req.TargetGenreID != 0 {
+		updates["genre_id"] = req.TargetGenreID
+	}
+
+	tx := config.DB.Begin()
+	if err := tx.Model(&models.Book{}).Where("id IN ?", changedIDs).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to bulk categorize books: %v", err)
+		utils.InternalServerError(c, "Failed to update books", err.Error())
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit bulk categorize: %v", err)
+		utils.InternalServerError(c, "Failed to update books", err.Error())
+		return
+	}
+
+	utils.LogInfo("Bulk categorized %d books (category %d, genre %d)", len(changedIDs), req.TargetCategoryID, req.TargetGenreID)
+	utils.Success(c, "Books re-categorized successfully", result)
+}
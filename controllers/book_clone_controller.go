@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// BookCloneRequest optionally sets the fields that usually differ between editions of a title
+type BookCloneRequest struct {
+	ISBN   string `json:"isbn"`
+	Name   string `json:"name"`
+	Format string `json:"format"`
+}
+
+// CloneBook copies a book with its tags and images into a new inactive draft without stock.
+// The draft gets a placeholder ISBN unless one is given, so it can be edited into another
+// edition of the title before it goes live.
+func CloneBook(c *gin.Context) {
+	utils.LogInfo("CloneBook called")
+
+	var req BookCloneRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.LogError("Invalid clone request: %v", err)
+			utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err)
+			return
+		}
+	}
+
+	var source models.Book
+	if err := config.DB.Preload("Tags").Preload("BookImages").First(&source, c.Param("id")).Error; err != nil {
+		utils.LogError("Book to clone not found: %v", err)
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
+
+	isbn := strings.TrimSpace(req.ISBN)
+	if isbn != "" && checkISBNConflict(c, isbn, 0) {
+		return
+	}
+	if isbn == "" {
+		isbn = "DRAFT-" + uuid.New().String()
+	}
+
+	clone := models.Book{
+		Name:               source.Name,
+		Description:        source.Description,
+		Price:              source.Price,
+		OriginalPrice:      source.OriginalPrice,
+		DiscountPercentage: source.DiscountPercentage,
+		DiscountEndDate:    source.DiscountEndDate,
+		CategoryID:         source.CategoryID,
+		GenreID:            source.GenreID,
+		Tags:               source.Tags,
+		IsFeatured:         source.IsFeatured,
+		Author:             source.Author,
+		AuthorID:           source.AuthorID,
+		Publisher:          source.Publisher,
+		ISBN:               isbn,
+		PublicationYear:    source.PublicationYear,
+		Pages:              source.Pages,
+		Language:           source.Language,
+		Format:             source.Format,
+		HSNCode:            source.HSNCode,
+		TaxRate:            source.TaxRate,
+		BinLocation:        source.BinLocation,
+		IsDigital:          source.IsDigital,
+		IsPreorder:         source.IsPreorder,
+		ReleaseDate:        source.ReleaseDate,
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		clone.Name = name
+	}
+	if format := strings.TrimSpace(req.Format); format != "" {
+		clone.Format = format
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to begin transaction: %v", tx.Error)
+		utils.InternalServerError(c, "Failed to begin transaction", nil)
+		return
+	}
+	// is_active defaults to true when false is created, so the draft is deactivated afterwards
+	if err := tx.Create(&clone).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to create clone of book %d: %v", source.ID, err)
+		utils.InternalServerError(c, "Failed to clone book", err.Error())
+		return
+	}
+	if err := tx.Model(&clone).Update("is_active", false).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to deactivate clone of book %d: %v", source.ID, err)
+		utils.InternalServerError(c, "Failed to clone book", err.Error())
+		return
+	}
+
+	// Uploaded images are copied to keys of their own, so deleting an image from either book
+	// leaves the other's intact
+	storage := utils.GetStorage()
+	var stored []string
+	images := make([]models.BookImage, 0, len(source.BookImages))
+	for _, image := range source.BookImages {
+		copied := models.BookImage{
+			BookID:       clone.ID,
+			URL:          image.URL,
+			ThumbnailURL: image.ThumbnailURL,
+			ContentType:  image.ContentType,
+			Width:        image.Width,
+			Height:       image.Height,
+			SizeBytes:    image.SizeBytes,
+		}
+		name := uuid.New().String()
+		if image.StorageKey != "" {
+			copied.StorageKey = fmt.Sprintf("books/%d/%s%s", clone.ID, name, path.Ext(image.StorageKey))
+			copied.URL = storage.URL(copied.StorageKey)
+		}
+		if image.ThumbnailKey != "" {
+			copied.ThumbnailKey = fmt.Sprintf("books/%d/%s_thumb.jpg", clone.ID, name)
+			copied.ThumbnailURL = storage.URL(copied.ThumbnailKey)
+		}
+		for from, to := range map[string]string{image.StorageKey: copied.StorageKey, image.ThumbnailKey: copied.ThumbnailKey} {
+			if from == "" {
+				continue
+			}
+			contentType := image.ContentType
+			if to == copied.ThumbnailKey {
+				contentType = "image/jpeg"
+			}
+			if err := copyStoredObject(storage, from, to, contentType); err != nil {
+				tx.Rollback()
+				cleanupStoredImages(storage, stored)
+				utils.LogError("Failed to copy image %s of book %d: %v", from, source.ID, err)
+				utils.InternalServerError(c, "Failed to copy book images", err.Error())
+				return
+			}
+			stored = append(stored, to)
+		}
+		if image.URL == source.ImageURL {
+			clone.ImageURL = copied.URL
+		}
+		images = append(images, copied)
+	}
+	if clone.ImageURL == "" {
+		clone.ImageURL = source.ImageURL
+	}
+	if len(images) > 0 {
+		if err := tx.Create(&images).Error; err != nil {
+			tx.Rollback()
+			cleanupStoredImages(storage, stored)
+			utils.LogError("Failed to save images of clone of book %d: %v", source.ID, err)
+			utils.InternalServerError(c, "Failed to copy book images", err.Error())
+			return
+		}
+	}
+	if err := tx.Model(&clone).Update("image_url", clone.ImageURL).Error; err != nil {
+		tx.Rollback()
+		cleanupStoredImages(storage, stored)
+		utils.LogError("Failed to set cover image of clone of book %d: %v", source.ID, err)
+		utils.InternalServerError(c, "Failed to clone book", err.Error())
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		cleanupStoredImages(storage, stored)
+		utils.LogError("Failed to commit clone of book %d: %v", source.ID, err)
+		utils.InternalServerError(c, "Failed to clone book", nil)
+		return
+	}
+
+	clone.BookImages = images
+	utils.LogInfo("Cloned book %d into draft %d", source.ID, clone.ID)
+	utils.Created(c, "Book cloned successfully", gin.H{
+		"book":        clone,
+		"cloned_from": source.ID,
+	})
+}
+
+// copyStoredObject copies a stored object to a new key
+func copyStoredObject(storage utils.Storage, from, to, contentType string) error {
+	data, err := storage.Get(from)
+	if err != nil {
+		return err
+	}
+	return storage.Put(to, data, contentType)
+}
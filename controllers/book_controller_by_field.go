@@ -7,6 +7,8 @@ import (
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UpdateBookByField handles book updates by any unique field
@@ -17,7 +19,7 @@ func UpdateBookByField(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -46,7 +48,7 @@ func UpdateBookByField(c *gin.Context) {
 	var book models.Book
 	if err := config.DB.Where(fmt.Sprintf("%s = ?", fieldName), fieldValue).First(&book).Error; err != nil {
 		utils.LogError("Book not found: %v", err)
-		utils.NotFound(c, "Book not found")
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
 		return
 	}
 
@@ -56,7 +58,7 @@ func UpdateBookByField(c *gin.Context) {
 	var updateData map[string]interface{}
 	if err := c.ShouldBindJSON(&updateData); err != nil {
 		utils.LogError("Invalid input: %v", err)
-		utils.BadRequest(c, "Invalid input", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err)
 		return
 	}
 
@@ -91,7 +93,7 @@ func UpdateBookByField(c *gin.Context) {
 		var category models.Category
 		if err := config.DB.First(&category, uint(categoryID)).Error; err != nil {
 			utils.LogError("Category not found: %v", err)
-			utils.BadRequest(c, "Invalid category ID", "The specified category does not exist")
+			utils.Fail(c, utils.CodeInvalidID, "Invalid category ID", "The specified category does not exist")
 			return
 		}
 		updates["category_id"] = uint(categoryID)
@@ -103,7 +105,7 @@ func UpdateBookByField(c *gin.Context) {
 		var genre models.Genre
 		if err := config.DB.First(&genre, uint(genreID)).Error; err != nil {
 			utils.LogError("Genre not found: %v", err)
-			utils.BadRequest(c, "Invalid genre ID", "The specified genre does not exist")
+			utils.Fail(c, utils.CodeInvalidID, "Invalid genre ID", "The specified genre does not exist")
 			return
 		}
 		updates["genre_id"] = uint(genreID)
@@ -168,11 +170,8 @@ func UpdateBookByField(c *gin.Context) {
 	}
 
 	if isbn, ok := updateData["isbn"].(string); ok && isbn != "" {
-		// Check if ISBN already exists in an active book (excluding the current book)
-		var existingBook models.Book
-		if err := config.DB.Where("isbn = ? AND id != ? AND deleted_at IS NULL", isbn, book.ID).First(&existingBook).Error; err == nil {
-			utils.LogError("ISBN already exists in an active book: %s", isbn)
-			utils.BadRequest(c, "A book with this ISBN already exists", nil)
+		// Check if ISBN already exists in another book, including books in the trash
+		if checkISBNConflict(c, isbn, book.ID) {
 			return
 		}
 		updates["isbn"] = isbn
@@ -202,7 +201,28 @@ func UpdateBookByField(c *gin.Context) {
 	// Update the book with only the provided fields
 	if len(updates) > 0 {
 		utils.LogDebug("Updating book with %d fields", len(updates))
-		if err := config.DB.Model(&book).Updates(updates).Error; err != nil {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			// A new stock is logged as an adjustment by its difference to the locked current stock
+			stockDelta := 0
+			if stock, ok := updates["stock"].(int); ok {
+				var currentStock int
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&models.Book{}).
+					Select("stock").Where("id = ?", book.ID).Scan(&currentStock).Error; err != nil {
+					return err
+				}
+				stockDelta = stock - currentStock
+			}
+			if err := tx.Model(&book).Updates(updates).Error; err != nil {
+				return err
+			}
+			return recordAdminStockMovement(c, tx, utils.StockChange{
+				BookID: book.ID,
+				Delta:  stockDelta,
+				Source: models.StockSourceAdmin,
+				Note:   "Stock set by product update",
+			})
+		})
+		if err != nil {
 			utils.LogError("Failed to update book: %v", err)
 			utils.InternalServerError(c, "Failed to update book", err.Error())
 			return
@@ -221,13 +241,14 @@ func UpdateBookByField(c *gin.Context) {
 	}
 	utils.LogDebug("Successfully fetched updated book details")
 
+	formatter := utils.NewResponseFormatter(c)
 	// Create clean response
 	response := gin.H{
 		"id":               updatedBook.ID,
 		"name":             updatedBook.Name,
 		"description":      updatedBook.Description,
-		"price":            updatedBook.Price,
-		"original_price":   updatedBook.OriginalPrice,
+		"price":            formatter.Money(updatedBook.Price),
+		"original_price":   formatter.Money(updatedBook.OriginalPrice),
 		"stock":            updatedBook.Stock,
 		"image_url":        updatedBook.ImageURL,
 		"is_active":        updatedBook.IsActive,
@@ -239,8 +260,8 @@ func UpdateBookByField(c *gin.Context) {
 		"pages":            updatedBook.Pages,
 		"language":         updatedBook.Language,
 		"format":           updatedBook.Format,
-		"created_at":       updatedBook.CreatedAt,
-		"updated_at":       updatedBook.UpdatedAt,
+		"created_at":       formatter.Timestamp(updatedBook.CreatedAt),
+		"updated_at":       formatter.Timestamp(updatedBook.UpdatedAt),
 		"category": gin.H{
 			"id":          updatedBook.Category.ID,
 			"name":        updatedBook.Category.Name,
@@ -11,26 +11,29 @@ import (
 )
 
 type BookRequest struct {
-	Name               string    `json:"name" binding:"required"`
-	Description        string    `json:"description" binding:"required"`
-	Price              float64   `json:"price" binding:"required,min=0"` // Price in local currency
-	OriginalPrice      float64   `json:"original_price"`
-	DiscountPercentage int       `json:"discount_percentage"`
-	DiscountEndDate    time.Time `json:"discount_end_date"`
-	Stock              int       `json:"stock" binding:"required,min=0"`
-	CategoryID         uint      `json:"category_id" binding:"required"`
-	GenreID            uint      `json:"genre_id" binding:"required"`
-	ImageURL           string    `json:"image_url"`
-	BookImages         []string  `json:"images"`
-	IsActive           bool      `json:"is_active"`
-	IsFeatured         bool      `json:"is_featured"`
-	Author             string    `json:"author" binding:"required"`
-	Publisher          string    `json:"publisher" binding:"required"`
-	ISBN               string    `json:"isbn" binding:"required"`
-	PublicationYear    int       `json:"publication_year" binding:"required"`
-	Pages              int       `json:"pages" binding:"required,min=1"`
-	Language           string    `json:"language"`
-	Format             string    `json:"format"`
+	Name               string     `json:"name" binding:"required"`
+	Description        string     `json:"description" binding:"required"`
+	Price              float64    `json:"price" binding:"required,min=0"` // Price in local currency
+	OriginalPrice      float64    `json:"original_price"`
+	DiscountPercentage int        `json:"discount_percentage"`
+	DiscountEndDate    time.Time  `json:"discount_end_date"`
+	Stock              int        `json:"stock" binding:"required,min=0"`
+	CategoryID         uint       `json:"category_id" binding:"required"`
+	GenreID            uint       `json:"genre_id" binding:"required"`
+	ImageURL           string     `json:"image_url"`
+	BookImages         []string   `json:"images"`
+	IsActive           bool       `json:"is_active"`
+	IsFeatured         bool       `json:"is_featured"`
+	Author             string     `json:"author"`    // name of a new or existing author, when author_id is not given
+	AuthorID           uint       `json:"author_id"` // takes precedence over author
+	Publisher          string     `json:"publisher" binding:"required"`
+	ISBN               string     `json:"isbn" binding:"required"`
+	PublicationYear    int        `json:"publication_year" binding:"required"`
+	Pages              int        `json:"pages" binding:"required,min=1"`
+	Language           string     `json:"language"`
+	Format             string     `json:"format"`
+	IsPreorder         bool       `json:"is_preorder"`
+	ReleaseDate        *time.Time `json:"release_date"` // required for pre-order books
 }
 
 // CreateBook handles book creation
@@ -41,7 +44,7 @@ func CreateBook(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -57,18 +60,13 @@ func CreateBook(c *gin.Context) {
 	var req BookRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid input: %v", err)
-		utils.BadRequest(c, "Invalid input", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err)
 		return
 	}
 	utils.LogDebug("Received book creation request - Name: %s, ISBN: %s", req.Name, req.ISBN)
 
-	// Check if ISBN already exists
-	var existingBook models.Book
-	if err := config.DB.Where("isbn = ? AND deleted_at IS NULL", req.ISBN).First(&existingBook).Error; err == nil {
-		utils.LogError("Book with ISBN %s already exists", req.ISBN)
-		utils.Conflict(c, "A book with this ISBN already exists", gin.H{
-			"isbn": req.ISBN,
-		})
+	// Check if ISBN already exists, including books in the trash
+	if checkISBNConflict(c, req.ISBN, 0) {
 		return
 	}
 	utils.LogDebug("No existing book found with ISBN: %s", req.ISBN)
@@ -77,7 +75,7 @@ func CreateBook(c *gin.Context) {
 	var category models.Category
 	if err := config.DB.First(&category, req.CategoryID).Error; err != nil {
 		utils.LogError("Category not found: %v", err)
-		utils.BadRequest(c, "Invalid category ID", "The specified category does not exist")
+		utils.Fail(c, utils.CodeInvalidID, "Invalid category ID", "The specified category does not exist")
 		return
 	}
 	utils.LogDebug("Found category: %s", category.Name)
@@ -86,11 +84,31 @@ func CreateBook(c *gin.Context) {
 	var genre models.Genre
 	if err := config.DB.First(&genre, req.GenreID).Error; err != nil {
 		utils.LogError("Genre not found: %v", err)
-		utils.BadRequest(c, "Invalid genre ID", "The specified genre does not exist")
+		utils.Fail(c, utils.CodeInvalidID, "Invalid genre ID", "The specified genre does not exist")
 		return
 	}
 	utils.LogDebug("Found genre: %s", genre.Name)
 
+	if req.AuthorID == 0 && strings.TrimSpace(req.Author) == "" {
+		utils.LogError("Book creation without an author")
+		utils.BadRequest(c, "Author is required", "Provide author_id or an author name")
+		return
+	}
+	if req.AuthorID > 0 {
+		var author models.Author
+		if err := config.DB.First(&author, req.AuthorID).Error; err != nil {
+			utils.LogError("Author not found: %v", err)
+			utils.Fail(c, utils.CodeInvalidID, "Invalid author ID", "The specified author does not exist")
+			return
+		}
+	}
+
+	if req.IsPreorder && (req.ReleaseDate == nil || !req.ReleaseDate.After(time.Now())) {
+		utils.LogError("Pre-order book without a future release date")
+		utils.BadRequest(c, "Invalid release date", "Pre-order books need a release date in the future")
+		return
+	}
+
 	// Create the book
 	book := models.Book{
 		Name:               req.Name,
@@ -112,6 +130,8 @@ func CreateBook(c *gin.Context) {
 		Pages:              req.Pages,
 		Language:           req.Language,
 		Format:             req.Format,
+		IsPreorder:         req.IsPreorder,
+		ReleaseDate:        req.ReleaseDate,
 	}
 	utils.LogDebug("Created book model for: %s", book.Name)
 
@@ -124,13 +144,30 @@ func CreateBook(c *gin.Context) {
 	}
 	utils.LogDebug("Started database transaction")
 
+	// Link the book to its author, creating the author on first use of a name
+	var author models.Author
+	var err error
+	if req.AuthorID > 0 {
+		err = tx.First(&author, req.AuthorID).Error
+	} else {
+		author, err = findOrCreateAuthor(tx, req.Author)
+	}
+	if err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to resolve author: %v", err)
+		utils.InternalServerError(c, "Failed to create book", err.Error())
+		return
+	}
+	book.Author = author.Name
+	book.AuthorID = &author.ID
+
 	// Create the book within transaction
 	if err := tx.Create(&book).Error; err != nil {
 		tx.Rollback()
 		utils.LogError("Failed to create book: %v", err)
 
 		if strings.Contains(err.Error(), "unique constraint") || strings.Contains(err.Error(), "duplicate key") {
-			utils.Conflict(c, "A book with this ISBN already exists", gin.H{
+			utils.Fail(c, utils.CodeAlreadyExists, "A book with this ISBN already exists", gin.H{
 				"isbn": req.ISBN,
 			})
 		} else {
@@ -140,6 +177,13 @@ func CreateBook(c *gin.Context) {
 	}
 	utils.LogDebug("Created book record with ID: %d", book.ID)
 
+	if err := recordAdminStockMovement(c, tx, utils.StockChange{BookID: book.ID, Delta: book.Stock, Source: models.StockSourceInitial}); err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to record initial stock of book %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to create book", err.Error())
+		return
+	}
+
 	// Insert BookImages if provided
 	var bookImages []string
 	if len(req.BookImages) > 0 {
@@ -166,13 +210,14 @@ func CreateBook(c *gin.Context) {
 	}
 	utils.LogDebug("Successfully committed transaction")
 
+	formatter := utils.NewResponseFormatter(c)
 	// Create a clean response without internal fields
 	response := gin.H{
 		"id":             book.ID,
 		"name":           book.Name,
 		"description":    book.Description,
-		"price":          book.Price,
-		"original_price": book.OriginalPrice,
+		"price":          formatter.Money(book.Price),
+		"original_price": formatter.Money(book.OriginalPrice),
 		"stock":          book.Stock,
 		"category": gin.H{
 			"id":   category.ID,
@@ -187,6 +232,7 @@ func CreateBook(c *gin.Context) {
 		"is_active":        book.IsActive,
 		"is_featured":      book.IsFeatured,
 		"author":           book.Author,
+		"author_id":        book.AuthorID,
 		"publisher":        book.Publisher,
 		"isbn":             book.ISBN,
 		"publication_year": book.PublicationYear,
@@ -1,15 +1,30 @@
 package controllers
 
 import (
-	"time"
+	"errors"
+	"strconv"
 
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/repositories"
+	"github.com/Govind-619/ReadSphere/services"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
 )
 
-func GetBookDetails(c *gin.Context) {
+// BookHandler serves book details from the catalog and manages the trash
+type BookHandler struct {
+	books *services.BookService
+}
+
+// NewBookHandler returns a BookHandler using books
+func NewBookHandler(books *services.BookService) *BookHandler {
+	return &BookHandler{books: books}
+}
+
+// GetBookDetails returns a book with its images, category and genre. Admins also see hidden
+// books and their catalog fields.
+func (h *BookHandler) GetBookDetails(c *gin.Context) {
 	utils.LogInfo("GetBookDetails called")
 
 	bookID := c.Param("id")
@@ -20,129 +35,75 @@ func GetBookDetails(c *gin.Context) {
 		utils.BadRequest(c, "Book ID is required", nil)
 		return
 	}
+	id, err := strconv.ParseUint(bookID, 10, 32)
+	if err != nil {
+		utils.LogError("Invalid book ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid book ID", nil)
+		return
+	}
 
-	// First, check if the book is blocked for non-admin users
+	// Blocked, inactive and deleted books are hidden from everyone but admins
 	_, isAdmin := c.Get("admin")
 	utils.LogInfo("User is admin: %v", isAdmin)
 
-	if !isAdmin {
-		// Debug query to check book status
-		var bookStatus struct {
-			Blocked   bool       `gorm:"column:blocked"`
-			IsActive  bool       `gorm:"column:is_active"`
-			DeletedAt *time.Time `gorm:"column:deleted_at"`
-		}
-
-		debugQuery := "SELECT blocked, is_active, deleted_at FROM books WHERE id = ?"
-		if err := config.DB.Raw(debugQuery, bookID).Scan(&bookStatus).Error; err != nil {
-			utils.LogError("Failed to check book status: %v", err)
-			utils.InternalServerError(c, "Failed to verify book status", err.Error())
-			return
-		}
-
-		utils.LogInfo("Book %s status - Blocked: %v, Active: %v, Deleted: %v",
-			bookID, bookStatus.Blocked, bookStatus.IsActive, bookStatus.DeletedAt != nil)
-
-		if bookStatus.Blocked {
-			utils.LogError("Access denied: Book %s is blocked", bookID)
-			utils.Forbidden(c, "This book is not available")
-			return
-		}
-
-		if !bookStatus.IsActive {
-			utils.LogError("Access denied: Book %s is not active", bookID)
-			utils.Forbidden(c, "This book is not available")
-			return
-		}
-
-		if bookStatus.DeletedAt != nil {
-			utils.LogError("Access denied: Book %s is deleted", bookID)
-			utils.NotFound(c, "Book not found")
-			return
-		}
-	}
-
-	// Now fetch the book details
-	var book models.Book
-	query := `
-		SELECT 
-			id, created_at, updated_at, deleted_at, 
-			name, description, price, original_price, discount_percentage, discount_end_date, stock, category_id, 
-			genre_id, image_url, is_active, is_featured, views, 
-			average_rating, total_reviews, author, publisher, 
-			isbn, publication_year, genre, pages, language, format,
-			blocked
-		FROM books 
-		WHERE id = ? AND deleted_at IS NULL
-	`
-
-	if err := config.DB.Raw(query, bookID).Scan(&book).Error; err != nil {
-		utils.LogError("Book not found: %v", err)
-		utils.NotFound(c, "Book not found")
+	book, err := h.books.GetDetails(uint(id), isAdmin)
+	switch {
+	case errors.Is(err, services.ErrBookUnavailable):
+		utils.LogError("Access denied: Book %s is blocked or not active", bookID)
+		utils.Forbidden(c, "This book is not available")
 		return
-	}
-
-	// Add this check for not found
-	if book.ID == 0 {
+	case errors.Is(err, repositories.ErrNotFound):
 		utils.LogError("Book not found: %s", bookID)
-		utils.NotFound(c, "Book not found")
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	case err != nil:
+		utils.LogError("Failed to load book %s: %v", bookID, err)
+		utils.InternalServerError(c, "Failed to retrieve book", err.Error())
 		return
 	}
+	bookImages := book.BookImages
 
-	utils.LogInfo("Book found in database: %s (ID: %s, Blocked: %v, Active: %v)",
-		book.Name, bookID, book.Blocked, book.IsActive)
-
-	// Now fetch the images from book_images table
-	var bookImages []models.BookImage
-	if err := config.DB.Where("book_id = ?", bookID).Find(&bookImages).Error; err != nil {
-		utils.LogError("Failed to fetch images for book %s: %v", bookID, err)
-	} else {
-		book.BookImages = bookImages
-		utils.LogInfo("Successfully fetched %d images for book %s", len(bookImages), bookID)
-	}
-
-	// Load category using raw SQL
-	var category models.Category
-	if err := config.DB.Raw("SELECT * FROM categories WHERE id = ?", book.CategoryID).Scan(&category).Error; err != nil {
-		utils.LogError("Failed to load category: %v", err)
-	} else {
-		book.Category = category
-		utils.LogInfo("Successfully loaded category: %s for book %s", category.Name, bookID)
-	}
-
-	// Load genre using raw SQL
-	var genre models.Genre
-	if err := config.DB.Raw("SELECT * FROM genres WHERE id = ?", book.GenreID).Scan(&genre).Error; err != nil {
-		utils.LogError("Failed to load genre: %v", err)
-	} else {
-		book.Genre = genre
-		utils.LogInfo("Successfully loaded genre: %s for book %s", genre.Name, bookID)
-	}
+	utils.LogInfo("Book found in database: %s (ID: %s, Blocked: %v, Active: %v, Images: %d)",
+		book.Name, bookID, book.Blocked, book.IsActive, len(bookImages))
 
 	if isAdmin {
 		utils.LogInfo("Admin access detected for book %s", bookID)
 	}
 
+	// Apply translations for the requested locale, falling back to the default text
+	locale := resolveCatalogLocale(c)
+	book.Name, book.Description = translateOne(models.TranslationEntityBook, book.ID, locale, book.Name, book.Description)
+	book.Category.Name, book.Category.Description = translateOne(models.TranslationEntityCategory, book.Category.ID, locale, book.Category.Name, book.Category.Description)
+	book.Genre.Name, book.Genre.Description = translateOne(models.TranslationEntityGenre, book.Genre.ID, locale, book.Genre.Name, book.Genre.Description)
+
+	formatter := utils.NewResponseFormatter(c)
 	// Create response based on user role
 	response := gin.H{
 		"book": gin.H{
 			"id":               book.ID,
 			"name":             book.Name,
 			"description":      book.Description,
-			"price":            book.Price,
-			"original_price":   book.OriginalPrice,
+			"price":            formatter.Money(book.Price),
+			"original_price":   formatter.Money(book.OriginalPrice),
 			"stock":            book.Stock,
 			"image_url":        book.ImageURL,
 			"images":           bookImages,
 			"author":           book.Author,
+			"author_id":        book.AuthorID,
 			"publisher":        book.Publisher,
 			"isbn":             book.ISBN,
 			"publication_year": book.PublicationYear,
 			"pages":            book.Pages,
 			"language":         book.Language,
 			"format":           book.Format,
-			"created_at":       book.CreatedAt,
-			"updated_at":       book.UpdatedAt,
+			"preview_url":      bookPreviewURL(*book),
+			"is_digital":       book.IsDigital,
+			"is_preorder":      utils.PreorderOpen(*book),
+			"release_date":     formatter.OptionalTimestamp(book.ReleaseDate),
+			"tags":             tagChips(book.Tags),
+			"created_at":       formatter.Timestamp(book.CreatedAt),
+			"updated_at":       formatter.Timestamp(book.UpdatedAt),
+			"locale":           locale,
 			"category": gin.H{
 				"id":          book.Category.ID,
 				"name":        book.Category.Name,
@@ -168,6 +129,11 @@ func GetBookDetails(c *gin.Context) {
 		utils.LogInfo("Added admin-specific fields for book %s", bookID)
 	}
 
+	// Count the view for shoppers; admins looking at the catalog are not shoppers
+	if !isAdmin {
+		recordBookViewFromRequest(c, book.ID)
+	}
+
 	utils.LogInfo("Successfully prepared book details response for book %s", bookID)
 	utils.Success(c, "Book retrieved successfully", response)
 }
@@ -187,7 +153,7 @@ func CheckBookExists(c *gin.Context) {
 	var book models.Book
 	if err := config.DB.First(&book, id).Error; err != nil {
 		utils.LogError("Book not found: %v", err)
-		utils.NotFound(c, "Book not found")
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
 		return
 	}
 
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// E-books are kept in private storage under ebookKeyPrefix and are only handed out by
+// DownloadLibraryFile. Keys under legacyEbookKeyPrefix are e-books uploaded to the public media
+// storage before, until MoveEbooksToPrivateStorage moves them.
+const (
+	ebookKeyPrefix       = "library/"
+	legacyEbookKeyPrefix = "ebooks/"
+)
+
+// ebookStorage returns the storage holding the e-book with the key
+func ebookStorage(key string) utils.Storage {
+	if strings.HasPrefix(key, legacyEbookKeyPrefix) {
+		return utils.GetStorage()
+	}
+	return utils.GetPrivateStorage()
+}
+
+// UploadDigitalBookFile stores the e-book file (PDF or EPUB, multipart form field "file") buyers
+// download, replacing any earlier file, and makes the book a digital book
+func UploadDigitalBookFile(c *gin.Context) {
+	utils.LogInfo("UploadDigitalBookFile called")
+
+	var book models.Book
+	if err := config.DB.First(&book, c.Param("id")).Error; err != nil {
+		utils.LogError("Book not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(utils.MaxDigitalBookSize+1024*1024))
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.LogError("Invalid e-book upload for book ID: %d: %v", book.ID, err)
+		utils.BadRequest(c, "Invalid upload", "Send the e-book as multipart/form-data under the field \"file\"")
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		utils.LogError("Failed to open uploaded e-book %s: %v", file.Filename, err)
+		utils.BadRequest(c, "Invalid e-book file", gin.H{"file": file.Filename, "error": err.Error()})
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(src, utils.MaxDigitalBookSize+1))
+	src.Close()
+	if err != nil {
+		utils.LogError("Failed to read uploaded e-book %s: %v", file.Filename, err)
+		utils.InternalServerError(c, "Failed to read uploaded file", err.Error())
+		return
+	}
+	contentType, extension, err := utils.DetectBookFileType(data, utils.MaxDigitalBookSize)
+	if err != nil {
+		utils.LogError("Rejected e-book %s for book ID: %d: %v", file.Filename, book.ID, err)
+		utils.BadRequest(c, "Invalid e-book file", gin.H{"file": file.Filename, "error": err.Error()})
+		return
+	}
+
+	storage := utils.GetPrivateStorage()
+	key := fmt.Sprintf("%s%d/%s%s", ebookKeyPrefix, book.ID, uuid.New().String(), extension)
+	if err := storage.Put(key, data, contentType); err != nil {
+		utils.LogError("Failed to store e-book for book ID: %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to store e-book", err.Error())
+		return
+	}
+
+	oldKey := book.DigitalFileKey
+	if err := config.DB.Model(&book).Updates(map[string]interface{}{
+		"is_digital":           true,
+		"digital_file_key":     key,
+		"digital_content_type": contentType,
+		"digital_size_bytes":   int64(len(data)),
+	}).Error; err != nil {
+		cleanupStoredImages(storage, []string{key})
+		utils.LogError("Failed to save e-book for book ID: %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to save e-book", err.Error())
+		return
+	}
+	if oldKey != "" {
+		cleanupStoredImages(ebookStorage(oldKey), []string{oldKey})
+	}
+
+	utils.LogInfo("Uploaded %s e-book for book ID: %d (%d bytes)", contentType, book.ID, len(data))
+	utils.Success(c, "E-book uploaded successfully", gin.H{
+		"book_id":      book.ID,
+		"is_digital":   true,
+		"content_type": contentType,
+		"size_bytes":   len(data),
+	})
+}
+
+// DeleteDigitalBookFile removes a book's e-book file and makes it a physical book again. Buyers
+// keep their entitlements but cannot download until a new file is uploaded.
+func DeleteDigitalBookFile(c *gin.Context) {
+	utils.LogInfo("DeleteDigitalBookFile called")
+
+	var book models.Book
+	if err := config.DB.First(&book, c.Param("id")).Error; err != nil {
+		utils.LogError("Book not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
+	if book.DigitalFileKey == "" {
+		utils.NotFound(c, "Book has no e-book file")
+		return
+	}
+
+	oldKey := book.DigitalFileKey
+	if err := config.DB.Model(&book).Updates(map[string]interface{}{
+		"is_digital":           false,
+		"digital_file_key":     "",
+		"digital_content_type": "",
+		"digital_size_bytes":   0,
+	}).Error; err != nil {
+		utils.LogError("Failed to remove e-book for book ID: %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to remove e-book", err.Error())
+		return
+	}
+	cleanupStoredImages(ebookStorage(oldKey), []string{oldKey})
+
+	utils.LogInfo("Removed e-book for book ID: %d", book.ID)
+	utils.Success(c, "E-book removed successfully", nil)
+}
+
+// MoveEbooksToPrivateStorage moves e-books uploaded to the public media storage into private
+// storage, so they can no longer be fetched without a download link
+func MoveEbooksToPrivateStorage() (string, error) {
+	var books []models.Book
+	if err := config.DB.Where("digital_file_key LIKE ?", legacyEbookKeyPrefix+"%").Find(&books).Error; err != nil {
+		return "", err
+	}
+
+	moved := 0
+	for _, book := range books {
+		oldKey := book.DigitalFileKey
+		data, err := utils.GetStorage().Get(oldKey)
+		if err != nil {
+			utils.LogError("Failed to read e-book %s for book ID: %d: %v", oldKey, book.ID, err)
+			continue
+		}
+		key := ebookKeyPrefix + strings.TrimPrefix(oldKey, legacyEbookKeyPrefix)
+		if err := utils.GetPrivateStorage().Put(key, data, book.DigitalContentType); err != nil {
+			return fmt.Sprintf("Moved %d of %d e-books", moved, len(books)), err
+		}
+		// Only move the key if the e-book was not replaced meanwhile
+		result := config.DB.Model(&models.Book{}).Where("id = ? AND digital_file_key = ?", book.ID, oldKey).
+			Update("digital_file_key", key)
+		if result.Error != nil {
+			cleanupStoredImages(utils.GetPrivateStorage(), []string{key})
+			return fmt.Sprintf("Moved %d of %d e-books", moved, len(books)), result.Error
+		}
+		if result.RowsAffected == 0 {
+			cleanupStoredImages(utils.GetPrivateStorage(), []string{key})
+			continue
+		}
+		cleanupStoredImages(utils.GetStorage(), []string{oldKey})
+		moved++
+	}
+	return fmt.Sprintf("Moved %d of %d e-books to private storage", moved, len(books)), nil
+}
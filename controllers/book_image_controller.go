@@ -2,136 +2,146 @@ package controllers
 
 import (
 	"fmt"
+	"io"
+	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
-// UploadBookImages handles multiple image uploads for a book
+// maxBookImagesPerUpload caps how many files one upload request may carry
+const maxBookImagesPerUpload = 5
+
+// UploadBookImages accepts one or more images as multipart form field "images", validates
+// them, stores them with a generated thumbnail and adds them to the book's images. The
+// first image becomes the book's cover when it has none.
 func UploadBookImages(c *gin.Context) {
 	utils.LogInfo("UploadBookImages called")
 
-	// Parse book ID
-	bookIDStr := c.Param("id")
-	bookID, err := strconv.ParseUint(bookIDStr, 10, 32)
-	if err != nil {
-		utils.LogError("Invalid book ID: %v", err)
-		utils.BadRequest(c, "Invalid book ID", "Please provide a valid book ID")
-		return
-	}
-	utils.LogDebug("Processing upload for book ID: %d", bookID)
-
-	// Check if book exists
 	var book models.Book
-	if err := config.DB.First(&book, bookID).Error; err != nil {
-		utils.LogError("Book not found: %v", err)
-		utils.NotFound(c, "Book not found")
+	if err := config.DB.First(&book, c.Param("id")).Error; err != nil {
+		utils.LogError("Book not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
 		return
 	}
 
-	// Get uploaded files
+	// Bound the whole request body before parsing the form
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(maxBookImagesPerUpload*utils.MaxBookImageSize+1024*1024))
 	form, err := c.MultipartForm()
 	if err != nil {
-		utils.LogError("Failed to parse form: %v", err)
-		utils.BadRequest(c, "Invalid form data", "Please provide valid image files")
+		utils.LogError("Invalid multipart form: %v", err)
+		utils.BadRequest(c, "Invalid upload", "Send the images as multipart/form-data under the field \"images\"")
 		return
 	}
-
 	files := form.File["images"]
 	if len(files) == 0 {
-		utils.LogError("No images uploaded")
-		utils.BadRequest(c, "No images uploaded", "Please select at least one image to upload")
-		return
-	}
-
-	if len(files) > 5 {
-		utils.LogError("Too many images uploaded: %d", len(files))
-		utils.BadRequest(c, "Too many images", "Maximum 5 images allowed per book")
+		utils.BadRequest(c, "No images uploaded", "Send the images as multipart/form-data under the field \"images\"")
 		return
 	}
-	utils.LogDebug("Processing %d image files", len(files))
-
-	// Create upload directory
-	uploadDir := "uploads/books"
-	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-		utils.LogError("Failed to create upload directory: %v", err)
-		utils.InternalServerError(c, "Failed to process upload", err.Error())
-		return
-	}
-	utils.LogDebug("Created upload directory: %s", uploadDir)
-
-	// Start transaction
-	tx := config.DB.Begin()
-	if tx.Error != nil {
-		utils.LogError("Failed to start transaction: %v", tx.Error)
-		utils.InternalServerError(c, "Failed to process upload", tx.Error.Error())
+	if len(files) > maxBookImagesPerUpload {
+		utils.BadRequest(c, fmt.Sprintf("At most %d images can be uploaded at once", maxBookImagesPerUpload), nil)
 		return
 	}
 
-	var uploadedImages []gin.H
-	for _, file := range files {
-		// Validate file
-		if err := utils.ValidateImageFile(file); err != nil {
-			tx.Rollback()
-			utils.LogError("Invalid file: %v", err)
-			utils.BadRequest(c, "Invalid file", err.Error())
+	// Validate every file before storing any of them
+	processed := make([]*utils.ProcessedImage, len(files))
+	for i, file := range files {
+		if file.Size > utils.MaxBookImageSize {
+			utils.BadRequest(c, "Invalid image", gin.H{"file": file.Filename, "error": "file size exceeds 5MB limit"})
 			return
 		}
+		src, err := file.Open()
+		if err != nil {
+			utils.LogError("Failed to open uploaded file %s: %v", file.Filename, err)
+			utils.BadRequest(c, "Invalid image", gin.H{"file": file.Filename, "error": err.Error()})
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(src, utils.MaxBookImageSize+1))
+		src.Close()
+		if err != nil {
+			utils.LogError("Failed to read uploaded file %s: %v", file.Filename, err)
+			utils.InternalServerError(c, "Failed to read uploaded file", err.Error())
+			return
+		}
+		img, err := utils.ProcessImage(data)
+		if err != nil {
+			utils.LogError("Rejected image %s for book ID: %d: %v", file.Filename, book.ID, err)
+			utils.BadRequest(c, "Invalid image", gin.H{"file": file.Filename, "error": err.Error()})
+			return
+		}
+		processed[i] = img
+	}
 
-		// Generate unique filename
-		timestamp := time.Now().UnixNano()
-		filename := fmt.Sprintf("%d_%s", timestamp, filepath.Base(file.Filename))
-		filepath := filepath.Join(uploadDir, filename)
-		utils.LogDebug("Generated filename: %s", filename)
-
-		// Save file
-		if err := c.SaveUploadedFile(file, filepath); err != nil {
-			tx.Rollback()
-			utils.LogError("Failed to save file: %v", err)
-			utils.InternalServerError(c, "Failed to save file", err.Error())
+	storage := utils.GetStorage()
+	var stored []string
+	images := make([]models.BookImage, 0, len(processed))
+	for _, img := range processed {
+		name := uuid.New().String()
+		image := models.BookImage{
+			BookID:      book.ID,
+			StorageKey:  fmt.Sprintf("books/%d/%s%s", book.ID, name, img.Extension),
+			ContentType: img.ContentType,
+			Width:       img.Width,
+			Height:      img.Height,
+			SizeBytes:   int64(len(img.Data)),
+		}
+		if err := storage.Put(image.StorageKey, img.Data, img.ContentType); err != nil {
+			cleanupStoredImages(storage, stored)
+			utils.LogError("Failed to store image for book ID: %d: %v", book.ID, err)
+			utils.InternalServerError(c, "Failed to store image", err.Error())
 			return
 		}
+		stored = append(stored, image.StorageKey)
+		image.URL = storage.URL(image.StorageKey)
 
-		// Create image record
-		imageURL := "/" + filepath
-		bookImage := models.BookImage{
-			BookID: uint(bookID),
-			URL:    imageURL,
+		if img.Thumbnail != nil {
+			image.ThumbnailKey = fmt.Sprintf("books/%d/%s_thumb.jpg", book.ID, name)
+			if err := storage.Put(image.ThumbnailKey, img.Thumbnail, img.ThumbnailType); err != nil {
+				cleanupStoredImages(storage, stored)
+				utils.LogError("Failed to store thumbnail for book ID: %d: %v", book.ID, err)
+				utils.InternalServerError(c, "Failed to store image", err.Error())
+				return
+			}
+			stored = append(stored, image.ThumbnailKey)
+			image.ThumbnailURL = storage.URL(image.ThumbnailKey)
 		}
+		images = append(images, image)
+	}
 
-		if err := tx.Create(&bookImage).Error; err != nil {
+	tx := config.DB.Begin()
+	if err := tx.Create(&images).Error; err != nil {
+		tx.Rollback()
+		cleanupStoredImages(storage, stored)
+		utils.LogError("Failed to save images for book ID: %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to save images", err.Error())
+		return
+	}
+	if book.ImageURL == "" {
+		if err := tx.Model(&book).Update("image_url", images[0].URL).Error; err != nil {
 			tx.Rollback()
-			utils.LogError("Failed to save image record: %v", err)
-			utils.InternalServerError(c, "Failed to save image record", err.Error())
+			cleanupStoredImages(storage, stored)
+			utils.LogError("Failed to set cover image for book ID: %d: %v", book.ID, err)
+			utils.InternalServerError(c, "Failed to save images", err.Error())
 			return
 		}
-
-		uploadedImages = append(uploadedImages, gin.H{
-			"id":  bookImage.ID,
-			"url": imageURL,
-		})
-		utils.LogDebug("Created image record with ID: %d", bookImage.ID)
 	}
-
-	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
-		utils.LogError("Failed to commit transaction: %v", err)
-		utils.InternalServerError(c, "Failed to complete upload", err.Error())
+		cleanupStoredImages(storage, stored)
+		utils.LogError("Failed to commit images for book ID: %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to save images", err.Error())
 		return
 	}
 
-	utils.LogInfo("Successfully uploaded %d images for book %d", len(uploadedImages), bookID)
-	utils.Success(c, "Images uploaded successfully", gin.H{
-		"book_id": bookID,
-		"images":  uploadedImages,
-		"count":   len(uploadedImages),
+	utils.LogInfo("Uploaded %d images for book ID: %d", len(images), book.ID)
+	utils.Created(c, "Images uploaded successfully", gin.H{
+		"book_id": book.ID,
+		"images":  images,
 	})
 }
 
@@ -144,7 +154,7 @@ func GetBookImages(c *gin.Context) {
 	bookID, err := strconv.ParseUint(bookIDStr, 10, 32)
 	if err != nil {
 		utils.LogError("Invalid book ID: %v", err)
-		utils.BadRequest(c, "Invalid book ID", "Please provide a valid book ID")
+		utils.Fail(c, utils.CodeInvalidID, "Invalid book ID", "Please provide a valid book ID")
 		return
 	}
 	utils.LogDebug("Fetching images for book ID: %d", bookID)
@@ -153,13 +163,13 @@ func GetBookImages(c *gin.Context) {
 	var book models.Book
 	if err := config.DB.First(&book, bookID).Error; err != nil {
 		utils.LogError("Book not found: %v", err)
-		utils.NotFound(c, "Book not found")
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
 		return
 	}
 
 	// Get book images
 	var images []models.BookImage
-	if err := config.DB.Where("book_id = ?", bookID).Find(&images).Error; err != nil {
+	if err := config.DB.Where("book_id = ?", bookID).Order("id ASC").Find(&images).Error; err != nil {
 		utils.LogError("Failed to fetch images: %v", err)
 		utils.InternalServerError(c, "Failed to fetch images", err.Error())
 		return
@@ -170,8 +180,11 @@ func GetBookImages(c *gin.Context) {
 	var formattedImages []gin.H
 	for _, img := range images {
 		formattedImages = append(formattedImages, gin.H{
-			"id":  img.ID,
-			"url": img.URL,
+			"id":            img.ID,
+			"url":           img.URL,
+			"thumbnail_url": img.ThumbnailURL,
+			"width":         img.Width,
+			"height":        img.Height,
 		})
 	}
 
@@ -183,48 +196,76 @@ func GetBookImages(c *gin.Context) {
 	})
 }
 
-// DeleteBookImage deletes a specific image from a book
+// DeleteBookImage removes an image from a book, along with its stored files when it was uploaded
 func DeleteBookImage(c *gin.Context) {
 	utils.LogInfo("DeleteBookImage called")
 
-	// Parse image ID
-	imageID := c.Param("image_id")
-	if imageID == "" {
-		utils.LogError("Empty image ID provided")
-		utils.BadRequest(c, "Invalid image ID", "Please provide a valid image ID")
+	var image models.BookImage
+	if err := config.DB.Where("id = ? AND book_id = ?", c.Param("image_id"), c.Param("id")).First(&image).Error; err != nil {
+		utils.LogError("Book image not found: %s for book %s", c.Param("image_id"), c.Param("id"))
+		utils.NotFound(c, "Book image not found")
 		return
 	}
-	utils.LogDebug("Deleting image with ID: %s", imageID)
-
-	// Get image record
-	var image models.BookImage
-	if err := config.DB.First(&image, imageID).Error; err != nil {
-		utils.LogError("Image not found: %v", err)
-		utils.NotFound(c, "Image not found")
+	var book models.Book
+	if err := config.DB.First(&book, image.BookID).Error; err != nil {
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
 		return
 	}
 
-	// Delete physical file if it exists
-	if image.URL != "" {
-		filePath := strings.TrimPrefix(image.URL, "/")
-		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
-			utils.LogError("Failed to delete file: %v", err)
-			// Continue with database deletion even if file deletion fails
-		} else {
-			utils.LogDebug("Deleted physical file: %s", filePath)
+	tx := config.DB.Begin()
+	if err := tx.Delete(&image).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to delete book image ID: %d: %v", image.ID, err)
+		utils.InternalServerError(c, "Failed to delete image", err.Error())
+		return
+	}
+	if book.ImageURL == image.URL {
+		// Fall back to the next remaining image as the cover
+		var next models.BookImage
+		cover := ""
+		if err := tx.Where("book_id = ?", book.ID).Order("id ASC").First(&next).Error; err == nil {
+			cover = next.URL
+		}
+		if err := tx.Model(&book).Update("image_url", cover).Error; err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to update cover image for book ID: %d: %v", book.ID, err)
+			utils.InternalServerError(c, "Failed to delete image", err.Error())
+			return
 		}
 	}
-
-	// Delete from database
-	if err := config.DB.Delete(&image).Error; err != nil {
-		utils.LogError("Failed to delete image record: %v", err)
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit image deletion for book ID: %d: %v", book.ID, err)
 		utils.InternalServerError(c, "Failed to delete image", err.Error())
 		return
 	}
 
-	utils.LogInfo("Successfully deleted image %d for book %d", image.ID, image.BookID)
+	// Files are removed after the row so a storage failure never leaves a dangling image record
+	var keys []string
+	for _, key := range []string{image.StorageKey, image.ThumbnailKey} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	cleanupStoredImages(utils.GetStorage(), keys)
+	if image.StorageKey == "" && strings.HasPrefix(image.URL, "/uploads/") {
+		// Images uploaded before the storage backend were saved straight to disk
+		if err := os.Remove(strings.TrimPrefix(image.URL, "/")); err != nil && !os.IsNotExist(err) {
+			utils.LogError("Failed to delete file: %v", err)
+		}
+	}
+
+	utils.LogInfo("Deleted image ID: %d from book ID: %d", image.ID, book.ID)
 	utils.Success(c, "Image deleted successfully", gin.H{
-		"book_id":  image.BookID,
+		"book_id":  book.ID,
 		"image_id": image.ID,
 	})
 }
+
+// cleanupStoredImages deletes stored objects, logging rather than failing on errors
+func cleanupStoredImages(storage utils.Storage, keys []string) {
+	for _, key := range keys {
+		if err := storage.Delete(key); err != nil {
+			utils.LogError("Failed to delete stored image %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminLookupISBN fetches a book's title, authors, publisher, pages, year and cover from the
+// ISBN lookup provider to prefill the create-book form
+func AdminLookupISBN(c *gin.Context) {
+	utils.LogInfo("AdminLookupISBN called")
+
+	isbn, valid := utils.NormalizeISBN(c.Query("isbn"))
+	if !valid {
+		utils.BadRequest(c, "Invalid ISBN", "Provide a 10 or 13 digit ISBN with a valid check digit")
+		return
+	}
+
+	provider := utils.GetISBNLookupProvider()
+	metadata, err := provider.Lookup(isbn)
+	if errors.Is(err, utils.ErrISBNNotFound) {
+		utils.LogInfo("ISBN %s not found by %s", isbn, provider.Name())
+		utils.NotFound(c, "No book found for this ISBN")
+		return
+	}
+	if err != nil {
+		utils.LogError("ISBN lookup of %s with %s failed: %v", isbn, provider.Name(), err)
+		utils.Fail(c, utils.CodeUpstreamFailed, "The ISBN lookup service is unavailable, please try again", nil)
+		return
+	}
+
+	// Let the admin know before they create a duplicate
+	response := gin.H{"book": metadata}
+	var existing models.Book
+	if err := config.DB.Unscoped().Select("id", "deleted_at").Where("isbn = ?", isbn).First(&existing).Error; err == nil {
+		response["existing_book_id"] = existing.ID
+		response["existing_book_trashed"] = existing.DeletedAt.Valid
+	}
+
+	utils.LogInfo("Looked up ISBN %s with %s", isbn, provider.Name())
+	utils.Success(c, "Book details found", response)
+}
@@ -2,9 +2,12 @@ package controllers
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -18,6 +21,8 @@ type BookListRequest struct {
 	SortBy       string  `form:"sort_by" binding:"oneof=name price created_at views average_rating"`
 	CategoryID   uint    `form:"category_id"`
 	GenreID      uint    `form:"genre_id"`
+	AuthorID     uint    `form:"author_id"`
+	Tags         string  `form:"tags"` // comma-separated tag slugs; books with any of them match
 	MinPrice     float64 `form:"min_price"`
 	MaxPrice     float64 `form:"max_price"`
 	IsNewArrival bool    `form:"new_arrival"`
@@ -62,6 +67,10 @@ func GetBooks(c *gin.Context) {
 	if genreID, err := strconv.ParseUint(c.Query("genre_id"), 10, 32); err == nil {
 		req.GenreID = uint(genreID)
 	}
+	if authorID, err := strconv.ParseUint(c.Query("author_id"), 10, 32); err == nil {
+		req.AuthorID = uint(authorID)
+	}
+	req.Tags = c.Query("tags")
 	req.IsNewArrival = c.Query("new_arrival") == "true"
 	req.IsFeatured = c.Query("featured") == "true"
 	minPrice := c.Query("min_price")
@@ -93,10 +102,27 @@ func GetBooks(c *gin.Context) {
 		}
 	}
 
-	// Build the base query with only essential fields
+	sortColumn, ok := bookSortColumns[req.SortBy]
+	if !ok {
+		req.SortBy, sortColumn = "created_at", bookSortColumns["created_at"]
+	}
+	if req.Order != "asc" {
+		req.Order = "desc"
+	}
+
+	// Cursor pagination reads the page past a cursor instead of skipping rows
+	cursor, cursorMode, err := utils.GetCursorParams(c, req.SortBy, req.Order)
+	if err != nil {
+		utils.LogError("Invalid cursor: %v", err)
+		utils.BadRequest(c, "Invalid cursor", "The cursor is malformed or belongs to another sort order")
+		return
+	}
+
+	// Build the base query with only essential fields, and the sort key for cursors
 	query := `
 		SELECT 
-			books.id, books.name, books.author, books.price, books.image_url, books.is_active, books.stock
+			books.id, books.name, books.author, books.price, books.image_url, books.is_active, books.stock,
+			CAST(` + sortColumn + ` AS TEXT) AS cursor_value
 		FROM books
 		JOIN categories ON books.category_id = categories.id
 		WHERE books.deleted_at IS NULL AND categories.deleted_at IS NULL
@@ -121,6 +147,34 @@ func GetBooks(c *gin.Context) {
 		query += fmt.Sprintf(" AND genre_id = %d", req.GenreID)
 	}
 
+	// Add author filter if provided
+	if req.AuthorID > 0 {
+		utils.LogInfo("Filtering by author_id: %d", req.AuthorID)
+		query += fmt.Sprintf(" AND author_id = %d", req.AuthorID)
+	}
+
+	// Add tag filter if provided, matching books with any of the tags
+	var tagFilter string
+	if req.Tags != "" {
+		var tagIDs []uint
+		if err := config.DB.Model(&models.Tag{}).Where("slug IN ?", strings.Split(req.Tags, ",")).Pluck("id", &tagIDs).Error; err != nil {
+			utils.LogError("Failed to resolve tags: %v", err)
+			utils.InternalServerError(c, "Failed to fetch books", err.Error())
+			return
+		}
+		utils.LogInfo("Filtering by tags: %s (%d known)", req.Tags, len(tagIDs))
+		if len(tagIDs) == 0 {
+			tagFilter = " AND FALSE"
+		} else {
+			ids := make([]string, len(tagIDs))
+			for i, id := range tagIDs {
+				ids[i] = strconv.FormatUint(uint64(id), 10)
+			}
+			tagFilter = fmt.Sprintf(" AND books.id IN (SELECT book_id FROM book_tags WHERE tag_id IN (%s))", strings.Join(ids, ","))
+		}
+		query += tagFilter
+	}
+
 	// Add price range filters if provided
 	if req.MinPrice > 0 {
 		utils.LogInfo("Filtering by min_price: %f", req.MinPrice)
@@ -151,6 +205,11 @@ func GetBooks(c *gin.Context) {
 		query += " AND is_featured = true"
 	}
 
+	if cursorMode {
+		fetchCursorBooks(c, &req, query, sortColumn, cursor)
+		return
+	}
+
 	// Get total count for pagination
 	countQuery := `
 		SELECT COUNT(*)
@@ -174,6 +233,14 @@ func GetBooks(c *gin.Context) {
 		countQuery += fmt.Sprintf(" AND genre_id = %d", req.GenreID)
 	}
 
+	// Add author filter if provided
+	if req.AuthorID > 0 {
+		countQuery += fmt.Sprintf(" AND author_id = %d", req.AuthorID)
+	}
+
+	// Add tag filter if provided
+	countQuery += tagFilter
+
 	// Add price range filters if provided
 	if req.MinPrice > 0 {
 		countQuery += fmt.Sprintf(" AND price >= %f", req.MinPrice)
@@ -200,7 +267,7 @@ func GetBooks(c *gin.Context) {
 	}
 
 	var total int64
-	if err := config.DB.Raw(countQuery).Scan(&total).Error; err != nil {
+	if err := utils.RequestDB(c.Request.Context()).Raw(countQuery).Scan(&total).Error; err != nil {
 		utils.LogError("Failed to count books: %v", err)
 		utils.InternalServerError(c, "Failed to count books", err.Error())
 		return
@@ -208,21 +275,8 @@ func GetBooks(c *gin.Context) {
 
 	utils.LogInfo("Total books count: %d", total)
 
-	// Add sorting
-	switch req.SortBy {
-	case "name":
-		query += fmt.Sprintf(" ORDER BY books.name %s", req.Order)
-	case "price":
-		query += fmt.Sprintf(" ORDER BY books.price %s", req.Order)
-	case "created_at":
-		query += fmt.Sprintf(" ORDER BY books.created_at %s", req.Order)
-	case "views":
-		query += fmt.Sprintf(" ORDER BY books.views %s", req.Order)
-	case "average_rating":
-		query += fmt.Sprintf(" ORDER BY books.average_rating %s", req.Order)
-	default:
-		query += fmt.Sprintf(" ORDER BY books.created_at %s", req.Order)
-	}
+	// Add sorting, by ID among books with the same value
+	query += fmt.Sprintf(" ORDER BY %s %s, books.id %s", sortColumn, req.Order, req.Order)
 
 	utils.LogInfo("Sorting by: %s %s", req.SortBy, req.Order)
 
@@ -234,7 +288,7 @@ func GetBooks(c *gin.Context) {
 
 	// Execute the query
 	var books []BookListItem
-	if err := config.DB.Raw(query).Scan(&books).Error; err != nil {
+	if err := utils.RequestDB(c.Request.Context()).Raw(query).Scan(&books).Error; err != nil {
 		utils.LogError("Failed to fetch books: %v", err)
 		utils.InternalServerError(c, "Failed to fetch books", err.Error())
 		return
@@ -242,6 +296,77 @@ func GetBooks(c *gin.Context) {
 
 	utils.LogInfo("Successfully fetched %d books", len(books))
 
+	sendBookList(c, &req, books, gin.H{
+		"total":       total,
+		"page":        req.Page,
+		"limit":       req.Limit,
+		"total_pages": (total + int64(req.Limit) - 1) / int64(req.Limit),
+	})
+}
+
+// bookSortColumns maps the sort_by values of the book listing to columns
+var bookSortColumns = map[string]string{
+	"name":           "books.name",
+	"price":          "books.price",
+	"created_at":     "books.created_at",
+	"views":          "books.views",
+	"average_rating": "books.average_rating",
+}
+
+// fetchCursorBooks sends the page of the filtered book query past the cursor. It skips the
+// total count, which costs as much as the offset it replaces on a large catalog.
+func fetchCursorBooks(c *gin.Context, req *BookListRequest, query, sortColumn string, cursor *utils.CursorParams) {
+	if cursor.Cursor != nil {
+		// The sort key goes back as a quoted literal, which Postgres reads as the column's type
+		query += fmt.Sprintf(" AND (%s, books.id) %s ('%s', %d)", sortColumn, cursor.Comparison(),
+			strings.ReplaceAll(cursor.Cursor.Value, "'", "''"), cursor.Cursor.ID)
+	}
+	direction := cursor.Direction()
+	query += fmt.Sprintf(" ORDER BY %s %s, books.id %s LIMIT %d", sortColumn, direction, direction, cursor.Limit+1)
+	utils.LogInfo("Cursor pagination - Sort: %s %s, Limit: %d, Backward: %t", req.SortBy, req.Order, cursor.Limit, cursor.Backward)
+
+	var rows []struct {
+		BookListItem
+		CursorValue string
+	}
+	if err := utils.RequestDB(c.Request.Context()).Raw(query).Scan(&rows).Error; err != nil {
+		utils.LogError("Failed to fetch books: %v", err)
+		utils.InternalServerError(c, "Failed to fetch books", err.Error())
+		return
+	}
+	n, more := cursor.Page(len(rows))
+	rows = rows[:n]
+	if cursor.Backward {
+		slices.Reverse(rows)
+	}
+	utils.LogInfo("Successfully fetched %d books", len(rows))
+
+	books := make([]BookListItem, len(rows))
+	var first, last *utils.Cursor
+	for i, row := range rows {
+		books[i] = row.BookListItem
+		position := &utils.Cursor{Sort: req.SortBy, Value: row.CursorValue, ID: row.ID}
+		if i == 0 {
+			first = position
+		}
+		last = position
+	}
+	sendBookList(c, req, books, cursor.Pagination(first, last, more))
+}
+
+// sendBookList translates the page of books and sends it with the available filters
+func sendBookList(c *gin.Context, req *BookListRequest, books []BookListItem, pagination gin.H) {
+	// Apply translations for the requested locale
+	locale := resolveCatalogLocale(c)
+	bookIDs := make([]uint, len(books))
+	for i, book := range books {
+		bookIDs[i] = book.ID
+	}
+	bookTranslations := loadTranslations(models.TranslationEntityBook, bookIDs, locale)
+	for i := range books {
+		books[i].Name, _ = translateText(bookTranslations, books[i].ID, books[i].Name, "")
+	}
+
 	// Get categories for filtering with only essential fields
 	type SimpleCategory struct {
 		ID          uint   `json:"id"`
@@ -254,6 +379,14 @@ func GetBooks(c *gin.Context) {
 		utils.LogError("Failed to fetch categories: %v", err)
 		// Continue anyway, as we have the books data
 	}
+	categoryIDs := make([]uint, len(categories))
+	for i, cat := range categories {
+		categoryIDs[i] = cat.ID
+	}
+	categoryTranslations := loadTranslations(models.TranslationEntityCategory, categoryIDs, locale)
+	for i := range categories {
+		categories[i].Name, categories[i].Description = translateText(categoryTranslations, categories[i].ID, categories[i].Name, categories[i].Description)
+	}
 
 	// Get genres for filtering with only essential fields
 	type SimpleGenre struct {
@@ -268,14 +401,24 @@ func GetBooks(c *gin.Context) {
 		// Continue anyway, as we have the books data
 	}
 
+	// Get tags for filtering
+	var tags []models.Tag
+	if err := config.DB.Order("name ASC").Find(&tags).Error; err != nil {
+		utils.LogError("Failed to fetch tags: %v", err)
+		// Continue anyway, as we have the books data
+	}
+	genreIDs := make([]uint, len(genres))
+	for i, genre := range genres {
+		genreIDs[i] = genre.ID
+	}
+	genreTranslations := loadTranslations(models.TranslationEntityGenre, genreIDs, locale)
+	for i := range genres {
+		genres[i].Name, genres[i].Description = translateText(genreTranslations, genres[i].ID, genres[i].Name, genres[i].Description)
+	}
+
 	response := BookListResponse{
-		Books: books,
-		Pagination: gin.H{
-			"total":       total,
-			"page":        req.Page,
-			"limit":       req.Limit,
-			"total_pages": (total + int64(req.Limit) - 1) / int64(req.Limit),
-		},
+		Books:      books,
+		Pagination: pagination,
 		Sort: gin.H{
 			"by":    req.SortBy,
 			"order": req.Order,
@@ -283,10 +426,14 @@ func GetBooks(c *gin.Context) {
 		Filters: gin.H{
 			"category_id": req.CategoryID,
 			"genre_id":    req.GenreID,
+			"author_id":   req.AuthorID,
+			"tags":        req.Tags,
+			"locale":      locale,
 		},
 		AvailableFilters: gin.H{
 			"categories": categories,
 			"genres":     genres,
+			"tags":       tagChips(tags),
 		},
 	}
 
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// bookPreviewURL is the public address the preview of a book is streamed from
+func bookPreviewURL(book models.Book) string {
+	if book.PreviewKey == "" {
+		return ""
+	}
+	return fmt.Sprintf("/v1/books/%d/preview", book.ID)
+}
+
+// UploadBookPreview stores a sample chapter (PDF or EPUB, multipart form field "file") for a
+// book, replacing any earlier preview
+func UploadBookPreview(c *gin.Context) {
+	utils.LogInfo("UploadBookPreview called")
+
+	var book models.Book
+	if err := config.DB.First(&book, c.Param("id")).Error; err != nil {
+		utils.LogError("Book not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(utils.MaxBookPreviewSize+1024*1024))
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.LogError("Invalid preview upload for book ID: %d: %v", book.ID, err)
+		utils.BadRequest(c, "Invalid upload", "Send the preview as multipart/form-data under the field \"file\"")
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		utils.LogError("Failed to open uploaded preview %s: %v", file.Filename, err)
+		utils.BadRequest(c, "Invalid preview file", gin.H{"file": file.Filename, "error": err.Error()})
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(src, utils.MaxBookPreviewSize+1))
+	src.Close()
+	if err != nil {
+		utils.LogError("Failed to read uploaded preview %s: %v", file.Filename, err)
+		utils.InternalServerError(c, "Failed to read uploaded file", err.Error())
+		return
+	}
+	contentType, extension, err := utils.DetectPreviewType(data)
+	if err != nil {
+		utils.LogError("Rejected preview %s for book ID: %d: %v", file.Filename, book.ID, err)
+		utils.BadRequest(c, "Invalid preview file", gin.H{"file": file.Filename, "error": err.Error()})
+		return
+	}
+
+	storage := utils.GetStorage()
+	key := fmt.Sprintf("books/%d/preview-%s%s", book.ID, uuid.New().String(), extension)
+	if err := storage.Put(key, data, contentType); err != nil {
+		utils.LogError("Failed to store preview for book ID: %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to store preview", err.Error())
+		return
+	}
+
+	oldKey := book.PreviewKey
+	if err := config.DB.Model(&book).Updates(map[string]interface{}{
+		"preview_key":          key,
+		"preview_content_type": contentType,
+		"preview_size_bytes":   int64(len(data)),
+	}).Error; err != nil {
+		cleanupStoredImages(storage, []string{key})
+		utils.LogError("Failed to save preview for book ID: %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to save preview", err.Error())
+		return
+	}
+	book.PreviewKey = key
+	if oldKey != "" {
+		cleanupStoredImages(storage, []string{oldKey})
+	}
+
+	utils.LogInfo("Uploaded %s preview for book ID: %d (%d bytes)", contentType, book.ID, len(data))
+	utils.Success(c, "Preview uploaded successfully", gin.H{
+		"book_id":      book.ID,
+		"preview_url":  bookPreviewURL(book),
+		"content_type": contentType,
+		"size_bytes":   len(data),
+	})
+}
+
+// DeleteBookPreview removes a book's sample chapter
+func DeleteBookPreview(c *gin.Context) {
+	utils.LogInfo("DeleteBookPreview called")
+
+	var book models.Book
+	if err := config.DB.First(&book, c.Param("id")).Error; err != nil {
+		utils.LogError("Book not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
+	if book.PreviewKey == "" {
+		utils.NotFound(c, "Book has no preview")
+		return
+	}
+
+	oldKey := book.PreviewKey
+	if err := config.DB.Model(&book).Updates(map[string]interface{}{
+		"preview_key":          "",
+		"preview_content_type": "",
+		"preview_size_bytes":   0,
+	}).Error; err != nil {
+		utils.LogError("Failed to remove preview for book ID: %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to remove preview", err.Error())
+		return
+	}
+	cleanupStoredImages(utils.GetStorage(), []string{oldKey})
+
+	utils.LogInfo("Removed preview for book ID: %d", book.ID)
+	utils.Success(c, "Preview removed successfully", nil)
+}
+
+// StreamBookPreview serves a book's sample chapter. Range requests are honoured so readers can
+// page through large files without downloading them whole.
+func StreamBookPreview(c *gin.Context) {
+	utils.LogInfo("StreamBookPreview called")
+
+	var book models.Book
+	if err := config.DB.Preload("Category").First(&book, c.Param("id")).Error; err != nil {
+		utils.LogError("Book not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
+	if !book.IsActive || book.Blocked || book.Category.Blocked {
+		utils.LogError("Preview requested for unavailable book ID: %d", book.ID)
+		utils.Forbidden(c, "This book is not available")
+		return
+	}
+	if book.PreviewKey == "" {
+		utils.NotFound(c, "This book has no preview")
+		return
+	}
+
+	data, err := utils.GetStorage().Get(book.PreviewKey)
+	if err != nil {
+		utils.LogError("Failed to read preview for book ID: %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to load preview", nil)
+		return
+	}
+
+	extension := ".pdf"
+	if book.PreviewContentType == utils.PreviewTypeEPUB {
+		extension = ".epub"
+	}
+	filename := fmt.Sprintf("book-%d-preview%s", book.ID, extension)
+	c.Header("Content-Type", book.PreviewContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	c.Header("Cache-Control", "public, max-age=3600")
+	http.ServeContent(c.Writer, c.Request, filename, book.UpdatedAt, bytes.NewReader(data))
+}
@@ -1,21 +1,207 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// GetBookReviews handles fetching reviews for a book
+// maxReviewImages caps how many images a review may carry
+const maxReviewImages = 3
+
+// ReviewRequest is a review of a book. Send it as JSON, or as multipart/form-data with up to
+// three images under the field "images".
+type ReviewRequest struct {
+	Rating  int    `json:"rating" form:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" form:"comment" binding:"max=2000"`
+}
+
+// AddReview adds the user's review of a book with optional images. Reviews of books the user
+// has ordered are marked as a verified purchase. Each user reviews a book once.
+func AddReview(c *gin.Context) {
+	utils.LogInfo("AddReview called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	bookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid book ID format: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid book ID", nil)
+		return
+	}
+	var book models.Book
+	if err := config.DB.Where("id = ? AND is_active = ?", bookID, true).First(&book).Error; err != nil {
+		utils.LogError("Book not found: %d", bookID)
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
+
+	// Bound the whole request body before parsing the form
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(maxReviewImages*utils.MaxBookImageSize+1024*1024))
+	var req ReviewRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.LogError("Invalid review for book ID: %d: %v", bookID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Rating must be between 1 and 5 and the comment at most 2000 characters", err)
+		return
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+
+	var existing int64
+	if err := config.DB.Model(&models.Review{}).Where("book_id = ? AND user_id = ?", book.ID, user.ID).Count(&existing).Error; err != nil {
+		utils.LogError("Failed to check existing reviews for book ID: %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to add review", err.Error())
+		return
+	}
+	if existing > 0 {
+		utils.LogError("User %d already reviewed book ID: %d", user.ID, book.ID)
+		utils.Conflict(c, "You have already reviewed this book", nil)
+		return
+	}
+
+	// Validate every image before storing any of them
+	var processed []*utils.ProcessedImage
+	if form, err := c.MultipartForm(); err == nil {
+		files := form.File["images"]
+		if len(files) > maxReviewImages {
+			utils.BadRequest(c, fmt.Sprintf("A review can have at most %d images", maxReviewImages), nil)
+			return
+		}
+		for _, file := range files {
+			if file.Size > utils.MaxBookImageSize {
+				utils.BadRequest(c, "Invalid image", gin.H{"file": file.Filename, "error": "file size exceeds 5MB limit"})
+				return
+			}
+			src, err := file.Open()
+			if err != nil {
+				utils.LogError("Failed to open uploaded file %s: %v", file.Filename, err)
+				utils.BadRequest(c, "Invalid image", gin.H{"file": file.Filename, "error": err.Error()})
+				return
+			}
+			data, err := io.ReadAll(io.LimitReader(src, utils.MaxBookImageSize+1))
+			src.Close()
+			if err != nil {
+				utils.LogError("Failed to read uploaded file %s: %v", file.Filename, err)
+				utils.InternalServerError(c, "Failed to read uploaded file", err.Error())
+				return
+			}
+			img, err := utils.ProcessImage(data)
+			if err != nil {
+				utils.LogError("Rejected review image %s for book ID: %d: %v", file.Filename, book.ID, err)
+				utils.BadRequest(c, "Invalid image", gin.H{"file": file.Filename, "error": err.Error()})
+				return
+			}
+			processed = append(processed, img)
+		}
+	} else if !errors.Is(err, http.ErrNotMultipart) {
+		utils.LogError("Invalid multipart form: %v", err)
+		utils.BadRequest(c, "Invalid upload", "Send the images as multipart/form-data under the field \"images\"")
+		return
+	}
+
+	verified, err := utils.HasPurchasedBook(config.DB, user.ID, book.ID)
+	if err != nil {
+		utils.LogError("Failed to check purchases of book ID: %d by user %d: %v", book.ID, user.ID, err)
+		utils.InternalServerError(c, "Failed to add review", err.Error())
+		return
+	}
+
+	storage := utils.GetStorage()
+	var stored []string
+	review := models.Review{
+		BookID:           book.ID,
+		UserID:           user.ID,
+		Rating:           req.Rating,
+		Comment:          req.Comment,
+		VerifiedPurchase: verified,
+		Status:           models.ReviewStatusPending,
+	}
+	if err := utils.FlagReview(config.DB, &review); err != nil {
+		utils.LogError("Failed to check review for banned words: %v", err)
+		utils.InternalServerError(c, "Failed to add review", err.Error())
+		return
+	}
+	for _, img := range processed {
+		name := uuid.New().String()
+		image := models.ReviewImage{
+			StorageKey:  fmt.Sprintf("reviews/%d/%s%s", book.ID, name, img.Extension),
+			ContentType: img.ContentType,
+			Width:       img.Width,
+			Height:      img.Height,
+			SizeBytes:   int64(len(img.Data)),
+		}
+		if err := storage.Put(image.StorageKey, img.Data, img.ContentType); err != nil {
+			cleanupStoredImages(storage, stored)
+			utils.LogError("Failed to store review image for book ID: %d: %v", book.ID, err)
+			utils.InternalServerError(c, "Failed to store image", err.Error())
+			return
+		}
+		stored = append(stored, image.StorageKey)
+		image.URL = storage.URL(image.StorageKey)
+
+		if img.Thumbnail != nil {
+			image.ThumbnailKey = fmt.Sprintf("reviews/%d/%s_thumb.jpg", book.ID, name)
+			if err := storage.Put(image.ThumbnailKey, img.Thumbnail, img.ThumbnailType); err != nil {
+				cleanupStoredImages(storage, stored)
+				utils.LogError("Failed to store review thumbnail for book ID: %d: %v", book.ID, err)
+				utils.InternalServerError(c, "Failed to store image", err.Error())
+				return
+			}
+			stored = append(stored, image.ThumbnailKey)
+			image.ThumbnailURL = storage.URL(image.ThumbnailKey)
+		}
+		review.Images = append(review.Images, image)
+	}
+
+	// Creating the review also creates its images
+	if err := config.DB.Omit("User").Create(&review).Error; err != nil {
+		cleanupStoredImages(storage, stored)
+		utils.LogError("Failed to save review for book ID: %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to add review", err.Error())
+		return
+	}
+
+	utils.LogInfo("User %d reviewed book ID: %d with %d images (verified purchase: %t)", user.ID, book.ID, len(review.Images), verified)
+	review.User = user
+	utils.Created(c, "Review submitted and awaiting approval", gin.H{
+		"review": review,
+	})
+}
+
+// GetBookReviews handles fetching reviews for a book, with their images and verified
+// purchase badge. Users do not see reviews hidden after abuse reports.
 func GetBookReviews(c *gin.Context) {
 	utils.LogInfo("GetBookReviews called")
 
 	bookID := c.Param("id")
 	utils.LogDebug("Fetching reviews for book ID: %s", bookID)
 
+	query := config.DB.Preload("User").Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where("book_id = ?", bookID)
+	// Reviews hidden after abuse reports are only shown to admins
+	if _, isAdmin := c.Get("admin"); !isAdmin {
+		query = query.Where("is_hidden = ?", false)
+	}
+
 	var reviews []models.Review
-	if err := config.DB.Preload("User").Where("book_id = ?", bookID).Find(&reviews).Error; err != nil {
+	if err := query.Find(&reviews).Error; err != nil {
 		utils.LogError("Failed to fetch reviews: %v", err)
 		utils.InternalServerError(c, "Failed to fetch reviews", err.Error())
 		return
@@ -41,7 +227,17 @@ func ApproveReview(c *gin.Context) {
 	}
 	utils.LogDebug("Found review to approve for book ID: %d", review.BookID)
 
+	now := time.Now()
 	review.IsApproved = true
+	review.Status = models.ReviewStatusApproved
+	review.RejectionReason = ""
+	review.ModeratedAt = &now
+	review.IsHidden = false
+	review.HiddenAt = nil
+	if adminVal, exists := c.Get("admin"); exists {
+		adminID := adminVal.(models.Admin).ID
+		review.ModeratedBy = &adminID
+	}
 	if err := config.DB.Save(&review).Error; err != nil {
 		utils.LogError("Failed to approve review: %v", err)
 		utils.InternalServerError(c, "Failed to approve review", err.Error())
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/repositories"
+	"github.com/Govind-619/ReadSphere/services"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// checkISBNConflict writes a 409 response and returns true if another book, active or
+// trashed, already uses the ISBN. The ISBN unique index covers trashed rows too, so a
+// trashed book has to be restored (or its ISBN changed) before the ISBN can be reused.
+func checkISBNConflict(c *gin.Context, isbn string, excludeID uint) bool {
+	var existingBook models.Book
+	if err := config.DB.Unscoped().Where("isbn = ? AND id != ?", isbn, excludeID).First(&existingBook).Error; err != nil {
+		return false
+	}
+	writeISBNConflict(c, &services.ISBNConflictError{ISBN: isbn, BookID: existingBook.ID, Trashed: existingBook.DeletedAt.Valid})
+	return true
+}
+
+// writeISBNConflict writes the response for an ISBN already used by another book
+func writeISBNConflict(c *gin.Context, conflict *services.ISBNConflictError) {
+	if conflict.Trashed {
+		utils.LogError("ISBN %s belongs to trashed book ID: %d", conflict.ISBN, conflict.BookID)
+		utils.Conflict(c, "A book with this ISBN is in the trash. Restore it instead of creating a new one", gin.H{
+			"isbn":            conflict.ISBN,
+			"trashed_book_id": conflict.BookID,
+		})
+		return
+	}
+
+	utils.LogError("ISBN conflict: %s already exists for book ID: %d", conflict.ISBN, conflict.BookID)
+	utils.Fail(c, utils.CodeAlreadyExists, "A book with this ISBN already exists", gin.H{
+		"isbn": conflict.ISBN,
+	})
+}
+
+// ListTrashedBooks lists soft-deleted books that can still be restored
+func (h *BookHandler) ListTrashedBooks(c *gin.Context) {
+	utils.LogInfo("ListTrashedBooks called")
+
+	page, limit := utils.GetPaginationParams(c)
+	search := c.Query("search")
+	if search != "" {
+		utils.LogDebug("Applied trash search filter: %s", search)
+	}
+
+	books, total, err := h.books.ListTrash(search, (page-1)*limit, limit)
+	if err != nil {
+		utils.LogError("Failed to fetch trashed books: %v", err)
+		utils.InternalServerError(c, "Failed to fetch trashed books", err.Error())
+		return
+	}
+
+	trashed := make([]gin.H, len(books))
+	formatter := utils.NewResponseFormatter(c)
+	for i, book := range books {
+		trashed[i] = gin.H{
+			"id":         book.ID,
+			"name":       book.Name,
+			"author":     book.Author,
+			"isbn":       book.ISBN,
+			"price":      formatter.Money(book.Price),
+			"stock":      book.Stock,
+			"image_url":  book.ImageURL,
+			"deleted_at": formatter.Timestamp(book.DeletedAt.Time),
+		}
+	}
+
+	utils.LogInfo("Retrieved %d trashed books", len(trashed))
+	utils.SuccessWithPagination(c, "Trashed books retrieved successfully", gin.H{
+		"books": trashed,
+	}, total, page, limit)
+}
+
+// RestoreBook moves a soft-deleted book out of the trash
+func (h *BookHandler) RestoreBook(c *gin.Context) {
+	utils.LogInfo("RestoreBook called")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid book ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid book ID", "Please provide a valid book ID")
+		return
+	}
+
+	book, err := h.books.Restore(uint(id))
+	var conflict *services.ISBNConflictError
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		utils.LogError("Trashed book not found: %d", id)
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found in trash", nil)
+		return
+	case errors.Is(err, services.ErrCategoryDeleted):
+		utils.LogError("Category %d of book %d no longer exists", book.CategoryID, book.ID)
+		utils.BadRequest(c, "Cannot restore book whose category has been deleted", gin.H{
+			"category_id": book.CategoryID,
+		})
+		return
+	case errors.As(err, &conflict):
+		writeISBNConflict(c, conflict)
+		return
+	case err != nil:
+		utils.LogError("Failed to restore book %d: %v", id, err)
+		utils.InternalServerError(c, "Failed to restore book", err.Error())
+		return
+	}
+
+	utils.LogInfo("Book restored successfully: %s (ID: %d)", book.Name, book.ID)
+	utils.Success(c, "Book restored successfully", gin.H{
+		"book": gin.H{
+			"id":        book.ID,
+			"name":      book.Name,
+			"isbn":      book.ISBN,
+			"is_active": book.IsActive,
+		},
+	})
+}
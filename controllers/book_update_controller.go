@@ -1,10 +1,14 @@
 package controllers
 
 import (
+	"strings"
+	"time"
+
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
 )
 
 // UpdateBook handles book updates
@@ -15,7 +19,7 @@ func UpdateBook(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -42,7 +46,7 @@ func UpdateBook(c *gin.Context) {
 	var book models.Book
 	if err := config.DB.First(&book, bookID).Error; err != nil {
 		utils.LogError("Book not found: %v", err)
-		utils.NotFound(c, "Book not found")
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
 		return
 	}
 
@@ -52,7 +56,7 @@ func UpdateBook(c *gin.Context) {
 	var updateData map[string]interface{}
 	if err := c.ShouldBindJSON(&updateData); err != nil {
 		utils.LogError("Invalid input: %v", err)
-		utils.BadRequest(c, "Invalid input", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err)
 		return
 	}
 
@@ -96,7 +100,7 @@ func UpdateBook(c *gin.Context) {
 		if err := tx.First(&category, uint(categoryID)).Error; err != nil {
 			tx.Rollback()
 			utils.LogError("Invalid category ID: %v", err)
-			utils.BadRequest(c, "Invalid category ID", "The specified category does not exist")
+			utils.Fail(c, utils.CodeInvalidID, "Invalid category ID", "The specified category does not exist")
 			return
 		}
 		updates["category_id"] = uint(categoryID)
@@ -108,7 +112,7 @@ func UpdateBook(c *gin.Context) {
 		if err := tx.First(&genre, uint(genreID)).Error; err != nil {
 			tx.Rollback()
 			utils.LogError("Invalid genre ID: %v", err)
-			utils.BadRequest(c, "Invalid genre ID", "The specified genre does not exist")
+			utils.Fail(c, utils.CodeInvalidID, "Invalid genre ID", "The specified genre does not exist")
 			return
 		}
 		updates["genre_id"] = uint(genreID)
@@ -130,21 +134,38 @@ func UpdateBook(c *gin.Context) {
 		updates["blocked"] = blocked
 		utils.LogInfo("Updating blocked status to: %v", blocked)
 	}
-	if author, ok := updateData["author"].(string); ok && author != "" {
-		updates["author"] = author
-		utils.LogInfo("Updating author to: %s", author)
+	if authorID, ok := updateData["author_id"].(float64); ok && authorID > 0 {
+		// Verify author exists
+		var author models.Author
+		if err := tx.First(&author, uint(authorID)).Error; err != nil {
+			tx.Rollback()
+			utils.LogError("Invalid author ID: %v", err)
+			utils.Fail(c, utils.CodeInvalidID, "Invalid author ID", "The specified author does not exist")
+			return
+		}
+		updates["author_id"] = author.ID
+		updates["author"] = author.Name
+		utils.LogInfo("Updating author to: %s (ID: %d)", author.Name, author.ID)
+	} else if name, ok := updateData["author"].(string); ok && strings.TrimSpace(name) != "" {
+		author, err := findOrCreateAuthor(tx, name)
+		if err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to resolve author: %v", err)
+			utils.InternalServerError(c, "Failed to update author", nil)
+			return
+		}
+		updates["author_id"] = author.ID
+		updates["author"] = author.Name
+		utils.LogInfo("Updating author to: %s (ID: %d)", author.Name, author.ID)
 	}
 	if publisher, ok := updateData["publisher"].(string); ok && publisher != "" {
 		updates["publisher"] = publisher
 		utils.LogInfo("Updating publisher to: %s", publisher)
 	}
 	if isbn, ok := updateData["isbn"].(string); ok && isbn != "" {
-		// Check ISBN uniqueness
-		var existingBook models.Book
-		if err := tx.Where("isbn = ? AND id != ?", isbn, bookID).First(&existingBook).Error; err == nil {
+		// Check ISBN uniqueness, including books in the trash
+		if checkISBNConflict(c, isbn, book.ID) {
 			tx.Rollback()
-			utils.LogError("ISBN conflict: %s already exists for book ID: %d", isbn, existingBook.ID)
-			utils.Conflict(c, "A book with this ISBN already exists", nil)
 			return
 		}
 		updates["isbn"] = isbn
@@ -166,6 +187,46 @@ func UpdateBook(c *gin.Context) {
 		updates["format"] = format
 		utils.LogInfo("Updating format to: %s", format)
 	}
+	if hsnCode, ok := updateData["hsn_code"].(string); ok {
+		updates["hsn_code"] = hsnCode
+		utils.LogInfo("Updating HSN code to: %s", hsnCode)
+	}
+	if binLocation, ok := updateData["bin_location"].(string); ok {
+		updates["bin_location"] = strings.TrimSpace(binLocation)
+		utils.LogInfo("Updating bin location to: %s", binLocation)
+	}
+	if taxRate, exists := updateData["tax_rate"]; exists {
+		// null resets the book to the store default rate
+		if taxRate == nil {
+			updates["tax_rate"] = nil
+		} else if rate, ok := taxRate.(float64); ok && rate >= 0 && rate <= 100 {
+			updates["tax_rate"] = rate
+			utils.LogInfo("Updating tax rate to: %.2f", rate)
+		} else {
+			tx.Rollback()
+			utils.BadRequest(c, "Invalid tax rate", "tax_rate must be a percentage between 0 and 100")
+			return
+		}
+	}
+	if isPreorder, exists := updateData["is_preorder"].(bool); exists {
+		updates["is_preorder"] = isPreorder
+		utils.LogInfo("Updating is_preorder to: %v", isPreorder)
+	}
+	if releaseDate, exists := updateData["release_date"]; exists {
+		// null removes the release date
+		if releaseDate == nil {
+			updates["release_date"] = nil
+		} else if value, ok := releaseDate.(string); ok {
+			date, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				tx.Rollback()
+				utils.BadRequest(c, "Invalid release date", "release_date must be an RFC 3339 timestamp")
+				return
+			}
+			updates["release_date"] = date
+			utils.LogInfo("Updating release date to: %s", date.Format(time.RFC3339))
+		}
+	}
 
 	// Handle images array if provided
 	if images, ok := updateData["images"].([]interface{}); ok {
@@ -192,6 +253,21 @@ func UpdateBook(c *gin.Context) {
 		utils.LogInfo("Successfully updated images")
 	}
 
+	// Setting the stock is an adjustment by the difference to the stock it replaces, read
+	// under a row lock so checkouts running meanwhile are not counted twice
+	stockDelta := 0
+	if stock, ok := updates["stock"].(int); ok {
+		var currentStock int
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&models.Book{}).
+			Select("stock").Where("id = ?", book.ID).Scan(&currentStock).Error; err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to lock stock of book: %v", err)
+			utils.InternalServerError(c, "Failed to update book", nil)
+			return
+		}
+		stockDelta = stock - currentStock
+	}
+
 	// Update the book if there are changes
 	if len(updates) > 0 {
 		utils.LogInfo("Applying %d updates to book", len(updates))
@@ -201,6 +277,17 @@ func UpdateBook(c *gin.Context) {
 			utils.InternalServerError(c, "Failed to update book", nil)
 			return
 		}
+		if err := recordAdminStockMovement(c, tx, utils.StockChange{
+			BookID: book.ID,
+			Delta:  stockDelta,
+			Source: models.StockSourceAdmin,
+			Note:   "Stock set by product update",
+		}); err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to record stock movement of book: %v", err)
+			utils.InternalServerError(c, "Failed to update book", nil)
+			return
+		}
 	} else {
 		utils.LogInfo("No updates to apply")
 	}
@@ -214,6 +301,12 @@ func UpdateBook(c *gin.Context) {
 
 	utils.LogInfo("Transaction committed successfully")
 
+	// Let back-in-stock subscribers know when the book comes back in stock
+	if stock, ok := updates["stock"].(int); ok && stock > 0 && book.Stock <= 0 {
+		utils.LogInfo("Book ID: %s restocked, notifying subscribers", bookID)
+		utils.TriggerBackInStockNotifications()
+	}
+
 	// Fetch updated book details
 	var updatedBook models.Book
 	if err := config.DB.First(&updatedBook, bookID).Error; err != nil {
@@ -240,19 +333,21 @@ func UpdateBook(c *gin.Context) {
 		utils.LogError("Failed to fetch book images: %v", err)
 	}
 
+	formatter := utils.NewResponseFormatter(c)
 	// Create clean response
 	response := gin.H{
 		"id":               updatedBook.ID,
 		"name":             updatedBook.Name,
 		"description":      updatedBook.Description,
-		"price":            updatedBook.Price,
-		"original_price":   updatedBook.OriginalPrice,
+		"price":            formatter.Money(updatedBook.Price),
+		"original_price":   formatter.Money(updatedBook.OriginalPrice),
 		"stock":            updatedBook.Stock,
 		"image_url":        updatedBook.ImageURL,
 		"is_active":        updatedBook.IsActive,
 		"is_featured":      updatedBook.IsFeatured,
 		"blocked":          updatedBook.Blocked,
 		"author":           updatedBook.Author,
+		"author_id":        updatedBook.AuthorID,
 		"publisher":        updatedBook.Publisher,
 		"isbn":             updatedBook.ISBN,
 		"publication_year": updatedBook.PublicationYear,
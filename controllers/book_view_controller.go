@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// recordBookViewFromRequest records a view of the book by the requesting user, or by the
+// client IP for anonymous visitors. Failures are logged and never fail the request.
+func recordBookViewFromRequest(c *gin.Context, bookID uint) bool {
+	var userID *uint
+	if userVal, exists := c.Get("user"); exists {
+		if user, ok := userVal.(models.User); ok {
+			userID = &user.ID
+		}
+	}
+	counted, err := utils.RecordBookView(bookID, userID, c.ClientIP())
+	if err != nil {
+		utils.LogError("Failed to record view of book ID: %d: %v", bookID, err)
+	}
+	return counted
+}
+
+// CountCachedBookView records the view of book details served from the catalog cache
+func CountCachedBookView(c *gin.Context) {
+	if bookID, err := strconv.ParseUint(c.Param("id"), 10, 32); err == nil {
+		recordBookViewFromRequest(c, uint(bookID))
+	}
+}
+
+// TrackBookView records a view of a book for clients that display cached book details
+func TrackBookView(c *gin.Context) {
+	utils.LogInfo("TrackBookView called")
+
+	bookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid book ID: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeInvalidID, "Invalid book ID", nil)
+		return
+	}
+
+	var book models.Book
+	if err := config.DB.Where("is_active = ? AND blocked = ?", true, false).First(&book, bookID).Error; err != nil {
+		utils.LogError("Book not found for view tracking - Book ID: %d: %v", bookID, err)
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
+
+	counted := recordBookViewFromRequest(c, book.ID)
+	utils.Success(c, "View recorded", gin.H{
+		"book_id": book.ID,
+		"counted": counted,
+	})
+}
+
+// GetRecentlyViewed returns the books the user looked at most recently, newest first
+func GetRecentlyViewed(c *gin.Context) {
+	utils.LogInfo("GetRecentlyViewed called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	// Books that have since been removed or taken off sale are skipped
+	var views []models.BookView
+	if err := config.DB.Preload("Book").
+		Joins("JOIN books ON books.id = book_views.book_id").
+		Where("book_views.user_id = ?", user.ID).
+		Where("books.deleted_at IS NULL AND books.is_active = ? AND books.blocked = ?", true, false).
+		Order("book_views.last_viewed_at DESC").
+		Limit(limit).
+		Find(&views).Error; err != nil {
+		utils.LogError("Failed to fetch recently viewed books for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch recently viewed books", nil)
+		return
+	}
+
+	bookIDs := make([]uint, len(views))
+	for i, view := range views {
+		bookIDs[i] = view.BookID
+	}
+	translations := loadTranslations(models.TranslationEntityBook, bookIDs, resolveCatalogLocale(c))
+
+	books := make([]gin.H, 0, len(views))
+	formatter := utils.NewResponseFormatter(c)
+	for _, view := range views {
+		name, _ := translateText(translations, view.Book.ID, view.Book.Name, "")
+		books = append(books, gin.H{
+			"id":             view.Book.ID,
+			"name":           name,
+			"author":         view.Book.Author,
+			"price":          formatter.Money(view.Book.Price),
+			"image_url":      view.Book.ImageURL,
+			"in_stock":       view.Book.Stock > 0,
+			"average_rating": view.Book.AverageRating,
+			"last_viewed_at": formatter.Timestamp(view.LastViewedAt),
+		})
+	}
+
+	utils.LogInfo("Retrieved %d recently viewed books for user ID: %d", len(books), user.ID)
+	utils.Success(c, "Recently viewed books retrieved successfully", gin.H{
+		"books": books,
+	})
+}
+
+// ClearRecentlyViewed empties the user's recently viewed list. View counts are unaffected.
+func ClearRecentlyViewed(c *gin.Context) {
+	utils.LogInfo("ClearRecentlyViewed called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	if err := config.DB.Where("user_id = ?", user.ID).Delete(&models.BookView{}).Error; err != nil {
+		utils.LogError("Failed to clear recently viewed books for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to clear recently viewed books", nil)
+		return
+	}
+
+	utils.LogInfo("Cleared recently viewed books for user ID: %d", user.ID)
+	utils.Success(c, "Recently viewed books cleared", nil)
+}
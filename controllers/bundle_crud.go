@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BundleRequest represents the bundle creation/update request. Items replace the books of
+// the bundle.
+type BundleRequest struct {
+	Name        string              `json:"name" binding:"required,max=255"`
+	Description string              `json:"description" binding:"max=5000"`
+	ImageURL    string              `json:"image_url" binding:"omitempty,url"`
+	Price       float64             `json:"price" binding:"required,gt=0"`
+	IsActive    *bool               `json:"is_active"`
+	Items       []BundleItemRequest `json:"items" binding:"required,min=2,max=20,dive"`
+}
+
+// BundleItemRequest is a book of a bundle and how many copies of it the bundle holds
+type BundleItemRequest struct {
+	BookID   uint `json:"book_id" binding:"required"`
+	Quantity int  `json:"quantity" binding:"omitempty,min=1,max=10"`
+}
+
+// bundleResponse is the bundle as shown in admin and storefront responses, with its stock
+// derived from the stock of its books
+func bundleResponse(f utils.ResponseFormatter, bundle models.Bundle) gin.H {
+	books := make([]gin.H, 0, len(bundle.Items))
+	for _, item := range bundle.Items {
+		books = append(books, gin.H{
+			"book_id":   item.BookID,
+			"name":      item.Book.Name,
+			"author":    item.Book.Author,
+			"image_url": item.Book.ImageURL,
+			"price":     f.Money(item.Book.Price),
+			"quantity":  item.Quantity,
+		})
+	}
+	return gin.H{
+		"id":          bundle.ID,
+		"name":        bundle.Name,
+		"description": bundle.Description,
+		"image_url":   bundle.ImageURL,
+		"price":       f.Money(utils.BundlePrice(bundle)),
+		"list_price":  f.Money(utils.BundleListPrice(bundle)),
+		"savings":     f.Money(utils.BundleSavings(bundle)),
+		"is_active":   bundle.IsActive,
+		"available":   utils.BundleAvailable(bundle),
+		"stock":       utils.BundleStock(bundle),
+		"books":       books,
+	}
+}
+
+// AdminGetBundles lists every bundle, including inactive ones
+func AdminGetBundles(c *gin.Context) {
+	utils.LogInfo("AdminGetBundles called")
+
+	var bundles []models.Bundle
+	if err := config.DB.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Items.Book.Category").Order("id DESC").Find(&bundles).Error; err != nil {
+		utils.LogError("Failed to fetch bundles: %v", err)
+		utils.InternalServerError(c, "Failed to fetch bundles", err.Error())
+		return
+	}
+
+	response := make([]gin.H, 0, len(bundles))
+	formatter := utils.NewResponseFormatter(c)
+	for _, bundle := range bundles {
+		response = append(response, bundleResponse(formatter, bundle))
+	}
+
+	utils.LogInfo("Retrieved %d bundles", len(response))
+	utils.Success(c, "Bundles retrieved successfully", gin.H{
+		"bundles": response,
+	})
+}
+
+// CreateBundle handles bundle creation
+func CreateBundle(c *gin.Context) {
+	utils.LogInfo("CreateBundle called")
+
+	var req BundleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid input: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err.Error())
+		return
+	}
+
+	bundle := models.Bundle{IsActive: true}
+	items, ok := applyBundleRequest(c, &bundle, req)
+	if !ok {
+		return
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&bundle).Error; err != nil {
+			return err
+		}
+		return replaceBundleItems(tx, bundle.ID, items)
+	})
+	if err != nil {
+		utils.LogError("Failed to create bundle: %v", err)
+		utils.InternalServerError(c, "Failed to create bundle", err.Error())
+		return
+	}
+
+	bundle, err = utils.LoadBundle(config.DB, bundle.ID)
+	if err != nil {
+		utils.LogError("Failed to load bundle ID: %d: %v", bundle.ID, err)
+		utils.InternalServerError(c, "Failed to load bundle", err.Error())
+		return
+	}
+
+	utils.LogInfo("Bundle created successfully: %s", bundle.Name)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Bundle created successfully", gin.H{
+		"bundle": bundleResponse(formatter, bundle),
+	})
+}
+
+// UpdateBundle handles bundle updates
+func UpdateBundle(c *gin.Context) {
+	utils.LogInfo("UpdateBundle called")
+
+	var bundle models.Bundle
+	if err := config.DB.First(&bundle, c.Param("id")).Error; err != nil {
+		utils.LogError("Bundle not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeBundleNotFound, "Bundle not found", nil)
+		return
+	}
+
+	var req BundleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid input: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err.Error())
+		return
+	}
+
+	items, ok := applyBundleRequest(c, &bundle, req)
+	if !ok {
+		return
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&bundle).Error; err != nil {
+			return err
+		}
+		return replaceBundleItems(tx, bundle.ID, items)
+	})
+	if err != nil {
+		utils.LogError("Failed to update bundle: %v", err)
+		utils.InternalServerError(c, "Failed to update bundle", err.Error())
+		return
+	}
+
+	bundle, err = utils.LoadBundle(config.DB, bundle.ID)
+	if err != nil {
+		utils.LogError("Failed to load bundle ID: %d: %v", bundle.ID, err)
+		utils.InternalServerError(c, "Failed to load bundle", err.Error())
+		return
+	}
+
+	utils.LogInfo("Bundle updated successfully: %s", bundle.Name)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Bundle updated successfully", gin.H{
+		"bundle": bundleResponse(formatter, bundle),
+	})
+}
+
+// DeleteBundle deletes a bundle and removes it from every cart. Ordered bundles keep their
+// order items.
+func DeleteBundle(c *gin.Context) {
+	utils.LogInfo("DeleteBundle called")
+
+	var bundle models.Bundle
+	if err := config.DB.First(&bundle, c.Param("id")).Error; err != nil {
+		utils.LogError("Bundle not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeBundleNotFound, "Bundle not found", nil)
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bundle_id = ?", bundle.ID).Delete(&models.CartBundle{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&bundle).Error
+	})
+	if err != nil {
+		utils.LogError("Failed to delete bundle: %v", err)
+		utils.InternalServerError(c, "Failed to delete bundle", err.Error())
+		return
+	}
+
+	utils.LogInfo("Bundle deleted successfully: %s", bundle.Name)
+	utils.Success(c, "Bundle deleted successfully", nil)
+}
+
+// applyBundleRequest validates the request and copies it onto the bundle, returning the books
+// of the bundle. It writes the error response and returns false when the request is invalid.
+func applyBundleRequest(c *gin.Context, bundle *models.Bundle, req BundleRequest) ([]models.BundleItem, bool) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		utils.BadRequest(c, "Invalid bundle", "Name is required")
+		return nil, false
+	}
+
+	items := make([]models.BundleItem, 0, len(req.Items))
+	seen := make(map[uint]bool, len(req.Items))
+	bookIDs := make([]uint, 0, len(req.Items))
+	for _, item := range req.Items {
+		if seen[item.BookID] {
+			utils.BadRequest(c, "Invalid bundle", "Each book can be listed only once")
+			return nil, false
+		}
+		seen[item.BookID] = true
+		quantity := item.Quantity
+		if quantity < 1 {
+			quantity = 1
+		}
+		items = append(items, models.BundleItem{BookID: item.BookID, Quantity: quantity})
+		bookIDs = append(bookIDs, item.BookID)
+	}
+
+	var books []models.Book
+	if err := config.DB.Where("id IN ?", bookIDs).Find(&books).Error; err != nil {
+		utils.LogError("Failed to fetch bundle books: %v", err)
+		utils.InternalServerError(c, "Failed to fetch bundle books", err.Error())
+		return nil, false
+	}
+	found := make(map[uint]models.Book, len(books))
+	for _, book := range books {
+		found[book.ID] = book
+	}
+	var missing []uint
+	priced := models.Bundle{}
+	for _, item := range items {
+		book, ok := found[item.BookID]
+		if !ok {
+			missing = append(missing, item.BookID)
+			continue
+		}
+		priced.Items = append(priced.Items, models.BundleItem{Book: book, Quantity: item.Quantity})
+	}
+	if len(missing) > 0 {
+		utils.LogError("Unknown book IDs for bundle: %v", missing)
+		utils.Fail(c, utils.CodeBookNotFound, "Some books do not exist", gin.H{"book_ids": missing})
+		return nil, false
+	}
+	if listPrice := utils.BundleListPrice(priced); req.Price > listPrice {
+		utils.BadRequest(c, "Invalid bundle", gin.H{
+			"price":      "Bundle price cannot be more than its books cost separately",
+			"list_price": listPrice,
+		})
+		return nil, false
+	}
+
+	bundle.Name = name
+	bundle.Description = strings.TrimSpace(req.Description)
+	bundle.ImageURL = req.ImageURL
+	bundle.Price = req.Price
+	if req.IsActive != nil {
+		bundle.IsActive = *req.IsActive
+	}
+	return items, true
+}
+
+// replaceBundleItems replaces the books of the bundle with the given ones
+func replaceBundleItems(tx *gorm.DB, bundleID uint, items []models.BundleItem) error {
+	if err := tx.Where("bundle_id = ?", bundleID).Delete(&models.BundleItem{}).Error; err != nil {
+		return err
+	}
+	for i := range items {
+		items[i].BundleID = bundleID
+	}
+	return tx.Create(&items).Error
+}
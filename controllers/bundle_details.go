@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetBundles lists the active bundles with their books, price and stock
+func GetBundles(c *gin.Context) {
+	utils.LogInfo("GetBundles called")
+
+	pagination := utils.NewPagination(c)
+	query := config.DB.Model(&models.Bundle{}).Where("is_active = ?", true)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count bundles: %v", err)
+		utils.InternalServerError(c, "Failed to fetch bundles", err.Error())
+		return
+	}
+	pagination.SetTotal(total)
+
+	var bundles []models.Bundle
+	if err := query.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Items.Book.Category").Order("id DESC").
+		Offset(pagination.Offset).Limit(pagination.Limit).Find(&bundles).Error; err != nil {
+		utils.LogError("Failed to fetch bundles: %v", err)
+		utils.InternalServerError(c, "Failed to fetch bundles", err.Error())
+		return
+	}
+
+	response := make([]gin.H, 0, len(bundles))
+	formatter := utils.NewResponseFormatter(c)
+	for _, bundle := range bundles {
+		response = append(response, bundleResponse(formatter, bundle))
+	}
+
+	utils.LogInfo("Retrieved %d bundles", len(response))
+	utils.Success(c, "Bundles retrieved successfully", gin.H{
+		"bundles": response,
+		"pagination": gin.H{
+			"total":       total,
+			"page":        pagination.Page,
+			"limit":       pagination.Limit,
+			"total_pages": pagination.LastPage,
+		},
+	})
+}
+
+// GetBundleDetails returns an active bundle with its books, price and stock
+func GetBundleDetails(c *gin.Context) {
+	utils.LogInfo("GetBundleDetails called")
+
+	bundleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid bundle ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid bundle ID", nil)
+		return
+	}
+
+	bundle, err := utils.LoadBundle(config.DB, uint(bundleID))
+	if err != nil || !bundle.IsActive {
+		utils.LogError("Bundle not found: %d", bundleID)
+		utils.Fail(c, utils.CodeBundleNotFound, "Bundle not found", nil)
+		return
+	}
+
+	utils.LogInfo("Retrieved bundle %s", bundle.Name)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Bundle retrieved successfully", gin.H{
+		"bundle": bundleResponse(formatter, bundle),
+	})
+}
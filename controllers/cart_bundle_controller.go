@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AddBundleToCart adds a bundle to the user's cart. The cart shows the bundle as one line per
+// book and checkout takes the copies from each book's stock.
+func AddBundleToCart(c *gin.Context) {
+	utils.LogInfo("AddBundleToCart called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
+		return
+	}
+	user, ok := userVal.(models.User)
+	if !ok {
+		utils.LogError("Invalid user type in context")
+		utils.BadRequest(c, "Invalid user in context", nil)
+		return
+	}
+	userID := user.ID
+
+	var req struct {
+		BundleID uint `json:"bundle_id" binding:"required"`
+		Quantity int  `json:"quantity"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format for user ID: %d: %v", userID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	if req.Quantity < 1 {
+		req.Quantity = 1
+	}
+	utils.LogInfo("Adding bundle ID: %d with quantity: %d to cart for user ID: %d", req.BundleID, req.Quantity, userID)
+
+	bundle, err := utils.LoadBundle(config.DB, req.BundleID)
+	if err != nil {
+		utils.LogError("Bundle not found: %d for user ID: %d", req.BundleID, userID)
+		utils.Fail(c, utils.CodeBundleNotFound, "Bundle not found", nil)
+		return
+	}
+	if !utils.BundleAvailable(bundle) {
+		utils.LogError("Bundle ID: %d is not available", req.BundleID)
+		utils.Fail(c, utils.CodeBookUnavailable, "Bundle not available", nil)
+		return
+	}
+
+	var cartBundle models.CartBundle
+	config.DB.Where("user_id = ? AND bundle_id = ?", userID, bundle.ID).First(&cartBundle)
+	quantity := cartBundle.Quantity + req.Quantity
+
+	maxQuantity := utils.MaxCartQuantity()
+	if quantity > maxQuantity {
+		utils.LogError("Quantity exceeds max limit for bundle ID: %d, requested: %d, max: %d", bundle.ID, quantity, maxQuantity)
+		utils.Fail(c, utils.CodeCartQuantityLimit, fmt.Sprintf("Cannot add more than %d of the same bundle", maxQuantity), nil)
+		return
+	}
+	if stock := utils.BundleStock(bundle); quantity > stock {
+		utils.LogError("Insufficient stock for bundle ID: %d, requested: %d, available: %d", bundle.ID, quantity, stock)
+		utils.Fail(c, utils.CodeOutOfStock, fmt.Sprintf("Not enough stock. Available: %d", stock), nil)
+		return
+	}
+
+	successMessage := "Bundle quantity updated"
+	if cartBundle.ID == 0 {
+		cartBundle = models.CartBundle{UserID: userID, BundleID: bundle.ID}
+		successMessage = "Bundle added to cart successfully"
+	}
+	cartBundle.Quantity = quantity
+	if err := config.DB.Save(&cartBundle).Error; err != nil {
+		utils.LogError("Failed to add bundle ID: %d to cart for user ID: %d: %v", bundle.ID, userID, err)
+		utils.InternalServerError(c, "Failed to add to cart", nil)
+		return
+	}
+
+	details, err := utils.NewPricingEngine(config.DB).PriceCart(userID)
+	if err != nil {
+		utils.LogError("Failed to fetch updated cart for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to fetch updated cart", nil)
+		return
+	}
+
+	utils.LogInfo("Bundle ID: %d in cart of user ID: %d with quantity: %d", bundle.ID, userID, quantity)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, successMessage, cartSummaryResponse(formatter, details))
+}
+
+// RemoveBundleFromCart removes a bundle and all of its books from the cart
+func RemoveBundleFromCart(c *gin.Context) {
+	utils.LogInfo("RemoveBundleFromCart called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
+		return
+	}
+	user, ok := userVal.(models.User)
+	if !ok {
+		utils.LogError("Invalid user type in context")
+		utils.BadRequest(c, "Invalid user in context", nil)
+		return
+	}
+	userID := user.ID
+
+	var req struct {
+		BundleID uint `json:"bundle_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format for user ID: %d: %v", userID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+
+	if err := config.DB.Where("user_id = ? AND bundle_id = ?", userID, req.BundleID).Delete(&models.CartBundle{}).Error; err != nil {
+		utils.LogError("Failed to remove bundle ID: %d from cart for user ID: %d: %v", req.BundleID, userID, err)
+		utils.InternalServerError(c, "Failed to remove bundle from cart", nil)
+		return
+	}
+	utils.LogInfo("Successfully removed bundle ID: %d from cart for user ID: %d", req.BundleID, userID)
+	utils.Success(c, "Bundle removed from cart successfully", nil)
+}
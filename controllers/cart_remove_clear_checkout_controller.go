@@ -14,7 +14,7 @@ func RemoveFromCart(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "Unauthorized")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -31,7 +31,7 @@ func RemoveFromCart(c *gin.Context) {
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request format for user ID: %d: %v", userID, err)
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 	utils.LogInfo("Removing book ID: %d from cart for user ID: %d", req.BookID, userID)
@@ -49,7 +49,7 @@ func ClearCart(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "Unauthorized")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -61,8 +61,11 @@ func ClearCart(c *gin.Context) {
 	userID := user.ID
 	utils.LogInfo("Processing clear cart for user ID: %d", userID)
 
-	db := config.DB
-	db.Where("user_id = ?", userID).Delete(&models.Cart{})
+	if err := utils.ClearCart(config.DB, userID); err != nil {
+		utils.LogError("Failed to clear cart for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to clear cart", nil)
+		return
+	}
 	utils.LogInfo("Successfully cleared cart for user ID: %d", userID)
 	utils.Success(c, "Cart cleared successfully", nil)
 }
@@ -74,7 +77,7 @@ func CheckoutCart(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "Unauthorized")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -91,7 +94,7 @@ func CheckoutCart(c *gin.Context) {
 	db.Preload("Book").Where("user_id = ?", userID).Find(&cartItems)
 	if len(cartItems) == 0 {
 		utils.LogError("Empty cart for user ID: %d", userID)
-		utils.BadRequest(c, "Cart is empty", nil)
+		utils.Fail(c, utils.CodeCartEmpty, "Cart is empty", nil)
 		return
 	}
 	utils.LogInfo("Found %d items in cart for user ID: %d", len(cartItems), userID)
@@ -99,7 +102,7 @@ func CheckoutCart(c *gin.Context) {
 	for _, item := range cartItems {
 		if !item.Book.IsActive || item.Book.Blocked {
 			utils.LogError("Book ID: %d is not available or blocked for user ID: %d", item.BookID, userID)
-			utils.BadRequest(c, "Book not available or blocked by admin", nil)
+			utils.Fail(c, utils.CodeBookUnavailable, "Book not available or blocked by admin", nil)
 			return
 		}
 		if item.Book.CategoryID != 0 {
@@ -108,13 +111,13 @@ func CheckoutCart(c *gin.Context) {
 			db.First(&category, item.Book.CategoryID)
 			if category.Blocked {
 				utils.LogError("Category ID: %d is blocked for book ID: %d, user ID: %d", item.Book.CategoryID, item.BookID, userID)
-				utils.BadRequest(c, "Category blocked by admin", nil)
+				utils.Fail(c, utils.CodeBookUnavailable, "Category blocked by admin", nil)
 				return
 			}
 		}
 		if item.Book.Stock < item.Quantity {
 			utils.LogError("Insufficient stock for book ID: %d, requested: %d, available: %d", item.BookID, item.Quantity, item.Book.Stock)
-			utils.BadRequest(c, "Book out of stock", nil)
+			utils.Fail(c, utils.CodeOutOfStock, "Book out of stock", nil)
 			return
 		}
 	}
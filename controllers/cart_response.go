@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// cartLineResponse is the response representation of a priced cart line
+func cartLineResponse(f utils.ResponseFormatter, line utils.CartLine) gin.H {
+	response := gin.H{
+		"id":                       line.CartItem.ID,
+		"book_id":                  line.Book.ID,
+		"name":                     line.Book.Name,
+		"author":                   line.Book.Author,
+		"image_url":                line.Book.ImageURL,
+		"category":                 line.Book.Category.Name,
+		"quantity":                 line.Quantity,
+		"original_price":           f.Money(line.UnitPrice),
+		"product_offer_percent":    line.Offer.ProductOfferPercent,
+		"category_offer_percent":   line.Offer.CategoryOfferPercent,
+		"applied_offer_percent":    line.Offer.AppliedOfferPercent,
+		"applied_offer_type":       line.Offer.AppliedOfferType,
+		"suppressed_offer":         line.Offer.SuppressedOffer,
+		"suppressed_offer_percent": line.Offer.SuppressedPercent,
+		"offer_capped_percent":     line.Offer.CappedPercent,
+		"offer_unit_price":         f.Money(line.OfferUnitPrice),
+		"product_discount":         f.Money(line.ProductDiscount),
+		"category_discount":        f.Money(line.CategoryDiscount),
+		"coupon_discount":          f.Money(line.CouponDiscount),
+		"total_discount":           f.Money(line.TotalDiscount()),
+		"final_unit_price":         f.Money(line.FinalUnitPrice()),
+		"item_total":               f.Money(line.Total),
+		"available":                line.Available,
+		"stock_status":             line.StockStatus,
+		"expires_at":               f.Timestamp(utils.CartItemExpiresAt(line.CartItem)),
+	}
+	// Books of a bundle are removed with their bundle and do not expire on their own
+	if line.Bundle != nil {
+		delete(response, "id")
+		delete(response, "expires_at")
+		response["bundle_id"] = line.Bundle.ID
+		response["bundle_name"] = line.Bundle.Name
+	}
+	return response
+}
+
+// cartSummaryResponse is the response representation of a priced cart shared by the
+// cart, coupon and checkout endpoints
+func cartSummaryResponse(f utils.ResponseFormatter, details *utils.CartDetails) gin.H {
+	items := make([]gin.H, 0, len(details.Lines))
+	for _, line := range details.Lines {
+		items = append(items, cartLineResponse(f, line))
+	}
+	return gin.H{
+		"cart":                     items,
+		"total_quantity":           details.TotalQuantity,
+		"subtotal":                 f.Money(details.Subtotal),
+		"product_discount":         f.Money(details.ProductDiscount),
+		"category_discount":        f.Money(details.CategoryDiscount),
+		"coupon_code":              details.CouponCode,
+		"coupon_discount":          f.Money(details.CouponDiscount),
+		"coupon_discount_per_unit": f.Money(details.CouponDiscountPerUnit),
+		"total_discount":           f.Money(details.TotalDiscount),
+		"final_total":              f.Money(details.FinalTotal),
+		"can_checkout":             details.CanCheckout,
+		"coupon_auto_applied":      details.CouponAutoApplied,
+		"coupon_alternatives":      couponAlternativesResponse(f, details.CouponAlternatives),
+		"offer_rules":              offerRulesSummary(utils.GetOfferRules()),
+	}
+}
+
+// offerRulesSummary tells shoppers how the offers on their cart were combined
+func offerRulesSummary(rules models.OfferRules) gin.H {
+	return gin.H{
+		"stacking_policy":      rules.StackingPolicy,
+		"max_discount_percent": rules.MaxDiscountPercent,
+	}
+}
+
+// couponAlternativesResponse is the response representation of the other auto-apply coupons
+// a cart qualifies for
+func couponAlternativesResponse(f utils.ResponseFormatter, alternatives []utils.CouponAlternative) []gin.H {
+	response := make([]gin.H, 0, len(alternatives))
+	for _, alternative := range alternatives {
+		response = append(response, gin.H{
+			"code":        alternative.Code,
+			"discount":    f.Money(alternative.Discount),
+			"final_total": f.Money(alternative.FinalTotal),
+		})
+	}
+	return response
+}
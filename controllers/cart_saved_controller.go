@@ -0,0 +1,301 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// savedItemRequest identifies the book to move between the cart and the saved list
+type savedItemRequest struct {
+	BookID uint `json:"book_id" binding:"required"`
+}
+
+// savedItemsResponse returns the user's saved-for-later items with their current price and
+// whether they can go back into the cart as they are
+func savedItemsResponse(f utils.ResponseFormatter, db *gorm.DB, userID uint) ([]gin.H, error) {
+	var saved []models.SavedItem
+	if err := db.Preload("Book.Category").Where("user_id = ?", userID).Order("updated_at DESC").Find(&saved).Error; err != nil {
+		return nil, err
+	}
+
+	books := make([]models.Book, len(saved))
+	for i, item := range saved {
+		books[i] = item.Book
+	}
+	offers, _ := utils.GetOfferBreakdowns(books)
+
+	items := make([]gin.H, 0, len(saved))
+	for _, item := range saved {
+		line := utils.PriceLine(item.Book, item.Quantity, offers[item.Book.ID])
+		items = append(items, gin.H{
+			"id":               item.ID,
+			"book_id":          item.BookID,
+			"name":             item.Book.Name,
+			"author":           item.Book.Author,
+			"image_url":        item.Book.ImageURL,
+			"quantity":         item.Quantity,
+			"original_price":   f.Money(line.UnitPrice),
+			"offer_unit_price": f.Money(line.OfferUnitPrice),
+			"available":        line.Available,
+			"stock_status":     line.StockStatus,
+			"saved_at":         f.Timestamp(item.UpdatedAt),
+		})
+	}
+	return items, nil
+}
+
+// GetSavedForLater lists the user's saved-for-later items
+func GetSavedForLater(c *gin.Context) {
+	utils.LogInfo("GetSavedForLater called")
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
+		return
+	}
+	userID := user.(models.User).ID
+
+	formatter := utils.NewResponseFormatter(c)
+	items, err := savedItemsResponse(formatter, config.DB, userID)
+	if err != nil {
+		utils.LogError("Failed to fetch saved items for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to fetch saved items", nil)
+		return
+	}
+
+	utils.LogInfo("Retrieved %d saved items for user ID: %d", len(items), userID)
+	utils.Success(c, "Saved items retrieved successfully", gin.H{
+		"saved_for_later": items,
+	})
+}
+
+// SaveForLater moves a cart item to the saved-for-later list, keeping its quantity
+func SaveForLater(c *gin.Context) {
+	utils.LogInfo("SaveForLater called")
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
+		return
+	}
+	userID := user.(models.User).ID
+
+	var req savedItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format for user ID: %d: %v", userID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to start transaction: %v", tx.Error)
+		utils.InternalServerError(c, "Failed to start transaction", nil)
+		return
+	}
+
+	var cartItem models.Cart
+	if err := tx.Where("user_id = ? AND book_id = ?", userID, req.BookID).First(&cartItem).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Book ID: %d not in cart for user ID: %d", req.BookID, userID)
+		utils.NotFound(c, "Item not found in cart")
+		return
+	}
+
+	// A book saved before keeps the larger of the two quantities
+	var saved models.SavedItem
+	err := tx.Where("user_id = ? AND book_id = ?", userID, req.BookID).First(&saved).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		tx.Rollback()
+		utils.LogError("Failed to check saved items for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to save item", nil)
+		return
+	}
+	saved.UserID = userID
+	saved.BookID = req.BookID
+	if cartItem.Quantity > saved.Quantity {
+		saved.Quantity = cartItem.Quantity
+	}
+	if err := tx.Save(&saved).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to save item for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to save item", nil)
+		return
+	}
+	if err := tx.Delete(&cartItem).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to remove cart item for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to save item", nil)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit transaction for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to save item", nil)
+		return
+	}
+
+	respondWithCartAndSaved(c, userID, "Item saved for later")
+}
+
+// MoveSavedToCart moves a saved-for-later item back into the cart with its quantity, after
+// checking the book can still be bought and the quantity is in stock
+func MoveSavedToCart(c *gin.Context) {
+	utils.LogInfo("MoveSavedToCart called")
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
+		return
+	}
+	userID := user.(models.User).ID
+
+	var req savedItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format for user ID: %d: %v", userID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to start transaction: %v", tx.Error)
+		utils.InternalServerError(c, "Failed to start transaction", nil)
+		return
+	}
+
+	var saved models.SavedItem
+	if err := tx.Where("user_id = ? AND book_id = ?", userID, req.BookID).First(&saved).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Book ID: %d not in saved items for user ID: %d", req.BookID, userID)
+		utils.NotFound(c, "Item not found in saved items")
+		return
+	}
+
+	var book models.Book
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Category").First(&book, req.BookID).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Book not found: %d for user ID: %d", req.BookID, userID)
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
+	if !book.IsActive || book.Blocked || book.Category.Blocked {
+		tx.Rollback()
+		utils.LogError("Book ID: %d is not available", req.BookID)
+		utils.Fail(c, utils.CodeBookUnavailable, "Book is no longer available", nil)
+		return
+	}
+
+	// Merge with any copies added to the cart since the item was saved
+	var cartItem models.Cart
+	quantity := saved.Quantity
+	if err := tx.Where("user_id = ? AND book_id = ?", userID, req.BookID).First(&cartItem).Error; err == nil {
+		quantity += cartItem.Quantity
+	}
+	if maxQuantity := utils.MaxCartQuantity(); quantity > maxQuantity {
+		tx.Rollback()
+		utils.LogError("Quantity exceeds max limit for book ID: %d, requested: %d, max: %d", req.BookID, quantity, maxQuantity)
+		utils.Fail(c, utils.CodeCartQuantityLimit, fmt.Sprintf("Cannot have more than %d copies of the same book in the cart", maxQuantity), nil)
+		return
+	}
+	if quantity > book.Stock && !utils.PreorderOpen(book) {
+		tx.Rollback()
+		utils.LogError("Insufficient stock for book ID: %d, requested: %d, available: %d", req.BookID, quantity, book.Stock)
+		utils.Fail(c, utils.CodeOutOfStock, fmt.Sprintf("Not enough stock. Available: %d", book.Stock), gin.H{
+			"available": book.Stock,
+			"requested": quantity,
+		})
+		return
+	}
+
+	offer, _ := utils.GetOfferBreakdownForBook(book.ID, book.CategoryID)
+	priced := utils.PriceLine(book, quantity, offer)
+	cartItem.UserID = userID
+	cartItem.BookID = req.BookID
+	cartItem.Quantity = quantity
+	cartItem.PriceAtAdd = priced.OfferUnitPrice
+	cartItem.StockStatusAtAdd = priced.StockStatus
+	if err := tx.Save(&cartItem).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to add saved item to cart for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to move item to cart", nil)
+		return
+	}
+	if err := tx.Delete(&saved).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to remove saved item for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to move item to cart", nil)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit transaction for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to move item to cart", nil)
+		return
+	}
+
+	respondWithCartAndSaved(c, userID, "Item moved to cart")
+}
+
+// RemoveSavedItem deletes an item from the saved-for-later list
+func RemoveSavedItem(c *gin.Context) {
+	utils.LogInfo("RemoveSavedItem called")
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
+		return
+	}
+	userID := user.(models.User).ID
+
+	var req savedItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format for user ID: %d: %v", userID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+
+	result := config.DB.Where("user_id = ? AND book_id = ?", userID, req.BookID).Delete(&models.SavedItem{})
+	if result.Error != nil {
+		utils.LogError("Failed to remove saved item for user ID: %d: %v", userID, result.Error)
+		utils.InternalServerError(c, "Failed to remove saved item", nil)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.NotFound(c, "Item not found in saved items")
+		return
+	}
+
+	utils.LogInfo("Removed book ID: %d from saved items for user ID: %d", req.BookID, userID)
+	utils.Success(c, "Saved item removed successfully", nil)
+}
+
+// respondWithCartAndSaved sends the priced cart together with the saved-for-later list
+func respondWithCartAndSaved(c *gin.Context, userID uint, message string) {
+	details, err := utils.NewPricingEngine(config.DB).PriceCart(userID)
+	if err != nil {
+		utils.LogError("Failed to fetch updated cart for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to fetch updated cart", nil)
+		return
+	}
+	formatter := utils.NewResponseFormatter(c)
+	saved, err := savedItemsResponse(formatter, config.DB, userID)
+	if err != nil {
+		utils.LogError("Failed to fetch saved items for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to fetch saved items", nil)
+		return
+	}
+
+	utils.LogInfo("%s for user ID: %d", message, userID)
+	response := cartSummaryResponse(formatter, details)
+	response["saved_for_later"] = saved
+	utils.Success(c, message, response)
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/Govind-619/ReadSphere/models"
+)
+
+// FieldChange captures the previous and new value of a changed field
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// priceFields are the fields whose changes are flagged as pricing changes in the feed
+var priceFields = map[string]bool{
+	"price":               true,
+	"original_price":      true,
+	"discount_percentage": true,
+	"discount_percent":    true,
+}
+
+// bookFieldValues returns the current value of every book column UpdateBook/UpdateBookByField can change
+func bookFieldValues(book models.Book) map[string]interface{} {
+	return map[string]interface{}{
+		"name":             book.Name,
+		"description":      book.Description,
+		"price":            book.Price,
+		"original_price":   book.OriginalPrice,
+		"stock":            book.Stock,
+		"category_id":      book.CategoryID,
+		"genre_id":         book.GenreID,
+		"image_url":        book.ImageURL,
+		"is_active":        book.IsActive,
+		"is_featured":      book.IsFeatured,
+		"blocked":          book.Blocked,
+		"author":           book.Author,
+		"author_id":        bookAuthorIDValue(book),
+		"publisher":        book.Publisher,
+		"isbn":             book.ISBN,
+		"publication_year": book.PublicationYear,
+		"pages":            book.Pages,
+		"language":         book.Language,
+		"format":           book.Format,
+		"hsn_code":         book.HSNCode,
+		"tax_rate":         bookTaxRateValue(book),
+		"bin_location":     book.BinLocation,
+		"is_preorder":      book.IsPreorder,
+		"release_date":     bookReleaseDateValue(book),
+	}
+}
+
+// bookAuthorIDValue returns the book's author ID, or nil when it is not linked to an author
+func bookAuthorIDValue(book models.Book) interface{} {
+	if book.AuthorID == nil {
+		return nil
+	}
+	return *book.AuthorID
+}
+
+// bookTaxRateValue returns the book's own tax rate, or nil when it uses the store default
+func bookTaxRateValue(book models.Book) interface{} {
+	if book.TaxRate == nil {
+		return nil
+	}
+	return *book.TaxRate
+}
+
+// bookReleaseDateValue returns the book's release date, or nil when it has none
+func bookReleaseDateValue(book models.Book) interface{} {
+	if book.ReleaseDate == nil {
+		return nil
+	}
+	return *book.ReleaseDate
+}
+
+// diffFields compares the pending updates against the current values and keeps only real changes
+func diffFields(current map[string]interface{}, updates map[string]interface{}) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	for field, newValue := range updates {
+		oldValue, tracked := current[field]
+		if !tracked {
+			continue
+		}
+		if fmt.Sprint(oldValue) != fmt.Sprint(newValue) {
+			changes[field] = FieldChange{Old: oldValue, New: newValue}
+		}
+	}
+	return changes
+}
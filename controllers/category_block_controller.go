@@ -24,7 +24,7 @@ func ToggleCategoryBlock(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -48,7 +48,7 @@ func ToggleCategoryBlock(c *gin.Context) {
 	id, err := strconv.ParseUint(categoryID, 10, 32)
 	if err != nil {
 		utils.LogError("Invalid category ID format: %v", err)
-		utils.BadRequest(c, "Invalid category ID format", "Category ID must be a valid number")
+		utils.Fail(c, utils.CodeInvalidID, "Invalid category ID format", "Category ID must be a valid number")
 		return
 	}
 	utils.LogDebug("Processing category ID: %d", id)
@@ -57,7 +57,7 @@ func ToggleCategoryBlock(c *gin.Context) {
 	var req CategoryBlockRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid input: %v", err)
-		utils.BadRequest(c, "Invalid input", "Invalid request format")
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", "Invalid request format")
 		return
 	}
 	utils.LogDebug("Received block request - Blocked: %v", req.Blocked)
@@ -76,7 +76,7 @@ func ToggleCategoryBlock(c *gin.Context) {
 	if err := tx.First(&category, id).Error; err != nil {
 		tx.Rollback()
 		utils.LogError("Category not found: %v", err)
-		utils.NotFound(c, "Category not found")
+		utils.Fail(c, utils.CodeCategoryNotFound, "Category not found", nil)
 		return
 	}
 	utils.LogDebug("Found category: %s", category.Name)
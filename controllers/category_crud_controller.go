@@ -25,7 +25,7 @@ func CreateCategory(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -41,7 +41,7 @@ func CreateCategory(c *gin.Context) {
 	var req CategoryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid input: %v", err)
-		utils.BadRequest(c, "Invalid input", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err)
 		return
 	}
 	utils.LogDebug("Received category creation request - Name: %s", req.Name)
@@ -50,7 +50,7 @@ func CreateCategory(c *gin.Context) {
 	var existingCategory models.Category
 	if err := config.DB.Where("name = ?", req.Name).First(&existingCategory).Error; err == nil {
 		utils.LogError("Category with name %s already exists", req.Name)
-		utils.Conflict(c, "A category with this name already exists", nil)
+		utils.Fail(c, utils.CodeAlreadyExists, "A category with this name already exists", nil)
 		return
 	}
 	utils.LogDebug("No existing category found with name: %s", req.Name)
@@ -84,7 +84,7 @@ func UpdateCategory(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -108,7 +108,7 @@ func UpdateCategory(c *gin.Context) {
 	id, err := strconv.ParseUint(categoryID, 10, 32)
 	if err != nil {
 		utils.LogError("Invalid category ID format: %v", err)
-		utils.BadRequest(c, "Invalid category ID format", "Category ID must be a valid number")
+		utils.Fail(c, utils.CodeInvalidID, "Invalid category ID format", "Category ID must be a valid number")
 		return
 	}
 	utils.LogDebug("Processing category ID: %d", id)
@@ -117,7 +117,7 @@ func UpdateCategory(c *gin.Context) {
 	var category models.Category
 	if err := config.DB.First(&category, id).Error; err != nil {
 		utils.LogError("Category not found: %v", err)
-		utils.NotFound(c, "Category not found")
+		utils.Fail(c, utils.CodeCategoryNotFound, "Category not found", nil)
 		return
 	}
 	utils.LogDebug("Found category: %s", category.Name)
@@ -126,7 +126,7 @@ func UpdateCategory(c *gin.Context) {
 	var req CategoryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid input: %v", err)
-		utils.BadRequest(c, "Invalid input", gin.H{
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", gin.H{
 			"name":        "Name is required and must be between 2 and 100 characters",
 			"description": "Description is required and must be between 10 and 500 characters",
 		})
@@ -148,7 +148,7 @@ func UpdateCategory(c *gin.Context) {
 	if err := tx.Where("name ILIKE ? AND id != ?", req.Name, id).First(&existingCategory).Error; err == nil {
 		tx.Rollback()
 		utils.LogError("Duplicate category name found: %s", req.Name)
-		utils.Conflict(c, "Category name already exists", "Please choose a different name")
+		utils.Fail(c, utils.CodeAlreadyExists, "Category name already exists", "Please choose a different name")
 		return
 	}
 	utils.LogDebug("No duplicate category name found")
@@ -205,7 +205,7 @@ func DeleteCategory(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -230,7 +230,7 @@ func DeleteCategory(c *gin.Context) {
 	var category models.Category
 	if err := config.DB.First(&category, categoryID).Error; err != nil {
 		utils.LogError("Category not found: %v", err)
-		utils.NotFound(c, "Category not found")
+		utils.Fail(c, utils.CodeCategoryNotFound, "Category not found", nil)
 		return
 	}
 	utils.LogDebug("Found category: %s", category.Name)
@@ -28,7 +28,7 @@ func GetCategories(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -123,6 +123,7 @@ func GetCategories(c *gin.Context) {
 	utils.LogDebug("Found %d categories", len(categories))
 
 	// Return standardized response
+	formatter := utils.NewResponseFormatter(c)
 	var simpleCategories []gin.H
 	for _, cat := range categories {
 		simpleCategories = append(simpleCategories, gin.H{
@@ -130,8 +131,8 @@ func GetCategories(c *gin.Context) {
 			"name":        cat.Name,
 			"description": cat.Description,
 			"blocked":     cat.Blocked,
-			"created_at":  cat.CreatedAt,
-			"updated_at":  cat.UpdatedAt,
+			"created_at":  formatter.Timestamp(cat.CreatedAt),
+			"updated_at":  formatter.Timestamp(cat.UpdatedAt),
 			"book_count":  0, // This will be updated in the next iteration
 		})
 	}
@@ -172,10 +173,21 @@ func ListCategories(c *gin.Context) {
 	var categories []models.Category
 	if err := config.DB.Where("deleted_at IS NULL").Find(&categories).Error; err != nil {
 		utils.LogError("Failed to fetch categories: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		utils.InternalServerError(c, "Failed to fetch categories", nil)
 		return
 	}
 
+	// Apply translations for the requested locale
+	locale := resolveCatalogLocale(c)
+	categoryIDs := make([]uint, len(categories))
+	for i, cat := range categories {
+		categoryIDs[i] = cat.ID
+	}
+	translations := loadTranslations(models.TranslationEntityCategory, categoryIDs, locale)
+	for i := range categories {
+		categories[i].Name, categories[i].Description = translateText(translations, categories[i].ID, categories[i].Name, categories[i].Description)
+	}
+
 	utils.LogInfo("Successfully retrieved %d categories", len(categories))
 	c.JSON(http.StatusOK, categories)
 }
@@ -186,7 +198,7 @@ func ListBooksByCategory(c *gin.Context) {
 	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		utils.LogError("Invalid category ID: %s", c.Param("id"))
-		utils.BadRequest(c, "Invalid category ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid category ID", nil)
 		return
 	}
 	utils.LogDebug("Processing category ID: %d", categoryID)
@@ -195,7 +207,7 @@ func ListBooksByCategory(c *gin.Context) {
 	var category models.Category
 	if err := config.DB.First(&category, categoryID).Error; err != nil {
 		utils.LogError("Category not found: %v", err)
-		utils.NotFound(c, "Category not found")
+		utils.Fail(c, utils.CodeCategoryNotFound, "Category not found", nil)
 		return
 	}
 	utils.LogDebug("Found category: %s", category.Name)
@@ -232,6 +244,18 @@ func ListBooksByCategory(c *gin.Context) {
 		return
 	}
 
+	// Apply translations for the requested locale
+	locale := resolveCatalogLocale(c)
+	category.Name, category.Description = translateOne(models.TranslationEntityCategory, category.ID, locale, category.Name, category.Description)
+	bookIDs := make([]uint, len(books))
+	for i, book := range books {
+		bookIDs[i] = book.ID
+	}
+	bookTranslations := loadTranslations(models.TranslationEntityBook, bookIDs, locale)
+	for i := range books {
+		books[i].Name, books[i].Description = translateText(bookTranslations, books[i].ID, books[i].Name, books[i].Description)
+	}
+
 	utils.LogInfo("Successfully retrieved %d books for category %s", len(books), category.Name)
 	utils.Success(c, "Books retrieved successfully", gin.H{
 		"category": gin.H{
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// BuyNowItem is the single book bought with buy-now
+type BuyNowItem struct {
+	BookID   uint `json:"book_id" binding:"required"`
+	Quantity int  `json:"quantity"`
+}
+
+// buyNowOrderRequest is the body of a buy-now order placement
+type buyNowOrderRequest struct {
+	BuyNowItem
+	placeOrderRequest
+}
+
+// priceBuyNow prices the buy-now item on its own, without the cart or its coupon
+func priceBuyNow(item BuyNowItem) (*utils.CartDetails, error) {
+	return utils.NewPricingEngine(config.DB).Price([]models.Cart{{BookID: item.BookID, Quantity: item.Quantity}}, nil)
+}
+
+// checkBuyNowItem validates the buy-now item with the same rules as adding it to the cart
+// and returns it priced. It responds with the error and returns false when it is invalid.
+func checkBuyNowItem(c *gin.Context, item *BuyNowItem) (*utils.CartDetails, bool) {
+	if item.Quantity < 1 {
+		item.Quantity = 1
+	}
+	maxQuantity := utils.MaxCartQuantity()
+	if item.Quantity > maxQuantity {
+		utils.LogError("Buy-now quantity exceeds max limit for book ID: %d, requested: %d, max: %d", item.BookID, item.Quantity, maxQuantity)
+		utils.Fail(c, utils.CodeCartQuantityLimit, fmt.Sprintf("Cannot buy more than %d copies of the same book", maxQuantity), nil)
+		return nil, false
+	}
+
+	details, err := priceBuyNow(*item)
+	if err != nil {
+		utils.LogError("Failed to price buy-now item for book ID: %d: %v", item.BookID, err)
+		utils.InternalServerError(c, "Failed to price item", err.Error())
+		return nil, false
+	}
+	if len(details.Lines) == 0 {
+		utils.LogError("Book not found: %d", item.BookID)
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return nil, false
+	}
+
+	book := details.Lines[0].Book
+	if !book.IsActive || book.Blocked || book.Category.Blocked {
+		utils.LogError("Book ID: %d is not available or blocked", item.BookID)
+		utils.Fail(c, utils.CodeBookUnavailable, "Book not available or blocked by admin", nil)
+		return nil, false
+	}
+	if item.Quantity > book.Stock && !utils.PreorderOpen(book) {
+		utils.LogError("Insufficient stock for book ID: %d, requested: %d, available: %d", item.BookID, item.Quantity, book.Stock)
+		utils.Fail(c, utils.CodeOutOfStock, fmt.Sprintf("Not enough stock. Available: %d", book.Stock), nil)
+		return nil, false
+	}
+	return details, true
+}
+
+// GetBuyNowSummary returns the checkout summary of a single book bought directly, leaving
+// the cart untouched
+func GetBuyNowSummary(c *gin.Context) {
+	utils.LogInfo("GetBuyNowSummary called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user, ok := userVal.(models.User)
+	if !ok {
+		utils.LogError("Invalid user type in context")
+		utils.BadRequest(c, "Invalid user in context", nil)
+		return
+	}
+
+	var item BuyNowItem
+	if err := c.ShouldBindJSON(&item); err != nil {
+		utils.LogError("Invalid request for user ID: %d: %v", user.ID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	utils.LogInfo("Processing buy-now summary of book ID: %d, quantity: %d for user ID: %d", item.BookID, item.Quantity, user.ID)
+
+	details, ok := checkBuyNowItem(c, &item)
+	if !ok {
+		return
+	}
+	sendCheckoutSummary(c, user, details)
+}
+
+// BuyNow places an order for a single book without going through the cart. The cart and
+// the coupon applied to it are left as they are.
+func BuyNow(c *gin.Context) {
+	utils.LogInfo("BuyNow called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user, ok := userVal.(models.User)
+	if !ok {
+		utils.LogError("Invalid user type in context")
+		utils.BadRequest(c, "Invalid user in context", nil)
+		return
+	}
+
+	var req buyNowOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request for user ID: %d: %v", user.ID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	utils.LogInfo("Processing buy-now order of book ID: %d, quantity: %d for user ID: %d", req.BookID, req.Quantity, user.ID)
+
+	if _, ok := checkBuyNowItem(c, &req.BuyNowItem); !ok {
+		return
+	}
+
+	item := req.BuyNowItem
+	placeOrder(c, user.ID, req.placeOrderRequest, func() (*utils.CartDetails, error) {
+		return priceBuyNow(item)
+	}, true)
+}
@@ -2,7 +2,10 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 	"time"
 
@@ -11,6 +14,7 @@ import (
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CheckoutSummary struct {
@@ -29,7 +33,7 @@ func GetCheckoutSummary(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -49,38 +53,19 @@ func GetCheckoutSummary(c *gin.Context) {
 	}
 	utils.LogInfo("Retrieved cart details for user ID: %d, items count: %d", user.ID, len(cartDetails.OrderItems))
 
-	// Format items for response
-	var items []gin.H
-	for _, item := range cartDetails.OrderItems {
-		offerBreakdown, _ := utils.GetOfferBreakdownForBook(item.BookID, item.Book.CategoryID)
-
-		// Get the item's coupon discount from cart details
-		itemCouponDiscount := item.CouponDiscount
-
-		items = append(items, gin.H{
-			"book_id":                item.BookID,
-			"name":                   item.Book.Name,
-			"image_url":              item.Book.ImageURL,
-			"quantity":               item.Quantity,
-			"original_price":         fmt.Sprintf("%.2f", item.Price),
-			"product_offer_percent":  offerBreakdown.ProductOfferPercent,
-			"category_offer_percent": offerBreakdown.CategoryOfferPercent,
-			"applied_offer_percent":  offerBreakdown.ProductOfferPercent + offerBreakdown.CategoryOfferPercent,
-			"applied_offer_type":     "product+category",
-			"final_unit_price":       fmt.Sprintf("%.2f", (item.Total-itemCouponDiscount)/float64(item.Quantity)),
-			"item_total":             fmt.Sprintf("%.2f", item.Total-itemCouponDiscount),
-			"product_discount":       fmt.Sprintf("%.2f", item.Discount),
-			"coupon_discount":        fmt.Sprintf("%.2f", itemCouponDiscount),
-			"total_discount":         fmt.Sprintf("%.2f", item.Discount+itemCouponDiscount),
-		})
-	}
-	utils.LogInfo("Formatted %d items for checkout summary using cart details", len(items))
+	sendCheckoutSummary(c, user, cartDetails)
+}
+
+// sendCheckoutSummary sends the checkout summary of the priced items: delivery to the default
+// address, wallet balance and the total for each payment method
+func sendCheckoutSummary(c *gin.Context, user models.User, cartDetails *utils.CartDetails) {
 
 	// Get wallet balance
 	wallet, err := utils.GetOrCreateWallet(user.ID)
 	var walletBalance float64 = 0
 	if err == nil {
-		walletBalance = wallet.Balance
+		// Funds held for unpaid orders are not available
+		walletBalance = wallet.Balance - wallet.Held
 		utils.LogInfo("Retrieved wallet balance: %.2f for user ID: %d", walletBalance, user.ID)
 	} else {
 		utils.LogError("Failed to get wallet for user ID: %d: %v", user.ID, err)
@@ -91,8 +76,12 @@ func GetCheckoutSummary(c *gin.Context) {
 	var deliveryCharge float64 = 0
 	var deliveryAvailable bool = true
 	var deliveryError string = ""
+	var deliveryEstimate gin.H
 
-	if err := config.DB.Where("user_id = ? AND is_default = ?", user.ID, true).First(&defaultAddress).Error; err == nil {
+	if cartDetails.IsDigitalOnly() {
+		// Digital books are not shipped
+		utils.LogInfo("Digital-only checkout for user ID: %d, no delivery charge", user.ID)
+	} else if err := config.DB.Where("user_id = ? AND is_default = ?", user.ID, true).First(&defaultAddress).Error; err == nil {
 		// Calculate delivery charge based on pincode
 		utils.LogInfo("Found default address with pincode: %s for user ID: %d", defaultAddress.PostalCode, user.ID)
 		charge, err := utils.GetDeliveryCharge(defaultAddress.PostalCode, cartDetails.FinalTotal)
@@ -103,35 +92,63 @@ func GetCheckoutSummary(c *gin.Context) {
 		} else {
 			deliveryCharge = charge
 			utils.LogInfo("Calculated delivery charge: %.2f for pincode %s, user ID: %d", deliveryCharge, defaultAddress.PostalCode, user.ID)
+			if deliveryEstimate, err = estimateWithSlots(defaultAddress.PostalCode); err != nil {
+				utils.LogError("Failed to estimate delivery for pincode %s, user ID: %d: %v", defaultAddress.PostalCode, user.ID, err)
+			}
 		}
 	} else {
-		// No default address found
-		utils.LogInfo("No default address found for user ID: %d, using default delivery charge", user.ID)
-		deliveryCharge = 50.0
+		// No default address found; there is nothing to price delivery for yet
+		utils.LogInfo("No default address found for user ID: %d", user.ID)
 		deliveryError = "No default address found. Please add a delivery address."
 		deliveryAvailable = false
 	}
 
 	totalWithDelivery := cartDetails.FinalTotal + deliveryCharge
 
+	// Show what each payment method would cost so fees and discounts are visible before paying
+	paymentOptions := make([]gin.H, 0, len(utils.PaymentMethods))
+	formatter := utils.NewResponseFormatter(c)
+	for _, method := range utils.PaymentMethods {
+		adjustment := utils.CalculatePaymentAdjustment(method, cartDetails.FinalTotal)
+		paymentOptions = append(paymentOptions, gin.H{
+			"payment_method":  method,
+			"adjustment_type": adjustment.Type,
+			"adjustment":      formatter.Money(adjustment.Amount),
+			"cashback":        formatter.Money(adjustment.Cashback),
+			"description":     adjustment.Description,
+			"total_payable":   formatter.Money(totalWithDelivery + adjustment.Amount),
+		})
+	}
+
 	utils.LogInfo("Successfully prepared checkout summary for user ID: %d", user.ID)
-	utils.Success(c, "Checkout summary retrieved successfully", gin.H{
-		"can_checkout":              len(items) > 0,
-		"cart":                      items,
-		"subtotal":                  fmt.Sprintf("%.2f", cartDetails.Subtotal),
-		"product_discount":          fmt.Sprintf("%.2f", cartDetails.ProductDiscount),
-		"category_discount":         fmt.Sprintf("%.2f", cartDetails.CategoryDiscount),
-		"coupon_code":               cartDetails.CouponCode,
-		"coupon_discount":           fmt.Sprintf("%.2f", cartDetails.CouponDiscount),
-		"subtotal_without_delivery": fmt.Sprintf("%.2f", cartDetails.FinalTotal),
-		"delivery_charge":           fmt.Sprintf("%.2f", deliveryCharge),
-		"final_total":               fmt.Sprintf("%.2f", totalWithDelivery),
-		"total_discount":            fmt.Sprintf("%.2f", cartDetails.ProductDiscount+cartDetails.CategoryDiscount+cartDetails.CouponDiscount),
-		"wallet_balance":            fmt.Sprintf("%.2f", walletBalance),
-		"can_use_wallet":            walletBalance >= totalWithDelivery,
-		"delivery_available":        deliveryAvailable,
-		"delivery_error":            deliveryError,
-	})
+	response := cartSummaryResponse(formatter, cartDetails)
+	response["can_checkout"] = cartDetails.CanCheckout && deliveryAvailable
+	response["subtotal_without_delivery"] = formatter.Money(cartDetails.FinalTotal)
+	response["delivery_charge"] = formatter.Money(deliveryCharge)
+	response["final_total"] = formatter.Money(totalWithDelivery)
+	response["wallet_balance"] = formatter.Money(walletBalance)
+	response["can_use_wallet"] = walletBalance >= totalWithDelivery
+	response["delivery_available"] = deliveryAvailable
+	response["delivery_error"] = deliveryError
+	response["delivery_estimate"] = deliveryEstimate
+	response["payment_options"] = paymentOptions
+	response["gift_wrap_fee"] = formatter.Money(utils.GiftWrapFee())
+	utils.Success(c, "Checkout summary retrieved successfully", response)
+}
+
+// placeOrderRequest is the body of an order placement
+type placeOrderRequest struct {
+	AddressID     uint            `json:"address_id"`
+	Address       *models.Address `json:"address"`
+	PaymentMethod string          `json:"payment_method" binding:"required"`
+	DeliveryNote  string          `json:"delivery_note" binding:"max=500"`
+	IsGift        bool            `json:"is_gift"`
+	GiftWrap      bool            `json:"gift_wrap"`
+	GiftMessage   string          `json:"gift_message" binding:"max=250"`
+	UseWallet     bool            `json:"use_wallet"` // pay what the wallet covers and the rest online
+	// DeliverySlotID and DeliverySlotDate (YYYY-MM-DD) book a delivery slot where the zone offers them
+	DeliverySlotID   uint   `json:"delivery_slot_id"`
+	DeliverySlotDate string `json:"delivery_slot_date"`
 }
 
 func PlaceOrder(c *gin.Context) {
@@ -139,7 +156,7 @@ func PlaceOrder(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -151,17 +168,22 @@ func PlaceOrder(c *gin.Context) {
 	userID := user.ID
 	utils.LogInfo("Processing order placement for user ID: %d", userID)
 
-	var req struct {
-		AddressID     uint            `json:"address_id"`
-		Address       *models.Address `json:"address"`
-		PaymentMethod string          `json:"payment_method" binding:"required"`
-	}
+	var req placeOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request for user ID: %d: %v", userID, err)
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 
+	placeOrder(c, userID, req, func() (*utils.CartDetails, error) {
+		return utils.GetCartDetails(userID)
+	}, false)
+}
+
+// placeOrder places an order for the items loadItems prices. Orders placed from the cart
+// empty it once paid; buy-now orders leave the cart alone.
+func placeOrder(c *gin.Context, userID uint, req placeOrderRequest, loadItems func() (*utils.CartDetails, error), buyNow bool) {
+
 	// Validate and normalize payment method
 	paymentMethod := strings.ToLower(strings.TrimSpace(req.PaymentMethod))
 	validMethods := map[string]bool{
@@ -171,11 +193,23 @@ func PlaceOrder(c *gin.Context) {
 	}
 	if !validMethods[paymentMethod] {
 		utils.LogError("Invalid payment method '%s' for user ID: %d", paymentMethod, userID)
-		utils.BadRequest(c, "Invalid payment method. Must be one of: cod, online, wallet", nil)
+		utils.Fail(c, utils.CodePaymentMethod, "Invalid payment method. Must be one of: cod, online, wallet", nil)
 		return
 	}
 	utils.LogInfo("Validated payment method: %s for user ID: %d", paymentMethod, userID)
 
+	// Gift wrapping and messages only go with gift orders
+	req.GiftMessage = strings.TrimSpace(req.GiftMessage)
+	if !req.IsGift && (req.GiftWrap || req.GiftMessage != "") {
+		utils.LogError("Gift options without is_gift for user ID: %d", userID)
+		utils.BadRequest(c, "Set is_gift to add gift wrapping or a gift message", nil)
+		return
+	}
+	var giftWrapFee float64
+	if req.GiftWrap {
+		giftWrapFee = utils.GiftWrapFee()
+	}
+
 	// Check for existing pending order within 5 minutes
 	var existingOrder models.Order
 	existingOrderFound := false
@@ -213,13 +247,13 @@ func PlaceOrder(c *gin.Context) {
 		var pendingPayment models.Payment
 		if err := config.DB.Where("order_id = ? AND status = ?", duplicateOrder.ID, "pending").First(&pendingPayment).Error; err == nil {
 			utils.LogError("User already has an order with this payment method pending - User ID: %d", userID)
-			utils.BadRequest(c, "You already have an order with this payment method pending. Please complete or cancel that order first.", nil)
+			utils.Fail(c, utils.CodePaymentPending, "You already have an order with this payment method pending. Please complete or cancel that order first.", nil)
 			return
 		}
 	}
 
 	// Get cart details
-	cartDetails, err := utils.GetCartDetails(userID)
+	cartDetails, err := loadItems()
 	if err != nil {
 		utils.LogError("Failed to get cart details for user ID: %d: %v", userID, err)
 		utils.InternalServerError(c, "Failed to get cart details", err.Error())
@@ -227,48 +261,130 @@ func PlaceOrder(c *gin.Context) {
 	}
 
 	// Calculate delivery charge based on the address being used
-	var deliveryCharge float64 = 0
+	var deliveryPincode string
 	if req.Address != nil {
 		// For new address
-		charge, err := utils.GetDeliveryCharge(req.Address.PostalCode, cartDetails.FinalTotal)
+		deliveryPincode = strings.TrimSpace(req.Address.PostalCode)
+	} else if req.AddressID != 0 {
+		var selectedAddress models.Address
+		if err := config.DB.Where("id = ? AND user_id = ?", req.AddressID, userID).First(&selectedAddress).Error; err != nil {
+			utils.LogError("Address not found, ID: %d, user ID: %d", req.AddressID, userID)
+			utils.Fail(c, utils.CodeAddressNotFound, "Address not found", nil)
+			return
+		}
+		deliveryPincode = selectedAddress.PostalCode
+	} else {
+		utils.LogError("No address provided for user ID: %d", userID)
+		utils.BadRequest(c, "Provide either address_id or address object", nil)
+		return
+	}
+	// Digital books are not shipped, so orders of only digital books have no delivery charge
+	digitalOnly := cartDetails.IsDigitalOnly()
+	var deliveryCharge float64
+	var deliveryEstimate *utils.DeliveryEstimate
+	if !digitalOnly {
+		deliveryCharge, err = utils.GetDeliveryCharge(deliveryPincode, cartDetails.FinalTotal)
 		if err != nil {
 			utils.LogError("Delivery not available for address - User ID: %d: %v", userID, err)
-			utils.BadRequest(c, "Delivery not available for this address", err.Error())
+			utils.Fail(c, utils.CodeDeliveryUnavailable, "Delivery not available for this address", err.Error())
+			return
+		}
+		deliveryEstimate, err = utils.EstimateDelivery(deliveryPincode, time.Now())
+		if err != nil {
+			utils.LogError("Failed to estimate delivery for pincode %s, user ID: %d: %v", deliveryPincode, userID, err)
+			utils.InternalServerError(c, "Failed to estimate delivery", err.Error())
 			return
 		}
-		deliveryCharge = charge
-	} else if req.AddressID != 0 {
-		// For existing address - we need to get the address first
-		// This will be handled later in the function
-		deliveryCharge = 50.0 // Default for now
-	} else {
-		// No address provided, use default
-		deliveryCharge = 50.0
 	}
 
-	totalWithDelivery := cartDetails.FinalTotal + deliveryCharge
-	utils.LogInfo("Calculated delivery charge: %.2f, total with delivery: %.2f for user ID: %d", deliveryCharge, totalWithDelivery, userID)
+	// A delivery slot needs both its ID and date, and only goes with shipped orders
+	var slotDate time.Time
+	if req.DeliverySlotID != 0 || req.DeliverySlotDate != "" {
+		if req.DeliverySlotID == 0 || req.DeliverySlotDate == "" {
+			utils.LogError("Incomplete delivery slot for user ID: %d", userID)
+			utils.BadRequest(c, "Provide both delivery_slot_id and delivery_slot_date to book a delivery slot", nil)
+			return
+		}
+		if deliveryEstimate == nil {
+			utils.LogError("Delivery slot requested for digital-only order, user ID: %d", userID)
+			utils.Fail(c, utils.CodeDeliverySlotInvalid, "Digital books are not delivered, so no delivery slot can be booked", nil)
+			return
+		}
+		slotDate, err = time.ParseInLocation("2006-01-02", req.DeliverySlotDate, time.Local)
+		if err != nil {
+			utils.LogError("Invalid delivery slot date %q for user ID: %d", req.DeliverySlotDate, userID)
+			utils.BadRequest(c, "delivery_slot_date must be in YYYY-MM-DD format", nil)
+			return
+		}
+	}
+
+	// Apply the fee, discount or cashback configured for the payment method
+	paymentAdjustment := utils.CalculatePaymentAdjustment(paymentMethod, cartDetails.FinalTotal)
+	totalWithDelivery := cartDetails.FinalTotal + deliveryCharge + paymentAdjustment.Amount + giftWrapFee
+	utils.LogInfo("Calculated delivery charge: %.2f, payment adjustment: %.2f, gift wrap fee: %.2f, total with delivery: %.2f for user ID: %d",
+		deliveryCharge, paymentAdjustment.Amount, giftWrapFee, totalWithDelivery, userID)
 
 	// Wallet payment: check balance
-	if paymentMethod == "wallet" {
+	if req.UseWallet && paymentMethod != "online" {
+		utils.LogError("use_wallet with payment method %s for user ID: %d", paymentMethod, userID)
+		utils.BadRequest(c, "use_wallet can only be combined with online payment", nil)
+		return
+	}
+	var walletAmount float64
+	if paymentMethod == "wallet" || req.UseWallet {
 		wallet, err := utils.GetOrCreateWallet(userID)
 		if err != nil {
 			utils.LogError("Failed to get wallet for user ID: %d: %v", userID, err)
 			utils.InternalServerError(c, "Failed to get wallet", err.Error())
 			return
 		}
-		if wallet.Balance < totalWithDelivery {
-			utils.LogError("Insufficient wallet balance for user ID: %d. Required: %.2f, Available: %.2f", userID, totalWithDelivery, wallet.Balance)
-			utils.BadRequest(c, "Insufficient wallet balance. Please top up your wallet or choose another payment method.", nil)
+		available := wallet.Balance - wallet.Held
+		if req.UseWallet {
+			if available <= 0 {
+				utils.LogError("No wallet balance available for split payment, user ID: %d", userID)
+				utils.Fail(c, utils.CodeWalletInsufficient, "Your wallet has no balance available to use", nil)
+				return
+			}
+			if available >= totalWithDelivery {
+				utils.LogError("Wallet covers the whole order for split payment, user ID: %d", userID)
+				utils.BadRequest(c, "Your wallet covers the whole order. Please pay with the wallet instead.", nil)
+				return
+			}
+			// The wallet part is held when the order is placed and captured once the rest is paid
+			walletAmount = math.Round(available*100) / 100
+		} else if available < totalWithDelivery {
+			utils.LogError("Insufficient wallet balance for user ID: %d. Required: %.2f, Available: %.2f", userID, totalWithDelivery, available)
+			utils.Fail(c, utils.CodeWalletInsufficient, "Insufficient wallet balance. Please top up your wallet or choose another payment method.", nil)
 			return
 		}
 	}
 
-	// Check COD limit
+	// Check COD serviceability and limit
 	if paymentMethod == "cod" {
-		if totalWithDelivery > 1000 {
+		if digitalOnly {
+			utils.LogError("COD requested for digital-only order, user ID: %d", userID)
+			utils.Fail(c, utils.CodeCODUnavailable, "Cash on Delivery is not available for digital books. Please choose online payment or wallet payment.", nil)
+			return
+		}
+		if !utils.IsCODAvailable(deliveryPincode) {
+			utils.LogError("COD not available for pincode %s, user ID: %d", deliveryPincode, userID)
+			utils.Fail(c, utils.CodeCODUnavailable, "Cash on Delivery is not available for this address. Please choose online payment or wallet payment.", nil)
+			return
+		}
+		eligible, refused, err := utils.CODEligibility(userID)
+		if err != nil {
+			utils.LogError("Failed to check COD eligibility for user ID: %d: %v", userID, err)
+			utils.InternalServerError(c, "Failed to check Cash on Delivery eligibility", err.Error())
+			return
+		}
+		if !eligible {
+			utils.LogError("COD withdrawn for user ID: %d after %d refused deliveries", userID, refused)
+			utils.Fail(c, utils.CodeCODNotEligible, "Cash on Delivery is not available for your account because of earlier refused deliveries. Please choose online payment or wallet payment.", nil)
+			return
+		}
+		if codLimit := utils.CODOrderLimit(); totalWithDelivery > codLimit {
 			utils.LogError("COD not available for amount %.2f, user ID: %d", totalWithDelivery, userID)
-			utils.BadRequest(c, "Cash on Delivery is not available for orders above ₹1000. Please choose online payment or wallet payment.", nil)
+			utils.Fail(c, utils.CodeCODLimitExceeded, fmt.Sprintf("Cash on Delivery is not available for orders above %s. Please choose online payment or wallet payment.", utils.FormatRequestMoney(c, codLimit)), nil)
 			return
 		}
 		utils.LogInfo("COD amount check passed for user ID: %d", userID)
@@ -292,7 +408,7 @@ func PlaceOrder(c *gin.Context) {
 		db.Where("id = ? AND user_id = ?", req.AddressID, userID).First(&address)
 		if address.ID == 0 {
 			utils.LogError("Address not found, ID: %d, user ID: %d", req.AddressID, userID)
-			utils.NotFound(c, "Address not found")
+			utils.Fail(c, utils.CodeAddressNotFound, "Address not found", nil)
 			return
 		}
 		utils.LogInfo("Retrieved existing address ID: %d for user ID: %d", address.ID, userID)
@@ -312,6 +428,9 @@ func PlaceOrder(c *gin.Context) {
 		CouponCode        string             `json:"coupon_code"`
 		FinalTotal        float64            `json:"final_total"`
 		DeliveryCharge    float64            `json:"delivery_charge"`
+		PaymentAdjustment float64            `json:"payment_adjustment"`
+		PaymentCashback   float64            `json:"payment_cashback"`
+		GiftWrapFee       float64            `json:"gift_wrap_fee"`
 		TotalWithDelivery float64            `json:"total_with_delivery"`
 		PaymentMethod     string             `json:"payment_method"`
 		OrderItems        []models.OrderItem `json:"order_items"`
@@ -324,6 +443,9 @@ func PlaceOrder(c *gin.Context) {
 		CouponCode:        cartDetails.CouponCode,
 		FinalTotal:        cartDetails.FinalTotal,
 		DeliveryCharge:    deliveryCharge,
+		PaymentAdjustment: paymentAdjustment.Amount,
+		PaymentCashback:   paymentAdjustment.Cashback,
+		GiftWrapFee:       giftWrapFee,
 		TotalWithDelivery: totalWithDelivery,
 		PaymentMethod:     paymentMethod,
 		OrderItems:        cartDetails.OrderItems,
@@ -340,7 +462,7 @@ func PlaceOrder(c *gin.Context) {
 	utils.LogInfo("Started transaction for order placement, user ID: %d", userID)
 
 	// Get cart details
-	cartDetails, err = utils.GetCartDetails(userID)
+	cartDetails, err = loadItems()
 	if err != nil {
 		utils.LogError("Failed to get cart details for order placement, user ID: %d: %v", userID, err)
 		tx.Rollback()
@@ -352,51 +474,124 @@ func PlaceOrder(c *gin.Context) {
 	if len(cartDetails.OrderItems) == 0 {
 		utils.LogError("Empty cart for user ID: %d", userID)
 		tx.Rollback()
-		utils.BadRequest(c, "Cannot place order with empty cart", nil)
+		utils.Fail(c, utils.CodeCartEmpty, "Cannot place order with empty cart", nil)
 		return
 	}
 	utils.LogInfo("Retrieved cart details for order placement, items count: %d", len(cartDetails.OrderItems))
 
-	// Validate stock and reduce it for each item
-	for _, item := range cartDetails.OrderItems {
-		// Lock the book row for update
-		var book models.Book
-		if err := tx.Set("gorm:pessimistic_lock", true).First(&book, item.BookID).Error; err != nil {
-			utils.LogError("Book not found, ID: %d, user ID: %d: %v", item.BookID, userID, err)
+	// Validate stock and reduce it for each item. Rows are locked in book ID order so that
+	// concurrent checkouts of the same books cannot deadlock. Pre-ordered books take no stock
+	// until their release.
+	preorderBooks := make(map[uint]bool)
+	stockItems := append([]models.OrderItem(nil), cartDetails.OrderItems...)
+	sort.Slice(stockItems, func(i, j int) bool { return stockItems[i].BookID < stockItems[j].BookID })
+	stockBookIDs := make([]uint, len(stockItems))
+	for i, item := range stockItems {
+		stockBookIDs[i] = item.BookID
+	}
+	// Lock all the book rows in one query (SELECT ... FOR UPDATE)
+	var lockedBooks []models.Book
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", stockBookIDs).Order("id").Find(&lockedBooks).Error; err != nil {
+		utils.LogError("Failed to lock books for user ID: %d: %v", userID, err)
+		tx.Rollback()
+		utils.InternalServerError(c, "Failed to update book stock", nil)
+		return
+	}
+	booksByID := make(map[uint]*models.Book, len(lockedBooks))
+	for i := range lockedBooks {
+		booksByID[lockedBooks[i].ID] = &lockedBooks[i]
+	}
+	for _, item := range stockItems {
+		book, ok := booksByID[item.BookID]
+		if !ok {
+			utils.LogError("Book not found, ID: %d, user ID: %d", item.BookID, userID)
 			tx.Rollback()
-			utils.NotFound(c, fmt.Sprintf("Book with ID %d not found", item.BookID))
+			utils.Fail(c, utils.CodeBookNotFound, fmt.Sprintf("Book with ID %d not found", item.BookID), nil)
 			return
 		}
+		if utils.PreorderOpen(*book) {
+			preorderBooks[book.ID] = true
+			utils.LogInfo("Pre-order of book ID: %d, stock is allocated on release", item.BookID)
+			continue
+		}
 
 		// Check if book has enough stock
 		if book.Stock < item.Quantity {
 			utils.LogError("Insufficient stock for book '%s', available: %d, requested: %d", book.Name, book.Stock, item.Quantity)
 			tx.Rollback()
-			utils.BadRequest(c, fmt.Sprintf("Book '%s' does not have enough stock. Available: %d, Requested: %d", book.Name, book.Stock, item.Quantity), nil)
+			utils.Fail(c, utils.CodeOutOfStock, fmt.Sprintf("Book '%s' does not have enough stock. Available: %d, Requested: %d", book.Name, book.Stock, item.Quantity), nil)
 			return
 		}
 
-		// Reduce stock
-		if err := tx.Model(&models.Book{}).Where("id = ?", item.BookID).UpdateColumn("stock", gorm.Expr("stock - ?", item.Quantity)).Error; err != nil {
-			utils.LogError("Failed to update book stock, ID: %d, user ID: %d: %v", item.BookID, userID, err)
+		// Reduce stock; the stock guard keeps it from going negative even without the row lock
+		result := tx.Model(&models.Book{}).Where("id = ? AND stock >= ?", item.BookID, item.Quantity).
+			UpdateColumn("stock", gorm.Expr("stock - ?", item.Quantity))
+		if result.Error != nil {
+			utils.LogError("Failed to update book stock, ID: %d, user ID: %d: %v", item.BookID, userID, result.Error)
 			tx.Rollback()
 			utils.InternalServerError(c, "Failed to update book stock", nil)
 			return
 		}
+		if result.RowsAffected == 0 {
+			utils.LogError("Stock changed during checkout for book '%s', user ID: %d", book.Name, userID)
+			tx.Rollback()
+			utils.Fail(c, utils.CodeStockChanged, fmt.Sprintf("Book '%s' just went out of stock, please review your cart", book.Name), nil)
+			return
+		}
+		// A book can be on several lines, alone and in a bundle, so keep its stock current
+		book.Stock -= item.Quantity
 		utils.LogInfo("Updated stock for book ID: %d, reduced by: %d", item.BookID, item.Quantity)
 	}
+	for i := range cartDetails.OrderItems {
+		cartDetails.OrderItems[i].PreorderPending = preorderBooks[cartDetails.OrderItems[i].BookID]
+	}
+
+	// Pre-orders ship on release, so only orders of books in stock get delivery dates and slots
+	var deliverySlot *models.DeliverySlot
+	if deliveryEstimate != nil && len(preorderBooks) > 0 {
+		if req.DeliverySlotID != 0 {
+			utils.LogError("Delivery slot requested for pre-order, user ID: %d", userID)
+			tx.Rollback()
+			utils.Fail(c, utils.CodeDeliverySlotInvalid, "Pre-orders ship when the books are released, so no delivery slot can be booked", nil)
+			return
+		}
+		deliveryEstimate = nil
+	}
+	if req.DeliverySlotID != 0 {
+		deliverySlot, err = utils.ReserveDeliverySlot(tx, *deliveryEstimate, req.DeliverySlotID, slotDate)
+		if err != nil {
+			tx.Rollback()
+			switch {
+			case errors.Is(err, utils.ErrDeliverySlotFull):
+				utils.LogError("Delivery slot ID: %d on %s is full, user ID: %d", req.DeliverySlotID, req.DeliverySlotDate, userID)
+				utils.Fail(c, utils.CodeDeliverySlotFull, "This delivery slot is fully booked. Please pick another slot.", nil)
+			case errors.Is(err, utils.ErrDeliverySlotInvalid), errors.Is(err, utils.ErrDeliverySlotDate):
+				utils.LogError("Delivery slot ID: %d on %s cannot be booked, user ID: %d: %v", req.DeliverySlotID, req.DeliverySlotDate, userID, err)
+				utils.Fail(c, utils.CodeDeliverySlotInvalid, "This delivery slot cannot be booked for this address and date", nil)
+			default:
+				utils.LogError("Failed to book delivery slot for user ID: %d: %v", userID, err)
+				utils.InternalServerError(c, "Failed to book delivery slot", err.Error())
+			}
+			return
+		}
+	}
 
 	order := models.Order{
-		UserID:            userID,
-		AddressID:         address.ID,
-		Address:           address,
-		TotalAmount:       cartDetails.Subtotal,
-		Discount:          cartDetails.ProductDiscount + cartDetails.CategoryDiscount,
-		CouponDiscount:    cartDetails.CouponDiscount,
-		CouponCode:        cartDetails.CouponCode,
-		FinalTotal:        cartDetails.FinalTotal,
-		DeliveryCharge:    deliveryCharge,
-		TotalWithDelivery: totalWithDelivery,
+		UserID:                userID,
+		AddressID:             address.ID,
+		Address:               address,
+		TotalAmount:           cartDetails.Subtotal,
+		Discount:              cartDetails.ProductDiscount + cartDetails.CategoryDiscount,
+		CouponDiscount:        cartDetails.CouponDiscount,
+		CouponCode:            cartDetails.CouponCode,
+		FinalTotal:            cartDetails.FinalTotal,
+		DeliveryCharge:        deliveryCharge,
+		TotalWithDelivery:     totalWithDelivery,
+		PaymentAdjustmentType: paymentAdjustment.Type,
+		PaymentAdjustment:     paymentAdjustment.Amount,
+		PaymentAdjustmentBase: cartDetails.FinalTotal,
+		PaymentCashback:       paymentAdjustment.Cashback,
+		WalletAmount:          walletAmount,
 		PaymentMethod: func() string {
 			if paymentMethod == "cod" || paymentMethod == "wallet" {
 				return paymentMethod
@@ -404,8 +599,24 @@ func PlaceOrder(c *gin.Context) {
 			return "" // For online, leave blank until payment is initiated
 		}(),
 		Status:          "Placed",
+		DeliveryNote:    strings.TrimSpace(req.DeliveryNote),
+		IsGift:          req.IsGift,
+		GiftWrap:        req.GiftWrap,
+		GiftWrapFee:     giftWrapFee,
+		GiftMessage:     req.GiftMessage,
 		OrderItems:      cartDetails.OrderItems,
 		OriginalDetails: string(originalDetailsJSON),
+		BuyNow:          buyNow,
+		IsPreorder:      len(preorderBooks) > 0,
+	}
+	if deliveryEstimate != nil {
+		order.EstimatedDeliveryFrom = &deliveryEstimate.EarliestDate
+		order.EstimatedDeliveryTo = &deliveryEstimate.LatestDate
+	}
+	if deliverySlot != nil {
+		order.DeliverySlotID = &deliverySlot.ID
+		order.DeliverySlotDate = &slotDate
+		order.DeliverySlotLabel = deliverySlot.StartTime + "-" + deliverySlot.EndTime
 	}
 
 	utils.LogInfo("Creating order for user ID: %d, total amount: %.2f, final total: %.2f, delivery charge: %.2f, total with delivery: %.2f",
@@ -419,6 +630,38 @@ func PlaceOrder(c *gin.Context) {
 	}
 	utils.LogInfo("Created order ID: %d for user ID: %d", order.ID, userID)
 
+	// Log the stock taken above now that the order and its items have IDs
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		if item.PreorderPending {
+			continue
+		}
+		if err := utils.RecordStockMovement(tx, utils.OrderItemStockChange(item, -item.Quantity, models.StockSourceOrder)); err != nil {
+			utils.LogError("Failed to record stock movement of book ID: %d, order ID: %d: %v", item.BookID, order.ID, err)
+			tx.Rollback()
+			utils.InternalServerError(c, "Failed to update book stock", nil)
+			return
+		}
+	}
+
+	// Hold the wallet part of a split payment so it cannot be spent while the online payment is open
+	if walletAmount > 0 {
+		hold, err := utils.HoldWalletFunds(tx, userID, order.ID, walletAmount)
+		if errors.Is(err, utils.ErrInsufficientWalletBalance) {
+			utils.LogError("Wallet balance changed before the hold for user ID: %d", userID)
+			tx.Rollback()
+			utils.Fail(c, utils.CodeWalletInsufficient, "Your wallet balance changed. Please review your payment and try again.", nil)
+			return
+		}
+		if err != nil {
+			utils.LogError("Failed to hold wallet funds for user ID: %d: %v", userID, err)
+			tx.Rollback()
+			utils.InternalServerError(c, "Failed to hold wallet funds", err.Error())
+			return
+		}
+		utils.LogInfo("Held %.2f in wallet ID: %d for order ID: %d", hold.Amount, hold.WalletID, order.ID)
+	}
+
 	// Increment coupon used_count if a coupon was used
 	if order.CouponCode != "" {
 		if err := tx.Model(&models.Coupon{}).Where("code = ?", order.CouponCode).UpdateColumn("used_count", gorm.Expr("used_count + ?", 1)).Error; err != nil {
@@ -431,8 +674,8 @@ func PlaceOrder(c *gin.Context) {
 	}
 
 	// Clear cart for COD and wallet payments
-	if paymentMethod == "cod" || paymentMethod == "wallet" {
-		if err := tx.Where("user_id = ?", userID).Delete(&models.Cart{}).Error; err != nil {
+	if !buyNow && (paymentMethod == "cod" || paymentMethod == "wallet") {
+		if err := utils.ClearCart(tx, userID); err != nil {
 			utils.LogError("Failed to clear cart for user ID: %d: %v", userID, err)
 			tx.Rollback()
 			utils.InternalServerError(c, "Failed to clear cart", err.Error())
@@ -452,43 +695,43 @@ func PlaceOrder(c *gin.Context) {
 
 	// Process wallet payment deduction
 	if paymentMethod == "wallet" {
-		// Get wallet within transaction
-		var wallet models.Wallet
-		if err := tx.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
-			utils.LogError("Failed to get wallet for deduction, user ID: %d: %v", userID, err)
+		// Debit the wallet in the same transaction as the order; the wallet row is locked, so
+		// the balance is checked again against concurrent payments
+		walletTransaction, err := utils.PostWalletEntry(tx, utils.WalletEntry{
+			UserID:      userID,
+			Amount:      totalWithDelivery,
+			Type:        models.TransactionTypeDebit,
+			Description: fmt.Sprintf("Payment for order #%d", order.ID),
+			Reference:   fmt.Sprintf("ORDER-%d", order.ID),
+			OrderID:     &order.ID,
+		})
+		if errors.Is(err, utils.ErrInsufficientWalletBalance) {
+			utils.LogError("Insufficient wallet balance at payment for user ID: %d", userID)
 			tx.Rollback()
-			utils.InternalServerError(c, "Failed to get wallet", err.Error())
+			utils.Fail(c, utils.CodeWalletInsufficient, "Insufficient wallet balance. Please top up your wallet or choose another payment method.", nil)
 			return
 		}
-
-		// Deduct amount from wallet
-		if err := tx.Model(&models.Wallet{}).Where("user_id = ?", userID).
-			UpdateColumn("balance", gorm.Expr("balance - ?", totalWithDelivery)).Error; err != nil {
-			utils.LogError("Failed to deduct from wallet, user ID: %d: %v", userID, err)
+		if err != nil {
+			utils.LogError("Failed to debit wallet, user ID: %d: %v", userID, err)
 			tx.Rollback()
 			utils.InternalServerError(c, "Failed to process wallet payment", err.Error())
 			return
 		}
-		utils.LogInfo("Deducted %.2f from wallet for user ID: %d, new balance: %.2f", totalWithDelivery, userID, wallet.Balance-totalWithDelivery)
+		utils.LogInfo("Deducted %.2f from wallet for user ID: %d, new balance: %.2f", totalWithDelivery, userID, walletTransaction.Wallet.Balance)
 
-		// Create wallet transaction record
-		walletTransaction := models.WalletTransaction{
-			WalletID:    wallet.ID,
-			Amount:      -totalWithDelivery, // Negative amount for debit
-			Type:        models.TransactionTypeDebit,
-			Description: fmt.Sprintf("Payment for order #%d", order.ID),
-			OrderID:     &order.ID,
-			Reference:   fmt.Sprintf("ORDER-%d", order.ID),
-			Status:      models.TransactionStatusCompleted,
+		// Wallet orders are paid immediately, so any cashback is earned now
+		if err := utils.CreditPaymentCashback(tx, &order); err != nil {
+			utils.LogError("Failed to credit payment cashback for order ID: %d: %v", order.ID, err)
+			tx.Rollback()
+			utils.InternalServerError(c, "Failed to credit payment cashback", err.Error())
+			return
 		}
-
-		if err := tx.Create(&walletTransaction).Error; err != nil {
-			utils.LogError("Failed to create wallet transaction record, user ID: %d: %v", userID, err)
+		if err := utils.GrantDigitalEntitlements(tx, &order); err != nil {
+			utils.LogError("Failed to grant digital books for order ID: %d: %v", order.ID, err)
 			tx.Rollback()
-			utils.InternalServerError(c, "Failed to create wallet transaction", err.Error())
+			utils.InternalServerError(c, "Failed to grant digital books", err.Error())
 			return
 		}
-		utils.LogInfo("Created wallet transaction record for order ID: %d", order.ID)
 	}
 
 	if err := tx.Commit().Error; err != nil {
@@ -497,19 +740,25 @@ func PlaceOrder(c *gin.Context) {
 		return
 	}
 	utils.LogInfo("Successfully committed transaction for order ID: %d", order.ID)
+	utils.RecordOrderPlaced(paymentMethod)
 
-	// For online payment, return redirect URL
+	formatter := utils.NewResponseFormatter(c)
+	// For online payment, return redirect URL; the order is confirmed once it is paid
 	if paymentMethod == "online" {
 		utils.LogInfo("Returning payment redirect URL for order ID: %d", order.ID)
 		utils.Success(c, "Please proceed to payment", gin.H{
 			"status": "success",
 			"data": gin.H{
-				"redirect_url": fmt.Sprintf("/v1/user/checkout/payment/initiate?order_id=%d", order.ID),
-				"order_id":     order.ID,
+				"redirect_url":  fmt.Sprintf("/v1/user/checkout/payment/initiate?order_id=%d", order.ID),
+				"order_id":      order.ID,
+				"wallet_amount": formatter.Money(order.WalletAmount),
+				"amount_due":    formatter.Money(order.TotalWithDelivery - order.WalletAmount),
+				"delivery_date": deliveryDateLabel(order, deliveryEstimate),
 			},
 		})
 		return
 	}
+	emailOrderPlaced(order.ID)
 
 	// For COD and wallet payments, return order confirmation
 	utils.LogInfo("Order placed successfully, ID: %d, payment method: %s", order.ID, order.PaymentMethod)
@@ -518,10 +767,16 @@ func PlaceOrder(c *gin.Context) {
 		"order_id":        order.ID,
 		"payment_method":  order.PaymentMethod,
 		"status":          order.Status,
-		"subtotal":        fmt.Sprintf("%.2f", cartDetails.FinalTotal),
-		"delivery_charge": fmt.Sprintf("%.2f", deliveryCharge),
-		"final_total":     fmt.Sprintf("%.2f", totalWithDelivery),
-		"delivery_date":   "3-7 working days",
+		"subtotal":        formatter.Money(cartDetails.FinalTotal),
+		"delivery_charge": formatter.Money(deliveryCharge),
+		"payment_adjustment": gin.H{
+			"type":     order.PaymentAdjustmentType,
+			"amount":   formatter.Money(order.PaymentAdjustment),
+			"cashback": formatter.Money(order.PaymentCashback),
+		},
+		"final_total":       formatter.Money(totalWithDelivery),
+		"delivery_date":     deliveryDateLabel(order, deliveryEstimate),
+		"delivery_schedule": deliveryScheduleResponse(order),
 		"shipping_address": gin.H{
 			"line1":       order.Address.Line1,
 			"line2":       order.Address.Line2,
@@ -537,8 +792,8 @@ func PlaceOrder(c *gin.Context) {
 		// Get updated wallet balance
 		updatedWallet, err := utils.GetOrCreateWallet(userID)
 		if err == nil {
-			response["wallet_balance"] = fmt.Sprintf("%.2f", updatedWallet.Balance)
-			response["amount_deducted"] = fmt.Sprintf("%.2f", totalWithDelivery)
+			response["wallet_balance"] = formatter.Money(updatedWallet.Balance)
+			response["amount_deducted"] = formatter.Money(totalWithDelivery)
 		}
 	}
 
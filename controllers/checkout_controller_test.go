@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/testutil"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkoutTables are the tables placing an online order from the cart reads or writes
+var checkoutTables = []interface{}{
+	&models.User{}, &models.Category{}, &models.Book{}, &models.BookImage{}, &models.Cart{},
+	&models.Bundle{}, &models.BundleItem{}, &models.CartBundle{}, &models.Address{},
+	&models.Order{}, &models.OrderItem{}, &models.StockMovement{}, &models.Payment{},
+	&models.Coupon{}, &models.UserActiveCoupon{}, &models.ProductOffer{}, &models.CategoryOffer{},
+	&models.OfferRules{}, &models.Setting{}, &models.PaymentMethodAdjustment{},
+	&models.DeliveryCharge{}, &models.DeliverySLA{}, &models.Wallet{},
+}
+
+// placeOrderAs runs PlaceOrder for the user with the JSON request body and returns the response
+func placeOrderAs(user models.User, body gin.H) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/user/checkout", bytes.NewReader(payload))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", user)
+	PlaceOrder(c)
+	return recorder
+}
+
+func TestPlaceOrderConcurrentLastUnit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t, checkoutTables...)
+
+	const buyers = 8
+	book := models.Book{Name: "Last Copy", Price: 250, OriginalPrice: 250, Stock: 1, IsActive: true, ISBN: "9780000000001"}
+	require.NoError(t, db.Create(&book).Error)
+	require.NoError(t, db.Create(&models.DeliveryCharge{
+		Pincode: "560001", PincodeFrom: "560000", PincodeTo: "560099", Charge: 40, IsActive: true,
+	}).Error)
+
+	users := make([]models.User, buyers)
+	addresses := make([]models.Address, buyers)
+	for i := range users {
+		users[i] = models.User{Username: fmt.Sprintf("buyer%d", i), Email: fmt.Sprintf("buyer%d@example.com", i)}
+		require.NoError(t, db.Create(&users[i]).Error)
+		addresses[i] = models.Address{UserID: users[i].ID, Line1: "1 MG Road", City: "Bengaluru", State: "Karnataka", Country: "India", PostalCode: "560001"}
+		require.NoError(t, db.Create(&addresses[i]).Error)
+		require.NoError(t, db.Create(&models.Cart{UserID: users[i].ID, BookID: book.ID, Quantity: 1}).Error)
+	}
+
+	codes := make([]int, buyers)
+	bodies := make([]string, buyers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range users {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			recorder := placeOrderAs(users[i], gin.H{"payment_method": "online", "address_id": addresses[i].ID})
+			codes[i] = recorder.Code
+			bodies[i] = recorder.Body.String()
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for i, code := range codes {
+		if code == http.StatusOK {
+			succeeded++
+			continue
+		}
+		assert.Contains(t, []int{http.StatusBadRequest, http.StatusConflict}, code, "buyer %d: %s", i, bodies[i])
+		assert.Regexp(t, `OUT_OF_STOCK|STOCK_CHANGED`, bodies[i], "buyer %d", i)
+	}
+	assert.Equal(t, 1, succeeded, "exactly one buyer gets the last copy")
+
+	var stored models.Book
+	require.NoError(t, db.First(&stored, book.ID).Error)
+	assert.Equal(t, 0, stored.Stock, "stock must not go negative")
+
+	var orders int64
+	require.NoError(t, db.Model(&models.Order{}).Count(&orders).Error)
+	assert.Equal(t, int64(1), orders)
+	var movements int64
+	require.NoError(t, db.Model(&models.StockMovement{}).Count(&movements).Error)
+	assert.Equal(t, int64(1), movements)
+}
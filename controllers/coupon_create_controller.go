@@ -19,6 +19,8 @@ type CreateCouponRequest struct {
 	MaxDiscount   float64   `json:"max_discount" binding:"required,gt=0"`
 	Expiry        time.Time `json:"expiry" binding:"required"`
 	UsageLimit    int       `json:"usage_limit" binding:"required,gt=0"`
+	AutoApply     bool      `json:"auto_apply"`
+	CouponTargetingRequest
 }
 
 // CreateCoupon creates a new coupon
@@ -28,7 +30,7 @@ func CreateCoupon(c *gin.Context) {
 	var req CreateCouponRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request format: %v", err)
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 	utils.LogInfo("Processing coupon creation with code: %s", req.Code)
@@ -57,6 +59,12 @@ func CreateCoupon(c *gin.Context) {
 		return
 	}
 
+	targets, ok := loadCouponTargets(c, tx, req.CouponTargetingRequest)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
 	// Check if coupon code already exists (case-insensitive)
 	var existingCoupon models.Coupon
 	if err := tx.Where("LOWER(code) = LOWER(?)", req.Code).First(&existingCoupon).Error; err == nil {
@@ -74,8 +82,21 @@ func CreateCoupon(c *gin.Context) {
 		MaxDiscount:   req.MaxDiscount,
 		Expiry:        req.Expiry,
 		UsageLimit:    req.UsageLimit,
+		AutoApply:     req.AutoApply,
 		Active:        true,
 	}
+	if req.FirstOrderOnly != nil {
+		coupon.FirstOrderOnly = *req.FirstOrderOnly
+	}
+	if req.UserSegment != nil {
+		coupon.UserSegment = *req.UserSegment
+	}
+	if req.MinAccountAgeDays != nil {
+		coupon.MinAccountAgeDays = *req.MinAccountAgeDays
+	}
+	if req.PerUserLimit != nil {
+		coupon.PerUserLimit = *req.PerUserLimit
+	}
 
 	if err := tx.Create(&coupon).Error; err != nil {
 		tx.Rollback()
@@ -90,6 +111,13 @@ func CreateCoupon(c *gin.Context) {
 		return
 	}
 
+	if err := saveCouponTargets(tx, &coupon, req.CouponTargetingRequest, targets); err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to save targeting of coupon %s: %v", coupon.Code, err)
+		utils.InternalServerError(c, "Failed to create coupon", err.Error())
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		utils.LogError("Failed to commit transaction: %v", err)
 		utils.InternalServerError(c, "Failed to commit transaction", nil)
@@ -97,19 +125,22 @@ func CreateCoupon(c *gin.Context) {
 	}
 
 	utils.LogInfo("Successfully created coupon with code: %s, ID: %d", coupon.Code, coupon.ID)
+	formatter := utils.NewResponseFormatter(c)
 	// Return consistent response format
 	utils.Success(c, "Coupon created successfully", gin.H{
 		"id":           coupon.ID,
 		"code":         strings.ToUpper(coupon.Code),
 		"type":         coupon.Type,
-		"value":        coupon.Value,
-		"min_order":    coupon.MinOrderValue,
-		"max_discount": coupon.MaxDiscount,
+		"value":        couponValueResponse(formatter, coupon),
+		"min_order":    formatter.Money(coupon.MinOrderValue),
+		"max_discount": formatter.Money(coupon.MaxDiscount),
 		"usage_limit":  coupon.UsageLimit,
 		"used_count":   0,
+		"auto_apply":   coupon.AutoApply,
 		"active":       coupon.Active,
 		"is_expired":   false,
 		"expiry":       coupon.Expiry.Format("2006-01-02"),
-		"created_at":   coupon.CreatedAt.Format("2006-01-02 15:04:05"),
+		"created_at":   formatter.Timestamp(coupon.CreatedAt),
+		"restrictions": couponRestrictions(coupon),
 	})
 }
@@ -54,7 +54,7 @@ func GetCoupons(c *gin.Context) {
 	// Apply pagination
 	offset := (page - 1) * limit
 	var coupons []models.Coupon
-	if err := query.Offset(offset).Limit(limit).Find(&coupons).Error; err != nil {
+	if err := query.Preload("Categories").Preload("Books").Offset(offset).Limit(limit).Find(&coupons).Error; err != nil {
 		utils.LogError("Failed to fetch coupons: %v", err)
 		utils.InternalServerError(c, "Failed to fetch coupons", nil)
 		return
@@ -64,17 +64,18 @@ func GetCoupons(c *gin.Context) {
 	// Format coupons with only necessary information
 	var formattedCoupons []gin.H
 	_, isAdmin := c.Get("admin")
+	formatter := utils.NewResponseFormatter(c)
 	for _, coupon := range coupons {
 		isExpired := time.Now().After(coupon.Expiry)
 		isValid := coupon.Active && !isExpired
 
 		// Create user-friendly description
-		description := fmt.Sprintf("%s %s off on orders above ₹%.2f (Max discount: ₹%.2f)",
+		description := fmt.Sprintf("%s %s off on orders above %s (Max discount: %s)",
 			func() string {
 				if coupon.Type == "percent" {
 					return fmt.Sprintf("%.0f%%", coupon.Value)
 				}
-				return fmt.Sprintf("₹%.2f", coupon.Value)
+				return utils.FormatRequestMoney(c, coupon.Value)
 			}(),
 			func() string {
 				if coupon.Type == "percent" {
@@ -82,8 +83,8 @@ func GetCoupons(c *gin.Context) {
 				}
 				return "flat"
 			}(),
-			coupon.MinOrderValue,
-			coupon.MaxDiscount,
+			utils.FormatRequestMoney(c, coupon.MinOrderValue),
+			utils.FormatRequestMoney(c, coupon.MaxDiscount),
 		)
 
 		// Check if admin is in context to determine response format
@@ -93,15 +94,17 @@ func GetCoupons(c *gin.Context) {
 				"id":           coupon.ID,
 				"code":         strings.ToUpper(coupon.Code),
 				"type":         coupon.Type,
-				"value":        coupon.Value,
-				"min_order":    coupon.MinOrderValue,
-				"max_discount": coupon.MaxDiscount,
+				"value":        couponValueResponse(formatter, coupon),
+				"min_order":    formatter.Money(coupon.MinOrderValue),
+				"max_discount": formatter.Money(coupon.MaxDiscount),
 				"usage_limit":  coupon.UsageLimit,
 				"used_count":   coupon.UsedCount,
+				"auto_apply":   coupon.AutoApply,
 				"active":       coupon.Active,
 				"is_expired":   isExpired,
 				"expiry":       coupon.Expiry.Format("2006-01-02"),
-				"created_at":   coupon.CreatedAt.Format("2006-01-02 15:04:05"),
+				"created_at":   formatter.Timestamp(coupon.CreatedAt),
+				"restrictions": couponRestrictions(coupon),
 			})
 		} else {
 			// User view - show minimal details
@@ -110,8 +113,8 @@ func GetCoupons(c *gin.Context) {
 				"description":  description,
 				"expiry":       coupon.Expiry.Format("2006-01-02"),
 				"is_valid":     isValid,
-				"max_discount": fmt.Sprintf("%.2f", coupon.MaxDiscount),
-				"min_order":    fmt.Sprintf("%.2f", coupon.MinOrderValue),
+				"max_discount": formatter.Money(coupon.MaxDiscount),
+				"min_order":    formatter.Money(coupon.MinOrderValue),
 			})
 		}
 	}
@@ -141,7 +144,7 @@ func DeleteCoupon(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -179,7 +182,7 @@ func DeleteCoupon(c *gin.Context) {
 		if err := query.First(&coupon).Error; err != nil {
 			tx.Rollback()
 			utils.LogError("Coupon not found with identifier: %s", identifier)
-			utils.NotFound(c, "Coupon not found")
+			utils.Fail(c, utils.CodeCouponNotFound, "Coupon not found", nil)
 			return
 		}
 	}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetEligibleCoupons evaluates the user's current cart against all active, unexpired coupons
+// and reports the discount of each applicable coupon and why the others don't apply
+func GetEligibleCoupons(c *gin.Context) {
+	utils.LogInfo("GetEligibleCoupons called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	cartDetails, err := utils.GetCartDetails(user.ID)
+	if err != nil {
+		utils.LogError("Failed to get cart details for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to get cart details", err.Error())
+		return
+	}
+	subtotal := cartDetails.Subtotal
+	offerTotal := cartDetails.OfferTotal()
+
+	// Recently expired coupons are included so users learn why a code they hold stopped working
+	var coupons []models.Coupon
+	if err := config.DB.Preload("Categories").Preload("Books").Where("active = ? AND expiry >= ?", true, time.Now().AddDate(0, 0, -30)).
+		Order("expiry ASC").Find(&coupons).Error; err != nil {
+		utils.LogError("Failed to fetch coupons: %v", err)
+		utils.InternalServerError(c, "Failed to fetch coupons", err.Error())
+		return
+	}
+
+	eligible := make([]gin.H, 0)
+	ineligible := make([]gin.H, 0)
+	discounts := make(map[uint]float64)
+	formatter := utils.NewResponseFormatter(c)
+	for _, coupon := range coupons {
+		entry := gin.H{
+			"id":              coupon.ID,
+			"code":            coupon.Code,
+			"type":            coupon.Type,
+			"value":           coupon.Value,
+			"min_order_value": formatter.Money(coupon.MinOrderValue),
+			"max_discount":    formatter.Money(coupon.MaxDiscount),
+			"expiry":          formatter.Timestamp(coupon.Expiry),
+			"restrictions":    couponRestrictions(coupon),
+			"auto_apply":      coupon.AutoApply,
+		}
+
+		reasons := utils.CheckCouponEligibility(user, coupon, cartDetails)
+		if len(reasons) > 0 {
+			entry["reasons"] = reasons
+			ineligible = append(ineligible, entry)
+			continue
+		}
+
+		discount := cartDetails.CouponDiscountFor(coupon)
+		discounts[coupon.ID] = discount
+		entry["discount"] = formatter.Money(discount)
+		entry["final_total"] = formatter.Money(offerTotal - discount)
+		eligible = append(eligible, entry)
+	}
+
+	// Best deal first
+	sort.SliceStable(eligible, func(i, j int) bool {
+		return discounts[eligible[i]["id"].(uint)] > discounts[eligible[j]["id"].(uint)]
+	})
+
+	var bestCoupon interface{}
+	if len(eligible) > 0 {
+		bestCoupon = eligible[0]["code"]
+	}
+
+	utils.LogInfo("Evaluated %d coupons for user ID: %d - %d eligible", len(coupons), user.ID, len(eligible))
+	utils.Success(c, "Coupon eligibility evaluated successfully", gin.H{
+		"cart_subtotal":  formatter.Money(subtotal),
+		"cart_total":     formatter.Money(offerTotal),
+		"applied_coupon": cartDetails.CouponCode,
+		"auto_applied":   cartDetails.CouponAutoApplied,
+		"best_coupon":    bestCoupon,
+		"eligible":       eligible,
+		"ineligible":     ineligible,
+	})
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CouponTargetingRequest holds the targeting rules of a coupon. Fields left out are not
+// changed; an empty list removes that restriction.
+type CouponTargetingRequest struct {
+	FirstOrderOnly    *bool   `json:"first_order_only"`
+	UserSegment       *string `json:"user_segment"`
+	MinAccountAgeDays *int    `json:"min_account_age_days" binding:"omitempty,gte=0"`
+	PerUserLimit      *int    `json:"per_user_limit" binding:"omitempty,gte=1"`
+	CategoryIDs       *[]uint `json:"category_ids"`
+	BookIDs           *[]uint `json:"book_ids"`
+	UserIDs           *[]uint `json:"user_ids"`
+}
+
+// couponTargets are the records a targeting request restricts a coupon to
+type couponTargets struct {
+	Categories []models.Category
+	Books      []models.Book
+	Users      []models.User
+}
+
+// missingIDs returns the requested IDs that were not found
+func missingIDs(requested []uint, found map[uint]bool) []uint {
+	missing := make([]uint, 0)
+	for _, id := range requested {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// loadCouponTargets validates a targeting request and loads the categories, books and users
+// it lists. It writes the error response and returns false when the request is invalid.
+func loadCouponTargets(c *gin.Context, tx *gorm.DB, req CouponTargetingRequest) (couponTargets, bool) {
+	var targets couponTargets
+	if req.UserSegment != nil {
+		if !utils.ValidCouponSegment(*req.UserSegment) {
+			utils.LogError("Invalid coupon user segment: %s", *req.UserSegment)
+			utils.BadRequest(c, "Invalid user segment", gin.H{
+				"valid_segments": []string{models.CouponSegmentReturning, models.CouponSegmentReferred, models.CouponSegmentSelected},
+			})
+			return targets, false
+		}
+		if *req.UserSegment == models.CouponSegmentSelected && (req.UserIDs == nil || len(*req.UserIDs) == 0) {
+			utils.LogError("Selected user segment without user IDs")
+			utils.BadRequest(c, "user_ids are required for the selected user segment", nil)
+			return targets, false
+		}
+	}
+
+	if req.CategoryIDs != nil && len(*req.CategoryIDs) > 0 {
+		if err := tx.Where("id IN ?", *req.CategoryIDs).Find(&targets.Categories).Error; err != nil {
+			utils.LogError("Failed to load coupon categories: %v", err)
+			utils.InternalServerError(c, "Failed to load categories", err.Error())
+			return targets, false
+		}
+		found := make(map[uint]bool)
+		for _, category := range targets.Categories {
+			found[category.ID] = true
+		}
+		if missing := missingIDs(*req.CategoryIDs, found); len(missing) > 0 {
+			utils.LogError("Unknown category IDs for coupon: %v", missing)
+			utils.Fail(c, utils.CodeCategoryNotFound, "Some categories do not exist", gin.H{"category_ids": missing})
+			return targets, false
+		}
+	}
+	if req.BookIDs != nil && len(*req.BookIDs) > 0 {
+		if err := tx.Where("id IN ?", *req.BookIDs).Find(&targets.Books).Error; err != nil {
+			utils.LogError("Failed to load coupon books: %v", err)
+			utils.InternalServerError(c, "Failed to load books", err.Error())
+			return targets, false
+		}
+		found := make(map[uint]bool)
+		for _, book := range targets.Books {
+			found[book.ID] = true
+		}
+		if missing := missingIDs(*req.BookIDs, found); len(missing) > 0 {
+			utils.LogError("Unknown book IDs for coupon: %v", missing)
+			utils.Fail(c, utils.CodeBookNotFound, "Some books do not exist", gin.H{"book_ids": missing})
+			return targets, false
+		}
+	}
+	if req.UserIDs != nil && len(*req.UserIDs) > 0 {
+		if err := tx.Where("id IN ?", *req.UserIDs).Find(&targets.Users).Error; err != nil {
+			utils.LogError("Failed to load coupon users: %v", err)
+			utils.InternalServerError(c, "Failed to load users", err.Error())
+			return targets, false
+		}
+		found := make(map[uint]bool)
+		for _, user := range targets.Users {
+			found[user.ID] = true
+		}
+		if missing := missingIDs(*req.UserIDs, found); len(missing) > 0 {
+			utils.LogError("Unknown user IDs for coupon: %v", missing)
+			utils.Fail(c, utils.CodeUserNotFound, "Some users do not exist", gin.H{"user_ids": missing})
+			return targets, false
+		}
+	}
+	return targets, true
+}
+
+// targetingUpdates returns the coupon columns a targeting request changes
+func targetingUpdates(req CouponTargetingRequest) map[string]interface{} {
+	updates := make(map[string]interface{})
+	if req.FirstOrderOnly != nil {
+		updates["first_order_only"] = *req.FirstOrderOnly
+	}
+	if req.UserSegment != nil {
+		updates["user_segment"] = *req.UserSegment
+	}
+	if req.MinAccountAgeDays != nil {
+		updates["min_account_age_days"] = *req.MinAccountAgeDays
+	}
+	if req.PerUserLimit != nil {
+		updates["per_user_limit"] = *req.PerUserLimit
+	}
+	return updates
+}
+
+// saveCouponTargets replaces the category, book and user restrictions the request lists
+func saveCouponTargets(tx *gorm.DB, coupon *models.Coupon, req CouponTargetingRequest, targets couponTargets) error {
+	if req.CategoryIDs != nil {
+		if err := tx.Model(coupon).Association("Categories").Replace(targets.Categories); err != nil {
+			return err
+		}
+	}
+	if req.BookIDs != nil {
+		if err := tx.Model(coupon).Association("Books").Replace(targets.Books); err != nil {
+			return err
+		}
+	}
+	if req.UserIDs != nil {
+		if err := tx.Model(coupon).Association("Users").Replace(targets.Users); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// couponRestrictions describes who a coupon is for and which books it covers. The coupon's
+// categories and books must be loaded.
+func couponRestrictions(coupon models.Coupon) gin.H {
+	categories := make([]gin.H, 0, len(coupon.Categories))
+	for _, category := range coupon.Categories {
+		categories = append(categories, gin.H{"id": category.ID, "name": category.Name})
+	}
+	books := make([]gin.H, 0, len(coupon.Books))
+	for _, book := range coupon.Books {
+		books = append(books, gin.H{"id": book.ID, "name": book.Name})
+	}
+	perUserLimit := coupon.PerUserLimit
+	if perUserLimit < 1 {
+		perUserLimit = 1
+	}
+	return gin.H{
+		"first_order_only":     coupon.FirstOrderOnly,
+		"user_segment":         coupon.UserSegment,
+		"min_account_age_days": coupon.MinAccountAgeDays,
+		"per_user_limit":       perUserLimit,
+		"categories":           categories,
+		"books":                books,
+	}
+}
+
+// couponValueResponse is the coupon's value in a response: an amount in the request's currency for
+// flat coupons and a plain percentage for percent coupons
+func couponValueResponse(f utils.ResponseFormatter, coupon models.Coupon) interface{} {
+	if coupon.Type == "percent" {
+		return coupon.Value
+	}
+	return f.Money(coupon.Value)
+}
@@ -19,6 +19,8 @@ type UpdateCouponRequest struct {
 	Expiry        time.Time `json:"expiry" binding:"omitempty"`
 	UsageLimit    int       `json:"usage_limit" binding:"omitempty,gt=0"`
 	Active        *bool     `json:"active" binding:"omitempty"`
+	AutoApply     *bool     `json:"auto_apply"`
+	CouponTargetingRequest
 }
 
 // UpdateCoupon updates an existing coupon
@@ -29,7 +31,7 @@ func UpdateCoupon(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -52,7 +54,7 @@ func UpdateCoupon(c *gin.Context) {
 	var req UpdateCouponRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request format for coupon %s: %v", identifier, err)
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 
@@ -74,7 +76,7 @@ func UpdateCoupon(c *gin.Context) {
 		if err := query.First(&coupon).Error; err != nil {
 			tx.Rollback()
 			utils.LogError("Coupon not found with identifier: %s", identifier)
-			utils.NotFound(c, "Coupon not found")
+			utils.Fail(c, utils.CodeCouponNotFound, "Coupon not found", nil)
 			return
 		}
 	}
@@ -104,8 +106,14 @@ func UpdateCoupon(c *gin.Context) {
 		return
 	}
 
+	targets, ok := loadCouponTargets(c, tx, req.CouponTargetingRequest)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
 	// Update fields if provided
-	updates := make(map[string]interface{})
+	updates := targetingUpdates(req.CouponTargetingRequest)
 	if req.Type != "" {
 		updates["type"] = req.Type
 	}
@@ -127,6 +135,9 @@ func UpdateCoupon(c *gin.Context) {
 	if req.Active != nil {
 		updates["active"] = *req.Active
 	}
+	if req.AutoApply != nil {
+		updates["auto_apply"] = *req.AutoApply
+	}
 	updates["updated_at"] = time.Now()
 
 	// Update the coupon
@@ -136,6 +147,18 @@ func UpdateCoupon(c *gin.Context) {
 		utils.InternalServerError(c, "Failed to update coupon", nil)
 		return
 	}
+	if err := saveCouponTargets(tx, &coupon, req.CouponTargetingRequest, targets); err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to save targeting of coupon %s: %v", coupon.Code, err)
+		utils.InternalServerError(c, "Failed to update coupon", nil)
+		return
+	}
+	if err := tx.Preload("Categories").Preload("Books").First(&coupon, coupon.ID).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to reload coupon %s: %v", coupon.Code, err)
+		utils.InternalServerError(c, "Failed to update coupon", nil)
+		return
+	}
 
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
@@ -147,18 +170,21 @@ func UpdateCoupon(c *gin.Context) {
 	// Return concise response
 	isExpired := time.Now().After(coupon.Expiry)
 	utils.LogInfo("Successfully updated coupon with ID: %d, code: %s", coupon.ID, coupon.Code)
+	formatter := utils.NewResponseFormatter(c)
 	utils.Success(c, "Coupon updated successfully", gin.H{
 		"id":           coupon.ID,
 		"code":         strings.ToUpper(coupon.Code),
 		"type":         coupon.Type,
-		"value":        coupon.Value,
-		"min_order":    coupon.MinOrderValue,
-		"max_discount": coupon.MaxDiscount,
+		"value":        couponValueResponse(formatter, coupon),
+		"min_order":    formatter.Money(coupon.MinOrderValue),
+		"max_discount": formatter.Money(coupon.MaxDiscount),
 		"usage_limit":  coupon.UsageLimit,
 		"used_count":   coupon.UsedCount,
+		"auto_apply":   coupon.AutoApply,
 		"active":       coupon.Active,
 		"is_expired":   isExpired,
 		"expiry":       coupon.Expiry.Format("2006-01-02"),
-		"last_updated": coupon.UpdatedAt.Format("2006-01-02 15:04:05"),
+		"last_updated": formatter.Timestamp(coupon.UpdatedAt),
+		"restrictions": couponRestrictions(coupon),
 	})
 }
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// couponUsageIntervals maps each interval, also its DATE_TRUNC unit, to the label format of its periods
+var couponUsageIntervals = map[string]string{
+	"day":   "2006-01-02",
+	"week":  "2006-01-02",
+	"month": "2006-01",
+}
+
+// conversionRate is the percentage of applications that ended in an order
+func conversionRate(orders, applications int64) float64 {
+	if applications == 0 {
+		return 0
+	}
+	return math.Round(float64(orders)/float64(applications)*10000) / 100
+}
+
+// AdminGetCouponUsage returns how a coupon performed over a date range: the orders placed with
+// it and who placed them, the discount given, and how many of the times it was applied to a
+// cart ended in an order, in total and per day, week or month. Cancelled orders are reported
+// apart and do not count as conversions.
+func AdminGetCouponUsage(c *gin.Context) {
+	utils.LogInfo("AdminGetCouponUsage called")
+
+	couponID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.LogError("Invalid coupon ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid coupon ID", nil)
+		return
+	}
+	var coupon models.Coupon
+	if err := config.DB.Unscoped().First(&coupon, couponID).Error; err != nil {
+		utils.LogError("Coupon not found: %d", couponID)
+		utils.Fail(c, utils.CodeCouponNotFound, "Coupon not found", nil)
+		return
+	}
+
+	interval := c.DefaultQuery("interval", "day")
+	labelFormat, ok := couponUsageIntervals[interval]
+	if !ok {
+		utils.LogError("Invalid interval: %s", interval)
+		utils.BadRequest(c, "Invalid interval", "interval must be day, week or month")
+		return
+	}
+
+	// Default to the coupon's whole life up to today
+	now := time.Now()
+	endDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Add(24 * time.Hour)
+	startDate := time.Date(coupon.CreatedAt.Year(), coupon.CreatedAt.Month(), coupon.CreatedAt.Day(), 0, 0, 0, 0, now.Location())
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			utils.LogError("Invalid start date format: %v", err)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid start date", "Start date must be in YYYY-MM-DD format")
+			return
+		}
+		startDate = parsed
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			utils.LogError("Invalid end date format: %v", err)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid end date", "End date must be in YYYY-MM-DD format")
+			return
+		}
+		// Include the entire end date
+		endDate = parsed.Add(24 * time.Hour)
+	}
+	if !endDate.After(startDate) {
+		utils.LogError("Invalid date range: %s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+		utils.Fail(c, utils.CodeInvalidDate, "Invalid date range", "End date must be after start date")
+		return
+	}
+
+	orders := config.DB.Model(&models.Order{}).
+		Where("LOWER(orders.coupon_code) = ?", strings.ToLower(coupon.Code)).
+		Where("orders.created_at >= ? AND orders.created_at < ?", startDate, endDate)
+	redeemed := orders.Session(&gorm.Session{}).Where("orders.status <> ?", models.OrderStatusCancelled)
+	applications := config.DB.Model(&models.CouponApplication{}).
+		Where("coupon_id = ? AND applied_at >= ? AND applied_at < ?", coupon.ID, startDate, endDate)
+
+	// Totals
+	var totals struct {
+		Orders      int64
+		Users       int64
+		Discount    float64
+		Revenue     float64
+		FirstUsedAt *time.Time
+		LastUsedAt  *time.Time
+	}
+	if err := redeemed.Session(&gorm.Session{}).
+		Select("COUNT(*) AS orders, COUNT(DISTINCT orders.user_id) AS users, " +
+			"COALESCE(SUM(orders.coupon_discount), 0) AS discount, COALESCE(SUM(orders.total_with_delivery), 0) AS revenue, " +
+			"MIN(orders.created_at) AS first_used_at, MAX(orders.created_at) AS last_used_at").
+		Scan(&totals).Error; err != nil {
+		utils.LogError("Failed to total usage of coupon %d: %v", coupon.ID, err)
+		utils.InternalServerError(c, "Failed to fetch coupon usage", err.Error())
+		return
+	}
+	var cancelled int64
+	if err := orders.Session(&gorm.Session{}).Where("orders.status = ?", models.OrderStatusCancelled).
+		Count(&cancelled).Error; err != nil {
+		utils.LogError("Failed to count cancelled orders of coupon %d: %v", coupon.ID, err)
+		utils.InternalServerError(c, "Failed to fetch coupon usage", err.Error())
+		return
+	}
+	var applied struct {
+		Applications int64
+		Users        int64
+	}
+	if err := applications.Session(&gorm.Session{}).
+		Select("COUNT(*) AS applications, COUNT(DISTINCT user_id) AS users").
+		Scan(&applied).Error; err != nil {
+		utils.LogError("Failed to count applications of coupon %d: %v", coupon.ID, err)
+		utils.InternalServerError(c, "Failed to fetch coupon usage", err.Error())
+		return
+	}
+
+	// Conversion over time
+	type bucketRow struct {
+		Period   time.Time
+		Count    int64
+		Discount float64
+		Revenue  float64
+	}
+	var orderBuckets, applicationBuckets []bucketRow
+	if err := redeemed.Session(&gorm.Session{}).
+		Select(fmt.Sprintf("DATE_TRUNC('%s', orders.created_at) AS period, COUNT(*) AS count, "+
+			"SUM(orders.coupon_discount) AS discount, SUM(orders.total_with_delivery) AS revenue", interval)).
+		Group("period").Scan(&orderBuckets).Error; err != nil {
+		utils.LogError("Failed to group orders of coupon %d: %v", coupon.ID, err)
+		utils.InternalServerError(c, "Failed to fetch coupon usage", err.Error())
+		return
+	}
+	if err := applications.Session(&gorm.Session{}).
+		Select(fmt.Sprintf("DATE_TRUNC('%s', applied_at) AS period, COUNT(*) AS count", interval)).
+		Group("period").Scan(&applicationBuckets).Error; err != nil {
+		utils.LogError("Failed to group applications of coupon %d: %v", coupon.ID, err)
+		utils.InternalServerError(c, "Failed to fetch coupon usage", err.Error())
+		return
+	}
+	type timelinePoint struct {
+		Period         string  `json:"period"`
+		Applications   int64   `json:"applications"`
+		Orders         int64   `json:"orders"`
+		Discount       float64 `json:"discount"`
+		Revenue        float64 `json:"revenue"`
+		ConversionRate float64 `json:"conversion_rate"`
+	}
+	points := make(map[string]*timelinePoint)
+	point := func(period time.Time) *timelinePoint {
+		label := period.Format(labelFormat)
+		if points[label] == nil {
+			points[label] = &timelinePoint{Period: label}
+		}
+		return points[label]
+	}
+	for _, row := range orderBuckets {
+		p := point(row.Period)
+		p.Orders = row.Count
+		p.Discount = math.Round(row.Discount*100) / 100
+		p.Revenue = math.Round(row.Revenue*100) / 100
+	}
+	for _, row := range applicationBuckets {
+		point(row.Period).Applications = row.Count
+	}
+	timeline := make([]timelinePoint, 0, len(points))
+	for _, p := range points {
+		p.ConversionRate = conversionRate(p.Orders, p.Applications)
+		timeline = append(timeline, *p)
+	}
+	sort.Slice(timeline, func(i, j int) bool { return timeline[i].Period < timeline[j].Period })
+
+	// The orders placed with the coupon, newest first
+	page, limit := utils.GetPaginationParams(c)
+	var total int64
+	if err := orders.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		utils.LogError("Failed to count orders of coupon %d: %v", coupon.ID, err)
+		utils.InternalServerError(c, "Failed to fetch coupon usage", err.Error())
+		return
+	}
+	var usages []models.Order
+	if err := orders.Session(&gorm.Session{}).Preload("User").
+		Order("orders.created_at DESC, orders.id DESC").Offset((page - 1) * limit).Limit(limit).
+		Find(&usages).Error; err != nil {
+		utils.LogError("Failed to fetch orders of coupon %d: %v", coupon.ID, err)
+		utils.InternalServerError(c, "Failed to fetch coupon usage", err.Error())
+		return
+	}
+	usageItems := make([]gin.H, 0, len(usages))
+	formatter := utils.NewResponseFormatter(c)
+	for _, order := range usages {
+		usageItems = append(usageItems, gin.H{
+			"order_id":        order.ID,
+			"user_id":         order.UserID,
+			"username":        order.User.Username,
+			"email":           order.User.Email,
+			"status":          order.Status,
+			"coupon_discount": formatter.Money(order.CouponDiscount),
+			"order_total":     formatter.Money(order.TotalWithDelivery),
+			"created_at":      formatter.Timestamp(order.CreatedAt),
+		})
+	}
+
+	averageOrder := 0.0
+	if totals.Orders > 0 {
+		averageOrder = totals.Revenue / float64(totals.Orders)
+	}
+	summary := gin.H{
+		"orders":               totals.Orders,
+		"cancelled_orders":     cancelled,
+		"unique_users":         totals.Users,
+		"total_discount":       formatter.Money(totals.Discount),
+		"revenue":              formatter.Money(totals.Revenue),
+		"average_order_value":  formatter.Money(averageOrder),
+		"applications":         applied.Applications,
+		"applying_users":       applied.Users,
+		"conversion_rate":      conversionRate(totals.Orders, applied.Applications),
+		"first_used_at":        nil,
+		"last_used_at":         nil,
+		"usage_limit":          coupon.UsageLimit,
+		"used_count":           coupon.UsedCount,
+		"usage_limit_consumed": 0.0,
+	}
+	if totals.FirstUsedAt != nil {
+		summary["first_used_at"] = formatter.Timestamp(*totals.FirstUsedAt)
+		summary["last_used_at"] = formatter.Timestamp(*totals.LastUsedAt)
+	}
+	if coupon.UsageLimit > 0 {
+		summary["usage_limit_consumed"] = math.Round(float64(coupon.UsedCount)/float64(coupon.UsageLimit)*10000) / 100
+	}
+
+	utils.LogInfo("Retrieved usage of coupon %s: %d orders from %d applications", coupon.Code, totals.Orders, applied.Applications)
+	utils.SuccessWithPagination(c, "Coupon usage retrieved successfully", gin.H{
+		"coupon": gin.H{
+			"id":     coupon.ID,
+			"code":   strings.ToUpper(coupon.Code),
+			"type":   coupon.Type,
+			"value":  coupon.Value,
+			"active": coupon.Active,
+			"expiry": coupon.Expiry.Format("2006-01-02"),
+		},
+		"period": gin.H{
+			"start_date": formatter.Timestamp(startDate),
+			"end_date":   formatter.Timestamp(endDate),
+			"interval":   interval,
+		},
+		"summary":  summary,
+		"timeline": timeline,
+		"orders":   usageItems,
+	}, total, page, limit)
+}
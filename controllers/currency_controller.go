@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// currencyList returns the supported currencies with their current rates
+func currencyList(f utils.ResponseFormatter) ([]gin.H, error) {
+	var rows []models.ExchangeRate
+	if err := config.DB.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	stored := make(map[string]models.ExchangeRate, len(rows))
+	for _, row := range rows {
+		stored[row.Currency] = row
+	}
+
+	currencies := make([]gin.H, 0)
+	for _, info := range utils.SupportedCurrencies() {
+		entry := gin.H{
+			"code":     info.Code,
+			"symbol":   info.Symbol,
+			"decimals": info.Decimals,
+			"base":     info.Code == utils.BaseCurrency(),
+		}
+		if rate, ok := utils.GetExchangeRate(info.Code); ok {
+			entry["rate"] = rate
+		} else {
+			entry["rate"] = nil
+		}
+		if row, ok := stored[info.Code]; ok {
+			entry["source"] = row.Source
+			entry["manual"] = row.Manual
+			entry["fetched_at"] = f.Timestamp(row.FetchedAt)
+		}
+		currencies = append(currencies, entry)
+	}
+	return currencies, nil
+}
+
+// GetCurrencies lists the currencies clients can request amounts in
+func GetCurrencies(c *gin.Context) {
+	utils.LogInfo("GetCurrencies called")
+
+	formatter := utils.NewResponseFormatter(c)
+	currencies, err := currencyList(formatter)
+	if err != nil {
+		utils.LogError("Failed to fetch exchange rates: %v", err)
+		utils.InternalServerError(c, "Failed to fetch currencies", err.Error())
+		return
+	}
+
+	utils.Success(c, "Currencies retrieved successfully", gin.H{
+		"base_currency":    utils.BaseCurrency(),
+		"request_currency": utils.GetRequestCurrency(c),
+		"currencies":       currencies,
+	})
+}
+
+// SetExchangeRateRequest sets a manual exchange rate; 0 hands the currency back to the rate feed
+type SetExchangeRateRequest struct {
+	Rate *float64 `json:"rate" binding:"required"`
+}
+
+// SetExchangeRate overrides the exchange rate of a supported currency
+func SetExchangeRate(c *gin.Context) {
+	utils.LogInfo("SetExchangeRate called")
+
+	code := strings.ToUpper(c.Param("code"))
+	if code == utils.BaseCurrency() {
+		utils.BadRequest(c, "The base currency has a fixed rate of 1", nil)
+		return
+	}
+	supported := false
+	for _, info := range utils.SupportedCurrencies() {
+		if info.Code == code {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		utils.NotFound(c, "Currency not supported")
+		return
+	}
+
+	var req SetExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+	if *req.Rate < 0 {
+		utils.BadRequest(c, "Rate must be positive, or 0 to use the rate feed again", nil)
+		return
+	}
+
+	row, err := utils.SetManualExchangeRate(code, *req.Rate)
+	if err != nil {
+		utils.LogError("Failed to set exchange rate for %s: %v", code, err)
+		utils.InternalServerError(c, "Failed to set exchange rate", err.Error())
+		return
+	}
+
+	utils.LogInfo("Exchange rate for %s set to %f (manual: %t)", code, row.Rate, row.Manual)
+	utils.Success(c, "Exchange rate updated successfully", gin.H{
+		"exchange_rate": row,
+	})
+}
@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// deliveryEstimateResponse is the estimated delivery of a checkout, with the delivery slots
+// that can be booked when the zone offers them
+func deliveryEstimateResponse(estimate utils.DeliveryEstimate, slots []utils.DeliverySlotOption) gin.H {
+	slotList := make([]gin.H, len(slots))
+	for i, slot := range slots {
+		slotList[i] = gin.H{
+			"slot_id":    slot.SlotID,
+			"date":       slot.Date.Format("2006-01-02"),
+			"start_time": slot.StartTime,
+			"end_time":   slot.EndTime,
+			"label":      slot.Label(),
+			"remaining":  slot.Remaining,
+		}
+	}
+	return gin.H{
+		"zone":          estimate.Zone,
+		"label":         estimate.Label(),
+		"min_days":      estimate.MinDays,
+		"max_days":      estimate.MaxDays,
+		"earliest_date": estimate.EarliestDate.Format("2006-01-02"),
+		"latest_date":   estimate.LatestDate.Format("2006-01-02"),
+		"slots_enabled": estimate.SlotsEnabled && len(slots) > 0,
+		"slots":         slotList,
+	}
+}
+
+// estimateWithSlots estimates delivery to the pincode from now and lists the bookable slots.
+// A failure to list slots only hides them.
+func estimateWithSlots(pincode string) (gin.H, error) {
+	estimate, err := utils.EstimateDelivery(pincode, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	slots, err := utils.AvailableDeliverySlots(*estimate)
+	if err != nil {
+		utils.LogError("Failed to list delivery slots for pincode %s: %v", pincode, err)
+		slots = nil
+	}
+	return deliveryEstimateResponse(*estimate, slots), nil
+}
+
+// deliveryScheduleResponse is the estimated delivery dates and booked slot of an order, or nil
+// when the order has no estimate, such as digital and pre-orders
+func deliveryScheduleResponse(order models.Order) gin.H {
+	if order.EstimatedDeliveryFrom == nil || order.EstimatedDeliveryTo == nil {
+		return nil
+	}
+	schedule := gin.H{
+		"estimated_from": order.EstimatedDeliveryFrom.Format("2006-01-02"),
+		"estimated_to":   order.EstimatedDeliveryTo.Format("2006-01-02"),
+	}
+	if order.DeliverySlotID != nil && order.DeliverySlotDate != nil {
+		schedule["slot"] = gin.H{
+			"slot_id": *order.DeliverySlotID,
+			"date":    order.DeliverySlotDate.Format("2006-01-02"),
+			"label":   order.DeliverySlotLabel,
+		}
+	}
+	return schedule
+}
+
+// deliveryDateLabel describes when a newly placed order arrives
+func deliveryDateLabel(order models.Order, estimate *utils.DeliveryEstimate) string {
+	switch {
+	case order.IsPreorder:
+		return "Ships when the pre-ordered books are released"
+	case estimate == nil:
+		return "Digital books are available in your library once paid"
+	case order.DeliverySlotDate != nil:
+		return fmt.Sprintf("%s, %s", order.DeliverySlotDate.Format("Mon, 02 Jan 2006"), order.DeliverySlotLabel)
+	case estimate.MinDays == estimate.MaxDays:
+		return fmt.Sprintf("%s (by %s)", estimate.Label(), estimate.LatestDate.Format("Mon, 02 Jan 2006"))
+	}
+	return fmt.Sprintf("%s (%s - %s)", estimate.Label(),
+		estimate.EarliestDate.Format("Mon, 02 Jan"), estimate.LatestDate.Format("Mon, 02 Jan 2006"))
+}
+
+// GetDeliveryEstimate estimates when an order to a pincode, or one of the user's addresses,
+// would arrive and lists the delivery slots that can be booked at checkout
+func GetDeliveryEstimate(c *gin.Context) {
+	utils.LogInfo("GetDeliveryEstimate called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	pincode := strings.TrimSpace(c.Query("pincode"))
+	if addressID := c.Query("address_id"); addressID != "" {
+		id, err := strconv.ParseUint(addressID, 10, 32)
+		if err != nil {
+			utils.LogError("Invalid address ID format: %v", err)
+			utils.Fail(c, utils.CodeInvalidID, "Invalid address ID", nil)
+			return
+		}
+		var address models.Address
+		if err := config.DB.Where("id = ? AND user_id = ?", id, user.ID).First(&address).Error; err != nil {
+			utils.LogError("Address not found, ID: %d, user ID: %d", id, user.ID)
+			utils.Fail(c, utils.CodeAddressNotFound, "Address not found", nil)
+			return
+		}
+		pincode = address.PostalCode
+	}
+	if !utils.IsValidPincode(pincode) {
+		utils.LogError("Invalid pincode for delivery estimate: %s", pincode)
+		utils.BadRequest(c, "Provide a valid 6-digit pincode or address_id", nil)
+		return
+	}
+
+	response, err := estimateWithSlots(pincode)
+	if errors.Is(err, utils.ErrDeliveryUnavailable) {
+		utils.LogError("Delivery not available for pincode %s", pincode)
+		utils.Fail(c, utils.CodeDeliveryUnavailable, "Delivery is not available for this pincode", nil)
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to estimate delivery for pincode %s: %v", pincode, err)
+		utils.InternalServerError(c, "Failed to estimate delivery", err.Error())
+		return
+	}
+	response["pincode"] = pincode
+
+	utils.LogInfo("Estimated delivery for pincode %s: %s", pincode, response["label"])
+	utils.Success(c, "Delivery estimate retrieved successfully", gin.H{
+		"delivery_estimate": response,
+	})
+}
+
+// CheckDeliveryServiceability tells whether a pincode is served, and at what delivery charge,
+// Cash on Delivery availability and delivery time, so product pages can show it before
+// anything is in the cart. amount is the order value the charge is worked out for.
+func CheckDeliveryServiceability(c *gin.Context) {
+	utils.LogInfo("CheckDeliveryServiceability called")
+
+	pincode := strings.TrimSpace(c.Query("pincode"))
+	if !utils.IsValidPincode(pincode) {
+		utils.LogError("Invalid pincode for serviceability check: %s", pincode)
+		utils.BadRequest(c, "Provide a valid 6-digit pincode", nil)
+		return
+	}
+	var amount float64
+	if value := c.Query("amount"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 {
+			utils.LogError("Invalid amount for serviceability check: %s", value)
+			utils.BadRequest(c, "amount must be a non-negative number", nil)
+			return
+		}
+		amount = parsed
+	}
+
+	rule, err := utils.FindDeliveryRule(pincode)
+	if err != nil {
+		utils.LogInfo("Pincode %s is not serviceable", pincode)
+		utils.Success(c, "Delivery is not available for this pincode", gin.H{
+			"pincode":            pincode,
+			"delivery_available": false,
+			"cod_available":      false,
+		})
+		return
+	}
+
+	charge, err := utils.GetDeliveryCharge(pincode, amount)
+	if err != nil {
+		utils.LogError("Failed to get delivery charge for pincode %s: %v", pincode, err)
+		utils.InternalServerError(c, "Failed to check delivery", err.Error())
+		return
+	}
+	estimate, err := utils.EstimateDelivery(pincode, time.Now())
+	if err != nil {
+		utils.LogError("Failed to estimate delivery for pincode %s: %v", pincode, err)
+		utils.InternalServerError(c, "Failed to check delivery", err.Error())
+		return
+	}
+	// Cash on Delivery also depends on the order total; whether the customer may use it is
+	// only known at checkout
+	codAvailable := utils.IsCODAvailable(pincode) && amount+charge <= utils.CODOrderLimit()
+
+	utils.LogInfo("Pincode %s is serviceable: charge %.2f, COD %t, %s", pincode, charge, codAvailable, estimate.Label())
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Delivery is available for this pincode", gin.H{
+		"pincode":             pincode,
+		"delivery_available":  true,
+		"delivery_charge":     formatter.Money(charge),
+		"free_delivery_above": formatter.Money(rule.FreeDeliveryAbove),
+		"delivery_message":    utils.GetFreeDeliveryInfo(pincode, amount)["message"],
+		"cod_available":       codAvailable,
+		"estimated_delivery": gin.H{
+			"label":         estimate.Label(),
+			"min_days":      estimate.MinDays,
+			"max_days":      estimate.MaxDays,
+			"earliest_date": estimate.EarliestDate.Format("2006-01-02"),
+			"latest_date":   estimate.LatestDate.Format("2006-01-02"),
+		},
+	})
+}
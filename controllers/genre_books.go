@@ -16,7 +16,7 @@ func ListBooksByGenre(c *gin.Context) {
 	genreID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		utils.LogError("Invalid genre ID: %v", err)
-		utils.BadRequest(c, "Invalid genre ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid genre ID", nil)
 		return
 	}
 	utils.LogDebug("Processing genre ID: %d", genreID)
@@ -25,7 +25,7 @@ func ListBooksByGenre(c *gin.Context) {
 	var genre models.Genre
 	if err := config.DB.First(&genre, genreID).Error; err != nil {
 		utils.LogError("Genre not found: %v", err)
-		utils.NotFound(c, "Genre not found")
+		utils.Fail(c, utils.CodeGenreNotFound, "Genre not found", nil)
 		return
 	}
 	utils.LogDebug("Found genre: %s", genre.Name)
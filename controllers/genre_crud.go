@@ -21,7 +21,7 @@ func CreateGenre(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -37,7 +37,7 @@ func CreateGenre(c *gin.Context) {
 	var req GenreRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid input: %v", err)
-		utils.BadRequest(c, "Invalid input", gin.H{
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", gin.H{
 			"error": "Name and description are required",
 		})
 		return
@@ -48,7 +48,7 @@ func CreateGenre(c *gin.Context) {
 	var existingGenre models.Genre
 	if err := config.DB.Where("name = ?", req.Name).First(&existingGenre).Error; err == nil {
 		utils.LogError("Genre with name %s already exists", req.Name)
-		utils.Conflict(c, "A genre with this name already exists", nil)
+		utils.Fail(c, utils.CodeAlreadyExists, "A genre with this name already exists", nil)
 		return
 	}
 	utils.LogDebug("No existing genre found with name: %s", req.Name)
@@ -82,7 +82,7 @@ func UpdateGenre(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -106,7 +106,7 @@ func UpdateGenre(c *gin.Context) {
 	var genre models.Genre
 	if err := config.DB.First(&genre, genreID).Error; err != nil {
 		utils.LogError("Genre not found: %v", err)
-		utils.NotFound(c, "Genre not found")
+		utils.Fail(c, utils.CodeGenreNotFound, "Genre not found", nil)
 		return
 	}
 	utils.LogDebug("Found genre to update: %s", genre.Name)
@@ -114,7 +114,7 @@ func UpdateGenre(c *gin.Context) {
 	var req GenreRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid input: %v", err)
-		utils.BadRequest(c, "Invalid input", gin.H{
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", gin.H{
 			"error": "Name and description are required",
 		})
 		return
@@ -125,7 +125,7 @@ func UpdateGenre(c *gin.Context) {
 	var existingGenre models.Genre
 	if err := config.DB.Where("name = ? AND id != ?", req.Name, genreID).First(&existingGenre).Error; err == nil {
 		utils.LogError("Another genre with name %s already exists", req.Name)
-		utils.Conflict(c, "Another genre with this name already exists", nil)
+		utils.Fail(c, utils.CodeAlreadyExists, "Another genre with this name already exists", nil)
 		return
 	}
 	utils.LogDebug("No name conflict found for genre update")
@@ -157,7 +157,7 @@ func DeleteGenre(c *gin.Context) {
 	admin, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		utils.Unauthorized(c, "Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
 		return
 	}
 
@@ -182,7 +182,7 @@ func DeleteGenre(c *gin.Context) {
 	var genre models.Genre
 	if err := config.DB.First(&genre, genreID).Error; err != nil {
 		utils.LogError("Genre not found: %v", err)
-		utils.NotFound(c, "Genre not found")
+		utils.Fail(c, utils.CodeGenreNotFound, "Genre not found", nil)
 		return
 	}
 	utils.LogDebug("Found genre to delete: %s", genre.Name)
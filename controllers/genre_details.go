@@ -23,7 +23,7 @@ func GetGenreDetails(c *gin.Context) {
 	var genre models.Genre
 	if err := config.DB.First(&genre, genreID).Error; err != nil {
 		log.Printf("Genre not found: %v", err)
-		utils.NotFound(c, "Genre not found")
+		utils.Fail(c, utils.CodeGenreNotFound, "Genre not found", nil)
 		return
 	}
 
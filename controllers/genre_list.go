@@ -82,12 +82,21 @@ func GetGenres(c *gin.Context) {
 		Name        string `json:"name"`
 		Description string `json:"description"`
 	}
+	// Apply translations for the requested locale
+	locale := resolveCatalogLocale(c)
+	genreIDs := make([]uint, len(genres))
+	for i, genre := range genres {
+		genreIDs[i] = genre.ID
+	}
+	translations := loadTranslations(models.TranslationEntityGenre, genreIDs, locale)
+
 	var simpleGenres []SimpleGenre
 	for _, genre := range genres {
+		name, description := translateText(translations, genre.ID, genre.Name, genre.Description)
 		simpleGenres = append(simpleGenres, SimpleGenre{
 			ID:          genre.ID,
-			Name:        genre.Name,
-			Description: genre.Description,
+			Name:        name,
+			Description: description,
 		})
 	}
 	utils.LogDebug("Converted genres to simple format")
@@ -0,0 +1,399 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	razorpay "github.com/razorpay/razorpay-go"
+)
+
+// PurchaseGiftCardRequest is a gift card bought for a recipient
+type PurchaseGiftCardRequest struct {
+	Amount         float64 `json:"amount" binding:"required,gt=0"`
+	RecipientEmail string  `json:"recipient_email" binding:"required,email"`
+	RecipientName  string  `json:"recipient_name" binding:"max=100"`
+	Message        string  `json:"message" binding:"max=250"`
+	PaymentMethod  string  `json:"payment_method" binding:"required,oneof=online wallet"`
+}
+
+// VerifyGiftCardPaymentRequest completes the online payment of a gift card
+type VerifyGiftCardPaymentRequest struct {
+	GiftCardID        uint   `json:"gift_card_id" binding:"required"`
+	RazorpayOrderID   string `json:"razorpay_order_id" binding:"required"`
+	RazorpayPaymentID string `json:"razorpay_payment_id" binding:"required"`
+	RazorpaySignature string `json:"razorpay_signature" binding:"required"`
+}
+
+// RedeemGiftCardRequest is a gift card code to credit to the wallet
+type RedeemGiftCardRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// giftCardResponse formats a gift card. The code is only shown once the card is paid for.
+func giftCardResponse(f utils.ResponseFormatter, card models.GiftCard) gin.H {
+	response := gin.H{
+		"id":              card.ID,
+		"amount":          f.Money(card.Amount),
+		"status":          utils.GiftCardStatus(card),
+		"recipient_name":  card.RecipientName,
+		"recipient_email": card.RecipientEmail,
+		"message":         card.Message,
+		"payment_method":  card.PaymentMethod,
+		"expires_at":      f.Timestamp(card.ExpiresAt),
+		"created_at":      f.Timestamp(card.CreatedAt),
+	}
+	if card.Status != models.GiftCardStatusPending {
+		response["code"] = card.Code
+	}
+	if card.RedeemedAt != nil {
+		response["redeemed_at"] = f.Timestamp(*card.RedeemedAt)
+	}
+	return response
+}
+
+// newGiftCard builds an unsaved gift card with a fresh code and the configured validity
+func newGiftCard(amount float64, recipientEmail, recipientName, message string) (models.GiftCard, error) {
+	code, err := utils.GenerateGiftCardCode()
+	if err != nil {
+		return models.GiftCard{}, err
+	}
+	return models.GiftCard{
+		Code:           code,
+		Amount:         amount,
+		RecipientEmail: strings.TrimSpace(recipientEmail),
+		RecipientName:  strings.TrimSpace(recipientName),
+		Message:        strings.TrimSpace(message),
+		ExpiresAt:      time.Now().Add(utils.GiftCardValidity()),
+	}, nil
+}
+
+// sendGiftCard emails an active gift card to its recipient. A failed email is logged and
+// reported in the response; the purchaser can still pass the code on.
+func sendGiftCard(card models.GiftCard, from string) bool {
+	if err := utils.SendGiftCardEmail(card, from); err != nil {
+		utils.LogError("Failed to email gift card ID: %d to %s: %v", card.ID, card.RecipientEmail, err)
+		return false
+	}
+	utils.LogInfo("Emailed gift card ID: %d to %s", card.ID, card.RecipientEmail)
+	return true
+}
+
+// PurchaseGiftCard buys a gift card. Wallet purchases are paid and emailed at once; online
+// purchases return a Razorpay order to pay, and the card is emailed once the payment is verified.
+func PurchaseGiftCard(c *gin.Context) {
+	utils.LogInfo("PurchaseGiftCard called")
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var req PurchaseGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid gift card request for user ID: %d: %v", user.ID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	if maxAmount := utils.GiftCardMaxAmount(); req.Amount > maxAmount {
+		utils.LogError("Gift card amount %.2f above the maximum %.2f for user ID: %d", req.Amount, maxAmount, user.ID)
+		utils.BadRequest(c, fmt.Sprintf("A gift card can be worth at most %s", utils.FormatBaseMoney(maxAmount)), nil)
+		return
+	}
+
+	card, err := newGiftCard(req.Amount, req.RecipientEmail, req.RecipientName, req.Message)
+	if err != nil {
+		utils.LogError("Failed to generate gift card code: %v", err)
+		utils.InternalServerError(c, "Failed to create gift card", err.Error())
+		return
+	}
+	card.PurchaserID = &user.ID
+	card.PaymentMethod = req.PaymentMethod
+
+	formatter := utils.NewResponseFormatter(c)
+	if req.PaymentMethod == "wallet" {
+		tx := config.DB.Begin()
+		if tx.Error != nil {
+			utils.LogError("Failed to begin transaction: %v", tx.Error)
+			utils.InternalServerError(c, "Failed to start transaction", nil)
+			return
+		}
+		card.Status = models.GiftCardStatusActive
+		if err := tx.Create(&card).Error; err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to create gift card for user ID: %d: %v", user.ID, err)
+			utils.InternalServerError(c, "Failed to create gift card", err.Error())
+			return
+		}
+		_, err := utils.PostWalletEntry(tx, utils.WalletEntry{
+			UserID:      user.ID,
+			Amount:      card.Amount,
+			Type:        models.TransactionTypeDebit,
+			Description: fmt.Sprintf("Gift card for %s", card.RecipientEmail),
+			Reference:   fmt.Sprintf("GIFTCARD-PURCHASE-%d", card.ID),
+		})
+		if errors.Is(err, utils.ErrInsufficientWalletBalance) {
+			tx.Rollback()
+			utils.LogError("Insufficient wallet balance for gift card, user ID: %d", user.ID)
+			utils.Fail(c, utils.CodeWalletInsufficient, "Insufficient wallet balance. Please top up your wallet or pay online.", nil)
+			return
+		}
+		if err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to debit wallet for gift card, user ID: %d: %v", user.ID, err)
+			utils.InternalServerError(c, "Failed to pay for gift card", err.Error())
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			utils.LogError("Failed to commit gift card purchase for user ID: %d: %v", user.ID, err)
+			utils.InternalServerError(c, "Failed to commit transaction", err.Error())
+			return
+		}
+
+		emailed := sendGiftCard(card, user.Username)
+		utils.LogInfo("User ID: %d bought gift card ID: %d with the wallet", user.ID, card.ID)
+		utils.Success(c, "Gift card purchased successfully", gin.H{
+			"gift_card":  giftCardResponse(formatter, card),
+			"email_sent": emailed,
+		})
+		return
+	}
+
+	// Online payment: the card waits for the Razorpay payment
+	client := razorpay.NewClient(os.Getenv("RAZORPAY_KEY"), os.Getenv("RAZORPAY_SECRET"))
+	rzOrder, err := client.Order.Create(map[string]interface{}{
+		"amount":          int(card.Amount * 100),
+		"currency":        utils.BaseCurrency(),
+		"receipt":         "gift_card_" + strconv.FormatUint(uint64(user.ID), 10) + "_" + time.Now().Format("20060102150405"),
+		"payment_capture": 1,
+	}, nil)
+	if err != nil {
+		utils.LogError("Failed to create Razorpay order for gift card, user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to create Razorpay order", err.Error())
+		return
+	}
+	card.Status = models.GiftCardStatusPending
+	card.RazorpayOrderID = fmt.Sprintf("%v", rzOrder["id"])
+	if err := config.DB.Create(&card).Error; err != nil {
+		utils.LogError("Failed to create gift card for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to create gift card", err.Error())
+		return
+	}
+
+	utils.LogInfo("Initiated online gift card purchase ID: %d for user ID: %d", card.ID, user.ID)
+	utils.Success(c, "Gift card order created successfully", gin.H{
+		"gift_card": giftCardResponse(formatter, card),
+		"order": gin.H{
+			"gift_card_id":      card.ID,
+			"razorpay_order_id": card.RazorpayOrderID,
+			"amount":            formatter.Money(card.Amount),
+			"amount_display":    utils.FormatBaseMoney(card.Amount),
+			"payment_type":      "gift_card",
+		},
+		"key": os.Getenv("RAZORPAY_KEY"),
+		"user": gin.H{
+			"name":  user.Username,
+			"email": user.Email,
+		},
+	})
+}
+
+// VerifyGiftCardPayment checks the Razorpay payment of a gift card, activates it and emails it
+// to the recipient
+func VerifyGiftCardPayment(c *gin.Context) {
+	utils.LogInfo("VerifyGiftCardPayment called")
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var req VerifyGiftCardPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request for user ID: %d: %v", user.ID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+
+	var card models.GiftCard
+	if err := config.DB.Where("id = ? AND purchaser_id = ?", req.GiftCardID, user.ID).First(&card).Error; err != nil {
+		utils.LogError("Gift card ID: %d not found for user ID: %d: %v", req.GiftCardID, user.ID, err)
+		utils.Fail(c, utils.CodeGiftCardInvalid, "Gift card not found", nil)
+		return
+	}
+	if card.RazorpayOrderID != req.RazorpayOrderID {
+		utils.LogError("Razorpay order ID mismatch for gift card ID: %d", card.ID)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid Razorpay order ID", nil)
+		return
+	}
+	if card.Status != models.GiftCardStatusPending {
+		utils.LogError("Gift card ID: %d is not awaiting payment, status: %s", card.ID, card.Status)
+		utils.Fail(c, utils.CodePaymentCompleted, "Payment already completed for this gift card", nil)
+		return
+	}
+
+	// Verify signature
+	h := hmac.New(sha256.New, []byte(os.Getenv("RAZORPAY_SECRET")))
+	h.Write([]byte(req.RazorpayOrderID + "|" + req.RazorpayPaymentID))
+	if hex.EncodeToString(h.Sum(nil)) != req.RazorpaySignature {
+		utils.LogError("Payment verification failed for gift card ID: %d", card.ID)
+		utils.RecordPaymentFailed("gift_card")
+		utils.Fail(c, utils.CodePaymentFailed, "Payment verification failed", gin.H{"retry": true})
+		return
+	}
+
+	// The card starts its validity once paid for
+	expiresAt := time.Now().Add(utils.GiftCardValidity())
+	result := config.DB.Model(&models.GiftCard{}).
+		Where("id = ? AND status = ?", card.ID, models.GiftCardStatusPending).
+		Updates(map[string]interface{}{
+			"status":              models.GiftCardStatusActive,
+			"razorpay_payment_id": req.RazorpayPaymentID,
+			"expires_at":          expiresAt,
+		})
+	if result.Error != nil {
+		utils.LogError("Failed to activate gift card ID: %d: %v", card.ID, result.Error)
+		utils.InternalServerError(c, "Failed to activate gift card", result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.LogError("Gift card ID: %d changed status before payment was recorded", card.ID)
+		utils.Fail(c, utils.CodePaymentCompleted, "Payment already completed for this gift card", nil)
+		return
+	}
+	card.Status = models.GiftCardStatusActive
+	card.RazorpayPaymentID = req.RazorpayPaymentID
+	card.ExpiresAt = expiresAt
+
+	emailed := sendGiftCard(card, user.Username)
+	utils.LogInfo("Gift card ID: %d paid online by user ID: %d", card.ID, user.ID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Gift card purchased successfully", gin.H{
+		"gift_card":  giftCardResponse(formatter, card),
+		"email_sent": emailed,
+	})
+}
+
+// RedeemGiftCard credits a gift card to the user's wallet
+func RedeemGiftCard(c *gin.Context) {
+	utils.LogInfo("RedeemGiftCard called")
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var req RedeemGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request for user ID: %d: %v", user.ID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Gift card code is required", err)
+		return
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to begin transaction: %v", tx.Error)
+		utils.InternalServerError(c, "Failed to start transaction", nil)
+		return
+	}
+	card, transaction, err := utils.RedeemGiftCard(tx, user.ID, req.Code)
+	if err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to redeem gift card for user ID: %d: %v", user.ID, err)
+		switch {
+		case errors.Is(err, utils.ErrGiftCardNotFound):
+			utils.Fail(c, utils.CodeGiftCardInvalid, "Invalid gift card code", nil)
+		case errors.Is(err, utils.ErrGiftCardRedeemed):
+			utils.Fail(c, utils.CodeGiftCardUnavailable, "This gift card has already been redeemed", nil)
+		case errors.Is(err, utils.ErrGiftCardExpired):
+			utils.Fail(c, utils.CodeGiftCardUnavailable, fmt.Sprintf("This gift card expired on %s", card.ExpiresAt.Format("2006-01-02")), nil)
+		case errors.Is(err, utils.ErrGiftCardVoided):
+			utils.Fail(c, utils.CodeGiftCardUnavailable, "This gift card is no longer valid", nil)
+		case errors.Is(err, utils.ErrGiftCardPending):
+			utils.Fail(c, utils.CodeGiftCardUnavailable, "This gift card has not been paid for yet", nil)
+		default:
+			utils.InternalServerError(c, "Failed to redeem gift card", err.Error())
+		}
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit gift card redemption for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to commit transaction", err.Error())
+		return
+	}
+
+	utils.LogInfo("User ID: %d redeemed gift card ID: %d for %.2f", user.ID, card.ID, card.Amount)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, fmt.Sprintf("%s added to your wallet", utils.FormatBaseMoney(card.Amount)), gin.H{
+		"gift_card": gin.H{
+			"id":          card.ID,
+			"amount":      formatter.Money(card.Amount),
+			"status":      card.Status,
+			"redeemed_at": formatter.Timestamp(*card.RedeemedAt),
+		},
+		"wallet": gin.H{
+			"balance":   formatter.Money(transaction.Wallet.Balance),
+			"available": formatter.Money(transaction.Wallet.Balance - transaction.Wallet.Held),
+		},
+	})
+}
+
+// ListMyGiftCards lists the gift cards the user bought and the ones they redeemed
+func ListMyGiftCards(c *gin.Context) {
+	utils.LogInfo("ListMyGiftCards called")
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var purchased, redeemed []models.GiftCard
+	if err := config.DB.Where("purchaser_id = ?", user.ID).Order("created_at DESC").Find(&purchased).Error; err != nil {
+		utils.LogError("Failed to fetch purchased gift cards for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch gift cards", err.Error())
+		return
+	}
+	if err := config.DB.Where("redeemed_by = ?", user.ID).Order("redeemed_at DESC").Find(&redeemed).Error; err != nil {
+		utils.LogError("Failed to fetch redeemed gift cards for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch gift cards", err.Error())
+		return
+	}
+
+	purchasedList := make([]gin.H, len(purchased))
+	formatter := utils.NewResponseFormatter(c)
+	for i, card := range purchased {
+		purchasedList[i] = giftCardResponse(formatter, card)
+	}
+	redeemedList := make([]gin.H, len(redeemed))
+	for i, card := range redeemed {
+		redeemedList[i] = gin.H{
+			"id":          card.ID,
+			"amount":      formatter.Money(card.Amount),
+			"redeemed_at": formatter.Timestamp(*card.RedeemedAt),
+		}
+	}
+
+	utils.LogInfo("Retrieved %d purchased and %d redeemed gift cards for user ID: %d", len(purchased), len(redeemed), user.ID)
+	utils.Success(c, "Gift cards retrieved successfully", gin.H{
+		"purchased": purchasedList,
+		"redeemed":  redeemedList,
+	})
+}
@@ -0,0 +1,9 @@
+package controllers
+
+// Handlers are the handlers whose services are injected when the router is set up, rather
+// than reaching for config.DB themselves
+type Handlers struct {
+	Books  *BookHandler
+	Orders *OrderHandler
+	Wallet *WalletHandler
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// readinessCheckTimeout bounds each dependency check so a hung dependency fails the probe
+// instead of hanging it
+const readinessCheckTimeout = 2 * time.Second
+
+// shuttingDown makes the readiness probe fail while the server drains, so load balancers
+// stop sending new requests before it stops
+var shuttingDown atomic.Bool
+
+// MarkShuttingDown reports the instance as not ready from now on
+func MarkShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// Healthz is the liveness probe: the process is up and serving requests
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz is the readiness probe. It checks the database, Redis when REDIS_URL is set and the
+// Razorpay credentials, and answers 503 when any of them is not usable.
+func Readyz(c *gin.Context) {
+	checks := gin.H{}
+	ready := true
+	fail := func(name, reason string) {
+		checks[name] = gin.H{"status": "fail", "error": reason}
+		ready = false
+	}
+
+	if shuttingDown.Load() {
+		fail("server", "shutting down")
+	}
+
+	start := time.Now()
+	if config.DB == nil {
+		fail("database", "not connected")
+	} else if sqlDB, err := config.DB.DB(); err != nil {
+		fail("database", err.Error())
+	} else {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readinessCheckTimeout)
+		err := sqlDB.PingContext(ctx)
+		cancel()
+		if err != nil {
+			fail("database", err.Error())
+		} else {
+			checks["database"] = gin.H{"status": "ok", "latency_ms": time.Since(start).Milliseconds()}
+		}
+	}
+
+	if os.Getenv("REDIS_URL") != "" {
+		start = time.Now()
+		if err := pingRedis(); err != nil {
+			fail("redis", err.Error())
+		} else {
+			checks["redis"] = gin.H{"status": "ok", "latency_ms": time.Since(start).Milliseconds()}
+		}
+	}
+
+	if os.Getenv("RAZORPAY_KEY") == "" || os.Getenv("RAZORPAY_SECRET") == "" {
+		fail("razorpay", "RAZORPAY_KEY or RAZORPAY_SECRET is not set")
+	} else {
+		checks["razorpay"] = gin.H{"status": "ok"}
+	}
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not_ready", http.StatusServiceUnavailable
+		utils.LogError("Readiness check failed: %v", checks)
+	}
+	c.JSON(code, gin.H{"status": status, "checks": checks})
+}
+
+// pingRedis pings Redis, giving up after readinessCheckTimeout
+func pingRedis() error {
+	client, err := utils.GetRedis()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), readinessCheckTimeout)
+	defer cancel()
+	return client.Ping(ctx).Err()
+}
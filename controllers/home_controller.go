@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetHome bundles everything the storefront home page shows: the curated sections that are
+// live right now, new arrivals and top-rated books. Each list holds at most limit books.
+func GetHome(c *gin.Context) {
+	utils.LogInfo("GetHome called")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 30 {
+		limit = 10
+	}
+	now := time.Now()
+	visibleBooks := func(db *gorm.DB) *gorm.DB {
+		return db.Where("books.is_active = ? AND books.blocked = ? AND books.deleted_at IS NULL", true, false)
+	}
+
+	var sections []models.HomeSection
+	if err := config.DB.
+		Where("is_active = ?", true).
+		Where("(starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)", now, now).
+		Order("position, id").
+		Find(&sections).Error; err != nil {
+		utils.LogError("Failed to fetch home sections: %v", err)
+		utils.InternalServerError(c, "Failed to load home page", nil)
+		return
+	}
+
+	sectionBooks := make(map[uint][]models.Book, len(sections))
+	var bookIDs []uint
+	for _, section := range sections {
+		var books []models.Book
+		if err := config.DB.Scopes(visibleBooks).
+			Joins("JOIN home_section_items ON home_section_items.book_id = books.id").
+			Where("home_section_items.section_id = ?", section.ID).
+			Order("home_section_items.position").
+			Limit(limit).
+			Find(&books).Error; err != nil {
+			utils.LogError("Failed to fetch books of home section %d: %v", section.ID, err)
+			utils.InternalServerError(c, "Failed to load home page", nil)
+			return
+		}
+		sectionBooks[section.ID] = books
+		for _, book := range books {
+			bookIDs = append(bookIDs, book.ID)
+		}
+	}
+
+	var newArrivals []models.Book
+	if err := config.DB.Scopes(visibleBooks).
+		Where("books.created_at >= ?", now.AddDate(0, 0, -30)).
+		Order("books.created_at DESC").
+		Limit(limit).
+		Find(&newArrivals).Error; err != nil {
+		utils.LogError("Failed to fetch new arrivals: %v", err)
+		utils.InternalServerError(c, "Failed to load home page", nil)
+		return
+	}
+
+	var topRated []models.Book
+	if err := config.DB.Scopes(visibleBooks).
+		Where("books.total_reviews > 0").
+		Order("books.average_rating DESC, books.total_reviews DESC").
+		Limit(limit).
+		Find(&topRated).Error; err != nil {
+		utils.LogError("Failed to fetch top rated books: %v", err)
+		utils.InternalServerError(c, "Failed to load home page", nil)
+		return
+	}
+
+	for _, book := range append(newArrivals, topRated...) {
+		bookIDs = append(bookIDs, book.ID)
+	}
+	translations := loadTranslations(models.TranslationEntityBook, bookIDs, resolveCatalogLocale(c))
+	formatter := utils.NewResponseFormatter(c)
+	cards := func(books []models.Book) []gin.H {
+		result := make([]gin.H, 0, len(books))
+		for _, book := range books {
+			name, _ := translateText(translations, book.ID, book.Name, "")
+			result = append(result, gin.H{
+				"id":             book.ID,
+				"name":           name,
+				"author":         book.Author,
+				"price":          formatter.Money(book.Price),
+				"original_price": formatter.Money(book.OriginalPrice),
+				"image_url":      book.ImageURL,
+				"in_stock":       book.Stock > 0,
+				"average_rating": book.AverageRating,
+				"total_reviews":  book.TotalReviews,
+			})
+		}
+		return result
+	}
+
+	// Sections with no visible books are left out
+	sectionResponse := make([]gin.H, 0, len(sections))
+	for _, section := range sections {
+		books := sectionBooks[section.ID]
+		if len(books) == 0 {
+			continue
+		}
+		sectionResponse = append(sectionResponse, gin.H{
+			"title":       section.Title,
+			"slug":        section.Slug,
+			"description": section.Description,
+			"books":       cards(books),
+		})
+	}
+
+	utils.LogInfo("Home page built with %d sections, %d new arrivals, %d top rated", len(sectionResponse), len(newArrivals), len(topRated))
+	utils.Success(c, "Home page retrieved successfully", gin.H{
+		"sections":     sectionResponse,
+		"new_arrivals": cards(newArrivals),
+		"top_rated":    cards(topRated),
+	})
+}
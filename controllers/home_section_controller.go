@@ -0,0 +1,345 @@
+package controllers
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HomeSectionRequest represents the home section create/update request. BookIDs sets the
+// section's books in display order; leave it out on update to keep the current books.
+type HomeSectionRequest struct {
+	Title       string     `json:"title" binding:"required,min=2,max=100"`
+	Slug        string     `json:"slug"`
+	Description string     `json:"description" binding:"max=500"`
+	Position    int        `json:"position"`
+	IsActive    *bool      `json:"is_active"`
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	BookIDs     []uint     `json:"book_ids"`
+}
+
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify turns a title into a URL-safe identifier, e.g. "Editor's Picks" -> "editor-s-picks"
+func slugify(s string) string {
+	return strings.Trim(slugInvalidChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// AdminListHomeSections lists every home section with its books, in display order
+func AdminListHomeSections(c *gin.Context) {
+	utils.LogInfo("AdminListHomeSections called")
+
+	var sections []models.HomeSection
+	if err := config.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Preload("Items.Book").Order("position, id").Find(&sections).Error; err != nil {
+		utils.LogError("Failed to fetch home sections: %v", err)
+		utils.InternalServerError(c, "Failed to fetch home sections", nil)
+		return
+	}
+
+	response := make([]gin.H, 0, len(sections))
+	formatter := utils.NewResponseFormatter(c)
+	for _, section := range sections {
+		response = append(response, homeSectionAdminResponse(formatter, section))
+	}
+
+	utils.LogInfo("Retrieved %d home sections", len(response))
+	utils.Success(c, "Home sections retrieved successfully", gin.H{
+		"sections": response,
+	})
+}
+
+// AdminCreateHomeSection creates a home section and assigns its books
+func AdminCreateHomeSection(c *gin.Context) {
+	utils.LogInfo("AdminCreateHomeSection called")
+
+	var req HomeSectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid home section request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+
+	section := models.HomeSection{IsActive: true}
+	if !applyHomeSectionRequest(c, &section, req) {
+		return
+	}
+
+	tx := config.DB.Begin()
+	if err := tx.Create(&section).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to create home section: %v", err)
+		utils.InternalServerError(c, "Failed to create home section", nil)
+		return
+	}
+	if err := setHomeSectionBooks(tx, section.ID, req.BookIDs); err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to assign books to home section %d: %v", section.ID, err)
+		utils.InternalServerError(c, "Failed to assign books", nil)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit home section: %v", err)
+		utils.InternalServerError(c, "Failed to create home section", nil)
+		return
+	}
+
+	utils.LogInfo("Created home section %s (ID: %d) with %d books", section.Slug, section.ID, len(req.BookIDs))
+	respondWithHomeSection(c, section.ID, "Home section created successfully", true)
+}
+
+// AdminUpdateHomeSection updates a home section, and its books when book_ids is given
+func AdminUpdateHomeSection(c *gin.Context) {
+	utils.LogInfo("AdminUpdateHomeSection called")
+
+	var section models.HomeSection
+	if err := config.DB.First(&section, c.Param("id")).Error; err != nil {
+		utils.LogError("Home section not found: %s", c.Param("id"))
+		utils.NotFound(c, "Home section not found")
+		return
+	}
+
+	var req HomeSectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid home section request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	if !applyHomeSectionRequest(c, &section, req) {
+		return
+	}
+
+	tx := config.DB.Begin()
+	if err := tx.Save(&section).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to update home section %d: %v", section.ID, err)
+		utils.InternalServerError(c, "Failed to update home section", nil)
+		return
+	}
+	if req.BookIDs != nil {
+		if err := setHomeSectionBooks(tx, section.ID, req.BookIDs); err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to assign books to home section %d: %v", section.ID, err)
+			utils.InternalServerError(c, "Failed to assign books", nil)
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit home section %d: %v", section.ID, err)
+		utils.InternalServerError(c, "Failed to update home section", nil)
+		return
+	}
+
+	utils.LogInfo("Updated home section %s (ID: %d)", section.Slug, section.ID)
+	respondWithHomeSection(c, section.ID, "Home section updated successfully", false)
+}
+
+// AdminSetHomeSectionBooks replaces the books of a home section; their order is the display order
+func AdminSetHomeSectionBooks(c *gin.Context) {
+	utils.LogInfo("AdminSetHomeSectionBooks called")
+
+	var section models.HomeSection
+	if err := config.DB.First(&section, c.Param("id")).Error; err != nil {
+		utils.LogError("Home section not found: %s", c.Param("id"))
+		utils.NotFound(c, "Home section not found")
+		return
+	}
+
+	var req struct {
+		BookIDs []uint `json:"book_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid home section books request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	if !validateHomeSectionBooks(c, req.BookIDs) {
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return setHomeSectionBooks(tx, section.ID, req.BookIDs)
+	}); err != nil {
+		utils.LogError("Failed to assign books to home section %d: %v", section.ID, err)
+		utils.InternalServerError(c, "Failed to assign books", nil)
+		return
+	}
+
+	utils.LogInfo("Assigned %d books to home section %d", len(req.BookIDs), section.ID)
+	respondWithHomeSection(c, section.ID, "Home section books updated successfully", false)
+}
+
+// AdminDeleteHomeSection deletes a home section and its book assignments
+func AdminDeleteHomeSection(c *gin.Context) {
+	utils.LogInfo("AdminDeleteHomeSection called")
+
+	var section models.HomeSection
+	if err := config.DB.First(&section, c.Param("id")).Error; err != nil {
+		utils.LogError("Home section not found: %s", c.Param("id"))
+		utils.NotFound(c, "Home section not found")
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("section_id = ?", section.ID).Delete(&models.HomeSectionItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&section).Error
+	}); err != nil {
+		utils.LogError("Failed to delete home section %d: %v", section.ID, err)
+		utils.InternalServerError(c, "Failed to delete home section", nil)
+		return
+	}
+
+	utils.LogInfo("Deleted home section %s (ID: %d)", section.Slug, section.ID)
+	utils.Success(c, "Home section deleted successfully", nil)
+}
+
+// applyHomeSectionRequest validates the request and copies it onto the section. It writes
+// the error response and returns false when the request is invalid.
+func applyHomeSectionRequest(c *gin.Context, section *models.HomeSection, req HomeSectionRequest) bool {
+	slug := slugify(req.Slug)
+	if slug == "" {
+		slug = slugify(req.Title)
+	}
+	if slug == "" {
+		utils.BadRequest(c, "Invalid slug", "Slug must contain letters or digits")
+		return false
+	}
+	var count int64
+	config.DB.Model(&models.HomeSection{}).Where("slug = ? AND id <> ?", slug, section.ID).Count(&count)
+	if count > 0 {
+		utils.LogError("Home section slug already in use: %s", slug)
+		utils.Fail(c, utils.CodeAlreadyExists, "Slug already in use", gin.H{"slug": slug})
+		return false
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		utils.BadRequest(c, "Invalid schedule", "ends_at must be after starts_at")
+		return false
+	}
+	if req.BookIDs != nil && !validateHomeSectionBooks(c, req.BookIDs) {
+		return false
+	}
+
+	section.Title = strings.TrimSpace(req.Title)
+	section.Slug = slug
+	section.Description = req.Description
+	section.Position = req.Position
+	if req.IsActive != nil {
+		section.IsActive = *req.IsActive
+	}
+	section.StartsAt = req.StartsAt
+	section.EndsAt = req.EndsAt
+	return true
+}
+
+// validateHomeSectionBooks checks that every book exists and none is listed twice
+func validateHomeSectionBooks(c *gin.Context, bookIDs []uint) bool {
+	seen := make(map[uint]bool, len(bookIDs))
+	for _, id := range bookIDs {
+		if seen[id] {
+			utils.BadRequest(c, "Duplicate book", gin.H{"book_id": id})
+			return false
+		}
+		seen[id] = true
+	}
+	if len(bookIDs) == 0 {
+		return true
+	}
+
+	var found []uint
+	if err := config.DB.Model(&models.Book{}).Where("id IN ?", bookIDs).Pluck("id", &found).Error; err != nil {
+		utils.LogError("Failed to verify home section books: %v", err)
+		utils.InternalServerError(c, "Failed to verify books", nil)
+		return false
+	}
+	if len(found) != len(bookIDs) {
+		existing := make(map[uint]bool, len(found))
+		for _, id := range found {
+			existing[id] = true
+		}
+		var missing []uint
+		for _, id := range bookIDs {
+			if !existing[id] {
+				missing = append(missing, id)
+			}
+		}
+		utils.BadRequest(c, "Books not found", gin.H{"book_ids": missing})
+		return false
+	}
+	return true
+}
+
+// setHomeSectionBooks replaces the section's books, positioned in the given order
+func setHomeSectionBooks(tx *gorm.DB, sectionID uint, bookIDs []uint) error {
+	if err := tx.Where("section_id = ?", sectionID).Delete(&models.HomeSectionItem{}).Error; err != nil {
+		return err
+	}
+	if len(bookIDs) == 0 {
+		return nil
+	}
+	items := make([]models.HomeSectionItem, len(bookIDs))
+	for i, bookID := range bookIDs {
+		items[i] = models.HomeSectionItem{SectionID: sectionID, BookID: bookID, Position: i + 1}
+	}
+	return tx.Create(&items).Error
+}
+
+// respondWithHomeSection reloads the section with its books and writes it as the response
+func respondWithHomeSection(c *gin.Context, sectionID uint, message string, created bool) {
+	var section models.HomeSection
+	if err := config.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Preload("Items.Book").First(&section, sectionID).Error; err != nil {
+		utils.LogError("Failed to reload home section %d: %v", sectionID, err)
+		utils.InternalServerError(c, "Home section saved but failed to fetch details", nil)
+		return
+	}
+	formatter := utils.NewResponseFormatter(c)
+	data := gin.H{"section": homeSectionAdminResponse(formatter, section)}
+	if created {
+		utils.Created(c, message, data)
+		return
+	}
+	utils.Success(c, message, data)
+}
+
+// homeSectionAdminResponse is the admin view of a section, including books that are
+// currently hidden from the storefront
+func homeSectionAdminResponse(f utils.ResponseFormatter, section models.HomeSection) gin.H {
+	books := make([]gin.H, 0, len(section.Items))
+	for _, item := range section.Items {
+		books = append(books, gin.H{
+			"book_id":  item.BookID,
+			"name":     item.Book.Name,
+			"position": item.Position,
+			"visible":  item.Book.ID != 0 && item.Book.IsActive && !item.Book.Blocked,
+		})
+	}
+	response := gin.H{
+		"id":          section.ID,
+		"title":       section.Title,
+		"slug":        section.Slug,
+		"description": section.Description,
+		"position":    section.Position,
+		"is_active":   section.IsActive,
+		"books":       books,
+		"created_at":  f.Timestamp(section.CreatedAt),
+		"updated_at":  f.Timestamp(section.UpdatedAt),
+	}
+	if section.StartsAt != nil {
+		response["starts_at"] = f.Timestamp(*section.StartsAt)
+	}
+	if section.EndsAt != nil {
+		response["ends_at"] = f.Timestamp(*section.EndsAt)
+	}
+	return response
+}
@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// invoiceUnavailableReason explains why no invoice can be issued for the order yet,
+// or returns "" when it can
+func invoiceUnavailableReason(order models.Order) string {
+	if order.Status == models.OrderStatusCancelled {
+		return "Cancelled orders have no invoice"
+	}
+	awaitingOnlinePayment := order.PaymentMethod == "" ||
+		((order.PaymentMethod == "RAZORPAY" || order.PaymentMethod == "online") && order.PaymentStatus != models.PaymentStatusCompleted)
+	if order.Status == models.OrderStatusPlaced && awaitingOnlinePayment {
+		return "The invoice is issued once the payment is completed"
+	}
+	return ""
+}
+
+// getOrIssueInvoice returns the order's invoice, issuing it with the next number of the
+// current financial year on first use. The order must have its items, books and address loaded.
+func getOrIssueInvoice(order models.Order) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := config.DB.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("order_id = ?", order.ID).First(&invoice).Error
+	if err == nil {
+		return &invoice, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	seller := utils.GetSellerTaxDetails()
+	now := time.Now()
+	invoice = models.Invoice{
+		OrderID:         order.ID,
+		FinancialYear:   utils.FinancialYear(now),
+		IssuedAt:        now,
+		SellerName:      seller.Name,
+		SellerGSTIN:     seller.GSTIN,
+		SellerAddress:   seller.Address,
+		SellerState:     seller.State,
+		SellerStateCode: seller.StateCode,
+		PlaceOfSupply:   order.Address.State,
+		InterState:      seller.IsInterState(order.Address.State),
+	}
+	invoice.Items = buildInvoiceItems(order, seller, invoice.InterState)
+	for _, item := range invoice.Items {
+		invoice.TaxableAmount += item.TaxableValue
+		invoice.CGSTAmount += item.CGSTAmount
+		invoice.SGSTAmount += item.SGSTAmount
+		invoice.IGSTAmount += item.IGSTAmount
+		invoice.TotalTax += item.TaxAmount
+		invoice.GrandTotal += item.Total
+	}
+	for _, amount := range []*float64{&invoice.TaxableAmount, &invoice.CGSTAmount, &invoice.SGSTAmount,
+		&invoice.IGSTAmount, &invoice.TotalTax, &invoice.GrandTotal} {
+		*amount = math.Round(*amount*100) / 100
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// The number is only consumed when the invoice is saved, so numbering has no gaps
+		var sequence int
+		if err := tx.Raw(`INSERT INTO invoice_sequences (financial_year, last_number) VALUES (?, 1)
+			ON CONFLICT (financial_year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+			RETURNING last_number`, invoice.FinancialYear).Scan(&sequence).Error; err != nil {
+			return err
+		}
+		invoice.Sequence = sequence
+		invoice.InvoiceNumber = utils.FormatInvoiceNumber(seller.InvoicePrefix, invoice.FinancialYear, sequence)
+		return tx.Create(&invoice).Error
+	})
+	if err != nil {
+		// Another request may have issued the invoice at the same time
+		var existing models.Invoice
+		if findErr := config.DB.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+			Where("order_id = ?", order.ID).First(&existing).Error; findErr == nil {
+			return &existing, nil
+		}
+		return nil, err
+	}
+
+	utils.LogInfo("Issued invoice %s for order ID: %d", invoice.InvoiceNumber, order.ID)
+	return &invoice, nil
+}
+
+// buildInvoiceItems turns the order's books and charges into taxed invoice lines. Order amounts
+// include tax, so the tax is extracted from each line rather than added on top.
+func buildInvoiceItems(order models.Order, seller utils.SellerTaxDetails, interState bool) []models.InvoiceItem {
+	items := make([]models.InvoiceItem, 0, len(order.OrderItems)+2)
+	for _, orderItem := range order.OrderItems {
+		amount := orderItem.Total - orderItem.CouponDiscount
+		if amount < 0 {
+			amount = 0
+		}
+		rate := seller.DefaultRate
+		if orderItem.Book.TaxRate != nil {
+			rate = *orderItem.Book.TaxRate
+		}
+		hsn := orderItem.Book.HSNCode
+		if hsn == "" {
+			hsn = utils.DefaultBookHSNCode
+		}
+		orderItemID := orderItem.ID
+		items = append(items, newInvoiceItem(&orderItemID, orderItem.Book.Name, hsn, orderItem.Quantity,
+			orderItem.Price, orderItem.Discount+orderItem.CouponDiscount, amount, rate, interState))
+	}
+	if order.DeliveryCharge != 0 {
+		items = append(items, newInvoiceItem(nil, "Delivery charges", utils.DeliveryServiceCode, 1,
+			order.DeliveryCharge, 0, order.DeliveryCharge, seller.ServiceRate, interState))
+	}
+	if order.GiftWrapFee != 0 {
+		items = append(items, newInvoiceItem(nil, "Gift wrapping", utils.DeliveryServiceCode, 1,
+			order.GiftWrapFee, 0, order.GiftWrapFee, seller.ServiceRate, interState))
+	}
+	if order.PaymentAdjustment != 0 {
+		description := "Payment method fee"
+		if order.PaymentAdjustment < 0 {
+			description = "Payment method discount"
+		}
+		items = append(items, newInvoiceItem(nil, description, utils.DeliveryServiceCode, 1,
+			order.PaymentAdjustment, 0, order.PaymentAdjustment, seller.ServiceRate, interState))
+	}
+	return items
+}
+
+func newInvoiceItem(orderItemID *uint, description, hsn string, quantity int, unitPrice, discount, amount, rate float64, interState bool) models.InvoiceItem {
+	split := utils.SplitInclusiveTax(amount, rate, interState)
+	return models.InvoiceItem{
+		OrderItemID:  orderItemID,
+		Description:  description,
+		HSNCode:      hsn,
+		Quantity:     quantity,
+		UnitPrice:    unitPrice,
+		Discount:     discount,
+		TaxableValue: split.TaxableValue,
+		TaxRate:      rate,
+		CGSTAmount:   split.CGST,
+		SGSTAmount:   split.SGST,
+		IGSTAmount:   split.IGST,
+		TaxAmount:    split.Tax,
+		Total:        amount,
+	}
+}
+
+// invoiceResponse is the JSON representation of an invoice
+func invoiceResponse(f utils.ResponseFormatter, invoice *models.Invoice, order models.Order) gin.H {
+	items := make([]gin.H, len(invoice.Items))
+	for i, item := range invoice.Items {
+		items[i] = gin.H{
+			"order_item_id": item.OrderItemID,
+			"description":   item.Description,
+			"hsn_code":      item.HSNCode,
+			"quantity":      item.Quantity,
+			"unit_price":    f.Money(item.UnitPrice),
+			"discount":      f.Money(item.Discount),
+			"taxable_value": f.Money(item.TaxableValue),
+			"tax_rate":      item.TaxRate,
+			"cgst_amount":   f.Money(item.CGSTAmount),
+			"sgst_amount":   f.Money(item.SGSTAmount),
+			"igst_amount":   f.Money(item.IGSTAmount),
+			"tax_amount":    f.Money(item.TaxAmount),
+			"total":         f.Money(item.Total),
+		}
+	}
+
+	billingAddress := strings.Join(nonEmpty(order.Address.Line1, order.Address.Line2, order.Address.City,
+		order.Address.State, order.Address.Country, order.Address.PostalCode), ", ")
+
+	return gin.H{
+		"invoice_number": invoice.InvoiceNumber,
+		"financial_year": invoice.FinancialYear,
+		"issued_at":      f.Timestamp(invoice.IssuedAt),
+		"order_id":       order.ID,
+		"order_date":     f.Timestamp(order.CreatedAt),
+		"payment_method": order.PaymentMethod,
+		"seller": gin.H{
+			"name":       invoice.SellerName,
+			"gstin":      invoice.SellerGSTIN,
+			"address":    invoice.SellerAddress,
+			"state":      invoice.SellerState,
+			"state_code": invoice.SellerStateCode,
+		},
+		"buyer": gin.H{
+			"name":    strings.TrimSpace(order.User.FirstName + " " + order.User.LastName),
+			"email":   order.User.Email,
+			"phone":   order.User.Phone,
+			"address": billingAddress,
+		},
+		"place_of_supply": invoice.PlaceOfSupply,
+		"inter_state":     invoice.InterState,
+		"items":           items,
+		"tax_summary": gin.H{
+			"taxable_amount": f.Money(invoice.TaxableAmount),
+			"cgst_amount":    f.Money(invoice.CGSTAmount),
+			"sgst_amount":    f.Money(invoice.SGSTAmount),
+			"igst_amount":    f.Money(invoice.IGSTAmount),
+			"total_tax":      f.Money(invoice.TotalTax),
+		},
+		"grand_total": f.Money(invoice.GrandTotal),
+	}
+}
+
+func nonEmpty(values ...string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// formatTaxRate renders a tax rate for the PDF, e.g. "5%" or "12.5%"
+func formatTaxRate(rate float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", rate), "0"), ".") + "%"
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotifications lists the user's notifications, newest first, with the unread count
+func GetNotifications(c *gin.Context) {
+	utils.LogInfo("GetNotifications called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	page, limit := utils.GetPaginationParams(c)
+	query := config.DB.Model(&models.Notification{}).Where("user_id = ?", user.ID)
+	if c.Query("unread_only") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	if notificationType := c.Query("type"); notificationType != "" {
+		query = query.Where("type = ?", notificationType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count notifications for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch notifications", err.Error())
+		return
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).
+		Find(&notifications).Error; err != nil {
+		utils.LogError("Failed to fetch notifications for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch notifications", err.Error())
+		return
+	}
+
+	unread, err := unreadNotificationCount(user.ID)
+	if err != nil {
+		utils.LogError("Failed to count unread notifications for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch notifications", err.Error())
+		return
+	}
+
+	utils.LogInfo("Retrieved %d notifications for user %d", len(notifications), user.ID)
+	utils.SuccessWithPagination(c, "Notifications retrieved successfully", gin.H{
+		"notifications": notifications,
+		"unread_count":  unread,
+	}, total, page, limit)
+}
+
+// GetUnreadNotificationCount returns how many of the user's notifications are unread
+func GetUnreadNotificationCount(c *gin.Context) {
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	unread, err := unreadNotificationCount(user.ID)
+	if err != nil {
+		utils.LogError("Failed to count unread notifications for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to count notifications", err.Error())
+		return
+	}
+	utils.Success(c, "Unread notifications counted", gin.H{
+		"unread_count": unread,
+	})
+}
+
+// MarkNotificationRead marks one of the user's notifications as read
+func MarkNotificationRead(c *gin.Context) {
+	utils.LogInfo("MarkNotificationRead called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	notificationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid notification ID: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeInvalidID, "Invalid notification ID", nil)
+		return
+	}
+
+	var notification models.Notification
+	if err := config.DB.Where("id = ? AND user_id = ?", notificationID, user.ID).First(&notification).Error; err != nil {
+		utils.LogError("Notification %d not found for user %d", notificationID, user.ID)
+		utils.Fail(c, utils.CodeNotFound, "Notification not found", nil)
+		return
+	}
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := config.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			utils.LogError("Failed to mark notification %d as read: %v", notification.ID, err)
+			utils.InternalServerError(c, "Failed to update notification", err.Error())
+			return
+		}
+		notification.ReadAt = &now
+	}
+
+	unread, err := unreadNotificationCount(user.ID)
+	if err != nil {
+		utils.LogError("Failed to count unread notifications for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to count notifications", err.Error())
+		return
+	}
+	utils.Success(c, "Notification marked as read", gin.H{
+		"notification": notification,
+		"unread_count": unread,
+	})
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user as read
+func MarkAllNotificationsRead(c *gin.Context) {
+	utils.LogInfo("MarkAllNotificationsRead called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	result := config.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", user.ID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		utils.LogError("Failed to mark notifications read for user %d: %v", user.ID, result.Error)
+		utils.InternalServerError(c, "Failed to update notifications", result.Error.Error())
+		return
+	}
+
+	utils.LogInfo("Marked %d notifications read for user %d", result.RowsAffected, user.ID)
+	utils.Success(c, "Notifications marked as read", gin.H{
+		"marked":       result.RowsAffected,
+		"unread_count": 0,
+	})
+}
+
+// unreadNotificationCount counts the user's unread notifications
+func unreadNotificationCount(userID uint) (int64, error) {
+	var count int64
+	err := config.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
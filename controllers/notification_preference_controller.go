@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationPreferenceRequest turns notifications and emails on or off; left out kinds keep
+// their current setting
+type NotificationPreferenceRequest struct {
+	OrderUpdates *bool `json:"order_updates"`
+	Wallet       *bool `json:"wallet"`
+	Promotions   *bool `json:"promotions"`
+	NewArrivals  *bool `json:"new_arrivals"`
+	PriceDrops   *bool `json:"price_drops"`
+}
+
+// GetNotificationPreferences returns which notifications and emails the user receives
+func GetNotificationPreferences(c *gin.Context) {
+	utils.LogInfo("GetNotificationPreferences called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	preference, err := utils.NotificationPreferenceFor(config.DB, user.ID)
+	if err != nil {
+		utils.LogError("Failed to load notification preferences for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to load notification preferences", err.Error())
+		return
+	}
+
+	utils.Success(c, "Notification preferences retrieved successfully", gin.H{
+		"preferences":       preference,
+		"marketing_consent": utils.HasConsent(user.ID, models.ConsentPurposeMarketing),
+	})
+}
+
+// UpdateNotificationPreferences opts the user in or out of notifications and emails
+func UpdateNotificationPreferences(c *gin.Context) {
+	utils.LogInfo("UpdateNotificationPreferences called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var req NotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid input: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err.Error())
+		return
+	}
+
+	preference, err := utils.NotificationPreferenceFor(config.DB, user.ID)
+	if err != nil {
+		utils.LogError("Failed to load notification preferences for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to update notification preferences", err.Error())
+		return
+	}
+	if req.OrderUpdates != nil {
+		preference.OrderUpdates = *req.OrderUpdates
+	}
+	if req.Wallet != nil {
+		preference.Wallet = *req.Wallet
+	}
+	if req.Promotions != nil {
+		preference.Promotions = *req.Promotions
+	}
+	if req.NewArrivals != nil {
+		preference.NewArrivals = *req.NewArrivals
+	}
+	if req.PriceDrops != nil {
+		preference.PriceDrops = *req.PriceDrops
+	}
+	// The columns are updated from a map so that turning an alert off is saved despite the
+	// true defaults
+	if preference.ID == 0 {
+		err = config.DB.Create(&preference).Error
+	}
+	if err == nil {
+		err = config.DB.Model(&preference).Updates(map[string]interface{}{
+			"order_updates": preference.OrderUpdates,
+			"wallet":        preference.Wallet,
+			"promotions":    preference.Promotions,
+			"new_arrivals":  preference.NewArrivals,
+			"price_drops":   preference.PriceDrops,
+		}).Error
+	}
+	if err != nil {
+		utils.LogError("Failed to save notification preferences for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to update notification preferences", err.Error())
+		return
+	}
+
+	utils.LogInfo("Notification preferences updated for user %d", user.ID)
+	utils.Success(c, "Notification preferences updated successfully", gin.H{
+		"preferences": preference,
+	})
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// routeDocs adds summaries and request schemas to the generated OpenAPI spec, keyed by handler
+// name. Routes without an entry are still documented, with a summary taken from the handler name.
+var routeDocs = map[string]utils.RouteDoc{
+	// Authentication
+	"RegisterUser":         {Summary: "Register a new user and send a verification OTP", Request: RegisterRequest{}},
+	"LoginUser":            {Summary: "Log in with email and password", Request: LoginRequest{}},
+	"SendPhoneLoginOTP":    {Summary: "Text a sign-in OTP to a verified phone number", Request: PhoneOTPRequest{}},
+	"VerifyPhoneLoginOTP":  {Summary: "Sign in with the OTP texted to the phone", Request: PhoneLoginRequest{}},
+	"RefreshUserSession":   {Summary: "Exchange a refresh token for new tokens", Request: RefreshTokenRequest{}},
+	"ForgotPassword":       {Summary: "Send a password reset OTP", Request: ForgotPasswordRequest{}},
+	"VerifyResetOTP":       {Summary: "Verify the password reset OTP", Request: VerifyResetOTPRequest{}},
+	"ResetPassword":        {Summary: "Set a new password after OTP verification", Request: ResetPasswordRequest{}},
+	"AdminLogin":           {Summary: "Admin login", Description: "With two-factor authentication enabled the response carries a two_factor_token for /v1/admin/login/2fa instead of the access token.", Request: AdminLoginRequest{}},
+	"AdminVerifyTwoFactor": {Summary: "Complete admin login with an authenticator or backup code", Request: AdminTwoFactorLoginRequest{}},
+
+	// Profile
+	"UpdateProfile":     {Summary: "Update the profile (except email)", Request: UpdateProfileRequest{}},
+	"UpdateEmail":       {Summary: "Start an email change", Request: UpdateEmailRequest{}},
+	"VerifyEmailUpdate": {Summary: "Confirm an email change with the OTP", Request: VerifyEmailUpdateRequest{}},
+	"VerifyPhone":       {Summary: "Verify the phone number with the texted OTP", Request: VerifyPhoneOTPRequest{}},
+	"ChangePassword":    {Summary: "Change the password", Request: ChangePasswordRequest{}},
+	"AddAddress":        {Summary: "Add an address", Request: AddAddressRequest{}},
+	"EditAddress":       {Summary: "Edit an address", Request: EditAddressRequest{}},
+
+	// Catalog
+	"GetBooks":                {Summary: "List books with filters and sorting", Query: BookListRequest{}},
+	"CreateBook":              {Summary: "Create a book", Request: BookRequest{}},
+	"CreateCategory":          {Summary: "Create a category", Request: CategoryRequest{}},
+	"UpdateCategory":          {Summary: "Update a category", Request: CategoryRequest{}},
+	"CreateGenre":             {Summary: "Create a genre", Request: GenreRequest{}},
+	"UpdateGenre":             {Summary: "Update a genre", Request: GenreRequest{}},
+	"CreateAuthor":            {Summary: "Create an author", Request: AuthorRequest{}},
+	"UpdateAuthor":            {Summary: "Update an author", Request: AuthorRequest{}},
+	"CreateTag":               {Summary: "Create a tag", Request: TagRequest{}},
+	"UpdateTag":               {Summary: "Update a tag", Request: TagRequest{}},
+	"SetBookTags":             {Summary: "Replace the tags of a book", Request: BookTagsRequest{}},
+	"CreateBundle":            {Summary: "Create a bundle", Request: BundleRequest{}},
+	"UpdateBundle":            {Summary: "Update a bundle", Request: BundleRequest{}},
+	"BulkCategorizeBooks":     {Summary: "Move books to another category or genre", Request: BulkCategorizeRequest{}},
+	"UpsertTranslation":       {Summary: "Create or update a catalog translation", Request: TranslationRequest{}},
+	"AdminCreateHomeSection":  {Summary: "Create a home page section", Request: HomeSectionRequest{}},
+	"AdminUpdateHomeSection":  {Summary: "Update a home page section", Request: HomeSectionRequest{}},
+	"AdminCreateBanner":       {Summary: "Create a storefront banner", Request: BannerRequest{}},
+	"AdminCreatePage":         {Summary: "Create a content page", Request: PageRequest{}},
+	"AdminUpdatePage":         {Summary: "Update a content page, saving a new version when its content changes", Request: PageRequest{}},
+	"CreateSupportTicket":     {Summary: "Open a support ticket", Request: CreateSupportTicketRequest{}},
+	"AddSupportTicketMessage": {Summary: "Reply to a support ticket", Request: SupportTicketMessageRequest{}},
+	"AdminReplySupportTicket": {Summary: "Reply to a support ticket as support", Request: SupportTicketMessageRequest{}},
+	"AdminCreateDispute":      {Summary: "Record a payment dispute or chargeback against an order", Request: CreateDisputeRequest{}},
+	"AdminUpdateDispute":      {Summary: "Update a payment dispute", Request: UpdateDisputeRequest{}},
+	"AdminUpdateBanner":       {Summary: "Update a storefront banner", Request: BannerRequest{}},
+
+	// Cart, coupons and account
+	"ApplyCoupon":                   {Summary: "Apply a coupon to the cart", Request: ApplyCouponRequest{}},
+	"CreateCoupon":                  {Summary: "Create a coupon", Request: CreateCouponRequest{}},
+	"UpdateCoupon":                  {Summary: "Update a coupon", Request: UpdateCouponRequest{}},
+	"UpdateConsent":                 {Summary: "Update consent and cookie preferences", Request: ConsentRequest{}},
+	"RequestAccountDeletion":        {Summary: "Schedule the account for deletion", Request: DeleteAccountRequest{}},
+	"AddReview":                     {Summary: "Review a book, optionally with up to 3 images", Request: ReviewRequest{}},
+	"ReportReview":                  {Summary: "Report a review as spam, offensive or fake", Request: ReviewReportRequest{}},
+	"UpdateNotificationPreferences": {Summary: "Opt in or out of notifications and emails", Request: NotificationPreferenceRequest{}},
+	"RequestOrderCancellation":      {Summary: "Ask for items to be cancelled after the cancellation window", Request: CancellationRequestBody{}},
+
+	// Administration
+	"GetUsers":                   {Summary: "List users with search and pagination", Query: UserListRequest{}},
+	"UpdateReferralSettings":     {Summary: "Update referral reward settings", Request: UpdateReferralSettingsRequest{}},
+	"UpdateOfferRules":           {Summary: "Change how product and category offers combine", Request: UpdateOfferRulesRequest{}},
+	"UpdateScheduledJob":         {Summary: "Enable, disable or reschedule a job", Request: UpdateScheduledJobRequest{}},
+	"PreviewEmailTemplate":       {Summary: "Render an email template with sample data", Description: "Responds with the HTML page, not JSON."},
+	"SetExchangeRate":            {Summary: "Set a manual exchange rate", Request: SetExchangeRateRequest{}},
+	"AdminUpdateSettings":        {Summary: "Update store settings", Request: UpdateSettingsRequest{}},
+	"AdminEnableTwoFactor":       {Summary: "Confirm two-factor setup and get backup codes", Request: AdminTwoFactorCodeRequest{}},
+	"AdminRegenerateBackupCodes": {Summary: "Replace two-factor backup codes", Request: AdminTwoFactorCodeRequest{}},
+	"AdminDisableTwoFactor":      {Summary: "Turn two-factor authentication off", Request: AdminDisableTwoFactorRequest{}},
+	"AdminSendAnnouncement":      {Summary: "Email a new arrival or price drop announcement", Request: AnnouncementRequest{}},
+	"AdminBulkUpdateOrderStatus": {Summary: "Move several orders to one status", Request: BulkOrderStatusRequest{}},
+	"AdjustUserWallet":           {Summary: "Credit or debit a user's wallet with a reason", Request: WalletAdjustmentRequest{}},
+	"AdminCreateOrderShipment":   {Summary: "Put order items into a separate shipment", Request: CreateShipmentRequest{}},
+	"AdminUpdateShipmentStatus":  {Summary: "Move a shipment forward; the order follows once all items have", Request: UpdateShipmentStatusRequest{}},
+	"AdminIssueGiftCard":         {Summary: "Issue a gift card and email it to the recipient", Request: IssueGiftCardRequest{}},
+	"AdminVoidGiftCard":          {Summary: "Void a gift card that has not been redeemed", Request: VoidGiftCardRequest{}},
+	"SetDeliverySLA":             {Summary: "Set the delivery time of a zone", Request: DeliverySLARequest{}},
+	"AddDeliverySlot":            {Summary: "Add a delivery slot to a zone", Request: DeliverySlotRequest{}},
+	"UpdateDeliverySlot":         {Summary: "Update a delivery slot", Request: DeliverySlotRequest{}},
+	"AdminBulkApproveReviews":    {Summary: "Approve several reviews; flagged ones need include_flagged", Request: BulkReviewModerationRequest{}},
+	"AdminBulkRejectReviews":     {Summary: "Reject several reviews with an optional reason", Request: BulkReviewModerationRequest{}},
+	"AdminAddBannedWords":        {Summary: "Add words that get reviews flagged", Request: BannedWordsRequest{}},
+	"AdminScheduleBook":          {Summary: "Schedule when a book goes live and is taken down", Request: VisibilityScheduleRequest{}},
+	"AdminScheduleProductOffer":  {Summary: "Schedule when a product offer is switched on and off", Request: VisibilityScheduleRequest{}},
+	"AdminScheduleCategoryOffer": {Summary: "Schedule when a category offer is switched on and off", Request: VisibilityScheduleRequest{}},
+	"CloneBook":                  {Summary: "Copy a book into an inactive draft", Request: BookCloneRequest{}},
+}
+
+// OpenAPISpec serves the OpenAPI document for the router's routes. The document is built on
+// the first request, once every route is registered.
+func OpenAPISpec(router *gin.Engine) gin.HandlerFunc {
+	var (
+		once sync.Once
+		spec map[string]interface{}
+	)
+	return func(c *gin.Context) {
+		once.Do(func() {
+			spec = utils.BuildOpenAPISpec(router.Routes(), routeDocs)
+			utils.LogInfo("Generated OpenAPI spec with %d paths", len(spec["paths"].(map[string]interface{})))
+		})
+		c.JSON(http.StatusOK, spec)
+	}
+}
+
+// GetErrorCodes lists the error codes the API can respond with and their HTTP status
+func GetErrorCodes(c *gin.Context) {
+	utils.LogInfo("GetErrorCodes called")
+	utils.Success(c, "Error codes retrieved successfully", gin.H{
+		"error_codes": utils.ErrorCodes(),
+	})
+}
+
+// swaggerUIPage renders the spec with Swagger UI loaded from a CDN
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>ReadSphere API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/swagger/doc.json", dom_id: "#swagger-ui", persistAuthorization: true });
+  </script>
+</body>
+</html>`
+
+// SwaggerUI serves an interactive page for browsing and trying the API
+func SwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"time"
 
@@ -12,13 +13,15 @@ import (
 	"gorm.io/gorm"
 )
 
-// CancelOrderItem cancels a single item in an order within 30 minutes of ordering
+// CancelOrderItem cancels a single item in an order within 30 minutes of ordering. An optional
+// quantity cancels only some of the item's copies; the cancelled copies are split off into
+// their own order item so the rest of the item stays as it was.
 func CancelOrderItem(c *gin.Context) {
 	utils.LogInfo("CancelOrderItem called")
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "Unauthorized")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
 		return
 	}
 	user := userVal.(models.User)
@@ -27,7 +30,7 @@ func CancelOrderItem(c *gin.Context) {
 	orderID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.LogError("Invalid order ID format: %v", err)
-		utils.BadRequest(c, "Invalid order ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
 		return
 	}
 	utils.LogDebug("Processing cancellation for order ID: %d", orderID)
@@ -37,17 +40,18 @@ func CancelOrderItem(c *gin.Context) {
 	_, err = fmt.Sscanf(itemIDStr, "%d", &itemID)
 	if err != nil || itemID == 0 {
 		utils.LogError("Invalid item ID format: %s", itemIDStr)
-		utils.BadRequest(c, "Invalid item ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid item ID", nil)
 		return
 	}
 	utils.LogDebug("Processing cancellation for item ID: %d in order ID: %d", itemID, orderID)
 
 	var req struct {
-		Reason string `json:"reason" binding:"required"`
+		Reason   string `json:"reason" binding:"required"`
+		Quantity int    `json:"quantity" binding:"omitempty,min=1"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Missing cancellation reason for item ID: %d, order ID: %d: %v", itemID, orderID, err)
-		utils.BadRequest(c, "Reason is required for item cancellation", nil)
+		utils.Fail(c, utils.CodeReasonRequired, "Reason is required for item cancellation", nil)
 		return
 	}
 	utils.LogDebug("Cancellation reason received for item ID: %d", itemID)
@@ -66,7 +70,7 @@ func CancelOrderItem(c *gin.Context) {
 	if err := tx.Preload("OrderItems").First(&order, orderID).Error; err != nil {
 		utils.LogError("Order not found - Order ID: %d: %v", orderID, err)
 		tx.Rollback()
-		utils.NotFound(c, "Order not found")
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 	utils.LogDebug("Found order ID: %d with %d items", orderID, len(order.OrderItems))
@@ -82,16 +86,19 @@ func CancelOrderItem(c *gin.Context) {
 	if order.Status != models.OrderStatusPlaced && order.Status != models.OrderStatusPaid {
 		utils.LogError("Order cannot be cancelled - Order ID: %d, Status: %s", orderID, order.Status)
 		tx.Rollback()
-		utils.BadRequest(c, "Items can only be cancelled before shipping", nil)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Items can only be cancelled before shipping", nil)
 		return
 	}
 
-	// Strict 30-minute cancellation window check
+	// Strict cancellation window check
 	timeSinceOrder := time.Since(order.CreatedAt)
-	if timeSinceOrder > 30*time.Minute {
+	cancellationWindow := utils.CancellationWindow()
+	if timeSinceOrder > cancellationWindow {
 		utils.LogError("Cancellation window expired - Order ID: %d, Created: %v", orderID, order.CreatedAt)
 		tx.Rollback()
-		utils.BadRequest(c, fmt.Sprintf("Cancellation window (30 minutes) has expired. Time elapsed: %.0f minutes", timeSinceOrder.Minutes()), nil)
+		utils.Fail(c, utils.CodeOrderWindowExpired, fmt.Sprintf("Cancellation window (%.0f minutes) has expired. Time elapsed: %.0f minutes", cancellationWindow.Minutes(), timeSinceOrder.Minutes()), gin.H{
+			"cancellation_request_url": fmt.Sprintf("/v1/user/orders/%d/cancellation-request", order.ID),
+		})
 		return
 	}
 	utils.LogDebug("Order within cancellation window - Order ID: %d", orderID)
@@ -110,7 +117,7 @@ func CancelOrderItem(c *gin.Context) {
 	if !found {
 		utils.LogError("Order item not found - Order ID: %d, Item ID: %d", orderID, itemID)
 		tx.Rollback()
-		utils.NotFound(c, "Order item not found")
+		utils.Fail(c, utils.CodeOrderItemNotFound, "Order item not found", nil)
 		return
 	}
 	utils.LogDebug("Found item ID: %d in order ID: %d", itemID, orderID)
@@ -119,7 +126,7 @@ func CancelOrderItem(c *gin.Context) {
 	if item.CancellationRequested {
 		utils.LogError("Item already has a cancellation request - Order ID: %d, Item ID: %d, Status: %s", orderID, itemID, item.CancellationStatus)
 		tx.Rollback()
-		utils.BadRequest(c, "This item has already been cancelled or has a pending cancellation request", nil)
+		utils.Fail(c, utils.CodeItemAlreadyCancelled, "This item has already been cancelled or has a pending cancellation request", nil)
 		return
 	}
 
@@ -127,13 +134,51 @@ func CancelOrderItem(c *gin.Context) {
 	if item.CancellationStatus == "Cancelled" {
 		utils.LogError("Item already cancelled - Order ID: %d, Item ID: %d", orderID, itemID)
 		tx.Rollback()
-		utils.BadRequest(c, "This item has already been cancelled", nil)
+		utils.Fail(c, utils.CodeItemAlreadyCancelled, "This item has already been cancelled", nil)
 		return
 	}
 
+	// Items of a split order cannot be cancelled once their shipment has left
+	if item.ShipmentID != nil {
+		var shipment models.OrderShipment
+		if err := tx.First(&shipment, *item.ShipmentID).Error; err == nil && shipment.Status != models.ShipmentStatusPending {
+			utils.LogError("Item already shipped - Order ID: %d, Item ID: %d, Shipment: %d", orderID, itemID, shipment.ID)
+			tx.Rollback()
+			utils.Fail(c, utils.CodeOrderStatusInvalid, "This item has already shipped", nil)
+			return
+		}
+	}
+
+	// Cancel the whole item unless a smaller quantity was asked for
+	cancelQuantity := req.Quantity
+	if cancelQuantity == 0 {
+		cancelQuantity = item.Quantity
+	}
+	if cancelQuantity > item.Quantity {
+		utils.LogError("Cancel quantity %d exceeds item quantity %d - Order ID: %d, Item ID: %d", cancelQuantity, item.Quantity, orderID, itemID)
+		tx.Rollback()
+		utils.Fail(c, utils.CodeQuantityInvalid, fmt.Sprintf("You can cancel at most %d copies of this item", item.Quantity), nil)
+		return
+	}
+	remainingQuantity := item.Quantity - cancelQuantity
+	if remainingQuantity > 0 {
+		cancelled, err := splitOrderItem(tx, &item, cancelQuantity)
+		if err != nil {
+			utils.LogError("Failed to split order item - Item ID: %d: %v", itemID, err)
+			tx.Rollback()
+			utils.InternalServerError(c, "Failed to update order item", nil)
+			return
+		}
+		utils.LogInfo("Split %d of %d copies off item ID: %d into item ID: %d for cancellation", cancelQuantity, item.Quantity+cancelQuantity, itemID, cancelled.ID)
+		item = cancelled
+	}
+
 	// Calculate refund amount for this item
 	// Use the final price the customer actually paid for this item
 	refundAmount := item.Total - item.CouponDiscount // This is the final price after all discounts
+	// Unwind this item's share of the payment method fee, discount or cashback
+	paymentAdjustmentRefund := utils.PaymentRefundAdjustment(&order, refundAmount)
+	refundAmount += paymentAdjustmentRefund
 	utils.LogInfo("Calculated refund amount: %.2f for order ID: %d, book ID: %d (final price paid: %.2f - %.2f coupon)", refundAmount, order.ID, item.BookID, item.Total, item.CouponDiscount)
 
 	// Update item status
@@ -152,28 +197,34 @@ func CancelOrderItem(c *gin.Context) {
 	utils.LogDebug("Updated item status to cancelled - Item ID: %d", itemID)
 
 	// Update book stock
-	if err := tx.Model(&models.Book{}).Where("id = ?", item.BookID).UpdateColumn("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
+	if restored, err := utils.RestockOrderItem(tx, &item, models.StockSourceCancellation); err != nil {
 		utils.LogError("Failed to update book stock for book ID: %d: %v", item.BookID, err)
 		tx.Rollback()
 		utils.InternalServerError(c, "Failed to update book stock", err.Error())
 		return
+	} else if restored {
+		utils.LogInfo("Updated book stock for book ID: %d, added: %d", item.BookID, item.Quantity)
 	}
-	utils.LogInfo("Updated book stock for book ID: %d, added: %d", item.BookID, item.Quantity)
 
+	formatter := utils.NewResponseFormatter(c)
 	// Prepare response based on payment method
 	itemResponse := gin.H{
 		"id":                  item.ID,
+		"original_item_id":    itemID,
+		"cancelled_quantity":  cancelQuantity,
+		"remaining_quantity":  remainingQuantity,
 		"cancellation_status": "Cancelled",
 		"cancellation_reason": req.Reason,
-		"refund_amount":       fmt.Sprintf("%.2f", refundAmount),
+		"refund_amount":       formatter.Money(refundAmount),
 		"refund_details": gin.H{
-			"item_total":       fmt.Sprintf("%.2f", item.Price*float64(item.Quantity)),
-			"item_discount":    fmt.Sprintf("%.2f", item.Discount),
-			"coupon_discount":  fmt.Sprintf("%.2f", item.CouponDiscount),
-			"final_price_paid": fmt.Sprintf("%.2f", item.Total-item.CouponDiscount),
-			"total_refunded":   fmt.Sprintf("%.2f", refundAmount),
-			"refund_status":    "refunded to wallet",
-			"refunded_to":      "wallet",
+			"item_total":         formatter.Money(item.Price * float64(item.Quantity)),
+			"item_discount":      formatter.Money(item.Discount),
+			"coupon_discount":    formatter.Money(item.CouponDiscount),
+			"final_price_paid":   formatter.Money(item.Total - item.CouponDiscount),
+			"payment_adjustment": formatter.Money(paymentAdjustmentRefund),
+			"total_refunded":     formatter.Money(refundAmount),
+			"refund_status":      "refunded to wallet",
+			"refunded_to":        "wallet",
 		},
 	}
 
@@ -183,35 +234,22 @@ func CancelOrderItem(c *gin.Context) {
 		itemResponse["refund_status"] = "No refund applicable for COD orders"
 	} else {
 		utils.LogDebug("Processing refund for non-COD order - Order ID: %d, Item ID: %d", orderID, itemID)
-		wallet, err := utils.GetOrCreateWallet(user.ID)
-		if err != nil {
-			utils.LogError("Failed to get wallet for user ID: %d, item ID: %d: %v", user.ID, itemID, err)
-			tx.Rollback()
-			utils.InternalServerError(c, "Failed to process refund", nil)
-			return
-		}
-
-		// Create refund transaction
-		reference := fmt.Sprintf("REFUND-ORDER-%d-ITEM-%d", orderID, itemID)
-		description := fmt.Sprintf("Refund for cancelled item in order #%d", orderID)
-
-		transaction, err := utils.CreateWalletTransaction(wallet.ID, refundAmount, models.TransactionTypeCredit, description, &order.ID, reference)
+		// Credit the refund in the same transaction as the cancellation
+		transaction, err := utils.PostWalletEntry(tx, utils.WalletEntry{
+			UserID:      user.ID,
+			Amount:      refundAmount,
+			Type:        models.TransactionTypeCredit,
+			Description: fmt.Sprintf("Refund for cancelled item in order #%d", orderID),
+			Reference:   fmt.Sprintf("REFUND-ORDER-%d-ITEM-%d", orderID, item.ID),
+			OrderID:     &order.ID,
+		})
 		if err != nil {
-			utils.LogError("Failed to create refund transaction - Item ID: %d: %v", itemID, err)
+			utils.LogError("Failed to credit refund - Item ID: %d: %v", itemID, err)
 			tx.Rollback()
 			utils.InternalServerError(c, "Failed to create refund transaction", nil)
 			return
 		}
-		utils.LogDebug("Created wallet transaction - Transaction ID: %d, Amount: %.2f", transaction.ID, transaction.Amount)
-
-		// Update wallet balance
-		if err := utils.UpdateWalletBalance(wallet.ID, refundAmount, models.TransactionTypeCredit); err != nil {
-			utils.LogError("Failed to update wallet balance - Wallet ID: %d, Item ID: %d: %v", wallet.ID, itemID, err)
-			tx.Rollback()
-			utils.InternalServerError(c, "Failed to update wallet balance", nil)
-			return
-		}
-		utils.LogDebug("Updated wallet balance - Wallet ID: %d, Amount: %.2f", wallet.ID, refundAmount)
+		utils.LogDebug("Credited refund - Transaction ID: %d, Amount: %.2f", transaction.ID, transaction.Amount)
 
 		// Update refund status in order item
 		item.RefundStatus = "completed"
@@ -227,17 +265,18 @@ func CancelOrderItem(c *gin.Context) {
 		utils.LogDebug("Updated item refund status to completed - Item ID: %d", itemID)
 
 		itemResponse["refund_details"] = gin.H{
-			"item_total":       fmt.Sprintf("%.2f", item.Price*float64(item.Quantity)),
-			"item_discount":    fmt.Sprintf("%.2f", item.Discount),
-			"coupon_discount":  fmt.Sprintf("%.2f", item.CouponDiscount),
-			"final_price_paid": fmt.Sprintf("%.2f", item.Total-item.CouponDiscount),
-			"total_refunded":   fmt.Sprintf("%.2f", refundAmount),
-			"refund_status":    "refunded to wallet",
-			"refunded_to":      "wallet",
+			"item_total":         formatter.Money(item.Price * float64(item.Quantity)),
+			"item_discount":      formatter.Money(item.Discount),
+			"coupon_discount":    formatter.Money(item.CouponDiscount),
+			"final_price_paid":   formatter.Money(item.Total - item.CouponDiscount),
+			"payment_adjustment": formatter.Money(paymentAdjustmentRefund),
+			"total_refunded":     formatter.Money(refundAmount),
+			"refund_status":      "refunded to wallet",
+			"refunded_to":        "wallet",
 			"transaction": gin.H{
 				"id":          transaction.ID,
 				"wallet_id":   transaction.WalletID,
-				"amount":      fmt.Sprintf("%.2f", transaction.Amount),
+				"amount":      formatter.Money(transaction.Amount),
 				"type":        transaction.Type,
 				"description": transaction.Description,
 				"order_id":    transaction.OrderID,
@@ -246,7 +285,7 @@ func CancelOrderItem(c *gin.Context) {
 			},
 		}
 		itemResponse["wallet"] = gin.H{
-			"balance": fmt.Sprintf("%.2f", wallet.Balance),
+			"balance": formatter.Money(transaction.Wallet.Balance),
 		}
 	}
 
@@ -265,8 +304,8 @@ func CancelOrderItem(c *gin.Context) {
 
 	// Calculate final total after all adjustments
 	order.FinalTotal = order.TotalAmount - order.Discount - order.CouponDiscount
-	// Add delivery charge to final total
-	order.TotalWithDelivery = order.FinalTotal + order.DeliveryCharge
+	// Add delivery charge, gift wrapping and the remaining payment method adjustment to final total
+	order.TotalWithDelivery = order.FinalTotal + order.DeliveryCharge + order.GiftWrapFee + utils.RemainingPaymentAdjustment(&order)
 
 	if err := tx.Save(&order).Error; err != nil {
 		utils.LogError("Failed to update order totals - Order ID: %d: %v", orderID, err)
@@ -282,16 +321,56 @@ func CancelOrderItem(c *gin.Context) {
 		return
 	}
 	utils.LogInfo("Successfully committed transaction for order ID: %d, item ID: %d", orderID, itemID)
+	if item.RefundStatus == "completed" {
+		utils.RecordRefundIssued("cancellation", item.RefundAmount)
+	}
 
 	utils.Success(c, "Item cancelled successfully", gin.H{
 		"item": itemResponse,
 		"order": gin.H{
 			"id":              order.ID,
-			"total_amount":    fmt.Sprintf("%.2f", order.TotalAmount),
-			"discount":        fmt.Sprintf("%.2f", order.Discount),
-			"coupon_discount": fmt.Sprintf("%.2f", order.CouponDiscount),
+			"total_amount":    formatter.Money(order.TotalAmount),
+			"discount":        formatter.Money(order.Discount),
+			"coupon_discount": formatter.Money(order.CouponDiscount),
 			"coupon_code":     order.CouponCode,
-			"final_total":     fmt.Sprintf("%.2f", order.TotalWithDelivery),
+			"final_total":     formatter.Money(order.TotalWithDelivery),
 		},
 	})
 }
+
+// splitOrderItem moves quantity copies of an order item into a new order item in the same
+// order, splitting its discount, total and coupon discount pro rata. The new item keeps the
+// bundle, shipment and pre-order state of the original, which keeps the remaining copies. It
+// returns the new item, which is the one to cancel.
+func splitOrderItem(tx *gorm.DB, item *models.OrderItem, quantity int) (models.OrderItem, error) {
+	share := float64(quantity) / float64(item.Quantity)
+	split := models.OrderItem{
+		OrderID:         item.OrderID,
+		BookID:          item.BookID,
+		Quantity:        quantity,
+		Price:           item.Price,
+		Discount:        math.Round(item.Discount*share*100) / 100,
+		Total:           math.Round(item.Total*share*100) / 100,
+		CouponDiscount:  math.Round(item.CouponDiscount*share*100) / 100,
+		BundleID:        item.BundleID,
+		PreorderPending: item.PreorderPending,
+		ShipmentID:      item.ShipmentID,
+	}
+	if err := tx.Create(&split).Error; err != nil {
+		return split, err
+	}
+
+	item.Quantity -= quantity
+	item.Discount = math.Round((item.Discount-split.Discount)*100) / 100
+	item.Total = math.Round((item.Total-split.Total)*100) / 100
+	item.CouponDiscount = math.Round((item.CouponDiscount-split.CouponDiscount)*100) / 100
+	if err := tx.Model(&models.OrderItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+		"quantity":        item.Quantity,
+		"discount":        item.Discount,
+		"total":           item.Total,
+		"coupon_discount": item.CouponDiscount,
+	}).Error; err != nil {
+		return split, err
+	}
+	return split, nil
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/testutil"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cancelOrderItemAs runs CancelOrderItem for the user with the JSON request body
+func cancelOrderItemAs(user models.User, orderID, itemID uint, body gin.H) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/user/orders/cancel-item", bytes.NewReader(payload))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{
+		{Key: "id", Value: fmt.Sprint(orderID)},
+		{Key: "item_id", Value: fmt.Sprint(itemID)},
+	}
+	c.Set("user", user)
+	CancelOrderItem(c)
+	return recorder
+}
+
+func TestCancelOrderItemPartOfPendingPreorder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t, &models.User{}, &models.Book{}, &models.Order{}, &models.OrderItem{},
+		&models.StockMovement{}, &models.OrderShipment{}, &models.Setting{}, &models.PaymentMethodAdjustment{})
+
+	user := models.User{Username: "preorderer", Email: "preorderer@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	release := time.Now().Add(14 * 24 * time.Hour)
+	book := models.Book{Name: "Coming Soon", Price: 100, Stock: 0, IsActive: true, IsPreorder: true, ReleaseDate: &release, ISBN: "9780000000002"}
+	require.NoError(t, db.Create(&book).Error)
+	order := models.Order{UserID: user.ID, Status: models.OrderStatusPlaced, PaymentMethod: "cod", IsPreorder: true,
+		TotalAmount: 300, FinalTotal: 300, TotalWithDelivery: 300}
+	require.NoError(t, db.Create(&order).Error)
+	bundleID, shipmentID := uint(7), uint(9)
+	item := models.OrderItem{OrderID: order.ID, BookID: book.ID, Quantity: 3, Price: 100, Total: 300,
+		PreorderPending: true, BundleID: &bundleID, ShipmentID: &shipmentID}
+	require.NoError(t, db.Create(&item).Error)
+	require.NoError(t, db.Create(&models.OrderShipment{OrderID: order.ID, Status: models.ShipmentStatusPending}).Error)
+
+	recorder := cancelOrderItemAs(user, order.ID, item.ID, gin.H{"reason": "Ordered too many", "quantity": 1})
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	var items []models.OrderItem
+	require.NoError(t, db.Where("order_id = ?", order.ID).Order("id").Find(&items).Error)
+	require.Len(t, items, 2)
+	kept, cancelled := items[0], items[1]
+
+	assert.Equal(t, 2, kept.Quantity)
+	assert.True(t, kept.PreorderPending, "the remaining copies still wait for the release")
+	assert.Equal(t, 1, cancelled.Quantity)
+	assert.Equal(t, "Cancelled", cancelled.CancellationStatus)
+	assert.False(t, cancelled.PreorderPending, "the cancelled copy is released from the pre-order")
+	assert.True(t, cancelled.StockRestored)
+	require.NotNil(t, cancelled.BundleID)
+	assert.Equal(t, bundleID, *cancelled.BundleID)
+	require.NotNil(t, cancelled.ShipmentID)
+	assert.Equal(t, shipmentID, *cancelled.ShipmentID)
+
+	var stored models.Book
+	require.NoError(t, db.First(&stored, book.ID).Error)
+	assert.Equal(t, 0, stored.Stock, "a pending pre-order took no stock, so cancelling it adds none")
+	var movements int64
+	require.NoError(t, db.Model(&models.StockMovement{}).Count(&movements).Error)
+	assert.Equal(t, int64(0), movements)
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 // CancelOrder cancels an entire order
@@ -19,7 +18,7 @@ func CancelOrder(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "Unauthorized")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
 		return
 	}
 	user := userVal.(models.User)
@@ -30,7 +29,7 @@ func CancelOrder(c *gin.Context) {
 	orderID, err := strconv.ParseUint(orderIDStr, 10, 32)
 	if err != nil {
 		utils.LogError("Invalid order ID format: %v", err)
-		utils.BadRequest(c, "Invalid order ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
 		return
 	}
 	utils.LogDebug("Processing cancellation for order ID: %d", orderID)
@@ -41,7 +40,7 @@ func CancelOrder(c *gin.Context) {
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Missing cancellation reason for order ID: %d: %v", orderID, err)
-		utils.BadRequest(c, "Reason is required", nil)
+		utils.Fail(c, utils.CodeReasonRequired, "Reason is required", nil)
 		return
 	}
 	utils.LogDebug("Cancellation reason received for order ID: %d", orderID)
@@ -50,7 +49,7 @@ func CancelOrder(c *gin.Context) {
 	var order models.Order
 	if err := config.DB.Preload("OrderItems").Where("id = ? AND user_id = ?", orderID, user.ID).First(&order).Error; err != nil {
 		utils.LogError("Order not found - Order ID: %d, User ID: %d: %v", orderID, user.ID, err)
-		utils.NotFound(c, "Order not found")
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 	utils.LogDebug("Found order ID: %d with %d items", orderID, len(order.OrderItems))
@@ -58,21 +57,29 @@ func CancelOrder(c *gin.Context) {
 	// Check if order is already cancelled
 	if order.Status == models.OrderStatusCancelled {
 		utils.LogError("Order already cancelled - Order ID: %d", orderID)
-		utils.BadRequest(c, "Order already cancelled", nil)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Order already cancelled", nil)
 		return
 	}
 
 	// Check if order can be cancelled based on status and time
 	if order.Status != models.OrderStatusPlaced && order.Status != models.OrderStatusPaid {
 		utils.LogError("Order cannot be cancelled - Order ID: %d, Status: %s", orderID, order.Status)
-		utils.BadRequest(c, "Order cannot be cancelled at this stage", nil)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Order cannot be cancelled at this stage", nil)
+		return
+	}
+	if shipped, err := orderHasShippedItems(config.DB, order.ID); err != nil || shipped {
+		utils.LogError("Order has shipped items - Order ID: %d: %v", orderID, err)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Part of this order has already shipped. Cancel the items that have not shipped instead.", nil)
 		return
 	}
 
-	// Check 30-minute cancellation window
-	if time.Since(order.CreatedAt) > 30*time.Minute {
+	// Check the cancellation window
+	cancellationWindow := utils.CancellationWindow()
+	if time.Since(order.CreatedAt) > cancellationWindow {
 		utils.LogError("Cancellation window expired - Order ID: %d, Created: %v", orderID, order.CreatedAt)
-		utils.BadRequest(c, "Cancellation window (30 minutes) has expired", nil)
+		utils.Fail(c, utils.CodeOrderWindowExpired, fmt.Sprintf("Cancellation window (%.0f minutes) has expired", cancellationWindow.Minutes()), gin.H{
+			"cancellation_request_url": fmt.Sprintf("/v1/user/orders/%d/cancellation-request", order.ID),
+		})
 		return
 	}
 	utils.LogDebug("Order within cancellation window - Order ID: %d", orderID)
@@ -86,16 +93,15 @@ func CancelOrder(c *gin.Context) {
 	}
 	utils.LogDebug("Started transaction for order cancellation - Order ID: %d", orderID)
 
-	// Restore stock for each book
-	for _, item := range order.OrderItems {
-		if err := tx.Model(&models.Book{}).Where("id = ?", item.BookID).UpdateColumn("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
-			utils.LogError("Failed to restore stock for book ID: %d, order ID: %d: %v", item.BookID, orderID, err)
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore book stock"})
-			return
-		}
-		utils.LogDebug("Restored stock for book ID: %d, quantity: %d", item.BookID, item.Quantity)
+	// Restore stock for each book not already restocked by an item cancellation
+	restocked, err := utils.RestockOrderItems(tx, order.OrderItems, models.StockSourceCancellation)
+	if err != nil {
+		utils.LogError("Failed to restore stock for order ID: %d: %v", orderID, err)
+		tx.Rollback()
+		utils.InternalServerError(c, "Failed to restore book stock", nil)
+		return
 	}
+	utils.LogDebug("Restored stock for %d items of order ID: %d", restocked, orderID)
 
 	// Update order status and details
 	order.Status = models.OrderStatusCancelled
@@ -104,9 +110,13 @@ func CancelOrder(c *gin.Context) {
 
 	// Calculate refund amount - use the existing order data
 	refundAmount := order.FinalTotal // This is the final amount after all discounts
-	if time.Since(order.CreatedAt) <= 30*time.Minute {
-		// Include delivery charge in refund for orders cancelled within 30 minutes
-		refundAmount = order.TotalWithDelivery // This includes delivery charge
+	// Unwind the payment method fee, discount or cashback
+	refundAmount += utils.PaymentRefundAdjustment(&order, order.FinalTotal)
+	// The order is cancelled before shipping, so it was never wrapped
+	refundAmount += order.GiftWrapFee
+	if time.Since(order.CreatedAt) <= cancellationWindow {
+		// Include delivery charge in refund for orders cancelled within the window
+		refundAmount += order.DeliveryCharge
 	}
 	utils.LogInfo("Calculated refund amount: %.2f for order ID: %d (using existing order data)", refundAmount, orderID)
 
@@ -117,49 +127,53 @@ func CancelOrder(c *gin.Context) {
 	if err := tx.Save(&order).Error; err != nil {
 		utils.LogError("Failed to update order status - Order ID: %d: %v", orderID, err)
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order"})
+		utils.InternalServerError(c, "Failed to update order", nil)
 		return
 	}
 	utils.LogDebug("Updated order status to cancelled - Order ID: %d", orderID)
 
+	if err := recordOrderStatusEvent(tx, order.ID, order.Status, "user", user.ID, req.Reason); err != nil {
+		utils.LogError("Failed to record cancellation event - Order ID: %d: %v", orderID, err)
+		tx.Rollback()
+		utils.InternalServerError(c, "Failed to update order", nil)
+		return
+	}
+	if err := utils.RevokeDigitalEntitlements(tx, order.ID); err != nil {
+		utils.LogError("Failed to revoke digital books - Order ID: %d: %v", orderID, err)
+		tx.Rollback()
+		utils.InternalServerError(c, "Failed to update order", nil)
+		return
+	}
+	// Give back any wallet funds still held for an unpaid split payment
+	if _, err := utils.ReleaseWalletHold(tx, order.UserID, order.ID); err != nil {
+		utils.LogError("Failed to release wallet hold - Order ID: %d: %v", orderID, err)
+		tx.Rollback()
+		utils.InternalServerError(c, "Failed to update order", nil)
+		return
+	}
+
 	// Only process refund if payment was not COD
 	var walletRefundProcessed bool
-	var wallet *models.Wallet
 	var transaction *models.WalletTransaction
 
 	if order.PaymentMethod != "COD" && order.PaymentMethod != "cod" {
 		utils.LogDebug("Processing refund for non-COD order - Order ID: %d, Payment Method: %s", orderID, order.PaymentMethod)
-		// Get or create wallet
-		wallet, err = utils.GetOrCreateWallet(user.ID)
-		if err != nil {
-			utils.LogError("Failed to get wallet for user ID: %d, order ID: %d: %v", user.ID, orderID, err)
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wallet"})
-			return
-		}
-
-		// Create a wallet transaction
-		orderIDUint := uint(orderID)
-		reference := fmt.Sprintf("REFUND-ORDER-%d", orderID)
-		description := fmt.Sprintf("Refund for cancelled order #%d", orderID)
-
-		transaction, err = utils.CreateWalletTransaction(wallet.ID, refundAmount, models.TransactionTypeCredit, description, &orderIDUint, reference)
+		// Credit the refund in the same transaction as the cancellation
+		transaction, err = utils.PostWalletEntry(tx, utils.WalletEntry{
+			UserID:      user.ID,
+			Amount:      refundAmount,
+			Type:        models.TransactionTypeCredit,
+			Description: fmt.Sprintf("Refund for cancelled order #%d", orderID),
+			Reference:   fmt.Sprintf("REFUND-ORDER-%d", orderID),
+			OrderID:     &order.ID,
+		})
 		if err != nil {
-			utils.LogError("Failed to create wallet transaction - Order ID: %d: %v", orderID, err)
+			utils.LogError("Failed to credit refund - Order ID: %d: %v", orderID, err)
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transaction"})
+			utils.InternalServerError(c, "Failed to create transaction", nil)
 			return
 		}
-		utils.LogDebug("Created wallet transaction - Transaction ID: %d, Amount: %.2f", transaction.ID, transaction.Amount)
-
-		// Update wallet balance
-		if err := utils.UpdateWalletBalance(wallet.ID, refundAmount, models.TransactionTypeCredit); err != nil {
-			utils.LogError("Failed to update wallet balance - Wallet ID: %d, Order ID: %d: %v", wallet.ID, orderID, err)
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update wallet balance"})
-			return
-		}
-		utils.LogDebug("Updated wallet balance - Wallet ID: %d, Amount: %.2f", wallet.ID, refundAmount)
+		utils.LogDebug("Credited refund - Transaction ID: %d, Amount: %.2f", transaction.ID, transaction.Amount)
 
 		// Update order refund status
 		now := time.Now()
@@ -169,7 +183,7 @@ func CancelOrder(c *gin.Context) {
 		if err := tx.Save(&order).Error; err != nil {
 			utils.LogError("Failed to update order refund status - Order ID: %d: %v", orderID, err)
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order refund status"})
+			utils.InternalServerError(c, "Failed to update order refund status", nil)
 			return
 		}
 		utils.LogDebug("Updated order refund status to completed - Order ID: %d", orderID)
@@ -180,11 +194,15 @@ func CancelOrder(c *gin.Context) {
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		utils.LogError("Failed to commit transaction - Order ID: %d: %v", orderID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		utils.InternalServerError(c, "Failed to commit transaction", nil)
 		return
 	}
 	utils.LogInfo("Successfully committed transaction for order ID: %d", orderID)
+	if walletRefundProcessed {
+		utils.RecordRefundIssued("cancellation", order.RefundAmount)
+	}
 
+	formatter := utils.NewResponseFormatter(c)
 	// Prepare response based on whether wallet refund was processed
 	if order.PaymentMethod == "COD" || order.PaymentMethod == "cod" {
 		utils.LogInfo("Order cancelled successfully (COD) - Order ID: %d", orderID)
@@ -203,23 +221,23 @@ func CancelOrder(c *gin.Context) {
 			"order": gin.H{
 				"id":            order.ID,
 				"status":        order.Status,
-				"refund_amount": fmt.Sprintf("%.2f", order.RefundAmount),
+				"refund_amount": formatter.Money(order.RefundAmount),
 				"refund_status": "refunded to wallet",
-				"refunded_at":   order.RefundedAt.Format("2006-01-02 15:04:05"),
+				"refunded_at":   formatter.Timestamp(*order.RefundedAt),
 				"refund_details": gin.H{
-					"original_total":      fmt.Sprintf("%.2f", order.TotalAmount),
-					"discount":            fmt.Sprintf("%.2f", order.Discount),
-					"coupon_discount":     fmt.Sprintf("%.2f", order.CouponDiscount),
-					"delivery_charge":     fmt.Sprintf("%.2f", order.DeliveryCharge),
-					"final_total":         fmt.Sprintf("%.2f", order.FinalTotal),
-					"total_with_delivery": fmt.Sprintf("%.2f", order.TotalWithDelivery),
-					"amount_refunded":     fmt.Sprintf("%.2f", refundAmount),
+					"original_total":      formatter.Money(order.TotalAmount),
+					"discount":            formatter.Money(order.Discount),
+					"coupon_discount":     formatter.Money(order.CouponDiscount),
+					"delivery_charge":     formatter.Money(order.DeliveryCharge),
+					"final_total":         formatter.Money(order.FinalTotal),
+					"total_with_delivery": formatter.Money(order.TotalWithDelivery),
+					"amount_refunded":     formatter.Money(refundAmount),
 				},
 			},
 			"transaction": gin.H{
 				"id":          transaction.ID,
 				"wallet_id":   transaction.WalletID,
-				"amount":      fmt.Sprintf("%.2f", transaction.Amount),
+				"amount":      formatter.Money(transaction.Amount),
 				"type":        transaction.Type,
 				"description": transaction.Description,
 				"order_id":    transaction.OrderID,
@@ -227,7 +245,7 @@ func CancelOrder(c *gin.Context) {
 				"status":      "success",
 			},
 			"wallet": gin.H{
-				"balance": fmt.Sprintf("%.2f", wallet.Balance),
+				"balance": formatter.Money(transaction.Wallet.Balance),
 			},
 		})
 	}
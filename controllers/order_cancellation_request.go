@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CancellationRequestBody asks for items of an order to be cancelled after the cancellation
+// window. Without item IDs every item that can still be cancelled is included.
+type CancellationRequestBody struct {
+	Reason  string `json:"reason" binding:"required,min=3,max=500"`
+	ItemIDs []uint `json:"item_ids"`
+}
+
+// pendingCancellationRequests counts the order's items waiting for a cancellation review,
+// only those in the shipment when shipmentID is set
+func pendingCancellationRequests(tx *gorm.DB, orderID uint, shipmentID *uint) (int64, error) {
+	query := tx.Model(&models.OrderItem{}).
+		Where("order_id = ? AND cancellation_requested = ? AND cancellation_status = ?", orderID, true, "Pending")
+	if shipmentID != nil {
+		query = query.Where("shipment_id = ?", *shipmentID)
+	}
+	var pending int64
+	err := query.Count(&pending).Error
+	return pending, err
+}
+
+// RequestOrderCancellation asks the store to cancel items of an order once the cancellation
+// window has passed. The items wait for an admin to approve or reject each of them, which has
+// to happen before they ship.
+func RequestOrderCancellation(c *gin.Context) {
+	utils.LogInfo("RequestOrderCancellation called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid order ID format: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
+		return
+	}
+	var req CancellationRequestBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid cancellation request for order ID: %d: %v", orderID, err)
+		utils.Fail(c, utils.CodeReasonRequired, "A reason of at least 3 characters is required", err)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to begin transaction for order ID: %d: %v", orderID, tx.Error)
+		utils.InternalServerError(c, "Failed to begin transaction", nil)
+		return
+	}
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", orderID, user.ID).
+		First(&order).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Order not found - Order ID: %d, User ID: %d: %v", orderID, user.ID, err)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return
+	}
+	if err := tx.Preload("Book").Where("order_id = ?", order.ID).Find(&order.OrderItems).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to fetch items of order ID: %d: %v", orderID, err)
+		utils.InternalServerError(c, "Failed to fetch order items", err.Error())
+		return
+	}
+
+	// Requests are for orders that have not shipped; within the window the user cancels directly
+	if order.Status != models.OrderStatusPlaced && order.Status != models.OrderStatusPaid && order.Status != models.OrderStatusProcessing {
+		tx.Rollback()
+		utils.LogError("Cancellation requested for order ID: %d in status %s", orderID, order.Status)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Cancellation can only be requested before the order ships", nil)
+		return
+	}
+	if time.Since(order.CreatedAt) <= utils.CancellationWindow() {
+		tx.Rollback()
+		utils.LogError("Cancellation requested within the window for order ID: %d", orderID)
+		utils.BadRequest(c, "This order is still within the cancellation window. Cancel it directly instead.", nil)
+		return
+	}
+
+	var shipments []models.OrderShipment
+	if err := tx.Where("order_id = ?", order.ID).Find(&shipments).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to fetch shipments of order ID: %d: %v", orderID, err)
+		utils.InternalServerError(c, "Failed to fetch shipments", err.Error())
+		return
+	}
+	shipped := make(map[uint]bool, len(shipments))
+	for _, shipment := range shipments {
+		shipped[shipment.ID] = shipment.Status != models.ShipmentStatusPending
+	}
+
+	// cancellable reports why an item cannot be requested, or "" when it can
+	cancellable := func(item models.OrderItem) string {
+		switch {
+		case item.CancellationRequested:
+			return "already cancelled or requested"
+		case item.ReturnRequested:
+			return "has a return request"
+		case item.Book.IsDigital:
+			return "digital books are in the library already"
+		case item.ShipmentID != nil && shipped[*item.ShipmentID]:
+			return "already shipped"
+		}
+		return ""
+	}
+
+	var requested []models.OrderItem
+	if len(req.ItemIDs) == 0 {
+		for _, item := range order.OrderItems {
+			if cancellable(item) == "" {
+				requested = append(requested, item)
+			}
+		}
+	} else {
+		byID := make(map[uint]models.OrderItem, len(order.OrderItems))
+		for _, item := range order.OrderItems {
+			byID[item.ID] = item
+		}
+		seen := make(map[uint]bool, len(req.ItemIDs))
+		for _, id := range req.ItemIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			item, ok := byID[id]
+			if !ok {
+				tx.Rollback()
+				utils.LogError("Item ID: %d not in order ID: %d", id, orderID)
+				utils.Fail(c, utils.CodeOrderItemNotFound, fmt.Sprintf("Item %d is not part of this order", id), nil)
+				return
+			}
+			if reason := cancellable(item); reason != "" {
+				tx.Rollback()
+				utils.LogError("Item ID: %d of order ID: %d cannot be requested: %s", id, orderID, reason)
+				utils.Fail(c, utils.CodeItemAlreadyCancelled, fmt.Sprintf("Item %d cannot be cancelled: %s", id, reason), nil)
+				return
+			}
+			requested = append(requested, item)
+		}
+	}
+	if len(requested) == 0 {
+		tx.Rollback()
+		utils.LogError("No items of order ID: %d can be requested for cancellation", orderID)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "No items of this order can be cancelled", nil)
+		return
+	}
+
+	ids := make([]uint, len(requested))
+	for i, item := range requested {
+		ids[i] = item.ID
+	}
+	if err := tx.Model(&models.OrderItem{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"cancellation_requested": true,
+		"cancellation_status":    "Pending",
+		"cancellation_reason":    req.Reason,
+	}).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to mark cancellation requests for order ID: %d: %v", orderID, err)
+		utils.InternalServerError(c, "Failed to request cancellation", err.Error())
+		return
+	}
+	if err := tx.Model(&order).Update("has_item_cancellation_requests", true).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to flag cancellation requests on order ID: %d: %v", orderID, err)
+		utils.InternalServerError(c, "Failed to request cancellation", err.Error())
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit cancellation request for order ID: %d: %v", orderID, err)
+		utils.InternalServerError(c, "Failed to request cancellation", nil)
+		return
+	}
+
+	items := make([]gin.H, len(requested))
+	for i, item := range requested {
+		items[i] = gin.H{
+			"item_id":             item.ID,
+			"book_name":           item.Book.Name,
+			"quantity":            item.Quantity,
+			"cancellation_status": "Pending",
+		}
+	}
+	utils.LogInfo("User %d requested cancellation of %d items of order ID: %d", user.ID, len(requested), orderID)
+	utils.Success(c, "Cancellation requested. We will let you know once it is reviewed.", gin.H{
+		"order_id": order.ID,
+		"reason":   req.Reason,
+		"items":    items,
+	})
+}
+
+// AdminListCancellationRequests lists item cancellation requests, pending ones by default
+func AdminListCancellationRequests(c *gin.Context) {
+	utils.LogInfo("AdminListCancellationRequests called")
+
+	status := c.DefaultQuery("status", "Pending")
+	if status != "Pending" && status != "Approved" && status != "Rejected" {
+		utils.LogError("Invalid cancellation request status: %s", status)
+		utils.BadRequest(c, "status must be Pending, Approved or Rejected", nil)
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+	query := config.DB.Model(&models.OrderItem{}).
+		Where("cancellation_requested = ? AND cancellation_status = ?", true, status)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count cancellation requests: %v", err)
+		utils.InternalServerError(c, "Failed to fetch cancellation requests", err.Error())
+		return
+	}
+	var items []models.OrderItem
+	if err := query.Preload("Book").Order("order_id DESC, id").
+		Offset((page - 1) * limit).Limit(limit).Find(&items).Error; err != nil {
+		utils.LogError("Failed to fetch cancellation requests: %v", err)
+		utils.InternalServerError(c, "Failed to fetch cancellation requests", err.Error())
+		return
+	}
+
+	orderIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		orderIDs = append(orderIDs, item.OrderID)
+	}
+	var orders []models.Order
+	if err := config.DB.Preload("User").Where("id IN ?", orderIDs).Find(&orders).Error; err != nil {
+		utils.LogError("Failed to fetch orders of cancellation requests: %v", err)
+		utils.InternalServerError(c, "Failed to fetch cancellation requests", err.Error())
+		return
+	}
+	ordersByID := make(map[uint]models.Order, len(orders))
+	for _, order := range orders {
+		ordersByID[order.ID] = order
+	}
+
+	requests := make([]gin.H, len(items))
+	formatter := utils.NewResponseFormatter(c)
+	for i, item := range items {
+		order := ordersByID[item.OrderID]
+		requests[i] = gin.H{
+			"order_id":       item.OrderID,
+			"order_status":   order.Status,
+			"payment_method": order.PaymentMethod,
+			"username":       order.User.Username,
+			"email":          order.User.Email,
+			"item_id":        item.ID,
+			"book_name":      item.Book.Name,
+			"quantity":       item.Quantity,
+			"total":          formatter.Money(item.Total - item.CouponDiscount),
+			"reason":         item.CancellationReason,
+			"status":         item.CancellationStatus,
+			"shipment_id":    item.ShipmentID,
+			"review_url":     fmt.Sprintf("/v1/admin/orders/%d/items/%d/cancellation/review", item.OrderID, item.ID),
+		}
+	}
+
+	utils.LogInfo("Retrieved %d cancellation requests", len(requests))
+	utils.SuccessWithPagination(c, "Cancellation requests retrieved successfully", gin.H{
+		"requests": requests,
+		"status":   status,
+	}, total, page, limit)
+}
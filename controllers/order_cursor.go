@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// orderCursorColumns are the order columns a listing can be paged through by cursor
+var orderCursorColumns = map[string]bool{
+	"id":                  true,
+	"status":              true,
+	"created_at":          true,
+	"final_total":         true,
+	"total_with_delivery": true,
+}
+
+// orderCursorValue is the value of column for order, as it is kept in a cursor
+func orderCursorValue(order models.Order, column string) string {
+	switch column {
+	case "status":
+		return order.Status
+	case "created_at":
+		return order.CreatedAt.Format(time.RFC3339Nano)
+	case "final_total":
+		return strconv.FormatFloat(order.FinalTotal, 'g', -1, 64)
+	case "total_with_delivery":
+		return strconv.FormatFloat(order.TotalWithDelivery, 'g', -1, 64)
+	default:
+		return strconv.FormatUint(uint64(order.ID), 10)
+	}
+}
+
+// orderCursorKey reads the value of column in the cursor back into the column's type, or
+// returns nil for the first page
+func orderCursorKey(params *utils.CursorParams, column string) (interface{}, error) {
+	if params.Cursor == nil {
+		return nil, nil
+	}
+	value := params.Cursor.Value
+	switch column {
+	case "status":
+		return value, nil
+	case "created_at":
+		return time.Parse(time.RFC3339Nano, value)
+	case "final_total", "total_with_delivery":
+		return strconv.ParseFloat(value, 64)
+	default:
+		return strconv.ParseUint(value, 10, 64)
+	}
+}
+
+// orderCursorPage cuts the orders read for a cursor page, limit+1 at most, down to the page in
+// listing order, and returns it with its pagination
+func orderCursorPage(orders []models.Order, params *utils.CursorParams, column string) ([]models.Order, gin.H) {
+	n, more := params.Page(len(orders))
+	orders = orders[:n]
+	if params.Backward {
+		slices.Reverse(orders)
+	}
+
+	var first, last *utils.Cursor
+	if len(orders) > 0 {
+		first = &utils.Cursor{Sort: params.Sort, Value: orderCursorValue(orders[0], column), ID: orders[0].ID}
+		last = &utils.Cursor{Sort: params.Sort, Value: orderCursorValue(orders[n-1], column), ID: orders[n-1].ID}
+	}
+	return orders, params.Pagination(first, last, more)
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// buildDeliveryReceiptPDF renders the single-page delivery receipt: recipient, items,
+// tracking timeline and delivery confirmation. Unlike the invoice it carries no prices.
+func buildDeliveryReceiptPDF(order models.Order, timeline []models.OrderStatusEvent) *gofpdf.Fpdf {
+	pdf := newStorePDF("DELIVERY RECEIPT")
+
+	pdf.SetFont("Arial", "", 12)
+	pdf.Cell(50, 8, "Order ID: "+strconv.Itoa(int(order.ID)))
+	pdf.Cell(60, 8, "Order Date: "+order.CreatedAt.Format("2006-01-02 15:04"))
+	pdf.Ln(8)
+
+	writePDFAddress(pdf, "Delivered To:", order.User.FirstName+" "+order.User.LastName, order.Address)
+	pdf.Ln(4)
+
+	// Items, without prices
+	var rows [][]string
+	for _, item := range order.OrderItems {
+		name := item.Book.Name
+		if item.CancellationStatus == "Cancelled" || item.CancellationStatus == "Approved" {
+			name += " (cancelled)"
+		}
+		rows = append(rows, []string{name, strconv.Itoa(item.Quantity)})
+	}
+	writePDFTable(pdf, []pdfColumn{{"Book", 130, "L"}, {"Qty", 30, "C"}}, rows)
+	pdf.Ln(6)
+
+	// Tracking timeline
+	pdf.SetFont("Arial", "B", 13)
+	pdf.Cell(100, 8, "Tracking Timeline")
+	pdf.Ln(8)
+	pdf.SetFont("Arial", "", 11)
+	for _, event := range timeline {
+		pdf.CellFormat(45, 6, event.CreatedAt.Format("2006-01-02 15:04"), "", 0, "L", false, 0, "")
+		line := event.Status
+		if event.Note != "" {
+			line += " - " + event.Note
+		}
+		pdf.CellFormat(0, 6, line, "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	// Delivery confirmation
+	pdf.SetFont("Arial", "B", 13)
+	pdf.Cell(100, 8, "Delivery Confirmation")
+	pdf.Ln(8)
+	pdf.SetFont("Arial", "", 12)
+	deliveredAt := "-"
+	if order.DeliveredAt != nil {
+		deliveredAt = order.DeliveredAt.Format("2006-01-02 15:04:05")
+	}
+	reference := order.DeliveryReference
+	if reference == "" {
+		reference = "Not captured"
+	}
+	pdf.Cell(0, 7, "Delivered At: "+deliveredAt)
+	pdf.Ln(7)
+	pdf.Cell(0, 7, "OTP / Signature Reference: "+reference)
+	pdf.Ln(7)
+	pdf.Cell(0, 7, fmt.Sprintf("Payment Method: %s", order.PaymentMethod))
+	pdf.Ln(12)
+
+	pdf.SetFont("Arial", "I", 10)
+	pdf.Cell(0, 8, "This receipt confirms delivery only. Refer to the invoice for tax and payment details.")
+	return pdf
+}
+
+// sendDeliveryReceipt loads the timeline for a delivered order and streams its receipt
+func sendDeliveryReceipt(c *gin.Context, order models.Order) {
+	if order.Status != models.OrderStatusDelivered && order.DeliveredAt == nil {
+		utils.LogError("Delivery receipt requested for undelivered order %d (status %s)", order.ID, order.Status)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Delivery receipt is available once the order is delivered", gin.H{
+			"status": order.Status,
+		})
+		return
+	}
+
+	timeline, err := orderTimeline(config.DB, order)
+	if err != nil {
+		utils.LogError("Failed to load timeline for order %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to generate delivery receipt", err.Error())
+		return
+	}
+
+	pdf := buildDeliveryReceiptPDF(order, timeline)
+	if err := writePDF(c, pdf, fmt.Sprintf("delivery-receipt-%d.pdf", order.ID)); err != nil {
+		utils.LogError("Failed to render delivery receipt for order %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to generate delivery receipt", err.Error())
+		return
+	}
+	utils.LogInfo("Delivery receipt generated for order ID: %d", order.ID)
+}
+
+// DownloadDeliveryReceipt returns the delivery receipt PDF of one of the user's orders
+func DownloadDeliveryReceipt(c *gin.Context) {
+	utils.LogInfo("DownloadDeliveryReceipt called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.LogError("Invalid order ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Preload("OrderItems.Book").Preload("Address").Preload("User").
+		Where("id = ? AND user_id = ?", orderID, user.ID).First(&order).Error; err != nil {
+		utils.LogError("Order not found - Order ID: %d, User ID: %d", orderID, user.ID)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return
+	}
+
+	sendDeliveryReceipt(c, order)
+}
+
+// AdminDownloadDeliveryReceipt returns the delivery receipt PDF of any order, e.g. for couriers
+func AdminDownloadDeliveryReceipt(c *gin.Context) {
+	utils.LogInfo("AdminDownloadDeliveryReceipt called")
+
+	if _, exists := c.Get("admin"); !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found", nil)
+		return
+	}
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.LogError("Invalid order ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Preload("OrderItems.Book").Preload("Address").Preload("User").
+		First(&order, orderID).Error; err != nil {
+		utils.LogError("Order not found - Order ID: %d", orderID)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return
+	}
+
+	sendDeliveryReceipt(c, order)
+}
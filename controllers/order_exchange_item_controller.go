@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ExchangeOrderItem requests a replacement for an item of a delivered order instead of a
+// refund. The request goes to an admin for approval, like a return.
+func ExchangeOrderItem(c *gin.Context) {
+	utils.LogInfo("ExchangeOrderItem called")
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.LogError("Invalid order ID format: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
+		return
+	}
+	itemID, err := strconv.Atoi(c.Param("item_id"))
+	if err != nil || itemID <= 0 {
+		utils.LogError("Invalid item ID format: %s", c.Param("item_id"))
+		utils.Fail(c, utils.CodeInvalidID, "Invalid item ID", nil)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Missing exchange reason for item ID: %d, order ID: %d: %v", itemID, orderID, err)
+		utils.Fail(c, utils.CodeReasonRequired, "Reason is required for exchange request", nil)
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Where("id = ? AND user_id = ?", orderID, user.ID).First(&order).Error; err != nil {
+		utils.LogError("Order not found - Order ID: %d: %v", orderID, err)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return
+	}
+	if order.Status != models.OrderStatusDelivered {
+		utils.LogError("Order cannot be exchanged - Order ID: %d, Status: %s", orderID, order.Status)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Items can only be exchanged after delivery", nil)
+		return
+	}
+
+	var item models.OrderItem
+	if err := config.DB.Preload("Book.Category").Where("id = ? AND order_id = ?", itemID, order.ID).First(&item).Error; err != nil {
+		utils.LogError("Order item not found - Order ID: %d, Item ID: %d", orderID, itemID)
+		utils.Fail(c, utils.CodeOrderItemNotFound, "Order item not found", nil)
+		return
+	}
+	if item.ExchangeStatus != "" && item.ExchangeStatus != models.ExchangeStatusRejected {
+		utils.LogError("Exchange already requested - Order ID: %d, Item ID: %d, Status: %s", orderID, itemID, item.ExchangeStatus)
+		utils.Fail(c, utils.CodeExchangeConflict, "Exchange already requested for this item", nil)
+		return
+	}
+	if item.ReturnRequested || item.CancellationStatus == "Cancelled" {
+		utils.LogError("Item already cancelled or returned - Order ID: %d, Item ID: %d", orderID, itemID)
+		utils.Fail(c, utils.CodeExchangeConflict, "This item has been cancelled or returned and cannot be exchanged", nil)
+		return
+	}
+
+	// Exchanges follow the category's return window, counted from delivery
+	exchangeWindow := utils.ReturnWindow(item.Book.Category)
+	deliveredAt := order.UpdatedAt
+	if order.DeliveredAt != nil {
+		deliveredAt = *order.DeliveredAt
+	}
+	if time.Since(deliveredAt) > exchangeWindow {
+		utils.LogError("Exchange window expired - Order ID: %d, Item ID: %d", orderID, itemID)
+		utils.Fail(c, utils.CodeOrderWindowExpired, fmt.Sprintf("Exchange window has expired (max %d days)", int(exchangeWindow.Hours()/24)), nil)
+		return
+	}
+
+	if err := config.DB.Model(&item).Updates(map[string]interface{}{
+		"exchange_status":        models.ExchangeStatusRequested,
+		"exchange_reason":        req.Reason,
+		"exchange_reject_reason": "",
+	}).Error; err != nil {
+		utils.LogError("Failed to request exchange - Item ID: %d: %v", itemID, err)
+		utils.InternalServerError(c, "Failed to submit exchange request", nil)
+		return
+	}
+
+	utils.LogInfo("Exchange requested for item ID: %d in order ID: %d by user ID: %d", itemID, orderID, user.ID)
+	utils.Success(c, "Exchange request submitted successfully", gin.H{
+		"item": gin.H{
+			"id":              item.ID,
+			"book_id":         item.BookID,
+			"name":            item.Book.Name,
+			"quantity":        item.Quantity,
+			"exchange_status": models.ExchangeStatusRequested,
+			"exchange_reason": req.Reason,
+		},
+		"note": "Your exchange request has been submitted. Once approved, a replacement will be shipped to your delivery address.",
+	})
+}
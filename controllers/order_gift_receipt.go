@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// buildGiftReceiptPDF renders the gift variant of the invoice for packing with a gift: the
+// recipient, books, quantities and gift message, without any prices
+func buildGiftReceiptPDF(order models.Order) *gofpdf.Fpdf {
+	pdf := newStorePDF("GIFT RECEIPT")
+
+	pdf.SetFont("Arial", "", 12)
+	pdf.Cell(50, 8, "Order ID: "+strconv.Itoa(int(order.ID)))
+	pdf.Cell(60, 8, "Order Date: "+order.CreatedAt.Format("2006-01-02"))
+	pdf.Ln(10)
+
+	writePDFAddress(pdf, "Gift For:", "", order.Address)
+	pdf.Ln(4)
+
+	if order.GiftMessage != "" {
+		pdf.SetFont("Arial", "B", 13)
+		pdf.Cell(100, 8, "Message:")
+		pdf.Ln(8)
+		pdf.SetFont("Arial", "I", 12)
+		pdf.MultiCell(0, 7, order.GiftMessage, "", "L", false)
+		pdf.Ln(6)
+	}
+
+	// Items, without prices
+	var rows [][]string
+	for _, item := range order.OrderItems {
+		if item.CancellationStatus == "Cancelled" || item.CancellationStatus == "Approved" {
+			continue
+		}
+		rows = append(rows, []string{item.Book.Name, strconv.Itoa(item.Quantity)})
+	}
+	writePDFTable(pdf, []pdfColumn{{"Book", 130, "L"}, {"Qty", 30, "C"}}, rows)
+	pdf.Ln(10)
+
+	pdf.SetFont("Arial", "I", 10)
+	pdf.Cell(0, 8, "A gift from ReadSphere. Prices are not shown on this receipt.")
+	return pdf
+}
+
+// sendGiftReceipt streams the gift receipt of a gift order
+func sendGiftReceipt(c *gin.Context, order models.Order) {
+	if !order.IsGift {
+		utils.LogError("Gift receipt requested for non-gift order %d", order.ID)
+		utils.BadRequest(c, "Gift receipts are only available for gift orders", nil)
+		return
+	}
+	if strings.EqualFold(order.Status, models.OrderStatusCancelled) {
+		utils.LogError("Gift receipt requested for cancelled order %d", order.ID)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Gift receipt is not available for cancelled orders", nil)
+		return
+	}
+
+	pdf := buildGiftReceiptPDF(order)
+	if err := writePDF(c, pdf, fmt.Sprintf("gift-receipt-%d.pdf", order.ID)); err != nil {
+		utils.LogError("Failed to render gift receipt for order %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to generate gift receipt", err.Error())
+		return
+	}
+	utils.LogInfo("Gift receipt generated for order ID: %d", order.ID)
+}
@@ -1,26 +1,25 @@
 package controllers
 
 import (
-	"bytes"
 	"fmt"
-	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
-	"github.com/jung-kurt/gofpdf"
 )
 
-// DownloadInvoice generates and returns a PDF invoice for the order
+// DownloadInvoice generates and returns a PDF invoice for the order, or the gift receipt
+// without prices with ?variant=gift
 func DownloadInvoice(c *gin.Context) {
 	utils.LogInfo("Starting invoice download process")
 
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("Unauthorized invoice download attempt - no user found in context")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
 		return
 	}
 	user := userVal.(models.User)
@@ -29,7 +28,7 @@ func DownloadInvoice(c *gin.Context) {
 	orderID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.LogError("Invalid order ID format in invoice download request: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
 		return
 	}
 	utils.LogInfo("Processing invoice download for order ID: %d", orderID)
@@ -37,38 +36,76 @@ func DownloadInvoice(c *gin.Context) {
 	var order models.Order
 	if err := config.DB.Preload("OrderItems.Book").Preload("Address").Preload("User").Where("id = ? AND user_id = ?", orderID, user.ID).First(&order).Error; err != nil {
 		utils.LogError("Order not found for invoice download - Order ID: %d, User ID: %d", orderID, user.ID)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 	utils.LogInfo("Found order for invoice generation - Order ID: %d", orderID)
 
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.AddPage()
+	// The gift variant leaves out prices so it can go in the parcel
+	if c.Query("variant") == "gift" {
+		sendGiftReceipt(c, order)
+		return
+	}
 
-	// Optional: Add logo (uncomment if logo.png exists)
-	//pdf.ImageOptions("logo.png", 150, 5, 55, 0, false, gofpdf.ImageOptions{}, 0, "")
+	if reason := invoiceUnavailableReason(order); reason != "" {
+		utils.LogError("Invoice not available for order ID: %d: %s", orderID, reason)
+		utils.BadRequest(c, reason, nil)
+		return
+	}
+	invoice, err := getOrIssueInvoice(order)
+	if err != nil {
+		utils.LogError("Failed to issue invoice for order ID: %d: %v", orderID, err)
+		utils.InternalServerError(c, "Failed to generate invoice", nil)
+		return
+	}
 
-	// Store info
-	pdf.SetFont("Arial", "B", 18)
-	pdf.Cell(100, 10, "Read Sphere")
+	// Invoice title and order info
+	pdf := newStorePDF("TAX INVOICE")
+
+	// QR code warehouse staff and customers scan to look the order up and verify the invoice
+	if err := drawQRCode(pdf, utils.OrderQRPayload(order.ID), 170, 10, 28); err != nil {
+		utils.LogError("Failed to draw QR code for order ID: %d: %v", orderID, err)
+	} else {
+		x, y := pdf.GetXY()
+		pdf.SetFont("Arial", "", 8)
+		pdf.Text(170, 42, utils.OrderCode(order.ID))
+		pdf.SetXY(x, y)
+	}
 	pdf.SetFont("Arial", "", 12)
+	pdf.Cell(95, 8, "Invoice No: "+invoice.InvoiceNumber)
+	pdf.Cell(95, 8, "Invoice Date: "+invoice.IssuedAt.Format("2006-01-02"))
 	pdf.Ln(8)
-	pdf.Cell(100, 8, "123 Main St, City, Country")
+	pdf.Cell(95, 8, "Order ID: "+strconv.Itoa(int(order.ID)))
+	pdf.Cell(95, 8, "Order Date: "+order.CreatedAt.Format("2006-01-02 15:04:05"))
 	pdf.Ln(8)
-	pdf.Cell(100, 8, "Email: support@readsphere.com | Phone: +91-12345-67890")
-	pdf.Ln(12)
+	pdf.Cell(95, 8, "Payment Method: "+order.PaymentMethod)
+	pdf.Cell(95, 8, "Status: "+order.Status)
+	pdf.Ln(10)
 
-	// Invoice title and order info
-	pdf.SetFont("Arial", "B", 16)
-	pdf.Cell(100, 10, "INVOICE")
-	pdf.Ln(12)
+	// Seller tax details
+	pdf.SetFont("Arial", "B", 13)
+	pdf.Cell(100, 8, "Sold By:")
+	pdf.Ln(7)
 	pdf.SetFont("Arial", "", 12)
-	pdf.Cell(50, 8, "Order ID: "+strconv.Itoa(int(order.ID)))
-	pdf.Cell(60, 8, "Order Date: "+order.CreatedAt.Format("2006-01-02 15:04:05"))
-	pdf.Ln(8)
-	pdf.Cell(50, 8, "Payment Method: "+order.PaymentMethod)
-	pdf.Cell(60, 8, "Status: "+order.Status)
-	pdf.Ln(8)
+	pdf.Cell(100, 8, invoice.SellerName)
+	pdf.Ln(6)
+	if invoice.SellerAddress != "" {
+		pdf.Cell(100, 8, invoice.SellerAddress)
+		pdf.Ln(6)
+	}
+	if invoice.SellerGSTIN != "" {
+		pdf.Cell(100, 8, "GSTIN: "+invoice.SellerGSTIN)
+		pdf.Ln(6)
+	}
+	if invoice.SellerState != "" {
+		state := invoice.SellerState
+		if invoice.SellerStateCode != "" {
+			state += " (" + invoice.SellerStateCode + ")"
+		}
+		pdf.Cell(100, 8, "State: "+state)
+		pdf.Ln(6)
+	}
+	pdf.Ln(2)
 
 	// Customer and shipping info
 	pdf.SetFont("Arial", "B", 13)
@@ -93,50 +130,107 @@ func DownloadInvoice(c *gin.Context) {
 		pdf.Ln(6)
 	}
 	pdf.Cell(100, 8, order.Address.City+", "+order.Address.State+", "+order.Address.Country+" - "+order.Address.PostalCode)
+	pdf.Ln(6)
+	pdf.Cell(100, 8, "Place of Supply: "+invoice.PlaceOfSupply)
 	pdf.Ln(10)
 
 	// Items table header
-	pdf.SetFont("Arial", "B", 12)
-	pdf.CellFormat(70, 8, "Book", "1", 0, "C", false, 0, "")
-	pdf.CellFormat(20, 8, "Qty", "1", 0, "C", false, 0, "")
-	pdf.CellFormat(30, 8, "Price", "1", 0, "C", false, 0, "")
+	pdf.SetFont("Arial", "B", 10)
+	pdf.CellFormat(62, 8, "Item", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(16, 8, "HSN", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(12, 8, "Qty", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(28, 8, "Taxable", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(16, 8, "Rate", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(26, 8, "Tax", "1", 0, "C", false, 0, "")
 	pdf.CellFormat(30, 8, "Total", "1", 0, "C", false, 0, "")
 	pdf.Ln(-1)
-	pdf.SetFont("Arial", "", 12)
-	for _, item := range order.OrderItems {
-		pdf.CellFormat(70, 8, item.Book.Name, "1", 0, "L", false, 0, "")
-		pdf.CellFormat(20, 8, strconv.Itoa(item.Quantity), "1", 0, "C", false, 0, "")
-		pdf.CellFormat(30, 8, fmt.Sprintf("%.2f", item.Price), "1", 0, "R", false, 0, "")
+	pdf.SetFont("Arial", "", 10)
+	for _, item := range invoice.Items {
+		pdf.CellFormat(62, 8, item.Description, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(16, 8, item.HSNCode, "1", 0, "C", false, 0, "")
+		pdf.CellFormat(12, 8, strconv.Itoa(item.Quantity), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(28, 8, fmt.Sprintf("%.2f", item.TaxableValue), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(16, 8, formatTaxRate(item.TaxRate), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(26, 8, fmt.Sprintf("%.2f", item.TaxAmount), "1", 0, "R", false, 0, "")
 		pdf.CellFormat(30, 8, fmt.Sprintf("%.2f", item.Total), "1", 0, "R", false, 0, "")
 		pdf.Ln(-1)
 	}
 
 	// Summary section
+	type summaryLine struct {
+		label  string
+		amount float64
+	}
+	summary := []summaryLine{
+		{"Subtotal:", order.TotalAmount},
+		{"Discount:", order.Discount + order.CouponDiscount},
+		{"Taxable Amount:", invoice.TaxableAmount},
+	}
+	if invoice.InterState {
+		summary = append(summary, summaryLine{"IGST:", invoice.IGSTAmount})
+	} else {
+		summary = append(summary, summaryLine{"CGST:", invoice.CGSTAmount}, summaryLine{"SGST:", invoice.SGSTAmount})
+	}
 	pdf.Ln(4)
-	pdf.SetFont("Arial", "B", 12)
-	pdf.CellFormat(120, 8, "Subtotal:", "", 0, "L", false, 0, "")
-	pdf.SetFont("Arial", "", 12)
-	pdf.CellFormat(30, 8, fmt.Sprintf("%.2f", order.TotalAmount), "", 1, "R", false, 0, "")
-	pdf.SetFont("Arial", "B", 12)
-	pdf.CellFormat(120, 8, "Discount:", "", 0, "L", false, 0, "")
-	pdf.SetFont("Arial", "", 12)
-	pdf.CellFormat(30, 8, fmt.Sprintf("%.2f", order.Discount), "", 1, "R", false, 0, "")
+	for _, line := range summary {
+		pdf.SetFont("Arial", "B", 12)
+		pdf.CellFormat(160, 8, line.label, "", 0, "L", false, 0, "")
+		pdf.SetFont("Arial", "", 12)
+		pdf.CellFormat(30, 8, fmt.Sprintf("%.2f", line.amount), "", 1, "R", false, 0, "")
+	}
 	pdf.SetFont("Arial", "B", 13)
-	pdf.CellFormat(120, 10, "Grand Total:", "", 0, "L", false, 0, "")
+	pdf.CellFormat(160, 10, "Grand Total:", "", 0, "L", false, 0, "")
 	pdf.SetFont("Arial", "B", 13)
-	pdf.CellFormat(30, 10, fmt.Sprintf("%.2f", order.FinalTotal), "", 1, "R", false, 0, "")
+	pdf.CellFormat(30, 10, fmt.Sprintf("%.2f", invoice.GrandTotal), "", 1, "R", false, 0, "")
+	pdf.SetFont("Arial", "I", 10)
+	pdf.Cell(0, 8, "Amounts are inclusive of GST.")
+	pdf.Ln(6)
 
 	// Thank you note
 	pdf.Ln(10)
 	pdf.SetFont("Arial", "I", 12)
 	pdf.Cell(0, 10, "Thank you for shopping with ReadSphere!")
 
-	var buf bytes.Buffer
-	_ = pdf.Output(&buf)
-	utils.LogInfo("PDF invoice generated successfully for order ID: %d", orderID)
-
-	c.Header("Content-Type", "application/pdf")
-	c.Header("Content-Disposition", "attachment; filename=invoice.pdf")
-	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+	if err := writePDF(c, pdf, "invoice-"+strings.ReplaceAll(invoice.InvoiceNumber, "/", "-")+".pdf"); err != nil {
+		utils.LogError("Failed to generate invoice PDF for order ID: %d: %v", orderID, err)
+		utils.InternalServerError(c, "Failed to generate invoice", nil)
+		return
+	}
 	utils.LogInfo("Invoice download completed for order ID: %d", orderID)
 }
+
+// GetInvoice returns the order's tax invoice as JSON, issuing it on first request
+func GetInvoice(c *gin.Context) {
+	utils.LogInfo("GetInvoice called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var order models.Order
+	if err := config.DB.Preload("OrderItems.Book").Preload("Address").Preload("User").
+		Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&order).Error; err != nil {
+		utils.LogError("Order not found for invoice - Order ID: %s, User ID: %d", c.Param("id"), user.ID)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return
+	}
+
+	if reason := invoiceUnavailableReason(order); reason != "" {
+		utils.BadRequest(c, reason, nil)
+		return
+	}
+	invoice, err := getOrIssueInvoice(order)
+	if err != nil {
+		utils.LogError("Failed to issue invoice for order ID: %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to generate invoice", err.Error())
+		return
+	}
+
+	utils.Success(c, "Invoice retrieved successfully", gin.H{
+		"invoice": invoiceResponse(utils.NewResponseFormatter(c), invoice, order),
+	})
+}
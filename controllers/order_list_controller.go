@@ -2,24 +2,41 @@ package controllers
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"math"
 	"strconv"
 	"time"
 
-	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/repositories"
+	"github.com/Govind-619/ReadSphere/services"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
 )
 
+// OrderHandler serves customers' own orders and their return requests
+type OrderHandler struct {
+	orders *services.OrderService
+}
+
+// NewOrderHandler returns an OrderHandler using orders
+func NewOrderHandler(orders *services.OrderService) *OrderHandler {
+	return &OrderHandler{orders: orders}
+}
+
+// userOrderSummary is an order in the customer's order list
+type userOrderSummary struct {
+	utils.OrderSummary
+	Date string `json:"date"`
+}
+
 // ListOrders lists all orders for the logged-in user, with optional search by ID/date/status
-func ListOrders(c *gin.Context) {
+func (h *OrderHandler) ListOrders(c *gin.Context) {
 	utils.LogInfo("ListOrders called")
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "Unauthorized")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
 		return
 	}
 	user := userVal.(models.User)
@@ -43,74 +60,65 @@ func ListOrders(c *gin.Context) {
 		order = "desc"
 	}
 
-	// Build base query
-	query := config.DB.Model(&models.Order{}).Where("user_id = ?", user.ID)
-
-	// Optional filters
-	if id := c.Query("id"); id != "" {
-		query = query.Where("id = ?", id)
-		utils.LogDebug("Filtering by order ID: %s", id)
-	}
-	if status := c.Query("status"); status != "" {
-		query = query.Where("status = ?", status)
-		utils.LogDebug("Filtering by status: %s", status)
-	}
-	if date := c.Query("date"); date != "" {
-		query = query.Where("DATE(created_at) = ?", date)
-		utils.LogDebug("Filtering by date: %s", date)
+	filter := repositories.OrderFilter{
+		ID:     c.Query("id"),
+		Status: c.Query("status"),
+		Date:   c.Query("date"),
+		SortBy: sortBy,
+		Order:  order,
+		Offset: (page - 1) * limit,
+		Limit:  limit,
+	}
+
+	// Cursor pagination reads the orders past a cursor instead of skipping rows
+	column := repositories.OrderSortColumn(sortBy)
+	cursor, cursorMode, err := utils.GetCursorParams(c, sortBy, order)
+	if err == nil && cursorMode {
+		filter.Keyset = &repositories.OrderKeyset{Backward: cursor.Backward}
+		if cursor.Cursor != nil {
+			filter.Keyset.ID = cursor.Cursor.ID
+			filter.Keyset.Value, err = orderCursorKey(cursor, column)
+		}
+		filter.Offset, filter.Limit = 0, cursor.Limit+1
 	}
-
-	// Get total count
-	var total int64
-	if err := query.Count(&total).Error; err != nil {
-		utils.LogError("Failed to count orders for user ID: %d: %v", user.ID, err)
-		utils.InternalServerError(c, "Failed to count orders", nil)
+	if err != nil {
+		utils.LogError("Invalid cursor: %v", err)
+		utils.BadRequest(c, "Invalid cursor", "The cursor is malformed or belongs to another sort order")
 		return
 	}
-	utils.LogDebug("Total orders found: %d", total)
-
-	// Apply sorting
-	switch sortBy {
-	case "id", "status", "created_at":
-		query = query.Order(fmt.Sprintf("%s %s", sortBy, order))
-	case "final_total":
-		query = query.Order(fmt.Sprintf("total_with_delivery %s", order))
-	default:
-		query = query.Order(fmt.Sprintf("created_at %s", order))
-	}
 
-	// Apply pagination
-	offset := (page - 1) * limit
-	var orders []models.Order
-	if err := query.Offset(offset).Limit(limit).Preload("OrderItems.Book").Find(&orders).Error; err != nil {
+	orders, total, err := h.orders.List(user.ID, filter)
+	if err != nil {
 		utils.LogError("Failed to fetch orders for user ID: %d: %v", user.ID, err)
 		utils.InternalServerError(c, "Failed to fetch orders", nil)
 		return
 	}
-	utils.LogDebug("Retrieved %d orders for user ID: %d", len(orders), user.ID)
+	utils.LogDebug("Retrieved %d of %d orders for user ID: %d", len(orders), total, user.ID)
+
+	pagination := gin.H{
+		"current_page": page,
+		"per_page":     limit,
+		"total":        total,
+		"total_pages":  int(math.Ceil(float64(total) / float64(limit))),
+	}
+	if cursorMode {
+		orders, pagination = orderCursorPage(orders, cursor, column)
+	}
 
 	// Prepare order summaries
-	summaries := make([]gin.H, 0, len(orders))
+	formatter := utils.NewResponseFormatter(c)
+	summaries := make([]userOrderSummary, 0, len(orders))
 	for _, o := range orders {
-		summaries = append(summaries, gin.H{
-			"id":           o.ID,
-			"date":         o.CreatedAt.Format("2006-01-02 15:04:05"),
-			"status":       o.Status,
-			"final_total":  fmt.Sprintf("%.2f", o.TotalWithDelivery),
-			"item_count":   len(o.OrderItems),
-			"payment_mode": o.PaymentMethod,
-		})
+		summary := userOrderSummary{OrderSummary: utils.NewOrderSummary(formatter, o), Date: formatter.Timestamp(o.CreatedAt)}
+		// Customers see what they paid, delivery included, as the order total
+		summary.FinalTotal = formatter.Money(o.TotalWithDelivery)
+		summaries = append(summaries, summary)
 	}
 
 	utils.LogInfo("Successfully retrieved orders for user ID: %d", user.ID)
 	utils.Success(c, "Orders retrieved successfully", gin.H{
-		"orders": summaries,
-		"pagination": gin.H{
-			"current_page": page,
-			"per_page":     limit,
-			"total":        total,
-			"total_pages":  int(math.Ceil(float64(total) / float64(limit))),
-		},
+		"orders":     summaries,
+		"pagination": pagination,
 		"filters": gin.H{
 			"status": c.Query("status"),
 			"date":   c.Query("date"),
@@ -124,49 +132,57 @@ func ListOrders(c *gin.Context) {
 }
 
 // GetOrderDetails returns detailed info for a specific order
-func GetOrderDetails(c *gin.Context) {
+func (h *OrderHandler) GetOrderDetails(c *gin.Context) {
 	utils.LogInfo("GetOrderDetails called")
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "Unauthorized")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
 		return
 	}
 	user := userVal.(models.User)
 	orderID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.LogError("Invalid order ID format: %v", err)
-		utils.BadRequest(c, "Invalid order ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
 		return
 	}
 	utils.LogInfo("Processing order details for order ID: %d, user ID: %d", orderID, user.ID)
 
-	var order models.Order
-	if err := config.DB.Preload("OrderItems.Book").Preload("Address").Preload("User").Where("id = ? AND user_id = ?", orderID, user.ID).First(&order).Error; err != nil {
-		utils.LogError("Order not found - Order ID: %d, User ID: %d: %v", orderID, user.ID, err)
-		utils.NotFound(c, "Order not found")
+	order, err := h.orders.Get(user.ID, uint(orderID))
+	if errors.Is(err, repositories.ErrNotFound) {
+		utils.LogError("Order not found - Order ID: %d, User ID: %d", orderID, user.ID)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to fetch order - Order ID: %d, User ID: %d: %v", orderID, user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch order", nil)
 		return
 	}
 	utils.LogDebug("Found order ID: %d with %d items", orderID, len(order.OrderItems))
 
 	// Prepare minimal items with IDs
 	items := make([]gin.H, 0, len(order.OrderItems))
+	formatter := utils.NewResponseFormatter(c)
 	for _, item := range order.OrderItems {
 		items = append(items, gin.H{
 			"id":          item.ID,
 			"book_id":     item.BookID,
 			"name":        item.Book.Name,
 			"quantity":    item.Quantity,
-			"price":       fmt.Sprintf("%.2f", item.Price),
-			"discount":    fmt.Sprintf("%.2f", item.Discount),
-			"final_price": fmt.Sprintf("%.2f", (item.Total-item.CouponDiscount)/float64(item.Quantity)),
-			"total":       fmt.Sprintf("%.2f", item.Total-item.CouponDiscount),
+			"price":       formatter.Money(item.Price),
+			"discount":    formatter.Money(item.Discount),
+			"final_price": formatter.Money((item.Total - item.CouponDiscount) / float64(item.Quantity)),
+			"total":       formatter.Money(item.Total - item.CouponDiscount),
 			"status": gin.H{
 				"cancellation_requested": item.CancellationRequested,
 				"cancellation_status":    item.CancellationStatus,
 				"return_requested":       item.ReturnRequested,
 				"return_status":          item.ReturnStatus,
+				"exchange_status":        item.ExchangeStatus,
 			},
+			"shipment_id": item.ShipmentID,
 		})
 	}
 
@@ -179,26 +195,18 @@ func GetOrderDetails(c *gin.Context) {
 		"postal_code": order.Address.PostalCode,
 	}
 
-	// Calculate action availability with better logic
-	timeSinceOrder := time.Since(order.CreatedAt)
+	actions := h.orders.Actions(order, time.Now())
 
-	// For cancel action: check if order is within 30 minutes AND has appropriate status
-	// Handle cases where order creation time might be in the future or there are timezone issues
-	canCancel := false
-
-	// Check if order creation time is in the future (timezone/system clock issue)
-	if timeSinceOrder < 0 {
-		utils.LogError("Order ID: %d has future creation time: %s (time since order: %v)",
-			order.ID, order.CreatedAt.Format("2006-01-02 15:04:05"), timeSinceOrder)
-	} else if timeSinceOrder > 30*time.Minute {
-		// Cancellation window expired
-	} else if order.Status != models.OrderStatusPlaced && order.Status != models.OrderStatusPaid {
-		// Order status doesn't allow cancellation
-	} else {
-		canCancel = true
+	// A split order cannot be cancelled as a whole once one of its shipments has left
+	shipments, err := h.orders.Shipments(order.ID)
+	if err != nil {
+		utils.LogError("Failed to fetch shipments - Order ID: %d: %v", orderID, err)
+	}
+	for _, shipment := range shipments {
+		if shipment.Status != models.ShipmentStatusPending {
+			actions.CanCancel = false
+		}
 	}
-
-	canReturn := order.Status == models.OrderStatusDelivered
 
 	// Unmarshal original_details if present
 	var originalDetailsObj interface{}
@@ -210,22 +218,26 @@ func GetOrderDetails(c *gin.Context) {
 	}
 
 	resp := gin.H{
-		"order_id":        order.ID,
-		"date":            order.CreatedAt.Format("2006-01-02 15:04:05"),
-		"status":          order.Status,
-		"payment_mode":    order.PaymentMethod,
-		"address":         address,
-		"items":           items,
-		"initial_amount":  fmt.Sprintf("%.2f", order.TotalAmount),
-		"discount":        fmt.Sprintf("%.2f", order.Discount),
-		"coupon_discount": fmt.Sprintf("%.2f", order.CouponDiscount),
-		"coupon_code":     order.CouponCode,
-		"subtotal":        fmt.Sprintf("%.2f", order.FinalTotal),
-		"delivery_charge": fmt.Sprintf("%.2f", order.DeliveryCharge),
-		"final_total":     fmt.Sprintf("%.2f", order.TotalWithDelivery),
+		"order_id":          order.ID,
+		"date":              formatter.Timestamp(order.CreatedAt),
+		"status":            order.Status,
+		"payment_mode":      order.PaymentMethod,
+		"address":           address,
+		"items":             items,
+		"initial_amount":    formatter.Money(order.TotalAmount),
+		"discount":          formatter.Money(order.Discount),
+		"coupon_discount":   formatter.Money(order.CouponDiscount),
+		"coupon_code":       order.CouponCode,
+		"subtotal":          formatter.Money(order.FinalTotal),
+		"delivery_charge":   formatter.Money(order.DeliveryCharge),
+		"final_total":       formatter.Money(order.TotalWithDelivery),
+		"delivery_note":     order.DeliveryNote,
+		"gift":              giftOptionsResponse(formatter, *order),
+		"delivery_schedule": deliveryScheduleResponse(*order),
+		"shipments":         formatOrderShipments(formatter, shipments, order.OrderItems),
 		"actions": gin.H{
-			"can_cancel": canCancel,
-			"can_return": canReturn,
+			"can_cancel": actions.CanCancel,
+			"can_return": actions.CanReturn,
 		},
 		"original_details": originalDetailsObj,
 	}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// paymentDeadline returns when an unpaid online order is cancelled
+func paymentDeadline(order models.Order) time.Time {
+	return order.CreatedAt.Add(onlinePaymentWindow)
+}
+
+// paymentWindowExpired reports whether the order can no longer be paid
+func paymentWindowExpired(order models.Order) bool {
+	return time.Now().After(paymentDeadline(order))
+}
+
+// cancelExpiredPaymentOrder cancels an order whose payment window has passed and
+// tells the client so
+func cancelExpiredPaymentOrder(c *gin.Context, order *models.Order) {
+	if err := config.DB.Preload("OrderItems").First(order, order.ID).Error; err != nil {
+		utils.LogError("Failed to load order items for order ID: %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to cancel order", err.Error())
+		return
+	}
+
+	tx := config.DB.Begin()
+	if err := cancelUnpaidOrder(tx, order, "Payment not completed within the payment window"); err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to cancel expired order ID: %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to cancel order", err.Error())
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit cancellation of order ID: %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to cancel order", err.Error())
+		return
+	}
+
+	utils.LogInfo("Order ID: %d cancelled, payment window expired", order.ID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Fail(c, utils.CodeOrderWindowExpired, "Payment window has expired and the order was cancelled", gin.H{
+		"order_id":           order.ID,
+		"status":             order.Status,
+		"payment_expired_at": formatter.Timestamp(paymentDeadline(*order)),
+	})
+}
+
+// RetryOrderPayment creates a new Razorpay order for an unpaid online order so the user can
+// pay again after a failed or abandoned attempt. Past the payment window the order is
+// cancelled and its stock released instead.
+func RetryOrderPayment(c *gin.Context) {
+	utils.LogInfo("RetryOrderPayment called")
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var order models.Order
+	if err := config.DB.Preload("Address").Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&order).Error; err != nil {
+		utils.LogError("Order not found for ID: %s, user ID: %d", c.Param("id"), user.ID)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return
+	}
+
+	if order.Status != models.OrderStatusPlaced {
+		utils.LogError("Order ID: %d is not awaiting payment, status: %s", order.ID, order.Status)
+		utils.BadRequest(c, "This order is not awaiting payment", gin.H{
+			"status": order.Status,
+		})
+		return
+	}
+	if order.PaymentMethod != "RAZORPAY" && order.PaymentMethod != "online" {
+		utils.LogError("Order ID: %d has no online payment to retry, method: %q", order.ID, order.PaymentMethod)
+		utils.Fail(c, utils.CodePaymentNotInitiated, "Payment has not been initiated for this order", nil)
+		return
+	}
+	if order.PaymentStatus == models.PaymentStatusCompleted {
+		utils.Fail(c, utils.CodePaymentCompleted, "Payment for this order has already been completed", nil)
+		return
+	}
+
+	if paymentWindowExpired(order) {
+		cancelExpiredPaymentOrder(c, &order)
+		return
+	}
+
+	razorpayOrderID, err := createRazorpayOrder(order)
+	if err != nil {
+		utils.LogError("Failed to create Razorpay order for retry of order ID: %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to create Razorpay order", err.Error())
+		return
+	}
+
+	// The previous Razorpay order is replaced, so a late verification of it is rejected
+	result := config.DB.Model(&models.Order{}).
+		Where("id = ? AND status = ?", order.ID, models.OrderStatusPlaced).
+		Updates(map[string]interface{}{
+			"payment_method":    "RAZORPAY",
+			"razorpay_order_id": razorpayOrderID,
+			"payment_status":    models.PaymentStatusPending,
+			"payment_attempts":  order.PaymentAttempts + 1,
+		})
+	if result.Error != nil {
+		utils.LogError("Failed to update order ID: %d for payment retry: %v", order.ID, result.Error)
+		utils.InternalServerError(c, "Failed to update order details", result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.Fail(c, utils.CodeOrderNotAwaitingPay, "This order is no longer awaiting payment", nil)
+		return
+	}
+
+	utils.LogInfo("Payment retry %d initiated for order ID: %d", order.PaymentAttempts+1, order.ID)
+	formatter := utils.NewResponseFormatter(c)
+	response := razorpayCheckoutResponse(formatter, user, order, razorpayOrderID)
+	response["payment_attempts"] = order.PaymentAttempts + 1
+	utils.Success(c, "Payment retry initiated successfully", response)
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// Reasons an ordered book could not be added back to the cart in full
+const (
+	reorderReasonNotFound    = "Book no longer exists"
+	reorderReasonUnavailable = "Book is no longer available"
+	reorderReasonOutOfStock  = "Out of stock"
+	reorderReasonLowStock    = "Not enough stock"
+	reorderReasonCartLimit   = "Cart quantity limit reached"
+)
+
+// Reorder adds the books of a past order back to the user's cart. Books that are no longer
+// available or out of stock are skipped, and quantities are cut down to the stock and the
+// cart limit; the response lists what could not be added in full.
+func Reorder(c *gin.Context) {
+	utils.LogInfo("Reorder called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	userID := userVal.(models.User).ID
+
+	var order models.Order
+	if err := config.DB.Preload("OrderItems").Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&order).Error; err != nil {
+		utils.LogError("Order not found for ID: %s, user ID: %d", c.Param("id"), userID)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return
+	}
+	utils.LogInfo("Reordering order ID: %d for user ID: %d", order.ID, userID)
+
+	// An order can list the same book more than once, e.g. after an exchange
+	var bookIDs []uint
+	quantities := make(map[uint]int)
+	for _, item := range order.OrderItems {
+		if _, seen := quantities[item.BookID]; !seen {
+			bookIDs = append(bookIDs, item.BookID)
+		}
+		quantities[item.BookID] += item.Quantity
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to start transaction: %v", tx.Error)
+		utils.InternalServerError(c, "Failed to start transaction", nil)
+		return
+	}
+
+	maxQuantity := utils.MaxCartQuantity()
+	added := make([]gin.H, 0, len(bookIDs))
+	skipped := make([]gin.H, 0)
+	for _, bookID := range bookIDs {
+		requested := quantities[bookID]
+
+		var book models.Book
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Category").First(&book, bookID).Error; err != nil {
+			skipped = append(skipped, gin.H{"book_id": bookID, "requested": requested, "added": 0, "reason": reorderReasonNotFound})
+			continue
+		}
+		if !book.IsActive || book.Blocked || book.Category.Blocked {
+			skipped = append(skipped, gin.H{"book_id": bookID, "title": book.Name, "requested": requested, "added": 0, "reason": reorderReasonUnavailable})
+			continue
+		}
+
+		var cartItem models.Cart
+		inCart := 0
+		if err := tx.Where("user_id = ? AND book_id = ?", userID, bookID).First(&cartItem).Error; err == nil {
+			inCart = cartItem.Quantity
+		}
+
+		// Add as many copies as the stock and the cart limit allow
+		quantity := requested
+		reason := ""
+		if inCart+quantity > maxQuantity {
+			quantity = maxQuantity - inCart
+			reason = reorderReasonCartLimit
+		}
+		if inCart+quantity > book.Stock {
+			quantity = book.Stock - inCart
+			reason = reorderReasonLowStock
+			if book.Stock < 1 {
+				reason = reorderReasonOutOfStock
+			}
+		}
+		if quantity < 1 {
+			skipped = append(skipped, gin.H{"book_id": bookID, "title": book.Name, "requested": requested, "added": 0, "reason": reason})
+			continue
+		}
+
+		offer, _ := utils.GetOfferBreakdownForBook(book.ID, book.CategoryID)
+		priced := utils.PriceLine(book, inCart+quantity, offer)
+		cartItem.UserID = userID
+		cartItem.BookID = bookID
+		cartItem.Quantity = inCart + quantity
+		cartItem.PriceAtAdd = priced.OfferUnitPrice
+		cartItem.StockStatusAtAdd = priced.StockStatus
+		if err := tx.Save(&cartItem).Error; err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to add book ID: %d to cart for user ID: %d: %v", bookID, userID, err)
+			utils.InternalServerError(c, "Failed to add items to cart", nil)
+			return
+		}
+		if err := tx.Where("user_id = ? AND book_id = ?", userID, bookID).Delete(&models.Wishlist{}).Error; err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to remove from wishlist for book ID: %d: %v", bookID, err)
+			utils.InternalServerError(c, "Failed to update wishlist", nil)
+			return
+		}
+
+		added = append(added, gin.H{"book_id": bookID, "title": book.Name, "quantity": quantity})
+		if quantity < requested {
+			skipped = append(skipped, gin.H{"book_id": bookID, "title": book.Name, "requested": requested, "added": quantity, "reason": reason})
+		}
+	}
+
+	if len(added) == 0 {
+		tx.Rollback()
+		utils.LogError("No items of order ID: %d could be added to the cart for user ID: %d", order.ID, userID)
+		utils.Fail(c, utils.CodeBookUnavailable, "None of the items in this order can be added to the cart", gin.H{
+			"skipped": skipped,
+		})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit transaction for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to add items to cart", nil)
+		return
+	}
+
+	details, err := utils.NewPricingEngine(config.DB).PriceCart(userID)
+	if err != nil {
+		utils.LogError("Failed to fetch updated cart for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to fetch updated cart", nil)
+		return
+	}
+
+	message := "Order items added to cart"
+	if len(skipped) > 0 {
+		message = "Some order items could not be added to cart"
+	}
+	utils.LogInfo("Reorder of order ID: %d for user ID: %d added %d books, skipped %d", order.ID, userID, len(added), len(skipped))
+	formatter := utils.NewResponseFormatter(c)
+	response := cartSummaryResponse(formatter, details)
+	response["added"] = added
+	response["skipped"] = skipped
+	utils.Success(c, message, response)
+}
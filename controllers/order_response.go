@@ -2,6 +2,8 @@ package controllers
 
 import (
 	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
 )
 
 type OrderBookMinimal struct {
@@ -46,3 +48,16 @@ type PlaceOrderMinimalResponse struct {
 	RedirectURL  string                 `json:"redirect_url"`
 	ThankYouPage map[string]interface{} `json:"thank_you_page"`
 }
+
+// giftOptionsResponse is the gift section of order details, or nil for orders that are not gifts
+func giftOptionsResponse(f utils.ResponseFormatter, order models.Order) gin.H {
+	if !order.IsGift {
+		return nil
+	}
+	return gin.H{
+		"is_gift":       true,
+		"gift_wrap":     order.GiftWrap,
+		"gift_wrap_fee": f.Money(order.GiftWrapFee),
+		"gift_message":  order.GiftMessage,
+	}
+}
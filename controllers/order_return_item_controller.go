@@ -16,7 +16,7 @@ func ReturnOrderItem(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "Unauthorized")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
 		return
 	}
 	user := userVal.(models.User)
@@ -25,7 +25,7 @@ func ReturnOrderItem(c *gin.Context) {
 	orderID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.LogError("Invalid order ID format: %v", err)
-		utils.BadRequest(c, "Invalid order ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
 		return
 	}
 	utils.LogDebug("Processing return for order ID: %d", orderID)
@@ -35,7 +35,7 @@ func ReturnOrderItem(c *gin.Context) {
 	_, err = fmt.Sscanf(itemIDStr, "%d", &itemID)
 	if err != nil || itemID == 0 {
 		utils.LogError("Invalid item ID format: %s", itemIDStr)
-		utils.BadRequest(c, "Invalid item ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid item ID", nil)
 		return
 	}
 	utils.LogDebug("Processing return for item ID: %d in order ID: %d", itemID, orderID)
@@ -45,7 +45,7 @@ func ReturnOrderItem(c *gin.Context) {
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Missing return reason for item ID: %d, order ID: %d: %v", itemID, orderID, err)
-		utils.BadRequest(c, "Reason is required for return request", nil)
+		utils.Fail(c, utils.CodeReasonRequired, "Reason is required for return request", nil)
 		return
 	}
 	utils.LogDebug("Return reason received for item ID: %d", itemID)
@@ -64,7 +64,7 @@ func ReturnOrderItem(c *gin.Context) {
 	if err := tx.Preload("OrderItems").First(&order, orderID).Error; err != nil {
 		utils.LogError("Order not found - Order ID: %d: %v", orderID, err)
 		tx.Rollback()
-		utils.NotFound(c, "Order not found")
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 	utils.LogDebug("Found order ID: %d with %d items", orderID, len(order.OrderItems))
@@ -80,7 +80,7 @@ func ReturnOrderItem(c *gin.Context) {
 	if order.Status != models.OrderStatusDelivered {
 		utils.LogError("Order cannot be returned - Order ID: %d, Status: %s", orderID, order.Status)
 		tx.Rollback()
-		utils.BadRequest(c, "Items can only be returned after delivery", nil)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Items can only be returned after delivery", nil)
 		return
 	}
 	utils.LogDebug("Order status verified for return - Order ID: %d", orderID)
@@ -99,7 +99,7 @@ func ReturnOrderItem(c *gin.Context) {
 	if !found {
 		utils.LogError("Order item not found - Order ID: %d, Item ID: %d", orderID, itemID)
 		tx.Rollback()
-		utils.NotFound(c, "Order item not found")
+		utils.Fail(c, utils.CodeOrderItemNotFound, "Order item not found", nil)
 		return
 	}
 	utils.LogDebug("Found item ID: %d in order ID: %d", itemID, orderID)
@@ -108,7 +108,13 @@ func ReturnOrderItem(c *gin.Context) {
 	if item.ReturnRequested {
 		utils.LogError("Return already requested - Order ID: %d, Item ID: %d", orderID, itemID)
 		tx.Rollback()
-		utils.BadRequest(c, "Return already requested for this item", nil)
+		utils.Fail(c, utils.CodeReturnAlreadyExists, "Return already requested for this item", nil)
+		return
+	}
+	if item.ExchangeStatus != "" && item.ExchangeStatus != models.ExchangeStatusRejected {
+		utils.LogError("Item has an exchange in progress - Order ID: %d, Item ID: %d, Status: %s", orderID, itemID, item.ExchangeStatus)
+		tx.Rollback()
+		utils.Fail(c, utils.CodeExchangeConflict, "This item has an exchange request and cannot be returned", nil)
 		return
 	}
 
@@ -145,18 +151,19 @@ func ReturnOrderItem(c *gin.Context) {
 	// Note: Stock will be restored when admin approves the return request
 	// Do not restore stock immediately as return requires admin approval
 
+	formatter := utils.NewResponseFormatter(c)
 	// Prepare response - returns should show pending status since they require admin approval
 	itemResponse := gin.H{
 		"id":            item.ID,
 		"return_status": "Pending",
 		"return_reason": req.Reason,
-		"refund_amount": fmt.Sprintf("%.2f", refundAmount),
+		"refund_amount": formatter.Money(refundAmount),
 		"refund_details": gin.H{
-			"item_total":       fmt.Sprintf("%.2f", item.Price*float64(item.Quantity)),
-			"item_discount":    fmt.Sprintf("%.2f", item.Discount),
-			"coupon_discount":  fmt.Sprintf("%.2f", item.CouponDiscount),
-			"final_price_paid": fmt.Sprintf("%.2f", item.Total-item.CouponDiscount),
-			"total_refunded":   fmt.Sprintf("%.2f", refundAmount),
+			"item_total":       formatter.Money(item.Price * float64(item.Quantity)),
+			"item_discount":    formatter.Money(item.Discount),
+			"coupon_discount":  formatter.Money(item.CouponDiscount),
+			"final_price_paid": formatter.Money(item.Total - item.CouponDiscount),
+			"total_refunded":   formatter.Money(refundAmount),
 			"refund_status":    "pending",
 			"refunded_to":      "wallet",
 		},
@@ -183,13 +190,13 @@ func ReturnOrderItem(c *gin.Context) {
 		"item": itemResponse,
 		"order": gin.H{
 			"id":                  order.ID,
-			"total_amount":        fmt.Sprintf("%.2f", order.TotalAmount),
-			"discount":            fmt.Sprintf("%.2f", order.Discount),
-			"coupon_discount":     fmt.Sprintf("%.2f", order.CouponDiscount),
+			"total_amount":        formatter.Money(order.TotalAmount),
+			"discount":            formatter.Money(order.Discount),
+			"coupon_discount":     formatter.Money(order.CouponDiscount),
 			"coupon_code":         order.CouponCode,
-			"delivery_charge":     fmt.Sprintf("%.2f", order.DeliveryCharge),
-			"total_with_delivery": fmt.Sprintf("%.2f", projectedFinalTotal+order.DeliveryCharge),
-			"final_total":         fmt.Sprintf("%.2f", projectedFinalTotal),
+			"delivery_charge":     formatter.Money(order.DeliveryCharge),
+			"total_with_delivery": formatter.Money(projectedFinalTotal + order.DeliveryCharge),
+			"final_total":         formatter.Money(projectedFinalTotal),
 		},
 		"note": "Your return request has been submitted. Our team will review it and process accordingly. The order totals shown above reflect the projected amounts after return processing.",
 	})
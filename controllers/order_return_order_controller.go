@@ -1,23 +1,25 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
-	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/repositories"
+	"github.com/Govind-619/ReadSphere/services"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
 )
 
 // ReturnOrder allows user to request a return for all items in a delivered order
-func ReturnOrder(c *gin.Context) {
+func (h *OrderHandler) ReturnOrder(c *gin.Context) {
 	utils.LogInfo("ReturnOrder called")
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "Unauthorized")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
 		return
 	}
 	user := userVal.(models.User)
@@ -26,7 +28,7 @@ func ReturnOrder(c *gin.Context) {
 	orderID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.LogError("Invalid order ID format: %v", err)
-		utils.BadRequest(c, "Invalid order ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
 		return
 	}
 	utils.LogDebug("Processing return for order ID: %d", orderID)
@@ -36,89 +38,38 @@ func ReturnOrder(c *gin.Context) {
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Missing return reason for order ID: %d: %v", orderID, err)
-		utils.BadRequest(c, "Return reason is required", nil)
+		utils.Fail(c, utils.CodeReasonRequired, "Return reason is required", nil)
 		return
 	}
 	utils.LogDebug("Return reason received for order ID: %d", orderID)
 
-	// Get order with items and their categories
-	var order models.Order
-	if err := config.DB.Preload("OrderItems.Book.Category").Where("id = ? AND user_id = ?", orderID, user.ID).First(&order).Error; err != nil {
-		utils.LogError("Order not found - Order ID: %d: %v", orderID, err)
-		utils.NotFound(c, "Order not found")
+	order, err := h.orders.RequestReturn(user.ID, uint(orderID), req.Reason, time.Now())
+	var expired *services.ReturnWindowExpiredError
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		utils.LogError("Order not found - Order ID: %d", orderID)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
-	}
-	utils.LogDebug("Found order ID: %d with %d items", orderID, len(order.OrderItems))
-
-	if order.Status != models.OrderStatusDelivered {
+	case errors.Is(err, services.ErrOrderNotDelivered):
 		utils.LogError("Order cannot be returned - Order ID: %d, Status: %s", orderID, order.Status)
-		utils.BadRequest(c, "Only delivered orders can be returned", nil)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Only delivered orders can be returned", nil)
 		return
-	}
-	utils.LogDebug("Order status verified for return - Order ID: %d", orderID)
-
-	// Check return window for each item
-	for _, item := range order.OrderItems {
-		returnWindow := 7 * 24 * time.Hour // Default 7 days
-		if item.Book.Category.ReturnWindow > 0 {
-			returnWindow = time.Duration(item.Book.Category.ReturnWindow) * 24 * time.Hour
-		}
-		if time.Since(order.UpdatedAt) > returnWindow {
-			utils.LogError("Return window expired - Order ID: %d, Item ID: %d, Window: %d days",
-				orderID, item.ID, int(returnWindow.Hours()/24))
-			utils.BadRequest(c, fmt.Sprintf("Return window has expired for some items (max %d days)",
-				int(returnWindow.Hours()/24)), nil)
-			return
-		}
-	}
-	utils.LogDebug("Return window verified for all items in order ID: %d", orderID)
-
-	// Start transaction
-	tx := config.DB.Begin()
-	if tx.Error != nil {
-		utils.LogError("Failed to begin transaction for order ID: %d: %v", orderID, tx.Error)
-		utils.InternalServerError(c, "Failed to begin transaction", nil)
+	case errors.Is(err, services.ErrExchangeInProgress):
+		utils.LogError("Item has an exchange in progress - Order ID: %d", orderID)
+		utils.Fail(c, utils.CodeExchangeConflict, "Some items have an exchange request, return them individually instead", nil)
 		return
-	}
-	utils.LogDebug("Started transaction for order return - Order ID: %d", orderID)
-
-	// Mark all items for return
-	for _, item := range order.OrderItems {
-		item.ReturnRequested = true
-		item.ReturnReason = req.Reason
-		item.ReturnStatus = "Pending"
-		if err := tx.Save(&item).Error; err != nil {
-			utils.LogError("Failed to update order item - Order ID: %d, Item ID: %d: %v",
-				orderID, item.ID, err)
-			tx.Rollback()
-			utils.InternalServerError(c, "Failed to update order items", nil)
-			return
-		}
-		utils.LogDebug("Updated item status to return requested - Order ID: %d, Item ID: %d",
-			orderID, item.ID)
-	}
-
-	// Update order status
-	order.Status = models.OrderStatusReturnRequested
-	order.ReturnReason = req.Reason
-	order.HasItemReturnRequests = true
-	order.UpdatedAt = time.Now()
-
-	if err := tx.Save(&order).Error; err != nil {
-		utils.LogError("Failed to update order - Order ID: %d: %v", orderID, err)
-		tx.Rollback()
-		utils.InternalServerError(c, "Failed to update order", nil)
+	case errors.As(err, &expired):
+		utils.LogError("Return window expired - Order ID: %d, Item ID: %d, Window: %d days", orderID, expired.ItemID, expired.Days())
+		utils.Fail(c, utils.CodeOrderWindowExpired, fmt.Sprintf("Return window has expired for some items (max %d days)", expired.Days()), nil)
 		return
-	}
-	utils.LogDebug("Updated order status to return requested - Order ID: %d", orderID)
-
-	if err := tx.Commit().Error; err != nil {
-		utils.LogError("Failed to commit transaction - Order ID: %d: %v", orderID, err)
+	case err != nil:
+		utils.LogError("Failed to submit return request - Order ID: %d: %v", orderID, err)
 		utils.InternalServerError(c, "Failed to submit return request", nil)
 		return
 	}
-	utils.LogInfo("Successfully committed transaction for order ID: %d", orderID)
+	utils.LogInfo("Return requested for order ID: %d with %d items", orderID, len(order.OrderItems))
 
+	formatter := utils.NewResponseFormatter(c)
 	// Prepare response
 	orderResponse := gin.H{
 		"id":            order.ID,
@@ -126,8 +77,8 @@ func ReturnOrder(c *gin.Context) {
 		"return_status": "Pending",
 		"items_count":   len(order.OrderItems),
 		"refund_details": gin.H{
-			"total_amount":         fmt.Sprintf("%.2f", order.TotalAmount),
-			"total_to_be_refunded": fmt.Sprintf("%.2f", order.FinalTotal),
+			"total_amount":         formatter.Money(order.TotalAmount),
+			"total_to_be_refunded": formatter.Money(order.FinalTotal),
 			"refund_status":        "Pending admin approval",
 			"refund_to":            "wallet",
 		},
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// shippableItems returns the order items that go in the parcel: cancelled items and digital
+// books are left out. Items are sorted by bin location so they can be picked in one pass.
+func shippableItems(order models.Order) []models.OrderItem {
+	var items []models.OrderItem
+	for _, item := range order.OrderItems {
+		if item.CancellationStatus == "Cancelled" || item.CancellationStatus == "Approved" || item.Book.IsDigital {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Book.BinLocation < items[j].Book.BinLocation
+	})
+	return items
+}
+
+// buildPackingSlipPDF renders the packing slip warehouse staff pick and pack an order from:
+// the books with their bin locations and quantities, and any gift or delivery instructions
+func buildPackingSlipPDF(order models.Order, items []models.OrderItem) *gofpdf.Fpdf {
+	pdf := newStorePDF("PACKING SLIP")
+
+	pdf.SetFont("Arial", "", 12)
+	pdf.Cell(50, 8, "Order ID: "+strconv.Itoa(int(order.ID)))
+	pdf.Cell(60, 8, "Order Date: "+order.CreatedAt.Format("2006-01-02 15:04"))
+	pdf.Ln(10)
+	drawCode39(pdf, utils.OrderCode(order.ID), 0.35, 12)
+	pdf.Ln(4)
+
+	writePDFAddress(pdf, "Ship To:", order.User.FirstName+" "+order.User.LastName, order.Address)
+	pdf.Ln(4)
+
+	var rows [][]string
+	units := 0
+	for _, item := range items {
+		bin := item.Book.BinLocation
+		if bin == "" {
+			bin = "-"
+		}
+		name := item.Book.Name
+		if item.PreorderPending {
+			name += " (awaiting release)"
+		}
+		rows = append(rows, []string{bin, name, item.Book.ISBN, strconv.Itoa(item.Quantity), ""})
+		units += item.Quantity
+	}
+	writePDFTable(pdf, []pdfColumn{
+		{"Bin", 25, "C"},
+		{"Book", 85, "L"},
+		{"ISBN", 40, "C"},
+		{"Qty", 15, "C"},
+		{"Packed", 20, "C"},
+	}, rows)
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(0, 8, fmt.Sprintf("Total units: %d", units))
+	pdf.Ln(10)
+
+	// Instructions for the packer
+	pdf.SetFont("Arial", "", 12)
+	if order.IsGift {
+		line := "Gift order: pack the gift receipt instead of the invoice"
+		if order.GiftWrap {
+			line += " and gift wrap the parcel"
+		}
+		pdf.MultiCell(0, 7, line+".", "", "L", false)
+	}
+	if order.DeliveryNote != "" {
+		pdf.MultiCell(0, 7, "Delivery note: "+order.DeliveryNote, "", "L", false)
+	}
+	pdf.Ln(4)
+
+	pdf.SetFont("Arial", "I", 10)
+	pdf.Cell(0, 8, "Internal document. Do not include in the parcel.")
+	return pdf
+}
+
+// buildShippingLabelPDF renders the label stuck on the parcel: the recipient's address and
+// phone, the order barcode and, for Cash on Delivery, the amount the courier collects
+func buildShippingLabelPDF(order models.Order, items []models.OrderItem) *gofpdf.Fpdf {
+	pdf := newStorePDF("SHIPPING LABEL")
+
+	pdf.SetFont("Arial", "", 12)
+	pdf.Cell(50, 8, "Order ID: "+strconv.Itoa(int(order.ID)))
+	pdf.Cell(60, 8, "Order Date: "+order.CreatedAt.Format("2006-01-02"))
+	pdf.Ln(10)
+
+	writePDFAddress(pdf, "Ship To:", order.User.FirstName+" "+order.User.LastName, order.Address)
+	if order.User.Phone != "" {
+		pdf.Cell(100, 6, "Phone: "+order.User.Phone)
+		pdf.Ln(6)
+	}
+	pdf.Ln(6)
+
+	drawCode39(pdf, utils.OrderCode(order.ID), 0.5, 18)
+	pdf.Ln(6)
+
+	units := 0
+	for _, item := range items {
+		units += item.Quantity
+	}
+	pdf.SetFont("Arial", "", 12)
+	pdf.Cell(0, 7, fmt.Sprintf("Contents: %d book(s)", units))
+	pdf.Ln(7)
+	if utils.NormalizePaymentMethod(order.PaymentMethod) == "cod" {
+		pdf.SetFont("Arial", "B", 14)
+		pdf.Cell(0, 9, fmt.Sprintf("CASH ON DELIVERY - Collect %s %.2f", utils.BaseCurrency(), order.TotalWithDelivery))
+	} else {
+		pdf.SetFont("Arial", "B", 14)
+		pdf.Cell(0, 9, "PREPAID - Do not collect payment")
+	}
+	pdf.Ln(10)
+	if order.DeliveryNote != "" {
+		pdf.SetFont("Arial", "", 12)
+		pdf.MultiCell(0, 7, "Delivery note: "+order.DeliveryNote, "", "L", false)
+	}
+	return pdf
+}
+
+// loadShippingDocumentOrder loads the order a packing slip or shipping label is requested for
+// and its shippable items, writing the error response when the order has nothing to ship
+func loadShippingDocumentOrder(c *gin.Context) (models.Order, []models.OrderItem, bool) {
+	if _, exists := c.Get("admin"); !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found", nil)
+		return models.Order{}, nil, false
+	}
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.LogError("Invalid order ID: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
+		return models.Order{}, nil, false
+	}
+
+	var order models.Order
+	if err := config.DB.Preload("OrderItems.Book").Preload("Address").Preload("User").
+		First(&order, orderID).Error; err != nil {
+		utils.LogError("Order not found - Order ID: %d", orderID)
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+		return models.Order{}, nil, false
+	}
+
+	if strings.EqualFold(order.Status, models.OrderStatusCancelled) {
+		utils.LogError("Shipping document requested for cancelled order %d", order.ID)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Cancelled orders are not shipped", nil)
+		return models.Order{}, nil, false
+	}
+	items := shippableItems(order)
+	if len(items) == 0 {
+		utils.LogError("Shipping document requested for order %d with nothing to ship", order.ID)
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "This order has no books to ship", nil)
+		return models.Order{}, nil, false
+	}
+	return order, items, true
+}
+
+// AdminDownloadPackingSlip returns the packing slip PDF of an order
+func AdminDownloadPackingSlip(c *gin.Context) {
+	utils.LogInfo("AdminDownloadPackingSlip called")
+
+	order, items, ok := loadShippingDocumentOrder(c)
+	if !ok {
+		return
+	}
+
+	pdf := buildPackingSlipPDF(order, items)
+	if err := writePDF(c, pdf, fmt.Sprintf("packing-slip-%d.pdf", order.ID)); err != nil {
+		utils.LogError("Failed to render packing slip for order %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to generate packing slip", err.Error())
+		return
+	}
+	utils.LogInfo("Packing slip generated for order ID: %d", order.ID)
+}
+
+// AdminDownloadShippingLabel returns the shipping label PDF of an order
+func AdminDownloadShippingLabel(c *gin.Context) {
+	utils.LogInfo("AdminDownloadShippingLabel called")
+
+	order, items, ok := loadShippingDocumentOrder(c)
+	if !ok {
+		return
+	}
+
+	pdf := buildShippingLabelPDF(order, items)
+	if err := writePDF(c, pdf, fmt.Sprintf("shipping-label-%d.pdf", order.ID)); err != nil {
+		utils.LogError("Failed to render shipping label for order %d: %v", order.ID, err)
+		utils.InternalServerError(c, "Failed to generate shipping label", err.Error())
+		return
+	}
+	utils.LogInfo("Shipping label generated for order ID: %d", order.ID)
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"gorm.io/gorm"
+)
+
+// recordOrderStatusEvent appends a status change to the order's tracking timeline and, unless
+// the user made the change, notifies the user
+func recordOrderStatusEvent(tx *gorm.DB, orderID uint, status, actorType string, actorID uint, note string) error {
+	event := models.OrderStatusEvent{
+		OrderID:   orderID,
+		Status:    status,
+		Note:      note,
+		ActorType: actorType,
+		ActorID:   actorID,
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		return err
+	}
+	if actorType == "user" {
+		return nil
+	}
+
+	var userID uint
+	if err := tx.Model(&models.Order{}).Select("user_id").Where("id = ?", orderID).Scan(&userID).Error; err != nil {
+		return err
+	}
+	_, err := utils.Notify(tx, userID, models.NotificationTypeOrder, fmt.Sprintf("Order #%d is %s", orderID, status),
+		note, fmt.Sprintf("/v1/user/orders/%d", orderID))
+	return err
+}
+
+// orderTimeline returns the order's status events, starting with its placement
+func orderTimeline(db *gorm.DB, order models.Order) ([]models.OrderStatusEvent, error) {
+	var events []models.OrderStatusEvent
+	if err := db.Where("order_id = ?", order.ID).Order("created_at ASC, id ASC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	placed := models.OrderStatusEvent{
+		OrderID:   order.ID,
+		Status:    models.OrderStatusPlaced,
+		ActorType: "user",
+		ActorID:   order.UserID,
+		CreatedAt: order.CreatedAt,
+	}
+	return append([]models.OrderStatusEvent{placed}, events...), nil
+}
+
+// emailOrderPlaced sends the order confirmation email in the background. Call it after the
+// order's transaction commits.
+func emailOrderPlaced(orderID uint) {
+	go func() {
+		if err := utils.SendOrderPlacedEmail(orderID); err != nil {
+			utils.LogError("Failed to email confirmation of order %d: %v", orderID, err)
+		}
+	}()
+}
+
+// emailOrderStatus sends the order status email in the background. Call it after the status
+// change commits.
+func emailOrderStatus(orderID uint, status, note string) {
+	go func() {
+		if err := utils.SendOrderStatusEmail(orderID, status, note); err != nil {
+			utils.LogError("Failed to email status %s of order %d: %v", status, orderID, err)
+		}
+	}()
+}
@@ -0,0 +1,413 @@
+package controllers
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// pageFormats are the body formats a page can be written in
+var pageFormats = map[string]bool{"markdown": true, "html": true}
+
+// unsafePageHTML matches markup that would run script when an HTML page body is rendered
+var unsafePageHTML = regexp.MustCompile(`(?i)<\s*/?\s*(script|iframe|object|embed)\b|\son\w+\s*=|javascript:`)
+
+// PageRequest represents the page create/update request. Format is markdown (the default)
+// or html.
+type PageRequest struct {
+	Title       string `json:"title" binding:"required,min=2,max=150"`
+	Slug        string `json:"slug"`
+	Format      string `json:"format"`
+	Body        string `json:"body" binding:"required"`
+	IsPublished *bool  `json:"is_published"`
+}
+
+// AdminListPages lists every page, published or not, without their bodies
+func AdminListPages(c *gin.Context) {
+	utils.LogInfo("AdminListPages called")
+
+	var pages []models.Page
+	if err := config.DB.Order("slug").Find(&pages).Error; err != nil {
+		utils.LogError("Failed to fetch pages: %v", err)
+		utils.InternalServerError(c, "Failed to fetch pages", nil)
+		return
+	}
+
+	response := make([]gin.H, 0, len(pages))
+	formatter := utils.NewResponseFormatter(c)
+	for _, page := range pages {
+		response = append(response, pageSummaryResponse(formatter, page))
+	}
+
+	utils.LogInfo("Retrieved %d pages", len(response))
+	utils.Success(c, "Pages retrieved successfully", gin.H{
+		"pages": response,
+	})
+}
+
+// AdminGetPage returns a page with its current content, published or not
+func AdminGetPage(c *gin.Context) {
+	utils.LogInfo("AdminGetPage called")
+
+	var page models.Page
+	if err := config.DB.First(&page, c.Param("id")).Error; err != nil {
+		utils.LogError("Page not found: %s", c.Param("id"))
+		utils.NotFound(c, "Page not found")
+		return
+	}
+
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Page retrieved successfully", gin.H{
+		"page": pageResponse(formatter, page),
+	})
+}
+
+// AdminCreatePage creates a page as its first version
+func AdminCreatePage(c *gin.Context) {
+	utils.LogInfo("AdminCreatePage called")
+
+	var req PageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid page request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+
+	page := models.Page{Version: 1}
+	if !applyPageRequest(c, &page, req) {
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&page).Error; err != nil {
+			return err
+		}
+		return createPageVersion(tx, c, page)
+	}); err != nil {
+		utils.LogError("Failed to create page %s: %v", page.Slug, err)
+		utils.InternalServerError(c, "Failed to create page", nil)
+		return
+	}
+
+	utils.LogInfo("Created page %s (ID: %d)", page.Slug, page.ID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Created(c, "Page created successfully", gin.H{
+		"page": pageResponse(formatter, page),
+	})
+}
+
+// AdminUpdatePage updates a page. A change to its title, format or body is saved as a new
+// version; publishing or renaming the slug alone is not.
+func AdminUpdatePage(c *gin.Context) {
+	utils.LogInfo("AdminUpdatePage called")
+
+	var page models.Page
+	if err := config.DB.First(&page, c.Param("id")).Error; err != nil {
+		utils.LogError("Page not found: %s", c.Param("id"))
+		utils.NotFound(c, "Page not found")
+		return
+	}
+
+	var req PageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid page request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+
+	title, format, body := page.Title, page.Format, page.Body
+	if !applyPageRequest(c, &page, req) {
+		return
+	}
+	changed := page.Title != title || page.Format != format || page.Body != body
+	if changed {
+		page.Version++
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&page).Error; err != nil {
+			return err
+		}
+		if !changed {
+			return nil
+		}
+		return createPageVersion(tx, c, page)
+	}); err != nil {
+		utils.LogError("Failed to update page %d: %v", page.ID, err)
+		utils.InternalServerError(c, "Failed to update page", nil)
+		return
+	}
+
+	utils.LogInfo("Updated page %s (ID: %d), now at version %d", page.Slug, page.ID, page.Version)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Page updated successfully", gin.H{
+		"page": pageResponse(formatter, page),
+	})
+}
+
+// AdminGetPageVersions lists the saved versions of a page, newest first
+func AdminGetPageVersions(c *gin.Context) {
+	utils.LogInfo("AdminGetPageVersions called")
+
+	var page models.Page
+	if err := config.DB.First(&page, c.Param("id")).Error; err != nil {
+		utils.LogError("Page not found: %s", c.Param("id"))
+		utils.NotFound(c, "Page not found")
+		return
+	}
+
+	var versions []models.PageVersion
+	if err := config.DB.Where("page_id = ?", page.ID).Order("version DESC").Find(&versions).Error; err != nil {
+		utils.LogError("Failed to fetch versions of page %d: %v", page.ID, err)
+		utils.InternalServerError(c, "Failed to fetch page versions", nil)
+		return
+	}
+
+	response := make([]gin.H, 0, len(versions))
+	formatter := utils.NewResponseFormatter(c)
+	for _, version := range versions {
+		response = append(response, gin.H{
+			"version":    version.Version,
+			"title":      version.Title,
+			"format":     version.Format,
+			"body":       version.Body,
+			"admin_id":   version.AdminID,
+			"current":    version.Version == page.Version,
+			"created_at": formatter.Timestamp(version.CreatedAt),
+		})
+	}
+
+	utils.LogInfo("Retrieved %d versions of page %d", len(response), page.ID)
+	utils.Success(c, "Page versions retrieved successfully", gin.H{
+		"page_id":  page.ID,
+		"slug":     page.Slug,
+		"versions": response,
+	})
+}
+
+// AdminRestorePageVersion makes an earlier version's content current again, saved as a new
+// version so the history is kept
+func AdminRestorePageVersion(c *gin.Context) {
+	utils.LogInfo("AdminRestorePageVersion called")
+
+	var page models.Page
+	if err := config.DB.First(&page, c.Param("id")).Error; err != nil {
+		utils.LogError("Page not found: %s", c.Param("id"))
+		utils.NotFound(c, "Page not found")
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil || number < 1 {
+		utils.BadRequest(c, "Invalid version", "Version must be a positive number")
+		return
+	}
+	var version models.PageVersion
+	if err := config.DB.Where("page_id = ? AND version = ?", page.ID, number).First(&version).Error; err != nil {
+		utils.LogError("Version %d of page %d not found", number, page.ID)
+		utils.NotFound(c, "Page version not found")
+		return
+	}
+	if version.Version == page.Version {
+		utils.BadRequest(c, "Version is already current", gin.H{"version": version.Version})
+		return
+	}
+
+	page.Title = version.Title
+	page.Format = version.Format
+	page.Body = version.Body
+	page.Version++
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&page).Error; err != nil {
+			return err
+		}
+		return createPageVersion(tx, c, page)
+	}); err != nil {
+		utils.LogError("Failed to restore version %d of page %d: %v", number, page.ID, err)
+		utils.InternalServerError(c, "Failed to restore page version", nil)
+		return
+	}
+
+	utils.LogInfo("Restored version %d of page %s as version %d", number, page.Slug, page.Version)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Page version restored successfully", gin.H{
+		"page":          pageResponse(formatter, page),
+		"restored_from": number,
+	})
+}
+
+// AdminDeletePage deletes a page and its versions
+func AdminDeletePage(c *gin.Context) {
+	utils.LogInfo("AdminDeletePage called")
+
+	var page models.Page
+	if err := config.DB.First(&page, c.Param("id")).Error; err != nil {
+		utils.LogError("Page not found: %s", c.Param("id"))
+		utils.NotFound(c, "Page not found")
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("page_id = ?", page.ID).Delete(&models.PageVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&page).Error
+	}); err != nil {
+		utils.LogError("Failed to delete page %d: %v", page.ID, err)
+		utils.InternalServerError(c, "Failed to delete page", nil)
+		return
+	}
+
+	utils.LogInfo("Deleted page %s (ID: %d)", page.Slug, page.ID)
+	utils.Success(c, "Page deleted successfully", nil)
+}
+
+// GetPages lists the published pages, without their bodies, for storefront navigation
+func GetPages(c *gin.Context) {
+	utils.LogInfo("GetPages called")
+
+	var pages []models.Page
+	if err := config.DB.Select("id, slug, title, format, version, is_published, created_at, updated_at").
+		Where("is_published = ?", true).Order("title").Find(&pages).Error; err != nil {
+		utils.LogError("Failed to fetch published pages: %v", err)
+		utils.InternalServerError(c, "Failed to fetch pages", nil)
+		return
+	}
+
+	response := make([]gin.H, 0, len(pages))
+	formatter := utils.NewResponseFormatter(c)
+	for _, page := range pages {
+		response = append(response, gin.H{
+			"slug":       page.Slug,
+			"title":      page.Title,
+			"updated_at": formatter.Timestamp(page.UpdatedAt),
+		})
+	}
+
+	utils.LogInfo("Retrieved %d published pages", len(response))
+	utils.Success(c, "Pages retrieved successfully", gin.H{
+		"pages": response,
+	})
+}
+
+// GetPage returns a published page by its slug
+func GetPage(c *gin.Context) {
+	utils.LogInfo("GetPage called")
+
+	slug := c.Param("slug")
+	var page models.Page
+	if err := config.DB.Where("slug = ? AND is_published = ?", slug, true).First(&page).Error; err != nil {
+		utils.LogError("Published page not found: %s", slug)
+		utils.NotFound(c, "Page not found")
+		return
+	}
+
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Page retrieved successfully", gin.H{
+		"page": gin.H{
+			"slug":       page.Slug,
+			"title":      page.Title,
+			"format":     page.Format,
+			"body":       page.Body,
+			"version":    page.Version,
+			"updated_at": formatter.Timestamp(page.UpdatedAt),
+		},
+	})
+}
+
+// applyPageRequest validates the request and copies it onto the page. It writes the error
+// response and returns false when the request is invalid.
+func applyPageRequest(c *gin.Context, page *models.Page, req PageRequest) bool {
+	slug := slugify(req.Slug)
+	if slug == "" {
+		slug = page.Slug
+	}
+	if slug == "" {
+		slug = slugify(req.Title)
+	}
+	if slug == "" {
+		utils.BadRequest(c, "Invalid slug", "Slug must contain letters or digits")
+		return false
+	}
+	var count int64
+	config.DB.Model(&models.Page{}).Where("slug = ? AND id <> ?", slug, page.ID).Count(&count)
+	if count > 0 {
+		utils.LogError("Page slug already in use: %s", slug)
+		utils.Fail(c, utils.CodeAlreadyExists, "Slug already in use", gin.H{"slug": slug})
+		return false
+	}
+
+	format := strings.ToLower(strings.TrimSpace(req.Format))
+	if format == "" {
+		format = page.Format
+	}
+	if format == "" {
+		format = "markdown"
+	}
+	if !pageFormats[format] {
+		utils.BadRequest(c, "Invalid format", "Format must be markdown or html")
+		return false
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		utils.BadRequest(c, "Invalid body", "Body must not be empty")
+		return false
+	}
+	if format == "html" && unsafePageHTML.MatchString(req.Body) {
+		utils.BadRequest(c, "Invalid body", "HTML bodies must not contain scripts, frames, embeds or event handlers")
+		return false
+	}
+
+	page.Title = strings.TrimSpace(req.Title)
+	page.Slug = slug
+	page.Format = format
+	page.Body = req.Body
+	if req.IsPublished != nil {
+		page.IsPublished = *req.IsPublished
+	}
+	return true
+}
+
+// createPageVersion saves the page's current content as its current version, by the acting
+// admin
+func createPageVersion(tx *gorm.DB, c *gin.Context, page models.Page) error {
+	version := models.PageVersion{
+		PageID:  page.ID,
+		Version: page.Version,
+		Title:   page.Title,
+		Format:  page.Format,
+		Body:    page.Body,
+	}
+	if adminVal, exists := c.Get("admin"); exists {
+		if admin, ok := adminVal.(models.Admin); ok {
+			version.AdminID = admin.ID
+		}
+	}
+	return tx.Create(&version).Error
+}
+
+// pageSummaryResponse is the admin list view of a page
+func pageSummaryResponse(f utils.ResponseFormatter, page models.Page) gin.H {
+	return gin.H{
+		"id":           page.ID,
+		"slug":         page.Slug,
+		"title":        page.Title,
+		"format":       page.Format,
+		"is_published": page.IsPublished,
+		"version":      page.Version,
+		"created_at":   f.Timestamp(page.CreatedAt),
+		"updated_at":   f.Timestamp(page.UpdatedAt),
+	}
+}
+
+// pageResponse is the admin view of a page with its current content
+func pageResponse(f utils.ResponseFormatter, page models.Page) gin.H {
+	response := pageSummaryResponse(f, page)
+	response["body"] = page.Body
+	return response
+}
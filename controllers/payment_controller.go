@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"os"
 	"strconv"
 
@@ -21,7 +22,7 @@ func InitiateRazorpayPayment(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user := userVal.(models.User)
@@ -33,7 +34,7 @@ func InitiateRazorpayPayment(c *gin.Context) {
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request for user ID: %d: %v", userID, err)
-		utils.BadRequest(c, "Invalid request. order_id is required", err.Error())
+		utils.BadRequest(c, "Invalid request. order_id is required", err)
 		return
 	}
 
@@ -41,7 +42,7 @@ func InitiateRazorpayPayment(c *gin.Context) {
 	var order models.Order
 	if err := db.Preload("Address").Where("id = ? AND user_id = ?", req.OrderID, userID).First(&order).Error; err != nil {
 		utils.LogError("Order not found for ID: %d, user ID: %d", req.OrderID, userID)
-		utils.NotFound(c, "Order not found")
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 	utils.LogInfo("Found order ID: %d for user ID: %d", order.ID, userID)
@@ -49,32 +50,26 @@ func InitiateRazorpayPayment(c *gin.Context) {
 	// Check if payment is already completed
 	if order.Status != "Placed" {
 		utils.LogError("Order payment already completed - Order ID: %d, Status: %s", order.ID, order.Status)
-		utils.BadRequest(c, "Payment for this order has already been completed", nil)
+		utils.Fail(c, utils.CodePaymentCompleted, "Payment for this order has already been completed", nil)
 		return
 	}
 
 	// Check if there's another pending payment for this order
 	if order.PaymentMethod == "RAZORPAY" || order.PaymentMethod == "online" {
 		utils.LogError("Payment already initiated for order ID: %d", order.ID)
-		utils.BadRequest(c, "A payment is already in progress for this order", nil)
+		utils.Fail(c, utils.CodePaymentPending, "A payment is already in progress for this order", gin.H{
+			"retry_url": fmt.Sprintf("/v1/user/orders/%d/retry-payment", order.ID),
+		})
 		return
 	}
 
-	// Razorpay expects amount in paise. Only multiply by 100 if FinalTotal is in rupees (float).
-	amountPaise := int(order.FinalTotal * 100)
-	if order.FinalTotal > 1000 { // If FinalTotal is already in paise, do not multiply
-		amountPaise = int(order.FinalTotal)
+	// Orders left unpaid past the payment window are cancelled instead
+	if paymentWindowExpired(order) {
+		cancelExpiredPaymentOrder(c, &order)
+		return
 	}
-	utils.LogInfo("Processing payment amount: %d paise for order ID: %d", amountPaise, order.ID)
 
-	client := razorpay.NewClient(os.Getenv("RAZORPAY_KEY"), os.Getenv("RAZORPAY_SECRET"))
-	orderData := map[string]interface{}{
-		"amount":          amountPaise,
-		"currency":        "INR",
-		"receipt":         "order_rcptid_" + strconv.FormatUint(uint64(order.ID), 10),
-		"payment_capture": 1,
-	}
-	rzOrder, err := client.Order.Create(orderData, nil)
+	razorpayOrderID, err := createRazorpayOrder(order)
 	if err != nil {
 		utils.LogError("Failed to create Razorpay order for order ID: %d: %v", order.ID, err)
 		utils.InternalServerError(c, "Failed to create Razorpay order", err.Error())
@@ -85,21 +80,55 @@ func InitiateRazorpayPayment(c *gin.Context) {
 	// Update order with Razorpay order ID
 	if err := db.Model(&order).Updates(map[string]interface{}{
 		"payment_method":    "RAZORPAY",
-		"razorpay_order_id": fmt.Sprintf("%v", rzOrder["id"]),
+		"razorpay_order_id": razorpayOrderID,
+		"payment_status":    models.PaymentStatusPending,
+		"payment_attempts":  1,
 	}).Error; err != nil {
 		utils.LogError("Failed to update order with Razorpay details for order ID: %d: %v", order.ID, err)
 		utils.InternalServerError(c, "Failed to update order details", err.Error())
 		return
 	}
 
-	utils.Success(c, "Payment initiated successfully", gin.H{
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Payment initiated successfully", razorpayCheckoutResponse(formatter, user, order, razorpayOrderID))
+}
+
+// createRazorpayOrder creates a Razorpay order for the order's payable total and returns its ID
+func createRazorpayOrder(order models.Order) (string, error) {
+	// Razorpay expects amount in paise. Charge the payable total, which includes delivery and
+	// the payment method adjustment, less the part held on the wallet.
+	amountPaise := int(math.Round((order.TotalWithDelivery - order.WalletAmount) * 100))
+	utils.LogInfo("Processing payment amount: %d paise for order ID: %d", amountPaise, order.ID)
+
+	client := razorpay.NewClient(os.Getenv("RAZORPAY_KEY"), os.Getenv("RAZORPAY_SECRET"))
+	orderData := map[string]interface{}{
+		"amount":          amountPaise,
+		"currency":        utils.BaseCurrency(),
+		"receipt":         "order_rcptid_" + strconv.FormatUint(uint64(order.ID), 10),
+		"payment_capture": 1,
+	}
+	rzOrder, err := client.Order.Create(orderData, nil)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v", rzOrder["id"]), nil
+}
+
+// razorpayCheckoutResponse is what the client needs to open the Razorpay checkout for an order
+func razorpayCheckoutResponse(f utils.ResponseFormatter, user models.User, order models.Order, razorpayOrderID string) gin.H {
+	return gin.H{
 		"order": gin.H{
-			"id":                order.ID,
-			"razorpay_order_id": rzOrder["id"],
-			"amount":            fmt.Sprintf("%.2f", order.FinalTotal),
-			"delivery_charge":   fmt.Sprintf("%.2f", order.DeliveryCharge),
-			"total_amount":      fmt.Sprintf("%.2f", order.TotalWithDelivery),
-			"amount_display":    fmt.Sprintf("₹%.2f", order.TotalWithDelivery),
+			"id":                 order.ID,
+			"razorpay_order_id":  razorpayOrderID,
+			"amount":             f.Money(order.FinalTotal),
+			"delivery_charge":    f.Money(order.DeliveryCharge),
+			"payment_adjustment": f.Money(order.PaymentAdjustment),
+			"total_amount":       f.Money(order.TotalWithDelivery),
+			"wallet_amount":      f.Money(order.WalletAmount),
+			"amount_due":         f.Money(order.TotalWithDelivery - order.WalletAmount),
+			"amount_display":     utils.FormatBaseMoney(order.TotalWithDelivery - order.WalletAmount),
+			"currency":           utils.BaseCurrency(),
+			"payment_expires_at": f.Timestamp(paymentDeadline(order)),
 		},
 		"address": gin.H{
 			"line1":       order.Address.Line1,
@@ -114,7 +143,7 @@ func InitiateRazorpayPayment(c *gin.Context) {
 			"name":  user.Username,
 			"email": user.Email,
 		},
-	})
+	}
 }
 
 // POST /user/checkout/payment/verify
@@ -123,7 +152,7 @@ func VerifyRazorpayPayment(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user := userVal.(models.User)
@@ -138,7 +167,7 @@ func VerifyRazorpayPayment(c *gin.Context) {
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request for user ID: %d: %v", userID, err)
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 
@@ -150,7 +179,16 @@ func VerifyRazorpayPayment(c *gin.Context) {
 	generatedSignature := hex.EncodeToString(h.Sum(nil))
 	if generatedSignature != req.RazorpaySignature {
 		utils.LogError("Payment verification failed for order ID: %d, user ID: %d", req.OrderID, userID)
-		utils.BadRequest(c, "Payment verification failed", gin.H{"retry": true})
+		utils.RecordPaymentFailed("order")
+		if err := config.DB.Model(&models.Order{}).
+			Where("id = ? AND user_id = ? AND status = ?", req.OrderID, userID, models.OrderStatusPlaced).
+			Update("payment_status", models.PaymentStatusFailed).Error; err != nil {
+			utils.LogError("Failed to mark payment failed for order ID: %d: %v", req.OrderID, err)
+		}
+		utils.Fail(c, utils.CodePaymentFailed, "Payment verification failed", gin.H{
+			"retry":     true,
+			"retry_url": fmt.Sprintf("/v1/user/orders/%d/retry-payment", req.OrderID),
+		})
 		return
 	}
 	utils.LogInfo("Payment signature verified for order ID: %d", req.OrderID)
@@ -160,7 +198,7 @@ func VerifyRazorpayPayment(c *gin.Context) {
 	var order models.Order
 	if err := db.Where("id = ? AND user_id = ?", req.OrderID, userID).First(&order).Error; err != nil {
 		utils.LogError("Order not found for ID: %d, user ID: %d: %v", req.OrderID, userID, err)
-		utils.NotFound(c, "Order not found")
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 	utils.LogInfo("Found order ID: %d for user ID: %d", order.ID, userID)
@@ -169,11 +207,20 @@ func VerifyRazorpayPayment(c *gin.Context) {
 	if order.RazorpayOrderID != req.RazorpayOrderID {
 		utils.LogError("Razorpay order ID mismatch for order ID: %d. Expected: %s, Received: %s",
 			req.OrderID, order.RazorpayOrderID, req.RazorpayOrderID)
-		utils.BadRequest(c, "Invalid Razorpay order ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid Razorpay order ID", nil)
 		return
 	}
 	utils.LogInfo("Razorpay order ID verified for order ID: %d", req.OrderID)
 
+	if order.Status != models.OrderStatusPlaced {
+		utils.LogError("Order ID: %d cannot be paid in status %s", order.ID, order.Status)
+		utils.BadRequest(c, "This order is no longer awaiting payment", gin.H{
+			"status":              order.Status,
+			"cancellation_reason": order.CancellationReason,
+		})
+		return
+	}
+
 	// Start transaction
 	tx := db.Begin()
 	if tx.Error != nil {
@@ -184,35 +231,75 @@ func VerifyRazorpayPayment(c *gin.Context) {
 
 	// Update order status
 	utils.LogInfo("Updating order ID: %d, current status: %s, new status: Paid", order.ID, order.Status)
-	if err := tx.Model(&order).Updates(map[string]interface{}{
-		"status":         "Paid",
-		"payment_method": "RAZORPAY",
-		"payment_status": "completed",
-	}).Error; err != nil {
-		utils.LogError("Failed to update order ID: %d: %v", order.ID, err)
+	// Only an order still awaiting payment is marked paid, so a concurrent cancellation wins cleanly
+	result := tx.Model(&models.Order{}).
+		Where("id = ? AND status = ?", order.ID, models.OrderStatusPlaced).
+		Updates(map[string]interface{}{
+			"status":              "Paid",
+			"payment_method":      "RAZORPAY",
+			"payment_status":      models.PaymentStatusCompleted,
+			"razorpay_payment_id": req.RazorpayPaymentID,
+			"razorpay_signature":  req.RazorpaySignature,
+		})
+	if result.Error != nil {
+		utils.LogError("Failed to update order ID: %d: %v", order.ID, result.Error)
+		tx.Rollback()
+		utils.InternalServerError(c, "Failed to update order", result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.LogError("Order ID: %d changed status before payment was recorded", order.ID)
 		tx.Rollback()
-		utils.InternalServerError(c, "Failed to update order", err.Error())
+		utils.Fail(c, utils.CodeOrderNotAwaitingPay, "This order is no longer awaiting payment", nil)
 		return
 	}
 	utils.LogInfo("Successfully updated order status to 'Paid' for order ID: %d", order.ID)
 
-	// Clear cart
-	if err := tx.Where("user_id = ?", userID).Delete(&models.Cart{}).Error; err != nil {
-		utils.LogError("Failed to clear cart for user ID: %d: %v", userID, err)
+	// Capture the wallet part of a split payment now that the rest is paid
+	if order.WalletAmount > 0 {
+		if _, err := utils.CaptureWalletHold(tx, userID, order.ID); err != nil {
+			utils.LogError("Failed to capture wallet hold for order ID: %d: %v", order.ID, err)
+			tx.Rollback()
+			utils.InternalServerError(c, "Failed to capture wallet payment", err.Error())
+			return
+		}
+		utils.LogInfo("Captured wallet hold of %.2f for order ID: %d", order.WalletAmount, order.ID)
+	}
+
+	// Clear cart, unless the order was bought directly
+	if !order.BuyNow {
+		if err := utils.ClearCart(tx, userID); err != nil {
+			utils.LogError("Failed to clear cart for user ID: %d: %v", userID, err)
+			tx.Rollback()
+			utils.InternalServerError(c, "Failed to clear cart", err.Error())
+			return
+		}
+		utils.LogInfo("Cleared cart for user ID: %d", userID)
+
+		// Clear active coupon
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserActiveCoupon{}).Error; err != nil {
+			utils.LogError("Failed to clear active coupon for user ID: %d: %v", userID, err)
+			tx.Rollback()
+			utils.InternalServerError(c, "Failed to clear active coupon", err.Error())
+			return
+		}
+		utils.LogInfo("Cleared active coupon for user ID: %d", userID)
+	}
+
+	if err := utils.GrantDigitalEntitlements(tx, &order); err != nil {
+		utils.LogError("Failed to grant digital books for order ID: %d: %v", order.ID, err)
 		tx.Rollback()
-		utils.InternalServerError(c, "Failed to clear cart", err.Error())
+		utils.InternalServerError(c, "Failed to grant digital books", err.Error())
 		return
 	}
-	utils.LogInfo("Cleared cart for user ID: %d", userID)
 
-	// Clear active coupon
-	if err := tx.Where("user_id = ?", userID).Delete(&models.UserActiveCoupon{}).Error; err != nil {
-		utils.LogError("Failed to clear active coupon for user ID: %d: %v", userID, err)
+	// Credit any cashback earned by paying online
+	if err := utils.CreditPaymentCashback(tx, &order); err != nil {
+		utils.LogError("Failed to credit payment cashback for order ID: %d: %v", order.ID, err)
 		tx.Rollback()
-		utils.InternalServerError(c, "Failed to clear active coupon", err.Error())
+		utils.InternalServerError(c, "Failed to credit payment cashback", err.Error())
 		return
 	}
-	utils.LogInfo("Cleared active coupon for user ID: %d", userID)
 
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
@@ -221,12 +308,16 @@ func VerifyRazorpayPayment(c *gin.Context) {
 		return
 	}
 	utils.LogInfo("Successfully completed payment verification for order ID: %d", order.ID)
+	emailOrderPlaced(order.ID)
 
+	formatter := utils.NewResponseFormatter(c)
 	utils.Success(c, "Thank you for your payment! Your order has been placed.", gin.H{
 		"order_id":              order.ID,
-		"subtotal":              fmt.Sprintf("%.2f", order.FinalTotal),
-		"delivery_charge":       fmt.Sprintf("%.2f", order.DeliveryCharge),
-		"final_total":           fmt.Sprintf("%.2f", order.TotalWithDelivery),
+		"subtotal":              formatter.Money(order.FinalTotal),
+		"delivery_charge":       formatter.Money(order.DeliveryCharge),
+		"payment_adjustment":    formatter.Money(order.PaymentAdjustment),
+		"cashback_credited":     formatter.Money(order.PaymentCashback),
+		"final_total":           formatter.Money(order.TotalWithDelivery),
 		"payment_method":        order.PaymentMethod,
 		"order_details_url":     "/user/orders/" + strconv.FormatUint(uint64(order.ID), 10),
 		"continue_shopping_url": "/books",
@@ -239,7 +330,7 @@ func GetPaymentMethods(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user := userVal.(models.User)
@@ -255,8 +346,9 @@ func GetPaymentMethods(c *gin.Context) {
 	finalTotal := cartDetails.FinalTotal
 	utils.LogInfo("Retrieved final total from cart: %.2f for user ID: %d", finalTotal, user.ID)
 
-	// Get delivery charge for default address
-	var deliveryCharge float64 = 50.0 // Default
+	// Get delivery charge for default address; without a deliverable default address
+	// the charge is only known once an address is chosen at checkout
+	var deliveryCharge float64
 	var defaultAddress models.Address
 	if err := config.DB.Where("user_id = ? AND is_default = ?", user.ID, true).First(&defaultAddress).Error; err == nil {
 		charge, err := utils.GetDeliveryCharge(defaultAddress.PostalCode, finalTotal)
@@ -276,49 +368,78 @@ func GetPaymentMethods(c *gin.Context) {
 		return
 	}
 	utils.LogInfo("Retrieved wallet for user ID: %d, balance: %.2f", user.ID, wallet.Balance)
+	// Funds held for unpaid orders cannot be spent again
+	available := wallet.Balance - wallet.Held
+
+	// Payable total per method, including its fee, discount or cashback
+	adjustments := make(map[string]utils.PaymentAdjustment)
+	payable := make(map[string]float64)
+	for _, method := range utils.PaymentMethods {
+		adjustments[method] = utils.CalculatePaymentAdjustment(method, finalTotal)
+		payable[method] = totalWithDelivery + adjustments[method].Amount
+	}
+	formatter := utils.NewResponseFormatter(c)
+	withAdjustment := func(method string, option gin.H) gin.H {
+		option["adjustment_type"] = adjustments[method].Type
+		option["adjustment"] = formatter.Money(adjustments[method].Amount)
+		option["cashback"] = formatter.Money(adjustments[method].Cashback)
+		option["adjustment_description"] = adjustments[method].Description
+		option["total_payable"] = formatter.Money(payable[method])
+		return option
+	}
 
 	// Available payment methods
 	paymentMethods := []gin.H{
-		{
+		withAdjustment("online", gin.H{
 			"id":          "online",
 			"name":        "Online Payment",
 			"description": "Pay securely with Razorpay",
 			"available":   true,
-		},
+		}),
 	}
 
 	// Only add wallet if balance is sufficient or cart is free
-	if totalWithDelivery == 0 || wallet.Balance >= totalWithDelivery {
-		paymentMethods = append(paymentMethods, gin.H{
+	if payable["wallet"] == 0 || available >= payable["wallet"] {
+		paymentMethods = append(paymentMethods, withAdjustment("wallet", gin.H{
 			"id":          "wallet",
 			"name":        "Wallet",
-			"description": fmt.Sprintf("Pay using your wallet balance (₹%.2f available)", wallet.Balance),
+			"description": fmt.Sprintf("Pay using your wallet balance (%s available)", utils.FormatRequestMoney(c, available)),
 			"available":   true,
-			"balance":     fmt.Sprintf("%.2f", wallet.Balance),
-		})
+			"balance":     formatter.Money(available),
+		}))
 	}
 
-	// Add COD only if amount is less than or equal to 1000
-	if totalWithDelivery <= 1000 {
-		utils.LogInfo("Adding COD option for user ID: %d as amount (%.2f) is <= 1000", user.ID, totalWithDelivery)
+	// Add COD only if the default address allows it and amount is within the COD limit
+	codLimit := utils.CODOrderLimit()
+	codServiceable := defaultAddress.ID == 0 || utils.IsCODAvailable(defaultAddress.PostalCode)
+	codEligible, refused, err := utils.CODEligibility(user.ID)
+	if err != nil {
+		utils.LogError("Failed to check COD eligibility for user ID: %d: %v", user.ID, err)
+	}
+	if !codServiceable {
+		utils.LogInfo("COD option not available for user ID: %d at pincode %s", user.ID, defaultAddress.PostalCode)
+	} else if !codEligible {
+		utils.LogInfo("COD option not available for user ID: %d after %d refused deliveries", user.ID, refused)
+	} else if payable["cod"] <= codLimit {
+		utils.LogInfo("Adding COD option for user ID: %d as amount (%.2f) is <= %.2f", user.ID, payable["cod"], codLimit)
 		paymentMethods = append([]gin.H{
-			{
+			withAdjustment("cod", gin.H{
 				"id":          "cod",
 				"name":        "Cash on Delivery",
 				"description": "Pay when you receive your order",
 				"available":   true,
-			},
+			}),
 		}, paymentMethods...)
 	} else {
-		utils.LogInfo("COD option not available for user ID: %d as amount (%.2f) is > 1000", user.ID, totalWithDelivery)
+		utils.LogInfo("COD option not available for user ID: %d as amount (%.2f) is > %.2f", user.ID, payable["cod"], codLimit)
 	}
 
 	utils.LogInfo("Successfully retrieved payment methods for user ID: %d", user.ID)
 	utils.Success(c, "Payment methods retrieved successfully", gin.H{
 		"payment_methods":     paymentMethods,
-		"wallet_balance":      fmt.Sprintf("%.2f", wallet.Balance),
-		"final_total":         fmt.Sprintf("%.2f", finalTotal),
-		"delivery_charge":     fmt.Sprintf("%.2f", deliveryCharge),
-		"total_with_delivery": fmt.Sprintf("%.2f", totalWithDelivery),
+		"wallet_balance":      formatter.Money(available),
+		"final_total":         formatter.Money(finalTotal),
+		"delivery_charge":     formatter.Money(deliveryCharge),
+		"total_with_delivery": formatter.Money(totalWithDelivery),
 	})
 }
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/gin-gonic/gin"
+	"github.com/jung-kurt/gofpdf"
+	"rsc.io/qr"
+)
+
+// newStorePDF starts an A4 document with the ReadSphere letterhead and the given title.
+// Invoices, delivery receipts and other customer documents share this template.
+func newStorePDF(title string) *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+
+	// Optional: Add logo (uncomment if logo.png exists)
+	//pdf.ImageOptions("logo.png", 150, 5, 55, 0, false, gofpdf.ImageOptions{}, 0, "")
+
+	// Store info
+	pdf.SetFont("Arial", "B", 18)
+	pdf.Cell(100, 10, "Read Sphere")
+	pdf.SetFont("Arial", "", 12)
+	pdf.Ln(8)
+	pdf.Cell(100, 8, "123 Main St, City, Country")
+	pdf.Ln(8)
+	pdf.Cell(100, 8, "Email: support@readsphere.com | Phone: +91-12345-67890")
+	pdf.Ln(12)
+
+	pdf.SetFont("Arial", "B", 16)
+	pdf.Cell(100, 10, title)
+	pdf.Ln(12)
+	return pdf
+}
+
+// writePDF renders the document and sends it as a download
+func writePDF(c *gin.Context, pdf *gofpdf.Fpdf, filename string) error {
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return err
+	}
+	c.Header("Content-Type", "application/pdf")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+	return nil
+}
+
+// pdfColumn is a column of a bordered table drawn by writePDFTable
+type pdfColumn struct {
+	Header string
+	Width  float64
+	Align  string // alignment of the body cells; headers are centered
+}
+
+// writePDFTable draws a bordered table with a bold header row
+func writePDFTable(pdf *gofpdf.Fpdf, columns []pdfColumn, rows [][]string) {
+	pdf.SetFont("Arial", "B", 12)
+	for _, column := range columns {
+		pdf.CellFormat(column.Width, 8, column.Header, "1", 0, "C", false, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFont("Arial", "", 11)
+	for _, row := range rows {
+		for i, column := range columns {
+			pdf.CellFormat(column.Width, 7, row[i], "1", 0, column.Align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+}
+
+// writePDFAddress writes a bold heading followed by the address, with the recipient's name
+// first when it is given
+func writePDFAddress(pdf *gofpdf.Fpdf, heading, name string, address models.Address) {
+	pdf.SetFont("Arial", "B", 13)
+	pdf.Cell(100, 8, heading)
+	pdf.Ln(7)
+	pdf.SetFont("Arial", "", 12)
+	if name = strings.TrimSpace(name); name != "" {
+		pdf.Cell(100, 6, name)
+		pdf.Ln(6)
+	}
+	pdf.Cell(100, 6, address.Line1)
+	pdf.Ln(6)
+	if address.Line2 != "" {
+		pdf.Cell(100, 6, address.Line2)
+		pdf.Ln(6)
+	}
+	region := address.City + ", " + address.State
+	if address.Country != "" {
+		region += ", " + address.Country
+	}
+	pdf.Cell(100, 6, region+" - "+address.PostalCode)
+	pdf.Ln(6)
+}
+
+// code39Patterns holds the Code 39 bar pattern of each character: nine elements alternating
+// bar and space, 1 for a wide element
+var code39Patterns = map[rune]string{
+	'0': "000110100", '1': "100100001", '2': "001100001", '3': "101100000", '4': "000110001",
+	'5': "100110000", '6': "001110000", '7': "000100101", '8': "100100100", '9': "001100100",
+	'A': "100001001", 'B': "001001001", 'C': "101001000", 'D': "000011001", 'E': "100011000",
+	'F': "001011000", 'G': "000001101", 'H': "100001100", 'I': "001001100", 'J': "000011100",
+	'K': "100000011", 'L': "001000011", 'M': "101000010", 'N': "000010011", 'O': "100010010",
+	'P': "001010010", 'Q': "000000111", 'R': "100000110", 'S': "001000110", 'T': "000010110",
+	'U': "110000001", 'V': "011000001", 'W': "111000000", 'X': "010010001", 'Y': "110010000",
+	'Z': "011010000", '-': "010000101", '.': "110000100", ' ': "011000100", '*': "010010100",
+}
+
+// drawCode39 draws value as a Code 39 barcode with its text underneath, starting at the
+// current position. Characters Code 39 cannot encode are left out.
+func drawCode39(pdf *gofpdf.Fpdf, value string, narrow, height float64) {
+	const wideRatio = 3
+	x, y := pdf.GetXY()
+	startX := x
+	encoded := "*" + strings.ToUpper(value) + "*"
+	for _, char := range encoded {
+		pattern, ok := code39Patterns[char]
+		if !ok {
+			continue
+		}
+		for i, element := range pattern {
+			width := narrow
+			if element == '1' {
+				width = narrow * wideRatio
+			}
+			if i%2 == 0 {
+				pdf.Rect(x, y, width, height, "F")
+			}
+			x += width
+		}
+		x += narrow // gap between characters
+	}
+
+	pdf.SetXY(startX, y+height+1)
+	pdf.SetFont("Courier", "", 11)
+	pdf.CellFormat(x-startX, 5, value, "", 1, "C", false, 0, "")
+}
+
+// drawQRCode draws text as a QR code of the given size with its top left corner at x, y,
+// leaving the current position unchanged
+func drawQRCode(pdf *gofpdf.Fpdf, text string, x, y, size float64) error {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return err
+	}
+	module := size / float64(code.Size)
+	for row := 0; row < code.Size; row++ {
+		for col := 0; col < code.Size; col++ {
+			if code.Black(col, row) {
+				pdf.Rect(x+float64(col)*module, y+float64(row)*module, module, module, "F")
+			}
+		}
+	}
+	return nil
+}
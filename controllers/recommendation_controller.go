@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetRecommendations returns books picked for the user from their purchases, wishlist,
+// cart and browsing, topped up with the store's best sellers
+func GetRecommendations(c *gin.Context) {
+	utils.LogInfo("GetRecommendations called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	recs, strategy, err := utils.GetRecommendations(user.ID, limit)
+	if err != nil {
+		utils.LogError("Failed to build recommendations for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch recommendations", nil)
+		return
+	}
+
+	ids := make([]uint, len(recs))
+	for i, rec := range recs {
+		ids[i] = rec.BookID
+	}
+	var books []models.Book
+	if len(ids) > 0 {
+		if err := config.DB.Where("id IN ?", ids).Find(&books).Error; err != nil {
+			utils.LogError("Failed to load recommended books for user ID: %d: %v", user.ID, err)
+			utils.InternalServerError(c, "Failed to fetch recommendations", nil)
+			return
+		}
+	}
+	byID := make(map[uint]models.Book, len(books))
+	for _, book := range books {
+		byID[book.ID] = book
+	}
+	translations := loadTranslations(models.TranslationEntityBook, ids, resolveCatalogLocale(c))
+
+	response := make([]gin.H, 0, len(recs))
+	formatter := utils.NewResponseFormatter(c)
+	for _, rec := range recs {
+		book, ok := byID[rec.BookID]
+		if !ok {
+			continue
+		}
+		name, _ := translateText(translations, book.ID, book.Name, "")
+		response = append(response, gin.H{
+			"id":             book.ID,
+			"name":           name,
+			"author":         book.Author,
+			"price":          formatter.Money(book.Price),
+			"image_url":      book.ImageURL,
+			"average_rating": book.AverageRating,
+			"score":          math.Round(rec.Score*1000) / 1000,
+			"reasons":        rec.Reasons,
+		})
+	}
+
+	utils.LogInfo("Returning %d recommendations from %s strategy for user ID: %d", len(response), strategy, user.ID)
+	utils.Success(c, "Recommendations retrieved successfully", gin.H{
+		"strategy":        strategy,
+		"recommendations": response,
+	})
+}
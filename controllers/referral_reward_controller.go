@@ -0,0 +1,396 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultReferralSettings are the rewards used until an admin saves settings:
+// a 25% coupon for both users, as the program has always given
+var defaultReferralSettings = models.ReferralSettings{
+	Enabled:               true,
+	ReferrerCouponPercent: 25,
+	ReferredCouponPercent: 25,
+	CouponMinOrderValue:   100,
+	CouponMaxDiscount:     500,
+	CouponValidityDays:    30,
+}
+
+// getReferralSettings returns the saved referral settings, or the defaults
+func getReferralSettings() (models.ReferralSettings, error) {
+	var settings models.ReferralSettings
+	err := config.DB.First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultReferralSettings, nil
+	}
+	return settings, err
+}
+
+// grantReferralRewards issues the configured coupons and wallet credits for a referral and
+// records each one in the reward ledger. Coupon codes include the usage ID, so a referrer
+// gets a distinct coupon for every friend who joins.
+func grantReferralRewards(tx *gorm.DB, settings models.ReferralSettings, usage *models.ReferralUsage, referralCode string) error {
+	expiry := time.Now().AddDate(0, 0, settings.CouponValidityDays)
+	issueCoupon := func(userID uint, role, code string, percent float64) (uint, error) {
+		coupon := models.Coupon{
+			Code:          code,
+			Type:          "percent",
+			Value:         percent,
+			MinOrderValue: settings.CouponMinOrderValue,
+			MaxDiscount:   settings.CouponMaxDiscount,
+			Expiry:        expiry,
+			UsageLimit:    1,
+			Active:        true,
+		}
+		if err := tx.Create(&coupon).Error; err != nil {
+			return 0, err
+		}
+		reward := models.ReferralReward{
+			ReferralUsageID: usage.ID,
+			UserID:          userID,
+			Role:            role,
+			RewardType:      models.ReferralRewardCoupon,
+			CouponID:        &coupon.ID,
+			CouponCode:      coupon.Code,
+			CouponPercent:   percent,
+		}
+		return coupon.ID, tx.Create(&reward).Error
+	}
+	creditWallet := func(userID uint, role string, amount float64, description string) error {
+		transaction, err := utils.CreditWallet(tx, userID, amount, description, fmt.Sprintf("REFERRAL-%d-%s", usage.ID, role))
+		if err != nil {
+			return err
+		}
+		reward := models.ReferralReward{
+			ReferralUsageID:     usage.ID,
+			UserID:              userID,
+			Role:                role,
+			RewardType:          models.ReferralRewardWallet,
+			WalletTransactionID: &transaction.ID,
+			Amount:              amount,
+		}
+		return tx.Create(&reward).Error
+	}
+
+	var err error
+	if settings.ReferrerCouponPercent > 0 {
+		code := fmt.Sprintf("REF-%s-BONUS-%d", referralCode, usage.ID)
+		if usage.ReferrerCouponID, err = issueCoupon(usage.ReferrerID, models.ReferralRoleReferrer, code, settings.ReferrerCouponPercent); err != nil {
+			return err
+		}
+	}
+	if settings.ReferredCouponPercent > 0 {
+		code := fmt.Sprintf("REF-NEW-%s-%d", referralCode, usage.ID)
+		if usage.ReferredCouponID, err = issueCoupon(usage.ReferredUserID, models.ReferralRoleReferred, code, settings.ReferredCouponPercent); err != nil {
+			return err
+		}
+	}
+	if settings.ReferrerWalletCredit > 0 {
+		if err := creditWallet(usage.ReferrerID, models.ReferralRoleReferrer, settings.ReferrerWalletCredit, "Referral reward for inviting a friend"); err != nil {
+			return err
+		}
+	}
+	if settings.ReferredWalletCredit > 0 {
+		if err := creditWallet(usage.ReferredUserID, models.ReferralRoleReferred, settings.ReferredWalletCredit, "Welcome reward for joining with a referral code"); err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(usage).Updates(map[string]interface{}{
+		"referrer_coupon_id": usage.ReferrerCouponID,
+		"referred_coupon_id": usage.ReferredCouponID,
+	}).Error
+}
+
+// formatReferralReward returns the ledger entry with the coupon's redemption state
+func formatReferralReward(f utils.ResponseFormatter, reward models.ReferralReward, coupons map[uint]models.Coupon) gin.H {
+	entry := gin.H{
+		"id":                reward.ID,
+		"referral_usage_id": reward.ReferralUsageID,
+		"user_id":           reward.UserID,
+		"role":              reward.Role,
+		"reward_type":       reward.RewardType,
+		"created_at":        f.Timestamp(reward.CreatedAt),
+	}
+	switch reward.RewardType {
+	case models.ReferralRewardCoupon:
+		entry["coupon_code"] = reward.CouponCode
+		entry["coupon_percent"] = reward.CouponPercent
+		if reward.CouponID != nil {
+			if coupon, ok := coupons[*reward.CouponID]; ok {
+				entry["redeemed"] = coupon.UsedCount > 0
+				entry["expires_at"] = f.Timestamp(coupon.Expiry)
+			}
+		}
+	case models.ReferralRewardWallet:
+		entry["amount"] = f.Money(reward.Amount)
+		entry["wallet_transaction_id"] = reward.WalletTransactionID
+	}
+	return entry
+}
+
+// rewardCoupons loads the coupons referenced by the rewards, keyed by ID
+func rewardCoupons(rewards []models.ReferralReward) map[uint]models.Coupon {
+	var ids []uint
+	for _, reward := range rewards {
+		if reward.CouponID != nil {
+			ids = append(ids, *reward.CouponID)
+		}
+	}
+	coupons := make(map[uint]models.Coupon, len(ids))
+	if len(ids) == 0 {
+		return coupons
+	}
+	var rows []models.Coupon
+	if err := config.DB.Unscoped().Where("id IN ?", ids).Find(&rows).Error; err != nil {
+		utils.LogError("Failed to load referral reward coupons: %v", err)
+		return coupons
+	}
+	for _, coupon := range rows {
+		coupons[coupon.ID] = coupon
+	}
+	return coupons
+}
+
+// GetUserReferralRewards returns the rewards the user earned through referrals
+func GetUserReferralRewards(c *gin.Context) {
+	utils.LogInfo("GetUserReferralRewards called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var rewards []models.ReferralReward
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&rewards).Error; err != nil {
+		utils.LogError("Failed to get referral rewards for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to get referral rewards", err.Error())
+		return
+	}
+
+	coupons := rewardCoupons(rewards)
+	formatted := make([]gin.H, len(rewards))
+	var walletTotal float64
+	couponCount, redeemedCount := 0, 0
+	formatter := utils.NewResponseFormatter(c)
+	for i, reward := range rewards {
+		formatted[i] = formatReferralReward(formatter, reward, coupons)
+		if reward.RewardType == models.ReferralRewardWallet {
+			walletTotal += reward.Amount
+		} else {
+			couponCount++
+			if redeemed, _ := formatted[i]["redeemed"].(bool); redeemed {
+				redeemedCount++
+			}
+		}
+	}
+
+	var referralCount int64
+	if err := config.DB.Model(&models.ReferralUsage{}).Where("referrer_id = ?", user.ID).Count(&referralCount).Error; err != nil {
+		referralCount = 0
+	}
+
+	utils.LogInfo("Retrieved %d referral rewards for user ID: %d", len(rewards), user.ID)
+	utils.Success(c, "Referral rewards retrieved successfully", gin.H{
+		"rewards": formatted,
+		"summary": gin.H{
+			"total_referrals":     referralCount,
+			"coupons_earned":      couponCount,
+			"coupons_redeemed":    redeemedCount,
+			"wallet_credit_total": formatter.Money(walletTotal),
+		},
+	})
+}
+
+// Admin: Get the referral reward settings
+func GetReferralSettings(c *gin.Context) {
+	utils.LogInfo("GetReferralSettings called")
+
+	settings, err := getReferralSettings()
+	if err != nil {
+		utils.LogError("Failed to get referral settings: %v", err)
+		utils.InternalServerError(c, "Failed to get referral settings", err.Error())
+		return
+	}
+
+	utils.Success(c, "Referral settings retrieved successfully", gin.H{
+		"settings": settings,
+	})
+}
+
+// UpdateReferralSettingsRequest changes the referral rewards; omitted fields are unchanged
+type UpdateReferralSettingsRequest struct {
+	Enabled               *bool    `json:"enabled"`
+	ReferrerCouponPercent *float64 `json:"referrer_coupon_percent"`
+	ReferredCouponPercent *float64 `json:"referred_coupon_percent"`
+	CouponMinOrderValue   *float64 `json:"coupon_min_order_value"`
+	CouponMaxDiscount     *float64 `json:"coupon_max_discount"`
+	CouponValidityDays    *int     `json:"coupon_validity_days"`
+	ReferrerWalletCredit  *float64 `json:"referrer_wallet_credit"`
+	ReferredWalletCredit  *float64 `json:"referred_wallet_credit"`
+}
+
+// Admin: Update the referral reward settings. They apply to referrals made from now on.
+func UpdateReferralSettings(c *gin.Context) {
+	utils.LogInfo("UpdateReferralSettings called")
+
+	adminVal, exists := c.Get("admin")
+	if !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found in context", nil)
+		return
+	}
+	admin := adminVal.(models.Admin)
+
+	var req UpdateReferralSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+
+	settings, err := getReferralSettings()
+	if err != nil {
+		utils.LogError("Failed to get referral settings: %v", err)
+		utils.InternalServerError(c, "Failed to get referral settings", err.Error())
+		return
+	}
+
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+	}
+	for _, field := range []struct {
+		value *float64
+		dest  *float64
+		name  string
+		max   float64
+	}{
+		{req.ReferrerCouponPercent, &settings.ReferrerCouponPercent, "referrer_coupon_percent", 100},
+		{req.ReferredCouponPercent, &settings.ReferredCouponPercent, "referred_coupon_percent", 100},
+		{req.CouponMinOrderValue, &settings.CouponMinOrderValue, "coupon_min_order_value", 0},
+		{req.CouponMaxDiscount, &settings.CouponMaxDiscount, "coupon_max_discount", 0},
+		{req.ReferrerWalletCredit, &settings.ReferrerWalletCredit, "referrer_wallet_credit", 0},
+		{req.ReferredWalletCredit, &settings.ReferredWalletCredit, "referred_wallet_credit", 0},
+	} {
+		if field.value == nil {
+			continue
+		}
+		if *field.value < 0 || (field.max > 0 && *field.value > field.max) {
+			utils.BadRequest(c, "Invalid "+field.name, "Value must not be negative and percentages must not exceed 100")
+			return
+		}
+		*field.dest = *field.value
+	}
+	if req.CouponValidityDays != nil {
+		if *req.CouponValidityDays < 1 {
+			utils.Fail(c, utils.CodeCouponInvalid, "Invalid coupon_validity_days", "Coupons must be valid for at least 1 day")
+			return
+		}
+		settings.CouponValidityDays = *req.CouponValidityDays
+	}
+	if settings.Enabled && settings.ReferrerCouponPercent == 0 && settings.ReferredCouponPercent == 0 &&
+		settings.ReferrerWalletCredit == 0 && settings.ReferredWalletCredit == 0 {
+		utils.BadRequest(c, "An enabled referral program must give at least one reward", nil)
+		return
+	}
+
+	settings.UpdatedBy = admin.ID
+	if err := config.DB.Save(&settings).Error; err != nil {
+		utils.LogError("Failed to save referral settings: %v", err)
+		utils.InternalServerError(c, "Failed to save referral settings", err.Error())
+		return
+	}
+
+	utils.LogInfo("Referral settings updated by admin %s", admin.Email)
+	utils.Success(c, "Referral settings updated successfully", gin.H{
+		"settings": settings,
+	})
+}
+
+// Admin: Audit the referral reward ledger, filtered by user_id, role, reward_type and date range
+func GetReferralRewardLedger(c *gin.Context) {
+	utils.LogInfo("GetReferralRewardLedger called")
+
+	page, limit := utils.GetPaginationParams(c)
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.ReferralReward{})
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if rewardType := c.Query("reward_type"); rewardType != "" {
+		query = query.Where("reward_type = ?", rewardType)
+	}
+	if from := c.Query("from"); from != "" {
+		fromDate, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			utils.BadRequest(c, "Invalid from date", "Use YYYY-MM-DD")
+			return
+		}
+		query = query.Where("created_at >= ?", fromDate)
+	}
+	if to := c.Query("to"); to != "" {
+		toDate, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			utils.BadRequest(c, "Invalid to date", "Use YYYY-MM-DD")
+			return
+		}
+		query = query.Where("created_at < ?", toDate.AddDate(0, 0, 1))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count referral rewards: %v", err)
+		utils.InternalServerError(c, "Failed to count referral rewards", err.Error())
+		return
+	}
+
+	var totals struct {
+		WalletCredited float64
+		Coupons        int64
+	}
+	if err := query.Session(&gorm.Session{}).
+		Select("COALESCE(SUM(amount), 0) AS wallet_credited, COUNT(*) FILTER (WHERE reward_type = ?) AS coupons", models.ReferralRewardCoupon).
+		Scan(&totals).Error; err != nil {
+		utils.LogError("Failed to total referral rewards: %v", err)
+		utils.InternalServerError(c, "Failed to total referral rewards", err.Error())
+		return
+	}
+
+	var rewards []models.ReferralReward
+	if err := query.Session(&gorm.Session{}).Select("*").Order("created_at DESC").
+		Limit(limit).Offset(offset).Find(&rewards).Error; err != nil {
+		utils.LogError("Failed to get referral rewards: %v", err)
+		utils.InternalServerError(c, "Failed to get referral rewards", err.Error())
+		return
+	}
+
+	coupons := rewardCoupons(rewards)
+	formatted := make([]gin.H, len(rewards))
+	formatter := utils.NewResponseFormatter(c)
+	for i, reward := range rewards {
+		formatted[i] = formatReferralReward(formatter, reward, coupons)
+	}
+
+	utils.LogInfo("Retrieved %d referral rewards", len(rewards))
+	utils.SuccessWithPagination(c, "Referral rewards retrieved successfully", gin.H{
+		"rewards": formatted,
+		"totals": gin.H{
+			"wallet_credited": formatter.Money(totals.WalletCredited),
+			"coupons_issued":  totals.Coupons,
+		},
+	}, total, page, limit)
+}
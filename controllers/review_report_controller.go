@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// ReviewReportRequest reports a review as spam, offensive or fake
+type ReviewReportRequest struct {
+	Reason  string `json:"reason" binding:"required,oneof=spam offensive fake"`
+	Details string `json:"details" binding:"max=500"`
+}
+
+// ReportReview records the user's abuse report of a review. Once the reports reach the
+// review_report_threshold setting the review is hidden and goes back to the moderation queue.
+func ReportReview(c *gin.Context) {
+	utils.LogInfo("ReportReview called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	bookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Fail(c, utils.CodeInvalidID, "Invalid book ID", nil)
+		return
+	}
+	reviewID, err := strconv.ParseUint(c.Param("reviewId"), 10, 32)
+	if err != nil {
+		utils.Fail(c, utils.CodeInvalidID, "Invalid review ID", nil)
+		return
+	}
+	var req ReviewReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid review report: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "reason must be spam, offensive or fake", err)
+		return
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.LogError("Failed to begin transaction: %v", tx.Error)
+		utils.InternalServerError(c, "Failed to begin transaction", nil)
+		return
+	}
+
+	// Locking the review serializes reports of it, so the count and threshold check stay exact
+	var review models.Review
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND book_id = ? AND is_hidden = ?", reviewID, bookID, false).First(&review).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Review %d of book ID: %d not found: %v", reviewID, bookID, err)
+		utils.NotFound(c, "Review not found")
+		return
+	}
+	if review.UserID == user.ID {
+		tx.Rollback()
+		utils.BadRequest(c, "You cannot report your own review", nil)
+		return
+	}
+	var existing int64
+	if err := tx.Model(&models.ReviewReport{}).Where("review_id = ? AND user_id = ?", review.ID, user.ID).Count(&existing).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to check reports of review %d: %v", review.ID, err)
+		utils.InternalServerError(c, "Failed to report review", err.Error())
+		return
+	}
+	if existing > 0 {
+		tx.Rollback()
+		utils.Conflict(c, "You have already reported this review", nil)
+		return
+	}
+
+	report := models.ReviewReport{
+		ReviewID: review.ID,
+		UserID:   user.ID,
+		Reason:   req.Reason,
+		Details:  strings.TrimSpace(req.Details),
+	}
+	if err := tx.Create(&report).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to save report of review %d: %v", review.ID, err)
+		utils.InternalServerError(c, "Failed to report review", err.Error())
+		return
+	}
+
+	updates := map[string]interface{}{"report_count": review.ReportCount + 1}
+	hidden := review.ReportCount+1 >= utils.ReviewReportThreshold()
+	if hidden {
+		// A hidden review waits for a moderator to approve it again
+		updates["is_hidden"] = true
+		updates["hidden_at"] = time.Now()
+		updates["status"] = models.ReviewStatusPending
+		updates["is_approved"] = false
+	}
+	if err := tx.Model(&review).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to update review %d: %v", review.ID, err)
+		utils.InternalServerError(c, "Failed to report review", err.Error())
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit report of review %d: %v", review.ID, err)
+		utils.InternalServerError(c, "Failed to report review", nil)
+		return
+	}
+
+	if hidden {
+		utils.LogInfo("Review %d hidden after %d reports", review.ID, review.ReportCount+1)
+	}
+	utils.LogInfo("User %d reported review %d as %s", user.ID, review.ID, req.Reason)
+	utils.Created(c, "Thanks, our moderators will look into this review", gin.H{
+		"report_id": report.ID,
+		"review_id": review.ID,
+		"reason":    report.Reason,
+	})
+}
+
+// AdminGetReviewReports lists the abuse reports of a review, newest first
+func AdminGetReviewReports(c *gin.Context) {
+	utils.LogInfo("AdminGetReviewReports called")
+
+	reviewID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Fail(c, utils.CodeInvalidID, "Invalid review ID", nil)
+		return
+	}
+	var review models.Review
+	if err := config.DB.First(&review, reviewID).Error; err != nil {
+		utils.LogError("Review %d not found: %v", reviewID, err)
+		utils.NotFound(c, "Review not found")
+		return
+	}
+
+	var reports []models.ReviewReport
+	if err := config.DB.Preload("User").Where("review_id = ?", review.ID).
+		Order("created_at DESC, id DESC").Find(&reports).Error; err != nil {
+		utils.LogError("Failed to fetch reports of review %d: %v", review.ID, err)
+		utils.InternalServerError(c, "Failed to fetch review reports", err.Error())
+		return
+	}
+
+	list := make([]gin.H, len(reports))
+	byReason := map[string]int{}
+	formatter := utils.NewResponseFormatter(c)
+	for i, report := range reports {
+		byReason[report.Reason]++
+		list[i] = gin.H{
+			"id":         report.ID,
+			"user_id":    report.UserID,
+			"username":   report.User.Username,
+			"reason":     report.Reason,
+			"details":    report.Details,
+			"created_at": formatter.Timestamp(report.CreatedAt),
+		}
+	}
+
+	utils.Success(c, "Review reports retrieved successfully", gin.H{
+		"review_id":    review.ID,
+		"report_count": review.ReportCount,
+		"is_hidden":    review.IsHidden,
+		"by_reason":    byReason,
+		"reports":      list,
+	})
+}
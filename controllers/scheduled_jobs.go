@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"gorm.io/gorm"
+)
+
+// onlinePaymentWindow is how long an online order may stay unpaid before it is cancelled
+var onlinePaymentWindow = 30 * time.Minute
+
+// RegisterScheduledJobs registers the application's background jobs with the scheduler
+func RegisterScheduledJobs(cfg *config.Config, handlers *Handlers) {
+	onlinePaymentWindow = cfg.OnlinePaymentWindow
+	accountDeletionGrace = cfg.AccountDeletionGrace
+
+	utils.RegisterJob("expire_discounts", "Ends book discounts past their end date and deactivates expired offers",
+		time.Hour, expireDiscountsJob)
+	utils.RegisterJob("publish_scheduled", "Publishes and unpublishes books and offers at their scheduled times",
+		time.Minute, publishScheduledJob)
+	utils.RegisterJob("expire_coupons", "Deactivates expired coupons and drops them from carts",
+		time.Hour, expireCouponsJob)
+	utils.RegisterJob("cancel_stale_online_orders", "Cancels online orders left unpaid past the payment window and restocks them",
+		5*time.Minute, cancelStaleOnlineOrdersJob)
+	utils.RegisterJob("allocate_preorders", "Allocates stock to pre-orders of released books and moves them to Processing",
+		15*time.Minute, allocatePreordersJob)
+	utils.RegisterJob("expire_gift_cards", "Marks gift cards past their expiry as expired",
+		time.Hour, expireGiftCardsJob)
+	utils.RegisterJob("cart_expiry", "Sends cart expiry reminders and removes expired cart items",
+		time.Hour, cartExpiryJob)
+	utils.RegisterJob("abandoned_carts", "Records abandoned carts, reminds their owners and tracks recovered orders",
+		time.Hour, abandonedCartsJob)
+	utils.RegisterJob(utils.BackInStockJobName, "Emails users waiting for books that are back in stock",
+		5*time.Minute, backInStockJob)
+	utils.RegisterJob("anonymize_deleted_accounts", "Anonymizes accounts whose deletion grace period has ended",
+		time.Hour, anonymizeDeletedAccountsJob)
+	utils.RegisterJob("cleanup_sessions", "Deletes user sessions that expired or were signed out over 30 days ago",
+		24*time.Hour, utils.CleanupExpiredSessions)
+	utils.RegisterJob("cleanup_login_failures", "Deletes failed login counts older than an hour",
+		time.Hour, utils.CleanupLoginFailures)
+	utils.RegisterJob("cleanup_phone_otps", "Deletes phone OTPs and OTP text requests that no longer count towards the limits",
+		time.Hour, utils.CleanupPhoneOTPs)
+	utils.RegisterJob("cleanup_email_otps", "Deletes email OTPs that expired and no longer count towards the limits",
+		time.Hour, utils.CleanupEmailOTPs)
+	utils.RegisterJob("move_ebooks_to_private_storage", "Moves e-books uploaded to the public media storage into private storage",
+		time.Hour, MoveEbooksToPrivateStorage)
+	utils.RegisterJob("cleanup_rate_limits", "Deletes request counts for rate limits whose window started over a day ago",
+		24*time.Hour, utils.CleanupRateLimitCounters)
+	utils.RegisterJob("reconcile_wallets", "Flags wallets whose balance does not match the sum of their ledger",
+		time.Hour, handlers.Wallet.ReconcileWalletsJob)
+	utils.RegisterJob("refresh_exchange_rates", "Fetches the latest exchange rates for the supported currencies",
+		cfg.ExchangeRateRefresh, utils.RefreshExchangeRates)
+	if os.Getenv("CATALOG_DIGEST_WEBHOOK_URL") != "" {
+		utils.RegisterJob("catalog_digest", "Delivers the daily catalog change digest to the webhook",
+			24*time.Hour, catalogDigestJob)
+	} else {
+		utils.LogInfo("Catalog digest webhook not configured, daily digest disabled")
+	}
+}
+
+func expireDiscountsJob() (string, error) {
+	now := time.Now()
+
+	// Books whose own discount ended go back to their original price
+	books := config.DB.Model(&models.Book{}).
+		Where("discount_percentage > 0 AND discount_end_date > ? AND discount_end_date < ?", time.Time{}, now).
+		Updates(map[string]interface{}{
+			"price":               gorm.Expr("CASE WHEN original_price > 0 THEN original_price ELSE price END"),
+			"discount_percentage": 0,
+		})
+	if books.Error != nil {
+		return "", books.Error
+	}
+
+	productOffers := config.DB.Model(&models.ProductOffer{}).
+		Where("active = ? AND end_date < ?", true, now).Update("active", false)
+	if productOffers.Error != nil {
+		return "", productOffers.Error
+	}
+	categoryOffers := config.DB.Model(&models.CategoryOffer{}).
+		Where("active = ? AND end_date < ?", true, now).Update("active", false)
+	if categoryOffers.Error != nil {
+		return "", categoryOffers.Error
+	}
+
+	if books.RowsAffected+productOffers.RowsAffected+categoryOffers.RowsAffected > 0 {
+		utils.InvalidateCatalogCache()
+	}
+	return fmt.Sprintf("%d book discounts ended, %d product and %d category offers deactivated",
+		books.RowsAffected, productOffers.RowsAffected, categoryOffers.RowsAffected), nil
+}
+
+// publishScheduledJob applies the scheduled visibility changes of books and offers that are due
+// and clears them, so each runs once
+func publishScheduledJob() (string, error) {
+	now := time.Now()
+	counts := make([]int64, 0, 6)
+	for _, schedule := range []struct {
+		model        interface{}
+		activeColumn string
+	}{
+		{&models.Book{}, "is_active"},
+		{&models.ProductOffer{}, "active"},
+		{&models.CategoryOffer{}, "active"},
+	} {
+		published := config.DB.Model(schedule.model).Where("publish_at <= ?", now).
+			Updates(map[string]interface{}{schedule.activeColumn: true, "publish_at": nil})
+		if published.Error != nil {
+			return "", published.Error
+		}
+		unpublished := config.DB.Model(schedule.model).Where("unpublish_at <= ?", now).
+			Updates(map[string]interface{}{schedule.activeColumn: false, "unpublish_at": nil})
+		if unpublished.Error != nil {
+			return "", unpublished.Error
+		}
+		counts = append(counts, published.RowsAffected, unpublished.RowsAffected)
+	}
+
+	for _, count := range counts {
+		if count > 0 {
+			utils.InvalidateCatalogCache()
+			break
+		}
+	}
+	return fmt.Sprintf("books: %d published, %d unpublished; product offers: %d on, %d off; category offers: %d on, %d off",
+		counts[0], counts[1], counts[2], counts[3], counts[4], counts[5]), nil
+}
+
+func expireCouponsJob() (string, error) {
+	now := time.Now()
+	coupons := config.DB.Model(&models.Coupon{}).Where("active = ? AND expiry < ?", true, now).Update("active", false)
+	if coupons.Error != nil {
+		return "", coupons.Error
+	}
+
+	applied := config.DB.Where("coupon_id IN (?)",
+		config.DB.Model(&models.Coupon{}).Select("id").Where("active = ? OR expiry < ?", false, now)).
+		Delete(&models.UserActiveCoupon{})
+	if applied.Error != nil {
+		return "", applied.Error
+	}
+
+	return fmt.Sprintf("%d coupons deactivated, removed from %d carts", coupons.RowsAffected, applied.RowsAffected), nil
+}
+
+func expireGiftCardsJob() (string, error) {
+	expired, err := utils.ExpireGiftCards()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d gift cards expired", expired), nil
+}
+
+func cancelStaleOnlineOrdersJob() (string, error) {
+	var orders []models.Order
+	if err := config.DB.Preload("OrderItems").
+		Where("status = ? AND payment_method IN ? AND created_at < ?",
+			models.OrderStatusPlaced, []string{"", "RAZORPAY", "online"}, time.Now().Add(-onlinePaymentWindow)).
+		Find(&orders).Error; err != nil {
+		return "", err
+	}
+
+	cancelled := 0
+	for i := range orders {
+		order := &orders[i]
+		tx := config.DB.Begin()
+		if err := cancelUnpaidOrder(tx, order, "Payment not completed within the payment window"); err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to cancel stale order %d: %v", order.ID, err)
+			continue
+		}
+		if err := tx.Commit().Error; err != nil {
+			utils.LogError("Failed to commit cancellation of stale order %d: %v", order.ID, err)
+			continue
+		}
+		cancelled++
+	}
+	return fmt.Sprintf("%d of %d unpaid online orders cancelled", cancelled, len(orders)), nil
+}
+
+// cancelUnpaidOrder cancels an order that was never paid: its stock and coupon usage are
+// released and the cancellation is added to the order timeline. No refund is due.
+func cancelUnpaidOrder(tx *gorm.DB, order *models.Order, reason string) error {
+	// Claim the order so a payment verified at the same moment is not cancelled
+	result := tx.Model(&models.Order{}).
+		Where("id = ? AND status = ?", order.ID, models.OrderStatusPlaced).
+		Updates(map[string]interface{}{
+			"status":              models.OrderStatusCancelled,
+			"cancellation_reason": reason,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("order %d is no longer awaiting payment", order.ID)
+	}
+	order.Status = models.OrderStatusCancelled
+	order.CancellationReason = reason
+
+	if _, err := utils.RestockOrderItems(tx, order.OrderItems, models.StockSourceCancellation); err != nil {
+		return err
+	}
+	// Give back any wallet funds held for a split payment
+	if _, err := utils.ReleaseWalletHold(tx, order.UserID, order.ID); err != nil {
+		return err
+	}
+	if order.CouponCode != "" {
+		if err := tx.Model(&models.Coupon{}).Where("code = ? AND used_count > 0", order.CouponCode).
+			UpdateColumn("used_count", gorm.Expr("used_count - 1")).Error; err != nil {
+			return err
+		}
+	}
+	return recordOrderStatusEvent(tx, order.ID, models.OrderStatusCancelled, "system", 0, reason)
+}
+
+func allocatePreordersJob() (string, error) {
+	// Paid pre-orders, oldest first, whose books have all been released. Unpaid online orders
+	// wait for their payment.
+	var orders []models.Order
+	if err := config.DB.Preload("OrderItems", "preorder_pending = ?", true).
+		Where("is_preorder = ? AND (status = ? OR (status = ? AND payment_method IN ?))",
+			true, models.OrderStatusPaid, models.OrderStatusPlaced, []string{"cod", "wallet"}).
+		Where("NOT EXISTS (SELECT 1 FROM order_items JOIN books ON books.id = order_items.book_id "+
+			"WHERE order_items.order_id = orders.id AND order_items.preorder_pending = ? AND books.release_date > ?)", true, time.Now()).
+		Order("created_at ASC").Find(&orders).Error; err != nil {
+		return "", err
+	}
+
+	allocated := 0
+	for i := range orders {
+		order := &orders[i]
+		tx := config.DB.Begin()
+		released, err := allocatePreorder(tx, order)
+		if err != nil || !released {
+			tx.Rollback()
+			if err != nil {
+				utils.LogError("Failed to allocate pre-order %d: %v", order.ID, err)
+			}
+			continue
+		}
+		if err := tx.Commit().Error; err != nil {
+			utils.LogError("Failed to commit allocation of pre-order %d: %v", order.ID, err)
+			continue
+		}
+		allocated++
+	}
+	return fmt.Sprintf("%d of %d released pre-orders allocated", allocated, len(orders)), nil
+}
+
+// allocatePreorder takes the stock for every waiting item of a released pre-order and moves
+// the order to Processing. It reports false, and the caller rolls back, when the stock does
+// not cover every item yet; the order then waits for the next run.
+func allocatePreorder(tx *gorm.DB, order *models.Order) (bool, error) {
+	sort.Slice(order.OrderItems, func(i, j int) bool { return order.OrderItems[i].BookID < order.OrderItems[j].BookID })
+	for i := range order.OrderItems {
+		allocated, err := utils.AllocatePreorderItem(tx, &order.OrderItems[i])
+		if err != nil || !allocated {
+			return false, err
+		}
+	}
+
+	result := tx.Model(&models.Order{}).
+		Where("id = ? AND status = ?", order.ID, order.Status).
+		Update("status", models.OrderStatusProcessing)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	order.Status = models.OrderStatusProcessing
+	return true, recordOrderStatusEvent(tx, order.ID, models.OrderStatusProcessing, "system", 0, "Pre-ordered books released")
+}
+
+func cartExpiryJob() (string, error) {
+	reminded, err := utils.SendCartExpiryReminders()
+	if err != nil {
+		return "", err
+	}
+	removed, err := utils.CleanupExpiredCarts()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d users reminded, %d expired cart rows removed", reminded, removed), nil
+}
+
+func abandonedCartsJob() (string, error) {
+	run, err := utils.ProcessAbandonedCarts()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d carts abandoned, %d reminders sent, %d recovered, %d closed",
+		run.Detected, run.Reminded, run.Recovered, run.Closed), nil
+}
+
+func backInStockJob() (string, error) {
+	sent, err := utils.SendBackInStockNotifications()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d back-in-stock emails sent", sent), nil
+}
+
+func catalogDigestJob() (string, error) {
+	count, err := SendCatalogChangeDigest(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d catalog changes sent", count), nil
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recordAdminStockMovement logs a stock change made by the admin in context
+func recordAdminStockMovement(c *gin.Context, tx *gorm.DB, change utils.StockChange) error {
+	if adminVal, exists := c.Get("admin"); exists {
+		if admin, ok := adminVal.(models.Admin); ok {
+			change.AdminID = &admin.ID
+		}
+	}
+	return utils.RecordStockMovement(tx, change)
+}
+
+// AdminGetStockMovements lists a book's stock movements, newest first, and reconciles their
+// sum against the book's current stock
+func AdminGetStockMovements(c *gin.Context) {
+	utils.LogInfo("AdminGetStockMovements called")
+
+	bookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Fail(c, utils.CodeInvalidID, "Invalid product ID", nil)
+		return
+	}
+	var book models.Book
+	if err := config.DB.Unscoped().First(&book, bookID).Error; err != nil {
+		utils.LogError("Book %d not found: %v", bookID, err)
+		utils.Fail(c, utils.CodeBookNotFound, "Product not found", nil)
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+	query := config.DB.Model(&models.StockMovement{}).Where("book_id = ?", book.ID)
+	if source := c.Query("source"); source != "" {
+		query = query.Where("source = ?", source)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count stock movements of book %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to fetch stock movements", err.Error())
+		return
+	}
+	var movements []models.StockMovement
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&movements).Error; err != nil {
+		utils.LogError("Failed to fetch stock movements of book %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to fetch stock movements", err.Error())
+		return
+	}
+
+	// The movements of every source add up to the stock the book should have
+	var movementsTotal int
+	if err := config.DB.Model(&models.StockMovement{}).Where("book_id = ?", book.ID).
+		Select("COALESCE(SUM(delta), 0)").Scan(&movementsTotal).Error; err != nil {
+		utils.LogError("Failed to sum stock movements of book %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to fetch stock movements", err.Error())
+		return
+	}
+
+	utils.SuccessWithPagination(c, "Stock movements retrieved successfully", gin.H{
+		"book_id":         book.ID,
+		"book_name":       book.Name,
+		"current_stock":   book.Stock,
+		"movements_total": movementsTotal,
+		"reconciled":      movementsTotal == book.Stock,
+		"discrepancy":     book.Stock - movementsTotal,
+		"movements":       movements,
+	}, total, page, limit)
+}
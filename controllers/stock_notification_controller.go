@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SubscribeStockNotification registers the user's interest in an out-of-stock book.
+// The user is emailed once when the book is back in stock.
+func SubscribeStockNotification(c *gin.Context) {
+	utils.LogInfo("SubscribeStockNotification called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	bookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid book ID: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeInvalidID, "Invalid book ID", nil)
+		return
+	}
+
+	var book models.Book
+	if err := config.DB.First(&book, bookID).Error; err != nil {
+		utils.LogError("Book not found - Book ID: %d: %v", bookID, err)
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
+	if !book.IsActive || book.Blocked {
+		utils.LogError("Book not available - Book ID: %d", bookID)
+		utils.Fail(c, utils.CodeBookUnavailable, "Book not available", nil)
+		return
+	}
+	if book.Stock > 0 {
+		utils.LogInfo("Book ID: %d is in stock, no notification needed for user ID: %d", bookID, user.ID)
+		utils.BadRequest(c, "Book is in stock", gin.H{"stock": book.Stock})
+		return
+	}
+
+	formatter := utils.NewResponseFormatter(c)
+	var notification models.StockNotification
+	err = config.DB.Where("user_id = ? AND book_id = ?", user.ID, book.ID).First(&notification).Error
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		notification = models.StockNotification{UserID: user.ID, BookID: book.ID}
+		if err := config.DB.Create(&notification).Error; err != nil {
+			utils.LogError("Failed to create stock notification for user ID: %d, book ID: %d: %v", user.ID, book.ID, err)
+			utils.InternalServerError(c, "Failed to subscribe", nil)
+			return
+		}
+	case err != nil:
+		utils.LogError("Failed to look up stock notification for user ID: %d, book ID: %d: %v", user.ID, book.ID, err)
+		utils.InternalServerError(c, "Failed to subscribe", nil)
+		return
+	case notification.NotifiedAt == nil:
+		utils.LogInfo("User ID: %d already subscribed to book ID: %d", user.ID, book.ID)
+		utils.Success(c, "You will be notified when this book is back in stock", gin.H{
+			"notification": stockNotificationResponse(formatter, notification, book),
+		})
+		return
+	default:
+		// Already notified about an earlier restock: wait for the next one
+		if err := config.DB.Model(&notification).Update("notified_at", nil).Error; err != nil {
+			utils.LogError("Failed to renew stock notification %d: %v", notification.ID, err)
+			utils.InternalServerError(c, "Failed to subscribe", nil)
+			return
+		}
+		notification.NotifiedAt = nil
+	}
+
+	utils.LogInfo("User ID: %d subscribed to back-in-stock notification for book ID: %d", user.ID, book.ID)
+	utils.Created(c, "You will be notified when this book is back in stock", gin.H{
+		"notification": stockNotificationResponse(formatter, notification, book),
+	})
+}
+
+// UnsubscribeStockNotification removes the user's back-in-stock subscription for a book
+func UnsubscribeStockNotification(c *gin.Context) {
+	utils.LogInfo("UnsubscribeStockNotification called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	result := config.DB.Where("user_id = ? AND book_id = ?", user.ID, c.Param("id")).Delete(&models.StockNotification{})
+	if result.Error != nil {
+		utils.LogError("Failed to remove stock notification for user ID: %d, book ID: %s: %v", user.ID, c.Param("id"), result.Error)
+		utils.InternalServerError(c, "Failed to unsubscribe", nil)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.NotFound(c, "Notification not found")
+		return
+	}
+
+	utils.LogInfo("User ID: %d unsubscribed from book ID: %s", user.ID, c.Param("id"))
+	utils.Success(c, "Notification removed", nil)
+}
+
+// GetStockNotifications lists the user's back-in-stock subscriptions
+func GetStockNotifications(c *gin.Context) {
+	utils.LogInfo("GetStockNotifications called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var notifications []models.StockNotification
+	if err := config.DB.Preload("Book").Where("user_id = ?", user.ID).
+		Order("created_at DESC").Find(&notifications).Error; err != nil {
+		utils.LogError("Failed to fetch stock notifications for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch notifications", nil)
+		return
+	}
+
+	response := make([]gin.H, 0, len(notifications))
+	formatter := utils.NewResponseFormatter(c)
+	for _, notification := range notifications {
+		response = append(response, stockNotificationResponse(formatter, notification, notification.Book))
+	}
+
+	utils.LogInfo("Retrieved %d stock notifications for user ID: %d", len(response), user.ID)
+	utils.Success(c, "Notifications retrieved successfully", gin.H{
+		"notifications": response,
+	})
+}
+
+// AdminGetStockNotificationDemand reports, per book, how many users are waiting for a restock
+func AdminGetStockNotificationDemand(c *gin.Context) {
+	utils.LogInfo("AdminGetStockNotificationDemand called")
+
+	page, limit := utils.GetPaginationParams(c)
+
+	type bookDemand struct {
+		BookID         uint
+		Name           string
+		Author         string
+		Stock          int
+		Pending        int64
+		Notified       int64
+		LastSubscribed time.Time
+	}
+
+	query := config.DB.Table("stock_notifications").
+		Joins("JOIN books ON books.id = stock_notifications.book_id").
+		Where("books.deleted_at IS NULL")
+	if c.DefaultQuery("pending_only", "true") == "true" {
+		query = query.Where("stock_notifications.notified_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Distinct("stock_notifications.book_id").Count(&total).Error; err != nil {
+		utils.LogError("Failed to count stock notification demand: %v", err)
+		utils.InternalServerError(c, "Failed to fetch demand", nil)
+		return
+	}
+
+	var rows []bookDemand
+	if err := query.Select("books.id AS book_id, books.name, books.author, books.stock, " +
+		"COUNT(*) FILTER (WHERE stock_notifications.notified_at IS NULL) AS pending, " +
+		"COUNT(*) FILTER (WHERE stock_notifications.notified_at IS NOT NULL) AS notified, " +
+		"MAX(stock_notifications.created_at) AS last_subscribed").
+		Group("books.id, books.name, books.author, books.stock").
+		Order("pending DESC, last_subscribed DESC").
+		Limit(limit).Offset((page - 1) * limit).
+		Scan(&rows).Error; err != nil {
+		utils.LogError("Failed to fetch stock notification demand: %v", err)
+		utils.InternalServerError(c, "Failed to fetch demand", nil)
+		return
+	}
+
+	demand := make([]gin.H, 0, len(rows))
+	formatter := utils.NewResponseFormatter(c)
+	for _, row := range rows {
+		demand = append(demand, gin.H{
+			"book_id":         row.BookID,
+			"name":            row.Name,
+			"author":          row.Author,
+			"stock":           row.Stock,
+			"pending":         row.Pending,
+			"notified":        row.Notified,
+			"last_subscribed": formatter.Timestamp(row.LastSubscribed),
+		})
+	}
+
+	utils.LogInfo("Retrieved back-in-stock demand for %d books", len(demand))
+	utils.SuccessWithPagination(c, "Stock notification demand retrieved successfully", gin.H{
+		"books": demand,
+	}, total, page, limit)
+}
+
+// stockNotificationResponse is the response representation of a back-in-stock subscription
+func stockNotificationResponse(f utils.ResponseFormatter, notification models.StockNotification, book models.Book) gin.H {
+	response := gin.H{
+		"id":         notification.ID,
+		"book_id":    book.ID,
+		"name":       book.Name,
+		"author":     book.Author,
+		"image_url":  book.ImageURL,
+		"in_stock":   book.Stock > 0,
+		"status":     "pending",
+		"created_at": f.Timestamp(notification.CreatedAt),
+	}
+	if notification.NotifiedAt != nil {
+		response["status"] = "notified"
+		response["notified_at"] = f.Timestamp(*notification.NotifiedAt)
+	}
+	return response
+}
@@ -0,0 +1,353 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// supportTicketStatuses are the statuses a ticket can be in
+var supportTicketStatuses = map[string]bool{
+	models.TicketStatusOpen:     true,
+	models.TicketStatusPending:  true,
+	models.TicketStatusResolved: true,
+}
+
+// CreateSupportTicketRequest opens a ticket, optionally about one of the user's orders
+type CreateSupportTicketRequest struct {
+	Subject string `json:"subject" binding:"required,min=3,max=150"`
+	Message string `json:"message" binding:"required,max=5000"`
+	OrderID *uint  `json:"order_id"`
+}
+
+// SupportTicketMessageRequest adds a message to a ticket. Status is only read from admins,
+// who may set the ticket's status along with the reply.
+type SupportTicketMessageRequest struct {
+	Message string `json:"message" binding:"required,max=5000"`
+	Status  string `json:"status"`
+}
+
+// CreateSupportTicket opens a support ticket with the user's first message
+func CreateSupportTicket(c *gin.Context) {
+	utils.LogInfo("CreateSupportTicket called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var req CreateSupportTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid support ticket request for user %d: %v", user.ID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	subject := strings.TrimSpace(req.Subject)
+	text := strings.TrimSpace(req.Message)
+	if subject == "" || text == "" {
+		utils.BadRequest(c, "Subject and message cannot be empty", nil)
+		return
+	}
+
+	if req.OrderID != nil {
+		var order models.Order
+		if err := config.DB.Select("id").Where("id = ? AND user_id = ?", *req.OrderID, user.ID).First(&order).Error; err != nil {
+			utils.LogError("Order %d not found for user %d: %v", *req.OrderID, user.ID, err)
+			utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
+			return
+		}
+	}
+
+	now := time.Now()
+	ticket := models.SupportTicket{
+		UserID:        user.ID,
+		OrderID:       req.OrderID,
+		Subject:       subject,
+		Status:        models.TicketStatusOpen,
+		LastMessageAt: now,
+		LastSender:    models.TicketSenderUser,
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&ticket).Error; err != nil {
+			return err
+		}
+		message := models.SupportTicketMessage{
+			TicketID:   ticket.ID,
+			SenderType: models.TicketSenderUser,
+			SenderID:   user.ID,
+			SenderName: supportUserName(user),
+			Message:    text,
+		}
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+		ticket.Messages = []models.SupportTicketMessage{message}
+		return nil
+	}); err != nil {
+		utils.LogError("Failed to create support ticket for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to create support ticket", nil)
+		return
+	}
+
+	utils.LogInfo("User %d opened support ticket %d", user.ID, ticket.ID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Created(c, "Support ticket created successfully", gin.H{
+		"ticket": supportTicketResponse(formatter, ticket, true),
+	})
+}
+
+// GetSupportTickets lists the user's tickets, most recently active first, optionally of one
+// status
+func GetSupportTickets(c *gin.Context) {
+	utils.LogInfo("GetSupportTickets called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	page, limit := utils.GetPaginationParams(c)
+	query := config.DB.Model(&models.SupportTicket{}).Where("user_id = ?", user.ID)
+	if status := c.Query("status"); status != "" {
+		if !supportTicketStatuses[status] {
+			utils.BadRequest(c, "Invalid status", "Status must be open, pending or resolved")
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError("Failed to count support tickets for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch support tickets", nil)
+		return
+	}
+
+	var tickets []models.SupportTicket
+	if err := query.Order("last_message_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).
+		Find(&tickets).Error; err != nil {
+		utils.LogError("Failed to fetch support tickets for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch support tickets", nil)
+		return
+	}
+
+	response := make([]gin.H, len(tickets))
+	formatter := utils.NewResponseFormatter(c)
+	for i, ticket := range tickets {
+		response[i] = supportTicketResponse(formatter, ticket, false)
+	}
+
+	utils.LogInfo("Retrieved %d support tickets for user %d", len(response), user.ID)
+	utils.SuccessWithPagination(c, "Support tickets retrieved successfully", gin.H{
+		"tickets": response,
+	}, total, page, limit)
+}
+
+// GetSupportTicket returns one of the user's tickets with its conversation
+func GetSupportTicket(c *gin.Context) {
+	utils.LogInfo("GetSupportTicket called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	ticket, ok := loadSupportTicket(c, config.DB.Where("user_id = ?", user.ID))
+	if !ok {
+		return
+	}
+
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Support ticket retrieved successfully", gin.H{
+		"ticket": supportTicketResponse(formatter, ticket, true),
+	})
+}
+
+// AddSupportTicketMessage adds the user's reply to their ticket. A reply reopens the ticket,
+// resolved or not, for support to pick up.
+func AddSupportTicketMessage(c *gin.Context) {
+	utils.LogInfo("AddSupportTicketMessage called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var req SupportTicketMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid support ticket message for user %d: %v", user.ID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	text := strings.TrimSpace(req.Message)
+	if text == "" {
+		utils.BadRequest(c, "Message cannot be empty", nil)
+		return
+	}
+
+	ticket, ok := loadSupportTicket(c, config.DB.Where("user_id = ?", user.ID))
+	if !ok {
+		return
+	}
+
+	message := models.SupportTicketMessage{
+		TicketID:   ticket.ID,
+		SenderType: models.TicketSenderUser,
+		SenderID:   user.ID,
+		SenderName: supportUserName(user),
+		Message:    text,
+	}
+	if err := addSupportTicketMessage(config.DB, &ticket, &message, models.TicketStatusOpen); err != nil {
+		utils.LogError("Failed to add message to support ticket %d: %v", ticket.ID, err)
+		utils.InternalServerError(c, "Failed to add message", nil)
+		return
+	}
+
+	utils.LogInfo("User %d replied to support ticket %d", user.ID, ticket.ID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Created(c, "Message added successfully", gin.H{
+		"ticket": supportTicketResponse(formatter, ticket, true),
+	})
+}
+
+// ResolveSupportTicket lets the user close their ticket once their issue is sorted out
+func ResolveSupportTicket(c *gin.Context) {
+	utils.LogInfo("ResolveSupportTicket called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	ticket, ok := loadSupportTicket(c, config.DB.Where("user_id = ?", user.ID))
+	if !ok {
+		return
+	}
+	if ticket.Status == models.TicketStatusResolved {
+		utils.BadRequest(c, "Ticket is already resolved", nil)
+		return
+	}
+
+	if err := setSupportTicketStatus(config.DB, &ticket, models.TicketStatusResolved); err != nil {
+		utils.LogError("Failed to resolve support ticket %d: %v", ticket.ID, err)
+		utils.InternalServerError(c, "Failed to resolve support ticket", nil)
+		return
+	}
+
+	utils.LogInfo("User %d resolved support ticket %d", user.ID, ticket.ID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Support ticket resolved successfully", gin.H{
+		"ticket": supportTicketResponse(formatter, ticket, true),
+	})
+}
+
+// loadSupportTicket loads the ticket named by :id through query, which may narrow it down to
+// a user, with its messages oldest first. It writes the error response and returns false
+// when there is no such ticket.
+func loadSupportTicket(c *gin.Context, query *gorm.DB) (models.SupportTicket, bool) {
+	var ticket models.SupportTicket
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid support ticket ID: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeInvalidID, "Invalid ticket ID", nil)
+		return ticket, false
+	}
+	if err := query.Preload("Messages", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at, id")
+	}).First(&ticket, ticketID).Error; err != nil {
+		utils.LogError("Support ticket not found: %s: %v", c.Param("id"), err)
+		utils.Fail(c, utils.CodeTicketNotFound, "Support ticket not found", nil)
+		return ticket, false
+	}
+	return ticket, true
+}
+
+// addSupportTicketMessage saves the message and moves the ticket to status with the message
+// as its latest
+func addSupportTicketMessage(db *gorm.DB, ticket *models.SupportTicket, message *models.SupportTicketMessage, status string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		ticket.LastMessageAt = message.CreatedAt
+		ticket.LastSender = message.SenderType
+		if err := setSupportTicketStatus(tx, ticket, status); err != nil {
+			return err
+		}
+		ticket.Messages = append(ticket.Messages, *message)
+		return nil
+	})
+}
+
+// setSupportTicketStatus saves the ticket with the status, stamping when it was resolved
+func setSupportTicketStatus(db *gorm.DB, ticket *models.SupportTicket, status string) error {
+	ticket.Status = status
+	if status == models.TicketStatusResolved {
+		now := time.Now()
+		ticket.ResolvedAt = &now
+	} else {
+		ticket.ResolvedAt = nil
+	}
+	return db.Model(ticket).Select("status", "resolved_at", "last_message_at", "last_sender").Updates(ticket).Error
+}
+
+// supportUserName is the name a user's messages are signed with
+func supportUserName(user models.User) string {
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		return name
+	}
+	return user.Username
+}
+
+// supportTicketResponse is the API view of a ticket, with its messages when withMessages is
+// set
+func supportTicketResponse(f utils.ResponseFormatter, ticket models.SupportTicket, withMessages bool) gin.H {
+	response := gin.H{
+		"id":              ticket.ID,
+		"subject":         ticket.Subject,
+		"order_id":        ticket.OrderID,
+		"status":          ticket.Status,
+		"last_sender":     ticket.LastSender,
+		"last_message_at": f.Timestamp(ticket.LastMessageAt),
+		"created_at":      f.Timestamp(ticket.CreatedAt),
+		"updated_at":      f.Timestamp(ticket.UpdatedAt),
+	}
+	if ticket.ResolvedAt != nil {
+		response["resolved_at"] = f.Timestamp(*ticket.ResolvedAt)
+	}
+	if withMessages {
+		messages := make([]gin.H, len(ticket.Messages))
+		for i, message := range ticket.Messages {
+			messages[i] = gin.H{
+				"id":          message.ID,
+				"sender_type": message.SenderType,
+				"sender_name": message.SenderName,
+				"message":     message.Message,
+				"created_at":  f.Timestamp(message.CreatedAt),
+			}
+		}
+		response["messages"] = messages
+	}
+	return response
+}
@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TagRequest represents the tag creation/update request. The slug is derived from the name
+// when left out.
+type TagRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+	Slug string `json:"slug" binding:"max=60"`
+}
+
+// BookTagsRequest replaces the tags of a book
+type BookTagsRequest struct {
+	TagIDs []uint `json:"tag_ids" binding:"max=20"`
+}
+
+// tagChip is a tag as shown on a book
+type tagChip struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// tagChips returns the tags as chips for book responses
+func tagChips(tags []models.Tag) []tagChip {
+	chips := make([]tagChip, 0, len(tags))
+	for _, tag := range tags {
+		chips = append(chips, tagChip{ID: tag.ID, Name: tag.Name, Slug: tag.Slug})
+	}
+	return chips
+}
+
+// GetTags lists the tags with the number of books carrying each
+func GetTags(c *gin.Context) {
+	utils.LogInfo("GetTags called")
+
+	type tagRow struct {
+		models.Tag
+		BookCount int64 `json:"book_count"`
+	}
+	var tags []tagRow
+	if err := config.DB.Model(&models.Tag{}).
+		Select("tags.*, (SELECT COUNT(*) FROM book_tags JOIN books ON books.id = book_tags.book_id AND books.deleted_at IS NULL WHERE book_tags.tag_id = tags.id) AS book_count").
+		Order("name ASC").Scan(&tags).Error; err != nil {
+		utils.LogError("Failed to fetch tags: %v", err)
+		utils.InternalServerError(c, "Failed to fetch tags", err.Error())
+		return
+	}
+
+	utils.LogInfo("Retrieved %d tags", len(tags))
+	utils.Success(c, "Tags retrieved successfully", gin.H{
+		"tags": tags,
+	})
+}
+
+// CreateTag handles tag creation
+func CreateTag(c *gin.Context) {
+	utils.LogInfo("CreateTag called")
+
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid input: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err.Error())
+		return
+	}
+
+	var tag models.Tag
+	if !applyTagRequest(c, &tag, req) {
+		return
+	}
+	if err := config.DB.Create(&tag).Error; err != nil {
+		utils.LogError("Failed to create tag: %v", err)
+		utils.InternalServerError(c, "Failed to create tag", err.Error())
+		return
+	}
+
+	utils.LogInfo("Tag created successfully: %s", tag.Slug)
+	utils.Success(c, "Tag created successfully", gin.H{
+		"tag": tag,
+	})
+}
+
+// UpdateTag handles tag updates
+func UpdateTag(c *gin.Context) {
+	utils.LogInfo("UpdateTag called")
+
+	var tag models.Tag
+	if err := config.DB.First(&tag, c.Param("id")).Error; err != nil {
+		utils.LogError("Tag not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeTagNotFound, "Tag not found", nil)
+		return
+	}
+
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid input: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err.Error())
+		return
+	}
+
+	if !applyTagRequest(c, &tag, req) {
+		return
+	}
+	if err := config.DB.Save(&tag).Error; err != nil {
+		utils.LogError("Failed to update tag: %v", err)
+		utils.InternalServerError(c, "Failed to update tag", err.Error())
+		return
+	}
+
+	utils.LogInfo("Tag updated successfully: %s", tag.Slug)
+	utils.Success(c, "Tag updated successfully", gin.H{
+		"tag": tag,
+	})
+}
+
+// DeleteTag deletes a tag and removes it from every book
+func DeleteTag(c *gin.Context) {
+	utils.LogInfo("DeleteTag called")
+
+	var tag models.Tag
+	if err := config.DB.First(&tag, c.Param("id")).Error; err != nil {
+		utils.LogError("Tag not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeTagNotFound, "Tag not found", nil)
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM book_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+	if err != nil {
+		utils.LogError("Failed to delete tag: %v", err)
+		utils.InternalServerError(c, "Failed to delete tag", err.Error())
+		return
+	}
+
+	utils.LogInfo("Tag deleted successfully: %s", tag.Slug)
+	utils.Success(c, "Tag deleted successfully", nil)
+}
+
+// SetBookTags replaces the tags of a book with the given ones; an empty list removes them all
+func SetBookTags(c *gin.Context) {
+	utils.LogInfo("SetBookTags called")
+
+	var book models.Book
+	if err := config.DB.First(&book, c.Param("id")).Error; err != nil {
+		utils.LogError("Book not found: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
+
+	var req BookTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid input: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", err.Error())
+		return
+	}
+
+	tags := []models.Tag{}
+	if len(req.TagIDs) > 0 {
+		if err := config.DB.Where("id IN ?", req.TagIDs).Order("name ASC").Find(&tags).Error; err != nil {
+			utils.LogError("Failed to fetch tags: %v", err)
+			utils.InternalServerError(c, "Failed to update book tags", err.Error())
+			return
+		}
+		found := make(map[uint]bool, len(tags))
+		for _, tag := range tags {
+			found[tag.ID] = true
+		}
+		var missing []uint
+		for _, id := range req.TagIDs {
+			if !found[id] {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) > 0 {
+			utils.LogError("Unknown tag IDs for book ID: %d: %v", book.ID, missing)
+			utils.Fail(c, utils.CodeTagNotFound, "Some tags do not exist", gin.H{"tag_ids": missing})
+			return
+		}
+	}
+
+	if err := config.DB.Model(&book).Association("Tags").Replace(tags); err != nil {
+		utils.LogError("Failed to update tags for book ID: %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to update book tags", err.Error())
+		return
+	}
+
+	utils.LogInfo("Set %d tags on book ID: %d", len(tags), book.ID)
+	utils.Success(c, "Book tags updated successfully", gin.H{
+		"book_id": book.ID,
+		"tags":    tagChips(tags),
+	})
+}
+
+// applyTagRequest validates the request and copies it onto the tag. It writes the error
+// response and returns false when the request is invalid.
+func applyTagRequest(c *gin.Context, tag *models.Tag, req TagRequest) bool {
+	name := strings.TrimSpace(req.Name)
+	slug := slugify(req.Slug)
+	if slug == "" {
+		slug = slugify(name)
+	}
+	if name == "" || slug == "" {
+		utils.BadRequest(c, "Invalid tag", "Name must contain letters or digits")
+		return false
+	}
+	var count int64
+	config.DB.Model(&models.Tag{}).Where("slug = ? AND id <> ?", slug, tag.ID).Count(&count)
+	if count > 0 {
+		utils.LogError("Tag slug already in use: %s", slug)
+		utils.Fail(c, utils.CodeAlreadyExists, "A tag with this slug already exists", gin.H{"slug": slug})
+		return false
+	}
+
+	tag.Name = name
+	tag.Slug = slug
+	return true
+}
@@ -37,7 +37,7 @@ func SimulatePayment(c *gin.Context) {
 	var orderID uint
 	if _, err := fmt.Sscanf(orderIDStr, "%d", &orderID); err != nil {
 		utils.LogError("Invalid order ID format: %s", orderIDStr)
-		utils.BadRequest(c, "Invalid order ID format", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID format", nil)
 		return
 	}
 
@@ -46,7 +46,7 @@ func SimulatePayment(c *gin.Context) {
 	var order models.Order
 	if err := db.Where("id = ?", orderID).First(&order).Error; err != nil {
 		utils.LogError("Order not found for ID: %d", orderID)
-		utils.NotFound(c, "Order not found")
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 
@@ -54,7 +54,7 @@ func SimulatePayment(c *gin.Context) {
 	utils.LogInfo("Checking order status for ID: %d, current status: %s", orderID, order.Status)
 	if order.Status != "Placed" {
 		utils.LogError("Order ID: %d is not in 'Placed' status. Current status: %s", orderID, order.Status)
-		utils.BadRequest(c, "Payment already completed for this order", nil)
+		utils.Fail(c, utils.CodePaymentCompleted, "Payment already completed for this order", nil)
 		return
 	}
 	utils.LogInfo("Order ID: %d is in 'Placed' status, proceeding with payment simulation", orderID)
@@ -63,7 +63,7 @@ func SimulatePayment(c *gin.Context) {
 	razorpayOrderID := order.RazorpayOrderID
 	if razorpayOrderID == "" {
 		utils.LogError("No Razorpay order ID found for order ID: %d", orderID)
-		utils.BadRequest(c, "Payment not initiated for this order", nil)
+		utils.Fail(c, utils.CodePaymentNotInitiated, "Payment not initiated for this order", nil)
 		return
 	}
 
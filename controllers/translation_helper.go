@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// resolveCatalogLocale determines the locale for a catalog response and
+// advertises it back to the client via the Content-Language header
+func resolveCatalogLocale(c *gin.Context) string {
+	locale := utils.GetRequestLocale(c)
+	c.Header("Content-Language", locale)
+	c.Header("Vary", "Accept-Language")
+	return locale
+}
+
+// loadTranslations returns the translations for the given entities keyed by entity ID.
+// Nothing is loaded for the default locale since the base records already hold it.
+func loadTranslations(entityType string, ids []uint, locale string) map[uint]models.Translation {
+	result := make(map[uint]models.Translation)
+	if locale == utils.DefaultLocale || len(ids) == 0 {
+		return result
+	}
+
+	var translations []models.Translation
+	if err := config.DB.Where("entity_type = ? AND locale = ? AND entity_id IN ?", entityType, locale, ids).
+		Find(&translations).Error; err != nil {
+		utils.LogError("Failed to load %s translations for locale %s: %v", entityType, locale, err)
+		return result
+	}
+
+	for _, t := range translations {
+		result[t.EntityID] = t
+	}
+	return result
+}
+
+// translateText returns the translated name and description for an entity,
+// keeping the default-locale values for any field that has no translation
+func translateText(translations map[uint]models.Translation, id uint, name, description string) (string, string) {
+	t, ok := translations[id]
+	if !ok {
+		return name, description
+	}
+	if t.Name != "" {
+		name = t.Name
+	}
+	if t.Description != "" {
+		description = t.Description
+	}
+	return name, description
+}
+
+// translateOne is a convenience wrapper around loadTranslations/translateText for a single entity
+func translateOne(entityType string, id uint, locale, name, description string) (string, string) {
+	return translateText(loadTranslations(entityType, []uint{id}, locale), id, name, description)
+}
@@ -0,0 +1,406 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// accountDeletionGrace is how long a deleted account can be restored by logging in
+var accountDeletionGrace = 30 * 24 * time.Hour
+
+// accountDeletionConfirmation must be sent back to confirm an account deletion
+const accountDeletionConfirmation = "DELETE"
+
+// finishedOrderStatuses are the order states that no longer block an account deletion
+var finishedOrderStatuses = []string{
+	models.OrderStatusDelivered,
+	models.OrderStatusCancelled,
+	models.OrderStatusRefunded,
+	models.OrderStatusReturnRejected,
+	models.OrderStatusReturnCompleted,
+}
+
+// DeleteAccountRequest confirms a user's account deletion
+type DeleteAccountRequest struct {
+	Password string `json:"password"` // required for accounts that sign in with a password
+	Confirm  string `json:"confirm" binding:"required"`
+	Reason   string `json:"reason"`
+}
+
+// RequestAccountDeletion disables the user's account immediately and schedules its personal
+// data for anonymization once the grace period ends. Logging in again before then restores it.
+func RequestAccountDeletion(c *gin.Context) {
+	utils.LogInfo("RequestAccountDeletion called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid account deletion request for user ID %d: %v", user.ID, err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	if strings.TrimSpace(req.Confirm) != accountDeletionConfirmation {
+		utils.BadRequest(c, fmt.Sprintf("Type %s in confirm to delete your account", accountDeletionConfirmation), nil)
+		return
+	}
+
+	// Accounts created through Google have a generated password until the user sets one
+	if !user.PasswordUnset {
+		if req.Password == "" {
+			utils.BadRequest(c, "Password is required to delete your account", nil)
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			utils.LogError("Account deletion rejected - Invalid password for user ID: %d", user.ID)
+			utils.Fail(c, utils.CodeInvalidCredentials, "Invalid password", nil)
+			return
+		}
+	}
+
+	var activeOrders int64
+	if err := config.DB.Model(&models.Order{}).
+		Where("user_id = ? AND status NOT IN ?", user.ID, finishedOrderStatuses).
+		Count(&activeOrders).Error; err != nil {
+		utils.LogError("Failed to count active orders for user ID %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to check orders", err.Error())
+		return
+	}
+	if activeOrders > 0 {
+		utils.LogError("Account deletion rejected - %d active orders for user ID: %d", activeOrders, user.ID)
+		utils.Conflict(c, "Your account has orders in progress, wait until they are completed or cancel them first", gin.H{
+			"active_orders": activeOrders,
+		})
+		return
+	}
+
+	now := time.Now()
+	scheduledAt := now.Add(accountDeletionGrace)
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{
+		"deletion_requested_at": now,
+		"deletion_scheduled_at": scheduledAt,
+	}).Error; err != nil {
+		utils.LogError("Failed to schedule deletion for user ID %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to delete account", err.Error())
+		return
+	}
+
+	// Sign the user out of the current session
+	if token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); token != "" {
+		blacklistedToken := models.BlacklistedToken{
+			Token:     token,
+			ExpiresAt: now.Add(24 * time.Hour), // Same as JWT expiration
+		}
+		if err := config.DB.Create(&blacklistedToken).Error; err != nil {
+			utils.LogError("Failed to blacklist token: %v", err)
+		}
+	}
+
+	var walletBalance float64
+	config.DB.Model(&models.Wallet{}).Where("user_id = ?", user.ID).Select("balance").Scan(&walletBalance)
+
+	utils.LogInfo("Account deletion scheduled for user ID %d at %s, reason: %s", user.ID, scheduledAt.Format("2006-01-02 15:04:05"), req.Reason)
+	formatter := utils.NewResponseFormatter(c)
+	response := gin.H{
+		"deletion_scheduled_at": formatter.Timestamp(scheduledAt),
+		"restore":               "Log in again before the scheduled date to keep your account",
+	}
+	if walletBalance > 0 {
+		response["wallet_balance"] = formatter.Money(walletBalance)
+		response["warning"] = "Your remaining wallet balance will be forfeited when the account is deleted"
+	}
+	utils.Success(c, "Account scheduled for deletion", response)
+}
+
+// anonymizeDeletedAccountsJob anonymizes accounts whose deletion grace period has ended.
+// Orders, invoices and consent records are kept for accounting and legal purposes but no
+// longer point to anyone identifiable.
+func anonymizeDeletedAccountsJob() (string, error) {
+	var users []models.User
+	if err := config.DB.Where("deletion_scheduled_at <= ? AND anonymized_at IS NULL", time.Now()).
+		Find(&users).Error; err != nil {
+		return "", err
+	}
+
+	anonymized := 0
+	for _, user := range users {
+		if err := config.DB.Transaction(func(tx *gorm.DB) error {
+			return anonymizeUser(tx, user.ID)
+		}); err != nil {
+			utils.LogError("Failed to anonymize user ID %d: %v", user.ID, err)
+			continue
+		}
+		anonymized++
+	}
+	return fmt.Sprintf("%d of %d deleted accounts anonymized", anonymized, len(users)), nil
+}
+
+// anonymizeUser strips the personal data from a user and removes their shopping state
+func anonymizeUser(tx *gorm.DB, userID uint) error {
+	placeholder := fmt.Sprintf("deleted-%d", userID)
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"username":          placeholder,
+		"email":             placeholder + "@deleted.invalid",
+		"password":          "",
+		"first_name":        "Deleted",
+		"last_name":         "User",
+		"phone":             "",
+		"phone_verified_at": nil,
+		"profile_image":     "",
+		"google_id":         gorm.Expr("NULL"),
+		"otp":               "",
+		"is_blocked":        true,
+		"anonymized_at":     time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+
+	// Street lines identify the person; city, state and postal code stay for tax records
+	if err := tx.Model(&models.Address{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"line1": "",
+		"line2": "",
+	}).Error; err != nil {
+		return err
+	}
+
+	for _, model := range []interface{}{&models.Cart{}, &models.CartBundle{}, &models.SavedItem{}, &models.Wishlist{}, &models.UserActiveCoupon{}, &models.StockNotification{}, &models.NotificationPreference{}, &models.Notification{}, &models.BookView{}, &models.UserSession{}} {
+		if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportUserData returns everything stored about the user as a downloadable JSON file,
+// or as a ZIP archive with one JSON file per section when format=zip
+func ExportUserData(c *gin.Context) {
+	utils.LogInfo("ExportUserData called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	format := strings.ToLower(c.DefaultQuery("format", "json"))
+	if format != "json" && format != "zip" {
+		utils.BadRequest(c, "Invalid format, use json or zip", nil)
+		return
+	}
+
+	formatter := utils.NewResponseFormatter(c)
+	sections, err := collectUserData(formatter, user)
+	if err != nil {
+		utils.LogError("Failed to collect data export for user ID %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to export data", err.Error())
+		return
+	}
+
+	baseName := fmt.Sprintf("readsphere-data-%d-%s", user.ID, time.Now().Format("20060102"))
+	if format == "json" {
+		data, err := json.MarshalIndent(sections, "", "  ")
+		if err != nil {
+			utils.InternalServerError(c, "Failed to export data", err.Error())
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", baseName))
+		c.Data(http.StatusOK, "application/json", data)
+		utils.LogInfo("Data export (json) generated for user ID %d", user.ID)
+		return
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, name := range userDataSections {
+		if err := writeZipJSON(archive, name+".json", sections[name]); err != nil {
+			utils.LogError("Failed to write %s to data export for user ID %d: %v", name, user.ID, err)
+			utils.InternalServerError(c, "Failed to export data", err.Error())
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		utils.InternalServerError(c, "Failed to export data", err.Error())
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.zip", baseName))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+	utils.LogInfo("Data export (zip) generated for user ID %d", user.ID)
+}
+
+// writeZipJSON adds value to the archive as an indented JSON file
+func writeZipJSON(archive *zip.Writer, name string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// userDataSections lists the data export sections in the order they are written
+var userDataSections = []string{"profile", "addresses", "orders", "reviews", "wallet", "consent", "referrals", "wishlist"}
+
+// collectUserData gathers the user's data for an export, keyed by section
+func collectUserData(f utils.ResponseFormatter, user models.User) (map[string]interface{}, error) {
+	sections := map[string]interface{}{
+		"exported_at": f.Timestamp(time.Now()),
+		"profile": gin.H{
+			"id":            user.ID,
+			"username":      user.Username,
+			"email":         user.Email,
+			"first_name":    user.FirstName,
+			"last_name":     user.LastName,
+			"phone":         user.Phone,
+			"profile_image": user.ProfileImage,
+			"is_verified":   user.IsVerified,
+			"google_linked": user.GoogleID != "",
+			"created_at":    f.Timestamp(user.CreatedAt),
+			"last_login_at": f.Timestamp(user.LastLoginAt),
+		},
+	}
+
+	var addresses []models.Address
+	if err := config.DB.Where("user_id = ?", user.ID).Order("id").Find(&addresses).Error; err != nil {
+		return nil, err
+	}
+	sections["addresses"] = addresses
+
+	var orders []models.Order
+	if err := config.DB.Preload("Address").Preload("OrderItems.Book").
+		Where("user_id = ?", user.ID).Order("created_at").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	orderData := make([]gin.H, 0, len(orders))
+	for _, order := range orders {
+		items := make([]gin.H, 0, len(order.OrderItems))
+		for _, item := range order.OrderItems {
+			items = append(items, gin.H{
+				"book_id":   item.BookID,
+				"book_name": item.Book.Name,
+				"quantity":  item.Quantity,
+				"price":     f.Money(item.Price),
+				"discount":  f.Money(item.Discount),
+				"total":     f.Money(item.Total),
+				"status":    orderItemStatus(item),
+			})
+		}
+		orderData = append(orderData, gin.H{
+			"id":             order.ID,
+			"status":         order.Status,
+			"payment_method": order.PaymentMethod,
+			"total":          f.Money(order.TotalWithDelivery),
+			"coupon_code":    order.CouponCode,
+			"address": gin.H{
+				"line1":       order.Address.Line1,
+				"line2":       order.Address.Line2,
+				"city":        order.Address.City,
+				"state":       order.Address.State,
+				"country":     order.Address.Country,
+				"postal_code": order.Address.PostalCode,
+			},
+			"items":      items,
+			"created_at": f.Timestamp(order.CreatedAt),
+		})
+	}
+	sections["orders"] = orderData
+
+	var reviews []models.Review
+	if err := config.DB.Preload("Images").Where("user_id = ?", user.ID).Order("created_at").Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+	reviewData := make([]gin.H, 0, len(reviews))
+	for _, review := range reviews {
+		imageURLs := make([]string, len(review.Images))
+		for i, image := range review.Images {
+			imageURLs[i] = image.URL
+		}
+		reviewData = append(reviewData, gin.H{
+			"book_id":           review.BookID,
+			"rating":            review.Rating,
+			"comment":           review.Comment,
+			"verified_purchase": review.VerifiedPurchase,
+			"image_urls":        imageURLs,
+			"is_approved":       review.IsApproved,
+			"created_at":        f.Timestamp(review.CreatedAt),
+		})
+	}
+	sections["reviews"] = reviewData
+
+	wallet := gin.H{"balance": f.Money(0), "transactions": []models.WalletTransaction{}}
+	var userWallet models.Wallet
+	if err := config.DB.Where("user_id = ?", user.ID).First(&userWallet).Error; err == nil {
+		var transactions []models.WalletTransaction
+		if err := config.DB.Where("wallet_id = ?", userWallet.ID).Order("created_at").Find(&transactions).Error; err != nil {
+			return nil, err
+		}
+		wallet = gin.H{"balance": f.Money(userWallet.Balance), "transactions": transactions}
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	sections["wallet"] = wallet
+
+	var consent []models.ConsentRecord
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&consent).Error; err != nil {
+		return nil, err
+	}
+	sections["consent"] = consent
+
+	var referralCode models.UserReferralCode
+	referrals := gin.H{}
+	if err := config.DB.Where("user_id = ?", user.ID).First(&referralCode).Error; err == nil {
+		referrals["code"] = referralCode.ReferralCode
+	}
+	var referredCount int64
+	config.DB.Model(&models.ReferralUsage{}).Where("referrer_id = ?", user.ID).Count(&referredCount)
+	referrals["users_referred"] = referredCount
+	var rewards []models.ReferralReward
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&rewards).Error; err != nil {
+		return nil, err
+	}
+	referrals["rewards"] = rewards
+	sections["referrals"] = referrals
+
+	var wishlist []models.Wishlist
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&wishlist).Error; err != nil {
+		return nil, err
+	}
+	sections["wishlist"] = wishlist
+
+	return sections, nil
+}
+
+// orderItemStatus summarizes an order item's cancellation or return state
+func orderItemStatus(item models.OrderItem) string {
+	switch {
+	case item.ReturnStatus != "":
+		return "return " + item.ReturnStatus
+	case item.CancellationStatus != "":
+		return "cancellation " + item.CancellationStatus
+	default:
+		return ""
+	}
+}
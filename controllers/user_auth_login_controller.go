@@ -5,12 +5,9 @@ import (
 	"strings"
 	"time"
 
-	"log"
-
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
-	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
@@ -20,6 +17,11 @@ import (
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	// RememberMe keeps this device signed in for longer; DeviceName labels it in the session list
+	RememberMe bool   `json:"remember_me"`
+	DeviceName string `json:"device_name"`
+	// CaptchaToken is the solved CAPTCHA, required after repeated failed logins for the email
+	CaptchaToken string `json:"captcha_token"`
 }
 
 // LoginUser handles user login
@@ -27,7 +29,7 @@ func LoginUser(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Login attempt failed - Invalid request format: %v", err)
-		utils.BadRequest(c, "Invalid email or password", err.Error())
+		utils.BadRequest(c, "Invalid email or password", err)
 		return
 	}
 
@@ -44,63 +46,106 @@ func LoginUser(c *gin.Context) {
 	// Check for SQL injection
 	if valid, msg := utils.ValidateSQLInjection(req.Email); !valid {
 		utils.LogError("Login attempt failed - SQL injection attempt detected: %s", req.Email)
-		utils.BadRequest(c, "Invalid input", msg)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", msg)
 		return
 	}
 
 	// Check for XSS
 	if valid, msg := utils.ValidateXSS(req.Email); !valid {
 		utils.LogError("Login attempt failed - XSS attempt detected: %s", req.Email)
-		utils.BadRequest(c, "Invalid input", msg)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", msg)
+		return
+	}
+
+	// After repeated failures for the email the login needs a CAPTCHA
+	if utils.LoginCaptchaRequired(req.Email) && !utils.CheckCaptcha(c, req.CaptchaToken) {
 		return
 	}
 
 	var user models.User
 	if err := config.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
 		utils.LogError("Login attempt failed - User not found: %s", req.Email)
-		utils.Unauthorized(c, "Invalid credentials")
+		failLogin(c, req.Email)
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 		utils.LogError("Login attempt failed - Invalid password for user: %s", req.Email)
-		utils.Unauthorized(c, "Invalid credentials")
+		failLogin(c, req.Email)
 		return
 	}
+	utils.ClearLoginFailures(req.Email)
 
 	if user.IsBlocked {
 		utils.LogError("Login attempt failed - Blocked account: %s", req.Email)
-		utils.Forbidden(c, "Account is blocked")
+		utils.Fail(c, utils.CodeAccountBlocked, "Account is blocked", nil)
 		return
 	}
 
+	completeUserLogin(c, user, req.Email, req.RememberMe, req.DeviceName)
+}
+
+// GetCaptchaConfig tells clients whether CAPTCHA is on, and which provider and site key to show
+// the widget with
+func GetCaptchaConfig(c *gin.Context) {
+	utils.Success(c, "CAPTCHA settings retrieved successfully", utils.CaptchaSettings())
+}
+
+// failLogin answers a login with wrong credentials, counting the failure for the email. The
+// response says when the next attempt needs a CAPTCHA; unknown emails count the same way.
+func failLogin(c *gin.Context, email string) {
+	var details interface{}
+	if utils.RecordLoginFailure(email) {
+		details = gin.H{"captcha_required": true}
+	}
+	utils.Fail(c, utils.CodeInvalidCredentials, "Invalid credentials", details)
+}
+
+// completeUserLogin signs the user in once their credentials are checked: it restores an
+// account pending deletion, records the login, starts a session for the device and responds
+// with its tokens. identifier names the user in the logs.
+func completeUserLogin(c *gin.Context, user models.User, identifier string, rememberMe bool, deviceName string) {
+	// Logging in during the deletion grace period restores the account
+	accountRestored := false
+	if user.DeletionRequestedAt != nil {
+		user.DeletionRequestedAt = nil
+		user.DeletionScheduledAt = nil
+		accountRestored = true
+		utils.LogInfo("Account deletion cancelled by login for user: %s", identifier)
+	}
+
 	// Update last login
 	user.LastLoginAt = time.Now()
 	if err := config.DB.Save(&user).Error; err != nil {
-		utils.LogError("Failed to update last login time for user: %s", req.Email)
+		utils.LogError("Failed to update last login time for user: %s", identifier)
 	}
 
-	// Generate JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.Model.ID,
-		"email":   user.Email,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
+	session, refreshToken, err := utils.StartSession(config.DB, c, user.ID, rememberMe, deviceName)
+	if err != nil {
+		utils.LogError("Failed to start session for user: %s: %v", identifier, err)
+		utils.InternalServerError(c, "Failed to generate token", nil)
+		return
+	}
 
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	// Generate JWT token
+	tokenString, err := utils.SessionToken(&user, session)
 	if err != nil {
-		utils.LogError("Failed to generate JWT token for user: %s", req.Email)
+		utils.LogError("Failed to generate JWT token for user: %s", identifier)
 		utils.InternalServerError(c, "Failed to generate token", err.Error())
 		return
 	}
 
-	utils.LogInfo("User logged in successfully: %s", req.Email)
+	utils.LogInfo("User logged in successfully: %s (session %d)", identifier, session.ID)
 	utils.Success(c, "Login successful", gin.H{
 		"token": tokenString, "user": gin.H{
 			"id":       user.ID,
 			"username": user.Username,
 			"email":    user.Email,
 		},
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": utils.NewResponseFormatter(c).Timestamp(session.ExpiresAt),
+		"session_id":         session.ID,
+		"account_restored":   accountRestored,
 	})
 }
 
@@ -116,7 +161,7 @@ func VerifyOTP(c *gin.Context) {
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("OTP verification failed - Invalid request format: %v", err)
-		utils.BadRequest(c, "Invalid request format", "Please provide OTP")
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", "Please provide OTP")
 		return
 	}
 
@@ -139,67 +184,23 @@ func VerifyOTP(c *gin.Context) {
 	})
 	if err != nil || !token.Valid {
 		utils.LogError("OTP verification failed - Invalid or expired registration token: %v", err)
-		utils.Unauthorized(c, "Invalid or expired registration token")
+		utils.Fail(c, utils.CodeTokenInvalid, "Invalid or expired registration token", nil)
 		return
 	}
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
 		utils.LogError("OTP verification failed - Invalid token claims")
-		utils.Unauthorized(c, "Invalid token claims")
+		utils.Fail(c, utils.CodeTokenInvalid, "Invalid token claims", nil)
 		return
 	}
 
 	email := claims["email"].(string)
 	utils.LogInfo("OTP verification attempt for email: %s", email)
 
-	// Use session to get OTP and expiry
-	session := sessions.Default(c)
-	sessEmail := session.Get("registration_email")
-	if sessEmail == nil || sessEmail.(string) != email {
-		utils.LogError("OTP verification failed - Session expired or email mismatch for: %s", email)
-		utils.BadRequest(c, "Session expired", "Session expired or email mismatch. Please register again.")
-		return
-	}
-	storedOTP := session.Get("registration_otp")
-	otpExpires := session.Get("registration_otp_expires")
-	if storedOTP == nil || otpExpires == nil {
-		utils.LogError("OTP verification failed - OTP session expired for: %s", email)
-		utils.BadRequest(c, "OTP session expired", "OTP session expired. Please register again.")
-		return
-	}
-	if time.Now().Unix() > otpExpires.(int64) {
-		// Check if registration token is still valid
-		regExpires := int64(claims["exp"].(float64))
-		if time.Now().Unix() > regExpires {
-			utils.LogError("OTP verification failed - Registration expired for: %s", email)
-			utils.BadRequest(c, "Registration expired", "Registration expired. Please register again.")
-			return
-		}
-		// Resend new OTP
-		newOTP := generateOTP()
-		log.Printf("Registration OTP for %s: %s", email, newOTP)
-		utils.LogInfo("Resending OTP to: %s", email)
-		session.Set("registration_otp", newOTP)
-		session.Set("registration_otp_expires", time.Now().Add(1*time.Minute).Unix())
-		if err := session.Save(); err != nil {
-			utils.LogError("Failed to save session for OTP resend: %v", err)
-			utils.InternalServerError(c, "Failed to save session", err.Error())
-			return
-		}
-		if err := utils.SendOTP(email, newOTP); err != nil {
-			utils.LogError("Failed to resend OTP to: %s", email)
-			utils.InternalServerError(c, "Failed to resend OTP", "Failed to send verification email")
-			return
-		}
-		utils.BadRequest(c, "OTP expired", gin.H{
-			"message":    "OTP expired. A new OTP has been sent to your email.",
-			"expires_in": regExpires - time.Now().Unix(),
-		})
-		return
-	}
-	if storedOTP.(string) != req.OTP {
-		utils.LogError("OTP verification failed - Invalid OTP for: %s", email)
-		utils.BadRequest(c, "Invalid OTP", "The OTP you entered is incorrect")
+	// Expired OTPs are replaced through /v1/auth/resend-otp
+	if err := utils.CheckOTP(utils.OTPPurposeRegistration, email, nil, req.OTP); err != nil {
+		utils.LogError("OTP verification failed for %s: %v", email, err)
+		utils.FailOTP(c, err)
 		return
 	}
 
@@ -207,7 +208,7 @@ func VerifyOTP(c *gin.Context) {
 	var user models.User
 	if err := config.DB.Where("email = ?", email).First(&user).Error; err == nil {
 		utils.LogError("OTP verification failed - User already exists: %s", email)
-		utils.Conflict(c, "User already exists", "An account with this email already exists. Please login.")
+		utils.Fail(c, utils.CodeAlreadyExists, "User already exists", "An account with this email already exists. Please login.")
 		return
 	}
 
@@ -239,7 +240,7 @@ func VerifyOTP(c *gin.Context) {
 		err := UseReferralCode(referralCode, user.ID)
 		if err != nil {
 			utils.LogError("Failed to process referral code for user: %s", email)
-			utils.BadRequest(c, "Invalid referral code", "Invalid or expired referral code")
+			utils.Fail(c, utils.CodeReferralInvalid, "Invalid referral code", "Invalid or expired referral code")
 			return
 		}
 	}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ResendOTPRequest represents the resend OTP request body
+type ResendOTPRequest struct {
+	Purpose string `json:"purpose" binding:"required"` // registration, reset or email_change
+	Email   string `json:"email" binding:"required,email"`
+}
+
+// ResendOTP emails a new OTP for a registration, password reset or email change in progress.
+// Phone OTPs are requested again from their send-otp endpoints, which apply the same limits.
+func ResendOTP(c *gin.Context) {
+	utils.LogInfo("ResendOTP called")
+
+	var req ResendOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("OTP resend failed - Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", "Please provide the purpose of the OTP and the email address")
+		return
+	}
+	if !utils.IsOTPPurpose(req.Purpose) || req.Purpose == utils.OTPPurposePhoneLogin || req.Purpose == utils.OTPPurposePhoneVerify {
+		utils.LogError("OTP resend failed - Unknown purpose: %s", req.Purpose)
+		utils.BadRequest(c, "Invalid purpose", "Purpose must be one of registration, reset or email_change; request phone OTPs again with send-otp")
+		return
+	}
+
+	otp, err := utils.ResendOTP(req.Purpose, req.Email)
+	if err != nil {
+		failEmailOTPSend(c, req.Email, otp, err)
+		return
+	}
+
+	utils.LogInfo("%s OTP resent to: %s", req.Purpose, req.Email)
+	utils.Success(c, "A new OTP has been sent to your email", gin.H{
+		"email":        req.Email,
+		"expires_in":   int(utils.OTPLifetime(req.Purpose).Seconds()),
+		"resends_left": utils.OTPMaxSends - otp.Sends,
+		"resend_after": int(utils.OTPResendCooldown.Seconds()),
+	})
+}
+
+// failEmailOTPSend sends the error response for an OTP that could not be emailed
+func failEmailOTPSend(c *gin.Context, email string, otp *models.EmailOTP, err error) {
+	if errors.Is(err, utils.ErrOTPResendCooldown) || errors.Is(err, utils.ErrOTPResendLimit) {
+		retryAfter := int(utils.OTPRetryAfter(otp).Seconds())
+		utils.LogError("OTP not sent to %s - %v, retry in %ds", email, err, retryAfter)
+		if errors.Is(err, utils.ErrOTPResendLimit) {
+			utils.Fail(c, utils.CodeOTPResendLimit, "OTP limit reached for this email address", gin.H{
+				"retry_after": retryAfter,
+			})
+			return
+		}
+		utils.Fail(c, utils.CodeOTPResendCooldown, "Please wait before requesting another OTP", gin.H{
+			"retry_after": retryAfter,
+		})
+		return
+	}
+	if errors.Is(err, utils.ErrOTPNotStarted) {
+		utils.LogError("OTP not sent to %s - no verification in progress", email)
+		utils.FailOTP(c, err)
+		return
+	}
+	utils.LogError("Failed to send OTP to %s: %v", email, err)
+	utils.InternalServerError(c, "Failed to send verification email", "An error occurred while sending the verification email. Please try again later.")
+}
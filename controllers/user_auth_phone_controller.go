@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// PhoneOTPRequest starts a phone OTP flow
+type PhoneOTPRequest struct {
+	Phone string `json:"phone" binding:"required"`
+}
+
+// VerifyPhoneOTPRequest completes a phone OTP flow
+type VerifyPhoneOTPRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	OTP   string `json:"otp" binding:"required"`
+}
+
+// PhoneLoginRequest signs in with the OTP texted to the phone
+type PhoneLoginRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	OTP   string `json:"otp" binding:"required"`
+	// RememberMe keeps this device signed in for longer; DeviceName labels it in the session list
+	RememberMe bool   `json:"remember_me"`
+	DeviceName string `json:"device_name"`
+}
+
+// SendPhoneLoginOTP texts a sign-in OTP to a verified phone number. The response is the same
+// whether or not an account has the number, so it cannot be used to find out who is
+// registered; no text is sent when none does, but the request counts towards the same limits.
+func SendPhoneLoginOTP(c *gin.Context) {
+	utils.LogInfo("SendPhoneLoginOTP called")
+
+	var req PhoneOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Phone login failed - Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", "Please provide your phone number")
+		return
+	}
+	phone, ok := formatOTPPhone(c, req.Phone)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	registered := config.DB.Select("id").Where("phone = ? AND phone_verified_at IS NOT NULL", phone).First(&user).Error == nil
+
+	otp, err := utils.StartPhoneOTP(c, utils.OTPPurposePhoneLogin, phone, nil, registered)
+	if err != nil {
+		failPhoneOTPSend(c, phone, otp, err)
+		return
+	}
+
+	if registered {
+		utils.LogInfo("Phone login OTP sent to %s for user ID: %d", phone, user.ID)
+	} else {
+		utils.LogInfo("Phone login requested for unregistered number %s, no OTP sent", phone)
+	}
+	respondPhoneOTPSent(c, utils.OTPPurposePhoneLogin, phone, otp.Sends)
+}
+
+// VerifyPhoneLoginOTP signs the user in with the OTP texted to their phone
+func VerifyPhoneLoginOTP(c *gin.Context) {
+	utils.LogInfo("VerifyPhoneLoginOTP called")
+
+	var req PhoneLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Phone login failed - Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", "Please provide your phone number and the OTP")
+		return
+	}
+	phone, ok := formatOTPPhone(c, req.Phone)
+	if !ok || !checkPhoneOTP(c, utils.OTPPurposePhoneLogin, phone, nil, req.OTP) {
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Where("phone = ? AND phone_verified_at IS NOT NULL", phone).First(&user).Error; err != nil {
+		utils.LogError("Phone login failed - No account for %s", phone)
+		utils.Fail(c, utils.CodeInvalidCredentials, "Invalid credentials", nil)
+		return
+	}
+	if user.IsBlocked {
+		utils.LogError("Phone login failed - Blocked account: %s", phone)
+		utils.Fail(c, utils.CodeAccountBlocked, "Account is blocked", nil)
+		return
+	}
+
+	completeUserLogin(c, user, phone, req.RememberMe, req.DeviceName)
+}
+
+// SendPhoneVerificationOTP texts an OTP to a phone number the signed-in user wants to link to
+// their account, their profile number when none is given
+func SendPhoneVerificationOTP(c *gin.Context) {
+	utils.LogInfo("SendPhoneVerificationOTP called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var req struct {
+		Phone string `json:"phone"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Phone verification failed - Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+	if req.Phone == "" {
+		req.Phone = user.Phone
+	}
+	if req.Phone == "" {
+		utils.BadRequest(c, "Phone number required", "Add a phone number to verify")
+		return
+	}
+	phone, ok := formatOTPPhone(c, req.Phone)
+	if !ok {
+		return
+	}
+	if phone == user.Phone && user.PhoneVerifiedAt != nil {
+		utils.BadRequest(c, "Phone number already verified", gin.H{"phone": phone})
+		return
+	}
+	if phoneTaken(phone, user.ID) {
+		utils.LogError("Phone verification failed - %s belongs to another account", phone)
+		utils.Fail(c, utils.CodeAlreadyExists, "Phone number already exists", nil)
+		return
+	}
+
+	otp, err := utils.StartPhoneOTP(c, utils.OTPPurposePhoneVerify, phone, &user.ID, true)
+	if err != nil {
+		failPhoneOTPSend(c, phone, otp, err)
+		return
+	}
+
+	utils.LogInfo("Phone verification OTP sent to %s for user ID: %d", phone, user.ID)
+	respondPhoneOTPSent(c, utils.OTPPurposePhoneVerify, phone, otp.Sends)
+}
+
+// VerifyPhone links the phone number the OTP was texted to with the signed-in user's account,
+// replacing their profile number, so it can be used to sign in
+func VerifyPhone(c *gin.Context) {
+	utils.LogInfo("VerifyPhone called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var req VerifyPhoneOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Phone verification failed - Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", "Please provide the phone number and the OTP")
+		return
+	}
+	phone, ok := formatOTPPhone(c, req.Phone)
+	if !ok || !checkPhoneOTP(c, utils.OTPPurposePhoneVerify, phone, &user.ID, req.OTP) {
+		return
+	}
+	// The number may have been taken while the OTP was on its way
+	if phoneTaken(phone, user.ID) {
+		utils.LogError("Phone verification failed - %s belongs to another account", phone)
+		utils.Fail(c, utils.CodeAlreadyExists, "Phone number already exists", nil)
+		return
+	}
+
+	now := time.Now()
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{
+		"phone":             phone,
+		"phone_verified_at": now,
+	}).Error; err != nil {
+		utils.LogError("Failed to verify phone for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to verify phone number", nil)
+		return
+	}
+
+	utils.LogInfo("Verified phone %s for user ID: %d", phone, user.ID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Phone number verified successfully", gin.H{
+		"phone":             phone,
+		"phone_verified_at": formatter.Timestamp(now),
+	})
+}
+
+// formatOTPPhone validates and normalizes a phone number. It writes the error response and
+// returns false when the number is invalid.
+func formatOTPPhone(c *gin.Context, phone string) (string, bool) {
+	valid, formatted := utils.ValidatePhone(phone)
+	if !valid {
+		utils.LogError("Invalid phone number: %s", formatted)
+		utils.BadRequest(c, "Invalid phone number", formatted)
+		return "", false
+	}
+	return formatted, true
+}
+
+// phoneTaken reports whether another account than userID has the phone number
+func phoneTaken(phone string, userID uint) bool {
+	var count int64
+	config.DB.Model(&models.User{}).Where("phone = ? AND id <> ?", phone, userID).Count(&count)
+	return count > 0
+}
+
+// checkPhoneOTP checks code against the OTP texted to the phone number for the flow and uses
+// it up. It writes the error response and returns false when there is no OTP or the code is
+// wrong.
+func checkPhoneOTP(c *gin.Context, purpose, phone string, userID *uint, code string) bool {
+	err := utils.CheckPhoneOTP(purpose, phone, userID, code)
+	if err == nil {
+		return true
+	}
+	utils.LogError("%s OTP check failed for %s: %v", purpose, phone, err)
+	switch {
+	case errors.Is(err, utils.ErrOTPExpired):
+		utils.Fail(c, utils.CodeOTPExpired, "OTP expired", "The OTP has expired. Request a new one with send-otp")
+	case errors.Is(err, utils.ErrOTPAttempts):
+		utils.Fail(c, utils.CodeOTPAttempts, "Too many incorrect attempts", "Request a new OTP with send-otp")
+	default:
+		utils.FailOTP(c, err)
+	}
+	return false
+}
+
+// failPhoneOTPSend sends the error response for an OTP that could not be texted
+func failPhoneOTPSend(c *gin.Context, phone string, otp *models.PhoneOTP, err error) {
+	if errors.Is(err, utils.ErrOTPResendCooldown) || errors.Is(err, utils.ErrOTPResendLimit) {
+		retryAfter := int(utils.PhoneOTPRetryAfter(otp).Seconds())
+		utils.LogError("Phone OTP not sent to %s - %v, retry in %ds", phone, err, retryAfter)
+		if errors.Is(err, utils.ErrOTPResendLimit) {
+			utils.Fail(c, utils.CodeOTPResendLimit, "OTP limit reached for this phone number", gin.H{
+				"retry_after": retryAfter,
+			})
+			return
+		}
+		utils.Fail(c, utils.CodeOTPResendCooldown, "Please wait before requesting another OTP", gin.H{
+			"retry_after": retryAfter,
+		})
+		return
+	}
+	utils.LogError("Failed to send phone OTP to %s: %v", phone, err)
+	utils.FailOTP(c, err)
+}
+
+// respondPhoneOTPSent confirms that an OTP was texted, with how long it stays valid and how
+// many more can be sent
+func respondPhoneOTPSent(c *gin.Context, purpose, phone string, sends int) {
+	utils.Success(c, "An OTP has been sent to your phone", gin.H{
+		"phone":        phone,
+		"expires_in":   int(utils.OTPLifetime(purpose).Seconds()),
+		"resends_left": utils.OTPMaxSends - sends,
+		"resend_after": int(utils.OTPResendCooldown.Seconds()),
+	})
+}
@@ -1,14 +1,12 @@
 package controllers
 
 import (
-	"log"
 	"os"
 	"time"
 
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
-	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
@@ -24,6 +22,8 @@ type RegisterRequest struct {
 	LastName        string `json:"last_name"`
 	Phone           string `json:"phone"`
 	ReferralCode    string `json:"referral_code"`
+	// CaptchaToken is the solved CAPTCHA, required when CAPTCHA_PROVIDER is set
+	CaptchaToken string `json:"captcha_token"`
 }
 
 // RegistrationData represents the registration data stored in session
@@ -43,7 +43,7 @@ func RegisterUser(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Registration attempt failed - Invalid request format: %v", err)
-		utils.BadRequest(c, "Invalid request format", "Please check your input data and ensure all required fields are provided correctly.")
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", "Please check your input data and ensure all required fields are provided correctly.")
 		return
 	}
 
@@ -109,54 +109,59 @@ func RegisterUser(c *gin.Context) {
 	// Check for SQL injection in all fields
 	if valid, msg := utils.ValidateSQLInjection(req.Username); !valid {
 		utils.LogError("Registration attempt failed - SQL injection attempt detected in username: %s", req.Username)
-		utils.BadRequest(c, "Invalid input", msg)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", msg)
 		return
 	}
 	if valid, msg := utils.ValidateSQLInjection(req.Email); !valid {
 		utils.LogError("Registration attempt failed - SQL injection attempt detected in email: %s", req.Email)
-		utils.BadRequest(c, "Invalid input", msg)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", msg)
 		return
 	}
 	if valid, msg := utils.ValidateSQLInjection(req.FirstName); !valid {
 		utils.LogError("Registration attempt failed - SQL injection attempt detected in first name: %s", req.FirstName)
-		utils.BadRequest(c, "Invalid input", msg)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", msg)
 		return
 	}
 	if valid, msg := utils.ValidateSQLInjection(req.LastName); !valid {
 		utils.LogError("Registration attempt failed - SQL injection attempt detected in last name: %s", req.LastName)
-		utils.BadRequest(c, "Invalid input", msg)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", msg)
 		return
 	}
 	if valid, msg := utils.ValidateSQLInjection(req.Phone); !valid {
 		utils.LogError("Registration attempt failed - SQL injection attempt detected in phone: %s", req.Phone)
-		utils.BadRequest(c, "Invalid input", msg)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", msg)
 		return
 	}
 
 	// Check for XSS in all fields
 	if valid, msg := utils.ValidateXSS(req.Username); !valid {
 		utils.LogError("Registration attempt failed - XSS attempt detected in username: %s", req.Username)
-		utils.BadRequest(c, "Invalid input", msg)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", msg)
 		return
 	}
 	if valid, msg := utils.ValidateXSS(req.Email); !valid {
 		utils.LogError("Registration attempt failed - XSS attempt detected in email: %s", req.Email)
-		utils.BadRequest(c, "Invalid input", msg)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", msg)
 		return
 	}
 	if valid, msg := utils.ValidateXSS(req.FirstName); !valid {
 		utils.LogError("Registration attempt failed - XSS attempt detected in first name: %s", req.FirstName)
-		utils.BadRequest(c, "Invalid input", msg)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", msg)
 		return
 	}
 	if valid, msg := utils.ValidateXSS(req.LastName); !valid {
 		utils.LogError("Registration attempt failed - XSS attempt detected in last name: %s", req.LastName)
-		utils.BadRequest(c, "Invalid input", msg)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", msg)
 		return
 	}
 	if valid, msg := utils.ValidateXSS(req.Phone); !valid {
 		utils.LogError("Registration attempt failed - XSS attempt detected in phone: %s", req.Phone)
-		utils.BadRequest(c, "Invalid input", msg)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid input", msg)
+		return
+	}
+
+	// Bots are stopped before the account lookups and the OTP email
+	if !utils.CheckCaptcha(c, req.CaptchaToken) {
 		return
 	}
 
@@ -164,14 +169,14 @@ func RegisterUser(c *gin.Context) {
 	var existingUser models.User
 	if err := config.DB.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
 		utils.LogError("Registration attempt failed - Username already exists: %s", req.Username)
-		utils.Conflict(c, "Username already exists", "The username you've chosen is already taken. Please choose a different username.")
+		utils.Fail(c, utils.CodeAlreadyExists, "Username already exists", "The username you've chosen is already taken. Please choose a different username.")
 		return
 	}
 
 	// Check if email already exists
 	if err := config.DB.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
 		utils.LogError("Registration attempt failed - Email already exists: %s", req.Email)
-		utils.Conflict(c, "Email already exists", "An account with this email address already exists. Please use a different email or try logging in.")
+		utils.Fail(c, utils.CodeAlreadyExists, "Email already exists", "An account with this email address already exists. Please use a different email or try logging in.")
 		return
 	}
 
@@ -179,7 +184,7 @@ func RegisterUser(c *gin.Context) {
 	if req.Phone != "" {
 		if err := config.DB.Where("phone = ?", req.Phone).First(&existingUser).Error; err == nil {
 			utils.LogError("Registration attempt failed - Phone already exists: %s", req.Phone)
-			utils.Conflict(c, "Phone number already exists", "An account with this phone number already exists. Please use a different phone number or try logging in.")
+			utils.Fail(c, utils.CodeAlreadyExists, "Phone number already exists", "An account with this phone number already exists. Please use a different phone number or try logging in.")
 			return
 		}
 	}
@@ -192,10 +197,7 @@ func RegisterUser(c *gin.Context) {
 		return
 	}
 
-	// Generate OTP and expiry
-	otp := generateOTP()
-	log.Printf("Registration OTP for %s: %s", req.Email, otp)
-	otpExpiry := time.Now().Add(1 * time.Minute).Unix()
+	// Registration expiry
 	regExpiry := time.Now().Add(15 * time.Minute).Unix()
 
 	// Create JWT with registration info (NO OTP in claims)
@@ -217,22 +219,10 @@ func RegisterUser(c *gin.Context) {
 		return
 	}
 
-	// Store OTP and expiry in session
-	session := sessions.Default(c)
-	session.Set("registration_otp", otp)
-	session.Set("registration_otp_expires", otpExpiry)
-	session.Set("registration_email", req.Email)
-	if err := session.Save(); err != nil {
-		utils.LogError("Registration attempt failed - Session save error for email: %s - %v", req.Email, err)
-		utils.InternalServerError(c, "Failed to save session", err.Error())
-		return
-	}
-
-	// Send OTP email
+	// Store the OTP for verification and email it
 	utils.LogInfo("Sending registration OTP to email: %s", req.Email)
-	if err := utils.SendOTP(req.Email, otp); err != nil {
-		utils.LogError("Registration attempt failed - OTP email error for email: %s - %v", req.Email, err)
-		utils.InternalServerError(c, "Failed to send verification email", "An error occurred while sending your verification email. Please try again later.")
+	if otp, err := utils.StartOTP(utils.OTPPurposeRegistration, req.Email, nil); err != nil {
+		failEmailOTPSend(c, req.Email, otp, err)
 		return
 	}
 
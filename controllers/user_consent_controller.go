@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ConsentRequest represents a consent/cookie preference update.
+// Purposes that are omitted keep their current state.
+type ConsentRequest struct {
+	Analytics     *bool  `json:"analytics"`
+	Marketing     *bool  `json:"marketing"`
+	PolicyVersion string `json:"policy_version"`
+	Source        string `json:"source"`
+}
+
+// buildConsentState returns the current consent state of every purpose for a user
+func buildConsentState(f utils.ResponseFormatter, userID uint) (gin.H, error) {
+	purposes := gin.H{}
+	for _, purpose := range utils.ConsentPurposes {
+		record, err := utils.GetLatestConsent(userID, purpose)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			purposes[purpose] = gin.H{
+				"granted":        false,
+				"policy_version": nil,
+				"updated_at":     nil,
+			}
+			continue
+		}
+		purposes[purpose] = gin.H{
+			"granted":        record.Granted,
+			"policy_version": record.PolicyVersion,
+			"updated_at":     f.Timestamp(record.CreatedAt),
+		}
+	}
+
+	return gin.H{
+		"necessary":              true,
+		"purposes":               purposes,
+		"current_policy_version": utils.CurrentConsentPolicyVersion(),
+	}, nil
+}
+
+// GetConsent returns the user's current consent state
+func GetConsent(c *gin.Context) {
+	utils.LogInfo("GetConsent called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	formatter := utils.NewResponseFormatter(c)
+	state, err := buildConsentState(formatter, user.ID)
+	if err != nil {
+		utils.LogError("Failed to load consent state for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to load consent preferences", err.Error())
+		return
+	}
+
+	utils.LogInfo("Consent state retrieved for user %d", user.ID)
+	utils.Success(c, "Consent preferences retrieved successfully", state)
+}
+
+// UpdateConsent records the user's consent choices
+func UpdateConsent(c *gin.Context) {
+	utils.LogInfo("UpdateConsent called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var req ConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid consent request: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
+		return
+	}
+	if req.Analytics == nil && req.Marketing == nil {
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", "At least one of analytics or marketing is required")
+		return
+	}
+
+	policyVersion := req.PolicyVersion
+	if policyVersion == "" {
+		policyVersion = utils.CurrentConsentPolicyVersion()
+	}
+	source := req.Source
+	if source == "" {
+		source = "api"
+	}
+
+	choices := map[string]*bool{
+		models.ConsentPurposeAnalytics: req.Analytics,
+		models.ConsentPurposeMarketing: req.Marketing,
+	}
+
+	tx := config.DB.Begin()
+	for purpose, granted := range choices {
+		if granted == nil {
+			continue
+		}
+		record := models.ConsentRecord{
+			UserID:        user.ID,
+			Purpose:       purpose,
+			Granted:       *granted,
+			PolicyVersion: policyVersion,
+			Source:        source,
+			IPAddress:     c.ClientIP(),
+			UserAgent:     c.Request.UserAgent(),
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			tx.Rollback()
+			utils.LogError("Failed to record %s consent for user %d: %v", purpose, user.ID, err)
+			utils.InternalServerError(c, "Failed to save consent preferences", err.Error())
+			return
+		}
+		utils.LogInfo("Recorded %s consent=%v for user %d (policy %s)", purpose, *granted, user.ID, policyVersion)
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.LogError("Failed to commit consent records for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to save consent preferences", err.Error())
+		return
+	}
+
+	formatter := utils.NewResponseFormatter(c)
+	state, err := buildConsentState(formatter, user.ID)
+	if err != nil {
+		utils.LogError("Failed to load consent state for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to load consent preferences", err.Error())
+		return
+	}
+
+	utils.Success(c, "Consent preferences updated successfully", state)
+}
+
+// GetConsentHistory returns the user's full consent log
+func GetConsentHistory(c *gin.Context) {
+	utils.LogInfo("GetConsentHistory called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	page, limit := utils.GetPaginationParams(c)
+
+	var total int64
+	if err := config.DB.Model(&models.ConsentRecord{}).Where("user_id = ?", user.ID).Count(&total).Error; err != nil {
+		utils.LogError("Failed to count consent records for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch consent history", err.Error())
+		return
+	}
+
+	var records []models.ConsentRecord
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).Limit(limit).Find(&records).Error; err != nil {
+		utils.LogError("Failed to fetch consent records for user %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch consent history", err.Error())
+		return
+	}
+
+	history := make([]gin.H, len(records))
+	formatter := utils.NewResponseFormatter(c)
+	for i, record := range records {
+		history[i] = gin.H{
+			"id":             record.ID,
+			"purpose":        record.Purpose,
+			"granted":        record.Granted,
+			"policy_version": record.PolicyVersion,
+			"source":         record.Source,
+			"created_at":     formatter.Timestamp(record.CreatedAt),
+		}
+	}
+
+	utils.LogInfo("Retrieved %d consent records for user %d", len(records), user.ID)
+	utils.SuccessWithPagination(c, "Consent history retrieved successfully", gin.H{
+		"history": history,
+	}, total, page, limit)
+}
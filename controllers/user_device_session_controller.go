@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshTokenRequest exchanges a refresh token for new tokens
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshUserSession exchanges a session's refresh token for a new access token and refresh
+// token, and extends the session. Each refresh token can be used once.
+func RefreshUserSession(c *gin.Context) {
+	utils.LogInfo("RefreshUserSession called")
+
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Session refresh failed - Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", "Please provide the refresh token")
+		return
+	}
+
+	session, refreshToken, err := utils.RefreshSession(config.DB, c, req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrSessionExpired):
+			utils.LogError("Session refresh failed - Session expired")
+			utils.Fail(c, utils.CodeTokenExpired, "Session has expired, Please login again", nil)
+		case errors.Is(err, utils.ErrSessionInvalid):
+			utils.LogError("Session refresh failed - Unknown or revoked refresh token")
+			utils.Fail(c, utils.CodeTokenInvalid, "Invalid refresh token, Please login again", nil)
+		default:
+			utils.LogError("Session refresh failed: %v", err)
+			utils.InternalServerError(c, "Failed to refresh session", nil)
+		}
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, session.UserID).Error; err != nil {
+		utils.LogError("Session refresh failed - User %d not found: %v", session.UserID, err)
+		utils.Fail(c, utils.CodeTokenInvalid, "Invalid refresh token, Please login again", nil)
+		return
+	}
+	if user.IsBlocked {
+		utils.LogError("Session refresh failed - Blocked account: %d", user.ID)
+		utils.Fail(c, utils.CodeAccountBlocked, "Account is blocked", nil)
+		return
+	}
+	if user.DeletionRequestedAt != nil {
+		utils.LogError("Session refresh failed - Account %d is scheduled for deletion", user.ID)
+		utils.Fail(c, utils.CodeAccountDeleted, "Account is scheduled for deletion, log in again to restore it", nil)
+		return
+	}
+
+	// The session is reloaded so the access token stops with the extended expiry
+	if err := config.DB.First(session, session.ID).Error; err != nil {
+		utils.LogError("Failed to reload session %d: %v", session.ID, err)
+		utils.InternalServerError(c, "Failed to refresh session", nil)
+		return
+	}
+	token, err := utils.SessionToken(&user, session)
+	if err != nil {
+		utils.LogError("Failed to generate token for session %d: %v", session.ID, err)
+		utils.InternalServerError(c, "Failed to generate token", nil)
+		return
+	}
+
+	utils.LogInfo("Refreshed session %d for user ID: %d", session.ID, user.ID)
+	utils.Success(c, "Session refreshed successfully", gin.H{
+		"token":              token,
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": utils.NewResponseFormatter(c).Timestamp(session.ExpiresAt),
+		"session_id":         session.ID,
+	})
+}
+
+// GetUserSessions lists the devices the user is signed in on, most recently used first
+func GetUserSessions(c *gin.Context) {
+	utils.LogInfo("GetUserSessions called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	var sessions []models.UserSession
+	if err := config.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+		Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		utils.LogError("Failed to fetch sessions for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to fetch sessions", nil)
+		return
+	}
+
+	currentID := currentSessionID(c)
+	formatter := utils.NewResponseFormatter(c)
+	response := make([]gin.H, len(sessions))
+	for i, session := range sessions {
+		response[i] = gin.H{
+			"id":           session.ID,
+			"device_name":  session.DeviceName,
+			"user_agent":   session.UserAgent,
+			"ip_address":   session.IPAddress,
+			"remember_me":  session.RememberMe,
+			"last_seen_at": formatter.Timestamp(session.LastSeenAt),
+			"signed_in_at": formatter.Timestamp(session.CreatedAt),
+			"expires_at":   formatter.Timestamp(session.ExpiresAt),
+			"current":      session.ID == currentID,
+		}
+	}
+
+	utils.LogInfo("Retrieved %d sessions for user ID: %d", len(response), user.ID)
+	utils.Success(c, "Sessions retrieved successfully", gin.H{
+		"sessions": response,
+	})
+}
+
+// RevokeUserSession signs one of the user's devices out. Its refresh token and access tokens
+// stop working straight away.
+func RevokeUserSession(c *gin.Context) {
+	utils.LogInfo("RevokeUserSession called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid session ID: %s", c.Param("id"))
+		utils.Fail(c, utils.CodeInvalidID, "Invalid session ID", nil)
+		return
+	}
+
+	result := config.DB.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, user.ID, time.Now()).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		utils.LogError("Failed to revoke session %d for user ID: %d: %v", sessionID, user.ID, result.Error)
+		utils.InternalServerError(c, "Failed to sign out session", nil)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.LogError("Session %d not found for user ID: %d", sessionID, user.ID)
+		utils.Fail(c, utils.CodeSessionNotFound, "Session not found", nil)
+		return
+	}
+
+	utils.LogInfo("Revoked session %d for user ID: %d", sessionID, user.ID)
+	utils.Success(c, "Session signed out successfully", gin.H{
+		"id":      sessionID,
+		"current": uint(sessionID) == currentSessionID(c),
+	})
+}
+
+// RevokeOtherUserSessions signs the user out of every device except the one making the request
+func RevokeOtherUserSessions(c *gin.Context) {
+	utils.LogInfo("RevokeOtherUserSessions called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	user := userVal.(models.User)
+
+	revoked, err := utils.RevokeSessions(config.DB, user.ID, currentSessionID(c))
+	if err != nil {
+		utils.LogError("Failed to revoke other sessions for user ID: %d: %v", user.ID, err)
+		utils.InternalServerError(c, "Failed to sign out other sessions", nil)
+		return
+	}
+
+	utils.LogInfo("Revoked %d other sessions for user ID: %d", revoked, user.ID)
+	utils.Success(c, "Signed out of all other sessions", gin.H{
+		"revoked": revoked,
+	})
+}
+
+// currentSessionID returns the session of the request's access token, 0 for tokens issued
+// without one
+func currentSessionID(c *gin.Context) uint {
+	sessionID, _ := c.Get("session_id")
+	id, _ := sessionID.(uint)
+	return id
+}
+
+// renewSessionToken issues a new access token for the session the request was made in, or a
+// token without a session when the request's token had none
+func renewSessionToken(user *models.User, sessionID uint) (string, error) {
+	if sessionID != 0 {
+		var session models.UserSession
+		if err := config.DB.First(&session, sessionID).Error; err == nil {
+			return utils.SessionToken(user, &session)
+		}
+	}
+	return utils.GenerateToken(user)
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"gorm.io/gorm"
+)
+
+// googleLinkStateTTL is how long a Google account link started from the profile stays valid
+const googleLinkStateTTL = 10 * time.Minute
+
+// GetGoogleLinkURL returns the Google sign-in URL that links the Google account to the
+// current user. The state is signed so the callback knows which account to link.
+func GetGoogleLinkURL(c *gin.Context) {
+	utils.LogInfo("GetGoogleLinkURL called")
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
+		return
+	}
+	userModel := user.(models.User)
+
+	if userModel.GoogleID != "" {
+		utils.LogError("Google account already linked for user ID: %d", userModel.ID)
+		utils.Fail(c, utils.CodeAlreadyExists, "A Google account is already linked", "Unlink it first to link a different Google account")
+		return
+	}
+
+	// A different claim than user_id keeps the state from being usable as a login token
+	state := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"link_user_id": userModel.ID,
+		"exp":          time.Now().Add(googleLinkStateTTL).Unix(),
+	})
+	stateString, err := state.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		utils.LogError("Failed to sign Google link state for user ID: %d: %v", userModel.ID, err)
+		utils.InternalServerError(c, "Failed to start Google linking", err.Error())
+		return
+	}
+
+	utils.LogInfo("Google link started for user ID: %d", userModel.ID)
+	utils.Success(c, "Open the URL to link your Google account", gin.H{
+		"auth_url":   config.GoogleOAuthConfig.AuthCodeURL(stateString),
+		"expires_in": int(googleLinkStateTTL.Seconds()),
+	})
+}
+
+// UnlinkGoogle removes the Google account from the current user. Accounts without a password
+// of their own have to set one first so they can still log in.
+func UnlinkGoogle(c *gin.Context) {
+	utils.LogInfo("UnlinkGoogle called")
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
+		return
+	}
+	userModel := user.(models.User)
+
+	if userModel.GoogleID == "" {
+		utils.LogError("No Google account linked for user ID: %d", userModel.ID)
+		utils.BadRequest(c, "No Google account is linked", nil)
+		return
+	}
+	if userModel.PasswordUnset {
+		utils.LogError("Google unlink rejected - No password set for user ID: %d", userModel.ID)
+		utils.BadRequest(c, "Set a password before unlinking Google", "Without a password you would not be able to log in")
+		return
+	}
+
+	if err := config.DB.Model(&userModel).Update("google_id", gorm.Expr("NULL")).Error; err != nil {
+		utils.LogError("Failed to unlink Google for user ID: %d: %v", userModel.ID, err)
+		utils.InternalServerError(c, "Failed to unlink Google account", err.Error())
+		return
+	}
+
+	utils.LogInfo("Google account unlinked for user ID: %d", userModel.ID)
+	utils.Success(c, "Google account unlinked successfully", gin.H{
+		"google_linked": false,
+	})
+}
+
+// linkGoogleAccount finishes a link started with GetGoogleLinkURL and redirects to the
+// frontend profile with the outcome
+func linkGoogleAccount(c *gin.Context, state string, googleUser GoogleUserInfo) {
+	redirect := func(params url.Values) {
+		c.Redirect(http.StatusTemporaryRedirect, googleFrontendURL("/profile", params))
+	}
+
+	token, err := jwt.Parse(state, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err != nil || !token.Valid {
+		utils.LogError("Google link failed - Invalid or expired state: %v", err)
+		redirect(url.Values{"google_link_error": {"link_expired"}})
+		return
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	userID, ok := claims["link_user_id"].(float64)
+	if !ok {
+		utils.LogError("Google link failed - State without user")
+		redirect(url.Values{"google_link_error": {"link_expired"}})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, uint(userID)).Error; err != nil {
+		utils.LogError("Google link failed - User %d not found: %v", uint(userID), err)
+		redirect(url.Values{"google_link_error": {"account_not_found"}})
+		return
+	}
+	if user.GoogleID == googleUser.ID {
+		redirect(url.Values{"google_linked": {"true"}})
+		return
+	}
+	if user.GoogleID != "" {
+		utils.LogError("Google link failed - User %d already linked to another Google account", user.ID)
+		redirect(url.Values{"google_link_error": {"already_linked"}})
+		return
+	}
+
+	var owner models.User
+	if err := config.DB.Where("google_id = ?", googleUser.ID).First(&owner).Error; err == nil {
+		utils.LogError("Google link failed - Google account already linked to user %d", owner.ID)
+		redirect(url.Values{"google_link_error": {"google_account_in_use"}})
+		return
+	}
+
+	if err := config.DB.Model(&user).Update("google_id", googleUser.ID).Error; err != nil {
+		utils.LogError("Google link failed - Failed to save Google ID for user %d: %v", user.ID, err)
+		redirect(url.Values{"google_link_error": {"link_failed"}})
+		return
+	}
+
+	utils.LogInfo("Google account %s linked to user %d", googleUser.Email, user.ID)
+	redirect(url.Values{"google_linked": {"true"}})
+}
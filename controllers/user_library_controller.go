@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetLibrary lists the digital books the user has bought
+func GetLibrary(c *gin.Context) {
+	utils.LogInfo("GetLibrary called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	userID := userVal.(models.User).ID
+
+	var entitlements []models.DigitalEntitlement
+	if err := config.DB.Preload("Book").Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").Find(&entitlements).Error; err != nil {
+		utils.LogError("Failed to fetch library for user ID: %d: %v", userID, err)
+		utils.InternalServerError(c, "Failed to fetch library", err.Error())
+		return
+	}
+
+	limit := utils.EbookDownloadLimit()
+	books := make([]gin.H, 0, len(entitlements))
+	formatter := utils.NewResponseFormatter(c)
+	for _, entitlement := range entitlements {
+		downloadsLeft := limit - entitlement.Downloads
+		if downloadsLeft < 0 {
+			downloadsLeft = 0
+		}
+		books = append(books, gin.H{
+			"book_id":        entitlement.BookID,
+			"name":           entitlement.Book.Name,
+			"author":         entitlement.Book.Author,
+			"image_url":      entitlement.Book.ImageURL,
+			"format":         entitlement.Book.DigitalContentType,
+			"order_id":       entitlement.OrderID,
+			"downloads":      entitlement.Downloads,
+			"downloads_left": downloadsLeft,
+			"available":      entitlement.Book.DigitalFileKey != "",
+			"purchased_at":   formatter.Timestamp(entitlement.CreatedAt),
+		})
+	}
+
+	utils.LogInfo("Retrieved %d library books for user ID: %d", len(books), userID)
+	utils.Success(c, "Library retrieved successfully", gin.H{
+		"books": books,
+	})
+}
+
+// GetLibraryDownloadLink issues a signed, short-lived link for downloading a digital book the
+// user has bought. Each link counts towards the book's download limit.
+func GetLibraryDownloadLink(c *gin.Context) {
+	utils.LogInfo("GetLibraryDownloadLink called")
+
+	userVal, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
+		return
+	}
+	userID := userVal.(models.User).ID
+
+	var entitlement models.DigitalEntitlement
+	if err := config.DB.Preload("Book").Where("user_id = ? AND book_id = ? AND revoked_at IS NULL", userID, c.Param("bookId")).
+		First(&entitlement).Error; err != nil {
+		utils.LogError("No entitlement for book %s, user ID: %d", c.Param("bookId"), userID)
+		utils.NotFound(c, "This book is not in your library")
+		return
+	}
+	if entitlement.Book.DigitalFileKey == "" {
+		utils.LogError("Book ID: %d has no e-book file", entitlement.BookID)
+		utils.Fail(c, utils.CodeBookUnavailable, "This e-book is not available for download right now", nil)
+		return
+	}
+
+	// Count the download only if the limit still allows it, so concurrent requests cannot overrun it
+	limit := utils.EbookDownloadLimit()
+	now := time.Now()
+	result := config.DB.Model(&models.DigitalEntitlement{}).
+		Where("id = ? AND downloads < ?", entitlement.ID, limit).
+		Updates(map[string]interface{}{
+			"downloads":        gorm.Expr("downloads + 1"),
+			"last_download_at": now,
+		})
+	if result.Error != nil {
+		utils.LogError("Failed to count download for entitlement ID: %d: %v", entitlement.ID, result.Error)
+		utils.InternalServerError(c, "Failed to create download link", nil)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.LogError("Download limit reached for book ID: %d, user ID: %d", entitlement.BookID, userID)
+		utils.Fail(c, utils.CodeTooManyRequests, fmt.Sprintf("Download limit of %d reached for this book", limit), nil)
+		return
+	}
+
+	token, expiresAt, err := utils.SignDownloadToken(entitlement.ID)
+	if err != nil {
+		utils.LogError("Failed to sign download link for entitlement ID: %d: %v", entitlement.ID, err)
+		utils.InternalServerError(c, "Failed to create download link", nil)
+		return
+	}
+
+	utils.LogInfo("Issued download link for book ID: %d to user ID: %d", entitlement.BookID, userID)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Download link created", gin.H{
+		"download_url":   "/v1/library/files?token=" + url.QueryEscape(token),
+		"expires_at":     formatter.Timestamp(expiresAt),
+		"downloads_left": limit - entitlement.Downloads - 1,
+	})
+}
+
+// DownloadLibraryFile serves the e-book file a signed download link points at. Range requests
+// are honoured so interrupted downloads can resume while the link is valid.
+func DownloadLibraryFile(c *gin.Context) {
+	utils.LogInfo("DownloadLibraryFile called")
+
+	entitlementID, err := utils.ParseDownloadToken(c.Query("token"))
+	if err != nil {
+		utils.LogError("Invalid download link: %v", err)
+		utils.Fail(c, utils.CodeTokenInvalid, "Download link is invalid or has expired", nil)
+		return
+	}
+
+	var entitlement models.DigitalEntitlement
+	if err := config.DB.Preload("Book").Where("id = ? AND revoked_at IS NULL", entitlementID).First(&entitlement).Error; err != nil {
+		utils.LogError("Entitlement ID: %d not found or revoked", entitlementID)
+		utils.NotFound(c, "This book is no longer in your library")
+		return
+	}
+	if entitlement.Book.DigitalFileKey == "" {
+		utils.LogError("Book ID: %d has no e-book file", entitlement.BookID)
+		utils.Fail(c, utils.CodeBookUnavailable, "This e-book is not available for download right now", nil)
+		return
+	}
+
+	data, err := ebookStorage(entitlement.Book.DigitalFileKey).Get(entitlement.Book.DigitalFileKey)
+	if err != nil {
+		utils.LogError("Failed to read e-book for book ID: %d: %v", entitlement.BookID, err)
+		utils.InternalServerError(c, "Failed to load e-book", nil)
+		return
+	}
+
+	extension := ".pdf"
+	if entitlement.Book.DigitalContentType == utils.PreviewTypeEPUB {
+		extension = ".epub"
+	}
+	filename := fmt.Sprintf("book-%d%s", entitlement.BookID, extension)
+	c.Header("Content-Type", entitlement.Book.DigitalContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "private, no-store")
+	http.ServeContent(c.Writer, c.Request, filename, entitlement.Book.UpdatedAt, bytes.NewReader(data))
+}
@@ -1,14 +1,12 @@
 package controllers
 
 import (
-	"log"
 	"os"
 	"time"
 
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
-	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
@@ -17,13 +15,15 @@ import (
 // ForgotPasswordRequest represents the forgot password request body
 type ForgotPasswordRequest struct {
 	Email string `json:"email" binding:"required,email"`
+	// CaptchaToken is the solved CAPTCHA, required when CAPTCHA_PROVIDER is set
+	CaptchaToken string `json:"captcha_token"`
 }
 
 func ForgotPassword(c *gin.Context) {
 	var req ForgotPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Password reset attempt failed - Invalid request format: %v", err)
-		utils.BadRequest(c, "Invalid request format", "Please provide a valid email address")
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", "Please provide a valid email address")
 		return
 	}
 
@@ -34,6 +34,11 @@ func ForgotPassword(c *gin.Context) {
 		return
 	}
 
+	// Bots are stopped before the account lookup and the OTP email
+	if !utils.CheckCaptcha(c, req.CaptchaToken) {
+		return
+	}
+
 	// Check if user exists
 	var user models.User
 	if err := config.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
@@ -42,105 +47,49 @@ func ForgotPassword(c *gin.Context) {
 		return
 	}
 
-	// Generate OTP
-	otp := generateOTP()
-	log.Println("Forgot Password OTP:", otp)
-	utils.LogInfo("Password reset OTP generated for %s", req.Email)
-
-	// Store email and OTP in session
-	session := sessions.Default(c)
-	session.Set("reset_email", req.Email)
-	session.Set("reset_otp", otp)
-	session.Set("reset_otp_expires", time.Now().Add(time.Minute*1).Unix())
-
-	if err := session.Save(); err != nil {
-		utils.LogError("Password reset attempt failed - Session save error for email: %s - %v", req.Email, err)
-		utils.InternalServerError(c, "Failed to save session", "An error occurred while processing your request. Please try again later.")
-		return
-	}
-
-	// Send OTP via email
-	if err := utils.SendOTP(req.Email, otp); err != nil {
-		utils.LogError("Password reset attempt failed - OTP email error for email: %s - %v", req.Email, err)
-		utils.InternalServerError(c, "Failed to send verification email", "An error occurred while sending the verification email. Please try again later.")
+	// Store the OTP for verification and email it
+	if otp, err := utils.StartOTP(utils.OTPPurposePasswordReset, req.Email, nil); err != nil {
+		failEmailOTPSend(c, req.Email, otp, err)
 		return
 	}
 
 	utils.LogInfo("Password reset OTP sent successfully to email: %s", req.Email)
 	utils.Success(c, "Password reset OTP has been sent to your email", gin.H{
 		"email":      req.Email,
-		"expires_in": 60, // OTP expires in 60 seconds
+		"expires_in": int(utils.OTPLifetime(utils.OTPPurposePasswordReset).Seconds()),
 	})
 }
 
 // VerifyResetOTPRequest represents the reset password OTP verification request body
 type VerifyResetOTPRequest struct {
-	OTP string `json:"otp" binding:"required"`
+	Email string `json:"email" binding:"required,email"`
+	OTP   string `json:"otp" binding:"required"`
 }
 
 func VerifyResetOTP(c *gin.Context) {
 	var req VerifyResetOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Password reset OTP verification failed - Invalid request format: %v", err)
-		utils.BadRequest(c, "Invalid request format", "Please provide OTP")
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", "Please provide the email address and the OTP")
 		return
 	}
 
-	// Get reset data from session
-	session := sessions.Default(c)
-	email := session.Get("reset_email")
-	if email == nil {
-		utils.LogError("Password reset OTP verification failed - No reset session found")
-		utils.BadRequest(c, "Invalid request", "Please request password reset first")
+	email := req.Email
+	// Verify OTP; expired OTPs are replaced through /v1/auth/resend-otp
+	if err := utils.CheckOTP(utils.OTPPurposePasswordReset, email, nil, req.OTP); err != nil {
+		utils.LogError("Password reset OTP verification failed for email: %s - %v", email, err)
+		utils.FailOTP(c, err)
 		return
 	}
 
-	// Check if OTP has expired
-	otpExpires := session.Get("reset_otp_expires").(int64)
-	if time.Now().Unix() > otpExpires {
-		// Generate new OTP
-		newOTP := generateOTP()
-		log.Println("Reset OTP expired, sending new OTP:", newOTP)
-		utils.LogInfo("Password reset OTP expired, generating new OTP for %s", email)
-
-		// Update session with new OTP and expiration time
-		session.Set("reset_otp", newOTP)
-		session.Set("reset_otp_expires", time.Now().Add(time.Minute*1).Unix())
-
-		if err := session.Save(); err != nil {
-			utils.LogError("Password reset OTP resend failed - Session save error for email: %s - %v", email, err)
-			utils.InternalServerError(c, "Failed to save session", "An error occurred while processing your request. Please try again later.")
-			return
-		}
-
-		// Send new OTP via email
-		if err := utils.SendOTP(email.(string), newOTP); err != nil {
-			utils.LogError("Password reset OTP resend failed - Email error for email: %s - %v", email, err)
-			utils.InternalServerError(c, "Failed to send verification email", "An error occurred while sending the verification email. Please try again later.")
-			return
-		}
-
-		utils.LogInfo("New password reset OTP sent successfully to email: %s", email)
-		utils.BadRequest(c, "OTP expired", gin.H{
-			"message":    "A new OTP has been sent to your email",
-			"email":      email,
-			"expires_in": 60,
-		})
-		return
-	}
-
-	// Verify OTP
-	storedOTP := session.Get("reset_otp").(string)
-	if storedOTP != req.OTP {
-		utils.LogError("Password reset OTP verification failed - Invalid OTP for email: %s", email)
-		utils.BadRequest(c, "Invalid OTP", "The OTP you entered is incorrect")
-		return
-	}
-
-	// Generate a temporary token for password reset
+	// Generate a temporary token for the password reset request. It works for the reset only,
+	// and once: it is issued before the password change it authorizes.
+	now := time.Now()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": email,
-		"exp":   time.Now().Add(15 * time.Minute).Unix(), // Token expires in 15 minutes
+		"email":   email,
+		"purpose": utils.OTPPurposePasswordReset,
+		"iat":     now.Unix(),
+		"exp":     now.Add(15 * time.Minute).Unix(), // Token expires in 15 minutes
 	})
 
 	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
@@ -150,14 +99,6 @@ func VerifyResetOTP(c *gin.Context) {
 		return
 	}
 
-	// Store token in session before clearing
-	session.Set("reset_token", tokenString)
-	if err := session.Save(); err != nil {
-		utils.LogError("Password reset OTP verification failed - Session save error for email: %s - %v", email, err)
-		utils.InternalServerError(c, "Failed to save session", "An error occurred while processing your request. Please try again later.")
-		return
-	}
-
 	utils.LogInfo("Password reset OTP verified successfully for email: %s", email)
 	utils.Success(c, "OTP verified successfully", gin.H{
 		"message":    "Please reset your password",
@@ -168,6 +109,7 @@ func VerifyResetOTP(c *gin.Context) {
 
 // ResetPasswordRequest represents the reset password request body
 type ResetPasswordRequest struct {
+	Token           string `json:"token" binding:"required"` // issued by VerifyResetOTP
 	NewPassword     string `json:"new_password" binding:"required"`
 	ConfirmPassword string `json:"confirm_password" binding:"required"`
 }
@@ -176,7 +118,7 @@ func ResetPassword(c *gin.Context) {
 	var req ResetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Password reset failed - Invalid request format: %v", err)
-		utils.BadRequest(c, "Invalid request format", "Please provide new password and confirm password")
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", "Please provide the reset token, new password and confirm password")
 		return
 	}
 
@@ -194,30 +136,21 @@ func ResetPassword(c *gin.Context) {
 		return
 	}
 
-	// Get token from session
-	session := sessions.Default(c)
-	tokenString := session.Get("reset_token")
-	if tokenString == nil {
-		utils.LogError("Password reset failed - No reset token found in session")
-		utils.Unauthorized(c, "Invalid request: Please verify your OTP first")
-		return
-	}
-
 	// Verify token
-	token, err := jwt.Parse(tokenString.(string), func(token *jwt.Token) (interface{}, error) {
+	token, err := jwt.Parse(req.Token, func(token *jwt.Token) (interface{}, error) {
 		return []byte(os.Getenv("JWT_SECRET")), nil
 	})
 
 	if err != nil || !token.Valid {
 		utils.LogError("Password reset failed - Invalid or expired token: %v", err)
-		utils.Unauthorized(c, "Invalid or expired token, Your password reset session has expired. Please request a new password reset.")
+		utils.Fail(c, utils.CodeTokenInvalid, "Invalid or expired token, Your password reset session has expired. Please request a new password reset.", nil)
 		return
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
+	if !ok || claims["purpose"] != utils.OTPPurposePasswordReset {
 		utils.LogError("Password reset failed - Invalid token claims")
-		utils.Unauthorized(c, "Invalid token: Invalid password reset token")
+		utils.Fail(c, utils.CodeTokenInvalid, "Invalid token: Invalid password reset token", nil)
 		return
 	}
 
@@ -230,6 +163,11 @@ func ResetPassword(c *gin.Context) {
 		utils.NotFound(c, "User not found: No account exists with this email address")
 		return
 	}
+	if utils.TokenRevoked(claims, &user) {
+		utils.LogError("Password reset failed - Token already used or password changed since for user: %s", email)
+		utils.Fail(c, utils.CodeTokenInvalid, "Invalid or expired token, Your password reset session has expired. Please request a new password reset.", nil)
+		return
+	}
 
 	// Check if new password is same as current password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.NewPassword)); err == nil {
@@ -238,16 +176,17 @@ func ResetPassword(c *gin.Context) {
 		return
 	}
 
-	// Check password history (last 3 passwords)
-	var passwordHistory []models.PasswordHistory
-	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Limit(3).Find(&passwordHistory).Error; err == nil {
-		for _, history := range passwordHistory {
-			if err := bcrypt.CompareHashAndPassword([]byte(history.Password), []byte(req.NewPassword)); err == nil {
-				utils.LogError("Password reset failed - Password recently used for user: %s", email)
-				utils.BadRequest(c, "Invalid password", "This password has been used recently. Please choose a different password")
-				return
-			}
-		}
+	// Check password history
+	reused, err := utils.PasswordRecentlyUsed(config.DB, user.ID, req.NewPassword)
+	if err != nil {
+		utils.LogError("Password reset failed - Password history lookup error for user: %s - %v", email, err)
+		utils.InternalServerError(c, "Failed to check password history", "An error occurred while processing your request. Please try again later.")
+		return
+	}
+	if reused {
+		utils.LogError("Password reset failed - Password recently used for user: %s", email)
+		utils.BadRequest(c, "Invalid password", "This password has been used recently. Please choose a different password")
+		return
 	}
 
 	// Hash new password
@@ -266,23 +205,17 @@ func ResetPassword(c *gin.Context) {
 		return
 	}
 
-	// Update user's password
-	if err := tx.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+	// Update user's password, add it to the password history and sign out existing sessions
+	if err := utils.SetPassword(tx, &user, string(hashedPassword)); err != nil {
 		tx.Rollback()
 		utils.LogError("Password reset failed - Password update error for user: %s - %v", email, err)
 		utils.InternalServerError(c, "Failed to update password", "An error occurred while updating your password. Please try again later.")
 		return
 	}
-
-	// Add to password history
-	passwordHistoryEntry := models.PasswordHistory{
-		UserID:   user.ID,
-		Password: string(hashedPassword),
-	}
-	if err := tx.Create(&passwordHistoryEntry).Error; err != nil {
+	if _, err := utils.RevokeSessions(tx, user.ID, 0); err != nil {
 		tx.Rollback()
-		utils.LogError("Password reset failed - Password history update error for user: %s - %v", email, err)
-		utils.InternalServerError(c, "Failed to update password history", "An error occurred while updating password history. Please try again later.")
+		utils.LogError("Password reset failed - Session revocation error for user: %s - %v", email, err)
+		utils.InternalServerError(c, "Failed to update password", "An error occurred while updating your password. Please try again later.")
 		return
 	}
 
@@ -293,10 +226,6 @@ func ResetPassword(c *gin.Context) {
 		return
 	}
 
-	// Clear session
-	session.Clear()
-	session.Save()
-
 	utils.LogInfo("Password reset completed successfully for user: %s", email)
 	utils.Success(c, "Password reset successfully", gin.H{
 		"redirect": gin.H{
@@ -16,7 +16,7 @@ func GetUserProfile(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
 		return
 	}
 
@@ -24,12 +24,15 @@ func GetUserProfile(c *gin.Context) {
 	utils.LogInfo("User profile retrieved for user ID: %d", userModel.ID)
 	utils.Success(c, "Profile retrieved successfully", gin.H{
 		"user": gin.H{
-			"username":      userModel.Username,
-			"email":         userModel.Email,
-			"first_name":    userModel.FirstName,
-			"last_name":     userModel.LastName,
-			"phone":         userModel.Phone,
-			"profile_image": userModel.ProfileImage,
+			"username":           userModel.Username,
+			"email":              userModel.Email,
+			"first_name":         userModel.FirstName,
+			"last_name":          userModel.LastName,
+			"phone":              userModel.Phone,
+			"profile_image":      userModel.ProfileImage,
+			"google_linked":      userModel.GoogleID != "",
+			"password_set":       !userModel.PasswordUnset,
+			"profile_incomplete": userModel.ProfileIncomplete,
 		},
 	})
 }
@@ -49,7 +52,7 @@ func UpdateProfile(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
 		return
 	}
 
@@ -59,7 +62,7 @@ func UpdateProfile(c *gin.Context) {
 	var req UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request format: %v", err)
-		utils.BadRequest(c, "Invalid request format", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
 		return
 	}
 
@@ -76,7 +79,7 @@ func UpdateProfile(c *gin.Context) {
 		var existingUser models.User
 		if err := config.DB.Where("username = ? AND id != ?", req.Username, userModel.ID).First(&existingUser).Error; err == nil {
 			utils.LogError("Username already exists: %s", req.Username)
-			utils.Conflict(c, "Username already exists", nil)
+			utils.Fail(c, utils.CodeAlreadyExists, "Username already exists", nil)
 			return
 		}
 		updates["username"] = req.Username
@@ -117,10 +120,12 @@ func UpdateProfile(c *gin.Context) {
 		var existingUser models.User
 		if err := config.DB.Where("phone = ? AND id != ?", formattedPhone, userModel.ID).First(&existingUser).Error; err == nil {
 			utils.LogError("Phone number already exists: %s", formattedPhone)
-			utils.Conflict(c, "Phone number already exists", nil)
+			utils.Fail(c, utils.CodeAlreadyExists, "Phone number already exists", nil)
 			return
 		}
 		updates["phone"] = formattedPhone
+		// A new number has to be verified again before it can be used to sign in
+		updates["phone_verified_at"] = nil
 		utils.LogInfo("Phone updated to: %s", formattedPhone)
 	}
 
@@ -146,6 +151,7 @@ func UpdateProfile(c *gin.Context) {
 	}
 
 	utils.LogInfo("Profile updated successfully for user ID: %d", updatedUser.ID)
+	formatter := utils.NewResponseFormatter(c)
 	utils.Success(c, "Profile updated successfully", gin.H{
 		"user": gin.H{
 			"id":            updatedUser.ID,
@@ -157,8 +163,92 @@ func UpdateProfile(c *gin.Context) {
 			"profile_image": updatedUser.ProfileImage,
 			"is_verified":   updatedUser.IsVerified,
 			"wallet": gin.H{
-				"balance": updatedUser.Wallet.Balance,
+				"balance": formatter.Money(updatedUser.Wallet.Balance),
 			},
 		},
 	})
 }
+
+// CompleteProfileRequest represents the profile completion request of an account created
+// through Google
+type CompleteProfileRequest struct {
+	Username string `json:"username" binding:"required"`
+	Phone    string `json:"phone" binding:"required"`
+}
+
+// CompleteProfile replaces the generated username of an account created through Google and
+// adds its phone number
+func CompleteProfile(c *gin.Context) {
+	utils.LogInfo("CompleteProfile called")
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
+		return
+	}
+
+	userModel := user.(models.User)
+	if !userModel.ProfileIncomplete {
+		utils.LogError("Profile already complete for user ID: %d", userModel.ID)
+		utils.BadRequest(c, "Profile is already complete", "Use PUT /v1/profile to update it")
+		return
+	}
+
+	var req CompleteProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+
+	if valid, msg := utils.ValidateUsername(req.Username); !valid {
+		utils.LogError("Invalid username: %s", msg)
+		utils.BadRequest(c, msg, nil)
+		return
+	}
+	var existingUser models.User
+	if err := config.DB.Where("username = ? AND id != ?", req.Username, userModel.ID).First(&existingUser).Error; err == nil {
+		utils.LogError("Username already exists: %s", req.Username)
+		utils.Fail(c, utils.CodeAlreadyExists, "Username already exists", nil)
+		return
+	}
+
+	valid, formattedPhone := utils.ValidatePhone(req.Phone)
+	if !valid {
+		utils.LogError("Invalid phone: %s", formattedPhone)
+		utils.BadRequest(c, formattedPhone, nil)
+		return
+	}
+	if err := config.DB.Where("phone = ? AND id != ?", formattedPhone, userModel.ID).First(&existingUser).Error; err == nil {
+		utils.LogError("Phone number already exists: %s", formattedPhone)
+		utils.Fail(c, utils.CodeAlreadyExists, "Phone number already exists", nil)
+		return
+	}
+
+	updates := map[string]interface{}{
+		"username":           req.Username,
+		"phone":              formattedPhone,
+		"profile_incomplete": false,
+	}
+	if formattedPhone != userModel.Phone {
+		updates["phone_verified_at"] = nil
+	}
+	if err := config.DB.Model(&userModel).Updates(updates).Error; err != nil {
+		utils.LogError("Failed to complete profile: %v", err)
+		utils.InternalServerError(c, "Failed to complete profile", err.Error())
+		return
+	}
+
+	utils.LogInfo("Profile completed for user ID: %d", userModel.ID)
+	utils.Success(c, "Profile completed successfully", gin.H{
+		"user": gin.H{
+			"id":                 userModel.ID,
+			"username":           req.Username,
+			"email":              userModel.Email,
+			"phone":              formattedPhone,
+			"password_set":       !userModel.PasswordUnset,
+			"profile_incomplete": false,
+		},
+	})
+}
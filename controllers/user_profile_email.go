@@ -1,13 +1,9 @@
 package controllers
 
 import (
-	"log"
-	"time"
-
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
-	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
 
@@ -23,7 +19,7 @@ func UpdateEmail(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
 		return
 	}
 
@@ -33,7 +29,7 @@ func UpdateEmail(c *gin.Context) {
 	var req UpdateEmailRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request format: %v", err)
-		utils.BadRequest(c, "Invalid request format", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
 		return
 	}
 
@@ -48,49 +44,27 @@ func UpdateEmail(c *gin.Context) {
 	var existingUser models.User
 	if err := config.DB.Where("email = ?", req.NewEmail).First(&existingUser).Error; err == nil {
 		utils.LogError("Email already exists: %s", req.NewEmail)
-		utils.Conflict(c, "Email already exists", nil)
+		utils.Fail(c, utils.CodeAlreadyExists, "Email already exists", nil)
 		return
 	}
 
-	// Generate OTP
-	otp := utils.GenerateOTP()
-	log.Printf("DEBUG: OTP for %s is %s", req.NewEmail, otp)
-	utils.LogInfo("Generated OTP for email update: %s", req.NewEmail)
-	otpExpiry := time.Now().Add(15 * time.Minute)
-
-	// Store OTP
-	if err := config.DB.Create(&models.UserOTP{
-		UserID:    userModel.ID,
-		Code:      otp,
-		ExpiresAt: otpExpiry,
-	}).Error; err != nil {
-		utils.LogError("Failed to store OTP: %v", err)
-		utils.InternalServerError(c, "Failed to generate verification code", err.Error())
+	// Store the new email with its OTP and send the OTP to the new address
+	if otp, err := utils.StartOTP(utils.OTPPurposeEmailChange, req.NewEmail, &userModel.ID); err != nil {
+		failEmailOTPSend(c, req.NewEmail, otp, err)
 		return
 	}
 
-	// Send OTP email
-	if err := utils.SendOTP(req.NewEmail, otp); err != nil {
-		utils.LogError("Failed to send OTP email: %v", err)
-		utils.InternalServerError(c, "Failed to send verification email", err.Error())
-		return
-	}
-
-	// Store new email in session for verification
-	session := sessions.Default(c)
-	session.Set("new_email", req.NewEmail)
-	session.Save()
-
 	utils.LogInfo("Email update initiated successfully for user ID: %d, new email: %s", userModel.ID, req.NewEmail)
 	utils.Success(c, "Verification code sent to new email address", gin.H{
 		"email":      req.NewEmail,
-		"expires_in": 900, // 15 minutes in seconds
+		"expires_in": int(utils.OTPLifetime(utils.OTPPurposeEmailChange).Seconds()),
 	})
 }
 
 // VerifyEmailUpdateRequest represents the email update verification request
 type VerifyEmailUpdateRequest struct {
-	OTP string `json:"otp" binding:"required"`
+	NewEmail string `json:"new_email" binding:"required,email"`
+	OTP      string `json:"otp" binding:"required"`
 }
 
 // VerifyEmailUpdate verifies the OTP and updates the email
@@ -100,7 +74,7 @@ func VerifyEmailUpdate(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
 		return
 	}
 
@@ -110,25 +84,24 @@ func VerifyEmailUpdate(c *gin.Context) {
 	var req VerifyEmailUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request format: %v", err)
-		utils.BadRequest(c, "Invalid request format", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
 		return
 	}
 
-	// Get new email from session
-	session := sessions.Default(c)
-	newEmail := session.Get("new_email")
-	if newEmail == nil {
-		utils.LogError("Email update not initiated for user ID: %d", userModel.ID)
-		utils.BadRequest(c, "Email update not initiated", "Please initiate email update first")
+	newEmail := req.NewEmail
+
+	// Verify the OTP sent to the new address for this user
+	if err := utils.CheckOTP(utils.OTPPurposeEmailChange, newEmail, &userModel.ID, req.OTP); err != nil {
+		utils.LogError("Email update OTP verification failed for user ID: %d: %v", userModel.ID, err)
+		utils.FailOTP(c, err)
 		return
 	}
 
-	// Verify OTP
-	var userOTP models.UserOTP
-	if err := config.DB.Where("user_id = ? AND code = ? AND expires_at > ?",
-		userModel.ID, req.OTP, time.Now()).First(&userOTP).Error; err != nil {
-		utils.LogError("Invalid or expired OTP for user ID: %d", userModel.ID)
-		utils.BadRequest(c, "Invalid or expired OTP", nil)
+	// The address may have been registered since the update was initiated
+	var existingUser models.User
+	if err := config.DB.Where("email = ? AND id != ?", newEmail, userModel.ID).First(&existingUser).Error; err == nil {
+		utils.LogError("Email already exists: %s", newEmail)
+		utils.Fail(c, utils.CodeAlreadyExists, "Email already exists", nil)
 		return
 	}
 
@@ -139,10 +112,6 @@ func VerifyEmailUpdate(c *gin.Context) {
 		return
 	}
 
-	// Clear session
-	session.Delete("new_email")
-	session.Save()
-
 	utils.LogInfo("Email updated successfully for user ID: %d, new email: %s", userModel.ID, newEmail)
 	utils.Success(c, "Email updated successfully", gin.H{
 		"user": gin.H{
@@ -19,7 +19,7 @@ func UploadProfileImage(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
 		return
 	}
 
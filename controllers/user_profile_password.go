@@ -1,11 +1,14 @@
 package controllers
 
 import (
+	"fmt"
+
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // ChangePasswordRequest represents the password change request
@@ -22,7 +25,7 @@ func ChangePassword(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
 		return
 	}
 
@@ -32,14 +35,21 @@ func ChangePassword(c *gin.Context) {
 	var req ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request format: %v", err)
-		utils.BadRequest(c, "Invalid request format", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+
+	// Accounts created through Google have no password the user knows yet
+	if userModel.PasswordUnset {
+		utils.LogError("Password change rejected - No password set for user ID: %d", userModel.ID)
+		utils.BadRequest(c, "No password set for this account", "Use /v1/profile/password/set to set a password first")
 		return
 	}
 
 	// Verify current password
 	if err := bcrypt.CompareHashAndPassword([]byte(userModel.Password), []byte(req.CurrentPassword)); err != nil {
 		utils.LogError("Current password verification failed for user ID: %d", userModel.ID)
-		utils.Unauthorized(c, "Current password is incorrect")
+		utils.Fail(c, utils.CodeInvalidCredentials, "Current password is incorrect", nil)
 		return
 	}
 
@@ -60,20 +70,21 @@ func ChangePassword(c *gin.Context) {
 	// Check if new password is same as current password
 	if err := bcrypt.CompareHashAndPassword([]byte(userModel.Password), []byte(req.NewPassword)); err == nil {
 		utils.LogError("New password same as current password for user ID: %d", userModel.ID)
-		utils.BadRequest(c, "New password cannot be the same as current password", nil)
+		utils.Fail(c, utils.CodePasswordReused, "New password cannot be the same as current password", nil)
 		return
 	}
 
 	// Check password history
-	var passwordHistory []models.PasswordHistory
-	if err := config.DB.Where("user_id = ?", userModel.ID).Order("created_at DESC").Limit(3).Find(&passwordHistory).Error; err == nil {
-		for _, history := range passwordHistory {
-			if err := bcrypt.CompareHashAndPassword([]byte(history.Password), []byte(req.NewPassword)); err == nil {
-				utils.LogError("Password recently used for user ID: %d", userModel.ID)
-				utils.BadRequest(c, "This password has been used recently", "Please choose a different password that hasn't been used in your last 3 passwords")
-				return
-			}
-		}
+	reused, err := utils.PasswordRecentlyUsed(config.DB, userModel.ID, req.NewPassword)
+	if err != nil {
+		utils.LogError("Failed to check password history for user ID: %d: %v", userModel.ID, err)
+		utils.InternalServerError(c, "Failed to check password history", err.Error())
+		return
+	}
+	if reused {
+		utils.LogError("Password recently used for user ID: %d", userModel.ID)
+		utils.Fail(c, utils.CodePasswordReused, "This password has been used recently", fmt.Sprintf("Please choose a different password that hasn't been used in your last %d passwords", utils.PasswordHistorySize))
+		return
 	}
 
 	// Hash new password
@@ -92,23 +103,18 @@ func ChangePassword(c *gin.Context) {
 		return
 	}
 
-	// Update password
-	if err := tx.Model(&userModel).Update("password", string(hashedPassword)).Error; err != nil {
+	// Update password, add it to the password history and revoke earlier tokens
+	if err := utils.SetPassword(tx, &userModel, string(hashedPassword)); err != nil {
 		tx.Rollback()
 		utils.LogError("Failed to update password in database for user ID: %d: %v", userModel.ID, err)
 		utils.InternalServerError(c, "Failed to update password", err.Error())
 		return
 	}
-
-	// Add to password history
-	passwordHistoryEntry := models.PasswordHistory{
-		UserID:   userModel.ID,
-		Password: string(hashedPassword),
-	}
-	if err := tx.Create(&passwordHistoryEntry).Error; err != nil {
+	sessionID := currentSessionID(c)
+	if _, err := utils.RevokeSessions(tx, userModel.ID, sessionID); err != nil {
 		tx.Rollback()
-		utils.LogError("Failed to update password history for user ID: %d: %v", userModel.ID, err)
-		utils.InternalServerError(c, "Failed to update password history", err.Error())
+		utils.LogError("Failed to revoke other sessions for user ID: %d: %v", userModel.ID, err)
+		utils.InternalServerError(c, "Failed to update password", err.Error())
 		return
 	}
 
@@ -119,14 +125,100 @@ func ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// Every other session is signed out by the change; this one continues with a new token
+	token, err := renewSessionToken(&userModel, sessionID)
+	if err != nil {
+		utils.LogError("Failed to generate token after password change for user ID: %d: %v", userModel.ID, err)
+		utils.InternalServerError(c, "Password changed, but failed to generate a new token. Please login again", err.Error())
+		return
+	}
+
 	utils.LogInfo("Password changed successfully for user ID: %d", userModel.ID)
 	utils.Success(c, "Password changed successfully", gin.H{
 		"user": gin.H{
 			"id": userModel.ID,
 		},
-		"redirect": gin.H{
-			"url":     "/login",
-			"message": "Please login again with your new password",
-		},
+		"token":   token,
+		"message": "You have been signed out of all other sessions",
+	})
+}
+
+// SetInitialPasswordRequest represents the request to set a password on an account created
+// through Google
+type SetInitialPasswordRequest struct {
+	NewPassword     string `json:"new_password" binding:"required"`
+	ConfirmPassword string `json:"confirm_password" binding:"required"`
+}
+
+// SetInitialPassword sets the first password of an account created through Google, so it
+// can also log in with email and password
+func SetInitialPassword(c *gin.Context) {
+	utils.LogInfo("SetInitialPassword called")
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.LogError("User not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
+		return
+	}
+
+	userModel := user.(models.User)
+	if !userModel.PasswordUnset {
+		utils.LogError("Password already set for user ID: %d", userModel.ID)
+		utils.BadRequest(c, "A password is already set", "Use /v1/user/change-password to change it")
+		return
+	}
+
+	var req SetInitialPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid request format: %v", err)
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request format", err)
+		return
+	}
+	if valid, msg := utils.ValidatePassword(req.NewPassword); !valid {
+		utils.LogError("Password validation failed for user ID: %d: %s", userModel.ID, msg)
+		utils.BadRequest(c, msg, nil)
+		return
+	}
+	if valid, msg := utils.ValidateConfirmPassword(req.NewPassword, req.ConfirmPassword); !valid {
+		utils.LogError("Password confirmation mismatch for user ID: %d", userModel.ID)
+		utils.BadRequest(c, msg, nil)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		utils.LogError("Failed to hash password for user ID: %d: %v", userModel.ID, err)
+		utils.InternalServerError(c, "Failed to hash password", err.Error())
+		return
+	}
+
+	sessionID := currentSessionID(c)
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := utils.SetPassword(tx, &userModel, string(hashedPassword)); err != nil {
+			return err
+		}
+		if _, err := utils.RevokeSessions(tx, userModel.ID, sessionID); err != nil {
+			return err
+		}
+		return tx.Model(&userModel).Update("password_unset", false).Error
+	})
+	if err != nil {
+		utils.LogError("Failed to set password for user ID: %d: %v", userModel.ID, err)
+		utils.InternalServerError(c, "Failed to set password", err.Error())
+		return
+	}
+
+	// Setting the password revokes earlier tokens, so this session continues with a new one
+	token, err := renewSessionToken(&userModel, sessionID)
+	if err != nil {
+		utils.LogError("Failed to generate token after setting password for user ID: %d: %v", userModel.ID, err)
+		utils.InternalServerError(c, "Password set, but failed to generate a new token. Please login again", err.Error())
+		return
+	}
+
+	utils.LogInfo("Password set for user ID: %d", userModel.ID)
+	utils.Success(c, "Password set successfully", gin.H{
+		"token": token,
 	})
 }
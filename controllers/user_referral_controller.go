@@ -92,7 +92,7 @@ func GetUserReferralCode(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -120,13 +120,14 @@ func GetUserReferralCode(c *gin.Context) {
 	}
 
 	utils.LogInfo("Successfully retrieved referral code for user ID: %d", user.ID)
+	formatter := utils.NewResponseFormatter(c)
 	utils.Success(c, "Referral code retrieved successfully", gin.H{
 		"data": gin.H{
 			"referral_code":   userCode.ReferralCode,
 			"referral_url":    "/referral/" + userCode.ReferralCode,
 			"total_referrals": referralCount,
 			"is_active":       userCode.IsActive,
-			"created_at":      userCode.CreatedAt.Format("2006-01-02 15:04:05"),
+			"created_at":      formatter.Timestamp(userCode.CreatedAt),
 		},
 	})
 }
@@ -139,7 +140,7 @@ func GetUserReferrals(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -182,6 +183,7 @@ func GetUserReferrals(c *gin.Context) {
 
 	// Format response
 	formattedReferrals := make([]gin.H, len(referrals))
+	formatter := utils.NewResponseFormatter(c)
 	for i, referral := range referrals {
 		formattedReferrals[i] = gin.H{
 			"referred_user": gin.H{
@@ -192,6 +194,7 @@ func GetUserReferrals(c *gin.Context) {
 				"last_name":  referral.ReferredUser.LastName,
 			},
 			"referrer_coupon_code": referral.ReferrerCoupon.Code,
+			"joined_at":            formatter.Timestamp(referral.UsedAt),
 		}
 	}
 
@@ -225,56 +228,35 @@ func UseReferralCode(referralCode string, referredUserID uint) error {
 		return utils.NewError("You have already used a referral code")
 	}
 
+	settings, err := getReferralSettings()
+	if err != nil {
+		return err
+	}
+	if !settings.Enabled {
+		utils.LogInfo("Referral program disabled, ignoring code %s for user %d", referralCode, referredUserID)
+		return utils.NewError("The referral program is currently paused")
+	}
+
 	// Start transaction
 	tx := config.DB.Begin()
 	if tx.Error != nil {
 		return tx.Error
 	}
 
-	// Create coupon for referrer (25% off)
-	referrerCoupon := models.Coupon{
-		Code:          "REF-" + userCode.ReferralCode + "-" + "BONUS",
-		Type:          "percent",
-		Value:         25,
-		MinOrderValue: 100,
-		MaxDiscount:   500,
-		Expiry:        time.Now().AddDate(0, 1, 0), // 1 month
-		UsageLimit:    1,
-		Active:        true,
-	}
-
-	if err := tx.Create(&referrerCoupon).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	// Create coupon for referred user (25% off)
-	referredCoupon := models.Coupon{
-		Code:          "REF-NEW-" + userCode.ReferralCode,
-		Type:          "percent",
-		Value:         25,
-		MinOrderValue: 100,
-		MaxDiscount:   500,
-		Expiry:        time.Now().AddDate(0, 1, 0), // 1 month
-		UsageLimit:    1,
-		Active:        true,
+	// Create referral usage record
+	referralUsage := models.ReferralUsage{
+		ReferrerID:     userCode.UserID,
+		ReferredUserID: referredUserID,
+		UsedAt:         time.Now(),
 	}
 
-	if err := tx.Create(&referredCoupon).Error; err != nil {
+	if err := tx.Create(&referralUsage).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// Create referral usage record
-	referralUsage := models.ReferralUsage{
-		ReferrerID:       userCode.UserID,
-		ReferredUserID:   referredUserID,
-		UsedAt:           time.Now(),
-		ReferrerCouponID: referrerCoupon.ID,
-		ReferredCouponID: referredCoupon.ID,
-	}
-
-	if err := tx.Create(&referralUsage).Error; err != nil {
+	// Grant the configured rewards to both users and record them in the reward ledger
+	if err := grantReferralRewards(tx, settings, &referralUsage, userCode.ReferralCode); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -1,9 +1,6 @@
 package controllers
 
 import (
-	"crypto/rand"
-	"math/big"
-	"net/http"
 	"strings"
 	"time"
 
@@ -27,7 +24,7 @@ func UserLogout(c *gin.Context) {
 	err := session.Save()
 	if err != nil {
 		utils.LogError("Failed to save cleared session: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		utils.InternalServerError(c, "Failed to logout", nil)
 		return
 	}
 
@@ -50,43 +47,14 @@ func UserLogout(c *gin.Context) {
 		}
 	}
 
-	utils.LogInfo("User session cleared and token blacklisted successfully")
-	utils.Success(c, "Logout successful", nil)
-}
-
-func AddReview(c *gin.Context) {
-	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		utils.LogError("Unauthorized review attempt - no user ID found")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	// Log the review attempt
-	utils.LogInfo("User %d attempting to add a review", userID)
-
-	// TODO: Add actual review logic here
-	// For now, just log the success
-	utils.LogInfo("Review added successfully by user %d", userID)
-	c.JSON(http.StatusOK, gin.H{"message": "Review added successfully"})
-}
-
-func generateOTP() string {
-	utils.LogDebug("Generating new OTP")
-	// Use crypto/rand for secure random number generation
-	b := make([]byte, 6)
-	for i := 0; i < 6; i++ {
-		num := 0
-		for {
-			r, err := rand.Int(rand.Reader, big.NewInt(10))
-			if err == nil {
-				num = int(r.Int64())
-				break
-			}
+	// Sign the device's session out so its refresh token stops working too
+	if sessionID := currentSessionID(c); sessionID != 0 {
+		if err := config.DB.Model(&models.UserSession{}).Where("id = ? AND revoked_at IS NULL", sessionID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			utils.LogError("Failed to revoke session %d: %v", sessionID, err)
 		}
-		b[i] = byte('0' + num)
 	}
-	utils.LogDebug("OTP generated successfully")
-	return string(b)
+
+	utils.LogInfo("User session cleared and token blacklisted successfully")
+	utils.Success(c, "Logout successful", nil)
 }
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// VisibilityScheduleRequest sets when a book or offer goes live and when it is taken down.
+// It replaces any earlier schedule; an omitted or null time schedules no change.
+type VisibilityScheduleRequest struct {
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+}
+
+// scheduleUpdates validates the schedule and returns the column updates that store it. A
+// publish time still ahead takes the item down until then, under activeColumn.
+func scheduleUpdates(req VisibilityScheduleRequest, activeColumn string) (map[string]interface{}, error) {
+	now := time.Now()
+	if req.PublishAt != nil && !req.PublishAt.After(now) {
+		return nil, fmt.Errorf("publish_at must be in the future")
+	}
+	if req.UnpublishAt != nil && !req.UnpublishAt.After(now) {
+		return nil, fmt.Errorf("unpublish_at must be in the future")
+	}
+	if req.PublishAt != nil && req.UnpublishAt != nil && !req.UnpublishAt.After(*req.PublishAt) {
+		return nil, fmt.Errorf("unpublish_at must be after publish_at")
+	}
+
+	updates := map[string]interface{}{
+		"publish_at":   req.PublishAt,
+		"unpublish_at": req.UnpublishAt,
+	}
+	if req.PublishAt != nil {
+		updates[activeColumn] = false
+	}
+	return updates, nil
+}
+
+// bindVisibilitySchedule parses the schedule of the request and the ID of the item it is for
+func bindVisibilitySchedule(c *gin.Context, activeColumn string) (uint64, map[string]interface{}, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Fail(c, utils.CodeInvalidID, "Invalid ID", nil)
+		return 0, nil, false
+	}
+	var req VisibilityScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid visibility schedule: %v", err)
+		utils.Fail(c, utils.CodeInvalidDate, "Invalid date format. Use RFC3339.", err)
+		return 0, nil, false
+	}
+	updates, err := scheduleUpdates(req, activeColumn)
+	if err != nil {
+		utils.BadRequest(c, err.Error(), nil)
+		return 0, nil, false
+	}
+	return id, updates, true
+}
+
+// AdminScheduleBook sets when a book goes live and when it is taken down
+func AdminScheduleBook(c *gin.Context) {
+	utils.LogInfo("AdminScheduleBook called")
+
+	bookID, updates, ok := bindVisibilitySchedule(c, "is_active")
+	if !ok {
+		return
+	}
+	var book models.Book
+	if err := config.DB.First(&book, bookID).Error; err != nil {
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
+		return
+	}
+	if err := config.DB.Model(&book).Updates(updates).Error; err != nil {
+		utils.LogError("Failed to schedule book %d: %v", book.ID, err)
+		utils.InternalServerError(c, "Failed to schedule book", err.Error())
+		return
+	}
+
+	utils.LogInfo("Scheduled book %d: publish at %v, unpublish at %v", book.ID, book.PublishAt, book.UnpublishAt)
+	formatter := utils.NewResponseFormatter(c)
+	utils.Success(c, "Book schedule updated successfully", gin.H{
+		"id":           book.ID,
+		"is_active":    book.IsActive,
+		"publish_at":   formatter.OptionalTimestamp(book.PublishAt),
+		"unpublish_at": formatter.OptionalTimestamp(book.UnpublishAt),
+	})
+}
+
+// AdminScheduleProductOffer sets when a product offer is switched on and off
+func AdminScheduleProductOffer(c *gin.Context) {
+	utils.LogInfo("AdminScheduleProductOffer called")
+
+	offerID, updates, ok := bindVisibilitySchedule(c, "active")
+	if !ok {
+		return
+	}
+	var offer models.ProductOffer
+	if err := config.DB.First(&offer, offerID).Error; err != nil {
+		utils.NotFound(c, "Offer not found")
+		return
+	}
+	if err := config.DB.Model(&offer).Updates(updates).Error; err != nil {
+		utils.LogError("Failed to schedule product offer %d: %v", offer.ID, err)
+		utils.InternalServerError(c, "Failed to schedule offer", err.Error())
+		return
+	}
+
+	utils.LogInfo("Scheduled product offer %d", offer.ID)
+	utils.Success(c, "Offer schedule updated successfully", gin.H{
+		"offer": formatProductOffer(utils.NewResponseFormatter(c), offer),
+	})
+}
+
+// AdminScheduleCategoryOffer sets when a category offer is switched on and off
+func AdminScheduleCategoryOffer(c *gin.Context) {
+	utils.LogInfo("AdminScheduleCategoryOffer called")
+
+	offerID, updates, ok := bindVisibilitySchedule(c, "active")
+	if !ok {
+		return
+	}
+	var offer models.CategoryOffer
+	if err := config.DB.First(&offer, offerID).Error; err != nil {
+		utils.NotFound(c, "Offer not found")
+		return
+	}
+	if err := config.DB.Model(&offer).Updates(updates).Error; err != nil {
+		utils.LogError("Failed to schedule category offer %d: %v", offer.ID, err)
+		utils.InternalServerError(c, "Failed to schedule offer", err.Error())
+		return
+	}
+
+	utils.LogInfo("Scheduled category offer %d", offer.ID)
+	utils.Success(c, "Offer schedule updated successfully", gin.H{
+		"offer": formatCategoryOffer(utils.NewResponseFormatter(c), offer),
+	})
+}
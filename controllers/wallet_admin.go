@@ -10,7 +10,6 @@ import (
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 func ApproveOrderReturn(c *gin.Context) {
@@ -19,7 +18,7 @@ func ApproveOrderReturn(c *gin.Context) {
 	_, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found", nil)
 		return
 	}
 
@@ -27,7 +26,7 @@ func ApproveOrderReturn(c *gin.Context) {
 	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		utils.LogError("Invalid order ID format: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
 		return
 	}
 	utils.LogInfo("Processing return approval for order ID: %d", orderID)
@@ -36,14 +35,14 @@ func ApproveOrderReturn(c *gin.Context) {
 	var order models.Order
 	if err := config.DB.Preload("User").Preload("OrderItems").Where("id = ?", orderID).First(&order).Error; err != nil {
 		utils.LogError("Order not found - Order ID: %d: %v", orderID, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 
 	// Check if order status is return requested
 	if order.Status != models.OrderStatusReturnRequested && order.Status != "Returned" {
 		utils.LogError("Invalid order status for return approval - Order ID: %d, Status: %s", orderID, order.Status)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Order is not in return requested status"})
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Order is not in return requested status", nil)
 		return
 	}
 
@@ -51,68 +50,53 @@ func ApproveOrderReturn(c *gin.Context) {
 	tx := config.DB.Begin()
 	if tx.Error != nil {
 		utils.LogError("Failed to begin transaction for order ID: %d: %v", orderID, tx.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to begin transaction"})
+		utils.InternalServerError(c, "Failed to begin transaction", nil)
 		return
 	}
 	utils.LogDebug("Started transaction for order ID: %d", orderID)
 
-	// Restock books
-	for _, item := range order.OrderItems {
-		if err := tx.Model(&models.Book{}).Where("id = ?", item.BookID).UpdateColumn("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
-			tx.Rollback()
-			utils.LogError("Failed to restock books for order ID: %d, Book ID: %d: %v", orderID, item.BookID, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restock books"})
-			return
-		}
-		utils.LogDebug("Restocked book ID: %d with quantity: %d", item.BookID, item.Quantity)
+	// Restock books; items already restocked by an earlier cancellation or approval are skipped
+	restocked, err := utils.RestockOrderItems(tx, order.OrderItems, models.StockSourceReturn)
+	if err != nil {
+		tx.Rollback()
+		utils.LogError("Failed to restock books for order ID: %d: %v", orderID, err)
+		utils.InternalServerError(c, "Failed to restock books", nil)
+		return
 	}
+	utils.LogDebug("Restocked %d items for order ID: %d", restocked, orderID)
 
 	// Update order status
 	order.Status = models.OrderStatusReturnApproved
 	order.RefundStatus = "pending"
-	order.RefundAmount = order.FinalTotal
+	// Refund the goods plus the payment method fee, minus any discount or cashback received
+	refundAmount := order.FinalTotal + utils.PaymentRefundAdjustment(&order, order.FinalTotal)
+	order.RefundAmount = refundAmount
 	order.RefundedToWallet = true
 
 	if err := tx.Save(&order).Error; err != nil {
 		tx.Rollback()
 		utils.LogError("Failed to update order status - Order ID: %d: %v", orderID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order"})
+		utils.InternalServerError(c, "Failed to update order", nil)
 		return
 	}
 	utils.LogDebug("Updated order status for order ID: %d", orderID)
 
-	// Get or create wallet
-	wallet, err := getOrCreateWallet(order.UserID)
-	if err != nil {
-		tx.Rollback()
-		utils.LogError("Failed to get wallet for user ID: %d: %v", order.UserID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wallet"})
-		return
-	}
-	utils.LogDebug("Retrieved wallet for user ID: %d", order.UserID)
-
-	// Create a wallet transaction
-	orderIDUint := uint(orderID)
-	reference := fmt.Sprintf("REFUND-RETURN-%d", orderID)
-	description := fmt.Sprintf("Refund for returned order #%d", orderID)
-
-	transaction, err := createWalletTransaction(wallet.ID, order.FinalTotal, models.TransactionTypeCredit, description, &orderIDUint, reference)
+	// Credit the refund in the same transaction as the order update
+	transaction, err := utils.PostWalletEntry(tx, utils.WalletEntry{
+		UserID:      order.UserID,
+		Amount:      refundAmount,
+		Type:        models.TransactionTypeCredit,
+		Description: fmt.Sprintf("Refund for returned order #%d", orderID),
+		Reference:   fmt.Sprintf("REFUND-RETURN-%d", orderID),
+		OrderID:     &order.ID,
+	})
 	if err != nil {
 		tx.Rollback()
-		utils.LogError("Failed to create wallet transaction - Order ID: %d: %v", orderID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transaction"})
-		return
-	}
-	utils.LogDebug("Created wallet transaction for order ID: %d", orderID)
-
-	// Update wallet balance
-	if err := updateWalletBalance(wallet.ID, order.FinalTotal, models.TransactionTypeCredit); err != nil {
-		tx.Rollback()
-		utils.LogError("Failed to update wallet balance - Wallet ID: %d: %v", wallet.ID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update wallet balance"})
+		utils.LogError("Failed to credit refund - Order ID: %d: %v", orderID, err)
+		utils.InternalServerError(c, "Failed to create transaction", nil)
 		return
 	}
-	utils.LogDebug("Updated wallet balance for wallet ID: %d", wallet.ID)
+	utils.LogDebug("Credited refund transaction %d for order ID: %d", transaction.ID, orderID)
 
 	// Update order refund status
 	now := time.Now()
@@ -123,7 +107,7 @@ func ApproveOrderReturn(c *gin.Context) {
 	if err := tx.Save(&order).Error; err != nil {
 		tx.Rollback()
 		utils.LogError("Failed to update order refund status - Order ID: %d: %v", orderID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order refund status"})
+		utils.InternalServerError(c, "Failed to update order refund status", nil)
 		return
 	}
 	utils.LogDebug("Updated order refund status for order ID: %d", orderID)
@@ -131,19 +115,21 @@ func ApproveOrderReturn(c *gin.Context) {
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		utils.LogError("Failed to commit transaction - Order ID: %d: %v", orderID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		utils.InternalServerError(c, "Failed to commit transaction", nil)
 		return
 	}
+	utils.RecordRefundIssued("return", order.RefundAmount)
 	utils.LogInfo("Successfully approved return and processed refund for order ID: %d", orderID)
 
+	formatter := utils.NewResponseFormatter(c)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Return approved and refunded to wallet",
 		"order": gin.H{
 			"id":            order.ID,
 			"status":        order.Status,
-			"refund_amount": fmt.Sprintf("%.2f", order.RefundAmount),
+			"refund_amount": formatter.Money(order.RefundAmount),
 			"refund_status": order.RefundStatus,
-			"refunded_at":   order.RefundedAt,
+			"refunded_at":   formatter.OptionalTimestamp(order.RefundedAt),
 		},
 		"transaction": transaction,
 	})
@@ -156,7 +142,7 @@ func RejectOrderReturn(c *gin.Context) {
 	_, exists := c.Get("admin")
 	if !exists {
 		utils.LogError("Admin not found in context")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found", nil)
 		return
 	}
 
@@ -164,7 +150,7 @@ func RejectOrderReturn(c *gin.Context) {
 	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		utils.LogError("Invalid order ID format: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID", nil)
 		return
 	}
 	utils.LogInfo("Processing return rejection for order ID: %d", orderID)
@@ -173,14 +159,14 @@ func RejectOrderReturn(c *gin.Context) {
 	var order models.Order
 	if err := config.DB.Where("id = ?", orderID).First(&order).Error; err != nil {
 		utils.LogError("Order not found - Order ID: %d: %v", orderID, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		utils.Fail(c, utils.CodeOrderNotFound, "Order not found", nil)
 		return
 	}
 
 	// Check if order status is valid for rejection
 	if order.Status != models.OrderStatusReturnRequested && order.Status != "Returned" {
 		utils.LogError("Invalid order status for rejection - Order ID: %d, Status: %s", orderID, order.Status)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Order is not in a valid state for return rejection"})
+		utils.Fail(c, utils.CodeOrderStatusInvalid, "Order is not in a valid state for return rejection", nil)
 		return
 	}
 
@@ -190,7 +176,7 @@ func RejectOrderReturn(c *gin.Context) {
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Missing rejection reason for order ID: %d: %v", orderID, err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+		utils.Fail(c, utils.CodeReasonRequired, "Reason is required", nil)
 		return
 	}
 
@@ -201,7 +187,7 @@ func RejectOrderReturn(c *gin.Context) {
 
 	if err := config.DB.Save(&order).Error; err != nil {
 		utils.LogError("Failed to update order status - Order ID: %d: %v", orderID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order"})
+		utils.InternalServerError(c, "Failed to update order", nil)
 		return
 	}
 	utils.LogInfo("Successfully rejected return request for order ID: %d", orderID)
@@ -235,52 +221,3 @@ func getOrCreateWallet(userID uint) (*models.Wallet, error) {
 	}
 	return &wallet, nil
 }
-
-// Helper function to create a wallet transaction
-func createWalletTransaction(walletID uint, amount float64, transactionType string, description string, orderID *uint, reference string) (*models.WalletTransaction, error) {
-	utils.LogDebug("Creating wallet transaction - Wallet ID: %d, Amount: %.2f, Type: %s", walletID, amount, transactionType)
-	transaction := models.WalletTransaction{
-		WalletID:    walletID,
-		Amount:      amount,
-		Type:        transactionType,
-		Description: description,
-		OrderID:     orderID,
-		Reference:   reference,
-		Status:      models.TransactionStatusCompleted,
-	}
-
-	if err := config.DB.Create(&transaction).Error; err != nil {
-		utils.LogError("Failed to create wallet transaction - Wallet ID: %d: %v", walletID, err)
-		return nil, err
-	}
-	utils.LogDebug("Created wallet transaction ID: %d", transaction.ID)
-	return &transaction, nil
-}
-
-// Helper function to update wallet balance
-func updateWalletBalance(walletID uint, amount float64, transactionType string) error {
-	utils.LogDebug("Updating wallet balance - Wallet ID: %d, Amount: %.2f, Type: %s", walletID, amount, transactionType)
-	var wallet models.Wallet
-	if err := config.DB.First(&wallet, walletID).Error; err != nil {
-		utils.LogError("Failed to get wallet - Wallet ID: %d: %v", walletID, err)
-		return err
-	}
-
-	switch transactionType {
-	case models.TransactionTypeCredit:
-		wallet.Balance += amount
-	case models.TransactionTypeDebit:
-		if wallet.Balance < amount {
-			utils.LogError("Insufficient balance - Wallet ID: %d, Required: %.2f, Available: %.2f", walletID, amount, wallet.Balance)
-			return fmt.Errorf("insufficient balance")
-		}
-		wallet.Balance -= amount
-	}
-
-	if err := config.DB.Save(&wallet).Error; err != nil {
-		utils.LogError("Failed to save wallet balance - Wallet ID: %d: %v", walletID, err)
-		return err
-	}
-	utils.LogDebug("Updated wallet balance - Wallet ID: %d, New Balance: %.2f", walletID, wallet.Balance)
-	return nil
-}
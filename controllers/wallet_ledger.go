@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/repositories"
+	"github.com/Govind-619/ReadSphere/services"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// walletLedgerQuery is the ledger page and filters requested in the query string
+type walletLedgerQuery struct {
+	Filter  repositories.TransactionFilter
+	Filters gin.H
+	Page    int
+	Limit   int
+}
+
+// parseWalletLedgerQuery reads the type, status and date range filters and the page from the
+// query string. It writes the error response itself and returns false on failure.
+func parseWalletLedgerQuery(c *gin.Context) (*walletLedgerQuery, bool) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+	utils.LogDebug("Pagination parameters - Page: %d, Limit: %d", page, limit)
+
+	filter := repositories.TransactionFilter{
+		Type:   c.Query("type"),
+		Status: c.Query("status"),
+		Offset: (page - 1) * limit,
+		Limit:  limit,
+	}
+
+	startDate := c.Query("start_date")
+	if startDate != "" {
+		start, err := time.Parse("2006-01-02", startDate)
+		if err != nil {
+			utils.LogError("Invalid start date: %v", err)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid start date", "Date must be in YYYY-MM-DD format")
+			return nil, false
+		}
+		filter.From = &start
+	}
+
+	endDate := c.Query("end_date")
+	if endDate != "" {
+		end, err := time.Parse("2006-01-02", endDate)
+		if err != nil {
+			utils.LogError("Invalid end date: %v", err)
+			utils.Fail(c, utils.CodeInvalidDate, "Invalid end date", "Date must be in YYYY-MM-DD format")
+			return nil, false
+		}
+		// End date is inclusive
+		until := end.AddDate(0, 0, 1)
+		filter.Until = &until
+	}
+
+	return &walletLedgerQuery{
+		Filter: filter,
+		Filters: gin.H{
+			"type":       filter.Type,
+			"status":     filter.Status,
+			"start_date": startDate,
+			"end_date":   endDate,
+		},
+		Page:  page,
+		Limit: limit,
+	}, true
+}
+
+// walletLedgerError writes the response for an error returned by the wallet service
+func walletLedgerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTransactionType):
+		utils.LogError("Invalid transaction type filter: %s", c.Query("type"))
+		utils.BadRequest(c, "Invalid transaction type", "Type must be 'credit', 'debit' or 'hold'")
+	case errors.Is(err, repositories.ErrNotFound):
+		utils.Fail(c, utils.CodeUserNotFound, "User not found", nil)
+	default:
+		utils.LogError("Failed to get wallet transactions: %v", err)
+		utils.InternalServerError(c, "Failed to get transactions", err.Error())
+	}
+}
+
+// formatLedgerEntries formats ledger entries for the response
+func formatLedgerEntries(f utils.ResponseFormatter, entries []services.LedgerEntry) []gin.H {
+	formatted := make([]gin.H, len(entries))
+	for i, entry := range entries {
+		formatted[i] = gin.H{
+			"id":            entry.ID,
+			"amount":        f.Money(entry.Amount),
+			"type":          entry.Type,
+			"status":        entry.Status,
+			"description":   entry.Description,
+			"reference":     entry.Reference,
+			"order_id":      entry.OrderID,
+			"balance_after": f.Money(entry.BalanceAfter),
+			"created_at":    f.Timestamp(entry.CreatedAt),
+		}
+	}
+	return formatted
+}
+
+// AdminGetUserWalletLedger returns any user's wallet ledger with the same filters as the user view
+func (h *WalletHandler) AdminGetUserWalletLedger(c *gin.Context) {
+	utils.LogInfo("AdminGetUserWalletLedger called")
+
+	if _, exists := c.Get("admin"); !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found", nil)
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.LogError("Invalid user ID format: %v", err)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid user ID", nil)
+		return
+	}
+
+	query, ok := parseWalletLedgerQuery(c)
+	if !ok {
+		return
+	}
+
+	user, ledger, err := h.wallets.AdminLedger(uint(userID), query.Filter)
+	if err != nil {
+		utils.LogError("Failed to get wallet ledger for user ID: %d: %v", userID, err)
+		walletLedgerError(c, err)
+		return
+	}
+
+	formatter := utils.NewResponseFormatter(c)
+	// Admins also see who posted manual adjustments
+	transactions := formatLedgerEntries(formatter, ledger.Entries)
+	for i, entry := range ledger.Entries {
+		transactions[i]["admin_id"] = entry.AdminID
+	}
+
+	utils.LogInfo("Retrieved %d ledger entries for user ID: %d", len(ledger.Entries), user.ID)
+	utils.SuccessWithPagination(c, "Wallet ledger retrieved successfully", gin.H{
+		"user": gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"email":    user.Email,
+		},
+		"wallet": gin.H{
+			"id":      ledger.Wallet.ID,
+			"balance": formatter.Money(ledger.Wallet.Balance),
+		},
+		"transactions": transactions,
+		"filters":      query.Filters,
+	}, ledger.Total, query.Page, query.Limit)
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ReconcileWalletsJob flags wallets whose balance no longer matches their ledger
+func (h *WalletHandler) ReconcileWalletsJob() (string, error) {
+	result, err := h.wallets.Reconcile(time.Now())
+	if err != nil {
+		return "", err
+	}
+	if result.Mismatched > 0 {
+		utils.LogError("Wallet reconciliation found %d wallets whose balance does not match their ledger", result.Mismatched)
+	}
+	return fmt.Sprintf("%d wallets checked, %d mismatched, %d mismatches resolved",
+		result.Checked, result.Mismatched, result.Resolved), nil
+}
+
+// AdminListWalletMismatches lists wallets flagged by reconciliation; resolved ones are
+// included with include_resolved=true
+func (h *WalletHandler) AdminListWalletMismatches(c *gin.Context) {
+	utils.LogInfo("AdminListWalletMismatches called")
+
+	if _, exists := c.Get("admin"); !exists {
+		utils.LogError("Admin not found in context")
+		utils.Fail(c, utils.CodeAuthRequired, "Admin not found", nil)
+		return
+	}
+
+	mismatches, err := h.wallets.Mismatches(c.Query("include_resolved") == "true")
+	if err != nil {
+		utils.LogError("Failed to list wallet mismatches: %v", err)
+		utils.InternalServerError(c, "Failed to list wallet mismatches", err.Error())
+		return
+	}
+
+	items := make([]gin.H, 0, len(mismatches))
+	formatter := utils.NewResponseFormatter(c)
+	for _, m := range mismatches {
+		item := gin.H{
+			"wallet_id":         m.WalletID,
+			"user_id":           m.UserID,
+			"balance":           formatter.Money(m.Balance),
+			"ledger_balance":    formatter.Money(m.LedgerBalance),
+			"difference":        formatter.Money(m.Difference),
+			"first_detected_at": formatter.Timestamp(m.FirstDetectedAt),
+			"last_checked_at":   formatter.Timestamp(m.LastCheckedAt),
+			"resolved_at":       nil,
+		}
+		if m.ResolvedAt != nil {
+			item["resolved_at"] = formatter.Timestamp(*m.ResolvedAt)
+		}
+		items = append(items, item)
+	}
+
+	utils.LogInfo("Retrieved %d wallet mismatches", len(items))
+	utils.Success(c, "Wallet mismatches retrieved successfully", gin.H{
+		"mismatches": items,
+		"count":      len(items),
+	})
+}
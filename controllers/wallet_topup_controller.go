@@ -22,7 +22,7 @@ func InitiateWalletTopup(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -39,7 +39,7 @@ func InitiateWalletTopup(c *gin.Context) {
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request body for user ID: %d: %v", userID, err)
-		utils.BadRequest(c, "Invalid request. Amount is required and must be positive", err.Error())
+		utils.BadRequest(c, "Invalid request. Amount is required and must be positive", err)
 		return
 	}
 	utils.LogDebug("Received topup request - User ID: %d, Amount: %.2f", userID, req.Amount)
@@ -60,7 +60,7 @@ func InitiateWalletTopup(c *gin.Context) {
 	client := razorpay.NewClient(os.Getenv("RAZORPAY_KEY"), os.Getenv("RAZORPAY_SECRET"))
 	orderData := map[string]interface{}{
 		"amount":          amountPaise,
-		"currency":        "INR",
+		"currency":        utils.BaseCurrency(),
 		"receipt":         "wallet_topup_" + strconv.FormatUint(uint64(userID), 10) + "_" + time.Now().Format("20060102150405"),
 		"payment_capture": 1,
 	}
@@ -89,12 +89,13 @@ func InitiateWalletTopup(c *gin.Context) {
 	utils.LogDebug("Created wallet topup order record - Order ID: %s", walletTopupOrder.RazorpayOrderID)
 
 	utils.LogInfo("Successfully initiated wallet topup for user ID: %d", userID)
+	formatter := utils.NewResponseFormatter(c)
 	utils.Success(c, "Wallet topup order created successfully", gin.H{
 		"order": gin.H{
 			"id":                walletTopupOrder.ID,
 			"razorpay_order_id": rzOrder["id"],
-			"amount":            fmt.Sprintf("%.2f", req.Amount),
-			"amount_display":    "₹" + fmt.Sprintf("%.2f", float64(amountPaise)/100),
+			"amount":            formatter.Money(req.Amount),
+			"amount_display":    utils.FormatBaseMoney(float64(amountPaise) / 100),
 			"payment_type":      "wallet_topup",
 		},
 		"key": os.Getenv("RAZORPAY_KEY"),
@@ -104,7 +105,7 @@ func InitiateWalletTopup(c *gin.Context) {
 		},
 		"wallet": gin.H{
 			"id":      wallet.ID,
-			"balance": fmt.Sprintf("%.2f", wallet.Balance),
+			"balance": formatter.Money(wallet.Balance),
 		},
 	})
 }
@@ -115,7 +116,7 @@ func VerifyWalletTopup(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -135,7 +136,7 @@ func VerifyWalletTopup(c *gin.Context) {
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request body for user ID: %d: %v", userID, err)
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 	utils.LogDebug("Received verification request - Order ID: %d, Razorpay Order ID: %s, Payment ID: %s", req.OrderID, req.RazorpayOrderID, req.RazorpayPaymentID)
@@ -153,14 +154,14 @@ func VerifyWalletTopup(c *gin.Context) {
 	if walletTopupOrder.RazorpayOrderID != req.RazorpayOrderID {
 		utils.LogError("Razorpay order ID mismatch - Order ID: %d, Expected: %s, Received: %s",
 			req.OrderID, walletTopupOrder.RazorpayOrderID, req.RazorpayOrderID)
-		utils.BadRequest(c, "Invalid Razorpay order ID", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid Razorpay order ID", nil)
 		return
 	}
 
 	// Check if order is still pending
 	if walletTopupOrder.Status != "pending" {
 		utils.LogError("Wallet topup order is not in pending status - Order ID: %d, Status: %s", req.OrderID, walletTopupOrder.Status)
-		utils.BadRequest(c, "Payment already completed for this wallet topup order", nil)
+		utils.Fail(c, utils.CodePaymentCompleted, "Payment already completed for this wallet topup order", nil)
 		return
 	}
 
@@ -181,7 +182,8 @@ func VerifyWalletTopup(c *gin.Context) {
 	if generatedSignature != req.RazorpaySignature {
 		utils.LogError("Payment verification failed - Order ID: %d, Razorpay Order ID: %s, Expected: %s, Got: %s",
 			req.OrderID, req.RazorpayOrderID, generatedSignature, req.RazorpaySignature)
-		utils.BadRequest(c, "Payment verification failed", gin.H{"retry": true})
+		utils.RecordPaymentFailed("wallet_topup")
+		utils.Fail(c, utils.CodePaymentFailed, "Payment verification failed", gin.H{"retry": true})
 		return
 	}
 	utils.LogDebug("Successfully verified payment signature for order ID: %d", req.OrderID)
@@ -195,39 +197,25 @@ func VerifyWalletTopup(c *gin.Context) {
 	}
 	utils.LogDebug("Started transaction for order ID: %d", req.OrderID)
 
-	// Get or create wallet
-	wallet, err := utils.GetOrCreateWallet(userID)
-	if err != nil {
-		tx.Rollback()
-		utils.LogError("Failed to get wallet for user ID: %d: %v", userID, err)
-		utils.InternalServerError(c, "Failed to get wallet", err.Error())
-		return
-	}
-	utils.LogDebug("Retrieved wallet for user ID: %d", userID)
-
-	// Create a wallet transaction
+	// Credit the wallet in the same transaction that completes the topup order
 	reference := fmt.Sprintf("TOPUP-%s", req.RazorpayPaymentID)
-	description := "Wallet topup via Razorpay"
-	utils.LogDebug("Creating wallet transaction - Reference: %s, Amount: %.2f", reference, amount)
-
-	transaction, err := utils.CreateWalletTransaction(wallet.ID, amount, models.TransactionTypeCredit, description, nil, reference)
+	utils.LogDebug("Crediting wallet - Reference: %s, Amount: %.2f", reference, amount)
+
+	transaction, err := utils.PostWalletEntry(tx, utils.WalletEntry{
+		UserID:      userID,
+		Amount:      amount,
+		Type:        models.TransactionTypeCredit,
+		Description: "Wallet topup via Razorpay",
+		Reference:   reference,
+	})
 	if err != nil {
 		tx.Rollback()
-		utils.LogError("Failed to create wallet transaction for order ID: %d: %v", req.OrderID, err)
+		utils.LogError("Failed to credit wallet for order ID: %d: %v", req.OrderID, err)
 		utils.InternalServerError(c, "Failed to create transaction", err.Error())
 		return
 	}
 	utils.LogDebug("Created wallet transaction ID: %d", transaction.ID)
 
-	// Update wallet balance
-	if err := utils.UpdateWalletBalance(wallet.ID, amount, models.TransactionTypeCredit); err != nil {
-		tx.Rollback()
-		utils.LogError("Failed to update wallet balance for wallet ID: %d: %v", wallet.ID, err)
-		utils.InternalServerError(c, "Failed to update wallet balance", err.Error())
-		return
-	}
-	utils.LogDebug("Updated wallet balance for wallet ID: %d", wallet.ID)
-
 	// Update wallet topup order status
 	walletTopupOrder.Status = "completed"
 	if err := tx.Save(&walletTopupOrder).Error; err != nil {
@@ -246,32 +234,27 @@ func VerifyWalletTopup(c *gin.Context) {
 	}
 	utils.LogDebug("Successfully committed transaction for order ID: %d", req.OrderID)
 
-	// Get updated wallet
-	updatedWallet, err := utils.GetOrCreateWallet(userID)
-	if err != nil {
-		utils.LogError("Failed to get updated wallet for user ID: %d: %v", userID, err)
-		utils.InternalServerError(c, "Failed to get updated wallet", err.Error())
-		return
-	}
-	utils.LogDebug("Retrieved updated wallet balance: %.2f", updatedWallet.Balance)
+	updatedWallet := transaction.Wallet
+	utils.LogDebug("Updated wallet balance: %.2f", updatedWallet.Balance)
 
 	utils.LogInfo("Successfully completed wallet topup for user ID: %d", userID)
+	formatter := utils.NewResponseFormatter(c)
 	utils.Success(c, "Money added to wallet successfully!", gin.H{
 		"order": gin.H{
 			"id":                  req.OrderID,
 			"razorpay_order_id":   req.RazorpayOrderID,
 			"razorpay_payment_id": req.RazorpayPaymentID,
-			"amount":              fmt.Sprintf("%.2f", amount),
-			"amount_display":      "₹" + fmt.Sprintf("%.2f", amount),
+			"amount":              formatter.Money(amount),
+			"amount_display":      utils.FormatBaseMoney(amount),
 			"status":              "completed",
 			"payment_type":        "wallet_topup",
 		},
 		"wallet": gin.H{
-			"id":               wallet.ID,
-			"balance":          fmt.Sprintf("%.2f", updatedWallet.Balance),
-			"amount_added":     fmt.Sprintf("%.2f", amount),
+			"id":               updatedWallet.ID,
+			"balance":          formatter.Money(updatedWallet.Balance),
+			"amount_added":     formatter.Money(amount),
 			"transaction_id":   transaction.ID,
-			"transaction_date": transaction.CreatedAt.Format("2006-01-02 15:04:05"),
+			"transaction_date": formatter.Timestamp(transaction.CreatedAt),
 			"reference":        reference,
 		},
 		"user": gin.H{
@@ -290,7 +273,7 @@ func SimulateWalletTopupPayment(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -314,7 +297,7 @@ func SimulateWalletTopupPayment(c *gin.Context) {
 	var orderID uint
 	if _, err := fmt.Sscanf(orderIDStr, "%d", &orderID); err != nil {
 		utils.LogError("Invalid order ID format: %s", orderIDStr)
-		utils.BadRequest(c, "Invalid order ID format", nil)
+		utils.Fail(c, utils.CodeInvalidID, "Invalid order ID format", nil)
 		return
 	}
 
@@ -331,7 +314,7 @@ func SimulateWalletTopupPayment(c *gin.Context) {
 	utils.LogInfo("Checking wallet topup order status for ID: %d, current status: %s", orderID, walletTopupOrder.Status)
 	if walletTopupOrder.Status != "pending" {
 		utils.LogError("Wallet topup order ID: %d is not in 'pending' status. Current status: %s", orderID, walletTopupOrder.Status)
-		utils.BadRequest(c, "Payment already completed for this wallet topup order", nil)
+		utils.Fail(c, utils.CodePaymentCompleted, "Payment already completed for this wallet topup order", nil)
 		return
 	}
 	utils.LogInfo("Wallet topup order ID: %d is in 'pending' status, proceeding with payment simulation", orderID)
@@ -340,7 +323,7 @@ func SimulateWalletTopupPayment(c *gin.Context) {
 	razorpayOrderID := walletTopupOrder.RazorpayOrderID
 	if razorpayOrderID == "" {
 		utils.LogError("No Razorpay order ID found for wallet topup order ID: %d", orderID)
-		utils.BadRequest(c, "Payment not initiated for this wallet topup order", nil)
+		utils.Fail(c, utils.CodePaymentNotInitiated, "Payment not initiated for this wallet topup order", nil)
 		return
 	}
 
@@ -1,22 +1,29 @@
 package controllers
 
 import (
-	"fmt"
-	"strconv"
-
-	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/services"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-gonic/gin"
 )
 
+// WalletHandler serves wallet balances and ledgers
+type WalletHandler struct {
+	wallets *services.WalletService
+}
+
+// NewWalletHandler returns a WalletHandler using wallets
+func NewWalletHandler(wallets *services.WalletService) *WalletHandler {
+	return &WalletHandler{wallets: wallets}
+}
+
 // GetWalletBalance returns the user's wallet balance
-func GetWalletBalance(c *gin.Context) {
+func (h *WalletHandler) GetWalletBalance(c *gin.Context) {
 	utils.LogInfo("GetWalletBalance called")
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -28,7 +35,7 @@ func GetWalletBalance(c *gin.Context) {
 	utils.LogInfo("Processing wallet balance request for user ID: %d", user.ID)
 
 	// Get or create wallet
-	wallet, err := getOrCreateWallet(user.ID)
+	wallet, err := h.wallets.Balance(user.ID)
 	if err != nil {
 		utils.LogError("Failed to get wallet for user ID: %d: %v", user.ID, err)
 		utils.InternalServerError(c, "Failed to get wallet", err.Error())
@@ -36,18 +43,22 @@ func GetWalletBalance(c *gin.Context) {
 	}
 	utils.LogInfo("Successfully retrieved wallet balance for user ID: %d", user.ID)
 
+	formatter := utils.NewResponseFormatter(c)
 	utils.Success(c, "Wallet balance retrieved successfully", gin.H{
-		"balance": fmt.Sprintf("%.2f", wallet.Balance),
+		"balance":   formatter.Money(wallet.Balance),
+		"held":      formatter.Money(wallet.Held),
+		"available": formatter.Money(wallet.Balance - wallet.Held),
 	})
 }
 
-// GetWalletTransactions returns the user's wallet transactions
-func GetWalletTransactions(c *gin.Context) {
+// GetWalletTransactions returns the user's wallet transactions, filterable by type, status
+// and date range, with the running balance after each transaction
+func (h *WalletHandler) GetWalletTransactions(c *gin.Context) {
 	utils.LogInfo("GetWalletTransactions called")
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -58,62 +69,29 @@ func GetWalletTransactions(c *gin.Context) {
 	}
 	utils.LogInfo("Processing wallet transactions request for user ID: %d", user.ID)
 
-	// Get or create wallet
-	wallet, err := getOrCreateWallet(user.ID)
-	if err != nil {
-		utils.LogError("Failed to get wallet for user ID: %d: %v", user.ID, err)
-		utils.InternalServerError(c, "Failed to get wallet", err.Error())
-		return
-	}
-
-	// Get pagination params
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 50 {
-		limit = 10
-	}
-	offset := (page - 1) * limit
-	utils.LogDebug("Pagination parameters - Page: %d, Limit: %d, Offset: %d", page, limit, offset)
-
-	// Get transactions
-	var transactions []models.WalletTransaction
-	var total int64
-	if err := config.DB.Model(&models.WalletTransaction{}).Where("wallet_id = ?", wallet.ID).Count(&total).Error; err != nil {
-		utils.LogError("Failed to count transactions for wallet ID: %d: %v", wallet.ID, err)
-		utils.InternalServerError(c, "Failed to count transactions", err.Error())
+	query, ok := parseWalletLedgerQuery(c)
+	if !ok {
 		return
 	}
-	utils.LogDebug("Found %d total transactions for wallet ID: %d", total, wallet.ID)
 
-	if err := config.DB.Where("wallet_id = ?", wallet.ID).Order("created_at DESC").Limit(limit).Offset(offset).Find(&transactions).Error; err != nil {
-		utils.LogError("Failed to get transactions for wallet ID: %d: %v", wallet.ID, err)
-		utils.InternalServerError(c, "Failed to get transactions", err.Error())
+	ledger, err := h.wallets.UserLedger(user.ID, query.Filter)
+	if err != nil {
+		utils.LogError("Failed to get wallet ledger for user ID: %d: %v", user.ID, err)
+		walletLedgerError(c, err)
 		return
 	}
-	utils.LogInfo("Successfully retrieved %d transactions for wallet ID: %d", len(transactions), wallet.ID)
-
-	// Format transaction amounts
-	formattedTransactions := make([]gin.H, len(transactions))
-	for i, txn := range transactions {
-		formattedTransactions[i] = gin.H{
-			"id":          txn.ID,
-			"amount":      fmt.Sprintf("%.2f", txn.Amount),
-			"type":        txn.Type,
-			"description": txn.Description,
-			"reference":   txn.Reference,
-			"created_at":  txn.CreatedAt.Format("2006-01-02 15:04:05"),
-		}
-	}
+	utils.LogInfo("Successfully retrieved %d transactions for wallet ID: %d", len(ledger.Entries), ledger.Wallet.ID)
 
+	formatter := utils.NewResponseFormatter(c)
 	utils.SuccessWithPagination(c, "Wallet transactions retrieved successfully", gin.H{
-		"transactions": formattedTransactions,
+		"transactions": formatLedgerEntries(formatter, ledger.Entries),
+		"filters":      query.Filters,
 		"wallet": gin.H{
-			"balance": fmt.Sprintf("%.2f", wallet.Balance),
+			"balance":   formatter.Money(ledger.Wallet.Balance),
+			"held":      formatter.Money(ledger.Wallet.Held),
+			"available": formatter.Money(ledger.Wallet.Balance - ledger.Wallet.Held),
 		},
-	}, total, page, limit)
+	}, ledger.Total, query.Page, query.Limit)
 }
 
 // ProcessOrderCancellation has been deprecated and merged into CancelOrder
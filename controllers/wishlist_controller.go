@@ -13,7 +13,7 @@ func AddToWishlist(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -30,7 +30,7 @@ func AddToWishlist(c *gin.Context) {
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request format for user ID: %d: %v", userID, err)
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 	utils.LogInfo("Adding book ID: %d to wishlist for user ID: %d", req.BookID, userID)
@@ -49,12 +49,12 @@ func AddToWishlist(c *gin.Context) {
 	book, err := utils.GetBookByIDForCart(req.BookID)
 	if err != nil || book == nil {
 		utils.LogError("Book not found - Book ID: %d for user ID: %d", req.BookID, userID)
-		utils.NotFound(c, "Book not found")
+		utils.Fail(c, utils.CodeBookNotFound, "Book not found", nil)
 		return
 	}
 	if !book.IsActive {
 		utils.LogError("Book not available - Book ID: %d for user ID: %d", req.BookID, userID)
-		utils.BadRequest(c, "Book not available", nil)
+		utils.Fail(c, utils.CodeBookUnavailable, "Book not available", nil)
 		return
 	}
 
@@ -70,8 +70,9 @@ func AddToWishlist(c *gin.Context) {
 	}
 	utils.LogInfo("Successfully added book ID: %d to wishlist for user ID: %d", req.BookID, userID)
 
+	formatter := utils.NewResponseFormatter(c)
 	// Build response (wishlist summary)
-	wishlistItems := getWishlistItems(userID)
+	wishlistItems := getWishlistItems(formatter, userID)
 	utils.Success(c, "Product added to wishlist successfully", gin.H{
 		"wishlist": wishlistItems,
 	})
@@ -83,7 +84,7 @@ func GetWishlist(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -95,7 +96,8 @@ func GetWishlist(c *gin.Context) {
 	userID := user.ID
 	utils.LogInfo("Retrieving wishlist for user ID: %d", userID)
 
-	wishlistItems := getWishlistItems(userID)
+	formatter := utils.NewResponseFormatter(c)
+	wishlistItems := getWishlistItems(formatter, userID)
 	utils.LogInfo("Successfully retrieved %d items from wishlist for user ID: %d", len(wishlistItems), userID)
 	utils.Success(c, "Wishlist retrieved successfully", gin.H{
 		"wishlist": wishlistItems,
@@ -108,7 +110,7 @@ func RemoveFromWishlist(c *gin.Context) {
 	userVal, exists := c.Get("user")
 	if !exists {
 		utils.LogError("User not found in context")
-		utils.Unauthorized(c, "User not found")
+		utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 		return
 	}
 	user, ok := userVal.(models.User)
@@ -125,7 +127,7 @@ func RemoveFromWishlist(c *gin.Context) {
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid request format for user ID: %d: %v", userID, err)
-		utils.BadRequest(c, "Invalid request", err.Error())
+		utils.Fail(c, utils.CodeInvalidRequest, "Invalid request", err)
 		return
 	}
 	utils.LogInfo("Removing book ID: %d from wishlist for user ID: %d", req.BookID, userID)
@@ -144,33 +146,50 @@ func RemoveFromWishlist(c *gin.Context) {
 	}
 	utils.LogInfo("Successfully removed book ID: %d from wishlist for user ID: %d", req.BookID, userID)
 
+	formatter := utils.NewResponseFormatter(c)
 	// Build response (wishlist summary)
-	wishlistItems := getWishlistItems(userID)
+	wishlistItems := getWishlistItems(formatter, userID)
 	utils.Success(c, "Product removed from wishlist successfully", gin.H{
 		"wishlist": wishlistItems,
 	})
 }
 
 // Helper function to get wishlist items
-func getWishlistItems(userID uint) []gin.H {
+func getWishlistItems(f utils.ResponseFormatter, userID uint) []gin.H {
 	utils.LogDebug("Getting wishlist items for user ID: %d", userID)
 	db := config.DB
 	var wishlistItems []models.Wishlist
 	db.Where("user_id = ?", userID).Find(&wishlistItems)
 	utils.LogDebug("Found %d items in wishlist for user ID: %d", len(wishlistItems), userID)
 
+	// Load the books in one query rather than one per item
+	bookIDs := make([]uint, len(wishlistItems))
+	for i, item := range wishlistItems {
+		bookIDs[i] = item.BookID
+	}
+	var books []models.Book
+	if len(bookIDs) > 0 {
+		if err := db.Where("id IN ?", bookIDs).Find(&books).Error; err != nil {
+			utils.LogError("Failed to get book details for user ID: %d: %v", userID, err)
+		}
+	}
+	booksByID := make(map[uint]models.Book, len(books))
+	for _, book := range books {
+		booksByID[book.ID] = book
+	}
+
 	var minimalWishlistItems []gin.H
 	for _, item := range wishlistItems {
-		book, err := utils.GetBookByIDForCart(item.BookID)
-		if err != nil || book == nil {
-			utils.LogError("Failed to get book details - Book ID: %d for user ID: %d: %v", item.BookID, userID, err)
+		book, ok := booksByID[item.BookID]
+		if !ok {
+			utils.LogError("Failed to get book details - Book ID: %d for user ID: %d", item.BookID, userID)
 			continue
 		}
 		minimalWishlistItems = append(minimalWishlistItems, gin.H{
 			"book_id":   book.ID,
 			"name":      book.Name,
 			"image_url": book.ImageURL,
-			"price":     book.Price,
+			"price":     f.Money(book.Price),
 			"stock_status": func() string {
 				if book.Stock < 1 {
 					return "Out of Stock"
@@ -1,13 +1,26 @@
 package main
 
 import (
+	"context"
 	"encoding/gob"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
 
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/controllers"
+	"github.com/Govind-619/ReadSphere/migrations"
+	"github.com/Govind-619/ReadSphere/repositories"
 	"github.com/Govind-619/ReadSphere/routes"
+	"github.com/Govind-619/ReadSphere/services"
 	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/pressly/goose/v3"
 
 )
 
@@ -17,18 +30,27 @@ func main() {
 		log.Fatal("Failed to initialize logger:", err)
 	}
 
+	// "migrate" manages the database schema and exits without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		return
+	}
+
 	// Register types for session serialization
 	gob.Register(controllers.RegistrationData{})
 
 	// Load environment variables
-	_, err := config.LoadConfig()
+	cfg, err := config.LoadConfig()
 	if err != nil {
 		utils.LogError("Error loading config: %v", err)
 		log.Fatal("Error loading config:", err)
 	}
 
-	// Initialize database
+	// Initialize database, logging slow queries
 	config.InitDB()
+	utils.InstrumentDB(config.DB, cfg.SlowQueryThreshold)
 
 	// Create sample admin
 	if err := controllers.CreateSampleAdmin(); err != nil {
@@ -45,21 +67,133 @@ func main() {
 	// Initialize Google OAuth
 	config.InitGoogleOAuth()
 
-	// Set up router
-	router := routes.SetupRouter()
+	// Currencies and the last known exchange rates
+	if err := utils.ConfigureCurrency(cfg.BaseCurrency, cfg.SupportedCurrencies, cfg.ExchangeRateURL); err != nil {
+		utils.LogError("Invalid currency configuration: %v", err)
+		log.Fatal("Invalid currency configuration:", err)
+	}
+	if err := utils.LoadExchangeRates(); err != nil {
+		utils.LogError("Failed to load exchange rates: %v", err)
+	}
+
+	// Cache for the public catalog endpoints
+	utils.ConfigureCatalogCache(cfg.CatalogCacheTTL, cfg.CatalogCacheStore)
+
+	// How long user sessions can be refreshed
+	utils.ConfigureSessions(cfg.SessionLifetime, cfg.RememberMeLifetime)
+
+	// Wire repositories into services, and services into the handlers that use them
+	handlers := &controllers.Handlers{
+		Books:  controllers.NewBookHandler(services.NewBookService(repositories.NewBookRepository(config.DB))),
+		Orders: controllers.NewOrderHandler(services.NewOrderService(repositories.NewOrderRepository(config.DB), utils.CancellationWindow, utils.ReturnWindow)),
+		Wallet: controllers.NewWalletHandler(services.NewWalletService(repositories.NewWalletRepository(config.DB), repositories.NewUserRepository(config.DB))),
+	}
+
+	// Start background jobs: discount/coupon expiry, stale order cleanup, cart expiry, wallet reconciliation, catalog digest
+	utils.ConfigureCartExpiry(cfg.CartTTL, cfg.CartReminderBefore)
+	controllers.RegisterScheduledJobs(cfg, handlers)
+	stopScheduler := utils.StartScheduler()
+
+	// Set up router, with request IDs on every API route
+	router := routes.SetupRouter(handlers, utils.RequestIDMiddleware())
 
 	// Add middleware
 	router.Use(utils.LoggerMiddleware())
 	router.Use(utils.CORSMiddleware())
 	router.Use(utils.RecoveryMiddleware())
-	router.Use(utils.RequestIDMiddleware())
 	router.Use(utils.SecurityHeadersMiddleware())
 
 
-	utils.LogInfo("Server starting on port 8080")
+	server := &http.Server{
+		Addr:         cfg.Address(),
+		Handler:      router,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+
 	// Start server
-	if err := router.Run("0.0.0.0:8080"); err != nil {
-		utils.LogError("Error starting server: %v", err)
-		log.Fatal("Error starting server:", err)
+	go func() {
+		utils.LogInfo("Server starting on %s", cfg.Address())
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			utils.LogError("Error starting server: %v", err)
+			log.Fatal("Error starting server:", err)
+		}
+	}()
+
+	// Wait for an interrupt or SIGTERM, then drain in-flight requests
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	utils.LogInfo("Received %s, shutting down server (timeout %s)", sig, cfg.ShutdownTimeout)
+	controllers.MarkShuttingDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		utils.LogError("Server forced to shut down: %v", err)
+	}
+	stopScheduler()
+
+	if err := config.CloseDB(); err != nil {
+		utils.LogError("Error closing database connection: %v", err)
+	}
+	utils.LogInfo("Server stopped")
+}
+
+// runMigrate implements "migrate [up|down [steps]|status|create <name>]"; up is the default
+func runMigrate(args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	if command == "create" {
+		if len(args) < 2 {
+			return errors.New("usage: migrate create <name>")
+		}
+		return migrations.Create("migrations/sql", args[1])
+	}
+
+	if _, err := config.ConnectDB(); err != nil {
+		return err
+	}
+	defer config.CloseDB()
+
+	switch command {
+	case "up":
+		if err := config.MigrateDB(); err != nil {
+			return err
+		}
+		fmt.Println("Database is up to date")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrations.Down(config.DB, steps)
+		for _, result := range reverted {
+			fmt.Println("Reverted", filepath.Base(result.Source.Path))
+		}
+		return err
+	case "status":
+		statuses, err := migrations.List(config.DB)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.State == goose.StateApplied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-50s %s\n", filepath.Base(s.Source.Path), state)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q (expected up, down, status or create)", command)
 	}
+	return nil
 }
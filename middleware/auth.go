@@ -1,8 +1,8 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
-	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -24,7 +24,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Validate the token
 		if authHeader == "" {
 			utils.LogError("Missing Authorization header")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			utils.Fail(c, utils.CodeAuthRequired, "Unauthorized", nil)
 			c.Abort()
 			return
 		}
@@ -36,7 +36,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		var blacklistedToken models.BlacklistedToken
 		if err := config.DB.Where("token = ? AND expires_at > ?", tokenString, time.Now()).First(&blacklistedToken).Error; err == nil {
 			utils.LogError("Token is blacklisted")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been invalidated, Please login for access"})
+			utils.Fail(c, utils.CodeTokenInvalid, "Token has been invalidated, Please login for access", nil)
 			c.Abort()
 			return
 		}
@@ -51,14 +51,14 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		if err != nil {
 			utils.LogError("Invalid token: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Please login for access"})
+			utils.Fail(c, utils.CodeTokenInvalid, "Please login for access", nil)
 			c.Abort()
 			return
 		}
 
 		if !token.Valid {
 			utils.LogError("Token validation failed")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Please login for access"})
+			utils.Fail(c, utils.CodeTokenInvalid, "Please login for access", nil)
 			c.Abort()
 			return
 		}
@@ -67,7 +67,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
 			utils.LogError("Invalid token claims")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			utils.Fail(c, utils.CodeTokenInvalid, "Invalid token claims", nil)
 			c.Abort()
 			return
 		}
@@ -76,7 +76,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		exp, ok := claims["exp"].(float64)
 		if !ok || float64(time.Now().Unix()) > exp {
 			utils.LogError("Token has expired")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has expired"})
+			utils.Fail(c, utils.CodeTokenExpired, "Token has expired", nil)
 			c.Abort()
 			return
 		}
@@ -88,14 +88,43 @@ func AuthMiddleware() gin.HandlerFunc {
 		var user models.User
 		if err := config.DB.First(&user, userID).Error; err != nil {
 			utils.LogError("User not found: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			utils.Fail(c, utils.CodeAuthRequired, "User not found", nil)
 			c.Abort()
 			return
 		}
 
 		if user.IsBlocked {
 			utils.LogError("Blocked user attempted access: %d", userID)
-			c.JSON(http.StatusForbidden, gin.H{"error": "Account is blocked"})
+			utils.Fail(c, utils.CodeAccountBlocked, "Account is blocked", nil)
+			c.Abort()
+			return
+		}
+
+		if utils.TokenRevoked(claims, &user) {
+			utils.LogError("Token issued before the last password change for user %d", userID)
+			utils.Fail(c, utils.CodeTokenInvalid, "Password was changed, Please login for access", nil)
+			c.Abort()
+			return
+		}
+
+		// Tokens issued for a session stop working when it is revoked or expires
+		sessionID := utils.SessionIDFromClaims(claims)
+		if sessionID != 0 {
+			if err := utils.CheckSession(sessionID, user.ID, c.ClientIP()); err != nil {
+				utils.LogError("Session %d of user %d rejected: %v", sessionID, userID, err)
+				code := utils.CodeTokenInvalid
+				if errors.Is(err, utils.ErrSessionExpired) {
+					code = utils.CodeTokenExpired
+				}
+				utils.Fail(c, code, "Session has been signed out, Please login for access", nil)
+				c.Abort()
+				return
+			}
+		}
+
+		if user.DeletionRequestedAt != nil {
+			utils.LogError("User with pending account deletion attempted access: %d", userID)
+			utils.Fail(c, utils.CodeAccountDeleted, "Account is scheduled for deletion, log in again to restore it", nil)
 			c.Abort()
 			return
 		}
@@ -103,11 +132,71 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Set user in context
 		c.Set("user", user)
 		c.Set("user_id", user.ID)
+		c.Set("session_id", sessionID)
 		utils.LogInfo("User %d authenticated successfully", userID)
 		c.Next()
 	}
 }
 
+// OptionalAuthMiddleware sets the user in the context when the request carries a valid user
+// token, and lets the request through anonymously otherwise. For public routes that
+// personalize their behaviour for logged-in users.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
+		if authHeader == "" || tokenString == authHeader {
+			c.Next()
+			return
+		}
+
+		var blacklistedToken models.BlacklistedToken
+		if err := config.DB.Where("token = ? AND expires_at > ?", tokenString, time.Now()).First(&blacklistedToken).Error; err == nil {
+			c.Next()
+			return
+		}
+
+		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return []byte(os.Getenv("JWT_SECRET")), nil
+		})
+		if err != nil || !token.Valid {
+			utils.LogDebug("Ignoring invalid token on public route: %v", err)
+			c.Next()
+			return
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			c.Next()
+			return
+		}
+		userIDClaim, ok := claims["user_id"].(float64)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		var user models.User
+		if err := config.DB.First(&user, uint(userIDClaim)).Error; err != nil || user.IsBlocked || user.DeletionRequestedAt != nil || utils.TokenRevoked(claims, &user) {
+			c.Next()
+			return
+		}
+		sessionID := utils.SessionIDFromClaims(claims)
+		if sessionID != 0 && utils.CheckSession(sessionID, user.ID, c.ClientIP()) != nil {
+			c.Next()
+			return
+		}
+
+		c.Set("user", user)
+		c.Set("user_id", user.ID)
+		c.Set("session_id", sessionID)
+		c.Next()
+	}
+}
+
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		utils.LogInfo("AdminMiddleware called")
@@ -115,7 +204,7 @@ func AdminMiddleware() gin.HandlerFunc {
 		user, exists := c.Get("user")
 		if !exists {
 			utils.LogError("User not found in context")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+			utils.Fail(c, utils.CodeAuthRequired, "User not found in context", nil)
 			c.Abort()
 			return
 		}
@@ -123,14 +212,14 @@ func AdminMiddleware() gin.HandlerFunc {
 		userModel, ok := user.(models.User)
 		if !ok {
 			utils.LogError("Invalid user type in context")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user type"})
+			utils.Fail(c, utils.CodeInternalError, "Invalid user type", nil)
 			c.Abort()
 			return
 		}
 
 		if !userModel.IsAdmin {
 			utils.LogError("Non-admin user attempted admin access: %d", userModel.ID)
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			utils.Fail(c, utils.CodeAdminRequired, "Admin access required", nil)
 			c.Abort()
 			return
 		}
@@ -147,7 +236,7 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			utils.LogError("Missing Authorization header")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			utils.Fail(c, utils.CodeAuthRequired, "Authorization header is required", nil)
 			c.Abort()
 			return
 		}
@@ -156,7 +245,7 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
 		if tokenString == authHeader {
 			utils.LogError("Invalid Bearer token format")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Please login for access"})
+			utils.Fail(c, utils.CodeTokenInvalid, "Please login for access", nil)
 			c.Abort()
 			return
 		}
@@ -165,7 +254,7 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 		var blacklistedToken models.BlacklistedToken
 		if err := config.DB.Where("token = ? AND expires_at > ?", tokenString, time.Now()).First(&blacklistedToken).Error; err == nil {
 			utils.LogError("Admin token is blacklisted")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been invalidated, Please login for access"})
+			utils.Fail(c, utils.CodeTokenInvalid, "Token has been invalidated, Please login for access", nil)
 			c.Abort()
 			return
 		}
@@ -174,7 +263,7 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 		jwtSecret := os.Getenv("JWT_SECRET")
 		if jwtSecret == "" {
 			utils.LogError("JWT secret not configured")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "JWT secret not configured"})
+			utils.Fail(c, utils.CodeInternalError, "JWT secret not configured", nil)
 			c.Abort()
 			return
 		}
@@ -189,14 +278,14 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 
 		if err != nil {
 			utils.LogError("Invalid admin token: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Please login for access"})
+			utils.Fail(c, utils.CodeTokenInvalid, "Please login for access", nil)
 			c.Abort()
 			return
 		}
 
 		if !token.Valid {
 			utils.LogError("Admin token validation failed")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Please login for access"})
+			utils.Fail(c, utils.CodeTokenInvalid, "Please login for access", nil)
 			c.Abort()
 			return
 		}
@@ -205,7 +294,7 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
 			utils.LogError("Invalid admin token claims")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			utils.Fail(c, utils.CodeTokenInvalid, "Invalid token claims", nil)
 			c.Abort()
 			return
 		}
@@ -214,7 +303,7 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 		adminID, ok := claims["admin_id"].(float64)
 		if !ok {
 			utils.LogError("Admin ID not found in token claims")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Please login for access"})
+			utils.Fail(c, utils.CodeTokenInvalid, "Please login for access", nil)
 			c.Abort()
 			return
 		}
@@ -225,14 +314,14 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 		var admin models.Admin
 		if err := config.DB.First(&admin, uint(adminID)).Error; err != nil {
 			utils.LogError("Admin not found: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+			utils.Fail(c, utils.CodeAuthRequired, "Admin not found", nil)
 			c.Abort()
 			return
 		}
 
 		if !admin.IsActive {
 			utils.LogError("Inactive admin attempted access: %d", admin.ID)
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin account is inactive"})
+			utils.Fail(c, utils.CodeAccountInactive, "Admin account is inactive", nil)
 			c.Abort()
 			return
 		}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/testutil"
+	"github.com/Govind-619/ReadSphere/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthErrorsUseTheResponseEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testutil.NewDB(t, &models.BlacklistedToken{}, &models.User{}, &models.Admin{})
+	tests := []struct {
+		name       string
+		middleware gin.HandlerFunc
+		header     string
+		wantStatus int
+		wantCode   utils.ErrorCode
+	}{
+		{"user without token", AuthMiddleware(), "", http.StatusUnauthorized, utils.CodeAuthRequired},
+		{"user with malformed token", AuthMiddleware(), "Bearer not-a-jwt", http.StatusUnauthorized, utils.CodeTokenInvalid},
+		{"admin without token", AdminAuthMiddleware(), "", http.StatusUnauthorized, utils.CodeAuthRequired},
+		{"admin check without user", AdminMiddleware(), "", http.StatusUnauthorized, utils.CodeAuthRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", "test-secret")
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodGet, "/v1/profile", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("Authorization", tt.header)
+			}
+
+			tt.middleware(c)
+
+			assert.True(t, c.IsAborted())
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+			var body utils.StandardResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			assert.Equal(t, "error", body.Status)
+			assert.Equal(t, tt.wantCode, body.Code)
+			assert.NotEmpty(t, body.Message)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Package migrations versions the database schema with goose. The first migration is a snapshot
+// of the schema; every change after it is a SQL file of its own in sql/.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+	"gorm.io/gorm"
+)
+
+// advisoryLockKey serializes migration runs when several replicas start at once
+const advisoryLockKey = 7340021
+
+// baselineVersion is the version of the schema snapshot every other migration builds on
+const baselineVersion = 1
+
+//go:embed sql/*.sql
+var sqlFiles embed.FS
+
+// newProvider returns a goose provider for the embedded migrations on the database
+func newProvider(db *sql.DB) (*goose.Provider, error) {
+	fsys, err := fs.Sub(sqlFiles, "sql")
+	if err != nil {
+		return nil, err
+	}
+	locker, err := lock.NewPostgresSessionLocker(lock.WithLockID(advisoryLockKey))
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(goose.DialectPostgres, db, fsys,
+		goose.WithSessionLocker(locker),
+		goose.WithDisableGlobalRegistry(true),
+	)
+}
+
+// providerFor returns a goose provider for the embedded migrations on the gorm database
+func providerFor(db *gorm.DB) (*goose.Provider, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return newProvider(sqlDB)
+}
+
+// Up applies every pending migration in version order, each in its own transaction, and
+// returns the ones it applied
+func Up(db *gorm.DB) ([]*goose.MigrationResult, error) {
+	provider, err := providerFor(db)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Up(context.Background())
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns the ones it
+// reverted. It stops at the baseline, which cannot be reverted.
+func Down(db *gorm.DB, steps int) ([]*goose.MigrationResult, error) {
+	provider, err := providerFor(db)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	var reverted []*goose.MigrationResult
+	for len(reverted) < steps {
+		current, err := provider.GetDBVersion(ctx)
+		if err != nil {
+			return reverted, err
+		}
+		if current == baselineVersion {
+			return reverted, errors.New("the baseline schema cannot be reverted")
+		}
+		result, err := provider.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			break
+		}
+		if err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, result)
+	}
+	return reverted, nil
+}
+
+// List returns every known migration and whether it has been applied
+func List(db *gorm.DB) ([]*goose.MigrationStatus, error) {
+	provider, err := providerFor(db)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Status(context.Background())
+}
+
+// Create writes an empty SQL migration into dir, numbered after the newest migration there
+func Create(dir, name string) error {
+	if !regexp.MustCompile(`^[a-z0-9_]+$`).MatchString(name) {
+		return fmt.Errorf("migration name %q must contain only lowercase letters, digits and underscores", name)
+	}
+	goose.SetSequential(true)
+	return goose.Create(nil, dir, name, "sql")
+}
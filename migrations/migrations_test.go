@@ -0,0 +1,111 @@
+package migrations
+
+import (
+	"database/sql"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Govind-619/ReadSphere/models"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+)
+
+// schemaModels are the models whose tables the migrations create
+var schemaModels = []interface{}{
+	&models.User{}, &models.UserBlockEvent{}, &models.Admin{}, &models.Book{}, &models.BookImage{},
+	&models.StockMovement{}, &models.Category{}, &models.Cart{}, &models.SavedItem{},
+	&models.DigitalEntitlement{}, &models.Author{}, &models.Tag{}, &models.Bundle{}, &models.BundleItem{},
+	&models.CartBundle{}, &models.Address{}, &models.Review{}, &models.ReviewImage{}, &models.BannedWord{},
+	&models.ReviewReport{}, &models.PasswordHistory{}, &models.Order{}, &models.OrderItem{},
+	&models.Wishlist{}, &models.StockNotification{}, &models.Announcement{},
+	&models.NotificationPreference{}, &models.Notification{}, &models.BookView{}, &models.HomeSection{},
+	&models.HomeSectionItem{}, &models.Banner{}, &models.Page{}, &models.PageVersion{},
+	&models.SupportTicket{}, &models.SupportTicketMessage{}, &models.OrderComment{},
+	&models.ReplacementShipment{}, &models.OrderShipment{}, &models.Setting{}, &models.Coupon{},
+	&models.UserReferralCode{}, &models.ReferralUsage{}, &models.Referral{}, &models.ReferralSignup{},
+	&models.ReferralSettings{}, &models.ReferralReward{}, &models.UserActiveCoupon{},
+	&models.CouponApplication{}, &models.ProductOffer{}, &models.CategoryOffer{}, &models.OfferRules{},
+	&models.Wallet{}, &models.WalletTransaction{}, &models.WalletTopupOrder{}, &models.WalletMismatch{},
+	&models.GiftCard{}, &models.BlacklistedToken{}, &models.UserSession{}, &models.LoginFailure{},
+	&models.PhoneOTP{}, &models.PhoneOTPSend{}, &models.EmailOTP{}, &models.RateLimitCounter{},
+	&models.Translation{}, &models.ConsentRecord{},
+	&models.BookPriceChange{}, &models.AdminAuditLog{}, &models.PaymentMethodAdjustment{},
+	&models.OrderDispute{}, &models.DisputeEvidence{}, &models.OrderStatusEvent{},
+	&models.DeliveryCharge{}, &models.CODBlockedPincode{}, &models.DeliverySLA{}, &models.DeliverySlot{},
+	&models.AbandonedCart{}, &models.ScheduledJob{}, &models.Invoice{}, &models.InvoiceItem{},
+	&models.InvoiceSequence{}, &models.ExchangeRate{},
+}
+
+func TestMigrationsStartWithBaseline(t *testing.T) {
+	db, err := sql.Open("pgx", "")
+	require.NoError(t, err)
+	defer db.Close()
+	provider, err := newProvider(db)
+	require.NoError(t, err)
+
+	sources := provider.ListSources()
+	require.NotEmpty(t, sources)
+	assert.Equal(t, int64(baselineVersion), sources[0].Version)
+	assert.Equal(t, "00001_baseline_schema.sql", filepath.Base(sources[0].Path))
+	for i, source := range sources {
+		assert.Equal(t, goose.TypeSQL, source.Type, "migrations are plain SQL")
+		assert.Equal(t, int64(i+1), source.Version, "migrations are numbered one after another")
+	}
+}
+
+// createTable matches a table created by a migration and captures its name and definition
+var createTable = regexp.MustCompile(`(?s)CREATE TABLE (?:IF NOT EXISTS )?"?(\w+)"? \((.*?)\n\);`)
+
+// addColumns matches the columns a migration adds to a table
+var addColumns = regexp.MustCompile(`(?s)ALTER TABLE "?(\w+)"?\s+((?:,?\s*ADD COLUMN (?:IF NOT EXISTS )?"?\w+"?[^,;]*)+);`)
+
+func TestMigrationsCreateEveryModelColumn(t *testing.T) {
+	files, err := fs.Glob(sqlFiles, "sql/*.sql")
+	require.NoError(t, err)
+	var all strings.Builder
+	for _, file := range files {
+		body, err := fs.ReadFile(sqlFiles, file)
+		require.NoError(t, err)
+		all.Write(body)
+	}
+
+	columns := make(map[string]map[string]bool)
+	addColumn := func(table, column string) {
+		if columns[table] == nil {
+			columns[table] = make(map[string]bool)
+		}
+		columns[table][column] = true
+	}
+	for _, match := range createTable.FindAllStringSubmatch(all.String(), -1) {
+		for _, line := range strings.Split(match[2], "\n") {
+			if column := regexp.MustCompile(`^\s*"(\w+)" `).FindStringSubmatch(line); column != nil {
+				addColumn(match[1], column[1])
+			}
+		}
+	}
+	for _, match := range addColumns.FindAllStringSubmatch(all.String(), -1) {
+		for _, column := range regexp.MustCompile(`ADD COLUMN (?:IF NOT EXISTS )?"?(\w+)"?`).FindAllStringSubmatch(match[2], -1) {
+			addColumn(match[1], column[1])
+		}
+	}
+
+	cache := &sync.Map{}
+	for _, model := range schemaModels {
+		parsed, err := schema.Parse(model, cache, schema.NamingStrategy{})
+		require.NoError(t, err)
+		require.Contains(t, columns, parsed.Table, "a migration creates the %s table", parsed.Table)
+		for _, field := range parsed.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			assert.True(t, columns[parsed.Table][field.DBName], "a migration creates %s.%s", parsed.Table, field.DBName)
+		}
+	}
+}
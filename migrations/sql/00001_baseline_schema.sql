@@ -0,0 +1,1485 @@
+-- The schema at the time migrations moved to goose: every table, column, index and foreign key
+-- of the models. A database created before migrations were versioned already has the original
+-- tables, so everything is created only if missing, and the columns added to those tables since
+-- are added right after them. Later schema changes go in their own migrations.
+
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS "users" (
+	"id" bigserial,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	"username" text NOT NULL,
+	"email" text NOT NULL,
+	"password" text,
+	"first_name" text,
+	"last_name" text,
+	"phone" text,
+	"profile_image" text,
+	"is_blocked" boolean,
+	"is_verified" boolean DEFAULT false,
+	"is_admin" boolean DEFAULT false,
+	"otp" text,
+	"otp_expiry" timestamptz,
+	"otp_expires_at" timestamptz,
+	"last_login_at" timestamptz,
+	"google_id" text DEFAULT null,
+	"password_changed_at" timestamptz,
+	"phone_verified_at" timestamptz,
+	"password_unset" boolean DEFAULT false,
+	"profile_incomplete" boolean DEFAULT false,
+	"deletion_requested_at" timestamptz,
+	"deletion_scheduled_at" timestamptz,
+	"anonymized_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "uni_users_google_id" UNIQUE ("google_id")
+);
+ALTER TABLE "users"
+	ADD COLUMN IF NOT EXISTS "password_changed_at" timestamptz,
+	ADD COLUMN IF NOT EXISTS "phone_verified_at" timestamptz,
+	ADD COLUMN IF NOT EXISTS "password_unset" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "profile_incomplete" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "deletion_requested_at" timestamptz,
+	ADD COLUMN IF NOT EXISTS "deletion_scheduled_at" timestamptz,
+	ADD COLUMN IF NOT EXISTS "anonymized_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_username" ON "users" ("username");
+
+CREATE TABLE IF NOT EXISTS "user_block_events" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"admin_id" bigint,
+	"blocked" boolean,
+	"reason" text,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_user_block_events_user_id" ON "user_block_events" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "admins" (
+	"id" bigserial,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	"email" text NOT NULL,
+	"password" text,
+	"first_name" text,
+	"last_name" text,
+	"last_login" timestamptz,
+	"is_active" boolean DEFAULT true,
+	"two_factor_enabled" boolean DEFAULT false,
+	"two_factor_secret" text,
+	"two_factor_last_step" bigint,
+	"two_factor_backup_codes" text,
+	"two_factor_failures" bigint,
+	PRIMARY KEY ("id")
+);
+ALTER TABLE "admins"
+	ADD COLUMN IF NOT EXISTS "two_factor_enabled" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "two_factor_secret" text,
+	ADD COLUMN IF NOT EXISTS "two_factor_last_step" bigint,
+	ADD COLUMN IF NOT EXISTS "two_factor_backup_codes" text,
+	ADD COLUMN IF NOT EXISTS "two_factor_failures" bigint;
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_admins_email" ON "admins" ("email");
+CREATE INDEX IF NOT EXISTS "idx_admins_deleted_at" ON "admins" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "genres" (
+	"id" bigserial,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	"name" text,
+	"description" text,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_genres_name" ON "genres" ("name");
+CREATE INDEX IF NOT EXISTS "idx_genres_deleted_at" ON "genres" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "categories" (
+	"id" bigserial,
+	"name" text NOT NULL,
+	"description" text,
+	"blocked" boolean DEFAULT false,
+	"return_window" bigint DEFAULT 7,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_categories_deleted_at" ON "categories" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "books" (
+	"id" bigserial,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	"name" text,
+	"description" text,
+	"price" decimal,
+	"original_price" decimal,
+	"discount_percentage" bigint,
+	"discount_end_date" timestamptz,
+	"stock" bigint,
+	"category_id" bigint,
+	"genre_id" bigint,
+	"image_url" text,
+	"is_active" boolean DEFAULT true,
+	"is_featured" boolean DEFAULT false,
+	"views" bigint DEFAULT 0,
+	"average_rating" decimal DEFAULT 0,
+	"total_reviews" bigint DEFAULT 0,
+	"author" text,
+	"author_id" bigint,
+	"publisher" text,
+	"isbn" text,
+	"publication_year" bigint,
+	"pages" bigint,
+	"language" text DEFAULT 'English',
+	"format" text DEFAULT 'Paperback',
+	"blocked" boolean DEFAULT false,
+	"hsn_code" text,
+	"tax_rate" decimal,
+	"bin_location" text,
+	"preview_key" text,
+	"preview_content_type" text,
+	"preview_size_bytes" bigint,
+	"is_digital" boolean DEFAULT false,
+	"digital_file_key" text,
+	"digital_content_type" text,
+	"digital_size_bytes" bigint,
+	"is_preorder" boolean DEFAULT false,
+	"release_date" timestamptz,
+	"publish_at" timestamptz,
+	"unpublish_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_genres_books" FOREIGN KEY ("genre_id") REFERENCES "genres"("id"),
+	CONSTRAINT "fk_books_category" FOREIGN KEY ("category_id") REFERENCES "categories"("id")
+);
+ALTER TABLE "books"
+	ADD COLUMN IF NOT EXISTS "author_id" bigint,
+	ADD COLUMN IF NOT EXISTS "hsn_code" text,
+	ADD COLUMN IF NOT EXISTS "tax_rate" decimal,
+	ADD COLUMN IF NOT EXISTS "bin_location" text,
+	ADD COLUMN IF NOT EXISTS "preview_key" text,
+	ADD COLUMN IF NOT EXISTS "preview_content_type" text,
+	ADD COLUMN IF NOT EXISTS "preview_size_bytes" bigint,
+	ADD COLUMN IF NOT EXISTS "is_digital" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "digital_file_key" text,
+	ADD COLUMN IF NOT EXISTS "digital_content_type" text,
+	ADD COLUMN IF NOT EXISTS "digital_size_bytes" bigint,
+	ADD COLUMN IF NOT EXISTS "is_preorder" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "release_date" timestamptz,
+	ADD COLUMN IF NOT EXISTS "publish_at" timestamptz,
+	ADD COLUMN IF NOT EXISTS "unpublish_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_books_unpublish_at" ON "books" ("unpublish_at");
+CREATE INDEX IF NOT EXISTS "idx_books_publish_at" ON "books" ("publish_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_books_isbn" ON "books" ("isbn");
+CREATE INDEX IF NOT EXISTS "idx_books_author_id" ON "books" ("author_id");
+CREATE INDEX IF NOT EXISTS "idx_books_deleted_at" ON "books" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "tags" (
+	"id" bigserial,
+	"name" text NOT NULL,
+	"slug" text NOT NULL,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_tags_slug" ON "tags" ("slug");
+
+CREATE TABLE IF NOT EXISTS "book_tags" (
+	"book_id" bigint,
+	"tag_id" bigint,
+	PRIMARY KEY ("book_id","tag_id"),
+	CONSTRAINT "fk_book_tags_book" FOREIGN KEY ("book_id") REFERENCES "books"("id") ON DELETE CASCADE,
+	CONSTRAINT "fk_book_tags_tag" FOREIGN KEY ("tag_id") REFERENCES "tags"("id") ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "book_images" (
+	"id" bigserial,
+	"book_id" bigint,
+	"url" text,
+	"thumbnail_url" text,
+	"storage_key" text,
+	"thumbnail_key" text,
+	"content_type" text,
+	"width" bigint,
+	"height" bigint,
+	"size_bytes" bigint,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_books_book_images" FOREIGN KEY ("book_id") REFERENCES "books"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_book_images_book_id" ON "book_images" ("book_id");
+
+CREATE TABLE IF NOT EXISTS "stock_movements" (
+	"id" bigserial,
+	"book_id" bigint,
+	"delta" bigint NOT NULL,
+	"stock_after" bigint,
+	"source" text NOT NULL,
+	"order_id" bigint,
+	"order_item_id" bigint,
+	"admin_id" bigint,
+	"note" text,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_stock_movements_order_id" ON "stock_movements" ("order_id");
+CREATE INDEX IF NOT EXISTS "idx_stock_movements_source" ON "stock_movements" ("source");
+CREATE INDEX IF NOT EXISTS "idx_stock_movements_book_created" ON "stock_movements" ("book_id","created_at");
+
+CREATE TABLE IF NOT EXISTS "carts" (
+	"id" bigserial,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	"user_id" bigint,
+	"book_id" bigint,
+	"quantity" bigint,
+	"price_at_add" decimal,
+	"stock_status_at_add" text,
+	"expiry_reminder_sent_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_carts_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+	CONSTRAINT "fk_carts_book" FOREIGN KEY ("book_id") REFERENCES "books"("id")
+);
+ALTER TABLE "carts"
+	ADD COLUMN IF NOT EXISTS "price_at_add" decimal,
+	ADD COLUMN IF NOT EXISTS "stock_status_at_add" text,
+	ADD COLUMN IF NOT EXISTS "expiry_reminder_sent_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_carts_deleted_at" ON "carts" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "saved_items" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"book_id" bigint NOT NULL,
+	"quantity" bigint,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_saved_items_book" FOREIGN KEY ("book_id") REFERENCES "books"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_saved_items_user_book" ON "saved_items" ("user_id","book_id");
+
+CREATE TABLE IF NOT EXISTS "digital_entitlements" (
+	"id" bigserial,
+	"user_id" bigint,
+	"book_id" bigint,
+	"order_id" bigint,
+	"downloads" bigint DEFAULT 0,
+	"last_download_at" timestamptz,
+	"revoked_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_digital_entitlements_book" FOREIGN KEY ("book_id") REFERENCES "books"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_digital_entitlements_order_id" ON "digital_entitlements" ("order_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_digital_entitlements_user_book" ON "digital_entitlements" ("user_id","book_id");
+
+CREATE TABLE IF NOT EXISTS "authors" (
+	"id" bigserial,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	"name" text NOT NULL,
+	"bio" text,
+	"photo_url" text,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_authors_deleted_at" ON "authors" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "bundles" (
+	"id" bigserial,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	"name" text NOT NULL,
+	"description" text,
+	"image_url" text,
+	"price" decimal,
+	"is_active" boolean DEFAULT true,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_bundles_deleted_at" ON "bundles" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "bundle_items" (
+	"id" bigserial,
+	"bundle_id" bigint NOT NULL,
+	"book_id" bigint NOT NULL,
+	"quantity" bigint DEFAULT 1,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_bundle_items_book" FOREIGN KEY ("book_id") REFERENCES "books"("id"),
+	CONSTRAINT "fk_bundles_items" FOREIGN KEY ("bundle_id") REFERENCES "bundles"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_bundle_book" ON "bundle_items" ("bundle_id","book_id");
+
+CREATE TABLE IF NOT EXISTS "cart_bundles" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"bundle_id" bigint NOT NULL,
+	"quantity" bigint,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_cart_bundles_bundle" FOREIGN KEY ("bundle_id") REFERENCES "bundles"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_cart_bundle_user_bundle" ON "cart_bundles" ("user_id","bundle_id");
+
+CREATE TABLE IF NOT EXISTS "addresses" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"line1" text,
+	"line2" text,
+	"city" text,
+	"state" text,
+	"country" text,
+	"postal_code" text,
+	"is_default" boolean DEFAULT false,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_users_addresses" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "reviews" (
+	"id" bigserial,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	"book_id" bigint,
+	"user_id" bigint,
+	"rating" bigint,
+	"comment" text,
+	"is_approved" boolean DEFAULT false,
+	"status" text DEFAULT 'pending',
+	"rejection_reason" text,
+	"moderated_by" bigint,
+	"moderated_at" timestamptz,
+	"is_flagged" boolean DEFAULT false,
+	"flagged_words" text,
+	"report_count" bigint DEFAULT 0,
+	"is_hidden" boolean DEFAULT false,
+	"hidden_at" timestamptz,
+	"verified_purchase" boolean DEFAULT false,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_books_reviews" FOREIGN KEY ("book_id") REFERENCES "books"("id"),
+	CONSTRAINT "fk_reviews_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+	CONSTRAINT "chk_reviews_rating" CHECK (rating >= 1 AND rating <= 5)
+);
+CREATE INDEX IF NOT EXISTS "idx_reviews_is_hidden" ON "reviews" ("is_hidden");
+CREATE INDEX IF NOT EXISTS "idx_reviews_is_flagged" ON "reviews" ("is_flagged");
+CREATE INDEX IF NOT EXISTS "idx_reviews_status" ON "reviews" ("status");
+CREATE INDEX IF NOT EXISTS "idx_reviews_deleted_at" ON "reviews" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "review_images" (
+	"id" bigserial,
+	"review_id" bigint,
+	"url" text,
+	"thumbnail_url" text,
+	"storage_key" text,
+	"thumbnail_key" text,
+	"content_type" text,
+	"width" bigint,
+	"height" bigint,
+	"size_bytes" bigint,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_reviews_images" FOREIGN KEY ("review_id") REFERENCES "reviews"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_review_images_review_id" ON "review_images" ("review_id");
+
+CREATE TABLE IF NOT EXISTS "banned_words" (
+	"id" bigserial,
+	"word" text NOT NULL,
+	"added_by" bigint,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_banned_words_word" ON "banned_words" ("word");
+
+CREATE TABLE IF NOT EXISTS "review_reports" (
+	"id" bigserial,
+	"review_id" bigint,
+	"user_id" bigint,
+	"reason" text NOT NULL,
+	"details" text,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_review_reports_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_review_reports_review_user" ON "review_reports" ("review_id","user_id");
+
+CREATE TABLE IF NOT EXISTS "password_histories" (
+	"id" bigserial,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"user_id" bigint NOT NULL,
+	"password" text NOT NULL,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_password_histories_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "orders" (
+	"id" bigserial,
+	"user_id" bigint,
+	"address_id" bigint,
+	"total_amount" decimal,
+	"discount" decimal,
+	"coupon_discount" decimal,
+	"coupon_id" bigint,
+	"coupon_code" text,
+	"final_total" decimal,
+	"delivery_charge" decimal DEFAULT 0,
+	"total_with_delivery" decimal,
+	"payment_adjustment_type" text,
+	"payment_adjustment" decimal,
+	"payment_adjustment_base" decimal,
+	"payment_cashback" decimal,
+	"cashback_credited" boolean DEFAULT false,
+	"payment_method" text,
+	"payment_id" text,
+	"razorpay_order_id" text,
+	"razorpay_payment_id" text,
+	"razorpay_signature" text,
+	"payment_status" text,
+	"payment_attempts" bigint DEFAULT 0,
+	"wallet_amount" decimal DEFAULT 0,
+	"status" text,
+	"buy_now" boolean DEFAULT false,
+	"is_preorder" boolean DEFAULT false,
+	"cancellation_reason" text,
+	"return_reason" text,
+	"return_reject_reason" text,
+	"refund_status" text,
+	"refund_amount" decimal,
+	"refunded_at" timestamptz,
+	"refunded_to_wallet" boolean,
+	"has_item_cancellation_requests" boolean,
+	"has_item_return_requests" boolean,
+	"delivered_at" timestamptz,
+	"delivery_reference" text,
+	"delivery_note" text,
+	"estimated_delivery_from" timestamptz,
+	"estimated_delivery_to" timestamptz,
+	"delivery_slot_id" bigint,
+	"delivery_slot_date" date,
+	"delivery_slot_label" text,
+	"is_gift" boolean DEFAULT false,
+	"gift_wrap" boolean DEFAULT false,
+	"gift_wrap_fee" decimal DEFAULT 0,
+	"gift_message" text,
+	"delivery_refused" boolean DEFAULT false,
+	"delivery_otp" text,
+	"delivery_otp_expires_at" timestamptz,
+	"delivery_otp_attempts" bigint DEFAULT 0,
+	"delivery_otp_verified_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"original_details" json,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_orders_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+	CONSTRAINT "fk_orders_address" FOREIGN KEY ("address_id") REFERENCES "addresses"("id")
+);
+ALTER TABLE "orders"
+	ADD COLUMN IF NOT EXISTS "payment_adjustment_type" text,
+	ADD COLUMN IF NOT EXISTS "payment_adjustment" decimal,
+	ADD COLUMN IF NOT EXISTS "payment_adjustment_base" decimal,
+	ADD COLUMN IF NOT EXISTS "payment_cashback" decimal,
+	ADD COLUMN IF NOT EXISTS "cashback_credited" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "payment_status" text,
+	ADD COLUMN IF NOT EXISTS "payment_attempts" bigint DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS "wallet_amount" decimal DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS "buy_now" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "is_preorder" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "delivered_at" timestamptz,
+	ADD COLUMN IF NOT EXISTS "delivery_reference" text,
+	ADD COLUMN IF NOT EXISTS "delivery_note" text,
+	ADD COLUMN IF NOT EXISTS "estimated_delivery_from" timestamptz,
+	ADD COLUMN IF NOT EXISTS "estimated_delivery_to" timestamptz,
+	ADD COLUMN IF NOT EXISTS "delivery_slot_id" bigint,
+	ADD COLUMN IF NOT EXISTS "delivery_slot_date" date,
+	ADD COLUMN IF NOT EXISTS "delivery_slot_label" text,
+	ADD COLUMN IF NOT EXISTS "is_gift" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "gift_wrap" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "gift_wrap_fee" decimal DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS "gift_message" text,
+	ADD COLUMN IF NOT EXISTS "delivery_refused" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "delivery_otp" text,
+	ADD COLUMN IF NOT EXISTS "delivery_otp_expires_at" timestamptz,
+	ADD COLUMN IF NOT EXISTS "delivery_otp_attempts" bigint DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS "delivery_otp_verified_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_orders_delivery_slot_id" ON "orders" ("delivery_slot_id");
+
+CREATE TABLE IF NOT EXISTS "order_items" (
+	"id" bigserial,
+	"order_id" bigint,
+	"book_id" bigint,
+	"quantity" bigint,
+	"price" decimal,
+	"discount" decimal,
+	"total" decimal,
+	"cancellation_requested" boolean,
+	"cancellation_reason" text,
+	"cancellation_status" text,
+	"return_requested" boolean,
+	"return_reason" text,
+	"return_status" text,
+	"refund_status" text,
+	"refund_amount" decimal,
+	"refunded_at" timestamptz,
+	"stock_restored" boolean DEFAULT false,
+	"coupon_discount" decimal,
+	"exchange_status" text,
+	"exchange_reason" text,
+	"exchange_reject_reason" text,
+	"bundle_id" bigint,
+	"preorder_pending" boolean DEFAULT false,
+	"shipment_id" bigint,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_order_items_book" FOREIGN KEY ("book_id") REFERENCES "books"("id"),
+	CONSTRAINT "fk_orders_order_items" FOREIGN KEY ("order_id") REFERENCES "orders"("id")
+);
+ALTER TABLE "order_items"
+	ADD COLUMN IF NOT EXISTS "exchange_status" text,
+	ADD COLUMN IF NOT EXISTS "exchange_reason" text,
+	ADD COLUMN IF NOT EXISTS "exchange_reject_reason" text,
+	ADD COLUMN IF NOT EXISTS "bundle_id" bigint,
+	ADD COLUMN IF NOT EXISTS "preorder_pending" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "shipment_id" bigint;
+CREATE INDEX IF NOT EXISTS "idx_order_items_shipment_id" ON "order_items" ("shipment_id");
+CREATE INDEX IF NOT EXISTS "idx_order_items_bundle_id" ON "order_items" ("bundle_id");
+
+CREATE TABLE IF NOT EXISTS "wishlists" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"book_id" bigint NOT NULL,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "stock_notifications" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"book_id" bigint NOT NULL,
+	"notified_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_stock_notifications_book" FOREIGN KEY ("book_id") REFERENCES "books"("id"),
+	CONSTRAINT "fk_stock_notifications_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_stock_notifications_notified_at" ON "stock_notifications" ("notified_at");
+CREATE INDEX IF NOT EXISTS "idx_stock_notifications_book_id" ON "stock_notifications" ("book_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_stock_notification_user_book" ON "stock_notifications" ("user_id","book_id");
+
+CREATE TABLE IF NOT EXISTS "announcements" (
+	"id" bigserial,
+	"admin_id" bigint,
+	"type" text NOT NULL,
+	"book_id" bigint,
+	"category_id" bigint,
+	"subject" text NOT NULL,
+	"message" text,
+	"status" text NOT NULL,
+	"recipients" bigint,
+	"sent" bigint,
+	"skipped_opt_out" bigint,
+	"skipped_consent" bigint,
+	"failed" bigint,
+	"created_at" timestamptz,
+	"completed_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_announcements_admin_id" ON "announcements" ("admin_id");
+CREATE INDEX IF NOT EXISTS "idx_announcements_type" ON "announcements" ("type");
+
+CREATE TABLE IF NOT EXISTS "notification_preferences" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"order_updates" boolean DEFAULT true,
+	"wallet" boolean DEFAULT true,
+	"promotions" boolean DEFAULT true,
+	"new_arrivals" boolean DEFAULT true,
+	"price_drops" boolean DEFAULT true,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_notification_preferences_user_id" ON "notification_preferences" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "notifications" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"type" text NOT NULL,
+	"title" text NOT NULL,
+	"message" text,
+	"link" text,
+	"read_at" timestamptz,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_notification_user_read" ON "notifications" ("user_id","read_at");
+
+CREATE TABLE IF NOT EXISTS "book_views" (
+	"id" bigserial,
+	"viewer_key" text NOT NULL,
+	"user_id" bigint,
+	"book_id" bigint NOT NULL,
+	"view_count" bigint DEFAULT 1,
+	"last_viewed_at" timestamptz,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_book_views_book" FOREIGN KEY ("book_id") REFERENCES "books"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_book_views_user_id" ON "book_views" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_book_view_viewer_book" ON "book_views" ("viewer_key","book_id");
+CREATE INDEX IF NOT EXISTS "idx_book_views_last_viewed_at" ON "book_views" ("last_viewed_at");
+
+CREATE TABLE IF NOT EXISTS "home_sections" (
+	"id" bigserial,
+	"title" text NOT NULL,
+	"slug" text NOT NULL,
+	"description" text,
+	"position" bigint DEFAULT 0,
+	"is_active" boolean DEFAULT true,
+	"starts_at" timestamptz,
+	"ends_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_home_sections_slug" ON "home_sections" ("slug");
+
+CREATE TABLE IF NOT EXISTS "home_section_items" (
+	"id" bigserial,
+	"section_id" bigint NOT NULL,
+	"book_id" bigint NOT NULL,
+	"position" bigint,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_home_sections_items" FOREIGN KEY ("section_id") REFERENCES "home_sections"("id") ON DELETE CASCADE,
+	CONSTRAINT "fk_home_section_items_book" FOREIGN KEY ("book_id") REFERENCES "books"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_home_section_book" ON "home_section_items" ("section_id","book_id");
+
+CREATE TABLE IF NOT EXISTS "banners" (
+	"id" bigserial,
+	"title" text NOT NULL,
+	"subtitle" text,
+	"image_url" text NOT NULL,
+	"image_key" text,
+	"link_url" text,
+	"placement" text NOT NULL,
+	"position" bigint DEFAULT 0,
+	"is_active" boolean DEFAULT true,
+	"starts_at" timestamptz,
+	"ends_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_banners_placement" ON "banners" ("placement");
+
+CREATE TABLE IF NOT EXISTS "pages" (
+	"id" bigserial,
+	"slug" text NOT NULL,
+	"title" text NOT NULL,
+	"format" text NOT NULL DEFAULT 'markdown',
+	"body" text,
+	"is_published" boolean DEFAULT false,
+	"version" bigint DEFAULT 1,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_pages_slug" ON "pages" ("slug");
+
+CREATE TABLE IF NOT EXISTS "page_versions" (
+	"id" bigserial,
+	"page_id" bigint NOT NULL,
+	"version" bigint NOT NULL,
+	"title" text,
+	"format" text,
+	"body" text,
+	"admin_id" bigint,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_pages_versions" FOREIGN KEY ("page_id") REFERENCES "pages"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_page_version" ON "page_versions" ("page_id","version");
+
+CREATE TABLE IF NOT EXISTS "support_tickets" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"order_id" bigint,
+	"subject" text NOT NULL,
+	"status" text NOT NULL DEFAULT 'open',
+	"last_message_at" timestamptz,
+	"last_sender" text,
+	"resolved_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_support_tickets_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_support_tickets_created_at" ON "support_tickets" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_support_tickets_last_message_at" ON "support_tickets" ("last_message_at");
+CREATE INDEX IF NOT EXISTS "idx_support_tickets_status" ON "support_tickets" ("status");
+CREATE INDEX IF NOT EXISTS "idx_support_tickets_order_id" ON "support_tickets" ("order_id");
+CREATE INDEX IF NOT EXISTS "idx_support_tickets_user_id" ON "support_tickets" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "support_ticket_messages" (
+	"id" bigserial,
+	"ticket_id" bigint NOT NULL,
+	"sender_type" text NOT NULL,
+	"sender_id" bigint,
+	"sender_name" text,
+	"message" text NOT NULL,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_support_tickets_messages" FOREIGN KEY ("ticket_id") REFERENCES "support_tickets"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_support_ticket_messages_ticket_id" ON "support_ticket_messages" ("ticket_id");
+
+CREATE TABLE IF NOT EXISTS "order_comments" (
+	"id" bigserial,
+	"order_id" bigint NOT NULL,
+	"admin_id" bigint NOT NULL,
+	"admin_email" text,
+	"comment" text NOT NULL,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_order_comments_order_id" ON "order_comments" ("order_id");
+
+CREATE TABLE IF NOT EXISTS "replacement_shipments" (
+	"id" bigserial,
+	"order_id" bigint NOT NULL,
+	"order_item_id" bigint NOT NULL,
+	"book_id" bigint NOT NULL,
+	"quantity" bigint,
+	"status" text,
+	"courier" text,
+	"tracking_number" text,
+	"shipped_at" timestamptz,
+	"delivered_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_replacement_shipments_order_item_id" ON "replacement_shipments" ("order_item_id");
+CREATE INDEX IF NOT EXISTS "idx_replacement_shipments_order_id" ON "replacement_shipments" ("order_id");
+
+CREATE TABLE IF NOT EXISTS "order_shipments" (
+	"id" bigserial,
+	"order_id" bigint NOT NULL,
+	"status" text,
+	"courier" text,
+	"tracking_number" text,
+	"delivery_reference" text,
+	"shipped_at" timestamptz,
+	"delivered_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_order_shipments_order_id" ON "order_shipments" ("order_id");
+
+CREATE TABLE IF NOT EXISTS "settings" (
+	"key" varchar(64),
+	"value" text NOT NULL,
+	"updated_by" bigint,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("key")
+);
+
+CREATE TABLE IF NOT EXISTS "coupons" (
+	"id" bigserial,
+	"code" text,
+	"type" text,
+	"value" decimal,
+	"min_order_value" decimal,
+	"max_discount" decimal,
+	"expiry" timestamptz,
+	"usage_limit" bigint,
+	"used_count" bigint,
+	"active" boolean,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	"first_order_only" boolean DEFAULT false,
+	"user_segment" text,
+	"min_account_age_days" bigint,
+	"per_user_limit" bigint DEFAULT 1,
+	"auto_apply" boolean DEFAULT false,
+	PRIMARY KEY ("id")
+);
+ALTER TABLE "coupons"
+	ADD COLUMN IF NOT EXISTS "first_order_only" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "user_segment" text,
+	ADD COLUMN IF NOT EXISTS "min_account_age_days" bigint,
+	ADD COLUMN IF NOT EXISTS "per_user_limit" bigint DEFAULT 1,
+	ADD COLUMN IF NOT EXISTS "auto_apply" boolean DEFAULT false;
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_coupons_code_lower" ON "coupons" ("code");
+CREATE INDEX IF NOT EXISTS "idx_coupons_deleted_at" ON "coupons" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "coupon_books" (
+	"coupon_id" bigint,
+	"book_id" bigint,
+	PRIMARY KEY ("coupon_id","book_id"),
+	CONSTRAINT "fk_coupon_books_book" FOREIGN KEY ("book_id") REFERENCES "books"("id"),
+	CONSTRAINT "fk_coupon_books_coupon" FOREIGN KEY ("coupon_id") REFERENCES "coupons"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "coupon_categories" (
+	"coupon_id" bigint,
+	"category_id" bigint,
+	PRIMARY KEY ("coupon_id","category_id"),
+	CONSTRAINT "fk_coupon_categories_coupon" FOREIGN KEY ("coupon_id") REFERENCES "coupons"("id"),
+	CONSTRAINT "fk_coupon_categories_category" FOREIGN KEY ("category_id") REFERENCES "categories"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "coupon_users" (
+	"coupon_id" bigint,
+	"user_id" bigint,
+	PRIMARY KEY ("coupon_id","user_id"),
+	CONSTRAINT "fk_coupon_users_coupon" FOREIGN KEY ("coupon_id") REFERENCES "coupons"("id"),
+	CONSTRAINT "fk_coupon_users_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "user_referral_codes" (
+	"id" bigserial,
+	"user_id" bigint,
+	"referral_code" text,
+	"is_active" boolean DEFAULT true,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_user_referral_codes_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_user_referral_codes_deleted_at" ON "user_referral_codes" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_referral_codes_referral_code" ON "user_referral_codes" ("referral_code");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_referral_codes_user_id" ON "user_referral_codes" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "referral_usages" (
+	"id" bigserial,
+	"referrer_id" bigint,
+	"referred_user_id" bigint,
+	"used_at" timestamptz,
+	"referrer_coupon_id" bigint,
+	"referred_coupon_id" bigint,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_referral_usages_referrer_code" FOREIGN KEY ("referrer_id") REFERENCES "user_referral_codes"("id"),
+	CONSTRAINT "fk_referral_usages_referred_user" FOREIGN KEY ("referred_user_id") REFERENCES "users"("id"),
+	CONSTRAINT "fk_referral_usages_referrer_coupon" FOREIGN KEY ("referrer_coupon_id") REFERENCES "coupons"("id"),
+	CONSTRAINT "fk_referral_usages_referred_coupon" FOREIGN KEY ("referred_coupon_id") REFERENCES "coupons"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_referral_usages_deleted_at" ON "referral_usages" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "referrals" (
+	"id" bigserial,
+	"referrer_user_id" bigint,
+	"referred_user_id" bigint,
+	"referral_code" text,
+	"referral_token" text,
+	"coupon_id" bigint,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_referrals_coupon" FOREIGN KEY ("coupon_id") REFERENCES "coupons"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_referrals_deleted_at" ON "referrals" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "referral_signups" (
+	"id" bigserial,
+	"user_id" bigint,
+	"referral_code" text,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_referral_signups_deleted_at" ON "referral_signups" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "referral_settings" (
+	"id" bigserial,
+	"enabled" boolean DEFAULT true,
+	"referrer_coupon_percent" decimal,
+	"referred_coupon_percent" decimal,
+	"coupon_min_order_value" decimal,
+	"coupon_max_discount" decimal,
+	"coupon_validity_days" bigint,
+	"referrer_wallet_credit" decimal,
+	"referred_wallet_credit" decimal,
+	"updated_by" bigint,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "referral_rewards" (
+	"id" bigserial,
+	"referral_usage_id" bigint NOT NULL,
+	"user_id" bigint NOT NULL,
+	"role" text NOT NULL,
+	"reward_type" text NOT NULL,
+	"coupon_id" bigint,
+	"coupon_code" text,
+	"coupon_percent" decimal,
+	"wallet_transaction_id" bigint,
+	"amount" decimal,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_referral_rewards_user_id" ON "referral_rewards" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_referral_rewards_referral_usage_id" ON "referral_rewards" ("referral_usage_id");
+
+CREATE TABLE IF NOT EXISTS "user_active_coupons" (
+	"id" bigserial,
+	"user_id" bigint,
+	"coupon_id" bigint,
+	"code" text,
+	"applied_at" timestamptz,
+	"auto_apply_disabled" boolean DEFAULT false,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_active_coupons_user_id" ON "user_active_coupons" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "coupon_applications" (
+	"id" bigserial,
+	"coupon_id" bigint NOT NULL,
+	"user_id" bigint NOT NULL,
+	"applied_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_coupon_applications_applied_at" ON "coupon_applications" ("applied_at");
+CREATE INDEX IF NOT EXISTS "idx_coupon_applications_user_id" ON "coupon_applications" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_coupon_applications_coupon_id" ON "coupon_applications" ("coupon_id");
+
+CREATE TABLE IF NOT EXISTS "product_offers" (
+	"id" bigserial,
+	"product_id" bigint NOT NULL,
+	"discount_percent" decimal NOT NULL,
+	"start_date" timestamptz NOT NULL,
+	"end_date" timestamptz NOT NULL,
+	"active" boolean DEFAULT true,
+	"exclusive" boolean DEFAULT false,
+	"publish_at" timestamptz,
+	"unpublish_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+ALTER TABLE "product_offers"
+	ADD COLUMN IF NOT EXISTS "exclusive" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "publish_at" timestamptz,
+	ADD COLUMN IF NOT EXISTS "unpublish_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_product_offers_product_id" ON "product_offers" ("product_id");
+CREATE INDEX IF NOT EXISTS "idx_product_offers_unpublish_at" ON "product_offers" ("unpublish_at");
+CREATE INDEX IF NOT EXISTS "idx_product_offers_publish_at" ON "product_offers" ("publish_at");
+
+CREATE TABLE IF NOT EXISTS "category_offers" (
+	"id" bigserial,
+	"category_id" bigint NOT NULL,
+	"discount_percent" decimal NOT NULL,
+	"start_date" timestamptz NOT NULL,
+	"end_date" timestamptz NOT NULL,
+	"active" boolean DEFAULT true,
+	"exclusive" boolean DEFAULT false,
+	"publish_at" timestamptz,
+	"unpublish_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+ALTER TABLE "category_offers"
+	ADD COLUMN IF NOT EXISTS "exclusive" boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS "publish_at" timestamptz,
+	ADD COLUMN IF NOT EXISTS "unpublish_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_category_offers_unpublish_at" ON "category_offers" ("unpublish_at");
+CREATE INDEX IF NOT EXISTS "idx_category_offers_publish_at" ON "category_offers" ("publish_at");
+CREATE INDEX IF NOT EXISTS "idx_category_offers_category_id" ON "category_offers" ("category_id");
+
+CREATE TABLE IF NOT EXISTS "offer_rules" (
+	"id" bigserial,
+	"stacking_policy" text DEFAULT 'stack',
+	"max_discount_percent" decimal DEFAULT 100,
+	"updated_by" bigint,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "wallets" (
+	"id" bigserial,
+	"user_id" bigint,
+	"balance" decimal DEFAULT 0,
+	"held" decimal DEFAULT 0,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_users_wallet" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+ALTER TABLE "wallets"
+	ADD COLUMN IF NOT EXISTS "held" decimal DEFAULT 0;
+CREATE INDEX IF NOT EXISTS "idx_wallets_deleted_at" ON "wallets" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_wallets_user_id" ON "wallets" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "wallet_transactions" (
+	"id" bigserial,
+	"wallet_id" bigint,
+	"amount" decimal,
+	"type" text,
+	"description" text,
+	"order_id" bigint,
+	"reference" text,
+	"status" text,
+	"admin_id" bigint,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_wallet_transactions_wallet" FOREIGN KEY ("wallet_id") REFERENCES "wallets"("id")
+);
+ALTER TABLE "wallet_transactions"
+	ADD COLUMN IF NOT EXISTS "admin_id" bigint;
+CREATE INDEX IF NOT EXISTS "idx_wallet_transactions_deleted_at" ON "wallet_transactions" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_wallet_transactions_admin_id" ON "wallet_transactions" ("admin_id");
+
+CREATE TABLE IF NOT EXISTS "wallet_topup_orders" (
+	"id" bigserial,
+	"user_id" bigint,
+	"razorpay_order_id" text,
+	"amount" decimal,
+	"status" text,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_wallet_topup_orders_razorpay_order_id" ON "wallet_topup_orders" ("razorpay_order_id");
+
+CREATE TABLE IF NOT EXISTS "wallet_mismatches" (
+	"id" bigserial,
+	"wallet_id" bigint,
+	"user_id" bigint,
+	"balance" decimal,
+	"ledger_balance" decimal,
+	"difference" decimal,
+	"first_detected_at" timestamptz,
+	"last_checked_at" timestamptz,
+	"resolved_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_wallet_mismatches_resolved_at" ON "wallet_mismatches" ("resolved_at");
+CREATE INDEX IF NOT EXISTS "idx_wallet_mismatches_user_id" ON "wallet_mismatches" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_wallet_mismatches_wallet_id" ON "wallet_mismatches" ("wallet_id");
+
+CREATE TABLE IF NOT EXISTS "gift_cards" (
+	"id" bigserial,
+	"code" varchar(32),
+	"amount" decimal,
+	"status" text,
+	"purchaser_id" bigint,
+	"issued_by" bigint,
+	"recipient_name" text,
+	"recipient_email" text,
+	"message" text,
+	"payment_method" text,
+	"razorpay_order_id" text,
+	"razorpay_payment_id" text,
+	"expires_at" timestamptz,
+	"redeemed_by" bigint,
+	"redeemed_at" timestamptz,
+	"voided_by" bigint,
+	"voided_at" timestamptz,
+	"void_reason" text,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_gift_cards_redeemed_by" ON "gift_cards" ("redeemed_by");
+CREATE INDEX IF NOT EXISTS "idx_gift_cards_razorpay_order_id" ON "gift_cards" ("razorpay_order_id");
+CREATE INDEX IF NOT EXISTS "idx_gift_cards_purchaser_id" ON "gift_cards" ("purchaser_id");
+CREATE INDEX IF NOT EXISTS "idx_gift_cards_status" ON "gift_cards" ("status");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_gift_cards_code" ON "gift_cards" ("code");
+
+CREATE TABLE IF NOT EXISTS "blacklisted_tokens" (
+	"id" bigserial,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"deleted_at" timestamptz,
+	"token" text NOT NULL,
+	"expires_at" timestamptz NOT NULL,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_blacklisted_tokens_token" ON "blacklisted_tokens" ("token");
+CREATE INDEX IF NOT EXISTS "idx_blacklisted_tokens_deleted_at" ON "blacklisted_tokens" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "user_sessions" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"refresh_token_hash" text NOT NULL,
+	"device_name" text,
+	"user_agent" text,
+	"ip_address" text,
+	"remember_me" boolean DEFAULT false,
+	"last_seen_at" timestamptz,
+	"expires_at" timestamptz,
+	"revoked_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_user_sessions_user_id" ON "user_sessions" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_user_sessions_expires_at" ON "user_sessions" ("expires_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_sessions_refresh_token_hash" ON "user_sessions" ("refresh_token_hash");
+
+CREATE TABLE IF NOT EXISTS "login_failures" (
+	"id" bigserial,
+	"email" text NOT NULL,
+	"count" bigint NOT NULL DEFAULT 0,
+	"last_failed_at" timestamptz NOT NULL,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_login_failures_email" ON "login_failures" ("email");
+
+CREATE TABLE IF NOT EXISTS "phone_otps" (
+	"id" bigserial,
+	"purpose" varchar(20) NOT NULL,
+	"phone" varchar(20) NOT NULL,
+	"user_id" bigint,
+	"code_hash" varchar(64),
+	"expires_at" timestamptz NOT NULL,
+	"sent_at" timestamptz NOT NULL,
+	"sends" bigint NOT NULL DEFAULT 0,
+	"window_started_at" timestamptz NOT NULL,
+	"attempts" bigint NOT NULL DEFAULT 0,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_phone_otps_user_id" ON "phone_otps" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_phone_otps_purpose_phone" ON "phone_otps" ("purpose","phone");
+
+CREATE TABLE IF NOT EXISTS "phone_otp_sends" (
+	"id" bigserial,
+	"ip" varchar(45) NOT NULL,
+	"phone" varchar(20) NOT NULL,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_phone_otp_sends_created_at" ON "phone_otp_sends" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_phone_otp_sends_ip" ON "phone_otp_sends" ("ip");
+
+CREATE TABLE IF NOT EXISTS "translations" (
+	"id" bigserial,
+	"entity_type" text NOT NULL,
+	"entity_id" bigint NOT NULL,
+	"locale" text NOT NULL,
+	"name" text,
+	"description" text,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_translations_entity_locale" ON "translations" ("entity_type","entity_id","locale");
+
+CREATE TABLE IF NOT EXISTS "consent_records" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"purpose" text NOT NULL,
+	"granted" boolean,
+	"policy_version" text NOT NULL,
+	"source" text,
+	"ip_address" text,
+	"user_agent" text,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_consent_records_purpose" ON "consent_records" ("purpose");
+CREATE INDEX IF NOT EXISTS "idx_consent_records_user_id" ON "consent_records" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "catalog_changes" (
+	"id" bigserial,
+	"entity_type" text NOT NULL,
+	"entity_id" bigint,
+	"entity_name" text,
+	"action" text NOT NULL,
+	"admin_id" bigint,
+	"admin_email" text,
+	"changes" text,
+	"is_price_change" boolean DEFAULT false,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_catalog_changes_created_at" ON "catalog_changes" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_catalog_changes_admin_id" ON "catalog_changes" ("admin_id");
+CREATE INDEX IF NOT EXISTS "idx_catalog_changes_entity_id" ON "catalog_changes" ("entity_id");
+CREATE INDEX IF NOT EXISTS "idx_catalog_changes_entity_type" ON "catalog_changes" ("entity_type");
+
+CREATE TABLE IF NOT EXISTS "admin_audit_logs" (
+	"id" bigserial,
+	"admin_id" bigint,
+	"admin_email" text,
+	"method" text NOT NULL,
+	"endpoint" text NOT NULL,
+	"path" text,
+	"entity_type" text,
+	"entity_id" text,
+	"status" bigint,
+	"request" text,
+	"before" text,
+	"after" text,
+	"ip_address" text,
+	"request_id" text,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_admin_audit_logs_created_at" ON "admin_audit_logs" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_admin_audit_entity" ON "admin_audit_logs" ("entity_type","entity_id");
+CREATE INDEX IF NOT EXISTS "idx_admin_audit_logs_endpoint" ON "admin_audit_logs" ("endpoint");
+CREATE INDEX IF NOT EXISTS "idx_admin_audit_logs_admin_id" ON "admin_audit_logs" ("admin_id");
+
+CREATE TABLE IF NOT EXISTS "payment_method_adjustments" (
+	"id" bigserial,
+	"payment_method" text NOT NULL,
+	"adjustment_type" text NOT NULL,
+	"percent" decimal DEFAULT 0,
+	"flat_amount" decimal DEFAULT 0,
+	"max_amount" decimal DEFAULT 0,
+	"min_order_amount" decimal DEFAULT 0,
+	"description" text,
+	"is_active" boolean DEFAULT true,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_payment_method_adjustments_payment_method" ON "payment_method_adjustments" ("payment_method");
+
+CREATE TABLE IF NOT EXISTS "order_disputes" (
+	"id" bigserial,
+	"order_id" bigint NOT NULL,
+	"user_id" bigint,
+	"razorpay_payment_id" text,
+	"razorpay_dispute_id" text,
+	"phase" text,
+	"reason_code" text,
+	"reason" text,
+	"amount" decimal NOT NULL,
+	"amount_deducted" decimal DEFAULT 0,
+	"status" text NOT NULL DEFAULT 'open',
+	"respond_by" timestamptz,
+	"opened_at" timestamptz,
+	"resolved_at" timestamptz,
+	"notes" text,
+	"admin_id" bigint,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_order_disputes_opened_at" ON "order_disputes" ("opened_at");
+CREATE INDEX IF NOT EXISTS "idx_order_disputes_respond_by" ON "order_disputes" ("respond_by");
+CREATE INDEX IF NOT EXISTS "idx_order_disputes_status" ON "order_disputes" ("status");
+CREATE INDEX IF NOT EXISTS "idx_order_disputes_razorpay_dispute_id" ON "order_disputes" ("razorpay_dispute_id");
+CREATE INDEX IF NOT EXISTS "idx_order_disputes_user_id" ON "order_disputes" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_order_disputes_order_id" ON "order_disputes" ("order_id");
+CREATE INDEX IF NOT EXISTS "idx_order_disputes_resolved_at" ON "order_disputes" ("resolved_at");
+
+CREATE TABLE IF NOT EXISTS "dispute_evidences" (
+	"id" bigserial,
+	"dispute_id" bigint NOT NULL,
+	"file_name" text,
+	"storage_key" text,
+	"content_type" text,
+	"size_bytes" bigint,
+	"description" text,
+	"admin_id" bigint,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_order_disputes_evidence" FOREIGN KEY ("dispute_id") REFERENCES "order_disputes"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_dispute_evidences_dispute_id" ON "dispute_evidences" ("dispute_id");
+
+CREATE TABLE IF NOT EXISTS "order_status_events" (
+	"id" bigserial,
+	"order_id" bigint NOT NULL,
+	"status" text NOT NULL,
+	"note" text,
+	"actor_type" text,
+	"actor_id" bigint,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_order_status_events_order_id" ON "order_status_events" ("order_id");
+
+CREATE TABLE IF NOT EXISTS "delivery_charges" (
+	"id" bigserial,
+	"pincode" text NOT NULL,
+	"pincode_from" text,
+	"pincode_to" text,
+	"zone" text,
+	"charge" decimal NOT NULL,
+	"min_order_amount" decimal DEFAULT 0,
+	"free_delivery_above" decimal DEFAULT 0,
+	"cod_available" boolean DEFAULT true,
+	"is_active" boolean DEFAULT true,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_delivery_charges_zone" ON "delivery_charges" ("zone");
+CREATE INDEX IF NOT EXISTS "idx_delivery_charges_pincode_to" ON "delivery_charges" ("pincode_to");
+CREATE INDEX IF NOT EXISTS "idx_delivery_charges_pincode_from" ON "delivery_charges" ("pincode_from");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_delivery_charges_pincode" ON "delivery_charges" ("pincode");
+
+CREATE TABLE IF NOT EXISTS "cod_blocked_pincodes" (
+	"id" bigserial,
+	"pincode" text NOT NULL,
+	"reason" text,
+	"admin_id" bigint,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_cod_blocked_pincodes_pincode" ON "cod_blocked_pincodes" ("pincode");
+
+CREATE TABLE IF NOT EXISTS "delivery_slas" (
+	"id" bigserial,
+	"zone" text NOT NULL,
+	"min_days" bigint NOT NULL,
+	"max_days" bigint NOT NULL,
+	"slots_enabled" boolean DEFAULT false,
+	"is_active" boolean DEFAULT true,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_delivery_slas_zone" ON "delivery_slas" ("zone");
+
+CREATE TABLE IF NOT EXISTS "delivery_slots" (
+	"id" bigserial,
+	"zone" text NOT NULL,
+	"start_time" text NOT NULL,
+	"end_time" text NOT NULL,
+	"capacity" bigint NOT NULL,
+	"is_active" boolean DEFAULT true,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_delivery_slots_zone" ON "delivery_slots" ("zone");
+
+CREATE TABLE IF NOT EXISTS "abandoned_carts" (
+	"id" bigserial,
+	"user_id" bigint NOT NULL,
+	"status" text NOT NULL,
+	"item_count" bigint,
+	"quantity" bigint,
+	"cart_value" decimal,
+	"last_activity_at" timestamptz,
+	"reminders_sent" bigint DEFAULT 0,
+	"last_reminder_at" timestamptz,
+	"recovered_order_id" bigint,
+	"recovered_value" decimal DEFAULT 0,
+	"resolved_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_abandoned_carts_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_abandoned_carts_created_at" ON "abandoned_carts" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_abandoned_carts_status" ON "abandoned_carts" ("status");
+CREATE INDEX IF NOT EXISTS "idx_abandoned_carts_user_id" ON "abandoned_carts" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "scheduled_jobs" (
+	"id" bigserial,
+	"name" text NOT NULL,
+	"description" text,
+	"interval_secs" bigint,
+	"enabled" boolean DEFAULT true,
+	"last_run_at" timestamptz,
+	"last_status" text,
+	"last_message" text,
+	"last_error" text,
+	"last_duration_ms" bigint,
+	"run_count" bigint DEFAULT 0,
+	"fail_count" bigint DEFAULT 0,
+	"next_run_at" timestamptz,
+	"locked_by" text,
+	"locked_until" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_scheduled_jobs_name" ON "scheduled_jobs" ("name");
+
+CREATE TABLE IF NOT EXISTS "invoices" (
+	"id" bigserial,
+	"order_id" bigint NOT NULL,
+	"invoice_number" text NOT NULL,
+	"financial_year" text,
+	"sequence" bigint,
+	"issued_at" timestamptz,
+	"seller_name" text,
+	"seller_gstin" text,
+	"seller_address" text,
+	"seller_state" text,
+	"seller_state_code" text,
+	"place_of_supply" text,
+	"inter_state" boolean,
+	"taxable_amount" decimal,
+	"cgst_amount" decimal,
+	"sgst_amount" decimal,
+	"igst_amount" decimal,
+	"total_tax" decimal,
+	"grand_total" decimal,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_invoices_financial_year" ON "invoices" ("financial_year");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_invoices_invoice_number" ON "invoices" ("invoice_number");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_invoices_order_id" ON "invoices" ("order_id");
+
+CREATE TABLE IF NOT EXISTS "invoice_items" (
+	"id" bigserial,
+	"invoice_id" bigint NOT NULL,
+	"order_item_id" bigint,
+	"description" text,
+	"hsn_code" text,
+	"quantity" bigint,
+	"unit_price" decimal,
+	"discount" decimal,
+	"taxable_value" decimal,
+	"tax_rate" decimal,
+	"cgst_amount" decimal,
+	"sgst_amount" decimal,
+	"igst_amount" decimal,
+	"tax_amount" decimal,
+	"total" decimal,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_invoices_items" FOREIGN KEY ("invoice_id") REFERENCES "invoices"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_invoice_items_invoice_id" ON "invoice_items" ("invoice_id");
+
+CREATE TABLE IF NOT EXISTS "invoice_sequences" (
+	"financial_year" text,
+	"last_number" bigint,
+	PRIMARY KEY ("financial_year")
+);
+
+CREATE TABLE IF NOT EXISTS "exchange_rates" (
+	"currency" varchar(3),
+	"rate" decimal NOT NULL,
+	"source" text,
+	"manual" boolean DEFAULT false,
+	"fetched_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("currency")
+);
@@ -0,0 +1,4 @@
+-- Several editions may share an ISBN, so it is no longer unique
+
+-- +goose Up
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_isbn_key;
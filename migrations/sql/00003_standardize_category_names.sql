@@ -0,0 +1,29 @@
+-- Category names are trimmed, categories whose names differ only in case are merged into the
+-- first one, and names are unique regardless of case
+
+-- +goose Up
+DROP INDEX IF EXISTS idx_categories_name_lower;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_name_key;
+DROP INDEX IF EXISTS idx_categories_name;
+
+UPDATE categories SET name = TRIM(name) WHERE name <> TRIM(name);
+
+-- Move the books of the duplicates to the first category with the name, then drop the duplicates
+UPDATE books SET category_id = duplicates.first_id
+FROM (
+	SELECT id, FIRST_VALUE(id) OVER (PARTITION BY LOWER(name) ORDER BY id) AS first_id
+	FROM categories
+	WHERE deleted_at IS NULL
+) AS duplicates
+WHERE books.category_id = duplicates.id
+	AND duplicates.id <> duplicates.first_id
+	AND books.deleted_at IS NULL;
+
+UPDATE categories SET deleted_at = NOW()
+WHERE deleted_at IS NULL
+	AND EXISTS (
+		SELECT 1 FROM categories AS earlier
+		WHERE earlier.deleted_at IS NULL AND LOWER(earlier.name) = LOWER(categories.name) AND earlier.id < categories.id
+	);
+
+CREATE UNIQUE INDEX idx_categories_name_lower ON categories (LOWER(name)) WHERE deleted_at IS NULL;
@@ -0,0 +1,9 @@
+-- Accounts created before usernames existed get a placeholder username
+
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username text;
+UPDATE users SET username = 'user_' || id::text WHERE username IS NULL;
+ALTER TABLE users ALTER COLUMN username SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE users ALTER COLUMN username DROP NOT NULL;
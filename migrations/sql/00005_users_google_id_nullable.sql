@@ -0,0 +1,6 @@
+-- Only accounts signed up through Google have a Google ID
+
+-- +goose Up
+ALTER TABLE users
+	ALTER COLUMN google_id DROP NOT NULL,
+	ALTER COLUMN google_id SET DEFAULT NULL;
@@ -0,0 +1,10 @@
+-- Coupon codes are stored upper case and unique regardless of case
+
+-- +goose Up
+UPDATE coupons SET code = UPPER(code) WHERE code != UPPER(code);
+DROP INDEX IF EXISTS idx_coupons_code_lower;
+CREATE UNIQUE INDEX idx_coupons_code_lower ON coupons (LOWER(code));
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_coupons_code_lower;
+CREATE UNIQUE INDEX idx_coupons_code_lower ON coupons (code);
@@ -0,0 +1,5 @@
+-- Delivery charges created before range support cover just their own pincode
+
+-- +goose Up
+UPDATE delivery_charges SET pincode_from = pincode, pincode_to = pincode
+	WHERE COALESCE(pincode_from, '') = '' OR COALESCE(pincode_to, '') = '';
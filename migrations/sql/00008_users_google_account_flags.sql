@@ -0,0 +1,11 @@
+-- Accounts created through Google got a generated password, unless it was reset since, and
+-- used the email address as username
+
+-- +goose Up
+UPDATE users SET password_unset = TRUE
+WHERE google_id IS NOT NULL
+	AND NOT EXISTS (SELECT 1 FROM password_histories WHERE password_histories.user_id = users.id);
+
+UPDATE users SET profile_incomplete = TRUE
+WHERE google_id IS NOT NULL
+	AND (username = email OR phone IS NULL OR phone = '');
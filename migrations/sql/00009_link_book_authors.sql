@@ -0,0 +1,21 @@
+-- Every distinct author name on the books becomes an author, treating names that differ only in
+-- case or surrounding spaces as the same author, and the books are linked to them
+
+-- +goose Up
+INSERT INTO authors (name, created_at, updated_at)
+SELECT DISTINCT ON (LOWER(TRIM(author))) TRIM(author), NOW(), NOW()
+FROM books
+WHERE TRIM(author) <> ''
+ORDER BY LOWER(TRIM(author)), author;
+
+UPDATE books SET author_id = authors.id
+FROM authors
+WHERE LOWER(TRIM(books.author)) = LOWER(authors.name) AND authors.deleted_at IS NULL;
+
+CREATE UNIQUE INDEX idx_authors_name_lower ON authors (LOWER(name)) WHERE deleted_at IS NULL;
+
+-- +goose Down
+-- The links and the authors created from them are dropped; the names stay on the books
+DROP INDEX IF EXISTS idx_authors_name_lower;
+UPDATE books SET author_id = NULL;
+DELETE FROM authors;
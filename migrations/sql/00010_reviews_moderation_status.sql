@@ -0,0 +1,4 @@
+-- Reviews approved before moderation statuses existed keep their approval
+
+-- +goose Up
+UPDATE reviews SET status = 'approved' WHERE is_approved = TRUE;
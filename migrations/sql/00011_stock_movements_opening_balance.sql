@@ -0,0 +1,9 @@
+-- Stock held before movements were logged becomes each book's opening balance, so the
+-- movements of every book add up to its stock
+
+-- +goose Up
+INSERT INTO stock_movements (book_id, delta, stock_after, source, note, created_at)
+SELECT id, stock, stock, 'opening_balance', 'Stock before movements were logged', NOW()
+FROM books
+WHERE stock <> 0
+  AND NOT EXISTS (SELECT 1 FROM stock_movements WHERE stock_movements.book_id = books.id);
@@ -0,0 +1,24 @@
+-- Email OTPs are kept on the server as a hash, keyed by flow and address, instead of in the
+-- client's session
+
+-- +goose Up
+CREATE TABLE "email_otps" (
+	"id" bigserial,
+	"purpose" varchar(20) NOT NULL,
+	"email" varchar(255) NOT NULL,
+	"user_id" bigint,
+	"code_hash" varchar(64),
+	"expires_at" timestamptz NOT NULL,
+	"sent_at" timestamptz NOT NULL,
+	"sends" bigint NOT NULL DEFAULT 0,
+	"window_started_at" timestamptz NOT NULL,
+	"attempts" bigint NOT NULL DEFAULT 0,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "idx_email_otps_purpose_email" ON "email_otps" ("purpose", "email");
+CREATE INDEX "idx_email_otps_user_id" ON "email_otps" ("user_id");
+
+-- +goose Down
+DROP TABLE IF EXISTS "email_otps";
@@ -0,0 +1,18 @@
+-- The second admin login step locks for a while after too many wrong codes, and admin logins
+-- are counted per admin and per IP
+
+-- +goose Up
+ALTER TABLE "admins" ADD COLUMN "two_factor_locked_until" timestamptz;
+CREATE TABLE "rate_limit_counters" (
+	"id" bigserial,
+	"key" varchar(255) NOT NULL,
+	"count" bigint NOT NULL DEFAULT 0,
+	"window_started_at" timestamptz NOT NULL,
+	PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "idx_rate_limit_counters_key" ON "rate_limit_counters" ("key");
+CREATE INDEX "idx_rate_limit_counters_window_started_at" ON "rate_limit_counters" ("window_started_at");
+
+-- +goose Down
+DROP TABLE IF EXISTS "rate_limit_counters";
+ALTER TABLE "admins" DROP COLUMN IF EXISTS "two_factor_locked_until";
@@ -0,0 +1,64 @@
+-- The catalog change feed is read from the admin audit log and the book price history instead
+-- of a table of its own. A trigger keeps the price history, so it also holds prices changed by
+-- jobs and bulk updates.
+
+-- +goose Up
+CREATE TABLE "book_price_changes" (
+	"id" bigserial,
+	"book_id" bigint NOT NULL,
+	"old_price" decimal,
+	"new_price" decimal,
+	"old_original_price" decimal,
+	"new_original_price" decimal,
+	"old_discount_percentage" bigint,
+	"new_discount_percentage" bigint,
+	"created_at" timestamptz NOT NULL DEFAULT NOW(),
+	PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_book_price_changes_book_id" ON "book_price_changes" ("book_id");
+CREATE INDEX "idx_book_price_changes_created_at" ON "book_price_changes" ("created_at");
+
+-- +goose StatementBegin
+CREATE FUNCTION record_book_price_change() RETURNS trigger AS $$
+BEGIN
+	INSERT INTO book_price_changes (book_id, old_price, new_price, old_original_price, new_original_price,
+		old_discount_percentage, new_discount_percentage, created_at)
+	VALUES (NEW.id, OLD.price, NEW.price, OLD.original_price, NEW.original_price,
+		OLD.discount_percentage, NEW.discount_percentage, NOW());
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER books_price_history
+AFTER UPDATE OF price, original_price, discount_percentage ON books
+FOR EACH ROW
+WHEN (OLD.price IS DISTINCT FROM NEW.price
+	OR OLD.original_price IS DISTINCT FROM NEW.original_price
+	OR OLD.discount_percentage IS DISTINCT FROM NEW.discount_percentage)
+EXECUTE FUNCTION record_book_price_change();
+
+DROP TABLE IF EXISTS "catalog_changes";
+
+-- +goose Down
+CREATE TABLE IF NOT EXISTS "catalog_changes" (
+	"id" bigserial,
+	"entity_type" text NOT NULL,
+	"entity_id" bigint,
+	"entity_name" text,
+	"action" text NOT NULL,
+	"admin_id" bigint,
+	"admin_email" text,
+	"changes" text,
+	"is_price_change" boolean DEFAULT false,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_catalog_changes_created_at" ON "catalog_changes" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_catalog_changes_admin_id" ON "catalog_changes" ("admin_id");
+CREATE INDEX IF NOT EXISTS "idx_catalog_changes_entity_id" ON "catalog_changes" ("entity_id");
+CREATE INDEX IF NOT EXISTS "idx_catalog_changes_entity_type" ON "catalog_changes" ("entity_type");
+
+DROP TRIGGER IF EXISTS books_price_history ON books;
+DROP FUNCTION IF EXISTS record_book_price_change();
+DROP TABLE IF EXISTS "book_price_changes";
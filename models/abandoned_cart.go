@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Abandoned cart statuses
+const (
+	AbandonedCartStatusOpen      = "open"      // the cart is still waiting, reminders may go out
+	AbandonedCartStatusRecovered = "recovered" // the user ordered after the cart was abandoned
+	AbandonedCartStatusClosed    = "closed"    // the cart was emptied or expired without an order
+)
+
+// AbandonedCart records a cart left untouched with items in it. A user has at most one open
+// record; it follows the cart until the user orders or the cart is gone.
+type AbandonedCart struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	UserID           uint       `json:"user_id" gorm:"index;not null"`
+	User             User       `json:"-"`
+	Status           string     `json:"status" gorm:"index;not null"`
+	ItemCount        int        `json:"item_count"`
+	Quantity         int        `json:"quantity"`
+	CartValue        float64    `json:"cart_value"`       // at the prices the user last saw
+	LastActivityAt   time.Time  `json:"last_activity_at"` // the cart's last change when it was last checked
+	RemindersSent    int        `json:"reminders_sent" gorm:"default:0"`
+	LastReminderAt   *time.Time `json:"last_reminder_at,omitempty"`
+	RecoveredOrderID *uint      `json:"recovered_order_id,omitempty"`
+	RecoveredValue   float64    `json:"recovered_value" gorm:"default:0"`
+	ResolvedAt       *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt        time.Time  `json:"detected_at" gorm:"index"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
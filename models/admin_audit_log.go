@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// AdminAuditLog records one successful write made through the admin API: who made it, the
+// endpoint, the entity it targeted and the entity before and after the change
+type AdminAuditLog struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	AdminID    uint      `json:"admin_id" gorm:"index"`
+	AdminEmail string    `json:"admin_email"`
+	Method     string    `json:"method" gorm:"not null"`
+	Endpoint   string    `json:"endpoint" gorm:"not null;index"` // route template, e.g. /v1/admin/books/:id
+	Path       string    `json:"path"`
+	EntityType string    `json:"entity_type" gorm:"index:idx_admin_audit_entity"` // admin route group, e.g. books
+	EntityID   string    `json:"entity_id" gorm:"index:idx_admin_audit_entity"`
+	Status     int       `json:"status"`
+	Request    string    `json:"request" gorm:"type:text"` // JSON request body, secrets redacted
+	Before     string    `json:"before" gorm:"type:text"`  // JSON snapshot of the entity before the change
+	After      string    `json:"after" gorm:"type:text"`   // JSON snapshot after it, or the response data
+	IPAddress  string    `json:"ip_address"`
+	RequestID  string    `json:"request_id"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Announcement types
+const (
+	AnnouncementTypeNewArrival = "new_arrival"
+	AnnouncementTypePriceDrop  = "price_drop"
+)
+
+// Announcement statuses
+const (
+	AnnouncementStatusSending = "sending"
+	AnnouncementStatusSent    = "sent"
+)
+
+// Announcement is an email an admin broadcast to the users interested in a book or category.
+// The counts are filled in as the emails go out.
+type Announcement struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	AdminID        uint       `json:"admin_id" gorm:"index"`
+	Type           string     `json:"type" gorm:"not null;index"` // new_arrival, price_drop
+	BookID         *uint      `json:"book_id,omitempty"`
+	CategoryID     *uint      `json:"category_id,omitempty"`
+	Subject        string     `json:"subject" gorm:"not null"`
+	Message        string     `json:"message"`
+	Status         string     `json:"status" gorm:"not null"` // sending, sent
+	Recipients     int        `json:"recipients"`
+	Sent           int        `json:"sent"`
+	SkippedOptOut  int        `json:"skipped_opt_out"` // users who turned this alert type off
+	SkippedConsent int        `json:"skipped_consent"` // users without marketing consent
+	Failed         int        `json:"failed"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+// Author is a writer whose books can be browsed together. Books keep the author's name in
+// Book.Author so existing responses and searches are unchanged.
+type Author struct {
+	gorm.Model
+	Name     string `json:"name" gorm:"not null"`
+	Bio      string `json:"bio"`
+	PhotoURL string `json:"photo_url"`
+}
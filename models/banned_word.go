@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// BannedWord is a word or phrase that gets a review flagged for moderation
+type BannedWord struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Word      string    `gorm:"uniqueIndex;not null" json:"word"`
+	AddedBy   uint      `json:"added_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
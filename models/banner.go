@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Banner is an admin-managed promotional image on the storefront, such as a home page hero
+// slide. Banners of a placement are shown in Position order while active and inside their
+// optional StartsAt/EndsAt window.
+type Banner struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	Title     string     `json:"title" gorm:"not null"`
+	Subtitle  string     `json:"subtitle"`
+	ImageURL  string     `json:"image_url" gorm:"not null"`
+	ImageKey  string     `json:"-"` // storage key when the image was uploaded, empty for external URLs
+	LinkURL   string     `json:"link_url"`
+	Placement string     `json:"placement" gorm:"not null;index"` // storefront slot, e.g. home_hero
+	Position  int        `json:"position" gorm:"default:0"`
+	IsActive  bool       `json:"is_active" gorm:"default:true"`
+	StartsAt  *time.Time `json:"starts_at,omitempty"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
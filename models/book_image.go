@@ -1,7 +1,17 @@
 package models
 
+import "time"
+
 type BookImage struct {
-	ID     uint   `gorm:"primaryKey" json:"id"`
-	BookID uint   `gorm:"index" json:"book_id"`
-	URL    string `json:"url"`
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	BookID       uint      `gorm:"index" json:"book_id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	StorageKey   string    `json:"-"` // set for uploaded images, empty for external URLs
+	ThumbnailKey string    `json:"-"`
+	ContentType  string    `json:"content_type,omitempty"`
+	Width        int       `json:"width,omitempty"`
+	Height       int       `json:"height,omitempty"`
+	SizeBytes    int64     `json:"size_bytes,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// BookView tracks when a viewer last looked at a book. Viewers are identified by user ID
+// when logged in and by IP address otherwise, so repeat views can be debounced.
+type BookView struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ViewerKey    string    `json:"-" gorm:"not null;uniqueIndex:idx_book_view_viewer_book"`
+	UserID       *uint     `json:"user_id,omitempty" gorm:"index"`
+	BookID       uint      `json:"book_id" gorm:"not null;uniqueIndex:idx_book_view_viewer_book"`
+	Book         Book      `json:"-" gorm:"foreignKey:BookID"`
+	ViewCount    int       `json:"view_count" gorm:"default:1"`
+	LastViewedAt time.Time `json:"last_viewed_at" gorm:"index"`
+	CreatedAt    time.Time `json:"created_at"`
+}
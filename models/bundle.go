@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Bundle is a box set of books sold together at its own price. Its books are still stocked,
+// ordered and returned one by one: an ordered bundle becomes an order item per book, each
+// carrying its share of the bundle price.
+type Bundle struct {
+	gorm.Model
+	Name        string       `json:"name" gorm:"not null"`
+	Description string       `json:"description"`
+	ImageURL    string       `json:"image_url"`
+	Price       float64      `json:"price"`
+	IsActive    bool         `json:"is_active" gorm:"default:true"`
+	Items       []BundleItem `json:"items,omitempty" gorm:"foreignKey:BundleID;constraint:OnDelete:CASCADE"`
+}
+
+// BundleItem is a book in a bundle and how many copies of it the bundle holds
+type BundleItem struct {
+	ID       uint `json:"id" gorm:"primaryKey"`
+	BundleID uint `json:"bundle_id" gorm:"not null;uniqueIndex:idx_bundle_book"`
+	BookID   uint `json:"book_id" gorm:"not null;uniqueIndex:idx_bundle_book"`
+	Book     Book `json:"-" gorm:"foreignKey:BookID"`
+	Quantity int  `json:"quantity" gorm:"default:1"`
+}
+
+// CartBundle is a bundle in a user's cart
+type CartBundle struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_cart_bundle_user_bundle"`
+	BundleID  uint      `json:"bundle_id" gorm:"not null;uniqueIndex:idx_cart_bundle_user_bundle"`
+	Bundle    Bundle    `json:"-" gorm:"foreignKey:BundleID"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Catalog change entity types
+const (
+	CatalogEntityBook          = "book"
+	CatalogEntityCategory      = "category"
+	CatalogEntityGenre         = "genre"
+	CatalogEntityAuthor        = "author"
+	CatalogEntityTag           = "tag"
+	CatalogEntityBundle        = "bundle"
+	CatalogEntityProductOffer  = "product_offer"
+	CatalogEntityCategoryOffer = "category_offer"
+)
+
+// Catalog change actions
+const (
+	CatalogActionCreate  = "create"
+	CatalogActionUpdate  = "update"
+	CatalogActionDelete  = "delete"
+	CatalogActionRestore = "restore"
+	CatalogActionClone   = "clone"
+)
+
+// BookPriceChange is an entry in a book's price history. A database trigger writes one whenever
+// a book's price, original price or discount changes, whether an admin or a job changed it.
+type BookPriceChange struct {
+	ID                    uint      `gorm:"primaryKey" json:"id"`
+	BookID                uint      `json:"book_id" gorm:"not null;index"`
+	OldPrice              float64   `json:"old_price"`
+	NewPrice              float64   `json:"new_price"`
+	OldOriginalPrice      float64   `json:"old_original_price"`
+	NewOriginalPrice      float64   `json:"new_original_price"`
+	OldDiscountPercentage int       `json:"old_discount_percentage"`
+	NewDiscountPercentage int       `json:"new_discount_percentage"`
+	CreatedAt             time.Time `json:"created_at" gorm:"not null;index"`
+}
@@ -5,12 +5,15 @@ import (
 )
 
 type CategoryOffer struct {
-	ID              uint      `gorm:"primaryKey"`
-	CategoryID      uint      `gorm:"not null;index"`
-	DiscountPercent float64   `gorm:"not null"` // e.g., 10.0 for 10%
-	StartDate       time.Time `gorm:"not null"`
-	EndDate         time.Time `gorm:"not null"`
-	Active          bool      `gorm:"default:true"`
+	ID              uint       `gorm:"primaryKey"`
+	CategoryID      uint       `gorm:"not null;index"`
+	DiscountPercent float64    `gorm:"not null"` // e.g., 10.0 for 10%
+	StartDate       time.Time  `gorm:"not null"`
+	EndDate         time.Time  `gorm:"not null"`
+	Active          bool       `gorm:"default:true"`
+	Exclusive       bool       `gorm:"default:false"` // under the stack policy, never stacks with the other offer type; the larger discount wins
+	PublishAt       *time.Time `gorm:"index"`         // the offer is switched on at this time
+	UnpublishAt     *time.Time `gorm:"index"`         // the offer is switched off at this time
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 }
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// CODBlockedPincode is a pincode where an admin has turned Cash on Delivery off, whatever
+// its delivery charge rule allows
+type CODBlockedPincode struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Pincode   string    `json:"pincode" gorm:"uniqueIndex;not null"`
+	Reason    string    `json:"reason"`
+	AdminID   uint      `json:"admin_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
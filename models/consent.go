@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// Consent purposes
+const (
+	ConsentPurposeAnalytics = "analytics"
+	ConsentPurposeMarketing = "marketing"
+)
+
+// ConsentRecord is an append-only log entry of a user's consent choice for a purpose.
+// The latest record per purpose is the user's current consent state.
+type ConsentRecord struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	UserID        uint      `json:"user_id" gorm:"not null;index"`
+	Purpose       string    `json:"purpose" gorm:"not null;index"` // analytics, marketing
+	Granted       bool      `json:"granted"`
+	PolicyVersion string    `json:"policy_version" gorm:"not null"`
+	Source        string    `json:"source"` // cookie_banner, profile, signup
+	IPAddress     string    `json:"ip_address"`
+	UserAgent     string    `json:"user_agent"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -20,8 +20,30 @@ type Coupon struct {
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Targeting rules. Zero values and empty lists place no restriction, except that a user
+	// may use a coupon once unless PerUserLimit allows more.
+	FirstOrderOnly    bool       `json:"first_order_only" gorm:"default:false"`
+	UserSegment       string     `json:"user_segment"`
+	MinAccountAgeDays int        `json:"min_account_age_days"`
+	PerUserLimit      int        `json:"per_user_limit" gorm:"default:1"`
+	Categories        []Category `json:"categories,omitempty" gorm:"many2many:coupon_categories"`
+	Books             []Book     `json:"books,omitempty" gorm:"many2many:coupon_books"`
+	Users             []User     `json:"-" gorm:"many2many:coupon_users"` // the users of the selected segment
+
+	// Auto-apply coupons are applied to qualifying carts without a coupon code; the one
+	// giving the biggest discount wins
+	AutoApply bool `json:"auto_apply" gorm:"default:false"`
 }
 
+// Coupon user segments
+const (
+	CouponSegmentAll       = ""
+	CouponSegmentReturning = "returning" // users with at least one order that was not cancelled
+	CouponSegmentReferred  = "referred"  // users who signed up with a referral code
+	CouponSegmentSelected  = "selected"  // only the users the coupon lists
+)
+
 type UserCoupon struct {
 	ID       uint      `gorm:"primaryKey" json:"id"`
 	UserID   uint      `json:"user_id"`
@@ -36,4 +58,17 @@ type UserActiveCoupon struct {
 	CouponID  uint      `json:"coupon_id"`
 	Code      string    `json:"code"`
 	AppliedAt time.Time `json:"applied_at"`
+
+	// Set, without a coupon, when the user removed an auto-applied coupon: no coupon is
+	// auto-applied to the cart until the user checks out or applies a coupon
+	AutoApplyDisabled bool `json:"auto_apply_disabled" gorm:"default:false"`
+}
+
+// CouponApplication records a user applying a coupon to their cart, so coupon analytics can
+// compare how often a coupon is tried with how often it ends up in an order
+type CouponApplication struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CouponID  uint      `json:"coupon_id" gorm:"index;not null"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	AppliedAt time.Time `json:"applied_at" gorm:"index"`
 }
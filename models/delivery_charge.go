@@ -4,12 +4,45 @@ import (
 	"time"
 )
 
+// DeliveryCharge is a delivery rule covering an inclusive range of pincodes.
+// Single-pincode rules have PincodeFrom == PincodeTo; Pincode holds the range start.
 type DeliveryCharge struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	Pincode        string    `json:"pincode" gorm:"uniqueIndex;not null"`
-	Charge         float64   `json:"charge" gorm:"not null"`
-	MinOrderAmount float64   `json:"min_order_amount" gorm:"default:0"`
-	IsActive       bool      `json:"is_active" gorm:"default:true"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	Pincode           string    `json:"pincode" gorm:"uniqueIndex;not null"`
+	PincodeFrom       string    `json:"pincode_from" gorm:"index"`
+	PincodeTo         string    `json:"pincode_to" gorm:"index"`
+	Zone              string    `json:"zone" gorm:"index"`
+	Charge            float64   `json:"charge" gorm:"not null"`
+	MinOrderAmount    float64   `json:"min_order_amount" gorm:"default:0"`
+	FreeDeliveryAbove float64   `json:"free_delivery_above" gorm:"default:0"` // 0 disables free delivery
+	CODAvailable      bool      `json:"cod_available" gorm:"default:true"`
+	IsActive          bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// DeliverySLA is the delivery time promised for a zone, in working days after the order is
+// placed. Zones without one use the default delivery time settings.
+type DeliverySLA struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Zone         string    `json:"zone" gorm:"uniqueIndex;not null"`
+	MinDays      int       `json:"min_days" gorm:"not null"`
+	MaxDays      int       `json:"max_days" gorm:"not null"`
+	SlotsEnabled bool      `json:"slots_enabled" gorm:"default:false"` // customers in the zone can pick a delivery slot
+	IsActive     bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// DeliverySlot is a time window of the day deliveries in a zone can be booked for. Capacity
+// caps the orders booked into the window on one day.
+type DeliverySlot struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Zone      string    `json:"zone" gorm:"index;not null"`
+	StartTime string    `json:"start_time" gorm:"not null"` // HH:MM
+	EndTime   string    `json:"end_time" gorm:"not null"`   // HH:MM
+	Capacity  int       `json:"capacity" gorm:"not null"`
+	IsActive  bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
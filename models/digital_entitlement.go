@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// DigitalEntitlement gives a user access to download a digital book they paid for. Buying
+// the same book again reuses the entitlement.
+type DigitalEntitlement struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	UserID         uint       `json:"user_id" gorm:"uniqueIndex:idx_digital_entitlements_user_book"`
+	BookID         uint       `json:"book_id" gorm:"uniqueIndex:idx_digital_entitlements_user_book"`
+	Book           Book       `json:"book" gorm:"foreignKey:BookID"`
+	OrderID        uint       `json:"order_id" gorm:"index"`
+	Downloads      int        `json:"downloads" gorm:"default:0"`
+	LastDownloadAt *time.Time `json:"last_download_at,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"` // set when the order granting it is cancelled
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// EmailOTP is the pending OTP of a registration, password reset or email change, one per flow
+// and address. Only a hash of the code is kept. Sends counts the OTPs emailed to the address
+// since WindowStartedAt, whoever asked for them, so the cooldown and limit cannot be reset by a
+// client.
+type EmailOTP struct {
+	ID              uint      `gorm:"primarykey"`
+	Purpose         string    `gorm:"size:20;not null;uniqueIndex:idx_email_otps_purpose_email"`
+	Email           string    `gorm:"size:255;not null;uniqueIndex:idx_email_otps_purpose_email"`
+	UserID          *uint     `gorm:"index"`   // the signed-in user changing their email; nil otherwise
+	CodeHash        string    `gorm:"size:64"` // empty once the OTP is used or discarded
+	ExpiresAt       time.Time `gorm:"not null"`
+	SentAt          time.Time `gorm:"not null"`
+	Sends           int       `gorm:"not null;default:0"`
+	WindowStartedAt time.Time `gorm:"not null"`
+	Attempts        int       `gorm:"not null;default:0"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// ExchangeRate is how many units of Currency one unit of the base currency buys
+type ExchangeRate struct {
+	Currency  string    `gorm:"primaryKey;size:3" json:"currency"`
+	Rate      float64   `gorm:"not null" json:"rate"`
+	Source    string    `json:"source"`                      // rate feed URL host, or "manual"
+	Manual    bool      `gorm:"default:false" json:"manual"` // manual rates are not overwritten by refreshes
+	FetchedAt time.Time `json:"fetched_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// GiftCard is store credit bought by a user or issued by an admin. Its code is emailed to the
+// recipient, who redeems it into their wallet before it expires.
+type GiftCard struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	Code              string     `gorm:"uniqueIndex;size:32" json:"code"`
+	Amount            float64    `json:"amount"`
+	Status            string     `gorm:"index" json:"status"`
+	PurchaserID       *uint      `gorm:"index" json:"purchaser_id"`
+	IssuedBy          *uint      `json:"issued_by,omitempty"` // admin who issued the card
+	RecipientName     string     `json:"recipient_name"`
+	RecipientEmail    string     `json:"recipient_email"`
+	Message           string     `json:"message"`
+	PaymentMethod     string     `json:"payment_method"` // online, wallet or admin
+	RazorpayOrderID   string     `gorm:"index" json:"razorpay_order_id,omitempty"`
+	RazorpayPaymentID string     `json:"razorpay_payment_id,omitempty"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	RedeemedBy        *uint      `gorm:"index" json:"redeemed_by"`
+	RedeemedAt        *time.Time `json:"redeemed_at"`
+	VoidedBy          *uint      `json:"voided_by,omitempty"`
+	VoidedAt          *time.Time `json:"voided_at,omitempty"`
+	VoidReason        string     `json:"void_reason,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// Gift card statuses
+const (
+	GiftCardStatusPending  = "pending" // awaiting the purchaser's online payment
+	GiftCardStatusActive   = "active"
+	GiftCardStatusRedeemed = "redeemed"
+	GiftCardStatusExpired  = "expired"
+	GiftCardStatusVoided   = "voided"
+)
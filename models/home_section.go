@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// HomeSection is an admin-curated shelf of books on the storefront home page, such as
+// "Featured this week". Sections are shown in Position order while active and inside
+// their optional StartsAt/EndsAt window.
+type HomeSection struct {
+	ID          uint              `json:"id" gorm:"primaryKey"`
+	Title       string            `json:"title" gorm:"not null"`
+	Slug        string            `json:"slug" gorm:"uniqueIndex;not null"`
+	Description string            `json:"description"`
+	Position    int               `json:"position" gorm:"default:0"`
+	IsActive    bool              `json:"is_active" gorm:"default:true"`
+	StartsAt    *time.Time        `json:"starts_at,omitempty"`
+	EndsAt      *time.Time        `json:"ends_at,omitempty"`
+	Items       []HomeSectionItem `json:"items,omitempty" gorm:"foreignKey:SectionID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// HomeSectionItem places a book in a home section at a position
+type HomeSectionItem struct {
+	ID        uint `json:"id" gorm:"primaryKey"`
+	SectionID uint `json:"section_id" gorm:"not null;uniqueIndex:idx_home_section_book"`
+	BookID    uint `json:"book_id" gorm:"not null;uniqueIndex:idx_home_section_book"`
+	Book      Book `json:"-" gorm:"foreignKey:BookID"`
+	Position  int  `json:"position"`
+}
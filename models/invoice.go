@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// Invoice is the tax invoice issued for an order. Seller details and amounts are
+// snapshotted when it is issued, so later catalog or configuration changes do not alter it.
+type Invoice struct {
+	ID              uint          `gorm:"primaryKey" json:"id"`
+	OrderID         uint          `gorm:"uniqueIndex;not null" json:"order_id"`
+	InvoiceNumber   string        `gorm:"uniqueIndex;not null" json:"invoice_number"`
+	FinancialYear   string        `gorm:"index" json:"financial_year"`
+	Sequence        int           `json:"sequence"`
+	IssuedAt        time.Time     `json:"issued_at"`
+	SellerName      string        `json:"seller_name"`
+	SellerGSTIN     string        `json:"seller_gstin"`
+	SellerAddress   string        `json:"seller_address"`
+	SellerState     string        `json:"seller_state"`
+	SellerStateCode string        `json:"seller_state_code"`
+	PlaceOfSupply   string        `json:"place_of_supply"`
+	InterState      bool          `json:"inter_state"`
+	TaxableAmount   float64       `json:"taxable_amount"`
+	CGSTAmount      float64       `json:"cgst_amount"`
+	SGSTAmount      float64       `json:"sgst_amount"`
+	IGSTAmount      float64       `json:"igst_amount"`
+	TotalTax        float64       `json:"total_tax"`
+	GrandTotal      float64       `json:"grand_total"`
+	Items           []InvoiceItem `json:"items" gorm:"foreignKey:InvoiceID"`
+	CreatedAt       time.Time     `json:"created_at"`
+}
+
+// InvoiceItem is one taxed line of an invoice: an ordered book or an order level charge
+type InvoiceItem struct {
+	ID           uint    `gorm:"primaryKey" json:"id"`
+	InvoiceID    uint    `gorm:"index;not null" json:"invoice_id"`
+	OrderItemID  *uint   `json:"order_item_id,omitempty"` // nil for delivery and payment charges
+	Description  string  `json:"description"`
+	HSNCode      string  `json:"hsn_code"`
+	Quantity     int     `json:"quantity"`
+	UnitPrice    float64 `json:"unit_price"`
+	Discount     float64 `json:"discount"`
+	TaxableValue float64 `json:"taxable_value"`
+	TaxRate      float64 `json:"tax_rate"`
+	CGSTAmount   float64 `json:"cgst_amount"`
+	SGSTAmount   float64 `json:"sgst_amount"`
+	IGSTAmount   float64 `json:"igst_amount"`
+	TaxAmount    float64 `json:"tax_amount"`
+	Total        float64 `json:"total"`
+}
+
+// InvoiceSequence holds the last invoice number issued in a financial year
+type InvoiceSequence struct {
+	FinancialYear string `gorm:"primaryKey" json:"financial_year"`
+	LastNumber    int    `json:"last_number"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// LoginFailure counts the failed logins for an email address since the last successful one,
+// whether or not an account has the address, so logins can ask for a CAPTCHA after repeated
+// failures
+type LoginFailure struct {
+	ID           uint      `gorm:"primarykey"`
+	Email        string    `gorm:"uniqueIndex;not null"`
+	Count        int       `gorm:"not null;default:0"`
+	LastFailedAt time.Time `gorm:"not null"`
+}
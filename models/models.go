@@ -26,9 +26,36 @@ type User struct {
 	GoogleID     string    `gorm:"unique;default:null" json:"google_id"`
 	Wallet       Wallet    `json:"wallet,omitempty" gorm:"foreignKey:UserID"`
 
+	// Tokens issued before the last password change are rejected
+	PasswordChangedAt *time.Time `json:"-"`
+
+	// Set once the user confirmed Phone with an SMS OTP; only verified numbers can sign in
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at,omitempty"`
+
+	// Accounts created through Google have a generated password and username until the user
+	// sets a password and completes the profile
+	PasswordUnset     bool `json:"password_unset" gorm:"default:false"`
+	ProfileIncomplete bool `json:"profile_incomplete" gorm:"default:false"`
+
+	// Account deletion: the account is disabled at DeletionRequestedAt and its personal
+	// data is anonymized once DeletionScheduledAt passes, unless the user logs in again first
+	DeletionRequestedAt *time.Time `json:"-"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	AnonymizedAt        *time.Time `json:"-"`
+
 	Addresses []Address `json:"addresses" gorm:"foreignKey:UserID"`
 }
 
+// UserBlockEvent records an admin blocking or unblocking a user
+type UserBlockEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	AdminID   uint      `json:"admin_id"`
+	Blocked   bool      `json:"blocked"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // Admin represents an administrator in the system
 type Admin struct {
 	gorm.Model
@@ -38,6 +65,14 @@ type Admin struct {
 	LastName  string    `json:"last_name"`
 	LastLogin time.Time `json:"last_login"`
 	IsActive  bool      `json:"is_active" gorm:"default:true"`
+
+	// TOTP two-factor authentication. The secret is set on setup and only enforced once enabled.
+	TwoFactorEnabled     bool       `json:"two_factor_enabled" gorm:"default:false"`
+	TwoFactorSecret      string     `json:"-"`
+	TwoFactorLastStep    int64      `json:"-"` // last TOTP time step used, so a code works only once
+	TwoFactorBackupCodes string     `json:"-"` // comma-separated hashes of unused backup codes
+	TwoFactorFailures    int        `json:"-"` // failed codes since the last successful login step
+	TwoFactorLockedUntil *time.Time `json:"-"` // set after too many failed codes; no code is checked before it
 }
 
 // Genre represents a book genre
@@ -64,6 +99,7 @@ type Book struct {
 	Genre              Genre       `json:"genre,omitempty" gorm:"foreignKey:GenreID"`
 	ImageURL           string      `json:"image_url"`
 	BookImages         []BookImage `json:"images" gorm:"foreignKey:BookID"`
+	Tags               []Tag       `json:"tags,omitempty" gorm:"many2many:book_tags;constraint:OnDelete:CASCADE"`
 	IsActive           bool        `json:"is_active" gorm:"default:true"`
 	IsFeatured         bool        `json:"is_featured" gorm:"default:false"`
 	Views              int         `json:"views" gorm:"default:0"`
@@ -71,6 +107,7 @@ type Book struct {
 	AverageRating      float64     `json:"average_rating" gorm:"default:0"`
 	TotalReviews       int         `json:"total_reviews" gorm:"default:0"`
 	Author             string      `json:"author"`
+	AuthorID           *uint       `json:"author_id,omitempty" gorm:"index"`
 	Publisher          string      `json:"publisher"`
 	ISBN               string      `json:"isbn" gorm:"uniqueIndex"`
 	PublicationYear    int         `json:"publication_year"`
@@ -78,8 +115,34 @@ type Book struct {
 	Language           string      `json:"language" gorm:"default:'English'"`
 	Format             string      `json:"format" gorm:"default:'Paperback'"`
 	Blocked            bool        `json:"blocked" gorm:"default:false"`
+	HSNCode            string      `json:"hsn_code,omitempty"`
+	TaxRate            *float64    `json:"tax_rate,omitempty"` // GST rate in percent; nil uses the store default
+	// Warehouse bin the book is picked from, printed on packing slips
+	BinLocation string `json:"bin_location,omitempty"`
+	// The sample chapter readers can preview, kept in media storage
+	PreviewKey         string `json:"-"`
+	PreviewContentType string `json:"preview_content_type,omitempty"`
+	PreviewSizeBytes   int64  `json:"preview_size_bytes,omitempty"`
+	// Digital books are delivered as a downloadable file instead of being shipped
+	IsDigital          bool   `json:"is_digital" gorm:"default:false"`
+	DigitalFileKey     string `json:"-"`
+	DigitalContentType string `json:"digital_content_type,omitempty"`
+	DigitalSizeBytes   int64  `json:"digital_size_bytes,omitempty"`
+	// Pre-order books can be ordered before their release date; stock is allocated on release
+	IsPreorder  bool       `json:"is_preorder" gorm:"default:false"`
+	ReleaseDate *time.Time `json:"release_date,omitempty"`
+	// Scheduled visibility changes, applied by the publish_scheduled job and then cleared
+	PublishAt   *time.Time `json:"publish_at,omitempty" gorm:"index"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty" gorm:"index"`
 }
 
+// Review moderation statuses
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+)
+
 // Review represents a book review
 type Review struct {
 	gorm.Model
@@ -89,6 +152,22 @@ type Review struct {
 	Rating     int    `json:"rating" gorm:"check:rating >= 1 AND rating <= 5"`
 	Comment    string `json:"comment"`
 	IsApproved bool   `json:"is_approved" gorm:"default:false"`
+	// Status is the moderation status; IsApproved stays in step with it for older clients
+	Status          string     `json:"status" gorm:"default:pending;index"`
+	RejectionReason string     `json:"rejection_reason,omitempty"`
+	ModeratedBy     *uint      `json:"moderated_by,omitempty"`
+	ModeratedAt     *time.Time `json:"moderated_at,omitempty"`
+	// IsFlagged marks reviews containing banned words, listed in FlaggedWords, for a closer look
+	IsFlagged    bool   `json:"is_flagged" gorm:"default:false;index"`
+	FlaggedWords string `json:"flagged_words,omitempty"`
+	// ReportCount counts users' abuse reports; IsHidden takes the review off the book page once
+	// they reach the review_report_threshold setting
+	ReportCount int        `json:"report_count" gorm:"default:0"`
+	IsHidden    bool       `json:"is_hidden" gorm:"default:false;index"`
+	HiddenAt    *time.Time `json:"hidden_at,omitempty"`
+	// VerifiedPurchase is set when the reviewer had ordered the book at the time of the review
+	VerifiedPurchase bool          `json:"verified_purchase" gorm:"default:false"`
+	Images           []ReviewImage `json:"images" gorm:"foreignKey:ReviewID"`
 }
 
 type Cart struct {
@@ -98,16 +177,13 @@ type Cart struct {
 	BookID   uint `json:"book_id"`
 	Book     Book `gorm:"foreignKey:BookID" json:"book"`
 	Quantity int  `json:"quantity"`
+	// PriceAtAdd and StockStatusAtAdd are the unit price after offers and the stock status the
+	// user last saw, so the cart can report what changed since
+	PriceAtAdd       float64 `json:"price_at_add"`
+	StockStatusAtAdd string  `json:"stock_status_at_add"`
+	// ExpiryReminderSentAt is set when the owner was warned that the item is about to expire;
+	// a reminder older than UpdatedAt belongs to a previous version of the item
+	ExpiryReminderSentAt *time.Time `json:"-"`
 }
 
 // Order struct moved to order.go. See models/order.go for details.
-
-// UserOTP represents a one-time password for user verification
-type UserOTP struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	UserID    uint      `json:"user_id" gorm:"not null"`
-	Code      string    `json:"code" gorm:"not null"`
-	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-}
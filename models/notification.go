@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Notification types; each can be turned off in the user's notification preferences
+const (
+	NotificationTypeOrder     = "order"
+	NotificationTypeWallet    = "wallet"
+	NotificationTypePromotion = "promotion"
+	NotificationTypeSupport   = "support"
+)
+
+// Notification is a message in the user's in-app notification center
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"-" gorm:"not null;index:idx_notification_user_read"`
+	Type      string     `json:"type" gorm:"not null"` // order, wallet, promotion, support
+	Title     string     `json:"title" gorm:"not null"`
+	Message   string     `json:"message"`
+	Link      string     `json:"link,omitempty"` // API path of what the notification is about
+	ReadAt    *time.Time `json:"read_at,omitempty" gorm:"index:idx_notification_user_read"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationPreference records which notifications and emails a user receives. Users
+// without a record receive every kind. Promotions covers every announcement; NewArrivals and
+// PriceDrops narrow it down.
+type NotificationPreference struct {
+	ID           uint      `json:"-" gorm:"primaryKey"`
+	UserID       uint      `json:"-" gorm:"not null;uniqueIndex"`
+	OrderUpdates bool      `json:"order_updates" gorm:"default:true"`
+	Wallet       bool      `json:"wallet" gorm:"default:true"`
+	Promotions   bool      `json:"promotions" gorm:"default:true"`
+	NewArrivals  bool      `json:"new_arrivals" gorm:"default:true"`
+	PriceDrops   bool      `json:"price_drops" gorm:"default:true"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
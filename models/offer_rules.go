@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// Offer stacking policies
+const (
+	OfferStackingStack     = "stack"     // product and category offers add up unless either is marked exclusive
+	OfferStackingBestOf    = "best_of"   // only the larger of the two offers applies
+	OfferStackingExclusive = "exclusive" // a book's product offer replaces its category offer
+)
+
+// OfferRules configures how product and category offers combine. There is a single row.
+type OfferRules struct {
+	ID                 uint      `gorm:"primaryKey" json:"-"`
+	StackingPolicy     string    `gorm:"default:stack" json:"stacking_policy"`
+	MaxDiscountPercent float64   `gorm:"default:100" json:"max_discount_percent"` // cap on the combined offer percent
+	UpdatedBy          uint      `json:"updated_by"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
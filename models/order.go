@@ -19,6 +19,22 @@ const (
 	OrderStatusReturnCompleted = "Return Completed"
 )
 
+// Order item exchange status constants
+const (
+	ExchangeStatusRequested = "ExchangeRequested"
+	ExchangeStatusApproved  = "ExchangeApproved"
+	ExchangeStatusRejected  = "ExchangeRejected"
+	ExchangeStatusShipped   = "ExchangeShipped"
+	ExchangeStatusDelivered = "ExchangeDelivered"
+)
+
+// Online payment status constants
+const (
+	PaymentStatusPending   = "pending"
+	PaymentStatusFailed    = "failed"
+	PaymentStatusCompleted = "completed"
+)
+
 // Order represents an order in the system
 type Order struct {
 	ID                          uint        `gorm:"primaryKey" json:"id"`
@@ -34,12 +50,22 @@ type Order struct {
 	FinalTotal                  float64     `json:"final_total"`
 	DeliveryCharge              float64     `json:"delivery_charge" gorm:"default:0"`
 	TotalWithDelivery           float64     `json:"total_with_delivery"`
+	PaymentAdjustmentType       string      `json:"payment_adjustment_type,omitempty"`
+	PaymentAdjustment           float64     `json:"payment_adjustment"`      // fee (+) or discount (-) applied for the payment method
+	PaymentAdjustmentBase       float64     `json:"payment_adjustment_base"` // order total the adjustment was calculated on
+	PaymentCashback             float64     `json:"payment_cashback"`        // wallet cashback earned for the payment method
+	CashbackCredited            bool        `json:"cashback_credited" gorm:"default:false"`
 	PaymentMethod               string      `json:"payment_method"`
 	PaymentID                   string      `json:"payment_id"`
 	RazorpayOrderID             string      `json:"razorpay_order_id"`
 	RazorpayPaymentID           string      `json:"razorpay_payment_id"`
 	RazorpaySignature           string      `json:"razorpay_signature"`
+	PaymentStatus               string      `json:"payment_status,omitempty"` // pending, failed, completed (online payments)
+	PaymentAttempts             int         `json:"payment_attempts" gorm:"default:0"`
+	WalletAmount                float64     `json:"wallet_amount" gorm:"default:0"` // part of an online order's total paid from the wallet
 	Status                      string      `json:"status"`
+	BuyNow                      bool        `json:"buy_now" gorm:"default:false"`     // placed with buy-now, not from the cart
+	IsPreorder                  bool        `json:"is_preorder" gorm:"default:false"` // holds books ordered before their release
 	CancellationReason          string      `json:"cancellation_reason,omitempty"`
 	ReturnReason                string      `json:"return_reason,omitempty"`
 	ReturnRejectReason          string      `json:"return_reject_reason,omitempty"`
@@ -49,6 +75,23 @@ type Order struct {
 	RefundedToWallet            bool        `json:"refunded_to_wallet,omitempty"`
 	HasItemCancellationRequests bool        `json:"has_item_cancellation_requests,omitempty"`
 	HasItemReturnRequests       bool        `json:"has_item_return_requests,omitempty"`
+	DeliveredAt                 *time.Time  `json:"delivered_at,omitempty"`
+	DeliveryReference           string      `json:"delivery_reference,omitempty"` // OTP or signature reference captured on delivery
+	DeliveryNote                string      `json:"delivery_note,omitempty"`      // instructions for the courier given at checkout
+	EstimatedDeliveryFrom       *time.Time  `json:"estimated_delivery_from,omitempty"`
+	EstimatedDeliveryTo         *time.Time  `json:"estimated_delivery_to,omitempty"`
+	DeliverySlotID              *uint       `json:"delivery_slot_id,omitempty" gorm:"index"` // slot the customer booked at checkout
+	DeliverySlotDate            *time.Time  `json:"delivery_slot_date,omitempty" gorm:"type:date"`
+	DeliverySlotLabel           string      `json:"delivery_slot_label,omitempty"` // the slot's window, kept if the slot changes later
+	IsGift                      bool        `json:"is_gift" gorm:"default:false"`
+	GiftWrap                    bool        `json:"gift_wrap" gorm:"default:false"`
+	GiftWrapFee                 float64     `json:"gift_wrap_fee" gorm:"default:0"`
+	GiftMessage                 string      `json:"gift_message,omitempty"`
+	DeliveryRefused             bool        `json:"delivery_refused,omitempty" gorm:"default:false"` // the customer refused the Cash on Delivery parcel
+	DeliveryOTP                 string      `json:"-"`
+	DeliveryOTPExpiresAt        *time.Time  `json:"-"`
+	DeliveryOTPAttempts         int         `json:"-" gorm:"default:0"`
+	DeliveryOTPVerifiedAt       *time.Time  `json:"delivery_otp_verified_at,omitempty"`
 	CreatedAt                   time.Time   `json:"created_at"`
 	UpdatedAt                   time.Time   `json:"updated_at"`
 	OrderItems                  []OrderItem `json:"items" gorm:"foreignKey:OrderID"`
@@ -75,4 +118,72 @@ type OrderItem struct {
 	RefundedAt            *time.Time `json:"refunded_at"`
 	StockRestored         bool       `json:"stock_restored" gorm:"default:false"`
 	CouponDiscount        float64    `json:"coupon_discount"`
+	ExchangeStatus        string     `json:"exchange_status,omitempty"`
+	ExchangeReason        string     `json:"exchange_reason,omitempty"`
+	ExchangeRejectReason  string     `json:"exchange_reject_reason,omitempty"`
+	BundleID              *uint      `json:"bundle_id,omitempty" gorm:"index"`      // set when the book was bought as part of a bundle
+	PreorderPending       bool       `json:"preorder_pending" gorm:"default:false"` // pre-ordered and waiting for stock on release
+	ShipmentID            *uint      `json:"shipment_id,omitempty" gorm:"index"`    // box the item ships in, when the order ships in several
+}
+
+// OrderStatusEvent records a status change of an order for its tracking timeline
+type OrderStatusEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	OrderID   uint      `json:"order_id" gorm:"index;not null"`
+	Status    string    `json:"status" gorm:"not null"`
+	Note      string    `json:"note,omitempty"`
+	ActorType string    `json:"actor_type"` // admin, user, system
+	ActorID   uint      `json:"actor_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrderComment is an internal note an admin adds to an order. Comments are only shown to admins.
+type OrderComment struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	OrderID    uint      `json:"order_id" gorm:"index;not null"`
+	AdminID    uint      `json:"admin_id" gorm:"not null"`
+	AdminEmail string    `json:"admin_email"`
+	Comment    string    `json:"comment" gorm:"type:text;not null"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Shipment status constants. A shipment only moves forward through them.
+const (
+	ShipmentStatusPending        = "Pending"
+	ShipmentStatusShipped        = "Shipped"
+	ShipmentStatusOutForDelivery = "Out for Delivery"
+	ShipmentStatusDelivered      = "Delivered"
+)
+
+// OrderShipment is one box of an order whose items ship separately. Each shipment has its own
+// status; the order moves to Shipped, Out for Delivery or Delivered once all its items have.
+type OrderShipment struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	OrderID           uint       `json:"order_id" gorm:"index;not null"`
+	Status            string     `json:"status"`
+	Courier           string     `json:"courier,omitempty"`
+	TrackingNumber    string     `json:"tracking_number,omitempty"`
+	DeliveryReference string     `json:"delivery_reference,omitempty"`
+	ShippedAt         *time.Time `json:"shipped_at,omitempty"`
+	DeliveredAt       *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// ReplacementShipment is the shipment of a replacement for an exchanged order item. Its stock
+// is reserved when the exchange is approved.
+type ReplacementShipment struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	OrderID        uint       `json:"order_id" gorm:"index;not null"`
+	OrderItemID    uint       `json:"order_item_id" gorm:"uniqueIndex;not null"`
+	BookID         uint       `json:"book_id" gorm:"not null"`
+	Quantity       int        `json:"quantity"`
+	Status         string     `json:"status"` // one of the exchange statuses from ExchangeApproved on
+	Courier        string     `json:"courier,omitempty"`
+	TrackingNumber string     `json:"tracking_number,omitempty"`
+	ShippedAt      *time.Time `json:"shipped_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Dispute statuses, as Razorpay reports them. A dispute is resolved once it is won, lost or
+// closed.
+const (
+	DisputeStatusOpen        = "open"
+	DisputeStatusUnderReview = "under_review"
+	DisputeStatusWon         = "won"
+	DisputeStatusLost        = "lost"
+	DisputeStatusClosed      = "closed"
+)
+
+// OrderDispute is a chargeback or other dispute a customer raised with their bank against the
+// Razorpay payment of an order. Amount is what the customer disputes; AmountDeducted is what
+// Razorpay held back from settlements while the dispute runs.
+type OrderDispute struct {
+	ID                uint              `json:"id" gorm:"primaryKey"`
+	OrderID           uint              `json:"order_id" gorm:"not null;index"`
+	UserID            uint              `json:"user_id" gorm:"index"`
+	RazorpayPaymentID string            `json:"razorpay_payment_id"`
+	RazorpayDisputeID string            `json:"razorpay_dispute_id" gorm:"index"`
+	Phase             string            `json:"phase"` // chargeback, pre_arbitration, arbitration, retrieval, fraud
+	ReasonCode        string            `json:"reason_code"`
+	Reason            string            `json:"reason" gorm:"type:text"`
+	Amount            float64           `json:"amount" gorm:"not null"`
+	AmountDeducted    float64           `json:"amount_deducted" gorm:"default:0"`
+	Status            string            `json:"status" gorm:"not null;default:'open';index"`
+	RespondBy         *time.Time        `json:"respond_by,omitempty" gorm:"index"` // deadline to submit evidence
+	OpenedAt          time.Time         `json:"opened_at" gorm:"index"`
+	ResolvedAt        *time.Time        `json:"resolved_at,omitempty" gorm:"index"`
+	Notes             string            `json:"notes" gorm:"type:text"`
+	AdminID           uint              `json:"admin_id"` // admin who recorded the dispute
+	Evidence          []DisputeEvidence `json:"evidence,omitempty" gorm:"foreignKey:DisputeID;constraint:OnDelete:CASCADE"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+}
+
+// DisputeEvidence is a file submitted, or to be submitted, in answer to a dispute
+type DisputeEvidence struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	DisputeID   uint      `json:"dispute_id" gorm:"not null;index"`
+	FileName    string    `json:"file_name"`
+	StorageKey  string    `json:"-"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	Description string    `json:"description"`
+	AdminID     uint      `json:"admin_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
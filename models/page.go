@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Page is an admin-edited storefront content page, such as About, Returns Policy or FAQ.
+// Body is Markdown or HTML as Format says; the storefront renders it. Every change to the
+// content is kept as a PageVersion, and Version is the number of the current one.
+type Page struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	Slug        string        `json:"slug" gorm:"uniqueIndex;not null"`
+	Title       string        `json:"title" gorm:"not null"`
+	Format      string        `json:"format" gorm:"not null;default:'markdown'"` // markdown or html
+	Body        string        `json:"body" gorm:"type:text"`
+	IsPublished bool          `json:"is_published" gorm:"default:false"`
+	Version     int           `json:"version" gorm:"default:1"`
+	Versions    []PageVersion `json:"versions,omitempty" gorm:"foreignKey:PageID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// PageVersion is the content of a page as it was saved by an admin
+type PageVersion struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	PageID    uint      `json:"page_id" gorm:"not null;uniqueIndex:idx_page_version"`
+	Version   int       `json:"version" gorm:"not null;uniqueIndex:idx_page_version"`
+	Title     string    `json:"title"`
+	Format    string    `json:"format"`
+	Body      string    `json:"body" gorm:"type:text"`
+	AdminID   uint      `json:"admin_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
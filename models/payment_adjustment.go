@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Payment adjustment types
+const (
+	PaymentAdjustmentFee      = "fee"      // added to the order total, e.g. a COD handling fee
+	PaymentAdjustmentDiscount = "discount" // taken off the order total, e.g. a prepaid discount
+	PaymentAdjustmentCashback = "cashback" // credited to the wallet once the order is paid
+)
+
+// PaymentMethodAdjustment is an admin-configured pricing rule for a checkout payment method
+type PaymentMethodAdjustment struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	PaymentMethod  string    `json:"payment_method" gorm:"uniqueIndex;not null"` // cod, online, wallet
+	AdjustmentType string    `json:"adjustment_type" gorm:"not null"`
+	Percent        float64   `json:"percent" gorm:"default:0"`
+	FlatAmount     float64   `json:"flat_amount" gorm:"default:0"`
+	MaxAmount      float64   `json:"max_amount" gorm:"default:0"` // 0 means no cap
+	MinOrderAmount float64   `json:"min_order_amount" gorm:"default:0"`
+	Description    string    `json:"description"`
+	IsActive       bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// PhoneOTP is the pending OTP of a phone sign-in or verification, one per flow and phone number.
+// Only a hash of the code is kept. Sends counts the OTPs texted to the number since
+// WindowStartedAt, whoever asked for them, so the cooldown and limit cannot be reset by a client.
+type PhoneOTP struct {
+	ID              uint      `gorm:"primarykey"`
+	Purpose         string    `gorm:"size:20;not null;uniqueIndex:idx_phone_otps_purpose_phone"`
+	Phone           string    `gorm:"size:20;not null;uniqueIndex:idx_phone_otps_purpose_phone"`
+	UserID          *uint     `gorm:"index"`   // the signed-in user verifying the number; nil for sign-in
+	CodeHash        string    `gorm:"size:64"` // empty once the OTP is used or discarded
+	ExpiresAt       time.Time `gorm:"not null"`
+	SentAt          time.Time `gorm:"not null"`
+	Sends           int       `gorm:"not null;default:0"`
+	WindowStartedAt time.Time `gorm:"not null"`
+	Attempts        int       `gorm:"not null;default:0"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// PhoneOTPSend records an OTP text requested from an IP address, so one client cannot text
+// many numbers
+type PhoneOTPSend struct {
+	ID        uint      `gorm:"primarykey"`
+	IP        string    `gorm:"size:45;not null;index"`
+	Phone     string    `gorm:"size:20;not null"`
+	CreatedAt time.Time `gorm:"index"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RateLimitCounter counts the requests made under a key, such as a route and client IP, since
+// WindowStartedAt, so limits hold across restarts and replicas
+type RateLimitCounter struct {
+	ID              uint      `gorm:"primarykey"`
+	Key             string    `gorm:"size:255;uniqueIndex;not null"`
+	Count           int       `gorm:"not null;default:0"`
+	WindowStartedAt time.Time `gorm:"not null;index"`
+}
@@ -58,3 +58,42 @@ type ReferralSignup struct {
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// ReferralSettings configures the rewards given for a successful referral. There is a single row.
+type ReferralSettings struct {
+	ID                    uint      `gorm:"primaryKey" json:"-"`
+	Enabled               bool      `gorm:"default:true" json:"enabled"`
+	ReferrerCouponPercent float64   `json:"referrer_coupon_percent"` // 0 disables the referrer coupon
+	ReferredCouponPercent float64   `json:"referred_coupon_percent"` // 0 disables the new user coupon
+	CouponMinOrderValue   float64   `json:"coupon_min_order_value"`
+	CouponMaxDiscount     float64   `json:"coupon_max_discount"`
+	CouponValidityDays    int       `json:"coupon_validity_days"`
+	ReferrerWalletCredit  float64   `json:"referrer_wallet_credit"` // credited to the referrer's wallet
+	ReferredWalletCredit  float64   `json:"referred_wallet_credit"` // credited to the new user's wallet
+	UpdatedBy             uint      `json:"updated_by"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// Referral reward ledger constants
+const (
+	ReferralRoleReferrer = "referrer"
+	ReferralRoleReferred = "referred"
+
+	ReferralRewardCoupon = "coupon"
+	ReferralRewardWallet = "wallet"
+)
+
+// ReferralReward is one reward granted for a referral: a coupon or a wallet credit
+type ReferralReward struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	ReferralUsageID     uint      `gorm:"index;not null" json:"referral_usage_id"`
+	UserID              uint      `gorm:"index;not null" json:"user_id"`
+	Role                string    `gorm:"not null" json:"role"`        // referrer, referred
+	RewardType          string    `gorm:"not null" json:"reward_type"` // coupon, wallet
+	CouponID            *uint     `json:"coupon_id,omitempty"`
+	CouponCode          string    `json:"coupon_code,omitempty"`
+	CouponPercent       float64   `json:"coupon_percent,omitempty"`
+	WalletTransactionID *uint     `json:"wallet_transaction_id,omitempty"`
+	Amount              float64   `json:"amount"` // wallet credit amount; 0 for coupons
+	CreatedAt           time.Time `json:"created_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ReviewImage is a photo a reviewer attached to their review
+type ReviewImage struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ReviewID     uint      `gorm:"index" json:"review_id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	ContentType  string    `json:"content_type,omitempty"`
+	Width        int       `json:"width,omitempty"`
+	Height       int       `json:"height,omitempty"`
+	SizeBytes    int64     `json:"size_bytes,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
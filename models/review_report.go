@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Review report reasons
+const (
+	ReviewReportSpam      = "spam"
+	ReviewReportOffensive = "offensive"
+	ReviewReportFake      = "fake"
+)
+
+// ReviewReport is a user's report that a review is abusive. Each user reports a review once.
+type ReviewReport struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ReviewID  uint      `gorm:"uniqueIndex:idx_review_reports_review_user" json:"review_id"`
+	UserID    uint      `gorm:"uniqueIndex:idx_review_reports_review_user" json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+	Reason    string    `gorm:"not null" json:"reason"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
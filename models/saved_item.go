@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SavedItem is a book the user moved out of the cart to buy later, with the quantity it had
+type SavedItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_saved_items_user_book"`
+	BookID    uint      `json:"book_id" gorm:"not null;uniqueIndex:idx_saved_items_user_book"`
+	Book      Book      `json:"book" gorm:"foreignKey:BookID"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// Scheduled job run outcomes
+const (
+	JobStatusSuccess = "success"
+	JobStatusFailed  = "failed"
+	JobStatusRunning = "running"
+)
+
+// ScheduledJob holds the schedule, lease and last-run state of a background job.
+// The lease (LockedBy/LockedUntil) makes sure only one server instance runs a job at a time.
+type ScheduledJob struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Name           string     `gorm:"uniqueIndex;not null" json:"name"`
+	Description    string     `json:"description"`
+	IntervalSecs   int64      `json:"interval_seconds"`
+	Enabled        bool       `gorm:"default:true" json:"enabled"`
+	LastRunAt      *time.Time `json:"last_run_at"`
+	LastStatus     string     `json:"last_status"`
+	LastMessage    string     `json:"last_message"`
+	LastError      string     `json:"last_error"`
+	LastDurationMs int64      `json:"last_duration_ms"`
+	RunCount       int        `gorm:"default:0" json:"run_count"`
+	FailCount      int        `gorm:"default:0" json:"fail_count"`
+	NextRunAt      *time.Time `json:"next_run_at"`
+	LockedBy       string     `json:"locked_by"`
+	LockedUntil    *time.Time `json:"locked_until"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
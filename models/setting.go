@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// Setting is an admin-editable store setting, stored as text and parsed by the settings service
+type Setting struct {
+	Key       string    `gorm:"primaryKey;size:64" json:"key"`
+	Value     string    `gorm:"not null" json:"value"`
+	UpdatedBy uint      `json:"updated_by"` // admin ID, 0 when never changed from the default
+	UpdatedAt time.Time `json:"updated_at"`
+}
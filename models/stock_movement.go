@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Stock movement sources
+const (
+	StockSourceOpening      = "opening_balance"
+	StockSourceInitial      = "initial"
+	StockSourceOrder        = "order"
+	StockSourcePreorder     = "preorder"
+	StockSourceCancellation = "cancellation"
+	StockSourceReturn       = "return"
+	StockSourceExchange     = "exchange"
+	StockSourceAdmin        = "admin_adjustment"
+)
+
+// StockMovement records one change to a book's stock: how much it moved, what it came to and
+// what caused it. The movements of a book add up to its stock.
+type StockMovement struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	BookID      uint      `gorm:"index:idx_stock_movements_book_created" json:"book_id"`
+	Delta       int       `gorm:"not null" json:"delta"`
+	StockAfter  int       `json:"stock_after"`
+	Source      string    `gorm:"index;not null" json:"source"`
+	OrderID     *uint     `gorm:"index" json:"order_id,omitempty"`
+	OrderItemID *uint     `json:"order_item_id,omitempty"`
+	AdminID     *uint     `json:"admin_id,omitempty"`
+	Note        string    `json:"note,omitempty"`
+	CreatedAt   time.Time `gorm:"index:idx_stock_movements_book_created" json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// StockNotification is a user's request to be emailed when an out-of-stock book is back in stock.
+// NotifiedAt is set once the email is sent; subscribing again clears it.
+type StockNotification struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_stock_notification_user_book"`
+	User       User       `json:"-" gorm:"foreignKey:UserID"`
+	BookID     uint       `json:"book_id" gorm:"not null;uniqueIndex:idx_stock_notification_user_book;index"`
+	Book       Book       `json:"-" gorm:"foreignKey:BookID"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty" gorm:"index"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
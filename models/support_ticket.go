@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Support ticket statuses. A ticket is open while it waits for support, pending while it
+// waits for the customer, and resolved once closed; a customer reply reopens it.
+const (
+	TicketStatusOpen     = "open"
+	TicketStatusPending  = "pending"
+	TicketStatusResolved = "resolved"
+)
+
+// Support ticket message senders
+const (
+	TicketSenderUser  = "user"
+	TicketSenderAdmin = "admin"
+)
+
+// SupportTicket is a customer's support request, optionally about one of their orders
+type SupportTicket struct {
+	ID            uint                   `json:"id" gorm:"primaryKey"`
+	UserID        uint                   `json:"user_id" gorm:"not null;index"`
+	User          User                   `json:"-" gorm:"foreignKey:UserID"`
+	OrderID       *uint                  `json:"order_id,omitempty" gorm:"index"`
+	Subject       string                 `json:"subject" gorm:"not null"`
+	Status        string                 `json:"status" gorm:"not null;default:'open';index"`
+	LastMessageAt time.Time              `json:"last_message_at" gorm:"index"`
+	LastSender    string                 `json:"last_sender"` // user or admin
+	ResolvedAt    *time.Time             `json:"resolved_at,omitempty"`
+	Messages      []SupportTicketMessage `json:"messages,omitempty" gorm:"foreignKey:TicketID;constraint:OnDelete:CASCADE"`
+	CreatedAt     time.Time              `json:"created_at" gorm:"index"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+}
+
+// SupportTicketMessage is one message in a ticket's conversation
+type SupportTicketMessage struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	TicketID   uint      `json:"ticket_id" gorm:"not null;index"`
+	SenderType string    `json:"sender_type" gorm:"not null"` // user or admin
+	SenderID   uint      `json:"sender_id"`
+	SenderName string    `json:"sender_name"`
+	Message    string    `json:"message" gorm:"type:text;not null"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Tag is a free-form label such as "award-winner" or "summer-reads". A book can carry any
+// number of tags in addition to its category and genre.
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null"`
+	Slug      string    `json:"slug" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Translation entity types
+const (
+	TranslationEntityBook     = "book"
+	TranslationEntityCategory = "category"
+	TranslationEntityGenre    = "genre"
+)
+
+// Translation stores a localized name and description for a catalog entity
+type Translation struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	EntityType  string    `json:"entity_type" gorm:"not null;uniqueIndex:idx_translations_entity_locale"` // book, category, genre
+	EntityID    uint      `json:"entity_id" gorm:"not null;uniqueIndex:idx_translations_entity_locale"`
+	Locale      string    `json:"locale" gorm:"not null;uniqueIndex:idx_translations_entity_locale"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
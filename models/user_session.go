@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// UserSession is a signed-in device. Its refresh token, stored only as a hash, exchanges for
+// new access tokens until the session expires or is revoked; access tokens carry the session
+// ID and stop working with it.
+type UserSession struct {
+	ID               uint       `gorm:"primarykey" json:"id"`
+	UserID           uint       `gorm:"index;not null" json:"-"`
+	RefreshTokenHash string     `gorm:"uniqueIndex;not null" json:"-"`
+	DeviceName       string     `json:"device_name"`
+	UserAgent        string     `json:"user_agent"`
+	IPAddress        string     `json:"ip_address"`
+	RememberMe       bool       `gorm:"default:false" json:"remember_me"`
+	LastSeenAt       time.Time  `json:"last_seen_at"`
+	ExpiresAt        time.Time  `gorm:"index" json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
@@ -11,6 +11,7 @@ type Wallet struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	UserID    uint           `json:"user_id" gorm:"uniqueIndex"`
 	Balance   float64        `json:"balance" gorm:"default:0"`
+	Held      float64        `json:"held" gorm:"default:0"` // part of the balance held for unpaid orders
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
@@ -22,20 +23,36 @@ type WalletTransaction struct {
 	WalletID    uint           `json:"wallet_id"`
 	Wallet      Wallet         `json:"-" gorm:"foreignKey:WalletID"`
 	Amount      float64        `json:"amount"`
-	Type        string         `json:"type"` // credit, debit
+	Type        string         `json:"type"` // credit, debit, hold
 	Description string         `json:"description"`
 	OrderID     *uint          `json:"order_id"`
 	Reference   string         `json:"reference"`
-	Status      string         `json:"status"` // pending, completed, failed, reversed
+	Status      string         `json:"status"`                          // pending, completed, failed, reversed; holds are pending, captured or released
+	AdminID     *uint          `json:"admin_id,omitempty" gorm:"index"` // admin who posted a manual adjustment
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// WalletMismatch flags a wallet whose balance differs from the sum of its completed ledger
+// entries. It is raised by the reconciliation job and resolved once the two agree again.
+type WalletMismatch struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	WalletID        uint       `gorm:"uniqueIndex" json:"wallet_id"`
+	UserID          uint       `gorm:"index" json:"user_id"`
+	Balance         float64    `json:"balance"`
+	LedgerBalance   float64    `json:"ledger_balance"`
+	Difference      float64    `json:"difference"` // Balance - LedgerBalance
+	FirstDetectedAt time.Time  `json:"first_detected_at"`
+	LastCheckedAt   time.Time  `json:"last_checked_at"`
+	ResolvedAt      *time.Time `gorm:"index" json:"resolved_at"`
+}
+
 // TransactionType constants
 const (
 	TransactionTypeCredit = "credit"
 	TransactionTypeDebit  = "debit"
+	TransactionTypeHold   = "hold" // funds set aside for an order; never moves the balance itself
 )
 
 // TransactionStatus constants
@@ -44,4 +61,6 @@ const (
 	TransactionStatusCompleted = "completed"
 	TransactionStatusFailed    = "failed"
 	TransactionStatusReversed  = "reversed"
+	TransactionStatusCaptured  = "captured" // hold turned into a debit
+	TransactionStatusReleased  = "released" // hold given back to the available balance
 )
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// BookStatus is the catalog state of a book, used to decide who may see it
+type BookStatus struct {
+	Blocked   bool
+	IsActive  bool
+	DeletedAt *time.Time
+}
+
+// BookRepository reads books from the catalog
+type BookRepository interface {
+	// FindStatus returns the catalog state of a book, including deleted books
+	FindStatus(id uint) (*BookStatus, error)
+	// FindWithDetails returns a book that is not deleted, with its images, category and genre
+	FindWithDetails(id uint) (*models.Book, error)
+	// FindByID returns a book that is not deleted
+	FindByID(id uint) (*models.Book, error)
+	// FindTrashed returns a deleted book
+	FindTrashed(id uint) (*models.Book, error)
+	// FindByISBN returns the book, deleted or not, other than excludeID that uses the ISBN
+	FindByISBN(isbn string, excludeID uint) (*models.Book, error)
+	// ListTrashed returns one page of deleted books, most recently deleted first, and the
+	// number of deleted books whose name, author or ISBN contains search
+	ListTrashed(search string, offset, limit int) ([]models.Book, int64, error)
+	// CategoryExists reports whether the category exists and is not deleted
+	CategoryExists(id uint) (bool, error)
+	// Delete soft-deletes a book, keeping its images
+	Delete(book *models.Book) error
+	// Restore undeletes a book
+	Restore(book *models.Book) error
+}
+
+type gormBookRepository struct {
+	db *gorm.DB
+}
+
+// NewBookRepository returns a BookRepository backed by db
+func NewBookRepository(db *gorm.DB) BookRepository {
+	return &gormBookRepository{db: db}
+}
+
+func (r *gormBookRepository) FindStatus(id uint) (*BookStatus, error) {
+	var status BookStatus
+	result := r.db.Raw("SELECT blocked, is_active, deleted_at FROM books WHERE id = ?", id).Scan(&status)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &status, nil
+}
+
+func (r *gormBookRepository) FindWithDetails(id uint) (*models.Book, error) {
+	var book models.Book
+	query := `
+		SELECT
+			id, created_at, updated_at, deleted_at,
+			name, description, price, original_price, discount_percentage, discount_end_date, stock, category_id,
+			genre_id, image_url, is_active, is_featured, views,
+			average_rating, total_reviews, author, author_id, publisher,
+			isbn, publication_year, genre, pages, language, format,
+			blocked, preview_key, preview_content_type, is_digital
+		FROM books
+		WHERE id = ? AND deleted_at IS NULL
+	`
+	if err := r.db.Raw(query, id).Scan(&book).Error; err != nil {
+		return nil, err
+	}
+	if book.ID == 0 {
+		return nil, ErrNotFound
+	}
+
+	if err := r.db.Where("book_id = ?", id).Find(&book.BookImages).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Raw("SELECT * FROM categories WHERE id = ?", book.CategoryID).Scan(&book.Category).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Raw("SELECT * FROM genres WHERE id = ?", book.GenreID).Scan(&book.Genre).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Raw("SELECT tags.* FROM tags JOIN book_tags ON book_tags.tag_id = tags.id WHERE book_tags.book_id = ? ORDER BY tags.name", id).Scan(&book.Tags).Error; err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+func (r *gormBookRepository) FindByID(id uint) (*models.Book, error) {
+	var book models.Book
+	if err := r.db.First(&book, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &book, nil
+}
+
+func (r *gormBookRepository) FindTrashed(id uint) (*models.Book, error) {
+	var book models.Book
+	if err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&book).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &book, nil
+}
+
+func (r *gormBookRepository) FindByISBN(isbn string, excludeID uint) (*models.Book, error) {
+	var book models.Book
+	if err := r.db.Unscoped().Where("isbn = ? AND id != ?", isbn, excludeID).First(&book).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &book, nil
+}
+
+func (r *gormBookRepository) ListTrashed(search string, offset, limit int) ([]models.Book, int64, error) {
+	query := r.db.Unscoped().Model(&models.Book{}).Where("deleted_at IS NOT NULL")
+	if search != "" {
+		searchTerm := "%" + search + "%"
+		query = query.Where("name ILIKE ? OR author ILIKE ? OR isbn ILIKE ?", searchTerm, searchTerm, searchTerm)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var books []models.Book
+	if err := query.Order("deleted_at DESC").Offset(offset).Limit(limit).Find(&books).Error; err != nil {
+		return nil, 0, err
+	}
+	return books, total, nil
+}
+
+func (r *gormBookRepository) CategoryExists(id uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Category{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gormBookRepository) Delete(book *models.Book) error {
+	return r.db.Delete(book).Error
+}
+
+func (r *gormBookRepository) Restore(book *models.Book) error {
+	return r.db.Unscoped().Model(book).Update("deleted_at", nil).Error
+}
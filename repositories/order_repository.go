@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// OrderFilter selects and orders a page of a user's orders. Empty fields do not filter.
+type OrderFilter struct {
+	ID     string
+	Status string
+	Date   string // YYYY-MM-DD
+	SortBy string // id, status, created_at or final_total
+	Order  string // asc or desc
+	Offset int
+	Limit  int
+	// Keyset, when set, reads the page past a position in the sort order instead of skipping
+	// Offset orders
+	Keyset *OrderKeyset
+}
+
+// OrderKeyset is a position in a listing of orders: the value of the sort column and the ID of
+// an order. Orders are read from the position in the listing's order, or back from it, nearest
+// first, when Backward is set.
+type OrderKeyset struct {
+	Value    interface{} // nil for the first page
+	ID       uint
+	Backward bool
+}
+
+// OrderRepository reads customers' orders
+type OrderRepository interface {
+	// ListByUser returns one page of the user's orders with their items and books, and the
+	// number of orders matching the filter. A keyset page is not counted.
+	ListByUser(userID uint, filter OrderFilter) ([]models.Order, int64, error)
+	// FindByUser returns the user's order with its items, books, address and user
+	FindByUser(orderID, userID uint) (*models.Order, error)
+	// Shipments returns the shipments of an order split into several boxes, oldest first
+	Shipments(orderID uint) ([]models.OrderShipment, error)
+	// FindWithItemCategories returns the user's order with its items, their books and the
+	// books' categories
+	FindWithItemCategories(orderID, userID uint) (*models.Order, error)
+	// SaveWithItems saves the order and its items in one transaction
+	SaveWithItems(order *models.Order) error
+}
+
+type gormOrderRepository struct {
+	db *gorm.DB
+}
+
+// NewOrderRepository returns an OrderRepository backed by db
+func NewOrderRepository(db *gorm.DB) OrderRepository {
+	return &gormOrderRepository{db: db}
+}
+
+// OrderSortColumn returns the column a user's orders are sorted by for a sort key clients use
+func OrderSortColumn(sortBy string) string {
+	if column, ok := orderSortColumns[sortBy]; ok {
+		return column
+	}
+	return "created_at"
+}
+
+// orderSortColumns maps the sort keys clients use to columns
+var orderSortColumns = map[string]string{
+	"id":          "id",
+	"status":      "status",
+	"created_at":  "created_at",
+	"final_total": "total_with_delivery",
+}
+
+func (r *gormOrderRepository) ListByUser(userID uint, filter OrderFilter) ([]models.Order, int64, error) {
+	query := r.db.Model(&models.Order{}).Where("user_id = ?", userID)
+	if filter.ID != "" {
+		query = query.Where("id = ?", filter.ID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Date != "" {
+		query = query.Where("DATE(created_at) = ?", filter.Date)
+	}
+
+	column := OrderSortColumn(filter.SortBy)
+	direction := "desc"
+	if filter.Order == "asc" {
+		direction = "asc"
+	}
+
+	var total int64
+	if keyset := filter.Keyset; keyset != nil {
+		if keyset.Backward && direction == "asc" {
+			direction = "desc"
+		} else if keyset.Backward {
+			direction = "asc"
+		}
+		if keyset.Value != nil {
+			comparison := ">"
+			if direction == "desc" {
+				comparison = "<"
+			}
+			query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, comparison), keyset.Value, keyset.ID)
+		}
+	} else if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var orders []models.Order
+	err := query.Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).
+		Offset(filter.Offset).Limit(filter.Limit).
+		Preload("OrderItems.Book").
+		Find(&orders).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return orders, total, nil
+}
+
+func (r *gormOrderRepository) FindByUser(orderID, userID uint) (*models.Order, error) {
+	var order models.Order
+	err := r.db.Preload("OrderItems.Book").Preload("Address").Preload("User").
+		Where("id = ? AND user_id = ?", orderID, userID).
+		First(&order).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &order, nil
+}
+
+func (r *gormOrderRepository) Shipments(orderID uint) ([]models.OrderShipment, error) {
+	var shipments []models.OrderShipment
+	err := r.db.Where("order_id = ?", orderID).Order("id").Find(&shipments).Error
+	return shipments, err
+}
+
+func (r *gormOrderRepository) FindWithItemCategories(orderID, userID uint) (*models.Order, error) {
+	var order models.Order
+	err := r.db.Preload("OrderItems.Book.Category").
+		Where("id = ? AND user_id = ?", orderID, userID).
+		First(&order).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &order, nil
+}
+
+func (r *gormOrderRepository) SaveWithItems(order *models.Order) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range order.OrderItems {
+			if err := tx.Omit("Book").Save(&order.OrderItems[i]).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Omit("OrderItems", "Address", "User").Save(order).Error
+	})
+}
@@ -0,0 +1,20 @@
+// Package repositories holds the data access used by the services. Each repository is an
+// interface with a GORM implementation, so services can be tested against fakes.
+package repositories
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when the requested record does not exist
+var ErrNotFound = errors.New("record not found")
+
+// notFound maps GORM's not-found error to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// UserRepository reads user accounts
+type UserRepository interface {
+	FindByID(id uint) (*models.User, error)
+}
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository returns a UserRepository backed by db
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &gormUserRepository{db: db}
+}
+
+func (r *gormUserRepository) FindByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// TransactionFilter selects a page of a wallet's transactions. Empty fields do not filter.
+type TransactionFilter struct {
+	Type   string
+	Status string
+	From   *time.Time // inclusive
+	Until  *time.Time // exclusive
+	Offset int
+	Limit  int
+}
+
+// WalletRepository reads wallets and their ledgers
+type WalletRepository interface {
+	// GetOrCreate returns the user's wallet, creating an empty one if needed
+	GetOrCreate(userID uint) (*models.Wallet, error)
+	// FindByUser returns the user's wallet, or ErrNotFound when they have none yet
+	FindByUser(userID uint) (*models.Wallet, error)
+	// ListTransactions returns one page of the wallet's transactions, newest first, and the
+	// number of transactions matching the filter
+	ListTransactions(walletID uint, filter TransactionFilter) ([]models.WalletTransaction, int64, error)
+	// BalancesAfter returns the wallet balance right after each of the given transactions
+	BalancesAfter(walletID uint, transactionIDs []uint) (map[uint]float64, error)
+	// LedgerBalances returns every wallet's stored balance next to the sum of its ledger
+	LedgerBalances() ([]LedgerBalance, error)
+	// ListMismatches returns the flagged wallets, newest first; resolved ones only when asked
+	ListMismatches(includeResolved bool) ([]models.WalletMismatch, error)
+	// SaveMismatch creates or updates a flagged wallet
+	SaveMismatch(mismatch *models.WalletMismatch) error
+}
+
+// LedgerBalance is a wallet's stored balance and the balance its ledger adds up to
+type LedgerBalance struct {
+	WalletID      uint
+	UserID        uint
+	Balance       float64
+	LedgerBalance float64
+}
+
+type gormWalletRepository struct {
+	db *gorm.DB
+}
+
+// NewWalletRepository returns a WalletRepository backed by db
+func NewWalletRepository(db *gorm.DB) WalletRepository {
+	return &gormWalletRepository{db: db}
+}
+
+// signedAmountSQL normalises transaction amounts to a signed value. Debits are stored
+// both as negative and positive amounts depending on where they were created. Holds only
+// set funds aside, so they never move the balance.
+const signedAmountSQL = "CASE WHEN type = 'debit' THEN -ABS(amount) WHEN type = 'hold' THEN 0 ELSE ABS(amount) END"
+
+func (r *gormWalletRepository) GetOrCreate(userID uint) (*models.Wallet, error) {
+	wallet := models.Wallet{UserID: userID}
+	if err := r.db.Where("user_id = ?", userID).FirstOrCreate(&wallet).Error; err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (r *gormWalletRepository) FindByUser(userID uint) (*models.Wallet, error) {
+	var wallet models.Wallet
+	if err := r.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &wallet, nil
+}
+
+func (r *gormWalletRepository) ListTransactions(walletID uint, filter TransactionFilter) ([]models.WalletTransaction, int64, error) {
+	query := r.db.Model(&models.WalletTransaction{}).Where("wallet_id = ?", walletID)
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transactions []models.WalletTransaction
+	if err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&transactions).Error; err != nil {
+		return nil, 0, err
+	}
+	return transactions, total, nil
+}
+
+func (r *gormWalletRepository) BalancesAfter(walletID uint, transactionIDs []uint) (map[uint]float64, error) {
+	balances := make(map[uint]float64, len(transactionIDs))
+	if len(transactionIDs) == 0 {
+		return balances, nil
+	}
+
+	// The running sum covers the whole ledger, not just the requested rows, so it always
+	// matches what the wallet held right after each transaction
+	var rows []struct {
+		ID           uint
+		BalanceAfter float64
+	}
+	ledgerSQL := `SELECT id, balance_after FROM (
+		SELECT id, SUM(CASE WHEN status IN (?, ?, ?) THEN 0 ELSE ` + signedAmountSQL + ` END)
+			OVER (ORDER BY created_at, id) AS balance_after
+		FROM wallet_transactions
+		WHERE wallet_id = ? AND deleted_at IS NULL
+	) ledger WHERE id IN ?`
+	err := r.db.Raw(ledgerSQL, models.TransactionStatusPending, models.TransactionStatusFailed, models.TransactionStatusReversed, walletID, transactionIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		balances[row.ID] = row.BalanceAfter
+	}
+	return balances, nil
+}
+
+func (r *gormWalletRepository) LedgerBalances() ([]LedgerBalance, error) {
+	var rows []LedgerBalance
+	err := r.db.Raw(`SELECT w.id AS wallet_id, w.user_id, w.balance,
+			COALESCE(SUM(CASE WHEN t.status IN (?, ?, ?) THEN 0 ELSE `+signedAmountSQL+` END), 0) AS ledger_balance
+		FROM wallets w
+		LEFT JOIN wallet_transactions t ON t.wallet_id = w.id AND t.deleted_at IS NULL
+		WHERE w.deleted_at IS NULL
+		GROUP BY w.id, w.user_id, w.balance
+		ORDER BY w.id`,
+		models.TransactionStatusPending, models.TransactionStatusFailed, models.TransactionStatusReversed).
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *gormWalletRepository) ListMismatches(includeResolved bool) ([]models.WalletMismatch, error) {
+	query := r.db.Order("first_detected_at DESC")
+	if !includeResolved {
+		query = query.Where("resolved_at IS NULL")
+	}
+	var mismatches []models.WalletMismatch
+	err := query.Find(&mismatches).Error
+	return mismatches, err
+}
+
+func (r *gormWalletRepository) SaveMismatch(mismatch *models.WalletMismatch) error {
+	return r.db.Save(mismatch).Error
+}
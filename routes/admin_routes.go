@@ -8,7 +8,7 @@ import (
 )
 
 // initAdminRoutes initializes all admin-related routes
-func initAdminRoutes(router *gin.RouterGroup) {
+func initAdminRoutes(router *gin.RouterGroup, handlers *controllers.Handlers) {
 	utils.LogInfo("Registering admin routes")
 
 	admin := router.Group("/admin")
@@ -21,20 +21,36 @@ func initAdminRoutes(router *gin.RouterGroup) {
 				"status":  "success",
 			})
 		})
-		admin.POST("/login", controllers.AdminLogin)
+		admin.POST("/login", controllers.AdminLoginRateLimit(), controllers.AdminLogin)
+		admin.POST("/login/2fa", controllers.AdminLoginRateLimit(), controllers.AdminVerifyTwoFactor)
 
 		// Protected admin routes
 		admin.Use(middleware.AdminAuthMiddleware())
+		// Catalog changes make the cached catalog responses stale
+		admin.Use(utils.InvalidateCatalogCacheOnWrite())
+		// Every successful write is recorded in the audit log
+		admin.Use(controllers.AdminAuditMiddleware())
 		{
 			// Logout (must be authenticated)
 			admin.POST("/logout", controllers.AdminLogout)
 
+			// Two-factor authentication
+			admin.GET("/2fa", controllers.AdminGetTwoFactorStatus)
+			admin.POST("/2fa/setup", controllers.AdminSetupTwoFactor)
+			admin.POST("/2fa/enable", controllers.AdminEnableTwoFactor)
+			admin.POST("/2fa/backup-codes", controllers.AdminRegenerateBackupCodes)
+			admin.POST("/2fa/disable", controllers.AdminDisableTwoFactor)
+
 			// Dashboard
 			admin.GET("/dashboard", controllers.GetDashboardOverview)
 
 			// User management
 			admin.GET("/users", controllers.GetUsers)
+			admin.GET("/users/:id", controllers.GetUserDetails)
 			admin.PUT("/users/:id/block", controllers.BlockUser)
+			admin.GET("/users/:id/wallet/transactions", handlers.Wallet.AdminGetUserWalletLedger)
+			admin.POST("/users/:id/wallet/adjust", controllers.AdjustUserWallet)
+			admin.GET("/wallets/mismatches", handlers.Wallet.AdminListWalletMismatches)
 
 			// Category management
 			admin.GET("/categories", controllers.GetCategories)
@@ -46,16 +62,84 @@ func initAdminRoutes(router *gin.RouterGroup) {
 
 			// Book management
 			admin.GET("/books", controllers.GetBooks)
+			admin.GET("/books/trash", handlers.Books.ListTrashedBooks)
 			admin.POST("/books", controllers.CreateBook)
+			admin.POST("/books/bulk-categorize", controllers.BulkCategorizeBooks)
 			admin.PUT("/books/field/:field/:value", controllers.UpdateBookByField)
-			admin.GET("/books/:id", controllers.GetBookDetails)
+			admin.GET("/books/isbn-lookup", controllers.AdminLookupISBN)
+			admin.GET("/books/:id", handlers.Books.GetBookDetails)
 			admin.PUT("/books/:id", controllers.UpdateBook)
-			admin.DELETE("/books/:id", controllers.DeleteBook)
+			admin.DELETE("/books/:id", handlers.Books.DeleteBook)
+			admin.POST("/books/:id/images", controllers.UploadBookImages)
+			admin.GET("/books/:id/images", controllers.GetBookImages)
+			admin.DELETE("/books/:id/images/:image_id", controllers.DeleteBookImage)
+			admin.PUT("/books/:id/preview", controllers.UploadBookPreview)
+			admin.DELETE("/books/:id/preview", controllers.DeleteBookPreview)
+			admin.PUT("/books/:id/digital-file", controllers.UploadDigitalBookFile)
+			admin.DELETE("/books/:id/digital-file", controllers.DeleteDigitalBookFile)
+			admin.POST("/books/:id/restore", handlers.Books.RestoreBook)
+			admin.POST("/books/:id/restock", controllers.AdminRestockBook)
+			admin.GET("/books/:id/stock-movements", controllers.AdminGetStockMovements)
+			admin.PUT("/books/:id/schedule", controllers.AdminScheduleBook)
+			admin.POST("/books/:id/clone", controllers.CloneBook)
 			admin.GET("/books/:id/check", controllers.CheckBookExists)
 			admin.GET("/books/:id/reviews", controllers.GetBookReviews)
 			admin.PUT("/books/:id/reviews/:reviewId/approve", controllers.ApproveReview)
 			admin.DELETE("/books/:id/reviews/:reviewId", controllers.DeleteReview)
 
+			// Review moderation
+			admin.GET("/reviews", controllers.AdminListReviews)
+			admin.POST("/reviews/bulk-approve", controllers.AdminBulkApproveReviews)
+			admin.POST("/reviews/bulk-reject", controllers.AdminBulkRejectReviews)
+			admin.GET("/reviews/:id/reports", controllers.AdminGetReviewReports)
+			admin.GET("/reviews/banned-words", controllers.AdminListBannedWords)
+			admin.POST("/reviews/banned-words", controllers.AdminAddBannedWords)
+			admin.DELETE("/reviews/banned-words/:id", controllers.AdminDeleteBannedWord)
+
+			// Home page curated sections
+			admin.GET("/home-sections", controllers.AdminListHomeSections)
+			admin.POST("/home-sections", controllers.AdminCreateHomeSection)
+			admin.PUT("/home-sections/:id", controllers.AdminUpdateHomeSection)
+			admin.PUT("/home-sections/:id/books", controllers.AdminSetHomeSectionBooks)
+			admin.DELETE("/home-sections/:id", controllers.AdminDeleteHomeSection)
+
+			// Storefront banners
+			admin.GET("/banners", controllers.AdminListBanners)
+			admin.POST("/banners", controllers.AdminCreateBanner)
+			admin.PUT("/banners/:id", controllers.AdminUpdateBanner)
+			admin.POST("/banners/:id/image", controllers.AdminUploadBannerImage)
+			admin.DELETE("/banners/:id", controllers.AdminDeleteBanner)
+
+			// Content pages
+			admin.GET("/pages", controllers.AdminListPages)
+			admin.POST("/pages", controllers.AdminCreatePage)
+			admin.GET("/pages/:id", controllers.AdminGetPage)
+			admin.PUT("/pages/:id", controllers.AdminUpdatePage)
+			admin.GET("/pages/:id/versions", controllers.AdminGetPageVersions)
+			admin.POST("/pages/:id/versions/:version/restore", controllers.AdminRestorePageVersion)
+			admin.DELETE("/pages/:id", controllers.AdminDeletePage)
+
+			// Support ticket queue
+			admin.GET("/support/tickets", controllers.AdminGetSupportTickets)
+			admin.GET("/support/tickets/:id", controllers.AdminGetSupportTicket)
+			admin.POST("/support/tickets/:id/messages", controllers.AdminReplySupportTicket)
+			admin.PUT("/support/tickets/:id/status", controllers.AdminUpdateSupportTicketStatus)
+
+			// Back-in-stock demand
+			admin.GET("/stock-notifications", controllers.AdminGetStockNotificationDemand)
+			admin.GET("/announcements", controllers.AdminGetAnnouncements)
+			admin.POST("/announcements", controllers.AdminSendAnnouncement)
+
+			// Catalog translations
+			admin.GET("/translations/:entity_type/:id", controllers.GetTranslations)
+			admin.PUT("/translations/:entity_type/:id/:locale", controllers.UpsertTranslation)
+			admin.DELETE("/translations/:entity_type/:id/:locale", controllers.DeleteTranslation)
+
+			// Catalog change feed
+			admin.GET("/catalog/changes", controllers.GetCatalogChanges)
+			admin.GET("/audit-logs", controllers.AdminGetAuditLogs)
+			admin.POST("/catalog/changes/digest", controllers.TriggerCatalogChangeDigest)
+
 			// Genre management routes
 			admin.POST("/genres", controllers.CreateGenre)
 			admin.PUT("/genres/:id", controllers.UpdateGenre)
@@ -63,11 +147,44 @@ func initAdminRoutes(router *gin.RouterGroup) {
 			admin.GET("/genres", controllers.GetGenres)
 			admin.GET("/genres/:id", controllers.ListBooksByGenre)
 
+			// Author management routes
+			admin.GET("/authors", controllers.GetAuthors)
+			admin.POST("/authors", controllers.CreateAuthor)
+			admin.PUT("/authors/:id", controllers.UpdateAuthor)
+			admin.DELETE("/authors/:id", controllers.DeleteAuthor)
+
+			// Tag management routes
+			admin.GET("/tags", controllers.GetTags)
+			admin.POST("/tags", controllers.CreateTag)
+			admin.PUT("/tags/:id", controllers.UpdateTag)
+			admin.DELETE("/tags/:id", controllers.DeleteTag)
+			admin.PUT("/books/:id/tags", controllers.SetBookTags)
+
+			// Bundle management routes
+			admin.GET("/bundles", controllers.AdminGetBundles)
+			admin.POST("/bundles", controllers.CreateBundle)
+			admin.PUT("/bundles/:id", controllers.UpdateBundle)
+			admin.DELETE("/bundles/:id", controllers.DeleteBundle)
+
 			// Order management (admin)
 			admin.GET("/orders", controllers.AdminListOrders)
 			admin.GET("/orders/returns", controllers.AdminListReturnRequests)
+			admin.GET("/orders/lookup", controllers.AdminLookupOrder)
+			admin.POST("/orders/bulk-status", controllers.AdminBulkUpdateOrderStatus)
 			admin.GET("/orders/:id", controllers.AdminGetOrderDetails)
 			admin.PUT("/orders/:id/status", controllers.AdminUpdateOrderStatus)
+			admin.GET("/orders/:id/shipments", controllers.AdminListOrderShipments)
+			admin.POST("/orders/:id/shipments", controllers.AdminCreateOrderShipment)
+			admin.PUT("/orders/:id/shipments/:shipment_id/status", controllers.AdminUpdateShipmentStatus)
+			admin.POST("/orders/:id/delivery-otp", controllers.SendDeliveryOTP)
+			admin.POST("/orders/:id/delivery-otp/verify", controllers.VerifyDeliveryOTP)
+			admin.POST("/orders/:id/delivery-refused", controllers.MarkDeliveryRefused)
+			admin.GET("/orders/:id/delivery-receipt", controllers.AdminDownloadDeliveryReceipt)
+			admin.GET("/orders/:id/packing-slip", controllers.AdminDownloadPackingSlip)
+			admin.GET("/orders/:id/shipping-label", controllers.AdminDownloadShippingLabel)
+			admin.GET("/orders/:id/comments", controllers.AdminGetOrderComments)
+			admin.POST("/orders/:id/comments", controllers.AdminAddOrderComment)
+			admin.DELETE("/orders/:id/comments/:comment_id", controllers.AdminDeleteOrderComment)
 
 			// Return and refund management
 			admin.POST("/orders/:id/return/approve", controllers.ApproveOrderReturn)
@@ -75,37 +192,79 @@ func initAdminRoutes(router *gin.RouterGroup) {
 			admin.GET("/orders/return-items", controllers.AdminListReturnItems)
 			admin.POST("/orders/:id/items/:item_id/review", controllers.AdminReviewReturnItem)
 
+			// Cancellation requests made after the cancellation window
+			admin.GET("/orders/cancellation-requests", controllers.AdminListCancellationRequests)
+			admin.POST("/orders/:id/items/:item_id/cancellation/review", controllers.AdminReviewItemCancellation)
+
+			// Exchange management
+			admin.GET("/orders/exchanges", controllers.AdminListExchangeRequests)
+			admin.POST("/orders/:id/items/:item_id/exchange/review", controllers.AdminReviewExchangeItem)
+			admin.PUT("/orders/:id/items/:item_id/exchange/shipment", controllers.AdminUpdateReplacementShipment)
+
 			// Coupon management
 			admin.POST("/coupons", controllers.CreateCoupon)
 			admin.GET("/coupons", controllers.GetCoupons)
 			admin.PUT("/coupons/:id", controllers.UpdateCoupon)
+			admin.GET("/coupons/:id/usage", controllers.AdminGetCouponUsage)
 			admin.DELETE("/coupons/:id", controllers.DeleteCoupon)
 
+			// Gift cards
+			admin.GET("/gift-cards", controllers.AdminListGiftCards)
+			admin.POST("/gift-cards", controllers.AdminIssueGiftCard)
+			admin.PUT("/gift-cards/:id/void", controllers.AdminVoidGiftCard)
+
 			// Product Offer routes
 			adminOffers := admin.Group("/offers")
 			adminOffers.POST("/products", controllers.CreateProductOffer)
 			adminOffers.GET("/products", controllers.ListProductOffers)
+			adminOffers.GET("/products/:id", controllers.GetProductOffer)
 			adminOffers.PUT("/products/:id", controllers.UpdateProductOffer)
 			adminOffers.PATCH("/products/:id", controllers.UpdateProductOffer)
 			adminOffers.DELETE("/products/:id", controllers.DeleteProductOffer)
+			adminOffers.PUT("/products/:id/schedule", controllers.AdminScheduleProductOffer)
 
 			// Category Offer routes
 			adminOffers.POST("/categories", controllers.CreateCategoryOffer)
 			adminOffers.GET("/categories", controllers.ListCategoryOffers)
+			adminOffers.GET("/categories/:id", controllers.GetCategoryOffer)
 			adminOffers.PUT("/categories/:id", controllers.UpdateCategoryOffer)
 			adminOffers.PATCH("/categories/:id", controllers.UpdateCategoryOffer)
 			adminOffers.DELETE("/categories/:id", controllers.DeleteCategoryOffer)
+			adminOffers.PUT("/categories/:id/schedule", controllers.AdminScheduleCategoryOffer)
+
+			// How product and category offers combine
+			adminOffers.GET("/rules", controllers.GetOfferRules)
+			adminOffers.PUT("/rules", controllers.UpdateOfferRules)
+
+			// Effective price of a book under its current or scheduled offers
+			adminOffers.GET("/preview/:book_id", controllers.PreviewBookOfferPrice)
 
 			// Referral management (admin)
 			admin.GET("/referrals", controllers.GetAllUserReferralCodes)
 			admin.GET("/referrals/stats", controllers.GetReferralStatistics)
 			admin.GET("/referrals/user/:user_id", controllers.GetUserReferralStats)
 			admin.POST("/referrals/toggle", controllers.ToggleReferralCodeStatus)
+			admin.GET("/referrals/settings", controllers.GetReferralSettings)
+			admin.PUT("/referrals/settings", controllers.UpdateReferralSettings)
+			admin.GET("/referrals/rewards", controllers.GetReferralRewardLedger)
 
 			// Sales report endpoints (admin)
 			admin.GET("/sales/report", controllers.GenerateSalesReport)
 			admin.GET("/sales/report/pdf", controllers.DownloadSalesReportPDF)
 			admin.GET("/sales/report/excel", controllers.DownloadSalesReportExcel)
+			admin.GET("/sales/report/csv", controllers.DownloadSalesReportCSV)
+			admin.GET("/sales/top-sellers", controllers.GetTopSellersReport)
+			admin.GET("/finance/report", controllers.GetFinanceReport)
+
+			// Payment disputes and chargebacks
+			admin.GET("/disputes", controllers.AdminListDisputes)
+			admin.POST("/disputes", controllers.AdminCreateDispute)
+			admin.GET("/disputes/:id", controllers.AdminGetDispute)
+			admin.PUT("/disputes/:id", controllers.AdminUpdateDispute)
+			admin.POST("/disputes/:id/evidence", controllers.AdminUploadDisputeEvidence)
+			admin.GET("/disputes/:id/evidence/:evidenceId", controllers.AdminDownloadDisputeEvidence)
+			admin.DELETE("/disputes/:id/evidence/:evidenceId", controllers.AdminDeleteDisputeEvidence)
+			admin.GET("/carts/abandoned", controllers.GetAbandonedCartReport)
 
 			// Dashboard routes
 			dashboard := admin.Group("/dashboard")
@@ -116,12 +275,49 @@ func initAdminRoutes(router *gin.RouterGroup) {
 				dashboard.GET("/top-categories", controllers.GetTopSellingCategories)
 			}
 
+			// API traffic analytics
+			admin.GET("/analytics/requests", controllers.GetAPIAnalytics)
+
+			// Store settings
+			admin.GET("/settings", controllers.AdminGetSettings)
+			admin.PUT("/settings", controllers.AdminUpdateSettings)
+
+			// Currencies and exchange rates
+			admin.GET("/currencies", controllers.GetCurrencies)
+			admin.PUT("/currencies/:code/rate", controllers.SetExchangeRate)
+
+			// Scheduled background jobs
+			admin.GET("/jobs", controllers.GetScheduledJobs)
+			admin.POST("/jobs/:name/run", controllers.RunScheduledJob)
+			admin.PUT("/jobs/:name", controllers.UpdateScheduledJob)
+
+			// Email templates rendered with sample data
+			admin.GET("/email-templates", controllers.GetEmailTemplates)
+			admin.GET("/email-templates/:name/preview", controllers.PreviewEmailTemplate)
+
 			// Delivery charge management
 			admin.GET("/delivery-charges", controllers.GetDeliveryCharges)
 			admin.POST("/delivery-charges", controllers.AddDeliveryCharge)
 			admin.PUT("/delivery-charges/:id", controllers.UpdateDeliveryCharge)
 			admin.DELETE("/delivery-charges/:id", controllers.DeleteDeliveryCharge)
 			admin.GET("/delivery-charges/pincode/:pincode", controllers.GetDeliveryChargeByPincode)
+
+			// Delivery times and slots by zone
+			admin.GET("/delivery-slas", controllers.GetDeliverySLAs)
+			admin.PUT("/delivery-slas", controllers.SetDeliverySLA)
+			admin.DELETE("/delivery-slas/:id", controllers.DeleteDeliverySLA)
+			admin.POST("/delivery-slots", controllers.AddDeliverySlot)
+			admin.PUT("/delivery-slots/:id", controllers.UpdateDeliverySlot)
+			admin.DELETE("/delivery-slots/:id", controllers.DeleteDeliverySlot)
+
+			admin.GET("/cod/blocked-pincodes", controllers.GetCODBlockedPincodes)
+			admin.POST("/cod/blocked-pincodes", controllers.BlockCODPincode)
+			admin.DELETE("/cod/blocked-pincodes/:pincode", controllers.UnblockCODPincode)
+
+			// Payment method fees, discounts and cashback
+			admin.GET("/payment-adjustments", controllers.GetPaymentAdjustments)
+			admin.PUT("/payment-adjustments/:method", controllers.UpsertPaymentAdjustment)
+			admin.DELETE("/payment-adjustments/:method", controllers.DeletePaymentAdjustment)
 		}
 	}
 
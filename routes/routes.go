@@ -6,24 +6,40 @@ import (
 	"github.com/Govind-619/ReadSphere/models"
 	"github.com/Govind-619/ReadSphere/utils"
 	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRouter initializes and returns the Gin router with all routes
-func SetupRouter() *gin.Engine {
+// SetupRouter initializes and returns the Gin router with all routes, served by handlers where
+// they have been moved to injected services. The middleware is applied to every route except
+// the health probes and /metrics.
+func SetupRouter(handlers *controllers.Handlers, middleware ...gin.HandlerFunc) *gin.Engine {
 	router := gin.Default()
 
-	// Setup session middleware with a secure key
-	store := cookie.NewStore([]byte("your-secret-key"))
-	store.Options(sessions.Options{
+	// Liveness and readiness probes, registered before the session and metrics middleware
+	router.GET("/healthz", controllers.Healthz)
+	router.GET("/readyz", controllers.Readyz)
+	router.GET("/metrics", utils.MetricsHandler)
+
+	// Middleware must be added before the routes it applies to
+	router.Use(middleware...)
+
+	// Binding errors name fields as clients send them and are translated per Accept-Language
+	utils.RegisterValidationFieldNames()
+	router.Use(utils.BindingErrorMiddleware())
+
+	// Setup session middleware; SESSION_STORE=redis shares sessions between replicas
+	store, backend := utils.NewSessionStore(sessions.Options{
 		MaxAge:   60 * 60 * 24, // 1 day
 		Path:     "/",
 		Secure:   false, // Set to true in production with HTTPS
 		HttpOnly: true,
 	})
+	utils.LogInfo("Using %s session store", backend)
 	router.Use(sessions.Sessions("readsphere", store))
 
+	// Record request metrics for Prometheus and the admin API analytics; must be registered before the routes
+	router.Use(utils.RequestMetricsMiddleware())
+
 	// Root route for health check or info
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -31,6 +47,15 @@ func SetupRouter() *gin.Engine {
 		})
 	})
 
+	// API specification for client generation, built from the registered routes
+	router.GET("/swagger", controllers.SwaggerUI)
+	router.GET("/swagger/doc.json", controllers.OpenAPISpec(router))
+
+	// Serve uploaded media when it is stored on local disk
+	if local, ok := utils.GetStorage().(*utils.LocalStorage); ok {
+		router.Static("/uploads", local.Dir)
+	}
+
 
 	// Auth routes (for OAuth)
 	auth := router.Group("/auth")
@@ -89,10 +114,10 @@ func SetupRouter() *gin.Engine {
 	api := router.Group("/v1")
 	{
 		// Initialize user routes (includes regular auth routes)
-		initUserRoutes(api)
+		initUserRoutes(api, handlers)
 
 		// Initialize admin routes
-		initAdminRoutes(api)
+		initAdminRoutes(api, handlers)
 
 		// Initialize user profile routes
 		SetupUserProfileRoutes(router)
@@ -28,6 +28,14 @@ func SetupUserProfileRoutes(router *gin.Engine) {
 		// Change password
 		profile.PUT("/password", controllers.ChangePassword)
 
+		// Accounts created through Google: first password and profile completion
+		profile.POST("/password/set", controllers.SetInitialPassword)
+		profile.PUT("/complete", controllers.CompleteProfile)
+
+		// Google account linking
+		profile.GET("/google/link", controllers.GetGoogleLinkURL)
+		profile.DELETE("/google/link", controllers.UnlinkGoogle)
+
 		// Upload profile image
 		profile.POST("/image", controllers.UploadProfileImage)
 
@@ -37,6 +45,7 @@ func SetupUserProfileRoutes(router *gin.Engine) {
 		profile.DELETE("/address/:id", controllers.DeleteAddress)
 		profile.PUT("/address/:id/default", controllers.SetDefaultAddress)
 		profile.GET("/address", controllers.GetAddresses)
+		profile.GET("/address/:id", controllers.GetAddress)
 	}
 
 	utils.LogInfo("User profile routes registration completed")
@@ -11,7 +11,7 @@ import (
 )
 
 // initUserRoutes initializes all user-related routes
-func initUserRoutes(router *gin.RouterGroup) {
+func initUserRoutes(router *gin.RouterGroup, handlers *controllers.Handlers) {
 	utils.LogInfo("Initializing user routes")
 
 	// Public routes (no authentication required)
@@ -71,12 +71,39 @@ func initUserRoutes(router *gin.RouterGroup) {
 	router.POST("/forgot-password", controllers.ForgotPassword)
 	router.POST("/verify-reset-otp", controllers.VerifyResetOTP)
 	router.POST("/reset-password", controllers.ResetPassword)
+	router.POST("/auth/resend-otp", controllers.ResendOTP)
+	router.POST("/auth/phone/send-otp", controllers.SendPhoneLoginOTP)
+	router.POST("/auth/phone/verify-otp", controllers.VerifyPhoneLoginOTP)
+	router.POST("/auth/refresh", controllers.RefreshUserSession)
+	router.GET("/auth/captcha", controllers.GetCaptchaConfig)
+
+	// Storefront home page
+	router.GET("/home", utils.CatalogCacheMiddleware(), controllers.GetHome)
+	router.GET("/banners", utils.CatalogCacheMiddleware(), controllers.GetBanners)
+
+	// Content pages (About, Returns Policy, FAQ, ...)
+	router.GET("/pages", utils.CatalogCacheMiddleware(), controllers.GetPages)
+	router.GET("/pages/:slug", utils.CatalogCacheMiddleware(), controllers.GetPage)
 
 	// Book routes
-	router.GET("/books", controllers.GetBooks)
-	router.GET("/books/:id", controllers.GetBookDetails)
-	router.GET("/categories", controllers.ListCategories)
-	router.GET("/categories/:id/books", controllers.ListBooksByCategory)
+	router.GET("/books", utils.CatalogCacheMiddleware(), controllers.GetBooks)
+	router.GET("/books/:id", utils.CatalogCacheMiddleware(controllers.CountCachedBookView), middleware.OptionalAuthMiddleware(), handlers.Books.GetBookDetails)
+	router.POST("/books/:id/view", middleware.OptionalAuthMiddleware(), controllers.TrackBookView)
+	router.GET("/books/:id/images", controllers.GetBookImages)
+	router.GET("/books/:id/preview", controllers.StreamBookPreview)
+	router.GET("/library/files", controllers.DownloadLibraryFile)
+	router.GET("/currencies", controllers.GetCurrencies)
+	router.GET("/error-codes", controllers.GetErrorCodes)
+	router.GET("/categories", utils.CatalogCacheMiddleware(), controllers.ListCategories)
+	router.GET("/categories/:id/books", utils.CatalogCacheMiddleware(), controllers.ListBooksByCategory)
+	router.GET("/authors", utils.CatalogCacheMiddleware(), controllers.GetAuthors)
+	router.GET("/authors/:id", utils.CatalogCacheMiddleware(), controllers.GetAuthorDetails)
+	router.GET("/tags", utils.CatalogCacheMiddleware(), controllers.GetTags)
+	router.GET("/bundles", utils.CatalogCacheMiddleware(), controllers.GetBundles)
+	router.GET("/bundles/:id", utils.CatalogCacheMiddleware(), controllers.GetBundleDetails)
+
+	// Delivery serviceability for product pages
+	router.GET("/delivery/check", controllers.CheckDeliveryServiceability)
 
 	// Referral routes
 	router.GET("/referral/:code", controllers.GetReferralCodeInfo)
@@ -86,6 +113,14 @@ func initUserRoutes(router *gin.RouterGroup) {
 	protected := router.Group("/user")
 	protected.Use(middleware.AuthMiddleware())
 	{
+		// Account security
+		protected.POST("/change-password", controllers.ChangePassword)
+		protected.POST("/phone/send-otp", controllers.SendPhoneVerificationOTP)
+		protected.POST("/phone/verify", controllers.VerifyPhone)
+		protected.GET("/sessions", controllers.GetUserSessions)
+		protected.DELETE("/sessions", controllers.RevokeOtherUserSessions)
+		protected.DELETE("/sessions/:id", controllers.RevokeUserSession)
+
 		protected.POST("/checkout/payment/initiate", paymentcontroller.InitiateRazorpayPayment)
 		protected.POST("/checkout/payment/verify", paymentcontroller.VerifyRazorpayPayment)
 		protected.GET("/checkout/payment/methods", paymentcontroller.GetPaymentMethods)
@@ -98,24 +133,72 @@ func initUserRoutes(router *gin.RouterGroup) {
 		protected.PUT("/cart/update", controllers.UpdateCart)
 		protected.DELETE("/cart/remove", controllers.RemoveFromCart)
 		protected.DELETE("/cart/clear", controllers.ClearCart)
+		protected.POST("/cart/bundles", controllers.AddBundleToCart)
+		protected.DELETE("/cart/bundles", controllers.RemoveBundleFromCart)
+
+		// Save for later
+		protected.POST("/cart/save-for-later", controllers.SaveForLater)
+		protected.GET("/saved", controllers.GetSavedForLater)
+		protected.POST("/saved/move-to-cart", controllers.MoveSavedToCart)
+		protected.DELETE("/saved/remove", controllers.RemoveSavedItem)
+
+		// Digital library routes
+		protected.GET("/library", controllers.GetLibrary)
+		protected.GET("/library/:bookId/download", controllers.GetLibraryDownloadLink)
 
 		// Wishlist operations
 		protected.POST("/wishlist/add", controllers.AddToWishlist)
 		protected.GET("/wishlist", controllers.GetWishlist)
 		protected.DELETE("/wishlist/remove", controllers.RemoveFromWishlist)
 
+		// Recently viewed books
+		protected.GET("/recently-viewed", controllers.GetRecentlyViewed)
+		protected.DELETE("/recently-viewed", controllers.ClearRecentlyViewed)
+
+		// Personalized recommendations
+		protected.GET("/recommendations", controllers.GetRecommendations)
+
+		// Back-in-stock notifications
+		protected.POST("/books/:id/notify", controllers.SubscribeStockNotification)
+		protected.DELETE("/books/:id/notify", controllers.UnsubscribeStockNotification)
+		protected.GET("/stock-notifications", controllers.GetStockNotifications)
+
+		// Notification center and preferences
+		protected.GET("/notifications", controllers.GetNotifications)
+		protected.GET("/notifications/unread-count", controllers.GetUnreadNotificationCount)
+		protected.PUT("/notifications/read-all", controllers.MarkAllNotificationsRead)
+		protected.PUT("/notifications/:id/read", controllers.MarkNotificationRead)
+		protected.GET("/notification-preferences", controllers.GetNotificationPreferences)
+		protected.PUT("/notification-preferences", controllers.UpdateNotificationPreferences)
+
+		// Support tickets
+		protected.POST("/support/tickets", controllers.CreateSupportTicket)
+		protected.GET("/support/tickets", controllers.GetSupportTickets)
+		protected.GET("/support/tickets/:id", controllers.GetSupportTicket)
+		protected.POST("/support/tickets/:id/messages", controllers.AddSupportTicketMessage)
+		protected.POST("/support/tickets/:id/resolve", controllers.ResolveSupportTicket)
+
 		// Checkout
 		protected.GET("/checkout", controllers.GetCheckoutSummary)
+		protected.GET("/checkout/delivery-estimate", controllers.GetDeliveryEstimate)
 		protected.POST("/checkout", controllers.PlaceOrder)
+		protected.POST("/checkout/buy-now/summary", controllers.GetBuyNowSummary)
+		protected.POST("/checkout/buy-now", controllers.BuyNow)
 
 		// Orders
-		protected.GET("/orders", controllers.ListOrders)
-		protected.GET("/orders/:id", controllers.GetOrderDetails)
+		protected.GET("/orders", handlers.Orders.ListOrders)
+		protected.GET("/orders/:id", handlers.Orders.GetOrderDetails)
 		protected.POST("/orders/:id/cancel", controllers.CancelOrder)
+		protected.POST("/orders/:id/retry-payment", controllers.RetryOrderPayment)
+		protected.POST("/orders/:id/reorder", controllers.Reorder)
 		protected.POST("/orders/:id/items/:item_id/cancel", controllers.CancelOrderItem)
-		protected.POST("/orders/:id/return", controllers.ReturnOrder)
+		protected.POST("/orders/:id/cancellation-request", controllers.RequestOrderCancellation)
+		protected.POST("/orders/:id/return", handlers.Orders.ReturnOrder)
 		protected.POST("/orders/:id/items/:item_id/return", controllers.ReturnOrderItem)
+		protected.POST("/orders/:id/items/:item_id/exchange", controllers.ExchangeOrderItem)
 		protected.GET("/orders/:id/invoice", controllers.DownloadInvoice)
+		protected.GET("/orders/:id/invoice/details", controllers.GetInvoice)
+		protected.GET("/orders/:id/delivery-receipt", controllers.DownloadDeliveryReceipt)
 
 		// Logout
 		protected.POST("/logout", controllers.UserLogout)
@@ -123,23 +206,41 @@ func initUserRoutes(router *gin.RouterGroup) {
 		// Reviews
 		protected.POST("/books/:id/review", controllers.AddReview)
 		protected.GET("/books/:id/reviews", controllers.GetBookReviews)
+		protected.POST("/books/:id/reviews/:reviewId/report", controllers.ReportReview)
 
 		// Coupon routes
 		protected.POST("/coupons/apply", controllers.ApplyCoupon)
 		protected.POST("/coupons/remove", controllers.RemoveCoupon)
 		protected.GET("/coupons", controllers.GetCoupons)
+		protected.GET("/coupons/eligible", controllers.GetEligibleCoupons)
 
 		// Wallet routes
-		protected.GET("/wallet", controllers.GetWalletBalance)
-		protected.GET("/wallet/transactions", controllers.GetWalletTransactions)
+		protected.GET("/wallet", handlers.Wallet.GetWalletBalance)
+		protected.GET("/wallet/transactions", handlers.Wallet.GetWalletTransactions)
 		protected.POST("/wallet/topup/initiate", controllers.InitiateWalletTopup)
 		protected.POST("/wallet/topup/verify", controllers.VerifyWalletTopup)
 		// Test wallet topup payment simulation (only in development)
 		protected.GET("/wallet/topup/simulate", controllers.SimulateWalletTopupPayment)
 
+		// Gift cards
+		protected.GET("/gift-cards", controllers.ListMyGiftCards)
+		protected.POST("/gift-cards", controllers.PurchaseGiftCard)
+		protected.POST("/gift-cards/verify", controllers.VerifyGiftCardPayment)
+		protected.POST("/gift-cards/redeem", controllers.RedeemGiftCard)
+
+		// Consent and cookie preferences
+		protected.GET("/consent", controllers.GetConsent)
+		protected.PUT("/consent", controllers.UpdateConsent)
+		protected.GET("/consent/history", controllers.GetConsentHistory)
+
+		// Account deletion and data export
+		protected.POST("/account/delete", controllers.RequestAccountDeletion)
+		protected.GET("/data-export", controllers.ExportUserData)
+
 		// User referral routes
 		protected.GET("/referral/code", controllers.GetUserReferralCode)
 		protected.GET("/referral/list", controllers.GetUserReferrals)
+		protected.GET("/referral/rewards", controllers.GetUserReferralRewards)
 	}
 
 	utils.LogInfo("User routes initialization completed")
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/repositories"
+	"gorm.io/gorm"
+)
+
+// ErrBookUnavailable is returned when shoppers ask for a blocked or inactive book
+var ErrBookUnavailable = errors.New("book is not available")
+
+// BookService serves the book catalog
+type BookService struct {
+	books repositories.BookRepository
+}
+
+// NewBookService returns a BookService reading from books
+func NewBookService(books repositories.BookRepository) *BookService {
+	return &BookService{books: books}
+}
+
+// GetDetails returns a book with its images, category and genre. Unless includeHidden is set,
+// as it is for admins, deleted books are not found and blocked or inactive books are
+// unavailable.
+func (s *BookService) GetDetails(id uint, includeHidden bool) (*models.Book, error) {
+	if !includeHidden {
+		status, err := s.books.FindStatus(id)
+		if err != nil {
+			return nil, err
+		}
+		if status.DeletedAt != nil {
+			return nil, repositories.ErrNotFound
+		}
+		if status.Blocked || !status.IsActive {
+			return nil, ErrBookUnavailable
+		}
+	}
+	return s.books.FindWithDetails(id)
+}
+
+// ErrCategoryDeleted is returned when restoring a book whose category no longer exists
+var ErrCategoryDeleted = errors.New("category of the book has been deleted")
+
+// ISBNConflictError is returned when another book, active or trashed, already uses the ISBN.
+// The ISBN unique index covers trashed rows too, so a trashed book has to be restored (or its
+// ISBN changed) before the ISBN can be reused.
+type ISBNConflictError struct {
+	ISBN    string
+	BookID  uint
+	Trashed bool
+}
+
+func (e *ISBNConflictError) Error() string {
+	if e.Trashed {
+		return fmt.Sprintf("ISBN %s belongs to trashed book %d", e.ISBN, e.BookID)
+	}
+	return fmt.Sprintf("ISBN %s already exists for book %d", e.ISBN, e.BookID)
+}
+
+// CheckISBN returns an *ISBNConflictError when a book other than excludeID uses the ISBN
+func (s *BookService) CheckISBN(isbn string, excludeID uint) error {
+	existing, err := s.books.FindByISBN(isbn, excludeID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return &ISBNConflictError{ISBN: isbn, BookID: existing.ID, Trashed: existing.DeletedAt.Valid}
+}
+
+// Trash soft-deletes a book, keeping its images so it can be restored
+func (s *BookService) Trash(id uint) (*models.Book, error) {
+	book, err := s.books.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.books.Delete(book); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// ListTrash returns one page of trashed books and the number matching search
+func (s *BookService) ListTrash(search string, offset, limit int) ([]models.Book, int64, error) {
+	return s.books.ListTrashed(search, offset, limit)
+}
+
+// Restore takes a book out of the trash. Its category must still exist for it to show up in
+// the catalog, and no other book may have taken its ISBN in the meantime.
+func (s *BookService) Restore(id uint) (*models.Book, error) {
+	book, err := s.books.FindTrashed(id)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := s.books.CategoryExists(book.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return book, ErrCategoryDeleted
+	}
+	if err := s.CheckISBN(book.ISBN, book.ID); err != nil {
+		return book, err
+	}
+	if err := s.books.Restore(book); err != nil {
+		return nil, err
+	}
+	book.DeletedAt = gorm.DeletedAt{}
+	return book, nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeBookRepository keeps books and categories in memory; deleted books stay in the map
+type fakeBookRepository struct {
+	books      map[uint]*models.Book
+	categories map[uint]bool
+}
+
+func newFakeBookRepository(books ...models.Book) *fakeBookRepository {
+	r := &fakeBookRepository{books: make(map[uint]*models.Book), categories: make(map[uint]bool)}
+	for i := range books {
+		book := books[i]
+		r.books[book.ID] = &book
+		r.categories[book.CategoryID] = true
+	}
+	return r
+}
+
+func (r *fakeBookRepository) FindStatus(id uint) (*repositories.BookStatus, error) {
+	book, ok := r.books[id]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	status := &repositories.BookStatus{Blocked: book.Blocked, IsActive: book.IsActive}
+	if book.DeletedAt.Valid {
+		status.DeletedAt = &book.DeletedAt.Time
+	}
+	return status, nil
+}
+
+func (r *fakeBookRepository) FindWithDetails(id uint) (*models.Book, error) {
+	return r.FindByID(id)
+}
+
+func (r *fakeBookRepository) FindByID(id uint) (*models.Book, error) {
+	book, ok := r.books[id]
+	if !ok || book.DeletedAt.Valid {
+		return nil, repositories.ErrNotFound
+	}
+	found := *book
+	return &found, nil
+}
+
+func (r *fakeBookRepository) FindTrashed(id uint) (*models.Book, error) {
+	book, ok := r.books[id]
+	if !ok || !book.DeletedAt.Valid {
+		return nil, repositories.ErrNotFound
+	}
+	found := *book
+	return &found, nil
+}
+
+func (r *fakeBookRepository) FindByISBN(isbn string, excludeID uint) (*models.Book, error) {
+	for id, book := range r.books {
+		if id != excludeID && book.ISBN == isbn {
+			found := *book
+			return &found, nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (r *fakeBookRepository) ListTrashed(search string, offset, limit int) ([]models.Book, int64, error) {
+	var books []models.Book
+	for _, book := range r.books {
+		if book.DeletedAt.Valid {
+			books = append(books, *book)
+		}
+	}
+	return books, int64(len(books)), nil
+}
+
+func (r *fakeBookRepository) CategoryExists(id uint) (bool, error) {
+	return r.categories[id], nil
+}
+
+func (r *fakeBookRepository) Delete(book *models.Book) error {
+	r.books[book.ID].DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (r *fakeBookRepository) Restore(book *models.Book) error {
+	r.books[book.ID].DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+func testBook(id uint, isbn string) models.Book {
+	book := models.Book{Name: "Book", ISBN: isbn, CategoryID: 1, IsActive: true}
+	book.ID = id
+	return book
+}
+
+func TestBookServiceGetDetails(t *testing.T) {
+	blocked := testBook(2, "isbn-2")
+	blocked.Blocked = true
+	inactive := testBook(3, "isbn-3")
+	inactive.IsActive = false
+	repo := newFakeBookRepository(testBook(1, "isbn-1"), blocked, inactive)
+	service := NewBookService(repo)
+
+	book, err := service.GetDetails(1, false)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), book.ID)
+
+	_, err = service.GetDetails(2, false)
+	assert.ErrorIs(t, err, ErrBookUnavailable)
+	_, err = service.GetDetails(3, false)
+	assert.ErrorIs(t, err, ErrBookUnavailable)
+	_, err = service.GetDetails(4, false)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	book, err = service.GetDetails(2, true)
+	require.NoError(t, err, "admins see blocked books")
+	assert.Equal(t, uint(2), book.ID)
+
+	_, err = service.Trash(1)
+	require.NoError(t, err)
+	_, err = service.GetDetails(1, false)
+	assert.ErrorIs(t, err, repositories.ErrNotFound, "trashed books are not found")
+}
+
+func TestBookServiceTrashAndRestore(t *testing.T) {
+	repo := newFakeBookRepository(testBook(1, "isbn-1"))
+	service := NewBookService(repo)
+
+	_, err := service.Restore(1)
+	assert.ErrorIs(t, err, repositories.ErrNotFound, "only trashed books can be restored")
+
+	book, err := service.Trash(1)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), book.ID)
+	trashed, total, err := service.ListTrash("", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, trashed, 1)
+
+	book, err = service.Restore(1)
+	require.NoError(t, err)
+	assert.False(t, book.DeletedAt.Valid)
+	assert.False(t, repo.books[1].DeletedAt.Valid)
+
+	_, err = service.Trash(99)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+}
+
+func TestBookServiceRestoreChecksCategoryAndISBN(t *testing.T) {
+	repo := newFakeBookRepository(testBook(1, "isbn-1"))
+	service := NewBookService(repo)
+	_, err := service.Trash(1)
+	require.NoError(t, err)
+
+	repo.categories[1] = false
+	_, err = service.Restore(1)
+	assert.ErrorIs(t, err, ErrCategoryDeleted)
+	repo.categories[1] = true
+
+	// Another book took the ISBN while the first was in the trash
+	taken := testBook(2, "isbn-1")
+	repo.books[2] = &taken
+	_, err = service.Restore(1)
+	var conflict *ISBNConflictError
+	require.True(t, errors.As(err, &conflict))
+	assert.Equal(t, uint(2), conflict.BookID)
+	assert.False(t, conflict.Trashed)
+	assert.True(t, repo.books[1].DeletedAt.Valid, "the book stays in the trash")
+}
+
+func TestBookServiceCheckISBN(t *testing.T) {
+	repo := newFakeBookRepository(testBook(1, "isbn-1"))
+	service := NewBookService(repo)
+	_, err := service.Trash(1)
+	require.NoError(t, err)
+
+	err = service.CheckISBN("isbn-1", 0)
+	var conflict *ISBNConflictError
+	require.True(t, errors.As(err, &conflict))
+	assert.True(t, conflict.Trashed)
+	assert.Equal(t, uint(1), conflict.BookID)
+
+	assert.NoError(t, service.CheckISBN("isbn-1", 1), "a book does not conflict with itself")
+	assert.NoError(t, service.CheckISBN("isbn-9", 0))
+}
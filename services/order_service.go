@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/repositories"
+)
+
+// Errors returned when an order cannot be returned
+var (
+	ErrOrderNotDelivered  = errors.New("only delivered orders can be returned")
+	ErrExchangeInProgress = errors.New("some items have an exchange request")
+)
+
+// ReturnWindowExpiredError is returned when an item of the order is past its return window
+type ReturnWindowExpiredError struct {
+	ItemID uint
+	Window time.Duration
+}
+
+// Days is the length of the return window in whole days
+func (e *ReturnWindowExpiredError) Days() int {
+	return int(e.Window.Hours() / 24)
+}
+
+func (e *ReturnWindowExpiredError) Error() string {
+	return fmt.Sprintf("return window of %d days has expired for item %d", e.Days(), e.ItemID)
+}
+
+// OrderService serves customers' orders
+type OrderService struct {
+	orders             repositories.OrderRepository
+	cancellationWindow func() time.Duration
+	returnWindow       func(models.Category) time.Duration
+}
+
+// NewOrderService returns an OrderService reading from orders. cancellationWindow reports how
+// long after placing an order the customer may still cancel it, and returnWindow how long
+// after delivery a book of the category may be returned.
+func NewOrderService(orders repositories.OrderRepository, cancellationWindow func() time.Duration, returnWindow func(models.Category) time.Duration) *OrderService {
+	return &OrderService{orders: orders, cancellationWindow: cancellationWindow, returnWindow: returnWindow}
+}
+
+// OrderActions are the actions a customer can currently take on an order
+type OrderActions struct {
+	CanCancel bool
+	CanReturn bool
+}
+
+// List returns one page of the user's orders and the number of orders matching the filter
+func (s *OrderService) List(userID uint, filter repositories.OrderFilter) ([]models.Order, int64, error) {
+	return s.orders.ListByUser(userID, filter)
+}
+
+// Get returns the user's order, or repositories.ErrNotFound when it is not theirs
+func (s *OrderService) Get(userID, orderID uint) (*models.Order, error) {
+	return s.orders.FindByUser(orderID, userID)
+}
+
+// Shipments returns the shipments of the order, empty unless it ships in several boxes
+func (s *OrderService) Shipments(orderID uint) ([]models.OrderShipment, error) {
+	return s.orders.Shipments(orderID)
+}
+
+// Actions reports what the customer may do with the order at now. Orders can be cancelled
+// within the cancellation window while placed or paid, and returned once delivered.
+func (s *OrderService) Actions(order *models.Order, now time.Time) OrderActions {
+	age := now.Sub(order.CreatedAt)
+	cancellable := order.Status == models.OrderStatusPlaced || order.Status == models.OrderStatusPaid
+	return OrderActions{
+		// A creation time in the future means clock trouble; do not offer cancellation then
+		CanCancel: cancellable && age >= 0 && age <= s.cancellationWindow(),
+		CanReturn: order.Status == models.OrderStatusDelivered,
+	}
+}
+
+// RequestReturn asks to return every item of a delivered order at now. Items with an exchange
+// in progress must be returned on their own, and every item must be within the return window
+// of its category, counted from the order's last update.
+func (s *OrderService) RequestReturn(userID, orderID uint, reason string, now time.Time) (*models.Order, error) {
+	order, err := s.orders.FindWithItemCategories(orderID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderStatusDelivered {
+		return order, ErrOrderNotDelivered
+	}
+	for _, item := range order.OrderItems {
+		if item.ExchangeStatus != "" && item.ExchangeStatus != models.ExchangeStatusRejected {
+			return order, ErrExchangeInProgress
+		}
+		window := s.returnWindow(item.Book.Category)
+		if now.Sub(order.UpdatedAt) > window {
+			return order, &ReturnWindowExpiredError{ItemID: item.ID, Window: window}
+		}
+	}
+
+	for i := range order.OrderItems {
+		order.OrderItems[i].ReturnRequested = true
+		order.OrderItems[i].ReturnReason = reason
+		order.OrderItems[i].ReturnStatus = "Pending"
+	}
+	order.Status = models.OrderStatusReturnRequested
+	order.ReturnReason = reason
+	order.HasItemReturnRequests = true
+	order.UpdatedAt = now
+	if err := s.orders.SaveWithItems(order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOrderRepository keeps orders in memory, keyed by ID
+type fakeOrderRepository struct {
+	orders map[uint]*models.Order
+	saved  int
+}
+
+func newFakeOrderRepository(orders ...models.Order) *fakeOrderRepository {
+	r := &fakeOrderRepository{orders: make(map[uint]*models.Order)}
+	for i := range orders {
+		order := orders[i]
+		r.orders[order.ID] = &order
+	}
+	return r
+}
+
+func (r *fakeOrderRepository) ListByUser(userID uint, filter repositories.OrderFilter) ([]models.Order, int64, error) {
+	var orders []models.Order
+	for _, order := range r.orders {
+		if order.UserID == userID && (filter.Status == "" || order.Status == filter.Status) {
+			orders = append(orders, *order)
+		}
+	}
+	return orders, int64(len(orders)), nil
+}
+
+func (r *fakeOrderRepository) FindByUser(orderID, userID uint) (*models.Order, error) {
+	order, ok := r.orders[orderID]
+	if !ok || order.UserID != userID {
+		return nil, repositories.ErrNotFound
+	}
+	found := *order
+	found.OrderItems = append([]models.OrderItem(nil), order.OrderItems...)
+	return &found, nil
+}
+
+func (r *fakeOrderRepository) Shipments(orderID uint) ([]models.OrderShipment, error) {
+	return nil, nil
+}
+
+func (r *fakeOrderRepository) FindWithItemCategories(orderID, userID uint) (*models.Order, error) {
+	return r.FindByUser(orderID, userID)
+}
+
+func (r *fakeOrderRepository) SaveWithItems(order *models.Order) error {
+	saved := *order
+	r.orders[order.ID] = &saved
+	r.saved++
+	return nil
+}
+
+// testReturnWindow allows returns for 7 days, or 30 for the category with ID 2
+func testReturnWindow(category models.Category) time.Duration {
+	if category.ID == 2 {
+		return 30 * 24 * time.Hour
+	}
+	return 7 * 24 * time.Hour
+}
+
+func newTestOrderService(repo repositories.OrderRepository) *OrderService {
+	return NewOrderService(repo, func() time.Duration { return time.Hour }, testReturnWindow)
+}
+
+// testOrder is a delivered order of user 1 with one item per category ID
+func testOrder(id uint, updatedAt time.Time, categoryIDs ...uint) models.Order {
+	order := models.Order{UserID: 1, Status: models.OrderStatusDelivered, TotalAmount: 500, FinalTotal: 450}
+	order.ID = id
+	order.UpdatedAt = updatedAt
+	for i, categoryID := range categoryIDs {
+		item := models.OrderItem{OrderID: id, Quantity: 1}
+		item.ID = uint(i + 1)
+		item.Book.Category.ID = categoryID
+		order.OrderItems = append(order.OrderItems, item)
+	}
+	return order
+}
+
+func TestOrderServiceRequestReturn(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	repo := newFakeOrderRepository(testOrder(1, now.Add(-3*24*time.Hour), 1, 2))
+	service := newTestOrderService(repo)
+
+	order, err := service.RequestReturn(1, 1, "Damaged", now)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusReturnRequested, order.Status)
+	assert.Equal(t, "Damaged", order.ReturnReason)
+	assert.True(t, order.HasItemReturnRequests)
+	for _, item := range repo.orders[1].OrderItems {
+		assert.True(t, item.ReturnRequested)
+		assert.Equal(t, "Damaged", item.ReturnReason)
+		assert.Equal(t, "Pending", item.ReturnStatus)
+	}
+	assert.Equal(t, 1, repo.saved)
+
+	_, err = service.RequestReturn(1, 1, "Again", now)
+	assert.ErrorIs(t, err, ErrOrderNotDelivered, "an order is returned once")
+	_, err = service.RequestReturn(2, 1, "Not mine", now)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+}
+
+func TestOrderServiceRequestReturnChecksEveryItem(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	exchanging := testOrder(2, now.Add(-24*time.Hour), 1, 1)
+	exchanging.OrderItems[1].ExchangeStatus = "Requested"
+	rejectedExchange := testOrder(3, now.Add(-24*time.Hour), 1)
+	rejectedExchange.OrderItems[0].ExchangeStatus = models.ExchangeStatusRejected
+	shipped := testOrder(4, now.Add(-24*time.Hour), 1)
+	shipped.Status = models.OrderStatusShipped
+	repo := newFakeOrderRepository(
+		testOrder(1, now.Add(-10*24*time.Hour), 2, 1), // the 7 day window has passed for the second item
+		exchanging, rejectedExchange, shipped,
+	)
+	service := newTestOrderService(repo)
+
+	_, err := service.RequestReturn(1, 1, "Late", now)
+	var expired *ReturnWindowExpiredError
+	require.True(t, errors.As(err, &expired))
+	assert.Equal(t, uint(2), expired.ItemID)
+	assert.Equal(t, 7, expired.Days())
+
+	_, err = service.RequestReturn(1, 2, "Exchanging", now)
+	assert.ErrorIs(t, err, ErrExchangeInProgress)
+
+	_, err = service.RequestReturn(1, 3, "Exchange was rejected", now)
+	assert.NoError(t, err)
+
+	_, err = service.RequestReturn(1, 4, "Not delivered", now)
+	assert.ErrorIs(t, err, ErrOrderNotDelivered)
+
+	assert.Equal(t, 1, repo.saved, "only the valid request is saved")
+	assert.Equal(t, models.OrderStatusDelivered, repo.orders[1].Status)
+}
+
+func TestOrderServiceActions(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	service := newTestOrderService(newFakeOrderRepository())
+
+	tests := []struct {
+		name   string
+		status string
+		age    time.Duration
+		want   OrderActions
+	}{
+		{"placed within the window", models.OrderStatusPlaced, 30 * time.Minute, OrderActions{CanCancel: true}},
+		{"paid within the window", models.OrderStatusPaid, 30 * time.Minute, OrderActions{CanCancel: true}},
+		{"placed after the window", models.OrderStatusPlaced, 2 * time.Hour, OrderActions{}},
+		{"created in the future", models.OrderStatusPlaced, -time.Minute, OrderActions{}},
+		{"shipped", models.OrderStatusShipped, 30 * time.Minute, OrderActions{}},
+		{"delivered", models.OrderStatusDelivered, 48 * time.Hour, OrderActions{CanReturn: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &models.Order{Status: tt.status}
+			order.CreatedAt = now.Add(-tt.age)
+			assert.Equal(t, tt.want, service.Actions(order, now))
+		})
+	}
+}
@@ -0,0 +1,3 @@
+// Package services holds business logic shared by the HTTP handlers. Services depend only on
+// repository interfaces, so they can be unit tested without a database.
+package services
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/repositories"
+)
+
+// ErrInvalidTransactionType is returned for a transaction type filter other than credit, debit or hold
+var ErrInvalidTransactionType = errors.New("type must be 'credit', 'debit' or 'hold'")
+
+// WalletService serves wallet balances and ledgers
+type WalletService struct {
+	wallets repositories.WalletRepository
+	users   repositories.UserRepository
+}
+
+// NewWalletService returns a WalletService reading from wallets and users
+func NewWalletService(wallets repositories.WalletRepository, users repositories.UserRepository) *WalletService {
+	return &WalletService{wallets: wallets, users: users}
+}
+
+// LedgerEntry is a wallet transaction with the balance right after it
+type LedgerEntry struct {
+	models.WalletTransaction
+	BalanceAfter float64
+}
+
+// Ledger is one page of a wallet's transactions
+type Ledger struct {
+	Wallet  *models.Wallet
+	Entries []LedgerEntry
+	Total   int64
+}
+
+// Balance returns the user's wallet, creating it if needed
+func (s *WalletService) Balance(userID uint) (*models.Wallet, error) {
+	return s.wallets.GetOrCreate(userID)
+}
+
+// UserLedger returns a page of the user's own ledger, creating the wallet if needed
+func (s *WalletService) UserLedger(userID uint, filter repositories.TransactionFilter) (*Ledger, error) {
+	if err := validateTransactionFilter(filter); err != nil {
+		return nil, err
+	}
+	wallet, err := s.wallets.GetOrCreate(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.ledger(wallet, filter)
+}
+
+// AdminLedger returns the user and a page of their ledger. A user without a wallet gets an
+// empty ledger with a zero balance; an unknown user is repositories.ErrNotFound.
+func (s *WalletService) AdminLedger(userID uint, filter repositories.TransactionFilter) (*models.User, *Ledger, error) {
+	user, err := s.users.FindByID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := validateTransactionFilter(filter); err != nil {
+		return nil, nil, err
+	}
+
+	wallet, err := s.wallets.FindByUser(userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return user, &Ledger{Wallet: &models.Wallet{UserID: userID}}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	ledger, err := s.ledger(wallet, filter)
+	return user, ledger, err
+}
+
+// ledger loads a page of the wallet's transactions with their running balances
+func (s *WalletService) ledger(wallet *models.Wallet, filter repositories.TransactionFilter) (*Ledger, error) {
+	transactions, total, err := s.wallets.ListTransactions(wallet.ID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(transactions))
+	for i, txn := range transactions {
+		ids[i] = txn.ID
+	}
+	balances, err := s.wallets.BalancesAfter(wallet.ID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]LedgerEntry, len(transactions))
+	for i, txn := range transactions {
+		entries[i] = LedgerEntry{WalletTransaction: txn, BalanceAfter: balances[txn.ID]}
+	}
+	return &Ledger{Wallet: wallet, Entries: entries, Total: total}, nil
+}
+
+// validateTransactionFilter rejects filters the ledger cannot apply
+func validateTransactionFilter(filter repositories.TransactionFilter) error {
+	if filter.Type != "" && filter.Type != models.TransactionTypeCredit && filter.Type != models.TransactionTypeDebit && filter.Type != models.TransactionTypeHold {
+		return ErrInvalidTransactionType
+	}
+	return nil
+}
+
+// mismatchTolerance absorbs floating point noise when comparing balances with ledger sums
+const mismatchTolerance = 0.005
+
+// ReconcileResult summarizes a reconciliation run
+type ReconcileResult struct {
+	Checked    int
+	Mismatched int // wallets currently flagged
+	Resolved   int // flags cleared by this run
+}
+
+// Reconcile compares every wallet's balance with the sum of its completed ledger entries.
+// Wallets that disagree are flagged, or their flag is refreshed; flagged wallets that agree
+// again are marked resolved.
+func (s *WalletService) Reconcile(now time.Time) (*ReconcileResult, error) {
+	balances, err := s.wallets.LedgerBalances()
+	if err != nil {
+		return nil, err
+	}
+	flagged, err := s.wallets.ListMismatches(true)
+	if err != nil {
+		return nil, err
+	}
+	byWallet := make(map[uint]*models.WalletMismatch, len(flagged))
+	for i := range flagged {
+		byWallet[flagged[i].WalletID] = &flagged[i]
+	}
+
+	result := &ReconcileResult{Checked: len(balances)}
+	for _, b := range balances {
+		difference := b.Balance - b.LedgerBalance
+		mismatch := byWallet[b.WalletID]
+
+		if math.Abs(difference) <= mismatchTolerance {
+			if mismatch != nil && mismatch.ResolvedAt == nil {
+				mismatch.ResolvedAt = &now
+				mismatch.LastCheckedAt = now
+				if err := s.wallets.SaveMismatch(mismatch); err != nil {
+					return nil, err
+				}
+				result.Resolved++
+			}
+			continue
+		}
+
+		if mismatch == nil {
+			mismatch = &models.WalletMismatch{WalletID: b.WalletID}
+		}
+		if mismatch.ID == 0 || mismatch.ResolvedAt != nil {
+			mismatch.FirstDetectedAt = now
+			mismatch.ResolvedAt = nil
+		}
+		mismatch.UserID = b.UserID
+		mismatch.Balance = b.Balance
+		mismatch.LedgerBalance = b.LedgerBalance
+		mismatch.Difference = math.Round(difference*100) / 100
+		mismatch.LastCheckedAt = now
+		if err := s.wallets.SaveMismatch(mismatch); err != nil {
+			return nil, err
+		}
+		result.Mismatched++
+	}
+	return result, nil
+}
+
+// Mismatches returns the wallets flagged by reconciliation
+func (s *WalletService) Mismatches(includeResolved bool) ([]models.WalletMismatch, error) {
+	return s.wallets.ListMismatches(includeResolved)
+}
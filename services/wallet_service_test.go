@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeWalletRepository keeps wallets, ledgers and flags in memory
+type fakeWalletRepository struct {
+	wallets      map[uint]*models.Wallet // by user ID
+	transactions map[uint][]models.WalletTransaction
+	balances     map[uint]float64 // balance after each transaction ID
+	ledger       []repositories.LedgerBalance
+	mismatches   []models.WalletMismatch
+	nextID       uint
+}
+
+func newFakeWalletRepository() *fakeWalletRepository {
+	return &fakeWalletRepository{
+		wallets:      make(map[uint]*models.Wallet),
+		transactions: make(map[uint][]models.WalletTransaction),
+		balances:     make(map[uint]float64),
+	}
+}
+
+func (r *fakeWalletRepository) GetOrCreate(userID uint) (*models.Wallet, error) {
+	if wallet, ok := r.wallets[userID]; ok {
+		return wallet, nil
+	}
+	r.nextID++
+	wallet := &models.Wallet{UserID: userID}
+	wallet.ID = r.nextID
+	r.wallets[userID] = wallet
+	return wallet, nil
+}
+
+func (r *fakeWalletRepository) FindByUser(userID uint) (*models.Wallet, error) {
+	if wallet, ok := r.wallets[userID]; ok {
+		return wallet, nil
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (r *fakeWalletRepository) ListTransactions(walletID uint, filter repositories.TransactionFilter) ([]models.WalletTransaction, int64, error) {
+	var matching []models.WalletTransaction
+	for _, txn := range r.transactions[walletID] {
+		if filter.Type == "" || txn.Type == filter.Type {
+			matching = append(matching, txn)
+		}
+	}
+	return matching, int64(len(matching)), nil
+}
+
+func (r *fakeWalletRepository) BalancesAfter(walletID uint, transactionIDs []uint) (map[uint]float64, error) {
+	balances := make(map[uint]float64, len(transactionIDs))
+	for _, id := range transactionIDs {
+		balances[id] = r.balances[id]
+	}
+	return balances, nil
+}
+
+func (r *fakeWalletRepository) LedgerBalances() ([]repositories.LedgerBalance, error) {
+	return r.ledger, nil
+}
+
+func (r *fakeWalletRepository) ListMismatches(includeResolved bool) ([]models.WalletMismatch, error) {
+	var mismatches []models.WalletMismatch
+	for _, mismatch := range r.mismatches {
+		if includeResolved || mismatch.ResolvedAt == nil {
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+	return mismatches, nil
+}
+
+func (r *fakeWalletRepository) SaveMismatch(mismatch *models.WalletMismatch) error {
+	for i := range r.mismatches {
+		if r.mismatches[i].ID == mismatch.ID {
+			r.mismatches[i] = *mismatch
+			return nil
+		}
+	}
+	mismatch.ID = uint(len(r.mismatches) + 1)
+	r.mismatches = append(r.mismatches, *mismatch)
+	return nil
+}
+
+// fakeUserRepository finds the users it was given
+type fakeUserRepository map[uint]models.User
+
+func (r fakeUserRepository) FindByID(id uint) (*models.User, error) {
+	user, ok := r[id]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return &user, nil
+}
+
+func TestWalletServiceAdminLedger(t *testing.T) {
+	wallets := newFakeWalletRepository()
+	users := fakeUserRepository{
+		1: {Model: gorm.Model{ID: 1}, Username: "with-wallet"},
+		2: {Model: gorm.Model{ID: 2}, Username: "without-wallet"},
+	}
+	wallet, _ := wallets.GetOrCreate(1)
+	wallet.Balance = 150
+	credit := models.WalletTransaction{WalletID: wallet.ID, Amount: 200, Type: models.TransactionTypeCredit}
+	credit.ID = 10
+	debit := models.WalletTransaction{WalletID: wallet.ID, Amount: 50, Type: models.TransactionTypeDebit}
+	debit.ID = 11
+	wallets.transactions[wallet.ID] = []models.WalletTransaction{debit, credit}
+	wallets.balances[10] = 200
+	wallets.balances[11] = 150
+	service := NewWalletService(wallets, users)
+
+	user, ledger, err := service.AdminLedger(1, repositories.TransactionFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, "with-wallet", user.Username)
+	assert.Equal(t, int64(2), ledger.Total)
+	require.Len(t, ledger.Entries, 2)
+	assert.Equal(t, 150.0, ledger.Entries[0].BalanceAfter)
+	assert.Equal(t, 200.0, ledger.Entries[1].BalanceAfter)
+
+	_, ledger, err = service.AdminLedger(1, repositories.TransactionFilter{Type: models.TransactionTypeCredit})
+	require.NoError(t, err)
+	require.Len(t, ledger.Entries, 1)
+	assert.Equal(t, uint(10), ledger.Entries[0].ID)
+
+	user, ledger, err = service.AdminLedger(2, repositories.TransactionFilter{})
+	require.NoError(t, err, "a user without a wallet gets an empty ledger")
+	assert.Equal(t, "without-wallet", user.Username)
+	assert.Empty(t, ledger.Entries)
+	assert.Equal(t, 0.0, ledger.Wallet.Balance)
+	_, err = wallets.FindByUser(2)
+	assert.ErrorIs(t, err, repositories.ErrNotFound, "viewing the ledger does not create a wallet")
+
+	_, _, err = service.AdminLedger(3, repositories.TransactionFilter{})
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	_, _, err = service.AdminLedger(1, repositories.TransactionFilter{Type: "refund"})
+	assert.True(t, errors.Is(err, ErrInvalidTransactionType))
+}
+
+func TestWalletServiceReconcile(t *testing.T) {
+	earlier := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	now := earlier.Add(24 * time.Hour)
+	resolvedAt := earlier.Add(time.Hour)
+
+	wallets := newFakeWalletRepository()
+	wallets.ledger = []repositories.LedgerBalance{
+		{WalletID: 1, UserID: 11, Balance: 100, LedgerBalance: 100},     // agrees
+		{WalletID: 2, UserID: 12, Balance: 100, LedgerBalance: 99.999},  // within the tolerance
+		{WalletID: 3, UserID: 13, Balance: 120, LedgerBalance: 100},     // newly off
+		{WalletID: 4, UserID: 14, Balance: 80, LedgerBalance: 100},      // still off
+		{WalletID: 5, UserID: 15, Balance: 50, LedgerBalance: 50},       // fixed since
+		{WalletID: 6, UserID: 16, Balance: 10.123, LedgerBalance: 10.1}, // off again after a fix
+	}
+	wallets.mismatches = []models.WalletMismatch{
+		{ID: 1, WalletID: 4, UserID: 14, Difference: -10, FirstDetectedAt: earlier, LastCheckedAt: earlier},
+		{ID: 2, WalletID: 5, UserID: 15, Difference: 5, FirstDetectedAt: earlier, LastCheckedAt: earlier},
+		{ID: 3, WalletID: 6, UserID: 16, Difference: 1, FirstDetectedAt: earlier, LastCheckedAt: earlier, ResolvedAt: &resolvedAt},
+	}
+	service := NewWalletService(wallets, fakeUserRepository{})
+
+	result, err := service.Reconcile(now)
+	require.NoError(t, err)
+	assert.Equal(t, &ReconcileResult{Checked: 6, Mismatched: 3, Resolved: 1}, result)
+
+	byWallet := make(map[uint]models.WalletMismatch)
+	for _, mismatch := range wallets.mismatches {
+		byWallet[mismatch.WalletID] = mismatch
+	}
+	assert.NotContains(t, byWallet, uint(1))
+	assert.NotContains(t, byWallet, uint(2))
+
+	assert.Equal(t, 20.0, byWallet[3].Difference)
+	assert.Equal(t, now, byWallet[3].FirstDetectedAt)
+	assert.Nil(t, byWallet[3].ResolvedAt)
+
+	assert.Equal(t, -20.0, byWallet[4].Difference, "an open flag is refreshed")
+	assert.Equal(t, earlier, byWallet[4].FirstDetectedAt, "an open flag keeps when it was first seen")
+	assert.Equal(t, now, byWallet[4].LastCheckedAt)
+
+	require.NotNil(t, byWallet[5].ResolvedAt)
+	assert.Equal(t, now, *byWallet[5].ResolvedAt)
+
+	assert.Nil(t, byWallet[6].ResolvedAt, "a resolved flag is reopened")
+	assert.Equal(t, now, byWallet[6].FirstDetectedAt)
+	assert.Equal(t, 0.02, byWallet[6].Difference)
+}
@@ -0,0 +1,105 @@
+// Package testutil holds helpers shared by the tests of other packages
+package testutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// NewDB opens an empty database with tables for the given models and makes it config.DB for
+// the rest of the test. With TEST_DATABASE_URL set each test gets its own schema in that
+// Postgres database, so row locks behave as in production; otherwise it gets a fresh SQLite
+// file whose transactions take the write lock when they begin, so concurrent transactions run
+// one after another instead of failing.
+func NewDB(t testing.TB, tables ...interface{}) *gorm.DB {
+	t.Helper()
+
+	gormConfig := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	var db *gorm.DB
+	if dsn := os.Getenv("TEST_DATABASE_URL"); dsn != "" {
+		db = newPostgresSchema(t, dsn, gormConfig)
+	} else {
+		path := filepath.Join(t.TempDir(), "test.db")
+		var err error
+		db, err = gorm.Open(sqlite.Open("file:"+path+"?_txlock=immediate&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"), gormConfig)
+		if err != nil {
+			t.Fatalf("open test database: %v", err)
+		}
+		t.Cleanup(func() {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		})
+	}
+
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() { config.DB = previous })
+	return db
+}
+
+// newPostgresSchema creates a schema for the test in the database at dsn, connects with it as
+// the search path and drops it when the test ends
+func newPostgresSchema(t testing.TB, dsn string, gormConfig *gorm.Config) *gorm.DB {
+	t.Helper()
+
+	admin, err := gorm.Open(postgres.Open(dsn), gormConfig)
+	if err != nil {
+		t.Fatalf("connect to TEST_DATABASE_URL: %v", err)
+	}
+	schema := fmt.Sprintf("test_%s_%d", strings.ToLower(sanitize(t.Name())), time.Now().UnixNano())
+	if err := admin.Exec(`CREATE SCHEMA "` + schema + `"`).Error; err != nil {
+		t.Fatalf("create test schema: %v", err)
+	}
+
+	schemaDSN := dsn + " search_path=" + schema
+	if strings.Contains(dsn, "://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		schemaDSN = dsn + separator + "search_path=" + schema
+	}
+	db, err := gorm.Open(postgres.Open(schemaDSN), gormConfig)
+	if err != nil {
+		t.Fatalf("connect to test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec(`DROP SCHEMA "` + schema + `" CASCADE`)
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// sanitize keeps the letters and digits of a test name for use in an identifier
+func sanitize(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+		if b.Len() == 30 {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// abandonedCartMaxReminders is how many reminders are sent for one abandoned cart
+const abandonedCartMaxReminders = 2
+
+// AbandonedCartRun counts what one pass over abandoned carts did
+type AbandonedCartRun struct {
+	Detected  int
+	Reminded  int
+	Recovered int
+	Closed    int
+}
+
+// abandonedCartSnapshot is a user's cart as the abandoned cart job sees it
+type abandonedCartSnapshot struct {
+	UserID         uint
+	ItemCount      int
+	Quantity       int
+	CartValue      float64
+	LastActivityAt time.Time
+}
+
+// ProcessAbandonedCarts resolves open abandoned carts whose owner has since ordered or whose
+// cart is gone, records carts left untouched for the abandoned_cart_hours setting and sends
+// their owners up to two reminders, if they granted marketing consent and kept promotions on
+func ProcessAbandonedCarts() (AbandonedCartRun, error) {
+	var run AbandonedCartRun
+	if err := resolveAbandonedCarts(&run); err != nil {
+		return run, err
+	}
+
+	now := time.Now()
+	var carts []abandonedCartSnapshot
+	if err := config.DB.Model(&models.Cart{}).
+		Select("user_id, COUNT(*) AS item_count, SUM(quantity) AS quantity, SUM(price_at_add * quantity) AS cart_value, MAX(updated_at) AS last_activity_at").
+		Where("updated_at >= ?", now.Add(-cartTTL)).
+		Group("user_id").
+		Having("MAX(updated_at) < ?", now.Add(-AbandonedCartAfter())).
+		Scan(&carts).Error; err != nil {
+		return run, err
+	}
+
+	for _, cart := range carts {
+		record, created, err := trackAbandonedCart(cart)
+		if err != nil {
+			LogError("Failed to record abandoned cart of user ID: %d: %v", cart.UserID, err)
+			continue
+		}
+		if created {
+			run.Detected++
+		}
+		if !abandonedCartReminderDue(*record, now) {
+			continue
+		}
+		sent, err := sendAbandonedCartReminder(record, now)
+		if err != nil {
+			LogError("Failed to send abandoned cart reminder to user ID: %d: %v", cart.UserID, err)
+			continue
+		}
+		if sent {
+			run.Reminded++
+		}
+	}
+
+	if run.Detected+run.Reminded+run.Recovered+run.Closed > 0 {
+		LogInfo("Abandoned carts: %d detected, %d reminded, %d recovered, %d closed",
+			run.Detected, run.Reminded, run.Recovered, run.Closed)
+	}
+	return run, nil
+}
+
+// resolveAbandonedCarts marks open abandoned carts recovered when their owner has placed an
+// order from the cart since, or closed when the cart was emptied or expired
+func resolveAbandonedCarts(run *AbandonedCartRun) error {
+	var open []models.AbandonedCart
+	if err := config.DB.Where("status = ?", models.AbandonedCartStatusOpen).Find(&open).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, record := range open {
+		// Online orders only count once paid, so an order left unpaid does not recover the cart
+		var order models.Order
+		err := config.DB.
+			Where("user_id = ? AND created_at >= ? AND buy_now = ? AND status <> ?",
+				record.UserID, record.CreatedAt, false, models.OrderStatusCancelled).
+			Where("payment_method IN ? OR payment_status = ?", []string{"cod", "wallet"}, models.PaymentStatusCompleted).
+			Order("created_at").First(&order).Error
+		if err == nil {
+			if err := config.DB.Model(&record).Updates(map[string]interface{}{
+				"status":             models.AbandonedCartStatusRecovered,
+				"recovered_order_id": order.ID,
+				"recovered_value":    order.TotalWithDelivery,
+				"resolved_at":        now,
+			}).Error; err != nil {
+				return err
+			}
+			run.Recovered++
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var items int64
+		if err := config.DB.Model(&models.Cart{}).
+			Where("user_id = ? AND updated_at >= ?", record.UserID, now.Add(-cartTTL)).
+			Count(&items).Error; err != nil {
+			return err
+		}
+		if items == 0 {
+			if err := config.DB.Model(&record).Updates(map[string]interface{}{
+				"status":      models.AbandonedCartStatusClosed,
+				"resolved_at": now,
+			}).Error; err != nil {
+				return err
+			}
+			run.Closed++
+		}
+	}
+	return nil
+}
+
+// trackAbandonedCart records the cart as abandoned, or refreshes the user's open record when
+// they changed the cart since it was last checked. It reports whether a record was created.
+func trackAbandonedCart(cart abandonedCartSnapshot) (*models.AbandonedCart, bool, error) {
+	var record models.AbandonedCart
+	err := config.DB.Where("user_id = ? AND status = ?", cart.UserID, models.AbandonedCartStatusOpen).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		record = models.AbandonedCart{
+			UserID:         cart.UserID,
+			Status:         models.AbandonedCartStatusOpen,
+			ItemCount:      cart.ItemCount,
+			Quantity:       cart.Quantity,
+			CartValue:      cart.CartValue,
+			LastActivityAt: cart.LastActivityAt,
+		}
+		if err := config.DB.Create(&record).Error; err != nil {
+			return nil, false, err
+		}
+		return &record, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if cart.LastActivityAt.After(record.LastActivityAt) {
+		record.ItemCount = cart.ItemCount
+		record.Quantity = cart.Quantity
+		record.CartValue = cart.CartValue
+		record.LastActivityAt = cart.LastActivityAt
+		if err := config.DB.Model(&record).Updates(map[string]interface{}{
+			"item_count":       record.ItemCount,
+			"quantity":         record.Quantity,
+			"cart_value":       record.CartValue,
+			"last_activity_at": record.LastActivityAt,
+		}).Error; err != nil {
+			return nil, false, err
+		}
+	}
+	return &record, false, nil
+}
+
+// abandonedCartReminderDue reports whether the record's next reminder should go out now
+func abandonedCartReminderDue(record models.AbandonedCart, now time.Time) bool {
+	switch {
+	case record.RemindersSent == 0:
+		return true
+	case record.RemindersSent >= abandonedCartMaxReminders || record.LastReminderAt == nil:
+		return false
+	}
+	return now.Sub(*record.LastReminderAt) >= AbandonedCartSecondReminderAfter()
+}
+
+// sendAbandonedCartReminder emails the owner of the abandoned cart the books waiting in it and
+// counts the reminder. The reminder is a marketing email, so users who have not granted
+// marketing consent or turned promotions off are skipped without counting one.
+func sendAbandonedCartReminder(record *models.AbandonedCart, now time.Time) (bool, error) {
+	preference, err := NotificationPreferenceFor(config.DB, record.UserID)
+	if err != nil {
+		return false, err
+	}
+	if !preference.Promotions {
+		return false, nil
+	}
+	var user models.User
+	if err := config.DB.First(&user, record.UserID).Error; err != nil {
+		return false, err
+	}
+	if user.Email == "" {
+		return false, nil
+	}
+
+	var items []models.Cart
+	if err := config.DB.Preload("Book").
+		Where("user_id = ? AND updated_at >= ?", record.UserID, now.Add(-cartTTL)).
+		Order("updated_at DESC").Find(&items).Error; err != nil {
+		return false, err
+	}
+	if len(items) == 0 {
+		return false, nil
+	}
+	lines := make([]EmailItem, len(items))
+	for i, item := range items {
+		lines[i] = EmailItem{Name: item.Book.Name, Quantity: item.Quantity}
+	}
+
+	brand := GetEmailBranding().Name
+	subject := fmt.Sprintf("You left books in your %s cart", brand)
+	if record.RemindersSent > 0 {
+		subject = fmt.Sprintf("Your %s cart is still waiting", brand)
+	}
+	sent, err := SendMarketingEmail(user.ID, user.Email, subject, "abandoned_cart", map[string]interface{}{
+		"Name":      user.FirstName,
+		"Items":     lines,
+		"CartTotal": FormatBaseMoney(record.CartValue),
+		"CartURL":   FrontendLink("/cart"),
+	})
+	if err != nil || !sent {
+		return false, err
+	}
+
+	record.RemindersSent++
+	record.LastReminderAt = &now
+	if err := config.DB.Model(record).Updates(map[string]interface{}{
+		"reminders_sent":   record.RemindersSent,
+		"last_reminder_at": now,
+	}).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
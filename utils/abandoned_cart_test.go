@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbandonedCartReminderNeedsMarketingConsent(t *testing.T) {
+	db := testutil.NewDB(t, &models.User{}, &models.Book{}, &models.Cart{}, &models.AbandonedCart{},
+		&models.NotificationPreference{}, &models.ConsentRecord{})
+
+	now := time.Now()
+	user := models.User{Username: "browser", Email: "browser@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	book := models.Book{Name: "Left Behind", Price: 300, Stock: 5, IsActive: true, ISBN: "9780000000003"}
+	require.NoError(t, db.Create(&book).Error)
+	require.NoError(t, db.Create(&models.Cart{UserID: user.ID, BookID: book.ID, Quantity: 1}).Error)
+	record := models.AbandonedCart{UserID: user.ID, Status: models.AbandonedCartStatusOpen, CartValue: 300, LastActivityAt: now}
+	require.NoError(t, db.Create(&record).Error)
+
+	sent, err := sendAbandonedCartReminder(&record, now)
+	require.NoError(t, err)
+	assert.False(t, sent, "promotions are on by default, but the user never granted marketing consent")
+
+	require.NoError(t, db.Create(&models.ConsentRecord{UserID: user.ID, Purpose: models.ConsentPurposeMarketing,
+		Granted: true, PolicyVersion: "1.0", CreatedAt: now.Add(-time.Hour)}).Error)
+	require.NoError(t, db.Create(&models.ConsentRecord{UserID: user.ID, Purpose: models.ConsentPurposeMarketing,
+		Granted: false, PolicyVersion: "1.0", CreatedAt: now}).Error)
+	sent, err = sendAbandonedCartReminder(&record, now)
+	require.NoError(t, err)
+	assert.False(t, sent, "withdrawn consent counts as none")
+
+	var stored models.AbandonedCart
+	require.NoError(t, db.First(&stored, record.ID).Error)
+	assert.Equal(t, 0, stored.RemindersSent, "a skipped reminder is not counted")
+	assert.Nil(t, stored.LastReminderAt)
+}
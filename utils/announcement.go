@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// AnnouncementAudience returns the users an announcement is meant for. A new arrival goes to
+// users who bought or wishlisted books of its category; a price drop goes to users who
+// wishlisted the book or bought other books by its author. Users who already bought the
+// announced book, and blocked, deleted or anonymized users, are left out.
+func AnnouncementAudience(announcement models.Announcement) ([]models.User, error) {
+	db := config.DB
+	var interested *gorm.DB
+	switch announcement.Type {
+	case models.AnnouncementTypeNewArrival:
+		bought := db.Table("order_items").Select("orders.user_id").
+			Joins("JOIN orders ON orders.id = order_items.order_id").
+			Joins("JOIN books ON books.id = order_items.book_id").
+			Where("books.category_id = ? AND orders.status <> ?", *announcement.CategoryID, models.OrderStatusCancelled)
+		wishlisted := db.Table("wishlists").Select("wishlists.user_id").
+			Joins("JOIN books ON books.id = wishlists.book_id").
+			Where("books.category_id = ?", *announcement.CategoryID)
+		interested = db.Where("users.id IN (?) OR users.id IN (?)", bought, wishlisted)
+	case models.AnnouncementTypePriceDrop:
+		var book models.Book
+		if err := db.First(&book, *announcement.BookID).Error; err != nil {
+			return nil, err
+		}
+		wishlisted := db.Table("wishlists").Select("user_id").Where("book_id = ?", book.ID)
+		if book.AuthorID == nil {
+			interested = db.Where("users.id IN (?)", wishlisted)
+			break
+		}
+		bought := db.Table("order_items").Select("orders.user_id").
+			Joins("JOIN orders ON orders.id = order_items.order_id").
+			Joins("JOIN books ON books.id = order_items.book_id").
+			Where("books.author_id = ? AND books.id <> ? AND orders.status <> ?", *book.AuthorID, book.ID, models.OrderStatusCancelled)
+		interested = db.Where("users.id IN (?) OR users.id IN (?)", bought, wishlisted)
+	default:
+		return nil, fmt.Errorf("unknown announcement type %q", announcement.Type)
+	}
+
+	query := db.Model(&models.User{}).Where(interested).
+		Where("users.is_blocked = ? AND users.anonymized_at IS NULL AND users.deletion_requested_at IS NULL", false)
+	if announcement.BookID != nil {
+		owners := db.Table("order_items").Select("orders.user_id").
+			Joins("JOIN orders ON orders.id = order_items.order_id").
+			Where("order_items.book_id = ? AND orders.status <> ?", *announcement.BookID, models.OrderStatusCancelled)
+		query = query.Where("users.id NOT IN (?)", owners)
+	}
+
+	var users []models.User
+	err := query.Order("users.id").Find(&users).Error
+	return users, err
+}
+
+// WantsAnnouncement reports whether the preference lets the announcement type through
+func WantsAnnouncement(preference models.NotificationPreference, announcementType string) bool {
+	if !preference.Promotions {
+		return false
+	}
+	switch announcementType {
+	case models.AnnouncementTypeNewArrival:
+		return preference.NewArrivals
+	case models.AnnouncementTypePriceDrop:
+		return preference.PriceDrops
+	}
+	return false
+}
+
+// SendAnnouncement emails the announcement to its audience, adds it to their notification
+// center and records the outcome on it. Users who turned the type off are skipped, and users
+// who have not given marketing consent only get the in-app notification.
+func SendAnnouncement(announcement *models.Announcement, users []models.User) error {
+	// The message is HTML written by an admin; the layout adds the link to the alert settings
+	message := template.HTML(announcement.Message)
+
+	for _, user := range users {
+		preference, err := NotificationPreferenceFor(config.DB, user.ID)
+		if err != nil {
+			LogError("Failed to load notification preferences for user ID: %d: %v", user.ID, err)
+			announcement.Failed++
+			continue
+		}
+		if !WantsAnnouncement(preference, announcement.Type) {
+			announcement.SkippedOptOut++
+			continue
+		}
+		if err := config.DB.Create(&models.Notification{
+			UserID: user.ID,
+			Type:   models.NotificationTypePromotion,
+			Title:  announcement.Subject,
+			Link:   announcementLink(announcement),
+		}).Error; err != nil {
+			LogError("Failed to add announcement %d to the notifications of user ID: %d: %v", announcement.ID, user.ID, err)
+		}
+		sent, err := SendMarketingEmail(user.ID, user.Email, announcement.Subject, "announcement", map[string]interface{}{
+			"Name": user.FirstName,
+			"Body": message,
+		})
+		switch {
+		case err != nil:
+			LogError("Failed to send announcement %d to user ID: %d: %v", announcement.ID, user.ID, err)
+			announcement.Failed++
+		case !sent:
+			announcement.SkippedConsent++
+		default:
+			announcement.Sent++
+		}
+	}
+
+	now := time.Now()
+	announcement.Status = models.AnnouncementStatusSent
+	announcement.CompletedAt = &now
+	return config.DB.Save(announcement).Error
+}
+
+// announcementLink is the API path an announcement notification points to
+func announcementLink(announcement *models.Announcement) string {
+	if announcement.BookID != nil {
+		return fmt.Sprintf("/v1/books/%d", *announcement.BookID)
+	}
+	if announcement.CategoryID != nil {
+		return fmt.Sprintf("/v1/categories/%d/books", *announcement.CategoryID)
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Book file size limits
+const (
+	MaxBookPreviewSize = 20 * 1024 * 1024
+	MaxDigitalBookSize = 100 * 1024 * 1024
+)
+
+// Preview file types
+const (
+	PreviewTypePDF  = "application/pdf"
+	PreviewTypeEPUB = "application/epub+zip"
+)
+
+// previewExtensions maps the accepted book file content types to file extensions
+var previewExtensions = map[string]string{
+	PreviewTypePDF:  ".pdf",
+	PreviewTypeEPUB: ".epub",
+}
+
+// DetectPreviewType identifies a sample chapter file, see DetectBookFileType
+func DetectPreviewType(data []byte) (string, string, error) {
+	return DetectBookFileType(data, MaxBookPreviewSize)
+}
+
+// DetectBookFileType identifies a PDF or EPUB file of at most maxSize bytes from its content and
+// returns its content type and file extension. EPUBs are zip archives whose first entry is an
+// uncompressed "mimetype" file.
+func DetectBookFileType(data []byte, maxSize int) (string, string, error) {
+	if len(data) == 0 {
+		return "", "", fmt.Errorf("file is empty")
+	}
+	if len(data) > maxSize {
+		return "", "", fmt.Errorf("file size exceeds %dMB limit", maxSize/1024/1024)
+	}
+
+	var contentType string
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		contentType = PreviewTypePDF
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")) && len(data) > 58 && string(data[30:58]) == "mimetypeapplication/epub+zip":
+		contentType = PreviewTypeEPUB
+	default:
+		return "", "", fmt.Errorf("unsupported file type, upload a PDF or EPUB")
+	}
+	return contentType, previewExtensions[contentType], nil
+}
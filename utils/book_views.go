@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bookViewDebounce is how long repeat views of a book by the same viewer are not counted again
+const bookViewDebounce = 30 * time.Minute
+
+// RecordBookView records that a viewer looked at a book and increments the book's view
+// counter, unless the same viewer already viewed it within the debounce window. Logged-in
+// users are identified by user ID, anyone else by IP. It reports whether the view was counted.
+func RecordBookView(bookID uint, userID *uint, ip string) (bool, error) {
+	viewerKey := "ip:" + ip
+	if userID != nil {
+		viewerKey = fmt.Sprintf("user:%d", *userID)
+	}
+	now := time.Now()
+
+	counted := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		view := models.BookView{ViewerKey: viewerKey, UserID: userID, BookID: bookID, ViewCount: 1, LastViewedAt: now}
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&view)
+		if created.Error != nil {
+			return created.Error
+		}
+
+		if created.RowsAffected == 0 {
+			// Seen before: count it only once the debounce window has passed
+			counter := tx.Model(&models.BookView{}).
+				Where("viewer_key = ? AND book_id = ? AND last_viewed_at < ?", viewerKey, bookID, now.Add(-bookViewDebounce)).
+				Updates(map[string]interface{}{
+					"view_count":     gorm.Expr("view_count + 1"),
+					"last_viewed_at": now,
+				})
+			if counter.Error != nil {
+				return counter.Error
+			}
+			if counter.RowsAffected == 0 {
+				// Debounced, but still the most recent view for the recently viewed list
+				return tx.Model(&models.BookView{}).
+					Where("viewer_key = ? AND book_id = ?", viewerKey, bookID).
+					UpdateColumn("last_viewed_at", now).Error
+			}
+		}
+
+		counted = true
+		return tx.Model(&models.Book{}).Where("id = ?", bookID).
+			UpdateColumn("views", gorm.Expr("views + 1")).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	LogDebug("Book view recorded for book %d by %s, counted: %v", bookID, viewerKey, counted)
+	return counted, nil
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// LoadBundle returns a bundle with its books and their categories
+func LoadBundle(db *gorm.DB, bundleID uint) (models.Bundle, error) {
+	var bundle models.Bundle
+	err := db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Items.Book.Category").First(&bundle, bundleID).Error
+	return bundle, err
+}
+
+// LoadBundles loads the bundles with their items and books like LoadBundle, by bundle ID, in
+// one query per level however many bundles there are. Missing bundles are left out.
+func LoadBundles(db *gorm.DB, bundleIDs []uint) (map[uint]models.Bundle, error) {
+	bundles := make(map[uint]models.Bundle, len(bundleIDs))
+	if len(bundleIDs) == 0 {
+		return bundles, nil
+	}
+	var found []models.Bundle
+	if err := db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Items.Book.Category").Where("id IN ?", bundleIDs).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for _, bundle := range found {
+		bundles[bundle.ID] = bundle
+	}
+	return bundles, nil
+}
+
+// BundleListPrice is what the books of one bundle cost when bought separately at list price
+func BundleListPrice(bundle models.Bundle) float64 {
+	total := 0.0
+	for _, item := range bundle.Items {
+		total += item.Book.Price * float64(item.Quantity)
+	}
+	return roundMoney(total)
+}
+
+// BundlePrice is what one bundle costs: its own price, capped at the list price of its books
+func BundlePrice(bundle models.Bundle) float64 {
+	return roundMoney(math.Min(bundle.Price, BundleListPrice(bundle)))
+}
+
+// BundleSavings is how much one bundle saves against buying its books separately at list price
+func BundleSavings(bundle models.Bundle) float64 {
+	return roundMoney(BundleListPrice(bundle) - BundlePrice(bundle))
+}
+
+// BundleStock is how many bundles the stock of their books allows
+func BundleStock(bundle models.Bundle) int {
+	if len(bundle.Items) == 0 {
+		return 0
+	}
+	stock := math.MaxInt
+	for _, item := range bundle.Items {
+		if item.Quantity < 1 {
+			continue
+		}
+		if available := item.Book.Stock / item.Quantity; available < stock {
+			stock = available
+		}
+	}
+	if stock < 0 || stock == math.MaxInt {
+		return 0
+	}
+	return stock
+}
+
+// BundleAvailable reports whether the bundle and every book in it can be sold. Deleted books
+// are not loaded with the bundle, so they show up with a zero ID.
+func BundleAvailable(bundle models.Bundle) bool {
+	if !bundle.IsActive || len(bundle.Items) == 0 {
+		return false
+	}
+	for _, item := range bundle.Items {
+		if !item.Book.IsActive || item.Book.Blocked || item.Book.Category.Blocked || item.Book.ID == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// PriceBundleLines prices quantity bundles as one line per book. The bundle price is split
+// over the books in proportion to their list prices, with the rounding remainder on the last
+// book, and the saving against the list price is shown as the books' product discount. Book
+// offers do not apply on top of the bundle price, and a bundle never costs more than its books.
+func PriceBundleLines(bundle models.Bundle, quantity int) []CartLine {
+	listPrice := BundleListPrice(bundle)
+	bundleTotal := roundMoney(BundlePrice(bundle) * float64(quantity))
+	available := BundleAvailable(bundle)
+
+	lines := make([]CartLine, 0, len(bundle.Items))
+	remaining := bundleTotal
+	for i, item := range bundle.Items {
+		copies := item.Quantity * quantity
+		line := PriceLine(item.Book, copies, OfferBreakdown{AppliedOfferType: "none"})
+
+		share := roundMoney(remaining)
+		if i < len(bundle.Items)-1 && listPrice > 0 {
+			share = roundMoney(bundleTotal * item.Book.Price * float64(item.Quantity) / listPrice)
+		}
+		remaining -= share
+
+		line.ProductDiscount = roundMoney(line.Subtotal - share)
+		line.CategoryDiscount = 0
+		line.OfferUnitPrice = roundMoney(share / float64(copies))
+		line.Total = line.OfferTotal()
+		line.Available = line.Available && available
+		line.Bundle = &bundle
+		lines = append(lines, line)
+	}
+	return lines
+}
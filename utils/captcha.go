@@ -0,0 +1,233 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Errors returned when a request's CAPTCHA is not accepted
+var (
+	ErrCaptchaRequired = errors.New("captcha token is required")
+	ErrCaptchaInvalid  = errors.New("captcha verification failed")
+)
+
+// loginFailureWindow is how long failed logins count towards the CAPTCHA threshold
+const loginFailureWindow = time.Hour
+
+// CaptchaProvider checks a CAPTCHA token solved by the client
+type CaptchaProvider interface {
+	Name() string
+	Verify(token, remoteIP string) (bool, error)
+}
+
+var (
+	captchaOnce          sync.Once
+	captchaProvider      CaptchaProvider
+	captchaLoginFailures = 3
+)
+
+// captchaVerifications counts CAPTCHA checks by provider and result
+var captchaVerifications = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "readsphere_captcha_verifications_total",
+	Help: "CAPTCHA tokens checked, by provider and result.",
+}, []string{"provider", "result"})
+
+// GetCaptchaProvider returns the provider selected by CAPTCHA_PROVIDER ("hcaptcha" or
+// "recaptcha", verified with CAPTCHA_SECRET_KEY), or nil when CAPTCHA is turned off, the default.
+// CAPTCHA_LOGIN_FAILURES sets how many failed logins for an email make the next login need one.
+func GetCaptchaProvider() CaptchaProvider {
+	captchaOnce.Do(func() {
+		if n, err := strconv.Atoi(os.Getenv("CAPTCHA_LOGIN_FAILURES")); err == nil && n >= 0 {
+			captchaLoginFailures = n
+		}
+
+		client := &http.Client{Timeout: 10 * time.Second}
+		secret := os.Getenv("CAPTCHA_SECRET_KEY")
+		name := strings.ToLower(os.Getenv("CAPTCHA_PROVIDER"))
+		switch name {
+		case "", "none":
+			LogInfo("CAPTCHA disabled")
+			return
+		case "hcaptcha":
+			captchaProvider = &siteVerifyProvider{name: "hcaptcha", endpoint: "https://api.hcaptcha.com/siteverify", secret: secret, client: client}
+		case "recaptcha":
+			captchaProvider = &siteVerifyProvider{name: "recaptcha", endpoint: "https://www.google.com/recaptcha/api/siteverify", secret: secret, client: client}
+		default:
+			LogError("Unknown CAPTCHA_PROVIDER %q, CAPTCHA disabled", name)
+			return
+		}
+		if secret == "" {
+			LogError("CAPTCHA_SECRET_KEY is not set, every %s check will fail", name)
+		}
+		LogInfo("CAPTCHA provider: %s, required on login after %d failures", captchaProvider.Name(), captchaLoginFailures)
+	})
+	return captchaProvider
+}
+
+// CaptchaEnabled reports whether a CAPTCHA provider is configured
+func CaptchaEnabled() bool {
+	return GetCaptchaProvider() != nil
+}
+
+// VerifyCaptcha checks the client's CAPTCHA token. It passes every request when CAPTCHA is
+// turned off and fails with ErrCaptchaRequired or ErrCaptchaInvalid otherwise.
+func VerifyCaptcha(token, remoteIP string) error {
+	provider := GetCaptchaProvider()
+	if provider == nil {
+		return nil
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		captchaVerifications.WithLabelValues(provider.Name(), "missing").Inc()
+		return ErrCaptchaRequired
+	}
+
+	ok, err := provider.Verify(token, remoteIP)
+	if err != nil {
+		captchaVerifications.WithLabelValues(provider.Name(), "error").Inc()
+		return fmt.Errorf("%w: %v", ErrCaptchaInvalid, err)
+	}
+	if !ok {
+		captchaVerifications.WithLabelValues(provider.Name(), "rejected").Inc()
+		return ErrCaptchaInvalid
+	}
+	captchaVerifications.WithLabelValues(provider.Name(), "passed").Inc()
+	return nil
+}
+
+// CheckCaptcha verifies the request's CAPTCHA token. It writes the error response and returns
+// false when the token is missing or not accepted.
+func CheckCaptcha(c *gin.Context, token string) bool {
+	err := VerifyCaptcha(token, c.ClientIP())
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrCaptchaRequired):
+		LogError("CAPTCHA missing on %s", c.FullPath())
+		Fail(c, CodeCaptchaRequired, "Please complete the CAPTCHA", gin.H{"captcha_required": true})
+	default:
+		LogError("CAPTCHA check failed on %s: %v", c.FullPath(), err)
+		Fail(c, CodeCaptchaInvalid, "CAPTCHA verification failed, please try again", gin.H{"captcha_required": true})
+	}
+	return false
+}
+
+// LoginCaptchaRequired reports whether the next login for the email needs a CAPTCHA, because
+// of the failed logins for it within the last hour
+func LoginCaptchaRequired(email string) bool {
+	if !CaptchaEnabled() {
+		return false
+	}
+	var failure models.LoginFailure
+	if err := config.DB.Where("email = ? AND last_failed_at > ?", strings.ToLower(email), time.Now().Add(-loginFailureWindow)).
+		First(&failure).Error; err != nil {
+		return false
+	}
+	return failure.Count >= captchaLoginFailures
+}
+
+// RecordLoginFailure counts a failed login for the email and reports whether the next login
+// needs a CAPTCHA. Failures older than an hour start the count again.
+func RecordLoginFailure(email string) bool {
+	if !CaptchaEnabled() {
+		return false
+	}
+	email = strings.ToLower(email)
+	now := time.Now()
+	err := config.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "email"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":          gorm.Expr("CASE WHEN login_failures.last_failed_at > ? THEN login_failures.count + 1 ELSE 1 END", now.Add(-loginFailureWindow)),
+			"last_failed_at": now,
+		}),
+	}).Create(&models.LoginFailure{Email: email, Count: 1, LastFailedAt: now}).Error
+	if err != nil {
+		LogError("Failed to record failed login for %s: %v", email, err)
+		return false
+	}
+	return LoginCaptchaRequired(email)
+}
+
+// ClearLoginFailures forgets the failed logins for the email after a successful login
+func ClearLoginFailures(email string) {
+	if err := config.DB.Where("email = ?", strings.ToLower(email)).Delete(&models.LoginFailure{}).Error; err != nil {
+		LogError("Failed to clear failed logins for %s: %v", email, err)
+	}
+}
+
+// CleanupLoginFailures deletes failed login counts that no longer count towards the threshold
+func CleanupLoginFailures() (string, error) {
+	result := config.DB.Where("last_failed_at < ?", time.Now().Add(-loginFailureWindow)).Delete(&models.LoginFailure{})
+	if result.Error != nil {
+		return "", result.Error
+	}
+	return fmt.Sprintf("Deleted %d failed login counts", result.RowsAffected), nil
+}
+
+// CaptchaSettings is what clients need to show the CAPTCHA widget
+func CaptchaSettings() map[string]interface{} {
+	provider := GetCaptchaProvider()
+	if provider == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+	return map[string]interface{}{
+		"enabled":              true,
+		"provider":             provider.Name(),
+		"site_key":             os.Getenv("CAPTCHA_SITE_KEY"),
+		"login_after_failures": captchaLoginFailures,
+	}
+}
+
+// siteVerifyProvider checks tokens with a siteverify endpoint, which hCaptcha and reCAPTCHA
+// share: a form post of the secret, token and client IP answered with {"success": bool}
+type siteVerifyProvider struct {
+	name     string
+	endpoint string
+	secret   string
+	client   *http.Client
+}
+
+func (p *siteVerifyProvider) Name() string { return p.name }
+
+func (p *siteVerifyProvider) Verify(token, remoteIP string) (bool, error) {
+	form := url.Values{"secret": {p.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	resp, err := p.client.PostForm(p.endpoint, form)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("%s siteverify returned status %d", p.name, resp.StatusCode)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	if !result.Success && len(result.ErrorCodes) > 0 {
+		LogDebug("%s rejected token: %s", p.name, strings.Join(result.ErrorCodes, ","))
+	}
+	return result.Success, nil
+}
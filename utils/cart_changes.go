@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+)
+
+// CartPriceChange describes a cart item whose price changed since the user last saw it
+type CartPriceChange struct {
+	BookID   uint    `json:"book_id"`
+	Name     string  `json:"name"`
+	OldPrice float64 `json:"old_price"`
+	NewPrice float64 `json:"new_price"`
+	Dropped  bool    `json:"price_dropped"`
+}
+
+// CartStockChange describes a cart item whose stock status changed since the user last saw it
+type CartStockChange struct {
+	BookID    uint   `json:"book_id"`
+	Name      string `json:"name"`
+	OldStatus string `json:"old_status"`
+	NewStatus string `json:"new_status"`
+	Available bool   `json:"available"`
+}
+
+// CartChanges lists what changed in a cart since the user last saw it
+type CartChanges struct {
+	PriceChanged []CartPriceChange `json:"price_changed"`
+	StockChanged []CartStockChange `json:"stock_changed"`
+}
+
+// SnapshotCartLine returns the values recorded on a cart row when the user sees its line
+func SnapshotCartLine(line CartLine) map[string]interface{} {
+	return map[string]interface{}{
+		"price_at_add":        line.OfferUnitPrice,
+		"stock_status_at_add": line.StockStatus,
+	}
+}
+
+// DetectCartChanges compares the priced lines with the price and stock status recorded on
+// their cart rows, then records the current values so each change is reported once. Rows
+// added before prices were recorded are only recorded.
+func DetectCartChanges(details *CartDetails) (*CartChanges, error) {
+	changes := &CartChanges{
+		PriceChanged: []CartPriceChange{},
+		StockChanged: []CartStockChange{},
+	}
+	for _, line := range details.Lines {
+		// Bundle lines are priced by the bundle, which keeps no snapshot
+		if line.Bundle != nil {
+			continue
+		}
+		item := line.CartItem
+		priceChanged := item.PriceAtAdd > 0 && math.Abs(item.PriceAtAdd-line.OfferUnitPrice) >= 0.01
+		stockChanged := item.StockStatusAtAdd != "" && item.StockStatusAtAdd != line.StockStatus
+		if priceChanged {
+			changes.PriceChanged = append(changes.PriceChanged, CartPriceChange{
+				BookID:   line.Book.ID,
+				Name:     line.Book.Name,
+				OldPrice: item.PriceAtAdd,
+				NewPrice: line.OfferUnitPrice,
+				Dropped:  line.OfferUnitPrice < item.PriceAtAdd,
+			})
+		}
+		if stockChanged {
+			changes.StockChanged = append(changes.StockChanged, CartStockChange{
+				BookID:    line.Book.ID,
+				Name:      line.Book.Name,
+				OldStatus: item.StockStatusAtAdd,
+				NewStatus: line.StockStatus,
+				Available: line.Available,
+			})
+		}
+		if item.PriceAtAdd == line.OfferUnitPrice && item.StockStatusAtAdd == line.StockStatus {
+			continue
+		}
+
+		// UpdateColumns leaves updated_at alone, which the cart expiry is measured from
+		if err := config.DB.Model(&models.Cart{}).Where("id = ?", item.ID).
+			UpdateColumns(SnapshotCartLine(line)).Error; err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
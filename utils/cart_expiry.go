@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+)
+
+// Cart expiry settings, overridden at startup from CART_TTL and CART_REMINDER_BEFORE
+var (
+	cartTTL            = 7 * 24 * time.Hour
+	cartReminderBefore = 24 * time.Hour
+)
+
+// ConfigureCartExpiry sets how long cart items live after their last change and
+// how long before expiry their owner is reminded
+func ConfigureCartExpiry(ttl, reminderBefore time.Duration) {
+	cartTTL = ttl
+	cartReminderBefore = reminderBefore
+	LogInfo("Cart items expire after %s, reminders sent %s before", ttl, reminderBefore)
+}
+
+// CartItemExpiresAt returns when the cart item expires
+func CartItemExpiresAt(item models.Cart) time.Time {
+	return item.UpdatedAt.Add(cartTTL)
+}
+
+// ExpiredCartItem describes a cart item dropped because it expired
+type ExpiredCartItem struct {
+	BookID    uint      `json:"book_id"`
+	Name      string    `json:"name"`
+	Quantity  int       `json:"quantity"`
+	UpdatedAt time.Time `json:"last_updated_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+	Reason    string    `json:"reason"`
+}
+
+// RemoveExpiredCartItems deletes the user's expired cart items and returns what was dropped
+func RemoveExpiredCartItems(userID uint) ([]ExpiredCartItem, error) {
+	var expired []models.Cart
+	if err := config.DB.Preload("Book").
+		Where("user_id = ? AND updated_at < ?", userID, time.Now().Add(-cartTTL)).
+		Find(&expired).Error; err != nil {
+		return nil, err
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(expired))
+	removed := make([]ExpiredCartItem, len(expired))
+	for i, item := range expired {
+		ids[i] = item.ID
+		removed[i] = ExpiredCartItem{
+			BookID:    item.BookID,
+			Name:      item.Book.Name,
+			Quantity:  item.Quantity,
+			UpdatedAt: item.UpdatedAt,
+			ExpiredAt: CartItemExpiresAt(item),
+			Reason:    fmt.Sprintf("Item was left in the cart for more than %s without changes", cartTTL),
+		}
+	}
+	if err := config.DB.Unscoped().Where("id IN ?", ids).Delete(&models.Cart{}).Error; err != nil {
+		return nil, err
+	}
+
+	LogInfo("Removed %d expired cart items for user ID: %d", len(removed), userID)
+	return removed, nil
+}
+
+// CleanupExpiredCarts hard-deletes expired cart items of all users, along with
+// soft-deleted cart rows older than the TTL. It returns the number of rows removed.
+func CleanupExpiredCarts() (int64, error) {
+	cutoff := time.Now().Add(-cartTTL)
+	result := config.DB.Unscoped().
+		Where("updated_at < ? OR (deleted_at IS NOT NULL AND deleted_at < ?)", cutoff, cutoff).
+		Delete(&models.Cart{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		LogInfo("Cart cleanup removed %d expired rows", result.RowsAffected)
+	}
+	return result.RowsAffected, nil
+}
+
+// SendCartExpiryReminders emails every user whose cart items expire within the reminder
+// window and have not been reminded about since their last change, unless they turned
+// promotions off. It returns the number of users notified.
+func SendCartExpiryReminders() (int, error) {
+	now := time.Now()
+	var items []models.Cart
+	if err := config.DB.Preload("Book").Preload("User").
+		Where("updated_at < ? AND updated_at >= ?", now.Add(cartReminderBefore-cartTTL), now.Add(-cartTTL)).
+		Where("expiry_reminder_sent_at IS NULL OR expiry_reminder_sent_at < updated_at").
+		Order("user_id, updated_at").
+		Find(&items).Error; err != nil {
+		return 0, err
+	}
+
+	byUser := make(map[uint][]models.Cart)
+	var userIDs []uint
+	for _, item := range items {
+		if _, seen := byUser[item.UserID]; !seen {
+			userIDs = append(userIDs, item.UserID)
+		}
+		byUser[item.UserID] = append(byUser[item.UserID], item)
+	}
+
+	notified := 0
+	for _, userID := range userIDs {
+		userItems := byUser[userID]
+		user := userItems[0].User
+		if user.Email == "" {
+			continue
+		}
+		// Reminders are promotional, so users who turned promotions off do not get them
+		if preference, err := NotificationPreferenceFor(config.DB, userID); err != nil || !preference.Promotions {
+			continue
+		}
+
+		items := make([]EmailItem, len(userItems))
+		ids := make([]uint, len(userItems))
+		for i, item := range userItems {
+			ids[i] = item.ID
+			items[i] = EmailItem{
+				Name:     item.Book.Name,
+				Note:     "Expires " + CartItemExpiresAt(item).Format("2006-01-02 15:04"),
+				Quantity: item.Quantity,
+			}
+		}
+
+		if err := SendTemplateEmail(user.Email, "Items in your cart are about to expire", "cart_expiry", map[string]interface{}{
+			"Name":    user.FirstName,
+			"Items":   items,
+			"CartURL": FrontendLink("/cart"),
+		}); err != nil {
+			LogError("Failed to send cart expiry reminder to user ID: %d: %v", userID, err)
+			continue
+		}
+
+		// UpdateColumn keeps updated_at, so the reminder does not extend the item's life
+		if err := config.DB.Model(&models.Cart{}).Where("id IN ?", ids).
+			UpdateColumn("expiry_reminder_sent_at", now).Error; err != nil {
+			LogError("Failed to mark cart expiry reminder for user ID: %d: %v", userID, err)
+			continue
+		}
+		notified++
+	}
+
+	if notified > 0 {
+		LogInfo("Sent cart expiry reminders to %d users", notified)
+	}
+	return notified, nil
+}
@@ -0,0 +1,333 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// Catalog cache backends selected by CATALOG_CACHE_STORE
+const (
+	CatalogCacheMemory = "memory"
+	CatalogCacheRedis  = "redis"
+)
+
+// catalogCacheKeyPrefix namespaces catalog cache keys in Redis
+const catalogCacheKeyPrefix = "catalog:"
+
+// CatalogCacheEntry is a rendered catalog response
+type CatalogCacheEntry struct {
+	Body        []byte `json:"body"`
+	ContentType string `json:"content_type"`
+	ETag        string `json:"etag"`
+}
+
+// CatalogCache keeps rendered catalog responses under keys that include the catalog version.
+// Bumping the version on a catalog change leaves the old entries unreachable until they expire.
+type CatalogCache interface {
+	Get(key string) (*CatalogCacheEntry, bool)
+	Set(key string, entry CatalogCacheEntry, ttl time.Duration)
+	// Version is the time of the last catalog change, in unix nanoseconds
+	Version() int64
+	Bump()
+}
+
+var (
+	catalogCache    CatalogCache
+	catalogCacheTTL time.Duration
+)
+
+// ConfigureCatalogCache sets how long catalog responses are cached and where. The redis store is
+// shared by every replica, so a change made on one is seen by all; when Redis is not configured
+// or cannot be reached each instance caches in memory. A ttl of zero turns caching off.
+func ConfigureCatalogCache(ttl time.Duration, store string) {
+	catalogCacheTTL = ttl
+	if ttl <= 0 {
+		catalogCache = nil
+		LogInfo("Catalog cache disabled")
+		return
+	}
+	if strings.ToLower(store) == CatalogCacheRedis {
+		client, err := GetRedis()
+		if err == nil {
+			catalogCache = &RedisCatalogCache{client: client}
+			LogInfo("Catalog cache: redis, ttl %s", ttl)
+			return
+		}
+		LogError("Redis catalog cache unavailable, falling back to memory: %v", err)
+	}
+	catalogCache = NewMemoryCatalogCache()
+	LogInfo("Catalog cache: memory, ttl %s", ttl)
+}
+
+// InvalidateCatalogCache makes every cached catalog response stale. Call it after a change to
+// books, categories, offers or anything else the catalog endpoints show.
+func InvalidateCatalogCache() {
+	if catalogCache != nil {
+		catalogCache.Bump()
+	}
+}
+
+// CatalogCacheMiddleware serves anonymous GET requests from the catalog cache, and sends an
+// ETag and Last-Modified with every response so clients can revalidate with a 304. Requests
+// with an Authorization header may get personalised data and always reach the handler. onHit
+// runs for requests answered from the cache, for side effects of the handler such as counting
+// a view.
+func CatalogCacheMiddleware(onHit ...gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cache := catalogCache
+		if cache == nil || c.Request.Method != http.MethodGet || c.GetHeader("Authorization") != "" {
+			c.Next()
+			return
+		}
+
+		version := cache.Version()
+		key := catalogCacheKey(c, version)
+		if entry, ok := cache.Get(key); ok {
+			for _, hit := range onHit {
+				hit(c)
+			}
+			c.Header("X-Cache", "HIT")
+			writeCatalogResponse(c, entry, version)
+			c.Abort()
+			return
+		}
+
+		// Hold the response back so it can be cached and answered conditionally
+		writer := &bufferedResponseWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.status != http.StatusOK {
+			c.Writer.WriteHeader(writer.status)
+			c.Writer.Write(writer.body.Bytes())
+			return
+		}
+		sum := sha256.Sum256(writer.body.Bytes())
+		entry := CatalogCacheEntry{
+			Body:        writer.body.Bytes(),
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			ETag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+		}
+		cache.Set(key, entry, catalogCacheTTL)
+		c.Header("X-Cache", "MISS")
+		writeCatalogResponse(c, &entry, version)
+	}
+}
+
+// catalogCacheKey identifies the response to the request: its path and query and the
+// headers that change how the response is rendered
+func catalogCacheKey(c *gin.Context, version int64) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		c.Request.URL.Path,
+		c.Request.URL.Query().Encode(),
+		GetRequestCurrency(c),
+		GetRequestLocale(c),
+		GetResponseFormat(c),
+	}, "|")))
+	return catalogCacheKeyPrefix + strconv.FormatInt(version, 10) + ":" + hex.EncodeToString(sum[:])
+}
+
+// writeCatalogResponse sends the cached response, or 304 Not Modified when the client's copy
+// is still current
+func writeCatalogResponse(c *gin.Context, entry *CatalogCacheEntry, version int64) {
+	lastModified := time.Unix(0, version).UTC().Truncate(time.Second)
+	c.Header("ETag", entry.ETag)
+	c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	c.Header("Cache-Control", "public, no-cache")
+	c.Header("Vary", "Accept-Language, Authorization, "+CurrencyHeader+", "+ResponseFormatHeader)
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == entry.ETag || tag == "*" {
+				c.Status(http.StatusNotModified)
+				return
+			}
+		}
+	} else if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !lastModified.After(since) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, entry.ContentType, entry.Body)
+}
+
+// bufferedResponseWriter holds the handler's response instead of sending it
+type bufferedResponseWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) WriteHeader(code int) { w.status = code }
+
+func (w *bufferedResponseWriter) WriteHeaderNow() {}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) { return w.body.Write(data) }
+
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) { return w.body.WriteString(s) }
+
+func (w *bufferedResponseWriter) Status() int { return w.status }
+
+func (w *bufferedResponseWriter) Size() int { return w.body.Len() }
+
+func (w *bufferedResponseWriter) Written() bool { return w.body.Len() > 0 }
+
+// catalogAdminPaths are the admin route groups whose changes show in the catalog
+var catalogAdminPaths = []string{
+	"books", "categories", "genres", "authors", "tags", "bundles", "offers",
+	"home-sections", "banners", "pages", "reviews", "currencies", "translations", "settings", "catalog",
+}
+
+// InvalidateCatalogCacheOnWrite invalidates the catalog cache after a successful admin change
+// to the catalog
+func InvalidateCatalogCacheOnWrite() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+		path := c.FullPath()
+		if i := strings.Index(path, "/admin/"); i >= 0 {
+			path = path[i+len("/admin/"):]
+		}
+		group := strings.SplitN(path, "/", 2)[0]
+		for _, catalogPath := range catalogAdminPaths {
+			if group == catalogPath {
+				LogDebug("Catalog changed by %s %s, invalidating the catalog cache", c.Request.Method, c.FullPath())
+				InvalidateCatalogCache()
+				return
+			}
+		}
+	}
+}
+
+// MemoryCatalogCache caches catalog responses in this instance's memory
+type MemoryCatalogCache struct {
+	mu      sync.RWMutex
+	version int64
+	entries map[string]memoryCatalogEntry
+}
+
+type memoryCatalogEntry struct {
+	entry     CatalogCacheEntry
+	expiresAt time.Time
+}
+
+// memoryCatalogCacheSize bounds the number of cached responses
+const memoryCatalogCacheSize = 2000
+
+// NewMemoryCatalogCache creates an empty in-memory catalog cache
+func NewMemoryCatalogCache() *MemoryCatalogCache {
+	return &MemoryCatalogCache{version: time.Now().UnixNano(), entries: map[string]memoryCatalogEntry{}}
+}
+
+func (m *MemoryCatalogCache) Get(key string) (*CatalogCacheEntry, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cached, ok := m.entries[key]
+	if !ok || time.Now().After(cached.expiresAt) {
+		return nil, false
+	}
+	return &cached.entry, true
+}
+
+func (m *MemoryCatalogCache) Set(key string, entry CatalogCacheEntry, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.entries) >= memoryCatalogCacheSize {
+		now := time.Now()
+		for k, cached := range m.entries {
+			if now.After(cached.expiresAt) {
+				delete(m.entries, k)
+			}
+		}
+		if len(m.entries) >= memoryCatalogCacheSize {
+			m.entries = map[string]memoryCatalogEntry{}
+		}
+	}
+	m.entries[key] = memoryCatalogEntry{entry: entry, expiresAt: time.Now().Add(ttl)}
+}
+
+func (m *MemoryCatalogCache) Version() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.version
+}
+
+// Bump moves to a new version and drops every entry, as none of them can be reached any more
+func (m *MemoryCatalogCache) Bump() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.version = time.Now().UnixNano()
+	m.entries = map[string]memoryCatalogEntry{}
+}
+
+// RedisCatalogCache caches catalog responses in Redis, shared by every replica
+type RedisCatalogCache struct {
+	client *redis.Client
+}
+
+// redisCatalogVersionKey holds the catalog version in Redis
+const redisCatalogVersionKey = catalogCacheKeyPrefix + "version"
+
+func (r *RedisCatalogCache) Get(key string) (*CatalogCacheEntry, bool) {
+	value, err := r.client.Get(context.Background(), key).Result()
+	if err != nil {
+		if err != redis.Nil {
+			LogError("Failed to read catalog cache: %v", err)
+		}
+		return nil, false
+	}
+	var entry CatalogCacheEntry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (r *RedisCatalogCache) Set(key string, entry CatalogCacheEntry, ttl time.Duration) {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := r.client.Set(context.Background(), key, value, ttl).Err(); err != nil {
+		LogError("Failed to write catalog cache: %v", err)
+	}
+}
+
+// Version reads the shared version, starting one when there is none yet. When Redis cannot be
+// reached the current time is used, so nothing is served from the cache.
+func (r *RedisCatalogCache) Version() int64 {
+	value, err := r.client.Get(context.Background(), redisCatalogVersionKey).Result()
+	if err == nil {
+		if version, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return version
+		}
+	}
+	if err != nil && err != redis.Nil {
+		LogError("Failed to read catalog cache version: %v", err)
+		return time.Now().UnixNano()
+	}
+	version := time.Now().UnixNano()
+	r.client.Set(context.Background(), redisCatalogVersionKey, version, 0)
+	return version
+}
+
+func (r *RedisCatalogCache) Bump() {
+	if err := r.client.Set(context.Background(), redisCatalogVersionKey, time.Now().UnixNano(), 0).Err(); err != nil {
+		LogError("Failed to invalidate catalog cache: %v", err)
+	}
+}
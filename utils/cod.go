@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// Delivery OTP limits
+const (
+	DeliveryOTPLifetime    = 24 * time.Hour
+	DeliveryOTPMaxAttempts = 5
+)
+
+// Delivery OTP errors
+var (
+	ErrDeliveryOTPNotIssued = errors.New("no delivery OTP has been generated for this order")
+	ErrDeliveryOTPExpired   = errors.New("delivery OTP has expired")
+	ErrDeliveryOTPInvalid   = errors.New("invalid delivery OTP")
+	ErrDeliveryOTPAttempts  = errors.New("too many wrong delivery OTP attempts")
+)
+
+// CODEligibility reports whether the user may still pay by Cash on Delivery, with the number
+// of their Cash on Delivery orders refused at the door
+func CODEligibility(userID uint) (bool, int64, error) {
+	var refused int64
+	if err := config.DB.Model(&models.Order{}).Where("user_id = ? AND delivery_refused = ?", userID, true).Count(&refused).Error; err != nil {
+		return false, 0, err
+	}
+	limit := CODMaxRefusedDeliveries()
+	return limit == 0 || refused < int64(limit), refused, nil
+}
+
+// IssueDeliveryOTP generates a new delivery OTP for the order and saves it, replacing any
+// earlier one
+func IssueDeliveryOTP(tx *gorm.DB, order *models.Order) (string, error) {
+	otp := GenerateOTP()
+	expiresAt := time.Now().Add(DeliveryOTPLifetime)
+	if err := tx.Model(order).Updates(map[string]interface{}{
+		"delivery_otp":            otp,
+		"delivery_otp_expires_at": expiresAt,
+		"delivery_otp_attempts":   0,
+	}).Error; err != nil {
+		return "", err
+	}
+	order.DeliveryOTP = otp
+	order.DeliveryOTPExpiresAt = &expiresAt
+	order.DeliveryOTPAttempts = 0
+	return otp, nil
+}
+
+// VerifyDeliveryOTP checks the code the delivery agent entered and records the handover when
+// it matches. Wrong codes count towards DeliveryOTPMaxAttempts.
+func VerifyDeliveryOTP(tx *gorm.DB, order *models.Order, code string) error {
+	if order.DeliveryOTP == "" || order.DeliveryOTPExpiresAt == nil {
+		return ErrDeliveryOTPNotIssued
+	}
+	if order.DeliveryOTPAttempts >= DeliveryOTPMaxAttempts {
+		return ErrDeliveryOTPAttempts
+	}
+	if time.Now().After(*order.DeliveryOTPExpiresAt) {
+		return ErrDeliveryOTPExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(order.DeliveryOTP), []byte(code)) != 1 {
+		order.DeliveryOTPAttempts++
+		if err := tx.Model(order).UpdateColumn("delivery_otp_attempts", order.DeliveryOTPAttempts).Error; err != nil {
+			return err
+		}
+		return ErrDeliveryOTPInvalid
+	}
+
+	now := time.Now()
+	if err := tx.Model(order).Updates(map[string]interface{}{
+		"delivery_otp":             "",
+		"delivery_otp_verified_at": now,
+		"delivery_reference":       "Delivery OTP verified",
+	}).Error; err != nil {
+		return err
+	}
+	order.DeliveryOTP = ""
+	order.DeliveryOTPVerifiedAt = &now
+	order.DeliveryReference = "Delivery OTP verified"
+	return nil
+}
+
+// DeliveryOTPRequired reports whether the order needs a verified delivery OTP before it can
+// be marked Delivered. Only Cash on Delivery orders are confirmed with an OTP.
+func DeliveryOTPRequired(order models.Order) bool {
+	return NormalizePaymentMethod(order.PaymentMethod) == "cod" && order.DeliveryOTPVerifiedAt == nil
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+)
+
+// defaultConsentPolicyVersion is used when CONSENT_POLICY_VERSION is not configured
+const defaultConsentPolicyVersion = "1.0"
+
+// ConsentPurposes lists the optional purposes a user can grant or withdraw consent for
+var ConsentPurposes = []string{models.ConsentPurposeAnalytics, models.ConsentPurposeMarketing}
+
+// CurrentConsentPolicyVersion returns the privacy policy version consent is currently collected against
+func CurrentConsentPolicyVersion() string {
+	if version := os.Getenv("CONSENT_POLICY_VERSION"); version != "" {
+		return version
+	}
+	return defaultConsentPolicyVersion
+}
+
+// GetLatestConsent returns the most recent consent record for a user and purpose, or nil if none exists
+func GetLatestConsent(userID uint, purpose string) (*models.ConsentRecord, error) {
+	var records []models.ConsentRecord
+	if err := config.DB.Where("user_id = ? AND purpose = ?", userID, purpose).
+		Order("created_at DESC, id DESC").Limit(1).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return &records[0], nil
+}
+
+// HasConsent reports whether a user currently grants consent for a purpose.
+// Users who have never made a choice are treated as not consenting.
+func HasConsent(userID uint, purpose string) bool {
+	record, err := GetLatestConsent(userID, purpose)
+	if err != nil {
+		LogError("Failed to check %s consent for user %d: %v", purpose, userID, err)
+		return false
+	}
+	return record != nil && record.Granted
+}
+
+// ErrMarketingEmailWithoutConsentCheck is returned by SendTemplateEmail for a marketing email,
+// which has to be sent with SendMarketingEmail so the user's consent is checked
+var ErrMarketingEmailWithoutConsentCheck = errors.New("marketing emails must be sent with SendMarketingEmail")
+
+// SendMarketingEmail renders the named template as a marketing email and sends it only if the
+// user has granted marketing consent. It returns (false, nil) when the email was skipped
+// because consent is missing. Every marketing email goes through here.
+func SendMarketingEmail(userID uint, to, subject, name string, data map[string]interface{}) (bool, error) {
+	if !HasConsent(userID, models.ConsentPurposeMarketing) {
+		LogInfo("Skipping marketing email to user %d: no marketing consent", userID)
+		return false, nil
+	}
+	view := make(map[string]interface{}, len(data)+1)
+	for key, value := range data {
+		view[key] = value
+	}
+	view["Marketing"] = true
+	body, err := RenderEmail(name, subject, view)
+	if err != nil {
+		return false, err
+	}
+	if err := SendEmail(to, subject, body); err != nil {
+		return false, fmt.Errorf("failed to send marketing email: %v", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarketingEmailsNeedTheConsentCheck(t *testing.T) {
+	db := testutil.NewDB(t, &models.ConsentRecord{})
+
+	err := SendTemplateEmail("reader@example.com", "New arrivals", "announcement", map[string]interface{}{
+		"Name": "Asha", "Marketing": true,
+	})
+	assert.ErrorIs(t, err, ErrMarketingEmailWithoutConsentCheck, "marketing emails cannot skip the consent check")
+
+	sent, err := SendMarketingEmail(1, "reader@example.com", "New arrivals", "announcement", map[string]interface{}{"Name": "Asha"})
+	require.NoError(t, err)
+	assert.False(t, sent, "users who never chose are treated as not consenting")
+
+	require.NoError(t, db.Create(&models.ConsentRecord{UserID: 1, Purpose: models.ConsentPurposeAnalytics, Granted: true, PolicyVersion: "1.0"}).Error)
+	sent, err = SendMarketingEmail(1, "reader@example.com", "New arrivals", "announcement", map[string]interface{}{"Name": "Asha"})
+	require.NoError(t, err)
+	assert.False(t, sent, "analytics consent does not cover marketing")
+}
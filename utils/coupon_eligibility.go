@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+)
+
+// Reasons a coupon cannot be applied to a cart
+const (
+	CouponReasonInactive        = "inactive"
+	CouponReasonExpired         = "expired"
+	CouponReasonUsageLimit      = "usage_limit_reached"
+	CouponReasonAlreadyUsed     = "already_used"
+	CouponReasonMinOrderNotMet  = "min_order_not_met"
+	CouponReasonEmptyCart       = "empty_cart"
+	CouponReasonFirstOrderOnly  = "first_order_only"
+	CouponReasonSegment         = "not_in_segment"
+	CouponReasonAccountAge      = "account_too_new"
+	CouponReasonNoEligibleItems = "no_eligible_items"
+)
+
+// CouponIneligibility explains why a coupon does not apply
+type CouponIneligibility struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidCouponSegment reports whether segment is a user segment a coupon can target
+func ValidCouponSegment(segment string) bool {
+	switch segment {
+	case models.CouponSegmentAll, models.CouponSegmentReturning, models.CouponSegmentReferred, models.CouponSegmentSelected:
+		return true
+	}
+	return false
+}
+
+// CouponUsesByUser counts the user's orders placed with the coupon that were not cancelled
+func CouponUsesByUser(userID uint, coupon models.Coupon) int64 {
+	var used int64
+	config.DB.Model(&models.Order{}).
+		Where("user_id = ? AND LOWER(coupon_code) = ? AND status <> ?", userID, strings.ToLower(coupon.Code), models.OrderStatusCancelled).
+		Count(&used)
+	return used
+}
+
+// couponUserInSegment reports whether the user belongs to the segment the coupon targets
+func couponUserInSegment(user models.User, coupon models.Coupon, orders int64) bool {
+	switch coupon.UserSegment {
+	case models.CouponSegmentReturning:
+		return orders > 0
+	case models.CouponSegmentReferred:
+		var referred int64
+		config.DB.Model(&models.ReferralUsage{}).Where("referred_user_id = ?", user.ID).Count(&referred)
+		return referred > 0
+	case models.CouponSegmentSelected:
+		var selected int64
+		config.DB.Table("coupon_users").Where("coupon_id = ? AND user_id = ?", coupon.ID, user.ID).Count(&selected)
+		return selected > 0
+	}
+	return true
+}
+
+// CheckCouponEligibility returns every reason the coupon cannot be applied by the user to the
+// priced cart. The coupon's category and book restrictions must be loaded. An empty result
+// means the coupon is applicable.
+func CheckCouponEligibility(user models.User, coupon models.Coupon, details *CartDetails) []CouponIneligibility {
+	var reasons []CouponIneligibility
+	if !coupon.Active {
+		reasons = append(reasons, CouponIneligibility{CouponReasonInactive, "Coupon is not active"})
+	}
+	if time.Now().After(coupon.Expiry) {
+		reasons = append(reasons, CouponIneligibility{CouponReasonExpired,
+			fmt.Sprintf("Coupon expired on %s", coupon.Expiry.Format("2006-01-02"))})
+	}
+	if coupon.UsedCount >= coupon.UsageLimit {
+		reasons = append(reasons, CouponIneligibility{CouponReasonUsageLimit, "Coupon usage limit reached"})
+	}
+
+	perUserLimit := coupon.PerUserLimit
+	if perUserLimit < 1 {
+		perUserLimit = 1
+	}
+	if used := CouponUsesByUser(user.ID, coupon); used >= int64(perUserLimit) {
+		message := "You have already used this coupon"
+		if perUserLimit > 1 {
+			message = fmt.Sprintf("You have already used this coupon %d times, the most allowed", used)
+		}
+		reasons = append(reasons, CouponIneligibility{CouponReasonAlreadyUsed, message})
+	}
+
+	// Who the coupon is for
+	var orders int64
+	if coupon.FirstOrderOnly || coupon.UserSegment == models.CouponSegmentReturning {
+		config.DB.Model(&models.Order{}).Where("user_id = ? AND status <> ?", user.ID, models.OrderStatusCancelled).Count(&orders)
+	}
+	if coupon.FirstOrderOnly && orders > 0 {
+		reasons = append(reasons, CouponIneligibility{CouponReasonFirstOrderOnly, "This coupon is only for your first order"})
+	}
+	if !couponUserInSegment(user, coupon, orders) {
+		message := "This coupon is not available for your account"
+		switch coupon.UserSegment {
+		case models.CouponSegmentReturning:
+			message = "This coupon is for customers who have ordered before"
+		case models.CouponSegmentReferred:
+			message = "This coupon is for customers who joined with a referral code"
+		}
+		reasons = append(reasons, CouponIneligibility{CouponReasonSegment, message})
+	}
+	if coupon.MinAccountAgeDays > 0 {
+		eligibleFrom := user.CreatedAt.AddDate(0, 0, coupon.MinAccountAgeDays)
+		if time.Now().Before(eligibleFrom) {
+			reasons = append(reasons, CouponIneligibility{CouponReasonAccountAge,
+				fmt.Sprintf("Your account must be %d days old to use this coupon (from %s)", coupon.MinAccountAgeDays, eligibleFrom.Format("2006-01-02"))})
+		}
+	}
+
+	// What the coupon applies to
+	subtotal, _ := details.CouponSubtotal(coupon)
+	switch {
+	case len(details.Lines) == 0:
+		reasons = append(reasons, CouponIneligibility{CouponReasonEmptyCart, "Your cart is empty"})
+	case subtotal <= 0:
+		reasons = append(reasons, CouponIneligibility{CouponReasonNoEligibleItems, "None of the books in your cart are covered by this coupon"})
+	case subtotal < coupon.MinOrderValue:
+		message := fmt.Sprintf("Add items worth %s more to use this coupon (minimum order %s)", FormatBaseMoney(coupon.MinOrderValue-subtotal), FormatBaseMoney(coupon.MinOrderValue))
+		if subtotal < details.Subtotal {
+			message = fmt.Sprintf("Add eligible items worth %s more to use this coupon (minimum %s on eligible items)", FormatBaseMoney(coupon.MinOrderValue-subtotal), FormatBaseMoney(coupon.MinOrderValue))
+		}
+		reasons = append(reasons, CouponIneligibility{CouponReasonMinOrderNotMet, message})
+	}
+	return reasons
+}
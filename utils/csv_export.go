@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// csvExportKey is set on the context by routes that always export CSV
+const csvExportKey = "export_csv"
+
+// ForceCSVExport marks the request so that WantsCSV reports true regardless of the query string
+func ForceCSVExport(c *gin.Context) {
+	c.Set(csvExportKey, true)
+}
+
+// WantsCSV reports whether the client asked for a CSV export via ?format=csv
+func WantsCSV(c *gin.Context) bool {
+	return c.GetBool(csvExportKey) || strings.EqualFold(c.Query("format"), "csv")
+}
+
+// WriteCSV streams the given headers and rows to the client as a CSV attachment.
+// The filename is suffixed with the current date, e.g. "orders" -> "orders_2025-01-31.csv".
+// Cells are escaped with EscapeCSVCell so names and titles cannot run as spreadsheet formulas.
+func WriteCSV(c *gin.Context, filename string, headers []string, rows [][]string) error {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s_%s.csv", filename, time.Now().Format("2006-01-02")))
+
+	writer := csv.NewWriter(c.Writer)
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		escaped := make([]string, len(row))
+		for i, cell := range row {
+			escaped[i] = EscapeCSVCell(cell)
+		}
+		if err := writer.Write(escaped); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+
+	LogInfo("CSV export %s written with %d rows", filename, len(rows))
+	return writer.Error()
+}
+
+// EscapeCSVCell prefixes a cell starting with =, +, -, @, a tab or a carriage return with ' so
+// spreadsheets show it as text instead of running it as a formula. Numbers such as -12.50 are
+// left as they are.
+func EscapeCSVCell(cell string) string {
+	if cell == "" || !strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return cell
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return cell
+	}
+	return "'" + cell
+}
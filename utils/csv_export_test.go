@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscapeCSVCell(t *testing.T) {
+	tests := map[string]string{
+		"":                         "",
+		"Jane Doe":                 "Jane Doe",
+		"=HYPERLINK(\"http://x\")": "'=HYPERLINK(\"http://x\")",
+		"+1+cmd":                   "'+1+cmd",
+		"-2+3":                     "'-2+3",
+		"@SUM(A1)":                 "'@SUM(A1)",
+		"\tcmd":                    "'\tcmd",
+		"-12.50":                   "-12.50",
+		"+91":                      "+91",
+		"a=b":                      "a=b",
+	}
+	for cell, want := range tests {
+		assert.Equal(t, want, EscapeCSVCell(cell), "cell %q", cell)
+	}
+}
+
+func TestWriteCSVEscapesRowsNotHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+
+	require.NoError(t, WriteCSV(c, "users", []string{"Name", "Balance"}, [][]string{{"=1+1", "-5"}}))
+	assert.Equal(t, "Name,Balance\n'=1+1,-5\n", recorder.Body.String())
+}
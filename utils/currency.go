@@ -0,0 +1,271 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// CurrencyHeader lets a client pick the currency amounts are returned in; the currency
+// query parameter takes precedence
+const CurrencyHeader = "X-Currency"
+
+// CurrencyInfo describes how a currency is displayed
+type CurrencyInfo struct {
+	Code     string `json:"code"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+// knownCurrencies are the currencies that can be enabled through SUPPORTED_CURRENCIES
+var knownCurrencies = map[string]CurrencyInfo{
+	"INR": {Code: "INR", Symbol: "₹", Decimals: 2},
+	"USD": {Code: "USD", Symbol: "$", Decimals: 2},
+	"EUR": {Code: "EUR", Symbol: "€", Decimals: 2},
+	"GBP": {Code: "GBP", Symbol: "£", Decimals: 2},
+	"AED": {Code: "AED", Symbol: "AED ", Decimals: 2},
+	"SGD": {Code: "SGD", Symbol: "S$", Decimals: 2},
+	"AUD": {Code: "AUD", Symbol: "A$", Decimals: 2},
+	"CAD": {Code: "CAD", Symbol: "C$", Decimals: 2},
+	"JPY": {Code: "JPY", Symbol: "¥", Decimals: 0},
+}
+
+// Currency settings, overridden at startup from BASE_CURRENCY, SUPPORTED_CURRENCIES and EXCHANGE_RATE_URL
+var (
+	baseCurrency        = "INR"
+	supportedCurrencies = []string{"INR"}
+	exchangeRateURL     string
+
+	ratesMu       sync.RWMutex
+	exchangeRates = map[string]float64{}
+
+	exchangeRateClient = &http.Client{Timeout: 15 * time.Second}
+)
+
+// ConfigureCurrency sets the base currency all prices are stored and charged in, the currencies
+// clients may request and the exchange rate feed. The feed URL may contain "{base}", replaced by
+// the base currency, and must return JSON with a "rates" object keyed by currency code.
+func ConfigureCurrency(base string, supported []string, rateURL string) error {
+	base = strings.ToUpper(base)
+	if _, ok := knownCurrencies[base]; !ok {
+		return fmt.Errorf("unsupported base currency %s", base)
+	}
+	codes := []string{base}
+	for _, code := range supported {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || code == base {
+			continue
+		}
+		if _, ok := knownCurrencies[code]; !ok {
+			return fmt.Errorf("unsupported currency %s", code)
+		}
+		codes = append(codes, code)
+	}
+	baseCurrency = base
+	supportedCurrencies = codes
+	exchangeRateURL = rateURL
+	LogInfo("Base currency %s, supported currencies %s", base, strings.Join(codes, ","))
+	return nil
+}
+
+// BaseCurrency returns the currency prices are stored and charged in
+func BaseCurrency() string {
+	return baseCurrency
+}
+
+// SupportedCurrencies returns the currencies clients may request, base currency first
+func SupportedCurrencies() []CurrencyInfo {
+	result := make([]CurrencyInfo, len(supportedCurrencies))
+	for i, code := range supportedCurrencies {
+		result[i] = knownCurrencies[code]
+	}
+	return result
+}
+
+// CurrencyInfoFor returns the display details of the currency, falling back to the base currency
+func CurrencyInfoFor(code string) CurrencyInfo {
+	if info, ok := knownCurrencies[code]; ok {
+		return info
+	}
+	return knownCurrencies[baseCurrency]
+}
+
+// GetExchangeRate returns the rate from the base currency to the currency
+func GetExchangeRate(code string) (float64, bool) {
+	if code == baseCurrency {
+		return 1, true
+	}
+	ratesMu.RLock()
+	defer ratesMu.RUnlock()
+	rate, ok := exchangeRates[code]
+	return rate, ok
+}
+
+// GetRequestCurrency returns the currency the request asked for through the currency query
+// parameter or X-Currency header. Unsupported currencies, or ones without a known rate,
+// fall back to the base currency.
+func GetRequestCurrency(c *gin.Context) string {
+	code := c.Query("currency")
+	if code == "" {
+		code = c.GetHeader(CurrencyHeader)
+	}
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" || code == baseCurrency {
+		return baseCurrency
+	}
+	for _, supported := range supportedCurrencies {
+		if supported == code {
+			if _, ok := GetExchangeRate(code); ok {
+				return code
+			}
+			break
+		}
+	}
+	return baseCurrency
+}
+
+// ConvertFromBase converts a base currency amount, rounded to the currency's decimals
+func ConvertFromBase(amount float64, code string) float64 {
+	rate, ok := GetExchangeRate(code)
+	if !ok {
+		return amount
+	}
+	return roundTo(amount*rate, CurrencyInfoFor(code).Decimals)
+}
+
+func roundTo(amount float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(amount*scale) / scale
+}
+
+// FormatMoney formats an amount already in the currency, e.g. "₹1234.50" or "¥1500"
+func FormatMoney(amount float64, code string) string {
+	info := CurrencyInfoFor(code)
+	return fmt.Sprintf("%s%.*f", info.Symbol, info.Decimals, amount)
+}
+
+// FormatBaseMoney formats a base currency amount
+func FormatBaseMoney(amount float64) string {
+	return FormatMoney(amount, baseCurrency)
+}
+
+// FormatRequestMoney converts a base currency amount to the request's currency and formats it
+func FormatRequestMoney(c *gin.Context, amount float64) string {
+	code := GetRequestCurrency(c)
+	return FormatMoney(ConvertFromBase(amount, code), code)
+}
+
+// LoadExchangeRates loads the stored exchange rates into memory
+func LoadExchangeRates() error {
+	var rows []models.ExchangeRate
+	if err := config.DB.Find(&rows).Error; err != nil {
+		return err
+	}
+	ratesMu.Lock()
+	defer ratesMu.Unlock()
+	exchangeRates = make(map[string]float64, len(rows))
+	for _, row := range rows {
+		if row.Rate > 0 {
+			exchangeRates[row.Currency] = row.Rate
+		}
+	}
+	return nil
+}
+
+// RefreshExchangeRates fetches the latest rates for the supported currencies from the feed
+// and stores them. Rates set manually by an admin are kept.
+func RefreshExchangeRates() (string, error) {
+	if exchangeRateURL == "" {
+		return "No exchange rate source configured", LoadExchangeRates()
+	}
+	feedURL := strings.ReplaceAll(exchangeRateURL, "{base}", baseCurrency)
+	resp, err := exchangeRateClient.Get(feedURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch exchange rates: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("exchange rate source returned status %d", resp.StatusCode)
+	}
+	var payload struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("invalid exchange rate response: %v", err)
+	}
+
+	source := feedURL
+	if parsed, err := url.Parse(feedURL); err == nil {
+		source = parsed.Host
+	}
+	now := time.Now()
+	var updated, missing []string
+	for _, code := range supportedCurrencies {
+		if code == baseCurrency {
+			continue
+		}
+		rate, ok := payload.Rates[code]
+		if !ok || rate <= 0 {
+			missing = append(missing, code)
+			continue
+		}
+		row := models.ExchangeRate{Currency: code, Rate: rate, Source: source, FetchedAt: now}
+		// Only overwrite rates that were not set manually
+		if err := config.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "currency"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "fetched_at", "updated_at"}),
+			Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "exchange_rates.manual = ?", Vars: []interface{}{false}}}},
+		}).Create(&row).Error; err != nil {
+			return "", err
+		}
+		updated = append(updated, code)
+	}
+	if err := LoadExchangeRates(); err != nil {
+		return "", err
+	}
+	// Cached prices in other currencies were converted with the old rates
+	if len(updated) > 0 {
+		InvalidateCatalogCache()
+	}
+
+	sort.Strings(missing)
+	message := fmt.Sprintf("Refreshed rates for %s", strings.Join(updated, ","))
+	if len(missing) > 0 {
+		message += fmt.Sprintf("; no rate for %s", strings.Join(missing, ","))
+	}
+	return message, nil
+}
+
+// SetManualExchangeRate stores an admin-set rate, which refreshes leave alone. A zero rate
+// clears the manual flag so the next refresh updates the currency again.
+func SetManualExchangeRate(code string, rate float64) (models.ExchangeRate, error) {
+	row := models.ExchangeRate{Currency: code, Rate: rate, Source: "manual", Manual: true, FetchedAt: time.Now()}
+	var err error
+	if rate == 0 {
+		err = config.DB.Model(&models.ExchangeRate{}).Where("currency = ?", code).
+			Updates(map[string]interface{}{"manual": false}).Error
+		if err == nil {
+			err = config.DB.Where("currency = ?", code).First(&row).Error
+		}
+	} else {
+		err = config.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "currency"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "manual", "fetched_at", "updated_at"}),
+		}).Create(&row).Error
+	}
+	if err != nil {
+		return row, err
+	}
+	return row, LoadExchangeRates()
+}
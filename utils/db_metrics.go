@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// dbQueryBuckets are the query duration histogram buckets, in seconds
+var dbQueryBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// Database metrics
+var (
+	dbQueryDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "readsphere_db_query_duration_seconds",
+		Help:    "Database query latency in seconds.",
+		Buckets: dbQueryBuckets,
+	})
+	dbSlowQueriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "readsphere_db_slow_queries_total",
+		Help: "Database queries slower than DB_SLOW_QUERY_THRESHOLD, by operation.",
+	}, []string{"operation"})
+)
+
+// Connection pool gauges, read from the pool when metrics are scraped
+var (
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{Name: "readsphere_db_open_connections", Help: "Open database connections, in use or idle."}, func() float64 {
+		return float64(dbPoolStats().OpenConnections)
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{Name: "readsphere_db_in_use_connections", Help: "Database connections currently in use."}, func() float64 {
+		return float64(dbPoolStats().InUse)
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{Name: "readsphere_db_idle_connections", Help: "Idle database connections."}, func() float64 {
+		return float64(dbPoolStats().Idle)
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{Name: "readsphere_db_max_open_connections", Help: "Most database connections the pool may open."}, func() float64 {
+		return float64(dbPoolStats().MaxOpenConnections)
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{Name: "readsphere_db_wait_count", Help: "Connections waited for because the pool was exhausted, since start."}, func() float64 {
+		return float64(dbPoolStats().WaitCount)
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{Name: "readsphere_db_wait_duration_seconds", Help: "Time spent waiting for a free connection, since start."}, func() float64 {
+		return dbPoolStats().WaitDuration.Seconds()
+	})
+)
+
+// dbPoolStats returns the connection pool statistics, empty before the database is connected
+func dbPoolStats() sql.DBStats {
+	if config.DB == nil {
+		return sql.DBStats{}
+	}
+	sqlDB, err := config.DB.DB()
+	if err != nil {
+		return sql.DBStats{}
+	}
+	return sqlDB.Stats()
+}
+
+// SlowQueryLogger is a GORM logger that times every query for the metrics and logs the
+// queries taking threshold or longer, with the ID of the request that ran them when the query
+// was given the request's context (see RequestDB)
+type SlowQueryLogger struct {
+	logger.Interface
+	threshold time.Duration
+}
+
+// NewSlowQueryLogger wraps GORM's default logger, leaving slow queries to the wrapper
+func NewSlowQueryLogger(threshold time.Duration) *SlowQueryLogger {
+	base := logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+		LogLevel: logger.Warn,
+		Colorful: true,
+	})
+	return &SlowQueryLogger{Interface: base, threshold: threshold}
+}
+
+// LogMode keeps the slow query logging when GORM changes the log level
+func (l *SlowQueryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &SlowQueryLogger{Interface: l.Interface.LogMode(level), threshold: l.threshold}
+}
+
+// Trace records the query's duration, and logs and counts it when it is slow
+func (l *SlowQueryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.Interface.Trace(ctx, begin, fc, err)
+
+	elapsed := time.Since(begin)
+	dbQueryDuration.Observe(elapsed.Seconds())
+	if l.threshold <= 0 || elapsed < l.threshold {
+		return
+	}
+	// Rendering the SQL is left to slow queries, to keep the others cheap
+	query, rows := fc()
+	dbSlowQueriesTotal.WithLabelValues(queryOperation(query)).Inc()
+
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = "-"
+	}
+	LogError("Slow query (%s, %d rows, request %s): %s", elapsed.Round(time.Millisecond), rows, requestID, query)
+}
+
+// queryOperation is the kind of statement, keeping the metric labels to a small set
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+	switch operation := strings.ToLower(fields[0]); operation {
+	case "select", "insert", "update", "delete":
+		return operation
+	default:
+		return "other"
+	}
+}
+
+// InstrumentDB sends the queries of db through the slow query logger
+func InstrumentDB(db *gorm.DB, threshold time.Duration) {
+	db.Logger = NewSlowQueryLogger(threshold)
+	if threshold > 0 {
+		LogInfo("Logging database queries slower than %s", threshold)
+	}
+}
+
+// RequestDB returns the database handle for queries made while serving the request, so slow
+// queries can be traced back to it by request ID
+func RequestDB(ctx context.Context) *gorm.DB {
+	return config.DB.WithContext(ctx)
+}
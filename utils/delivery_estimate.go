@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Errors returned when a delivery slot cannot be booked
+var (
+	ErrDeliverySlotInvalid = errors.New("delivery slot is not available for this address")
+	ErrDeliverySlotDate    = errors.New("delivery slot date is outside the bookable dates")
+	ErrDeliverySlotFull    = errors.New("delivery slot is fully booked")
+)
+
+// DeliveryEstimate is when an order to a pincode is expected to arrive
+type DeliveryEstimate struct {
+	Zone         string
+	MinDays      int
+	MaxDays      int
+	EarliestDate time.Time
+	LatestDate   time.Time
+	SlotsEnabled bool
+}
+
+// Label describes the delivery time, such as "3-7 working days"
+func (e DeliveryEstimate) Label() string {
+	if e.MinDays == e.MaxDays {
+		if e.MaxDays == 1 {
+			return "1 working day"
+		}
+		return fmt.Sprintf("%d working days", e.MaxDays)
+	}
+	return fmt.Sprintf("%d-%d working days", e.MinDays, e.MaxDays)
+}
+
+// DeliverySlotOption is a delivery slot on a date that can still be booked
+type DeliverySlotOption struct {
+	SlotID    uint
+	Date      time.Time
+	StartTime string
+	EndTime   string
+	Remaining int
+}
+
+// Label describes the slot's window, such as "09:00-13:00"
+func (o DeliverySlotOption) Label() string {
+	return o.StartTime + "-" + o.EndTime
+}
+
+// startOfDay returns midnight of t's day in t's location
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// isWorkingDay reports whether deliveries are made on t's day, which is any weekday
+func isWorkingDay(t time.Time) bool {
+	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+}
+
+// AddWorkingDays returns the day that is days working days after from, skipping weekends
+func AddWorkingDays(from time.Time, days int) time.Time {
+	day := startOfDay(from)
+	for days > 0 {
+		day = day.AddDate(0, 0, 1)
+		if isWorkingDay(day) {
+			days--
+		}
+	}
+	return day
+}
+
+// zoneDeliverySLA returns the active delivery time configured for a zone, or nil when the zone
+// uses the defaults
+func zoneDeliverySLA(zone string) (*models.DeliverySLA, error) {
+	if zone == "" {
+		return nil, nil
+	}
+	var sla models.DeliverySLA
+	err := config.DB.Where("LOWER(zone) = ? AND is_active = ?", strings.ToLower(zone), true).First(&sla).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sla, nil
+}
+
+// EstimateDelivery estimates when an order placed at from reaches the pincode, using the
+// delivery time of the pincode's zone. It returns ErrDeliveryUnavailable when the pincode is
+// not served.
+func EstimateDelivery(pincode string, from time.Time) (*DeliveryEstimate, error) {
+	rule, err := FindDeliveryRule(pincode)
+	if err != nil {
+		return nil, ErrDeliveryUnavailable
+	}
+
+	estimate := DeliveryEstimate{Zone: rule.Zone}
+	estimate.MinDays, estimate.MaxDays = DefaultDeliveryDays()
+	sla, err := zoneDeliverySLA(rule.Zone)
+	if err != nil {
+		return nil, err
+	}
+	if sla != nil {
+		estimate.MinDays, estimate.MaxDays = sla.MinDays, sla.MaxDays
+		estimate.SlotsEnabled = sla.SlotsEnabled
+	}
+	if estimate.MaxDays < estimate.MinDays {
+		estimate.MaxDays = estimate.MinDays
+	}
+	estimate.EarliestDate = AddWorkingDays(from, estimate.MinDays)
+	estimate.LatestDate = AddWorkingDays(from, estimate.MaxDays)
+	return &estimate, nil
+}
+
+// deliverySlotDates returns the working days a slot can be booked for under the estimate
+func deliverySlotDates(estimate DeliveryEstimate) []time.Time {
+	day := estimate.EarliestDate
+	if !isWorkingDay(day) {
+		day = AddWorkingDays(day, 1)
+	}
+	dates := make([]time.Time, 0, DeliverySlotDays())
+	for len(dates) < cap(dates) {
+		dates = append(dates, day)
+		day = AddWorkingDays(day, 1)
+	}
+	return dates
+}
+
+// bookedDeliverySlots counts the orders booked into each of the slots on each date between
+// from and to, keyed by slot ID and date
+func bookedDeliverySlots(db *gorm.DB, slotIDs []uint, from, to time.Time) (map[string]int, error) {
+	var rows []struct {
+		DeliverySlotID   uint
+		DeliverySlotDate time.Time
+		Booked           int
+	}
+	if err := db.Model(&models.Order{}).
+		Select("delivery_slot_id, delivery_slot_date, COUNT(*) AS booked").
+		Where("delivery_slot_id IN ? AND delivery_slot_date BETWEEN ? AND ? AND status <> ?",
+			slotIDs, from.Format("2006-01-02"), to.Format("2006-01-02"), models.OrderStatusCancelled).
+		Group("delivery_slot_id, delivery_slot_date").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	booked := make(map[string]int, len(rows))
+	for _, row := range rows {
+		booked[slotBookingKey(row.DeliverySlotID, row.DeliverySlotDate)] = row.Booked
+	}
+	return booked, nil
+}
+
+func slotBookingKey(slotID uint, date time.Time) string {
+	return fmt.Sprintf("%d/%s", slotID, date.Format("2006-01-02"))
+}
+
+// AvailableDeliverySlots lists the slots of the estimate's zone that still have room, for each
+// bookable date. It is empty when the zone does not offer slots.
+func AvailableDeliverySlots(estimate DeliveryEstimate) ([]DeliverySlotOption, error) {
+	if !estimate.SlotsEnabled {
+		return nil, nil
+	}
+	var slots []models.DeliverySlot
+	if err := config.DB.Where("LOWER(zone) = ? AND is_active = ?", strings.ToLower(estimate.Zone), true).
+		Order("start_time").Find(&slots).Error; err != nil {
+		return nil, err
+	}
+	if len(slots) == 0 {
+		return nil, nil
+	}
+
+	dates := deliverySlotDates(estimate)
+	slotIDs := make([]uint, len(slots))
+	for i, slot := range slots {
+		slotIDs[i] = slot.ID
+	}
+	booked, err := bookedDeliverySlots(config.DB, slotIDs, dates[0], dates[len(dates)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	options := make([]DeliverySlotOption, 0, len(dates)*len(slots))
+	for _, date := range dates {
+		for _, slot := range slots {
+			remaining := slot.Capacity - booked[slotBookingKey(slot.ID, date)]
+			if remaining <= 0 {
+				continue
+			}
+			options = append(options, DeliverySlotOption{
+				SlotID:    slot.ID,
+				Date:      date,
+				StartTime: slot.StartTime,
+				EndTime:   slot.EndTime,
+				Remaining: remaining,
+			})
+		}
+	}
+	return options, nil
+}
+
+// ReserveDeliverySlot checks that the slot can be booked on the date for a delivery under the
+// estimate and has room left. The slot row is locked through tx so concurrent checkouts
+// cannot overbook it; the booking itself is the order saved in the same transaction.
+func ReserveDeliverySlot(tx *gorm.DB, estimate DeliveryEstimate, slotID uint, date time.Time) (*models.DeliverySlot, error) {
+	if !estimate.SlotsEnabled {
+		return nil, ErrDeliverySlotInvalid
+	}
+	var slot models.DeliverySlot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND is_active = ?", slotID, true).First(&slot).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !strings.EqualFold(slot.Zone, estimate.Zone)) {
+		return nil, ErrDeliverySlotInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bookable := false
+	for _, d := range deliverySlotDates(estimate) {
+		if d.Format("2006-01-02") == date.Format("2006-01-02") {
+			bookable = true
+			break
+		}
+	}
+	if !bookable {
+		return nil, ErrDeliverySlotDate
+	}
+
+	booked, err := bookedDeliverySlots(tx, []uint{slot.ID}, date, date)
+	if err != nil {
+		return nil, err
+	}
+	if booked[slotBookingKey(slot.ID, date)] >= slot.Capacity {
+		return nil, ErrDeliverySlotFull
+	}
+	return &slot, nil
+}
@@ -1,46 +1,81 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 )
 
+// ErrDeliveryUnavailable is returned when no active delivery charge rule covers a pincode
+var ErrDeliveryUnavailable = errors.New("delivery is not available for this pincode")
+
+// IsValidPincode reports whether the value is a valid 6-digit Indian PIN code
+func IsValidPincode(pincode string) bool {
+	return postalCodeIndiaRegex.MatchString(pincode)
+}
+
+// FindDeliveryRule returns the active delivery charge rule whose pincode range covers the pincode
+func FindDeliveryRule(pincode string) (*models.DeliveryCharge, error) {
+	var rule models.DeliveryCharge
+	if err := config.DB.Where("is_active = ? AND pincode_from <= ? AND pincode_to >= ?", true, pincode, pincode).
+		Order("pincode_to, pincode_from DESC").
+		First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
 // GetDeliveryCharge calculates delivery charge based on pincode and order amount
 func GetDeliveryCharge(pincode string, orderAmount float64) (float64, error) {
-	db := config.DB
-
 	// Debug: Log the pincode being searched
 	LogInfo("Searching for delivery charge for pincode: %s, order amount: %.2f", pincode, orderAmount)
 
-	// Find delivery charge for the specific pincode
-	var deliveryCharge models.DeliveryCharge
-	if err := db.Where("pincode = ? AND is_active = ?", pincode, true).
-		First(&deliveryCharge).Error; err == nil {
+	rule, err := FindDeliveryRule(pincode)
+	if err != nil {
+		// Pincodes without a delivery charge rule are not serviceable
+		LogInfo("Pincode %s not covered by any delivery charge rule", pincode)
+		return 0, ErrDeliveryUnavailable
+	}
 
-		LogInfo("Found delivery charge: %.2f for pincode %s", deliveryCharge.Charge, pincode)
-		return deliveryCharge.Charge, nil
+	if rule.FreeDeliveryAbove > 0 && orderAmount >= rule.FreeDeliveryAbove {
+		LogInfo("Free delivery for pincode %s: order amount %.2f meets threshold %.2f", pincode, orderAmount, rule.FreeDeliveryAbove)
+		return 0, nil
 	}
 
-	// Debug: Log when pincode not found
-	LogInfo("Pincode %s not found in delivery_charges table, using default charge: 50.0", pincode)
+	LogInfo("Found delivery charge: %.2f for pincode %s", rule.Charge, pincode)
+	return rule.Charge, nil
+}
 
-	// Default delivery charge if pincode not found
-	return 50.0, nil
+// ValidateDeliveryPincode returns a field error when the pincode is not covered by
+// an active delivery charge rule, so undeliverable addresses are rejected at save time
+func ValidateDeliveryPincode(pincode string) *FieldValidationError {
+	if IsDeliveryAvailable(pincode) {
+		return nil
+	}
+	return &FieldValidationError{"postal_code", "Delivery is not available for this pincode"}
 }
 
 // IsDeliveryAvailable checks if delivery is available to the given pincode
 func IsDeliveryAvailable(pincode string) bool {
-	db := config.DB
-
-	// Check if there's a delivery charge rule for this pincode
-	var count int64
-	db.Model(&models.DeliveryCharge{}).
-		Where("pincode = ? AND is_active = ?", pincode, true).
-		Count(&count)
+	_, err := FindDeliveryRule(pincode)
+	return err == nil
+}
 
-	return count > 0
+// IsCODAvailable checks if cash on delivery is offered for the given pincode: its delivery
+// rule allows it and an admin has not turned it off for the pincode
+func IsCODAvailable(pincode string) bool {
+	rule, err := FindDeliveryRule(pincode)
+	if err != nil || !rule.CODAvailable {
+		return false
+	}
+	var blocked int64
+	if err := config.DB.Model(&models.CODBlockedPincode{}).Where("pincode = ?", pincode).Count(&blocked).Error; err != nil {
+		LogError("Failed to check COD block for pincode %s: %v", pincode, err)
+		return false
+	}
+	return blocked == 0
 }
 
 // GetDeliveryChargeBreakdown returns detailed delivery charge information
@@ -61,15 +96,7 @@ func GetDeliveryChargeBreakdown(pincode string, orderAmount float64) (map[string
 
 // GetDeliveryChargeByPincode returns delivery charge info for a specific pincode
 func GetDeliveryChargeByPincode(pincode string) (*models.DeliveryCharge, error) {
-	db := config.DB
-
-	var deliveryCharge models.DeliveryCharge
-	if err := db.Where("pincode = ? AND is_active = ?", pincode, true).
-		First(&deliveryCharge).Error; err != nil {
-		return nil, err
-	}
-
-	return &deliveryCharge, nil
+	return FindDeliveryRule(pincode)
 }
 
 // DebugDeliveryChargesTable logs the status of the delivery_charges table
@@ -93,8 +120,8 @@ func DebugDeliveryChargesTable() {
 
 		LogInfo("Sample delivery charges:")
 		for _, charge := range sampleCharges {
-			LogInfo("  Pincode: %s, Charge: %.2f, Min Order: %.2f, Active: %t",
-				charge.Pincode, charge.Charge, charge.MinOrderAmount, charge.IsActive)
+			LogInfo("  Pincodes: %s-%s, Zone: %s, Charge: %.2f, Free Above: %.2f, COD: %t, Active: %t",
+				charge.PincodeFrom, charge.PincodeTo, charge.Zone, charge.Charge, charge.FreeDeliveryAbove, charge.CODAvailable, charge.IsActive)
 		}
 	}
 }
@@ -102,20 +129,30 @@ func DebugDeliveryChargesTable() {
 // GetFreeDeliveryInfo returns information about delivery charge
 func GetFreeDeliveryInfo(pincode string, orderAmount float64) map[string]interface{} {
 	// Check specific pincode charges
-	charge, err := GetDeliveryChargeByPincode(pincode)
+	rule, err := GetDeliveryChargeByPincode(pincode)
 	if err != nil {
 		return map[string]interface{}{
-			"eligible":       false,
-			"message":        "Delivery not available for this pincode",
-			"regular_charge": 50.0,
-			"current_charge": 50.0,
+			"eligible": false,
+			"message":  "Delivery not available for this pincode",
+		}
+	}
+
+	currentCharge := rule.Charge
+	message := fmt.Sprintf("Delivery charge: %s", FormatBaseMoney(rule.Charge))
+	if rule.FreeDeliveryAbove > 0 {
+		if orderAmount >= rule.FreeDeliveryAbove {
+			currentCharge = 0
+			message = "Free delivery applied"
+		} else {
+			message = fmt.Sprintf("Add %s more for free delivery", FormatBaseMoney(rule.FreeDeliveryAbove-orderAmount))
 		}
 	}
 
 	return map[string]interface{}{
-		"eligible":       true,
-		"regular_charge": charge.Charge,
-		"current_charge": charge.Charge,
-		"message":        fmt.Sprintf("Delivery charge: ₹%.2f", charge.Charge),
+		"eligible":            true,
+		"regular_charge":      rule.Charge,
+		"current_charge":      currentCharge,
+		"free_delivery_above": rule.FreeDeliveryAbove,
+		"message":             message,
 	}
 }
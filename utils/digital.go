@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/golang-jwt/jwt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EbookDownloadLinkTTL is how long a digital book download link stays valid
+const EbookDownloadLinkTTL = 15 * time.Minute
+
+// ErrDownloadLinkInvalid is returned for download links that are malformed, tampered with or expired
+var ErrDownloadLinkInvalid = errors.New("download link is invalid or has expired")
+
+// GrantDigitalEntitlements gives the buyer of a paid order access to the digital books in it.
+// Cancelled items are left out; a book bought again has its entitlement restored.
+func GrantDigitalEntitlements(tx *gorm.DB, order *models.Order) error {
+	var items []models.OrderItem
+	if err := tx.Preload("Book").Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+		return err
+	}
+	for _, item := range items {
+		if !item.Book.IsDigital || item.CancellationStatus == "Cancelled" || item.CancellationStatus == "Approved" {
+			continue
+		}
+		entitlement := models.DigitalEntitlement{
+			UserID:  order.UserID,
+			BookID:  item.BookID,
+			OrderID: order.ID,
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "book_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"order_id": order.ID, "revoked_at": nil, "downloads": 0, "updated_at": time.Now()}),
+		}).Create(&entitlement).Error; err != nil {
+			return err
+		}
+		LogInfo("Granted digital book ID: %d to user ID: %d for order ID: %d", item.BookID, order.UserID, order.ID)
+	}
+	return nil
+}
+
+// RevokeDigitalEntitlements withdraws the digital books granted by an order, e.g. when it is cancelled
+func RevokeDigitalEntitlements(tx *gorm.DB, orderID uint) error {
+	return tx.Model(&models.DigitalEntitlement{}).
+		Where("order_id = ? AND revoked_at IS NULL", orderID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// SignDownloadToken returns a link token for downloading the entitlement's book and when it expires
+func SignDownloadToken(entitlementID uint) (string, time.Time, error) {
+	expiresAt := time.Now().Add(EbookDownloadLinkTTL)
+	// A claim of its own keeps the token from being usable as a login token
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"download_entitlement_id": entitlementID,
+		"exp":                     expiresAt.Unix(),
+	})
+	signed, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// ParseDownloadToken returns the entitlement a download link token was issued for
+func ParseDownloadToken(tokenString string) (uint, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err != nil || !token.Valid {
+		return 0, ErrDownloadLinkInvalid
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, ErrDownloadLinkInvalid
+	}
+	entitlementID, ok := claims["download_entitlement_id"].(float64)
+	if !ok {
+		return 0, ErrDownloadLinkInvalid
+	}
+	return uint(entitlementID), nil
+}
@@ -2,8 +2,8 @@ package utils
 
 import (
 	"fmt"
-	"net/smtp"
 	"os"
+	"strconv"
 
 	"gopkg.in/gomail.v2"
 )
@@ -15,84 +15,90 @@ type EmailConfig struct {
 	Username string
 	Password string
 	From     string
+	FromName string
+	ReplyTo  string
 }
 
-// SendEmail sends an email using SMTP
-func SendEmail(to, subject, body string) error {
-	// Get SMTP configuration from environment variables
-	smtpHost := os.Getenv("SMTP_HOST")
-	smtpPort := os.Getenv("SMTP_PORT")
-	smtpUsername := os.Getenv("SMTP_USERNAME")
-	smtpPassword := os.Getenv("SMTP_PASSWORD")
-
-	// Create email message
-	message := fmt.Sprintf("Subject: %s\r\n"+
-		"To: %s\r\n"+
-		"Content-Type: text/html; charset=UTF-8\r\n"+
-		"\r\n"+
-		"%s", subject, to, body)
-
-	// Connect to SMTP server
-	auth := smtp.PlainAuth("", smtpUsername, smtpPassword, smtpHost)
-	addr := fmt.Sprintf("%s:%s", smtpHost, smtpPort)
-
-	// Send email
-	err := smtp.SendMail(addr, auth, smtpUsername, []string{to}, []byte(message))
-	if err != nil {
-		return fmt.Errorf("failed to send email: %v", err)
+// LoadEmailConfig reads the SMTP server and sender identity from the environment. The sender
+// address defaults to SMTP_USERNAME and the sender name to the brand name.
+func LoadEmailConfig() EmailConfig {
+	port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	if err != nil || port <= 0 {
+		port = 587
 	}
-
-	return nil
-}
-
-// SendOTP sends an OTP via email
-func SendOTP(to, otp string) error {
-	// Get email configuration from environment variables
-	config := EmailConfig{
+	return EmailConfig{
 		Host:     os.Getenv("SMTP_HOST"),
-		Port:     587, // Default SMTP port
+		Port:     port,
 		Username: os.Getenv("SMTP_USERNAME"),
 		Password: os.Getenv("SMTP_PASSWORD"),
-		From:     os.Getenv("SMTP_FROM"),
+		From:     getEnvOr("SMTP_FROM", os.Getenv("SMTP_USERNAME")),
+		FromName: getEnvOr("SMTP_FROM_NAME", GetEmailBranding().Name),
+		ReplyTo:  os.Getenv("EMAIL_REPLY_TO"),
 	}
+}
+
+// SendEmail sends an HTML email using SMTP. Bodies are normally rendered from a template with
+// SendTemplateEmail.
+func SendEmail(to, subject, body string) error {
+	config := LoadEmailConfig()
 
-	// Create email message
 	m := gomail.NewMessage()
-	m.SetHeader("From", config.From)
+	m.SetAddressHeader("From", config.From, config.FromName)
 	m.SetHeader("To", to)
-	m.SetHeader("Subject", "Your ReadSphere Registration OTP")
-
-	// Create email body
-	body := fmt.Sprintf(`
-		<h2>Welcome to ReadSphere!</h2>
-		<p>Thank you for registering. Please use the following OTP to verify your email address:</p>
-		<h1 style="color: #4CAF50; font-size: 32px; letter-spacing: 5px;">%s</h1>
-		<p>This OTP will expire in 15 minutes.</p>
-		<p>If you didn't request this OTP, please ignore this email.</p>
-	`, otp)
+	if config.ReplyTo != "" {
+		m.SetHeader("Reply-To", config.ReplyTo)
+	}
+	m.SetHeader("Subject", subject)
 	m.SetBody("text/html", body)
 
-	// Create SMTP dialer
 	d := gomail.NewDialer(config.Host, config.Port, config.Username, config.Password)
-
-	// Send email
 	if err := d.DialAndSend(m); err != nil {
 		return fmt.Errorf("failed to send email: %v", err)
 	}
+	return nil
+}
+
+// otpEmailCopy is the subject, heading and introduction of the OTP email of each purpose
+var otpEmailCopy = map[string][3]string{
+	OTPPurposeRegistration:  {"Verify your %s email", "Welcome to %s!", "Thank you for registering. Please use the following OTP to verify your email address:"},
+	OTPPurposePasswordReset: {"Your %s password reset code", "Reset your %s password", "Use the following OTP to reset your password:"},
+	OTPPurposeEmailChange:   {"Confirm your new %s email address", "Confirm your new %s email", "Use the following OTP to confirm this address for your account:"},
+}
+
+// SendOTP emails an OTP for the purpose's flow
+func SendOTP(to, otp, purpose string) error {
+	brand := GetEmailBranding().Name
+	text, ok := otpEmailCopy[purpose]
+	if !ok {
+		text = [3]string{"Your %s verification code", "Your %s verification code", "Use the following OTP to continue:"}
+	}
+
+	err := SendTemplateEmail(to, fmt.Sprintf(text[0], brand), "otp", map[string]interface{}{
+		"Title":   fmt.Sprintf(text[1], brand),
+		"Intro":   text[2],
+		"OTP":     otp,
+		"Minutes": int(OTPLifetime(purpose).Minutes()),
+	})
+	if err != nil {
+		return err
+	}
+	RecordOTPSent()
 
 	return nil
 }
 
 // SendPasswordResetEmail sends a password reset email
 func SendPasswordResetEmail(to, resetToken string) error {
-	subject := "Password Reset Request"
-	body := fmt.Sprintf(`
-		<h2>Password Reset Request</h2>
-		<p>You have requested to reset your password. Click the link below to proceed:</p>
-		<p><a href="%s/reset-password?token=%s">Reset Password</a></p>
-		<p>This link will expire in 1 hour.</p>
-		<p>If you didn't request this reset, please ignore this email.</p>
-	`, os.Getenv("FRONTEND_URL"), resetToken)
+	return SendTemplateEmail(to, "Password Reset Request", "password_reset", map[string]interface{}{
+		"ResetURL": FrontendLink("/reset-password?token=" + resetToken),
+	})
+}
 
-	return SendEmail(to, subject, body)
+// SendDeliveryOTPEmail sends the OTP the customer gives the delivery agent on handover
+func SendDeliveryOTPEmail(to string, orderID uint, otp string) error {
+	subject := fmt.Sprintf("Delivery OTP for your %s order #%d", GetEmailBranding().Name, orderID)
+	return SendTemplateEmail(to, subject, "delivery_otp", map[string]interface{}{
+		"OrderID": orderID,
+		"OTP":     otp,
+	})
 }
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"html/template"
+	"time"
+)
+
+// EmailPreview renders the named template with sample data, so the branding and layout can be
+// checked without sending mail
+func EmailPreview(name string) (string, error) {
+	brand := GetEmailBranding().Name
+	items := []EmailItem{
+		{Name: "The Pragmatic Programmer", Note: "David Thomas, Andrew Hunt", Quantity: 1, Amount: FormatBaseMoney(749)},
+		{Name: "Clean Code", Note: "Robert C. Martin", Quantity: 2, Amount: FormatBaseMoney(1198)},
+	}
+	expires := time.Now().AddDate(1, 0, 0).Format("2 Jan 2006")
+
+	samples := map[string]struct {
+		subject string
+		data    map[string]interface{}
+	}{
+		"otp": {"Verify your " + brand + " email", map[string]interface{}{
+			"Title":   "Welcome to " + brand + "!",
+			"Intro":   "Thank you for registering. Please use the following OTP to verify your email address:",
+			"OTP":     "482913",
+			"Minutes": 10,
+		}},
+		"delivery_otp": {"Delivery OTP for your " + brand + " order #1042", map[string]interface{}{
+			"OrderID": 1042,
+			"OTP":     "7391",
+		}},
+		"password_reset": {"Password Reset Request", map[string]interface{}{
+			"ResetURL": FrontendLink("/reset-password?token=sample"),
+		}},
+		"order_placed": {"Your " + brand + " order #1042 is confirmed", map[string]interface{}{
+			"Name":    "Asha",
+			"OrderID": 1042,
+			"Items":   items,
+			"Totals": []EmailTotal{
+				{Label: "Subtotal", Amount: FormatBaseMoney(1947)},
+				{Label: "Discounts", Amount: "-" + FormatBaseMoney(100)},
+				{Label: "Delivery", Amount: FormatBaseMoney(40)},
+				{Label: "Total", Amount: FormatBaseMoney(1887)},
+			},
+			"PaymentMethod": "Cash on Delivery",
+			"DeliveryDate":  time.Now().AddDate(0, 0, 4).Format("Mon, 2 Jan 2006"),
+			"OrderURL":      FrontendLink("/orders/1042"),
+		}},
+		"order_status": {"Your " + brand + " order #1042 is Shipped", map[string]interface{}{
+			"Name":     "Asha",
+			"OrderID":  1042,
+			"Status":   "Shipped",
+			"Message":  "Your order has been handed to the courier and is on its way.",
+			"Note":     "Tracking number SAMPLE123",
+			"Items":    items,
+			"OrderURL": FrontendLink("/orders/1042"),
+		}},
+		"gift_card": {"Ravi sent you a " + brand + " gift card", map[string]interface{}{
+			"Name":      "Asha",
+			"Amount":    FormatBaseMoney(500),
+			"From":      "Ravi",
+			"Message":   "Happy birthday!",
+			"Code":      "GIFT-SAMPLE-CODE",
+			"ExpiresAt": expires,
+		}},
+		"cart_expiry": {"Items in your cart are about to expire", map[string]interface{}{
+			"Name":    "Asha",
+			"Items":   items,
+			"CartURL": FrontendLink("/cart"),
+		}},
+		"abandoned_cart": {"You left books in your " + brand + " cart", map[string]interface{}{
+			"Name":      "Asha",
+			"Items":     items,
+			"CartTotal": FormatBaseMoney(1947),
+			"CartURL":   FrontendLink("/cart"),
+			"Marketing": true,
+		}},
+		"back_in_stock": {"Clean Code is back in stock", map[string]interface{}{
+			"Name":    "Asha",
+			"Book":    "Clean Code",
+			"Author":  "Robert C. Martin",
+			"BookURL": FrontendLink("/books/1"),
+		}},
+		"announcement": {"New arrivals this week", map[string]interface{}{
+			"Name":      "Asha",
+			"Body":      template.HTML("<p>Fresh titles have just landed in the store. Take a look before they sell out!</p>"),
+			"Marketing": true,
+		}},
+	}
+
+	sample, ok := samples[name]
+	if !ok {
+		return "", ErrEmailTemplateNotFound
+	}
+	return RenderEmail(name, sample.subject, sample.data)
+}
@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed templates/email/*.html
+var emailTemplateFiles embed.FS
+
+// ErrEmailTemplateNotFound is returned for a template name that has no file
+var ErrEmailTemplateNotFound = errors.New("email template not found")
+
+// EmailBranding is the sender identity and look shared by every email, from EMAIL_BRAND_NAME,
+// EMAIL_BRAND_COLOR, EMAIL_LOGO_URL, EMAIL_SUPPORT_ADDRESS and FRONTEND_URL
+type EmailBranding struct {
+	Name         string
+	Color        string
+	LogoURL      string
+	SupportEmail string
+	FrontendURL  string
+}
+
+// EmailItem is a line of the item table in order and cart emails
+type EmailItem struct {
+	Name     string
+	Note     string
+	Quantity int
+	Amount   string
+}
+
+// EmailTotal is a labelled amount below the item table
+type EmailTotal struct {
+	Label  string
+	Amount string
+}
+
+var (
+	emailTemplatesOnce sync.Once
+	emailTemplates     map[string]*template.Template
+	emailTemplatesErr  error
+	emailBranding      EmailBranding
+)
+
+// hexColor guards the brand color, which ends up inside inline styles
+var hexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// emailLayoutFiles are parsed into every template; the other files each hold one email's content
+var emailLayoutFiles = []string{"layout.html", "partials.html"}
+
+// GetEmailBranding returns the branding emails are rendered with
+func GetEmailBranding() EmailBranding {
+	loadEmailTemplates()
+	return emailBranding
+}
+
+// loadEmailTemplates reads the branding and parses the embedded templates once
+func loadEmailTemplates() {
+	emailTemplatesOnce.Do(func() {
+		emailBranding = EmailBranding{
+			Name:         getEnvOr("EMAIL_BRAND_NAME", "ReadSphere"),
+			Color:        getEnvOr("EMAIL_BRAND_COLOR", "#4CAF50"),
+			LogoURL:      os.Getenv("EMAIL_LOGO_URL"),
+			SupportEmail: os.Getenv("EMAIL_SUPPORT_ADDRESS"),
+			FrontendURL:  strings.TrimRight(os.Getenv("FRONTEND_URL"), "/"),
+		}
+		if !hexColor.MatchString(emailBranding.Color) {
+			LogError("EMAIL_BRAND_COLOR %q is not a hex color, using the default", emailBranding.Color)
+			emailBranding.Color = "#4CAF50"
+		}
+
+		styles := emailStyles(emailBranding.Color)
+		base, err := template.New("email").Funcs(template.FuncMap{
+			"style": func(name string) template.CSS { return styles[name] },
+			"dict":  emailDict,
+		}).ParseFS(emailTemplateFiles, prefixEmailFiles(emailLayoutFiles)...)
+		if err != nil {
+			emailTemplatesErr = fmt.Errorf("failed to parse email layout: %v", err)
+			return
+		}
+
+		files, err := emailTemplateFiles.ReadDir("templates/email")
+		if err != nil {
+			emailTemplatesErr = err
+			return
+		}
+		emailTemplates = map[string]*template.Template{}
+		for _, file := range files {
+			if isEmailLayoutFile(file.Name()) {
+				continue
+			}
+			tmpl, err := template.Must(base.Clone()).ParseFS(emailTemplateFiles, "templates/email/"+file.Name())
+			if err != nil {
+				emailTemplatesErr = fmt.Errorf("failed to parse email template %s: %v", file.Name(), err)
+				return
+			}
+			emailTemplates[strings.TrimSuffix(file.Name(), path.Ext(file.Name()))] = tmpl
+		}
+	})
+}
+
+// EmailTemplateNames lists the available email templates
+func EmailTemplateNames() []string {
+	loadEmailTemplates()
+	names := make([]string, 0, len(emailTemplates))
+	for name := range emailTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RenderEmail renders the named template inside the branded layout. data is the template's
+// own fields; Brand, Subject, Year and Marketing (set it for promotional emails, which get a
+// link to the alert settings) are added for the layout.
+func RenderEmail(name, subject string, data map[string]interface{}) (string, error) {
+	loadEmailTemplates()
+	if emailTemplatesErr != nil {
+		return "", emailTemplatesErr
+	}
+	tmpl, ok := emailTemplates[name]
+	if !ok {
+		return "", ErrEmailTemplateNotFound
+	}
+
+	view := map[string]interface{}{
+		"Brand":     emailBranding,
+		"Subject":   subject,
+		"Year":      time.Now().Year(),
+		"Marketing": false,
+	}
+	for key, value := range data {
+		view[key] = value
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "layout", view); err != nil {
+		return "", fmt.Errorf("failed to render email template %s: %v", name, err)
+	}
+	return buf.String(), nil
+}
+
+// SendTemplateEmail renders the named template and emails it. Marketing emails are refused;
+// they are sent with SendMarketingEmail, which checks the user's consent.
+func SendTemplateEmail(to, subject, name string, data map[string]interface{}) error {
+	if marketing, _ := data["Marketing"].(bool); marketing {
+		return ErrMarketingEmailWithoutConsentCheck
+	}
+	body, err := RenderEmail(name, subject, data)
+	if err != nil {
+		return err
+	}
+	return SendEmail(to, subject, body)
+}
+
+// FrontendLink returns the absolute storefront URL of a path such as /orders/12
+func FrontendLink(link string) string {
+	return GetEmailBranding().FrontendURL + link
+}
+
+// emailStyles are the inline styles of the layout and partials. Many mail clients drop <style>
+// blocks, so every element carries its own.
+func emailStyles(color string) map[string]template.CSS {
+	styles := map[string]string{
+		"body":       "margin:0;padding:0;background-color:#f4f4f7;font-family:Helvetica,Arial,sans-serif;color:#333333;",
+		"wrapper":    "background-color:#f4f4f7;padding:24px 0;",
+		"container":  "max-width:600px;width:100%;background-color:#ffffff;border-radius:6px;",
+		"header":     "background-color:" + color + ";padding:20px 24px;color:#ffffff;font-size:22px;font-weight:bold;border-radius:6px 6px 0 0;",
+		"logo":       "display:block;border:0;",
+		"content":    "padding:24px;font-size:15px;line-height:1.6;",
+		"heading":    "margin:0 0 16px;font-size:20px;color:#222222;",
+		"code":       "margin:16px 0;font-size:32px;font-weight:bold;letter-spacing:6px;color:" + color + ";",
+		"buttonRow":  "margin:24px 0;",
+		"button":     "display:inline-block;padding:12px 24px;background-color:" + color + ";color:#ffffff;text-decoration:none;border-radius:4px;font-weight:bold;",
+		"table":      "border-collapse:collapse;margin:16px 0;",
+		"cell":       "padding:8px 0;border-bottom:1px solid #eeeeee;",
+		"cellRight":  "padding:8px 0 8px 12px;border-bottom:1px solid #eeeeee;text-align:right;white-space:nowrap;",
+		"muted":      "color:#888888;font-size:13px;",
+		"note":       "padding:12px;background-color:#f7f7f7;border-left:3px solid " + color + ";",
+		"footer":     "padding:16px 24px;text-align:center;",
+		"footerText": "margin:4px 0;font-size:12px;color:#888888;",
+		"footerLink": "color:#888888;",
+	}
+	css := make(map[string]template.CSS, len(styles))
+	for name, style := range styles {
+		css[name] = template.CSS(style)
+	}
+	return css
+}
+
+// emailDict builds the argument map of a partial from key and value pairs
+func emailDict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict needs key and value pairs")
+	}
+	dict := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict key %v is not a string", pairs[i])
+		}
+		dict[key] = pairs[i+1]
+	}
+	return dict, nil
+}
+
+func prefixEmailFiles(names []string) []string {
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = "templates/email/" + name
+	}
+	return paths
+}
+
+func isEmailLayoutFile(name string) bool {
+	for _, layout := range emailLayoutFiles {
+		if name == layout {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+package utils
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCode is a stable, machine-readable identifier for an error response. Messages may
+// change and be translated; codes do not, so clients should branch on the code.
+type ErrorCode string
+
+// Generic codes, used by the status helpers (BadRequest, NotFound...) when no specific code applies
+const (
+	CodeBadRequest       ErrorCode = "BAD_REQUEST"
+	CodeUnauthorized     ErrorCode = "UNAUTHORIZED"
+	CodeForbidden        ErrorCode = "FORBIDDEN"
+	CodeNotFound         ErrorCode = "NOT_FOUND"
+	CodeConflict         ErrorCode = "CONFLICT"
+	CodeValidationFailed ErrorCode = "VALIDATION_FAILED"
+	CodeTooManyRequests  ErrorCode = "TOO_MANY_REQUESTS"
+	CodeInternalError    ErrorCode = "INTERNAL_ERROR"
+	CodeUpstreamFailed   ErrorCode = "UPSTREAM_UNAVAILABLE"
+)
+
+// Request and authentication codes
+const (
+	CodeInvalidRequest     ErrorCode = "INVALID_REQUEST"
+	CodeInvalidID          ErrorCode = "INVALID_ID"
+	CodeInvalidDate        ErrorCode = "INVALID_DATE"
+	CodeAuthRequired       ErrorCode = "AUTH_REQUIRED"
+	CodeInvalidCredentials ErrorCode = "INVALID_CREDENTIALS"
+	CodeTokenInvalid       ErrorCode = "TOKEN_INVALID"
+	CodeTokenExpired       ErrorCode = "TOKEN_EXPIRED"
+	CodeAccountBlocked     ErrorCode = "ACCOUNT_BLOCKED"
+	CodeAccountInactive    ErrorCode = "ACCOUNT_INACTIVE"
+	CodeAccountDeleted     ErrorCode = "ACCOUNT_PENDING_DELETION"
+	CodeAdminRequired      ErrorCode = "ADMIN_REQUIRED"
+	CodeOTPInvalid         ErrorCode = "OTP_INVALID"
+	CodeOTPExpired         ErrorCode = "OTP_EXPIRED"
+	CodeOTPAttempts        ErrorCode = "OTP_ATTEMPTS_EXCEEDED"
+	CodeOTPResendCooldown  ErrorCode = "OTP_RESEND_COOLDOWN"
+	CodeOTPResendLimit     ErrorCode = "OTP_RESEND_LIMIT_REACHED"
+	CodeSessionExpired     ErrorCode = "SESSION_EXPIRED"
+	CodeTwoFactorInvalid   ErrorCode = "TWO_FACTOR_CODE_INVALID"
+	CodeCaptchaRequired    ErrorCode = "CAPTCHA_REQUIRED"
+	CodeCaptchaInvalid     ErrorCode = "CAPTCHA_INVALID"
+	CodePasswordReused     ErrorCode = "PASSWORD_REUSED"
+	CodeAlreadyExists      ErrorCode = "ALREADY_EXISTS"
+)
+
+// Resource codes
+const (
+	CodeUserNotFound      ErrorCode = "USER_NOT_FOUND"
+	CodeBookNotFound      ErrorCode = "BOOK_NOT_FOUND"
+	CodeCategoryNotFound  ErrorCode = "CATEGORY_NOT_FOUND"
+	CodeGenreNotFound     ErrorCode = "GENRE_NOT_FOUND"
+	CodeAuthorNotFound    ErrorCode = "AUTHOR_NOT_FOUND"
+	CodeTagNotFound       ErrorCode = "TAG_NOT_FOUND"
+	CodeBundleNotFound    ErrorCode = "BUNDLE_NOT_FOUND"
+	CodeOrderNotFound     ErrorCode = "ORDER_NOT_FOUND"
+	CodeOrderItemNotFound ErrorCode = "ORDER_ITEM_NOT_FOUND"
+	CodeAddressNotFound   ErrorCode = "ADDRESS_NOT_FOUND"
+	CodeCouponNotFound    ErrorCode = "COUPON_NOT_FOUND"
+	CodeOfferNotFound     ErrorCode = "OFFER_NOT_FOUND"
+	CodeTicketNotFound    ErrorCode = "TICKET_NOT_FOUND"
+	CodeDisputeNotFound   ErrorCode = "DISPUTE_NOT_FOUND"
+	CodeSessionNotFound   ErrorCode = "SESSION_NOT_FOUND"
+)
+
+// Catalog, cart and checkout codes
+const (
+	CodeBookUnavailable     ErrorCode = "BOOK_UNAVAILABLE"
+	CodeOutOfStock          ErrorCode = "OUT_OF_STOCK"
+	CodeStockChanged        ErrorCode = "STOCK_CHANGED"
+	CodeCartEmpty           ErrorCode = "CART_EMPTY"
+	CodeCartQuantityLimit   ErrorCode = "CART_QUANTITY_LIMIT"
+	CodeQuantityInvalid     ErrorCode = "QUANTITY_INVALID"
+	CodeDeliveryUnavailable ErrorCode = "DELIVERY_UNAVAILABLE"
+	CodeCouponInvalid       ErrorCode = "COUPON_INVALID"
+	CodeCouponExpired       ErrorCode = "COUPON_EXPIRED"
+	CodeCouponLimitReached  ErrorCode = "COUPON_LIMIT_REACHED"
+	CodeCouponAlreadyUsed   ErrorCode = "COUPON_ALREADY_USED"
+	CodeCouponMinOrder      ErrorCode = "COUPON_MIN_ORDER_NOT_MET"
+	CodeCouponNotEligible   ErrorCode = "COUPON_NOT_ELIGIBLE"
+	CodeCODUnavailable      ErrorCode = "COD_UNAVAILABLE"
+	CodeDeliverySlotInvalid ErrorCode = "DELIVERY_SLOT_INVALID"
+	CodeDeliverySlotFull    ErrorCode = "DELIVERY_SLOT_FULL"
+	CodeCODLimitExceeded    ErrorCode = "COD_LIMIT_EXCEEDED"
+	CodeCODNotEligible      ErrorCode = "COD_NOT_ELIGIBLE"
+	CodePaymentMethod       ErrorCode = "PAYMENT_METHOD_INVALID"
+	CodePaymentFailed       ErrorCode = "PAYMENT_VERIFICATION_FAILED"
+	CodePaymentCompleted    ErrorCode = "PAYMENT_ALREADY_COMPLETED"
+	CodePaymentNotInitiated ErrorCode = "PAYMENT_NOT_INITIATED"
+	CodePaymentPending      ErrorCode = "PAYMENT_IN_PROGRESS"
+	CodeWalletInsufficient  ErrorCode = "WALLET_INSUFFICIENT_BALANCE"
+	CodeReferralInvalid     ErrorCode = "REFERRAL_CODE_INVALID"
+	CodeGiftCardInvalid     ErrorCode = "GIFT_CARD_INVALID"
+	CodeGiftCardUnavailable ErrorCode = "GIFT_CARD_UNAVAILABLE"
+)
+
+// Order lifecycle codes
+const (
+	CodeOrderWindowExpired   ErrorCode = "ORDER_WINDOW_EXPIRED"
+	CodeOrderStatusInvalid   ErrorCode = "ORDER_STATUS_INVALID"
+	CodeOrderNotAwaitingPay  ErrorCode = "ORDER_NOT_AWAITING_PAYMENT"
+	CodeItemAlreadyCancelled ErrorCode = "ITEM_ALREADY_CANCELLED"
+	CodeReturnAlreadyExists  ErrorCode = "RETURN_ALREADY_REQUESTED"
+	CodeExchangeConflict     ErrorCode = "EXCHANGE_STATE_INVALID"
+	CodeReasonRequired       ErrorCode = "REASON_REQUIRED"
+	CodeJobRunning           ErrorCode = "JOB_ALREADY_RUNNING"
+	CodeDeliveryOTPRequired  ErrorCode = "DELIVERY_OTP_REQUIRED"
+	CodeDeliveryOTPInvalid   ErrorCode = "DELIVERY_OTP_INVALID"
+)
+
+// errorCodeStatus is the registry of error codes and the HTTP status each is sent with
+var errorCodeStatus = map[ErrorCode]int{
+	CodeBadRequest:       http.StatusBadRequest,
+	CodeUnauthorized:     http.StatusUnauthorized,
+	CodeForbidden:        http.StatusForbidden,
+	CodeNotFound:         http.StatusNotFound,
+	CodeConflict:         http.StatusConflict,
+	CodeValidationFailed: http.StatusUnprocessableEntity,
+	CodeTooManyRequests:  http.StatusTooManyRequests,
+	CodeInternalError:    http.StatusInternalServerError,
+	CodeUpstreamFailed:   http.StatusBadGateway,
+
+	CodeInvalidRequest:     http.StatusBadRequest,
+	CodeInvalidID:          http.StatusBadRequest,
+	CodeInvalidDate:        http.StatusBadRequest,
+	CodeAuthRequired:       http.StatusUnauthorized,
+	CodeInvalidCredentials: http.StatusUnauthorized,
+	CodeTokenInvalid:       http.StatusUnauthorized,
+	CodeTokenExpired:       http.StatusUnauthorized,
+	CodeAccountBlocked:     http.StatusForbidden,
+	CodeAccountInactive:    http.StatusForbidden,
+	CodeAccountDeleted:     http.StatusForbidden,
+	CodeAdminRequired:      http.StatusForbidden,
+	CodeOTPInvalid:         http.StatusBadRequest,
+	CodeOTPExpired:         http.StatusBadRequest,
+	CodeOTPAttempts:        http.StatusBadRequest,
+	CodeOTPResendCooldown:  http.StatusTooManyRequests,
+	CodeOTPResendLimit:     http.StatusTooManyRequests,
+	CodeSessionExpired:     http.StatusBadRequest,
+	CodeTwoFactorInvalid:   http.StatusUnauthorized,
+	CodeCaptchaRequired:    http.StatusBadRequest,
+	CodeCaptchaInvalid:     http.StatusBadRequest,
+	CodePasswordReused:     http.StatusBadRequest,
+	CodeAlreadyExists:      http.StatusConflict,
+
+	CodeUserNotFound:      http.StatusNotFound,
+	CodeBookNotFound:      http.StatusNotFound,
+	CodeCategoryNotFound:  http.StatusNotFound,
+	CodeGenreNotFound:     http.StatusNotFound,
+	CodeAuthorNotFound:    http.StatusNotFound,
+	CodeTagNotFound:       http.StatusNotFound,
+	CodeBundleNotFound:    http.StatusNotFound,
+	CodeOrderNotFound:     http.StatusNotFound,
+	CodeOrderItemNotFound: http.StatusNotFound,
+	CodeAddressNotFound:   http.StatusNotFound,
+	CodeCouponNotFound:    http.StatusNotFound,
+	CodeOfferNotFound:     http.StatusNotFound,
+	CodeTicketNotFound:    http.StatusNotFound,
+	CodeDisputeNotFound:   http.StatusNotFound,
+	CodeSessionNotFound:   http.StatusNotFound,
+
+	CodeBookUnavailable:     http.StatusBadRequest,
+	CodeOutOfStock:          http.StatusBadRequest,
+	CodeStockChanged:        http.StatusConflict,
+	CodeCartEmpty:           http.StatusBadRequest,
+	CodeCartQuantityLimit:   http.StatusBadRequest,
+	CodeQuantityInvalid:     http.StatusBadRequest,
+	CodeDeliveryUnavailable: http.StatusBadRequest,
+	CodeCouponInvalid:       http.StatusBadRequest,
+	CodeCouponExpired:       http.StatusBadRequest,
+	CodeCouponLimitReached:  http.StatusBadRequest,
+	CodeCouponAlreadyUsed:   http.StatusBadRequest,
+	CodeCouponMinOrder:      http.StatusBadRequest,
+	CodeCouponNotEligible:   http.StatusBadRequest,
+	CodeCODUnavailable:      http.StatusBadRequest,
+	CodeDeliverySlotInvalid: http.StatusBadRequest,
+	CodeDeliverySlotFull:    http.StatusConflict,
+	CodeCODLimitExceeded:    http.StatusBadRequest,
+	CodeCODNotEligible:      http.StatusBadRequest,
+	CodePaymentMethod:       http.StatusBadRequest,
+	CodePaymentFailed:       http.StatusBadRequest,
+	CodePaymentCompleted:    http.StatusBadRequest,
+	CodePaymentNotInitiated: http.StatusBadRequest,
+	CodePaymentPending:      http.StatusBadRequest,
+	CodeWalletInsufficient:  http.StatusBadRequest,
+	CodeReferralInvalid:     http.StatusBadRequest,
+	CodeGiftCardInvalid:     http.StatusNotFound,
+	CodeGiftCardUnavailable: http.StatusBadRequest,
+
+	CodeOrderWindowExpired:   http.StatusBadRequest,
+	CodeOrderStatusInvalid:   http.StatusBadRequest,
+	CodeOrderNotAwaitingPay:  http.StatusConflict,
+	CodeItemAlreadyCancelled: http.StatusBadRequest,
+	CodeReturnAlreadyExists:  http.StatusBadRequest,
+	CodeExchangeConflict:     http.StatusBadRequest,
+	CodeReasonRequired:       http.StatusBadRequest,
+	CodeJobRunning:           http.StatusConflict,
+	CodeDeliveryOTPRequired:  http.StatusBadRequest,
+	CodeDeliveryOTPInvalid:   http.StatusBadRequest,
+}
+
+// Status returns the HTTP status the code is sent with
+func (code ErrorCode) Status() int {
+	if status, ok := errorCodeStatus[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// defaultErrorCode is the generic code for a status, for errors sent without a specific code
+func defaultErrorCode(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeValidationFailed
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	}
+	return CodeInternalError
+}
+
+// Fail sends an error response with a specific code, using the code's registered status
+func Fail(c *gin.Context, code ErrorCode, message string, err interface{}) {
+	sendError(c, code.Status(), code, message, err)
+}
+
+// ErrorCodes lists every registered code with its status, for documentation
+func ErrorCodes() []gin.H {
+	codes := make([]gin.H, 0, len(errorCodeStatus))
+	for code, status := range errorCodeStatus {
+		codes = append(codes, gin.H{"code": code, "status": status})
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i]["code"].(ErrorCode) < codes[j]["code"].(ErrorCode) })
+	return codes
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Errors returned when a gift card cannot be redeemed
+var (
+	ErrGiftCardNotFound = errors.New("gift card not found")
+	ErrGiftCardRedeemed = errors.New("gift card has already been redeemed")
+	ErrGiftCardExpired  = errors.New("gift card has expired")
+	ErrGiftCardVoided   = errors.New("gift card has been voided")
+	ErrGiftCardPending  = errors.New("gift card has not been paid for")
+)
+
+// giftCardCodeGroups is the number of four character groups in a gift card code
+const giftCardCodeGroups = 3
+
+// GenerateGiftCardCode returns a new random code such as GC-7KQ2-M4XZ-P9TA
+func GenerateGiftCardCode() (string, error) {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	encoded := totpEncoding.EncodeToString(raw) // 13 characters
+	groups := make([]string, giftCardCodeGroups)
+	for i := range groups {
+		groups[i] = encoded[i*4 : i*4+4]
+	}
+	return "GC-" + strings.Join(groups, "-"), nil
+}
+
+// NormalizeGiftCardCode upper-cases a code and drops surrounding spaces
+func NormalizeGiftCardCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// GiftCardStatus is the card's status as of now: an active card past its expiry is expired
+// even before the expiry job marks it
+func GiftCardStatus(card models.GiftCard) string {
+	if card.Status == models.GiftCardStatusActive && time.Now().After(card.ExpiresAt) {
+		return models.GiftCardStatusExpired
+	}
+	return card.Status
+}
+
+// RedeemGiftCard credits the card's value to the user's wallet and marks the card redeemed,
+// both through tx. The card row is locked so it can only be redeemed once.
+func RedeemGiftCard(tx *gorm.DB, userID uint, code string) (*models.GiftCard, *models.WalletTransaction, error) {
+	var card models.GiftCard
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ?", NormalizeGiftCardCode(code)).First(&card).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrGiftCardNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch GiftCardStatus(card) {
+	case models.GiftCardStatusActive:
+	case models.GiftCardStatusRedeemed:
+		return &card, nil, ErrGiftCardRedeemed
+	case models.GiftCardStatusExpired:
+		return &card, nil, ErrGiftCardExpired
+	case models.GiftCardStatusVoided:
+		return &card, nil, ErrGiftCardVoided
+	default:
+		return &card, nil, ErrGiftCardPending
+	}
+
+	now := time.Now()
+	if err := tx.Model(&card).Updates(map[string]interface{}{
+		"status":      models.GiftCardStatusRedeemed,
+		"redeemed_by": userID,
+		"redeemed_at": now,
+	}).Error; err != nil {
+		return nil, nil, err
+	}
+	card.Status = models.GiftCardStatusRedeemed
+	card.RedeemedBy = &userID
+	card.RedeemedAt = &now
+
+	transaction, err := PostWalletEntry(tx, WalletEntry{
+		UserID:      userID,
+		Amount:      card.Amount,
+		Type:        models.TransactionTypeCredit,
+		Description: fmt.Sprintf("Gift card %s redeemed", card.Code),
+		Reference:   fmt.Sprintf("GIFTCARD-REDEEM-%d", card.ID),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &card, transaction, nil
+}
+
+// ExpireGiftCards marks active gift cards past their expiry as expired and returns how many
+// were changed
+func ExpireGiftCards() (int64, error) {
+	result := config.DB.Model(&models.GiftCard{}).
+		Where("status = ? AND expires_at < ?", models.GiftCardStatusActive, time.Now()).
+		Update("status", models.GiftCardStatusExpired)
+	return result.RowsAffected, result.Error
+}
+
+// SendGiftCardEmail emails the gift card's code to its recipient. from names the sender.
+func SendGiftCardEmail(card models.GiftCard, from string) error {
+	subject := fmt.Sprintf("%s sent you a %s gift card", from, GetEmailBranding().Name)
+	name := card.RecipientName
+	if name == "" {
+		name = "there"
+	}
+
+	return SendTemplateEmail(card.RecipientEmail, subject, "gift_card", map[string]interface{}{
+		"Name":      name,
+		"From":      from,
+		"Amount":    FormatBaseMoney(card.Amount),
+		"Message":   card.Message,
+		"Code":      card.Code,
+		"ExpiresAt": card.ExpiresAt.Format("2006-01-02"),
+	})
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default HSN/SAC codes: printed books and goods transport services
+const (
+	DefaultBookHSNCode  = "4901"
+	DeliveryServiceCode = "9965"
+)
+
+// SellerTaxDetails are the seller's registration details and tax rates printed on invoices,
+// read from the SELLER_* and GST_* environment variables
+type SellerTaxDetails struct {
+	Name          string
+	GSTIN         string
+	Address       string
+	State         string
+	StateCode     string
+	InvoicePrefix string
+	// DefaultRate applies to books without their own tax rate
+	DefaultRate float64
+	// ServiceRate applies to delivery charges and payment method fees
+	ServiceRate float64
+}
+
+// GetSellerTaxDetails returns the configured seller tax details
+func GetSellerTaxDetails() SellerTaxDetails {
+	return SellerTaxDetails{
+		Name:          getEnvOr("SELLER_NAME", "ReadSphere"),
+		GSTIN:         os.Getenv("SELLER_GSTIN"),
+		Address:       os.Getenv("SELLER_ADDRESS"),
+		State:         os.Getenv("SELLER_STATE"),
+		StateCode:     os.Getenv("SELLER_STATE_CODE"),
+		InvoicePrefix: getEnvOr("INVOICE_PREFIX", "RS"),
+		DefaultRate:   envRate("GST_DEFAULT_RATE", 0),
+		ServiceRate:   envRate("GST_SERVICE_RATE", 18),
+	}
+}
+
+func envRate(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 100 {
+		LogError("Invalid %s %q, using %.2f", key, value, fallback)
+		return fallback
+	}
+	return rate
+}
+
+// IsInterState reports whether a supply to the given state is inter-state (IGST) rather
+// than intra-state (CGST + SGST). Without a configured seller state every supply is intra-state.
+func (s SellerTaxDetails) IsInterState(placeOfSupply string) bool {
+	if s.State == "" || placeOfSupply == "" {
+		return false
+	}
+	return !strings.EqualFold(strings.TrimSpace(s.State), strings.TrimSpace(placeOfSupply))
+}
+
+// FinancialYear returns the Indian financial year (April to March) of t, e.g. "2026-27"
+func FinancialYear(t time.Time) string {
+	start := t.Year()
+	if t.Month() < time.April {
+		start--
+	}
+	return fmt.Sprintf("%d-%02d", start, (start+1)%100)
+}
+
+// FormatInvoiceNumber builds the invoice number, e.g. "RS/2026-27/000042"
+func FormatInvoiceNumber(prefix, financialYear string, sequence int) string {
+	return fmt.Sprintf("%s/%s/%06d", prefix, financialYear, sequence)
+}
+
+// TaxSplit is the tax contained in a tax-inclusive amount
+type TaxSplit struct {
+	TaxableValue float64
+	CGST         float64
+	SGST         float64
+	IGST         float64
+	Tax          float64
+}
+
+// SplitInclusiveTax extracts the tax from an amount that already includes it at the given rate.
+// Intra-state tax is split evenly between CGST and SGST.
+func SplitInclusiveTax(amount, rate float64, interState bool) TaxSplit {
+	taxable := roundMoney(amount * 100 / (100 + rate))
+	tax := roundMoney(amount - taxable)
+	split := TaxSplit{TaxableValue: taxable, Tax: tax}
+	if interState {
+		split.IGST = tax
+	} else {
+		split.CGST = roundMoney(math.Floor(tax*100/2) / 100)
+		split.SGST = roundMoney(tax - split.CGST)
+	}
+	return split
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+
+	// Register decoders for the accepted upload formats
+	_ "image/gif"
+	_ "image/png"
+)
+
+// Image upload limits
+const (
+	MaxBookImageSize      = 5 * 1024 * 1024
+	MaxBookImageDimension = 6000
+	ThumbnailMaxDimension = 320
+)
+
+// imageExtensions maps the accepted image content types to file extensions
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// ProcessedImage is a validated upload along with its generated thumbnail
+type ProcessedImage struct {
+	Data          []byte
+	ContentType   string
+	Extension     string
+	Width         int
+	Height        int
+	Thumbnail     []byte
+	ThumbnailType string
+}
+
+// ProcessImage validates an uploaded image by its content (not its file name) and builds a
+// JPEG thumbnail no larger than ThumbnailMaxDimension on either side. WebP images are
+// accepted but stored without a thumbnail, as the standard library cannot decode them.
+func ProcessImage(data []byte) (*ProcessedImage, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+	if len(data) > MaxBookImageSize {
+		return nil, fmt.Errorf("file size exceeds %dMB limit", MaxBookImageSize/(1024*1024))
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported file type %s. Allowed types: jpg, png, gif, webp", contentType)
+	}
+	result := &ProcessedImage{Data: data, ContentType: contentType, Extension: ext}
+	if contentType == "image/webp" {
+		return result, nil
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %v", err)
+	}
+	if cfg.Width > MaxBookImageDimension || cfg.Height > MaxBookImageDimension {
+		return nil, fmt.Errorf("image dimensions %dx%d exceed %dpx limit", cfg.Width, cfg.Height, MaxBookImageDimension)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %v", err)
+	}
+	result.Width, result.Height = cfg.Width, cfg.Height
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resizeToFit(img, ThumbnailMaxDimension), &jpeg.Options{Quality: 85}); err != nil {
+		return nil, fmt.Errorf("failed to generate thumbnail: %v", err)
+	}
+	result.Thumbnail = buf.Bytes()
+	result.ThumbnailType = "image/jpeg"
+	return result, nil
+}
+
+// resizeToFit scales the image down, keeping its aspect ratio, so neither side exceeds
+// maxDim. Each target pixel averages the source pixels it covers; transparent areas
+// are flattened onto white since the thumbnail is a JPEG.
+func resizeToFit(src image.Image, maxDim int) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := srcW, srcH
+	if srcW > maxDim || srcH > maxDim {
+		if srcW >= srcH {
+			dstW, dstH = maxDim, max(1, srcH*maxDim/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*maxDim/srcH), maxDim
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcW/dstW)
+
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					// Composite over white
+					r += uint64(pr + (0xffff - pa))
+					g += uint64(pg + (0xffff - pa))
+					b += uint64(pb + (0xffff - pa))
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: 0xffff})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrISBNNotFound is returned by a lookup provider that has no book with the ISBN
+var ErrISBNNotFound = errors.New("no book found for this ISBN")
+
+// BookMetadata is what a lookup provider knows about a book, used to prefill the book form
+type BookMetadata struct {
+	ISBN            string   `json:"isbn"`
+	Title           string   `json:"title"`
+	Subtitle        string   `json:"subtitle,omitempty"`
+	Authors         []string `json:"authors"`
+	Author          string   `json:"author"` // the authors joined, as the book form takes them
+	Publisher       string   `json:"publisher"`
+	Pages           int      `json:"pages"`
+	PublicationYear int      `json:"publication_year"`
+	CoverURL        string   `json:"cover_url"`
+	Description     string   `json:"description,omitempty"`
+	Source          string   `json:"source"`
+}
+
+// ISBNLookupProvider finds the metadata of a book by its normalized ISBN
+type ISBNLookupProvider interface {
+	Name() string
+	Lookup(isbn string) (*BookMetadata, error)
+}
+
+var (
+	isbnLookupOnce     sync.Once
+	isbnLookupProvider ISBNLookupProvider
+)
+
+// GetISBNLookupProvider returns the provider selected by ISBN_LOOKUP_PROVIDER ("openlibrary", the
+// default, or "google", which uses GOOGLE_BOOKS_API_KEY when set)
+func GetISBNLookupProvider() ISBNLookupProvider {
+	isbnLookupOnce.Do(func() {
+		client := &http.Client{Timeout: 10 * time.Second}
+		switch strings.ToLower(os.Getenv("ISBN_LOOKUP_PROVIDER")) {
+		case "google":
+			isbnLookupProvider = &GoogleBooksProvider{APIKey: os.Getenv("GOOGLE_BOOKS_API_KEY"), client: client}
+		default:
+			isbnLookupProvider = &OpenLibraryProvider{client: client}
+		}
+		LogInfo("ISBN lookup provider: %s", isbnLookupProvider.Name())
+	})
+	return isbnLookupProvider
+}
+
+// NormalizeISBN strips spaces and hyphens from an ISBN-10 or ISBN-13 and reports whether its
+// check digit is valid
+func NormalizeISBN(isbn string) (string, bool) {
+	isbn = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(isbn)))
+	switch len(isbn) {
+	case 10:
+		sum := 0
+		for i, r := range isbn {
+			digit := int(r - '0')
+			if r == 'X' && i == 9 {
+				digit = 10
+			} else if r < '0' || r > '9' {
+				return isbn, false
+			}
+			sum += (10 - i) * digit
+		}
+		return isbn, sum%11 == 0
+	case 13:
+		sum := 0
+		for i, r := range isbn {
+			if r < '0' || r > '9' {
+				return isbn, false
+			}
+			weight := 1
+			if i%2 == 1 {
+				weight = 3
+			}
+			sum += weight * int(r-'0')
+		}
+		return isbn, sum%10 == 0
+	}
+	return isbn, false
+}
+
+var yearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+// parseYear returns the first four-digit year in a publication date such as "2004-05-01" or "May 2004"
+func parseYear(date string) int {
+	year, _ := strconv.Atoi(yearPattern.FindString(date))
+	return year
+}
+
+// fetchJSON decodes the JSON response of a GET request
+func fetchJSON(client *http.Client, endpoint string, out interface{}) error {
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("lookup returned status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// OpenLibraryProvider looks books up in the Open Library books API, which needs no key
+type OpenLibraryProvider struct {
+	client *http.Client
+}
+
+func (p *OpenLibraryProvider) Name() string { return "openlibrary" }
+
+func (p *OpenLibraryProvider) Lookup(isbn string) (*BookMetadata, error) {
+	var result map[string]struct {
+		Title    string `json:"title"`
+		Subtitle string `json:"subtitle"`
+		Authors  []struct {
+			Name string `json:"name"`
+		} `json:"authors"`
+		Publishers []struct {
+			Name string `json:"name"`
+		} `json:"publishers"`
+		NumberOfPages int    `json:"number_of_pages"`
+		PublishDate   string `json:"publish_date"`
+		Cover         struct {
+			Medium string `json:"medium"`
+			Large  string `json:"large"`
+		} `json:"cover"`
+	}
+	endpoint := "https://openlibrary.org/api/books?format=json&jscmd=data&bibkeys=" + url.QueryEscape("ISBN:"+isbn)
+	if err := fetchJSON(p.client, endpoint, &result); err != nil {
+		return nil, err
+	}
+	book, ok := result["ISBN:"+isbn]
+	if !ok {
+		return nil, ErrISBNNotFound
+	}
+
+	metadata := &BookMetadata{
+		ISBN:            isbn,
+		Title:           book.Title,
+		Subtitle:        book.Subtitle,
+		Pages:           book.NumberOfPages,
+		PublicationYear: parseYear(book.PublishDate),
+		CoverURL:        book.Cover.Large,
+		Source:          p.Name(),
+	}
+	if metadata.CoverURL == "" {
+		metadata.CoverURL = book.Cover.Medium
+	}
+	for _, author := range book.Authors {
+		metadata.Authors = append(metadata.Authors, author.Name)
+	}
+	if len(book.Publishers) > 0 {
+		metadata.Publisher = book.Publishers[0].Name
+	}
+	metadata.Author = strings.Join(metadata.Authors, ", ")
+	return metadata, nil
+}
+
+// GoogleBooksProvider looks books up in the Google Books volumes API. The API key is optional
+// but raises the request quota.
+type GoogleBooksProvider struct {
+	APIKey string
+	client *http.Client
+}
+
+func (p *GoogleBooksProvider) Name() string { return "google" }
+
+func (p *GoogleBooksProvider) Lookup(isbn string) (*BookMetadata, error) {
+	var result struct {
+		Items []struct {
+			VolumeInfo struct {
+				Title         string   `json:"title"`
+				Subtitle      string   `json:"subtitle"`
+				Authors       []string `json:"authors"`
+				Publisher     string   `json:"publisher"`
+				PublishedDate string   `json:"publishedDate"`
+				Description   string   `json:"description"`
+				PageCount     int      `json:"pageCount"`
+				ImageLinks    struct {
+					Thumbnail string `json:"thumbnail"`
+				} `json:"imageLinks"`
+			} `json:"volumeInfo"`
+		} `json:"items"`
+	}
+	query := url.Values{"q": {"isbn:" + isbn}}
+	if p.APIKey != "" {
+		query.Set("key", p.APIKey)
+	}
+	if err := fetchJSON(p.client, "https://www.googleapis.com/books/v1/volumes?"+query.Encode(), &result); err != nil {
+		return nil, err
+	}
+	if len(result.Items) == 0 {
+		return nil, ErrISBNNotFound
+	}
+
+	volume := result.Items[0].VolumeInfo
+	return &BookMetadata{
+		ISBN:            isbn,
+		Title:           volume.Title,
+		Subtitle:        volume.Subtitle,
+		Authors:         volume.Authors,
+		Author:          strings.Join(volume.Authors, ", "),
+		Publisher:       volume.Publisher,
+		Pages:           volume.PageCount,
+		PublicationYear: parseYear(volume.PublishedDate),
+		// Google serves covers over plain HTTP by default
+		CoverURL:    strings.Replace(volume.ImageLinks.Thumbnail, "http://", "https://", 1),
+		Description: volume.Description,
+		Source:      p.Name(),
+	}, nil
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultLocale is used when a request does not ask for a supported locale
+const DefaultLocale = "en"
+
+// SupportedLocales lists the storefront locales catalog translations can be stored for
+var SupportedLocales = map[string]bool{
+	"en": true,
+	"hi": true,
+	"ta": true,
+	"te": true,
+	"ml": true,
+	"kn": true,
+	"bn": true,
+	"mr": true,
+	"gu": true,
+}
+
+// IsSupportedLocale reports whether translations can be stored for the given locale
+func IsSupportedLocale(locale string) bool {
+	return SupportedLocales[strings.ToLower(strings.TrimSpace(locale))]
+}
+
+// GetRequestLocale resolves the locale for a request from the "lang" query
+// parameter or the Accept-Language header, falling back to DefaultLocale
+func GetRequestLocale(c *gin.Context) string {
+	if lang := strings.ToLower(strings.TrimSpace(c.Query("lang"))); lang != "" && SupportedLocales[lang] {
+		return lang
+	}
+
+	// Accept-Language entries are already ordered by preference in practice,
+	// so the first supported primary tag wins (e.g. "ta-IN,ta;q=0.9,en;q=0.8")
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		primary := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+		if SupportedLocales[primary] {
+			return primary
+		}
+	}
+
+	return DefaultLocale
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"runtime/debug"
 	"time"
@@ -51,14 +52,6 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
-// RateLimitMiddleware implements rate limiting
-func RateLimitMiddleware() gin.HandlerFunc {
-	// TODO: Implement rate limiting using Redis or in-memory store
-	return func(c *gin.Context) {
-		c.Next()
-	}
-}
-
 // RecoveryMiddleware recovers from panics
 func RecoveryMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -66,26 +59,38 @@ func RecoveryMiddleware() gin.HandlerFunc {
 			if err := recover(); err != nil {
 				// Log the error with stack trace
 				LogErrorWithStack(fmt.Errorf("%v", err), debug.Stack())
-				c.AbortWithStatusJSON(500, gin.H{
-					"success": false,
-					"message": "Internal server error",
-				})
+				InternalServerError(c, "Internal server error", nil)
+				c.Abort()
 			}
 		}()
 		c.Next()
 	}
 }
 
-// RequestIDMiddleware adds a unique request ID to each request
+// requestIDKey holds the request ID in the request's context
+type requestIDKey struct{}
+
+// RequestIDMiddleware adds a unique request ID to each request, also in the request's context
+// so that work done with the context, such as database queries, can be traced to the request
 func RequestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := uuid.New().String()
 		c.Set("RequestID", requestID)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDKey{}, requestID))
 		c.Writer.Header().Set("X-Request-ID", requestID)
 		c.Next()
 	}
 }
 
+// RequestIDFromContext returns the ID of the request the context belongs to, or "" outside one
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
 // ValidateRequestMiddleware validates request body against a schema
 func ValidateRequestMiddleware(schema interface{}) gin.HandlerFunc {
 	return func(c *gin.Context) {
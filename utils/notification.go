@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// NotificationPreferenceFor returns the user's notification preferences, with everything on
+// when the user never changed them
+func NotificationPreferenceFor(db *gorm.DB, userID uint) (models.NotificationPreference, error) {
+	preference := models.NotificationPreference{
+		UserID:       userID,
+		OrderUpdates: true,
+		Wallet:       true,
+		Promotions:   true,
+		NewArrivals:  true,
+		PriceDrops:   true,
+	}
+	err := db.Where("user_id = ?", userID).First(&preference).Error
+	if err == gorm.ErrRecordNotFound {
+		err = nil
+	}
+	return preference, err
+}
+
+// WantsNotification reports whether the preference lets notifications of the type through
+func WantsNotification(preference models.NotificationPreference, notificationType string) bool {
+	switch notificationType {
+	case models.NotificationTypeOrder:
+		return preference.OrderUpdates
+	case models.NotificationTypeWallet:
+		return preference.Wallet
+	case models.NotificationTypePromotion:
+		return preference.Promotions
+	}
+	return true
+}
+
+// Notify adds a notification to the user's notification center through db, unless the user
+// turned its type off. It reports whether the notification was added.
+func Notify(db *gorm.DB, userID uint, notificationType, title, message, link string) (bool, error) {
+	preference, err := NotificationPreferenceFor(db, userID)
+	if err != nil {
+		return false, err
+	}
+	if !WantsNotification(preference, notificationType) {
+		LogDebug("User %d turned %s notifications off, skipping %q", userID, notificationType, title)
+		return false, nil
+	}
+
+	notification := models.Notification{
+		UserID:  userID,
+		Type:    notificationType,
+		Title:   title,
+		Message: message,
+		Link:    link,
+	}
+	if err := db.Create(&notification).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
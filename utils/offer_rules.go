@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// DefaultOfferRules are used until an admin saves offer rules: offers stack, as they always
+// have, up to 100%
+var DefaultOfferRules = models.OfferRules{
+	StackingPolicy:     models.OfferStackingStack,
+	MaxDiscountPercent: 100,
+}
+
+var (
+	offerRulesMu       sync.RWMutex
+	offerRulesCache    *models.OfferRules
+	offerRulesLoadedAt time.Time
+)
+
+// loadOfferRules reads the saved offer rules, or the defaults
+func loadOfferRules() models.OfferRules {
+	var rules models.OfferRules
+	err := config.DB.First(&rules).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DefaultOfferRules
+	}
+	if err != nil {
+		LogError("Failed to load offer rules, using defaults: %v", err)
+		return DefaultOfferRules
+	}
+	return rules
+}
+
+// GetOfferRules returns the offer rules from the cache, reloading them when they are older
+// than settingsCacheTTL
+func GetOfferRules() models.OfferRules {
+	offerRulesMu.RLock()
+	if offerRulesCache != nil && time.Since(offerRulesLoadedAt) < settingsCacheTTL {
+		rules := *offerRulesCache
+		offerRulesMu.RUnlock()
+		return rules
+	}
+	offerRulesMu.RUnlock()
+
+	offerRulesMu.Lock()
+	defer offerRulesMu.Unlock()
+	if offerRulesCache == nil || time.Since(offerRulesLoadedAt) >= settingsCacheTTL {
+		rules := loadOfferRules()
+		offerRulesCache = &rules
+		offerRulesLoadedAt = time.Now()
+	}
+	return *offerRulesCache
+}
+
+// ValidateOfferRules checks the stacking policy and the discount cap
+func ValidateOfferRules(rules models.OfferRules) error {
+	switch rules.StackingPolicy {
+	case models.OfferStackingStack, models.OfferStackingBestOf, models.OfferStackingExclusive:
+	default:
+		return fmt.Errorf("stacking_policy must be %s, %s or %s", models.OfferStackingStack, models.OfferStackingBestOf, models.OfferStackingExclusive)
+	}
+	if rules.MaxDiscountPercent <= 0 || rules.MaxDiscountPercent > 100 {
+		return fmt.Errorf("max_discount_percent must be more than 0 and at most 100")
+	}
+	return nil
+}
+
+// SaveOfferRules validates and stores the offer rules, then drops the cache
+func SaveOfferRules(rules *models.OfferRules) error {
+	if err := ValidateOfferRules(*rules); err != nil {
+		return err
+	}
+	if err := config.DB.Save(rules).Error; err != nil {
+		return err
+	}
+	offerRulesMu.Lock()
+	offerRulesCache = nil
+	offerRulesMu.Unlock()
+	return nil
+}
+
+// CombineOffers applies the offer rules to a book's product and category offer percents. The
+// global stacking policy decides first: under best_of and exclusive the offers' own Exclusive
+// flags are ignored. Only the stack policy looks at the flags, keeping the larger offer when
+// either is exclusive. The cap applies last, whatever the policy.
+func CombineOffers(rules models.OfferRules, prodPercent, catPercent float64, prodExclusive, catExclusive bool) OfferBreakdown {
+	breakdown := OfferBreakdown{
+		ProductOfferPercent:  prodPercent,
+		CategoryOfferPercent: catPercent,
+		StackingPolicy:       rules.StackingPolicy,
+		CapPercent:           rules.MaxDiscountPercent,
+	}
+	suppress := func(offerType string) {
+		breakdown.SuppressedOffer = offerType
+		if offerType == "product" {
+			breakdown.SuppressedPercent = breakdown.ProductOfferPercent
+			breakdown.ProductOfferPercent = 0
+		} else {
+			breakdown.SuppressedPercent = breakdown.CategoryOfferPercent
+			breakdown.CategoryOfferPercent = 0
+		}
+	}
+
+	if prodPercent > 0 && catPercent > 0 {
+		switch rules.StackingPolicy {
+		case models.OfferStackingExclusive:
+			suppress("category")
+		case models.OfferStackingBestOf:
+			if prodPercent >= catPercent {
+				suppress("category")
+			} else {
+				suppress("product")
+			}
+		default:
+			// Under the stack policy exclusive offers don't stack: keep the larger discount only
+			if prodExclusive || catExclusive {
+				if prodPercent >= catPercent {
+					suppress("category")
+				} else {
+					suppress("product")
+				}
+			}
+		}
+	}
+
+	// The cap comes off the category offer first, then the product offer
+	if excess := breakdown.ProductOfferPercent + breakdown.CategoryOfferPercent - breakdown.CapPercent; excess > 0 {
+		breakdown.CappedPercent = excess
+		fromCategory := math.Min(excess, breakdown.CategoryOfferPercent)
+		breakdown.CategoryOfferPercent -= fromCategory
+		breakdown.ProductOfferPercent -= excess - fromCategory
+	}
+
+	switch {
+	case breakdown.ProductOfferPercent == 0 && breakdown.CategoryOfferPercent == 0:
+		breakdown.AppliedOfferType = "none"
+	case breakdown.CategoryOfferPercent == 0:
+		breakdown.AppliedOfferType = "product"
+	case breakdown.ProductOfferPercent == 0:
+		breakdown.AppliedOfferType = "category"
+	default:
+		breakdown.AppliedOfferType = "product+category"
+	}
+	breakdown.AppliedOfferPercent = breakdown.ProductOfferPercent + breakdown.CategoryOfferPercent
+	return breakdown
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombineOffers(t *testing.T) {
+	rules := func(policy string, capPercent float64) models.OfferRules {
+		return models.OfferRules{StackingPolicy: policy, MaxDiscountPercent: capPercent}
+	}
+
+	tests := []struct {
+		name                          string
+		rules                         models.OfferRules
+		product, category             float64
+		productExcl, categoryExcl     bool
+		wantProduct, wantCategory     float64
+		wantApplied, wantSuppressed   string
+		wantSuppressedPct, wantCapped float64
+	}{
+		{"stack adds both", rules(models.OfferStackingStack, 100), 10, 5, false, false,
+			10, 5, "product+category", "", 0, 0},
+		{"stack with an exclusive product offer keeps the larger", rules(models.OfferStackingStack, 100), 10, 20, true, false,
+			0, 20, "category", "product", 10, 0},
+		{"stack with an exclusive category offer keeps the larger", rules(models.OfferStackingStack, 100), 25, 10, false, true,
+			25, 0, "product", "category", 10, 0},
+		{"stack with one offer ignores the flag", rules(models.OfferStackingStack, 100), 15, 0, true, false,
+			15, 0, "product", "", 0, 0},
+		{"best_of keeps the larger", rules(models.OfferStackingBestOf, 100), 10, 20, false, false,
+			0, 20, "category", "product", 10, 0},
+		{"best_of ignores the exclusive flags", rules(models.OfferStackingBestOf, 100), 10, 20, true, false,
+			0, 20, "category", "product", 10, 0},
+		{"exclusive policy lets the product offer win", rules(models.OfferStackingExclusive, 100), 10, 30, false, false,
+			10, 0, "product", "category", 30, 0},
+		{"exclusive policy wins over an exclusive category offer", rules(models.OfferStackingExclusive, 100), 10, 30, false, true,
+			10, 0, "product", "category", 30, 0},
+		{"exclusive policy keeps a lone category offer", rules(models.OfferStackingExclusive, 100), 0, 30, false, true,
+			0, 30, "category", "", 0, 0},
+		{"cap comes off the category offer first", rules(models.OfferStackingStack, 20), 15, 10, false, false,
+			15, 5, "product+category", "", 0, 5},
+		{"cap below the product offer", rules(models.OfferStackingStack, 10), 15, 5, false, false,
+			10, 0, "product", "", 0, 10},
+		{"cap applies after an exclusive flag", rules(models.OfferStackingStack, 20), 10, 30, false, true,
+			0, 20, "category", "product", 10, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CombineOffers(tt.rules, tt.product, tt.category, tt.productExcl, tt.categoryExcl)
+			assert.Equal(t, tt.wantProduct, got.ProductOfferPercent, "product percent")
+			assert.Equal(t, tt.wantCategory, got.CategoryOfferPercent, "category percent")
+			assert.Equal(t, tt.wantProduct+tt.wantCategory, got.AppliedOfferPercent, "applied percent")
+			assert.Equal(t, tt.wantApplied, got.AppliedOfferType)
+			assert.Equal(t, tt.wantSuppressed, got.SuppressedOffer)
+			assert.Equal(t, tt.wantSuppressedPct, got.SuppressedPercent, "suppressed percent")
+			assert.Equal(t, tt.wantCapped, got.CappedPercent, "capped percent")
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
 )
 
 // OfferBreakdown holds details of both product and category offers
@@ -13,36 +14,85 @@ type OfferBreakdown struct {
 	ProductOfferPercent  float64 `json:"product_offer_percent"`
 	CategoryOfferPercent float64 `json:"category_offer_percent"`
 	AppliedOfferPercent  float64 `json:"applied_offer_percent"`
-	AppliedOfferType     string  `json:"applied_offer_type"` // "product", "category", or "none"
+	AppliedOfferType     string  `json:"applied_offer_type"` // "product", "category", "product+category" or "none"
+	StackingPolicy       string  `json:"stacking_policy,omitempty"`
+	SuppressedOffer      string  `json:"suppressed_offer,omitempty"`   // offer type left out by the stacking policy
+	SuppressedPercent    float64 `json:"suppressed_percent,omitempty"` // percent of the offer left out
+	CapPercent           float64 `json:"cap_percent,omitempty"`        // most the offers may take off together
+	CappedPercent        float64 `json:"capped_percent,omitempty"`     // percent removed by the cap
 }
 
 // GetOfferBreakdownForBook returns the product offer, category offer, and the final applied offer for a book
 func GetOfferBreakdownForBook(bookID uint, categoryID uint) (OfferBreakdown, error) {
-	db := config.DB
-	now := time.Now()
-	var prodOffer models.ProductOffer
-	var catOffer models.CategoryOffer
-	prodPercent := 0.0
-	catPercent := 0.0
+	return GetOfferBreakdownForBookAt(bookID, categoryID, time.Now())
+}
+
+// GetOfferBreakdownForBookAt returns the offer breakdown of a book at the given time.
+// The product and category offers are combined by the offer rules; an offer left out is
+// reported as 0 with the suppressed offer, and the total never exceeds the rules' cap.
+func GetOfferBreakdownForBookAt(bookID uint, categoryID uint, at time.Time) (OfferBreakdown, error) {
+	book := models.Book{CategoryID: categoryID}
+	book.ID = bookID
+	breakdowns, err := GetOfferBreakdownsAt([]models.Book{book}, at)
+	return breakdowns[bookID], err
+}
+
+// GetOfferBreakdowns returns the current offer breakdown of each book, by book ID
+func GetOfferBreakdowns(books []models.Book) (map[uint]OfferBreakdown, error) {
+	return GetOfferBreakdownsAt(books, time.Now())
+}
+
+// GetOfferBreakdownsAt returns the offer breakdown of each book at the given time, by book
+// ID. The product and category offers of all the books are read in one query each, so
+// carts and lists are priced without a lookup per book. A book whose offers cannot be read
+// gets no offer, along with the error.
+func GetOfferBreakdownsAt(books []models.Book, at time.Time) (map[uint]OfferBreakdown, error) {
+	return LoadOfferBreakdowns(config.DB, books, at)
+}
 
-	// Check product-specific offer
-	err1 := db.Where("product_id = ? AND active = ? AND start_date <= ? AND end_date >= ?", bookID, true, now, now).First(&prodOffer).Error
-	if err1 == nil {
-		prodPercent = prodOffer.DiscountPercent
+// LoadOfferBreakdowns is GetOfferBreakdownsAt reading the offers from db
+func LoadOfferBreakdowns(db *gorm.DB, books []models.Book, at time.Time) (map[uint]OfferBreakdown, error) {
+	breakdowns := make(map[uint]OfferBreakdown, len(books))
+	if len(books) == 0 {
+		return breakdowns, nil
 	}
-	// Check category-specific offer
-	err2 := db.Where("category_id = ? AND active = ? AND start_date <= ? AND end_date >= ?", categoryID, true, now, now).First(&catOffer).Error
-	if err2 == nil {
-		catPercent = catOffer.DiscountPercent
+	bookIDs := make([]uint, len(books))
+	categoryIDs := make([]uint, len(books))
+	for i, book := range books {
+		bookIDs[i] = book.ID
+		categoryIDs[i] = book.CategoryID
 	}
 
-	// Return both product and category discounts
-	return OfferBreakdown{
-		ProductOfferPercent:  prodPercent,
-		CategoryOfferPercent: catPercent,
-		AppliedOfferPercent:  prodPercent + catPercent, // Sum both for total discount
-		AppliedOfferType:     "product+category",
-	}, nil
+	// Best offer first, so the first offer seen for a book or category is the one that applies
+	var prodOffers []models.ProductOffer
+	err := db.Where("product_id IN ? AND active = ? AND start_date <= ? AND end_date >= ?", bookIDs, true, at, at).
+		Order("discount_percent DESC").Find(&prodOffers).Error
+	var catOffers []models.CategoryOffer
+	if err == nil {
+		err = db.Where("category_id IN ? AND active = ? AND start_date <= ? AND end_date >= ?", categoryIDs, true, at, at).
+			Order("discount_percent DESC").Find(&catOffers).Error
+	}
+
+	bestProdOffer := make(map[uint]models.ProductOffer, len(prodOffers))
+	for _, offer := range prodOffers {
+		if _, ok := bestProdOffer[offer.ProductID]; !ok {
+			bestProdOffer[offer.ProductID] = offer
+		}
+	}
+	bestCatOffer := make(map[uint]models.CategoryOffer, len(catOffers))
+	for _, offer := range catOffers {
+		if _, ok := bestCatOffer[offer.CategoryID]; !ok {
+			bestCatOffer[offer.CategoryID] = offer
+		}
+	}
+
+	rules := GetOfferRules()
+	for _, book := range books {
+		prodOffer := bestProdOffer[book.ID]
+		catOffer := bestCatOffer[book.CategoryID]
+		breakdowns[book.ID] = CombineOffers(rules, prodOffer.DiscountPercent, catOffer.DiscountPercent, prodOffer.Exclusive, catOffer.Exclusive)
+	}
+	return breakdowns, err
 }
 
 // Deprecated: Use GetOfferBreakdownForBook instead if you want detailed offer info
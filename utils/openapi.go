@@ -0,0 +1,376 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteDoc adds what cannot be read from the router to a route's OpenAPI operation
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Request     interface{} // JSON body, a struct value with json and binding tags
+	Query       interface{} // query parameters, a struct value with form and binding tags
+}
+
+// OpenAPISecurityScheme names the bearer token scheme in the generated spec
+const OpenAPISecurityScheme = "bearerAuth"
+
+var pathParamPattern = regexp.MustCompile(`:([A-Za-z0-9_]+)|\*([A-Za-z0-9_]+)`)
+
+// BuildOpenAPISpec generates an OpenAPI 3 document from the registered routes. Operations are
+// named after their handlers; docs, keyed by handler name, add summaries and request schemas.
+// Routes under /v1/user, /v1/profile and /v1/admin (except login) require a bearer token.
+func BuildOpenAPISpec(routes gin.RoutesInfo, docs map[string]RouteDoc) map[string]interface{} {
+	sorted := make(gin.RoutesInfo, len(routes))
+	copy(sorted, routes)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Path == sorted[j].Path {
+			return sorted[i].Method < sorted[j].Method
+		}
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	schemas := map[string]interface{}{
+		"Envelope": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"status":  map[string]interface{}{"type": "string", "example": "success"},
+				"message": map[string]interface{}{"type": "string"},
+				"data":    map[string]interface{}{"type": "object"},
+			},
+		},
+		"Error": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"status":  map[string]interface{}{"type": "string", "example": "error"},
+				"code":    map[string]interface{}{"type": "string", "example": string(CodeInvalidRequest), "description": "Stable error code, see GET /v1/error-codes"},
+				"message": map[string]interface{}{"type": "string"},
+				"error":   map[string]interface{}{},
+			},
+		},
+	}
+
+	paths := map[string]interface{}{}
+	operationIDs := map[string]int{}
+	for _, route := range sorted {
+		if !strings.HasPrefix(route.Path, "/v1/") && !strings.HasPrefix(route.Path, "/auth/") {
+			continue
+		}
+		handler := handlerName(route.Handler)
+		doc := docs[handler]
+
+		// Inline handlers are named func1, func2... so name them after the route instead
+		operationID := handler
+		if strings.HasPrefix(handler, "func") {
+			operationID = strings.ToLower(route.Method) + strings.NewReplacer("/", "_", ":", "", "-", "_").Replace(route.Path)
+		}
+		if n := operationIDs[operationID]; n > 0 {
+			operationIDs[operationID]++
+			operationID = fmt.Sprintf("%s%d", operationID, n+1)
+		} else {
+			operationIDs[operationID]++
+		}
+
+		summary := doc.Summary
+		if summary == "" {
+			summary = humanizeHandlerName(handler)
+		}
+		if summary == "" {
+			summary = route.Method + " " + route.Path
+		}
+		operation := map[string]interface{}{
+			"operationId": operationID,
+			"summary":     summary,
+			"tags":        []string{routeTag(route.Path)},
+			"responses":   openAPIResponses(),
+		}
+		if doc.Description != "" {
+			operation["description"] = doc.Description
+		}
+
+		parameters := []interface{}{}
+		for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+			name := match[1] + match[2]
+			parameters = append(parameters, map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+		if doc.Query != nil {
+			parameters = append(parameters, queryParameters(reflect.TypeOf(doc.Query))...)
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		if doc.Request != nil {
+			requestType := indirectType(reflect.TypeOf(doc.Request))
+			schemas[requestType.Name()] = typeSchema(requestType, "json")
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": map[string]interface{}{"$ref": "#/components/schemas/" + requestType.Name()},
+					},
+				},
+			}
+		}
+
+		if routeRequiresAuth(route.Path) {
+			operation["security"] = []interface{}{map[string]interface{}{OpenAPISecurityScheme: []string{}}}
+		}
+
+		path := pathParamPattern.ReplaceAllString(route.Path, "{$1$2}")
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       AppName + " API",
+			"version":     APIVersion,
+			"description": "Generated from the registered routes. Successful responses use the Envelope schema, errors the Error schema.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				OpenAPISecurityScheme: map[string]interface{}{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+				},
+			},
+			"schemas": schemas,
+		},
+	}
+}
+
+// handlerName strips the package path, and the receiver of handler methods, from a
+// handler's function name
+func handlerName(fullName string) string {
+	name := fullName[strings.LastIndex(fullName, "/")+1:]
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.LastIndex(name, ")."); i >= 0 {
+		name = name[i+2:]
+	}
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// humanizeHandlerName turns AdminGetOrderDetails into "Admin get order details"
+func humanizeHandlerName(name string) string {
+	if strings.HasPrefix(name, "func") || name == "" {
+		return ""
+	}
+	var words []string
+	start := 0
+	runes := []rune(name)
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsUpper(runes[i]) && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+	for i := 1; i < len(words); i++ {
+		if strings.ToUpper(words[i]) != words[i] {
+			words[i] = strings.ToLower(words[i])
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// routeTag groups operations by their first resource segment, e.g. "admin/orders"
+func routeTag(path string) string {
+	segments := strings.Split(strings.TrimPrefix(strings.TrimPrefix(path, "/v1"), "/"), "/")
+	if (segments[0] == "admin" || segments[0] == "user") && len(segments) > 1 && !strings.HasPrefix(segments[1], ":") {
+		return segments[0] + "/" + segments[1]
+	}
+	return segments[0]
+}
+
+// routeRequiresAuth reports whether a route sits behind the user or admin auth middleware
+func routeRequiresAuth(path string) bool {
+	switch {
+	case strings.HasPrefix(path, "/v1/admin/login"):
+		return false
+	case strings.HasPrefix(path, "/v1/admin/"), strings.HasPrefix(path, "/v1/user/"), strings.HasPrefix(path, "/v1/profile"):
+		return true
+	}
+	return false
+}
+
+func openAPIResponses() map[string]interface{} {
+	ref := func(name string) map[string]interface{} {
+		return map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/" + name},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"200": map[string]interface{}{"description": "Success", "content": ref("Envelope")},
+		"400": map[string]interface{}{"description": "Invalid request", "content": ref("Error")},
+		"401": map[string]interface{}{"description": "Not authenticated", "content": ref("Error")},
+		"404": map[string]interface{}{"description": "Not found", "content": ref("Error")},
+		"500": map[string]interface{}{"description": "Server error", "content": ref("Error")},
+	}
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// fieldName returns the name of a struct field under a tag ("json" or "form"), or "" when skipped
+func fieldName(field reflect.StructField, tag string) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+	name := strings.Split(field.Tag.Get(tag), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// applyBindingRules adds the validator rules OpenAPI can express to a schema and reports
+// whether the field is required
+func applyBindingRules(schema map[string]interface{}, binding string) bool {
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		limit, _ := strconv.ParseFloat(value, 64)
+		switch key {
+		case "required":
+			required = true
+		case "oneof":
+			schema["enum"] = strings.Fields(value)
+		case "email":
+			schema["format"] = "email"
+		case "min", "max", "gte", "lte", "gt", "lt":
+			if schema["type"] == "string" || schema["type"] == "array" {
+				suffix := "Length"
+				if schema["type"] == "array" {
+					suffix = "Items"
+				}
+				if key == "min" || key == "gte" {
+					schema["min"+suffix] = limit
+				} else if key == "max" || key == "lte" {
+					schema["max"+suffix] = limit
+				}
+				continue
+			}
+			switch key {
+			case "min", "gte":
+				schema["minimum"] = limit
+			case "max", "lte":
+				schema["maximum"] = limit
+			case "gt":
+				schema["minimum"], schema["exclusiveMinimum"] = limit, true
+			case "lt":
+				schema["maximum"], schema["exclusiveMaximum"] = limit, true
+			}
+		}
+	}
+	return required
+}
+
+// typeSchema describes a Go type as an inline OpenAPI schema
+func typeSchema(t reflect.Type, tag string) map[string]interface{} {
+	t = indirectType(t)
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), tag)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), tag)}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct {
+				embedded := typeSchema(field.Type, tag)
+				for name, schema := range embedded["properties"].(map[string]interface{}) {
+					properties[name] = schema
+				}
+				if names, ok := embedded["required"].([]string); ok {
+					required = append(required, names...)
+				}
+				continue
+			}
+			name := fieldName(field, tag)
+			if name == "" {
+				continue
+			}
+			schema := typeSchema(field.Type, tag)
+			if applyBindingRules(schema, field.Tag.Get("binding")) {
+				required = append(required, name)
+			}
+			properties[name] = schema
+		}
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+		return schema
+	}
+	return map[string]interface{}{}
+}
+
+// queryParameters describes the form-tagged fields of a struct as query parameters
+func queryParameters(t reflect.Type) []interface{} {
+	t = indirectType(t)
+	var parameters []interface{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("form"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		schema := typeSchema(field.Type, "form")
+		required := applyBindingRules(schema, field.Tag.Get("binding"))
+		parameters = append(parameters, map[string]interface{}{
+			"name":     name,
+			"in":       "query",
+			"required": required,
+			"schema":   schema,
+		})
+	}
+	return parameters
+}
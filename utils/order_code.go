@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// orderCodePrefix starts the order code printed on invoices, packing slips and labels
+const orderCodePrefix = "RS-"
+
+// ErrInvalidOrderCode is returned for a scanned code that does not identify an order
+var ErrInvalidOrderCode = errors.New("invalid order code")
+
+// OrderCode is the human-readable code of an order, e.g. RS-00000123, encoded in the barcodes
+// of its shipping documents
+func OrderCode(orderID uint) string {
+	return fmt.Sprintf("%s%08d", orderCodePrefix, orderID)
+}
+
+// OrderVerificationToken signs the order ID so a scanned invoice QR code can be told apart
+// from a guessed order number
+func OrderVerificationToken(orderID uint) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	mac.Write([]byte("order:" + strconv.FormatUint(uint64(orderID), 10)))
+	return strings.ToUpper(hex.EncodeToString(mac.Sum(nil))[:12])
+}
+
+// OrderQRPayload is the text encoded in an invoice's QR code: the order code and its
+// verification token
+func OrderQRPayload(orderID uint) string {
+	return OrderCode(orderID) + "." + OrderVerificationToken(orderID)
+}
+
+// ParseOrderCode returns the order a scanned code points to. It accepts an invoice QR
+// payload, whose token must match, an order code from a label or a plain order ID.
+func ParseOrderCode(code string) (uint, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	number, token, signed := strings.Cut(code, ".")
+	number = strings.TrimPrefix(number, orderCodePrefix)
+
+	id, err := strconv.ParseUint(number, 10, 64)
+	if err != nil || id == 0 {
+		return 0, ErrInvalidOrderCode
+	}
+	orderID := uint(id)
+	if signed && !hmac.Equal([]byte(token), []byte(OrderVerificationToken(orderID))) {
+		return 0, ErrInvalidOrderCode
+	}
+	return orderID, nil
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+)
+
+// orderStatusMessages explain a status change in the order status email
+var orderStatusMessages = map[string]string{
+	models.OrderStatusProcessing:      "We are getting your order ready.",
+	models.OrderStatusPaid:            "We have received your payment.",
+	models.OrderStatusShipped:         "Your order has been handed to the courier and is on its way.",
+	models.OrderStatusDelivered:       "Your order has been delivered. Happy reading!",
+	models.OrderStatusCancelled:       "Your order has been cancelled. Any amount you paid is refunded to your wallet or payment method.",
+	models.OrderStatusRefunded:        "Your refund has been processed.",
+	models.OrderStatusReturnApproved:  "Your return has been approved. We will let you know once it is picked up and refunded.",
+	models.OrderStatusReturnRejected:  "Your return request could not be approved.",
+	models.OrderStatusReturnCompleted: "Your return is complete and the refund has been issued.",
+}
+
+// paymentMethodLabels name the payment methods in emails
+var paymentMethodLabels = map[string]string{
+	"cod":    "Cash on Delivery",
+	"online": "Paid online",
+	"wallet": "Paid from wallet",
+}
+
+// SendOrderPlacedEmail emails the order confirmation with its items and totals, unless the
+// user turned order updates off
+func SendOrderPlacedEmail(orderID uint) error {
+	order, ok, err := loadOrderForEmail(orderID)
+	if err != nil || !ok {
+		return err
+	}
+
+	totals := []EmailTotal{{Label: "Subtotal", Amount: FormatBaseMoney(order.TotalAmount)}}
+	if discount := order.Discount + order.CouponDiscount; discount > 0 {
+		totals = append(totals, EmailTotal{Label: "Discounts", Amount: "-" + FormatBaseMoney(discount)})
+	}
+	if order.DeliveryCharge > 0 {
+		totals = append(totals, EmailTotal{Label: "Delivery", Amount: FormatBaseMoney(order.DeliveryCharge)})
+	}
+	if order.GiftWrapFee > 0 {
+		totals = append(totals, EmailTotal{Label: "Gift wrap", Amount: FormatBaseMoney(order.GiftWrapFee)})
+	}
+	if order.PaymentAdjustment != 0 {
+		totals = append(totals, EmailTotal{Label: "Payment adjustment", Amount: FormatBaseMoney(order.PaymentAdjustment)})
+	}
+	totals = append(totals, EmailTotal{Label: "Total", Amount: FormatBaseMoney(order.TotalWithDelivery)})
+
+	paymentMethod := paymentMethodLabels[NormalizePaymentMethod(order.PaymentMethod)]
+	if paymentMethod == "" {
+		paymentMethod = order.PaymentMethod
+	}
+	deliveryDate := ""
+	if order.EstimatedDeliveryTo != nil {
+		deliveryDate = order.EstimatedDeliveryTo.Format("Mon, 2 Jan 2006")
+	}
+
+	subject := fmt.Sprintf("Your %s order #%d is confirmed", GetEmailBranding().Name, order.ID)
+	return SendTemplateEmail(order.User.Email, subject, "order_placed", map[string]interface{}{
+		"Name":          order.User.FirstName,
+		"OrderID":       order.ID,
+		"Items":         orderEmailItems(order),
+		"Totals":        totals,
+		"PaymentMethod": paymentMethod,
+		"DeliveryDate":  deliveryDate,
+		"OrderURL":      FrontendLink(fmt.Sprintf("/orders/%d", order.ID)),
+	})
+}
+
+// SendOrderStatusEmail emails a change of the order's status with the note given for it,
+// unless the user turned order updates off
+func SendOrderStatusEmail(orderID uint, status, note string) error {
+	order, ok, err := loadOrderForEmail(orderID)
+	if err != nil || !ok {
+		return err
+	}
+
+	message, known := orderStatusMessages[status]
+	if !known {
+		message = fmt.Sprintf("The status of your order is now %s.", status)
+	}
+	subject := fmt.Sprintf("Your %s order #%d is %s", GetEmailBranding().Name, order.ID, status)
+	return SendTemplateEmail(order.User.Email, subject, "order_status", map[string]interface{}{
+		"Name":     order.User.FirstName,
+		"OrderID":  order.ID,
+		"Status":   status,
+		"Message":  message,
+		"Note":     note,
+		"Items":    orderEmailItems(order),
+		"OrderURL": FrontendLink(fmt.Sprintf("/orders/%d", order.ID)),
+	})
+}
+
+// loadOrderForEmail loads the order with its user and items, and reports whether the user
+// gets order emails
+func loadOrderForEmail(orderID uint) (models.Order, bool, error) {
+	var order models.Order
+	if err := config.DB.Preload("User").Preload("OrderItems.Book").First(&order, orderID).Error; err != nil {
+		return order, false, err
+	}
+	if order.User.Email == "" || order.User.AnonymizedAt != nil {
+		return order, false, nil
+	}
+	preference, err := NotificationPreferenceFor(config.DB, order.UserID)
+	if err != nil {
+		return order, false, err
+	}
+	if !WantsNotification(preference, models.NotificationTypeOrder) {
+		LogDebug("User %d turned order updates off, no email for order %d", order.UserID, order.ID)
+		return order, false, nil
+	}
+	return order, true, nil
+}
+
+func orderEmailItems(order models.Order) []EmailItem {
+	items := make([]EmailItem, len(order.OrderItems))
+	for i, item := range order.OrderItems {
+		items[i] = EmailItem{
+			Name:     item.Book.Name,
+			Note:     item.Book.Author,
+			Quantity: item.Quantity,
+			Amount:   FormatBaseMoney(item.Total),
+		}
+	}
+	return items
+}
@@ -0,0 +1,250 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OTP flows. Their state is kept on the server between the request that sends an OTP and the
+// one verifying it, keyed by the address (see StartOTP) or the phone number (see StartPhoneOTP).
+const (
+	OTPPurposeRegistration  = "registration"
+	OTPPurposePasswordReset = "reset"
+	OTPPurposeEmailChange   = "email_change"
+	OTPPurposePhoneLogin    = "phone_login"  // sign in with a verified phone number
+	OTPPurposePhoneVerify   = "phone_verify" // verify a phone number for the signed-in user
+)
+
+// Limits on sending and checking OTPs. An address or number gets at most OTPMaxSends OTPs per
+// flow within otpSendWindow, OTPResendCooldown apart, however many clients ask for them.
+const (
+	OTPResendCooldown = 30 * time.Second // minimum time between two OTPs of a flow
+	OTPMaxSends       = 5                // OTPs a flow may send within otpSendWindow
+	OTPMaxAttempts    = 5                // wrong codes accepted before the OTP is discarded
+	otpSendWindow     = time.Hour
+)
+
+// otpLifetimes is how long the OTP of each flow stays valid
+var otpLifetimes = map[string]time.Duration{
+	OTPPurposeRegistration:  time.Minute,
+	OTPPurposePasswordReset: time.Minute,
+	OTPPurposeEmailChange:   15 * time.Minute,
+	OTPPurposePhoneLogin:    5 * time.Minute,
+	OTPPurposePhoneVerify:   5 * time.Minute,
+}
+
+// OTP flow errors
+var (
+	ErrOTPNotStarted     = errors.New("no otp flow in progress")
+	ErrOTPExpired        = errors.New("otp expired")
+	ErrOTPInvalid        = errors.New("otp is incorrect")
+	ErrOTPAttempts       = errors.New("too many incorrect otp attempts")
+	ErrOTPResendCooldown = errors.New("otp was sent too recently")
+	ErrOTPResendLimit    = errors.New("otp resend limit reached")
+	ErrOTPRateLimited    = errors.New("too many otp requests from this address")
+)
+
+// IsOTPPurpose reports whether purpose names an OTP flow
+func IsOTPPurpose(purpose string) bool {
+	_, ok := otpLifetimes[purpose]
+	return ok
+}
+
+// OTPLifetime returns how long the OTP of the flow stays valid
+func OTPLifetime(purpose string) time.Duration {
+	return otpLifetimes[purpose]
+}
+
+// StartOTP emails a new OTP for the flow to the address, replacing the address's previous OTP
+// for the flow. Like phone OTPs, the state is kept on the server keyed by the address, with
+// only a hash of the code, so the cooldown and limits hold whoever asks. userID ties an email
+// change to the signed-in user and is nil for the other flows. The returned OTP is set on
+// cooldown and limit errors too, to tell when to retry.
+func StartOTP(purpose, email string, userID *uint) (*models.EmailOTP, error) {
+	return sendEmailOTP(purpose, email, userID, false)
+}
+
+// ResendOTP emails a new OTP for the flow already started for the address, under the same
+// cooldown and limits as StartOTP
+func ResendOTP(purpose, email string) (*models.EmailOTP, error) {
+	return sendEmailOTP(purpose, email, nil, true)
+}
+
+// OTPRetryAfter returns how long until the address may be emailed another OTP for the flow:
+// the rest of the cooldown, or of the window once OTPMaxSends were sent
+func OTPRetryAfter(otp *models.EmailOTP) time.Duration {
+	return otpRetryAfter(otp.SentAt, otp.WindowStartedAt, otp.Sends)
+}
+
+// CheckOTP compares code with the OTP emailed to the address for the flow and uses it up when
+// it matches. userID must be the user the OTP was sent for. Wrong codes are counted, and once
+// OTPMaxAttempts is reached the OTP is discarded so a new one has to be requested.
+func CheckOTP(purpose, email string, userID *uint, code string) error {
+	var result error
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var otp models.EmailOTP
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("purpose = ? AND email = ?", purpose, email).First(&otp).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !sameUserID(otp.UserID, userID)) {
+			result = ErrOTPNotStarted
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case otp.CodeHash == "" && otp.Attempts >= OTPMaxAttempts:
+			result = ErrOTPAttempts
+			return nil
+		case otp.CodeHash == "" || time.Now().After(otp.ExpiresAt):
+			result = ErrOTPExpired
+			return nil
+		case hmac.Equal([]byte(otp.CodeHash), []byte(hashOTP(email, code))):
+			return tx.Model(&otp).Update("code_hash", "").Error
+		}
+
+		otp.Attempts++
+		result = ErrOTPInvalid
+		if otp.Attempts >= OTPMaxAttempts {
+			otp.CodeHash = ""
+			result = ErrOTPAttempts
+		}
+		return tx.Model(&otp).Updates(map[string]interface{}{
+			"attempts":  otp.Attempts,
+			"code_hash": otp.CodeHash,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+// CleanupEmailOTPs deletes email OTPs that expired and no longer count towards the limit of
+// their address
+func CleanupEmailOTPs() (string, error) {
+	now := time.Now()
+	result := config.DB.Where("expires_at < ? AND window_started_at < ?", now, now.Add(-otpSendWindow)).
+		Delete(&models.EmailOTP{})
+	if result.Error != nil {
+		return "", result.Error
+	}
+	return fmt.Sprintf("Deleted %d email OTPs", result.RowsAffected), nil
+}
+
+// FailOTP sends the error response for an OTP flow error
+func FailOTP(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrOTPNotStarted):
+		Fail(c, CodeSessionExpired, "No verification in progress", "Please start the verification again")
+	case errors.Is(err, ErrOTPExpired):
+		Fail(c, CodeOTPExpired, "OTP expired", "The OTP has expired. Request a new one with /v1/auth/resend-otp")
+	case errors.Is(err, ErrOTPInvalid):
+		Fail(c, CodeOTPInvalid, "Invalid OTP", "The OTP you entered is incorrect")
+	case errors.Is(err, ErrOTPAttempts):
+		Fail(c, CodeOTPAttempts, "Too many incorrect attempts", "Request a new OTP with /v1/auth/resend-otp")
+	case errors.Is(err, ErrOTPResendLimit):
+		Fail(c, CodeOTPResendLimit, "OTP resend limit reached", "Please start the verification again")
+	case errors.Is(err, ErrOTPRateLimited):
+		Fail(c, CodeTooManyRequests, "Too many OTP requests", "Please try again later")
+	default:
+		InternalServerError(c, "Failed to process OTP", err.Error())
+	}
+}
+
+// sendOTPEmail emails an OTP; tests replace it to read the codes
+var sendOTPEmail = SendOTP
+
+// sendEmailOTP counts and stores a new OTP for the flow and address and emails it. A resend
+// needs the flow to have been started and keeps the user it was started for.
+func sendEmailOTP(purpose, email string, userID *uint, resend bool) (*models.EmailOTP, error) {
+	now := time.Now()
+	code := GenerateOTP()
+
+	var otp models.EmailOTP
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Create the row first so concurrent requests for the address wait on the same lock
+		if !resend {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.EmailOTP{
+				Purpose: purpose, Email: email, ExpiresAt: now, WindowStartedAt: now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("purpose = ? AND email = ?", purpose, email).First(&otp).Error
+		if resend && errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOTPNotStarted
+		}
+		if err != nil {
+			return err
+		}
+		if resend {
+			userID = otp.UserID
+		}
+
+		if now.Sub(otp.WindowStartedAt) >= otpSendWindow {
+			otp.Sends = 0
+			otp.WindowStartedAt = now
+		}
+		if now.Sub(otp.SentAt) < OTPResendCooldown {
+			return ErrOTPResendCooldown
+		}
+		if otp.Sends >= OTPMaxSends {
+			return ErrOTPResendLimit
+		}
+
+		otp.UserID = userID
+		otp.CodeHash = hashOTP(email, code)
+		otp.ExpiresAt = now.Add(OTPLifetime(purpose))
+		otp.SentAt = now
+		otp.Sends++
+		otp.Attempts = 0
+		return tx.Save(&otp).Error
+	})
+	if err != nil {
+		return &otp, err
+	}
+	return &otp, sendOTPEmail(email, code, purpose)
+}
+
+// otpRetryAfter returns how long until another OTP may be sent after the last one at sentAt,
+// given the sends counted since windowStartedAt
+func otpRetryAfter(sentAt, windowStartedAt time.Time, sends int) time.Duration {
+	wait := time.Until(sentAt.Add(OTPResendCooldown))
+	if sends >= OTPMaxSends {
+		wait = time.Until(windowStartedAt.Add(otpSendWindow))
+	}
+	if wait < 0 {
+		return 0
+	}
+	return wait.Round(time.Second)
+}
+
+// hashOTP hashes the code sent to the address or phone number. It is keyed with JWT_SECRET so
+// the six-digit codes cannot be recovered from the table by trying them all.
+func hashOTP(recipient, code string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	mac.Write([]byte(recipient + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sameUserID reports whether two optional user IDs are equal
+func sameUserID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordOTPEmails makes OTP emails record the last code sent to each address for the rest of
+// the test instead of sending it
+func recordOTPEmails(t *testing.T) map[string]string {
+	previous := sendOTPEmail
+	codes := map[string]string{}
+	sendOTPEmail = func(to, otp, purpose string) error {
+		codes[to] = otp
+		return nil
+	}
+	t.Cleanup(func() { sendOTPEmail = previous })
+	return codes
+}
+
+func TestEmailOTPKeepsOnlyAHash(t *testing.T) {
+	db := testutil.NewDB(t, &models.EmailOTP{})
+	codes := recordOTPEmails(t)
+	const email = "reader@example.com"
+
+	otp, err := StartOTP(OTPPurposePasswordReset, email, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, otp.Sends)
+	code := codes[email]
+	require.Len(t, code, 6)
+
+	var stored models.EmailOTP
+	require.NoError(t, db.Where("purpose = ? AND email = ?", OTPPurposePasswordReset, email).First(&stored).Error)
+	assert.Len(t, stored.CodeHash, 64)
+	assert.NotContains(t, stored.CodeHash, code)
+
+	assert.ErrorIs(t, CheckOTP(OTPPurposePasswordReset, email, nil, "000000x"), ErrOTPInvalid)
+	assert.ErrorIs(t, CheckOTP(OTPPurposeRegistration, email, nil, code), ErrOTPNotStarted, "OTPs belong to their flow")
+	assert.NoError(t, CheckOTP(OTPPurposePasswordReset, email, nil, code))
+	assert.ErrorIs(t, CheckOTP(OTPPurposePasswordReset, email, nil, code), ErrOTPExpired, "an OTP works once")
+}
+
+func TestEmailOTPLimitsPerAddress(t *testing.T) {
+	db := testutil.NewDB(t, &models.EmailOTP{})
+	codes := recordOTPEmails(t)
+	const email = "limits@example.com"
+	ageLastEmail := func(by time.Duration) {
+		require.NoError(t, db.Model(&models.EmailOTP{}).Where("email = ?", email).
+			Update("sent_at", time.Now().Add(-by)).Error)
+	}
+
+	_, err := ResendOTP(OTPPurposeRegistration, email)
+	assert.ErrorIs(t, err, ErrOTPNotStarted, "only a started flow can be resent")
+
+	_, err = StartOTP(OTPPurposeRegistration, email, nil)
+	require.NoError(t, err)
+	otp, err := StartOTP(OTPPurposeRegistration, email, nil)
+	assert.ErrorIs(t, err, ErrOTPResendCooldown, "starting again does not skip the cooldown")
+	assert.InDelta(t, OTPResendCooldown.Seconds(), OTPRetryAfter(otp).Seconds(), 1)
+
+	for sends := 2; sends <= OTPMaxSends; sends++ {
+		ageLastEmail(OTPResendCooldown)
+		otp, err = ResendOTP(OTPPurposeRegistration, email)
+		require.NoError(t, err)
+		assert.Equal(t, sends, otp.Sends)
+	}
+	ageLastEmail(OTPResendCooldown)
+	otp, err = StartOTP(OTPPurposeRegistration, email, nil)
+	assert.ErrorIs(t, err, ErrOTPResendLimit, "starting again does not reset the limit")
+	assert.Greater(t, OTPRetryAfter(otp), 50*time.Minute)
+
+	require.NoError(t, db.Model(&models.EmailOTP{}).Where("email = ?", email).
+		Update("window_started_at", time.Now().Add(-otpSendWindow)).Error)
+	otp, err = ResendOTP(OTPPurposeRegistration, email)
+	require.NoError(t, err, "the limit starts again after an hour")
+	assert.Equal(t, 1, otp.Sends)
+
+	code := codes[email]
+	for attempt := 1; attempt < OTPMaxAttempts; attempt++ {
+		assert.ErrorIs(t, CheckOTP(OTPPurposeRegistration, email, nil, "wrong"), ErrOTPInvalid)
+	}
+	assert.ErrorIs(t, CheckOTP(OTPPurposeRegistration, email, nil, "wrong"), ErrOTPAttempts)
+	assert.ErrorIs(t, CheckOTP(OTPPurposeRegistration, email, nil, code), ErrOTPAttempts, "the OTP is discarded")
+}
+
+func TestEmailChangeOTPBelongsToTheUser(t *testing.T) {
+	testutil.NewDB(t, &models.EmailOTP{})
+	codes := recordOTPEmails(t)
+	const email = "new-address@example.com"
+	owner, other := uint(1), uint(2)
+
+	_, err := StartOTP(OTPPurposeEmailChange, email, &owner)
+	require.NoError(t, err)
+	code := codes[email]
+
+	assert.ErrorIs(t, CheckOTP(OTPPurposeEmailChange, email, &other, code), ErrOTPNotStarted)
+	assert.ErrorIs(t, CheckOTP(OTPPurposeEmailChange, email, nil, code), ErrOTPNotStarted)
+	assert.NoError(t, CheckOTP(OTPPurposeEmailChange, email, &owner, code))
+}
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -77,3 +80,123 @@ func SendPaginatedResponse(c *gin.Context, data interface{}, pagination *Paginat
 	response := NewPaginatedResponse(data, pagination)
 	Success(c, "Success", response)
 }
+
+// ErrInvalidCursor is returned for a cursor that cannot be decoded or that belongs to a listing
+// in another sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a row of a keyset-paginated listing by the value of its sort key and its ID,
+// which breaks ties between rows with the same value
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"i"`
+}
+
+// EncodeCursor turns the cursor into the opaque token clients send back as after or before
+func EncodeCursor(cursor Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor reads a token made by EncodeCursor
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// CursorParams are the parameters of a keyset-paginated request. Rows are read after Cursor in
+// the listing's order, or before it when Backward is set; a nil Cursor reads the first page.
+type CursorParams struct {
+	Sort     string
+	Order    string // asc or desc
+	Cursor   *Cursor
+	Backward bool
+	Limit    int
+}
+
+// GetCursorParams returns the cursor pagination parameters of a listing sorted by sort in order,
+// and whether the request asked for cursor pagination at all: with an after or before cursor,
+// or with pagination=cursor for the first page. Other requests keep page and limit.
+func GetCursorParams(c *gin.Context, sort, order string) (*CursorParams, bool, error) {
+	after, before := c.Query("after"), c.Query("before")
+	if after == "" && before == "" && c.Query("pagination") != "cursor" {
+		return nil, false, nil
+	}
+	_, limit := GetPaginationParams(c)
+	params := &CursorParams{Sort: sort, Order: order, Limit: limit}
+
+	token := after
+	if before != "" {
+		token = before
+		params.Backward = true
+	}
+	if token != "" {
+		cursor, err := DecodeCursor(token)
+		if err != nil {
+			return nil, true, err
+		}
+		if cursor.Sort != sort {
+			return nil, true, ErrInvalidCursor
+		}
+		params.Cursor = cursor
+	}
+	return params, true, nil
+}
+
+// Direction is the order to read rows in: the listing's order, reversed when paging backward
+func (p *CursorParams) Direction() string {
+	if p.Backward == (p.Order == "asc") {
+		return "desc"
+	}
+	return "asc"
+}
+
+// Comparison is the operator that selects the rows past the cursor in the reading direction
+func (p *CursorParams) Comparison() string {
+	if p.Direction() == "asc" {
+		return ">"
+	}
+	return "<"
+}
+
+// Page takes the n rows read, limit+1 at most, and returns how many of them belong on the page
+// and whether the listing goes on past them. When paging backward the rows were read in
+// reverse, and the caller flips the page back.
+func (p *CursorParams) Page(n int) (int, bool) {
+	if n > p.Limit {
+		return p.Limit, true
+	}
+	return n, false
+}
+
+// Pagination is the pagination block of a cursor-paginated response. first and last are the
+// cursors of the first and last rows on the page, nil when it is empty, and more reports whether
+// rows were left beyond the page in the reading direction.
+func (p *CursorParams) Pagination(first, last *Cursor, more bool) gin.H {
+	var next, prev interface{}
+	hasNext, hasPrev := more, p.Cursor != nil
+	if p.Backward {
+		hasNext, hasPrev = p.Cursor != nil, more
+	}
+	if hasNext && last != nil {
+		next = EncodeCursor(*last)
+	}
+	if hasPrev && first != nil {
+		prev = EncodeCursor(*first)
+	}
+	return gin.H{
+		"mode":        "cursor",
+		"limit":       p.Limit,
+		"next_cursor": next,
+		"prev_cursor": prev,
+		"has_more":    next != nil,
+	}
+}
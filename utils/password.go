@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/golang-jwt/jwt"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// PasswordHistorySize is the number of previous passwords a new password may not repeat
+const PasswordHistorySize = 3
+
+// PasswordRecentlyUsed reports whether password matches one of the user's last
+// PasswordHistorySize passwords
+func PasswordRecentlyUsed(db *gorm.DB, userID uint, password string) (bool, error) {
+	var history []models.PasswordHistory
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Limit(PasswordHistorySize).Find(&history).Error; err != nil {
+		return false, err
+	}
+	for _, entry := range history {
+		if bcrypt.CompareHashAndPassword([]byte(entry.Password), []byte(password)) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SetPassword stores the password hash on the user, records it in the password history and
+// revokes the user's tokens issued before the change. Call it inside a transaction.
+func SetPassword(tx *gorm.DB, user *models.User, hashedPassword string) error {
+	// Token issue times have second precision, so the cut-off is truncated to match
+	changedAt := time.Now().Truncate(time.Second)
+	if err := tx.Model(user).Updates(map[string]interface{}{
+		"password":            hashedPassword,
+		"password_changed_at": changedAt,
+	}).Error; err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+	user.PasswordChangedAt = &changedAt
+
+	return tx.Create(&models.PasswordHistory{
+		UserID:   user.ID,
+		Password: hashedPassword,
+	}).Error
+}
+
+// TokenRevoked reports whether a user token was issued before the user's last password
+// change. Tokens without an issue time predate the check and count as issued at zero.
+func TokenRevoked(claims jwt.MapClaims, user *models.User) bool {
+	if user.PasswordChangedAt == nil {
+		return false
+	}
+	issuedAt, _ := claims["iat"].(float64)
+	return int64(issuedAt) < user.PasswordChangedAt.Unix()
+}
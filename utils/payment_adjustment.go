@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// PaymentMethods lists the checkout payment methods that can carry an adjustment
+var PaymentMethods = []string{"cod", "online", "wallet"}
+
+// PaymentAdjustment is the effect of a payment method rule on one order amount
+type PaymentAdjustment struct {
+	Type        string  `json:"type,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Amount      float64 `json:"amount"`   // signed: fees are positive, discounts negative
+	Cashback    float64 `json:"cashback"` // wallet credit, does not change the payable total
+}
+
+// NormalizePaymentMethod maps stored order payment methods to their adjustment key
+func NormalizePaymentMethod(method string) string {
+	method = strings.ToLower(strings.TrimSpace(method))
+	if method == "razorpay" {
+		return "online"
+	}
+	return method
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// CalculatePaymentAdjustment returns the adjustment for paying the given amount with the
+// payment method. Methods without an active rule, or below its minimum, get a zero adjustment.
+func CalculatePaymentAdjustment(paymentMethod string, amount float64) PaymentAdjustment {
+	var rule models.PaymentMethodAdjustment
+	if err := config.DB.Where("payment_method = ? AND is_active = ?", NormalizePaymentMethod(paymentMethod), true).
+		First(&rule).Error; err != nil {
+		return PaymentAdjustment{}
+	}
+	if amount <= 0 || amount < rule.MinOrderAmount {
+		return PaymentAdjustment{}
+	}
+
+	value := amount*rule.Percent/100 + rule.FlatAmount
+	if rule.MaxAmount > 0 && value > rule.MaxAmount {
+		value = rule.MaxAmount
+	}
+	value = roundMoney(value)
+
+	adjustment := PaymentAdjustment{Type: rule.AdjustmentType, Description: rule.Description}
+	switch rule.AdjustmentType {
+	case models.PaymentAdjustmentFee:
+		adjustment.Amount = value
+	case models.PaymentAdjustmentDiscount:
+		// A discount can never make the order free
+		adjustment.Amount = -math.Min(value, amount)
+	case models.PaymentAdjustmentCashback:
+		adjustment.Cashback = value
+	}
+	return adjustment
+}
+
+// PaymentRefundAdjustment returns how much to add to a refund of the given goods amount so the
+// payment method adjustment is unwound pro rata: fees are refunded, discounts are withheld and
+// cashback already credited to the wallet is clawed back.
+func PaymentRefundAdjustment(order *models.Order, amount float64) float64 {
+	if order.PaymentAdjustmentBase <= 0 {
+		return 0
+	}
+	share := amount / order.PaymentAdjustmentBase
+	adjustment := order.PaymentAdjustment * share
+	if order.CashbackCredited {
+		adjustment -= order.PaymentCashback * share
+	}
+	return roundMoney(adjustment)
+}
+
+// RemainingPaymentAdjustment returns the part of the order's fee or discount that still applies
+// to its current goods total after items have been cancelled or returned
+func RemainingPaymentAdjustment(order *models.Order) float64 {
+	if order.PaymentAdjustmentBase <= 0 {
+		return 0
+	}
+	return roundMoney(order.PaymentAdjustment * order.FinalTotal / order.PaymentAdjustmentBase)
+}
+
+// CreditPaymentCashback credits the order's payment cashback to the user's wallet exactly once.
+// Must be called inside a transaction once the order is paid.
+func CreditPaymentCashback(tx *gorm.DB, order *models.Order) error {
+	if order.PaymentCashback <= 0 {
+		return nil
+	}
+
+	result := tx.Model(&models.Order{}).
+		Where("id = ? AND cashback_credited = ?", order.ID, false).
+		UpdateColumn("cashback_credited", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	order.CashbackCredited = true
+	if result.RowsAffected == 0 {
+		LogDebug("Cashback already credited for order %d", order.ID)
+		return nil
+	}
+
+	if _, err := PostWalletEntry(tx, WalletEntry{
+		UserID:      order.UserID,
+		Amount:      order.PaymentCashback,
+		Type:        models.TransactionTypeCredit,
+		Description: fmt.Sprintf("Payment cashback for order #%d", order.ID),
+		Reference:   fmt.Sprintf("CASHBACK-ORDER-%d", order.ID),
+		OrderID:     &order.ID,
+	}); err != nil {
+		return err
+	}
+
+	LogInfo("Credited cashback %.2f for order %d to user %d", order.PaymentCashback, order.ID, order.UserID)
+	return nil
+}
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PhoneOTPMaxSendsPerIP is how many OTP texts one IP address may request within otpSendWindow,
+// to any numbers
+const PhoneOTPMaxSendsPerIP = 10
+
+// StartPhoneOTP texts a new OTP for the flow to the phone number, replacing the number's
+// previous OTP for the flow. The state is kept on the server keyed by the number, with only a
+// hash of the code, so the cooldown and limits hold whoever asks. userID ties a verification to
+// the signed-in user and is nil for sign-in. When deliver is false the OTP is counted and
+// stored the same way but not texted, so the responses do not tell whether an account has the
+// number. The returned OTP is set on cooldown and limit errors too, to tell when to retry.
+func StartPhoneOTP(c *gin.Context, purpose, phone string, userID *uint, deliver bool) (*models.PhoneOTP, error) {
+	now := time.Now()
+	ip := c.ClientIP()
+	code := GenerateOTP()
+
+	var otp models.PhoneOTP
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Create the row first so concurrent requests for the number wait on the same lock
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.PhoneOTP{
+			Purpose: purpose, Phone: phone, ExpiresAt: now, WindowStartedAt: now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("purpose = ? AND phone = ?", purpose, phone).First(&otp).Error; err != nil {
+			return err
+		}
+
+		if now.Sub(otp.WindowStartedAt) >= otpSendWindow {
+			otp.Sends = 0
+			otp.WindowStartedAt = now
+		}
+		if now.Sub(otp.SentAt) < OTPResendCooldown {
+			return ErrOTPResendCooldown
+		}
+		if otp.Sends >= OTPMaxSends {
+			return ErrOTPResendLimit
+		}
+
+		var recent int64
+		if err := tx.Model(&models.PhoneOTPSend{}).
+			Where("ip = ? AND created_at > ?", ip, now.Add(-otpSendWindow)).Count(&recent).Error; err != nil {
+			return err
+		}
+		if recent >= PhoneOTPMaxSendsPerIP {
+			return ErrOTPRateLimited
+		}
+		if err := tx.Create(&models.PhoneOTPSend{IP: ip, Phone: phone, CreatedAt: now}).Error; err != nil {
+			return err
+		}
+
+		otp.UserID = userID
+		otp.CodeHash = hashOTP(phone, code)
+		otp.ExpiresAt = now.Add(OTPLifetime(purpose))
+		otp.SentAt = now
+		otp.Sends++
+		otp.Attempts = 0
+		return tx.Save(&otp).Error
+	})
+	if err != nil {
+		return &otp, err
+	}
+
+	if !deliver {
+		return &otp, nil
+	}
+	return &otp, SendSMSOTP(phone, code, int(OTPLifetime(purpose).Minutes()))
+}
+
+// PhoneOTPRetryAfter returns how long until the number may be texted another OTP for the
+// flow: the rest of the cooldown, or of the window once OTPMaxSends were sent
+func PhoneOTPRetryAfter(otp *models.PhoneOTP) time.Duration {
+	return otpRetryAfter(otp.SentAt, otp.WindowStartedAt, otp.Sends)
+}
+
+// CheckPhoneOTP compares code with the OTP texted to the phone number for the flow and uses it
+// up when it matches. userID must be the user the OTP was sent for. Wrong codes are counted, and
+// once OTPMaxAttempts is reached the OTP is discarded so a new one has to be requested.
+func CheckPhoneOTP(purpose, phone string, userID *uint, code string) error {
+	var result error
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var otp models.PhoneOTP
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("purpose = ? AND phone = ?", purpose, phone).First(&otp).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !sameUserID(otp.UserID, userID)) {
+			result = ErrOTPNotStarted
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case otp.CodeHash == "" && otp.Attempts >= OTPMaxAttempts:
+			result = ErrOTPAttempts
+			return nil
+		case otp.CodeHash == "" || time.Now().After(otp.ExpiresAt):
+			result = ErrOTPExpired
+			return nil
+		case hmac.Equal([]byte(otp.CodeHash), []byte(hashOTP(phone, code))):
+			return tx.Model(&otp).Update("code_hash", "").Error
+		}
+
+		otp.Attempts++
+		result = ErrOTPInvalid
+		if otp.Attempts >= OTPMaxAttempts {
+			otp.CodeHash = ""
+			result = ErrOTPAttempts
+		}
+		return tx.Model(&otp).Updates(map[string]interface{}{
+			"attempts":  otp.Attempts,
+			"code_hash": otp.CodeHash,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+// CleanupPhoneOTPs deletes phone OTPs that expired and no longer count towards the limit of
+// their number, and the texts requested per IP address that no longer count towards its limit
+func CleanupPhoneOTPs() (string, error) {
+	now := time.Now()
+	otps := config.DB.Where("expires_at < ? AND window_started_at < ?", now, now.Add(-otpSendWindow)).
+		Delete(&models.PhoneOTP{})
+	if otps.Error != nil {
+		return "", otps.Error
+	}
+	sends := config.DB.Where("created_at < ?", now.Add(-otpSendWindow)).Delete(&models.PhoneOTPSend{})
+	if sends.Error != nil {
+		return "", sends.Error
+	}
+	return fmt.Sprintf("Deleted %d phone OTPs and %d OTP text requests", otps.RowsAffected, sends.RowsAffected), nil
+}
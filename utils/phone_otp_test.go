@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/testutil"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSMSProvider keeps the last code texted to each number
+type recordingSMSProvider map[string]string
+
+func (recordingSMSProvider) Name() string { return "recording" }
+
+func (p recordingSMSProvider) Send(phone, message string) error {
+	p[phone] = strings.Fields(message)[0]
+	return nil
+}
+
+// recordSMS makes the SMS provider record the texts for the rest of the test
+func recordSMS(t *testing.T) recordingSMSProvider {
+	GetSMSProvider()
+	previous := smsProvider
+	texts := recordingSMSProvider{}
+	smsProvider = texts
+	t.Cleanup(func() { smsProvider = previous })
+	return texts
+}
+
+// requestFrom is a request context from the IP address, with no session
+func requestFrom(ip string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/auth/phone/send-otp", nil)
+	c.Request.RemoteAddr = ip + ":40000"
+	return c
+}
+
+func TestPhoneOTPKeepsOnlyAHash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t, &models.PhoneOTP{}, &models.PhoneOTPSend{})
+	texts := recordSMS(t)
+	const phone = "+919876543210"
+
+	otp, err := StartPhoneOTP(requestFrom("192.0.2.1"), OTPPurposePhoneLogin, phone, nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, otp.Sends)
+	code := texts[phone]
+	require.Len(t, code, 6)
+
+	var stored models.PhoneOTP
+	require.NoError(t, db.Where("purpose = ? AND phone = ?", OTPPurposePhoneLogin, phone).First(&stored).Error)
+	assert.Len(t, stored.CodeHash, 64)
+	assert.NotContains(t, stored.CodeHash, code)
+
+	assert.ErrorIs(t, CheckPhoneOTP(OTPPurposePhoneLogin, phone, nil, "000000x"), ErrOTPInvalid)
+	assert.ErrorIs(t, CheckPhoneOTP(OTPPurposePhoneVerify, phone, nil, code), ErrOTPNotStarted, "OTPs belong to their flow")
+	assert.NoError(t, CheckPhoneOTP(OTPPurposePhoneLogin, phone, nil, code))
+	assert.ErrorIs(t, CheckPhoneOTP(OTPPurposePhoneLogin, phone, nil, code), ErrOTPExpired, "an OTP works once")
+}
+
+func TestPhoneOTPLimitsPerNumber(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t, &models.PhoneOTP{}, &models.PhoneOTPSend{})
+	texts := recordSMS(t)
+	const phone = "+919876543211"
+	ageLastText := func(by time.Duration) {
+		require.NoError(t, db.Model(&models.PhoneOTP{}).Where("phone = ?", phone).
+			Update("sent_at", time.Now().Add(-by)).Error)
+	}
+
+	_, err := StartPhoneOTP(requestFrom("192.0.2.1"), OTPPurposePhoneLogin, phone, nil, true)
+	require.NoError(t, err)
+	otp, err := StartPhoneOTP(requestFrom("192.0.2.2"), OTPPurposePhoneLogin, phone, nil, true)
+	assert.ErrorIs(t, err, ErrOTPResendCooldown, "the cooldown holds for every client")
+	assert.InDelta(t, OTPResendCooldown.Seconds(), PhoneOTPRetryAfter(otp).Seconds(), 1)
+
+	for sends := 2; sends <= OTPMaxSends; sends++ {
+		ageLastText(OTPResendCooldown)
+		otp, err = StartPhoneOTP(requestFrom("192.0.2.3"), OTPPurposePhoneLogin, phone, nil, true)
+		require.NoError(t, err)
+		assert.Equal(t, sends, otp.Sends)
+	}
+	ageLastText(OTPResendCooldown)
+	otp, err = StartPhoneOTP(requestFrom("192.0.2.4"), OTPPurposePhoneLogin, phone, nil, true)
+	assert.ErrorIs(t, err, ErrOTPResendLimit)
+	assert.Greater(t, PhoneOTPRetryAfter(otp), 50*time.Minute)
+
+	require.NoError(t, db.Model(&models.PhoneOTP{}).Where("phone = ?", phone).
+		Update("window_started_at", time.Now().Add(-otpSendWindow)).Error)
+	otp, err = StartPhoneOTP(requestFrom("192.0.2.4"), OTPPurposePhoneLogin, phone, nil, true)
+	require.NoError(t, err, "the limit starts again after an hour")
+	assert.Equal(t, 1, otp.Sends)
+
+	code := texts[phone]
+	for attempt := 1; attempt < OTPMaxAttempts; attempt++ {
+		assert.ErrorIs(t, CheckPhoneOTP(OTPPurposePhoneLogin, phone, nil, "wrong"), ErrOTPInvalid)
+	}
+	assert.ErrorIs(t, CheckPhoneOTP(OTPPurposePhoneLogin, phone, nil, "wrong"), ErrOTPAttempts)
+	assert.ErrorIs(t, CheckPhoneOTP(OTPPurposePhoneLogin, phone, nil, code), ErrOTPAttempts, "the OTP is discarded")
+}
+
+func TestPhoneOTPLimitsPerIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testutil.NewDB(t, &models.PhoneOTP{}, &models.PhoneOTPSend{})
+	texts := recordSMS(t)
+
+	for i := 0; i < PhoneOTPMaxSendsPerIP; i++ {
+		phone := "+91987654" + strings.Repeat(string(rune('0'+i%10)), 4)
+		_, err := StartPhoneOTP(requestFrom("192.0.2.10"), OTPPurposePhoneLogin, phone, nil, i%2 == 0)
+		require.NoError(t, err, "text %d", i)
+	}
+	assert.Len(t, texts, PhoneOTPMaxSendsPerIP/2, "unregistered numbers count but get no text")
+
+	_, err := StartPhoneOTP(requestFrom("192.0.2.10"), OTPPurposePhoneLogin, "+919000000000", nil, true)
+	assert.ErrorIs(t, err, ErrOTPRateLimited)
+	assert.NotContains(t, texts, "+919000000000")
+	_, err = StartPhoneOTP(requestFrom("192.0.2.11"), OTPPurposePhoneLogin, "+919000000000", nil, true)
+	assert.NoError(t, err, "other addresses are not limited")
+}
+
+func TestPhoneOTPVerificationBelongsToTheUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testutil.NewDB(t, &models.PhoneOTP{}, &models.PhoneOTPSend{})
+	texts := recordSMS(t)
+	const phone = "+919876543212"
+	owner, other := uint(1), uint(2)
+
+	_, err := StartPhoneOTP(requestFrom("192.0.2.1"), OTPPurposePhoneVerify, phone, &owner, true)
+	require.NoError(t, err)
+	code := texts[phone]
+
+	assert.ErrorIs(t, CheckPhoneOTP(OTPPurposePhoneVerify, phone, &other, code), ErrOTPNotStarted)
+	assert.ErrorIs(t, CheckPhoneOTP(OTPPurposePhoneVerify, phone, nil, code), ErrOTPNotStarted)
+	assert.NoError(t, CheckPhoneOTP(OTPPurposePhoneVerify, phone, &owner, code))
+}
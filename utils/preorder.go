@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// PreorderOpen reports whether the book can be pre-ordered: it is marked for pre-order and
+// its release date has not arrived yet
+func PreorderOpen(book models.Book) bool {
+	return book.IsPreorder && book.ReleaseDate != nil && book.ReleaseDate.After(time.Now())
+}
+
+// AllocatePreorderItem takes a released pre-order item's copies from the book's stock. The
+// item is claimed first, so a concurrent cancellation cannot release it after allocation. It
+// reports false when the item is no longer waiting or the stock does not cover it yet; the
+// caller then rolls back. Must be called inside a transaction.
+func AllocatePreorderItem(tx *gorm.DB, item *models.OrderItem) (bool, error) {
+	claimed := tx.Model(&models.OrderItem{}).Where("id = ? AND preorder_pending = ?", item.ID, true).
+		UpdateColumn("preorder_pending", false)
+	if claimed.Error != nil || claimed.RowsAffected == 0 {
+		return false, claimed.Error
+	}
+
+	stock := tx.Model(&models.Book{}).Where("id = ? AND stock >= ?", item.BookID, item.Quantity).
+		UpdateColumn("stock", gorm.Expr("stock - ?", item.Quantity))
+	if stock.Error != nil || stock.RowsAffected == 0 {
+		return false, stock.Error
+	}
+	if err := RecordStockMovement(tx, OrderItemStockChange(item, -item.Quantity, models.StockSourcePreorder)); err != nil {
+		return false, err
+	}
+
+	item.PreorderPending = false
+	LogDebug("Allocated %d units of book %d to pre-order item %d", item.Quantity, item.BookID, item.ID)
+	return true, nil
+}
@@ -0,0 +1,427 @@
+package utils
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// Stock status labels shown for cart lines
+const (
+	StockStatusOutOfStock = "Out of Stock"
+	StockStatusFewLeft    = "Only a few left"
+	StockStatusInStock    = "In Stock"
+	StockStatusPreorder   = "Pre-order"
+)
+
+// CartLine is a priced cart item. Amounts are line totals unless named per unit.
+type CartLine struct {
+	CartItem         models.Cart
+	Book             models.Book
+	Quantity         int
+	Offer            OfferBreakdown
+	UnitPrice        float64 // list price of one copy
+	OfferUnitPrice   float64 // price of one copy after product and category offers
+	Subtotal         float64 // list price of all copies
+	ProductDiscount  float64
+	CategoryDiscount float64
+	CouponDiscount   float64
+	Total            float64 // payable after offers and coupon
+	Available        bool    // book is active, not blocked, its category is not blocked and stock covers the quantity
+	StockStatus      string
+	Bundle           *models.Bundle // set when the book is in the cart as part of a bundle
+}
+
+// OfferTotal is the line total after offers, before the coupon
+func (l CartLine) OfferTotal() float64 {
+	return roundMoney(l.Subtotal - l.ProductDiscount - l.CategoryDiscount)
+}
+
+// TotalDiscount is every discount applied to the line
+func (l CartLine) TotalDiscount() float64 {
+	return roundMoney(l.ProductDiscount + l.CategoryDiscount + l.CouponDiscount)
+}
+
+// FinalUnitPrice is the payable price of one copy after all discounts
+func (l CartLine) FinalUnitPrice() float64 {
+	if l.Quantity == 0 {
+		return 0
+	}
+	return roundMoney(l.Total / float64(l.Quantity))
+}
+
+// PriceLine prices quantity copies of a book with its offers. The coupon share is added
+// later by ApplyCoupon.
+func PriceLine(book models.Book, quantity int, offer OfferBreakdown) CartLine {
+	qty := float64(quantity)
+	line := CartLine{
+		Book:             book,
+		Quantity:         quantity,
+		Offer:            offer,
+		UnitPrice:        book.Price,
+		Subtotal:         roundMoney(book.Price * qty),
+		ProductDiscount:  roundMoney(book.Price * offer.ProductOfferPercent / 100 * qty),
+		CategoryDiscount: roundMoney(book.Price * offer.CategoryOfferPercent / 100 * qty),
+		OfferUnitPrice:   roundMoney(ApplyOfferToPrice(book.Price, offer.ProductOfferPercent+offer.CategoryOfferPercent)),
+	}
+	line.Total = line.OfferTotal()
+	line.Available = book.IsActive && !book.Blocked && !book.Category.Blocked && book.Stock >= quantity
+	switch {
+	case PreorderOpen(book):
+		// Pre-orders take no stock until the book is released
+		line.Available = book.IsActive && !book.Blocked && !book.Category.Blocked
+		line.StockStatus = StockStatusPreorder
+	case book.Stock < quantity:
+		line.StockStatus = StockStatusOutOfStock
+	case book.Stock <= 3:
+		line.StockStatus = StockStatusFewLeft
+	default:
+		line.StockStatus = StockStatusInStock
+	}
+	return line
+}
+
+// IsDigitalOnly reports whether every line is a digital book, so nothing needs shipping
+func (d *CartDetails) IsDigitalOnly() bool {
+	if len(d.Lines) == 0 {
+		return false
+	}
+	for _, line := range d.Lines {
+		if !line.Book.IsDigital {
+			return false
+		}
+	}
+	return true
+}
+
+// CalculateCouponDiscount returns the discount a coupon gives on the cart subtotal,
+// capped at the coupon's maximum discount for percent coupons
+func CalculateCouponDiscount(coupon models.Coupon, subtotal float64) float64 {
+	if coupon.Type == "percent" {
+		discount := (subtotal * coupon.Value) / 100
+		if coupon.MaxDiscount > 0 && discount > coupon.MaxDiscount {
+			discount = coupon.MaxDiscount
+		}
+		return roundMoney(discount)
+	}
+	return roundMoney(coupon.Value)
+}
+
+// OfferTotal is the cart total after offers, before any coupon
+func (d *CartDetails) OfferTotal() float64 {
+	return roundMoney(d.Subtotal - d.ProductDiscount - d.CategoryDiscount)
+}
+
+// CouponCoversBook reports whether the coupon's category and book restrictions allow it on
+// the book. A coupon without restrictions covers every book.
+func CouponCoversBook(coupon models.Coupon, book models.Book) bool {
+	if len(coupon.Categories) == 0 && len(coupon.Books) == 0 {
+		return true
+	}
+	for _, category := range coupon.Categories {
+		if category.ID == book.CategoryID {
+			return true
+		}
+	}
+	for _, restricted := range coupon.Books {
+		if restricted.ID == book.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// CouponSubtotal returns the list price and the total after offers of the lines the coupon
+// covers
+func (d *CartDetails) CouponSubtotal(coupon models.Coupon) (subtotal, offerTotal float64) {
+	for _, line := range d.Lines {
+		if CouponCoversBook(coupon, line.Book) {
+			subtotal += line.Subtotal
+			offerTotal += line.OfferTotal()
+		}
+	}
+	return roundMoney(subtotal), roundMoney(offerTotal)
+}
+
+// CouponDiscountFor returns the discount the coupon would give on this cart. Only the lines
+// the coupon covers count towards it.
+func (d *CartDetails) CouponDiscountFor(coupon models.Coupon) float64 {
+	subtotal, offerTotal := d.CouponSubtotal(coupon)
+	if subtotal <= 0 {
+		return 0
+	}
+	return math.Min(CalculateCouponDiscount(coupon, subtotal), offerTotal)
+}
+
+// ApplyCoupon sets the coupon discount of the cart and spreads it per copy over the lines the
+// coupon covers. The discount is calculated on their subtotal and never exceeds their total
+// after offers. A nil coupon removes any coupon discount.
+func (d *CartDetails) ApplyCoupon(coupon *models.Coupon) {
+	d.Coupon = coupon
+	d.CouponCode = ""
+	d.CouponDiscount = 0
+	d.CouponDiscountPerUnit = 0
+	for i := range d.Lines {
+		d.Lines[i].CouponDiscount = 0
+	}
+
+	if coupon != nil && d.TotalQuantity > 0 {
+		d.CouponCode = coupon.Code
+		var covered []int
+		coveredQuantity := 0
+		for i, line := range d.Lines {
+			if CouponCoversBook(*coupon, line.Book) {
+				covered = append(covered, i)
+				coveredQuantity += line.Quantity
+			}
+		}
+
+		if coveredQuantity > 0 {
+			d.CouponDiscount = d.CouponDiscountFor(*coupon)
+			d.CouponDiscountPerUnit = d.CouponDiscount / float64(coveredQuantity)
+
+			// Round each share and give the rounding remainder to the last covered line so
+			// the shares add up to the cart's coupon discount exactly
+			remaining := d.CouponDiscount
+			for n, i := range covered {
+				share := roundMoney(d.CouponDiscountPerUnit * float64(d.Lines[i].Quantity))
+				if n == len(covered)-1 {
+					share = roundMoney(remaining)
+				}
+				d.Lines[i].CouponDiscount = share
+				remaining -= share
+			}
+		}
+	}
+
+	for i := range d.Lines {
+		d.Lines[i].Total = roundMoney(d.Lines[i].OfferTotal() - d.Lines[i].CouponDiscount)
+	}
+	d.TotalDiscount = roundMoney(d.ProductDiscount + d.CategoryDiscount + d.CouponDiscount)
+	d.FinalTotal = roundMoney(d.Subtotal - d.TotalDiscount)
+	d.OrderItems = d.orderItems()
+}
+
+// orderItems converts the priced lines to order items for checkout
+func (d *CartDetails) orderItems() []models.OrderItem {
+	items := make([]models.OrderItem, 0, len(d.Lines))
+	for _, line := range d.Lines {
+		item := models.OrderItem{
+			BookID:         line.Book.ID,
+			Book:           line.Book,
+			Quantity:       line.Quantity,
+			Price:          line.UnitPrice,
+			Discount:       roundMoney(line.ProductDiscount + line.CategoryDiscount),
+			Total:          line.OfferTotal(),
+			CouponDiscount: line.CouponDiscount,
+		}
+		if line.Bundle != nil {
+			bundleID := line.Bundle.ID
+			item.BundleID = &bundleID
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// PricingEngine prices carts. Cart, coupon and checkout handlers all use it so they
+// report the same amounts.
+type PricingEngine struct {
+	db *gorm.DB
+}
+
+// NewPricingEngine returns a pricing engine reading from db
+func NewPricingEngine(db *gorm.DB) *PricingEngine {
+	return &PricingEngine{db: db}
+}
+
+// PriceCart prices the user's cart with the coupon the user has applied. Without one, the
+// auto-apply coupon giving the biggest discount is applied, unless the user removed an
+// auto-applied coupon; the other auto-apply coupons the cart qualifies for are reported as
+// alternatives.
+func (e *PricingEngine) PriceCart(userID uint) (*CartDetails, error) {
+	coupon, autoApply, err := e.activeCoupon(userID)
+	if err != nil {
+		return nil, err
+	}
+	details, err := e.PriceCartWithCoupon(userID, coupon)
+	if err != nil || len(details.Lines) == 0 {
+		return details, err
+	}
+
+	candidates, err := e.autoApplyCoupons(userID, details)
+	if err != nil {
+		return nil, err
+	}
+	if coupon == nil && autoApply && len(candidates) > 0 {
+		details.ApplyCoupon(candidates[0])
+		details.CouponAutoApplied = true
+	}
+	for _, candidate := range candidates {
+		if details.Coupon != nil && candidate.ID == details.Coupon.ID {
+			continue
+		}
+		discount := details.CouponDiscountFor(*candidate)
+		details.CouponAlternatives = append(details.CouponAlternatives, CouponAlternative{
+			Code:       candidate.Code,
+			Discount:   discount,
+			FinalTotal: roundMoney(details.OfferTotal() - discount),
+		})
+	}
+	return details, nil
+}
+
+// autoApplyCoupons returns the auto-apply coupons the user can use on the priced cart, the
+// biggest discount first
+func (e *PricingEngine) autoApplyCoupons(userID uint, details *CartDetails) ([]*models.Coupon, error) {
+	var coupons []models.Coupon
+	if err := e.db.Preload("Categories").Preload("Books").
+		Where("auto_apply = ? AND active = ? AND expiry > ?", true, true, time.Now()).
+		Order("id").Find(&coupons).Error; err != nil {
+		return nil, err
+	}
+	if len(coupons) == 0 {
+		return nil, nil
+	}
+	var user models.User
+	if err := e.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var candidates []*models.Coupon
+	discounts := make(map[uint]float64)
+	for i := range coupons {
+		if len(CheckCouponEligibility(user, coupons[i], details)) > 0 {
+			continue
+		}
+		discount := details.CouponDiscountFor(coupons[i])
+		if discount <= 0 {
+			continue
+		}
+		discounts[coupons[i].ID] = discount
+		candidates = append(candidates, &coupons[i])
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return discounts[candidates[i].ID] > discounts[candidates[j].ID]
+	})
+	return candidates, nil
+}
+
+// PriceCartWithCoupon prices the user's cart, including its bundles, with the given coupon,
+// or none when nil
+func (e *PricingEngine) PriceCartWithCoupon(userID uint, coupon *models.Coupon) (*CartDetails, error) {
+	var cartItems []models.Cart
+	if err := e.db.Where("user_id = ?", userID).Order("id").Find(&cartItems).Error; err != nil {
+		return nil, err
+	}
+	var cartBundles []models.CartBundle
+	if err := e.db.Where("user_id = ?", userID).Order("id").Find(&cartBundles).Error; err != nil {
+		return nil, err
+	}
+	return e.PriceWithBundles(cartItems, cartBundles, coupon)
+}
+
+// Price prices cart items with their current offers and the coupon. Items whose book no
+// longer exists are left out.
+func (e *PricingEngine) Price(cartItems []models.Cart, coupon *models.Coupon) (*CartDetails, error) {
+	return e.PriceWithBundles(cartItems, nil, coupon)
+}
+
+// PriceWithBundles prices cart items and cart bundles together with the coupon. Bundles that
+// no longer exist are left out like missing books.
+func (e *PricingEngine) PriceWithBundles(cartItems []models.Cart, cartBundles []models.CartBundle, coupon *models.Coupon) (*CartDetails, error) {
+	details := &CartDetails{CanCheckout: true}
+	addLine := func(line CartLine) {
+		details.Lines = append(details.Lines, line)
+		details.Subtotal += line.Subtotal
+		details.ProductDiscount += line.ProductDiscount
+		details.CategoryDiscount += line.CategoryDiscount
+		details.TotalQuantity += line.Quantity
+		if !line.Available {
+			details.CanCheckout = false
+		}
+	}
+
+	// Books, offers and bundles are each loaded in one query, not one per cart line
+	bookIDs := make([]uint, len(cartItems))
+	for i, item := range cartItems {
+		bookIDs[i] = item.BookID
+	}
+	var books []models.Book
+	if len(bookIDs) > 0 {
+		if err := e.db.Preload("Category").Where("id IN ?", bookIDs).Find(&books).Error; err != nil {
+			return nil, err
+		}
+	}
+	booksByID := make(map[uint]models.Book, len(books))
+	for _, book := range books {
+		booksByID[book.ID] = book
+	}
+	offers, err := LoadOfferBreakdowns(e.db, books, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range cartItems {
+		book, ok := booksByID[item.BookID]
+		if !ok {
+			continue
+		}
+		line := PriceLine(book, item.Quantity, offers[book.ID])
+		line.CartItem = item
+		addLine(line)
+	}
+
+	bundleIDs := make([]uint, len(cartBundles))
+	for i, cartBundle := range cartBundles {
+		bundleIDs[i] = cartBundle.BundleID
+	}
+	bundles, err := LoadBundles(e.db, bundleIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, cartBundle := range cartBundles {
+		bundle, ok := bundles[cartBundle.BundleID]
+		if !ok {
+			continue
+		}
+		for _, line := range PriceBundleLines(bundle, cartBundle.Quantity) {
+			addLine(line)
+		}
+	}
+	details.Subtotal = roundMoney(details.Subtotal)
+	details.ProductDiscount = roundMoney(details.ProductDiscount)
+	details.CategoryDiscount = roundMoney(details.CategoryDiscount)
+	if len(details.Lines) == 0 {
+		details.CanCheckout = false
+	}
+
+	details.ApplyCoupon(coupon)
+	return details, nil
+}
+
+// activeCoupon returns the coupon the user has applied to their cart, or nil, and whether a
+// coupon may be auto-applied instead
+func (e *PricingEngine) activeCoupon(userID uint) (*models.Coupon, bool, error) {
+	var active models.UserActiveCoupon
+	if err := e.db.Where("user_id = ?", userID).First(&active).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+	if active.AutoApplyDisabled {
+		return nil, false, nil
+	}
+	var coupon models.Coupon
+	if err := e.db.Preload("Categories").Preload("Books").First(&coupon, active.CouponID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+	return &coupon, false, nil
+}
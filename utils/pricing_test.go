@@ -0,0 +1,308 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// pricedCart adds up the lines the way the pricing engine does before a coupon is applied
+func pricedCart(lines ...CartLine) *CartDetails {
+	details := &CartDetails{CanCheckout: len(lines) > 0}
+	for _, line := range lines {
+		details.Lines = append(details.Lines, line)
+		details.Subtotal += line.Subtotal
+		details.ProductDiscount += line.ProductDiscount
+		details.CategoryDiscount += line.CategoryDiscount
+		details.TotalQuantity += line.Quantity
+		details.CanCheckout = details.CanCheckout && line.Available
+	}
+	details.Subtotal = roundMoney(details.Subtotal)
+	details.ProductDiscount = roundMoney(details.ProductDiscount)
+	details.CategoryDiscount = roundMoney(details.CategoryDiscount)
+	return details
+}
+
+func pricingBook(id uint, price float64, stock int, categoryID uint) models.Book {
+	book := models.Book{Name: "Pricing Test", Price: price, Stock: stock, IsActive: true, CategoryID: categoryID}
+	book.ID = id
+	book.Category.ID = categoryID
+	return book
+}
+
+func TestPriceLine(t *testing.T) {
+	released := time.Now().Add(30 * 24 * time.Hour)
+	preorder := pricingBook(1, 300, 0, 1)
+	preorder.IsPreorder = true
+	preorder.ReleaseDate = &released
+	blocked := pricingBook(1, 100, 10, 1)
+	blocked.Category.Blocked = true
+
+	tests := []struct {
+		name                               string
+		book                               models.Book
+		quantity                           int
+		offer                              OfferBreakdown
+		subtotal, product, category, total float64
+		offerUnitPrice                     float64
+		available                          bool
+		stockStatus                        string
+	}{
+		{"no offers", pricingBook(1, 199.99, 10, 1), 3, OfferBreakdown{},
+			599.97, 0, 0, 599.97, 199.99, true, StockStatusInStock},
+		{"product and category offers stack", pricingBook(1, 100, 10, 1), 2, OfferBreakdown{ProductOfferPercent: 10, CategoryOfferPercent: 5},
+			200, 20, 10, 170, 85, true, StockStatusInStock},
+		{"discounts round to the paisa", pricingBook(1, 33.33, 10, 1), 3, OfferBreakdown{ProductOfferPercent: 15},
+			99.99, 15, 0, 84.99, 28.33, true, StockStatusInStock},
+		{"few left", pricingBook(1, 100, 3, 1), 1, OfferBreakdown{},
+			100, 0, 0, 100, 100, true, StockStatusFewLeft},
+		{"stock below quantity", pricingBook(1, 100, 1, 1), 2, OfferBreakdown{},
+			200, 0, 0, 200, 100, false, StockStatusOutOfStock},
+		{"blocked category", blocked, 1, OfferBreakdown{},
+			100, 0, 0, 100, 100, false, StockStatusInStock},
+		{"pre-order takes no stock", preorder, 2, OfferBreakdown{},
+			600, 0, 0, 600, 300, true, StockStatusPreorder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := PriceLine(tt.book, tt.quantity, tt.offer)
+			assert.Equal(t, tt.subtotal, line.Subtotal, "subtotal")
+			assert.Equal(t, tt.product, line.ProductDiscount, "product discount")
+			assert.Equal(t, tt.category, line.CategoryDiscount, "category discount")
+			assert.Equal(t, tt.total, line.Total, "total")
+			assert.Equal(t, tt.offerUnitPrice, line.OfferUnitPrice, "offer unit price")
+			assert.Equal(t, tt.available, line.Available, "available")
+			assert.Equal(t, tt.stockStatus, line.StockStatus, "stock status")
+		})
+	}
+}
+
+func TestCalculateCouponDiscount(t *testing.T) {
+	tests := []struct {
+		name     string
+		coupon   models.Coupon
+		subtotal float64
+		want     float64
+	}{
+		{"flat", models.Coupon{Type: "flat", Value: 50}, 400, 50},
+		{"flat rounds to the paisa", models.Coupon{Type: "flat", Value: 49.999}, 400, 50},
+		{"flat is not capped by the subtotal here", models.Coupon{Type: "flat", Value: 500}, 100, 500},
+		{"percent without a cap", models.Coupon{Type: "percent", Value: 10}, 400, 40},
+		{"percent below the cap", models.Coupon{Type: "percent", Value: 20, MaxDiscount: 150}, 500, 100},
+		{"percent at the cap", models.Coupon{Type: "percent", Value: 20, MaxDiscount: 150}, 750, 150},
+		{"percent above the cap", models.Coupon{Type: "percent", Value: 20, MaxDiscount: 150}, 1000, 150},
+		{"percent rounds to the paisa", models.Coupon{Type: "percent", Value: 12.5}, 99.99, 12.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CalculateCouponDiscount(tt.coupon, tt.subtotal))
+		})
+	}
+}
+
+func TestApplyCoupon(t *testing.T) {
+	categoryOne := models.Category{}
+	categoryOne.ID = 1
+
+	tests := []struct {
+		name           string
+		lines          []CartLine
+		coupon         *models.Coupon
+		couponDiscount float64
+		lineDiscounts  []float64
+		finalTotal     float64
+	}{
+		{"no coupon",
+			[]CartLine{PriceLine(pricingBook(1, 100, 10, 1), 2, OfferBreakdown{ProductOfferPercent: 10})},
+			nil, 0, []float64{0}, 180},
+		{"rounding remainder goes to the last covered line",
+			[]CartLine{
+				PriceLine(pricingBook(1, 100, 10, 1), 1, OfferBreakdown{}),
+				PriceLine(pricingBook(2, 100, 10, 1), 1, OfferBreakdown{}),
+				PriceLine(pricingBook(3, 100, 10, 1), 1, OfferBreakdown{}),
+			},
+			&models.Coupon{Code: "TEN", Type: "flat", Value: 10}, 10, []float64{3.33, 3.33, 3.34}, 290},
+		{"shares follow the quantity",
+			[]CartLine{
+				PriceLine(pricingBook(1, 100, 10, 1), 3, OfferBreakdown{}),
+				PriceLine(pricingBook(2, 100, 10, 1), 1, OfferBreakdown{}),
+			},
+			&models.Coupon{Code: "FORTY", Type: "flat", Value: 40}, 40, []float64{30, 10}, 360},
+		{"percent is taken on the list price, not the offer price",
+			[]CartLine{PriceLine(pricingBook(1, 100, 10, 1), 2, OfferBreakdown{ProductOfferPercent: 10})},
+			&models.Coupon{Code: "TENPC", Type: "percent", Value: 10}, 20, []float64{20}, 160},
+		{"percent is capped at the maximum discount",
+			[]CartLine{PriceLine(pricingBook(1, 500, 10, 1), 2, OfferBreakdown{})},
+			&models.Coupon{Code: "HALF", Type: "percent", Value: 50, MaxDiscount: 100}, 100, []float64{100}, 900},
+		{"discount never exceeds the total after offers",
+			[]CartLine{PriceLine(pricingBook(1, 100, 10, 1), 2, OfferBreakdown{ProductOfferPercent: 10, CategoryOfferPercent: 5})},
+			&models.Coupon{Code: "BIG", Type: "flat", Value: 500}, 170, []float64{170}, 0},
+		{"restricted coupon covers only its category",
+			[]CartLine{
+				PriceLine(pricingBook(1, 200, 10, 1), 1, OfferBreakdown{}),
+				PriceLine(pricingBook(2, 300, 10, 2), 1, OfferBreakdown{}),
+			},
+			&models.Coupon{Code: "CAT", Type: "percent", Value: 10, Categories: []models.Category{categoryOne}}, 20, []float64{20, 0}, 480},
+		{"restricted coupon covering nothing gives nothing",
+			[]CartLine{PriceLine(pricingBook(2, 300, 10, 2), 1, OfferBreakdown{})},
+			&models.Coupon{Code: "CAT", Type: "flat", Value: 50, Categories: []models.Category{categoryOne}}, 0, []float64{0}, 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := pricedCart(tt.lines...)
+			details.ApplyCoupon(tt.coupon)
+
+			assert.Equal(t, tt.couponDiscount, details.CouponDiscount, "coupon discount")
+			assert.Equal(t, tt.finalTotal, details.FinalTotal, "final total")
+			require.Len(t, details.OrderItems, len(tt.lines))
+			var lineTotals float64
+			for i, line := range details.Lines {
+				assert.Equal(t, tt.lineDiscounts[i], line.CouponDiscount, "coupon share of line %d", i)
+				assert.Equal(t, line.CouponDiscount, details.OrderItems[i].CouponDiscount, "order item %d", i)
+				lineTotals += line.Total
+			}
+			assert.Equal(t, tt.finalTotal, roundMoney(lineTotals), "line totals add up to the final total")
+		})
+	}
+}
+
+func TestApplyCouponReplacesEarlierCoupon(t *testing.T) {
+	details := pricedCart(PriceLine(pricingBook(1, 100, 10, 1), 2, OfferBreakdown{}))
+	details.ApplyCoupon(&models.Coupon{Code: "FIFTY", Type: "flat", Value: 50})
+	require.Equal(t, 150.0, details.FinalTotal)
+
+	details.ApplyCoupon(nil)
+	assert.Equal(t, "", details.CouponCode)
+	assert.Equal(t, 0.0, details.CouponDiscount)
+	assert.Equal(t, 0.0, details.Lines[0].CouponDiscount)
+	assert.Equal(t, 200.0, details.FinalTotal)
+}
+
+func TestCheckCouponEligibilityMinimumOrder(t *testing.T) {
+	testutil.NewDB(t, &models.Order{})
+	categoryOne := models.Category{}
+	categoryOne.ID = 1
+	coupon := func(categories ...models.Category) models.Coupon {
+		return models.Coupon{Code: "MIN500", Type: "flat", Value: 50, MinOrderValue: 500, Active: true,
+			Expiry: time.Now().Add(24 * time.Hour), UsageLimit: 10, Categories: categories}
+	}
+
+	tests := []struct {
+		name    string
+		coupon  models.Coupon
+		lines   []CartLine
+		reasons []string
+	}{
+		{"just below the minimum", coupon(),
+			[]CartLine{PriceLine(pricingBook(1, 499.99, 10, 1), 1, OfferBreakdown{})},
+			[]string{CouponReasonMinOrderNotMet}},
+		{"exactly the minimum", coupon(),
+			[]CartLine{PriceLine(pricingBook(1, 250, 10, 1), 2, OfferBreakdown{})}, nil},
+		{"minimum is on the list price before offers", coupon(),
+			[]CartLine{PriceLine(pricingBook(1, 500, 10, 1), 1, OfferBreakdown{ProductOfferPercent: 20})}, nil},
+		{"only covered books count towards the minimum", coupon(categoryOne),
+			[]CartLine{
+				PriceLine(pricingBook(1, 300, 10, 1), 1, OfferBreakdown{}),
+				PriceLine(pricingBook(2, 300, 10, 2), 1, OfferBreakdown{}),
+			},
+			[]string{CouponReasonMinOrderNotMet}},
+		{"empty cart", coupon(), nil, []string{CouponReasonEmptyCart}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := models.User{Model: gorm.Model{ID: 1}}
+			var reasons []string
+			for _, reason := range CheckCouponEligibility(user, tt.coupon, pricedCart(tt.lines...)) {
+				reasons = append(reasons, reason.Code)
+			}
+			assert.Equal(t, tt.reasons, reasons)
+		})
+	}
+}
+
+// countQueries counts the SELECT queries run on db from now on
+func countQueries(t *testing.T, db *gorm.DB) *int {
+	t.Helper()
+	count := new(int)
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("test:count_queries", func(*gorm.DB) { *count++ }))
+	return count
+}
+
+// seedPricedCart stores books across categories with product and category offers and a
+// bundle, and returns cart items for the books and a cart bundle
+func seedPricedCart(t *testing.T, db *gorm.DB, books int) ([]models.Cart, []models.CartBundle) {
+	t.Helper()
+	now := time.Now()
+	var cartItems []models.Cart
+	var bundleItems []models.BundleItem
+	for i := 0; i < books; i++ {
+		category := models.Category{Name: fmt.Sprintf("Category %d", i)}
+		require.NoError(t, db.Create(&category).Error)
+		book := models.Book{Name: fmt.Sprintf("Book %d", i), Price: 100, Stock: 10, IsActive: true,
+			CategoryID: category.ID, ISBN: fmt.Sprintf("isbn-%d", i)}
+		require.NoError(t, db.Create(&book).Error)
+		require.NoError(t, db.Create(&models.ProductOffer{ProductID: book.ID, DiscountPercent: 10,
+			StartDate: now.Add(-time.Hour), EndDate: now.Add(time.Hour), Active: true}).Error)
+		require.NoError(t, db.Create(&models.CategoryOffer{CategoryID: category.ID, DiscountPercent: 5,
+			StartDate: now.Add(-time.Hour), EndDate: now.Add(time.Hour), Active: true}).Error)
+		cartItems = append(cartItems, models.Cart{BookID: book.ID, Quantity: 1})
+		bundleItems = append(bundleItems, models.BundleItem{BookID: book.ID, Quantity: 1})
+	}
+	bundle := models.Bundle{Name: "Everything", Price: float64(80 * books), IsActive: true, Items: bundleItems}
+	require.NoError(t, db.Create(&bundle).Error)
+	return cartItems, []models.CartBundle{{BundleID: bundle.ID, Quantity: 1}}
+}
+
+var pricingTables = []interface{}{
+	&models.Category{}, &models.Book{}, &models.ProductOffer{}, &models.CategoryOffer{},
+	&models.OfferRules{}, &models.Bundle{}, &models.BundleItem{},
+}
+
+func TestPriceWithBundlesQueryCount(t *testing.T) {
+	queries := make(map[int]int)
+	for _, books := range []int{2, 20} {
+		db := testutil.NewDB(t, pricingTables...)
+		cartItems, cartBundles := seedPricedCart(t, db, books)
+		engine := NewPricingEngine(db)
+		_, err := engine.PriceWithBundles(cartItems, cartBundles, nil) // loads the cached offer rules
+		require.NoError(t, err)
+
+		count := countQueries(t, db)
+		details, err := engine.PriceWithBundles(cartItems, cartBundles, nil)
+		require.NoError(t, err)
+		require.Len(t, details.Lines, 2*books)
+		queries[books] = *count
+	}
+	assert.Equal(t, queries[2], queries[20], "the queries do not grow with the cart")
+	// Books with their categories, product offers, category offers, and bundles with their
+	// items, books and categories
+	assert.LessOrEqual(t, queries[20], 8)
+}
+
+func TestPriceWithBundlesReadsOffersFromEngineDB(t *testing.T) {
+	db := testutil.NewDB(t, pricingTables...)
+	cartItems, _ := seedPricedCart(t, db, 1)
+	testutil.NewDB(t) // config.DB now has no offers, or even tables
+
+	details, err := NewPricingEngine(db).PriceWithBundles(cartItems, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, details.Lines, 1)
+	assert.Equal(t, 10.0, details.ProductDiscount)
+	assert.Equal(t, 5.0, details.CategoryDiscount)
+}
+
+func TestPriceWithBundlesReturnsOfferError(t *testing.T) {
+	db := testutil.NewDB(t, pricingTables...)
+	cartItems, _ := seedPricedCart(t, db, 1)
+	require.NoError(t, db.Migrator().DropTable(&models.CategoryOffer{}))
+
+	details, err := NewPricingEngine(db).PriceWithBundles(cartItems, nil, nil)
+	assert.Error(t, err, "a cart is not priced without its offers")
+	assert.Nil(t, details)
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics are registered with the default Prometheus registry, which also carries the Go
+// runtime and process collectors, and are exposed at /metrics.
+
+// defaultLatencyBuckets are the request duration histogram buckets, in seconds
+var defaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// HTTP metrics, recorded by RequestMetricsMiddleware
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served, by method, route and status.",
+	}, []string{"method", "route", "status"})
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds, by method and route.",
+		Buckets: defaultLatencyBuckets,
+	}, []string{"method", "route"})
+	httpRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being served.",
+	})
+)
+
+// Business metrics
+var (
+	ordersPlacedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "readsphere_orders_placed_total",
+		Help: "Orders placed, by payment method.",
+	}, []string{"payment_method"})
+	paymentsFailedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "readsphere_payments_failed_total",
+		Help: "Failed payment verifications, by payment purpose.",
+	}, []string{"purpose"})
+	refundsIssuedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "readsphere_refunds_issued_total",
+		Help: "Refunds credited to wallets, by reason.",
+	}, []string{"reason"})
+	refundAmountTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "readsphere_refund_amount_total",
+		Help: "Amount refunded to wallets in the base currency, by reason.",
+	}, []string{"reason"})
+	otpsSentTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "readsphere_otps_sent_total",
+		Help: "OTP emails sent.",
+	})
+)
+
+// RecordOrderPlaced counts a placed order
+func RecordOrderPlaced(paymentMethod string) {
+	ordersPlacedTotal.WithLabelValues(paymentMethod).Inc()
+}
+
+// RecordPaymentFailed counts a failed payment; purpose is "order", "wallet_topup" or "gift_card"
+func RecordPaymentFailed(purpose string) {
+	paymentsFailedTotal.WithLabelValues(purpose).Inc()
+}
+
+// RecordRefundIssued counts a refund credited to a wallet; reason is e.g. "cancellation" or "return"
+func RecordRefundIssued(reason string, amount float64) {
+	refundsIssuedTotal.WithLabelValues(reason).Inc()
+	refundAmountTotal.WithLabelValues(reason).Add(amount)
+}
+
+// RecordOTPSent counts a sent OTP email
+func RecordOTPSent() {
+	otpsSentTotal.Inc()
+}
+
+// metricsHandler serves the default registry
+var metricsHandler = promhttp.Handler()
+
+// MetricsHandler serves all registered metrics in the Prometheus exposition format. When
+// METRICS_TOKEN is set, scrapers must send it as a bearer token.
+func MetricsHandler(c *gin.Context) {
+	if token := os.Getenv("METRICS_TOKEN"); token != "" && c.GetHeader("Authorization") != "Bearer "+token {
+		Unauthorized(c, "Invalid metrics token")
+		return
+	}
+	metricsHandler.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rateLimitRetention is how long counters are kept after their window starts; no limit uses a
+// longer window
+const rateLimitRetention = 24 * time.Hour
+
+// HitRateLimit counts a request under the key and reports whether it is within limit requests per
+// window and, when it is not, how long until the window starts again
+func HitRateLimit(key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	now := time.Now()
+	cutoff := now.Add(-window)
+	err := config.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":             gorm.Expr("CASE WHEN rate_limit_counters.window_started_at > ? THEN rate_limit_counters.count + 1 ELSE 1 END", cutoff),
+			"window_started_at": gorm.Expr("CASE WHEN rate_limit_counters.window_started_at > ? THEN rate_limit_counters.window_started_at ELSE ? END", cutoff, now),
+		}),
+	}).Create(&models.RateLimitCounter{Key: key, Count: 1, WindowStartedAt: now}).Error
+	if err != nil {
+		return false, 0, err
+	}
+
+	var counter models.RateLimitCounter
+	if err := config.DB.Where("key = ?", key).First(&counter).Error; err != nil {
+		return false, 0, err
+	}
+	if counter.Count <= limit {
+		return true, 0, nil
+	}
+	return false, counter.WindowStartedAt.Add(window).Sub(now), nil
+}
+
+// RateLimited counts a request under the key and, once there are more than limit per window,
+// sends a too many requests response and reports true. Requests are let through when the
+// count cannot be kept.
+func RateLimited(c *gin.Context, key string, limit int, window time.Duration) bool {
+	allowed, retryAfter, err := HitRateLimit(key, limit, window)
+	if err != nil {
+		LogError("Failed to count request for %s: %v", key, err)
+		return false
+	}
+	if allowed {
+		return false
+	}
+	seconds := int(retryAfter.Seconds()) + 1
+	LogError("Rate limit reached for %s, retry in %ds", key, seconds)
+	c.Header("Retry-After", fmt.Sprint(seconds))
+	Fail(c, CodeTooManyRequests, "Too many requests, please try again later", gin.H{
+		"retry_after": seconds,
+	})
+	return true
+}
+
+// RateLimitMiddleware allows each client IP limit requests per window to the routes it guards,
+// counted under name
+func RateLimitMiddleware(name string, limit int, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if RateLimited(c, name+":ip:"+c.ClientIP(), limit, window) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CleanupRateLimitCounters deletes counters whose window has long passed
+func CleanupRateLimitCounters() (string, error) {
+	result := config.DB.Where("window_started_at < ?", time.Now().Add(-rateLimitRetention)).Delete(&models.RateLimitCounter{})
+	if result.Error != nil {
+		return "", result.Error
+	}
+	return fmt.Sprintf("Deleted %d rate limit counters", result.RowsAffected), nil
+}
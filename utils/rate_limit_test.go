@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHitRateLimitCountsPerKeyAndWindow(t *testing.T) {
+	db := testutil.NewDB(t, &models.RateLimitCounter{})
+	const key, limit, window = "login:ip:203.0.113.7", 3, time.Minute
+
+	for i := 0; i < limit; i++ {
+		allowed, _, err := HitRateLimit(key, limit, window)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, retryAfter, err := HitRateLimit(key, limit, window)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.InDelta(t, window.Seconds(), retryAfter.Seconds(), 2)
+
+	allowed, _, err = HitRateLimit("login:ip:203.0.113.8", limit, window)
+	require.NoError(t, err)
+	assert.True(t, allowed, "each key has its own count")
+
+	require.NoError(t, db.Model(&models.RateLimitCounter{}).Where("key = ?", key).
+		Update("window_started_at", time.Now().Add(-window)).Error)
+	allowed, _, err = HitRateLimit(key, limit, window)
+	require.NoError(t, err)
+	assert.True(t, allowed, "the count starts again with a new window")
+
+	var counter models.RateLimitCounter
+	require.NoError(t, db.Where("key = ?", key).First(&counter).Error)
+	assert.Equal(t, 1, counter.Count)
+}
@@ -0,0 +1,347 @@
+package utils
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+)
+
+// Recommendation is a book suggested to a user, with a relevance score and why it was picked
+type Recommendation struct {
+	BookID  uint
+	Score   float64
+	Reasons []string
+}
+
+// RecommendationStrategy produces ranked book recommendations for a user. Implement it to
+// plug in a different model and install it with SetRecommendationStrategy.
+type RecommendationStrategy interface {
+	Name() string
+	Recommend(userID uint, limit int) ([]Recommendation, error)
+}
+
+// recommender is the strategy behind GetRecommendations
+var recommender RecommendationStrategy = NewDefaultRecommender()
+
+// SetRecommendationStrategy replaces the strategy used for user recommendations
+func SetRecommendationStrategy(strategy RecommendationStrategy) {
+	recommender = strategy
+	LogInfo("Recommendation strategy set to %s", strategy.Name())
+}
+
+// GetRecommendations returns up to limit recommendations for the user from the current strategy
+func GetRecommendations(userID uint, limit int) ([]Recommendation, string, error) {
+	recs, err := recommender.Recommend(userID, limit)
+	return recs, recommender.Name(), err
+}
+
+// WeightedStrategy is a strategy and its share of a blended score
+type WeightedStrategy struct {
+	Strategy RecommendationStrategy
+	Weight   float64
+}
+
+// BlendedRecommender combines several strategies. Each strategy's scores are normalized to
+// 0..1 and weighted, books the user already bought or has in their cart are left out, and
+// only books that are on sale and in stock are kept.
+type BlendedRecommender struct {
+	Strategies []WeightedStrategy
+}
+
+// NewDefaultRecommender blends purchase history, wishlist/cart/browsing interest and best sellers
+func NewDefaultRecommender() *BlendedRecommender {
+	return &BlendedRecommender{Strategies: []WeightedStrategy{
+		{Strategy: PurchaseHistoryStrategy{}, Weight: 3},
+		{Strategy: InterestStrategy{}, Weight: 2},
+		{Strategy: BestSellerStrategy{Window: 90 * 24 * time.Hour}, Weight: 1},
+	}}
+}
+
+// Name identifies the strategy in responses
+func (b *BlendedRecommender) Name() string {
+	return "blended"
+}
+
+// Recommend merges the recommendations of every strategy into one ranked list
+func (b *BlendedRecommender) Recommend(userID uint, limit int) ([]Recommendation, error) {
+	excluded, err := ownedBookIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[uint]*Recommendation)
+	for _, weighted := range b.Strategies {
+		recs, err := weighted.Strategy.Recommend(userID, limit*3)
+		if err != nil {
+			// One failing source should not take the others down
+			LogError("Recommendation strategy %s failed for user ID: %d: %v", weighted.Strategy.Name(), userID, err)
+			continue
+		}
+		maxScore := 0.0
+		for _, rec := range recs {
+			if rec.Score > maxScore {
+				maxScore = rec.Score
+			}
+		}
+		if maxScore == 0 {
+			continue
+		}
+		for _, rec := range recs {
+			if excluded[rec.BookID] {
+				continue
+			}
+			entry, ok := merged[rec.BookID]
+			if !ok {
+				entry = &Recommendation{BookID: rec.BookID}
+				merged[rec.BookID] = entry
+			}
+			entry.Score += weighted.Weight * rec.Score / maxScore
+			for _, reason := range rec.Reasons {
+				if !containsString(entry.Reasons, reason) {
+					entry.Reasons = append(entry.Reasons, reason)
+				}
+			}
+		}
+	}
+
+	ids := make([]uint, 0, len(merged))
+	for id := range merged {
+		ids = append(ids, id)
+	}
+	available, err := availableBookIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := make([]Recommendation, 0, len(available))
+	for _, id := range ids {
+		if available[id] {
+			ranked = append(ranked, *merged[id])
+		}
+	}
+	return topRecommendations(ranked, limit), nil
+}
+
+// PurchaseHistoryStrategy recommends books sharing an author, category or genre with the
+// books the user has bought
+type PurchaseHistoryStrategy struct{}
+
+// Name identifies the strategy
+func (PurchaseHistoryStrategy) Name() string {
+	return "purchase_history"
+}
+
+// Recommend scores books by their affinity with the user's purchases
+func (PurchaseHistoryStrategy) Recommend(userID uint, limit int) ([]Recommendation, error) {
+	var affinities []bookAffinity
+	if err := config.DB.Table("order_items").
+		Select("books.category_id, books.genre_id, books.author, SUM(order_items.quantity) AS weight").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Joins("JOIN books ON books.id = order_items.book_id").
+		Where("orders.user_id = ? AND orders.status NOT IN ?", userID, []string{models.OrderStatusCancelled, models.OrderStatusRefunded}).
+		Group("books.category_id, books.genre_id, books.author").
+		Scan(&affinities).Error; err != nil {
+		return nil, err
+	}
+	return recommendByAffinity(affinities, limit, "Based on your purchase history")
+}
+
+// InterestStrategy recommends books similar to those in the user's wishlist and cart and
+// those the user viewed recently
+type InterestStrategy struct{}
+
+// Name identifies the strategy
+func (InterestStrategy) Name() string {
+	return "interest"
+}
+
+// Recommend scores books by their affinity with what the user is looking at. Wishlist and
+// cart books count double a view.
+func (InterestStrategy) Recommend(userID uint, limit int) ([]Recommendation, error) {
+	var affinities []bookAffinity
+	sources := []struct {
+		table  string
+		weight float64
+		where  string
+		args   []interface{}
+	}{
+		{"wishlists", 2, "wishlists.user_id = ?", []interface{}{userID}},
+		{"carts", 2, "carts.user_id = ? AND carts.deleted_at IS NULL", []interface{}{userID}},
+		{"book_views", 1, "book_views.user_id = ? AND book_views.last_viewed_at > ?", []interface{}{userID, time.Now().AddDate(0, 0, -30)}},
+	}
+	for _, source := range sources {
+		var rows []bookAffinity
+		if err := config.DB.Table(source.table).
+			Select("books.category_id, books.genre_id, books.author, COUNT(*) * ? AS weight", source.weight).
+			Joins("JOIN books ON books.id = "+source.table+".book_id").
+			Where(source.where, source.args...).
+			Group("books.category_id, books.genre_id, books.author").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		affinities = append(affinities, rows...)
+	}
+	return recommendByAffinity(affinities, limit, "Based on books you are interested in")
+}
+
+// BestSellerStrategy recommends the store's best sellers over a recent window
+type BestSellerStrategy struct {
+	Window time.Duration
+}
+
+// Name identifies the strategy
+func (BestSellerStrategy) Name() string {
+	return "best_sellers"
+}
+
+// Recommend scores books by copies sold in the window
+func (s BestSellerStrategy) Recommend(userID uint, limit int) ([]Recommendation, error) {
+	var rows []struct {
+		BookID   uint
+		Quantity float64
+	}
+	if err := config.DB.Table("order_items").
+		Select("order_items.book_id, SUM(order_items.quantity) AS quantity").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.created_at > ? AND orders.status NOT IN ?", time.Now().Add(-s.Window),
+			[]string{models.OrderStatusCancelled, models.OrderStatusRefunded, models.OrderStatusReturnCompleted}).
+		Group("order_items.book_id").
+		Order("quantity DESC").
+		Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	recs := make([]Recommendation, len(rows))
+	for i, row := range rows {
+		recs[i] = Recommendation{BookID: row.BookID, Score: row.Quantity, Reasons: []string{"Popular right now"}}
+	}
+	return recs, nil
+}
+
+// bookAffinity is how strongly a user is drawn to a category, genre and author combination
+type bookAffinity struct {
+	CategoryID uint
+	GenreID    uint
+	Author     string
+	Weight     float64
+}
+
+// recommendByAffinity scores on-sale books by how much the user likes their category, genre
+// and author. A shared author counts twice as much as a shared category or genre.
+func recommendByAffinity(affinities []bookAffinity, limit int, reason string) ([]Recommendation, error) {
+	if len(affinities) == 0 {
+		return nil, nil
+	}
+
+	categories := make(map[uint]float64)
+	genres := make(map[uint]float64)
+	authors := make(map[string]float64)
+	for _, a := range affinities {
+		categories[a.CategoryID] += a.Weight
+		genres[a.GenreID] += a.Weight
+		if a.Author != "" {
+			authors[a.Author] += a.Weight
+		}
+	}
+
+	var candidates []models.Book
+	if err := config.DB.Select("id, category_id, genre_id, author").
+		Where("is_active = ? AND blocked = ? AND stock > 0", true, false).
+		Where("category_id IN ? OR genre_id IN ? OR author IN ?", mapKeys(categories), mapKeys(genres), stringMapKeys(authors)).
+		Order("average_rating DESC").
+		Limit(500).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	recs := make([]Recommendation, 0, len(candidates))
+	for _, book := range candidates {
+		score := categories[book.CategoryID] + genres[book.GenreID] + 2*authors[book.Author]
+		if score > 0 {
+			recs = append(recs, Recommendation{BookID: book.ID, Score: score, Reasons: []string{reason}})
+		}
+	}
+	return topRecommendations(recs, limit), nil
+}
+
+// ownedBookIDs returns the books the user has bought or has in their cart
+func ownedBookIDs(userID uint) (map[uint]bool, error) {
+	var ids []uint
+	if err := config.DB.Table("order_items").Distinct("order_items.book_id").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.user_id = ? AND orders.status <> ?", userID, models.OrderStatusCancelled).
+		Pluck("order_items.book_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	var cartIDs []uint
+	if err := config.DB.Model(&models.Cart{}).Where("user_id = ?", userID).Pluck("book_id", &cartIDs).Error; err != nil {
+		return nil, err
+	}
+
+	owned := make(map[uint]bool, len(ids)+len(cartIDs))
+	for _, id := range append(ids, cartIDs...) {
+		owned[id] = true
+	}
+	return owned, nil
+}
+
+// availableBookIDs returns which of the books are on sale and in stock
+func availableBookIDs(ids []uint) (map[uint]bool, error) {
+	available := make(map[uint]bool, len(ids))
+	if len(ids) == 0 {
+		return available, nil
+	}
+	var found []uint
+	if err := config.DB.Model(&models.Book{}).
+		Where("id IN ? AND is_active = ? AND blocked = ? AND stock > 0", ids, true, false).
+		Pluck("id", &found).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range found {
+		available[id] = true
+	}
+	return available, nil
+}
+
+// topRecommendations sorts by score, highest first, and keeps at most limit. Ties go to
+// the newer book.
+func topRecommendations(recs []Recommendation, limit int) []Recommendation {
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Score != recs[j].Score {
+			return recs[i].Score > recs[j].Score
+		}
+		return recs[i].BookID > recs[j].BookID
+	})
+	if len(recs) > limit {
+		recs = recs[:limit]
+	}
+	return recs
+}
+
+func mapKeys(m map[uint]float64) []uint {
+	keys := make([]uint, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func stringMapKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
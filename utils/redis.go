@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTimeout bounds the initial ping to Redis
+const redisTimeout = 5 * time.Second
+
+var (
+	redisOnce   sync.Once
+	redisClient *redis.Client
+	redisErr    error
+)
+
+// GetRedis returns the client configured by REDIS_URL, connecting on first use.
+// It returns an error when REDIS_URL is not set or Redis cannot be reached.
+func GetRedis() (*redis.Client, error) {
+	redisOnce.Do(func() {
+		rawURL := os.Getenv("REDIS_URL")
+		if rawURL == "" {
+			redisErr = errors.New("REDIS_URL is not set")
+			return
+		}
+		opts, err := redis.ParseURL(rawURL)
+		if err != nil {
+			redisErr = fmt.Errorf("invalid REDIS_URL: %v", err)
+			return
+		}
+		client := redis.NewClient(opts)
+		ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		defer cancel()
+		if err := client.Ping(ctx).Err(); err != nil {
+			client.Close()
+			redisErr = fmt.Errorf("redis ping failed: %v", err)
+			return
+		}
+		LogInfo("Connected to Redis at %s", opts.Addr)
+		redisClient = client
+	})
+	return redisClient, redisErr
+}
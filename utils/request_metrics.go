@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/gin-gonic/gin"
+)
+
+// maxRequestSamples bounds the memory used by the request metrics store; the oldest
+// samples are overwritten first
+const maxRequestSamples = 200000
+
+// RequestSample is one served API request
+type RequestSample struct {
+	Time     time.Time
+	Method   string
+	Route    string
+	Status   int
+	Latency  time.Duration
+	Consumer string
+}
+
+// requestMetricsStore keeps recent request samples in a ring buffer
+type requestMetricsStore struct {
+	mu      sync.Mutex
+	samples []RequestSample
+	next    int
+}
+
+var requestMetrics = &requestMetricsStore{}
+
+func (s *requestMetricsStore) add(sample RequestSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.samples) < maxRequestSamples {
+		s.samples = append(s.samples, sample)
+		return
+	}
+	s.samples[s.next] = sample
+	s.next = (s.next + 1) % maxRequestSamples
+}
+
+func (s *requestMetricsStore) since(from time.Time) []RequestSample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]RequestSample, 0, len(s.samples))
+	for _, sample := range s.samples {
+		if !sample.Time.Before(from) {
+			result = append(result, sample)
+		}
+	}
+	return result
+}
+
+// requestConsumer identifies who made the request: the authenticated user or admin, else the client IP
+func requestConsumer(c *gin.Context) string {
+	if userVal, exists := c.Get("user"); exists {
+		if user, ok := userVal.(models.User); ok {
+			return fmt.Sprintf("user:%d", user.ID)
+		}
+	}
+	if adminVal, exists := c.Get("admin"); exists {
+		if admin, ok := adminVal.(models.Admin); ok {
+			return fmt.Sprintf("admin:%d", admin.ID)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// RequestMetricsMiddleware records the route, status, latency and consumer of every request,
+// both for the Prometheus metrics and for the admin API analytics. Requests that match no
+// route are grouped under "unmatched" to keep the label set bounded.
+func RequestMetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		httpRequestsInFlight.Inc()
+		c.Next()
+		httpRequestsInFlight.Dec()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		latency := time.Since(start)
+		status := c.Writer.Status()
+		httpRequestsTotal.WithLabelValues(c.Request.Method, route, strconv.Itoa(status)).Inc()
+		httpRequestDuration.WithLabelValues(c.Request.Method, route).Observe(latency.Seconds())
+		requestMetrics.add(RequestSample{
+			Time:     start,
+			Method:   c.Request.Method,
+			Route:    route,
+			Status:   status,
+			Latency:  latency,
+			Consumer: requestConsumer(c),
+		})
+	}
+}
+
+// RouteTraffic summarizes the requests served by one route
+type RouteTraffic struct {
+	Method       string  `json:"method"`
+	Route        string  `json:"route"`
+	Requests     int     `json:"requests"`
+	Errors       int     `json:"errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	P95LatencyMs float64 `json:"p95_latency_ms"`
+}
+
+// ConsumerTraffic summarizes the requests made by one consumer
+type ConsumerTraffic struct {
+	Consumer  string  `json:"consumer"`
+	Requests  int     `json:"requests"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// TrafficSummary is the API traffic over a window
+type TrafficSummary struct {
+	From         time.Time         `json:"from"`
+	To           time.Time         `json:"to"`
+	Requests     int               `json:"requests"`
+	Errors       int               `json:"errors"`
+	ErrorRate    float64           `json:"error_rate"`
+	P95LatencyMs float64           `json:"p95_latency_ms"`
+	Routes       []RouteTraffic    `json:"routes"`
+	TopConsumers []ConsumerTraffic `json:"top_consumers"`
+}
+
+// p95 returns the 95th percentile latency in milliseconds
+func p95(latencies []time.Duration) float64 {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	index := (len(latencies)*95+99)/100 - 1
+	return roundMoney(float64(latencies[index]) / float64(time.Millisecond))
+}
+
+func errorRate(errors, requests int) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return roundMoney(float64(errors) * 100 / float64(requests))
+}
+
+// SummarizeTraffic aggregates the requests recorded in the window ending now.
+// Responses with status 400 and above count as errors.
+func SummarizeTraffic(window time.Duration, topConsumers int) TrafficSummary {
+	now := time.Now()
+	samples := requestMetrics.since(now.Add(-window))
+
+	summary := TrafficSummary{From: now.Add(-window), To: now, Requests: len(samples)}
+	routeLatencies := make(map[string][]time.Duration)
+	routes := make(map[string]*RouteTraffic)
+	consumers := make(map[string]*ConsumerTraffic)
+	all := make([]time.Duration, 0, len(samples))
+
+	for _, sample := range samples {
+		isError := sample.Status >= 400
+		key := sample.Method + " " + sample.Route
+		route, ok := routes[key]
+		if !ok {
+			route = &RouteTraffic{Method: sample.Method, Route: sample.Route}
+			routes[key] = route
+		}
+		consumer, ok := consumers[sample.Consumer]
+		if !ok {
+			consumer = &ConsumerTraffic{Consumer: sample.Consumer}
+			consumers[sample.Consumer] = consumer
+		}
+
+		route.Requests++
+		consumer.Requests++
+		if isError {
+			route.Errors++
+			consumer.Errors++
+			summary.Errors++
+		}
+		routeLatencies[key] = append(routeLatencies[key], sample.Latency)
+		all = append(all, sample.Latency)
+	}
+
+	summary.ErrorRate = errorRate(summary.Errors, summary.Requests)
+	summary.P95LatencyMs = p95(all)
+
+	summary.Routes = make([]RouteTraffic, 0, len(routes))
+	for key, route := range routes {
+		route.ErrorRate = errorRate(route.Errors, route.Requests)
+		route.P95LatencyMs = p95(routeLatencies[key])
+		summary.Routes = append(summary.Routes, *route)
+	}
+	sort.Slice(summary.Routes, func(i, j int) bool {
+		return summary.Routes[i].Requests > summary.Routes[j].Requests
+	})
+
+	summary.TopConsumers = make([]ConsumerTraffic, 0, len(consumers))
+	for _, consumer := range consumers {
+		consumer.ErrorRate = errorRate(consumer.Errors, consumer.Requests)
+		summary.TopConsumers = append(summary.TopConsumers, *consumer)
+	}
+	sort.Slice(summary.TopConsumers, func(i, j int) bool {
+		return summary.TopConsumers[i].Requests > summary.TopConsumers[j].Requests
+	})
+	if len(summary.TopConsumers) > topConsumers {
+		summary.TopConsumers = summary.TopConsumers[:topConsumers]
+	}
+
+	return summary
+}
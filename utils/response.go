@@ -9,16 +9,18 @@ import (
 // StandardResponse represents the standard API response structure
 type StandardResponse struct {
 	Status  string      `json:"status"`
+	Code    ErrorCode   `json:"code,omitempty"` // set on errors, see ErrorCode
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 }
 
-// Success sends a standardized success response
+// Success sends a standardized success response. Payload data follows the request's
+// response format (see GetResponseFormat).
 func Success(c *gin.Context, message string, data interface{}) {
 	c.JSON(http.StatusOK, StandardResponse{
 		Status:  "success",
 		Message: message,
-		Data:    data,
+		Data:    formatResponseData(c, data),
 	})
 }
 
@@ -27,7 +29,7 @@ func Created(c *gin.Context, message string, data interface{}) {
 	c.JSON(http.StatusCreated, StandardResponse{
 		Status:  "success",
 		Message: message,
-		Data:    data,
+		Data:    formatResponseData(c, data),
 	})
 }
 
@@ -36,7 +38,7 @@ func SuccessWithPagination(c *gin.Context, message string, data interface{}, tot
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"message": message,
-		"data":    data,
+		"data":    formatResponseData(c, data),
 		"pagination": gin.H{
 			"total":       total,
 			"page":        page,
@@ -46,14 +48,20 @@ func SuccessWithPagination(c *gin.Context, message string, data interface{}, tot
 	})
 }
 
-// Error sends a standardized error response
+// Error sends a standardized error response with the generic code for the status
 func Error(c *gin.Context, statusCode int, message string, err interface{}) {
+	sendError(c, statusCode, defaultErrorCode(statusCode), message, err)
+}
+
+// sendError writes the error envelope
+func sendError(c *gin.Context, statusCode int, code ErrorCode, message string, err interface{}) {
 	response := StandardResponse{
 		Status:  "error",
+		Code:    code,
 		Message: message,
 	}
 	if err != nil {
-		response.Data = gin.H{"error": err}
+		response.Data = errorDetails(c, err)
 	}
 	c.JSON(statusCode, response)
 }
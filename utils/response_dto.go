@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"github.com/Govind-619/ReadSphere/models"
+)
+
+// OrderSummary is the shared list representation of an order for users and admins. Handlers
+// that show more embed it in their own response struct.
+type OrderSummary struct {
+	ID                uint   `json:"id"`
+	Status            string `json:"status"`
+	PaymentMode       string `json:"payment_mode"`
+	TotalAmount       Money  `json:"total_amount"`
+	Discount          Money  `json:"discount"`
+	CouponDiscount    Money  `json:"coupon_discount"`
+	DeliveryCharge    Money  `json:"delivery_charge"`
+	TotalWithDelivery Money  `json:"total_with_delivery"`
+	FinalTotal        Money  `json:"final_total"`
+	ItemCount         int    `json:"item_count"`
+	CreatedAt         string `json:"created_at"`
+}
+
+// NewOrderSummary builds the order's summary in the request's response format
+func NewOrderSummary(f ResponseFormatter, order models.Order) OrderSummary {
+	return OrderSummary{
+		ID:                order.ID,
+		Status:            order.Status,
+		PaymentMode:       order.PaymentMethod,
+		TotalAmount:       f.Money(order.TotalAmount),
+		Discount:          f.Money(order.Discount),
+		CouponDiscount:    f.Money(order.CouponDiscount),
+		DeliveryCharge:    f.Money(order.DeliveryCharge),
+		TotalWithDelivery: f.Money(order.TotalWithDelivery),
+		FinalTotal:        f.Money(order.FinalTotal),
+		ItemCount:         len(order.OrderItems),
+		CreatedAt:         f.Timestamp(order.CreatedAt),
+	}
+}
+
+// OrderItemSummary is the shared representation of an order line
+type OrderItemSummary struct {
+	ID                 uint   `json:"id"`
+	BookID             uint   `json:"book_id"`
+	Name               string `json:"name"`
+	Quantity           int    `json:"quantity"`
+	Price              Money  `json:"price"`
+	Discount           Money  `json:"discount"`
+	Total              Money  `json:"total"`
+	CancellationStatus string `json:"cancellation_status"`
+	ReturnStatus       string `json:"return_status"`
+}
+
+// NewOrderItemSummary builds the order line's summary in the request's response format
+func NewOrderItemSummary(f ResponseFormatter, item models.OrderItem) OrderItemSummary {
+	return OrderItemSummary{
+		ID:                 item.ID,
+		BookID:             item.BookID,
+		Name:               item.Book.Name,
+		Quantity:           item.Quantity,
+		Price:              f.Money(item.Price),
+		Discount:           f.Money(item.Discount),
+		Total:              f.Money(item.Total),
+		CancellationStatus: item.CancellationStatus,
+		ReturnStatus:       item.ReturnStatus,
+	}
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response formats. V2 returns money as numbers rounded to 2 decimals and timestamps in RFC3339;
+// legacy keeps the historical mix of "%.2f" strings and "2006-01-02 15:04:05" timestamps.
+const (
+	ResponseFormatV2     = "v2"
+	ResponseFormatLegacy = "legacy"
+
+	// ResponseFormatHeader lets a client pick the format of a single request during the transition
+	ResponseFormatHeader = "X-Response-Format"
+
+	legacyTimestampLayout = "2006-01-02 15:04:05"
+)
+
+// GetResponseFormat returns the response format of the request: the X-Response-Format header
+// when set, else the RESPONSE_FORMAT environment variable, else legacy so existing clients keep
+// working until they opt in to v2
+func GetResponseFormat(c *gin.Context) string {
+	format := strings.ToLower(strings.TrimSpace(c.GetHeader(ResponseFormatHeader)))
+	if format == "" {
+		format = strings.ToLower(strings.TrimSpace(os.Getenv("RESPONSE_FORMAT")))
+	}
+	if format == ResponseFormatV2 {
+		return ResponseFormatV2
+	}
+	return ResponseFormatLegacy
+}
+
+// ResponseFormatter formats money and timestamps for the request's response format and
+// currency. An empty Currency means the base currency.
+type ResponseFormatter struct {
+	Format   string
+	Currency string
+}
+
+// NewResponseFormatter returns the formatter for the request
+func NewResponseFormatter(c *gin.Context) ResponseFormatter {
+	return ResponseFormatter{Format: GetResponseFormat(c), Currency: GetRequestCurrency(c)}
+}
+
+// Money is an amount in a response, already in the request's currency. Handlers build it with
+// ResponseFormatter.Money, and it encodes as a number rounded to the currency's decimals, or as
+// a string with those decimals in the legacy format.
+type Money struct {
+	Amount   float64
+	Decimals int
+	Legacy   bool
+}
+
+// MarshalJSON encodes the amount in the response format it was built for
+func (m Money) MarshalJSON() ([]byte, error) {
+	if m.Legacy {
+		return json.Marshal(m.String())
+	}
+	return json.Marshal(roundTo(m.Amount, m.Decimals))
+}
+
+// String formats the amount with the currency's decimals
+func (m Money) String() string {
+	return fmt.Sprintf("%.*f", m.Decimals, m.Amount)
+}
+
+// Money converts a base currency amount to the request's currency and returns it as a
+// response value. Only amounts passed through here are converted; percentages, counts and
+// other numbers are sent as they are.
+func (f ResponseFormatter) Money(amount float64) Money {
+	code := f.Currency
+	if code == "" {
+		code = BaseCurrency()
+	}
+	if code != BaseCurrency() {
+		amount = ConvertFromBase(amount, code)
+	}
+	return Money{Amount: amount, Decimals: CurrencyInfoFor(code).Decimals, Legacy: f.Format == ResponseFormatLegacy}
+}
+
+// Timestamp formats a time in RFC3339, or as "2006-01-02 15:04:05" in legacy format
+func (f ResponseFormatter) Timestamp(t time.Time) string {
+	if f.Format == ResponseFormatLegacy {
+		return t.Format(legacyTimestampLayout)
+	}
+	return t.Format(time.RFC3339)
+}
+
+// OptionalTimestamp formats a nullable time, returning nil when it is unset
+func (f ResponseFormatter) OptionalTimestamp(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return f.Timestamp(*t)
+}
+
+// formatResponseData reports the currency of a success payload in the X-Currency response
+// header. Handlers convert and format the amounts themselves through ResponseFormatter.
+func formatResponseData(c *gin.Context, data interface{}) interface{} {
+	c.Header(CurrencyHeader, GetRequestCurrency(c))
+	return data
+}
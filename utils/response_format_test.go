@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoneyMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		amount float64
+		want   string
+	}{
+		{"v2 whole amount", ResponseFormatV2, 10, `10`},
+		{"v2 rounds to 2 decimals", ResponseFormatV2, 12.345, `12.35`},
+		{"v2 negative", ResponseFormatV2, -4.5, `-4.5`},
+		{"legacy pads to 2 decimals", ResponseFormatLegacy, 10, `"10.00"`},
+		{"legacy rounds to 2 decimals", ResponseFormatLegacy, 99.999, `"100.00"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(ResponseFormatter{Format: tt.format}.Money(tt.amount))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestTimestampKeepsZone(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+1800)
+	at := time.Date(2024, 5, 1, 10, 30, 0, 0, ist)
+
+	assert.Equal(t, "2024-05-01T10:30:00+05:30", ResponseFormatter{Format: ResponseFormatV2}.Timestamp(at))
+	assert.Equal(t, "2024-05-01 10:30:00", ResponseFormatter{Format: ResponseFormatLegacy}.Timestamp(at))
+	assert.Nil(t, ResponseFormatter{Format: ResponseFormatV2}.OptionalTimestamp(nil))
+}
+
+func TestGetResponseFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		header string
+		env    string
+		want   string
+	}{
+		{"legacy when nothing is set", "", "", ResponseFormatLegacy},
+		{"environment default", "", "v2", ResponseFormatV2},
+		{"header wins over the environment", "legacy", "v2", ResponseFormatLegacy},
+		{"header opts in to v2", "V2", "", ResponseFormatV2},
+		{"unknown value falls back to legacy", "v3", "", ResponseFormatLegacy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RESPONSE_FORMAT", tt.env)
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				c.Request.Header.Set(ResponseFormatHeader, tt.header)
+			}
+			assert.Equal(t, tt.want, GetResponseFormat(c))
+		})
+	}
+}
+
+func TestSuccessSendsHandlerValuesUnchanged(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Request.Header.Set(ResponseFormatHeader, ResponseFormatV2)
+
+	formatter := NewResponseFormatter(c)
+	Success(c, "ok", gin.H{
+		"total":      formatter.Money(25),
+		"note":       "2024-01-02 10:00:00",
+		"price_code": "12.50",
+	})
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, float64(25), body.Data["total"])
+	assert.Equal(t, "2024-01-02 10:00:00", body.Data["note"], "strings are not reparsed as timestamps")
+	assert.Equal(t, "12.50", body.Data["price_code"], "strings under money-like keys stay strings")
+}
+
+// withExchangeRates makes the currencies requestable at the given rates for the rest of the test
+func withExchangeRates(t *testing.T, rates map[string]float64) {
+	t.Helper()
+	previousSupported := supportedCurrencies
+	ratesMu.Lock()
+	previousRates := exchangeRates
+	exchangeRates = rates
+	ratesMu.Unlock()
+	supportedCurrencies = []string{baseCurrency}
+	for code := range rates {
+		supportedCurrencies = append(supportedCurrencies, code)
+	}
+	t.Cleanup(func() {
+		supportedCurrencies = previousSupported
+		ratesMu.Lock()
+		exchangeRates = previousRates
+		ratesMu.Unlock()
+	})
+}
+
+func TestMoneyConvertsToFormatterCurrency(t *testing.T) {
+	withExchangeRates(t, map[string]float64{"USD": 0.012, "JPY": 1.8})
+
+	tests := []struct {
+		name      string
+		formatter ResponseFormatter
+		amount    float64
+		want      string
+	}{
+		{"base currency is not converted", ResponseFormatter{Format: ResponseFormatV2}, 1000, `1000`},
+		{"converted at the rate", ResponseFormatter{Format: ResponseFormatV2, Currency: "USD"}, 1000, `12`},
+		{"rounded to the currency's decimals", ResponseFormatter{Format: ResponseFormatV2, Currency: "JPY"}, 999.99, `1800`},
+		{"legacy uses the currency's decimals", ResponseFormatter{Format: ResponseFormatLegacy, Currency: "JPY"}, 1000, `"1800"`},
+		{"legacy converted amount", ResponseFormatter{Format: ResponseFormatLegacy, Currency: "USD"}, 1250, `"15.00"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.formatter.Money(tt.amount))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestSuccessConvertsOnlyMoney(t *testing.T) {
+	withExchangeRates(t, map[string]float64{"USD": 0.012})
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest("GET", "/?currency=USD", nil)
+	c.Request.Header.Set(ResponseFormatHeader, ResponseFormatV2)
+
+	formatter := NewResponseFormatter(c)
+	Success(c, "ok", gin.H{
+		"price":            formatter.Money(500),
+		"discount":         25.0,
+		"discount_percent": 25.0,
+	})
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, "USD", recorder.Header().Get(CurrencyHeader))
+	assert.Equal(t, 6.0, body.Data["price"])
+	assert.Equal(t, 25.0, body.Data["discount"], "a percentage under a money-like key is not converted")
+	assert.Equal(t, 25.0, body.Data["discount_percent"])
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// HasPurchasedBook reports whether the user has an order containing the book that was not
+// cancelled and, for online payments, was paid. Items cancelled on their own do not count.
+func HasPurchasedBook(db *gorm.DB, userID, bookID uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.user_id = ? AND order_items.book_id = ? AND orders.status <> ?",
+			userID, bookID, models.OrderStatusCancelled).
+		Where("orders.payment_method IN ? OR orders.payment_status = ?", []string{"cod", "wallet"}, models.PaymentStatusCompleted).
+		Where("COALESCE(order_items.cancellation_status, '') NOT IN ?", []string{"Cancelled", "Approved"}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// bannedWords returns the banned word list
+func bannedWords(db *gorm.DB) ([]string, error) {
+	var words []string
+	err := db.Model(&models.BannedWord{}).Order("word").Pluck("word", &words).Error
+	return words, err
+}
+
+// NormalizeBannedWord lowercases the text and reduces it to words separated by single spaces,
+// so banned words match whole words regardless of case and punctuation
+func NormalizeBannedWord(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// matchBannedWords returns the words that occur in the text as whole words or phrases
+func matchBannedWords(words []string, text string) []string {
+	normalized := " " + NormalizeBannedWord(text) + " "
+	var found []string
+	for _, word := range words {
+		needle := NormalizeBannedWord(word)
+		if needle != "" && strings.Contains(normalized, " "+needle+" ") {
+			found = append(found, word)
+		}
+	}
+	return found
+}
+
+// FlagReview marks the review as flagged when its comment contains banned words
+func FlagReview(db *gorm.DB, review *models.Review) error {
+	words, err := bannedWords(db)
+	if err != nil {
+		return err
+	}
+	found := matchBannedWords(words, review.Comment)
+	review.IsFlagged = len(found) > 0
+	review.FlaggedWords = strings.Join(found, ", ")
+	return nil
+}
+
+// RescanPendingReviews checks the pending reviews against the current banned words, flagging
+// or clearing each, and returns how many are flagged
+func RescanPendingReviews(db *gorm.DB) (int, error) {
+	words, err := bannedWords(db)
+	if err != nil {
+		return 0, err
+	}
+	var reviews []models.Review
+	if err := db.Select("id", "comment", "is_flagged", "flagged_words").
+		Where("status = ?", models.ReviewStatusPending).Find(&reviews).Error; err != nil {
+		return 0, err
+	}
+
+	flagged := 0
+	for _, review := range reviews {
+		found := matchBannedWords(words, review.Comment)
+		if len(found) > 0 {
+			flagged++
+		}
+		flaggedWords := strings.Join(found, ", ")
+		if review.IsFlagged == (len(found) > 0) && review.FlaggedWords == flaggedWords {
+			continue
+		}
+		if err := db.Model(&models.Review{}).Where("id = ?", review.ID).Updates(map[string]interface{}{
+			"is_flagged":    len(found) > 0,
+			"flagged_words": flaggedWords,
+		}).Error; err != nil {
+			return flagged, err
+		}
+	}
+	return flagged, nil
+}
@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// JobFunc runs a scheduled job and returns a short summary of what it did
+type JobFunc func() (string, error)
+
+type registeredJob struct {
+	name        string
+	description string
+	interval    time.Duration
+	run         JobFunc
+}
+
+// schedulerTick is how often the scheduler checks for due jobs
+const schedulerTick = time.Minute
+
+// minJobLease bounds how long a job stays locked to the instance running it
+const minJobLease = 5 * time.Minute
+
+var (
+	jobsMu         sync.Mutex
+	jobs           = make(map[string]*registeredJob)
+	jobOrder       []string
+	ErrJobNotFound = errors.New("scheduled job not found")
+	ErrJobLocked   = errors.New("scheduled job is already running")
+
+	// schedulerInstance identifies this process in job leases
+	schedulerInstance = func() string {
+		host, _ := os.Hostname()
+		return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8])
+	}()
+)
+
+// RegisterJob adds a background job that runs every interval. Call before StartScheduler.
+func RegisterJob(name, description string, interval time.Duration, run JobFunc) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	if _, exists := jobs[name]; !exists {
+		jobOrder = append(jobOrder, name)
+	}
+	jobs[name] = &registeredJob{name: name, description: description, interval: interval, run: run}
+}
+
+// StartScheduler syncs the registered jobs to the database and runs them in the background
+// until the returned stop function is called. Stop waits for a running job to finish.
+func StartScheduler() (stop func()) {
+	jobsMu.Lock()
+	names := append([]string(nil), jobOrder...)
+	jobsMu.Unlock()
+
+	for _, name := range names {
+		job := jobs[name]
+		record := models.ScheduledJob{Name: name}
+		if err := config.DB.Where(models.ScheduledJob{Name: name}).
+			Attrs(models.ScheduledJob{Enabled: true}).
+			FirstOrCreate(&record).Error; err != nil {
+			LogError("Failed to register scheduled job %s: %v", name, err)
+			continue
+		}
+		config.DB.Model(&record).Updates(map[string]interface{}{
+			"description":   job.description,
+			"interval_secs": int64(job.interval / time.Second),
+		})
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(schedulerTick)
+		defer ticker.Stop()
+		for {
+			for _, name := range names {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := runJob(name, false); err != nil && !errors.Is(err, ErrJobLocked) {
+					LogError("Scheduled job %s: %v", name, err)
+				}
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	LogInfo("Scheduler started with %d jobs as %s", len(names), schedulerInstance)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+			LogInfo("Scheduler stopped")
+		})
+	}
+}
+
+// RunJobNow runs a job immediately, regardless of its schedule, unless another instance holds it
+func RunJobNow(name string) (models.ScheduledJob, error) {
+	return runJob(name, true)
+}
+
+// runJob claims the job's lease and runs it. Unforced runs only claim jobs that are enabled
+// and due. It returns ErrJobLocked when the job is not due or another instance holds it.
+func runJob(name string, force bool) (models.ScheduledJob, error) {
+	jobsMu.Lock()
+	job, ok := jobs[name]
+	jobsMu.Unlock()
+	if !ok {
+		return models.ScheduledJob{}, ErrJobNotFound
+	}
+
+	now := time.Now()
+	lease := job.interval
+	if lease < minJobLease {
+		lease = minJobLease
+	}
+	lockedUntil := now.Add(lease)
+
+	claim := config.DB.Model(&models.ScheduledJob{}).
+		Where("name = ? AND (locked_until IS NULL OR locked_until < ?)", name, now)
+	if !force {
+		claim = claim.Where("enabled = ? AND (next_run_at IS NULL OR next_run_at <= ?)", true, now)
+	}
+	result := claim.Updates(map[string]interface{}{
+		"locked_by":    schedulerInstance,
+		"locked_until": lockedUntil,
+		"last_status":  models.JobStatusRunning,
+	})
+	if result.Error != nil {
+		return models.ScheduledJob{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ScheduledJob{}, ErrJobLocked
+	}
+
+	LogDebug("Running scheduled job %s", name)
+	message, runErr := safeRunJob(job)
+	duration := time.Since(now)
+	nextRun := now.Add(job.interval)
+
+	updates := map[string]interface{}{
+		"last_run_at":      now,
+		"last_duration_ms": duration.Milliseconds(),
+		"last_message":     message,
+		"run_count":        gorm.Expr("run_count + 1"),
+		"next_run_at":      nextRun,
+		"locked_by":        "",
+		"locked_until":     nil,
+	}
+	if runErr != nil {
+		updates["last_status"] = models.JobStatusFailed
+		updates["last_error"] = runErr.Error()
+		updates["fail_count"] = gorm.Expr("fail_count + 1")
+		LogError("Scheduled job %s failed after %s: %v", name, duration, runErr)
+	} else {
+		updates["last_status"] = models.JobStatusSuccess
+		updates["last_error"] = ""
+		if message != "" {
+			LogInfo("Scheduled job %s finished in %s: %s", name, duration, message)
+		}
+	}
+
+	var record models.ScheduledJob
+	if err := config.DB.Model(&models.ScheduledJob{}).
+		Where("name = ? AND locked_by = ?", name, schedulerInstance).
+		Updates(updates).Error; err != nil {
+		LogError("Failed to record run of scheduled job %s: %v", name, err)
+	}
+	config.DB.Where("name = ?", name).First(&record)
+	return record, runErr
+}
+
+// safeRunJob keeps a panicking job from taking down the scheduler
+func safeRunJob(job *registeredJob) (message string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.run()
+}
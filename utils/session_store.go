@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+	"github.com/redis/go-redis/v9"
+)
+
+// Session store backends
+const (
+	SessionStoreCookie = "cookie"
+	SessionStoreRedis  = "redis"
+)
+
+// sessionKeyPrefix namespaces session keys in Redis
+const sessionKeyPrefix = "session:"
+
+// NewSessionStore returns the session store selected by SESSION_STORE. The redis store keeps
+// session data server side so every replica sees it; when Redis is not configured or cannot
+// be reached the signed cookie store is used instead, which suits local development.
+func NewSessionStore(options sessions.Options) (sessions.Store, string) {
+	secret := []byte(getEnvOr("SESSION_SECRET", "your-secret-key"))
+
+	backend := strings.ToLower(getEnvOr("SESSION_STORE", SessionStoreCookie))
+	if backend == SessionStoreRedis {
+		client, err := GetRedis()
+		if err == nil {
+			store := NewRedisSessionStore(client, secret)
+			store.Options(options)
+			return store, SessionStoreRedis
+		}
+		LogError("Redis session store unavailable, falling back to cookie sessions: %v", err)
+	}
+
+	store := cookie.NewStore(secret)
+	store.Options(options)
+	return store, SessionStoreCookie
+}
+
+// RedisSessionStore keeps session values in Redis. The cookie only carries the signed session ID.
+type RedisSessionStore struct {
+	client  *redis.Client
+	Codecs  []securecookie.Codec
+	options *gsessions.Options
+}
+
+// NewRedisSessionStore creates a Redis-backed store whose session IDs are signed with keyPairs
+func NewRedisSessionStore(client *redis.Client, keyPairs ...[]byte) *RedisSessionStore {
+	store := &RedisSessionStore{
+		client:  client,
+		Codecs:  securecookie.CodecsFromPairs(keyPairs...),
+		options: &gsessions.Options{Path: "/", MaxAge: 86400 * 30},
+	}
+	// Values are stored in Redis, so they are not bound by the cookie size limit
+	for _, codec := range store.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxLength(0)
+		}
+	}
+	return store
+}
+
+// Options sets the cookie options of new sessions
+func (s *RedisSessionStore) Options(options sessions.Options) {
+	s.options = options.ToGorillaOptions()
+	for _, codec := range s.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(options.MaxAge)
+		}
+	}
+}
+
+// Get returns the session for the request, cached for the rest of the request
+func (s *RedisSessionStore) Get(r *http.Request, name string) (*gsessions.Session, error) {
+	return gsessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session named by the request cookie, or starts an empty one
+func (s *RedisSessionStore) New(r *http.Request, name string) (*gsessions.Session, error) {
+	session := gsessions.NewSession(s, name)
+	opts := *s.options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	if err := securecookie.DecodeMulti(name, c.Value, &session.ID, s.Codecs...); err != nil {
+		return session, err
+	}
+	data, err := s.client.Get(r.Context(), sessionKeyPrefix+session.ID).Result()
+	if err == redis.Nil {
+		// Expired or removed: start over with a fresh ID
+		session.ID = ""
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+	if err := securecookie.DecodeMulti(name, data, &session.Values, s.Codecs...); err != nil {
+		return session, err
+	}
+	session.IsNew = false
+	return session, nil
+}
+
+// Save writes the session values to Redis and the session ID cookie to the response.
+// A session with MaxAge <= 0 is deleted.
+func (s *RedisSessionStore) Save(r *http.Request, w http.ResponseWriter, session *gsessions.Session) error {
+	if session.Options.MaxAge <= 0 {
+		if session.ID != "" {
+			if err := s.client.Del(r.Context(), sessionKeyPrefix+session.ID).Err(); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, gsessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = base32.StdEncoding.WithPadding(base32.NoPadding).
+			EncodeToString(securecookie.GenerateRandomKey(32))
+	}
+	data, err := securecookie.EncodeMulti(session.Name(), session.Values, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	ttl := time.Duration(session.Options.MaxAge) * time.Second
+	if err := s.client.Set(r.Context(), sessionKeyPrefix+session.ID, data, ttl).Err(); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, gsessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
@@ -0,0 +1,297 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Setting keys
+const (
+	SettingCancellationWindowMinutes = "cancellation_window_minutes"
+	SettingReturnWindowDays          = "return_window_days"
+	SettingCODOrderLimit             = "cod_order_limit"
+	SettingMaxCartQuantity           = "max_cart_quantity"
+	SettingCODMaxRefusedDeliveries   = "cod_max_refused_deliveries"
+	SettingGiftWrapFee               = "gift_wrap_fee"
+	SettingEbookDownloadLimit        = "ebook_download_limit"
+	SettingGiftCardValidityDays      = "gift_card_validity_days"
+	SettingGiftCardMaxAmount         = "gift_card_max_amount"
+	SettingDeliveryMinDays           = "delivery_min_days"
+	SettingDeliveryMaxDays           = "delivery_max_days"
+	SettingDeliverySlotDays          = "delivery_slot_days"
+	SettingAbandonedCartHours        = "abandoned_cart_hours"
+	SettingAbandonedCartRemindHours  = "abandoned_cart_second_reminder_hours"
+	SettingReviewReportThreshold     = "review_report_threshold"
+)
+
+// settingDefinition describes a setting, its default and the range it accepts
+type settingDefinition struct {
+	Description string
+	Default     float64
+	Min         float64
+	Max         float64
+	Integer     bool
+}
+
+var settingDefinitions = map[string]settingDefinition{
+	SettingCancellationWindowMinutes: {Description: "Minutes after placing an order during which it can be cancelled", Default: 30, Min: 0, Max: 7 * 24 * 60, Integer: true},
+	SettingReturnWindowDays:          {Description: "Days after delivery during which items can be returned, unless the category sets its own window", Default: 7, Min: 0, Max: 365, Integer: true},
+	SettingCODOrderLimit:             {Description: "Largest order total that can be paid by Cash on Delivery", Default: 1000, Min: 0, Max: 10000000},
+	SettingMaxCartQuantity:           {Description: "Most copies of one book a cart can hold", Default: 5, Min: 1, Max: 100, Integer: true},
+	SettingCODMaxRefusedDeliveries:   {Description: "Refused Cash on Delivery orders after which a user can no longer pay by Cash on Delivery, 0 to never withdraw it", Default: 2, Min: 0, Max: 100, Integer: true},
+	SettingGiftWrapFee:               {Description: "Fee charged for gift wrapping an order", Default: 30, Min: 0, Max: 10000},
+	SettingEbookDownloadLimit:        {Description: "Download links a buyer can get for each digital book", Default: 5, Min: 1, Max: 1000, Integer: true},
+	SettingGiftCardValidityDays:      {Description: "Days a gift card can be redeemed after it is bought or issued", Default: 365, Min: 1, Max: 3650, Integer: true},
+	SettingGiftCardMaxAmount:         {Description: "Largest value of one gift card", Default: 10000, Min: 1, Max: 10000000},
+	SettingDeliveryMinDays:           {Description: "Fewest working days to deliver to a zone without its own delivery time", Default: 3, Min: 0, Max: 60, Integer: true},
+	SettingDeliveryMaxDays:           {Description: "Most working days to deliver to a zone without its own delivery time", Default: 7, Min: 0, Max: 60, Integer: true},
+	SettingDeliverySlotDays:          {Description: "Working days, from the earliest delivery date, for which a delivery slot can be booked", Default: 5, Min: 1, Max: 30, Integer: true},
+	SettingAbandonedCartHours:        {Description: "Hours a cart with items must go untouched before it counts as abandoned and its owner is reminded", Default: 24, Min: 1, Max: 24 * 30, Integer: true},
+	SettingAbandonedCartRemindHours:  {Description: "Hours after the first abandoned cart reminder before the second one is sent", Default: 48, Min: 1, Max: 24 * 30, Integer: true},
+	SettingReviewReportThreshold:     {Description: "Abuse reports after which a review is hidden until a moderator approves it", Default: 3, Min: 1, Max: 1000, Integer: true},
+}
+
+// settingsCacheTTL bounds how stale another instance's cached settings can get after an update
+const settingsCacheTTL = time.Minute
+
+var (
+	settingsMu       sync.RWMutex
+	settingsCache    map[string]float64
+	settingsLoadedAt time.Time
+)
+
+// SettingValue is a setting as shown to admins
+type SettingValue struct {
+	Key         string     `json:"key"`
+	Value       float64    `json:"value"`
+	Default     float64    `json:"default"`
+	Min         float64    `json:"min"`
+	Max         float64    `json:"max"`
+	Description string     `json:"description"`
+	UpdatedBy   uint       `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// loadSettings reads the stored settings over the defaults. Unparseable stored values fall back to the default.
+func loadSettings() map[string]float64 {
+	values := make(map[string]float64, len(settingDefinitions))
+	for key, def := range settingDefinitions {
+		values[key] = def.Default
+	}
+
+	var stored []models.Setting
+	if err := config.DB.Find(&stored).Error; err != nil {
+		LogError("Failed to load settings, using defaults: %v", err)
+		return values
+	}
+	for _, setting := range stored {
+		if _, ok := settingDefinitions[setting.Key]; !ok {
+			continue
+		}
+		value, err := strconv.ParseFloat(setting.Value, 64)
+		if err != nil {
+			LogError("Ignoring invalid value %q for setting %s", setting.Value, setting.Key)
+			continue
+		}
+		values[setting.Key] = value
+	}
+	return values
+}
+
+// settingValue returns a setting from the cache, reloading it when it is older than settingsCacheTTL
+func settingValue(key string) float64 {
+	settingsMu.RLock()
+	if settingsCache != nil && time.Since(settingsLoadedAt) < settingsCacheTTL {
+		value := settingsCache[key]
+		settingsMu.RUnlock()
+		return value
+	}
+	settingsMu.RUnlock()
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	if settingsCache == nil || time.Since(settingsLoadedAt) >= settingsCacheTTL {
+		settingsCache = loadSettings()
+		settingsLoadedAt = time.Now()
+	}
+	return settingsCache[key]
+}
+
+// invalidateSettings makes the next read reload the settings from the database
+func invalidateSettings() {
+	settingsMu.Lock()
+	settingsCache = nil
+	settingsMu.Unlock()
+}
+
+// CancellationWindow is how long after placing an order it can still be cancelled
+func CancellationWindow() time.Duration {
+	return time.Duration(settingValue(SettingCancellationWindowMinutes)) * time.Minute
+}
+
+// ReturnWindowDays is the default number of days after delivery items can be returned in
+func ReturnWindowDays() int {
+	return int(settingValue(SettingReturnWindowDays))
+}
+
+// ReturnWindow is the return window for a category, its own window when set and the store default otherwise
+func ReturnWindow(category models.Category) time.Duration {
+	days := ReturnWindowDays()
+	if category.ReturnWindow > 0 {
+		days = category.ReturnWindow
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// CODOrderLimit is the largest order total that can be paid by Cash on Delivery
+func CODOrderLimit() float64 {
+	return settingValue(SettingCODOrderLimit)
+}
+
+// CODMaxRefusedDeliveries is the number of refused deliveries after which a user loses
+// Cash on Delivery, or 0 when refusals never withdraw it
+func CODMaxRefusedDeliveries() int {
+	return int(settingValue(SettingCODMaxRefusedDeliveries))
+}
+
+// GiftWrapFee is the fee charged for gift wrapping an order
+func GiftWrapFee() float64 {
+	return settingValue(SettingGiftWrapFee)
+}
+
+// EbookDownloadLimit is the number of download links a buyer can get for each digital book
+func EbookDownloadLimit() int {
+	return int(settingValue(SettingEbookDownloadLimit))
+}
+
+// GiftCardValidity is how long a gift card can be redeemed after it is bought or issued
+func GiftCardValidity() time.Duration {
+	return time.Duration(settingValue(SettingGiftCardValidityDays)) * 24 * time.Hour
+}
+
+// GiftCardMaxAmount is the largest value of one gift card
+func GiftCardMaxAmount() float64 {
+	return settingValue(SettingGiftCardMaxAmount)
+}
+
+// DefaultDeliveryDays is the delivery time, in working days, of zones without their own
+func DefaultDeliveryDays() (int, int) {
+	return int(settingValue(SettingDeliveryMinDays)), int(settingValue(SettingDeliveryMaxDays))
+}
+
+// DeliverySlotDays is the number of working days, from the earliest delivery date, for which
+// a delivery slot can be booked
+func DeliverySlotDays() int {
+	return int(settingValue(SettingDeliverySlotDays))
+}
+
+// AbandonedCartAfter is how long a cart with items must go untouched to count as abandoned
+func AbandonedCartAfter() time.Duration {
+	return time.Duration(settingValue(SettingAbandonedCartHours)) * time.Hour
+}
+
+// AbandonedCartSecondReminderAfter is how long after the first abandoned cart reminder the
+// second one is sent
+func AbandonedCartSecondReminderAfter() time.Duration {
+	return time.Duration(settingValue(SettingAbandonedCartRemindHours)) * time.Hour
+}
+
+// ReviewReportThreshold is how many abuse reports hide a review
+func ReviewReportThreshold() int {
+	return int(settingValue(SettingReviewReportThreshold))
+}
+
+// MaxCartQuantity is the most copies of one book a cart can hold
+func MaxCartQuantity() int {
+	return int(settingValue(SettingMaxCartQuantity))
+}
+
+// GetSettings returns all settings with their current values, sorted by key
+func GetSettings() ([]SettingValue, error) {
+	var stored []models.Setting
+	if err := config.DB.Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	storedByKey := make(map[string]models.Setting, len(stored))
+	for _, setting := range stored {
+		storedByKey[setting.Key] = setting
+	}
+
+	settings := make([]SettingValue, 0, len(settingDefinitions))
+	for key, def := range settingDefinitions {
+		value := SettingValue{
+			Key:         key,
+			Value:       def.Default,
+			Default:     def.Default,
+			Min:         def.Min,
+			Max:         def.Max,
+			Description: def.Description,
+		}
+		if setting, ok := storedByKey[key]; ok {
+			if parsed, err := strconv.ParseFloat(setting.Value, 64); err == nil {
+				value.Value = parsed
+			}
+			updatedAt := setting.UpdatedAt
+			value.UpdatedBy = setting.UpdatedBy
+			value.UpdatedAt = &updatedAt
+		}
+		settings = append(settings, value)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings, nil
+}
+
+// ValidateSetting checks that a value is allowed for a setting
+func ValidateSetting(key string, value float64) error {
+	def, ok := settingDefinitions[key]
+	if !ok {
+		return fmt.Errorf("unknown setting %s", key)
+	}
+	if def.Integer && value != float64(int64(value)) {
+		return fmt.Errorf("%s must be a whole number", key)
+	}
+	if value < def.Min || value > def.Max {
+		return fmt.Errorf("%s must be between %g and %g", key, def.Min, def.Max)
+	}
+	return nil
+}
+
+// UpdateSettings validates and stores several settings at once, then drops the cache
+func UpdateSettings(values map[string]float64, adminID uint) error {
+	for key, value := range values {
+		if err := ValidateSetting(key, value); err != nil {
+			return err
+		}
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for key, value := range values {
+			setting := models.Setting{
+				Key:       key,
+				Value:     strconv.FormatFloat(value, 'f', -1, 64),
+				UpdatedBy: adminID,
+				UpdatedAt: time.Now(),
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "key"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by", "updated_at"}),
+			}).Create(&setting).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	invalidateSettings()
+	return nil
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// SMSProvider delivers text messages to phone numbers
+type SMSProvider interface {
+	Name() string
+	Send(phone, message string) error
+}
+
+var (
+	smsOnce     sync.Once
+	smsProvider SMSProvider
+)
+
+// smsSentTotal counts the text messages handed to the SMS provider
+var smsSentTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "readsphere_sms_sent_total",
+	Help: "Text messages sent, by SMS provider.",
+}, []string{"provider"})
+
+// GetSMSProvider returns the provider selected by SMS_PROVIDER ("console", the default, which
+// only logs the messages and is meant for development)
+func GetSMSProvider() SMSProvider {
+	smsOnce.Do(func() {
+		// Gateways are added here by name as they are integrated
+		if name := strings.ToLower(os.Getenv("SMS_PROVIDER")); name != "" && name != "console" {
+			LogError("Unknown SMS_PROVIDER %q, falling back to the console provider", name)
+		}
+		smsProvider = ConsoleSMSProvider{}
+		LogInfo("SMS provider: %s", smsProvider.Name())
+	})
+	return smsProvider
+}
+
+// ConsoleSMSProvider writes text messages to the log instead of sending them
+type ConsoleSMSProvider struct{}
+
+// Name identifies the provider
+func (ConsoleSMSProvider) Name() string { return "console" }
+
+// Send logs the message
+func (ConsoleSMSProvider) Send(phone, message string) error {
+	LogInfo("SMS to %s: %s", phone, message)
+	return nil
+}
+
+// SendSMSOTP texts an OTP to the phone number
+func SendSMSOTP(phone, otp string, lifetimeMinutes int) error {
+	provider := GetSMSProvider()
+	message := fmt.Sprintf("%s is your ReadSphere verification code. It expires in %d minutes. Do not share it with anyone.", otp, lifetimeMinutes)
+	if err := provider.Send(phone, message); err != nil {
+		return fmt.Errorf("failed to send SMS: %v", err)
+	}
+	smsSentTotal.WithLabelValues(provider.Name()).Inc()
+	return nil
+}
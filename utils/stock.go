@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// StockChange is a change made to a book's stock and what caused it
+type StockChange struct {
+	BookID      uint
+	Delta       int
+	Source      string
+	OrderID     *uint
+	OrderItemID *uint
+	AdminID     *uint
+	Note        string
+}
+
+// OrderItemStockChange is the stock change of an order item's copies leaving (negative delta)
+// or returning to stock
+func OrderItemStockChange(item *models.OrderItem, delta int, source string) StockChange {
+	orderID, itemID := item.OrderID, item.ID
+	return StockChange{BookID: item.BookID, Delta: delta, Source: source, OrderID: &orderID, OrderItemID: &itemID}
+}
+
+// RecordStockMovement logs a change already made to the book's stock. Call it in the same
+// transaction as the change, so the stock it records is read under the change's row lock.
+func RecordStockMovement(tx *gorm.DB, change StockChange) error {
+	if change.Delta == 0 {
+		return nil
+	}
+	var stockAfter int
+	if err := tx.Unscoped().Model(&models.Book{}).Select("stock").Where("id = ?", change.BookID).Scan(&stockAfter).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.StockMovement{
+		BookID:      change.BookID,
+		Delta:       change.Delta,
+		StockAfter:  stockAfter,
+		Source:      change.Source,
+		OrderID:     change.OrderID,
+		OrderItemID: change.OrderItemID,
+		AdminID:     change.AdminID,
+		Note:        change.Note,
+	}).Error
+}
+
+// RestockOrderItem returns an order item's quantity to the book's stock exactly once and
+// records the movement under source. The item's StockRestored flag is claimed atomically
+// before the stock is touched, so repeated cancellations, returns or approvals of the same
+// item never restock it twice. It reports whether stock was restored by this call. Must be
+// called inside a transaction.
+func RestockOrderItem(tx *gorm.DB, item *models.OrderItem, source string) (bool, error) {
+	// A pre-order still waiting for its release holds no stock; releasing it only stops the
+	// allocation
+	released := tx.Model(&models.OrderItem{}).
+		Where("id = ? AND preorder_pending = ?", item.ID, true).
+		UpdateColumns(map[string]interface{}{"preorder_pending": false, "stock_restored": true})
+	if released.Error != nil {
+		return false, released.Error
+	}
+	if released.RowsAffected > 0 {
+		LogDebug("Released pre-order item %d without restocking", item.ID)
+		item.PreorderPending = false
+		item.StockRestored = true
+		return false, nil
+	}
+
+	result := tx.Model(&models.OrderItem{}).
+		Where("id = ? AND stock_restored = ?", item.ID, false).
+		UpdateColumn("stock_restored", true)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		LogDebug("Stock already restored for order item %d, skipping", item.ID)
+		item.StockRestored = true
+		return false, nil
+	}
+
+	if err := tx.Model(&models.Book{}).Where("id = ?", item.BookID).
+		UpdateColumn("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
+		return false, err
+	}
+	if err := RecordStockMovement(tx, OrderItemStockChange(item, item.Quantity, source)); err != nil {
+		return false, err
+	}
+
+	item.StockRestored = true
+	LogDebug("Restored %d units of book %d for order item %d", item.Quantity, item.BookID, item.ID)
+	return true, nil
+}
+
+// RestockOrderItems restores stock for every item of the order that has not been restocked yet
+// and returns how many items were restocked by this call. Must be called inside a transaction.
+func RestockOrderItems(tx *gorm.DB, items []models.OrderItem, source string) (int, error) {
+	restocked := 0
+	for i := range items {
+		restored, err := RestockOrderItem(tx, &items[i], source)
+		if err != nil {
+			return restocked, err
+		}
+		if restored {
+			restocked++
+		}
+	}
+	return restocked, nil
+}
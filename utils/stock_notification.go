@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+)
+
+// BackInStockJobName is the scheduled job that emails back-in-stock subscribers
+const BackInStockJobName = "back_in_stock_notifications"
+
+// SendBackInStockNotifications emails every pending subscriber of a book that is in stock
+// and on sale again, and marks their subscription sent. It returns the number of emails sent.
+func SendBackInStockNotifications() (int, error) {
+	var pending []models.StockNotification
+	if err := config.DB.Preload("User").Preload("Book").
+		Joins("JOIN books ON books.id = stock_notifications.book_id").
+		Where("stock_notifications.notified_at IS NULL").
+		Where("books.stock > 0 AND books.is_active = ? AND books.blocked = ? AND books.deleted_at IS NULL", true, false).
+		Order("stock_notifications.book_id, stock_notifications.created_at").
+		Find(&pending).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, notification := range pending {
+		user := notification.User
+		if user.Email == "" || user.AnonymizedAt != nil {
+			continue
+		}
+
+		book := notification.Book
+		if err := SendTemplateEmail(user.Email, fmt.Sprintf("%s is back in stock", book.Name), "back_in_stock", map[string]interface{}{
+			"Name":    user.FirstName,
+			"Book":    book.Name,
+			"Author":  book.Author,
+			"BookURL": FrontendLink(fmt.Sprintf("/books/%d", book.ID)),
+		}); err != nil {
+			LogError("Failed to send back-in-stock email to user ID: %d for book ID: %d: %v", user.ID, book.ID, err)
+			continue
+		}
+
+		if err := config.DB.Model(&models.StockNotification{}).Where("id = ?", notification.ID).
+			UpdateColumn("notified_at", time.Now()).Error; err != nil {
+			LogError("Failed to mark back-in-stock notification %d as sent: %v", notification.ID, err)
+			continue
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		LogInfo("Sent %d back-in-stock notifications", sent)
+	}
+	return sent, nil
+}
+
+// TriggerBackInStockNotifications runs the back-in-stock job in the background right away,
+// so subscribers hear about a restock without waiting for the next scheduled run
+func TriggerBackInStockNotifications() {
+	go func() {
+		if _, err := RunJobNow(BackInStockJobName); err != nil && !errors.Is(err, ErrJobLocked) {
+			LogError("Failed to run back-in-stock notifications: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/Govind-619/ReadSphere/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// seedSoldItem stores a book with stock left after an order item of quantity copies was sold
+func seedSoldItem(t *testing.T, db *gorm.DB, stock, quantity int) (models.Book, models.OrderItem) {
+	t.Helper()
+	book := models.Book{Name: "Restock Test", Price: 100, Stock: stock, IsActive: true}
+	require.NoError(t, db.Create(&book).Error)
+	item := models.OrderItem{OrderID: 1, BookID: book.ID, Quantity: quantity, Price: 100, Total: float64(100 * quantity)}
+	require.NoError(t, db.Create(&item).Error)
+	return book, item
+}
+
+func restock(t *testing.T, db *gorm.DB, itemID uint, source string) bool {
+	t.Helper()
+	var restored bool
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		var item models.OrderItem
+		if err := tx.First(&item, itemID).Error; err != nil {
+			return err
+		}
+		var err error
+		restored, err = RestockOrderItem(tx, &item, source)
+		return err
+	}))
+	return restored
+}
+
+func bookStock(t *testing.T, db *gorm.DB, bookID uint) int {
+	t.Helper()
+	var book models.Book
+	require.NoError(t, db.First(&book, bookID).Error)
+	return book.Stock
+}
+
+func movementCount(t *testing.T, db *gorm.DB, itemID uint) int64 {
+	t.Helper()
+	var count int64
+	require.NoError(t, db.Model(&models.StockMovement{}).Where("order_item_id = ?", itemID).Count(&count).Error)
+	return count
+}
+
+func TestRestockOrderItemCancelThenReturn(t *testing.T) {
+	db := testutil.NewDB(t, &models.Book{}, &models.OrderItem{}, &models.StockMovement{})
+	book, item := seedSoldItem(t, db, 5, 2)
+
+	assert.True(t, restock(t, db, item.ID, models.StockSourceCancellation), "cancellation restocks the item")
+	assert.Equal(t, 7, bookStock(t, db, book.ID))
+
+	assert.False(t, restock(t, db, item.ID, models.StockSourceReturn), "return of a cancelled item must not restock again")
+	assert.Equal(t, 7, bookStock(t, db, book.ID))
+	assert.Equal(t, int64(1), movementCount(t, db, item.ID))
+
+	var stored models.OrderItem
+	require.NoError(t, db.First(&stored, item.ID).Error)
+	assert.True(t, stored.StockRestored)
+}
+
+func TestRestockOrderItemDoubleReturnApproval(t *testing.T) {
+	db := testutil.NewDB(t, &models.Book{}, &models.OrderItem{}, &models.StockMovement{})
+	book, item := seedSoldItem(t, db, 0, 3)
+
+	assert.True(t, restock(t, db, item.ID, models.StockSourceReturn))
+	assert.False(t, restock(t, db, item.ID, models.StockSourceReturn), "approving the same return twice must restock once")
+	assert.Equal(t, 3, bookStock(t, db, book.ID))
+	assert.Equal(t, int64(1), movementCount(t, db, item.ID))
+}
+
+func TestRestockOrderItemStaleCopyIsBlocked(t *testing.T) {
+	db := testutil.NewDB(t, &models.Book{}, &models.OrderItem{}, &models.StockMovement{})
+	book, item := seedSoldItem(t, db, 1, 1)
+
+	// Two handlers loaded the item before either restocked it; only the first claim counts
+	first, second := item, item
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		restored, err := RestockOrderItem(tx, &first, models.StockSourceCancellation)
+		assert.True(t, restored)
+		return err
+	}))
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		restored, err := RestockOrderItem(tx, &second, models.StockSourceReturn)
+		assert.False(t, restored)
+		return err
+	}))
+	assert.True(t, second.StockRestored)
+	assert.Equal(t, 2, bookStock(t, db, book.ID))
+}
+
+func TestRestockOrderItemsCountsOnlyNewRestocks(t *testing.T) {
+	db := testutil.NewDB(t, &models.Book{}, &models.OrderItem{}, &models.StockMovement{})
+	book, cancelled := seedSoldItem(t, db, 4, 1)
+	kept := models.OrderItem{OrderID: 1, BookID: book.ID, Quantity: 2, Price: 100, Total: 200}
+	require.NoError(t, db.Create(&kept).Error)
+
+	assert.True(t, restock(t, db, cancelled.ID, models.StockSourceCancellation))
+
+	var items []models.OrderItem
+	require.NoError(t, db.Where("order_id = ?", 1).Order("id").Find(&items).Error)
+	var restocked int
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		restocked, err = RestockOrderItems(tx, items, models.StockSourceCancellation)
+		return err
+	}))
+	assert.Equal(t, 1, restocked, "only the item not restocked yet counts")
+	assert.Equal(t, 7, bookStock(t, db, book.ID))
+}
+
+func TestRestockOrderItemReleasesPendingPreorder(t *testing.T) {
+	db := testutil.NewDB(t, &models.Book{}, &models.OrderItem{}, &models.StockMovement{})
+	book, item := seedSoldItem(t, db, 0, 1)
+	require.NoError(t, db.Model(&item).Update("preorder_pending", true).Error)
+
+	assert.False(t, restock(t, db, item.ID, models.StockSourceCancellation), "a pending pre-order holds no stock")
+	assert.Equal(t, 0, bookStock(t, db, book.ID))
+	assert.False(t, restock(t, db, item.ID, models.StockSourceReturn))
+	assert.Equal(t, 0, bookStock(t, db, book.ID))
+	assert.Equal(t, int64(0), movementCount(t, db, item.ID))
+}
@@ -0,0 +1,297 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Storage is where uploaded media is kept. Keys are slash-separated paths such as
+// "books/12/cover.jpg"; URL returns the public (CDN-friendly) address of a key.
+type Storage interface {
+	Put(key string, data []byte, contentType string) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+	URL(key string) string
+}
+
+var (
+	storageOnce    sync.Once
+	defaultStorage Storage
+
+	privateStorageOnce sync.Once
+	privateStorage     Storage
+)
+
+// GetStorage returns the storage backend selected by STORAGE_BACKEND ("local", the default, or "s3")
+func GetStorage() Storage {
+	storageOnce.Do(func() {
+		baseURL := strings.TrimRight(os.Getenv("MEDIA_BASE_URL"), "/")
+		switch strings.ToLower(os.Getenv("STORAGE_BACKEND")) {
+		case "s3":
+			s3 := newS3Storage(os.Getenv("S3_BUCKET"), baseURL)
+			if s3.BaseURL == "" {
+				s3.BaseURL = s3.Endpoint
+			}
+			LogInfo("Media storage: S3 bucket %s (%s)", s3.Bucket, s3.Region)
+			defaultStorage = s3
+		default:
+			local := &LocalStorage{Dir: getEnvOr("UPLOAD_DIR", "uploads"), BaseURL: baseURL}
+			if local.BaseURL == "" {
+				local.BaseURL = "/uploads"
+			}
+			LogInfo("Media storage: local directory %s", local.Dir)
+			defaultStorage = local
+		}
+	})
+	return defaultStorage
+}
+
+// GetPrivateStorage returns the storage for files the API hands out only to those allowed to
+// have them, such as e-books. Its objects have no public address: with the s3 backend they are
+// kept in PRIVATE_S3_BUCKET, which must not allow public reads, and otherwise in
+// PRIVATE_UPLOAD_DIR ("private_uploads" by default), which is never served.
+func GetPrivateStorage() Storage {
+	privateStorageOnce.Do(func() {
+		bucket := os.Getenv("PRIVATE_S3_BUCKET")
+		if strings.ToLower(os.Getenv("STORAGE_BACKEND")) == "s3" && bucket != "" {
+			s3 := newS3Storage(bucket, "")
+			s3.Private = true
+			LogInfo("Private file storage: S3 bucket %s (%s)", s3.Bucket, s3.Region)
+			privateStorage = s3
+			return
+		}
+		if strings.ToLower(os.Getenv("STORAGE_BACKEND")) == "s3" {
+			LogError("PRIVATE_S3_BUCKET is not set, keeping private files on local disk")
+		}
+		local := &LocalStorage{Dir: getEnvOr("PRIVATE_UPLOAD_DIR", "private_uploads")}
+		LogInfo("Private file storage: local directory %s", local.Dir)
+		privateStorage = local
+	})
+	return privateStorage
+}
+
+// newS3Storage returns the storage for the bucket configured by the S3_* variables
+func newS3Storage(bucket, baseURL string) *S3Storage {
+	s3 := &S3Storage{
+		Bucket:    bucket,
+		Region:    getEnvOr("S3_REGION", "us-east-1"),
+		Endpoint:  strings.TrimRight(os.Getenv("S3_ENDPOINT"), "/"),
+		AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		BaseURL:   baseURL,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+	if s3.Endpoint == "" {
+		s3.Endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", s3.Bucket, s3.Region)
+	}
+	return s3
+}
+
+func getEnvOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// LocalStorage keeps media on local disk, served by the router under /uploads. Private storage
+// has no BaseURL and is not served.
+type LocalStorage struct {
+	Dir     string
+	BaseURL string
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if strings.HasPrefix(clean, "..") || filepath.IsAbs(clean) {
+		return "", fmt.Errorf("invalid storage key: %s", key)
+	}
+	return filepath.Join(s.Dir, clean), nil
+}
+
+// Put writes the object to disk
+func (s *LocalStorage) Put(key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create upload directory: %v", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Get reads the object from disk
+func (s *LocalStorage) Get(key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Delete removes the object; a missing object is not an error
+func (s *LocalStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// URL returns the public address of the object, or "" for private storage
+func (s *LocalStorage) URL(key string) string {
+	if s.BaseURL == "" {
+		return ""
+	}
+	return s.BaseURL + "/" + key
+}
+
+// S3Storage keeps media in an S3 (or S3-compatible) bucket. Requests are signed with
+// AWS Signature Version 4. Endpoint is the bucket's virtual-hosted URL; BaseURL may
+// point at a CDN in front of the bucket. A Private bucket has no public address.
+type S3Storage struct {
+	Bucket    string
+	Region    string
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	BaseURL   string
+	Private   bool
+	client    *http.Client
+}
+
+// Put uploads the object with a long-lived cache header, since keys are never reused.
+// Objects in a private bucket are never cached by shared caches.
+func (s *S3Storage) Put(key string, data []byte, contentType string) error {
+	req, err := http.NewRequest(http.MethodPut, s.Endpoint+"/"+key, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if s.Private {
+		req.Header.Set("Cache-Control", "private, no-store")
+	} else {
+		req.Header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	return s.do(req, data)
+}
+
+// Get downloads the object
+func (s *S3Storage) Get(key string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, s.Endpoint+"/"+key, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, nil, time.Now().UTC())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("s3 GET %s: status %d: %s", req.URL.Path, resp.StatusCode, message)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Delete removes the object
+func (s *S3Storage) Delete(key string) error {
+	req, err := http.NewRequest(http.MethodDelete, s.Endpoint+"/"+key, nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req, nil)
+}
+
+// URL returns the public address of the object, or "" in a private bucket
+func (s *S3Storage) URL(key string) string {
+	if s.Private {
+		return ""
+	}
+	return s.BaseURL + "/" + key
+}
+
+func (s *S3Storage) do(req *http.Request, body []byte) error {
+	s.sign(req, body, time.Now().UTC())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 %s %s: status %d: %s", req.Method, req.URL.Path, resp.StatusCode, message)
+	}
+	return nil
+}
+
+// sign adds the AWS Signature Version 4 headers to the request
+func (s *S3Storage) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if req.Header.Get("Content-Type") != "" {
+		signedHeaders = []string{"cache-control", "content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
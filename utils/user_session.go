@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Govind-619/ReadSphere/config"
+	"github.com/Govind-619/ReadSphere/models"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"gorm.io/gorm"
+)
+
+// Session lifetimes, overridden at startup from SESSION_LIFETIME and REMEMBER_ME_LIFETIME
+var (
+	sessionLifetime    = 7 * 24 * time.Hour
+	rememberMeLifetime = 30 * 24 * time.Hour
+)
+
+const (
+	// accessTokenLifetime is how long an access token is valid; clients get a new one with the
+	// session's refresh token
+	accessTokenLifetime = 24 * time.Hour
+	// sessionTouchInterval limits how often a session's last seen time is written
+	sessionTouchInterval = 5 * time.Minute
+	// sessionRetention is how long expired and revoked sessions are kept before cleanup
+	sessionRetention = 30 * 24 * time.Hour
+)
+
+// Errors returned when a session can no longer be used
+var (
+	ErrSessionInvalid = errors.New("session has been signed out")
+	ErrSessionExpired = errors.New("session has expired")
+)
+
+// ConfigureSessions sets how long sessions can be refreshed, for devices that are and are not
+// remembered
+func ConfigureSessions(lifetime, rememberMe time.Duration) {
+	sessionLifetime = lifetime
+	rememberMeLifetime = rememberMe
+	LogInfo("Sessions last %s, %s on remembered devices", lifetime, rememberMe)
+}
+
+// SessionLifetime returns how long a session lasts after its last refresh
+func SessionLifetime(rememberMe bool) time.Duration {
+	if rememberMe {
+		return rememberMeLifetime
+	}
+	return sessionLifetime
+}
+
+// StartSession records a session for the user on the requesting device and returns it with its
+// refresh token. deviceName defaults to one derived from the user agent.
+func StartSession(db *gorm.DB, c *gin.Context, userID uint, rememberMe bool, deviceName string) (*models.UserSession, string, error) {
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	userAgent := c.Request.UserAgent()
+	deviceName = strings.TrimSpace(deviceName)
+	if deviceName == "" {
+		deviceName = DeviceName(userAgent)
+	}
+	if len(deviceName) > 100 {
+		deviceName = deviceName[:100]
+	}
+
+	now := time.Now()
+	session := models.UserSession{
+		UserID:           userID,
+		RefreshTokenHash: hash,
+		DeviceName:       deviceName,
+		UserAgent:        userAgent,
+		IPAddress:        c.ClientIP(),
+		RememberMe:       rememberMe,
+		LastSeenAt:       now,
+		ExpiresAt:        now.Add(SessionLifetime(rememberMe)),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, "", err
+	}
+	return &session, token, nil
+}
+
+// SessionToken issues an access token for the user's session. It never outlives the session.
+func SessionToken(user *models.User, session *models.UserSession) (string, error) {
+	now := time.Now()
+	expiresAt := now.Add(accessTokenLifetime)
+	if session.ExpiresAt.Before(expiresAt) {
+		expiresAt = session.ExpiresAt
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID,
+		"email":   user.Email,
+		"sid":     session.ID,
+		"iat":     now.Unix(),
+		"exp":     expiresAt.Unix(),
+	})
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+// RefreshSession exchanges a refresh token for a new one and extends the session by its
+// lifetime. The old refresh token stops working.
+func RefreshSession(db *gorm.DB, c *gin.Context, refreshToken string) (*models.UserSession, string, error) {
+	oldHash := hashRefreshToken(refreshToken)
+
+	var session models.UserSession
+	if err := db.Where("refresh_token_hash = ?", oldHash).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrSessionInvalid
+		}
+		return nil, "", err
+	}
+	now := time.Now()
+	if session.RevokedAt != nil {
+		return nil, "", ErrSessionInvalid
+	}
+	if now.After(session.ExpiresAt) {
+		return nil, "", ErrSessionExpired
+	}
+
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+	updates := map[string]interface{}{
+		"refresh_token_hash": hash,
+		"last_seen_at":       now,
+		"expires_at":         now.Add(SessionLifetime(session.RememberMe)),
+		"ip_address":         c.ClientIP(),
+	}
+	// A token used twice at the same time only refreshes the session once
+	result := db.Model(&session).Where("refresh_token_hash = ? AND revoked_at IS NULL", oldHash).Updates(updates)
+	if result.Error != nil {
+		return nil, "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, "", ErrSessionInvalid
+	}
+	return &session, token, nil
+}
+
+// SessionIDFromClaims returns the session of an access token, or 0 for tokens issued without one
+func SessionIDFromClaims(claims jwt.MapClaims) uint {
+	sid, _ := claims["sid"].(float64)
+	return uint(sid)
+}
+
+// CheckSession returns an error when the user's session has been revoked or has expired, and
+// records the device as seen from ip otherwise
+func CheckSession(sessionID, userID uint, ip string) error {
+	var session models.UserSession
+	if err := config.DB.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionInvalid
+		}
+		return err
+	}
+	now := time.Now()
+	if session.RevokedAt != nil {
+		return ErrSessionInvalid
+	}
+	if now.After(session.ExpiresAt) {
+		return ErrSessionExpired
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval || session.IPAddress != ip {
+		if err := config.DB.Model(&session).UpdateColumns(map[string]interface{}{
+			"last_seen_at": now,
+			"ip_address":   ip,
+		}).Error; err != nil {
+			LogError("Failed to update last seen time of session %d: %v", session.ID, err)
+		}
+	}
+	return nil
+}
+
+// RevokeSessions signs the user out of every active session except keepID, which is 0 to
+// revoke them all, and returns how many were revoked
+func RevokeSessions(db *gorm.DB, userID, keepID uint) (int64, error) {
+	result := db.Model(&models.UserSession{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL AND expires_at > ?", userID, keepID, time.Now()).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// CleanupExpiredSessions deletes sessions that expired or were revoked over the retention period ago
+func CleanupExpiredSessions() (string, error) {
+	cutoff := time.Now().Add(-sessionRetention)
+	result := config.DB.Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).Delete(&models.UserSession{})
+	if result.Error != nil {
+		return "", result.Error
+	}
+	return fmt.Sprintf("Deleted %d old sessions", result.RowsAffected), nil
+}
+
+// DeviceName describes a device by its browser and operating system, such as "Chrome on Windows"
+func DeviceName(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	browser := "Browser"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/"), strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/"), strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "okhttp"), strings.Contains(ua, "dalvik"):
+		browser = "Android app"
+	case strings.Contains(ua, "cfnetwork"):
+		browser = "iOS app"
+	case strings.Contains(ua, "curl"), strings.Contains(ua, "postman"):
+		browser = "API client"
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		platform = "iOS"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os"), strings.Contains(ua, "macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
+
+// newRefreshToken returns a random refresh token and the hash it is stored as
+func newRefreshToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashRefreshToken(token), nil
+}
+
+// hashRefreshToken is the form refresh tokens are stored and looked up in
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// validationMessages holds the field error templates per locale. Keys are validator tags,
+// with a ".string" or ".items" suffix where the wording depends on the field's kind.
+// {param} is replaced by the tag's parameter. Locales without an entry fall back to English.
+var validationMessages = map[string]map[string]string{
+	"en": {
+		"_summary":     "Some fields are invalid",
+		"_default":     "is invalid",
+		"_malformed":   "Request body is not valid JSON",
+		"_type":        "must be a {param}",
+		"required":     "is required",
+		"email":        "must be a valid email address",
+		"url":          "must be a valid URL",
+		"numeric":      "must contain only digits",
+		"alphanum":     "must contain only letters and digits",
+		"oneof":        "must be one of: {param}",
+		"len":          "must be exactly {param}",
+		"len.string":   "must be exactly {param} characters long",
+		"len.items":    "must contain exactly {param} items",
+		"min":          "must be at least {param}",
+		"min.string":   "must be at least {param} characters long",
+		"min.items":    "must contain at least {param} items",
+		"max":          "must be at most {param}",
+		"max.string":   "must be at most {param} characters long",
+		"max.items":    "must contain at most {param} items",
+		"gt":           "must be greater than {param}",
+		"gte":          "must be at least {param}",
+		"lt":           "must be less than {param}",
+		"lte":          "must be at most {param}",
+		"eqfield":      "must match {param}",
+		"nefield":      "must be different from {param}",
+		"_type.number": "must be a number",
+		"_type.string": "must be text",
+		"_type.bool":   "must be true or false",
+		"_type.array":  "must be a list",
+		"_type.object": "must be an object",
+	},
+	"hi": {
+		"_summary":     "कुछ फ़ील्ड अमान्य हैं",
+		"_default":     "अमान्य है",
+		"_malformed":   "अनुरोध का मुख्य भाग मान्य JSON नहीं है",
+		"_type":        "{param} होना चाहिए",
+		"required":     "आवश्यक है",
+		"email":        "एक मान्य ईमेल पता होना चाहिए",
+		"url":          "एक मान्य URL होना चाहिए",
+		"numeric":      "में केवल अंक होने चाहिए",
+		"alphanum":     "में केवल अक्षर और अंक होने चाहिए",
+		"oneof":        "इनमें से एक होना चाहिए: {param}",
+		"len":          "ठीक {param} होना चाहिए",
+		"len.string":   "ठीक {param} वर्णों का होना चाहिए",
+		"len.items":    "में ठीक {param} आइटम होने चाहिए",
+		"min":          "कम से कम {param} होना चाहिए",
+		"min.string":   "कम से कम {param} वर्णों का होना चाहिए",
+		"min.items":    "में कम से कम {param} आइटम होने चाहिए",
+		"max":          "अधिकतम {param} होना चाहिए",
+		"max.string":   "अधिकतम {param} वर्णों का होना चाहिए",
+		"max.items":    "में अधिकतम {param} आइटम होने चाहिए",
+		"gt":           "{param} से अधिक होना चाहिए",
+		"gte":          "कम से कम {param} होना चाहिए",
+		"lt":           "{param} से कम होना चाहिए",
+		"lte":          "अधिकतम {param} होना चाहिए",
+		"eqfield":      "{param} से मेल खाना चाहिए",
+		"nefield":      "{param} से अलग होना चाहिए",
+		"_type.number": "एक संख्या होनी चाहिए",
+		"_type.string": "पाठ होना चाहिए",
+		"_type.bool":   "true या false होना चाहिए",
+		"_type.array":  "एक सूची होनी चाहिए",
+		"_type.object": "एक ऑब्जेक्ट होना चाहिए",
+	},
+}
+
+// RegisterValidationFieldNames makes binding errors name fields by their json (or form) tag,
+// as clients send them, instead of by the Go field name
+func RegisterValidationFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
+
+// validationMessage returns the template for a key in the locale, falling back to English
+func validationMessage(locale, key string) string {
+	if msg, ok := validationMessages[locale][key]; ok {
+		return msg
+	}
+	return validationMessages["en"][key]
+}
+
+// fieldErrorMessage words a single failed validation rule
+func fieldErrorMessage(locale string, fe validator.FieldError) string {
+	key := fe.Tag()
+	switch fe.Kind() {
+	case reflect.String:
+		key += ".string"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		key += ".items"
+	}
+	msg := validationMessage(locale, key)
+	if msg == "" {
+		msg = validationMessage(locale, fe.Tag())
+	}
+	if msg == "" {
+		msg = validationMessage(locale, "_default")
+	}
+	param := fe.Param()
+	if fe.Tag() == "oneof" {
+		param = strings.Join(strings.Fields(param), ", ")
+	}
+	return strings.ReplaceAll(msg, "{param}", param)
+}
+
+// fieldPath is the client-facing path of a failed field, without the root struct name
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fe.Field()
+}
+
+// TranslateBindingError turns a request binding error into a localized summary and
+// field-keyed messages. ok is false for errors that are not about the request body.
+func TranslateBindingError(err error, locale string) (summary string, fields map[string]string, ok bool) {
+	var validationErrors validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+
+	switch {
+	case errors.As(err, &validationErrors):
+		fields = make(map[string]string, len(validationErrors))
+		for _, fe := range validationErrors {
+			path := fieldPath(fe)
+			if _, exists := fields[path]; !exists {
+				fields[path] = fieldErrorMessage(locale, fe)
+			}
+		}
+		return validationMessage(locale, "_summary"), fields, true
+	case errors.As(err, &typeErr):
+		msg := validationMessage(locale, "_type."+jsonKind(typeErr.Type))
+		if msg == "" {
+			msg = strings.ReplaceAll(validationMessage(locale, "_type"), "{param}", typeErr.Type.String())
+		}
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return validationMessage(locale, "_summary"), map[string]string{field: msg}, true
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return validationMessage(locale, "_malformed"), nil, true
+	}
+	return "", nil, false
+}
+
+// jsonKind names the JSON type a Go type is decoded from
+func jsonKind(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+	return ""
+}
+
+// errorDetails builds the "data" of an error response. Binding errors are translated into the
+// request's locale with per-field messages; other errors are sent as their text.
+func errorDetails(c *gin.Context, err interface{}) gin.H {
+	e, isError := err.(error)
+	if !isError {
+		return gin.H{"error": err}
+	}
+	locale := GetRequestLocale(c)
+	if summary, fields, ok := TranslateBindingError(e, locale); ok {
+		details := gin.H{"error": summary}
+		if len(fields) > 0 {
+			details["fields"] = fields
+		}
+		return details
+	}
+	return gin.H{"error": e.Error()}
+}
+
+// BindingErrorMiddleware answers requests whose handler failed gin's c.Bind* with the standard
+// error envelope and localized field messages, since gin only sets the 400 status for them.
+// Handlers using c.ShouldBind* get the same details by passing the error to the response helpers.
+func BindingErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		bindErrors := c.Errors.ByType(gin.ErrorTypeBind)
+		if len(bindErrors) == 0 || c.Writer.Size() > 0 {
+			return
+		}
+		c.JSON(c.Writer.Status(), StandardResponse{
+			Status:  "error",
+			Code:    CodeInvalidRequest,
+			Message: "Invalid request",
+			Data:    errorDetails(c, bindErrors.Last().Err),
+		})
+	}
+}
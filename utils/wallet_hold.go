@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Govind-619/ReadSphere/models"
+	"gorm.io/gorm"
+)
+
+// Holds set wallet funds aside for an order paid partly online. The hold is authorized when
+// the order is placed, so the funds cannot be spent twice while the online payment is open;
+// it is captured as a debit once the payment is confirmed, or released if the order is
+// cancelled unpaid. The wallet row is locked for each step, like any other posting.
+
+// HoldWalletFunds authorizes a hold of amount on the user's wallet for the order. It fails
+// with ErrInsufficientWalletBalance when the available balance does not cover it.
+func HoldWalletFunds(tx *gorm.DB, userID uint, orderID uint, amount float64) (*models.WalletTransaction, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("wallet hold amount must be positive, got %.2f", amount)
+	}
+	wallet, err := lockWallet(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.Balance-wallet.Held < amount {
+		return nil, ErrInsufficientWalletBalance
+	}
+
+	if err := tx.Model(&models.Wallet{}).Where("id = ?", wallet.ID).
+		UpdateColumn("held", gorm.Expr("held + ?", amount)).Error; err != nil {
+		return nil, err
+	}
+	wallet.Held += amount
+
+	hold := models.WalletTransaction{
+		WalletID:    wallet.ID,
+		Amount:      amount,
+		Type:        models.TransactionTypeHold,
+		Description: fmt.Sprintf("Held for order #%d until its payment completes", orderID),
+		OrderID:     &orderID,
+		Reference:   fmt.Sprintf("HOLD-ORDER-%d", orderID),
+		Status:      models.TransactionStatusPending,
+	}
+	if err := tx.Create(&hold).Error; err != nil {
+		return nil, err
+	}
+	hold.Wallet = wallet
+	return &hold, nil
+}
+
+// pendingWalletHold returns the order's open hold, or nil when it has none
+func pendingWalletHold(tx *gorm.DB, orderID uint) (*models.WalletTransaction, error) {
+	var hold models.WalletTransaction
+	err := tx.Where("order_id = ? AND type = ? AND status = ?", orderID, models.TransactionTypeHold, models.TransactionStatusPending).
+		First(&hold).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// settleWalletHold locks the hold's wallet, takes the hold off the held funds and marks it
+// with status. The hold is re-read under the lock so a concurrent settlement is seen.
+func settleWalletHold(tx *gorm.DB, userID uint, orderID uint, status string) (*models.WalletTransaction, error) {
+	if _, err := lockWallet(tx, userID); err != nil {
+		return nil, err
+	}
+	hold, err := pendingWalletHold(tx, orderID)
+	if err != nil || hold == nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.Wallet{}).Where("id = ?", hold.WalletID).
+		UpdateColumn("held", gorm.Expr("GREATEST(held - ?, 0)", hold.Amount)).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(hold).Update("status", status).Error; err != nil {
+		return nil, err
+	}
+	hold.Status = status
+	return hold, nil
+}
+
+// CaptureWalletHold turns the order's open hold into a completed debit of the same amount.
+// It returns nil when the order has no open hold.
+func CaptureWalletHold(tx *gorm.DB, userID uint, orderID uint) (*models.WalletTransaction, error) {
+	hold, err := settleWalletHold(tx, userID, orderID, models.TransactionStatusCaptured)
+	if err != nil || hold == nil {
+		return nil, err
+	}
+	return PostWalletEntry(tx, WalletEntry{
+		UserID:      userID,
+		Amount:      hold.Amount,
+		Type:        models.TransactionTypeDebit,
+		Description: fmt.Sprintf("Wallet part of the payment for order #%d", orderID),
+		Reference:   fmt.Sprintf("ORDER-%d", orderID),
+		OrderID:     &orderID,
+	})
+}
+
+// ReleaseWalletHold gives the order's open hold back to the available balance. It returns
+// nil when the order has no open hold.
+func ReleaseWalletHold(tx *gorm.DB, userID uint, orderID uint) (*models.WalletTransaction, error) {
+	return settleWalletHold(tx, userID, orderID, models.TransactionStatusReleased)
+}
@@ -1,9 +1,13 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/Govind-619/ReadSphere/config"
 	"github.com/Govind-619/ReadSphere/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GetOrCreateWallet retrieves or creates a wallet for a user
@@ -25,36 +29,101 @@ func GetOrCreateWallet(userID uint) (*models.Wallet, error) {
 	return &wallet, nil
 }
 
-// CreateWalletTransaction creates a new wallet transaction
-func CreateWalletTransaction(walletID uint, amount float64, transactionType string, description string, orderID *uint, reference string) (*models.WalletTransaction, error) {
-	transaction := models.WalletTransaction{
-		WalletID:    walletID,
-		Amount:      amount,
-		Type:        transactionType,
-		Description: description,
-		OrderID:     orderID,
-		Reference:   reference,
+// lockWallet loads the user's wallet locked for update through tx, creating it if the user has
+// none yet
+func lockWallet(tx *gorm.DB, userID uint) (models.Wallet, error) {
+	var wallet models.Wallet
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&wallet).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		wallet = models.Wallet{UserID: userID}
+		err = tx.Create(&wallet).Error
 	}
+	return wallet, err
+}
+
+// ErrInsufficientWalletBalance is returned when a debit is larger than the available wallet
+// balance, the balance less any held funds
+var ErrInsufficientWalletBalance = errors.New("insufficient wallet balance")
 
-	if err := config.DB.Create(&transaction).Error; err != nil {
+// WalletEntry is a completed wallet transaction to post to a user's wallet
+type WalletEntry struct {
+	UserID      uint
+	Amount      float64 // always positive; Type gives the direction
+	Type        string  // models.TransactionTypeCredit or models.TransactionTypeDebit
+	Description string
+	Reference   string
+	OrderID     *uint
+	AdminID     *uint // set for manual adjustments made by an admin
+}
+
+// PostWalletEntry records a completed wallet transaction and moves the wallet balance by the
+// same amount, both through tx, so the ledger and the balance commit or roll back together
+// with the caller's other changes. The wallet row stays locked until tx ends, which serializes
+// concurrent postings to one wallet. The wallet is created if the user has none yet, and the
+// user is notified of the posting. The returned transaction carries the wallet with its new
+// balance.
+func PostWalletEntry(tx *gorm.DB, entry WalletEntry) (*models.WalletTransaction, error) {
+	if entry.Amount <= 0 {
+		return nil, fmt.Errorf("wallet entry amount must be positive, got %.2f", entry.Amount)
+	}
+
+	wallet, err := lockWallet(tx, entry.UserID)
+	if err != nil {
 		return nil, err
 	}
 
-	return &transaction, nil
-}
+	change := entry.Amount
+	switch entry.Type {
+	case models.TransactionTypeCredit:
+	case models.TransactionTypeDebit:
+		// Funds held for unpaid orders cannot be spent again
+		if wallet.Balance-wallet.Held < entry.Amount {
+			return nil, ErrInsufficientWalletBalance
+		}
+		change = -entry.Amount
+	default:
+		return nil, fmt.Errorf("invalid wallet transaction type %q", entry.Type)
+	}
 
-// UpdateWalletBalance updates the wallet balance based on transaction type
-func UpdateWalletBalance(walletID uint, amount float64, transactionType string) error {
-	var wallet models.Wallet
-	if err := config.DB.First(&wallet, walletID).Error; err != nil {
-		return err
+	if err := tx.Model(&models.Wallet{}).Where("id = ?", wallet.ID).
+		UpdateColumn("balance", gorm.Expr("balance + ?", change)).Error; err != nil {
+		return nil, err
 	}
+	wallet.Balance += change
 
-	if transactionType == models.TransactionTypeDebit {
-		wallet.Balance -= amount
-	} else {
-		wallet.Balance += amount
+	transaction := models.WalletTransaction{
+		WalletID:    wallet.ID,
+		Amount:      entry.Amount,
+		Type:        entry.Type,
+		Description: entry.Description,
+		OrderID:     entry.OrderID,
+		Reference:   entry.Reference,
+		Status:      models.TransactionStatusCompleted,
+		AdminID:     entry.AdminID,
 	}
+	if err := tx.Create(&transaction).Error; err != nil {
+		return nil, err
+	}
+	transaction.Wallet = wallet
 
-	return config.DB.Save(&wallet).Error
+	title := fmt.Sprintf("%s credited to your wallet", FormatMoney(entry.Amount, BaseCurrency()))
+	if entry.Type == models.TransactionTypeDebit {
+		title = fmt.Sprintf("%s debited from your wallet", FormatMoney(entry.Amount, BaseCurrency()))
+	}
+	if _, err := Notify(tx, entry.UserID, models.NotificationTypeWallet, title, entry.Description, "/v1/user/wallet/transactions"); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// CreditWallet adds a completed credit to the user's wallet inside the transaction,
+// creating the wallet if needed
+func CreditWallet(tx *gorm.DB, userID uint, amount float64, description, reference string) (*models.WalletTransaction, error) {
+	return PostWalletEntry(tx, WalletEntry{
+		UserID:      userID,
+		Amount:      amount,
+		Type:        models.TransactionTypeCredit,
+		Description: description,
+		Reference:   reference,
+	})
 }